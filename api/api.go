@@ -4,14 +4,28 @@ package api
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/http"
+	"os"
+	"strconv"
 	"time"
 
 	"github.com/AdrianWangs/go-cache/api/handlers"
 	"github.com/AdrianWangs/go-cache/api/routes"
+	"github.com/AdrianWangs/go-cache/internal/access"
+	"github.com/AdrianWangs/go-cache/internal/admin"
+	"github.com/AdrianWangs/go-cache/internal/auth"
+	"github.com/AdrianWangs/go-cache/internal/consistenthash"
+	"github.com/AdrianWangs/go-cache/internal/deletequeue"
 	"github.com/AdrianWangs/go-cache/internal/discovery"
+	"github.com/AdrianWangs/go-cache/internal/health"
+	"github.com/AdrianWangs/go-cache/internal/peers"
+	"github.com/AdrianWangs/go-cache/pkg/fanout"
 	"github.com/AdrianWangs/go-cache/pkg/logger"
 	"github.com/AdrianWangs/go-cache/pkg/router"
+	"github.com/AdrianWangs/go-cache/pkg/router/promrecorder"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 // ApiServerConfig API服务器配置
@@ -21,19 +35,94 @@ type ApiServerConfig struct {
 	ApiPort       int                   // API服务器端口
 	Replicas      int                   // 虚拟节点倍数
 	BasePath      string                // 内部通信路径
-	Protocol      handlers.ProtocolType // 通信协议类型
+	Protocol      handlers.ProtocolType // 默认通信协议：登记了所提供协议的节点优先使用 gRPC，未登记的旧版本节点使用该协议
+
+	BaseURLPrefix string // 所有路由的路径前缀，用于反向代理把API服务器映射到子路径（例如 /cache），为空时挂载在根路径；/health 和 /ready 在根路径下同样可用
+
+	RequestTimeout  time.Duration // 访问缓存节点的请求超时，默认3s
+	DialTimeout     time.Duration // 与缓存节点建立连接的超时，默认2s
+	EtcdDialTimeout time.Duration // 连接etcd的超时，默认5s
+	ShutdownTimeout time.Duration // 优雅关闭的超时，默认5s
+	NodeLimit       peers.Limit   // 发往每个缓存节点的未完成请求数上限，超出时读请求改由下一个节点处理，零值表示不限制
+
+	AdminToken     string // 管理接口访问令牌，为空时管理接口关闭
+	AdminRateLimit int    // 导入导出每秒处理的条目上限，0 表示不限速
+
+	HedgeDelay  time.Duration // 对冲读取的等待时间，0 表示关闭对冲
+	HedgeBudget float64       // 对冲请求占读请求的最大百分比，默认5
+
+	HotKeySpread handlers.HotKeySpread // 缓存节点复制热点 key 后读请求的分配方式: round-robin（默认）、rendezvous 或 off
+
+	ReadRepairRate float64       // 每秒最多发起的读修复数，0 表示关闭读修复
+	ReadRepairTTL  time.Duration // 读修复写到副本节点的值的最长有效期，默认30s
+
+	SeedNodes []discovery.NodeInfo // 首次从etcd同步之前使用的种子节点，收到第一份节点列表后被替换
+
+	MaxDiscoveryLag time.Duration // 服务发现中断超过该时长后 /health 返回 503，默认30s
+
+	FanOutConcurrency int // 聚合接口（组统计、批量读取）同时访问的节点数上限，默认16
+
+	DeleteRetryMaxSize int           // 删除重试队列的容量，0 表示不开启删除重试，发往归属节点失败的删除直接返回错误
+	DeleteRetryMaxAge  time.Duration // 删除最长重试时间，超过后放弃并计入失败，默认10m
+	DeleteJournal      string        // 删除重试队列的日志文件，为空时队列只在内存中，重启后丢失
+
+	DeleteMode          handlers.DeleteMode // 单个 key 的删除发往的节点: owner（默认）、owner+previous 或 broadcast
+	DeletePreviousGrace time.Duration       // owner+previous 下节点成员变化后仍向上一代哈希环的归属节点删除的时长，默认30s
+
+	RingHash string // 一致性哈希函数: crc32（默认，与旧版本兼容）或 xxhash64，必须与缓存节点的 -ring-hash 一致
+
+	Signer *auth.Signer // 对发往缓存节点的请求签名，为 nil 时不签名；节点须配置相同的密钥
+
+	Access *access.Store // 客户端令牌的按组授权，为 nil 或策略中没有令牌时不做限制；可在运行时替换策略
+
+	Settings map[string]string // 启动参数等生效的配置，由 /api/admin/info 脱敏后返回
+
+	Identity     string // 本 API 服务器的标识，在读取响应的 X-GoCache-Routed-By 响应头中返回，默认为 主机名:端口
+	HideIdentity bool   // 读取响应中不返回 API 服务器和缓存节点的标识，用于把拓扑信息视为敏感的部署
+
+	CachePolicies handlers.CachePolicies // 读取响应的按组 HTTP 缓存策略，键为组名，handlers.DefaultCachePolicy（*）为默认策略；为空时所有读取响应都是 no-store
+	Purger        handlers.Purger        // 删除 key 后清除 CDN 缓存，为 nil 时不清除
+
+	Proxy handlers.ProxyUpstreams // 按组配置的上游，配置了上游的组可以通过 /api/proxy/{group}/{path...} 作为缓存反向代理访问
+
+	PrometheusMetrics bool // 在 /metrics 以 Prometheus 文本格式导出各路由的请求数和耗时，默认关闭
+
+	StatsWindow int // /api/metrics 每分钟统计窗口保留的分钟数，0 使用默认的 60，小于 0 时关闭
+
+	Watcher NodeWatcher // 节点列表来源，为 nil 时根据 EtcdEndpoints 创建 etcd 监视
+
+	Logger logger.Logger // API服务器启动、停止和服务发现的日志写入的 Logger，为 nil 时使用 logger 包的全局日志（见 logger.SetLogger）；处理请求的日志仍通过 logger 包输出
 }
 
+// NodeWatcher 提供缓存节点列表及其变化，discovery.ServiceWatcher 是基于 etcd 的实现，
+// 测试集群 (internal/testutil/cluster) 使用不依赖 etcd 的实现
+type NodeWatcher interface {
+	// Watch 开始监视，返回节点列表更新和错误两个通道，ctx 取消时两个通道都会关闭
+	Watch(ctx context.Context) (<-chan []discovery.NodeInfo, <-chan error)
+	// Status 返回当前的监视状态
+	Status() discovery.WatchStatus
+	// Close 释放资源
+	Close() error
+}
+
+const (
+	defaultShutdownTimeout = 5 * time.Second  // 默认的优雅关闭超时
+	defaultDiscoveryLag    = 30 * time.Second // 默认的服务发现最长中断时间
+)
+
 // ApiServer API服务器
 type ApiServer struct {
-	config         *ApiServerConfig          // 配置
-	serviceWatcher *discovery.ServiceWatcher // 服务发现
-	httpServer     *http.Server              // HTTP服务器
-	router         *router.Router            // 路由器
-	cacheHandler   *handlers.CacheHandler    // 缓存处理器
-	nodeHandler    *handlers.NodeHandler     // 节点处理器
-	metricsHandler *handlers.MetricsHandler  // 指标处理器
-	cancelWatch    context.CancelFunc        // 用于取消服务发现
+	config         *ApiServerConfig         // 配置
+	serviceWatcher NodeWatcher              // 服务发现
+	httpServer     *http.Server             // HTTP服务器
+	router         *router.Router           // 路由器
+	cacheHandler   *handlers.CacheHandler   // 缓存处理器
+	nodeHandler    *handlers.NodeHandler    // 节点处理器
+	metricsHandler *handlers.MetricsHandler // 指标处理器
+	adminHandler   *handlers.AdminHandler   // 管理处理器
+	promHandler    http.Handler             // Prometheus 指标处理器，未开启时为 nil
+	cancelWatch    context.CancelFunc       // 用于取消服务发现
+	log            logger.Logger            // 日志写入的 Logger
 }
 
 // NewApiServer 创建新的API服务器
@@ -42,10 +131,26 @@ func NewApiServer(config *ApiServerConfig) (*ApiServer, error) {
 		return nil, fmt.Errorf("API服务器配置不能为空")
 	}
 
-	// 创建服务发现
-	serviceWatcher, err := discovery.NewServiceWatcher(config.EtcdEndpoints, config.ServiceName)
+	ringHash, err := consistenthash.ParseHash(config.RingHash)
 	if err != nil {
-		return nil, fmt.Errorf("创建服务发现失败: %v", err)
+		return nil, err
+	}
+	config.RingHash = ringHash
+
+	log := logger.Or(config.Logger)
+
+	// 创建服务发现
+	serviceWatcher := config.Watcher
+	if serviceWatcher == nil {
+		sw, err := discovery.NewServiceWatcher(config.EtcdEndpoints, config.ServiceName,
+			discovery.WithDialTimeout(config.EtcdDialTimeout),
+			discovery.WithStateHook(func(status discovery.WatchStatus) { logDiscoveryState(log, status) }),
+			discovery.WithLogger(log),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("创建服务发现失败: %v", err)
+		}
+		serviceWatcher = sw
 	}
 
 	// 设置默认协议
@@ -53,46 +158,140 @@ func NewApiServer(config *ApiServerConfig) (*ApiServer, error) {
 		config.Protocol = handlers.ProtocolHTTP
 	}
 
+	if config.ShutdownTimeout <= 0 {
+		config.ShutdownTimeout = defaultShutdownTimeout
+	}
+	if config.MaxDiscoveryLag <= 0 {
+		config.MaxDiscoveryLag = defaultDiscoveryLag
+	}
+
+	// 访问缓存节点使用的超时配置
+	getterOpts := []handlers.GetterOption{
+		handlers.WithRequestTimeout(config.RequestTimeout),
+		handlers.WithDialTimeout(config.DialTimeout),
+		handlers.WithSigner(config.Signer),
+		handlers.WithPeerLimit(config.NodeLimit),
+	}
+
 	// 创建处理器
+	metricsHandler := handlers.NewMetricsHandler()
+	if config.StatsWindow != 0 {
+		metricsHandler.SetStatsWindow(config.StatsWindow, nil)
+	}
 	cacheHandler := handlers.NewCacheHandler(config.BasePath, config.Replicas, handlers.CacheHandlerOptions{
-		Protocol: config.Protocol,
+		Protocol:      config.Protocol,
+		GetterOptions: getterOpts,
+		Hedge: handlers.HedgeConfig{
+			Delay:         config.HedgeDelay,
+			BudgetPercent: config.HedgeBudget,
+		},
+		Delete: handlers.DeleteConfig{
+			Mode:  config.DeleteMode,
+			Grace: config.DeletePreviousGrace,
+		},
+		FanOut:       fanout.Options{Concurrency: config.FanOutConcurrency},
+		RingHash:     config.RingHash,
+		HotKeySpread: config.HotKeySpread,
+		ReadRepair: handlers.ReadRepairConfig{
+			Rate:   config.ReadRepairRate,
+			MaxTTL: config.ReadRepairTTL,
+		},
+		Identity:      identity(config),
+		CachePolicies: config.CachePolicies,
+		Purger:        config.Purger,
+		Proxy:         config.Proxy,
+		OnRead:        metricsHandler.RecordRead,
 	})
 	nodeHandler := handlers.NewNodeHandler()
-	metricsHandler := handlers.NewMetricsHandler()
+	metricsHandler.SetHedgeStats(cacheHandler.HedgeStats)
+	metricsHandler.SetHotKeyStats(cacheHandler.HotKeyStats)
+	metricsHandler.SetDiscoveryStatus(serviceWatcher.Status)
+	metricsHandler.SetNodeStats(cacheHandler.NodeStats)
+	metricsHandler.SetClientCancelled(cacheHandler.ClientCancelled)
+	metricsHandler.SetNotModified(cacheHandler.NotModified)
+	if config.ReadRepairRate > 0 {
+		metricsHandler.SetReadRepairStats(cacheHandler.ReadRepairStats)
+	}
+	if len(config.Proxy) > 0 {
+		metricsHandler.SetProxyStats(cacheHandler.ProxyStats)
+	}
+	if config.DeleteRetryMaxSize > 0 {
+		opts := []deletequeue.Option{
+			deletequeue.WithMaxSize(config.DeleteRetryMaxSize),
+			deletequeue.WithMaxAge(config.DeleteRetryMaxAge),
+		}
+		if config.DeleteJournal != "" {
+			opts = append(opts, deletequeue.WithJournal(config.DeleteJournal))
+		}
+		if err := cacheHandler.EnableDeleteRetry(opts...); err != nil {
+			return nil, fmt.Errorf("开启删除重试失败: %v", err)
+		}
+		metricsHandler.SetDeleteRetryStats(cacheHandler.DeleteRetryStats)
+	}
+	nodeHandler.SetHealthChecker(newHealthChecker(config, serviceWatcher, nodeHandler))
+	adminHandler := handlers.NewAdminHandler(cacheHandler, config.AdminToken, config.AdminRateLimit)
+	discoveryMode := "etcd"
+	if config.Watcher != nil {
+		discoveryMode = "custom"
+	}
+	adminHandler.SetInfo((&admin.InfoSource{
+		Component: "apiserver",
+		StartTime: time.Now(),
+		Config:    config.Settings,
+		Discovery: func() admin.DiscoveryInfo {
+			return admin.DiscoveryInfo{Mode: discoveryMode, State: serviceWatcher.Status().State.String()}
+		},
+	}).Info)
 
 	// 设置节点变更回调
-	nodeHandler.SetServiceChangeHook(func(nodes []string) {
-		// 当节点列表变化时更新缓存处理器中的节点列表
-		if config.Protocol == handlers.ProtocolGRPC {
-			// 使用gRPC getter
-			cacheHandler.UpdatePeers(nodes, func(addr string) handlers.NodeGetter {
-				return handlers.NewGRPCGetter(addr)
-			})
-		} else {
-			// 使用HTTP getter
-			cacheHandler.UpdatePeers(nodes, func(baseURL string) handlers.NodeGetter {
-				return handlers.NewHTTPGetter(baseURL)
-			})
-		}
-	})
+	// 当节点列表变化时更新缓存处理器中的节点列表，每个节点按登记的协议选择 getter
+	nodeHandler.SetServiceChangeHook(cacheHandler.UpdatePeers)
+	nodeHandler.SetNodeProtocols(cacheHandler.NodeProtocols)
 
 	// 创建路由器
 	r := router.New()
 
-	// 添加中间件 (示例日志和指标中间件)
-	r.Use(router.LoggingMiddleware())
-	r.Use(router.RecoveryMiddleware())
+	// 按注册路由统计请求，/api/metrics 展示内存中的统计和每分钟速率，开启时同时导出 Prometheus 指标
+	routeStats := router.NewMemoryRecorder()
+	metricsHandler.SetRouteStats(routeStats.Routes)
+	var promHandler http.Handler
+	if config.PrometheusMetrics {
+		reg := prometheus.NewRegistry()
+		promRec, err := promrecorder.New(reg, "gocache_api")
+		if err != nil {
+			return nil, fmt.Errorf("注册 Prometheus 指标失败: %v", err)
+		}
+		r.SetRecorder(router.MultiRecorder(routeStats, metricsHandler, promRec))
+		promHandler = promhttp.HandlerFor(reg, promhttp.HandlerOpts{})
+	} else {
+		r.SetRecorder(router.MultiRecorder(routeStats, metricsHandler))
+	}
+
+	// 添加中间件
+	r.Use(router.LoggingMiddleware(handlers.CacheKeyPathPrefix, handlers.ProxyPathPrefix))
+	r.Use(router.RecoveryMiddleware(handlers.CacheKeyPathPrefix, handlers.ProxyPathPrefix))
+	if config.Access != nil {
+		// 解析请求令牌的授权范围，由各处理器按组和操作校验
+		r.Use(func(h router.Handler) router.Handler {
+			return config.Access.Middleware(h)
+		})
+	}
+	// 每个响应都带上路由所用哈希环的代数，客户端发现它增大时即知道节点成员发生了变化
 	r.Use(func(h router.Handler) router.Handler {
 		return router.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-			metricsHandler.IncrementRequestCount() // 记录请求次数
+			w.Header().Set(peers.HeaderRingGeneration, strconv.FormatUint(cacheHandler.RingGeneration(), 10))
 			h.ServeHTTP(w, req)
 		})
 	})
+	// 去掉路径前缀后再分发，处理器按不带前缀的路径解析；
+	// 负载均衡器的健康检查无法配置路径时仍可访问根路径下的 /health 和 /ready
+	r.Mount(config.BaseURLPrefix, "/health", "/ready")
 
-	// 创建HTTP服务器
+	// 创建HTTP服务器；路径中字面量的 "." 和 ".." 返回 400，而不是由 ServeMux 重定向到清理后的路径，
+	// 这样的 key 需要写成 %2E
 	server := &http.Server{
 		Addr:    fmt.Sprintf(":%d", config.ApiPort),
-		Handler: r,
+		Handler: peers.RejectDotSegments(r),
 	}
 
 	return &ApiServer{
@@ -103,13 +302,94 @@ func NewApiServer(config *ApiServerConfig) (*ApiServer, error) {
 		cacheHandler:   cacheHandler,
 		nodeHandler:    nodeHandler,
 		metricsHandler: metricsHandler,
+		adminHandler:   adminHandler,
+		promHandler:    promHandler,
+		log:            log,
 	}, nil
 }
 
-// Start 启动API服务器
+// newHealthChecker 创建 API 服务器的健康检查：
+// 服务发现（关键，中断超过 MaxDiscoveryLag 视为不可用）和可路由的节点数（关键，为 0 时不可用）。
+// 首次同步节点列表后就绪；配置了种子节点时启动即就绪
+func newHealthChecker(config *ApiServerConfig, watcher NodeWatcher, nodes *handlers.NodeHandler) *health.Checker {
+	seeded := len(config.SeedNodes) > 0
+	checker := health.NewChecker()
+
+	checker.Add("discovery", true, func() health.Result {
+		status := watcher.Status()
+		details := map[string]interface{}{
+			"state":     status.State.String(),
+			"since":     status.Since,
+			"last_sync": status.LastSync,
+		}
+		if status.LastError != "" {
+			details["last_error"] = status.LastError
+		}
+		if !status.Degraded() {
+			return health.OK(details)
+		}
+
+		lag := time.Since(status.Since)
+		details["lag"] = lag.Round(time.Millisecond).String()
+		switch {
+		case !status.Synced() && !seeded:
+			return health.Down("尚未从etcd同步节点列表", details)
+		case lag > config.MaxDiscoveryLag:
+			return health.Down(fmt.Sprintf("服务发现已中断 %v，超过 %v", lag.Round(time.Second), config.MaxDiscoveryLag), details)
+		default:
+			return health.Degraded("服务发现中断，使用已知的节点列表", details)
+		}
+	})
+
+	checker.Add("nodes", true, func() health.Result {
+		count := nodes.NodeCount()
+		details := map[string]interface{}{"count": count}
+		if count == 0 {
+			return health.Down("没有可路由的缓存节点", details)
+		}
+		return health.OK(details)
+	})
+
+	checker.AddReady(func() bool {
+		return seeded || watcher.Status().Synced()
+	})
+	return checker
+}
+
+// logDiscoveryState 记录服务发现的状态变化，中断期间节点列表不再更新
+func logDiscoveryState(log logger.Logger, status discovery.WatchStatus) {
+	if status.Degraded() {
+		log.Warnf("服务发现状态: %s，节点列表可能已经过期", status.State)
+		return
+	}
+	log.Infof("服务发现状态: %s", status.State)
+}
+
+// Start 在 ApiPort 上启动API服务器，直到服务器关闭才返回
 func (s *ApiServer) Start() error {
+	l, err := net.Listen("tcp", s.httpServer.Addr)
+	if err != nil {
+		s.log.Errorf("HTTP服务器启动失败: %v", err)
+		return fmt.Errorf("HTTP服务器启动失败: %w", err)
+	}
+	s.log.Infof("API服务器启动在 http://localhost:%d%s", s.config.ApiPort, s.router.Prefix())
+	return s.Serve(l)
+}
+
+// Serve 注册路由、启动服务发现并在 l 上提供服务，直到服务器关闭才返回。
+// 测试中可以传入随机端口的 listener
+func (s *ApiServer) Serve(l net.Listener) error {
 	// 注册路由
-	routes.RegisterRoutes(s.router, s.cacheHandler, s.nodeHandler, s.metricsHandler)
+	routes.RegisterRoutes(s.router, s.cacheHandler, s.nodeHandler, s.metricsHandler, s.adminHandler)
+	if s.promHandler != nil {
+		s.router.Register("/metrics", s.promHandler)
+	}
+
+	// 服务发现收敛之前先使用种子节点，启动后即可路由请求
+	if len(s.config.SeedNodes) > 0 {
+		s.log.Infof("使用 %d 个种子节点: %v", len(s.config.SeedNodes), s.config.SeedNodes)
+		s.nodeHandler.UpdateNodes(s.config.SeedNodes)
+	}
 
 	// 创建用于服务发现的上下文
 	watchCtx, cancelWatch := context.WithCancel(context.Background())
@@ -117,67 +397,90 @@ func (s *ApiServer) Start() error {
 
 	// 启动服务发现
 	go func() {
-		logger.Info("启动服务发现...")
+		s.log.Infof("启动服务发现...")
 		updatesChan, errChan := s.serviceWatcher.Watch(watchCtx)
 		for {
 			select {
 			case services, ok := <-updatesChan:
 				if !ok {
-					logger.Warn("服务发现更新通道已关闭")
+					s.log.Warnf("服务发现更新通道已关闭")
 					return
 				}
-				logger.Infof("发现服务变化，当前有 %d 个节点: %v", len(services), services)
-				s.nodeHandler.UpdateNodeAddresses(services)
+				s.log.Infof("发现服务变化，当前有 %d 个节点: %v", len(services), services)
+				s.nodeHandler.UpdateNodes(services)
 			case err, ok := <-errChan:
 				if !ok {
-					logger.Warn("服务发现错误通道已关闭")
+					s.log.Warnf("服务发现错误通道已关闭")
 					return
 				}
-				logger.Errorf("服务发现遇到错误: %v", err)
-				// 这里可以添加重试逻辑或退出
+				s.log.Errorf("服务发现遇到错误: %v", err) // Watch 会自行重试并重新建立监视
 			case <-watchCtx.Done():
-				logger.Info("服务发现已停止 (context canceled)")
+				s.log.Infof("服务发现已停止 (context canceled)")
 				return
 			}
 		}
 	}()
 
 	// 启动HTTP服务器
-	logger.Infof("API服务器启动在 http://localhost:%d", s.config.ApiPort)
-	if err := s.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-		logger.Errorf("HTTP服务器启动失败: %v", err)
+	if err := s.httpServer.Serve(l); err != nil && err != http.ErrServerClosed {
+		s.log.Errorf("HTTP服务器启动失败: %v", err)
 		return fmt.Errorf("HTTP服务器启动失败: %w", err)
 	}
 	return nil
 }
 
+// Nodes 返回API服务器当前使用的节点列表
+func (s *ApiServer) Nodes() []discovery.NodeInfo {
+	return s.nodeHandler.Nodes()
+}
+
 // Stop 停止API服务器
 func (s *ApiServer) Stop() error {
-	logger.Info("正在停止API服务器...")
+	s.log.Infof("正在停止API服务器...")
 
 	// 停止服务发现
 	if s.cancelWatch != nil {
 		s.cancelWatch()
-		logger.Info("已发送停止信号给服务发现")
+		s.log.Infof("已发送停止信号给服务发现")
 	}
 
 	// 创建一个有超时的上下文用于HTTP服务器关闭
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.ShutdownTimeout)
 	defer cancel()
 
 	// 优雅地关闭HTTP服务器
 	if err := s.httpServer.Shutdown(ctx); err != nil {
-		logger.Errorf("HTTP服务器关闭失败: %v", err)
+		s.log.Errorf("HTTP服务器关闭失败: %v", err)
 		// 即使关闭失败，也要继续关闭其他资源
 	}
 
+	// 停止删除重试，开启了删除日志时未完成的删除留给下次启动
+	if err := s.cacheHandler.Close(); err != nil {
+		s.log.Errorf("关闭删除重试队列失败: %v", err)
+	}
+
 	// 关闭服务发现客户端连接 (如果需要，可以放在最后)
 	if s.serviceWatcher != nil {
 		if err := s.serviceWatcher.Close(); err != nil {
-			logger.Errorf("服务发现客户端关闭失败: %v", err)
+			s.log.Errorf("服务发现客户端关闭失败: %v", err)
 		}
 	}
 
-	logger.Info("API服务器已停止")
+	s.log.Infof("API服务器已停止")
 	return nil
 }
+
+// identity 返回读取响应中报告的 API 服务器标识，不公开标识时为空
+func identity(config *ApiServerConfig) string {
+	switch {
+	case config.HideIdentity:
+		return ""
+	case config.Identity != "":
+		return config.Identity
+	}
+	host, err := os.Hostname()
+	if err != nil {
+		host = "localhost"
+	}
+	return net.JoinHostPort(host, strconv.Itoa(config.ApiPort))
+}
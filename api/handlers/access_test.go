@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/AdrianWangs/go-cache/config"
+	"github.com/AdrianWangs/go-cache/internal/access"
+)
+
+// TestCacheHandlersEnforceGroupScope 读写处理器按令牌的组和操作授权，拒绝的请求不发往节点
+func TestCacheHandlersEnforceGroupScope(t *testing.T) {
+	policy, err := access.NewPolicy(config.AccessConfig{Tokens: []config.AccessToken{
+		{ID: "reader", Token: "tok-r", Groups: []string{"scores"}},
+		{ID: "writer", Token: "tok-w", Groups: []string{"*"}, Ops: []string{"read", "write"}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := access.NewStore(policy)
+
+	tests := []struct {
+		name   string
+		method string
+		group  string
+		token  string
+		status int // 0 表示请求被放行，状态码由节点的结果决定
+	}{
+		{"读取允许的组", http.MethodGet, "scores", "tok-r", 0},
+		{"读取范围外的组", http.MethodGet, "users", "tok-r", http.StatusForbidden},
+		{"只读令牌删除", http.MethodDelete, "scores", "tok-r", http.StatusForbidden},
+		{"通配符读取", http.MethodGet, "users", "tok-w", 0},
+		{"通配符删除", http.MethodDelete, "users", "tok-w", 0},
+		{"未携带令牌", http.MethodGet, "scores", "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			factory := &recordingFactory{}
+			h := NewCacheHandler("/_gocache/", 50, CacheHandlerOptions{Getters: factory})
+			h.UpdatePeers(nodesWithGroups(3, "scores", "users"))
+
+			srv := store.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodDelete {
+					h.DeleteCacheHandler(w, r)
+				} else {
+					h.GetCacheHandler(w, r)
+				}
+			}))
+			r := httptest.NewRequest(tt.method, "/api/cache/"+tt.group+"/k", nil)
+			if tt.token != "" {
+				r.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			srv.ServeHTTP(w, r)
+
+			if tt.status != 0 {
+				if w.Code != tt.status {
+					t.Fatalf("状态码 = %d, want %d: %s", w.Code, tt.status, w.Body)
+				}
+				if n := factory.calls(); n != 0 {
+					t.Fatalf("被拒绝的请求发往了节点 %d 次", n)
+				}
+				return
+			}
+			if w.Code == http.StatusForbidden || w.Code == http.StatusUnauthorized {
+				t.Fatalf("允许的请求被拒绝: %d %s", w.Code, w.Body)
+			}
+			if factory.calls() == 0 {
+				t.Fatal("允许的请求没有发往节点")
+			}
+		})
+	}
+}
@@ -0,0 +1,294 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/AdrianWangs/go-cache/internal/access"
+	"github.com/AdrianWangs/go-cache/internal/admin"
+	"github.com/AdrianWangs/go-cache/internal/cache"
+	"github.com/AdrianWangs/go-cache/pkg/logger"
+	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
+)
+
+// exportFlushEvery 集群导出时每写出多少条目刷新一次响应
+const exportFlushEvery = 100
+
+// AdminHandler 集群级管理处理器，负责缓存组的导出与导入
+type AdminHandler struct {
+	cacheHandler *CacheHandler     // 提供节点列表与一致性哈希环
+	token        string            // 访问令牌，为空时管理接口关闭
+	limiter      *admin.Limiter    // 并发与条目速率限制
+	info         func() admin.Info // /api/admin/info 返回的组件信息
+}
+
+// ClusterImportResult 集群导入结果
+type ClusterImportResult struct {
+	Total cache.ImportResult            `json:"total"` // 合计
+	Nodes map[string]cache.ImportResult `json:"nodes"` // 各节点结果
+}
+
+// NewAdminHandler 创建管理处理器，entriesPerSecond 为导入导出的条目速率上限，0 表示不限速
+func NewAdminHandler(cacheHandler *CacheHandler, token string, entriesPerSecond int) *AdminHandler {
+	return &AdminHandler{
+		cacheHandler: cacheHandler,
+		token:        token,
+		limiter:      admin.NewLimiter(1, entriesPerSecond),
+	}
+}
+
+// GroupHandler 处理 /api/admin/groups/{group}/{export|import} 请求
+func (h *AdminHandler) GroupHandler(w http.ResponseWriter, r *http.Request) {
+	group, action, ok := parseAdminGroupPath(r.URL.EscapedPath())
+	if !ok {
+		if h.authorize(w, r, access.AllGroups) {
+			http.Error(w, "Bad Request: expected /api/admin/groups/{group}/{export|import}", http.StatusBadRequest)
+		}
+		return
+	}
+	if !h.authorize(w, r, group) {
+		return
+	}
+
+	var op func(http.ResponseWriter, *http.Request, string)
+	switch {
+	case action == "export" && r.Method == http.MethodGet:
+		op = h.exportCluster
+	case action == "import" && r.Method == http.MethodPost:
+		op = h.importCluster
+	case action == "export" || action == "import":
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	default:
+		http.Error(w, fmt.Sprintf("Unknown action: %s", action), http.StatusNotFound)
+		return
+	}
+
+	if !h.limiter.TryAcquire() {
+		http.Error(w, "Too Many Requests: another admin operation is running", http.StatusTooManyRequests)
+		return
+	}
+	defer h.limiter.Release()
+	op(w, r, group)
+}
+
+// SetInfo 设置 /api/admin/info 返回的配置与构建信息，须在开始处理请求之前调用
+func (h *AdminHandler) SetInfo(fn func() admin.Info) {
+	h.info = fn
+}
+
+// InfoHandler 处理 /api/admin/info 请求，返回 API 服务器生效的配置（已脱敏）与构建信息
+func (h *AdminHandler) InfoHandler(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r, access.AllGroups) {
+		return
+	}
+	info := h.info
+	if info == nil {
+		http.Error(w, "Not Found: info is not configured", http.StatusNotFound)
+		return
+	}
+	admin.InfoHandler(w, r, info)
+}
+
+// RingHandler 处理 /api/admin/ring 请求，报告 API 服务器路由使用的哈希环
+func (h *AdminHandler) RingHandler(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r, access.AllGroups) {
+		return
+	}
+	admin.RingHandler(w, r, h.cacheHandler.RingReport)
+}
+
+// authorize 校验管理接口的访问权限。请求携带访问控制中的已知令牌时，要求令牌对 group
+// 拥有 admin 权限（group 为 access.AllGroups 时要求通配符）；否则使用管理令牌校验
+func (h *AdminHandler) authorize(w http.ResponseWriter, r *http.Request, group string) bool {
+	if access.ScopeFrom(r.Context()) != nil {
+		return access.Authorize(w, r, group, access.OpAdmin)
+	}
+	return admin.Authorize(w, r, h.token)
+}
+
+// exportCluster 依次导出每个节点上的组，并将结果拼接为一个 ndjson 流
+func (h *AdminHandler) exportCluster(w http.ResponseWriter, r *http.Request, group string) {
+	nodes := h.sortedNodes()
+	if len(nodes) == 0 {
+		http.Error(w, "Service Unavailable: no cache nodes", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	flusher, _ := w.(http.Flusher)
+	enc := cache.NewEntryEncoder(w)
+
+	count := 0
+	for _, node := range nodes {
+		getter, release := h.transferGetter(node)
+		err := getter.ExportGroup(r.Context(), group, func(e *pb.ExportEntry) error {
+			if err := h.limiter.Wait(r.Context()); err != nil {
+				return err
+			}
+			if err := enc.Encode(cache.ExportEntryFromProto(e)); err != nil {
+				return err
+			}
+			count++
+			if count%exportFlushEvery == 0 {
+				if err := enc.Flush(); err != nil {
+					return err
+				}
+				if flusher != nil {
+					flusher.Flush()
+				}
+			}
+			return nil
+		})
+		release()
+		if err != nil {
+			// 响应已经开始，中断连接让客户端感知导出不完整
+			logger.Errorf("导出节点 %s 上的组 %s 失败: %v", node, group, err)
+			enc.Flush()
+			panic(http.ErrAbortHandler)
+		}
+	}
+
+	if err := enc.Flush(); err != nil {
+		logger.Warnf("写出集群导出结果失败: %v", err)
+		return
+	}
+	logger.Infof("集群导出组 %s 完成，%d 个节点，共 %d 个条目", group, len(nodes), count)
+}
+
+// nodeImport 到单个节点的导入流
+type nodeImport struct {
+	stream  pb.GroupCache_ImportClient
+	release func()
+}
+
+// importCluster 读取 ndjson 条目，按一致性哈希环将每个条目发送给其归属节点
+func (h *AdminHandler) importCluster(w http.ResponseWriter, r *http.Request, group string) {
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	result := ClusterImportResult{Nodes: make(map[string]cache.ImportResult)}
+	imports := make(map[string]*nodeImport)
+	defer func() {
+		for _, imp := range imports {
+			imp.release()
+		}
+	}()
+
+	if err := h.routeImport(ctx, group, cache.NewEntryDecoder(r.Body), imports, &result); err != nil {
+		logger.Errorf("集群导入组 %s 失败: %v", group, err)
+		http.Error(w, fmt.Sprintf("Bad Request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	// 关闭所有导入流并汇总结果
+	for node, imp := range imports {
+		resp, closeErr := imp.stream.CloseAndRecv()
+		if closeErr != nil {
+			logger.Errorf("节点 %s 导入组 %s 失败: %v", node, group, closeErr)
+			http.Error(w, fmt.Sprintf("Bad Gateway: node %s: %v", node, closeErr), http.StatusBadGateway)
+			return
+		}
+		nodeResult := cache.ImportResult{
+			Imported: resp.GetImported(),
+			Expired:  resp.GetExpired(),
+			Skipped:  resp.GetSkipped(),
+		}
+		result.Nodes[node] = nodeResult
+		result.Total.Add(nodeResult)
+	}
+
+	logger.Infof("集群导入组 %s 完成: 导入 %d, 过期 %d, 跳过 %d",
+		group, result.Total.Imported, result.Total.Expired, result.Total.Skipped)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// routeImport 将解码出的每个条目发送到其归属节点的导入流，按需打开新的流
+func (h *AdminHandler) routeImport(ctx context.Context, group string, dec *cache.EntryDecoder,
+	imports map[string]*nodeImport, result *ClusterImportResult) error {
+	for {
+		e, err := dec.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := h.limiter.Wait(ctx); err != nil {
+			return err
+		}
+
+		node, _ := h.cacheHandler.pickNode(e.Key)
+		if node == "" {
+			result.Total.Skipped++
+			continue
+		}
+
+		imp, ok := imports[node]
+		if !ok {
+			getter, release := h.transferGetter(node)
+			stream, err := getter.ImportGroup(ctx)
+			if err != nil {
+				release()
+				return fmt.Errorf("打开到节点 %s 的导入流失败: %v", node, err)
+			}
+			imp = &nodeImport{stream: stream, release: release}
+			imports[node] = imp
+		}
+
+		if err := imp.stream.Send(&pb.ImportRequest{Group: group, Entry: e.Proto()}); err != nil {
+			return fmt.Errorf("向节点 %s 发送条目失败: %v", node, err)
+		}
+	}
+}
+
+// sortedNodes 返回当前所有节点地址，按地址排序保证导出顺序稳定
+func (h *AdminHandler) sortedNodes() []string {
+	getters := h.cacheHandler.GetNodeGetters()
+	nodes := make([]string, 0, len(getters))
+	for node := range getters {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+	return nodes
+}
+
+// transferGetter 返回可用于导入导出的 gRPC getter。
+// 当 API 服务器使用 HTTP 协议时按节点的 gRPC 地址临时创建连接，release 负责关闭
+func (h *AdminHandler) transferGetter(node string) (*GRPCGetter, func()) {
+	if getter, ok := h.cacheHandler.GetNodeGetters()[node].(*GRPCGetter); ok {
+		return getter, func() {}
+	}
+	addr := node
+	if info, ok := h.cacheHandler.nodeInfo(node); ok {
+		addr = info.GRPCAddr
+	}
+	getter := NewGRPCGetter(addr, h.cacheHandler.getterOpts...)
+	return getter, func() { getter.Close() }
+}
+
+// parseAdminGroupPath 从转义后的路径中解析 /api/admin/groups/{group}/{action}
+func parseAdminGroupPath(escapedPath string) (group, action string, ok bool) {
+	const prefix = "/api/admin/groups/"
+	if !strings.HasPrefix(escapedPath, prefix) {
+		return "", "", false
+	}
+
+	parts := strings.Split(escapedPath[len(prefix):], "/")
+	if len(parts) != 2 || parts[0] == "" {
+		return "", "", false
+	}
+
+	group, err := url.PathUnescape(parts[0])
+	if err != nil {
+		return "", "", false
+	}
+	return group, parts[1], true
+}
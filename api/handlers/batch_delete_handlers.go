@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+
+	"github.com/AdrianWangs/go-cache/internal/access"
+	"github.com/AdrianWangs/go-cache/internal/cache"
+	"github.com/AdrianWangs/go-cache/pkg/fanout"
+	"github.com/AdrianWangs/go-cache/pkg/logger"
+)
+
+// BatchDeleteRequest 批量删除的请求体
+type BatchDeleteRequest struct {
+	Group string   `json:"group"`
+	Keys  []string `json:"keys"`
+}
+
+// BatchDeleteResponse 批量删除响应。每个 key 只出现在 Deleted、NotFound、Pending、Errors 之一中
+type BatchDeleteResponse struct {
+	Group    string            `json:"group"`
+	Deleted  []string          `json:"deleted"`           // 已删除的 key
+	NotFound []string          `json:"notFound"`          // 节点上本来就没有缓存的 key
+	Pending  []string          `json:"pending,omitempty"` // 归属节点暂时不可达、已进入重试队列的 key
+	Errors   map[string]string `json:"errors,omitempty"`  // 删除失败的 key 及原因
+	Nodes    []BatchNodeStatus `json:"nodes"`             // 各节点的结果
+}
+
+// BatchDeleteHandler 处理 POST /api/cache/batch-delete 请求，一次删除多个 key。
+// key 按一致性哈希环分配到节点，每个节点只发送一次批量删除；
+// 部分 key 或节点失败时返回 207 和各 key 的结果；开启删除重试时，不可达节点上的 key 进入重试队列，
+// 没有失败但有 key 等待重试时返回 202；全部成功时返回 200
+func (h *CacheHandler) BatchDeleteHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed, only POST is supported", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var body BatchDeleteRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, fmt.Sprintf("Bad Request: %v", err), http.StatusBadRequest)
+		return
+	}
+	group := body.Group
+	if group == "" {
+		http.Error(w, "Bad Request: group is required", http.StatusBadRequest)
+		return
+	}
+
+	keys := dedupKeys(body.Keys)
+	if len(keys) == 0 {
+		http.Error(w, "Bad Request: no keys", http.StatusBadRequest)
+		return
+	}
+	if len(keys) > maxBatchKeys {
+		http.Error(w, fmt.Sprintf("Bad Request: at most %d keys per batch", maxBatchKeys), http.StatusBadRequest)
+		return
+	}
+
+	if !access.Authorize(w, r, group, access.OpWrite) {
+		return
+	}
+	if h.isUnknownGroup(group) {
+		writeGroupNotFound(w, group)
+		return
+	}
+
+	// 按归属节点分组
+	byNode := make(map[string][]string)
+	getters := make(map[string]NodeGetter)
+	for _, key := range keys {
+		node, getter := h.pickNode(key)
+		if getter == nil {
+			http.Error(w, "No suitable cache node available", http.StatusServiceUnavailable)
+			return
+		}
+		byNode[node] = append(byNode[node], key)
+		getters[node] = getter
+	}
+	nodes := make([]string, 0, len(byNode))
+	for node := range byNode {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+
+	results := fanout.FanOut(r.Context(), nodes, func(ctx context.Context, node string) ([]cache.DeleteResult, error) {
+		return batchDelete(ctx, getters[node], group, byNode[node])
+	}, h.fanOut)
+
+	resp := BatchDeleteResponse{
+		Group:    group,
+		Deleted:  []string{},
+		NotFound: []string{},
+		Nodes:    make([]BatchNodeStatus, 0, len(nodes)),
+	}
+	for _, key := range keys {
+		// 归属节点删除时已使热点 key 的副本失效
+		h.hot.forget(group, key)
+	}
+	for _, res := range fanout.Ordered(nodes, results) {
+		status := BatchNodeStatus{
+			Node:       res.Target,
+			Keys:       len(byNode[res.Target]),
+			DurationMs: res.Duration.Milliseconds(),
+		}
+		done := make(map[string]bool, len(res.Value))
+		for _, result := range res.Value {
+			done[result.Key] = true
+			switch result.Status {
+			case cache.DeleteDeleted:
+				resp.Deleted = append(resp.Deleted, result.Key)
+			case cache.DeleteNotFound:
+				resp.NotFound = append(resp.NotFound, result.Key)
+			default:
+				addDeleteError(&resp, result.Key, result.Error)
+			}
+		}
+		if res.Err != nil {
+			// 节点整体失败（例如只读或超时），尚未得到结果的 key 进入重试队列或记为失败
+			status.Error = res.Err.Error()
+			for _, key := range byNode[res.Target] {
+				if done[key] {
+					continue
+				}
+				if h.queueDelete(res.Target, group, key, res.Err) {
+					resp.Pending = append(resp.Pending, key)
+				} else {
+					addDeleteError(&resp, key, res.Err.Error())
+				}
+			}
+		}
+		resp.Nodes = append(resp.Nodes, status)
+	}
+	sort.Strings(resp.Deleted)
+	sort.Strings(resp.NotFound)
+	sort.Strings(resp.Pending)
+	h.purge(group, slices.Concat(resp.Deleted, resp.NotFound, resp.Pending)...)
+
+	// 审计日志：批量删除是破坏性操作，始终记录来源和结果
+	logger.Infof("批量删除 group=%s token=%s from=%s: %d 个 key，%d 个节点，删除 %d，不存在 %d，等待重试 %d，失败 %d",
+		group, access.TokenID(r.Context()), r.RemoteAddr, len(keys), len(nodes), len(resp.Deleted), len(resp.NotFound), len(resp.Pending), len(resp.Errors))
+
+	w.Header().Set("Content-Type", "application/json")
+	if len(resp.Errors) > 0 {
+		w.WriteHeader(http.StatusMultiStatus)
+	} else if len(resp.Pending) > 0 {
+		w.WriteHeader(http.StatusAccepted)
+	}
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		logger.Errorf("序列化批量删除响应失败: %v", err)
+	}
+}
+
+// batchDelete 在单个节点上删除 keys。节点不支持批量删除时退回逐个删除，
+// 此时节点无法区分 key 是否存在，成功删除的 key 都报告为 deleted
+func batchDelete(ctx context.Context, getter NodeGetter, group string, keys []string) ([]cache.DeleteResult, error) {
+	results, err := getter.DeleteBatch(ctx, group, keys)
+	if !errors.Is(err, ErrDeleteBatchUnimplemented) {
+		return results, err
+	}
+
+	logger.Debugf("节点不支持批量删除，逐个删除 %d 个 key: %v", len(keys), err)
+	results = make([]cache.DeleteResult, 0, len(keys))
+	for _, key := range keys {
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+		result := cache.DeleteResult{Key: key, Status: cache.DeleteDeleted}
+		if err := getter.Delete(ctx, group, key); err != nil {
+			switch {
+			case isKeyNotFound(err):
+				result.Status = cache.DeleteNotFound
+			case errors.Is(err, cache.ErrReadOnly) || cache.IsReadOnlyError(err):
+				// 只读对整个节点生效，剩下的 key 不必再试
+				return results, err
+			default:
+				result.Status = cache.DeleteError
+				result.Error = err.Error()
+			}
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// addDeleteError 记录一个 key 的删除错误
+func addDeleteError(resp *BatchDeleteResponse, key, msg string) {
+	if resp.Errors == nil {
+		resp.Errors = make(map[string]string)
+	}
+	resp.Errors[key] = msg
+}
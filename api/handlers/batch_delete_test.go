@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/AdrianWangs/go-cache/internal/cache"
+)
+
+// batchDeleteGetter 支持批量删除的 NodeGetter：missing 中的 key 报告为不存在，
+// failing 中的 key 删除失败，err 不为空时整个批次失败
+type batchDeleteGetter struct {
+	stubGetter
+	missing map[string]bool
+	failing map[string]bool
+	err     error
+
+	mu      sync.Mutex
+	batches [][]string // 每次 DeleteBatch 收到的 key
+}
+
+func (g *batchDeleteGetter) DeleteBatch(ctx context.Context, group string, keys []string) ([]cache.DeleteResult, error) {
+	g.mu.Lock()
+	g.batches = append(g.batches, keys)
+	g.mu.Unlock()
+	if g.err != nil {
+		return nil, g.err
+	}
+	results := make([]cache.DeleteResult, 0, len(keys))
+	for _, key := range keys {
+		switch {
+		case g.missing[key]:
+			results = append(results, cache.DeleteResult{Key: key, Status: cache.DeleteNotFound})
+		case g.failing[key]:
+			results = append(results, cache.DeleteResult{Key: key, Status: cache.DeleteError, Error: "磁盘错误"})
+		default:
+			results = append(results, cache.DeleteResult{Key: key, Status: cache.DeleteDeleted})
+		}
+	}
+	return results, nil
+}
+
+// newBatchDeleteHandler 创建有 3 个 scores 组节点的处理器，每个节点一个 batchDeleteGetter
+func newBatchDeleteHandler(t *testing.T) *CacheHandler {
+	t.Helper()
+	h := NewCacheHandler("/_gocache/", 50, CacheHandlerOptions{
+		Getters: GetterFactoryFunc(func(protocol ProtocolType, addr string) NodeGetter {
+			return &batchDeleteGetter{missing: map[string]bool{}, failing: map[string]bool{}}
+		}),
+	})
+	h.UpdatePeers(nodesWithGroups(3, "scores"))
+	return h
+}
+
+// ownerOf 返回 key 的归属节点及其 getter
+func ownerOf(t *testing.T, h *CacheHandler, key string) (string, *batchDeleteGetter) {
+	t.Helper()
+	node, getter := h.pickNode(key)
+	if getter == nil {
+		t.Fatalf("%s 没有归属节点", key)
+	}
+	return node, getter.(*batchDeleteGetter)
+}
+
+// postBatchDelete 以 body 请求 POST /api/cache/batch-delete
+func postBatchDelete(h *CacheHandler, body any) *httptest.ResponseRecorder {
+	data, _ := json.Marshal(body)
+	r := httptest.NewRequest(http.MethodPost, "/api/cache/batch-delete", bytes.NewReader(data))
+	w := httptest.NewRecorder()
+	h.BatchDeleteHandler(w, r)
+	return w
+}
+
+// decodeBatchDelete 解析批量删除响应
+func decodeBatchDelete(t *testing.T, w *httptest.ResponseRecorder) BatchDeleteResponse {
+	t.Helper()
+	var resp BatchDeleteResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("响应不是 JSON: %v", err)
+	}
+	return resp
+}
+
+// TestBatchDeleteGroupsByOwner 每个节点只收到一次批量删除，包含它负责的全部 key；
+// 全部成功时返回 200，不存在的 key 单独列出
+func TestBatchDeleteGroupsByOwner(t *testing.T) {
+	h := newBatchDeleteHandler(t)
+	keys := make([]string, 30)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("k%02d", i)
+	}
+	_, missingOwner := ownerOf(t, h, "k07")
+	missingOwner.missing["k07"] = true
+
+	w := postBatchDelete(h, BatchDeleteRequest{Group: "scores", Keys: append(keys, "k00", "k01")})
+	if w.Code != http.StatusOK {
+		t.Fatalf("状态码 %d: %s", w.Code, w.Body.String())
+	}
+	resp := decodeBatchDelete(t, w)
+	if len(resp.Deleted) != 29 || !slices.Equal(resp.NotFound, []string{"k07"}) || len(resp.Errors) != 0 || len(resp.Pending) != 0 {
+		t.Fatalf("响应 = %+v", resp)
+	}
+
+	want := make(map[*batchDeleteGetter][]string)
+	for _, key := range keys {
+		_, g := ownerOf(t, h, key)
+		want[g] = append(want[g], key)
+	}
+	if len(want) != 3 || len(resp.Nodes) != 3 {
+		t.Fatalf("30 个 key 分布在 %d 个节点上，响应中有 %d 个节点", len(want), len(resp.Nodes))
+	}
+	for g, keys := range want {
+		if len(g.batches) != 1 {
+			t.Fatalf("节点收到 %d 次批量删除", len(g.batches))
+		}
+		got := slices.Clone(g.batches[0])
+		sort.Strings(got)
+		if !slices.Equal(got, keys) {
+			t.Fatalf("节点收到 %v, want %v", got, keys)
+		}
+	}
+}
+
+// TestBatchDeletePartialFailure 单个 key 失败或整个节点失败时返回 207，
+// 每个 key 只出现在一种结果中，失败的节点在 nodes 中给出原因
+func TestBatchDeletePartialFailure(t *testing.T) {
+	h := newBatchDeleteHandler(t)
+	keys := make([]string, 30)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("k%02d", i)
+	}
+	downNode, down := ownerOf(t, h, "k00")
+	down.err = errors.New("connection refused")
+	var failed string
+	for _, key := range keys {
+		if node, g := ownerOf(t, h, key); node != downNode {
+			g.failing[key] = true
+			failed = key
+			break
+		}
+	}
+
+	w := postBatchDelete(h, BatchDeleteRequest{Group: "scores", Keys: keys})
+	if w.Code != http.StatusMultiStatus {
+		t.Fatalf("状态码 %d, want 207: %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+	resp := decodeBatchDelete(t, w)
+
+	seen := make(map[string]bool)
+	for _, key := range slices.Concat(resp.Deleted, resp.NotFound, resp.Pending) {
+		seen[key] = true
+	}
+	for key, msg := range resp.Errors {
+		if seen[key] {
+			t.Fatalf("%s 同时出现在多种结果中", key)
+		}
+		seen[key] = true
+		node, _ := ownerOf(t, h, key)
+		switch {
+		case key == failed:
+			if msg != "磁盘错误" {
+				t.Fatalf("%s 的错误 = %q", key, msg)
+			}
+		case node == downNode:
+			if msg != "connection refused" {
+				t.Fatalf("%s 的错误 = %q", key, msg)
+			}
+		default:
+			t.Fatalf("%s 不应失败: %q", key, msg)
+		}
+	}
+	if len(seen) != len(keys) {
+		t.Fatalf("响应包含 %d 个 key, want %d: %+v", len(seen), len(keys), resp)
+	}
+	for _, key := range resp.Deleted {
+		if node, _ := ownerOf(t, h, key); node == downNode || key == failed {
+			t.Fatalf("%s 被报告为已删除", key)
+		}
+	}
+
+	for _, n := range resp.Nodes {
+		if (n.Node == downNode) != (n.Error != "") {
+			t.Fatalf("节点状态 %+v, 失败的节点是 %s", n, downNode)
+		}
+	}
+}
+
+// TestBatchDeleteKeyLimit 去重后超过上限的请求被拒绝，不会发往任何节点
+func TestBatchDeleteKeyLimit(t *testing.T) {
+	keys := make([]string, maxBatchKeys)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("k%d", i)
+	}
+
+	h := newBatchDeleteHandler(t)
+	// 重复的 key 只计一次
+	if w := postBatchDelete(h, BatchDeleteRequest{Group: "scores", Keys: append(keys, keys[0])}); w.Code != http.StatusOK {
+		t.Fatalf("%d 个不同的 key: 状态码 %d", maxBatchKeys, w.Code)
+	}
+
+	h = newBatchDeleteHandler(t)
+	w := postBatchDelete(h, BatchDeleteRequest{Group: "scores", Keys: append(keys, "one-more")})
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("%d 个 key: 状态码 %d, want 400", maxBatchKeys+1, w.Code)
+	}
+	for _, key := range keys[:10] {
+		if _, g := ownerOf(t, h, key); len(g.batches) != 0 {
+			t.Fatalf("超过上限的请求发往了节点: %v", g.batches)
+		}
+	}
+}
+
+// TestBatchDeleteBadRequest 请求体不完整、方法错误或组不存在时不发往节点
+func TestBatchDeleteBadRequest(t *testing.T) {
+	h := newBatchDeleteHandler(t)
+	for _, tt := range []struct {
+		name string
+		body any
+		want int
+	}{
+		{"缺少组", BatchDeleteRequest{Keys: []string{"a"}}, http.StatusBadRequest},
+		{"没有 key", BatchDeleteRequest{Group: "scores"}, http.StatusBadRequest},
+		{"只有空 key", BatchDeleteRequest{Group: "scores", Keys: []string{""}}, http.StatusBadRequest},
+		{"不是对象", []string{"a"}, http.StatusBadRequest},
+		{"未知组", BatchDeleteRequest{Group: "nope", Keys: []string{"a"}}, http.StatusNotFound},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := postBatchDelete(h, tt.body); w.Code != tt.want {
+				t.Fatalf("状态码 %d, want %d: %s", w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+
+	w := httptest.NewRecorder()
+	h.BatchDeleteHandler(w, httptest.NewRequest(http.MethodGet, "/api/cache/batch-delete", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("GET: 状态码 %d", w.Code)
+	}
+	if _, g := ownerOf(t, h, "a"); len(g.batches) != 0 {
+		t.Fatalf("无效请求发往了节点: %v", g.batches)
+	}
+}
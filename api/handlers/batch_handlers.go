@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/internal/access"
+	"github.com/AdrianWangs/go-cache/pkg/cacheerrors"
+	"github.com/AdrianWangs/go-cache/pkg/fanout"
+	"github.com/AdrianWangs/go-cache/pkg/logger"
+	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
+)
+
+// maxBatchKeys 单次批量读取的 key 数上限
+const maxBatchKeys = 1000
+
+// BatchGetRequest 批量读取的 POST 请求体
+type BatchGetRequest struct {
+	Keys []string `json:"keys"`
+}
+
+// BatchNodeStatus 批量读取中单个节点的结果
+type BatchNodeStatus struct {
+	Node       string `json:"node"`            // 节点标识
+	Keys       int    `json:"keys"`            // 发往该节点的 key 数
+	DurationMs int64  `json:"durationMs"`      // 该节点上所有读取的耗时（毫秒）
+	Error      string `json:"error,omitempty"` // 节点整体失败（例如超时）的原因
+}
+
+// BatchGetResponse 批量读取响应。values 中的值以字符串返回，适用于文本值；
+// 以 Accept: application/json 或 ?format=json 请求时另外在 entries 中返回 base64 编码的值及其元数据
+type BatchGetResponse struct {
+	Group   string            `json:"group"`
+	Values  map[string]string `json:"values"`           // 读取成功的 key
+	Missing []string          `json:"missing"`          // 不存在的 key
+	Errors  map[string]string `json:"errors,omitempty"` // 读取失败的 key 及原因
+	Nodes   []BatchNodeStatus `json:"nodes"`            // 各节点的结果
+
+	Entries map[string]ValueEnvelope `json:"entries,omitempty"` // 读取成功的 key 的完整结果，只在 JSON 格式下返回
+}
+
+// batchNodeResult 单个节点上各 key 的读取结果
+type batchNodeResult struct {
+	values  map[string]*pb.Response
+	missing []string
+	errors  map[string]string
+}
+
+// has 报告 key 是否已经有结果
+func (r batchNodeResult) has(key string) bool {
+	if _, ok := r.values[key]; ok {
+		return true
+	}
+	if _, ok := r.errors[key]; ok {
+		return true
+	}
+	for _, k := range r.missing {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// BatchGetHandler 处理 /api/batch/{group} 请求，一次读取多个 key。
+// GET 通过重复的 key 查询参数传入 key，POST 使用 {"keys": [...]} 请求体。
+// key 按一致性哈希环分配到节点，各节点并发读取，部分节点失败时其余结果照常返回。
+// 内容协商与单个 key 的读取相同，JSON 格式下错误以 ErrorEnvelope 返回
+func (h *CacheHandler) BatchGetHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Vary", "Accept")
+	format, err := negotiateReadFormat(r)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Bad Request: %v", err), http.StatusBadRequest)
+		return
+	}
+	group, err := url.PathUnescape(strings.Trim(strings.TrimPrefix(r.URL.EscapedPath(), "/api/batch"), "/"))
+	if err != nil || group == "" || strings.Contains(group, "/") {
+		writeReadError(w, format, http.StatusBadRequest, EnvelopeCodeBadRequest, "Bad Request: expected /api/batch/{group}")
+		return
+	}
+	if !access.Authorize(w, r, group, access.OpRead) {
+		return
+	}
+
+	var keys []string
+	switch r.Method {
+	case http.MethodGet, "":
+		keys = r.URL.Query()["key"]
+	case http.MethodPost:
+		var body BatchGetRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeReadError(w, format, http.StatusBadRequest, EnvelopeCodeBadRequest, fmt.Sprintf("Bad Request: %v", err))
+			return
+		}
+		keys = body.Keys
+	default:
+		writeReadError(w, format, http.StatusMethodNotAllowed, EnvelopeCodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	keys = dedupKeys(keys)
+	if len(keys) == 0 {
+		writeReadError(w, format, http.StatusBadRequest, EnvelopeCodeBadRequest, "Bad Request: no keys")
+		return
+	}
+	if len(keys) > maxBatchKeys {
+		writeReadError(w, format, http.StatusBadRequest, EnvelopeCodeBadRequest, fmt.Sprintf("Bad Request: at most %d keys per batch", maxBatchKeys))
+		return
+	}
+
+	if h.isUnknownGroup(group) {
+		writeReadGroupNotFound(w, format, group)
+		return
+	}
+
+	// 按归属节点分组
+	byNode := make(map[string][]string)
+	getters := make(map[string]NodeGetter)
+	for _, key := range keys {
+		node, getter := h.pickNode(key)
+		if getter == nil {
+			writeReadError(w, format, http.StatusServiceUnavailable, EnvelopeCodeNoNodeAvailable, "No suitable cache node available")
+			return
+		}
+		byNode[node] = append(byNode[node], key)
+		getters[node] = getter
+	}
+	nodes := make([]string, 0, len(byNode))
+	for node := range byNode {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+
+	results := fanout.FanOut(r.Context(), nodes, func(ctx context.Context, node string) (batchNodeResult, error) {
+		return batchGet(ctx, getters[node], group, byNode[node])
+	}, h.fanOut)
+	if h.clientGone(r) {
+		logger.Debugf("客户端已断开，放弃批量读取: group=%s, keys=%d", group, len(keys))
+		return
+	}
+
+	resp := BatchGetResponse{
+		Group:   group,
+		Values:  make(map[string]string, len(keys)),
+		Missing: []string{},
+		Nodes:   make([]BatchNodeStatus, 0, len(nodes)),
+	}
+	if format == formatJSON {
+		resp.Entries = make(map[string]ValueEnvelope, len(keys))
+	}
+	now := time.Now()
+	for _, res := range fanout.Ordered(nodes, results) {
+		status := BatchNodeStatus{
+			Node:       res.Target,
+			Keys:       len(byNode[res.Target]),
+			DurationMs: res.Duration.Milliseconds(),
+		}
+		for key, value := range res.Value.values {
+			resp.Values[key] = string(value.GetValue())
+			if resp.Entries != nil {
+				resp.Entries[key] = newValueEnvelope(group, key, value, now)
+			}
+		}
+		resp.Missing = append(resp.Missing, res.Value.missing...)
+		for key, msg := range res.Value.errors {
+			addBatchError(&resp, key, msg)
+		}
+		if res.Err != nil {
+			// 节点中途失败（例如超时），尚未得到结果的 key 记为失败
+			status.Error = res.Err.Error()
+			for _, key := range byNode[res.Target] {
+				if !res.Value.has(key) {
+					addBatchError(&resp, key, res.Err.Error())
+				}
+			}
+		}
+		resp.Nodes = append(resp.Nodes, status)
+	}
+	sort.Strings(resp.Missing)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		logger.Errorf("序列化批量读取响应失败: %v", err)
+		return
+	}
+	logger.Debugf("批量读取 group=%s: %d 个 key，%d 个节点，命中 %d，不存在 %d，失败 %d",
+		group, len(keys), len(nodes), len(resp.Values), len(resp.Missing), len(resp.Errors))
+}
+
+// batchGet 在单个节点上依次读取 keys。ctx 取消时停止并返回已读取的结果和 ctx 的错误
+func batchGet(ctx context.Context, getter NodeGetter, group string, keys []string) (batchNodeResult, error) {
+	result := batchNodeResult{values: make(map[string]*pb.Response, len(keys))}
+	for _, key := range keys {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+
+		resp := &pb.Response{}
+		err := getter.GetByProto(ctx, &pb.Request{Group: group, Key: key}, resp)
+		switch {
+		case err == nil:
+			result.values[key] = resp
+		case isKeyNotFound(err):
+			result.missing = append(result.missing, key)
+		default:
+			if ctx.Err() != nil {
+				return result, ctx.Err()
+			}
+			if result.errors == nil {
+				result.errors = make(map[string]string)
+			}
+			result.errors[key] = err.Error()
+		}
+	}
+	return result, nil
+}
+
+// isKeyNotFound 判断错误是否表示键不存在，组不存在不算。getter 已把节点的错误响应映射为 cacheerrors 中的错误
+func isKeyNotFound(err error) bool {
+	return cacheerrors.IsKeyNotFoundError(err)
+}
+
+// addBatchError 记录一个 key 的读取错误
+func addBatchError(resp *BatchGetResponse, key, msg string) {
+	if resp.Errors == nil {
+		resp.Errors = make(map[string]string)
+	}
+	resp.Errors[key] = msg
+}
+
+// dedupKeys 去掉空 key 和重复的 key，保持原有顺序
+func dedupKeys(keys []string) []string {
+	seen := make(map[string]bool, len(keys))
+	unique := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if key != "" && !seen[key] {
+			seen[key] = true
+			unique = append(unique, key)
+		}
+	}
+	return unique
+}
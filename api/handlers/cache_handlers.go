@@ -2,14 +2,25 @@
 package handlers
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/AdrianWangs/go-cache/internal/access"
 	"github.com/AdrianWangs/go-cache/internal/cache"
 	"github.com/AdrianWangs/go-cache/internal/consistenthash"
+	"github.com/AdrianWangs/go-cache/internal/deletequeue"
+	"github.com/AdrianWangs/go-cache/internal/discovery"
+	"github.com/AdrianWangs/go-cache/internal/peers"
+	"github.com/AdrianWangs/go-cache/pkg/cacheerrors"
+	"github.com/AdrianWangs/go-cache/pkg/fanout"
 	"github.com/AdrianWangs/go-cache/pkg/logger"
 	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
 )
@@ -24,31 +35,84 @@ const (
 	ProtocolGRPC ProtocolType = "grpc"
 )
 
+// CacheKeyPathPrefix 单个 key 读写删除路由 /api/cache/{group}/{key} 的前缀，
+// 请求日志按它找出路径中的 key，见 router.LoggingMiddleware
+const CacheKeyPathPrefix = "/api/cache/"
+
+// errNoNode 没有可以处理请求的缓存节点
+var errNoNode = errors.New("no suitable cache node available")
+
 // CacheHandler 缓存处理器，处理缓存相关的请求
 type CacheHandler struct {
-	mu          sync.RWMutex
-	basePath    string                // 缓存节点内部通信路径
-	ring        *consistenthash.Map   // 一致性哈希环
-	replicas    int                   // 虚拟节点倍数
-	nodeGetters map[string]NodeGetter // 节点地址到 NodeGetter 的映射
-	protocol    ProtocolType          // 通信协议类型
+	mu           sync.RWMutex
+	basePath     string                        // 缓存节点内部通信路径
+	ring         *consistenthash.Map           // 一致性哈希环
+	replicas     int                           // 虚拟节点倍数
+	ringHash     string                        // 一致性哈希函数名称，见 consistenthash.NewByName
+	nodeGetters  map[string]NodeGetter         // 节点标识到 NodeGetter 的映射
+	nodes        map[string]discovery.NodeInfo // 节点标识到注册信息的映射
+	counters     map[string]*peers.Counters    // 节点标识到请求与错误统计的映射，节点留在集群中时保留
+	protocol     ProtocolType                  // 默认通信协议，用于未登记协议的节点
+	protocols    map[string]ProtocolType       // 节点标识到访问该节点所用协议的映射
+	getterOpts   []GetterOption                // 创建 NodeGetter 时使用的选项，也用于导入导出等临时连接
+	getters      GetterFactory                 // 创建 NodeGetter 的工厂
+	hedger       *hedger                       // 对冲读取，未开启时为 nil
+	hot          *hotRoutes                    // 已复制热点 key 的读请求分散，关闭时为 nil
+	repair       *readRepairer                 // 非归属节点提供结果后的读修复，未开启时为 nil
+	registry     groupRegistry                 // 集群的缓存组注册表
+	fanOut       fanout.Options                // 向多个节点并发调用的配置
+	deletes      *deletequeue.Queue            // 发往归属节点失败的删除的重试队列，未开启时为 nil
+	deleteConfig DeleteConfig                  // 单个 key 的删除发往哪些节点
+	prevRing     previousRing                  // 成员变化前的哈希环，owner+previous 下用于删除
+	identity     string                        // 本 API 服务器的标识，在 X-GoCache-Routed-By 中返回，为空时不公开节点标识
+	policies     CachePolicies                 // 读取响应的按组 HTTP 缓存策略
+	purger       Purger                        // 删除 key 后清除 CDN 缓存
+	proxy        *proxy                        // 按组的缓存反向代理，没有组配置上游时为 nil
+
+	onRead func(source string) // 成功读取后的回调，见 CacheHandlerOptions.OnRead
+
+	clientCancelled int64 // 客户端在收到响应之前断开的读取请求数
+	notModified     int64 // If-None-Match 与当前值的 ETag 匹配而返回 304 的读取请求数
 }
 
-// NodeGetter 统一了获取缓存节点数据的接口
+// NodeGetter 统一了获取缓存节点数据的接口。
+// 所有方法都接收调用方的 ctx：ctx 取消（例如客户端断开连接）时对节点的请求随之中止，
+// getter 自身的请求超时作为 ctx 的子截止时间生效
 type NodeGetter interface {
 	// Get 返回指定组和键的值
-	Get(group string, key string) ([]byte, error)
+	Get(ctx context.Context, group string, key string) ([]byte, error)
 	// GetByProto 使用 protobuf 获取指定请求的值
-	GetByProto(req *pb.Request, resp *pb.Response) error
+	GetByProto(ctx context.Context, req *pb.Request, resp *pb.Response) error
 	// Delete 删除指定组和键的缓存
-	Delete(group string, key string) error
+	Delete(ctx context.Context, group string, key string) error
+	// DeleteBatch 按请求顺序返回每个键的删除结果，旧版本节点返回 ErrDeleteBatchUnimplemented
+	DeleteBatch(ctx context.Context, group string, keys []string) ([]cache.DeleteResult, error)
+	// Stats 获取节点上各缓存组的统计信息，旧版本节点返回 ErrStatsUnimplemented
+	Stats(ctx context.Context) (*pb.StatsResponse, error)
 }
 
 // CacheHandlerOptions 缓存处理器选项
 type CacheHandlerOptions struct {
-	Protocol ProtocolType // 通信协议类型，默认HTTP
+	Protocol      ProtocolType     // 默认通信协议，用于未登记所提供协议的旧版本节点，默认HTTP
+	GetterOptions []GetterOption   // 创建 NodeGetter 时使用的选项（超时等）
+	Getters       GetterFactory    // 创建 NodeGetter 的工厂，默认为 NewGetterFactory(GetterOptions...)
+	Hedge         HedgeConfig      // 对冲读取配置，默认关闭
+	Delete        DeleteConfig     // 单个 key 删除发往的节点，默认只发往归属节点
+	FanOut        fanout.Options   // 聚合接口向多个节点并发调用的配置，并发数默认 16
+	RingHash      string           // 一致性哈希函数: crc32（默认）或 xxhash64，集群中所有节点必须相同
+	HotKeySpread  HotKeySpread     // 被复制的热点 key 的读请求分配方式，默认 round-robin
+	ReadRepair    ReadRepairConfig // 非归属节点提供结果后的读修复，默认关闭
+	Identity      string           // 本 API 服务器的标识，读取响应在 X-GoCache-Routed-By 中返回它，并透传节点的 X-GoCache-Node；为空时两者都不返回
+	CachePolicies CachePolicies    // 读取响应的按组 HTTP 缓存策略，默认所有组都不可缓存
+	Purger        Purger           // 删除 key 后清除 CDN 缓存，默认 NopPurger
+	Proxy         ProxyUpstreams   // 按组配置的上游，配置了上游的组可以通过 /api/proxy/{group}/{path...} 访问，默认没有
+
+	OnRead func(source string) // 每次成功读取单个 key 后以节点返回的值来源（cache、loader 或 peer）调用，用于命中统计，可为 nil
 }
 
+// defaultFanOutConcurrency 聚合接口默认同时访问的节点数
+const defaultFanOutConcurrency = 16
+
 // NewCacheHandler 创建新的缓存处理器
 func NewCacheHandler(basePath string, replicas int, options ...CacheHandlerOptions) *CacheHandler {
 	// 默认选项
@@ -61,52 +125,130 @@ func NewCacheHandler(basePath string, replicas int, options ...CacheHandlerOptio
 		opts = options[0]
 	}
 
-	logger.Infof("缓存处理器使用 %s 协议", opts.Protocol)
+	if opts.Protocol == "" {
+		opts.Protocol = ProtocolHTTP
+	}
+	if opts.Getters == nil {
+		opts.Getters = NewGetterFactory(opts.GetterOptions...)
+	}
+	logger.Infof("缓存处理器默认使用 %s 协议，登记了所提供协议的节点优先使用 gRPC", opts.Protocol)
+
+	if opts.FanOut.Concurrency <= 0 {
+		opts.FanOut.Concurrency = defaultFanOutConcurrency
+	}
+	if opts.Delete.Mode == "" {
+		opts.Delete.Mode = DeleteOwner
+	}
+	if opts.Delete.Grace <= 0 {
+		opts.Delete.Grace = defaultPreviousRingGrace
+	}
+	if opts.Delete.Mode != DeleteOwner {
+		logger.Infof("单个 key 的删除方式: %s", opts.Delete.Mode)
+	}
+	if opts.Purger == nil {
+		opts.Purger = NopPurger{}
+	}
+
+	h := &CacheHandler{
+		basePath:     basePath,
+		replicas:     replicas,
+		ringHash:     opts.RingHash,
+		nodeGetters:  make(map[string]NodeGetter),
+		nodes:        make(map[string]discovery.NodeInfo),
+		counters:     make(map[string]*peers.Counters),
+		protocol:     opts.Protocol,
+		protocols:    make(map[string]ProtocolType),
+		getterOpts:   opts.GetterOptions,
+		getters:      opts.Getters,
+		hedger:       newHedger(opts.Hedge),
+		hot:          newHotRoutes(opts.HotKeySpread),
+		repair:       newReadRepairer(opts.ReadRepair),
+		fanOut:       opts.FanOut,
+		identity:     opts.Identity,
+		deleteConfig: opts.Delete,
+		policies:     opts.CachePolicies,
+		purger:       opts.Purger,
+		proxy:        newProxy(opts.Proxy),
+		onRead:       opts.OnRead,
+	}
+	h.ring = h.newRing()
+	return h
+}
 
-	return &CacheHandler{
-		basePath:    basePath,
-		replicas:    replicas,
-		ring:        consistenthash.New(replicas, nil),
-		nodeGetters: make(map[string]NodeGetter),
-		protocol:    opts.Protocol,
+// newRing 按配置的哈希函数创建空的一致性哈希环，名称无效时退回与旧版本兼容的 crc32
+func (h *CacheHandler) newRing(opts ...consistenthash.Option) *consistenthash.Map {
+	ring, err := consistenthash.NewByName(h.replicas, h.ringHash, opts...)
+	if err != nil {
+		logger.Errorf("创建一致性哈希环失败，使用 %s: %v", consistenthash.HashCRC32, err)
+		return consistenthash.NewCompat(h.replicas, opts...)
 	}
+	return ring
 }
 
-// UpdatePeers 更新节点列表和一致性哈希环
-func (h *CacheHandler) UpdatePeers(peers []string, getterFactory func(baseURL string) NodeGetter) {
+// UpdatePeers 更新节点列表和一致性哈希环。
+// 环以节点标识 (NodeInfo.Key) 为 key，与协议无关。每个节点的协议单独选择（见 selectProtocol），
+// getter 由 GetterFactory 按协议使用节点的 gRPC 或 HTTP 地址创建；地址或所选协议变化时重建。
+// 仍在集群中的节点保留其请求统计，即使 getter 因此重建。
+// 节点列表先经过 discovery.CanonicalNodes 规范化、去重和排序，列表的顺序不影响哈希环
+func (h *CacheHandler) UpdatePeers(nodes []discovery.NodeInfo) {
+	nodes, duplicates := discovery.CanonicalNodes(nodes)
+	if len(duplicates) > 0 {
+		logger.Warnf("节点列表中有重复的节点，只保留每个节点的第一条注册信息: %v", duplicates)
+	}
+
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
-	// 重建一致性哈希环
-	h.ring = consistenthash.New(h.replicas, nil)
-	h.ring.Add(peers...)
+	// 成员变化时重建一致性哈希环，新环的代数在旧环之上递增，旧环保留供删除使用；
+	// 成员不变时环也不变，沿用旧环
+	oldRing, oldNodes := h.ring, make([]string, 0, len(h.nodes))
+	for peer := range h.nodes {
+		oldNodes = append(oldNodes, peer)
+	}
+	members := discovery.NodeKeys(nodes)
+	slices.Sort(oldNodes)
+	if !slices.Equal(oldNodes, members) {
+		h.ring = h.newRing(consistenthash.WithGeneration(oldRing.Generation()))
+		h.ring.Add(members...)
+		h.rememberPreviousRing(oldRing, oldNodes, members)
+		logger.Infof("哈希环更新到第 %d 代，共 %d 个节点", h.ring.Generation(), len(members))
+	}
 
 	// 更新 node getters
 	newGetters := make(map[string]NodeGetter)
-	for _, peer := range peers {
-		if getter, ok := h.nodeGetters[peer]; ok {
-			// 复用现有的 getter
+	newNodes := make(map[string]discovery.NodeInfo, len(nodes))
+	newCounters := make(map[string]*peers.Counters, len(nodes))
+	newProtocols := make(map[string]ProtocolType, len(nodes))
+	for _, node := range nodes {
+		peer := node.Key()
+		newNodes[peer] = node
+		counters, ok := h.counters[peer]
+		if !ok {
+			counters = new(peers.Counters)
+		}
+		newCounters[peer] = counters
+		protocol := h.selectProtocol(node)
+		newProtocols[peer] = protocol
+		if getter, ok := h.nodeGetters[peer]; ok && h.nodes[peer].SameAddrs(node) && h.protocols[peer] == protocol {
+			// 地址和协议未变化，复用现有的 getter
 			newGetters[peer] = getter
 		} else {
-			// 为新节点创建 getter
-			var baseURL string
-			if h.protocol == ProtocolGRPC {
-				// 对于gRPC，直接使用地址
-				baseURL = peer
-				newGetters[peer] = NewGRPCGetter(baseURL)
-				logger.Infof("为节点 %s 创建新的 gRPC getter", peer)
-			} else {
-				// 对于HTTP，构建基础URL
-				baseURL = fmt.Sprintf("http://%s%s", peer, h.basePath)
-				newGetters[peer] = getterFactory(baseURL)
-				logger.Infof("为节点 %s 创建新的 HTTP getter (URL: %s)", peer, baseURL)
+			// 为新节点（或地址、协议发生变化的节点）创建 getter
+			addr := h.getterAddr(protocol, node)
+			newGetters[peer] = h.getters.NewGetter(protocol, addr)
+			logger.Infof("为节点 %s 创建新的 %s getter (地址: %s)", peer, protocol, addr)
+			if g, ok := newGetters[peer].(countedGetter); ok {
+				g.setCounters(counters)
 			}
 		}
+		if g, ok := newGetters[peer].(versionedGetter); ok {
+			g.protoVersion().Set(node.ProtoVersion)
+		}
 	}
 
 	// 关闭不再使用的getter连接
 	for peer, getter := range h.nodeGetters {
-		if _, exists := newGetters[peer]; !exists {
+		if newGetters[peer] != getter {
 			// 如果是GRPCGetter，关闭连接
 			if grpcGetter, ok := getter.(*GRPCGetter); ok {
 				if err := grpcGetter.Close(); err != nil {
@@ -117,6 +259,30 @@ func (h *CacheHandler) UpdatePeers(peers []string, getterFactory func(baseURL st
 	}
 
 	h.nodeGetters = newGetters
+	h.nodes = newNodes
+	h.counters = newCounters
+	h.protocols = newProtocols
+	h.registry = newGroupRegistry(nodes)
+}
+
+// nodeInfo 返回节点标识对应的注册信息
+func (h *CacheHandler) nodeInfo(key string) (discovery.NodeInfo, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	info, ok := h.nodes[key]
+	return info, ok
+}
+
+// NodeStats 返回发往各节点的请求与错误统计，以节点标识为 key
+func (h *CacheHandler) NodeStats() map[string]peers.Stats {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	stats := make(map[string]peers.Stats, len(h.counters))
+	for peer, c := range h.counters {
+		stats[peer] = c.Snapshot()
+	}
+	return stats
 }
 
 // GetNodeGetters 获取所有节点getter
@@ -132,27 +298,39 @@ func (h *CacheHandler) GetNodeGetters() map[string]NodeGetter {
 	return result
 }
 
-// GetCacheHandler 处理 /cache/{group}/{key} 或 /api/cache/{group}/{key} 请求
+// GetCacheHandler 处理 /cache/{group}/{key} 或 /api/cache/{group}/{key} 请求。
+// 默认原样返回值；Accept: application/json 或 ?format=json 时返回 ValueEnvelope，错误返回 ErrorEnvelope。
+// 响应带由值的内容计算的 ETag，If-None-Match 匹配时返回 304；原样返回时支持 Range 与 If-Range
 func (h *CacheHandler) GetCacheHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Vary", "Accept")
+	// 错误响应和没有缓存策略的组不可缓存，读取成功后按组的策略改写
+	w.Header().Set("Cache-Control", "no-store")
+	format, err := negotiateReadFormat(r)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Bad Request: %v", err), http.StatusBadRequest)
+		return
+	}
+
 	// 解析 URL 路径
-	parts := h.parseCachePath(r.URL.Path)
-	if parts == nil {
-		http.Error(w, "Bad Request: expected /cache/{group}/{key} or /api/cache/{group}/{key}", http.StatusBadRequest)
+	groupName, key, ok := parseCachePath(r.URL.EscapedPath())
+	if !ok {
+		writeReadError(w, format, http.StatusBadRequest, EnvelopeCodeBadRequest,
+			"Bad Request: expected /cache/{group}/{key} or /api/cache/{group}/{key}")
 		return
 	}
 
-	groupName, key := parts[0], parts[1]
-	logger.Debugf("收到缓存请求: group=%s, key=%s", groupName, key)
+	logger.Debugf("收到缓存请求: group=%s, key=%s", groupName, logger.Key(key))
 
-	// 根据 key 选择节点
-	nodeAddr, getter := h.pickNode(key)
-	if getter == nil {
-		http.Error(w, "No suitable cache node available", http.StatusServiceUnavailable)
-		logger.Warnf("无法为 key '%s' 找到合适的缓存节点", key)
+	if !access.Authorize(w, r, groupName, access.OpRead) {
 		return
 	}
 
-	logger.Debugf("选择节点 %s 处理 key=%s (group=%s)", nodeAddr, key, groupName)
+	// 组不在注册表中时直接返回，不访问节点
+	if h.isUnknownGroup(groupName) {
+		writeReadGroupNotFound(w, format, groupName)
+		logger.Warnf("组不存在: %s", groupName)
+		return
+	}
 
 	// 创建 protobuf 请求
 	req := &pb.Request{
@@ -161,63 +339,59 @@ func (h *CacheHandler) GetCacheHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	res := &pb.Response{}
 
-	// 发送请求到选中的节点
-	err := getter.GetByProto(req, res)
+	// 发送请求到 key 所在的节点，开启对冲时可能由下一个节点提供结果；
+	// 归属节点复制了热点 key 时可能由副本节点提供结果
+	nodeAddr, err := h.getHot(r.Context(), clientIdentity(r.Context(), r.RemoteAddr), key, req, res)
+	if errors.Is(err, errNoNode) {
+		writeReadError(w, format, http.StatusServiceUnavailable, EnvelopeCodeNoNodeAvailable, "No suitable cache node available")
+		logger.Warnf("无法为 key '%s' 找到合适的缓存节点", logger.Key(key))
+		return
+	}
+	if err != nil && h.clientGone(r) {
+		logger.Debugf("客户端已断开，放弃读取: group=%s, key=%s, node=%s", groupName, logger.Key(key), nodeAddr)
+		return
+	}
 	if err != nil {
-		// 使用错误类型比较
-		errMsg := err.Error()
-
-		// 我们有两种情况：
-		// 1. 错误可能是我们自己的CacheError
-		// 2. 错误可能是从远程节点返回的错误消息
-
-		// 先尝试使用错误类型系统判断
-		if errors.Is(err, cache.ErrNotFound) || cache.IsKeyNotFoundError(err) {
-			// 键不存在错误
-			http.Error(w, fmt.Sprintf("Key not found: %s", key), http.StatusNotFound)
-			logger.Warnf("键不存在: %s (group=%s)", key, groupName)
-		} else if errors.Is(err, cache.ErrEmptyKey) || cache.IsKeyEmptyError(err) {
-			// 键为空错误
-			http.Error(w, "Key is empty", http.StatusBadRequest)
-			logger.Warnf("键为空错误: %s", errMsg)
-		} else if errors.Is(err, cache.ErrNoSuchGroup) || cache.IsGroupNotFoundError(err) {
-			// 组不存在错误
-			http.Error(w, fmt.Sprintf("Group not found: %s", groupName), http.StatusNotFound)
-			logger.Warnf("组不存在: %s", groupName)
-		} else if strings.Contains(errMsg, "key not found") ||
-			strings.Contains(errMsg, "not found") ||
-			strings.Contains(errMsg, "not exist") ||
-			strings.Contains(errMsg, "本地未找到") ||
-			strings.Contains(errMsg, "未找到") {
-			// 通过错误消息判断是键不存在（兼容来自远程节点的错误消息）
-			http.Error(w, fmt.Sprintf("Key not found: %s", key), http.StatusNotFound)
-			logger.Warnf("键不存在: %s (group=%s)", key, groupName)
-		} else if strings.Contains(errMsg, "key is empty") ||
-			strings.Contains(errMsg, "键为空") {
-			// 通过错误消息判断是键为空
-			http.Error(w, "Key is empty", http.StatusBadRequest)
-			logger.Warnf("键为空错误: %s", errMsg)
-		} else if strings.Contains(errMsg, "no such group") ||
-			strings.Contains(errMsg, "group not found") ||
-			strings.Contains(errMsg, "组不存在") {
-			// 通过错误消息判断是组不存在
-			http.Error(w, fmt.Sprintf("Group not found: %s", groupName), http.StatusNotFound)
-			logger.Warnf("组不存在: %s", groupName)
-		} else {
-			// 其他类型的错误仍然返回500
-			http.Error(w, fmt.Sprintf("Failed to get data: %v", err), http.StatusInternalServerError)
-			logger.Errorf("从节点 %s 获取数据失败: %v", nodeAddr, err)
-		}
+		writeNodeError(w, format, nodeAddr, groupName, key, "Failed to get data", err)
 		return
 	}
 
-	// 返回响应
-	w.Header().Set("Content-Type", "application/octet-stream")
-	w.Write(res.Value)
+	if h.onRead != nil {
+		h.onRead(res.GetSource())
+	}
+
+	// 返回响应，节点提供的过期时间、版本、来源和节点标识通过 X-GoCache-* 响应头透传；
+	// 不公开标识时去掉节点标识，否则附上本 API 服务器的标识
+	if h.identity == "" {
+		res.Node = nil
+	} else {
+		w.Header().Set(peers.HeaderRoutedBy, h.identity)
+	}
+	peers.WriteMetaHeaders(w.Header(), res)
+	now := time.Now()
+	setCacheHeaders(w.Header(), h.policies.lookup(groupName), res, r.Header.Get("Authorization") != "", now)
+	if format == formatJSON {
+		// JSON 中的剩余有效期等字段随时间变化，值相同即视为等价，因此使用弱 ETag
+		etag := `W/"` + peers.ValueDigest(res.Value) + `-json"`
+		w.Header().Set("ETag", etag)
+		if peers.ETagMatches(r.Header.Get("If-None-Match"), etag) {
+			w.WriteHeader(http.StatusNotModified)
+			h.countNotModified(groupName, key)
+			return
+		}
+		writeJSON(w, http.StatusOK, newValueEnvelope(groupName, key, res, now))
+	} else {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		if peers.ServeValue(w, r, res.Value) {
+			h.countNotModified(groupName, key)
+			return
+		}
+	}
 	logger.Debugf("成功从节点 %s 获取数据, 长度: %d bytes", nodeAddr, len(res.Value))
 }
 
-// DeleteCacheHandler 处理 /cache/{group}/{key} 或 /api/cache/{group}/{key} 的DELETE请求
+// DeleteCacheHandler 处理 /cache/{group}/{key} 或 /api/cache/{group}/{key} 的DELETE请求。
+// 开启删除重试时，归属节点暂时不可达的删除进入重试队列并返回 202
 func (h *CacheHandler) DeleteCacheHandler(w http.ResponseWriter, r *http.Request) {
 	// 只处理DELETE请求
 	if r.Method != http.MethodDelete {
@@ -226,90 +400,145 @@ func (h *CacheHandler) DeleteCacheHandler(w http.ResponseWriter, r *http.Request
 	}
 
 	// 解析 URL 路径
-	parts := h.parseCachePath(r.URL.Path)
-	if parts == nil {
+	groupName, key, ok := parseCachePath(r.URL.EscapedPath())
+	if !ok {
 		http.Error(w, "Bad Request: expected /cache/{group}/{key} or /api/cache/{group}/{key}", http.StatusBadRequest)
 		return
 	}
 
-	groupName, key := parts[0], parts[1]
-	logger.Debugf("收到删除缓存请求: group=%s, key=%s", groupName, key)
+	logger.Debugf("收到删除缓存请求: group=%s, key=%s", groupName, logger.Key(key))
+
+	if !access.Authorize(w, r, groupName, access.OpWrite) {
+		return
+	}
+
+	if h.isUnknownGroup(groupName) {
+		writeGroupNotFound(w, groupName)
+		logger.Warnf("组不存在无法删除: %s", groupName)
+		return
+	}
 
-	// 根据 key 选择节点
-	nodeAddr, getter := h.pickNode(key)
-	if getter == nil {
+	// 根据 key 和删除方式选择节点，第一个为归属节点
+	nodes, getters := h.deleteTargets(key)
+	if len(nodes) == 0 {
 		http.Error(w, "No suitable cache node available", http.StatusServiceUnavailable)
-		logger.Warnf("无法为 key '%s' 找到合适的缓存节点", key)
+		logger.Warnf("无法为 key '%s' 找到合适的缓存节点", logger.Key(key))
 		return
 	}
+	nodeAddr := nodes[0]
 
-	logger.Debugf("选择节点 %s 删除 key=%s (group=%s)", nodeAddr, key, groupName)
+	logger.Debugf("选择节点 %v 删除 key=%s (group=%s)", nodes, logger.Key(key), groupName)
 
-	// 发送删除请求到选中的节点
-	err := getter.Delete(groupName, key)
+	// 发送删除请求到选中的节点，归属节点的结果决定响应
+	acked, err := h.deleteOn(r.Context(), nodes, getters, groupName, key)
+	w.Header().Set(peers.HeaderDeleteTargets, strconv.Itoa(len(nodes)))
+	w.Header().Set(peers.HeaderDeleteAcked, strconv.Itoa(acked))
 	if err != nil {
-		// 错误处理逻辑与Get类似
-		errMsg := err.Error()
-
-		if errors.Is(err, cache.ErrNotFound) || cache.IsKeyNotFoundError(err) {
-			// 键不存在错误
-			http.Error(w, fmt.Sprintf("Key not found: %s", key), http.StatusNotFound)
-			logger.Warnf("键不存在无法删除: %s (group=%s)", key, groupName)
-		} else if errors.Is(err, cache.ErrEmptyKey) || cache.IsKeyEmptyError(err) {
-			// 键为空错误
-			http.Error(w, "Key is empty", http.StatusBadRequest)
-			logger.Warnf("键为空错误: %s", errMsg)
-		} else if errors.Is(err, cache.ErrNoSuchGroup) || cache.IsGroupNotFoundError(err) {
-			// 组不存在错误
-			http.Error(w, fmt.Sprintf("Group not found: %s", groupName), http.StatusNotFound)
-			logger.Warnf("组不存在: %s", groupName)
-		} else if strings.Contains(errMsg, "key not found") ||
-			strings.Contains(errMsg, "not found") {
-			// 通过错误消息判断是键不存在
-			http.Error(w, fmt.Sprintf("Key not found: %s", key), http.StatusNotFound)
-			logger.Warnf("键不存在无法删除: %s (group=%s)", key, groupName)
-		} else {
-			// 其他类型的错误返回500
-			http.Error(w, fmt.Sprintf("Failed to delete data: %v", err), http.StatusInternalServerError)
-			logger.Errorf("从节点 %s 删除数据失败: %v", nodeAddr, err)
+		switch {
+		case cacheerrors.IsKeyNotFoundError(err):
+			// 节点提前淘汰的值可能仍在 CDN 中
+			h.purge(groupName, key)
+		case h.queueDelete(nodeAddr, groupName, key, err):
+			// 节点暂时不可达，删除已进入重试队列
+			h.hot.forget(groupName, key)
+			h.purge(groupName, key)
+			w.WriteHeader(http.StatusAccepted)
+			w.Write([]byte("Delete accepted, pending retry"))
+			return
 		}
+		writeNodeError(w, formatRaw, nodeAddr, groupName, key, "Failed to delete data", err)
 		return
 	}
 
+	// 归属节点删除时已使副本失效，之后的读请求回到归属节点
+	h.hot.forget(groupName, key)
+	h.purge(groupName, key)
+
 	// 删除成功，返回200 OK
 	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("Deleted successfully"))
-	logger.Debugf("成功从节点 %s 删除数据: %s (group=%s)", nodeAddr, key, groupName)
+	if len(nodes) > 1 {
+		fmt.Fprintf(w, "Deleted successfully, acknowledged by %d of %d nodes", acked, len(nodes))
+	} else {
+		w.Write([]byte("Deleted successfully"))
+	}
+	logger.Debugf("成功从节点 %s 删除数据: %s (group=%s)", nodeAddr, logger.Key(key), groupName)
 }
 
-// 解析缓存路径 /cache/{group}/{key} 或 /api/cache/{group}/{key}
-func (h *CacheHandler) parseCachePath(path string) []string {
-	parts := strings.Split(path, "/")
-
-	// 移除空字符串元素（由分割"/"产生）
-	var nonEmptyParts []string
-	for _, part := range parts {
-		if part != "" {
-			nonEmptyParts = append(nonEmptyParts, part)
+// deleteOn 在 nodes 上删除 key，返回确认删除（成功或键不存在）的节点数以及归属节点 nodes[0] 的错误。
+// 多个节点时通过 fanout 并发发送，其他节点失败时按删除重试的规则进入重试队列，不影响返回的错误
+func (h *CacheHandler) deleteOn(ctx context.Context, nodes []string, getters []NodeGetter, group, key string) (int, error) {
+	if len(nodes) == 1 {
+		err := getters[0].Delete(ctx, group, key)
+		if err == nil || isKeyNotFound(err) {
+			return 1, err
 		}
+		return 0, err
 	}
 
-	// 检查路径格式
-	if len(nonEmptyParts) < 3 {
-		return nil
+	byNode := make(map[string]NodeGetter, len(nodes))
+	for i, node := range nodes {
+		byNode[node] = getters[i]
 	}
-
-	// 处理 /api/cache/{group}/{key} 格式
-	if len(nonEmptyParts) >= 4 && nonEmptyParts[0] == "api" && nonEmptyParts[1] == "cache" {
-		return []string{nonEmptyParts[2], nonEmptyParts[3]} // [group, key]
+	results := fanout.FanOut(ctx, nodes, func(ctx context.Context, node string) (struct{}, error) {
+		return struct{}{}, byNode[node].Delete(ctx, group, key)
+	}, h.fanOut)
+
+	acked := 0
+	for _, node := range nodes {
+		err := results[node].Err
+		switch {
+		case err == nil || isKeyNotFound(err):
+			acked++
+		case node != nodes[0] && !h.queueDelete(node, group, key, err):
+			logger.Warnf("从节点 %s 删除失败（非归属节点）: group=%s key=%s: %v", node, group, logger.Key(key), err)
+		}
 	}
+	return acked, results[nodes[0]].Err
+}
 
-	// 处理 /cache/{group}/{key} 格式
-	if len(nonEmptyParts) >= 3 && nonEmptyParts[0] == "cache" {
-		return []string{nonEmptyParts[1], nonEmptyParts[2]} // [group, key]
+// parseCachePath 解析转义后的缓存路径 /cache/{group}/{key} 或 /api/cache/{group}/{key}，
+// 规则见 peers.SplitGroupKey；开头多余的 "/" 会被忽略，key 为空时解析失败
+func parseCachePath(escapedPath string) (group, key string, ok bool) {
+	path := "/" + strings.TrimLeft(escapedPath, "/")
+	for _, prefix := range []string{CacheKeyPathPrefix, "/cache/"} {
+		if strings.HasPrefix(path, prefix) {
+			group, key, ok = peers.SplitGroupKey(path, prefix)
+			return group, key, ok && key != ""
+		}
 	}
+	return "", "", false
+}
+
+// RingReport 返回当前哈希环的描述以及 samples 个模拟 key 在各节点上的占比
+func (h *CacheHandler) RingReport(samples int) consistenthash.Report {
+	h.mu.RLock()
+	ring := h.ring
+	h.mu.RUnlock()
+	return ring.Report(samples)
+}
 
-	return nil
+// RingGeneration 返回 API 服务器路由所用哈希环的代数：启动时为 0，节点成员每变化一次加 1
+func (h *CacheHandler) RingGeneration() uint64 {
+	h.mu.RLock()
+	ring := h.ring
+	h.mu.RUnlock()
+	return ring.Generation()
+}
+
+// pickNodes 沿哈希环为 key 选择至多 n 个不同的节点及其 getter，第一个为主节点
+func (h *CacheHandler) pickNodes(key string, n int) ([]string, []NodeGetter) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	var nodes []string
+	var getters []NodeGetter
+	for _, node := range h.ring.GetN(key, n) {
+		if getter, ok := h.nodeGetters[node]; ok {
+			nodes = append(nodes, node)
+			getters = append(getters, getter)
+		}
+	}
+	return nodes, getters
 }
 
 // 根据 key 选择节点和对应的 getter
@@ -318,17 +547,17 @@ func (h *CacheHandler) pickNode(key string) (string, NodeGetter) {
 	defer h.mu.RUnlock()
 
 	if len(h.nodeGetters) == 0 {
-		logger.Warnf("无可用节点处理请求: key=%s", key)
+		logger.Warnf("无可用节点处理请求: key=%s", logger.Key(key))
 		return "", nil
 	}
 
 	node := h.ring.Get(key)
 	if node == "" {
-		logger.Warnf("一致性哈希环无法为key=%s分配节点", key)
+		logger.Warnf("一致性哈希环无法为key=%s分配节点", logger.Key(key))
 		return "", nil
 	}
 
-	logger.Debugf("一致性哈希选择节点 %s 处理 key=%s", node, key)
+	logger.Debugf("一致性哈希选择节点 %s 处理 key=%s", node, logger.Key(key))
 
 	if getter, ok := h.nodeGetters[node]; ok {
 		return node, getter
@@ -337,3 +566,29 @@ func (h *CacheHandler) pickNode(key string) (string, NodeGetter) {
 	logger.Warnf("找不到节点 %s 的getter，可能节点列表与getter不同步", node)
 	return "", nil
 }
+
+// clientGone 判断客户端是否已断开连接。客户端断开时 r.Context() 被取消，发往节点的请求随之中止，
+// 节点上没有其他调用方等待的加载也会被取消；此时不再写响应，只计入 ClientCancelled
+func (h *CacheHandler) clientGone(r *http.Request) bool {
+	if r.Context().Err() == nil {
+		return false
+	}
+	atomic.AddInt64(&h.clientCancelled, 1)
+	return true
+}
+
+// ClientCancelled 返回客户端在收到响应之前断开的读取请求数
+func (h *CacheHandler) ClientCancelled() int64 {
+	return atomic.LoadInt64(&h.clientCancelled)
+}
+
+// countNotModified 记录一次返回 304 的读取。值仍然从节点读取，节点照常计入命中
+func (h *CacheHandler) countNotModified(group, key string) {
+	atomic.AddInt64(&h.notModified, 1)
+	logger.Debugf("值未变化，返回 304: group=%s, key=%s", group, logger.Key(key))
+}
+
+// NotModified 返回 If-None-Match 与当前值的 ETag 匹配而返回 304 的读取请求数
+func (h *CacheHandler) NotModified() int64 {
+	return atomic.LoadInt64(&h.notModified)
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/AdrianWangs/go-cache/internal/discovery"
+)
+
+// nodeOwners 返回 n 个 key 各自归属的节点
+func nodeOwners(h *CacheHandler, n int) map[string]string {
+	owners := make(map[string]string, n)
+	for i := 0; i < n; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		owners[key], _ = h.pickNode(key)
+	}
+	return owners
+}
+
+// TestUpdatePeersOrderAndDuplicates 同一组成员无论顺序、重复和地址写法如何，
+// 每个 key 都归属同一个节点，每个节点只有一份虚拟节点
+func TestUpdatePeersOrderAndDuplicates(t *testing.T) {
+	nodes := nodesWithGroups(4, "scores")
+	lists := [][]discovery.NodeInfo{
+		nodes,
+		{nodes[3], nodes[1], nodes[0], nodes[2]},
+		{nodes[2], nodes[0], nodes[2], nodes[3], nodes[1], nodes[0]},
+		{
+			{GRPCAddr: " 10.0.0.4:9090", Groups: []string{"scores"}},
+			{GRPCAddr: "grpc://10.0.0.2:9090/", Groups: []string{"scores"}},
+			nodes[0], nodes[2], nodes[1],
+		},
+	}
+
+	var want map[string]string
+	for i, list := range lists {
+		h := NewCacheHandler("/_gocache/", 50, CacheHandlerOptions{Getters: &recordingFactory{}})
+		h.UpdatePeers(list)
+		d := h.ring.Describe()
+		if d.Nodes != 4 || d.VirtualNodes != 4*d.Replicas {
+			t.Errorf("列表 %d: %d 个节点、%d 个虚拟节点, want 4 个节点、%d 个虚拟节点", i, d.Nodes, d.VirtualNodes, 4*d.Replicas)
+		}
+		got := nodeOwners(h, 2000)
+		if want == nil {
+			want = got
+			continue
+		}
+		for key, owner := range want {
+			if got[key] != owner {
+				t.Fatalf("列表 %d: %s 归属 %q, want %q", i, key, got[key], owner)
+			}
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/internal/cache"
+	"github.com/AdrianWangs/go-cache/internal/peers"
+	"github.com/AdrianWangs/go-cache/pkg/cacheerrors"
+	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
+)
+
+// TestGetterErrorStatuses 节点对读取返回的各类错误：两种 NodeGetter 的纯 HTTP 和 Protobuf 读取
+// 都还原出同一个预定义错误，包括不带错误码、只能按状态码和响应内容区分的旧节点
+func TestGetterErrorStatuses(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		code   string // 错误码响应头，为空时模拟旧节点
+		body   string
+		want   error // 为 nil 时不应映射为任何预定义错误
+	}{
+		{"旧节点键不存在", http.StatusNotFound, "", "key 'k' not found", cache.ErrNotFound},
+		{"旧节点组不存在", http.StatusNotFound, "", "no such group: scores", cache.ErrNoSuchGroup},
+		{"旧节点键为空", http.StatusBadRequest, "", "key is empty", cache.ErrEmptyKey},
+		{"旧节点限流", http.StatusTooManyRequests, "", "rate limited", cache.ErrRateLimited},
+		{"旧节点内部错误", http.StatusInternalServerError, "", "boom", nil},
+		{"旧节点请求格式错误", http.StatusBadRequest, "", "bad request format", nil},
+		{"错误码键不存在", http.StatusNotFound, cacheerrors.ErrorCode(cache.ErrNotFound), "key 'k' not found", cache.ErrNotFound},
+		{"错误码组不存在", http.StatusNotFound, cacheerrors.ErrorCode(cache.ErrNoSuchGroup), "missing", cache.ErrNoSuchGroup},
+		{"错误码键为空", http.StatusBadRequest, cacheerrors.ErrorCode(cache.ErrEmptyKey), "", cache.ErrEmptyKey},
+		{"错误码限流", http.StatusTooManyRequests, cacheerrors.ErrorCode(cache.ErrRateLimited), "", cache.ErrRateLimited},
+	}
+	sentinels := []error{cache.ErrNotFound, cache.ErrNoSuchGroup, cache.ErrEmptyKey, cache.ErrRateLimited}
+
+	for _, tt := range tests {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if tt.code != "" {
+				w.Header().Set(peers.HeaderErrorCode, tt.code)
+			}
+			http.Error(w, tt.body, tt.status)
+		}))
+		baseURL := srv.URL + "/_go_cache/"
+		ctx := context.Background()
+		calls := map[string]func() error{
+			"HTTPGetter.Get": func() error {
+				_, err := NewHTTPGetter(baseURL).Get(ctx, "scores", "k")
+				return err
+			},
+			"HTTPGetter.GetByProto": func() error {
+				return NewHTTPGetter(baseURL).GetByProto(ctx, &pb.Request{Group: "scores", Key: "k"}, &pb.Response{})
+			},
+			"ProtoGetter.GetByProto": func() error {
+				return NewProtoGetter(baseURL).GetByProto(ctx, &pb.Request{Group: "scores", Key: "k"}, &pb.Response{})
+			},
+		}
+		for name, call := range calls {
+			t.Run(tt.name+"/"+name, func(t *testing.T) {
+				err := call()
+				if tt.want != nil {
+					if !errors.Is(err, tt.want) {
+						t.Fatalf("got %v, want %v", err, tt.want)
+					}
+					return
+				}
+				for _, sentinel := range sentinels {
+					if errors.Is(err, sentinel) {
+						t.Fatalf("%d %q 被映射为 %v", tt.status, tt.body, err)
+					}
+				}
+				if err == nil || !strings.Contains(err.Error(), tt.body) {
+					t.Fatalf("got %v, 错误中应包含响应内容 %q", err, tt.body)
+				}
+			})
+		}
+		srv.Close()
+	}
+}
+
+// TestRetryAfterPassthrough 节点限流时给出的 Retry-After 经三种读取方式还原到错误中，
+// 并由 API Server 原样返回给客户端
+func TestRetryAfterPassthrough(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(peers.HeaderErrorCode, cacheerrors.ErrorCode(cache.ErrRateLimited))
+		w.Header().Set(cacheerrors.HeaderRetryAfter, "7")
+		http.Error(w, "rate limited", http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+	baseURL := srv.URL + "/_go_cache/"
+	ctx := context.Background()
+
+	getters := map[string]NodeGetter{"HTTPGetter": NewHTTPGetter(baseURL), "ProtoGetter": NewProtoGetter(baseURL)}
+	for name, getter := range getters {
+		t.Run(name, func(t *testing.T) {
+			err := getter.GetByProto(ctx, &pb.Request{Group: "scores", Key: "k"}, &pb.Response{})
+			if !errors.Is(err, cache.ErrRateLimited) || cacheerrors.RetryAfter(err) != 7*time.Second {
+				t.Fatalf("got %v, 重试间隔 %v", err, cacheerrors.RetryAfter(err))
+			}
+
+			h := NewCacheHandler("/_gocache/", 50, CacheHandlerOptions{
+				Getters: GetterFactoryFunc(func(ProtocolType, string) NodeGetter { return getter }),
+			})
+			h.UpdatePeers(nodesWithGroups(1, "scores"))
+			for _, accept := range []string{"", "application/json"} {
+				w := serveRead(h.GetCacheHandler, "/api/cache/scores/k", accept)
+				if w.Code != http.StatusTooManyRequests || w.Header().Get(cacheerrors.HeaderRetryAfter) != "7" {
+					t.Fatalf("Accept %q: %d, Retry-After %q", accept, w.Code, w.Header().Get(cacheerrors.HeaderRetryAfter))
+				}
+			}
+		})
+	}
+	if _, err := NewHTTPGetter(baseURL).Get(ctx, "scores", "k"); cacheerrors.RetryAfter(err) != 7*time.Second {
+		t.Fatalf("HTTPGetter.Get: %v, 重试间隔 %v", err, cacheerrors.RetryAfter(err))
+	}
+}
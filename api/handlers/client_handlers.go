@@ -3,13 +3,16 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"net/http"
-	"net/url"
 	"strings"
+	"time"
 
 	"github.com/AdrianWangs/go-cache/internal/cache"
+	"github.com/AdrianWangs/go-cache/internal/peers"
+	"github.com/AdrianWangs/go-cache/pkg/cacheerrors"
 	"github.com/AdrianWangs/go-cache/pkg/logger"
 	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
 	"google.golang.org/protobuf/proto"
@@ -27,50 +30,119 @@ var defaultHTTPClient HTTPClient = &http.Client{}
 
 // HTTPGetter 使用HTTP协议实现的NodeGetter
 type HTTPGetter struct {
-	baseURL    string     // 基础URL
-	httpClient HTTPClient // HTTP客户端
+	baseURL    string          // 基础URL
+	httpClient HTTPClient      // HTTP客户端
+	timeout    time.Duration   // 请求超时
+	counters   *peers.Counters // 请求与错误统计，为 nil 时不记录
+	limit      peers.Limit     // 未完成请求数的上限，与 counters 一起按节点保留
+
+	version peers.PeerVersion // 节点在响应头中声明的协议版本
 }
 
 // NewHTTPGetter 创建新的HTTP客户端
-func NewHTTPGetter(baseURL string) *HTTPGetter {
+func NewHTTPGetter(baseURL string, opts ...GetterOption) *HTTPGetter {
+	cfg := newGetterConfig(opts...)
 	return &HTTPGetter{
 		baseURL:    baseURL,
-		httpClient: defaultHTTPClient,
+		httpClient: cfg.httpClient(),
+		timeout:    cfg.requestTimeout,
+		counters:   new(peers.Counters),
+		limit:      cfg.limit,
+	}
+}
+
+// keyURL 返回节点上 group/key 的纯 HTTP 地址，baseURL 末尾的斜杠可有可无
+func keyURL(baseURL, group, key string) string {
+	return fmt.Sprintf("%v/%v/%v", strings.TrimSuffix(baseURL, "/"), peers.EscapePathSegment(group), peers.EscapePathSegment(key))
+}
+
+// newRequest 在 parent 的基础上创建带超时的HTTP请求，返回的cancel必须在读完响应后调用
+func newRequest(parent context.Context, method, u string, body io.Reader, timeout time.Duration) (*http.Request, context.CancelFunc, error) {
+	ctx, cancel := context.WithTimeout(parent, timeout)
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		cancel()
+		return nil, nil, err
+	}
+	return req, cancel, nil
+}
+
+// Get 通过HTTP获取缓存值，ctx 取消时请求随之中止
+func (h *HTTPGetter) Get(ctx context.Context, group, key string) ([]byte, error) {
+	resp := &pb.Response{}
+	if err := h.GetPlain(ctx, &pb.Request{Group: group, Key: key}, resp); err != nil {
+		return nil, err
 	}
+	return resp.Value, nil
 }
 
-// Get 通过HTTP获取缓存值
-func (h *HTTPGetter) Get(group, key string) ([]byte, error) {
+// GetPlain 通过纯 HTTP GET 获取缓存值，响应头中的过期时间、版本和来源
+// 写入 resp 中与 Protobuf 响应相同的字段
+func (h *HTTPGetter) GetPlain(ctx context.Context, r *pb.Request, resp *pb.Response) error {
 	// 构建请求URL
-	u := fmt.Sprintf("%v/%v/%v", h.baseURL, url.QueryEscape(group), url.QueryEscape(key))
+	u := keyURL(h.baseURL, r.GetGroup(), r.GetKey())
 
-	logger.Debugf("发送HTTP GET请求: %s", u)
+	logger.Debugf("发送HTTP GET请求: %s (group=%s, key=%s)",
+		h.baseURL, r.GetGroup(), logger.Key(r.GetKey()))
 
-	// 发送HTTP请求
-	res, err := h.httpClient.Get(u)
+	req, cancel, err := newRequest(ctx, http.MethodGet, u, nil, h.timeout)
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("创建请求失败: %v", err)
 	}
-	defer res.Body.Close()
+	defer cancel()
 
-	// 检查响应状态
-	if res.StatusCode == http.StatusNotFound {
-		return nil, fmt.Errorf("key not found: %s", key)
-	} else if res.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("服务器返回错误: %v", res.Status)
+	release, err := h.counters.Acquire(ctx, h.limit)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	// 发送HTTP请求
+	call := h.counters.Start(0)
+	res, err := h.httpClient.Do(req)
+	if err != nil {
+		call.Fail(err)
+		return err
+	}
+	defer peers.CloseBody(res.Body)
+
+	// 检查响应状态，节点给出错误码时优先按错误码映射
+	h.version.Observe(res.Header)
+	call.Status(res.StatusCode)
+	if res.StatusCode != http.StatusOK {
+		if err := errorFromResponse(res); err != nil {
+			return err
+		}
+		// 没有错误码的旧节点只能按状态码和响应内容区分
+		errMsg := peers.ErrorBody(res.Body)
+		switch res.StatusCode {
+		case http.StatusNotFound:
+			if strings.Contains(errMsg, "no such group") {
+				return cache.ErrNoSuchGroup
+			}
+			return cache.ErrNotFound
+		case http.StatusBadRequest:
+			if strings.Contains(errMsg, "key is empty") {
+				return cache.ErrEmptyKey
+			}
+		}
+		return fmt.Errorf("服务器返回错误: %v, 详情: %s", res.Status, errMsg)
 	}
 
 	// 读取响应内容
 	bytes, err := io.ReadAll(res.Body)
+	call.Received(len(bytes))
 	if err != nil {
-		return nil, fmt.Errorf("读取响应失败: %v", err)
+		call.Fail(err)
+		return fmt.Errorf("读取响应失败: %v", err)
 	}
 
-	return bytes, nil
+	resp.Value = bytes
+	return peers.ReadMetaHeaders(res.Header, resp)
 }
 
-// GetByProto 通过Protobuf获取缓存值
-func (h *HTTPGetter) GetByProto(req *pb.Request, resp *pb.Response) error {
+// GetByProto 通过Protobuf获取缓存值，ctx 取消时请求随之中止
+func (h *HTTPGetter) GetByProto(ctx context.Context, req *pb.Request, resp *pb.Response) error {
 	// 序列化请求
 	body, err := proto.Marshal(req)
 	if err != nil {
@@ -79,32 +151,49 @@ func (h *HTTPGetter) GetByProto(req *pb.Request, resp *pb.Response) error {
 
 	// 构建完整的URL (baseURL包含basePath)
 	logger.Debugf("发送Protobuf POST请求: %s (group=%s, key=%s)",
-		h.baseURL, req.GetGroup(), req.GetKey())
+		h.baseURL, req.GetGroup(), logger.Key(req.GetKey()))
 
 	// 创建HTTP请求
-	httpReq, err := http.NewRequest(http.MethodPost, h.baseURL, bytes.NewReader(body))
+	httpReq, cancel, err := newRequest(ctx, http.MethodPost, h.baseURL, bytes.NewReader(body), h.timeout)
 	if err != nil {
 		return fmt.Errorf("创建请求失败: %v", err)
 	}
+	defer cancel()
 
 	// 设置正确的Content-Type
 	httpReq.Header.Set("Content-Type", "application/protobuf")
 
+	release, err := h.counters.Acquire(ctx, h.limit)
+	if err != nil {
+		return err
+	}
+	defer release()
+
 	// 发送HTTP POST请求
+	call := h.counters.Start(len(body))
 	res, err := h.httpClient.Do(httpReq)
 	if err != nil {
+		call.Fail(err)
 		return fmt.Errorf("发送请求失败: %v", err)
 	}
-	defer res.Body.Close()
+	defer peers.CloseBody(res.Body)
 
 	// 检查响应状态
+	h.version.Observe(res.Header)
+	call.Status(res.StatusCode)
+	if err := errorFromResponse(res); err != nil {
+		// 节点给出了错误码，或状态码含义唯一（限流、组不允许对外读取）
+		return err
+	}
 	if res.StatusCode == http.StatusNotFound {
+		if isNoSuchGroup(res) {
+			return cache.ErrNoSuchGroup
+		}
 		// 返回统一的"键不存在"错误
 		return cache.ErrNotFound
 	} else if res.StatusCode != http.StatusOK {
 		// 读取错误响应内容，以便提供更详细的错误信息
-		errBody, _ := io.ReadAll(res.Body)
-		errMsg := string(errBody)
+		errMsg := peers.ErrorBody(res.Body)
 
 		// 根据错误消息判断错误类型
 		if strings.Contains(errMsg, "key not found") ||
@@ -127,45 +216,68 @@ func (h *HTTPGetter) GetByProto(req *pb.Request, resp *pb.Response) error {
 
 	// 读取响应体
 	respBody, err := io.ReadAll(res.Body)
+	call.Received(len(respBody))
 	if err != nil {
+		call.Fail(err)
 		return fmt.Errorf("读取响应失败: %v", err)
 	}
 
 	// 反序列化响应
 	if err = proto.Unmarshal(respBody, resp); err != nil {
+		call.Fail(err)
 		return fmt.Errorf("反序列化响应失败: %v", err)
 	}
 
 	return nil
 }
 
-// Delete 删除指定组和键的缓存
-func (h *HTTPGetter) Delete(group string, key string) error {
+// Delete 删除指定组和键的缓存，ctx 取消时请求随之中止
+func (h *HTTPGetter) Delete(ctx context.Context, group string, key string) error {
 	// 构建请求URL
-	u := fmt.Sprintf("%v/%v/%v", h.baseURL, url.QueryEscape(group), url.QueryEscape(key))
+	u := keyURL(h.baseURL, group, key)
 
-	logger.Debugf("发送HTTP DELETE请求: %s", u)
+	logger.Debugf("发送HTTP DELETE请求: %s (group=%s, key=%s)",
+		h.baseURL, group, logger.Key(key))
 
 	// 创建DELETE请求
-	req, err := http.NewRequest(http.MethodDelete, u, nil)
+	req, cancel, err := newRequest(ctx, http.MethodDelete, u, nil, h.timeout)
 	if err != nil {
 		return fmt.Errorf("创建DELETE请求失败: %v", err)
 	}
+	defer cancel()
+
+	release, err := h.counters.Acquire(ctx, h.limit)
+	if err != nil {
+		return err
+	}
+	defer release()
 
 	// 发送HTTP请求
+	call := h.counters.Start(0)
 	res, err := h.httpClient.Do(req)
 	if err != nil {
+		call.Fail(err)
 		return fmt.Errorf("发送DELETE请求失败: %v", err)
 	}
-	defer res.Body.Close()
+	defer peers.CloseBody(res.Body)
 
 	// 检查响应状态
+	h.version.Observe(res.Header)
+	call.Status(res.StatusCode)
+	if err := errorFromResponse(res); err != nil {
+		return err
+	}
 	if res.StatusCode == http.StatusNotFound {
-		return fmt.Errorf("key not found: %s", key)
+		if isNoSuchGroup(res) {
+			return cache.ErrNoSuchGroup
+		}
+		return cache.ErrNotFound
+	} else if res.StatusCode == http.StatusServiceUnavailable {
+		// 节点处于只读模式
+		return cache.ErrReadOnly
 	} else if res.StatusCode != http.StatusOK {
 		// 读取错误响应内容
-		errBody, _ := io.ReadAll(res.Body)
-		errMsg := string(errBody)
+		errMsg := peers.ErrorBody(res.Body)
 
 		return fmt.Errorf("服务器返回错误: %v, 详情: %s", res.Status, errMsg)
 	}
@@ -175,20 +287,29 @@ func (h *HTTPGetter) Delete(group string, key string) error {
 
 // ProtoGetter 专用于Protobuf通信的客户端
 type ProtoGetter struct {
-	baseURL    string     // 基础URL
-	httpClient HTTPClient // HTTP客户端
+	baseURL    string          // 基础URL
+	httpClient HTTPClient      // HTTP客户端
+	timeout    time.Duration   // 请求超时
+	counters   *peers.Counters // 请求与错误统计，为 nil 时不记录
+	limit      peers.Limit     // 未完成请求数的上限，与 counters 一起按节点保留
+
+	version peers.PeerVersion // 节点在响应头中声明的协议版本
 }
 
 // NewProtoGetter 创建新的Protobuf客户端
-func NewProtoGetter(baseURL string) *ProtoGetter {
+func NewProtoGetter(baseURL string, opts ...GetterOption) *ProtoGetter {
+	cfg := newGetterConfig(opts...)
 	return &ProtoGetter{
 		baseURL:    baseURL,
-		httpClient: defaultHTTPClient,
+		httpClient: cfg.httpClient(),
+		timeout:    cfg.requestTimeout,
+		counters:   new(peers.Counters),
+		limit:      cfg.limit,
 	}
 }
 
-// Get 通过HTTP获取缓存值
-func (p *ProtoGetter) Get(group, key string) ([]byte, error) {
+// Get 通过Protobuf获取缓存值，ctx 取消时请求随之中止
+func (p *ProtoGetter) Get(ctx context.Context, group, key string) ([]byte, error) {
 	// 构建Protobuf请求
 	req := &pb.Request{
 		Group: group,
@@ -197,15 +318,15 @@ func (p *ProtoGetter) Get(group, key string) ([]byte, error) {
 
 	// 发送Protobuf请求
 	resp := &pb.Response{}
-	if err := p.GetByProto(req, resp); err != nil {
+	if err := p.GetByProto(ctx, req, resp); err != nil {
 		return nil, err
 	}
 
 	return resp.Value, nil
 }
 
-// GetByProto 通过Protobuf获取缓存值
-func (p *ProtoGetter) GetByProto(req *pb.Request, resp *pb.Response) error {
+// GetByProto 通过Protobuf获取缓存值，ctx 取消时请求随之中止
+func (p *ProtoGetter) GetByProto(ctx context.Context, req *pb.Request, resp *pb.Response) error {
 	// 序列化请求
 	body, err := proto.Marshal(req)
 	if err != nil {
@@ -214,32 +335,49 @@ func (p *ProtoGetter) GetByProto(req *pb.Request, resp *pb.Response) error {
 
 	// 使用baseURL作为请求地址
 	logger.Debugf("发送Protobuf请求: %s (group=%s, key=%s)",
-		p.baseURL, req.GetGroup(), req.GetKey())
+		p.baseURL, req.GetGroup(), logger.Key(req.GetKey()))
 
 	// 创建HTTP请求
-	httpReq, err := http.NewRequest(http.MethodPost, p.baseURL, bytes.NewReader(body))
+	httpReq, cancel, err := newRequest(ctx, http.MethodPost, p.baseURL, bytes.NewReader(body), p.timeout)
 	if err != nil {
 		return fmt.Errorf("创建请求失败: %v", err)
 	}
+	defer cancel()
 
 	// 设置正确的Content-Type
 	httpReq.Header.Set("Content-Type", "application/protobuf")
 
+	release, err := p.counters.Acquire(ctx, p.limit)
+	if err != nil {
+		return err
+	}
+	defer release()
+
 	// 发送HTTP POST请求
+	call := p.counters.Start(len(body))
 	res, err := p.httpClient.Do(httpReq)
 	if err != nil {
+		call.Fail(err)
 		return fmt.Errorf("发送请求失败: %v", err)
 	}
-	defer res.Body.Close()
+	defer peers.CloseBody(res.Body)
 
 	// 检查响应状态
+	p.version.Observe(res.Header)
+	call.Status(res.StatusCode)
+	if err := errorFromResponse(res); err != nil {
+		// 节点给出了错误码，或状态码含义唯一（限流、组不允许对外读取）
+		return err
+	}
 	if res.StatusCode == http.StatusNotFound {
+		if isNoSuchGroup(res) {
+			return cache.ErrNoSuchGroup
+		}
 		// 返回统一的"键不存在"错误
 		return cache.ErrNotFound
 	} else if res.StatusCode != http.StatusOK {
 		// 读取错误响应内容，以便提供更详细的错误信息
-		errBody, _ := io.ReadAll(res.Body)
-		errMsg := string(errBody)
+		errMsg := peers.ErrorBody(res.Body)
 
 		// 根据错误消息判断错误类型
 		if strings.Contains(errMsg, "key not found") ||
@@ -262,45 +400,68 @@ func (p *ProtoGetter) GetByProto(req *pb.Request, resp *pb.Response) error {
 
 	// 读取响应体
 	respBody, err := io.ReadAll(res.Body)
+	call.Received(len(respBody))
 	if err != nil {
+		call.Fail(err)
 		return fmt.Errorf("读取响应失败: %v", err)
 	}
 
 	// 反序列化响应
 	if err = proto.Unmarshal(respBody, resp); err != nil {
+		call.Fail(err)
 		return fmt.Errorf("反序列化响应失败: %v", err)
 	}
 
 	return nil
 }
 
-// Delete 删除指定组和键的缓存
-func (p *ProtoGetter) Delete(group string, key string) error {
+// Delete 删除指定组和键的缓存，ctx 取消时请求随之中止
+func (p *ProtoGetter) Delete(ctx context.Context, group string, key string) error {
 	// 构建删除URL
-	u := fmt.Sprintf("%v/%v/%v", p.baseURL, url.QueryEscape(group), url.QueryEscape(key))
+	u := keyURL(p.baseURL, group, key)
 
-	logger.Debugf("发送Protobuf DELETE请求: %s", u)
+	logger.Debugf("发送Protobuf DELETE请求: %s (group=%s, key=%s)",
+		p.baseURL, group, logger.Key(key))
 
 	// 创建DELETE请求
-	req, err := http.NewRequest(http.MethodDelete, u, nil)
+	req, cancel, err := newRequest(ctx, http.MethodDelete, u, nil, p.timeout)
 	if err != nil {
 		return fmt.Errorf("创建DELETE请求失败: %v", err)
 	}
+	defer cancel()
+
+	release, err := p.counters.Acquire(ctx, p.limit)
+	if err != nil {
+		return err
+	}
+	defer release()
 
 	// 发送HTTP请求
+	call := p.counters.Start(0)
 	res, err := p.httpClient.Do(req)
 	if err != nil {
+		call.Fail(err)
 		return fmt.Errorf("发送DELETE请求失败: %v", err)
 	}
-	defer res.Body.Close()
+	defer peers.CloseBody(res.Body)
 
 	// 检查响应状态
+	p.version.Observe(res.Header)
+	call.Status(res.StatusCode)
+	if err := errorFromResponse(res); err != nil {
+		return err
+	}
 	if res.StatusCode == http.StatusNotFound {
+		if isNoSuchGroup(res) {
+			return cache.ErrNoSuchGroup
+		}
 		return cache.ErrNotFound
+	} else if res.StatusCode == http.StatusServiceUnavailable {
+		// 节点处于只读模式
+		return cache.ErrReadOnly
 	} else if res.StatusCode != http.StatusOK {
 		// 读取错误响应内容
-		errBody, _ := io.ReadAll(res.Body)
-		errMsg := string(errBody)
+		errMsg := peers.ErrorBody(res.Body)
 
 		// 根据错误消息判断错误类型
 		if strings.Contains(errMsg, "key not found") ||
@@ -318,3 +479,158 @@ func (p *ProtoGetter) Delete(group string, key string) error {
 
 	return nil
 }
+
+// Stats 通过Protobuf获取节点统计信息
+func (h *HTTPGetter) Stats(ctx context.Context) (*pb.StatsResponse, error) {
+	return fetchStats(ctx, h.httpClient, h.baseURL, h.timeout, &h.version)
+}
+
+// Stats 通过Protobuf获取节点统计信息
+func (p *ProtoGetter) Stats(ctx context.Context) (*pb.StatsResponse, error) {
+	return fetchStats(ctx, p.httpClient, p.baseURL, p.timeout, &p.version)
+}
+
+// protoVersion 返回记录节点协议版本的位置
+func (h *HTTPGetter) protoVersion() *peers.PeerVersion {
+	return &h.version
+}
+
+// protoVersion 返回记录节点协议版本的位置
+func (p *ProtoGetter) protoVersion() *peers.PeerVersion {
+	return &p.version
+}
+
+// setCounters 设置记录请求与错误统计的计数器
+func (h *HTTPGetter) setCounters(c *peers.Counters) {
+	h.counters = c
+}
+
+// setCounters 设置记录请求与错误统计的计数器
+func (p *ProtoGetter) setCounters(c *peers.Counters) {
+	p.counters = c
+}
+
+// fetchStats 向节点 HTTPPool 的统计路由发送 StatsRequest。已知节点的协议版本不支持时直接返回
+// ErrStatsUnimplemented，不发送请求
+func fetchStats(ctx context.Context, client HTTPClient, baseURL string, timeout time.Duration, version *peers.PeerVersion) (*pb.StatsResponse, error) {
+	if err := version.Check(peers.FeatureStats); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrStatsUnimplemented, err)
+	}
+	body, err := proto.Marshal(&pb.StatsRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("序列化请求失败: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	u := strings.TrimSuffix(baseURL, "/") + "/" + statsPath
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/protobuf")
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("发送请求失败: %v", err)
+	}
+	defer peers.CloseBody(res.Body)
+
+	version.Observe(res.Header)
+	switch res.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusNotImplemented, http.StatusBadRequest:
+		// 旧版本节点没有统计路由，会把请求当作普通的缓存请求处理
+		return nil, fmt.Errorf("%w: 节点返回 %s", ErrStatsUnimplemented, res.Status)
+	default:
+		return nil, fmt.Errorf("服务器返回错误: %v", res.Status)
+	}
+
+	respBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取响应失败: %v", err)
+	}
+
+	resp := &pb.StatsResponse{}
+	if err := proto.Unmarshal(respBody, resp); err != nil {
+		return nil, fmt.Errorf("反序列化响应失败: %v", err)
+	}
+	return resp, nil
+}
+
+// setPath 节点 HTTPPool 上写入路由相对于 basePath 的路径
+const setPath = "_set"
+
+// Set 通过节点 HTTPPool 的写入路由把值写入节点自身的缓存
+func (h *HTTPGetter) Set(ctx context.Context, req *pb.SetRequest) error {
+	return setHTTP(ctx, h.httpClient, h.baseURL, h.timeout, h.counters, h.limit, &h.version, req)
+}
+
+// Set 通过节点 HTTPPool 的写入路由把值写入节点自身的缓存
+func (p *ProtoGetter) Set(ctx context.Context, req *pb.SetRequest) error {
+	return setHTTP(ctx, p.httpClient, p.baseURL, p.timeout, p.counters, p.limit, &p.version, req)
+}
+
+// setHTTP 向节点 HTTPPool 的写入路由发送 SetRequest，节点只写入自己的缓存，不转发给归属节点。
+// 已知节点的协议版本不支持时直接返回 peers.ErrUnsupported，不发送请求
+func setHTTP(ctx context.Context, client HTTPClient, baseURL string, timeout time.Duration,
+	counters *peers.Counters, limit peers.Limit, version *peers.PeerVersion, req *pb.SetRequest) error {
+	if err := version.Check(peers.FeatureSet); err != nil {
+		return err
+	}
+	body, err := proto.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("序列化请求失败: %v", err)
+	}
+
+	u := strings.TrimSuffix(baseURL, "/") + "/" + setPath
+	httpReq, cancel, err := newRequest(ctx, http.MethodPut, u, bytes.NewReader(body), timeout)
+	if err != nil {
+		return fmt.Errorf("创建请求失败: %v", err)
+	}
+	defer cancel()
+	httpReq.Header.Set("Content-Type", "application/protobuf")
+
+	release, err := counters.Acquire(ctx, limit)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	call := counters.Start(len(body))
+	res, err := client.Do(httpReq)
+	if err != nil {
+		call.Fail(err)
+		return fmt.Errorf("发送请求失败: %v", err)
+	}
+	defer peers.CloseBody(res.Body)
+
+	version.Observe(res.Header)
+	call.Status(res.StatusCode)
+	if res.StatusCode == http.StatusMethodNotAllowed && version.Get() == peers.ProtocolUnknown {
+		// 旧版本节点没有写入路由
+		return peers.Unsupported(peers.FeatureSet, peers.ProtocolUnknown)
+	}
+	if err := errorFromResponse(res); err != nil {
+		return err
+	}
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("服务器返回错误: %v: %s", res.Status, peers.ErrorBody(res.Body))
+	}
+	return nil
+}
+
+// errorFromResponse 按节点返回的错误码，或含义唯一的状态码，把错误响应映射为预定义错误，
+// 见 cacheerrors.ErrorFromHTTP；限流错误保留节点给出的 Retry-After。
+// 无法映射时返回 nil，由调用方按旧版本节点的状态码和响应内容判断
+func errorFromResponse(res *http.Response) error {
+	err := cacheerrors.ErrorFromHTTP(res.StatusCode, res.Header.Get(peers.HeaderErrorCode))
+	return cacheerrors.WithRetryAfter(err, res.Header.Get(cacheerrors.HeaderRetryAfter))
+}
+
+// isNoSuchGroup 判断 404 响应是否表示节点上没有该组：节点对组不存在和键不存在都返回 404，
+// 只能通过响应内容区分
+func isNoSuchGroup(res *http.Response) bool {
+	return strings.Contains(peers.ErrorBody(res.Body), "no such group")
+}
@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/internal/cache"
+	"github.com/AdrianWangs/go-cache/internal/peers"
+	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
+)
+
+// valueGetter 返回 values 中共享的值，版本和过期时间按节点不同，与各节点各自加载的副本一致
+type valueGetter struct {
+	stubGetter
+	values *sharedValues
+	node   uint64 // 节点序号，用于区分各节点上的版本
+}
+
+// sharedValues 所有节点看到的同一份值
+type sharedValues struct {
+	mu     sync.Mutex
+	values map[string]string
+}
+
+func (v *sharedValues) set(key, value string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.values[key] = value
+}
+
+func (g *valueGetter) GetByProto(ctx context.Context, req *pb.Request, resp *pb.Response) error {
+	g.calls.Add(1)
+	g.values.mu.Lock()
+	value, ok := g.values.values[req.Key]
+	g.values.mu.Unlock()
+	if !ok {
+		return cache.ErrNotFound
+	}
+	resp.Value = []byte(value)
+	version := g.node<<32 | uint64(g.calls.Load())
+	resp.Version = &version
+	expiresAt := time.Now().Add(time.Duration(g.node) * time.Minute).UnixNano()
+	resp.ExpiresAt = &expiresAt
+	return nil
+}
+
+// valueFactory 为每个节点创建读取 values 的 valueGetter
+type valueFactory struct {
+	values *sharedValues
+	nodes  atomic.Uint64
+}
+
+func (f *valueFactory) NewGetter(protocol ProtocolType, addr string) NodeGetter {
+	return &valueGetter{stubGetter: stubGetter{protocol: protocol, addr: addr}, values: f.values, node: f.nodes.Add(1)}
+}
+
+// conditionalGet 带上 headers 读取 /api/cache/scores/{key}
+func conditionalGet(h *CacheHandler, key string, headers map[string]string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, "/api/cache/scores/"+key, nil)
+	for name, v := range headers {
+		r.Header.Set(name, v)
+	}
+	w := httptest.NewRecorder()
+	h.GetCacheHandler(w, r)
+	return w
+}
+
+func newConditionalHandler(values map[string]string) (*CacheHandler, *sharedValues) {
+	shared := &sharedValues{values: values}
+	h := NewCacheHandler("/_gocache/", 50, CacheHandlerOptions{Getters: &valueFactory{values: shared}})
+	h.UpdatePeers(nodesWithGroups(3, "scores"))
+	return h, shared
+}
+
+func TestConditionalGet(t *testing.T) {
+	h, _ := newConditionalHandler(map[string]string{"Tom": "630"})
+	etag := peers.ValueETag([]byte("630"))
+	stale := peers.ValueETag([]byte("629"))
+	tests := []struct {
+		name    string
+		headers map[string]string
+		status  int
+		body    string
+	}{
+		{"没有校验器", nil, http.StatusOK, "630"},
+		{"校验器匹配", map[string]string{"If-None-Match": etag}, http.StatusNotModified, ""},
+		{"弱校验器匹配", map[string]string{"If-None-Match": "W/" + etag}, http.StatusNotModified, ""},
+		{"列表中有匹配的校验器", map[string]string{"If-None-Match": stale + ", " + etag}, http.StatusNotModified, ""},
+		{"通配符", map[string]string{"If-None-Match": "*"}, http.StatusNotModified, ""},
+		{"校验器不匹配", map[string]string{"If-None-Match": stale}, http.StatusOK, "630"},
+		{"范围读取", map[string]string{"Range": "bytes=1-2"}, http.StatusPartialContent, "30"},
+		{"If-Range 仍是当前值", map[string]string{"Range": "bytes=1-2", "If-Range": etag}, http.StatusPartialContent, "30"},
+		{"If-Range 已过期", map[string]string{"Range": "bytes=1-2", "If-Range": stale}, http.StatusOK, "630"},
+	}
+	var notModified int64
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := conditionalGet(h, "Tom", tt.headers)
+			if w.Code != tt.status || w.Body.String() != tt.body {
+				t.Fatalf("响应 = %d %q, want %d %q", w.Code, w.Body.String(), tt.status, tt.body)
+			}
+			if got := w.Header().Get("ETag"); got != etag {
+				t.Fatalf("ETag = %q, want %q", got, etag)
+			}
+			if w.Header().Get(peers.HeaderVersion) == "" {
+				t.Fatal("304 和 200 响应都应带上元数据响应头")
+			}
+			if tt.status == http.StatusNotModified {
+				notModified++
+			}
+			if got := h.NotModified(); got != notModified {
+				t.Fatalf("NotModified = %d, want %d", got, notModified)
+			}
+		})
+	}
+
+	// 不存在的 key 没有 ETag，校验器不会使其返回 304
+	w := conditionalGet(h, "missing", map[string]string{"If-None-Match": "*"})
+	if w.Code != http.StatusNotFound || w.Header().Get("ETag") != "" {
+		t.Fatalf("不存在的 key = %d, ETag %q", w.Code, w.Header().Get("ETag"))
+	}
+}
+
+// TestConditionalGetAfterChange 客户端先得到 304，值改变后用旧的 ETag 再次校验得到新值和新的 ETag
+func TestConditionalGetAfterChange(t *testing.T) {
+	h, values := newConditionalHandler(map[string]string{"Tom": "630"})
+	w := conditionalGet(h, "Tom", nil)
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || etag == "" {
+		t.Fatalf("首次读取 = %d, ETag %q", w.Code, etag)
+	}
+	if w := conditionalGet(h, "Tom", map[string]string{"If-None-Match": etag}); w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Fatalf("值未改变时的校验 = %d %q", w.Code, w.Body.String())
+	}
+
+	values.set("Tom", "631")
+	w = conditionalGet(h, "Tom", map[string]string{"If-None-Match": etag})
+	if w.Code != http.StatusOK || w.Body.String() != "631" {
+		t.Fatalf("值改变后的校验 = %d %q", w.Code, w.Body.String())
+	}
+	newETag := w.Header().Get("ETag")
+	if newETag == etag || newETag != peers.ValueETag([]byte("631")) {
+		t.Fatalf("值改变后的 ETag = %q, 旧的 %q", newETag, etag)
+	}
+	if w := conditionalGet(h, "Tom", map[string]string{"If-None-Match": newETag}); w.Code != http.StatusNotModified {
+		t.Fatalf("新的 ETag 校验 = %d", w.Code)
+	}
+	if got := h.NotModified(); got != 2 {
+		t.Fatalf("NotModified = %d, want 2", got)
+	}
+}
+
+// TestConditionalGetJSON JSON 响应使用弱 ETag，与原始字节的 ETag 不同，校验规则相同
+func TestConditionalGetJSON(t *testing.T) {
+	h, values := newConditionalHandler(map[string]string{"Tom": "630"})
+	accept := map[string]string{"Accept": "application/json"}
+	w := conditionalGet(h, "Tom", accept)
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || etag != `W/"`+peers.ValueDigest([]byte("630"))+`-json"` {
+		t.Fatalf("JSON 读取 = %d, ETag %q", w.Code, etag)
+	}
+	if raw := peers.ValueETag([]byte("630")); peers.ETagMatches(raw, etag) {
+		t.Fatal("JSON 与原始字节的 ETag 不应匹配")
+	}
+
+	// 剩余有效期等字段随读取变化，ETag 不变
+	w = conditionalGet(h, "Tom", map[string]string{"Accept": "application/json", "If-None-Match": etag})
+	if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Fatalf("JSON 校验 = %d %q", w.Code, w.Body.String())
+	}
+
+	values.set("Tom", "631")
+	w = conditionalGet(h, "Tom", map[string]string{"Accept": "application/json", "If-None-Match": etag})
+	if w.Code != http.StatusOK || w.Header().Get("ETag") == etag {
+		t.Fatalf("值改变后的 JSON 校验 = %d, ETag %q", w.Code, w.Header().Get("ETag"))
+	}
+}
+
+// TestETagSameAcrossNodes 不同节点持有的同一个值版本和过期时间不同，ETag 相同
+func TestETagSameAcrossNodes(t *testing.T) {
+	shared := &sharedValues{values: map[string]string{"Tom": "630"}}
+	factory := &valueFactory{values: shared}
+	ctx := context.Background()
+	var etag string
+	versions := make(map[uint64]bool)
+	for _, node := range nodesWithGroups(3, "scores") {
+		res := &pb.Response{}
+		g := factory.NewGetter(ProtocolGRPC, node.GRPCAddr)
+		if err := g.GetByProto(ctx, &pb.Request{Group: "scores", Key: "Tom"}, res); err != nil {
+			t.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		peers.ServeValue(w, httptest.NewRequest(http.MethodGet, "/api/cache/scores/Tom", nil), res.Value)
+		if etag == "" {
+			etag = w.Header().Get("ETag")
+		} else if got := w.Header().Get("ETag"); got != etag {
+			t.Fatalf("节点 %s 的 ETag = %q, want %q", node.GRPCAddr, got, etag)
+		}
+		versions[res.GetVersion()] = true
+	}
+	if len(versions) != 3 {
+		t.Fatalf("各节点的版本应不同: %v", versions)
+	}
+}
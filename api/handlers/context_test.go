@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/AdrianWangs/go-cache/internal/discovery"
+	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
+)
+
+// blockingNode 是只在请求的 ctx 结束时返回的 gRPC 节点，
+// started 在收到请求时通知，cancelled 在节点看到 ctx.Done() 时通知
+type blockingNode struct {
+	pb.UnimplementedGroupCacheServer
+	started   chan struct{}
+	cancelled chan struct{}
+}
+
+func (n *blockingNode) block(ctx context.Context) error {
+	n.started <- struct{}{}
+	<-ctx.Done()
+	n.cancelled <- struct{}{}
+	return ctx.Err()
+}
+
+func (n *blockingNode) Get(ctx context.Context, req *pb.Request) (*pb.Response, error) {
+	return nil, n.block(ctx)
+}
+
+func (n *blockingNode) Delete(ctx context.Context, req *pb.DeleteRequest) (*pb.DeleteResponse, error) {
+	return nil, n.block(ctx)
+}
+
+// startBlockingNode 在随机端口上启动 blockingNode，返回节点和地址
+func startBlockingNode(t *testing.T) (*blockingNode, string) {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	node := &blockingNode{started: make(chan struct{}, 1), cancelled: make(chan struct{}, 1)}
+	srv := grpc.NewServer()
+	pb.RegisterGroupCacheServer(srv, node)
+	go srv.Serve(l)
+	t.Cleanup(srv.Stop)
+	return node, l.Addr().String()
+}
+
+// TestClientDisconnectCancelsNodeCall 客户端断开连接后，发往节点的 gRPC 调用随之取消
+func TestClientDisconnectCancelsNodeCall(t *testing.T) {
+	node, addr := startBlockingNode(t)
+	h := NewCacheHandler("/_gocache/", 50, CacheHandlerOptions{
+		GetterOptions: []GetterOption{WithRequestTimeout(time.Minute)},
+	})
+	h.UpdatePeers([]discovery.NodeInfo{{ID: "node-1", GRPCAddr: addr, Protocols: []string{discovery.ProtocolGRPC}}})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/cache/", h.GetCacheHandler)
+	mux.HandleFunc("DELETE /api/cache/", h.DeleteCacheHandler)
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	for _, method := range []string{http.MethodGet, http.MethodDelete} {
+		t.Run(method, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			req, _ := http.NewRequestWithContext(ctx, method, srv.URL+"/api/cache/scores/Tom", nil)
+			done := make(chan error, 1)
+			go func() {
+				resp, err := http.DefaultClient.Do(req)
+				if err == nil {
+					resp.Body.Close()
+				}
+				done <- err
+			}()
+
+			select {
+			case <-node.started:
+			case <-time.After(5 * time.Second):
+				t.Fatal("请求没有到达节点")
+			}
+			// 取消请求会关闭客户端连接
+			cancel()
+			select {
+			case <-node.cancelled:
+			case <-time.After(5 * time.Second):
+				t.Fatal("客户端断开后节点没有看到 ctx.Done()")
+			}
+			if err := <-done; err == nil {
+				t.Fatal("取消的请求返回了响应")
+			}
+		})
+	}
+}
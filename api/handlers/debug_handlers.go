@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/AdrianWangs/go-cache/internal/access"
+	"github.com/AdrianWangs/go-cache/pkg/logger"
+)
+
+// samplePathPrefix 抽样接口的路径前缀，API 服务器与缓存节点相同
+const samplePathPrefix = "/api/debug/sample/"
+
+// SampleHandler 处理 GET /api/debug/sample/{group}?node={节点标识}&n=20[&values=1&maxlen=256]，
+// 将请求转发给指定节点的 HTTP 服务器，由节点随机抽取组内的条目。
+// 请求的 Authorization 头原样转发，节点使用自己的管理令牌校验
+func (h *AdminHandler) SampleHandler(w http.ResponseWriter, r *http.Request) {
+	escapedGroup := strings.TrimPrefix(r.URL.EscapedPath(), samplePathPrefix)
+	group, err := url.PathUnescape(escapedGroup)
+	if err != nil || escapedGroup == "" || strings.Contains(escapedGroup, "/") {
+		if h.authorize(w, r, access.AllGroups) {
+			http.Error(w, "Bad Request: expected /api/debug/sample/{group}", http.StatusBadRequest)
+		}
+		return
+	}
+	if !h.authorize(w, r, group) {
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	node := query.Get("node")
+	if node == "" {
+		http.Error(w, "Bad Request: node is required, see /api/nodes for node keys", http.StatusBadRequest)
+		return
+	}
+	info, ok := h.cacheHandler.nodeInfo(node)
+	if !ok {
+		http.Error(w, fmt.Sprintf("Node not found: %s", node), http.StatusNotFound)
+		return
+	}
+	if info.HTTPAddr == "" {
+		http.Error(w, fmt.Sprintf("Bad Gateway: node %s did not register an HTTP address", node), http.StatusBadGateway)
+		return
+	}
+	query.Del("node")
+
+	u := fmt.Sprintf("http://%s%s%s?%s", info.HTTPAddr, samplePathPrefix, escapedGroup, query.Encode())
+	cfg := newGetterConfig(h.cacheHandler.getterOpts...)
+	req, cancel, err := newRequest(r.Context(), http.MethodGet, u, nil, cfg.requestTimeout)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Bad Request: %v", err), http.StatusBadRequest)
+		return
+	}
+	defer cancel()
+	req.Header.Set("Authorization", r.Header.Get("Authorization"))
+
+	res, err := defaultHTTPClient.Do(req)
+	if err != nil {
+		logger.Warnf("转发抽样请求到节点 %s 失败: %v", node, err)
+		http.Error(w, fmt.Sprintf("Bad Gateway: %v", err), http.StatusBadGateway)
+		return
+	}
+	defer res.Body.Close()
+
+	for _, header := range []string{"Content-Type", "WWW-Authenticate"} {
+		if v := res.Header.Get(header); v != "" {
+			w.Header().Set(header, v)
+		}
+	}
+	w.WriteHeader(res.StatusCode)
+	if _, err := io.Copy(w, res.Body); err != nil {
+		logger.Warnf("转发节点 %s 的抽样响应中断: %v", node, err)
+	}
+}
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/internal/cache"
+	"github.com/AdrianWangs/go-cache/internal/peers"
+	"github.com/AdrianWangs/go-cache/pkg/cacheerrors"
+	"github.com/AdrianWangs/go-cache/pkg/logger"
+	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// deleteBatchPath 节点 HTTPPool 上批量删除路由相对于 basePath 的路径
+const deleteBatchPath = "_delete_batch"
+
+// ErrDeleteBatchUnimplemented 表示节点版本过旧，不支持 DeleteBatch 调用，调用方应逐个删除
+var ErrDeleteBatchUnimplemented = errors.New("delete batch not implemented by node")
+
+// DeleteBatch 通过节点 HTTPPool 的批量删除路由删除 keys
+func (h *HTTPGetter) DeleteBatch(ctx context.Context, group string, keys []string) ([]cache.DeleteResult, error) {
+	return deleteBatchHTTP(ctx, h.httpClient, h.baseURL, h.timeout, h.counters, h.limit, &h.version, group, keys)
+}
+
+// DeleteBatch 通过节点 HTTPPool 的批量删除路由删除 keys
+func (p *ProtoGetter) DeleteBatch(ctx context.Context, group string, keys []string) ([]cache.DeleteResult, error) {
+	return deleteBatchHTTP(ctx, p.httpClient, p.baseURL, p.timeout, p.counters, p.limit, &p.version, group, keys)
+}
+
+// deleteBatchHTTP 向节点 HTTPPool 的批量删除路由发送 DeleteBatchRequest。
+// 使用 DELETE 方法：旧版本节点会按普通删除解析路径并返回 400，不会产生副作用。
+// 已知节点的协议版本不支持时直接返回 ErrDeleteBatchUnimplemented，不发送请求
+func deleteBatchHTTP(ctx context.Context, client HTTPClient, baseURL string, timeout time.Duration,
+	counters *peers.Counters, limit peers.Limit, version *peers.PeerVersion, group string, keys []string) ([]cache.DeleteResult, error) {
+	if err := version.Check(peers.FeatureDeleteBatch); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDeleteBatchUnimplemented, err)
+	}
+	body, err := proto.Marshal(&pb.DeleteBatchRequest{Group: group, Keys: keys})
+	if err != nil {
+		return nil, fmt.Errorf("序列化请求失败: %v", err)
+	}
+
+	u := strings.TrimSuffix(baseURL, "/") + "/" + deleteBatchPath
+	logger.Debugf("发送批量DELETE请求: %s (group=%s, keys=%d)", u, group, len(keys))
+
+	req, cancel, err := newRequest(ctx, http.MethodDelete, u, bytes.NewReader(body), timeout)
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %v", err)
+	}
+	defer cancel()
+	req.Header.Set("Content-Type", "application/protobuf")
+
+	release, err := counters.Acquire(ctx, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	call := counters.Start(len(body))
+	res, err := client.Do(req)
+	if err != nil {
+		call.Fail(err)
+		return nil, fmt.Errorf("发送请求失败: %v", err)
+	}
+	defer peers.CloseBody(res.Body)
+
+	version.Observe(res.Header)
+	call.Status(res.StatusCode)
+	if err := errorFromResponse(res); err != nil {
+		return nil, err
+	}
+	switch res.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		if isNoSuchGroup(res) {
+			return nil, cache.ErrNoSuchGroup
+		}
+		return nil, fmt.Errorf("%w: 节点返回 %s", ErrDeleteBatchUnimplemented, res.Status)
+	case http.StatusBadRequest, http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		// 旧版本节点没有批量删除路由，会把路径当作 /<group>/<key> 解析失败
+		return nil, fmt.Errorf("%w: 节点返回 %s", ErrDeleteBatchUnimplemented, res.Status)
+	case http.StatusServiceUnavailable:
+		// 节点处于只读模式
+		return nil, cache.ErrReadOnly
+	default:
+		return nil, fmt.Errorf("服务器返回错误: %v, 详情: %s", res.Status, peers.ErrorBody(res.Body))
+	}
+
+	respBody, err := io.ReadAll(res.Body)
+	call.Received(len(respBody))
+	if err != nil {
+		call.Fail(err)
+		return nil, fmt.Errorf("读取响应失败: %v", err)
+	}
+	resp := &pb.DeleteBatchResponse{}
+	if err := proto.Unmarshal(respBody, resp); err != nil {
+		call.Fail(err)
+		return nil, fmt.Errorf("反序列化响应失败: %v", err)
+	}
+	return checkDeleteResults(keys, resp)
+}
+
+// DeleteBatch 通过gRPC的DeleteBatch方法删除 keys，旧版本节点返回 ErrDeleteBatchUnimplemented
+func (g *GRPCGetter) DeleteBatch(ctx context.Context, group string, keys []string) ([]cache.DeleteResult, error) {
+	if err := g.version.Check(peers.FeatureDeleteBatch); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDeleteBatchUnimplemented, err)
+	}
+	req := &pb.DeleteBatchRequest{Group: group, Keys: keys}
+	release, err := g.counters.Acquire(ctx, g.limit)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	call := g.counters.Start(proto.Size(req))
+
+	// 确保连接已建立
+	if err := g.ensureConnection(); err != nil {
+		call.FailClass(peers.ErrConnRefused)
+		return nil, err
+	}
+
+	// 在调用方上下文的基础上应用请求超时
+	ctx, cancel := context.WithTimeout(ctx, g.timeout)
+	defer cancel()
+
+	resp, err := g.client.DeleteBatch(ctx, req)
+	if cacheErr := cacheerrors.ErrorFromGRPC(err); cacheErr != nil {
+		call.Fail(err)
+		return nil, cacheErr
+	}
+	switch status.Code(err) {
+	case codes.OK:
+	case codes.Unimplemented:
+		return nil, fmt.Errorf("%w: %v", ErrDeleteBatchUnimplemented, err)
+	case codes.NotFound:
+		// 早于统一错误映射的节点组不存在时的状态信息不是 ErrNoSuchGroup 的信息
+		return nil, cache.ErrNoSuchGroup
+	case codes.FailedPrecondition:
+		call.Fail(err)
+		return nil, cache.ErrReadOnly
+	default:
+		call.Fail(err)
+		return nil, err
+	}
+	call.Received(proto.Size(resp))
+	return checkDeleteResults(keys, resp)
+}
+
+// checkDeleteResults 校验节点返回的结果与请求的键一一对应
+func checkDeleteResults(keys []string, resp *pb.DeleteBatchResponse) ([]cache.DeleteResult, error) {
+	results := cache.DeleteResultsFromProto(resp)
+	if len(results) != len(keys) {
+		return nil, fmt.Errorf("节点返回 %d 个结果，请求了 %d 个键", len(results), len(keys))
+	}
+	for i, r := range results {
+		if r.Key != keys[i] {
+			return nil, fmt.Errorf("节点返回的第 %d 个结果对应键 %q，期望 %q", i, r.Key, keys[i])
+		}
+	}
+	return results, nil
+}
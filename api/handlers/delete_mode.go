@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/internal/consistenthash"
+)
+
+// DeleteMode 单个 key 的删除请求发往哪些节点
+type DeleteMode string
+
+const (
+	// DeleteOwner 只发往当前哈希环上的归属节点（默认）
+	DeleteOwner DeleteMode = "owner"
+	// DeleteOwnerPrevious 哈希环在 DeleteConfig.Grace 内变化过时，同时发往上一代环上的归属节点，
+	// 新节点加入后仍持有旧值的原归属节点不会继续提供它
+	DeleteOwnerPrevious DeleteMode = "owner+previous"
+	// DeleteBroadcast 发往所有节点，适合更看重正确性而不在意开销的小集群
+	DeleteBroadcast DeleteMode = "broadcast"
+)
+
+// defaultPreviousRingGrace 未指定时，哈希环变化后保留上一代环的时长
+const defaultPreviousRingGrace = 30 * time.Second
+
+// ParseDeleteMode 解析删除方式，空字符串表示默认的 owner
+func ParseDeleteMode(s string) (DeleteMode, error) {
+	switch mode := DeleteMode(strings.ToLower(strings.TrimSpace(s))); mode {
+	case "":
+		return DeleteOwner, nil
+	case DeleteOwner, DeleteOwnerPrevious, DeleteBroadcast:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("未知的删除方式 %q，只能是 owner、owner+previous 或 broadcast", s)
+	}
+}
+
+// DeleteConfig 单个 key 删除的配置
+type DeleteConfig struct {
+	Mode  DeleteMode    // 删除发往的节点，默认 owner
+	Grace time.Duration // owner+previous 下哈希环变化后仍向上一代环的归属节点删除的时长，<=0 时使用默认值 30s
+}
+
+// previousRing 哈希环成员变化之前的一代环
+type previousRing struct {
+	ring  *consistenthash.Map
+	until time.Time // 超过该时间后不再使用
+}
+
+// rememberPreviousRing 在节点成员变化时保存旧的哈希环，只在 owner+previous 下保存。调用方持有写锁
+func (h *CacheHandler) rememberPreviousRing(old *consistenthash.Map, oldNodes []string, newNodes []string) {
+	if h.deleteConfig.Mode != DeleteOwnerPrevious || len(oldNodes) == 0 {
+		return
+	}
+	slices.Sort(oldNodes)
+	slices.Sort(newNodes)
+	if slices.Equal(oldNodes, newNodes) {
+		return
+	}
+	h.prevRing = previousRing{ring: old, until: time.Now().Add(h.deleteConfig.Grace)}
+}
+
+// deleteTargets 按删除方式返回删除 key 时要访问的节点及其 getter，第一个为当前的归属节点
+func (h *CacheHandler) deleteTargets(key string) ([]string, []NodeGetter) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	owner := h.ring.Get(key)
+	getter, ok := h.nodeGetters[owner]
+	if !ok {
+		return nil, nil
+	}
+	nodes, getters := []string{owner}, []NodeGetter{getter}
+
+	switch h.deleteConfig.Mode {
+	case DeleteOwnerPrevious:
+		if h.prevRing.ring == nil || !time.Now().Before(h.prevRing.until) {
+			break
+		}
+		// 上一代环的归属节点已离开集群时无法访问，它也不再被路由到
+		if prev := h.prevRing.ring.Get(key); prev != owner {
+			if g, ok := h.nodeGetters[prev]; ok {
+				nodes, getters = append(nodes, prev), append(getters, g)
+			}
+		}
+	case DeleteBroadcast:
+		others := make([]string, 0, len(h.nodeGetters))
+		for node := range h.nodeGetters {
+			if node != owner {
+				others = append(others, node)
+			}
+		}
+		slices.Sort(others)
+		for _, node := range others {
+			nodes, getters = append(nodes, node), append(getters, h.nodeGetters[node])
+		}
+	}
+	return nodes, getters
+}
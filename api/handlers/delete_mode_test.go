@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestParseDeleteMode(t *testing.T) {
+	tests := []struct {
+		in   string
+		want DeleteMode // 为空时期望解析失败
+	}{
+		{"", DeleteOwner},
+		{"owner", DeleteOwner},
+		{" Owner+Previous ", DeleteOwnerPrevious},
+		{"BROADCAST", DeleteBroadcast},
+		{"all", ""},
+	}
+	for _, tt := range tests {
+		got, err := ParseDeleteMode(tt.in)
+		if got != tt.want || (err != nil) != (tt.want == "") {
+			t.Errorf("ParseDeleteMode(%q) = %q, %v; want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+// TestDeleteTargetsPreviousRing owner+previous 只在成员变化后的 Grace 内同时删除上一代环的归属节点，
+// 成员不变的更新不替换上一代环
+func TestDeleteTargetsPreviousRing(t *testing.T) {
+	const grace = 200 * time.Millisecond
+	h := NewCacheHandler("/_go_cache/", 50, CacheHandlerOptions{
+		Getters: &recordingFactory{},
+		Delete:  DeleteConfig{Mode: DeleteOwnerPrevious, Grace: grace},
+	})
+	h.UpdatePeers(nodesWithGroups(3))
+	if nodes, _ := h.deleteTargets("k"); len(nodes) != 1 {
+		t.Fatalf("首次加入节点后删除发往 %v, want 只有归属节点", nodes)
+	}
+
+	before := make(map[string]string)
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		before[key] = h.ring.Get(key)
+	}
+	h.UpdatePeers(nodesWithGroups(4))
+	h.UpdatePeers(nodesWithGroups(4)) // 成员不变
+
+	moved := 0
+	for key, prev := range before {
+		nodes, getters := h.deleteTargets(key)
+		owner := h.ring.Get(key)
+		switch {
+		case owner == prev && len(nodes) != 1:
+			t.Fatalf("归属未变的 %s 删除发往 %v", key, nodes)
+		case owner != prev && (len(nodes) != 2 || nodes[0] != owner || nodes[1] != prev || len(getters) != 2):
+			t.Fatalf("归属从 %s 变为 %s 的 %s 删除发往 %v", prev, owner, key, nodes)
+		case owner != prev:
+			moved++
+		}
+	}
+	if moved == 0 {
+		t.Fatal("加入节点后没有 key 改变归属")
+	}
+
+	time.Sleep(grace)
+	for key := range before {
+		if nodes, _ := h.deleteTargets(key); len(nodes) != 1 {
+			t.Fatalf("Grace 之后 %s 删除发往 %v", key, nodes)
+		}
+	}
+}
+
+func TestDeleteTargetsBroadcast(t *testing.T) {
+	h := NewCacheHandler("/_go_cache/", 50, CacheHandlerOptions{
+		Getters: &recordingFactory{},
+		Delete:  DeleteConfig{Mode: DeleteBroadcast},
+	})
+	h.UpdatePeers(nodesWithGroups(5))
+	nodes, getters := h.deleteTargets("k")
+	if len(nodes) != 5 || len(getters) != 5 || nodes[0] != h.ring.Get("k") {
+		t.Fatalf("删除发往 %v, want 归属节点 %s 在前的全部 5 个节点", nodes, h.ring.Get("k"))
+	}
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/AdrianWangs/go-cache/internal/cache"
+	"github.com/AdrianWangs/go-cache/internal/deletequeue"
+	"github.com/AdrianWangs/go-cache/pkg/cacheerrors"
+	"github.com/AdrianWangs/go-cache/pkg/logger"
+)
+
+// EnableDeleteRetry 开启删除重试：删除请求发往归属节点失败（节点不可达、超时等）时，
+// 删除进入重试队列并返回 202，由后台按指数退避重试到成功或超过最长重试时间；
+// 队列已满时请求仍然失败。必须在处理请求之前调用
+func (h *CacheHandler) EnableDeleteRetry(opts ...deletequeue.Option) error {
+	q, err := deletequeue.New(h.retryDelete, opts...)
+	if err != nil {
+		return err
+	}
+	h.deletes = q
+	return nil
+}
+
+// DeleteRetryStats 返回删除重试队列的统计，未开启时返回 nil
+func (h *CacheHandler) DeleteRetryStats() *deletequeue.Stats {
+	if h.deletes == nil {
+		return nil
+	}
+	stats := h.deletes.Stats()
+	return &stats
+}
+
+// Close 停止删除重试，开启了删除日志时未完成的删除留给下次启动
+func (h *CacheHandler) Close() error {
+	if h.deletes == nil {
+		return nil
+	}
+	return h.deletes.Close()
+}
+
+// queueDelete 把发往 node 失败的删除加入重试队列，未开启删除重试、错误不可重试或队列已满时返回 false
+func (h *CacheHandler) queueDelete(node, group, key string, err error) bool {
+	if h.deletes == nil || !retryableDelete(err) {
+		return false
+	}
+	if qerr := h.deletes.Add(node, group, key); qerr != nil {
+		logger.Errorf("删除无法加入重试队列: node=%s group=%s key=%s: %v", node, group, logger.Key(key), qerr)
+		return false
+	}
+	logger.Warnf("删除发往节点 %s 失败，已加入重试队列: group=%s key=%s: %v", node, group, logger.Key(key), err)
+	return true
+}
+
+// retryableDelete 判断删除失败是否可能在稍后重试时成功。节点不可达、超时和内部错误可以重试；
+// 键为空、组不存在、只读、组不允许访问等节点明确拒绝的删除不重试
+func retryableDelete(err error) bool {
+	if isKeyNotFound(err) {
+		return false
+	}
+	switch cacheerrors.TypeOf(err) {
+	case cacheerrors.ErrTypeInternalError, cacheerrors.ErrTypeNetworkError:
+		return true
+	}
+	return false
+}
+
+// retryDelete 重试一个排队的删除。节点已经离开集群时保留删除等它回来，直到超过最长重试时间；
+// 键不存在视为删除成功
+func (h *CacheHandler) retryDelete(ctx context.Context, d deletequeue.Delete) error {
+	h.mu.RLock()
+	getter, ok := h.nodeGetters[d.Target]
+	h.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("node %s is not in the cluster", d.Target)
+	}
+
+	err := getter.Delete(ctx, d.Group, d.Key)
+	switch {
+	case err == nil || isKeyNotFound(err):
+		h.hot.forget(d.Group, d.Key)
+		return nil
+	case errors.Is(err, cache.ErrNoSuchGroup) || cache.IsGroupNotFoundError(err),
+		errors.Is(err, cache.ErrEmptyKey) || cache.IsKeyEmptyError(err),
+		cache.IsGroupForbiddenError(err):
+		return fmt.Errorf("%w: %v", deletequeue.ErrPermanent, err)
+	}
+	return err
+}
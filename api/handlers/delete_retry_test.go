@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/internal/cache"
+	"github.com/AdrianWangs/go-cache/internal/deletequeue"
+	"github.com/AdrianWangs/go-cache/internal/server"
+)
+
+// restartableNode 在固定地址上提供 scores 组的节点，可以停止后在同一地址重新启动
+type restartableNode struct {
+	addr    string
+	handler http.Handler
+	group   *cache.Group
+	srv     *httptest.Server
+}
+
+func startRestartableNode(t *testing.T) *restartableNode {
+	t.Helper()
+	registry := cache.NewRegistry()
+	t.Cleanup(func() { registry.Close() })
+	srv := httptest.NewUnstartedServer(nil)
+	n := &restartableNode{addr: srv.Listener.Addr().String(), srv: srv}
+	pool := server.NewHTTPPool("http://"+n.addr, server.WithRegistry(registry))
+	n.handler = pool
+	n.group = cache.NewGroup("scores", 1<<20, cache.GetterFunc(func(key string) ([]byte, error) {
+		return []byte("v:" + key), nil
+	}), time.Hour, cache.WithRegistry(registry))
+	srv.Config.Handler = pool
+	srv.Start()
+	t.Cleanup(func() { n.srv.Close() })
+	return n
+}
+
+// kill 停止节点，之后的请求连接失败
+func (n *restartableNode) kill() {
+	n.srv.Close()
+}
+
+// revive 在原地址上重新启动节点，缓存的数据保留
+func (n *restartableNode) revive(t *testing.T) {
+	t.Helper()
+	var l net.Listener
+	var err error
+	// 刚关闭的端口可能短暂不可用
+	for i := 0; i < 50; i++ {
+		if l, err = net.Listen("tcp", n.addr); err == nil {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("无法在 %s 上重新启动节点: %v", n.addr, err)
+	}
+	n.srv = httptest.NewUnstartedServer(n.handler)
+	n.srv.Listener.Close()
+	n.srv.Listener = l
+	n.srv.Start()
+}
+
+func (n *restartableNode) cached(key string) bool {
+	_, _, ok := n.group.Peek(key)
+	return ok
+}
+
+// TestDeleteRetryAfterNodeRevives 节点停止期间的删除返回 202 并进入重试队列，
+// 节点恢复后删除最终生效；队列已满时删除直接失败
+func TestDeleteRetryAfterNodeRevives(t *testing.T) {
+	node := startRestartableNode(t)
+	h := NewCacheHandler("/_gocache/", 50, CacheHandlerOptions{
+		Getters: GetterFactoryFunc(func(ProtocolType, string) NodeGetter {
+			return NewProtoGetter("http://" + node.addr + "/_gocache/")
+		}),
+	})
+	h.UpdatePeers(nodesWithGroups(1, "scores"))
+	if err := h.EnableDeleteRetry(deletequeue.WithMaxSize(3), deletequeue.WithBackoff(10*time.Millisecond, 50*time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { h.Close() })
+
+	keys := []string{"k0", "k1", "k2", "k3"}
+	for _, key := range keys {
+		if w := serveRead(h.GetCacheHandler, "/api/cache/scores/"+key, ""); w.Code != http.StatusOK {
+			t.Fatalf("读取 %s: %d %s", key, w.Code, w.Body)
+		}
+		if !node.cached(key) {
+			t.Fatalf("%s 未缓存在节点上", key)
+		}
+	}
+
+	node.kill()
+	del := func(key string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.DeleteCacheHandler(w, httptest.NewRequest(http.MethodDelete, "/api/cache/scores/"+key, nil))
+		return w
+	}
+	for _, key := range keys[:3] {
+		if w := del(key); w.Code != http.StatusAccepted {
+			t.Fatalf("节点停止时删除 %s: %d %s, want 202", key, w.Code, w.Body)
+		}
+	}
+	if w := del(keys[3]); w.Code < 500 {
+		t.Fatalf("队列已满时删除: %d %s, want 5xx", w.Code, w.Body)
+	}
+	if st := h.DeleteRetryStats(); st.Depth != 3 || st.Rejected != 1 {
+		t.Fatalf("节点停止时的统计 = %+v", st)
+	}
+
+	node.revive(t)
+	deadline := time.Now().Add(5 * time.Second)
+	for h.DeleteRetryStats().Depth > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("节点恢复后删除未生效: %+v", h.DeleteRetryStats())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	for _, key := range keys[:3] {
+		if node.cached(key) {
+			t.Fatalf("%s 在节点恢复后仍被缓存", key)
+		}
+	}
+	if !node.cached(keys[3]) {
+		t.Fatal("未进入队列的删除不应生效")
+	}
+	if st := h.DeleteRetryStats(); st.Succeeded != 3 || st.Failed != 0 {
+		t.Fatalf("节点恢复后的统计 = %+v", st)
+	}
+	if w := del(keys[3]); w.Code != http.StatusOK {
+		t.Fatalf("节点恢复后删除: %d %s", w.Code, w.Body)
+	}
+}
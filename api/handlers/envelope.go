@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/internal/peers"
+	"github.com/AdrianWangs/go-cache/pkg/cacheerrors"
+	"github.com/AdrianWangs/go-cache/pkg/logger"
+	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
+)
+
+// readFormat 读取接口的响应格式
+type readFormat int
+
+const (
+	// formatRaw 值以 application/octet-stream 原样返回，错误为纯文本，是默认格式
+	formatRaw readFormat = iota
+	// formatJSON 值和错误都放在 JSON 信封中返回
+	formatJSON
+)
+
+// 读取接口 ?format= 参数的取值
+const (
+	formatParamJSON = "json"
+	formatParamRaw  = "raw"
+)
+
+// JSON 格式的错误信封中的错误码，与节点返回的 cache.ErrorCode* 一一对应，另有 API 服务器自身的错误
+const (
+	EnvelopeCodeBadRequest        = "BAD_REQUEST"
+	EnvelopeCodeKeyEmpty          = "KEY_EMPTY"
+	EnvelopeCodeNotFound          = "NOT_FOUND"
+	EnvelopeCodeGroupNotFound     = "GROUP_NOT_FOUND"
+	EnvelopeCodeGroupForbidden    = "GROUP_FORBIDDEN"
+	EnvelopeCodeRateLimited       = "RATE_LIMITED"
+	EnvelopeCodeNoPeerAvailable   = "NO_PEER_AVAILABLE"
+	EnvelopeCodeOriginUnavailable = "ORIGIN_UNAVAILABLE"
+	EnvelopeCodeReadOnly          = "READ_ONLY"
+	EnvelopeCodeValueTransform    = "VALUE_TRANSFORM"
+	EnvelopeCodeGroupClosed       = "GROUP_CLOSED"
+	EnvelopeCodeNoNodeAvailable   = "NO_NODE_AVAILABLE"
+	EnvelopeCodeNodeBusy          = "NODE_BUSY"
+	EnvelopeCodeMethodNotAllowed  = "METHOD_NOT_ALLOWED"
+	EnvelopeCodeInternal          = "INTERNAL"
+)
+
+// envelopeCodes 节点错误码（cacheerrors.ErrorCode*）对应的 JSON 错误码
+var envelopeCodes = map[string]string{
+	cacheerrors.ErrorCodeKeyEmpty:       EnvelopeCodeKeyEmpty,
+	cacheerrors.ErrorCodeKeyNotFound:    EnvelopeCodeNotFound,
+	cacheerrors.ErrorCodeGroupNotFound:  EnvelopeCodeGroupNotFound,
+	cacheerrors.ErrorCodeRateLimited:    EnvelopeCodeRateLimited,
+	cacheerrors.ErrorCodeReadOnly:       EnvelopeCodeReadOnly,
+	cacheerrors.ErrorCodeNoPeer:         EnvelopeCodeNoPeerAvailable,
+	cacheerrors.ErrorCodeOrigin:         EnvelopeCodeOriginUnavailable,
+	cacheerrors.ErrorCodeGroupForbidden: EnvelopeCodeGroupForbidden,
+	cacheerrors.ErrorCodeValueTransform: EnvelopeCodeValueTransform,
+	cacheerrors.ErrorCodeGroupClosed:    EnvelopeCodeGroupClosed,
+	cacheerrors.ErrorCodeInternal:       EnvelopeCodeInternal,
+}
+
+// ValueEnvelope JSON 格式的单个 key 读取结果
+type ValueEnvelope struct {
+	Group   string  `json:"group"`
+	Key     string  `json:"key"`
+	Value   []byte  `json:"value"`             // 值，base64 编码
+	TTLMs   *int64  `json:"ttl_ms,omitempty"`  // 剩余有效时间（毫秒），永不过期或节点未提供时省略
+	Version *uint64 `json:"version,omitempty"` // 节点上的写入版本，节点未提供时省略
+	Source  string  `json:"source,omitempty"`  // 值的来源，例如 cache、peer 或 origin，节点未提供时省略
+	Node    string  `json:"node,omitempty"`    // 产生该值的节点标识，未知或不公开时省略
+}
+
+// ErrorEnvelope JSON 格式的错误响应: {"error": {"code": ..., "message": ...}}
+type ErrorEnvelope struct {
+	Error ErrorBody `json:"error"`
+}
+
+// ErrorBody 错误信封的内容
+type ErrorBody struct {
+	Code    string `json:"code"`            // 错误码，EnvelopeCode* 之一
+	Message string `json:"message"`         // 错误描述
+	Group   string `json:"group,omitempty"` // 相关的缓存组
+}
+
+// negotiateReadFormat 选择读取接口的响应格式。?format= 优先，只能是 json 或 raw；
+// 否则 Accept 中 application/json 的权重高于 application/octet-stream 时使用 JSON。
+// 通配符不参与比较，无法解析的 Accept 条目被忽略，未指定时为原始格式
+func negotiateReadFormat(r *http.Request) (readFormat, error) {
+	switch f := r.URL.Query().Get("format"); f {
+	case "":
+	case formatParamJSON:
+		return formatJSON, nil
+	case formatParamRaw:
+		return formatRaw, nil
+	default:
+		return formatRaw, fmt.Errorf("unknown format %q, expected %s or %s", f, formatParamJSON, formatParamRaw)
+	}
+
+	var qJSON, qRaw float64
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil || q < 0 || q > 1 {
+				continue
+			}
+		}
+		switch mediaType {
+		case "application/json":
+			qJSON = max(qJSON, q)
+		case "application/octet-stream":
+			qRaw = max(qRaw, q)
+		}
+	}
+	if qJSON > 0 && qJSON > qRaw {
+		return formatJSON, nil
+	}
+	return formatRaw, nil
+}
+
+// newValueEnvelope 根据节点的响应构造 JSON 格式的读取结果
+func newValueEnvelope(group, key string, res *pb.Response, now time.Time) ValueEnvelope {
+	env := ValueEnvelope{Group: group, Key: key, Value: res.GetValue(), Source: res.GetSource(), Node: res.GetNode()}
+	if env.Value == nil {
+		// 空值编码为 ""，而不是 null
+		env.Value = []byte{}
+	}
+	if res.ExpiresAt != nil {
+		ttl := max(time.Unix(0, res.GetExpiresAt()).Sub(now), 0).Milliseconds()
+		env.TTLMs = &ttl
+	}
+	if res.Version != nil {
+		version := res.GetVersion()
+		env.Version = &version
+	}
+	return env
+}
+
+// writeJSON 以 status 输出 JSON 响应
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeReadError 按响应格式输出读取接口的错误：JSON 格式为错误信封，否则为纯文本
+func writeReadError(w http.ResponseWriter, format readFormat, status int, code, message string) {
+	if format == formatJSON {
+		writeJSON(w, status, ErrorEnvelope{Error: ErrorBody{Code: code, Message: message}})
+		return
+	}
+	http.Error(w, message, status)
+}
+
+// writeReadGroupNotFound 按响应格式输出组不存在的错误；原始格式沿用 writeGroupNotFound 的结构化响应
+func writeReadGroupNotFound(w http.ResponseWriter, format readFormat, group string) {
+	if format == formatJSON {
+		writeJSON(w, http.StatusNotFound, ErrorEnvelope{Error: ErrorBody{
+			Code:    EnvelopeCodeGroupNotFound,
+			Message: fmt.Sprintf("Group not found: %s", group),
+			Group:   group,
+		}})
+		return
+	}
+	writeGroupNotFound(w, group)
+}
+
+// writeNodeError 输出访问节点失败的响应。状态码和 X-GoCache-Error-Code 由 cacheerrors 按错误类型决定，
+// 与节点自身的响应一致；节点繁忙不是缓存错误，单独返回 503。failure 是内部错误的描述前缀
+func writeNodeError(w http.ResponseWriter, format readFormat, node, group, key, failure string, err error) {
+	if errors.Is(err, peers.ErrPeerBusy) {
+		// 节点（以及对冲或改读的下一个节点）未完成的请求已达上限，请求没有发出
+		writeReadError(w, format, http.StatusServiceUnavailable, EnvelopeCodeNodeBusy, "Service Unavailable: node busy")
+		logger.Warnf("节点 %s 繁忙: group=%s, key=%s", node, group, logger.Key(key))
+		return
+	}
+
+	code := cacheerrors.ErrorCode(err)
+	status := cacheerrors.HTTPStatus(err)
+	w.Header().Set(peers.HeaderErrorCode, code)
+	if code == cacheerrors.ErrorCodeGroupNotFound {
+		writeReadGroupNotFound(w, format, group)
+		logger.Warnf("组不存在: %s", group)
+		return
+	}
+
+	var message string
+	switch code {
+	case cacheerrors.ErrorCodeKeyNotFound:
+		message = fmt.Sprintf("Key not found: %s", key)
+	case cacheerrors.ErrorCodeKeyEmpty:
+		message = "Key is empty"
+	case cacheerrors.ErrorCodeGroupForbidden:
+		message = fmt.Sprintf("Forbidden: group %s is not servable", group)
+	case cacheerrors.ErrorCodeRateLimited:
+		message = "Too Many Requests: rate limit exceeded"
+		w.Header().Set(cacheerrors.HeaderRetryAfter, cacheerrors.RetryAfterHeader(err))
+	case cacheerrors.ErrorCodeReadOnly:
+		message = "Service Unavailable: cache node is read-only"
+	case cacheerrors.ErrorCodeNoPeer:
+		message = "Service Unavailable: no peer available"
+	case cacheerrors.ErrorCodeOrigin:
+		message = "Service Unavailable: origin unavailable"
+	default:
+		message = fmt.Sprintf("%s: %v", failure, err)
+	}
+	writeReadError(w, format, status, envelopeCodes[code], message)
+
+	if status >= http.StatusInternalServerError && status != http.StatusServiceUnavailable {
+		logger.Errorf("节点 %s 处理请求失败: group=%s, key=%s: %v", node, group, logger.Key(key), err)
+	} else {
+		logger.Warnf("节点 %s 返回错误: group=%s, key=%s: %v", node, group, logger.Key(key), err)
+	}
+}
@@ -0,0 +1,249 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/internal/cache"
+	"github.com/AdrianWangs/go-cache/internal/server"
+)
+
+func TestNegotiateReadFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		accept  string
+		want    readFormat
+		wantErr bool
+	}{
+		{"未指定", "", "", formatRaw, false},
+		{"format=json", "?format=json", "", formatJSON, false},
+		{"format=raw 优先于 Accept", "?format=raw", "application/json", formatRaw, false},
+		{"未知的 format", "?format=xml", "application/json", formatRaw, true},
+		{"Accept JSON", "", "application/json", formatJSON, false},
+		{"带参数的 JSON", "", "application/json; charset=utf-8", formatJSON, false},
+		{"原始格式权重更高", "", "application/json;q=0.5, application/octet-stream", formatRaw, false},
+		{"JSON 权重更高", "", "application/octet-stream;q=0.9, application/json", formatJSON, false},
+		{"权重相同时为原始格式", "", "application/json, application/octet-stream", formatRaw, false},
+		{"JSON 权重为 0", "", "application/json;q=0", formatRaw, false},
+		{"通配符不参与比较", "", "*/*", formatRaw, false},
+		{"浏览器的 Accept", "", "text/html, application/xhtml+xml, application/json;q=0.9, */*;q=0.8", formatJSON, false},
+		{"无法解析的权重被忽略", "", "application/json;q=abc", formatRaw, false},
+		{"超出范围的权重被忽略", "", "application/json;q=2", formatRaw, false},
+		{"无法解析的条目被忽略", "", "application/, ;;, application/json", formatJSON, false},
+		{"完全无法解析", "", "@@@", formatRaw, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/cache/scores/Tom"+tt.query, nil)
+			if tt.accept != "" {
+				r.Header.Set("Accept", tt.accept)
+			}
+			got, err := negotiateReadFormat(r)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("格式 = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// newEnvelopeHandler 启动一个提供 scores 组的节点，返回把所有 key 路由到该节点的 CacheHandler。
+// missing 在数据源中不存在
+func newEnvelopeHandler(t *testing.T) *CacheHandler {
+	t.Helper()
+	registry := cache.NewRegistry()
+	t.Cleanup(func() { registry.Close() })
+	srv := httptest.NewUnstartedServer(nil)
+	pool := server.NewHTTPPool("http://"+srv.Listener.Addr().String(), server.WithRegistry(registry), server.WithSelfID("node-a"))
+	srv.Config.Handler = pool
+	srv.Start()
+	t.Cleanup(srv.Close)
+	cache.NewGroup("scores", 1<<20, cache.GetterFunc(func(key string) ([]byte, error) {
+		if key == "missing" {
+			return nil, cache.ErrNotFound
+		}
+		return []byte("v:" + key), nil
+	}), time.Hour, cache.WithRegistry(registry))
+
+	base := srv.URL + pool.BasePath()
+	h := NewCacheHandler("/_gocache/", 50, CacheHandlerOptions{
+		Identity: "api-1",
+		Getters: GetterFactoryFunc(func(ProtocolType, string) NodeGetter {
+			return NewProtoGetter(base)
+		}),
+	})
+	h.UpdatePeers(nodesWithGroups(1, "scores"))
+	return h
+}
+
+// serveRead 以 GET 请求 target，accept 非空时设置 Accept 头
+func serveRead(handler http.HandlerFunc, target, accept string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, target, nil)
+	if accept != "" {
+		r.Header.Set("Accept", accept)
+	}
+	w := httptest.NewRecorder()
+	handler(w, r)
+	return w
+}
+
+// decodeErrorEnvelope 解析 JSON 格式的错误响应
+func decodeErrorEnvelope(t *testing.T, w *httptest.ResponseRecorder) ErrorBody {
+	t.Helper()
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("Content-Type = %q, want application/json: %s", ct, w.Body)
+	}
+	var env ErrorEnvelope
+	if err := json.Unmarshal(w.Body.Bytes(), &env); err != nil {
+		t.Fatalf("错误响应不是信封: %v: %s", err, w.Body)
+	}
+	return env.Error
+}
+
+// TestGetCacheHandlerFormats 单个 key 的读取：默认原样返回值，JSON 格式返回带元数据的信封，
+// 无法解析的 Accept 按原始格式处理
+func TestGetCacheHandlerFormats(t *testing.T) {
+	h := newEnvelopeHandler(t)
+
+	for _, accept := range []string{"", "*/*", "application/json;q=abc", "@@@"} {
+		w := serveRead(h.GetCacheHandler, "/api/cache/scores/Tom", accept)
+		if w.Code != http.StatusOK || w.Body.String() != "v:Tom" {
+			t.Fatalf("Accept %q: %d %q, want 200 v:Tom", accept, w.Code, w.Body)
+		}
+		if ct := w.Header().Get("Content-Type"); ct != "application/octet-stream" {
+			t.Fatalf("Accept %q: Content-Type = %q", accept, ct)
+		}
+		if w.Header().Get("Vary") != "Accept" {
+			t.Fatalf("Accept %q: 缺少 Vary: Accept", accept)
+		}
+	}
+
+	for _, target := range []string{"/api/cache/scores/Tom?format=json", "/api/cache/scores/Tom"} {
+		w := serveRead(h.GetCacheHandler, target, "application/json")
+		if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/json" {
+			t.Fatalf("%s: %d %s", target, w.Code, w.Header().Get("Content-Type"))
+		}
+		// 值以 base64 编码
+		if !strings.Contains(w.Body.String(), `"value":"djpUb20="`) {
+			t.Fatalf("%s: 值未以 base64 编码: %s", target, w.Body)
+		}
+		var env ValueEnvelope
+		if err := json.Unmarshal(w.Body.Bytes(), &env); err != nil {
+			t.Fatal(err)
+		}
+		if env.Group != "scores" || env.Key != "Tom" || string(env.Value) != "v:Tom" {
+			t.Fatalf("%s: 信封 = %+v", target, env)
+		}
+		if env.TTLMs == nil || *env.TTLMs <= 0 || *env.TTLMs > time.Hour.Milliseconds() {
+			t.Fatalf("%s: ttl_ms = %v", target, env.TTLMs)
+		}
+		if env.Version == nil || env.Source == "" || env.Node != "node-a" {
+			t.Fatalf("%s: 元数据不完整 %+v", target, env)
+		}
+		if etag := w.Header().Get("ETag"); !strings.HasPrefix(etag, `W/"`) {
+			t.Fatalf("%s: JSON 格式的 ETag = %q, want 弱 ETag", target, etag)
+		}
+	}
+
+	// 未知的 format 以纯文本拒绝
+	w := serveRead(h.GetCacheHandler, "/api/cache/scores/Tom?format=xml", "application/json")
+	if w.Code != http.StatusBadRequest || strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+		t.Fatalf("format=xml: %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+}
+
+// TestGetCacheHandlerErrorEnvelope JSON 格式的错误以信封返回，原始格式为纯文本
+func TestGetCacheHandlerErrorEnvelope(t *testing.T) {
+	h := newEnvelopeHandler(t)
+	tests := []struct {
+		name   string
+		target string
+		status int
+		code   string
+		group  string
+	}{
+		{"键不存在", "/api/cache/scores/missing", http.StatusNotFound, EnvelopeCodeNotFound, ""},
+		{"组不存在", "/api/cache/users/Tom", http.StatusNotFound, EnvelopeCodeGroupNotFound, "users"},
+		{"路径不完整", "/api/cache/scores", http.StatusBadRequest, EnvelopeCodeBadRequest, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serveRead(h.GetCacheHandler, tt.target, "application/json")
+			if w.Code != tt.status {
+				t.Fatalf("状态码 = %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			body := decodeErrorEnvelope(t, w)
+			if body.Code != tt.code || body.Message == "" || body.Group != tt.group {
+				t.Fatalf("错误信封 = %+v, want code %s group %q", body, tt.code, tt.group)
+			}
+			if w.Header().Get("Cache-Control") != "no-store" {
+				t.Fatalf("错误响应可被缓存: %q", w.Header().Get("Cache-Control"))
+			}
+
+			// 原始格式不使用信封，组不存在时沿用原有的结构化响应
+			raw := serveRead(h.GetCacheHandler, tt.target, "")
+			if raw.Code != tt.status || strings.Contains(raw.Body.String(), `"code"`) {
+				t.Fatalf("原始格式: %d %s", raw.Code, raw.Body)
+			}
+		})
+	}
+}
+
+// TestBatchGetHandlerFormats 批量读取在两种格式下返回相同的值，JSON 格式另外返回每个 key 的信封
+func TestBatchGetHandlerFormats(t *testing.T) {
+	h := newEnvelopeHandler(t)
+	const target = "/api/batch/scores?key=Tom&key=Ann&key=missing"
+
+	decode := func(w *httptest.ResponseRecorder) BatchGetResponse {
+		t.Helper()
+		if w.Code != http.StatusOK || w.Header().Get("Vary") != "Accept" {
+			t.Fatalf("状态码 = %d, Vary = %q: %s", w.Code, w.Header().Get("Vary"), w.Body)
+		}
+		var resp BatchGetResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		if resp.Values["Tom"] != "v:Tom" || resp.Values["Ann"] != "v:Ann" || len(resp.Missing) != 1 || resp.Missing[0] != "missing" {
+			t.Fatalf("批量结果 = %+v", resp)
+		}
+		return resp
+	}
+
+	if resp := decode(serveRead(h.BatchGetHandler, target, "")); resp.Entries != nil {
+		t.Fatalf("原始格式返回了 entries: %+v", resp.Entries)
+	}
+	for _, w := range []*httptest.ResponseRecorder{
+		serveRead(h.BatchGetHandler, target, "application/json"),
+		serveRead(h.BatchGetHandler, target+"&format=json", ""),
+	} {
+		resp := decode(w)
+		if len(resp.Entries) != 2 {
+			t.Fatalf("entries = %+v, want Tom 和 Ann", resp.Entries)
+		}
+		env := resp.Entries["Tom"]
+		if env.Group != "scores" || env.Key != "Tom" || string(env.Value) != "v:Tom" || env.TTLMs == nil || env.Version == nil {
+			t.Fatalf("Tom 的信封 = %+v", env)
+		}
+	}
+
+	// 错误在 JSON 格式下以信封返回
+	w := serveRead(h.BatchGetHandler, "/api/batch/users?key=Tom", "application/json")
+	if body := decodeErrorEnvelope(t, w); w.Code != http.StatusNotFound || body.Code != EnvelopeCodeGroupNotFound || body.Group != "users" {
+		t.Fatalf("组不存在: %d %+v", w.Code, body)
+	}
+	w = serveRead(h.BatchGetHandler, "/api/batch/scores", "application/json")
+	if body := decodeErrorEnvelope(t, w); w.Code != http.StatusBadRequest || body.Code != EnvelopeCodeBadRequest {
+		t.Fatalf("没有 key: %d %+v", w.Code, body)
+	}
+	w = serveRead(h.BatchGetHandler, target+"&format=xml", "")
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("format=xml: %d", w.Code)
+	}
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"fmt"
+
+	"github.com/AdrianWangs/go-cache/internal/discovery"
+	"github.com/AdrianWangs/go-cache/pkg/logger"
+)
+
+// GetterFactory 按协议创建访问缓存节点的 NodeGetter，超时、签名等选项在创建工厂时确定
+type GetterFactory interface {
+	// NewGetter 创建以 protocol 访问 addr 的 NodeGetter：
+	// gRPC 的 addr 为 host:port，HTTP 的 addr 为包含 basePath 的基础 URL
+	NewGetter(protocol ProtocolType, addr string) NodeGetter
+}
+
+// GetterFactoryFunc 把函数适配为 GetterFactory
+type GetterFactoryFunc func(protocol ProtocolType, addr string) NodeGetter
+
+// NewGetter 实现 GetterFactory
+func (f GetterFactoryFunc) NewGetter(protocol ProtocolType, addr string) NodeGetter {
+	return f(protocol, addr)
+}
+
+// NewGetterFactory 返回用 opts 创建 GRPCGetter 和 HTTPGetter 的 GetterFactory，是 CacheHandler 的默认工厂
+func NewGetterFactory(opts ...GetterOption) GetterFactory {
+	return GetterFactoryFunc(func(protocol ProtocolType, addr string) NodeGetter {
+		if protocol == ProtocolGRPC {
+			return NewGRPCGetter(addr, opts...)
+		}
+		return NewHTTPGetter(addr, opts...)
+	})
+}
+
+// selectProtocol 为节点选择协议：节点登记了所提供的协议时优先 gRPC，不支持时使用 HTTP；
+// 旧版本节点未登记协议，或登记的协议都无法识别时使用默认协议 h.protocol
+func (h *CacheHandler) selectProtocol(node discovery.NodeInfo) ProtocolType {
+	switch {
+	case !node.HasProtocolInfo():
+		return h.protocol
+	case node.Supports(discovery.ProtocolGRPC):
+		return ProtocolGRPC
+	case node.Supports(discovery.ProtocolHTTP):
+		return ProtocolHTTP
+	}
+	logger.Warnf("节点 %s 登记的协议 %v 均不受支持，使用默认协议 %s", node.Key(), node.Protocols, h.protocol)
+	return h.protocol
+}
+
+// getterAddr 返回以 protocol 访问节点时传给 GetterFactory 的地址
+func (h *CacheHandler) getterAddr(protocol ProtocolType, node discovery.NodeInfo) string {
+	if protocol == ProtocolGRPC {
+		return node.GRPCAddr
+	}
+	addr := node.HTTPAddr
+	if addr == "" {
+		// 旧版本节点只注册了 gRPC 地址
+		addr = node.GRPCAddr
+		logger.Warnf("节点 %s 未登记 HTTP 地址，回退为使用 %s", node.Key(), addr)
+	}
+	return fmt.Sprintf("http://%s%s", addr, h.basePath)
+}
+
+// NodeProtocols 返回访问各节点所用的协议，以节点标识为 key
+func (h *CacheHandler) NodeProtocols() map[string]ProtocolType {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	protocols := make(map[string]ProtocolType, len(h.protocols))
+	for peer, p := range h.protocols {
+		protocols[peer] = p
+	}
+	return protocols
+}
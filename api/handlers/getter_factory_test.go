@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/AdrianWangs/go-cache/internal/discovery"
+)
+
+// registrations 同一组节点分别以旧格式和结构化格式注册的值
+var registrations = map[string][]string{
+	"legacy": {"10.0.0.1:9090", "10.0.0.2:9090", "10.0.0.3:9090"},
+	"structured": {
+		`{"grpc_addr":"10.0.0.1:9090","http_addr":"10.0.0.1:8001"}`,
+		`{"grpc_addr":"10.0.0.2:9090","http_addr":"10.0.0.2:8001"}`,
+		`{"grpc_addr":"10.0.0.3:9090","http_addr":"10.0.0.3:8001"}`,
+	},
+}
+
+func parseRegistrations(values []string) []discovery.NodeInfo {
+	nodes := make([]discovery.NodeInfo, 0, len(values))
+	for _, v := range values {
+		nodes = append(nodes, discovery.ParseNodeInfo(v))
+	}
+	return nodes
+}
+
+func TestUpdatePeersGetterAddresses(t *testing.T) {
+	tests := []struct {
+		format   string
+		protocol ProtocolType
+		want     string // 10.0.0.1 的 getter 地址
+	}{
+		{"legacy", ProtocolGRPC, "10.0.0.1:9090"},
+		// 旧版本节点只登记了 gRPC 地址，只能回退到它
+		{"legacy", ProtocolHTTP, "http://10.0.0.1:9090/_gocache/"},
+		{"structured", ProtocolGRPC, "10.0.0.1:9090"},
+		{"structured", ProtocolHTTP, "http://10.0.0.1:8001/_gocache/"},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s/%s", tt.format, tt.protocol), func(t *testing.T) {
+			factory := &recordingFactory{}
+			h := NewCacheHandler("/_gocache/", 50, CacheHandlerOptions{Protocol: tt.protocol, Getters: factory})
+			h.UpdatePeers(parseRegistrations(registrations[tt.format]))
+
+			getter, ok := h.GetNodeGetters()["10.0.0.1:9090"].(*stubGetter)
+			if !ok {
+				t.Fatalf("节点未以 gRPC 地址为标识: %v", h.GetNodeGetters())
+			}
+			if getter.protocol != tt.protocol || getter.addr != tt.want {
+				t.Fatalf("getter = %s %q, want %s %q", getter.protocol, getter.addr, tt.protocol, tt.want)
+			}
+		})
+	}
+}
+
+// TestRingIndependentOfProtocolAndFormat key 的归属与协议和注册格式无关
+func TestRingIndependentOfProtocolAndFormat(t *testing.T) {
+	var all []*CacheHandler
+	for _, format := range []string{"legacy", "structured"} {
+		for _, protocol := range []ProtocolType{ProtocolGRPC, ProtocolHTTP} {
+			h := NewCacheHandler("/_gocache/", 50, CacheHandlerOptions{Protocol: protocol, Getters: &recordingFactory{}})
+			h.UpdatePeers(parseRegistrations(registrations[format]))
+			all = append(all, h)
+		}
+	}
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		want, _ := all[0].pickNode(key)
+		for _, h := range all[1:] {
+			if got, _ := h.pickNode(key); got != want {
+				t.Fatalf("%s 归属 %s 与 %s 不同", key, got, want)
+			}
+		}
+	}
+}
+
+// TestUpdatePeersReusesGetters 节点升级为结构化注册后 HTTP 地址改变，getter 重建；注册信息不变时复用
+func TestUpdatePeersReusesGetters(t *testing.T) {
+	factory := &recordingFactory{}
+	h := NewCacheHandler("/_gocache/", 50, CacheHandlerOptions{Protocol: ProtocolHTTP, Getters: factory})
+	h.UpdatePeers(parseRegistrations(registrations["legacy"]))
+	h.UpdatePeers(parseRegistrations(registrations["legacy"]))
+	if n := factory.count(); n != 3 {
+		t.Fatalf("创建了 %d 个 getter，want 3", n)
+	}
+	h.UpdatePeers(parseRegistrations(registrations["structured"]))
+	if n := factory.count(); n != 6 {
+		t.Fatalf("创建了 %d 个 getter，want 6", n)
+	}
+}
+
+// TestOwnershipFollowsNodeID 节点换了 IP 但标识不变时，仍拥有原来的 key，getter 指向新地址
+func TestOwnershipFollowsNodeID(t *testing.T) {
+	factory := &recordingFactory{}
+	h := NewCacheHandler("/_gocache/", 50, CacheHandlerOptions{Protocol: ProtocolGRPC, Getters: factory})
+	h.UpdatePeers(parseRegistrations([]string{
+		`{"id":"node-a","grpc_addr":"10.0.0.1:9090"}`,
+		`{"id":"node-b","grpc_addr":"10.0.0.2:9090"}`,
+		`{"id":"node-c","grpc_addr":"10.0.0.3:9090"}`,
+	}))
+	before := make(map[string]string)
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		before[key], _ = h.pickNode(key)
+	}
+
+	h.UpdatePeers(parseRegistrations([]string{
+		`{"id":"node-a","grpc_addr":"10.0.0.1:9090"}`,
+		`{"id":"node-b","grpc_addr":"10.0.0.9:9191"}`,
+		`{"id":"node-c","grpc_addr":"10.0.0.3:9090"}`,
+	}))
+	for key, owner := range before {
+		node, getter := h.pickNode(key)
+		if node != owner {
+			t.Fatalf("%s: 归属从 %s 变为 %s", key, owner, node)
+		}
+		if node == "node-b" && getter.(*stubGetter).addr != "10.0.0.9:9191" {
+			t.Fatalf("node-b 的 getter 地址 = %q", getter.(*stubGetter).addr)
+		}
+	}
+}
+
+// TestSelectProtocol 登记了协议的节点优先 gRPC，只提供 HTTP 时使用 HTTP；
+// 未登记协议或登记的协议都无法识别时使用默认协议
+func TestSelectProtocol(t *testing.T) {
+	tests := []struct {
+		name      string
+		protocols []string
+		def       ProtocolType
+		want      ProtocolType
+	}{
+		{"未登记，默认 HTTP", nil, ProtocolHTTP, ProtocolHTTP},
+		{"未登记，默认 gRPC", nil, ProtocolGRPC, ProtocolGRPC},
+		{"只提供 HTTP", []string{"http"}, ProtocolGRPC, ProtocolHTTP},
+		{"只提供 gRPC", []string{"grpc"}, ProtocolHTTP, ProtocolGRPC},
+		{"都提供时优先 gRPC", []string{"http", "grpc"}, ProtocolHTTP, ProtocolGRPC},
+		{"无法识别的协议", []string{"quic"}, ProtocolGRPC, ProtocolGRPC},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewCacheHandler("/_gocache/", 50, CacheHandlerOptions{Protocol: tt.def, Getters: &recordingFactory{}})
+			if got := h.selectProtocol(discovery.NodeInfo{GRPCAddr: "10.0.0.1:9090", Protocols: tt.protocols}); got != tt.want {
+				t.Fatalf("selectProtocol = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// mixedCluster 一个旧版本节点、一个只提供 HTTP 的节点和一个两种协议都提供的节点
+func mixedCluster(cProtocols ...string) []discovery.NodeInfo {
+	return []discovery.NodeInfo{
+		{ID: "node-a", GRPCAddr: "10.0.0.1:9090", HTTPAddr: "10.0.0.1:8001"},
+		{ID: "node-b", GRPCAddr: "10.0.0.2:9090", HTTPAddr: "10.0.0.2:8001", Protocols: []string{"http"}},
+		{ID: "node-c", GRPCAddr: "10.0.0.3:9090", HTTPAddr: "10.0.0.3:8001", Protocols: cProtocols},
+	}
+}
+
+// TestUpdatePeersMixedCluster 每个节点按登记的协议创建 getter，协议变化时只重建该节点的 getter
+func TestUpdatePeersMixedCluster(t *testing.T) {
+	factory := &recordingFactory{}
+	h := NewCacheHandler("/_gocache/", 50, CacheHandlerOptions{Protocol: ProtocolGRPC, Getters: factory})
+	check := func(want map[string]string) {
+		t.Helper()
+		protocols := h.NodeProtocols()
+		for node, addr := range want {
+			getter := h.GetNodeGetters()[node].(*stubGetter)
+			if getter.addr != addr || protocols[node] != getter.protocol {
+				t.Fatalf("%s: getter %s %q, 记录的协议 %s, want 地址 %q", node, getter.protocol, getter.addr, protocols[node], addr)
+			}
+		}
+	}
+
+	h.UpdatePeers(mixedCluster("grpc", "http"))
+	check(map[string]string{
+		"node-a": "10.0.0.1:9090",                  // 旧版本节点使用默认协议
+		"node-b": "http://10.0.0.2:8001/_gocache/", // 只提供 HTTP
+		"node-c": "10.0.0.3:9090",                  // 优先 gRPC
+	})
+	before := h.GetNodeGetters()
+
+	// 运行期间 node-c 不再提供 gRPC（例如改为部署在代理之后）
+	h.UpdatePeers(mixedCluster("http"))
+	check(map[string]string{"node-c": "http://10.0.0.3:8001/_gocache/"})
+	after := h.GetNodeGetters()
+	if after["node-a"] != before["node-a"] || after["node-b"] != before["node-b"] || after["node-c"] == before["node-c"] {
+		t.Fatal("只有协议变化的节点应重建 getter")
+	}
+	if n := factory.count(); n != 4 {
+		t.Fatalf("创建了 %d 个 getter，want 4", n)
+	}
+
+	// 重新提供 gRPC
+	h.UpdatePeers(mixedCluster("grpc"))
+	check(map[string]string{"node-c": "10.0.0.3:9090"})
+
+	// 离开集群的节点不再出现在协议列表中
+	h.UpdatePeers(mixedCluster("grpc")[:2])
+	if protocols := h.NodeProtocols(); len(protocols) != 2 || protocols["node-c"] != "" {
+		t.Fatalf("节点离开后的协议 = %v", protocols)
+	}
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/internal/auth"
+	"github.com/AdrianWangs/go-cache/internal/peers"
+	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
+)
+
+const (
+	defaultRequestTimeout = 3 * time.Second // 默认请求超时
+	defaultDialTimeout    = 2 * time.Second // 默认建立连接超时
+)
+
+// GetterOption 配置 NodeGetter 的可选参数
+type GetterOption func(*getterConfig)
+
+// getterConfig NodeGetter 的公共配置
+type getterConfig struct {
+	requestTimeout time.Duration // 单次请求超时
+	dialTimeout    time.Duration // 建立连接超时（仅gRPC使用）
+	signer         *auth.Signer  // 请求签名，为 nil 时不签名
+	limit          peers.Limit   // 每个节点未完成请求数的上限
+}
+
+// newGetterConfig 使用默认值创建配置并应用选项
+func newGetterConfig(opts ...GetterOption) getterConfig {
+	cfg := getterConfig{
+		requestTimeout: defaultRequestTimeout,
+		dialTimeout:    defaultDialTimeout,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+// WithRequestTimeout 设置单次请求超时
+func WithRequestTimeout(timeout time.Duration) GetterOption {
+	return func(c *getterConfig) {
+		if timeout > 0 {
+			c.requestTimeout = timeout
+		}
+	}
+}
+
+// WithDialTimeout 设置建立连接的超时
+func WithDialTimeout(timeout time.Duration) GetterOption {
+	return func(c *getterConfig) {
+		if timeout > 0 {
+			c.dialTimeout = timeout
+		}
+	}
+}
+
+// WithSigner 使用 signer 对发往缓存节点的每个请求签名，节点配置了相同密钥时才会接受
+func WithSigner(signer *auth.Signer) GetterOption {
+	return func(c *getterConfig) {
+		c.signer = signer
+	}
+}
+
+// WithPeerLimit 限制发往每个节点的未完成请求数：超出上限的请求最多等待 limit.QueueWait，
+// 仍没有空位时不发出，返回 peers.ErrPeerBusy，避免一个慢节点占住大量 goroutine
+func WithPeerLimit(limit peers.Limit) GetterOption {
+	return func(c *getterConfig) {
+		c.limit = limit
+	}
+}
+
+// httpClient 返回 HTTP 协议的 getter 使用的客户端：未配置签名时使用共享的默认客户端
+func (c getterConfig) httpClient() HTTPClient {
+	if c.signer == nil {
+		return defaultHTTPClient
+	}
+	return &http.Client{Transport: c.signer.Transport(nil)}
+}
+
+// countedGetter 由记录请求统计的 NodeGetter 实现。CacheHandler 为每个节点保存一份计数器，
+// 在创建 getter 后注入，节点地址变化导致 getter 重建时统计不会丢失
+type countedGetter interface {
+	setCounters(c *peers.Counters)
+}
+
+// versionedGetter 由记录节点协议版本的 NodeGetter 实现。节点在注册信息中登记的版本在创建或更新
+// getter 时写入，之后以节点响应中声明的版本为准
+type versionedGetter interface {
+	protoVersion() *peers.PeerVersion
+}
+
+// nodeSetter 由能把值写入节点自身缓存的 NodeGetter 实现，读修复通过它修复副本节点，缓存反向代理通过它写入从上游获取的值。
+// gRPC 没有写入调用，GRPCGetter 不实现它
+type nodeSetter interface {
+	Set(ctx context.Context, req *pb.SetRequest) error
+}
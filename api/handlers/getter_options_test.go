@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestGetterConfigDefaults(t *testing.T) {
+	cfg := newGetterConfig()
+	if cfg.requestTimeout != defaultRequestTimeout || cfg.dialTimeout != defaultDialTimeout {
+		t.Fatalf("默认值 = %v/%v", cfg.requestTimeout, cfg.dialTimeout)
+	}
+
+	// 非正数不覆盖默认值
+	cfg = newGetterConfig(WithRequestTimeout(0), WithDialTimeout(-time.Second))
+	if cfg.requestTimeout != defaultRequestTimeout || cfg.dialTimeout != defaultDialTimeout {
+		t.Fatalf("非正数覆盖了默认值: %v/%v", cfg.requestTimeout, cfg.dialTimeout)
+	}
+
+	cfg = newGetterConfig(WithRequestTimeout(time.Second), WithDialTimeout(2*time.Second))
+	if cfg.requestTimeout != time.Second || cfg.dialTimeout != 2*time.Second {
+		t.Fatalf("选项未生效: %v/%v", cfg.requestTimeout, cfg.dialTimeout)
+	}
+}
+
+// TestHTTPGetterRequestTimeout 节点迟迟不响应时，请求在配置的超时后返回
+func TestHTTPGetterRequestTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	const timeout = 100 * time.Millisecond
+	getter := NewHTTPGetter(srv.URL+"/_gocache/", WithRequestTimeout(timeout))
+	start := time.Now()
+	if _, err := getter.Get(context.Background(), "scores", "Tom"); err == nil {
+		t.Fatal("慢节点的请求没有超时")
+	}
+	if elapsed := time.Since(start); elapsed < timeout || elapsed > timeout+time.Second {
+		t.Fatalf("请求在 %v 后返回，期望约 %v", elapsed, timeout)
+	}
+}
+
+// TestGRPCGetterDialTimeout 节点接受连接但不完成握手时，建立连接在配置的超时后失败
+func TestGRPCGetterDialTimeout(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { lis.Close() })
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			// 只读不写，客户端关闭连接后退出
+			go io.Copy(io.Discard, conn)
+		}
+	}()
+
+	const timeout = 100 * time.Millisecond
+	getter := NewGRPCGetter(lis.Addr().String(), WithDialTimeout(timeout), WithRequestTimeout(10*time.Second))
+	t.Cleanup(func() { getter.Close() })
+	start := time.Now()
+	if _, err := getter.Get(context.Background(), "scores", "Tom"); err == nil {
+		t.Fatal("握手不完成的节点连接成功")
+	}
+	if elapsed := time.Since(start); elapsed < timeout || elapsed > timeout+time.Second {
+		t.Fatalf("连接在 %v 后失败，期望约 %v", elapsed, timeout)
+	}
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/AdrianWangs/go-cache/internal/discovery"
+)
+
+// ErrorCodeGroupNotFound 结构化错误响应中表示组不存在的错误码
+const ErrorCodeGroupNotFound = "GroupNotFound"
+
+// ErrorResponse 结构化的错误响应
+type ErrorResponse struct {
+	Error   string `json:"error"`           // 错误码
+	Group   string `json:"group,omitempty"` // 相关的缓存组
+	Message string `json:"message"`         // 错误描述
+}
+
+// groupRegistry 集群的缓存组注册表，由节点注册信息中登记的组列表构建
+type groupRegistry struct {
+	groups   map[string]int // 组名到提供该组的节点数
+	complete bool           // 所有节点都登记了组信息，只有此时才能判定组不存在
+}
+
+// newGroupRegistry 根据节点列表构建注册表
+func newGroupRegistry(nodes []discovery.NodeInfo) groupRegistry {
+	reg := groupRegistry{
+		groups:   make(map[string]int),
+		complete: len(nodes) > 0,
+	}
+	for _, node := range nodes {
+		if !node.HasGroupInfo() {
+			// 旧版本节点没有登记组信息，无法在本地判定组是否存在
+			reg.complete = false
+			continue
+		}
+		for _, g := range node.Groups {
+			reg.groups[g]++
+		}
+	}
+	return reg
+}
+
+// unknown 报告 name 是否确定不是集群中的缓存组
+func (reg groupRegistry) unknown(name string) bool {
+	if !reg.complete {
+		return false
+	}
+	_, ok := reg.groups[name]
+	return !ok
+}
+
+// isUnknownGroup 报告 name 是否确定不是集群中的缓存组。
+// 有节点未登记组信息时无法判定，总是返回 false，由节点自行检查
+func (h *CacheHandler) isUnknownGroup(name string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.registry.unknown(name)
+}
+
+// RegisteredGroups 返回注册表中的组及提供该组的节点数；
+// complete 为 false 表示有节点未登记组信息，列表可能不完整
+func (h *CacheHandler) RegisteredGroups() (groups map[string]int, complete bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	groups = make(map[string]int, len(h.registry.groups))
+	for name, n := range h.registry.groups {
+		groups[name] = n
+	}
+	return groups, h.registry.complete
+}
+
+// writeGroupNotFound 返回结构化的 404 GroupNotFound 错误
+func writeGroupNotFound(w http.ResponseWriter, group string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusNotFound)
+	json.NewEncoder(w).Encode(ErrorResponse{
+		Error:   ErrorCodeGroupNotFound,
+		Group:   group,
+		Message: fmt.Sprintf("Group not found: %s", group),
+	})
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/AdrianWangs/go-cache/internal/discovery"
+)
+
+// nodesWithGroups 返回登记了 groups 的 n 个节点
+func nodesWithGroups(n int, groups ...string) []discovery.NodeInfo {
+	nodes := make([]discovery.NodeInfo, n)
+	for i := range nodes {
+		nodes[i] = discovery.NodeInfo{GRPCAddr: fmt.Sprintf("10.0.0.%d:9090", i+1), Groups: groups}
+	}
+	return nodes
+}
+
+// serveCache 以 method 请求 /api/cache/{group}/k
+func serveCache(h *CacheHandler, method, group string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, "/api/cache/"+group+"/k", nil)
+	w := httptest.NewRecorder()
+	if method == http.MethodDelete {
+		h.DeleteCacheHandler(w, r)
+	} else {
+		h.GetCacheHandler(w, r)
+	}
+	return w
+}
+
+func TestUnknownGroupRejectedLocally(t *testing.T) {
+	factory := &recordingFactory{}
+	h := NewCacheHandler("/_gocache/", 50, CacheHandlerOptions{Getters: factory})
+	h.UpdatePeers(nodesWithGroups(3, "scores"))
+
+	for _, method := range []string{http.MethodGet, http.MethodDelete} {
+		w := serveCache(h, method, "users")
+		if w.Code != http.StatusNotFound {
+			t.Fatalf("%s: status = %d, want 404", method, w.Code)
+		}
+		var resp ErrorResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		if resp.Error != ErrorCodeGroupNotFound || resp.Group != "users" {
+			t.Fatalf("%s: response = %+v", method, resp)
+		}
+	}
+	if n := factory.calls(); n != 0 {
+		t.Fatalf("未知组的请求发往了节点 %d 次", n)
+	}
+
+	// 已知组照常转发到节点
+	serveCache(h, http.MethodGet, "scores")
+	if n := factory.calls(); n == 0 {
+		t.Fatal("已知组的请求没有发往节点")
+	}
+}
+
+// TestNewGroupVisibleAfterOneRefresh 节点新增组后，下一次注册信息刷新即可访问
+func TestNewGroupVisibleAfterOneRefresh(t *testing.T) {
+	factory := &recordingFactory{}
+	h := NewCacheHandler("/_gocache/", 50, CacheHandlerOptions{Getters: factory})
+	h.UpdatePeers(nodesWithGroups(3, "scores"))
+	if !h.isUnknownGroup("users") {
+		t.Fatal("users 不应存在")
+	}
+
+	h.UpdatePeers(nodesWithGroups(3, "scores", "users"))
+	if h.isUnknownGroup("users") {
+		t.Fatal("刷新后 users 仍被判定为未知")
+	}
+	before := factory.calls()
+	if w := serveCache(h, http.MethodGet, "users"); w.Code == http.StatusNotFound && factory.calls() == before {
+		t.Fatal("刷新后 users 的请求仍在本地被拒绝")
+	}
+	groups, complete := h.RegisteredGroups()
+	if !complete || groups["users"] != 3 || groups["scores"] != 3 {
+		t.Fatalf("RegisteredGroups = %v, %v", groups, complete)
+	}
+}
+
+// TestLegacyNodeDisablesLocalCheck 有节点未登记组信息时无法判定组不存在，请求交给节点检查
+func TestLegacyNodeDisablesLocalCheck(t *testing.T) {
+	h := NewCacheHandler("/_gocache/", 50, CacheHandlerOptions{Getters: &recordingFactory{}})
+	nodes := append(nodesWithGroups(2, "scores"), discovery.ParseNodeInfo("10.0.0.9:9090"))
+	h.UpdatePeers(nodes)
+	if h.isUnknownGroup("users") {
+		t.Fatal("注册表不完整时判定了组不存在")
+	}
+	if _, complete := h.RegisteredGroups(); complete {
+		t.Fatal("注册表不应完整")
+	}
+
+	// 没有节点时同样无法判定
+	empty := NewCacheHandler("/_gocache/", 50, CacheHandlerOptions{Getters: &recordingFactory{}})
+	if empty.isUnknownGroup("users") {
+		t.Fatal("没有节点时判定了组不存在")
+	}
+}
+
+func TestGroupsListingFromRegistry(t *testing.T) {
+	h := NewCacheHandler("/_gocache/", 50, CacheHandlerOptions{Getters: &recordingFactory{}})
+	h.UpdatePeers(nodesWithGroups(2, "scores", "users"))
+
+	w := httptest.NewRecorder()
+	h.GetGroupsHandler(w, httptest.NewRequest(http.MethodGet, "/api/groups", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d", w.Code)
+	}
+	var resp GroupsResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if !resp.RegistryComplete || len(resp.Groups) != 2 || resp.Groups[0].Name != "scores" || resp.Groups[1].RegisteredNodes != 2 {
+		t.Fatalf("response = %+v", resp)
+	}
+
+	w = httptest.NewRecorder()
+	h.GetGroupsHandler(w, httptest.NewRequest(http.MethodGet, "/api/groups?group=missing", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("unknown group filter: status = %d, want 404", w.Code)
+	}
+}
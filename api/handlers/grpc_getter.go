@@ -3,27 +3,47 @@ package handlers
 import (
 	"context"
 	"fmt"
+	"io"
 	"time"
 
+	"github.com/AdrianWangs/go-cache/internal/auth"
+	"github.com/AdrianWangs/go-cache/internal/cache"
+	"github.com/AdrianWangs/go-cache/internal/peers"
+	"github.com/AdrianWangs/go-cache/pkg/cacheerrors"
 	"github.com/AdrianWangs/go-cache/pkg/logger"
 	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 )
 
 // GRPCGetter 实现从gRPC缓存节点获取数据的NodeGetter接口
 type GRPCGetter struct {
-	addr    string              // 服务器地址 (格式: host:port)
-	timeout time.Duration       // 请求超时
-	conn    *grpc.ClientConn    // gRPC连接
-	client  pb.GroupCacheClient // gRPC客户端
+	addr        string              // 服务器地址 (格式: host:port)
+	timeout     time.Duration       // 请求超时
+	dialTimeout time.Duration       // 建立连接超时
+	conn        *grpc.ClientConn    // gRPC连接
+	client      pb.GroupCacheClient // gRPC客户端
+	counters    *peers.Counters     // 请求与错误统计，为 nil 时不记录
+	limit       peers.Limit         // 未完成请求数的上限，与 counters 一起按节点保留
+	signer      *auth.Signer        // 请求签名，为 nil 时不签名
+
+	version peers.PeerVersion // 节点在响应头或注册信息中声明的协议版本
 }
 
 // NewGRPCGetter 创建一个新的gRPC缓存数据获取器
-func NewGRPCGetter(addr string) *GRPCGetter {
+func NewGRPCGetter(addr string, opts ...GetterOption) *GRPCGetter {
+	cfg := newGetterConfig(opts...)
 	return &GRPCGetter{
-		addr:    addr,
-		timeout: 3 * time.Second, // 默认超时时间
+		addr:        addr,
+		timeout:     cfg.requestTimeout,
+		dialTimeout: cfg.dialTimeout,
+		signer:      cfg.signer,
+		counters:    new(peers.Counters),
+		limit:       cfg.limit,
 	}
 }
 
@@ -34,11 +54,19 @@ func (g *GRPCGetter) ensureConnection() error {
 	}
 
 	// 创建新连接
-	conn, err := grpc.Dial(g.addr,
+	dialOpts := []grpc.DialOption{
 		grpc.WithTransportCredentials(insecure.NewCredentials()),
 		grpc.WithBlock(),
-		grpc.WithTimeout(2*time.Second),
-	)
+		grpc.WithTimeout(g.dialTimeout),
+		grpc.WithChainUnaryInterceptor(g.observeVersion),
+	}
+	if g.signer != nil {
+		dialOpts = append(dialOpts,
+			grpc.WithUnaryInterceptor(g.signer.UnaryClientInterceptor()),
+			grpc.WithStreamInterceptor(g.signer.StreamClientInterceptor()),
+		)
+	}
+	conn, err := grpc.Dial(g.addr, dialOpts...)
 	if err != nil {
 		return fmt.Errorf("无法连接到gRPC服务器 %s: %v", g.addr, err)
 	}
@@ -49,6 +77,22 @@ func (g *GRPCGetter) ensureConnection() error {
 	return nil
 }
 
+// observeVersion 从每次一元调用的响应头中记录节点声明的协议版本
+func (g *GRPCGetter) observeVersion(ctx context.Context, method string, req, reply interface{},
+	cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	var header metadata.MD
+	err := invoker(ctx, method, req, reply, cc, append(opts, grpc.Header(&header))...)
+	if v := header.Get(peers.MetadataProtoVersion); len(v) > 0 {
+		g.version.Set(peers.ParseProtoVersion(v[0]))
+	}
+	return err
+}
+
+// protoVersion 返回记录节点协议版本的位置
+func (g *GRPCGetter) protoVersion() *peers.PeerVersion {
+	return &g.version
+}
+
 // Close 关闭gRPC连接
 func (g *GRPCGetter) Close() error {
 	if g.conn != nil {
@@ -60,25 +104,38 @@ func (g *GRPCGetter) Close() error {
 	return nil
 }
 
-// Get 从gRPC缓存节点获取数据
-func (g *GRPCGetter) Get(group string, key string) ([]byte, error) {
+// Get 从gRPC缓存节点获取数据，ctx 取消时调用随之中止
+func (g *GRPCGetter) Get(ctx context.Context, group string, key string) ([]byte, error) {
+	req := &pb.Request{
+		Group: group,
+		Key:   key,
+	}
+	release, err := g.counters.Acquire(ctx, g.limit)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	call := g.counters.Start(proto.Size(req))
+
 	// 确保连接已建立
 	if err := g.ensureConnection(); err != nil {
+		call.FailClass(peers.ErrConnRefused)
 		return nil, err
 	}
 
-	// 创建带超时的上下文
-	ctx, cancel := context.WithTimeout(context.Background(), g.timeout)
+	// 在调用方上下文的基础上应用请求超时
+	ctx, cancel := context.WithTimeout(ctx, g.timeout)
 	defer cancel()
 
 	// 发送gRPC请求
-	req := &pb.Request{
-		Group: group,
-		Key:   key,
-	}
-
 	resp, err := g.client.Get(ctx, req)
 	if err != nil {
+		call.Fail(err)
+		if ctx.Err() != nil {
+			// 调用方已取消或已超时，重试没有意义
+			return nil, err
+		}
 		// 如果是连接问题，尝试重连
 		logger.Warnf("gRPC调用失败: %v，将尝试重连", err)
 		g.Close() // 关闭旧连接
@@ -95,22 +152,47 @@ func (g *GRPCGetter) Get(group string, key string) ([]byte, error) {
 		}
 	}
 
+	call.Received(proto.Size(resp))
 	return resp.Value, nil
 }
 
-// GetByProto 通过protobuf从gRPC缓存节点获取数据
-func (g *GRPCGetter) GetByProto(req *pb.Request, resp *pb.Response) error {
+// GetByProto 通过protobuf从gRPC缓存节点获取数据，ctx 取消时调用随之中止
+func (g *GRPCGetter) GetByProto(ctx context.Context, req *pb.Request, resp *pb.Response) error {
+	release, err := g.counters.Acquire(ctx, g.limit)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	call := g.counters.Start(proto.Size(req))
+
 	// 确保连接已建立
 	if err := g.ensureConnection(); err != nil {
+		call.FailClass(peers.ErrConnRefused)
 		return err
 	}
 
-	// 创建带超时的上下文
-	ctx, cancel := context.WithTimeout(context.Background(), g.timeout)
+	// 在调用方上下文的基础上应用请求超时
+	ctx, cancel := context.WithTimeout(ctx, g.timeout)
 	defer cancel()
 
 	// 发送gRPC请求
 	result, err := g.client.Get(ctx, req)
+	if cacheErr := cacheerrors.ErrorFromGRPC(err); cacheErr != nil {
+		// 节点返回了缓存错误（键或组不存在、限流、缺失策略不允许回源、数据源熔断等），
+		// 连接本身正常，重连重试同一节点没有意义。数据源熔断同样是 Unavailable，不按连接失败统计
+		if cacheerrors.IsOriginUnavailableError(cacheErr) {
+			call.FailClass(peers.ErrStatus)
+		} else {
+			call.Fail(err)
+		}
+		return cacheErr
+	}
+	call.Fail(err)
+	if err != nil && ctx.Err() != nil {
+		// 调用方已取消或已超时，重试没有意义
+		return err
+	}
 	if err != nil {
 		// 如果是连接问题，尝试重连
 		logger.Warnf("gRPC调用失败: %v，将尝试重连", err)
@@ -129,34 +211,61 @@ func (g *GRPCGetter) GetByProto(req *pb.Request, resp *pb.Response) error {
 	}
 
 	// 复制结果到响应
+	call.Received(proto.Size(result))
 	resp.Value = result.Value
 	return nil
 }
 
+// setCounters 设置记录请求与错误统计的计数器
+func (g *GRPCGetter) setCounters(c *peers.Counters) {
+	g.counters = c
+}
+
 // SetTimeout 设置请求超时时间
 func (g *GRPCGetter) SetTimeout(timeout time.Duration) {
 	g.timeout = timeout
 }
 
-// Delete 从gRPC缓存节点删除指定的缓存项
-func (g *GRPCGetter) Delete(group string, key string) error {
+// Delete 从gRPC缓存节点删除指定的缓存项，ctx 取消时调用随之中止
+func (g *GRPCGetter) Delete(ctx context.Context, group string, key string) error {
+	// 创建请求
+	req := &pb.DeleteRequest{
+		Group: group,
+		Key:   key,
+	}
+	release, err := g.counters.Acquire(ctx, g.limit)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	call := g.counters.Start(proto.Size(req))
+
 	// 确保连接已建立
 	if err := g.ensureConnection(); err != nil {
+		call.FailClass(peers.ErrConnRefused)
 		return err
 	}
 
-	// 创建带超时的上下文
-	ctx, cancel := context.WithTimeout(context.Background(), g.timeout)
+	// 在调用方上下文的基础上应用请求超时
+	ctx, cancel := context.WithTimeout(ctx, g.timeout)
 	defer cancel()
 
-	// 创建请求
-	req := &pb.DeleteRequest{
-		Group: group,
-		Key:   key,
-	}
-
 	// 发送gRPC请求
-	_, err := g.client.Delete(ctx, req)
+	_, err = g.client.Delete(ctx, req)
+	call.Fail(err)
+	if cacheErr := cacheerrors.ErrorFromGRPC(err); cacheErr != nil {
+		// 节点只读、键为空或组不存在，重试没有意义
+		return cacheErr
+	}
+	if status.Code(err) == codes.FailedPrecondition {
+		// 早于统一错误映射的节点只读时的状态信息可能不是 ErrReadOnly 的信息
+		return cache.ErrReadOnly
+	}
+	if err != nil && ctx.Err() != nil {
+		// 调用方已取消或已超时，重试没有意义
+		return err
+	}
 	if err != nil {
 		// 如果是连接问题，尝试重连
 		logger.Warnf("gRPC Delete调用失败: %v，将尝试重连", err)
@@ -176,3 +285,64 @@ func (g *GRPCGetter) Delete(group string, key string) error {
 
 	return nil
 }
+
+// Stats 通过gRPC获取节点统计信息
+func (g *GRPCGetter) Stats(ctx context.Context) (*pb.StatsResponse, error) {
+	if err := g.version.Check(peers.FeatureStats); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrStatsUnimplemented, err)
+	}
+
+	// 确保连接已建立
+	if err := g.ensureConnection(); err != nil {
+		return nil, err
+	}
+
+	// 在调用方上下文的基础上应用请求超时
+	ctx, cancel := context.WithTimeout(ctx, g.timeout)
+	defer cancel()
+
+	resp, err := g.client.Stats(ctx, &pb.StatsRequest{})
+	if err != nil {
+		if status.Code(err) == codes.Unimplemented {
+			return nil, fmt.Errorf("%w: %v", ErrStatsUnimplemented, err)
+		}
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ExportGroup 以流的形式导出节点上指定组的条目，fn 返回错误时中止导出
+func (g *GRPCGetter) ExportGroup(ctx context.Context, group string, fn func(*pb.ExportEntry) error) error {
+	// 确保连接已建立
+	if err := g.ensureConnection(); err != nil {
+		return err
+	}
+
+	// 导出可能持续较长时间，由调用方的上下文控制生命周期
+	stream, err := g.client.Export(ctx, &pb.ExportRequest{Group: group})
+	if err != nil {
+		return err
+	}
+
+	for {
+		entry, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+}
+
+// ImportGroup 打开到节点的导入流，调用方负责 Send 条目并 CloseAndRecv 获取结果
+func (g *GRPCGetter) ImportGroup(ctx context.Context) (pb.GroupCache_ImportClient, error) {
+	// 确保连接已建立
+	if err := g.ensureConnection(); err != nil {
+		return nil, err
+	}
+	return g.client.Import(ctx)
+}
@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/internal/peers"
+	"github.com/AdrianWangs/go-cache/pkg/logger"
+	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
+	"google.golang.org/protobuf/proto"
+)
+
+// defaultHedgeBudget 未指定预算时，对冲请求占读请求的最大百分比
+const defaultHedgeBudget = 5.0
+
+// HedgeConfig 对冲读取配置。
+// 主节点在 Delay 内未响应时，向哈希环上的下一个节点发送同样的读请求，取先返回的成功结果
+type HedgeConfig struct {
+	Delay         time.Duration // 发出对冲请求前的等待时间，0 表示关闭对冲
+	BudgetPercent float64       // 对冲请求数占读请求数的最大百分比，<=0 时使用默认值 5
+}
+
+// HedgeStats 对冲读取统计
+type HedgeStats struct {
+	Requests int64 `json:"requests"` // 可对冲的读请求数
+	Hedged   int64 `json:"hedged"`   // 实际发出的对冲请求数
+	HedgeWon int64 `json:"hedgeWon"` // 对冲请求先于主请求返回的次数
+}
+
+// hedger 记录对冲统计并执行预算控制
+type hedger struct {
+	delay    time.Duration
+	budget   float64
+	requests int64
+	hedged   int64
+	won      int64
+}
+
+// newHedger 根据配置创建 hedger，未开启对冲时返回 nil
+func newHedger(cfg HedgeConfig) *hedger {
+	if cfg.Delay <= 0 {
+		return nil
+	}
+	budget := cfg.BudgetPercent
+	if budget <= 0 {
+		budget = defaultHedgeBudget
+	}
+	return &hedger{delay: cfg.Delay, budget: budget}
+}
+
+// allow 在预算内时占用一次对冲名额
+func (h *hedger) allow() bool {
+	for {
+		hedged := atomic.LoadInt64(&h.hedged)
+		limit := float64(atomic.LoadInt64(&h.requests)) * h.budget / 100
+		if float64(hedged+1) > limit {
+			return false
+		}
+		if atomic.CompareAndSwapInt64(&h.hedged, hedged, hedged+1) {
+			return true
+		}
+	}
+}
+
+// stats 返回当前的对冲统计
+func (h *hedger) stats() HedgeStats {
+	if h == nil {
+		return HedgeStats{}
+	}
+	return HedgeStats{
+		Requests: atomic.LoadInt64(&h.requests),
+		Hedged:   atomic.LoadInt64(&h.hedged),
+		HedgeWon: atomic.LoadInt64(&h.won),
+	}
+}
+
+// hedgeResult 一次节点读取的结果
+type hedgeResult struct {
+	node  string
+	resp  *pb.Response
+	err   error
+	hedge bool
+}
+
+// HedgeStats 返回对冲读取统计，未开启对冲时全部为 0
+func (h *CacheHandler) HedgeStats() HedgeStats {
+	return h.hedger.stats()
+}
+
+// getWithHedge 从 nodes[0] 读取 key 对应的值并写入 res，返回最终提供结果的节点。
+// 只用于幂等的读请求：主请求超过对冲延迟未返回且预算允许时，向 nodes[1] 发出对冲请求，
+// 先成功的结果胜出，另一个请求通过 ctx 取消。nodes[0] 未完成的请求已达上限（peers.ErrPeerBusy）时
+// 请求没有发出，不论是否开启对冲都立即改由 nodes[1] 读取
+func (h *CacheHandler) getWithHedge(ctx context.Context, key string, nodes []string, getters []NodeGetter, req *pb.Request, res *pb.Response) (string, error) {
+	if h.hedger == nil || len(getters) < 2 {
+		err := getters[0].GetByProto(ctx, req, res)
+		if errors.Is(err, peers.ErrPeerBusy) && len(getters) > 1 {
+			// 主节点未完成的请求已达上限，请求没有发出，改由下一个节点读取
+			logger.Debugf("节点 %s 繁忙，改向 %s 读取: key=%s", nodes[0], nodes[1], logger.Key(key))
+			return nodes[1], getters[1].GetByProto(ctx, req, res)
+		}
+		return nodes[0], err
+	}
+	atomic.AddInt64(&h.hedger.requests, 1)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel() // 返回时取消仍在进行的请求
+
+	results := make(chan hedgeResult, 2)
+	send := func(i int, hedge bool) {
+		go func() {
+			resp := &pb.Response{}
+			err := getters[i].GetByProto(ctx, req, resp)
+			results <- hedgeResult{node: nodes[i], resp: resp, err: err, hedge: hedge}
+		}()
+	}
+	send(0, false)
+
+	timer := time.NewTimer(h.hedger.delay)
+	defer timer.Stop()
+
+	pending := 1
+	sent := false // 已经向 nodes[1] 发出请求
+	var firstErr error
+	for {
+		select {
+		case <-timer.C:
+			if !sent && h.hedger.allow() {
+				sent = true
+				logger.Debugf("节点 %s 超过 %v 未响应，向 %s 发出对冲请求: key=%s",
+					nodes[0], h.hedger.delay, nodes[1], logger.Key(key))
+				send(1, true)
+				pending++
+			}
+		case r := <-results:
+			pending--
+			if !r.hedge && !sent && errors.Is(r.err, peers.ErrPeerBusy) {
+				// 主节点繁忙，请求没有发出：不占用对冲预算，立即改由下一个节点读取
+				logger.Debugf("节点 %s 繁忙，改向 %s 读取: key=%s", nodes[0], nodes[1], logger.Key(key))
+				sent = true
+				send(1, false)
+				pending++
+				continue
+			}
+			if r.err == nil {
+				if r.hedge {
+					atomic.AddInt64(&h.hedger.won, 1)
+				}
+				// 复制完整的响应，包括过期时间、版本和复制情况等元数据
+				proto.Reset(res)
+				proto.Merge(res, r.resp)
+				return r.node, nil
+			}
+			if !r.hedge || firstErr == nil {
+				firstErr = r.err
+			}
+			if pending == 0 {
+				// 主请求在对冲发出前失败，或两个请求都失败
+				return nodes[0], firstErr
+			}
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/internal/peers"
+	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
+)
+
+// latencyGetter 在 delay 后返回 value 或 err，ctx 先取消时记录取消并返回
+type latencyGetter struct {
+	stubGetter
+	delay     time.Duration
+	value     string
+	err       error
+	cancelled chan struct{}
+}
+
+func newLatencyGetter(delay time.Duration, value string, err error) *latencyGetter {
+	return &latencyGetter{delay: delay, value: value, err: err, cancelled: make(chan struct{}, 1)}
+}
+
+func (g *latencyGetter) GetByProto(ctx context.Context, req *pb.Request, resp *pb.Response) error {
+	select {
+	case <-time.After(g.delay):
+	case <-ctx.Done():
+		g.cancelled <- struct{}{}
+		return ctx.Err()
+	}
+	if g.err != nil {
+		return g.err
+	}
+	resp.Value = []byte(g.value)
+	return nil
+}
+
+// hedgeRead 以两个节点 a、b 执行一次可对冲的读取
+func hedgeRead(h *CacheHandler, a, b NodeGetter) (string, *pb.Response, error) {
+	res := &pb.Response{}
+	node, err := h.getWithHedge(context.Background(), "k", []string{"a", "b"}, []NodeGetter{a, b},
+		&pb.Request{Group: "g", Key: "k"}, res)
+	return node, res, err
+}
+
+func newHedgeHandler(delay time.Duration, budget float64) *CacheHandler {
+	return NewCacheHandler("/_gocache/", 50, CacheHandlerOptions{
+		Getters: &recordingFactory{},
+		Hedge:   HedgeConfig{Delay: delay, BudgetPercent: budget},
+	})
+}
+
+func TestHedgeFastReplicaWins(t *testing.T) {
+	h := newHedgeHandler(10*time.Millisecond, 100)
+	slow := newLatencyGetter(5*time.Second, "slow", nil)
+	fast := newLatencyGetter(0, "fast", nil)
+
+	start := time.Now()
+	node, res, err := hedgeRead(h, slow, fast)
+	if err != nil || node != "b" || string(res.Value) != "fast" {
+		t.Fatalf("hedged read = %s %q %v, want b fast", node, res.Value, err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("hedged read took %v", elapsed)
+	}
+	select {
+	case <-slow.cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("慢节点的请求没有被取消")
+	}
+	if stats := h.HedgeStats(); stats != (HedgeStats{Requests: 1, Hedged: 1, HedgeWon: 1}) {
+		t.Fatalf("stats = %+v", stats)
+	}
+}
+
+func TestHedgeNotSentForFastPrimary(t *testing.T) {
+	h := newHedgeHandler(time.Second, 100)
+	node, res, err := hedgeRead(h, newLatencyGetter(0, "a", nil), newLatencyGetter(0, "b", nil))
+	if err != nil || node != "a" || string(res.Value) != "a" {
+		t.Fatalf("read = %s %q %v", node, res.Value, err)
+	}
+	if stats := h.HedgeStats(); stats.Hedged != 0 {
+		t.Fatalf("stats = %+v, want no hedge", stats)
+	}
+}
+
+func TestHedgeBudget(t *testing.T) {
+	h := newHedgeHandler(time.Millisecond, 10)
+	// 10% 的预算下每 10 个读请求最多对冲 1 个
+	for i := 0; i < 20; i++ {
+		if _, _, err := hedgeRead(h, newLatencyGetter(20*time.Millisecond, "a", nil), newLatencyGetter(0, "b", nil)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if stats := h.HedgeStats(); stats.Requests != 20 || stats.Hedged != 2 {
+		t.Fatalf("stats = %+v, want 2 of 20 hedged", stats)
+	}
+}
+
+func TestHedgeBothFail(t *testing.T) {
+	h := newHedgeHandler(time.Millisecond, 100)
+	errA, errB := errors.New("a failed"), errors.New("b failed")
+	node, _, err := hedgeRead(h, newLatencyGetter(20*time.Millisecond, "", errA), newLatencyGetter(0, "", errB))
+	if node != "a" || !errors.Is(err, errA) {
+		t.Fatalf("read = %s %v, want the primary's error", node, err)
+	}
+}
+
+func TestBusyPrimaryFailsOverWithoutHedge(t *testing.T) {
+	for _, delay := range []time.Duration{0, time.Second} {
+		h := newHedgeHandler(delay, 100)
+		node, res, err := hedgeRead(h, newLatencyGetter(0, "", peers.ErrPeerBusy), newLatencyGetter(0, "b", nil))
+		if err != nil || node != "b" || string(res.Value) != "b" {
+			t.Fatalf("delay %v: read = %s %q %v, want b", delay, node, res.Value, err)
+		}
+		if stats := h.HedgeStats(); stats.Hedged != 0 {
+			t.Fatalf("delay %v: stats = %+v, failover counted as a hedge", delay, stats)
+		}
+	}
+}
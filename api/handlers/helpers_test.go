@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/AdrianWangs/go-cache/internal/cache"
+	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
+)
+
+// stubGetter 记录创建参数和调用次数的 NodeGetter，所有调用返回 cache.ErrNotFound
+type stubGetter struct {
+	protocol ProtocolType
+	addr     string
+	calls    atomic.Int64 // Get、GetByProto 和 Delete 的调用次数
+}
+
+func (g *stubGetter) Get(ctx context.Context, group, key string) ([]byte, error) {
+	g.calls.Add(1)
+	return nil, cache.ErrNotFound
+}
+
+func (g *stubGetter) GetByProto(ctx context.Context, req *pb.Request, resp *pb.Response) error {
+	g.calls.Add(1)
+	return cache.ErrNotFound
+}
+
+func (g *stubGetter) Delete(ctx context.Context, group, key string) error {
+	g.calls.Add(1)
+	return cache.ErrNotFound
+}
+
+func (g *stubGetter) DeleteBatch(ctx context.Context, group string, keys []string) ([]cache.DeleteResult, error) {
+	return nil, ErrDeleteBatchUnimplemented
+}
+
+func (g *stubGetter) Stats(ctx context.Context) (*pb.StatsResponse, error) {
+	return &pb.StatsResponse{}, nil
+}
+
+// recordingFactory 创建 stubGetter 并记录每次创建
+type recordingFactory struct {
+	mu      sync.Mutex
+	created []*stubGetter
+}
+
+func (f *recordingFactory) NewGetter(protocol ProtocolType, addr string) NodeGetter {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	g := &stubGetter{protocol: protocol, addr: addr}
+	f.created = append(f.created, g)
+	return g
+}
+
+// count 返回创建过的 getter 数
+func (f *recordingFactory) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.created)
+}
+
+// calls 返回所有 getter 的调用次数之和
+func (f *recordingFactory) calls() int64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var n int64
+	for _, g := range f.created {
+		n += g.calls.Load()
+	}
+	return n
+}
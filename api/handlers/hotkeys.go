@@ -0,0 +1,273 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/internal/access"
+	"github.com/AdrianWangs/go-cache/internal/cache"
+	"github.com/AdrianWangs/go-cache/pkg/logger"
+	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
+)
+
+// HotKeySpread 热点 key 被归属节点复制后，读请求在归属节点与副本节点之间的分配方式
+type HotKeySpread string
+
+const (
+	// SpreadRoundRobin 依次轮流选择归属节点和各副本节点（默认）
+	SpreadRoundRobin HotKeySpread = "round-robin"
+	// SpreadRendezvous 按客户端标识做 rendezvous 哈希，同一客户端固定读取同一个节点
+	SpreadRendezvous HotKeySpread = "rendezvous"
+	// SpreadOff 不分散读取，始终访问归属节点
+	SpreadOff HotKeySpread = "off"
+)
+
+// ParseHotKeySpread 解析分配方式，空字符串表示默认的 round-robin
+func ParseHotKeySpread(s string) (HotKeySpread, error) {
+	switch spread := HotKeySpread(strings.ToLower(strings.TrimSpace(s))); spread {
+	case "":
+		return SpreadRoundRobin, nil
+	case SpreadRoundRobin, SpreadRendezvous, SpreadOff:
+		return spread, nil
+	default:
+		return "", fmt.Errorf("未知的热点 key 分配方式 %q，只能是 round-robin、rendezvous 或 off", s)
+	}
+}
+
+// maxHotRoutes 记录的热点 key 上限，超出时丢弃已过期的记录，仍然超出则不再记录新的 key
+const maxHotRoutes = 4096
+
+// hotRoute 一个已被复制的热点 key：归属节点之后的 replicas 个节点在 until 之前持有副本
+type hotRoute struct {
+	replicas int
+	until    time.Time
+}
+
+// HotKeyStats 热点 key 分散读取的统计
+type HotKeyStats struct {
+	Spread           HotKeySpread `json:"spread"`           // 分配方式
+	Routes           int          `json:"routes"`           // 当前记录的已复制热点 key 数
+	ReplicaReads     int64        `json:"replicaReads"`     // 由副本节点提供结果的读请求数
+	ReplicaFallbacks int64        `json:"replicaFallbacks"` // 副本节点失败后回退到归属节点的次数
+}
+
+// hotRoutes 记录节点在响应中报告的热点 key 复制情况，用于把读请求分散到副本节点
+type hotRoutes struct {
+	spread HotKeySpread
+
+	mu     sync.Mutex
+	routes map[string]hotRoute // 以 group + "\x00" + key 为键
+
+	next      uint64 // round-robin 计数
+	reads     int64
+	fallbacks int64
+}
+
+// newHotRoutes 创建热点 key 路由表，spread 为 off 时返回 nil
+func newHotRoutes(spread HotKeySpread) *hotRoutes {
+	if spread == SpreadOff {
+		return nil
+	}
+	if spread == "" {
+		spread = SpreadRoundRobin
+	}
+	return &hotRoutes{spread: spread, routes: make(map[string]hotRoute)}
+}
+
+// hotRouteKey 路由表中的键
+func hotRouteKey(group, key string) string {
+	return group + "\x00" + key
+}
+
+// lookup 返回仍在有效期内的热点 key 的副本数，未复制时返回 0
+func (t *hotRoutes) lookup(group, key string) int {
+	if t == nil {
+		return 0
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	rk := hotRouteKey(group, key)
+	route, ok := t.routes[rk]
+	if !ok {
+		return 0
+	}
+	if !time.Now().Before(route.until) {
+		delete(t.routes, rk)
+		return 0
+	}
+	return route.replicas
+}
+
+// update 根据归属节点的响应更新 key 的复制情况：报告了副本时记录，否则删除记录
+func (t *hotRoutes) update(group, key string, res *pb.Response) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	rk := hotRouteKey(group, key)
+	if res.GetReplicas() == 0 || res.ReplicatedUntil == nil {
+		delete(t.routes, rk)
+		return
+	}
+	until := time.Unix(0, res.GetReplicatedUntil())
+	if !time.Now().Before(until) {
+		delete(t.routes, rk)
+		return
+	}
+	if _, ok := t.routes[rk]; !ok && len(t.routes) >= maxHotRoutes {
+		t.pruneLocked()
+		if len(t.routes) >= maxHotRoutes {
+			return
+		}
+	}
+	t.routes[rk] = hotRoute{replicas: int(res.GetReplicas()), until: until}
+}
+
+// forget 删除 key 的记录，删除缓存后调用
+func (t *hotRoutes) forget(group, key string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.routes, hotRouteKey(group, key))
+}
+
+// pruneLocked 丢弃已过期的记录，调用方持有 t.mu
+func (t *hotRoutes) pruneLocked() {
+	now := time.Now()
+	for rk, route := range t.routes {
+		if !now.Before(route.until) {
+			delete(t.routes, rk)
+		}
+	}
+}
+
+// choose 在 n 个候选节点（下标 0 为归属节点）中为 client 选择一个
+func (t *hotRoutes) choose(nodes []string, client string) int {
+	if t.spread == SpreadRendezvous {
+		best, bestScore := 0, uint64(0)
+		for i, node := range nodes {
+			h := fnv.New64a()
+			h.Write([]byte(client))
+			h.Write([]byte{0})
+			h.Write([]byte(node))
+			if score := h.Sum64(); i == 0 || score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+		return best
+	}
+	return int(atomic.AddUint64(&t.next, 1) % uint64(len(nodes)))
+}
+
+// stats 返回分散读取的统计
+func (t *hotRoutes) stats() HotKeyStats {
+	if t == nil {
+		return HotKeyStats{Spread: SpreadOff}
+	}
+	t.mu.Lock()
+	t.pruneLocked()
+	routes := len(t.routes)
+	t.mu.Unlock()
+	return HotKeyStats{
+		Spread:           t.spread,
+		Routes:           routes,
+		ReplicaReads:     atomic.LoadInt64(&t.reads),
+		ReplicaFallbacks: atomic.LoadInt64(&t.fallbacks),
+	}
+}
+
+// HotKeyStats 返回热点 key 分散读取的统计
+func (h *CacheHandler) HotKeyStats() HotKeyStats {
+	return h.hot.stats()
+}
+
+// clientIdentity 返回 rendezvous 分配使用的客户端标识：有访问令牌时使用令牌标识，否则使用客户端 IP
+func clientIdentity(ctx context.Context, remoteAddr string) string {
+	if scope := access.ScopeFrom(ctx); scope != nil {
+		return "token:" + scope.TokenID
+	}
+	if host, _, err := net.SplitHostPort(remoteAddr); err == nil {
+		return host
+	}
+	return remoteAddr
+}
+
+// getHot 读取 key，key 已被复制时按分配方式选择归属节点或某个副本节点。
+// 副本节点失败（例如副本已过期且无法从归属节点加载）时回退到归属节点；
+// 归属节点的响应用于更新 key 的复制情况。开启读修复时，副本节点提供的结果在后台与归属节点比较，见 checkReplica
+func (h *CacheHandler) getHot(ctx context.Context, client, key string, req *pb.Request, res *pb.Response) (string, error) {
+	replicas := h.hot.lookup(req.Group, key)
+	if replicas == 0 {
+		return h.getOwnerFirst(ctx, key, req, res)
+	}
+
+	nodes, getters := h.pickNodes(key, replicas+1)
+	if len(getters) == 0 {
+		return "", errNoNode
+	}
+	i := h.hot.choose(nodes, client)
+	if i == 0 {
+		return h.getOwnerFirst(ctx, key, req, res)
+	}
+
+	// 副本节点在前，归属节点作为对冲和回退的节点
+	node, err := h.getWithHedge(ctx, key, []string{nodes[i], nodes[0]}, []NodeGetter{getters[i], getters[0]}, req, res)
+	if err == nil {
+		if node == nodes[0] {
+			h.hot.update(req.Group, key, res)
+		} else {
+			atomic.AddInt64(&h.hot.reads, 1)
+			if res.GetSource() == string(cache.SourcePeer) {
+				// 副本节点没有自己的副本，从归属节点转取了结果
+				h.repairMissing(req.Group, key, nodes[i], getters[i], res)
+			} else {
+				h.checkReplica(req.Group, key, nodes[0], getters[0], nodes[i], getters[i], res)
+			}
+		}
+		return node, nil
+	}
+	if node == nodes[0] || ctx.Err() != nil {
+		return node, err
+	}
+
+	atomic.AddInt64(&h.hot.fallbacks, 1)
+	logger.Debugf("副本节点 %s 读取失败，回退到归属节点 %s: group=%s, key=%s: %v", nodes[i], nodes[0], req.Group, logger.Key(key), err)
+	h.hot.forget(req.Group, key)
+	res.Reset()
+	if err := getters[0].GetByProto(ctx, req, res); err != nil {
+		return nodes[0], err
+	}
+	h.hot.update(req.Group, key, res)
+	if isKeyNotFound(err) {
+		// 副本节点没有归属节点上的 key
+		h.repairMissing(req.Group, key, nodes[i], getters[i], res)
+	}
+	return nodes[0], nil
+}
+
+// getOwnerFirst 从归属节点读取 key（开启对冲时下一个节点作为对冲节点），并记录归属节点报告的复制情况
+func (h *CacheHandler) getOwnerFirst(ctx context.Context, key string, req *pb.Request, res *pb.Response) (string, error) {
+	nodes, getters := h.pickNodes(key, 2)
+	if len(getters) == 0 {
+		return "", errNoNode
+	}
+	node, err := h.getWithHedge(ctx, key, nodes, getters, req, res)
+	if err == nil && node == nodes[0] {
+		h.hot.update(req.Group, key, res)
+	} else if err == nil {
+		// 对冲或改读时由下一个节点提供了结果
+		h.checkReplica(req.Group, key, nodes[0], getters[0], nodes[1], getters[1], res)
+	}
+	return node, err
+}
@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/AdrianWangs/go-cache/internal/access"
+	"github.com/AdrianWangs/go-cache/internal/cache"
+	"github.com/AdrianWangs/go-cache/internal/peers"
+	"github.com/AdrianWangs/go-cache/pkg/fanout"
+	"github.com/AdrianWangs/go-cache/pkg/logger"
+)
+
+// defaultClusterHotKeys /api/admin/hotkeys 未指定 n 时返回的热点 key 数量
+const defaultClusterHotKeys = 20
+
+// hotKeysOversample 向每个节点请求的 key 数是 n 的倍数：节点各自的前 n 个之外的 key
+// 合并后也可能进入前 n 个，多取一些可以减小截断带来的误差
+const hotKeysOversample = 2
+
+// 节点热点 key 的获取状态
+const (
+	NodeHotKeysOK      = "ok"      // 成功获取
+	NodeHotKeysSkipped = "skipped" // 节点不支持、没有该组或未开启热点统计
+	NodeHotKeysError   = "error"   // 获取失败
+)
+
+// errHotKeysUnsupported 表示节点无法提供该组的热点 key，节点记为 skipped 而不是失败
+var errHotKeysUnsupported = errors.New("hot keys not available on node")
+
+// NodeHotKeysStatus 单个节点的热点 key 获取结果
+type NodeHotKeysStatus struct {
+	Node       string `json:"node"`            // 节点标识
+	Status     string `json:"status"`          // ok / skipped / error
+	Error      string `json:"error,omitempty"` // 跳过或失败的原因
+	Keys       int    `json:"keys"`            // 节点报告的 key 数
+	DurationMs int64  `json:"durationMs"`      // 调用耗时（毫秒）
+}
+
+// ClusterHotKeysResponse /api/admin/hotkeys 响应
+type ClusterHotKeysResponse struct {
+	Group string              `json:"group"`
+	Keys  []ClusterHotKey     `json:"keys"`  // 集群范围内读取最多的 key，从多到少
+	Nodes []NodeHotKeysStatus `json:"nodes"` // 各节点状态
+}
+
+// HotKeysHandler 处理 GET /api/admin/hotkeys?group={group}&n=20：并发请求每个节点的
+// /api/admin/groups/{group}/hotkeys，合并各节点的热点 key 并按读取次数排序。
+// 请求的 Authorization 头原样转发，节点使用自己的管理令牌校验
+func (h *AdminHandler) HotKeysHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	group := query.Get("group")
+	if group == "" {
+		if h.authorize(w, r, access.AllGroups) {
+			http.Error(w, "Bad Request: group is required", http.StatusBadRequest)
+		}
+		return
+	}
+	if !h.authorize(w, r, group) {
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	n := defaultClusterHotKeys
+	if v := query.Get("n"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "Bad Request: n must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		n = parsed
+	}
+
+	nodes := h.sortedNodes()
+	if len(nodes) == 0 {
+		http.Error(w, "Service Unavailable: no cache nodes", http.StatusServiceUnavailable)
+		return
+	}
+
+	perNode := n * hotKeysOversample
+	auth := r.Header.Get("Authorization")
+	results := fanout.FanOut(r.Context(), nodes, func(ctx context.Context, node string) (cache.HotKeyReport, error) {
+		return h.nodeHotKeys(ctx, node, group, perNode, auth)
+	}, h.cacheHandler.fanOut)
+
+	resp := ClusterHotKeysResponse{Group: group, Nodes: make([]NodeHotKeysStatus, 0, len(nodes))}
+	lists := make([]nodeHotKeys, 0, len(nodes))
+	for _, res := range fanout.Ordered(nodes, results) {
+		status := NodeHotKeysStatus{Node: res.Target, Status: NodeHotKeysOK, DurationMs: res.Duration.Milliseconds()}
+		switch {
+		case errors.Is(res.Err, errHotKeysUnsupported):
+			status.Status = NodeHotKeysSkipped
+			status.Error = res.Err.Error()
+		case res.Err != nil:
+			status.Status = NodeHotKeysError
+			status.Error = res.Err.Error()
+			logger.Warnf("获取节点 %s 上组 %s 的热点 key 失败: %v", res.Target, group, res.Err)
+		default:
+			status.Keys = len(res.Value.Keys)
+			lists = append(lists, nodeHotKeys{
+				Node:      res.Target,
+				Keys:      res.Value.Keys,
+				Truncated: len(res.Value.Keys) >= perNode,
+			})
+		}
+		resp.Nodes = append(resp.Nodes, status)
+	}
+	resp.Keys = mergeHotKeys(lists, n)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// nodeHotKeys 请求单个节点上组的前 n 个热点 key。节点没有登记 HTTP 地址、版本过旧、
+// 没有该组或未开启热点统计时返回 errHotKeysUnsupported
+func (h *AdminHandler) nodeHotKeys(ctx context.Context, node, group string, n int, auth string) (cache.HotKeyReport, error) {
+	info, ok := h.cacheHandler.nodeInfo(node)
+	if !ok || info.HTTPAddr == "" {
+		return cache.HotKeyReport{}, fmt.Errorf("%w: no HTTP address registered", errHotKeysUnsupported)
+	}
+
+	u := fmt.Sprintf("http://%s/api/admin/groups/%s/hotkeys?n=%d", info.HTTPAddr, url.PathEscape(group), n)
+	cfg := newGetterConfig(h.cacheHandler.getterOpts...)
+	req, cancel, err := newRequest(ctx, http.MethodGet, u, nil, cfg.requestTimeout)
+	if err != nil {
+		return cache.HotKeyReport{}, err
+	}
+	defer cancel()
+	req.Header.Set("Authorization", auth)
+
+	res, err := defaultHTTPClient.Do(req)
+	if err != nil {
+		return cache.HotKeyReport{}, err
+	}
+	defer peers.CloseBody(res.Body)
+
+	switch res.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		// 节点没有该组，或者版本过旧不认识 hotkeys
+		return cache.HotKeyReport{}, fmt.Errorf("%w: %s", errHotKeysUnsupported, peers.ErrorBody(res.Body))
+	default:
+		return cache.HotKeyReport{}, fmt.Errorf("node returned %s: %s", res.Status, peers.ErrorBody(res.Body))
+	}
+
+	var report cache.HotKeyReport
+	if err := json.NewDecoder(res.Body).Decode(&report); err != nil {
+		return cache.HotKeyReport{}, fmt.Errorf("decode hot keys: %v", err)
+	}
+	if !report.Enabled {
+		return cache.HotKeyReport{}, fmt.Errorf("%w: hot key tracking is disabled", errHotKeysUnsupported)
+	}
+	return report, nil
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"sort"
+
+	"github.com/AdrianWangs/go-cache/internal/cache"
+)
+
+// nodeHotKeys 单个节点报告的热点 key，按读取次数从多到少排列
+type nodeHotKeys struct {
+	Node      string
+	Keys      []cache.HotKey
+	Truncated bool // 节点只返回了前若干个 key，未报告的 key 也可能被读取过
+}
+
+// ClusterHotKey 集群范围内的一个热点 key
+type ClusterHotKey struct {
+	Key   string  `json:"key"`
+	Reads int64   `json:"reads"` // 各节点报告的读取次数之和
+	Rate  float64 `json:"rate"`  // 各节点报告的每秒读取次数之和
+
+	// MaxError 可能漏计的读取次数：截断了列表却没有报告该 key 的节点，
+	// 每个最多读取过该节点列表中最小的次数
+	MaxError int64 `json:"maxError"`
+
+	Nodes map[string]int64 `json:"nodes"` // 报告该 key 的节点及其读取次数
+}
+
+// mergeHotKeys 合并各节点的热点 key 列表，返回读取次数最多的 n 个 key。
+// 热点复制和热点缓存使同一个 key 在多个节点上被读取，合并时累加各节点的计数；
+// 节点的计数本身是近似值，截断的列表带来的误差上限记入 MaxError。
+// 读取次数相同时按 key 排序，结果与节点的顺序无关
+func mergeHotKeys(lists []nodeHotKeys, n int) []ClusterHotKey {
+	merged := make(map[string]*ClusterHotKey)
+	for _, list := range lists {
+		for _, hk := range list.Keys {
+			k, ok := merged[hk.Key]
+			if !ok {
+				k = &ClusterHotKey{Key: hk.Key, Nodes: make(map[string]int64)}
+				merged[hk.Key] = k
+			}
+			k.Reads += hk.Reads
+			k.Rate += hk.Rate
+			k.Nodes[list.Node] += hk.Reads
+		}
+	}
+
+	// 截断列表的节点中没有出现的 key，最多被读取过该节点列表中最小的次数
+	for _, list := range lists {
+		if !list.Truncated || len(list.Keys) == 0 {
+			continue
+		}
+		floor := list.Keys[len(list.Keys)-1].Reads
+		for _, k := range merged {
+			if _, ok := k.Nodes[list.Node]; !ok {
+				k.MaxError += floor
+			}
+		}
+	}
+
+	keys := make([]ClusterHotKey, 0, len(merged))
+	for _, k := range merged {
+		keys = append(keys, *k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Reads != keys[j].Reads {
+			return keys[i].Reads > keys[j].Reads
+		}
+		return keys[i].Key < keys[j].Key
+	})
+	if n > 0 && len(keys) > n {
+		keys = keys[:n]
+	}
+	return keys
+}
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/AdrianWangs/go-cache/internal/cache"
+)
+
+// hotKeys 按参数顺序返回 key 与读取次数交替给出的热点 key 列表，每秒读取次数取读取次数的一半
+func hotKeys(pairs ...interface{}) []cache.HotKey {
+	keys := make([]cache.HotKey, 0, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		reads := int64(pairs[i+1].(int))
+		keys = append(keys, cache.HotKey{Key: pairs[i].(string), Reads: reads, Rate: float64(reads) / 2})
+	}
+	return keys
+}
+
+func TestMergeHotKeys(t *testing.T) {
+	tests := []struct {
+		name  string
+		lists []nodeHotKeys
+		n     int
+		want  []ClusterHotKey
+	}{
+		{
+			name:  "没有节点",
+			lists: nil,
+			n:     5,
+			want:  []ClusterHotKey{},
+		},
+		{
+			name: "多个节点报告同一个 key 时累加",
+			lists: []nodeHotKeys{
+				{Node: "node-1", Keys: hotKeys("a", 50, "b", 10)},
+				{Node: "node-2", Keys: hotKeys("b", 45, "c", 30)},
+			},
+			n: 5,
+			want: []ClusterHotKey{
+				{Key: "b", Reads: 55, Rate: 27.5, Nodes: map[string]int64{"node-1": 10, "node-2": 45}},
+				{Key: "a", Reads: 50, Rate: 25, Nodes: map[string]int64{"node-1": 50}},
+				{Key: "c", Reads: 30, Rate: 15, Nodes: map[string]int64{"node-2": 30}},
+			},
+		},
+		{
+			name: "只返回前 n 个",
+			lists: []nodeHotKeys{
+				{Node: "node-1", Keys: hotKeys("a", 50, "b", 40, "c", 30)},
+			},
+			n: 2,
+			want: []ClusterHotKey{
+				{Key: "a", Reads: 50, Rate: 25, Nodes: map[string]int64{"node-1": 50}},
+				{Key: "b", Reads: 40, Rate: 20, Nodes: map[string]int64{"node-1": 40}},
+			},
+		},
+		{
+			name: "读取次数相同时按 key 排序",
+			lists: []nodeHotKeys{
+				{Node: "node-1", Keys: hotKeys("b", 10)},
+				{Node: "node-2", Keys: hotKeys("a", 10)},
+			},
+			n: 5,
+			want: []ClusterHotKey{
+				{Key: "a", Reads: 10, Rate: 5, Nodes: map[string]int64{"node-2": 10}},
+				{Key: "b", Reads: 10, Rate: 5, Nodes: map[string]int64{"node-1": 10}},
+			},
+		},
+		{
+			name: "截断的列表中没有出现的 key 计入误差",
+			lists: []nodeHotKeys{
+				{Node: "node-1", Keys: hotKeys("a", 50, "b", 20), Truncated: true},
+				{Node: "node-2", Keys: hotKeys("c", 40, "a", 5), Truncated: true},
+				{Node: "node-3", Keys: hotKeys("d", 1)},
+			},
+			n: 5,
+			want: []ClusterHotKey{
+				{Key: "a", Reads: 55, Rate: 27.5, Nodes: map[string]int64{"node-1": 50, "node-2": 5}},
+				{Key: "c", Reads: 40, Rate: 20, MaxError: 20, Nodes: map[string]int64{"node-2": 40}},
+				{Key: "b", Reads: 20, Rate: 10, MaxError: 5, Nodes: map[string]int64{"node-1": 20}},
+				{Key: "d", Reads: 1, Rate: 0.5, MaxError: 25, Nodes: map[string]int64{"node-3": 1}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mergeHotKeys(tt.lists, tt.n)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestMergeHotKeysOrderIndependent 结果与节点的顺序无关
+func TestMergeHotKeysOrderIndependent(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	var lists []nodeHotKeys
+	for _, node := range []string{"node-1", "node-2", "node-3", "node-4"} {
+		var pairs []interface{}
+		letters := rng.Perm(26)
+		for i, reads := 0, 100; reads > 0 && i < len(letters); i, reads = i+1, reads-1-rng.Intn(20) {
+			pairs = append(pairs, string(rune('a'+letters[i])), reads)
+		}
+		lists = append(lists, nodeHotKeys{Node: node, Keys: hotKeys(pairs...), Truncated: rng.Intn(2) == 0})
+	}
+
+	want := mergeHotKeys(lists, 10)
+	for i := 0; i < 20; i++ {
+		rng.Shuffle(len(lists), func(i, j int) { lists[i], lists[j] = lists[j], lists[i] })
+		if got := mergeHotKeys(lists, 10); !reflect.DeepEqual(got, want) {
+			t.Fatalf("节点顺序变化后结果不同:\n%+v\n%+v", got, want)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
+)
+
+// DefaultCachePolicy 缓存策略表中默认策略的键，适用于没有单独配置策略的组
+const DefaultCachePolicy = "*"
+
+// CachePolicy 读取接口成功响应的 HTTP 缓存策略，供 API 服务器前面的 CDN 和浏览器使用
+type CachePolicy struct {
+	// MaxAge 响应可被缓存的最长时间，实际的 max-age 取条目剩余有效期与它的较小者，
+	// 没有过期时间的条目使用 MaxAge；<=0 表示不可缓存，响应为 Cache-Control: no-store
+	MaxAge time.Duration
+}
+
+// CachePolicies 按组名配置的缓存策略，DefaultCachePolicy 为键的策略适用于其他组；
+// 组既没有策略也没有默认策略时响应不可缓存
+type CachePolicies map[string]CachePolicy
+
+// ParseCachePolicies 解析逗号分隔的 组名=最长缓存时间 列表，例如 "public=60s,*=0"，
+// 组名 * 为默认策略；空字符串表示所有组都不可缓存
+func ParseCachePolicies(s string) (CachePolicies, error) {
+	policies := make(CachePolicies)
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		group, value, ok := strings.Cut(item, "=")
+		group = strings.TrimSpace(group)
+		if !ok || group == "" {
+			return nil, fmt.Errorf("无效的缓存策略 %q，格式为 组名=最长缓存时间", item)
+		}
+		maxAge, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("无效的缓存策略 %q: %v", item, err)
+		}
+		policies[group] = CachePolicy{MaxAge: maxAge}
+	}
+	return policies, nil
+}
+
+// lookup 返回组的缓存策略，组没有单独配置时使用默认策略
+func (p CachePolicies) lookup(group string) CachePolicy {
+	if policy, ok := p[group]; ok {
+		return policy
+	}
+	return p[DefaultCachePolicy]
+}
+
+// setCacheHeaders 按策略为读取成功的响应设置 Cache-Control 和 Age。
+// max-age 向下取整到秒，CDN 不会在条目过期之后继续提供它；Age 恒为 0，
+// 响应由 API 服务器当场从节点读取，条目已经存在的时间体现在缩短的 max-age 中。
+// 请求带有 Authorization 时使用 private，共享缓存不会把一个令牌读到的值提供给其他客户端
+func setCacheHeaders(h http.Header, policy CachePolicy, res *pb.Response, authorized bool, now time.Time) {
+	if policy.MaxAge <= 0 {
+		h.Set("Cache-Control", "no-store")
+		return
+	}
+	maxAge := policy.MaxAge
+	if res.ExpiresAt != nil {
+		maxAge = min(maxAge, max(time.Unix(0, res.GetExpiresAt()).Sub(now), 0))
+	}
+	scope := "public"
+	if authorized {
+		scope = "private"
+	}
+	h.Set("Cache-Control", fmt.Sprintf("%s, max-age=%d", scope, int64(maxAge/time.Second)))
+	h.Set("Age", "0")
+}
@@ -0,0 +1,266 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
+)
+
+func TestParseCachePolicies(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    CachePolicies
+		wantErr bool
+	}{
+		{"", CachePolicies{}, false},
+		{"public=60s", CachePolicies{"public": {MaxAge: time.Minute}}, false},
+		{" public = 1m , *=0 ,", CachePolicies{"public": {MaxAge: time.Minute}, "*": {}}, false},
+		{"public", nil, true},
+		{"=60s", nil, true},
+		{"public=soon", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseCachePolicies(tt.in)
+		if (err != nil) != tt.wantErr || (!tt.wantErr && !reflect.DeepEqual(got, tt.want)) {
+			t.Errorf("ParseCachePolicies(%q) = %v, %v, want %v", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestCachePoliciesLookup(t *testing.T) {
+	policies := CachePolicies{"public": {MaxAge: time.Minute}, DefaultCachePolicy: {MaxAge: time.Second}}
+	if got := policies.lookup("public"); got.MaxAge != time.Minute {
+		t.Fatalf("public 的策略 = %v", got)
+	}
+	if got := policies.lookup("other"); got.MaxAge != time.Second {
+		t.Fatalf("其他组的策略 = %v, want 默认策略", got)
+	}
+	if got := (CachePolicies{"public": {MaxAge: time.Minute}}).lookup("other"); got.MaxAge != 0 {
+		t.Fatalf("没有默认策略时其他组的策略 = %v", got)
+	}
+	if got := CachePolicies(nil).lookup("public"); got.MaxAge != 0 {
+		t.Fatalf("nil 策略表 = %v", got)
+	}
+}
+
+// TestSetCacheHeaders max-age 取条目剩余有效期与策略上限的较小者，向下取整到秒
+func TestSetCacheHeaders(t *testing.T) {
+	now := time.Unix(1000, 0)
+	tests := []struct {
+		name         string
+		maxAge       time.Duration
+		remaining    time.Duration // 条目的剩余有效期，-1 表示没有过期时间
+		authorized   bool
+		cacheControl string
+	}{
+		{"没有策略", 0, 30 * time.Second, false, "no-store"},
+		{"剩余有效期短于上限", time.Minute, 30 * time.Second, false, "public, max-age=30"},
+		{"向下取整到秒", time.Minute, 10*time.Second + 900*time.Millisecond, false, "public, max-age=10"},
+		{"不足一秒", time.Minute, 400 * time.Millisecond, false, "public, max-age=0"},
+		{"剩余有效期长于上限", time.Minute, time.Hour, false, "public, max-age=60"},
+		{"刚好等于上限", time.Minute, time.Minute, false, "public, max-age=60"},
+		{"已经过期", time.Minute, -5 * time.Second, false, "public, max-age=0"},
+		{"没有过期时间", time.Minute, -1, false, "public, max-age=60"},
+		{"带授权的请求", time.Minute, 30 * time.Second, true, "private, max-age=30"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := &pb.Response{Value: []byte("v")}
+			if tt.remaining != -1 {
+				expiresAt := now.Add(tt.remaining).UnixNano()
+				res.ExpiresAt = &expiresAt
+			}
+			h := make(http.Header)
+			setCacheHeaders(h, CachePolicy{MaxAge: tt.maxAge}, res, tt.authorized, now)
+			if got := h.Get("Cache-Control"); got != tt.cacheControl {
+				t.Fatalf("Cache-Control = %q, want %q", got, tt.cacheControl)
+			}
+			wantAge := "0"
+			if tt.maxAge == 0 {
+				wantAge = ""
+			}
+			if got := h.Get("Age"); got != wantAge {
+				t.Fatalf("Age = %q, want %q", got, wantAge)
+			}
+		})
+	}
+}
+
+// expiringGetter 返回剩余有效期为 remaining 的值，remaining 为 0 时值没有过期时间
+type expiringGetter struct {
+	stubGetter
+	remaining *atomic.Int64
+}
+
+func (g *expiringGetter) GetByProto(ctx context.Context, req *pb.Request, resp *pb.Response) error {
+	if req.Key == "missing" {
+		return g.stubGetter.GetByProto(ctx, req, resp)
+	}
+	resp.Value = []byte("v:" + req.Key)
+	if remaining := time.Duration(g.remaining.Load()); remaining != 0 {
+		expiresAt := time.Now().Add(remaining).UnixNano()
+		resp.ExpiresAt = &expiresAt
+	}
+	return nil
+}
+
+type expiringFactory struct {
+	remaining atomic.Int64
+}
+
+func (f *expiringFactory) NewGetter(protocol ProtocolType, addr string) NodeGetter {
+	return &expiringGetter{stubGetter: stubGetter{protocol: protocol, addr: addr}, remaining: &f.remaining}
+}
+
+// TestGetCacheHeaders 读取响应按组的策略和节点返回的过期时间设置缓存头，错误响应不可缓存
+func TestGetCacheHeaders(t *testing.T) {
+	factory := &expiringFactory{}
+	h := NewCacheHandler("/_gocache/", 50, CacheHandlerOptions{
+		Getters:       factory,
+		CachePolicies: CachePolicies{"public": {MaxAge: time.Minute}, DefaultCachePolicy: {MaxAge: 10 * time.Second}, "secret": {}},
+	})
+	h.UpdatePeers(nodesWithGroups(3, "public", "users", "secret"))
+
+	// 剩余有效期多留半秒，读取经过的时间不影响取整
+	tests := []struct {
+		name         string
+		target       string
+		remaining    time.Duration
+		auth         bool
+		accept       string
+		status       int
+		cacheControl string
+	}{
+		{"剩余有效期短于上限", "/api/cache/public/Tom", 30*time.Second + 500*time.Millisecond, false, "", http.StatusOK, "public, max-age=30"},
+		{"剩余有效期长于上限", "/api/cache/public/Tom", time.Hour, false, "", http.StatusOK, "public, max-age=60"},
+		{"没有过期时间", "/api/cache/public/Tom", 0, false, "", http.StatusOK, "public, max-age=60"},
+		{"JSON 响应", "/api/cache/public/Tom", 20*time.Second + 500*time.Millisecond, false, "application/json", http.StatusOK, "public, max-age=20"},
+		{"带授权的请求", "/api/cache/public/Tom", 30*time.Second + 500*time.Millisecond, true, "", http.StatusOK, "private, max-age=30"},
+		{"默认策略", "/api/cache/users/Tom", time.Hour, false, "", http.StatusOK, "public, max-age=10"},
+		{"不可缓存的组", "/api/cache/secret/Tom", time.Hour, false, "", http.StatusOK, "no-store"},
+		{"不存在的 key", "/api/cache/public/missing", time.Hour, false, "", http.StatusNotFound, "no-store"},
+		{"不存在的组", "/api/cache/nope/Tom", time.Hour, false, "", http.StatusNotFound, "no-store"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			factory.remaining.Store(int64(tt.remaining))
+			r := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.auth {
+				r.Header.Set("Authorization", "Bearer token")
+			}
+			if tt.accept != "" {
+				r.Header.Set("Accept", tt.accept)
+			}
+			w := httptest.NewRecorder()
+			h.GetCacheHandler(w, r)
+			if w.Code != tt.status {
+				t.Fatalf("状态码 = %d, want %d", w.Code, tt.status)
+			}
+			if got := w.Header().Get("Cache-Control"); got != tt.cacheControl {
+				t.Fatalf("Cache-Control = %q, want %q", got, tt.cacheControl)
+			}
+			if tt.status == http.StatusOK && tt.cacheControl != "no-store" && w.Header().Get("Age") != "0" {
+				t.Fatalf("Age = %q", w.Header().Get("Age"))
+			}
+		})
+	}
+}
+
+// recordingPurger 记录清除的组和 key
+type recordingPurger struct {
+	purged chan string
+}
+
+func (p *recordingPurger) Purge(ctx context.Context, group, key string) error {
+	p.purged <- group + "/" + key
+	return nil
+}
+
+// TestDeletePurges 删除 key 后在后台清除 CDN 缓存，包括节点上已经不存在的 key
+func TestDeletePurges(t *testing.T) {
+	purger := &recordingPurger{purged: make(chan string, 4)}
+	h := NewCacheHandler("/_gocache/", 50, CacheHandlerOptions{Getters: &recordingFactory{}, Purger: purger})
+	h.UpdatePeers(nodesWithGroups(3, "public"))
+
+	if w := serveCache(h, http.MethodDelete, "public"); w.Code != http.StatusNotFound {
+		t.Fatalf("删除不存在的 key = %d", w.Code)
+	}
+	select {
+	case got := <-purger.purged:
+		if got != "public/k" {
+			t.Fatalf("清除了 %q", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("删除后没有清除 CDN 缓存")
+	}
+
+	// 不存在的组不访问节点，也不清除
+	serveCache(h, http.MethodDelete, "nope")
+	select {
+	case got := <-purger.purged:
+		t.Fatalf("不存在的组清除了 %q", got)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestHTTPPurger(t *testing.T) {
+	var mu sync.Mutex
+	var got []string
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, r.Method+" "+r.URL.EscapedPath()+" "+r.Header.Get("X-Purge-Token"))
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+	setStatus := func(code int) {
+		mu.Lock()
+		defer mu.Unlock()
+		status = code
+	}
+
+	p := NewHTTPPurger(srv.URL + "/api/cache/{group}/{key}")
+	p.Header = http.Header{"X-Purge-Token": {"secret"}}
+	ctx := context.Background()
+	if err := p.Purge(ctx, "public", "a/b c"); err != nil {
+		t.Fatal(err)
+	}
+	p.Method = http.MethodDelete
+	setStatus(http.StatusNotFound)
+	if err := p.Purge(ctx, "public", "Tom"); err != nil {
+		t.Fatalf("404 应视为成功: %v", err)
+	}
+	setStatus(http.StatusInternalServerError)
+	if err := p.Purge(ctx, "public", "Tom"); err == nil {
+		t.Fatal("500 应返回错误")
+	}
+
+	want := []string{
+		"PURGE /api/cache/public/a%2Fb%20c secret",
+		"DELETE /api/cache/public/Tom secret",
+		"DELETE /api/cache/public/Tom secret",
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("清除请求 = %q, want %q", got, want)
+	}
+}
+
+func TestNopPurger(t *testing.T) {
+	if err := (NopPurger{}).Purge(context.Background(), "g", "k"); err != nil {
+		t.Fatal(err)
+	}
+	h := NewCacheHandler("/_gocache/", 50, CacheHandlerOptions{Getters: &recordingFactory{}})
+	if _, ok := h.purger.(NopPurger); !ok {
+		t.Fatalf("默认的 Purger = %T, want NopPurger", h.purger)
+	}
+}
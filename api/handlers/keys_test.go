@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/internal/cache"
+	"github.com/AdrianWangs/go-cache/internal/server"
+	"github.com/AdrianWangs/go-cache/internal/testutil/keys"
+	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
+)
+
+// TestHTTPGetterKeysRoundTrip API 侧的 HTTPGetter 经纯 HTTP 与 Protobuf 两条路径请求
+// 缓存节点，键原样到达数据源
+func TestHTTPGetterKeysRoundTrip(t *testing.T) {
+	registry := cache.NewRegistry()
+	t.Cleanup(func() { registry.Close() })
+	cache.NewGroup("scores", 1<<20, cache.GetterFunc(func(key string) ([]byte, error) {
+		return []byte("v:" + key), nil
+	}), time.Hour, cache.WithRegistry(registry))
+
+	pool := server.NewHTTPPool("http://self", server.WithRegistry(registry))
+	srv := httptest.NewServer(pool)
+	t.Cleanup(srv.Close)
+	getter := NewHTTPGetter(srv.URL + pool.BasePath())
+
+	for _, key := range keys.Routing {
+		t.Run(key, func(t *testing.T) {
+			ctx := context.Background()
+			v, err := getter.Get(ctx, "scores", key)
+			if err != nil {
+				t.Fatalf("GetPlain: %v", err)
+			}
+			if string(v) != "v:"+key {
+				t.Fatalf("GetPlain = %q, want %q", v, "v:"+key)
+			}
+
+			resp := &pb.Response{}
+			if err := getter.GetByProto(ctx, &pb.Request{Group: "scores", Key: key}, resp); err != nil {
+				t.Fatalf("GetByProto: %v", err)
+			}
+			if string(resp.Value) != "v:"+key {
+				t.Fatalf("GetByProto = %q, want %q", resp.Value, "v:"+key)
+			}
+		})
+	}
+}
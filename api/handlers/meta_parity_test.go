@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/internal/cache"
+	"github.com/AdrianWangs/go-cache/internal/server"
+	"github.com/AdrianWangs/go-cache/pkg/cacheerrors"
+	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
+)
+
+// TestGetterMetadataParity 纯 HTTP 与 protobuf 两种 getter 从同一节点读取，得到相同的元数据和错误
+func TestGetterMetadataParity(t *testing.T) {
+	registry := cache.NewRegistry()
+	t.Cleanup(func() { registry.Close() })
+	srv := httptest.NewUnstartedServer(nil)
+	pool := server.NewHTTPPool("http://"+srv.Listener.Addr().String(), server.WithRegistry(registry), server.WithSelfID("node-b"))
+	srv.Config.Handler = pool
+	srv.Start()
+	t.Cleanup(srv.Close)
+	cache.NewGroup("scores", 1<<20, cache.GetterFunc(func(key string) ([]byte, error) {
+		if key == "missing" {
+			return nil, cache.ErrNotFound
+		}
+		return []byte("v:" + key), nil
+	}), time.Hour, cache.WithRegistry(registry))
+
+	base := srv.URL + pool.BasePath()
+	getters := map[string]NodeGetter{
+		"http":     NewHTTPGetter(base),
+		"protobuf": NewProtoGetter(base),
+	}
+	ctx := context.Background()
+	// 先读取一次使值进入缓存，之后两种 getter 看到同一个条目
+	if _, err := getters["protobuf"].Get(ctx, "scores", "Tom"); err != nil {
+		t.Fatal(err)
+	}
+
+	responses := make(map[string]*pb.Response)
+	for name, g := range getters {
+		resp := &pb.Response{}
+		if err := g.GetByProto(ctx, &pb.Request{Group: "scores", Key: "Tom"}, resp); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if resp.ExpiresAt == nil || resp.Version == nil || resp.GetSource() != string(cache.SourceCache) || resp.GetNode() != "node-b" {
+			t.Fatalf("%s: 元数据不完整 %v", name, resp)
+		}
+		responses[name] = resp
+
+		err := g.GetByProto(ctx, &pb.Request{Group: "scores", Key: "missing"}, &pb.Response{})
+		if !cacheerrors.IsKeyNotFoundError(err) {
+			t.Fatalf("%s: 不存在的 key 返回 %v", name, err)
+		}
+	}
+	h, p := responses["http"], responses["protobuf"]
+	if h.GetExpiresAt() != p.GetExpiresAt() || h.GetVersion() != p.GetVersion() || string(h.Value) != string(p.Value) {
+		t.Fatalf("两种协议的响应不同:\nhttp:     %v\nprotobuf: %v", h, p)
+	}
+}
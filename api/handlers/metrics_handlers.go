@@ -6,42 +6,151 @@ import (
 	"net/http"
 	"runtime"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/AdrianWangs/go-cache/internal/deletequeue"
+	"github.com/AdrianWangs/go-cache/internal/discovery"
+	"github.com/AdrianWangs/go-cache/internal/peers"
+	"github.com/AdrianWangs/go-cache/internal/ratewindow"
 	"github.com/AdrianWangs/go-cache/pkg/logger"
+	"github.com/AdrianWangs/go-cache/pkg/router"
 )
 
 // MetricsHandler 系统指标处理器
 type MetricsHandler struct {
-	mu           sync.RWMutex
-	startTime    time.Time // 服务启动时间
-	requestCount int64     // 总请求次数
-	hitCount     int64     // 缓存命中次数
-	missCount    int64     // 缓存未命中次数
+	mu        sync.RWMutex
+	startTime time.Time // 服务启动时间
+	hitCount  int64     // 缓存命中次数
+	missCount int64     // 缓存未命中次数
+
+	rates atomic.Pointer[ratewindow.Window] // 每分钟统计窗口，为 nil 时关闭，见 SetStatsWindow
+
+	routeStats func() []router.RouteStats // 各路由的请求统计来源，总请求次数由它汇总，可为 nil
+
+	hedgeStats      func() HedgeStats             // 对冲读取统计来源，可为 nil
+	hotKeyStats     func() HotKeyStats            // 热点 key 分散读取统计来源，可为 nil
+	discoveryStatus func() discovery.WatchStatus  // 服务发现状态来源，可为 nil
+	nodeStats       func() map[string]peers.Stats // 各缓存节点的请求与错误统计来源，可为 nil
+	clientCancelled func() int64                  // 客户端断开而放弃的读取请求数来源，可为 nil
+	notModified     func() int64                  // 返回 304 的读取请求数来源，可为 nil
+	deleteRetry     func() *deletequeue.Stats     // 删除重试队列统计来源，可为 nil
+	readRepair      func() *ReadRepairStats       // 读修复统计来源，可为 nil
+	proxy           func() *ProxyStats            // 缓存反向代理统计来源，可为 nil
 }
 
 // MetricsResponse 系统指标响应
 type MetricsResponse struct {
 	Uptime       string  `json:"uptime"`       // 运行时间
 	NumGoroutine int     `json:"numGoroutine"` // goroutine数量
-	RequestCount int64   `json:"requestCount"` // 总请求次数
+	RequestCount int64   `json:"requestCount"` // 总请求次数，各路由请求数之和
 	HitCount     int64   `json:"hitCount"`     // 缓存命中次数
 	MissCount    int64   `json:"missCount"`    // 缓存未命中次数
 	HitRate      float64 `json:"hitRate"`      // 缓存命中率
+
+	Rates *MetricsRates `json:"rates,omitempty"` // 最近 1、5、15 分钟的每分钟请求、错误、命中和未命中数，关闭统计窗口时省略
+
+	HedgedCount   int64 `json:"hedgedCount"`   // 发出的对冲请求数
+	HedgeWonCount int64 `json:"hedgeWonCount"` // 对冲请求胜出次数
+
+	HotKeys *HotKeyStats `json:"hotKeys,omitempty"` // 热点 key 分散读取统计
+
+	ClientCancelledCount int64 `json:"clientCancelledCount"` // 客户端在收到响应之前断开的读取请求数
+	NotModifiedCount     int64 `json:"notModifiedCount"`     // If-None-Match 匹配而返回 304 的读取请求数
+
+	DeleteRetry *deletequeue.Stats `json:"deleteRetry,omitempty"` // 删除重试队列的深度和结果，未开启删除重试时省略
+
+	ReadRepair *ReadRepairStats `json:"readRepair,omitempty"` // 副本不一致与读修复的次数，未开启读修复时省略
+
+	Proxy *ProxyStats `json:"proxy,omitempty"` // 缓存反向代理的命中与上游请求数，没有组配置上游时省略
+
+	Discovery         *discovery.WatchStatus `json:"discovery,omitempty"` // 服务发现状态
+	DiscoveryDegraded bool                   `json:"discoveryDegraded"`   // 服务发现是否中断，节点列表可能已经过期
+
+	Nodes map[string]peers.Stats `json:"nodes,omitempty"` // 节点标识到发往该节点的请求与错误统计
+
+	Routes []router.RouteStats `json:"routes,omitempty"` // 按注册路由和方法的请求数、状态码和耗时，未匹配任何路由的请求计入 unmatched
+
+	Log *logger.Stats `json:"log,omitempty"` // 异步日志的缓冲与丢弃统计，未开启异步日志时省略
 }
 
 // NewMetricsHandler 创建新的指标处理器
 func NewMetricsHandler() *MetricsHandler {
-	return &MetricsHandler{
+	h := &MetricsHandler{
 		startTime: time.Now(),
 	}
+	h.SetStatsWindow(ratewindow.DefaultBuckets, nil)
+	return h
+}
+
+// SetRouteStats 设置各路由请求统计的来源
+func (h *MetricsHandler) SetRouteStats(fn func() []router.RouteStats) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.routeStats = fn
+}
+
+// SetHedgeStats 设置对冲读取统计的来源
+func (h *MetricsHandler) SetHedgeStats(fn func() HedgeStats) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.hedgeStats = fn
+}
+
+// SetHotKeyStats 设置热点 key 分散读取统计的来源
+func (h *MetricsHandler) SetHotKeyStats(fn func() HotKeyStats) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.hotKeyStats = fn
+}
+
+// SetDiscoveryStatus 设置服务发现状态的来源
+func (h *MetricsHandler) SetDiscoveryStatus(fn func() discovery.WatchStatus) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.discoveryStatus = fn
+}
+
+// SetNodeStats 设置各缓存节点请求与错误统计的来源
+func (h *MetricsHandler) SetNodeStats(fn func() map[string]peers.Stats) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.nodeStats = fn
+}
+
+// SetClientCancelled 设置客户端断开而放弃的读取请求数的来源
+func (h *MetricsHandler) SetClientCancelled(fn func() int64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.clientCancelled = fn
+}
+
+// SetNotModified 设置返回 304 的读取请求数的来源
+func (h *MetricsHandler) SetNotModified(fn func() int64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.notModified = fn
+}
+
+// SetDeleteRetryStats 设置删除重试队列统计的来源
+func (h *MetricsHandler) SetDeleteRetryStats(fn func() *deletequeue.Stats) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.deleteRetry = fn
 }
 
-// IncrementRequestCount 增加请求计数
-func (h *MetricsHandler) IncrementRequestCount() {
+// SetReadRepairStats 设置读修复统计的来源
+func (h *MetricsHandler) SetReadRepairStats(fn func() *ReadRepairStats) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
-	h.requestCount++
+	h.readRepair = fn
+}
+
+// SetProxyStats 设置缓存反向代理统计的来源
+func (h *MetricsHandler) SetProxyStats(fn func() *ProxyStats) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.proxy = fn
 }
 
 // IncrementHitCount 增加命中计数
@@ -49,6 +158,7 @@ func (h *MetricsHandler) IncrementHitCount() {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 	h.hitCount++
+	h.rates.Load().Add(rateHits, 1)
 }
 
 // IncrementMissCount 增加未命中计数
@@ -56,17 +166,36 @@ func (h *MetricsHandler) IncrementMissCount() {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 	h.missCount++
+	h.rates.Load().Add(rateMisses, 1)
 }
 
 // GetMetricsHandler 获取系统指标
 func (h *MetricsHandler) GetMetricsHandler(w http.ResponseWriter, r *http.Request) {
 	h.mu.RLock()
-	requestCount := h.requestCount
+	routeStats := h.routeStats
 	hitCount := h.hitCount
 	missCount := h.missCount
 	uptime := time.Since(h.startTime).String()
+	hedgeStats := h.hedgeStats
+	hotKeyStats := h.hotKeyStats
+	discoveryStatus := h.discoveryStatus
+	nodeStats := h.nodeStats
+	clientCancelled := h.clientCancelled
+	notModified := h.notModified
+	deleteRetry := h.deleteRetry
+	readRepair := h.readRepair
+	proxy := h.proxy
 	h.mu.RUnlock()
 
+	var routes []router.RouteStats
+	var requestCount int64
+	if routeStats != nil {
+		routes = routeStats()
+		for _, rs := range routes {
+			requestCount += rs.Requests
+		}
+	}
+
 	// 计算命中率
 	var hitRate float64
 	if requestCount > 0 {
@@ -80,6 +209,43 @@ func (h *MetricsHandler) GetMetricsHandler(w http.ResponseWriter, r *http.Reques
 		HitCount:     hitCount,
 		MissCount:    missCount,
 		HitRate:      hitRate,
+		Routes:       routes,
+		Rates:        metricsRates(h.rates.Load()),
+	}
+	if hedgeStats != nil {
+		hs := hedgeStats()
+		metrics.HedgedCount = hs.Hedged
+		metrics.HedgeWonCount = hs.HedgeWon
+	}
+	if hotKeyStats != nil {
+		hs := hotKeyStats()
+		metrics.HotKeys = &hs
+	}
+	if clientCancelled != nil {
+		metrics.ClientCancelledCount = clientCancelled()
+	}
+	if notModified != nil {
+		metrics.NotModifiedCount = notModified()
+	}
+	if deleteRetry != nil {
+		metrics.DeleteRetry = deleteRetry()
+	}
+	if readRepair != nil {
+		metrics.ReadRepair = readRepair()
+	}
+	if proxy != nil {
+		metrics.Proxy = proxy()
+	}
+	if discoveryStatus != nil {
+		status := discoveryStatus()
+		metrics.Discovery = &status
+		metrics.DiscoveryDegraded = status.Degraded()
+	}
+	if nodeStats != nil {
+		metrics.Nodes = nodeStats()
+	}
+	if ls := logger.GetStats(); ls.Async {
+		metrics.Log = &ls
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/internal/discovery"
+)
+
+// getMetrics 请求 /api/metrics 并解析响应
+func getMetrics(t *testing.T, h *MetricsHandler) MetricsResponse {
+	t.Helper()
+	w := httptest.NewRecorder()
+	h.GetMetricsHandler(w, httptest.NewRequest(http.MethodGet, "/api/metrics", nil))
+	var resp MetricsResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("%v: %s", err, w.Body)
+	}
+	return resp
+}
+
+func TestMetricsDiscoveryState(t *testing.T) {
+	h := NewMetricsHandler()
+	if resp := getMetrics(t, h); resp.Discovery != nil || resp.DiscoveryDegraded {
+		t.Fatalf("未设置服务发现时 = %+v", resp.Discovery)
+	}
+
+	status := discovery.WatchStatus{State: discovery.WatchConnected, LastSync: time.Now()}
+	h.SetDiscoveryStatus(func() discovery.WatchStatus { return status })
+	if resp := getMetrics(t, h); resp.Discovery == nil || resp.DiscoveryDegraded {
+		t.Fatalf("监视正常时 = %+v, degraded=%v", resp.Discovery, resp.DiscoveryDegraded)
+	}
+
+	for _, state := range []discovery.WatchState{discovery.WatchDisconnected, discovery.WatchResyncing} {
+		status = discovery.WatchStatus{State: state, LastError: "etcd unavailable"}
+		resp := getMetrics(t, h)
+		if !resp.DiscoveryDegraded || resp.Discovery.State != state || resp.Discovery.LastError == "" {
+			t.Fatalf("%s 时 = %+v, degraded=%v", state, resp.Discovery, resp.DiscoveryDegraded)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/internal/cache"
+	"github.com/AdrianWangs/go-cache/internal/ratewindow"
+)
+
+// 每分钟统计窗口计数的事件
+const (
+	rateRequests = iota
+	rateErrors
+	rateHits
+	rateMisses
+	rateEvents
+)
+
+// MetricsRates 最近几分钟的每分钟速率，与 MetricsResponse 中启动以来的累计值并列；
+// Version 为 JSON 结构的版本，字段含义改变或删除字段时递增
+type MetricsRates struct {
+	Version       int               `json:"version"`       // 结构版本，见 ratewindow.SchemaVersion
+	BucketSeconds int               `json:"bucketSeconds"` // 每个桶的时长（秒）
+	Buckets       int               `json:"buckets"`       // 保留的桶数，见 ApiServerConfig.StatsWindow
+	Last1m        MetricsRateWindow `json:"last1m"`        // 最近 1 分钟
+	Last5m        MetricsRateWindow `json:"last5m"`        // 最近 5 分钟
+	Last15m       MetricsRateWindow `json:"last15m"`       // 最近 15 分钟
+}
+
+// MetricsRateWindow 一段时间内按已结束的整分钟平均的每分钟速率，不含正在进行的这一分钟
+type MetricsRateWindow struct {
+	Minutes  int     `json:"minutes"`  // 实际覆盖的分钟数，启动不久时少于这段时间
+	Requests float64 `json:"requests"` // 每分钟请求数，各路由之和
+	Errors   float64 `json:"errors"`   // 每分钟返回 5xx 的请求数
+	Hits     float64 `json:"hits"`     // 每分钟命中缓存（节点本地或对等节点）的读取数
+	Misses   float64 `json:"misses"`   // 每分钟由数据源加载的读取数
+	HitRate  float64 `json:"hitRate"`  // 命中数占命中与未命中之和的百分比
+}
+
+// SetStatsWindow 设置每分钟统计窗口保留的桶数（分钟），小于等于 0 时关闭；
+// now 为时间来源，为 nil 时使用 time.Now。替换窗口会清空已有的计数
+func (h *MetricsHandler) SetStatsWindow(buckets int, now func() time.Time) {
+	if buckets <= 0 {
+		h.rates.Store(nil)
+		return
+	}
+	h.rates.Store(ratewindow.New(rateEvents, buckets, now))
+}
+
+// RecordRequest 实现 router.Recorder，把请求和 5xx 响应计入每分钟统计窗口
+func (h *MetricsHandler) RecordRequest(route, method string, status int, duration time.Duration) {
+	w := h.rates.Load()
+	w.Add(rateRequests, 1)
+	if status >= http.StatusInternalServerError {
+		w.Add(rateErrors, 1)
+	}
+}
+
+// RecordRead 按读取结果的来源计数：缓存节点本地或对等节点的缓存为命中，
+// 由数据源加载为未命中，节点未提供来源时不计
+func (h *MetricsHandler) RecordRead(source string) {
+	switch cache.Source(source) {
+	case cache.SourceCache, cache.SourcePeer:
+		h.IncrementHitCount()
+	case cache.SourceLoader:
+		h.IncrementMissCount()
+	}
+}
+
+// metricsRates 汇总每分钟统计窗口，窗口关闭时返回 nil
+func metricsRates(w *ratewindow.Window) *MetricsRates {
+	if w == nil {
+		return nil
+	}
+	return &MetricsRates{
+		Version:       ratewindow.SchemaVersion,
+		BucketSeconds: ratewindow.BucketSeconds,
+		Buckets:       w.Buckets(),
+		Last1m:        metricsRateWindow(w.Last(1)),
+		Last5m:        metricsRateWindow(w.Last(5)),
+		Last15m:       metricsRateWindow(w.Last(15)),
+	}
+}
+
+// metricsRateWindow 把窗口内的计数换算为每分钟速率
+func metricsRateWindow(s ratewindow.Sums) MetricsRateWindow {
+	r := MetricsRateWindow{
+		Minutes:  s.Minutes,
+		Requests: s.Rate(rateRequests),
+		Errors:   s.Rate(rateErrors),
+		Hits:     s.Rate(rateHits),
+		Misses:   s.Rate(rateMisses),
+	}
+	if reads := s.Counts[rateHits] + s.Counts[rateMisses]; reads > 0 {
+		r.HitRate = float64(s.Counts[rateHits]) / float64(reads) * 100
+	}
+	return r
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/internal/ratewindow"
+	"github.com/AdrianWangs/go-cache/pkg/lru"
+)
+
+// TestMetricsRates 用假时钟检查每分钟统计窗口的换算、桶的轮转和超过窗口的空闲
+func TestMetricsRates(t *testing.T) {
+	clock := lru.NewFakeClock(time.Unix(100*ratewindow.BucketSeconds, 0))
+	h := NewMetricsHandler()
+	h.SetStatsWindow(20, clock.Now)
+
+	h.RecordRequest("/api/cache/{group}/{key}", http.MethodGet, http.StatusOK, time.Millisecond)
+	h.RecordRequest("/api/cache/{group}/{key}", http.MethodGet, http.StatusNotFound, time.Millisecond)
+	h.RecordRequest("/api/cache/{group}/{key}", http.MethodGet, http.StatusBadGateway, time.Millisecond)
+	for _, source := range []string{"cache", "peer", "loader", ""} {
+		h.RecordRead(source)
+	}
+
+	// 正在进行的这一分钟不计入
+	if r := getMetrics(t, h).Rates; r == nil || r.Last1m.Minutes != 0 || r.Last1m.Requests != 0 {
+		t.Fatalf("第一分钟内 rates = %+v", r)
+	}
+	clock.Advance(time.Minute)
+	resp := getMetrics(t, h)
+	r := resp.Rates
+	if r.Version != ratewindow.SchemaVersion || r.BucketSeconds != 60 || r.Buckets != 20 {
+		t.Fatalf("rates = %+v", r)
+	}
+	got := r.Last1m
+	if got.Minutes != 1 || got.Requests != 3 || got.Errors != 1 || got.Hits != 2 || got.Misses != 1 || math.Abs(got.HitRate-200.0/3) > 1e-9 {
+		t.Fatalf("last1m = %+v", got)
+	}
+	if resp.HitCount != 2 || resp.MissCount != 1 {
+		t.Fatalf("累计命中 %d、未命中 %d, want 2、1", resp.HitCount, resp.MissCount)
+	}
+
+	// 之后四分钟每分钟一个请求，5 分钟平均含第一分钟
+	for m := 0; m < 4; m++ {
+		h.RecordRequest("/api/metrics", http.MethodGet, http.StatusOK, time.Millisecond)
+		clock.Advance(time.Minute)
+	}
+	r = getMetrics(t, h).Rates
+	if r.Last1m.Requests != 1 || r.Last5m.Minutes != 5 || r.Last5m.Requests != 7.0/5 || r.Last15m != r.Last5m {
+		t.Fatalf("rates = %+v", r)
+	}
+
+	// 空闲超过窗口后不再报告旧的计数，累计值不变
+	clock.Advance(time.Hour)
+	resp = getMetrics(t, h)
+	if resp.Rates.Last15m != (MetricsRateWindow{Minutes: 15}) {
+		t.Fatalf("空闲后 last15m = %+v", resp.Rates.Last15m)
+	}
+	if resp.HitCount != 2 {
+		t.Fatalf("空闲后累计命中 = %d", resp.HitCount)
+	}
+}
+
+func TestMetricsRatesDisabled(t *testing.T) {
+	h := NewMetricsHandler()
+	if r := getMetrics(t, h).Rates; r == nil || r.Buckets != ratewindow.DefaultBuckets {
+		t.Fatalf("默认的 rates = %+v", r)
+	}
+
+	h.SetStatsWindow(0, nil)
+	h.RecordRequest("/api/metrics", http.MethodGet, http.StatusOK, time.Millisecond)
+	h.RecordRead("cache")
+	w := httptest.NewRecorder()
+	h.GetMetricsHandler(w, httptest.NewRequest(http.MethodGet, "/api/metrics", nil))
+	if strings.Contains(w.Body.String(), `"rates"`) {
+		t.Fatalf("关闭统计窗口后仍返回 rates: %s", w.Body)
+	}
+	var resp MetricsResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.HitCount != 1 {
+		t.Fatalf("关闭统计窗口后累计命中 = %d, %v", resp.HitCount, err)
+	}
+}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/AdrianWangs/go-cache/internal/cache"
+	"github.com/AdrianWangs/go-cache/internal/peers"
+	"github.com/AdrianWangs/go-cache/pkg/cacheerrors"
+	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
+)
+
+// errGetter 所有调用都返回 err 的 NodeGetter
+type errGetter struct {
+	stubGetter
+	err error
+}
+
+func (g *errGetter) GetByProto(ctx context.Context, req *pb.Request, resp *pb.Response) error {
+	return g.err
+}
+
+func (g *errGetter) Delete(ctx context.Context, group, key string) error {
+	return g.err
+}
+
+// nodeErrors 节点可能返回的所有预定义错误，以及不属于缓存错误的内部错误
+var nodeErrors = []error{
+	cacheerrors.ErrEmptyKey,
+	cacheerrors.ErrNotFound,
+	cacheerrors.ErrNoSuchGroup,
+	cacheerrors.ErrRateLimited,
+	cacheerrors.ErrReadOnly,
+	cacheerrors.ErrNoPeerAvailable,
+	cacheerrors.ErrOriginUnavailable,
+	cacheerrors.ErrGroupForbidden,
+	cacheerrors.ErrValueTransform,
+	cacheerrors.ErrGroupClosed,
+	errors.New("boom"),
+}
+
+// TestEnvelopeCodesExhaustive 每个节点错误码都有对应的 JSON 错误码，且互不相同
+func TestEnvelopeCodesExhaustive(t *testing.T) {
+	seen := make(map[string]string)
+	for _, err := range nodeErrors {
+		code := cacheerrors.ErrorCode(err)
+		envelope, ok := envelopeCodes[code]
+		if !ok {
+			t.Fatalf("错误码 %s 没有对应的 JSON 错误码", code)
+		}
+		if other, dup := seen[envelope]; dup && other != code {
+			t.Fatalf("%s 与 %s 对应同一个 JSON 错误码 %s", code, other, envelope)
+		}
+		seen[envelope] = code
+	}
+	if len(seen) != len(envelopeCodes) {
+		t.Fatalf("nodeErrors 只覆盖了 %d 个错误码，映射表有 %d 个", len(seen), len(envelopeCodes))
+	}
+}
+
+// TestNodeErrorMapping 读取和删除时节点返回的每种错误：状态码和错误码与 cacheerrors 的映射一致，
+// 且调用方能从响应还原出同一个预定义错误；包装后的错误结果相同
+func TestNodeErrorMapping(t *testing.T) {
+	for _, base := range nodeErrors {
+		for _, err := range []error{base, fmt.Errorf("node-1: %w", base)} {
+			t.Run(err.Error(), func(t *testing.T) {
+				getter := &errGetter{err: err}
+				h := NewCacheHandler("/_gocache/", 50, CacheHandlerOptions{
+					Getters: GetterFactoryFunc(func(ProtocolType, string) NodeGetter { return getter }),
+				})
+				h.UpdatePeers(nodesWithGroups(1, "scores"))
+
+				code := cacheerrors.ErrorCode(err)
+				status := cacheerrors.HTTPStatus(err)
+				check := func(name string, w *httptest.ResponseRecorder) {
+					t.Helper()
+					if w.Code != status || w.Header().Get(peers.HeaderErrorCode) != code {
+						t.Fatalf("%s: %d %s, want %d %s: %s", name, w.Code, w.Header().Get(peers.HeaderErrorCode), status, code, w.Body)
+					}
+					if got := cacheerrors.ErrorFromHTTP(w.Code, w.Header().Get(peers.HeaderErrorCode)); got != cacheerrors.ErrorFromCode(code) {
+						t.Fatalf("%s: 响应还原为 %v", name, got)
+					}
+					// 不知道重试间隔的限流错误（例如来自 gRPC）以 1 秒返回
+					if got := w.Header().Get(cacheerrors.HeaderRetryAfter); got != cacheerrors.RetryAfterHeader(err) ||
+						(code == cacheerrors.ErrorCodeRateLimited) != (got == "1") {
+						t.Fatalf("%s: Retry-After %q", name, got)
+					}
+				}
+
+				check("原始格式读取", serveRead(h.GetCacheHandler, "/api/cache/scores/k", ""))
+				w := serveRead(h.GetCacheHandler, "/api/cache/scores/k", "application/json")
+				check("JSON 格式读取", w)
+				var env ErrorEnvelope
+				if err := json.Unmarshal(w.Body.Bytes(), &env); err != nil {
+					t.Fatal(err)
+				}
+				if env.Error.Code != envelopeCodes[code] {
+					t.Fatalf("JSON 错误码 = %s, want %s", env.Error.Code, envelopeCodes[code])
+				}
+
+				r := httptest.NewRequest(http.MethodDelete, "/api/cache/scores/k", nil)
+				w = httptest.NewRecorder()
+				h.DeleteCacheHandler(w, r)
+				check("删除", w)
+			})
+		}
+	}
+}
+
+// TestNodeBusyNotCacheError 节点繁忙不是缓存错误，返回 503 和 NODE_BUSY
+func TestNodeBusyNotCacheError(t *testing.T) {
+	getter := &errGetter{err: peers.ErrPeerBusy}
+	h := NewCacheHandler("/_gocache/", 50, CacheHandlerOptions{
+		Getters: GetterFactoryFunc(func(ProtocolType, string) NodeGetter { return getter }),
+	})
+	h.UpdatePeers(nodesWithGroups(1, "scores"))
+	w := serveRead(h.GetCacheHandler, "/api/cache/scores/k", "application/json")
+	if body := decodeErrorEnvelope(t, w); w.Code != http.StatusServiceUnavailable || body.Code != EnvelopeCodeNodeBusy {
+		t.Fatalf("%d %+v", w.Code, body)
+	}
+}
+
+// TestHTTPGetterMissesAreSentinels 旧节点只返回 404 时，纯 HTTP getter 的读取和删除都映射为预定义错误
+func TestHTTPGetterMissesAreSentinels(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/_gocache/users/k" {
+			http.Error(w, "no such group: users", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "not found", http.StatusNotFound)
+	}))
+	defer srv.Close()
+	g := NewHTTPGetter(srv.URL + "/_gocache/")
+	ctx := context.Background()
+
+	if _, err := g.Get(ctx, "scores", "k"); !errors.Is(err, cache.ErrNotFound) {
+		t.Fatalf("Get = %v", err)
+	}
+	if err := g.Delete(ctx, "scores", "k"); !errors.Is(err, cache.ErrNotFound) {
+		t.Fatalf("Delete = %v", err)
+	}
+	if _, err := g.Get(ctx, "users", "k"); !errors.Is(err, cache.ErrNoSuchGroup) {
+		t.Fatalf("Get 不存在的组 = %v", err)
+	}
+}
@@ -2,115 +2,307 @@
 package handlers
 
 import (
-	"encoding/json"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
 	"net/http"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
+	"github.com/AdrianWangs/go-cache/internal/discovery"
+	"github.com/AdrianWangs/go-cache/internal/health"
+	"github.com/AdrianWangs/go-cache/internal/peers"
 	"github.com/AdrianWangs/go-cache/pkg/logger"
 )
 
+// defaultNodesMaxAge 节点列表响应的默认 Cache-Control max-age，与缓存节点默认的刷新间隔一致
+const defaultNodesMaxAge = 5 * time.Second
+
+// NodesSchema 节点列表接口的响应格式
+type NodesSchema string
+
+const (
+	// NodesSchemaLegacy /peers 的旧格式: {"peers": [...], "nodes": [...]}
+	NodesSchemaLegacy NodesSchema = "peers"
+	// NodesSchemaCurrent /api/nodes 的格式: {"count", "nodes", "details"}
+	NodesSchemaCurrent NodesSchema = "nodes"
+)
+
+// 通过 Accept 请求头显式选择响应格式时使用的媒体类型
+const (
+	mediaTypeLegacyPeers = "application/vnd.gocache.peers+json"
+	mediaTypeNodes       = "application/vnd.gocache.nodes+json"
+	// MediaTypeNodesDelta 增量响应的媒体类型，见 NodesDelta
+	MediaTypeNodesDelta = "application/vnd.gocache.nodes-delta+json"
+)
+
 // NodeHandler 节点服务管理处理器
 type NodeHandler struct {
 	mu                sync.RWMutex
-	nodeAddresses     []string       // 缓存节点地址列表
-	serviceChangeHook func([]string) // 节点变更通知回调函数
+	nodes             []discovery.NodeInfo       // 缓存节点列表
+	version           string                     // 节点列表的摘要，用于生成 ETag
+	history           []nodesSnapshot            // 最近的历史版本，用于计算增量响应，最早的在前
+	maxAge            time.Duration              // 节点列表响应的 Cache-Control max-age
+	serviceChangeHook func([]discovery.NodeInfo) // 节点变更通知回调函数
+	checker           *health.Checker            // 健康检查，可为 nil
+
+	protocols func() map[string]ProtocolType // 访问各节点所用协议的来源，可为 nil
 }
 
 // NodeResponse 节点信息响应
 type NodeResponse struct {
-	Count int      `json:"count"` // 节点数量
-	Nodes []string `json:"nodes"` // 节点地址列表
+	Count   int                  `json:"count"`   // 节点数量
+	Nodes   []string             `json:"nodes"`   // 节点标识列表（即一致性哈希环上的 key）
+	Details []discovery.NodeInfo `json:"details"` // 节点的完整注册信息
+
+	Protocols map[string]ProtocolType `json:"protocols,omitempty"` // 节点标识到 API 服务器访问该节点所用协议的映射
+
+	Version    string `json:"version,omitempty"`    // 节点列表的版本，可作为 since 参数请求增量
+	Total      int    `json:"total,omitempty"`      // 分页时节点列表的总数，Count 为本页的数量
+	NextCursor string `json:"nextCursor,omitempty"` // 分页时下一页的 cursor，为空表示已是最后一页
 }
 
 // 旧版本响应格式，用于兼容
 type LegacyPeersResponse struct {
-	Peers []string `json:"peers"` // 节点地址列表
+	Peers []string             `json:"peers"`           // 节点 gRPC 地址列表
+	Nodes []discovery.NodeInfo `json:"nodes,omitempty"` // 节点的完整注册信息，供节点按标识构建哈希环
+
+	Version    string `json:"version,omitempty"`    // 节点列表的版本，可作为 since 参数请求增量
+	NextCursor string `json:"nextCursor,omitempty"` // 分页时下一页的 cursor，为空表示已是最后一页
 }
 
 // NewNodeHandler 创建新的节点处理器
 func NewNodeHandler() *NodeHandler {
 	return &NodeHandler{
-		nodeAddresses: make([]string, 0),
+		nodes:   make([]discovery.NodeInfo, 0),
+		version: nodesVersion(nil),
+		maxAge:  defaultNodesMaxAge,
 	}
 }
 
+// SetCacheMaxAge 设置节点列表响应的 Cache-Control max-age，应与客户端的轮询间隔一致
+func (h *NodeHandler) SetCacheMaxAge(d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.maxAge = d
+}
+
+// SetHealthChecker 设置 /health 和 /ready 使用的健康检查
+func (h *NodeHandler) SetHealthChecker(c *health.Checker) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checker = c
+}
+
+// Nodes 返回当前已知的节点列表
+func (h *NodeHandler) Nodes() []discovery.NodeInfo {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.getNodes()
+}
+
+// NodeCount 返回当前已知的节点数
+func (h *NodeHandler) NodeCount() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.nodes)
+}
+
+// SetNodeProtocols 设置 /api/nodes 中各节点所用协议的来源，通常为 CacheHandler.NodeProtocols
+func (h *NodeHandler) SetNodeProtocols(protocols func() map[string]ProtocolType) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.protocols = protocols
+}
+
 // SetServiceChangeHook 设置节点变更通知回调
-func (h *NodeHandler) SetServiceChangeHook(hook func([]string)) {
+func (h *NodeHandler) SetServiceChangeHook(hook func([]discovery.NodeInfo)) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 	h.serviceChangeHook = hook
 }
 
-// UpdateNodeAddresses 更新节点地址列表
-func (h *NodeHandler) UpdateNodeAddresses(addresses []string) {
+// UpdateNodes 更新节点列表，任一节点的注册信息（包括地址）变化都会触发回调
+func (h *NodeHandler) UpdateNodes(nodes []discovery.NodeInfo) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
 	// 判断节点列表是否发生实质性变化
-	if !isStringSliceEqual(h.nodeAddresses, addresses) {
-		logger.Infof("节点列表更新，从 %d 个节点变为 %d 个节点", len(h.nodeAddresses), len(addresses))
-		h.nodeAddresses = addresses
+	if !isStringSliceEqual(encodeNodes(h.nodes), encodeNodes(nodes)) {
+		logger.Infof("节点列表更新，从 %d 个节点变为 %d 个节点", len(h.nodes), len(nodes))
+		h.remember(h.version, h.nodes)
+		h.nodes = nodes
+		h.version = nodesVersion(nodes)
 
 		// 触发回调通知
 		if h.serviceChangeHook != nil {
-			h.serviceChangeHook(h.getNodeAddresses())
+			h.serviceChangeHook(h.getNodes())
 		}
 	}
 }
 
-// 获取节点地址列表的副本
-func (h *NodeHandler) getNodeAddresses() []string {
-	result := make([]string, len(h.nodeAddresses))
-	copy(result, h.nodeAddresses)
+// 获取节点列表的副本
+func (h *NodeHandler) getNodes() []discovery.NodeInfo {
+	result := make([]discovery.NodeInfo, len(h.nodes))
+	copy(result, h.nodes)
 	return result
 }
 
-// GetNodesHandler 获取当前节点列表
+// encodeNodes 将节点信息编码为字符串，便于比较
+func encodeNodes(nodes []discovery.NodeInfo) []string {
+	encoded := make([]string, 0, len(nodes))
+	for _, n := range nodes {
+		encoded = append(encoded, n.Encode())
+	}
+	return encoded
+}
+
+// nodesVersion 计算节点列表的摘要，与节点顺序无关
+func nodesVersion(nodes []discovery.NodeInfo) string {
+	encoded := encodeNodes(nodes)
+	sort.Strings(encoded)
+	sum := sha256.Sum256([]byte(strings.Join(encoded, "\n")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// GetNodesHandler 以当前格式返回节点列表，等同于 NodesHandler(NodesSchemaCurrent)
 func (h *NodeHandler) GetNodesHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
+	h.serveNodes(w, r, NodesSchemaCurrent)
+}
+
+// NodesHandler 返回以 schema 格式输出节点列表的处理器，/peers 与 /api/nodes 共用。
+// 请求头 Accept 指定 gocache 的媒体类型时以其为准；支持 If-None-Match 条件请求、
+// cursor/limit 分页、gzip 压缩，以及 delta=1 时相对客户端已知版本的增量响应
+func (h *NodeHandler) NodesHandler(schema NodesSchema) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.serveNodes(w, r, schema)
+	}
+}
+
+// serveNodes 输出节点列表，节点列表未变化时返回 304
+func (h *NodeHandler) serveNodes(w http.ResponseWriter, r *http.Request, schema NodesSchema) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
+	// 内容协商：显式请求某种格式时覆盖路由的默认格式
+	accept := r.Header.Get("Accept")
+	switch {
+	case strings.Contains(accept, mediaTypeLegacyPeers):
+		schema = NodesSchemaLegacy
+	case strings.Contains(accept, mediaTypeNodes):
+		schema = NodesSchemaCurrent
+	}
+
+	query := r.URL.Query()
+	page, err := parseNodesPage(query)
+	if err != nil {
+		http.Error(w, "Bad Request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	wantDelta := query.Get("delta") == "1" || query.Get("delta") == "true" || strings.Contains(accept, MediaTypeNodesDelta)
+	since := query.Get("since")
+	if since == "" {
+		since = etagVersion(r.Header.Get("If-None-Match"))
+	}
+
+	// 节点列表与其摘要在同一把锁下读取，保证 ETag 与响应内容一致
 	h.mu.RLock()
-	nodes := h.getNodeAddresses()
+	nodes := h.getNodes()
+	version := h.version
+	etag := fmt.Sprintf(`"%s-%s"`, schema, version)
+	maxAge := h.maxAge
+	protocols := h.protocols
+	var base []discovery.NodeInfo
+	haveBase := false
+	if wantDelta && since != "" {
+		base, haveBase = h.lookup(since)
+	}
 	h.mu.RUnlock()
 
-	// 检查路径，如果是/peers则使用旧的格式
-	if r.URL.Path == "/peers" {
-		response := LegacyPeersResponse{
-			Peers: nodes,
-		}
-		w.Header().Set("Content-Type", "application/json")
-		if err := json.NewEncoder(w).Encode(response); err != nil {
-			logger.Errorf("序列化旧格式节点列表响应失败: %v", err)
-			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-			return
-		}
-		logger.Debugf("返回旧格式节点列表，共 %d 个节点", len(nodes))
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", fmt.Sprintf("max-age=%d", int(maxAge/time.Second)))
+	w.Header().Set("Vary", "Accept, Accept-Encoding")
+	if peers.ETagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
 		return
 	}
 
-	// 默认使用新的格式
-	response := NodeResponse{
-		Count: len(nodes),
-		Nodes: nodes,
+	if haveBase {
+		// 客户端已知的版本仍在历史中，只返回变化的部分
+		added, removed := discovery.DiffNodes(base, nodes)
+		writeNodesJSON(w, r, MediaTypeNodesDelta, NodesDelta{Version: version, Added: added, Removed: removed})
+		logger.Debugf("返回节点列表增量 (%s -> %s)，新增或变化 %d 个，移除 %d 个", since, version, len(added), len(removed))
+		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(response); err != nil {
-		logger.Errorf("序列化节点列表响应失败: %v", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-		return
+	total := len(nodes)
+	nodes, next := page.apply(nodes)
+
+	var response interface{}
+	if schema == NodesSchemaLegacy {
+		// 旧格式的 peers 只包含 gRPC 地址，与节点过去注册的值一致
+		peers := make([]string, 0, len(nodes))
+		for _, n := range nodes {
+			peers = append(peers, n.GRPCAddr)
+		}
+		response = LegacyPeersResponse{
+			Peers:      peers,
+			Nodes:      nodes,
+			Version:    version,
+			NextCursor: next,
+		}
+	} else {
+		resp := NodeResponse{
+			Count:      len(nodes),
+			Nodes:      discovery.NodeKeys(nodes),
+			Details:    nodes,
+			Version:    version,
+			NextCursor: next,
+		}
+		if page.limit > 0 {
+			resp.Total = total
+		}
+		if protocols != nil {
+			resp.Protocols = protocols()
+		}
+		response = resp
 	}
 
-	logger.Debugf("返回节点列表，共 %d 个节点", len(nodes))
+	writeNodesJSON(w, r, "application/json", response)
+	logger.Debugf("返回节点列表 (格式 %s)，共 %d 个节点", schema, len(nodes))
 }
 
-// HealthCheckHandler 健康检查处理器
+// HealthCheckHandler 健康检查处理器，汇总各组件的状态，关键组件不可用时返回 503。
+// 未设置健康检查时固定返回 ok
 func (h *NodeHandler) HealthCheckHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	w.Write([]byte(`{"status":"ok"}`))
+	h.mu.RLock()
+	checker := h.checker
+	h.mu.RUnlock()
+
+	if checker == nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"status":"ok"}`))
+		return
+	}
+	checker.HealthHandler(w, r)
+}
+
+// ReadyHandler 就绪检查处理器，首次同步节点列表之前返回 503
+func (h *NodeHandler) ReadyHandler(w http.ResponseWriter, r *http.Request) {
+	h.mu.RLock()
+	checker := h.checker
+	h.mu.RUnlock()
+
+	if checker == nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ready":true}`))
+		return
+	}
+	checker.ReadyHandler(w, r)
 }
 
 // 比较两个字符串切片是否相等
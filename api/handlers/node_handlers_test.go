@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/internal/discovery"
+)
+
+var testNodes = []discovery.NodeInfo{
+	{ID: "node-1", GRPCAddr: "10.0.0.1:9090", HTTPAddr: "10.0.0.1:8001"},
+	{ID: "node-2", GRPCAddr: "10.0.0.2:9090", HTTPAddr: "10.0.0.2:8001"},
+}
+
+// getNodes 以 schema 路由请求节点列表，header 为附加的请求头
+func getNodes(h *NodeHandler, schema NodesSchema, header ...string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, "/nodes", nil)
+	for i := 0; i+1 < len(header); i += 2 {
+		r.Header.Set(header[i], header[i+1])
+	}
+	w := httptest.NewRecorder()
+	h.NodesHandler(schema)(w, r)
+	return w
+}
+
+func TestNodesSchemas(t *testing.T) {
+	h := NewNodeHandler()
+	h.UpdateNodes(testNodes)
+
+	w := getNodes(h, NodesSchemaLegacy)
+	var legacy LegacyPeersResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &legacy); err != nil {
+		t.Fatalf("旧格式: %v: %s", err, w.Body)
+	}
+	if strings.Join(legacy.Peers, ",") != "10.0.0.1:9090,10.0.0.2:9090" || len(legacy.Nodes) != 2 {
+		t.Fatalf("旧格式 = %+v", legacy)
+	}
+
+	w = getNodes(h, NodesSchemaCurrent)
+	var current NodeResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &current); err != nil {
+		t.Fatalf("当前格式: %v: %s", err, w.Body)
+	}
+	if current.Count != 2 || strings.Join(current.Nodes, ",") != "node-1,node-2" || len(current.Details) != 2 {
+		t.Fatalf("当前格式 = %+v", current)
+	}
+
+	// Accept 显式指定格式时覆盖路由的默认格式
+	w = getNodes(h, NodesSchemaCurrent, "Accept", mediaTypeLegacyPeers)
+	if !strings.Contains(w.Body.String(), `"peers"`) {
+		t.Fatalf("Accept 旧格式得到 %s", w.Body)
+	}
+	w = getNodes(h, NodesSchemaLegacy, "Accept", mediaTypeNodes)
+	if !strings.Contains(w.Body.String(), `"details"`) {
+		t.Fatalf("Accept 当前格式得到 %s", w.Body)
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/peers", nil)
+	rec := httptest.NewRecorder()
+	h.NodesHandler(NodesSchemaLegacy)(rec, r)
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("POST 状态码 = %d", rec.Code)
+	}
+}
+
+func TestNodesETag(t *testing.T) {
+	h := NewNodeHandler()
+	h.SetCacheMaxAge(10 * time.Second)
+	h.UpdateNodes(testNodes)
+
+	w := getNodes(h, NodesSchemaLegacy)
+	etag := w.Header().Get("ETag")
+	if etag == "" || w.Header().Get("Cache-Control") != "max-age=10" {
+		t.Fatalf("响应头 = %v", w.Header())
+	}
+	// 两种格式的 ETag 不同，避免共享缓存混用响应
+	if other := getNodes(h, NodesSchemaCurrent).Header().Get("ETag"); other == etag {
+		t.Fatalf("两种格式的 ETag 相同: %s", etag)
+	}
+
+	w = getNodes(h, NodesSchemaLegacy, "If-None-Match", etag)
+	if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Fatalf("If-None-Match 命中: %d %q", w.Code, w.Body)
+	}
+	if w = getNodes(h, NodesSchemaLegacy, "If-None-Match", `"other", W/`+etag); w.Code != http.StatusNotModified {
+		t.Fatalf("弱比较的 If-None-Match: %d", w.Code)
+	}
+
+	// 顺序不同的相同列表不改变 ETag
+	h.UpdateNodes([]discovery.NodeInfo{testNodes[1], testNodes[0]})
+	if w = getNodes(h, NodesSchemaLegacy, "If-None-Match", etag); w.Code != http.StatusNotModified {
+		t.Fatalf("顺序变化后: %d", w.Code)
+	}
+
+	h.UpdateNodes(testNodes[:1])
+	w = getNodes(h, NodesSchemaLegacy, "If-None-Match", etag)
+	if w.Code != http.StatusOK || w.Header().Get("ETag") == etag {
+		t.Fatalf("列表变化后: %d, ETag %s", w.Code, w.Header().Get("ETag"))
+	}
+}
+
+// TestNodesConcurrentUpdates 请求期间并发更新节点列表，每个响应的 ETag 都与内容一致
+func TestNodesConcurrentUpdates(t *testing.T) {
+	h := NewNodeHandler()
+	lists := make([][]discovery.NodeInfo, 5)
+	versions := make(map[string]int)
+	for i := range lists {
+		for j := 0; j <= i; j++ {
+			lists[i] = append(lists[i], discovery.NodeInfo{GRPCAddr: fmt.Sprintf("10.0.0.%d:9090", j+1)})
+		}
+		versions[nodesVersion(lists[i])] = len(lists[i])
+	}
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+				h.UpdateNodes(lists[i%len(lists)])
+			}
+		}
+	}()
+
+	for i := 0; i < 500; i++ {
+		w := getNodes(h, NodesSchemaLegacy)
+		var resp LegacyPeersResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		if w.Header().Get("ETag") != fmt.Sprintf(`"%s-%s"`, NodesSchemaLegacy, resp.Version) {
+			t.Fatalf("ETag %s 与内容版本 %s 不一致", w.Header().Get("ETag"), resp.Version)
+		}
+		if n, ok := versions[resp.Version]; ok && n != len(resp.Peers) {
+			t.Fatalf("版本 %s 应有 %d 个节点，响应有 %d 个", resp.Version, n, len(resp.Peers))
+		}
+	}
+	close(stop)
+	wg.Wait()
+}
+
+// TestSeedNodesRoutableBeforeDiscovery 种子节点在首次同步之前即可路由，第一份节点列表替换它们
+func TestSeedNodesRoutableBeforeDiscovery(t *testing.T) {
+	seeds, err := discovery.ParseSeeds([]string{"node-1=10.0.0.1:9090"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodes := NewNodeHandler()
+	cacheHandler := NewCacheHandler("/_gocache/", 50, CacheHandlerOptions{Protocol: ProtocolGRPC, Getters: &recordingFactory{}})
+	nodes.SetServiceChangeHook(cacheHandler.UpdatePeers)
+
+	nodes.UpdateNodes(seeds)
+	if node, getter := cacheHandler.pickNode("k"); node != "node-1" || getter == nil {
+		t.Fatalf("种子阶段选择节点 %q", node)
+	}
+
+	nodes.UpdateNodes(testNodes)
+	for i := 0; i < 100; i++ {
+		if node, _ := cacheHandler.pickNode(fmt.Sprintf("key-%d", i)); node != "node-1" && node != "node-2" {
+			t.Fatalf("同步后选择节点 %q", node)
+		}
+	}
+	if got := cacheHandler.GetNodeGetters()["node-1"].(*stubGetter).addr; got != "10.0.0.1:9090" {
+		t.Fatalf("node-1 的地址 = %q", got)
+	}
+}
+
+// TestNodesShowProtocols /api/nodes 显示访问每个节点所用的协议，旧格式不受影响
+func TestNodesShowProtocols(t *testing.T) {
+	cache := NewCacheHandler("/_gocache/", 50, CacheHandlerOptions{Protocol: ProtocolHTTP, Getters: &recordingFactory{}})
+	h := NewNodeHandler()
+	h.SetServiceChangeHook(cache.UpdatePeers)
+	h.SetNodeProtocols(cache.NodeProtocols)
+	h.UpdateNodes([]discovery.NodeInfo{
+		{ID: "node-1", GRPCAddr: "10.0.0.1:9090", HTTPAddr: "10.0.0.1:8001", Protocols: []string{"grpc", "http"}},
+		{ID: "node-2", GRPCAddr: "10.0.0.2:9090", HTTPAddr: "10.0.0.2:8001"},
+	})
+
+	var resp NodeResponse
+	if err := json.Unmarshal(getNodes(h, NodesSchemaCurrent).Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Protocols["node-1"] != ProtocolGRPC || resp.Protocols["node-2"] != ProtocolHTTP {
+		t.Fatalf("protocols = %v", resp.Protocols)
+	}
+	var legacy map[string]json.RawMessage
+	if err := json.Unmarshal(getNodes(h, NodesSchemaLegacy).Body.Bytes(), &legacy); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := legacy["protocols"]; ok {
+		t.Fatal("旧格式包含 protocols")
+	}
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/AdrianWangs/go-cache/internal/discovery"
+	"github.com/AdrianWangs/go-cache/pkg/logger"
+)
+
+const (
+	// nodesHistorySize 保留的历史版本数，客户端的版本早于这些时退回完整列表
+	nodesHistorySize = 32
+	// nodesGzipMinSize 响应达到该大小且客户端接受 gzip 时压缩，更小的响应压缩收益不大
+	nodesGzipMinSize = 1 << 10
+)
+
+// NodesDelta 节点列表的增量响应（Content-Type 为 MediaTypeNodesDelta）：
+// 相对客户端已知版本新增或注册信息变化的节点，以及离开的节点标识。
+// 客户端先移除 Removed，再按标识加入或替换 Added，即得到 Version 对应的列表，
+// 见 discovery.ApplyNodesDiff
+type NodesDelta struct {
+	Version string               `json:"version"` // 应用增量后节点列表的版本
+	Added   []discovery.NodeInfo `json:"added"`   // 新增或注册信息变化的节点
+	Removed []string             `json:"removed"` // 离开的节点标识（一致性哈希环上的 key）
+}
+
+// nodesSnapshot 节点列表的一个历史版本
+type nodesSnapshot struct {
+	version string
+	nodes   []discovery.NodeInfo
+}
+
+// remember 记录被替换的节点列表，只保留最近 nodesHistorySize 个版本；调用方需持有写锁
+func (h *NodeHandler) remember(version string, nodes []discovery.NodeInfo) {
+	h.history = append(h.history, nodesSnapshot{version: version, nodes: nodes})
+	if len(h.history) > nodesHistorySize {
+		h.history = h.history[len(h.history)-nodesHistorySize:]
+	}
+}
+
+// lookup 返回版本 version 的节点列表，包括当前版本；调用方需持有读锁
+func (h *NodeHandler) lookup(version string) ([]discovery.NodeInfo, bool) {
+	if version == h.version {
+		return h.nodes, true
+	}
+	for i := len(h.history) - 1; i >= 0; i-- {
+		if h.history[i].version == version {
+			return h.history[i].nodes, true
+		}
+	}
+	return nil, false
+}
+
+// etagVersion 从 If-None-Match 中取出节点列表的版本，即 ETag "{schema}-{version}" 中的 version；
+// 有多个值时取第一个
+func etagVersion(ifNoneMatch string) string {
+	if ifNoneMatch == "" {
+		return ""
+	}
+	first, _, _ := strings.Cut(ifNoneMatch, ",")
+	tag := strings.Trim(strings.TrimPrefix(strings.TrimSpace(first), "W/"), `"`)
+	if i := strings.LastIndexByte(tag, '-'); i >= 0 {
+		return tag[i+1:]
+	}
+	return ""
+}
+
+// nodesPage 节点列表的分页参数，limit 为 0 表示不分页
+type nodesPage struct {
+	cursor string // 上一页最后一个节点的标识，从它之后开始
+	limit  int
+}
+
+// parseNodesPage 解析 cursor 和 limit 查询参数
+func parseNodesPage(query url.Values) (nodesPage, error) {
+	page := nodesPage{cursor: query.Get("cursor")}
+	if s := query.Get("limit"); s != "" {
+		limit, err := strconv.Atoi(s)
+		if err != nil || limit <= 0 {
+			return page, fmt.Errorf("limit must be a positive integer")
+		}
+		page.limit = limit
+	}
+	if page.cursor != "" && page.limit == 0 {
+		return page, fmt.Errorf("cursor requires limit")
+	}
+	return page, nil
+}
+
+// apply 返回本页的节点和下一页的 cursor。分页时节点按标识排序，
+// cursor 是上一页最后一个节点的标识，因此翻页期间节点列表变化也不会重复或跳过未变化的节点
+func (p nodesPage) apply(nodes []discovery.NodeInfo) ([]discovery.NodeInfo, string) {
+	if p.limit == 0 {
+		return nodes, ""
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Key() < nodes[j].Key() })
+	start := sort.Search(len(nodes), func(i int) bool { return nodes[i].Key() > p.cursor })
+	nodes = nodes[start:]
+	if len(nodes) <= p.limit {
+		return nodes, ""
+	}
+	nodes = nodes[:p.limit]
+	return nodes, nodes[len(nodes)-1].Key()
+}
+
+// acceptsGzip 判断请求的 Accept-Encoding 是否接受 gzip
+func acceptsGzip(r *http.Request) bool {
+	for _, enc := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		enc, params, _ := strings.Cut(strings.TrimSpace(enc), ";")
+		if strings.EqualFold(strings.TrimSpace(enc), "gzip") {
+			return strings.ReplaceAll(params, " ", "") != "q=0"
+		}
+	}
+	return false
+}
+
+// writeNodesJSON 以 contentType 输出 v 的 JSON，响应较大且客户端接受 gzip 时压缩
+func writeNodesJSON(w http.ResponseWriter, r *http.Request, contentType string, v interface{}) {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(v); err != nil {
+		logger.Errorf("序列化节点列表响应失败: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	if buf.Len() < nodesGzipMinSize || !acceptsGzip(r) {
+		w.Write(buf.Bytes())
+		return
+	}
+	w.Header().Set("Content-Encoding", "gzip")
+	zw := gzip.NewWriter(w)
+	zw.Write(buf.Bytes())
+	if err := zw.Close(); err != nil {
+		logger.Warnf("写入压缩的节点列表响应失败: %v", err)
+	}
+}
@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/AdrianWangs/go-cache/internal/discovery"
+)
+
+// requestNodes 以 schema 路由请求 target，header 为附加的请求头
+func requestNodes(h *NodeHandler, schema NodesSchema, target string, header ...string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, target, nil)
+	for i := 0; i+1 < len(header); i += 2 {
+		r.Header.Set(header[i], header[i+1])
+	}
+	w := httptest.NewRecorder()
+	h.NodesHandler(schema)(w, r)
+	return w
+}
+
+// manyNodes 返回 n 个标识为 node-000.. 的节点
+func manyNodes(n int) []discovery.NodeInfo {
+	nodes := make([]discovery.NodeInfo, n)
+	for i := range nodes {
+		nodes[i] = discovery.NodeInfo{ID: fmt.Sprintf("node-%03d", i), GRPCAddr: fmt.Sprintf("10.0.%d.%d:9090", i/250, i%250)}
+	}
+	return nodes
+}
+
+// nodeSet 返回节点列表与顺序无关的编码
+func nodeSet(nodes []discovery.NodeInfo) string {
+	enc := make([]string, len(nodes))
+	for i, n := range nodes {
+		enc[i] = n.Encode()
+	}
+	sort.Strings(enc)
+	return strings.Join(enc, "\n")
+}
+
+// TestNodesDeltaChurn 客户端带上次的 ETag 请求增量，在节点反复加入、离开和变化时，
+// 把每次的增量应用到已知列表都得到服务端的当前列表；列表没有变化时返回 304
+func TestNodesDeltaChurn(t *testing.T) {
+	h := NewNodeHandler()
+	rng := rand.New(rand.NewSource(1))
+	pool := manyNodes(40)
+	pick := func() []discovery.NodeInfo {
+		var nodes []discovery.NodeInfo
+		for _, n := range pool {
+			if rng.Intn(3) > 0 {
+				if rng.Intn(10) == 0 {
+					n.HTTPAddr = fmt.Sprintf("10.1.0.%d:8001", rng.Intn(250))
+				}
+				nodes = append(nodes, n)
+			}
+		}
+		return nodes
+	}
+
+	h.UpdateNodes(pick())
+	w := requestNodes(h, NodesSchemaCurrent, "/api/nodes")
+	var full NodeResponse
+	json.Unmarshal(w.Body.Bytes(), &full)
+	known, etag := full.Details, w.Header().Get("ETag")
+
+	deltas := 0
+	for round := 0; round < 50; round++ {
+		if round%5 != 4 {
+			h.UpdateNodes(pick())
+		}
+		w := requestNodes(h, NodesSchemaCurrent, "/api/nodes?delta=1", "If-None-Match", etag)
+		if round%5 == 4 {
+			if w.Code != http.StatusNotModified {
+				t.Fatalf("第 %d 轮: 列表未变化时状态码 %d", round, w.Code)
+			}
+			continue
+		}
+		if w.Code != http.StatusOK || w.Header().Get("Content-Type") != MediaTypeNodesDelta {
+			t.Fatalf("第 %d 轮: %d %s, want 增量响应", round, w.Code, w.Header().Get("Content-Type"))
+		}
+		var delta NodesDelta
+		if err := json.Unmarshal(w.Body.Bytes(), &delta); err != nil {
+			t.Fatal(err)
+		}
+		deltas++
+		known = discovery.ApplyNodesDiff(known, delta.Added, delta.Removed)
+		etag = w.Header().Get("ETag")
+		if want := h.getNodes(); nodeSet(known) != nodeSet(want) {
+			t.Fatalf("第 %d 轮: 应用增量后的列表与服务端不同", round)
+		}
+		if !strings.Contains(etag, delta.Version) {
+			t.Fatalf("第 %d 轮: ETag %s 与增量版本 %s 不一致", round, etag, delta.Version)
+		}
+	}
+	if deltas != 40 {
+		t.Fatalf("收到 %d 个增量, want 40", deltas)
+	}
+}
+
+// TestNodesDeltaFallback 不请求增量的旧客户端和版本已不在历史中的客户端都得到完整列表
+func TestNodesDeltaFallback(t *testing.T) {
+	h := NewNodeHandler()
+	nodes := manyNodes(nodesHistorySize + 2)
+	h.UpdateNodes(nodes[:1])
+	first := requestNodes(h, NodesSchemaLegacy, "/peers").Header().Get("ETag")
+	h.UpdateNodes(nodes[:2])
+
+	// 旧客户端只带 If-None-Match，列表变化后得到完整列表
+	w := requestNodes(h, NodesSchemaLegacy, "/peers", "If-None-Match", first)
+	var legacy LegacyPeersResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &legacy); err != nil || w.Header().Get("Content-Type") != "application/json" || len(legacy.Nodes) != 2 {
+		t.Fatalf("旧客户端得到 %s %s", w.Header().Get("Content-Type"), w.Body)
+	}
+
+	// 也可以用 since 参数代替 If-None-Match
+	w = requestNodes(h, NodesSchemaLegacy, "/peers?delta=1&since="+etagVersion(first))
+	if w.Header().Get("Content-Type") != MediaTypeNodesDelta {
+		t.Fatalf("since 参数得到 %s %s", w.Header().Get("Content-Type"), w.Body)
+	}
+
+	for i := 3; i <= len(nodes); i++ {
+		h.UpdateNodes(nodes[:i])
+	}
+	w = requestNodes(h, NodesSchemaLegacy, "/peers?delta=1", "If-None-Match", first)
+	if err := json.Unmarshal(w.Body.Bytes(), &legacy); err != nil || len(legacy.Nodes) != len(nodes) || legacy.Version == "" {
+		t.Fatalf("版本过旧时得到 %s %s", w.Header().Get("Content-Type"), w.Body)
+	}
+}
+
+func TestNodesPagination(t *testing.T) {
+	h := NewNodeHandler()
+	h.UpdateNodes(manyNodes(25))
+
+	var seen []string
+	cursor, pages := "", 0
+	for {
+		w := requestNodes(h, NodesSchemaCurrent, "/api/nodes?limit=10&cursor="+cursor)
+		var page NodeResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+			t.Fatal(err)
+		}
+		pages++
+		if page.Total != 25 || page.Count != len(page.Details) || page.Count > 10 {
+			t.Fatalf("第 %d 页 = %+v", pages, page)
+		}
+		seen = append(seen, page.Nodes...)
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+	if pages != 3 || len(seen) != 25 || !sort.StringsAreSorted(seen) {
+		t.Fatalf("%d 页，节点 %v", pages, seen)
+	}
+
+	// 翻页期间离开的节点不影响后面的页
+	w := requestNodes(h, NodesSchemaCurrent, "/api/nodes?limit=10")
+	var page NodeResponse
+	json.Unmarshal(w.Body.Bytes(), &page)
+	h.UpdateNodes(manyNodes(25)[5:])
+	w = requestNodes(h, NodesSchemaCurrent, "/api/nodes?limit=10&cursor="+page.NextCursor)
+	json.Unmarshal(w.Body.Bytes(), &page)
+	if page.Nodes[0] != "node-010" {
+		t.Fatalf("节点离开后的下一页从 %s 开始", page.Nodes[0])
+	}
+
+	for _, target := range []string{"/api/nodes?limit=0", "/api/nodes?limit=x", "/api/nodes?cursor=node-001"} {
+		if w := requestNodes(h, NodesSchemaCurrent, target); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: 状态码 %d, want 400", target, w.Code)
+		}
+	}
+}
+
+func TestNodesGzip(t *testing.T) {
+	h := NewNodeHandler()
+	h.UpdateNodes(manyNodes(100))
+
+	w := requestNodes(h, NodesSchemaCurrent, "/api/nodes", "Accept-Encoding", "br, gzip")
+	if w.Header().Get("Content-Encoding") != "gzip" || !strings.Contains(w.Header().Get("Vary"), "Accept-Encoding") {
+		t.Fatalf("响应头 = %v", w.Header())
+	}
+	zr, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(zr)
+	var resp NodeResponse
+	if err := json.Unmarshal(body, &resp); err != nil || resp.Count != 100 {
+		t.Fatalf("解压后的响应: %v, %d 个节点", err, resp.Count)
+	}
+
+	for _, enc := range []string{"", "gzip;q=0", "identity"} {
+		if w := requestNodes(h, NodesSchemaCurrent, "/api/nodes", "Accept-Encoding", enc); w.Header().Get("Content-Encoding") != "" {
+			t.Errorf("Accept-Encoding %q 时压缩了响应", enc)
+		}
+	}
+
+	// 小响应不压缩
+	h.UpdateNodes(testNodes)
+	if w := requestNodes(h, NodesSchemaCurrent, "/api/nodes", "Accept-Encoding", "gzip"); w.Header().Get("Content-Encoding") != "" {
+		t.Error("小响应被压缩")
+	}
+}
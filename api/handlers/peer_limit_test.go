@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/internal/peers"
+	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
+)
+
+// TestPeerLimitBoundsGoroutines 向从不响应的节点发送大量读取：只有 MaxInFlight 个在等待，
+// 其余立即以 ErrPeerBusy 失败，goroutine 数不随读取数增长
+func TestPeerLimitBoundsGoroutines(t *testing.T) {
+	const maxInFlight, callers = 4, 200
+	type limitedGetter interface {
+		GetByProto(ctx context.Context, req *pb.Request, resp *pb.Response) error
+	}
+	getters := map[string]func(url string) (limitedGetter, *peers.Counters){
+		"HTTPGetter": func(url string) (limitedGetter, *peers.Counters) {
+			h := NewHTTPGetter(url, WithRequestTimeout(time.Minute), WithPeerLimit(peers.Limit{MaxInFlight: maxInFlight}))
+			return h, h.counters
+		},
+		"ProtoGetter": func(url string) (limitedGetter, *peers.Counters) {
+			p := NewProtoGetter(url, WithRequestTimeout(time.Minute), WithPeerLimit(peers.Limit{MaxInFlight: maxInFlight}))
+			return p, p.counters
+		},
+	}
+	for name, newGetter := range getters {
+		t.Run(name, func(t *testing.T) {
+			done := make(chan struct{})
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				select {
+				case <-r.Context().Done():
+				case <-done:
+				}
+			}))
+			defer srv.Close()
+			defer close(done)
+			getter, counters := newGetter(srv.URL + "/_go_cache/")
+			before := runtime.NumGoroutine()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			errs := make(chan error, callers)
+			for i := 0; i < callers; i++ {
+				go func() {
+					errs <- getter.GetByProto(ctx, &pb.Request{Group: "scores", Key: "k"}, &pb.Response{})
+				}()
+			}
+			for i := 0; i < callers-maxInFlight; i++ {
+				if err := <-errs; !errors.Is(err, peers.ErrPeerBusy) {
+					t.Fatalf("超出上限的读取 = %v, want ErrPeerBusy", err)
+				}
+			}
+
+			// 剩下的只有等待中的读取及其连接，不是每个调用方一个
+			deadline := time.Now().Add(5 * time.Second)
+			for runtime.NumGoroutine()-before > maxInFlight*8 {
+				if time.Now().After(deadline) {
+					t.Fatalf("goroutine 数 %d, 开始时 %d", runtime.NumGoroutine(), before)
+				}
+				time.Sleep(time.Millisecond)
+			}
+			if s := counters.Snapshot(); s.InFlight != maxInFlight || s.Busy != callers-maxInFlight || s.Requests != maxInFlight {
+				t.Fatalf("统计 = %+v", s)
+			}
+
+			cancel()
+			for i := 0; i < maxInFlight; i++ {
+				if err := <-errs; err == nil || errors.Is(err, peers.ErrPeerBusy) {
+					t.Fatalf("取消后的读取 = %v", err)
+				}
+			}
+			if s := counters.Snapshot(); s.InFlight != 0 {
+				t.Fatalf("所有读取返回后 InFlight = %d", s.InFlight)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/AdrianWangs/go-cache/internal/cache"
+	"github.com/AdrianWangs/go-cache/internal/peers"
+	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
+)
+
+// oldNode 模拟协议版本协商之前的节点：只有按 /<group>/<key> 读取和删除的路由，
+// 响应中不声明协议版本；其他方法返回 405，路径不是 /<group>/<key> 时返回 400
+type oldNode struct {
+	mu       sync.Mutex
+	values   map[string]string
+	requests map[string]int // 按 "方法 路径" 统计的请求数
+	version  string         // 非空时在响应头中声明的协议版本
+}
+
+func newOldNode(t *testing.T, values map[string]string) (*oldNode, string) {
+	n := &oldNode{values: values, requests: make(map[string]int)}
+	srv := httptest.NewServer(n)
+	t.Cleanup(srv.Close)
+	return n, srv.URL + "/_go_cache"
+}
+
+func (n *oldNode) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.requests[r.Method+" "+r.URL.Path]++
+	if n.version != "" {
+		w.Header().Set(peers.HeaderProtoVersion, n.version)
+	}
+
+	if r.Method != http.MethodGet && r.Method != http.MethodDelete {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/_go_cache/"), "/", 2)
+	if len(parts) != 2 {
+		http.Error(w, "bad request format", http.StatusBadRequest)
+		return
+	}
+	key := parts[0] + "/" + parts[1]
+	if _, ok := n.values[key]; !ok {
+		http.Error(w, "key not found", http.StatusNotFound)
+		return
+	}
+	if r.Method == http.MethodGet {
+		w.Write([]byte(n.values[key]))
+		return
+	}
+	delete(n.values, key)
+}
+
+// count 返回 "方法 路径" 的请求数
+func (n *oldNode) count(req string) int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.requests[req]
+}
+
+// setterGetter 记录节点协议版本并支持写入的 HTTP 客户端，HTTPGetter 和 ProtoGetter 都实现它
+type setterGetter interface {
+	NodeGetter
+	Set(ctx context.Context, req *pb.SetRequest) error
+	protoVersion() *peers.PeerVersion
+}
+
+// TestNewClientOldNode 新版本的客户端访问不声明协议版本的旧节点：读取照常，可选操作给出明确的
+// 不支持错误，批量删除退回逐个删除
+func TestNewClientOldNode(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		getter func(base string) setterGetter
+	}{
+		{"HTTP", func(base string) setterGetter {
+			return NewHTTPGetter(base)
+		}},
+		{"Protobuf", func(base string) setterGetter {
+			return NewProtoGetter(base)
+		}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			node, base := newOldNode(t, map[string]string{"users/a": "1", "users/b": "2"})
+			g := tt.getter(base)
+			ctx := context.Background()
+
+			if _, err := g.Stats(ctx); !errors.Is(err, ErrStatsUnimplemented) {
+				t.Fatalf("Stats = %v, want ErrStatsUnimplemented", err)
+			}
+
+			err := g.Set(ctx, &pb.SetRequest{Group: "users", Key: "a", Value: []byte("x")})
+			if !errors.Is(err, peers.ErrUnsupported) || !strings.Contains(err.Error(), "upgrade") {
+				t.Fatalf("Set = %v, want ErrUnsupported asking to upgrade the node", err)
+			}
+
+			results, err := batchDelete(ctx, g, "users", []string{"a", "b", "missing"})
+			if err != nil {
+				t.Fatalf("batchDelete: %v", err)
+			}
+			want := []cache.DeleteStatus{cache.DeleteDeleted, cache.DeleteDeleted, cache.DeleteNotFound}
+			for i, r := range results {
+				if r.Status != want[i] {
+					t.Fatalf("结果 %d = %+v, want %v", i, r, want[i])
+				}
+			}
+			if node.count("DELETE /_go_cache/"+deleteBatchPath) != 1 || node.count("DELETE /_go_cache/users/a") != 1 {
+				t.Fatalf("请求 = %v", node.requests)
+			}
+			if v := g.protoVersion().Get(); v != peers.ProtocolUnknown {
+				t.Fatalf("旧节点的协议版本 = %d", v)
+			}
+		})
+	}
+}
+
+// TestClientRecordsVersion 节点声明协议版本后客户端记录它；之后不带版本头的响应不会清除记录
+func TestClientRecordsVersion(t *testing.T) {
+	node, base := newOldNode(t, map[string]string{"users/a": "1"})
+	node.version = "1"
+	g := NewHTTPGetter(base)
+
+	if _, err := g.Get(context.Background(), "users", "a"); err != nil {
+		t.Fatal(err)
+	}
+	if v := g.protoVersion().Get(); v != peers.ProtocolV1 {
+		t.Fatalf("协议版本 = %d, want %d", v, peers.ProtocolV1)
+	}
+
+	// 已知支持写入的节点返回 405 时不再报告为旧版本
+	err := g.Set(context.Background(), &pb.SetRequest{Group: "users", Key: "a", Value: []byte("x")})
+	if err == nil || strings.Contains(err.Error(), "upgrade") {
+		t.Fatalf("v1 节点的 Set = %v", err)
+	}
+
+	node.mu.Lock()
+	node.version = ""
+	node.mu.Unlock()
+	g.Get(context.Background(), "users", "a")
+	if v := g.protoVersion().Get(); v != peers.ProtocolV1 {
+		t.Fatalf("不带版本头的响应后协议版本 = %d", v)
+	}
+}
@@ -0,0 +1,440 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/internal/access"
+	"github.com/AdrianWangs/go-cache/internal/peers"
+	"github.com/AdrianWangs/go-cache/internal/singleflight"
+	"github.com/AdrianWangs/go-cache/pkg/logger"
+	"github.com/AdrianWangs/go-cache/pkg/lru"
+	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
+	"google.golang.org/protobuf/proto"
+)
+
+// ProxyPathPrefix 缓存反向代理路由 /api/proxy/{group}/{path...} 的前缀，path 即缓存的 key，
+// 请求日志按它找出路径中的 key，见 router.LoggingMiddleware
+const ProxyPathPrefix = "/api/proxy/"
+
+// HeaderProxyStatus 代理响应的来源: HIT（缓存命中）、MISS（从上游获取并写入缓存）、
+// BYPASS（从上游获取但未写入缓存）或 NEGATIVE（上游 404 的缓存）
+const HeaderProxyStatus = "X-GoCache-Proxy"
+
+const (
+	defaultProxyTimeout     = 10 * time.Second // 访问上游的默认超时
+	defaultProxyTTL         = 5 * time.Minute  // 从上游获取的值写入缓存后默认的有效期
+	defaultProxyNegativeTTL = 30 * time.Second // 上游 404 默认的缓存时长
+	defaultProxyMaxBody     = 1 << 20          // 默认可缓存的最大响应体字节数
+	proxyNegativeBytes      = 4 << 20          // 上游 404 缓存的容量（按 key 的字节数计）
+)
+
+// ProxyUpstream 一个缓存组的上游配置。组配置了上游后，API 服务器在 /api/proxy/{group}/{path...}
+// 上充当缓存反向代理：缓存未命中时由 API 服务器请求上游，把响应体通过写入接口存到 key 的归属节点后返回。
+// 提供该组的缓存节点不应配置数据源，未缓存的 key 应返回不存在
+type ProxyUpstream struct {
+	URL            string        // 上游地址，请求的 path 和查询参数拼接在它之后，例如 https://origin.example.com/assets
+	Timeout        time.Duration // 一次上游请求的超时，默认 10s
+	ForwardHeaders []string      // 转发给上游的请求头，默认不转发；响应被所有客户端共享，不应转发 Authorization 等与客户端相关的请求头
+	MaxBodyBytes   int64         // 可缓存的最大响应体字节数，更大的响应直接转发给客户端而不写入缓存，默认 1MiB
+	TTL            time.Duration // 写入缓存的值的有效期，默认 5m；上游响应的 max-age 更短时使用 max-age
+	NegativeTTL    time.Duration // 上游 404 在本 API 服务器上缓存的时长，默认 30s，负数表示不缓存
+	ContentType    string        // 返回缓存的值时使用的 Content-Type，为空时按内容推断
+	Client         *http.Client  // 访问上游的客户端，默认 http.DefaultClient
+}
+
+// ProxyUpstreams 按组名配置的上游，没有配置上游的组不能通过 /api/proxy/ 访问
+type ProxyUpstreams map[string]ProxyUpstream
+
+// ProxyStats 缓存反向代理的统计，Hits 与 UpstreamFetches 之比即代理的命中情况
+type ProxyStats struct {
+	Hits             int64 `json:"hits"`               // 由缓存提供的响应数
+	Fills            int64 `json:"fills"`              // 从上游获取并写入缓存的响应数
+	Bypassed         int64 `json:"bypassed"`           // 从上游获取但未写入缓存的响应数：no-store、超过大小上限或节点不可用
+	NegativeHits     int64 `json:"negative_hits"`      // 由上游 404 的缓存提供的 404 响应数
+	UpstreamFetches  int64 `json:"upstream_fetches"`   // 发往上游的请求数，并发的相同未命中只请求一次
+	UpstreamNotFound int64 `json:"upstream_not_found"` // 上游返回 404 的次数
+	UpstreamErrors   int64 `json:"upstream_errors"`    // 上游请求失败或返回 200、404 以外状态码的次数
+	StoreFailures    int64 `json:"store_failures"`     // 写入归属节点失败的次数，响应仍然返回给客户端
+}
+
+// proxy 缓存反向代理，按组保存上游配置
+type proxy struct {
+	upstreams ProxyUpstreams
+	fills     singleflight.Group // 相同 key 并发的未命中只请求一次上游
+	negative  *lru.Cache         // 上游 404 的缓存，以 group + "\x00" + key 为键
+
+	hits, fillCount, bypassed, negativeHits, fetches, notFound, upstreamErrors, storeFailures int64
+}
+
+// proxyResponse 一次上游请求的结果，由并发的相同未命中共享
+type proxyResponse struct {
+	status      int
+	contentType string
+	body        []byte
+	ttl         time.Duration // 写入缓存的有效期，0 表示不写入缓存
+	streamed    bool          // 响应体超过大小上限，已直接转发给发起请求的客户端，其他调用方需要自行请求上游
+}
+
+// negativeEntry 上游 404 缓存的条目
+type negativeEntry struct{}
+
+// Len 实现 lru.Value，容量只按 key 计算
+func (negativeEntry) Len() int { return 0 }
+
+// newProxy 按配置创建 proxy，没有配置上游时返回 nil
+func newProxy(upstreams ProxyUpstreams) *proxy {
+	if len(upstreams) == 0 {
+		return nil
+	}
+	p := &proxy{
+		upstreams: make(ProxyUpstreams, len(upstreams)),
+		negative:  lru.New(proxyNegativeBytes, nil, lru.WithAccessTracking(false)),
+	}
+	for group, up := range upstreams {
+		if up.Timeout <= 0 {
+			up.Timeout = defaultProxyTimeout
+		}
+		if up.TTL <= 0 {
+			up.TTL = defaultProxyTTL
+		}
+		if up.NegativeTTL == 0 {
+			up.NegativeTTL = defaultProxyNegativeTTL
+		}
+		if up.MaxBodyBytes <= 0 {
+			up.MaxBodyBytes = defaultProxyMaxBody
+		}
+		if up.Client == nil {
+			up.Client = http.DefaultClient
+		}
+		up.URL = strings.TrimRight(up.URL, "/")
+		p.upstreams[group] = up
+		logger.Infof("缓存组 %s 开启缓存反向代理，上游: %s", group, up.URL)
+	}
+	return p
+}
+
+// stats 返回当前的代理统计
+func (p *proxy) stats() ProxyStats {
+	return ProxyStats{
+		Hits:             atomic.LoadInt64(&p.hits),
+		Fills:            atomic.LoadInt64(&p.fillCount),
+		Bypassed:         atomic.LoadInt64(&p.bypassed),
+		NegativeHits:     atomic.LoadInt64(&p.negativeHits),
+		UpstreamFetches:  atomic.LoadInt64(&p.fetches),
+		UpstreamNotFound: atomic.LoadInt64(&p.notFound),
+		UpstreamErrors:   atomic.LoadInt64(&p.upstreamErrors),
+		StoreFailures:    atomic.LoadInt64(&p.storeFailures),
+	}
+}
+
+// ProxyStats 返回缓存反向代理的统计，没有组配置上游时返回 nil
+func (h *CacheHandler) ProxyStats() *ProxyStats {
+	if h.proxy == nil {
+		return nil
+	}
+	stats := h.proxy.stats()
+	return &stats
+}
+
+// ProxyHandler 处理 GET /api/proxy/{group}/{path...}：path（带查询参数时包括查询参数）作为组中的 key，
+// 缓存命中时直接返回值；未命中时请求组的上游，把 200 响应写入 key 的归属节点后返回。
+// 上游 404 在本 API 服务器上缓存 NegativeTTL；上游响应带 Cache-Control: no-store、no-cache 或 private、
+// 或者响应体超过 MaxBodyBytes 时只转发不缓存。只代理 GET，其他方法返回 405
+func (h *CacheHandler) ProxyHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	if r.Method != http.MethodGet && r.Method != "" {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	group, key, upstreamPath, ok := parseProxyPath(r.URL)
+	if !ok {
+		http.Error(w, "Bad Request: expected /api/proxy/{group}/{path}", http.StatusBadRequest)
+		return
+	}
+	if !access.Authorize(w, r, group, access.OpRead) {
+		return
+	}
+	var up ProxyUpstream
+	if h.proxy != nil {
+		up, ok = h.proxy.upstreams[group]
+	}
+	if !ok {
+		http.Error(w, fmt.Sprintf("Not Found: group %s has no upstream", group), http.StatusNotFound)
+		return
+	}
+	p := h.proxy
+
+	if up.NegativeTTL > 0 {
+		if _, found := p.negative.Get(hotRouteKey(group, key)); found {
+			atomic.AddInt64(&p.negativeHits, 1)
+			w.Header().Set(HeaderProxyStatus, "NEGATIVE")
+			http.Error(w, "Not Found", http.StatusNotFound)
+			return
+		}
+	}
+
+	req := &pb.Request{Group: group, Key: key}
+	res := &pb.Response{}
+	nodeAddr, err := h.getHot(r.Context(), clientIdentity(r.Context(), r.RemoteAddr), key, req, res)
+	if err != nil && h.clientGone(r) {
+		return
+	}
+	if err == nil {
+		atomic.AddInt64(&p.hits, 1)
+		w.Header().Set(HeaderProxyStatus, "HIT")
+		h.writeProxyValue(w, r, group, up, res)
+		return
+	}
+	// 节点不可用或读取失败时仍然从上游提供响应，只是不写入缓存
+	store := isKeyNotFound(err)
+	if !store {
+		logger.Warnf("代理读取缓存失败，直接请求上游: group=%s, key=%s, node=%s: %v", group, logger.Key(key), nodeAddr, err)
+	}
+
+	// 相同 key 并发的未命中共享一次上游请求；只有发起请求的调用方在响应体超过上限时直接收到转发
+	leader := false
+	v, err := p.fills.Do(hotRouteKey(group, key), func() (interface{}, error) {
+		leader = true
+		return h.fetchUpstream(w, r, group, key, upstreamPath, up, store)
+	})
+	if err != nil {
+		if h.clientGone(r) {
+			return
+		}
+		http.Error(w, "Bad Gateway: upstream request failed", http.StatusBadGateway)
+		return
+	}
+	resp := v.(*proxyResponse)
+	if resp.streamed {
+		if leader {
+			return
+		}
+		// 超过大小上限的响应无法共享，自行请求上游，不写入缓存
+		if resp, err = h.fetchUpstream(w, r, group, key, upstreamPath, up, false); err != nil {
+			if !h.clientGone(r) {
+				http.Error(w, "Bad Gateway: upstream request failed", http.StatusBadGateway)
+			}
+			return
+		}
+		if resp.streamed {
+			return
+		}
+	}
+	h.writeProxyResponse(w, r, group, resp)
+}
+
+// parseProxyPath 解析 /api/proxy/{group}/{path...}，返回组名、key 和转发给上游的转义后的路径（包括查询参数）。
+// path 先经过清理：去掉 "." 段和重复的 "/"，保留末尾的 "/"；含 ".." 段时（包括 %2e%2e 以及
+// %2F 转义的斜杠分隔出的 ".."）返回 ok 为 false，避免请求越出上游地址的路径
+func parseProxyPath(u *url.URL) (group, key, upstreamPath string, ok bool) {
+	rest, found := strings.CutPrefix(u.EscapedPath(), ProxyPathPrefix)
+	if !found {
+		return "", "", "", false
+	}
+	escGroup, escPath, found := strings.Cut(rest, "/")
+	if !found || escGroup == "" || escPath == "" {
+		return "", "", "", false
+	}
+	group, err := url.PathUnescape(escGroup)
+	if err != nil {
+		return "", "", "", false
+	}
+	key, upstreamPath, ok = cleanProxyPath(escPath)
+	if !ok {
+		return "", "", "", false
+	}
+	if u.RawQuery != "" {
+		key += "?" + u.RawQuery
+		upstreamPath += "?" + u.RawQuery
+	}
+	return group, key, upstreamPath, true
+}
+
+// cleanProxyPath 按段清理转义的路径 escPath，返回反转义后的 key 和以 "/" 开头的转义路径；
+// 有 ".." 段、无法反转义或清理后为空时 ok 为 false
+func cleanProxyPath(escPath string) (key, upstreamPath string, ok bool) {
+	segments := strings.Split(escPath, "/")
+	var keys, escaped []string
+	for i, seg := range segments {
+		unescaped, err := url.PathUnescape(seg)
+		if err != nil {
+			return "", "", false
+		}
+		for _, part := range strings.FieldsFunc(unescaped, func(r rune) bool { return r == '/' || r == '\\' }) {
+			if part == ".." {
+				return "", "", false
+			}
+		}
+		last := i == len(segments)-1
+		if unescaped == "." || (unescaped == "" && !last) {
+			continue
+		}
+		if unescaped == "" && len(keys) == 0 {
+			return "", "", false
+		}
+		keys = append(keys, unescaped)
+		escaped = append(escaped, seg)
+	}
+	if len(keys) == 0 {
+		return "", "", false
+	}
+	return strings.Join(keys, "/"), "/" + strings.Join(escaped, "/"), true
+}
+
+// fetchUpstream 请求上游并按结果更新统计：200 且可缓存时写入 key 的归属节点（store 为 false 时不写入），
+// 404 记入上游 404 的缓存。响应体超过 MaxBodyBytes 时直接转发给 w 并返回 streamed 的结果
+func (h *CacheHandler) fetchUpstream(w http.ResponseWriter, r *http.Request, group, key, upstreamPath string, up ProxyUpstream, store bool) (*proxyResponse, error) {
+	p := h.proxy
+	atomic.AddInt64(&p.fetches, 1)
+
+	// 上游请求不随发起请求的客户端断开而取消，共享结果的其他调用方仍在等待
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), up.Timeout)
+	defer cancel()
+	upReq, err := http.NewRequestWithContext(ctx, http.MethodGet, up.URL+upstreamPath, nil)
+	if err != nil {
+		atomic.AddInt64(&p.upstreamErrors, 1)
+		return nil, err
+	}
+	for _, name := range up.ForwardHeaders {
+		if values := r.Header.Values(name); len(values) > 0 {
+			upReq.Header[http.CanonicalHeaderKey(name)] = values
+		}
+	}
+	upRes, err := up.Client.Do(upReq)
+	if err != nil {
+		atomic.AddInt64(&p.upstreamErrors, 1)
+		logger.Warnf("请求上游失败: group=%s, key=%s: %v", group, logger.Key(key), err)
+		return nil, err
+	}
+	defer upRes.Body.Close()
+
+	resp := &proxyResponse{status: upRes.StatusCode, contentType: upRes.Header.Get("Content-Type")}
+	body, err := io.ReadAll(io.LimitReader(upRes.Body, up.MaxBodyBytes+1))
+	if err != nil {
+		atomic.AddInt64(&p.upstreamErrors, 1)
+		return nil, err
+	}
+	if int64(len(body)) > up.MaxBodyBytes {
+		// 超过上限的响应体不缓存也不共享，直接转发给发起请求的客户端
+		atomic.AddInt64(&p.bypassed, 1)
+		logger.Debugf("上游响应体超过 %d 字节，不缓存: group=%s, key=%s", up.MaxBodyBytes, group, logger.Key(key))
+		if resp.contentType != "" {
+			w.Header().Set("Content-Type", resp.contentType)
+		}
+		w.Header().Set(HeaderProxyStatus, "BYPASS")
+		w.WriteHeader(upRes.StatusCode)
+		w.Write(body)
+		io.Copy(w, upRes.Body)
+		resp.streamed = true
+		return resp, nil
+	}
+	resp.body = body
+
+	switch upRes.StatusCode {
+	case http.StatusOK:
+		resp.ttl = cacheableTTL(upRes.Header, up.TTL)
+		if !store || resp.ttl <= 0 {
+			resp.ttl = 0
+			atomic.AddInt64(&p.bypassed, 1)
+			return resp, nil
+		}
+		if err := h.storeProxied(ctx, group, key, body, resp.ttl); err != nil {
+			// 写入失败不影响本次响应，下一次请求再从上游获取
+			resp.ttl = 0
+			atomic.AddInt64(&p.storeFailures, 1)
+			atomic.AddInt64(&p.bypassed, 1)
+			logger.Warnf("代理写入缓存失败: group=%s, key=%s: %v", group, logger.Key(key), err)
+			return resp, nil
+		}
+		atomic.AddInt64(&p.fillCount, 1)
+	case http.StatusNotFound:
+		atomic.AddInt64(&p.notFound, 1)
+		if up.NegativeTTL > 0 {
+			p.negative.Add(hotRouteKey(group, key), negativeEntry{}, up.NegativeTTL)
+		}
+	default:
+		atomic.AddInt64(&p.upstreamErrors, 1)
+		logger.Warnf("上游返回 %s: group=%s, key=%s", upRes.Status, group, logger.Key(key))
+	}
+	return resp, nil
+}
+
+// cacheableTTL 按上游响应的 Cache-Control 返回写入缓存的有效期：no-store、no-cache、private 或 max-age=0 时为 0，
+// 有更短的 max-age（s-maxage 优先）时使用它，否则为 ttl
+func cacheableTTL(header http.Header, ttl time.Duration) time.Duration {
+	maxAge := time.Duration(-1)
+	for _, directive := range strings.Split(strings.Join(header.Values("Cache-Control"), ","), ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		switch strings.ToLower(name) {
+		case "no-store", "no-cache", "private":
+			return 0
+		case "max-age", "s-maxage":
+			secs, err := strconv.ParseInt(strings.Trim(value, `"`), 10, 64)
+			if err != nil {
+				continue
+			}
+			if age := time.Duration(secs) * time.Second; maxAge < 0 || strings.EqualFold(name, "s-maxage") {
+				maxAge = age
+			}
+		}
+	}
+	if maxAge >= 0 && maxAge < ttl {
+		return maxAge
+	}
+	return ttl
+}
+
+// storeProxied 把从上游获取的值写入 key 的归属节点
+func (h *CacheHandler) storeProxied(ctx context.Context, group, key string, value []byte, ttl time.Duration) error {
+	node, getter := h.pickNode(key)
+	if getter == nil {
+		return errNoNode
+	}
+	setter, err := h.setterFor(node, getter)
+	if err != nil {
+		return err
+	}
+	return setter.Set(ctx, &pb.SetRequest{Group: group, Key: key, Value: value, TtlMs: proto.Int64(max(ttl.Milliseconds(), 1))})
+}
+
+// writeProxyValue 返回缓存命中的值，按组的 HTTP 缓存策略设置响应头
+func (h *CacheHandler) writeProxyValue(w http.ResponseWriter, r *http.Request, group string, up ProxyUpstream, res *pb.Response) {
+	contentType := up.ContentType
+	if contentType == "" {
+		contentType = http.DetectContentType(res.Value)
+	}
+	w.Header().Set("Content-Type", contentType)
+	setCacheHeaders(w.Header(), h.policies.lookup(group), res, r.Header.Get("Authorization") != "", time.Now())
+	peers.ServeValue(w, r, res.Value)
+}
+
+// writeProxyResponse 返回上游的响应。写入了缓存的 200 响应标记为 MISS，并按组的 HTTP 缓存策略设置响应头；
+// 未写入缓存的 200 响应标记为 BYPASS
+func (h *CacheHandler) writeProxyResponse(w http.ResponseWriter, r *http.Request, group string, resp *proxyResponse) {
+	if resp.contentType != "" {
+		w.Header().Set("Content-Type", resp.contentType)
+	}
+	if resp.status == http.StatusOK {
+		if resp.ttl > 0 {
+			w.Header().Set(HeaderProxyStatus, "MISS")
+			now := time.Now()
+			res := &pb.Response{ExpiresAt: proto.Int64(now.Add(resp.ttl).UnixNano())}
+			setCacheHeaders(w.Header(), h.policies.lookup(group), res, r.Header.Get("Authorization") != "", now)
+		} else {
+			w.Header().Set(HeaderProxyStatus, "BYPASS")
+		}
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(resp.body)))
+	w.WriteHeader(resp.status)
+	w.Write(resp.body)
+}
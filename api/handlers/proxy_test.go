@@ -0,0 +1,269 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
+)
+
+// storingGetter 在 valueGetter 的基础上接受写入，写入的值所有节点共享
+type storingGetter struct {
+	valueGetter
+	factory *storingFactory
+}
+
+func (g *storingGetter) Set(ctx context.Context, req *pb.SetRequest) error {
+	g.factory.sets.Add(1)
+	g.factory.lastTTL.Store(req.GetTtlMs())
+	g.values.set(req.Key, string(req.Value))
+	return nil
+}
+
+type storingFactory struct {
+	values  *sharedValues
+	nodes   atomic.Uint64
+	sets    atomic.Int64
+	lastTTL atomic.Int64 // 最近一次写入的有效期（毫秒）
+}
+
+func (f *storingFactory) NewGetter(protocol ProtocolType, addr string) NodeGetter {
+	return &storingGetter{
+		valueGetter: valueGetter{stubGetter: stubGetter{protocol: protocol, addr: addr}, values: f.values, node: f.nodes.Add(1)},
+		factory:     f,
+	}
+}
+
+// stored 返回节点上 key 的值
+func (f *storingFactory) stored(key string) (string, bool) {
+	f.values.mu.Lock()
+	defer f.values.mu.Unlock()
+	v, ok := f.values.values[key]
+	return v, ok
+}
+
+// testUpstream 记录收到的请求：/missing 返回 404，/big 返回超过大小上限的响应体，
+// /nostore 带 Cache-Control: no-store，/short 带 max-age=60，其他路径返回 "origin:" 加路径和查询参数
+type testUpstream struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []string
+}
+
+const testProxyMaxBody = 64
+
+func newTestUpstream(t *testing.T) *testUpstream {
+	up := &testUpstream{}
+	up.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		up.mu.Lock()
+		up.requests = append(up.requests, r.URL.RequestURI()+" "+r.Header.Get("Accept-Language")+" "+r.Header.Get("Authorization"))
+		up.mu.Unlock()
+		switch r.URL.Path {
+		case "/static/missing":
+			http.NotFound(w, r)
+		case "/static/big":
+			w.Write([]byte(strings.Repeat("b", testProxyMaxBody+1)))
+		case "/static/nostore":
+			w.Header().Set("Cache-Control", "no-store")
+			w.Write([]byte("private"))
+		case "/static/short":
+			w.Header().Set("Cache-Control", "public, max-age=60")
+			w.Write([]byte("short"))
+		case "/static/broken":
+			http.Error(w, "boom", http.StatusInternalServerError)
+		default:
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte("origin:" + r.URL.RequestURI()[len("/static"):]))
+		}
+	}))
+	t.Cleanup(up.Close)
+	return up
+}
+
+// count 返回上游收到的请求数
+func (up *testUpstream) count() int {
+	up.mu.Lock()
+	defer up.mu.Unlock()
+	return len(up.requests)
+}
+
+// last 返回上游收到的最后一个请求的 URI 和转发的请求头
+func (up *testUpstream) last() string {
+	up.mu.Lock()
+	defer up.mu.Unlock()
+	if len(up.requests) == 0 {
+		return ""
+	}
+	return up.requests[len(up.requests)-1]
+}
+
+func newProxyHandler(t *testing.T) (*CacheHandler, *storingFactory, *testUpstream) {
+	up := newTestUpstream(t)
+	factory := &storingFactory{values: &sharedValues{values: make(map[string]string)}}
+	h := NewCacheHandler("/_gocache/", 50, CacheHandlerOptions{
+		Getters: factory,
+		Proxy: ProxyUpstreams{"assets": {
+			URL:            up.URL + "/static/",
+			ForwardHeaders: []string{"Accept-Language"},
+			MaxBodyBytes:   testProxyMaxBody,
+			TTL:            10 * time.Minute,
+		}},
+	})
+	h.UpdatePeers(nodesWithGroups(3, "assets", "users"))
+	return h, factory, up
+}
+
+// serveProxy 以 method 请求 target，带上 headers
+func serveProxy(h *CacheHandler, method, target string, headers map[string]string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, nil)
+	for name, v := range headers {
+		r.Header.Set(name, v)
+	}
+	w := httptest.NewRecorder()
+	h.ProxyHandler(w, r)
+	return w
+}
+
+// TestProxy 按顺序覆盖未命中写入、命中、上游 404 的缓存和超过大小上限的转发
+func TestProxy(t *testing.T) {
+	h, factory, up := newProxyHandler(t)
+	headers := map[string]string{"Accept-Language": "zh", "Authorization": "Bearer secret"}
+	steps := []struct {
+		name      string
+		target    string
+		status    int
+		proxy     string // X-GoCache-Proxy
+		body      string
+		upstreams int // 至此上游收到的请求数
+	}{
+		{"未命中时请求上游并写入", "/api/proxy/assets/css/site.css", http.StatusOK, "MISS", "origin:/css/site.css", 1},
+		{"命中", "/api/proxy/assets/css/site.css", http.StatusOK, "HIT", "origin:/css/site.css", 1},
+		{"查询参数是 key 的一部分", "/api/proxy/assets/css/site.css?v=2", http.StatusOK, "MISS", "origin:/css/site.css?v=2", 2},
+		{"上游 404", "/api/proxy/assets/missing", http.StatusNotFound, "", "404 page not found\n", 3},
+		{"上游 404 的缓存", "/api/proxy/assets/missing", http.StatusNotFound, "NEGATIVE", "Not Found\n", 3},
+		{"超过大小上限", "/api/proxy/assets/big", http.StatusOK, "BYPASS", strings.Repeat("b", testProxyMaxBody+1), 4},
+		{"超过大小上限时每次都请求上游", "/api/proxy/assets/big", http.StatusOK, "BYPASS", strings.Repeat("b", testProxyMaxBody+1), 5},
+		{"no-store", "/api/proxy/assets/nostore", http.StatusOK, "BYPASS", "private", 6},
+		{"no-store 不写入缓存", "/api/proxy/assets/nostore", http.StatusOK, "BYPASS", "private", 7},
+		{"上游错误原样转发", "/api/proxy/assets/broken", http.StatusInternalServerError, "", "boom\n", 8},
+	}
+	for _, step := range steps {
+		w := serveProxy(h, http.MethodGet, step.target, headers)
+		if w.Code != step.status || w.Body.String() != step.body {
+			t.Fatalf("%s: 响应 = %d %q, want %d %q", step.name, w.Code, w.Body.String(), step.status, step.body)
+		}
+		if got := w.Header().Get(HeaderProxyStatus); got != step.proxy {
+			t.Fatalf("%s: %s = %q, want %q", step.name, HeaderProxyStatus, got, step.proxy)
+		}
+		if got := up.count(); got != step.upstreams {
+			t.Fatalf("%s: 上游收到 %d 个请求, want %d", step.name, got, step.upstreams)
+		}
+	}
+
+	// 只转发配置的请求头
+	if got := up.last(); got != "/static/broken zh " {
+		t.Fatalf("上游收到的请求 = %q", got)
+	}
+	for key, want := range map[string]string{"css/site.css": "origin:/css/site.css", "css/site.css?v=2": "origin:/css/site.css?v=2"} {
+		if v, ok := factory.stored(key); !ok || v != want {
+			t.Fatalf("节点上 %s = %q, %v", key, v, ok)
+		}
+	}
+	for _, key := range []string{"missing", "big", "nostore", "broken"} {
+		if _, ok := factory.stored(key); ok {
+			t.Fatalf("%s 不应写入节点", key)
+		}
+	}
+	if got := factory.lastTTL.Load(); factory.sets.Load() != 2 || got != (10*time.Minute).Milliseconds() {
+		t.Fatalf("写入 %d 次，有效期 = %dms", factory.sets.Load(), got)
+	}
+
+	want := ProxyStats{Hits: 1, Fills: 2, Bypassed: 4, NegativeHits: 1, UpstreamFetches: 8, UpstreamNotFound: 1, UpstreamErrors: 1}
+	if got := h.ProxyStats(); got == nil || *got != want {
+		t.Fatalf("统计 = %+v, want %+v", got, want)
+	}
+}
+
+// TestProxyMaxAge 上游响应的 max-age 短于配置的 TTL 时按 max-age 写入
+func TestProxyMaxAge(t *testing.T) {
+	h, factory, _ := newProxyHandler(t)
+	if w := serveProxy(h, http.MethodGet, "/api/proxy/assets/short", nil); w.Code != http.StatusOK || w.Header().Get(HeaderProxyStatus) != "MISS" {
+		t.Fatalf("响应 = %d %s", w.Code, w.Header().Get(HeaderProxyStatus))
+	}
+	if got := factory.lastTTL.Load(); got != time.Minute.Milliseconds() {
+		t.Fatalf("写入的有效期 = %dms, want 60000", got)
+	}
+}
+
+func TestProxyRejects(t *testing.T) {
+	h, _, up := newProxyHandler(t)
+	tests := []struct {
+		name   string
+		method string
+		target string
+		status int
+	}{
+		{"POST", http.MethodPost, "/api/proxy/assets/a", http.StatusMethodNotAllowed},
+		{"DELETE", http.MethodDelete, "/api/proxy/assets/a", http.StatusMethodNotAllowed},
+		{"没有配置上游的组", http.MethodGet, "/api/proxy/users/a", http.StatusNotFound},
+		{"缺少路径", http.MethodGet, "/api/proxy/assets", http.StatusBadRequest},
+		{"..", http.MethodGet, "/api/proxy/assets/../etc/passwd", http.StatusBadRequest},
+		{"%2e%2e", http.MethodGet, "/api/proxy/assets/css/%2e%2e/%2E%2E/etc/passwd", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := serveProxy(h, tt.method, tt.target, nil); w.Code != tt.status {
+				t.Fatalf("状态码 = %d, want %d", w.Code, tt.status)
+			}
+		})
+	}
+	if up.count() != 0 {
+		t.Fatalf("被拒绝的请求到达了上游: %d 个", up.count())
+	}
+}
+
+func TestParseProxyPath(t *testing.T) {
+	tests := []struct {
+		target   string
+		ok       bool
+		group    string
+		key      string
+		upstream string
+	}{
+		{"/api/proxy/assets/a/b.css", true, "assets", "a/b.css", "/a/b.css"},
+		{"/api/proxy/assets/a//b/./c/", true, "assets", "a/b/c/", "/a/b/c/"},
+		{"/api/proxy/assets/a%20b?x=1&y=2", true, "assets", "a b?x=1&y=2", "/a%20b?x=1&y=2"},
+		{"/api/proxy/assets/a..b/.c", true, "assets", "a..b/.c", "/a..b/.c"},
+		{"/api/proxy/my%20group/a", true, "my group", "a", "/a"},
+		{"/api/proxy/assets/..", false, "", "", ""},
+		{"/api/proxy/assets/a/../b", false, "", "", ""},
+		{"/api/proxy/assets/a/%2e%2e/b", false, "", "", ""},
+		{"/api/proxy/assets/a/%2E%2e", false, "", "", ""},
+		{"/api/proxy/assets/a%2F..%2Fb", false, "", "", ""},
+		{"/api/proxy/assets/..%5Cwin.ini", false, "", "", ""},
+		{"/api/proxy/assets/./", false, "", "", ""},
+		{"/api/proxy/assets//", false, "", "", ""},
+		{"/api/proxy/assets/", false, "", "", ""},
+		{"/api/proxy/assets", false, "", "", ""},
+		{"/api/proxy//a", false, "", "", ""},
+		{"/api/cache/assets/a", false, "", "", ""},
+	}
+	for _, tt := range tests {
+		u, err := url.Parse(tt.target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		group, key, upstream, ok := parseProxyPath(u)
+		if ok != tt.ok || group != tt.group || key != tt.key || upstream != tt.upstream {
+			t.Errorf("parseProxyPath(%s) = %q, %q, %q, %v, want %q, %q, %q, %v",
+				tt.target, group, key, upstream, ok, tt.group, tt.key, tt.upstream, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/pkg/logger"
+)
+
+// purgeTimeout 一次批量清除（一个删除请求涉及的所有 key）的总超时
+const purgeTimeout = 10 * time.Second
+
+// Purger 在 key 被删除后通知 CDN 清除缓存的响应。清除是尽力而为的：
+// 在后台进行，失败只记录日志，不影响删除请求的结果
+type Purger interface {
+	// Purge 清除组 group 中 key 的缓存响应
+	Purge(ctx context.Context, group, key string) error
+}
+
+// NopPurger 不做任何事的 Purger，未配置 CDN 时使用
+type NopPurger struct{}
+
+// Purge 实现 Purger
+func (NopPurger) Purge(ctx context.Context, group, key string) error {
+	return nil
+}
+
+// HTTPPurger 向 CDN 发送 HTTP 清除请求的 Purger，适用于接受 PURGE 方法或清除 API 的 CDN 和 Varnish 等反向代理
+type HTTPPurger struct {
+	URL    string       // 清除地址模板，{group} 和 {key} 替换为 url.PathEscape 编码后的组名和 key，例如 https://cdn.example.com/api/cache/{group}/{key}
+	Method string       // 请求方法，默认 PURGE
+	Header http.Header  // 附加的请求头，例如 CDN 清除 API 的令牌
+	Client *http.Client // 发送请求的客户端，默认 http.DefaultClient
+}
+
+// NewHTTPPurger 创建使用地址模板 urlTemplate 和 PURGE 方法的 HTTPPurger
+func NewHTTPPurger(urlTemplate string) *HTTPPurger {
+	return &HTTPPurger{URL: urlTemplate}
+}
+
+// Purge 实现 Purger，2xx 以及表示没有缓存的 404 视为成功
+func (p *HTTPPurger) Purge(ctx context.Context, group, key string) error {
+	method := p.Method
+	if method == "" {
+		method = "PURGE"
+	}
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	u := strings.NewReplacer("{group}", url.PathEscape(group), "{key}", url.PathEscape(key)).Replace(p.URL)
+	req, err := http.NewRequestWithContext(ctx, method, u, nil)
+	if err != nil {
+		return err
+	}
+	for name, values := range p.Header {
+		req.Header[name] = values
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode/100 != 2 && resp.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("清除 %s 返回 %s", u, resp.Status)
+	}
+	return nil
+}
+
+// purge 在后台依次清除 keys 的 CDN 缓存，不等待结果
+func (h *CacheHandler) purge(group string, keys ...string) {
+	if _, nop := h.purger.(NopPurger); nop || len(keys) == 0 {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), purgeTimeout)
+		defer cancel()
+		for _, key := range keys {
+			if err := h.purger.Purge(ctx, group, key); err != nil {
+				logger.Warnf("清除 CDN 缓存失败: group=%s key=%s: %v", group, logger.Key(key), err)
+			}
+		}
+	}()
+}
@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/internal/cache"
+	"github.com/AdrianWangs/go-cache/pkg/logger"
+	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	defaultReadRepairTTL = 30 * time.Second // 写到副本节点的值默认的最长有效期，与热点 key 副本的默认有效期相同
+	maxPendingRepairs    = 1024             // 同时进行的读修复上限，超出时跳过新的修复
+	readRepairTimeout    = 5 * time.Second  // 一次读修复（比较读取加写入）的总超时
+)
+
+// ReadRepairConfig 读修复配置。
+// 非归属节点（对冲、繁忙改读或热点 key 的副本节点）用自己缓存中的值提供了读取结果时，在后台向归属节点
+// 再读一次：值不同时把归属节点的值写到该节点，归属节点没有该 key 时删除该节点的副本；副本节点返回
+// key 不存在而归属节点有值时，直接把归属节点的值写到副本节点
+type ReadRepairConfig struct {
+	Rate   float64       // 每秒最多发起的读修复数，0 表示关闭读修复
+	Burst  int           // 突发的读修复数，默认为 Rate 向上取整
+	MaxTTL time.Duration // 写到副本节点的值的最长有效期，默认 30s，且不超过归属节点上的剩余有效期
+}
+
+// ReadRepairStats 读修复统计，由 Diverged、Missing 与 Checks 之比可以看出副本的不一致程度
+type ReadRepairStats struct {
+	Checks   int64 `json:"checks"`   // 向归属节点发起的比较读取数
+	Diverged int64 `json:"diverged"` // 副本节点的值与归属节点不同的次数
+	Missing  int64 `json:"missing"`  // 副本节点没有 key（返回不存在或从归属节点转取）而归属节点有值的次数
+	Orphaned int64 `json:"orphaned"` // 副本节点有值而归属节点没有该 key 的次数
+	Repaired int64 `json:"repaired"` // 成功写入或删除副本的次数
+	Failed   int64 `json:"failed"`   // 比较读取或修复失败的次数，包括节点不支持写入
+	Dropped  int64 `json:"dropped"`  // 因限速、同一 key 的修复正在进行或修复过多而跳过的次数
+	Pending  int   `json:"pending"`  // 正在进行的修复数
+}
+
+// readRepairer 执行读修复，按 key 去重并限速
+type readRepairer struct {
+	maxTTL  time.Duration
+	limiter *cache.RateLimiter
+
+	mu      sync.Mutex
+	pending map[string]struct{} // 正在修复的 key，以 group + "\x00" + key 为键
+
+	checks, diverged, missing, orphaned, repaired, failed, dropped int64
+}
+
+// newReadRepairer 根据配置创建 readRepairer，未开启读修复时返回 nil
+func newReadRepairer(cfg ReadRepairConfig) *readRepairer {
+	if cfg.Rate <= 0 {
+		return nil
+	}
+	if cfg.MaxTTL <= 0 {
+		cfg.MaxTTL = defaultReadRepairTTL
+	}
+	return &readRepairer{
+		maxTTL:  cfg.MaxTTL,
+		limiter: cache.NewRateLimiter(cache.RateLimit{Rate: cfg.Rate, Burst: cfg.Burst}, nil),
+		pending: make(map[string]struct{}),
+	}
+}
+
+// stats 返回当前的读修复统计
+func (r *readRepairer) stats() ReadRepairStats {
+	r.mu.Lock()
+	pending := len(r.pending)
+	r.mu.Unlock()
+	return ReadRepairStats{
+		Checks:   atomic.LoadInt64(&r.checks),
+		Diverged: atomic.LoadInt64(&r.diverged),
+		Missing:  atomic.LoadInt64(&r.missing),
+		Orphaned: atomic.LoadInt64(&r.orphaned),
+		Repaired: atomic.LoadInt64(&r.repaired),
+		Failed:   atomic.LoadInt64(&r.failed),
+		Dropped:  atomic.LoadInt64(&r.dropped),
+		Pending:  pending,
+	}
+}
+
+// start 为 key 占用一次修复，同一 key 的修复正在进行、修复过多或超出限速时返回 false
+func (r *readRepairer) start(group, key string) bool {
+	rk := hotRouteKey(group, key)
+	r.mu.Lock()
+	_, busy := r.pending[rk]
+	ok := !busy && len(r.pending) < maxPendingRepairs && r.limiter.Allow()
+	if ok {
+		r.pending[rk] = struct{}{}
+	}
+	r.mu.Unlock()
+	if !ok {
+		atomic.AddInt64(&r.dropped, 1)
+	}
+	return ok
+}
+
+// finish 释放 key 的修复
+func (r *readRepairer) finish(group, key string) {
+	r.mu.Lock()
+	delete(r.pending, hotRouteKey(group, key))
+	r.mu.Unlock()
+}
+
+// ttl 返回写到副本节点的值的有效期：不超过 maxTTL、归属节点上的剩余有效期和热点 key 的复制期限。
+// 已经过期时返回 0
+func (r *readRepairer) ttl(owner *pb.Response) time.Duration {
+	ttl := r.maxTTL
+	now := time.Now()
+	if owner.ExpiresAt != nil {
+		ttl = min(ttl, time.Unix(0, owner.GetExpiresAt()).Sub(now))
+	}
+	if owner.ReplicatedUntil != nil {
+		ttl = min(ttl, time.Unix(0, owner.GetReplicatedUntil()).Sub(now))
+	}
+	return max(ttl, 0)
+}
+
+// ReadRepairStats 返回读修复统计，未开启读修复时返回 nil
+func (h *CacheHandler) ReadRepairStats() *ReadRepairStats {
+	if h.repair == nil {
+		return nil
+	}
+	stats := h.repair.stats()
+	return &stats
+}
+
+// checkReplica 在后台比较 node 提供的结果与归属节点 owner 上的值，不一致时修复 node。
+// node 从归属节点转取的结果 (source=peer) 不会与归属节点不同，不做比较
+func (h *CacheHandler) checkReplica(group, key, owner string, ownerGetter NodeGetter, node string, getter NodeGetter, served *pb.Response) {
+	if h.repair == nil || served.GetSource() == string(cache.SourcePeer) {
+		return
+	}
+	if !h.repair.start(group, key) {
+		return
+	}
+	value := served.GetValue()
+	go func() {
+		defer h.repair.finish(group, key)
+		ctx, cancel := context.WithTimeout(context.Background(), readRepairTimeout)
+		defer cancel()
+
+		atomic.AddInt64(&h.repair.checks, 1)
+		res := &pb.Response{}
+		err := ownerGetter.GetByProto(ctx, &pb.Request{Group: group, Key: key}, res)
+		switch {
+		case err == nil:
+			if !bytes.Equal(res.GetValue(), value) {
+				// 各节点的版本号是节点本地的写入序号，不能跨节点比较，只能比较值
+				atomic.AddInt64(&h.repair.diverged, 1)
+				logger.Infof("读修复: 节点 %s 的值与归属节点 %s 不同，写入归属节点的值: group=%s, key=%s", node, owner, group, logger.Key(key))
+				h.recordRepair(node, group, key, h.pushReplica(ctx, node, getter, group, key, res))
+			}
+		case isKeyNotFound(err):
+			atomic.AddInt64(&h.repair.orphaned, 1)
+			logger.Infof("读修复: 节点 %s 有归属节点 %s 上已不存在的 key，删除副本: group=%s, key=%s", node, owner, group, logger.Key(key))
+			h.recordRepair(node, group, key, getter.Delete(ctx, group, key))
+		default:
+			atomic.AddInt64(&h.repair.failed, 1)
+			logger.Debugf("读修复: 从归属节点 %s 读取失败: group=%s, key=%s: %v", owner, group, logger.Key(key), err)
+		}
+	}()
+}
+
+// repairMissing 在后台把归属节点的值写到没有该 key 的副本节点 node：node 返回 key 不存在，
+// 或者没有自己的副本而从归属节点转取了结果，owner 为归属节点的响应
+func (h *CacheHandler) repairMissing(group, key, node string, getter NodeGetter, owner *pb.Response) {
+	if h.repair == nil {
+		return
+	}
+	atomic.AddInt64(&h.repair.missing, 1)
+	if !h.repair.start(group, key) {
+		return
+	}
+	owner = proto.Clone(owner).(*pb.Response)
+	go func() {
+		defer h.repair.finish(group, key)
+		ctx, cancel := context.WithTimeout(context.Background(), readRepairTimeout)
+		defer cancel()
+
+		logger.Infof("读修复: 节点 %s 上没有归属节点的 key，写入归属节点的值: group=%s, key=%s", node, group, logger.Key(key))
+		h.recordRepair(node, group, key, h.pushReplica(ctx, node, getter, group, key, owner))
+	}()
+}
+
+// recordRepair 记录一次修复的结果
+func (h *CacheHandler) recordRepair(node, group, key string, err error) {
+	if err != nil {
+		atomic.AddInt64(&h.repair.failed, 1)
+		logger.Warnf("读修复: 修复节点 %s 失败: group=%s, key=%s: %v", node, group, logger.Key(key), err)
+		return
+	}
+	atomic.AddInt64(&h.repair.repaired, 1)
+}
+
+// pushReplica 把归属节点的值写到节点 node
+func (h *CacheHandler) pushReplica(ctx context.Context, node string, getter NodeGetter, group, key string, owner *pb.Response) error {
+	ttl := h.repair.ttl(owner)
+	if ttl <= 0 {
+		// 归属节点上的值已经过期，副本随后会从归属节点重新获取
+		return nil
+	}
+	setter, err := h.setterFor(node, getter)
+	if err != nil {
+		return err
+	}
+	req := &pb.SetRequest{Group: group, Key: key, Value: owner.GetValue(), TtlMs: proto.Int64(max(ttl.Milliseconds(), 1))}
+	return setter.Set(ctx, req)
+}
+
+// setterFor 返回向节点 node 写入的 nodeSetter。getter 不支持写入（gRPC 没有写入调用）时
+// 改用节点登记的 HTTP 地址
+func (h *CacheHandler) setterFor(node string, getter NodeGetter) (nodeSetter, error) {
+	if setter, ok := getter.(nodeSetter); ok {
+		return setter, nil
+	}
+	info, found := h.nodeInfo(node)
+	if !found || info.HTTPAddr == "" {
+		return nil, errors.New("node does not accept writes and has no HTTP address registered")
+	}
+	return NewHTTPGetter(h.getterAddr(ProtocolHTTP, info), h.getterOpts...), nil
+}
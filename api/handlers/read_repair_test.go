@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
+	"google.golang.org/protobuf/proto"
+)
+
+// TestReadRepairerStart 同一 key 同时只有一个修复，超出限速的修复被跳过并计数
+func TestReadRepairerStart(t *testing.T) {
+	if r := newReadRepairer(ReadRepairConfig{}); r != nil {
+		t.Fatal("Rate 为 0 时开启了读修复")
+	}
+	r := newReadRepairer(ReadRepairConfig{Rate: 0.001, Burst: 2})
+	if !r.start("g", "a") {
+		t.Fatal("第一个修复被跳过")
+	}
+	if r.start("g", "a") {
+		t.Fatal("同一 key 的修复正在进行时又开始了一个")
+	}
+	if !r.start("other", "a") {
+		t.Fatal("其他组的同名 key 被当作同一个修复")
+	}
+	r.finish("g", "a")
+	if r.start("g", "a") {
+		t.Fatal("超出限速的修复没有被跳过")
+	}
+	if s := r.stats(); s.Dropped != 2 || s.Pending != 1 {
+		t.Fatalf("统计 = %+v", s)
+	}
+}
+
+func TestReadRepairTTL(t *testing.T) {
+	r := newReadRepairer(ReadRepairConfig{Rate: 1, MaxTTL: time.Minute})
+	now := time.Now()
+	tests := []struct {
+		name  string
+		owner *pb.Response
+		want  time.Duration // 允许 1s 误差
+	}{
+		{"没有有效期", &pb.Response{}, time.Minute},
+		{"归属节点上的剩余有效期更短", &pb.Response{ExpiresAt: proto.Int64(now.Add(10 * time.Second).UnixNano())}, 10 * time.Second},
+		{"副本先到期", &pb.Response{
+			ExpiresAt:       proto.Int64(now.Add(time.Hour).UnixNano()),
+			ReplicatedUntil: proto.Int64(now.Add(20 * time.Second).UnixNano()),
+		}, 20 * time.Second},
+		{"已过期", &pb.Response{ExpiresAt: proto.Int64(now.Add(-time.Second).UnixNano())}, 0},
+	}
+	for _, tt := range tests {
+		if got := r.ttl(tt.owner); got > tt.want || got < tt.want-time.Second {
+			t.Errorf("%s: ttl = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/AdrianWangs/go-cache/internal/discovery"
+	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
+	"google.golang.org/protobuf/proto"
+)
+
+// TestRingGeneration 只有节点成员变化时哈希环的代数才加 1，成员不变的更新沿用旧环
+func TestRingGeneration(t *testing.T) {
+	h := NewCacheHandler("/_gocache/", 50, CacheHandlerOptions{Getters: &recordingFactory{}})
+	if g := h.RingGeneration(); g != 0 {
+		t.Fatalf("启动时的代数 = %d", g)
+	}
+
+	three := nodesWithGroups(3, "scores")
+	reversed := slices.Clone(three)
+	slices.Reverse(reversed)
+	steps := []struct {
+		name  string
+		nodes []discovery.NodeInfo
+		want  uint64
+	}{
+		{"首次更新", three, 1},
+		{"成员相同", nodesWithGroups(3, "scores"), 1},
+		{"顺序不同", reversed, 1},
+		{"只有组变化", nodesWithGroups(3, "scores", "users"), 1},
+		{"重复的节点", append(nodesWithGroups(3, "scores"), three[0]), 1},
+		{"节点加入", nodesWithGroups(4, "scores"), 2},
+		{"节点离开", nodesWithGroups(2, "scores"), 3},
+		{"没有节点", nil, 4},
+		{"没有节点时再次更新", nil, 4},
+	}
+	for _, step := range steps {
+		h.UpdatePeers(step.nodes)
+		if g := h.RingGeneration(); g != step.want {
+			t.Fatalf("%s: 代数 = %d, want %d", step.name, g, step.want)
+		}
+	}
+}
+
+// generationGetter 的 Stats 返回节点哈希环的代数
+type generationGetter struct {
+	stubGetter
+	generation uint64
+}
+
+func (g *generationGetter) Stats(ctx context.Context) (*pb.StatsResponse, error) {
+	return &pb.StatsResponse{RingGeneration: proto.Uint64(g.generation)}, nil
+}
+
+// generationFactory 创建的 getter 按创建顺序报告代数 1、2、3……
+type generationFactory struct {
+	created uint64
+}
+
+func (f *generationFactory) NewGetter(protocol ProtocolType, addr string) NodeGetter {
+	f.created++
+	return &generationGetter{stubGetter: stubGetter{protocol: protocol, addr: addr}, generation: f.created}
+}
+
+// TestGroupsRingGeneration 组列表返回 API 服务器的代数和各节点各自报告的代数
+func TestGroupsRingGeneration(t *testing.T) {
+	h := NewCacheHandler("/_gocache/", 50, CacheHandlerOptions{Getters: &generationFactory{}})
+	h.UpdatePeers(nodesWithGroups(2, "scores"))
+	h.UpdatePeers(nodesWithGroups(3, "scores"))
+
+	w := httptest.NewRecorder()
+	h.GetGroupsHandler(w, httptest.NewRequest(http.MethodGet, "/api/groups", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("状态码 = %d", w.Code)
+	}
+	var resp GroupsResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.RingGeneration != 2 {
+		t.Fatalf("ringGeneration = %d, want 2", resp.RingGeneration)
+	}
+	generations := make(map[uint64]bool)
+	for _, node := range resp.Nodes {
+		generations[node.RingGeneration] = true
+	}
+	if len(resp.Nodes) != 3 || len(generations) != 3 || generations[0] {
+		t.Fatalf("各节点报告的代数 = %+v", resp.Nodes)
+	}
+}
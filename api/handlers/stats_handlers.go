@@ -0,0 +1,291 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+
+	"github.com/AdrianWangs/go-cache/pkg/fanout"
+	"github.com/AdrianWangs/go-cache/pkg/logger"
+	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
+)
+
+// statsPath 节点 HTTPPool 上统计路由相对于 basePath 的路径
+const statsPath = "_stats"
+
+// ErrStatsUnimplemented 表示节点版本过旧，不支持 Stats 调用
+var ErrStatsUnimplemented = errors.New("stats not implemented by node")
+
+// 节点统计状态
+const (
+	NodeStatsOK            = "ok"            // 成功获取统计
+	NodeStatsUnimplemented = "unimplemented" // 节点不支持 Stats
+	NodeStatsError         = "error"         // 获取失败
+)
+
+// GroupSummary 集群范围内单个缓存组的汇总统计
+type GroupSummary struct {
+	Name      string `json:"name"`      // 组名
+	Hits      int64  `json:"hits"`      // 命中次数
+	Misses    int64  `json:"misses"`    // 未命中次数
+	Gets      int64  `json:"gets"`      // 请求总数
+	Evictions int64  `json:"evictions"` // 容量淘汰次数
+	Bytes     int64  `json:"bytes"`     // 占用字节数
+	Entries   int64  `json:"entries"`   // 条目数
+	MaxBytes  int64  `json:"maxBytes"`  // 容量上限之和
+	Throttled int64  `json:"throttled"` // 因限流被拒绝的请求数
+	Nodes     int    `json:"nodes"`     // 报告该组的节点数
+
+	RegisteredNodes int `json:"registeredNodes"` // 在注册信息中登记该组的节点数
+
+	Cost        int64   `json:"cost"`        // 计入容量上限的总成本之和
+	FillPercent float64 `json:"fillPercent"` // 填充率：cost 与 maxBytes 之比（百分比）
+
+	EvictionsPerMinute       int64 `json:"evictionsPerMinute"`       // 各节点最近一分钟内容量淘汰数之和
+	EvictedAgeP50Ms          int64 `json:"evictedAgeP50Ms"`          // 有容量淘汰的节点中被淘汰条目存活时长中位数的最小值（毫秒），即淘汰压力最大的节点
+	EvictionPressureWarnings int64 `json:"evictionPressureWarnings"` // 各节点因淘汰过快输出的警告次数之和
+
+	CancelledGets int64 `json:"cancelledGets"` // 各节点等待加载期间调用方放弃的读取次数之和
+	AbortedLoads  int64 `json:"abortedLoads"`  // 各节点因调用方都已放弃而取消的加载次数之和
+
+	WatermarkEvictions int64 `json:"watermarkEvictions"` // 各节点超过高水位后台提前淘汰的条目数之和
+
+	LoadsExecuted   int64 `json:"loadsExecuted"`   // 各节点实际执行的加载次数之和
+	LoadsDeduped    int64 `json:"loadsDeduped"`    // 各节点加入进行中的加载而未重复执行的次数之和
+	CurrentInflight int64 `json:"currentInflight"` // 各节点正在执行的加载数之和
+}
+
+// NodeStatsStatus 单个节点的统计获取结果
+type NodeStatsStatus struct {
+	Node          string `json:"node"`                    // 节点地址
+	Status        string `json:"status"`                  // ok / unimplemented / error
+	Error         string `json:"error,omitempty"`         // 错误信息
+	UptimeSeconds int64  `json:"uptimeSeconds,omitempty"` // 节点运行时间
+	NodeThrottled int64  `json:"nodeThrottled,omitempty"` // 因节点级限流被拒绝的请求数
+	Mode          string `json:"mode,omitempty"`          // 节点级模式：readwrite / readonly / readonly-local
+	DurationMs    int64  `json:"durationMs"`              // Stats 调用耗时（毫秒）
+
+	ProtoVersion int `json:"protoVersion,omitempty"` // 节点声明的协议版本，旧版本节点为空
+
+	Warmup *NodeWarmup `json:"warmup,omitempty"` // 节点启动预热的进度，未开启预热时为空
+	Prime  *NodePrime  `json:"prime,omitempty"`  // 节点按清单预加载的进度，未配置清单时为空
+
+	RingGeneration uint64 `json:"ringGeneration,omitempty"` // 节点哈希环的代数，只在本节点内递增，与其他节点和 API 服务器的代数不可比较；旧版本节点为空
+}
+
+// NodeWarmup 节点启动预热的进度，见 server.WarmupStatus
+type NodeWarmup struct {
+	State     string `json:"state"`           // running / done / cancelled / failed
+	ElapsedMs int64  `json:"elapsedMs"`       // 已用时间（毫秒）
+	Selected  int64  `json:"selected"`        // 选中要拉取的 key 数
+	Pulled    int64  `json:"pulled"`          // 已拉取的 key 数
+	Bytes     int64  `json:"bytes"`           // 已拉取的字节数
+	Failed    int64  `json:"failed"`          // 拉取失败的 key 数
+	Error     string `json:"error,omitempty"` // 预热未完成的原因
+}
+
+// NodePrime 节点按清单预加载的进度，见 server.PrimeStatus
+type NodePrime struct {
+	State     string `json:"state"`           // running / done / cancelled
+	ElapsedMs int64  `json:"elapsedMs"`       // 已用时间（毫秒）
+	Total     int64  `json:"total"`           // 清单中的 key 数
+	Done      int64  `json:"done"`            // 已处理的 key 数
+	Loaded    int64  `json:"loaded"`          // 加载到缓存的 key 数
+	NotOwned  int64  `json:"notOwned"`        // 归属其他节点而跳过的 key 数
+	Missing   int64  `json:"missing"`         // 数据源中不存在的 key 数
+	Failed    int64  `json:"failed"`          // 加载失败的 key 数
+	Error     string `json:"error,omitempty"` // 预加载未完成的原因
+}
+
+// GroupsResponse /api/groups 响应
+type GroupsResponse struct {
+	Groups []GroupSummary    `json:"groups"` // 各组汇总
+	Nodes  []NodeStatsStatus `json:"nodes"`  // 各节点状态
+
+	RegistryComplete bool `json:"registryComplete"` // 所有节点都登记了组信息，groups 即集群的完整组列表
+
+	RingGeneration uint64 `json:"ringGeneration"` // API 服务器路由所用哈希环的代数，见 CacheHandler.RingGeneration
+}
+
+// nodeStatsResult 单个节点的 Stats 调用结果
+type nodeStatsResult = fanout.Result[*pb.StatsResponse]
+
+// collectStats 并发向所有节点请求统计信息
+func (h *CacheHandler) collectStats(ctx context.Context) []nodeStatsResult {
+	getters := h.GetNodeGetters()
+	nodes := make([]string, 0, len(getters))
+	for node := range getters {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+
+	results := fanout.FanOut(ctx, nodes, func(ctx context.Context, node string) (*pb.StatsResponse, error) {
+		return getters[node].Stats(ctx)
+	}, h.fanOut)
+	return fanout.Ordered(nodes, results)
+}
+
+// aggregateStats 将各节点的统计合并为按组汇总的结果，不支持或失败的节点只记录状态
+func aggregateStats(results []nodeStatsResult, groupFilter string) GroupsResponse {
+	summaries := make(map[string]*GroupSummary)
+	nodes := make([]NodeStatsStatus, 0, len(results))
+
+	for _, r := range results {
+		status := NodeStatsStatus{
+			Node:       r.Target,
+			Status:     NodeStatsOK,
+			DurationMs: r.Duration.Milliseconds(),
+		}
+		switch {
+		case errors.Is(r.Err, ErrStatsUnimplemented):
+			status.Status = NodeStatsUnimplemented
+		case r.Err != nil:
+			status.Status = NodeStatsError
+			status.Error = r.Err.Error()
+		default:
+			status.UptimeSeconds = r.Value.GetUptimeSeconds()
+			status.NodeThrottled = r.Value.GetNodeThrottled()
+			status.Mode = r.Value.GetMode()
+			status.RingGeneration = r.Value.GetRingGeneration()
+			if w := r.Value.GetWarmup(); w != nil {
+				status.Warmup = &NodeWarmup{
+					State:     w.GetState(),
+					ElapsedMs: w.GetElapsedMs(),
+					Selected:  w.GetSelected(),
+					Pulled:    w.GetPulled(),
+					Bytes:     w.GetBytes(),
+					Failed:    w.GetFailed(),
+					Error:     w.GetError(),
+				}
+			}
+			if pr := r.Value.GetPrime(); pr != nil {
+				status.Prime = &NodePrime{
+					State:     pr.GetState(),
+					ElapsedMs: pr.GetElapsedMs(),
+					Total:     pr.GetTotal(),
+					Done:      pr.GetDone(),
+					Loaded:    pr.GetLoaded(),
+					NotOwned:  pr.GetNotOwned(),
+					Missing:   pr.GetMissing(),
+					Failed:    pr.GetFailed(),
+					Error:     pr.GetError(),
+				}
+			}
+			for _, gs := range r.Value.GetGroups() {
+				if groupFilter != "" && gs.GetName() != groupFilter {
+					continue
+				}
+				sum, ok := summaries[gs.GetName()]
+				if !ok {
+					sum = &GroupSummary{Name: gs.GetName()}
+					summaries[gs.GetName()] = sum
+				}
+				sum.Hits += gs.GetHits()
+				sum.Misses += gs.GetMisses()
+				sum.Gets += gs.GetGets()
+				sum.Evictions += gs.GetEvictions()
+				sum.Bytes += gs.GetBytes()
+				sum.Entries += gs.GetEntries()
+				sum.MaxBytes += gs.GetMaxBytes()
+				if gs.Cost != nil {
+					sum.Cost += gs.GetCost()
+				} else {
+					// 旧版本节点不报告成本，按字节数计
+					sum.Cost += gs.GetBytes()
+				}
+				sum.Throttled += gs.GetThrottled()
+				if gs.GetEvictionsPerMinute() > 0 {
+					if p50 := gs.GetEvictedAgeP50Ms(); sum.EvictionsPerMinute == 0 || p50 < sum.EvictedAgeP50Ms {
+						sum.EvictedAgeP50Ms = p50
+					}
+					sum.EvictionsPerMinute += gs.GetEvictionsPerMinute()
+				}
+				sum.EvictionPressureWarnings += gs.GetEvictionPressureWarnings()
+				sum.CancelledGets += gs.GetCancelledGets()
+				sum.AbortedLoads += gs.GetAbortedLoads()
+				sum.WatermarkEvictions += gs.GetWatermarkEvictions()
+				sum.LoadsExecuted += gs.GetLoadsExecuted()
+				sum.LoadsDeduped += gs.GetLoadsDeduped()
+				sum.CurrentInflight += gs.GetCurrentInflight()
+				sum.Nodes++
+			}
+		}
+		nodes = append(nodes, status)
+	}
+
+	resp := GroupsResponse{
+		Groups: make([]GroupSummary, 0, len(summaries)),
+		Nodes:  nodes,
+	}
+	for _, sum := range summaries {
+		if sum.MaxBytes > 0 {
+			sum.FillPercent = float64(sum.Cost) / float64(sum.MaxBytes) * 100
+		}
+		resp.Groups = append(resp.Groups, *sum)
+	}
+	sort.Slice(resp.Groups, func(i, j int) bool { return resp.Groups[i].Name < resp.Groups[j].Name })
+	sort.Slice(resp.Nodes, func(i, j int) bool { return resp.Nodes[i].Node < resp.Nodes[j].Node })
+	return resp
+}
+
+// GetGroupsHandler 处理 /api/groups 请求，汇总所有节点上各缓存组的统计
+func (h *CacheHandler) GetGroupsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	groupFilter := r.URL.Query().Get("group")
+	if groupFilter != "" && h.isUnknownGroup(groupFilter) {
+		writeGroupNotFound(w, groupFilter)
+		return
+	}
+
+	results := h.collectStats(r.Context())
+	response := aggregateStats(results, groupFilter)
+	getters := h.GetNodeGetters()
+	for i := range response.Nodes {
+		if g, ok := getters[response.Nodes[i].Node].(versionedGetter); ok {
+			response.Nodes[i].ProtoVersion = g.protoVersion().Get()
+		}
+	}
+	registered, complete := h.RegisteredGroups()
+	mergeRegistry(&response, registered, complete, groupFilter)
+	response.RingGeneration = h.RingGeneration()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logger.Errorf("序列化组统计响应失败: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	logger.Debugf("返回组统计，共 %d 个组，%d 个节点", len(response.Groups), len(response.Nodes))
+}
+
+// mergeRegistry 将组注册表合并进统计结果：登记了但没有统计的组（例如节点暂时不可达）也会列出
+func mergeRegistry(resp *GroupsResponse, registered map[string]int, complete bool, groupFilter string) {
+	resp.RegistryComplete = complete
+
+	index := make(map[string]int, len(resp.Groups))
+	for i, g := range resp.Groups {
+		index[g.Name] = i
+	}
+	added := false
+	for name, n := range registered {
+		if groupFilter != "" && name != groupFilter {
+			continue
+		}
+		if i, ok := index[name]; ok {
+			resp.Groups[i].RegisteredNodes = n
+			continue
+		}
+		resp.Groups = append(resp.Groups, GroupSummary{Name: name, RegisteredNodes: n})
+		added = true
+	}
+	if added {
+		sort.Slice(resp.Groups, func(i, j int) bool { return resp.Groups[i].Name < resp.Groups[j].Name })
+	}
+}
@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/AdrianWangs/go-cache/internal/cache"
+	cachegrpc "github.com/AdrianWangs/go-cache/internal/cachenode/grpc"
+	"github.com/AdrianWangs/go-cache/internal/discovery"
+	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
+)
+
+// TestAggregateStatsSumsSize 各节点同名组的字节数、条目数、容量和成本相加，填充率按总和计算
+func TestAggregateStatsSumsSize(t *testing.T) {
+	results := []nodeStatsResult{
+		{Target: "node-1", Value: &pb.StatsResponse{Groups: []*pb.GroupStats{
+			{Name: "users", Bytes: proto.Int64(300), Entries: proto.Int64(3), MaxBytes: proto.Int64(1000), Cost: proto.Int64(400)},
+			{Name: "orders", Bytes: proto.Int64(50), Entries: proto.Int64(1), MaxBytes: proto.Int64(100), Cost: proto.Int64(50)},
+		}}},
+		// 旧版本节点不报告成本，按字节数计
+		{Target: "node-2", Value: &pb.StatsResponse{Groups: []*pb.GroupStats{
+			{Name: "users", Bytes: proto.Int64(100), Entries: proto.Int64(2), MaxBytes: proto.Int64(1000)},
+		}}},
+		{Target: "node-3", Err: errors.New("connection refused")},
+	}
+
+	resp := aggregateStats(results, "")
+	if len(resp.Groups) != 2 || len(resp.Nodes) != 3 {
+		t.Fatalf("groups = %+v, nodes = %+v", resp.Groups, resp.Nodes)
+	}
+	orders, users := resp.Groups[0], resp.Groups[1]
+	if users.Bytes != 400 || users.Entries != 5 || users.MaxBytes != 2000 || users.Cost != 500 || users.Nodes != 2 {
+		t.Fatalf("users = %+v", users)
+	}
+	if users.FillPercent != 25 {
+		t.Fatalf("users 填充率 = %v, want 25", users.FillPercent)
+	}
+	if orders.FillPercent != 50 || orders.Nodes != 1 {
+		t.Fatalf("orders = %+v", orders)
+	}
+
+	// 没有容量上限的组填充率为 0
+	resp = aggregateStats([]nodeStatsResult{{Target: "node-1", Value: &pb.StatsResponse{Groups: []*pb.GroupStats{
+		{Name: "unbounded", Bytes: proto.Int64(10), Cost: proto.Int64(10)},
+	}}}}, "unbounded")
+	if len(resp.Groups) != 1 || resp.Groups[0].FillPercent != 0 {
+		t.Fatalf("unbounded = %+v", resp.Groups)
+	}
+}
+
+// TestAggregateStatsSumsLoads 各节点同名组的加载次数、去重次数和进行中的加载数相加，旧版本节点不报告时按 0 计
+func TestAggregateStatsSumsLoads(t *testing.T) {
+	resp := aggregateStats([]nodeStatsResult{
+		{Target: "node-1", Value: &pb.StatsResponse{Groups: []*pb.GroupStats{
+			{Name: "users", LoadsExecuted: proto.Int64(10), LoadsDeduped: proto.Int64(990), CurrentInflight: proto.Int64(2)},
+		}}},
+		{Target: "node-2", Value: &pb.StatsResponse{Groups: []*pb.GroupStats{
+			{Name: "users", LoadsExecuted: proto.Int64(5), LoadsDeduped: proto.Int64(5)},
+		}}},
+		{Target: "node-3", Value: &pb.StatsResponse{Groups: []*pb.GroupStats{{Name: "users"}}}},
+	}, "")
+	users := resp.Groups[0]
+	if users.LoadsExecuted != 15 || users.LoadsDeduped != 995 || users.CurrentInflight != 2 || users.Nodes != 3 {
+		t.Fatalf("users = %+v", users)
+	}
+}
+
+// legacyNode 是 Stats 调用出现之前的 gRPC 节点，只实现了读取
+type legacyNode struct {
+	pb.UnimplementedGroupCacheServer
+}
+
+func (legacyNode) Get(ctx context.Context, req *pb.Request) (*pb.Response, error) {
+	return &pb.Response{Value: []byte("legacy")}, nil
+}
+
+// serveGRPC 在随机端口上以 gRPC 提供 srv，返回地址
+func serveGRPC(t *testing.T, srv pb.GroupCacheServer) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := grpc.NewServer()
+	pb.RegisterGroupCacheServer(s, srv)
+	go s.Serve(l)
+	t.Cleanup(s.Stop)
+	return l.Addr().String()
+}
+
+// TestGroupsOverGRPC 通过 gRPC 汇总新旧两个版本节点的统计：新节点的计数计入汇总，
+// 返回 Unimplemented 的旧节点和不可达的节点只记录状态，不影响汇总结果
+func TestGroupsOverGRPC(t *testing.T) {
+	name := fmt.Sprintf("stats-rpc-%d", time.Now().UnixNano())
+	g := cache.NewGroup(name, 1<<20, cache.GetterFunc(func(key string) ([]byte, error) {
+		return []byte("v:" + key), nil
+	}), time.Hour)
+	t.Cleanup(func() { g.Close() })
+	for _, key := range []string{"a", "b", "a"} {
+		if _, err := g.Get(key); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	downAddr := l.Addr().String()
+	l.Close()
+
+	addrs := map[string]string{
+		"new":  serveGRPC(t, cachegrpc.NewCacheServer("new")),
+		"old":  serveGRPC(t, legacyNode{}),
+		"down": downAddr,
+	}
+	var nodes []discovery.NodeInfo
+	for id, addr := range addrs {
+		nodes = append(nodes, discovery.NodeInfo{ID: id, GRPCAddr: addr, Groups: []string{name}, Protocols: []string{discovery.ProtocolGRPC}})
+	}
+	h := NewCacheHandler("/_gocache/", 50, CacheHandlerOptions{
+		GetterOptions: []GetterOption{WithRequestTimeout(5 * time.Second), WithDialTimeout(200 * time.Millisecond)},
+	})
+	h.UpdatePeers(nodes)
+
+	// 旧节点的 Unimplemented 映射为 ErrStatsUnimplemented
+	if _, err := NewGRPCGetter(addrs["old"]).Stats(context.Background()); !errors.Is(err, ErrStatsUnimplemented) {
+		t.Fatalf("旧节点 Stats = %v, want ErrStatsUnimplemented", err)
+	}
+
+	w := httptest.NewRecorder()
+	h.GetGroupsHandler(w, httptest.NewRequest(http.MethodGet, "/api/groups?group="+name, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("状态码 %d: %s", w.Code, w.Body.String())
+	}
+	var resp GroupsResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(resp.Groups) != 1 {
+		t.Fatalf("groups = %+v", resp.Groups)
+	}
+	sum := resp.Groups[0]
+	if sum.Name != name || sum.Nodes != 1 || sum.RegisteredNodes != 3 || sum.Hits != 1 || sum.Gets != 3 || sum.Misses != 2 ||
+		sum.Entries != 2 || sum.Bytes == 0 || sum.MaxBytes != 1<<20 {
+		t.Fatalf("汇总 = %+v", sum)
+	}
+
+	statuses := make(map[string]NodeStatsStatus)
+	for _, n := range resp.Nodes {
+		statuses[n.Node] = n
+	}
+	if len(statuses) != 3 {
+		t.Fatalf("nodes = %+v", resp.Nodes)
+	}
+	if s := statuses["new"]; s.Status != NodeStatsOK || s.Error != "" || s.Mode == "" {
+		t.Fatalf("新节点 = %+v", s)
+	}
+	if s := statuses["old"]; s.Status != NodeStatsUnimplemented || s.Error != "" {
+		t.Fatalf("旧节点 = %+v", s)
+	}
+	if s := statuses["down"]; s.Status != NodeStatsError || s.Error == "" {
+		t.Fatalf("不可达的节点 = %+v", s)
+	}
+}
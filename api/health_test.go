@@ -0,0 +1,66 @@
+package api
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/api/handlers"
+	"github.com/AdrianWangs/go-cache/internal/discovery"
+	"github.com/AdrianWangs/go-cache/internal/health"
+)
+
+// statusWatcher 只提供监视状态的 NodeWatcher
+type statusWatcher struct {
+	status discovery.WatchStatus
+}
+
+func (w *statusWatcher) Watch(ctx context.Context) (<-chan []discovery.NodeInfo, <-chan error) {
+	return nil, nil
+}
+
+func (w *statusWatcher) Status() discovery.WatchStatus { return w.status }
+
+func (w *statusWatcher) Close() error { return nil }
+
+func TestHealthChecker(t *testing.T) {
+	now := time.Now()
+	node := []discovery.NodeInfo{{GRPCAddr: "10.0.0.1:9090"}}
+	tests := []struct {
+		name      string
+		seeds     []discovery.NodeInfo
+		status    discovery.WatchStatus
+		nodes     []discovery.NodeInfo
+		discovery health.Status
+		overall   health.Status
+		ready     bool
+	}{
+		{"尚未同步", nil, discovery.WatchStatus{State: discovery.WatchResyncing, Since: now}, nil,
+			health.StatusDown, health.StatusDown, false},
+		{"种子节点", node, discovery.WatchStatus{State: discovery.WatchResyncing, Since: now}, node,
+			health.StatusDegraded, health.StatusDegraded, true},
+		{"正常", nil, discovery.WatchStatus{State: discovery.WatchConnected, Since: now, LastSync: now}, node,
+			health.StatusOK, health.StatusOK, true},
+		{"没有节点", nil, discovery.WatchStatus{State: discovery.WatchConnected, Since: now, LastSync: now}, nil,
+			health.StatusOK, health.StatusDown, true},
+		{"短暂中断", nil, discovery.WatchStatus{State: discovery.WatchDisconnected, Since: now.Add(-10 * time.Second), LastSync: now}, node,
+			health.StatusDegraded, health.StatusDegraded, true},
+		{"中断过久", nil, discovery.WatchStatus{State: discovery.WatchDisconnected, Since: now.Add(-time.Minute), LastSync: now}, node,
+			health.StatusDown, health.StatusDown, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodes := handlers.NewNodeHandler()
+			nodes.UpdateNodes(tt.nodes)
+			cfg := &ApiServerConfig{SeedNodes: tt.seeds, MaxDiscoveryLag: 30 * time.Second}
+			report := newHealthChecker(cfg, &statusWatcher{status: tt.status}, nodes).Report()
+
+			if got := report.Components["discovery"].Status; got != tt.discovery {
+				t.Errorf("discovery = %s (%s), want %s", got, report.Components["discovery"].Message, tt.discovery)
+			}
+			if report.Status != tt.overall || report.Ready != tt.ready {
+				t.Errorf("Status = %s, Ready = %v; want %s, %v", report.Status, report.Ready, tt.overall, tt.ready)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/AdrianWangs/go-cache/pkg/logger"
+)
+
+// lineRecorder 是记录写入的每一行的 logger.Logger
+type lineRecorder struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (r *lineRecorder) record(level, format string, args []interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lines = append(r.lines, level+" "+fmt.Sprintf(format, args...))
+}
+
+func (r *lineRecorder) Debugf(format string, args ...interface{}) { r.record("DEBUG", format, args) }
+func (r *lineRecorder) Infof(format string, args ...interface{})  { r.record("INFO", format, args) }
+func (r *lineRecorder) Warnf(format string, args ...interface{})  { r.record("WARN", format, args) }
+func (r *lineRecorder) Errorf(format string, args ...interface{}) { r.record("ERROR", format, args) }
+func (r *lineRecorder) WithFields(logger.Fields) logger.Logger    { return r }
+
+// recorded 返回包含 substr 的行
+func (r *lineRecorder) recorded(substr string) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var found []string
+	for _, line := range r.lines {
+		if strings.Contains(line, substr) {
+			found = append(found, line)
+		}
+	}
+	return found
+}
+
+// TestConfigLogger API服务器的启停日志写入 ApiServerConfig.Logger，不经过 logger 包的全局日志
+func TestConfigLogger(t *testing.T) {
+	global := &lineRecorder{}
+	logger.SetLogger(global)
+	defer logger.SetLogger(nil)
+
+	rec := &lineRecorder{}
+	s, err := NewApiServer(&ApiServerConfig{Watcher: &statusWatcher{}, Logger: rec})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"INFO 正在停止API服务器...", "INFO API服务器已停止"} {
+		if found := rec.recorded(want); len(found) != 1 {
+			t.Errorf("%q 记录了 %d 次, want 1 次; 记录 = %q", want, len(found), rec.recorded(""))
+		}
+	}
+	if found := global.recorded("API服务器"); len(found) != 0 {
+		t.Fatalf("全局日志收到了 %q", found)
+	}
+
+	// 没有配置 Logger 时使用 logger 包的全局日志
+	s, err = NewApiServer(&ApiServerConfig{Watcher: &statusWatcher{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Stop()
+	if found := global.recorded("INFO API服务器已停止"); len(found) != 1 {
+		t.Fatalf("全局日志记录 = %q", global.recorded(""))
+	}
+}
@@ -11,15 +11,16 @@ import (
 
 // RegisterRoutes 注册所有API路由
 func RegisterRoutes(r *router.Router, cacheHandler *handlers.CacheHandler,
-	nodeHandler *handlers.NodeHandler, metricsHandler *handlers.MetricsHandler) {
+	nodeHandler *handlers.NodeHandler, metricsHandler *handlers.MetricsHandler, adminHandler *handlers.AdminHandler) {
 
 	logger.Info("正在注册API路由...")
 
 	// 注册健康检查路由
 	r.RegisterFunc("/health", nodeHandler.HealthCheckHandler)
+	r.RegisterFunc("/ready", nodeHandler.ReadyHandler)
 
-	// 兼容性路由 - 旧的 /peers 接口
-	r.RegisterFunc("/peers", nodeHandler.GetNodesHandler)
+	// 兼容性路由 - 旧的 /peers 接口，与 /api/nodes 共用同一个处理器
+	r.RegisterFunc("/peers", nodeHandler.NodesHandler(handlers.NodesSchemaLegacy))
 
 	// 注册API路由组
 	apiGroup := r.Group("/api")
@@ -36,14 +37,42 @@ func RegisterRoutes(r *router.Router, cacheHandler *handlers.CacheHandler,
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
 	})
+	// 批量删除: POST /api/cache/batch-delete，精确匹配优先于上面的 /api/cache/ 前缀
+	cacheRoutes.RegisterFunc("/batch-delete", cacheHandler.BatchDeleteHandler)
+
+	// 缓存反向代理: GET /api/proxy/{group}/{path...}，只有配置了上游的组可用
+	proxyRoutes := apiGroup.Group("/proxy")
+	proxyRoutes.RegisterFunc("/", cacheHandler.ProxyHandler)
+
+	// 批量读取路由组: /api/batch/{group}
+	batchRoutes := apiGroup.Group("/batch")
+	batchRoutes.RegisterFunc("/", cacheHandler.BatchGetHandler)
 
 	// 节点路由组
 	nodeRoutes := apiGroup.Group("/nodes")
-	nodeRoutes.RegisterFunc("", nodeHandler.GetNodesHandler)
+	nodeRoutes.RegisterFunc("", nodeHandler.NodesHandler(handlers.NodesSchemaCurrent))
+
+	// 缓存组统计路由组
+	groupRoutes := apiGroup.Group("/groups")
+	groupRoutes.RegisterFunc("", cacheHandler.GetGroupsHandler)
 
 	// 监控指标路由组
 	metricsRoutes := apiGroup.Group("/metrics")
 	metricsRoutes.RegisterFunc("", metricsHandler.GetMetricsHandler)
 
+	// 管理路由组: /api/admin/groups/{group}/{export|import}、/api/admin/ring、/api/admin/info 与 /api/admin/hotkeys
+	adminRoutes := apiGroup.Group("/admin")
+	adminRoutes.RegisterFunc("/groups/", adminHandler.GroupHandler)
+	// 哈希环分布: /api/admin/ring
+	adminRoutes.RegisterFunc("/ring", adminHandler.RingHandler)
+	// 配置与构建信息: /api/admin/info
+	adminRoutes.RegisterFunc("/info", adminHandler.InfoHandler)
+	// 集群热点 key: /api/admin/hotkeys?group={group}&n=20
+	adminRoutes.RegisterFunc("/hotkeys", adminHandler.HotKeysHandler)
+
+	// 调试路由组: /api/debug/sample/{group}?node={节点标识}，转发到指定节点
+	debugRoutes := apiGroup.Group("/debug")
+	debugRoutes.RegisterFunc("/sample/", adminHandler.SampleHandler)
+
 	logger.Info("API路由注册完成")
 }
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/config"
+	"github.com/AdrianWangs/go-cache/internal/admin"
+)
+
+// TestSettingsMaskSecrets API 服务器信息接口的配置中不出现管理令牌、签名密钥和 URL 中的凭据
+func TestSettingsMaskSecrets(t *testing.T) {
+	const secret = "s3cret-value"
+	for name, value := range map[string]string{
+		"admin-token":    secret,
+		"cdn-purge-url":  "https://purge:" + secret + "@cdn.example.com/{group}/{key}",
+		"etcd-endpoints": "http://root:" + secret + "@10.0.0.2:2379",
+	} {
+		old := flag.CommandLine.Lookup(name).Value.String()
+		if err := flag.CommandLine.Set(name, value); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { flag.CommandLine.Set(name, old) })
+	}
+	auth := config.AuthConfig{Keys: []string{"k1:" + secret}}
+
+	src := &admin.InfoSource{Component: "apiserver", StartTime: time.Now(), Config: settings(auth)}
+	out, err := json.Marshal(src.Info())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(out), secret) {
+		t.Fatalf("API 服务器信息包含敏感值: %s", out)
+	}
+	if cfg := src.Info().Config; cfg["admin-token"] != admin.Masked || cfg["auth.keys"] != admin.Masked {
+		t.Fatalf("config = %v", cfg)
+	}
+}
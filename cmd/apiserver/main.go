@@ -2,14 +2,27 @@ package main
 
 import (
 	"flag"
+	"fmt"
+	"net/url"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/AdrianWangs/go-cache/api"
 	"github.com/AdrianWangs/go-cache/api/handlers"
+	"github.com/AdrianWangs/go-cache/config"
+	"github.com/AdrianWangs/go-cache/internal/access"
+	"github.com/AdrianWangs/go-cache/internal/admin"
+	"github.com/AdrianWangs/go-cache/internal/auth"
+	"github.com/AdrianWangs/go-cache/internal/consistenthash"
+	"github.com/AdrianWangs/go-cache/internal/deletequeue"
+	"github.com/AdrianWangs/go-cache/internal/discovery"
+	"github.com/AdrianWangs/go-cache/internal/peers"
 	"github.com/AdrianWangs/go-cache/pkg/logger"
+	"github.com/AdrianWangs/go-cache/pkg/version"
 )
 
 var (
@@ -19,10 +32,62 @@ var (
 	replicas      = flag.Int("replicas", 3, "一致性哈希虚拟节点倍数")
 	basePath      = flag.String("base-path", "/_gocache/", "缓存节点内部通信路径")
 	protocol      = flag.String("protocol", "grpc", "通信协议 (http 或 grpc)")
+	baseURLPrefix = flag.String("base-url-prefix", "", "所有路由的路径前缀，用于反向代理把API服务器映射到子路径（例如 /cache）；/health 和 /ready 在根路径下同样可用")
+	ringHash      = flag.String("ring-hash", consistenthash.HashCRC32, "一致性哈希函数 (crc32 或 xxhash64)，必须与所有缓存节点的 -ring-hash 相同")
+	identity      = flag.String("identity", "", "本 API 服务器的标识，在读取响应的 X-GoCache-Routed-By 响应头中返回（留空则为 主机名:端口）")
+	exposeID      = flag.Bool("expose-identity", true, "在读取响应中返回本 API 服务器和产生该值的缓存节点的标识，拓扑信息敏感时关闭")
+
+	defaultTimeouts = config.DefaultTimeouts()
+	requestTimeout  = flag.Duration("request-timeout", defaultTimeouts.APIRequest.Std(), "访问缓存节点的请求超时")
+	nodeMaxInFlight = flag.Int("node-max-inflight", 0, "发往每个缓存节点的未完成请求数上限，超出的读请求改由下一个节点处理（0表示不限制）")
+	nodeQueueWait   = flag.Duration("node-queue-wait", 0, "未完成的请求达到 -node-max-inflight 时等待空位的最长时间（0表示立即放弃）")
+	dialTimeout     = flag.Duration("dial-timeout", defaultTimeouts.PeerDial.Std(), "与缓存节点建立连接的超时")
+	etcdDialTimeout = flag.Duration("etcd-dial-timeout", defaultTimeouts.EtcdDial.Std(), "连接etcd的超时")
+	shutdownTimeout = flag.Duration("shutdown-timeout", defaultTimeouts.Shutdown.Std(), "优雅关闭的超时")
+
+	adminToken     = flag.String("admin-token", "", "管理接口访问令牌（留空则关闭管理接口）")
+	adminRateLimit = flag.Int("admin-rate-limit", 0, "导入导出每秒处理的条目上限（0表示不限速）")
+
+	httpCachePolicy = flag.String("http-cache-policy", "", "读取响应的按组 HTTP 缓存策略，逗号分隔的 组名=最长缓存时间，组名 * 为默认策略，例如 public=60s,*=0（留空则所有读取响应都不可缓存）")
+	cdnPurgeURL     = flag.String("cdn-purge-url", "", "删除 key 后发送 PURGE 请求的地址模板，{group} 和 {key} 替换为编码后的组名和 key（留空则不清除 CDN 缓存）")
+
+	prometheusMetrics = flag.Bool("prometheus-metrics", false, "在 /metrics 以 Prometheus 文本格式导出各路由的请求数和耗时")
+
+	hedgeDelay  = flag.Duration("hedge-delay", 0, "主节点超过该时间未响应时向下一个节点发出对冲读请求（0表示关闭）")
+	hedgeBudget = flag.Float64("hedge-budget", 5, "对冲请求占读请求的最大百分比")
+
+	readRepairRate = flag.Float64("read-repair-rate", 0, "非归属节点提供读取结果后，每秒最多在后台与归属节点比较并修复的次数（0表示关闭读修复）")
+	readRepairTTL  = flag.Duration("read-repair-ttl", 30*time.Second, "读修复写到副本节点的值的最长有效期")
+
+	hotKeySpread = flag.String("hot-key-spread", string(handlers.SpreadRoundRobin), "缓存节点复制热点key后读请求的分配方式 (round-robin: 轮流访问归属节点和副本节点; rendezvous: 按客户端固定选择节点; off: 始终访问归属节点)")
+
+	fanOutConcurrency = flag.Int("fanout-concurrency", 16, "聚合接口（组统计、批量读取）同时访问的节点数上限")
+
+	deleteRetryMaxSize = flag.Int("delete-retry-max-size", deletequeue.DefaultMaxSize, "发往归属节点失败的删除进入重试队列并返回202，队列的容量（0表示关闭删除重试）")
+	deleteRetryMaxAge  = flag.Duration("delete-retry-max-age", deletequeue.DefaultMaxAge, "删除的最长重试时间，超过后放弃")
+	deleteJournal      = flag.String("delete-journal", "", "删除重试队列的日志文件，重启后继续重试其中的删除（留空则只保存在内存中）")
+
+	deleteMode          = flag.String("delete-mode", string(handlers.DeleteOwner), "单个key的删除发往的节点 (owner: 只发往归属节点; owner+previous: 节点成员变化后的 -delete-previous-grace 内同时发往上一代哈希环的归属节点; broadcast: 发往所有节点)")
+	deletePreviousGrace = flag.Duration("delete-previous-grace", 30*time.Second, "owner+previous 下节点成员变化后仍向上一代哈希环的归属节点删除的时长")
+
+	maxDiscoveryLag = flag.Duration("max-discovery-lag", config.DefaultHealth().MaxDiscoveryLag.Std(), "服务发现中断超过该时长后 /health 返回 503")
+	seedNodes       = flag.String("seed-nodes", "", "首次从etcd同步之前使用的种子节点，逗号分隔，格式为 [id=]grpc地址[|http地址]")
+
+	accessConfig = flag.String("access-config", "", "客户端令牌授权配置文件（JSON，使用其中的 access 部分），留空则不限制；收到 SIGHUP 时重新加载")
+	proxyConfig  = flag.String("proxy-config", "", "缓存反向代理的上游配置文件（JSON，使用其中的 proxy 部分），配置了上游的组可以通过 /api/proxy/{group}/{path} 访问（留空则不开启）")
+
+	logAsync      = flag.Bool("log-async", false, "异步写日志：日志行先进入缓冲区，由后台goroutine格式化并写出")
+	logBufferSize = flag.Int("log-buffer-size", logger.DefaultAsyncBufferSize, "异步日志缓冲的行数")
+	logOverflow   = flag.String("log-overflow", logger.DropOldest.String(), "异步日志缓冲区满时的处理方式 (drop-oldest: 丢弃最早的行，不阻塞请求; block: 等待写出，不丢日志)")
+	logKeyMode    = flag.String("log-key-mode", "", "日志中缓存 key 的写法 (plain: 原样; hash: 简短的稳定摘要，可用于关联同一个 key 的日志; omit: 不写出)，留空时使用环境变量 GOCACHE_LOG_KEY_MODE，默认 plain")
 )
 
 func main() {
 	flag.Parse()
+	if *logAsync {
+		enableAsyncLog(*logBufferSize, *logOverflow)
+	}
+	setLogKeyMode(*logKeyMode)
 
 	endpoints := strings.Split(*etcdEndpoints, ",")
 	if len(endpoints) == 0 || endpoints[0] == "" {
@@ -40,14 +105,72 @@ func main() {
 		logger.Fatalf("不支持的协议类型: %s，只能是 http 或 grpc", *protocol)
 	}
 
-	logger.Info("API服务节点启动中...")
+	spread, err := handlers.ParseHotKeySpread(*hotKeySpread)
+	if err != nil {
+		logger.Fatalf("%v", err)
+	}
+	delMode, err := handlers.ParseDeleteMode(*deleteMode)
+	if err != nil {
+		logger.Fatalf("%v", err)
+	}
+	cachePolicies, err := handlers.ParseCachePolicies(*httpCachePolicy)
+	if err != nil {
+		logger.Fatalf("%v", err)
+	}
+	var purger handlers.Purger
+	if *cdnPurgeURL != "" {
+		purger = handlers.NewHTTPPurger(*cdnPurgeURL)
+	}
+
+	logger.Infof("API服务节点启动中，版本 %s", version.Get())
 	logger.Infof("Etcd Endpoints: %v", endpoints)
 	logger.Infof("监视的服务名称: %s", *serviceName)
 	logger.Infof("API监听端口: %d", *apiPort)
 	logger.Infof("一致性哈希虚拟节点倍数: %d", *replicas)
+	logger.Infof("一致性哈希函数: %s", *ringHash)
 	logger.Infof("缓存节点内部通信路径: %s", *basePath)
 	logger.Infof("使用通信协议: %s", protocolType)
 
+	var seeds []discovery.NodeInfo
+	if *seedNodes != "" {
+		var err error
+		seeds, err = discovery.ParseSeeds(strings.Split(*seedNodes, ","))
+		if err != nil {
+			logger.Fatalf("解析种子节点失败: %v", err)
+		}
+	}
+
+	// 对发往缓存节点的请求签名，密钥来自环境变量 GOCACHE_AUTH_KEYS，须与各节点一致
+	authConfig := config.LoadFromEnv().Auth
+	signer, _, err := auth.FromConfig(authConfig)
+	if err != nil {
+		logger.Fatalf("加载签名密钥失败: %v", err)
+	}
+	if signer != nil {
+		logger.Info("已开启内部请求签名")
+	}
+
+	// 客户端令牌按组授权，配置文件在收到 SIGHUP 时重新加载
+	var accessStore *access.Store
+	if *accessConfig != "" {
+		policy, err := loadAccessPolicy(*accessConfig)
+		if err != nil {
+			logger.Fatalf("加载访问控制配置失败: %v", err)
+		}
+		accessStore = access.NewStore(policy)
+		go reloadAccessOnHangup(accessStore, *accessConfig)
+		logger.Infof("已开启客户端令牌授权，配置文件: %s", *accessConfig)
+	}
+
+	// 缓存反向代理的上游
+	var upstreams handlers.ProxyUpstreams
+	if *proxyConfig != "" {
+		upstreams, err = loadProxyUpstreams(*proxyConfig)
+		if err != nil {
+			logger.Fatalf("加载缓存反向代理配置失败: %v", err)
+		}
+	}
+
 	// 创建 ApiServer 配置
 	cfg := &api.ApiServerConfig{
 		EtcdEndpoints: endpoints,
@@ -56,6 +179,50 @@ func main() {
 		Replicas:      *replicas,
 		BasePath:      *basePath,
 		Protocol:      protocolType,
+		BaseURLPrefix: *baseURLPrefix,
+		RingHash:      *ringHash,
+		Identity:      *identity,
+		HideIdentity:  !*exposeID,
+
+		RequestTimeout:  *requestTimeout,
+		NodeLimit:       peers.Limit{MaxInFlight: *nodeMaxInFlight, QueueWait: *nodeQueueWait},
+		DialTimeout:     *dialTimeout,
+		EtcdDialTimeout: *etcdDialTimeout,
+		ShutdownTimeout: *shutdownTimeout,
+
+		AdminToken:     *adminToken,
+		AdminRateLimit: *adminRateLimit,
+
+		CachePolicies: cachePolicies,
+		Purger:        purger,
+		Proxy:         upstreams,
+
+		PrometheusMetrics: *prometheusMetrics,
+
+		HedgeDelay:  *hedgeDelay,
+		HedgeBudget: *hedgeBudget,
+
+		ReadRepairRate: *readRepairRate,
+		ReadRepairTTL:  *readRepairTTL,
+
+		HotKeySpread: spread,
+
+		SeedNodes:       seeds,
+		MaxDiscoveryLag: *maxDiscoveryLag,
+
+		FanOutConcurrency: *fanOutConcurrency,
+
+		DeleteRetryMaxSize: *deleteRetryMaxSize,
+		DeleteRetryMaxAge:  *deleteRetryMaxAge,
+		DeleteJournal:      *deleteJournal,
+
+		DeleteMode:          delMode,
+		DeletePreviousGrace: *deletePreviousGrace,
+
+		Signer: signer,
+		Access: accessStore,
+
+		Settings: settings(authConfig),
 	}
 
 	// 创建并启动 ApiServer
@@ -76,4 +243,97 @@ func main() {
 	logger.Info("收到停止信号，API服务开始关闭...")
 	apiServer.Stop()
 	logger.Info("API服务已关闭")
+	logger.Flush() // 写出异步日志缓冲区中剩余的行
+}
+
+// enableAsyncLog 开启异步日志
+func enableAsyncLog(size int, overflow string) {
+	policy, err := logger.ParseOverflow(overflow)
+	if err != nil {
+		logger.Fatalf("无效的异步日志配置: %v", err)
+	}
+	logger.EnableAsync(logger.AsyncOptions{BufferSize: size, Overflow: policy})
+	logger.Infof("已开启异步日志，缓冲 %d 行，缓冲区满时 %s", size, policy)
+}
+
+// setLogKeyMode 设置日志中缓存 key 的写法，name 为空时使用环境变量 GOCACHE_LOG_KEY_MODE
+func setLogKeyMode(name string) {
+	if name == "" {
+		name = config.LoadFromEnv().LogKeyMode
+	}
+	mode, err := logger.ParseKeyMode(name)
+	if err != nil {
+		logger.Fatalf("无效的 -log-key-mode: %v", err)
+	}
+	logger.SetKeyMode(mode)
+	if mode != logger.KeyPlain {
+		logger.Infof("日志中的缓存 key 写法: %s", mode)
+	}
+}
+
+// loadAccessPolicy 从配置文件的 access 部分创建授权策略
+func loadAccessPolicy(path string) (*access.Policy, error) {
+	cfg, err := config.LoadFromFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return access.NewPolicy(cfg.Access)
+}
+
+// loadProxyUpstreams 从配置文件的 proxy 部分读取各组的上游，上游地址必须是 http 或 https 的绝对地址
+func loadProxyUpstreams(path string) (handlers.ProxyUpstreams, error) {
+	cfg, err := config.LoadFromFile(path)
+	if err != nil {
+		return nil, err
+	}
+	upstreams := make(handlers.ProxyUpstreams, len(cfg.Proxy))
+	for _, p := range cfg.Proxy {
+		if p.Group == "" {
+			return nil, fmt.Errorf("proxy 中的上游缺少 group")
+		}
+		if _, dup := upstreams[p.Group]; dup {
+			return nil, fmt.Errorf("组 %s 配置了多个上游", p.Group)
+		}
+		u, err := url.Parse(p.UpstreamURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("组 %s 的上游地址 %q 无效", p.Group, p.UpstreamURL)
+		}
+		upstreams[p.Group] = handlers.ProxyUpstream{
+			URL:            p.UpstreamURL,
+			Timeout:        p.Timeout.Std(),
+			ForwardHeaders: p.ForwardHeaders,
+			MaxBodyBytes:   p.MaxBodyBytes,
+			TTL:            p.TTL.Std(),
+			NegativeTTL:    p.NegativeTTL.Std(),
+			ContentType:    p.ContentType,
+		}
+	}
+	return upstreams, nil
+}
+
+// reloadAccessOnHangup 每次收到 SIGHUP 时重新加载授权配置，配置无效时保留当前策略
+func reloadAccessOnHangup(store *access.Store, path string) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	for range hup {
+		cfg, err := config.LoadFromFile(path)
+		if err == nil {
+			err = store.Reload(cfg.Access)
+		}
+		if err != nil {
+			logger.Errorf("重新加载访问控制配置失败，继续使用当前配置: %v", err)
+			continue
+		}
+		logger.Infof("已重新加载访问控制配置: %s", path)
+	}
+}
+
+// settings 返回 API 服务器生效的配置：所有命令行参数和签名配置，未脱敏
+func settings(authConfig config.AuthConfig) map[string]string {
+	cfg := admin.FlagConfig(flag.CommandLine)
+	cfg["auth.keys"] = strings.Join(authConfig.Keys, ",")
+	cfg["auth.clock_skew"] = authConfig.ClockSkew.Std().String()
+	cfg["auth.nonce_cache_size"] = strconv.Itoa(authConfig.NonceCacheSize)
+	cfg["auth.max_body_bytes"] = strconv.FormatInt(authConfig.MaxBodyBytes, 10)
+	return cfg
 }
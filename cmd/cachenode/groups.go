@@ -0,0 +1,250 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/config"
+	"github.com/AdrianWangs/go-cache/internal/cache"
+	"github.com/AdrianWangs/go-cache/internal/deletequeue"
+	"github.com/AdrianWangs/go-cache/internal/peers"
+	"github.com/AdrianWangs/go-cache/pkg/ciphers"
+	"github.com/AdrianWangs/go-cache/pkg/getters"
+	"github.com/AdrianWangs/go-cache/pkg/logger"
+	"github.com/AdrianWangs/go-cache/pkg/lru"
+)
+
+// defaultGroupTTL 未设置 TTL 时缓存组使用的过期时间
+const defaultGroupTTL = time.Hour
+
+// demoData 演示数据源的数据，只在数据源类型为 demo 时使用
+var demoData = map[string]string{
+	"Tom":  "630",
+	"Jack": "589",
+	"Sam":  "567",
+}
+
+// newGetter 根据数据源配置创建缓存组的 Getter
+func newGetter(group string, src config.SourceConfig) (cache.Getter, error) {
+	switch src.Type {
+	case "", config.SourceNone:
+		logger.Warnf("缓存组 %s 未配置数据源，未缓存的 key 都返回不存在", group)
+		return cache.GetterFunc(func(key string) ([]byte, error) {
+			return nil, nil // 空值表示 key 不存在
+		}), nil
+	case config.SourceDemo:
+		logger.Warnf("缓存组 %s 使用演示数据源，仅用于演示和本地测试", group)
+		return cache.GetterFunc(func(key string) ([]byte, error) {
+			logger.Debugf("[演示数据源] 尝试获取 key: %s (group=%s)", logger.Key(key), group)
+			if v, ok := demoData[key]; ok {
+				return []byte(v), nil
+			}
+			return nil, nil // 空值表示 key 不存在
+		}), nil
+	case config.SourceHTTP:
+		logger.Infof("缓存组 %s 从 HTTP 数据源加载: %s", group, src.URL)
+		return getters.NewHTTP(src.URL, time.Duration(src.Timeout))
+	case config.SourceExec:
+		logger.Infof("缓存组 %s 执行命令加载: %s", group, src.Command)
+		return getters.NewExec(src.Command, time.Duration(src.Timeout))
+	case config.SourceRedis:
+		r, err := getters.NewRedis(src.URL, time.Duration(src.Timeout))
+		if err != nil {
+			return nil, err
+		}
+		logger.Infof("缓存组 %s 从 Redis 数据源加载: %s", group, r)
+		return r, nil
+	default:
+		return nil, fmt.Errorf("缓存组 %s 的数据源类型不支持: %q，只能是 %s、%s、%s、%s 或 %s", group, src.Type,
+			config.SourceNone, config.SourceDemo, config.SourceHTTP, config.SourceExec, config.SourceRedis)
+	}
+}
+
+// flagGroupConfig 根据命令行参数生成单个缓存组的配置，未指定配置文件时使用
+func flagGroupConfig() config.GroupConfig {
+	return config.GroupConfig{
+		Name: *groupName,
+		Source: config.SourceConfig{
+			Type:    *sourceType,
+			URL:     *sourceURL,
+			Command: *sourceCmd,
+			Timeout: config.Duration(*sourceTimeout),
+		},
+		MaxBytes:     *cacheSize,
+		TTL:          config.Duration(time.Duration(*ttl) * time.Second),
+		MaxAge:       config.Duration(*maxAge),
+		MaxIdle:      config.Duration(*maxIdle),
+		RateLimit:    *rateLimit,
+		RateBurst:    *rateBurst,
+		RefreshAhead: *refreshAhead,
+		Eviction:     *eviction,
+		MissPolicy:   *missPolicy,
+		DeleteMarker: config.Duration(*deleteMarker),
+		HotKeys: config.HotKeyConfig{
+			Track:        *hotKeyTrack,
+			QPSThreshold: *hotKeyQPS,
+			Replicas:     *hotKeyReplicas,
+			ReplicaTTL:   config.Duration(*hotKeyTTL),
+		},
+		OriginBreaker: config.BreakerConfig{
+			FailureRate: *breakerRate,
+			Window:      config.Duration(*breakerWindow),
+			OpenFor:     config.Duration(*breakerOpen),
+		},
+		TombstoneRetention: config.Duration(*tombstoneRetention),
+		TombstoneCapacity:  *tombstoneCapacity,
+		EvictionWarnAge:    config.Duration(*evictionWarnAge),
+		HighWatermark:      *highWatermark,
+		LowWatermark:       *lowWatermark,
+		EncryptValues:      *encryptValues,
+		DefaultDeadline:    config.Duration(*defaultDeadline),
+		StrictDeadline:     *strictDeadline,
+		ClientRateLimit:    *clientRateLimit,
+		ClientRateBurst:    *clientRateBurst,
+	}
+}
+
+// loadValueCipher 有缓存组开启值加密时从 keyFile 或环境变量加载密钥，没有组开启时返回 nil
+func loadValueCipher(cfgs []config.GroupConfig, keyFile string) (*ciphers.AESGCM, error) {
+	for _, cfg := range cfgs {
+		if !cfg.EncryptValues {
+			continue
+		}
+		c, err := ciphers.Load(keyFile)
+		if err != nil {
+			return nil, err
+		}
+		logger.Infof("已加载值加密密钥，加密使用密钥: %s", c.PrimaryID())
+		return c, nil
+	}
+	return nil, nil
+}
+
+// createGroups 创建配置的所有缓存组，并为每个组注册同一个 PeerPicker，picker 为 nil 时（单机模式）不注册；
+// deletes 不为 nil 时各组发往归属节点失败的删除进入该重试队列，开启值加密的组用 valueCipher 加密，
+// notifier 不为 nil 时各组从本地缓存移除的 key 通知给它
+func createGroups(cfgs []config.GroupConfig, picker peers.PeerPicker, deletes *deletequeue.Queue, valueCipher *ciphers.AESGCM, notifier cache.Notifier) ([]*cache.Group, error) {
+	if len(cfgs) == 0 {
+		return nil, fmt.Errorf("没有配置任何缓存组")
+	}
+
+	seen := make(map[string]bool, len(cfgs))
+	for _, cfg := range cfgs {
+		if cfg.Name == "" {
+			return nil, fmt.Errorf("缓存组名不能为空")
+		}
+		if seen[cfg.Name] {
+			return nil, fmt.Errorf("缓存组 %s 重复配置", cfg.Name)
+		}
+		seen[cfg.Name] = true
+	}
+
+	groups := make([]*cache.Group, 0, len(cfgs))
+	for _, cfg := range cfgs {
+		getter, err := newGetter(cfg.Name, cfg.Source)
+		if err != nil {
+			return nil, err
+		}
+
+		maxBytes := cfg.MaxBytes
+		if maxBytes <= 0 {
+			maxBytes = *cacheSize
+		}
+		policy, err := lru.ParsePolicy(cfg.Eviction)
+		if err != nil {
+			return nil, fmt.Errorf("缓存组 %s 的淘汰策略无效: %w", cfg.Name, err)
+		}
+		miss, err := cache.ParseMissPolicy(cfg.MissPolicy)
+		if err != nil {
+			return nil, fmt.Errorf("缓存组 %s 的缺失策略无效: %w", cfg.Name, err)
+		}
+		if miss == cache.MissPeerOnly && picker == nil {
+			return nil, fmt.Errorf("缓存组 %s 的缺失策略 %s 在单机模式下不会从数据源加载任何 key，请改用 %s 或 %s",
+				cfg.Name, miss, cache.MissOriginFallback, cache.MissOriginOnlyIfOwner)
+		}
+		groupTTL := cfg.TTL.Std()
+		if groupTTL <= 0 {
+			groupTTL = defaultGroupTTL
+		}
+
+		if cfg.HotKeys.QPSThreshold < 0 || cfg.HotKeys.Replicas < 0 {
+			return nil, fmt.Errorf("缓存组 %s 的热点 key 配置无效: qps_threshold 和 replicas 不能为负数", cfg.Name)
+		}
+		if br := cfg.OriginBreaker; br.FailureRate < 0 || br.FailureRate > 1 {
+			return nil, fmt.Errorf("缓存组 %s 的数据源熔断配置无效: failure_rate 必须在 0 到 1 之间", cfg.Name)
+		}
+		if cfg.TombstoneCapacity < 0 {
+			return nil, fmt.Errorf("缓存组 %s 的墓碑配置无效: tombstone_capacity 不能为负数", cfg.Name)
+		}
+		if hw, lw := cfg.HighWatermark, cfg.LowWatermark; (hw != 0 || lw != 0) && !(0 < lw && lw < hw && hw <= 1) {
+			return nil, fmt.Errorf("缓存组 %s 的水位配置无效: 需要 0 < low_watermark < high_watermark <= 1", cfg.Name)
+		}
+		if cfg.EncryptValues && valueCipher == nil {
+			return nil, fmt.Errorf("缓存组 %s 开启了值加密，但没有加载密钥", cfg.Name)
+		}
+
+		opts := []cache.GroupOption{
+			cache.WithRefreshAhead(cfg.RefreshAhead),
+			cache.WithMaxAge(cfg.MaxAge.Std()),
+			cache.WithMaxIdle(cfg.MaxIdle.Std()),
+			cache.WithSweepInterval(cfg.SweepInterval.Std()),
+			cache.WithRateLimit(cache.RateLimit{Rate: cfg.RateLimit, Burst: cfg.RateBurst}),
+			cache.WithClientRateLimit(cache.RateLimit{Rate: cfg.ClientRateLimit, Burst: cfg.ClientRateBurst}),
+			cache.WithEvictionPolicy(policy),
+			cache.WithMissPolicy(miss),
+			cache.WithDeleteMarker(cfg.DeleteMarker.Std()),
+			cache.WithTombstones(cfg.TombstoneRetention.Std(), cfg.TombstoneCapacity),
+			cache.WithDefaultDeadline(cfg.DefaultDeadline.Std()),
+			cache.WithStrictDeadline(cfg.StrictDeadline),
+		}
+		if hk := cfg.HotKeys; hk.Enabled() {
+			opts = append(opts, cache.WithHotKeys(cache.HotKeyConfig{
+				Window:     hk.Window.Std(),
+				MaxTracked: hk.MaxTracked,
+				Threshold:  hk.QPSThreshold,
+				Replicas:   hk.Replicas,
+				ReplicaTTL: hk.ReplicaTTL.Std(),
+			}))
+		}
+		if br := cfg.OriginBreaker; br.FailureRate > 0 {
+			opts = append(opts, cache.WithOriginBreaker(cache.BreakerConfig{
+				FailureRate: br.FailureRate,
+				Window:      br.Window.Std(),
+				MinRequests: br.MinRequests,
+				OpenFor:     br.OpenFor.Std(),
+				Probes:      br.Probes,
+			}))
+		}
+		if age := cfg.EvictionWarnAge.Std(); age > 0 {
+			opts = append(opts, cache.WithEvictionPressure(age))
+		}
+		if cfg.HighWatermark > 0 {
+			opts = append(opts, cache.WithWatermarks(cfg.HighWatermark, cfg.LowWatermark))
+		}
+		if deletes != nil {
+			opts = append(opts, cache.WithDeleteRetry(deletes))
+		}
+		if cfg.EncryptValues {
+			opts = append(opts, cache.WithKeyedValueTransform(valueCipher.EncryptFor, valueCipher.DecryptFor))
+		}
+		if notifier != nil {
+			opts = append(opts, cache.WithEvictionNotifier(notifier))
+		}
+		group := cache.NewGroup(cfg.Name, maxBytes, getter, groupTTL, opts...)
+		if picker != nil {
+			group.RegisterPeers(picker)
+		}
+		groups = append(groups, group)
+		logger.Infof("已创建缓存组: %s, 大小: %d字节, TTL: %v", cfg.Name, maxBytes, groupTTL)
+	}
+	return groups, nil
+}
+
+// closeGroups 关闭所有缓存组，停止它们的后台 goroutine
+func closeGroups(groups []*cache.Group) {
+	for _, g := range groups {
+		if err := g.Close(); err != nil {
+			logger.Warnf("关闭缓存组 %s 失败: %v", g.Name(), err)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/api/handlers"
+	"github.com/AdrianWangs/go-cache/config"
+	"github.com/AdrianWangs/go-cache/internal/cache"
+	cachegrpc "github.com/AdrianWangs/go-cache/internal/cachenode/grpc"
+	"github.com/AdrianWangs/go-cache/internal/server"
+)
+
+// freeAddr 返回一个当前空闲的本地地址
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	return l.Addr().String()
+}
+
+// TestTwoGroupsOverAllTransports 一个节点上的两个缓存组有同名的 key，
+// 通过 gRPC 和 protobuf HTTP 读取和删除都落在请求的组上
+func TestTwoGroupsOverAllTransports(t *testing.T) {
+	users := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "user:%s", r.URL.Path[1:])
+	}))
+	defer users.Close()
+
+	suffix := fmt.Sprint(time.Now().UnixNano())
+	scoresName, usersName := "scores-"+suffix, "users-"+suffix
+	groups, err := createGroups([]config.GroupConfig{
+		{Name: scoresName, Source: config.SourceConfig{Type: config.SourceDemo}},
+		{Name: usersName, Source: config.SourceConfig{Type: config.SourceHTTP, URL: users.URL}},
+	}, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeGroups(groups)
+
+	grpcAddr := freeAddr(t)
+	grpcServer := cachegrpc.NewCacheServer(grpcAddr)
+	if err := grpcServer.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer grpcServer.Stop()
+
+	pool := server.NewHTTPPool("http://127.0.0.1", server.WithProtocol(server.ProtocolProtobuf))
+	httpServer := httptest.NewServer(pool)
+	defer httpServer.Close()
+
+	transports := map[string]handlers.NodeGetter{
+		"grpc":     handlers.NewGRPCGetter(grpcAddr),
+		"protobuf": handlers.NewProtoGetter(httpServer.URL + pool.BasePath()),
+	}
+	ctx := context.Background()
+	for name, getter := range transports {
+		t.Run(name, func(t *testing.T) {
+			for group, want := range map[string]string{scoresName: "630", usersName: "user:Tom"} {
+				if v, err := getter.Get(ctx, group, "Tom"); err != nil || string(v) != want {
+					t.Fatalf("Get(%s, Tom) = %q, %v; want %q", group, v, err, want)
+				}
+			}
+			if _, err := getter.Get(ctx, "missing-"+suffix, "Tom"); err == nil {
+				t.Fatal("不存在的组应返回错误")
+			}
+
+			// 删除只影响请求的组
+			if err := getter.Delete(ctx, usersName, "Tom"); err != nil {
+				t.Fatal(err)
+			}
+			if _, _, ok := cache.GetGroup(usersName).Peek("Tom"); ok {
+				t.Fatalf("%s 中的 Tom 未被删除", usersName)
+			}
+			if _, _, ok := cache.GetGroup(scoresName).Peek("Tom"); !ok {
+				t.Fatalf("删除 %s 时 %s 中的 Tom 也被删除", usersName, scoresName)
+			}
+		})
+	}
+}
+
+func TestCreateGroupsValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		cfgs []config.GroupConfig
+	}{
+		{"没有组", nil},
+		{"组名为空", []config.GroupConfig{{Name: ""}}},
+		{"重复的组", []config.GroupConfig{{Name: "dup"}, {Name: "dup"}}},
+		{"未知数据源", []config.GroupConfig{{Name: "x", Source: config.SourceConfig{Type: "ftp"}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			groups, err := createGroups(tt.cfgs, nil, nil, nil, nil)
+			closeGroups(groups)
+			if err == nil {
+				t.Fatal("应返回错误")
+			}
+		})
+	}
+}
+
+// TestDemoSourceOptIn 未配置数据源时不使用演示数据
+func TestDemoSourceOptIn(t *testing.T) {
+	none, err := newGetter("g", config.SourceConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err := none.Get("Tom"); err != nil || v != nil {
+		t.Fatalf("未配置数据源时 Get(Tom) = %q, %v", v, err)
+	}
+	demo, err := newGetter("g", config.SourceConfig{Type: config.SourceDemo})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err := demo.Get("Tom"); err != nil || string(v) != "630" {
+		t.Fatalf("演示数据源 Get(Tom) = %q, %v", v, err)
+	}
+	if _, err := newGetter("g", config.SourceConfig{Type: "ftp"}); err == nil {
+		t.Fatal("未知数据源类型应返回错误")
+	}
+}
@@ -2,75 +2,171 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"flag"
 	"fmt"
-	"io"
-	"net"
-	"net/http"
 	"os"
 	"os/signal"
+	"slices"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
 
+	"github.com/AdrianWangs/go-cache/config"
+	"github.com/AdrianWangs/go-cache/internal/admin"
+	"github.com/AdrianWangs/go-cache/internal/auth"
 	"github.com/AdrianWangs/go-cache/internal/cache"
 	"github.com/AdrianWangs/go-cache/internal/cachenode/grpc"
 	httpserver "github.com/AdrianWangs/go-cache/internal/cachenode/http"
+	"github.com/AdrianWangs/go-cache/internal/cachenode/peers"
+	"github.com/AdrianWangs/go-cache/internal/consistenthash"
+	"github.com/AdrianWangs/go-cache/internal/deletequeue"
 	"github.com/AdrianWangs/go-cache/internal/discovery"
+	"github.com/AdrianWangs/go-cache/internal/evictnotify"
+	"github.com/AdrianWangs/go-cache/internal/health"
+	peerproto "github.com/AdrianWangs/go-cache/internal/peers"
+	"github.com/AdrianWangs/go-cache/internal/selfcheck"
 	"github.com/AdrianWangs/go-cache/internal/server"
+	"github.com/AdrianWangs/go-cache/pkg/ciphers"
 	"github.com/AdrianWangs/go-cache/pkg/logger"
+	"github.com/AdrianWangs/go-cache/pkg/lru"
+	"github.com/AdrianWangs/go-cache/pkg/version"
 )
 
 var (
+	standalone    = flag.Bool("standalone", false, "单机模式：不注册到etcd、不获取节点列表，本节点独立提供 HTTP 和 gRPC 服务，所有 key 从本地数据源加载，不需要 etcd 和 API 服务器")
 	etcdEndpoints = flag.String("etcd-endpoints", "localhost:2379", "etcd集群地址，多个用逗号分隔")
 	serviceName   = flag.String("service-name", "go-cache-nodes", "服务名称")
 	nodeHost      = flag.String("node-host", "", "本节点主机名或IP地址（留空则自动检测）")
 	nodePort      = flag.Int("node-port", 9090, "本节点gRPC监听端口")
 	httpPort      = flag.Int("http-port", 9091, "本节点HTTP监听端口")
+	protocols     = flag.String("protocols", "grpc,http", "向API服务器登记本节点提供的协议，逗号分隔 (grpc、http)；API服务器在双方都支持时优先使用gRPC，gRPC端口位于只转发HTTP/1.1的代理之后时设为 http")
 	apiAddr       = flag.String("api-addr", "localhost:8080", "API服务器地址")
 	cacheSize     = flag.Int64("cache-size", 1024*1024*64, "缓存大小 (bytes)")
+	configFile    = flag.String("config", "", "配置文件路径；配置了 groups 时创建其中的所有缓存组，忽略下面的单个缓存组参数")
 	groupName     = flag.String("group-name", "scores", "缓存组名称")
+	sourceType    = flag.String("source", config.SourceNone, "缓存组的数据源类型 (none: 不回源，未缓存的 key 返回不存在; demo: 内置的演示数据; http: GET {-source-url}/{key}; exec: 执行 -source-cmd; redis: 从 -source-url 的 Redis 读取)")
+	sourceURL     = flag.String("source-url", "", "http 数据源的基础地址，或 redis 数据源的 redis://[user:password@]host:port[/db]")
+	sourceCmd     = flag.String("source-cmd", "", "exec 数据源的命令模板，{key} 替换为 key，标准输出即值，退出码 1 表示不存在，例如 \"cat /data/{key}\"")
+	sourceTimeout = flag.Duration("source-timeout", 0, "数据源单次加载的超时（0表示默认的5s）")
 	leaseTTL      = flag.Int64("lease-ttl", 10, "etcd租约TTL（秒）")
 	ttl           = flag.Int64("ttl", 0, "缓存过期时间（秒）")
-)
+	maxAge        = flag.Duration("max-age", 0, "缓存条目自插入起的最长存活时间（0表示不限制）")
+	maxIdle       = flag.Duration("max-idle", 0, "缓存条目未被访问的最长时间（0表示不限制）")
+	refreshAhead  = flag.Float64("refresh-ahead", 0, "提前刷新阈值（TTL已消耗比例，0-1之间，0表示关闭）")
+	eviction      = flag.String("eviction", lru.PolicyNameLRU, "缓存淘汰策略 (lru: 严格LRU; clock: CLOCK/second-chance，命中只需读锁，命中率略低; cost: 按成本/大小比淘汰 (GreedyDual-Size)，未设置成本函数时等同 lru)")
+	missPolicy    = flag.String("miss-policy", "origin-fallback", "归属节点无法提供数据时的缺失策略 (origin-fallback: 回退到本地数据源; peer-only: 仅当本节点是归属节点时回源，否则返回错误; origin-only-if-owner: 同 peer-only，但未注册节点的组视为归属所有key)")
+	deleteMarker  = flag.Duration("delete-marker", 0, "删除key后归属节点放置加载标记的时长，期间的读取共享同一次重新加载，防止缓存击穿（0表示关闭，建议300ms）")
+	rateLimit     = flag.Float64("rate-limit", 0, "缓存组每秒请求数上限（0表示不限制）")
+	rateBurst     = flag.Int("rate-burst", 0, "缓存组限流的突发容量（0表示与 rate-limit 相同）")
+	nodeRateLimit = flag.Float64("node-rate-limit", 0, "本节点所有缓存组合计的每秒请求数上限（0表示不限制）")
+	nodeRateBurst = flag.Int("node-rate-burst", 0, "节点级限流的突发容量（0表示与 node-rate-limit 相同）")
+	nodeMode      = flag.String("mode", "readwrite", "节点模式 (readwrite、readonly 或 readonly-local)")
+	nodeID        = flag.String("node-id", "", "本节点的稳定标识（留空则按 -node-id-mode 确定）")
+	nodeIDMode    = flag.String("node-id-mode", "address", "节点标识来源 (address: 使用规范化的gRPC地址，与旧版本key归属一致; persistent: 生成并保存到 -node-id-file)")
+	nodeIDFile    = flag.String("node-id-file", "gocache-node-id", "persistent 模式下保存节点标识的文件")
+	exposeID      = flag.Bool("expose-identity", true, "在读取响应中返回产生该值的节点标识 (X-GoCache-Node 响应头和 gRPC 响应的 node 字段)，拓扑信息敏感时关闭")
+	ringHash      = flag.String("ring-hash", consistenthash.HashCRC32, "一致性哈希函数 (crc32: 与旧版本key归属一致; xxhash64: 64位哈希并处理虚拟节点冲突)，必须与API服务器及其他节点相同")
+	maxHops       = flag.Int("max-hops", peerproto.DefaultMaxHops, "请求被节点转发达到该次数后不再转发，直接在本地应答，用于切断哈希环不一致造成的转发环路")
 
-// 模拟数据源
-var db = map[string]string{
-	"Tom":  "630",
-	"Jack": "589",
-	"Sam":  "567",
-}
+	hotKeyQPS      = flag.Float64("hot-key-qps", 0, "单个key每秒读取达到该值时由归属节点复制到后续节点（0表示不复制）")
+	hotKeyReplicas = flag.Int("hot-key-replicas", 2, "热点key复制到的额外节点数")
+	hotKeyTTL      = flag.Duration("hot-key-ttl", cache.DefaultHotKeyReplicaTTL, "热点key副本的存活时间")
+	hotKeyTrack    = flag.Bool("hot-key-track", false, "统计每个key的读取次数，供 /api/admin/groups/{group}/hotkeys 查看（设置 -hot-key-qps 时总是开启）")
 
-// getLocalIP 获取本地非环回IP地址
-func getLocalIP() (string, error) {
-	addrs, err := net.InterfaceAddrs()
-	if err != nil {
-		return "", err
-	}
-	for _, address := range addrs {
-		// 检查ip地址判断是否回环地址
-		if ipnet, ok := address.(*net.IPNet); ok && !ipnet.IP.IsLoopback() {
-			if ipnet.IP.To4() != nil {
-				return ipnet.IP.String(), nil
-			}
-		}
-	}
-	return "", fmt.Errorf("无法找到本地非环回IP地址")
-}
+	breakerRate   = flag.Float64("origin-breaker-rate", 0, "数据源熔断的失败率阈值（0-1之间，0表示关闭）：一个统计窗口内失败的加载达到该比例时熔断，未命中直接返回数据源不可用")
+	breakerWindow = flag.Duration("origin-breaker-window", cache.DefaultBreakerWindow, "数据源熔断统计失败率的窗口")
+	breakerOpen   = flag.Duration("origin-breaker-open", cache.DefaultBreakerOpenFor, "数据源熔断后到开始探测恢复的时长")
+
+	tombstoneRetention = flag.Duration("tombstone-retention", 0, "删除key后保留墓碑的时长，期间读取直接返回不存在，删除前开始的加载结果不写入缓存（0表示关闭，建议为节点间请求超时的2倍）")
+	tombstoneCapacity  = flag.Int("tombstone-capacity", cache.DefaultTombstoneCapacity, "每个缓存组最多保留的墓碑数，超出时丢弃最早的")
+
+	servableGroups = flag.String("servable-groups", "", "允许对等节点和API服务器读取的缓存组，逗号分隔（留空则不限制）；其他组只供本节点内部使用，读取时返回403。配置文件的 servable_groups 优先，收到 SIGHUP 时重新加载")
+
+	warmup        = flag.Bool("warmup", false, "加入哈希环后，从之前缓存这些 key 的节点拉取现归本节点的热点 key，避免冷启动时未命中全部回源")
+	warmupKeys    = flag.Int("warmup-keys", server.DefaultWarmupKeys, "预热时每个缓存组最多拉取的 key 数，按对等节点统计的读取次数从高到低选取")
+	warmupBytes   = flag.Int64("warmup-bytes", 0, "预热拉取的值的总字节数上限（0表示不限制）")
+	warmupTimeout = flag.Duration("warmup-timeout", server.DefaultWarmupTimeout, "预热的时间预算，用完后结束预热")
+	warmupRate    = flag.Int("warmup-rate", 0, "预热每秒拉取的 key 数上限（0表示不限速）")
+
+	primeFile        = flag.String("prime-file", "", "启动时预加载的 key 清单，每行一个 key，或 组名<TAB>key（不写组名时为第一个缓存组），空行和 # 开头的行被忽略；加入哈希环后加载归属本节点的 key")
+	primeConcurrency = flag.Int("prime-concurrency", server.DefaultPrimeConcurrency, "按清单预加载时同时加载的 key 数")
+	primeTimeout     = flag.Duration("prime-timeout", server.DefaultPrimeTimeout, "按清单预加载的时间预算，用完后结束预加载")
+	primeReady       = flag.String("prime-ready", string(server.PrimeReadyWait), "预加载与 /ready 的关系 (wait: 预加载结束或时间预算用完后就绪; strict: 清单在预算内全部处理完才就绪，否则保持未就绪; off: 不等待预加载)")
+
+	deleteRetryMaxSize = flag.Int("delete-retry-max-size", deletequeue.DefaultMaxSize, "删除发往归属节点失败时进入重试队列并返回202，队列的容量（0表示关闭删除重试，直接返回错误）")
+	deleteRetryMaxAge  = flag.Duration("delete-retry-max-age", deletequeue.DefaultMaxAge, "删除的最长重试时间，超过后放弃")
+	deleteJournal      = flag.String("delete-journal", "", "删除重试队列的日志文件，重启后继续重试其中的删除（留空则只保存在内存中）")
+
+	evictNotifyURL    = flag.String("evict-notify-url", "", "把从本地缓存移除的 key（容量淘汰、过期、删除、清空）批量以 JSON POST 到该地址（留空则不通知）")
+	evictNotifyWindow = flag.Duration("evict-notify-window", evictnotify.DefaultWindow, "淘汰通知的批次收集时长")
+	evictNotifyBatch  = flag.Int("evict-notify-batch", evictnotify.DefaultMaxBatch, "淘汰通知每批最多的事件数")
+	evictNotifyQueue  = flag.Int("evict-notify-queue", evictnotify.DefaultQueueSize, "淘汰通知等待发送的事件上限，超过后丢弃并计数")
+
+	evictionWarnAge = flag.Duration("eviction-warn-age", 0, "统计容量淘汰时条目的存活时长，最近一分钟被淘汰条目的存活时长中位数低于该值时告警，提示缓存容量不足（0表示关闭，建议10s）")
+	highWatermark   = flag.Float64("high-watermark", 0, "占用超过容量的该比例时，由后台提前淘汰到 -low-watermark，写入不再同步承担淘汰（0表示关闭，例如0.95）")
+	lowWatermark    = flag.Float64("low-watermark", 0, "超过高水位后后台淘汰到容量的该比例（例如0.9），需小于 -high-watermark")
+
+	encryptValues = flag.Bool("encrypt-values", false, "用 AES-GCM 加密缓存组在内存、导出流和快照中保存的值，读取时解密；节点间和返回给客户端的仍是明文，需要时开启 TLS")
+	valueKeyFile  = flag.String("value-key-file", "", "值加密密钥文件，每个密钥写作 标识:base64密钥，第一个用于加密（留空则读取环境变量 "+ciphers.EnvKeys+"）")
+
+	defaultDeadline = flag.Duration("default-deadline", 0, "未设截止时间的读取的总时限，覆盖从归属节点获取、失败后回源和数据源加载（0表示不限制）")
+	strictDeadline  = flag.Bool("strict-deadline", false, "调用方的截止时间晚于 -default-deadline 时也以其为上限")
+
+	clientRateLimit = flag.Float64("client-rate-limit", 0, "缓存组对每个客户端主机的每秒请求数上限，在 -rate-limit 之外单独计算，防止单个客户端占满整个组的配额（0表示不限制）")
+	clientRateBurst = flag.Int("client-rate-burst", 0, "客户端限流的突发容量（0表示与 client-rate-limit 相同）")
+
+	defaultTimeouts = config.DefaultTimeouts()
+	peerTimeout     = flag.Duration("peer-timeout", defaultTimeouts.PeerRequest.Std(), "节点间请求超时")
+	peerMaxInFlight = flag.Int("peer-max-inflight", 0, "发往每个对等节点的未完成请求数上限，超出的请求不发出，改从数据源加载（0表示不限制）")
+	peerQueueWait   = flag.Duration("peer-queue-wait", 0, "未完成的请求达到 -peer-max-inflight 时等待空位的最长时间（0表示立即放弃）")
+	etcdDialTimeout = flag.Duration("etcd-dial-timeout", defaultTimeouts.EtcdDial.Std(), "连接etcd的超时")
+	shutdownTimeout = flag.Duration("shutdown-timeout", defaultTimeouts.Shutdown.Std(), "优雅关闭的超时")
+
+	adminToken     = flag.String("admin-token", "", "管理接口访问令牌（留空则关闭管理接口）")
+	adminRateLimit = flag.Int("admin-rate-limit", 0, "导入导出每秒处理的条目上限（0表示不限速）")
+	adminAddr      = flag.String("admin-addr", "", "状态、健康检查、管理与调试接口的单独监听地址，例如 127.0.0.1:9092（留空则与 -http-port 共用）")
+
+	peerSource         = flag.String("peer-source", "api", "节点列表来源 (api: API Server 的 /peers; etcd: 直接监视etcd)")
+	peerUpdateInterval = flag.Duration("peer-update-interval", 5*time.Second, "更新节点列表的间隔，连续失败时按指数退避")
+	maxPeerSyncAge     = flag.Duration("max-peer-sync-age", config.DefaultHealth().MaxPeerSyncAge.Std(), "节点列表超过该时长未成功更新时 /health 返回 503")
+	seedPeers          = flag.String("seed-peers", "", "启动时立即使用的种子节点，逗号分隔，格式为 [id=]grpc地址[|http地址]；收到第一份服务发现结果后被替换")
+
+	requireRegistration = flag.Bool("require-registration", true, "要求注册到etcd：注册成功前 /ready 返回503，重试预算用完后退出；设为 false 时注册成功前按单机模式在本地提供服务，并在后台持续重试注册")
+	registerAttempts    = flag.Int("register-attempts", 0, "启动时注册到etcd的最多尝试次数，两次尝试之间按带抖动的指数退避等待（0表示不限制，只受 -register-timeout 约束）；-require-registration=false 时不限制")
+	registerTimeout     = flag.Duration("register-timeout", time.Minute, "启动时注册到etcd的总时长预算，从第一次尝试算起（0表示不限制）；-require-registration=false 时不限制")
+
+	selfCheck        = flag.Bool("self-check", false, "注册到etcd之前自检：端口可以绑定、etcd可以读写、通告的地址可以连回本机，任何一项失败则退出")
+	selfCheckSkip    = flag.String("self-check-skip", "", "跳过的自检项，逗号分隔 (bind、etcd、dial、tls)，用于无法访问etcd等隔离网络的部署")
+	selfCheckTimeout = flag.Duration("self-check-timeout", selfcheck.DefaultTimeout, "单项自检的超时")
+
+	logAsync      = flag.Bool("log-async", false, "异步写日志：日志行先进入缓冲区，由后台goroutine格式化并写出")
+	logBufferSize = flag.Int("log-buffer-size", logger.DefaultAsyncBufferSize, "异步日志缓冲的行数")
+	logOverflow   = flag.String("log-overflow", logger.DropOldest.String(), "异步日志缓冲区满时的处理方式 (drop-oldest: 丢弃最早的行，不阻塞请求; block: 等待写出，不丢日志)")
+	logKeyMode    = flag.String("log-key-mode", "", "日志中缓存 key 的写法 (plain: 原样; hash: 简短的稳定摘要，可用于关联同一个 key 的日志; omit: 不写出)，留空时使用环境变量 GOCACHE_LOG_KEY_MODE，默认 plain")
+)
 
 func main() {
+	startTime := time.Now()
 	flag.Parse()
+	if *logAsync {
+		enableAsyncLog(*logBufferSize, *logOverflow)
+	}
+	setLogKeyMode(*logKeyMode)
+	defer logger.Flush() // 退出前写出异步日志缓冲区中剩余的行
 
-	endpoints := strings.Split(*etcdEndpoints, ",")
-	if len(endpoints) == 0 || endpoints[0] == "" {
-		logger.Fatal("etcd-endpoints 不能为空")
+	var endpoints []string
+	if !*standalone {
+		endpoints = strings.Split(*etcdEndpoints, ",")
+		if len(endpoints) == 0 || endpoints[0] == "" {
+			logger.Fatal("etcd-endpoints 不能为空")
+		}
 	}
 
 	host := *nodeHost
 	if host == "" {
 		var err error
-		host, err = getLocalIP()
+		host, err = discovery.LocalIP()
 		if err != nil {
 			logger.Fatalf("自动获取本地IP失败: %v。请使用 -node-host 指定。", err)
 		}
@@ -81,112 +177,315 @@ func main() {
 	// HTTP地址
 	httpAddr := fmt.Sprintf("%s:%d", host, *httpPort)
 
-	logger.Info("缓存节点启动中...")
-	logger.Infof("Etcd Endpoints: %v", endpoints)
-	logger.Infof("服务名称: %s", *serviceName)
+	logger.Infof("缓存节点启动中，版本 %s", version.Get())
 	logger.Infof("节点gRPC地址: %s", grpcAddr)
 	logger.Infof("节点HTTP地址: %s", httpAddr)
-	logger.Infof("租约 TTL: %ds", *leaseTTL)
-	logger.Infof("API 服务器地址: %s", *apiAddr)
-	logger.Infof("缓存组名称: %s", *groupName)
-	logger.Infof("缓存大小: %d bytes", *cacheSize)
-
-	// 创建ServiceDiscovery实例
-	sd, err := discovery.NewServiceDiscovery(endpoints, *serviceName, grpcAddr, *leaseTTL)
+	advertised, err := discovery.ParseProtocols(*protocols)
 	if err != nil {
-		logger.Fatalf("创建Service Discovery失败: %v", err)
+		logger.Fatalf("无效的 -protocols: %v", err)
 	}
-
-	// 注册服务并启动心跳
-	if err := sd.Register(); err != nil {
-		logger.Fatalf("注册服务失败: %v", err)
+	if *standalone {
+		logger.Info("单机模式：不注册到etcd，不获取节点列表，所有 key 由本节点从数据源加载")
+	} else {
+		logger.Infof("Etcd Endpoints: %v", endpoints)
+		logger.Infof("服务名称: %s", *serviceName)
+		logger.Infof("登记的协议: %v", advertised)
+		logger.Infof("租约 TTL: %ds", *leaseTTL)
+		logger.Infof("API 服务器地址: %s", *apiAddr)
 	}
-	defer func() {
-		logger.Info("开始注销服务...")
-		if err := sd.Unregister(); err != nil {
-			logger.Errorf("注销服务失败: %v", err)
-		} else {
-			logger.Info("服务注销成功")
+
+	// 缓存组配置：配置文件中的 groups，未配置时使用命令行参数描述的单个组。
+	// 签名密钥来自配置文件的 auth，未配置时来自环境变量 GOCACHE_AUTH_KEYS
+	groupConfigs := []config.GroupConfig{flagGroupConfig()}
+	authConfig := config.LoadFromEnv().Auth
+	servable := cache.NewGroupAllowlist(strings.Split(*servableGroups, ",")...)
+	if *configFile != "" {
+		cfg, err := config.LoadFromFile(*configFile)
+		if err != nil {
+			logger.Fatalf("加载配置文件失败: %v", err)
 		}
-		// 确保关闭连接
-		if err := sd.Close(); err != nil {
-			logger.Errorf("关闭etcd连接失败: %v", err)
+		if len(cfg.Groups) > 0 {
+			groupConfigs = cfg.Groups
 		}
-	}()
+		if len(cfg.Auth.Keys) > 0 {
+			authConfig = cfg.Auth
+		}
+		if len(cfg.ServableGroups) > 0 {
+			servable.Set(cfg.ServableGroups...)
+		}
+		if cfg.LogAsync && !*logAsync {
+			size, overflow := *logBufferSize, *logOverflow
+			if cfg.LogBufferSize > 0 {
+				size = cfg.LogBufferSize
+			}
+			if cfg.LogOverflow != "" {
+				overflow = cfg.LogOverflow
+			}
+			enableAsyncLog(size, overflow)
+		}
+		if cfg.LogKeyMode != "" && *logKeyMode == "" {
+			setLogKeyMode(cfg.LogKeyMode)
+		}
+	}
+	signer, verifier, err := auth.FromConfig(authConfig)
+	if err != nil {
+		logger.Fatalf("加载签名密钥失败: %v", err)
+	}
+	if verifier != nil {
+		logger.Infof("已开启内部请求签名，有效密钥数: %d", len(authConfig.Keys))
+	}
 
-	logger.Infof("缓存节点 %s 已成功注册到etcd", grpcAddr)
+	mode, err := cache.ParseMode(*nodeMode)
+	if err != nil {
+		logger.Fatalf("无效的节点模式: %v", err)
+	}
+	cache.SetNodeMode(mode)
+	logger.Infof("节点模式: %s", mode)
 
-	// --- 创建缓存逻辑 ---
-	// 1. 创建缓存组
-	getter := cache.GetterFunc(func(key string) ([]byte, error) {
-		logger.Debugf("[本地数据源] 尝试获取 key: %s", key)
-		if v, ok := db[key]; ok {
-			logger.Debugf("[本地数据源] 找到 key: %s, value: %s", key, v)
-			return []byte(v), nil
-		}
-		logger.Debugf("[本地数据源] 未找到 key: %s", key)
-		return nil, fmt.Errorf("本地未找到 key: %s", key)
-	})
+	// 节点标识，一致性哈希环以它为 key，地址变化不影响 key 的归属
+	id, err := discovery.ResolveNodeID(discovery.NodeIDMode(*nodeIDMode), *nodeID, grpcAddr, *nodeIDFile)
+	if err != nil {
+		logger.Fatalf("确定节点标识失败: %v", err)
+	}
+	logger.Infof("节点标识: %s", id)
 
-	var cacheTTL time.Duration
-	if *ttl > 0 {
-		cacheTTL = time.Duration(*ttl) * time.Second
-	} else {
-		// 如果未设置TTL或为0，则默认使用1小时
-		cacheTTL = time.Hour
-		logger.Infof("未设置缓存TTL，将使用默认值: %v", cacheTTL)
+	hashName, err := consistenthash.ParseHash(*ringHash)
+	if err != nil {
+		logger.Fatalf("无效的一致性哈希函数: %v", err)
+	}
+	logger.Infof("一致性哈希函数: %s", hashName)
+	if names := servable.Names(); names != nil {
+		logger.Infof("允许对外读取的缓存组: %v", names)
 	}
 
-	group := cache.NewGroup(*groupName, *cacheSize, getter, cacheTTL)
-	logger.Infof("已创建缓存组: %s, 大小: %d字节, TTL: %v", *groupName, *cacheSize, cacheTTL)
+	if *selfCheck {
+		runSelfCheck(selfcheck.Config{
+			Addrs:           []string{grpcAddr, httpAddr},
+			EtcdEndpoints:   endpoints, // 单机模式下为空，跳过 etcd 检查
+			EtcdDialTimeout: *etcdDialTimeout,
+			ServiceName:     *serviceName,
+			NodeID:          id,
+		})
+	}
 
-	// 2. 创建 HTTP Pool，显式设置 Protobuf 协议
+	// 1. 创建 HTTP Pool，显式设置 Protobuf 协议
 	pool := server.NewHTTPPool(httpAddr,
+		server.WithSelfID(id),                        // 与注册的节点标识一致
 		server.WithProtocol(server.ProtocolProtobuf), // 明确指定 Protobuf 协议
+		server.WithPeerTimeout(*peerTimeout),
+		server.WithPeerLimit(peerproto.Limit{MaxInFlight: *peerMaxInFlight, QueueWait: *peerQueueWait}),
+		server.WithShutdownTimeout(*shutdownTimeout),
+		server.WithRingHash(hashName), // 与 API 服务器的 -ring-hash 一致
+		server.WithMaxHops(*maxHops),
+		server.WithSigner(signer),     // 对发往其他节点的请求签名
+		server.WithVerifier(verifier), // 校验 API 服务器和其他节点的请求签名
+		server.WithGroupAllowlist(servable),
+		server.WithExposeIdentity(*exposeID),
 	)
 
-	// 3. 注册 PeerPicker
-	group.RegisterPeers(pool)
+	// 删除发往归属节点失败时进入重试队列，归属节点恢复后补上删除；单机模式下没有其他归属节点
+	var deletes *deletequeue.Queue
+	if *deleteRetryMaxSize > 0 && !*standalone {
+		opts := []deletequeue.Option{
+			deletequeue.WithMaxSize(*deleteRetryMaxSize),
+			deletequeue.WithMaxAge(*deleteRetryMaxAge),
+		}
+		if *deleteJournal != "" {
+			opts = append(opts, deletequeue.WithJournal(*deleteJournal))
+		}
+		deletes, err = deletequeue.New(cache.DeleteRetrySender(cache.GetGroup), opts...)
+		if err != nil {
+			logger.Fatalf("创建删除重试队列失败: %v", err)
+		}
+		defer deletes.Close() // 在服务器停止之后关闭，开启了删除日志时未完成的删除留给下次启动
+	}
+
+	valueCipher, err := loadValueCipher(groupConfigs, *valueKeyFile)
+	if err != nil {
+		logger.Fatalf("加载值加密密钥失败: %v", err)
+	}
+
+	var manifest []server.PrimeKey
+	primeReadiness := server.PrimeReadyOff
+	if *primeFile != "" {
+		manifest, err = server.LoadPrimeManifest(*primeFile, groupConfigs[0].Name)
+		if err != nil {
+			logger.Fatalf("读取预加载清单失败: %v", err)
+		}
+		primeReadiness, err = server.ParsePrimeReadiness(*primeReady)
+		if err != nil {
+			logger.Fatalf("无效的 -prime-ready: %v", err)
+		}
+		logger.Infof("预加载清单 %s 包含 %d 个 key，/ready 等待方式: %s", *primeFile, len(manifest), primeReadiness)
+	}
+
+	// 从本地缓存移除的 key 批量通知给数据源，关闭时在服务器停止之后发出剩余的通知
+	var notifier cache.Notifier
+	var batcher *evictnotify.Batcher
+	if *evictNotifyURL != "" {
+		batcher = evictnotify.New(evictnotify.NewHTTPSink(*evictNotifyURL),
+			evictnotify.WithWindow(*evictNotifyWindow),
+			evictnotify.WithMaxBatch(*evictNotifyBatch),
+			evictnotify.WithQueueSize(*evictNotifyQueue),
+		)
+		notifier = batcher
+		defer closeEvictNotifier(batcher, *shutdownTimeout)
+		logger.Infof("已开启淘汰通知: %s", *evictNotifyURL)
+	}
+
+	// 2. 创建所有缓存组并注册 PeerPicker，在注册到 etcd 之前完成，使登记的组列表从一开始就完整。
+	// 单机模式下不注册 PeerPicker，缓存组直接从数据源加载，pool 只负责 HTTP 协议的请求
+	var picker peerproto.PeerPicker = pool
+	if *standalone {
+		picker = nil
+	}
+	groups, err := createGroups(groupConfigs, picker, deletes, valueCipher, notifier)
+	if err != nil {
+		logger.Fatalf("创建缓存组失败: %v", err)
+	}
+	// 服务器停止之后关闭缓存组，等待后台清理、提前刷新和热点复制退出，再发出剩余的淘汰通知
+	defer closeGroups(groups)
+	cache.SetNodeRateLimit(cache.RateLimit{Rate: *nodeRateLimit, Burst: *nodeRateBurst})
 
-	// 4. 创建和启动 gRPC 服务器
-	grpcServer := grpc.NewCacheServer(grpcAddr)
+	// 3-4. 准备注册到etcd和获取节点列表，服务器启动后开始注册，单机模式下都跳过
+	var (
+		sd        *discovery.ServiceDiscovery
+		updater   *peers.Updater
+		publisher *registrationPublisher
+		regState  *registrationState
+	)
+	if !*standalone {
+		var stop func()
+		sd, updater, publisher, stop = joinCluster(endpoints, grpcAddr, httpAddr, id, advertised, pool)
+		defer stop()
+		regState = &registrationState{required: *requireRegistration}
+	}
+	// 生效的配置与构建信息，由 HTTP 的 /api/admin/info 和 gRPC 的 Info 返回，敏感值统一脱敏
+	info := &admin.InfoSource{
+		Component: "cachenode",
+		StartTime: startTime,
+		Config:    nodeConfig(groupConfigs, authConfig),
+		Discovery: func() admin.DiscoveryInfo {
+			if updater == nil {
+				return admin.DiscoveryInfo{Mode: "standalone", State: "standalone"}
+			}
+			if !regState.Registered() {
+				return admin.DiscoveryInfo{Mode: *peerSource, State: "registering"}
+			}
+			return admin.DiscoveryInfo{Mode: *peerSource, State: updater.Status().State()}
+		},
+		ServableGroups: servable.Names,
+	}
+
+	// 5. 创建和启动 gRPC 服务器
+	grpcServer := grpc.NewCacheServer(grpcAddr,
+		grpc.WithPeerStats(pool.PeerStats), // 在 Stats 响应中报告发往各对等节点的请求统计
+		grpc.WithNodeID(id),
+		grpc.WithExposeIdentity(*exposeID),
+		grpc.WithMaxHops(*maxHops),
+		grpc.WithVerifier(verifier),
+		grpc.WithInfo(info.Info),
+		grpc.WithGroupAllowlist(servable),    // 与 HTTP Pool 共用，重新加载后两种协议同时生效
+		grpc.WithOwnedLister(pool.ListOwned), // 按 HTTP Pool 的哈希环列出归属其他节点的 key
+		grpc.WithWarmupStats(pool.WarmupStats),
+		grpc.WithPrimeStats(pool.PrimeStats),
+		grpc.WithRingGeneration(pool.RingGeneration),
+	)
 	if err := grpcServer.Start(); err != nil {
 		logger.Fatalf("启动gRPC服务器失败: %v", err)
 	}
 	defer grpcServer.Stop()
 
-	// 5. 创建和启动 HTTP 服务器 (提供API接口)
-	httpServer := httpserver.NewServer(httpAddr)
+	// 6. 创建和启动 HTTP 服务器 (提供API接口)
+	exposedID := ""
+	if *exposeID {
+		exposedID = id
+	}
+	// 单机模式下两者都为 nil：没有需要更新的注册信息，/health 和 /ready 不检查节点列表
+	var (
+		modeChanged func()
+		peerStatus  func() peers.Status
+	)
+	if !*standalone {
+		modeChanged, peerStatus = publisher.Notify, updater.Status
+	}
+	httpOpts := []httpserver.ServerOption{
+		httpserver.WithAdminToken(*adminToken),
+		httpserver.WithNodeID(exposedID), // 为空时不返回 X-GoCache-Node
+		httpserver.WithAdminRateLimit(*adminRateLimit),
+		httpserver.WithAdminAddr(*adminAddr),       // 为空时所有路由共用 HTTP 端口
+		httpserver.WithModeChangeHook(modeChanged), // 模式变化后尽快更新注册信息
+		httpserver.WithPeerStatus(peerStatus),      // 在 /status、/health 和 /ready 中反映节点列表的状态
+		httpserver.WithMaxPeerSyncAge(*maxPeerSyncAge),
+		httpserver.WithHandler(pool.BasePath(), pool), // 节点间通信与 API 服务器的 HTTP 协议路径
+		httpserver.WithRing(pool.Ring),                // 在 /api/admin/ring 中报告节点间路由的哈希环
+		httpserver.WithInfo(info.Info),
+		httpserver.WithReadyCheck(func() bool { return manifest == nil || pool.PrimeReady(primeReadiness) }),
+		evictNotifyHealthCheck(batcher), // 开启淘汰通知时在 /health 中报告发送状态
+	}
+	httpOpts = append(httpOpts, regState.serverOptions()...) // 在 /health 和 /ready 中反映注册进度
+	httpServer := httpserver.NewServer(httpAddr, httpOpts...)
 	if err := httpServer.Start(); err != nil {
 		logger.Fatalf("启动HTTP服务器失败: %v", err)
 	}
-	defer httpServer.Stop()
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), *shutdownTimeout)
+		defer cancel()
+		if err := httpServer.Shutdown(ctx); err != nil {
+			logger.Warnf("关闭HTTP服务器: %v", err)
+		}
+	}()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 
-	// 6. 定期从 API Server 更新 Peer 列表
+	// 7. 注册到etcd，成功后定期更新 Peer 列表；收到停止信号时 ctx 被取消，注册重试随之停止
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel() // 确保在退出时停止更新goroutine
-
-	go func(ctx context.Context) {
-		ticker := time.NewTicker(5 * time.Second) // 每5秒更新一次
-		defer ticker.Stop()
-		updatePeers(pool, *apiAddr) // 初始更新一次
-		for {
-			select {
-			case <-ticker.C:
-				updatePeers(pool, *apiAddr)
-			case <-ctx.Done():
-				logger.Info("停止更新 peer 列表")
-				return
-			}
+	registerFailed := make(chan error, 1)
+	if !*standalone {
+		budget := registerBudget{attempt: *etcdDialTimeout}
+		if *requireRegistration {
+			budget.attempts, budget.total = *registerAttempts, *registerTimeout
 		}
-	}(ctx)
+		go runWhenRegistered(ctx, sd, budget, regState, updater, publisher, registerFailed)
+	}
+	if *configFile != "" {
+		go reloadServableOnHangup(servable, *configFile)
+	}
+	switch {
+	case *warmup && *standalone:
+		logger.Warn("单机模式没有其他节点，忽略 -warmup")
+	case *warmup:
+		// 关闭时 ctx 被取消，未完成的预热随之停止
+		go warmUpWhenOnRing(ctx, pool, updater, id, server.WarmupOptions{
+			MaxKeys:  *warmupKeys,
+			MaxBytes: *warmupBytes,
+			Timeout:  *warmupTimeout,
+			Rate:     *warmupRate,
+		})
+	}
+	if manifest != nil {
+		opts := server.PrimeOptions{
+			Timeout:     *primeTimeout,
+			Concurrency: *primeConcurrency,
+		}
+		if *standalone {
+			// 没有哈希环，所有 key 都归本节点，立即预加载
+			go prime(ctx, pool, manifest, opts)
+		} else {
+			go primeWhenOnRing(ctx, pool, updater, id, manifest, opts)
+		}
+	}
 
 	logger.Infof("缓存节点已启动，提供 gRPC 服务于 %s 和 HTTP 服务于 %s", grpcAddr, httpAddr)
 
-	// 优雅关机处理
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit // 阻塞直到接收到停止信号
+	// 优雅关机处理：阻塞直到接收到停止信号，要求注册而重试预算用完时退出
+	select {
+	case <-quit:
+	case err := <-registerFailed:
+		logger.Fatalf("注册服务失败: %v", err)
+	}
 
 	logger.Info("收到停止信号，缓存节点开始关闭...")
 	cancel() // 停止 peer 更新 goroutine
@@ -195,56 +494,213 @@ func main() {
 	logger.Info("缓存节点已关闭")
 }
 
-// --- 更新 Peer 列表的函数 ---
-func updatePeers(pool *server.HTTPPool, apiAddr string) {
-	// 构建API地址
-	peerURL := fmt.Sprintf("http://%s/peers", apiAddr)
-	resp, err := http.Get(peerURL)
+// joinCluster 创建服务注册和节点列表更新器，返回的 stop 在退出时关闭节点监视并注销服务。
+// 注册（见 runWhenRegistered）、更新器和发布器由调用方在服务器启动后运行
+func joinCluster(endpoints []string, grpcAddr, httpAddr, id string, advertised []string,
+	pool *server.HTTPPool) (*discovery.ServiceDiscovery, *peers.Updater, *registrationPublisher, func()) {
+	// 3. 创建ServiceDiscovery实例
+	sd, err := discovery.NewServiceDiscovery(endpoints, *serviceName, grpcAddr, *leaseTTL,
+		discovery.WithDialTimeout(*etcdDialTimeout),
+		discovery.WithHTTPAddr(httpAddr), // 同时登记 HTTP 地址，供 HTTP 协议的 API 服务器使用
+		discovery.WithProtocols(advertised...),
+		discovery.WithNodeID(id),
+		discovery.WithGroups(cache.GroupNames), // 登记本节点的缓存组，供 API 服务器在本地校验组名
+		discovery.WithMode(func() string { return cache.NodeMode().String() }),
+	)
 	if err != nil {
-		logger.Errorf("从 API Server (%s) 获取 peers 失败: %v", peerURL, err)
-		return
+		logger.Fatalf("创建Service Discovery失败: %v", err)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		logger.Errorf("从 API Server (%s) 获取 peers 失败，状态码: %d", peerURL, resp.StatusCode)
-		return
+	var watcher *discovery.ServiceWatcher
+	stop := func() {
+		if watcher != nil {
+			watcher.Close()
+		}
+		logger.Info("开始注销服务...")
+		if err := sd.Unregister(); err != nil {
+			logger.Errorf("注销服务失败: %v", err)
+		} else {
+			logger.Info("服务注销成功")
+		}
+		// 确保关闭连接
+		if err := sd.Close(); err != nil {
+			logger.Errorf("关闭etcd连接失败: %v", err)
+		}
+	}
+
+	// 4. 节点列表更新器：从 API Server 的 /peers 或直接从 etcd 获取节点列表
+	var source peers.Source
+	switch *peerSource {
+	case "api":
+		source = peers.NewHTTPSource(*apiAddr)
+	case "etcd":
+		watcher, err = discovery.NewServiceWatcher(endpoints, *serviceName, discovery.WithDialTimeout(*etcdDialTimeout))
+		if err != nil {
+			logger.Fatalf("创建节点监视失败: %v", err)
+		}
+		source = peers.NewEtcdSource(watcher)
+	default:
+		logger.Fatalf("不支持的节点列表来源: %s，只能是 api 或 etcd", *peerSource)
 	}
+	updater := peers.NewUpdater(source, peers.PoolApplier(pool),
+		peers.WithInterval(*peerUpdateInterval),
+		peers.WithTimeout(*peerTimeout),
+		peers.WithDeltaApplier(peers.PoolDeltaApplier(pool)),
+	)
+	if *seedPeers != "" {
+		seeds, err := discovery.ParseSeeds(strings.Split(*seedPeers, ","))
+		if err != nil {
+			logger.Fatalf("解析种子节点失败: %v", err)
+		}
+		// 服务发现收敛之前先使用种子节点，避免启动窗口内所有请求都回源
+		updater.Seed(seeds)
+	}
+
+	// 节点对外公布的状态变化时重新发布注册信息，最多每秒一次
+	return sd, updater, newRegistrationPublisher(sd), stop
+}
+
+// enableAsyncLog 开启异步日志
+func enableAsyncLog(size int, overflow string) {
+	policy, err := logger.ParseOverflow(overflow)
+	if err != nil {
+		logger.Fatalf("无效的异步日志配置: %v", err)
+	}
+	logger.EnableAsync(logger.AsyncOptions{BufferSize: size, Overflow: policy})
+	logger.Infof("已开启异步日志，缓冲 %d 行，缓冲区满时 %s", size, policy)
+}
+
+// setLogKeyMode 设置日志中缓存 key 的写法，name 为空时使用环境变量 GOCACHE_LOG_KEY_MODE
+func setLogKeyMode(name string) {
+	if name == "" {
+		name = config.LoadFromEnv().LogKeyMode
+	}
+	mode, err := logger.ParseKeyMode(name)
+	if err != nil {
+		logger.Fatalf("无效的 -log-key-mode: %v", err)
+	}
+	logger.SetKeyMode(mode)
+	if mode != logger.KeyPlain {
+		logger.Infof("日志中的缓存 key 写法: %s", mode)
+	}
+}
 
-	// 读取响应体
-	bodyBytes, err := io.ReadAll(resp.Body)
+// runSelfCheck 执行启动自检并输出检查清单，任何一项失败时退出
+func runSelfCheck(cfg selfcheck.Config) {
+	skip, err := selfcheck.ParseSkip(*selfCheckSkip)
 	if err != nil {
-		logger.Errorf("读取 API Server (%s) 响应失败: %v", peerURL, err)
+		logger.Fatalf("无效的自检参数: %v", err)
+	}
+	results := selfcheck.Run(context.Background(), selfcheck.Checks(cfg), skip, *selfCheckTimeout)
+	selfcheck.Log(results)
+	if failed := selfcheck.Failed(results); len(failed) > 0 {
+		logger.Fatalf("启动自检失败 %d 项，首个失败: %s %s: %s。%s",
+			len(failed), failed[0].Name, failed[0].Target, failed[0].Error, failed[0].Hint)
+	}
+}
+
+// waitOnRing 等到节点列表同步完成、本节点出现在哈希环上，ctx 取消时返回 false
+func waitOnRing(ctx context.Context, pool *server.HTTPPool, updater *peers.Updater, id string) bool {
+	ticker := time.NewTicker(200 * time.Millisecond)
+	defer ticker.Stop()
+	for updater.Status().State() != "synced" || !slices.Contains(pool.Peers(), id) {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return false
+		}
+	}
+	return true
+}
+
+// warmUpWhenOnRing 等到本节点出现在哈希环上之后执行一次预热
+func warmUpWhenOnRing(ctx context.Context, pool *server.HTTPPool, updater *peers.Updater, id string, opts server.WarmupOptions) {
+	if !waitOnRing(ctx, pool, updater, id) {
 		return
 	}
+	if err := pool.WarmUp(ctx, opts); err != nil {
+		logger.Warnf("预热未完成: %v", err)
+	}
+}
 
-	// 尝试使用JSON解析
-	var result struct {
-		Peers []string `json:"peers"`
+// primeWhenOnRing 等到本节点出现在哈希环上、能判断 key 的归属之后按清单预加载一次
+func primeWhenOnRing(ctx context.Context, pool *server.HTTPPool, updater *peers.Updater, id string, manifest []server.PrimeKey, opts server.PrimeOptions) {
+	if !waitOnRing(ctx, pool, updater, id) {
+		return
 	}
-	if err := json.Unmarshal(bodyBytes, &result); err != nil {
-		logger.Warnf("解析JSON响应失败: %v，尝试使用旧的解析方式", err)
+	prime(ctx, pool, manifest, opts)
+}
 
-		// 兼容旧的解析逻辑
-		bodyString := string(bodyBytes)
-		bodyString = strings.TrimPrefix(bodyString, `{"peers": ["`)
-		bodyString = strings.TrimSuffix(bodyString, `"]}`)
-		if bodyString == "" { // 处理空列表的情况
-			pool.Set() // 设置为空列表
-			logger.Info("从 API Server 获取到空的 peer 列表")
-			return
+// prime 按清单预加载一次
+func prime(ctx context.Context, pool *server.HTTPPool, manifest []server.PrimeKey, opts server.PrimeOptions) {
+	if err := pool.Prime(ctx, manifest, opts); err != nil {
+		logger.Warnf("按清单预加载未完成: %v", err)
+	}
+}
+
+// reloadServableOnHangup 每次收到 SIGHUP 时从配置文件重新加载允许对外读取的缓存组，
+// 文件无效时保留当前列表。与启动时相同，配置文件中没有 servable_groups 时使用 -servable-groups
+func reloadServableOnHangup(servable *cache.GroupAllowlist, path string) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	for range hup {
+		cfg, err := config.LoadFromFile(path)
+		if err != nil {
+			logger.Errorf("重新加载配置文件失败，继续使用当前的缓存组允许列表: %v", err)
+			continue
+		}
+		names := cfg.ServableGroups
+		if len(names) == 0 {
+			names = strings.Split(*servableGroups, ",")
 		}
-		result.Peers = strings.Split(bodyString, `", "`)
+		servable.Set(names...)
+		logger.Infof("已重新加载允许对外读取的缓存组: %v（为空表示不限制）", servable.Names())
 	}
+}
 
-	// 如果获取到了peers列表
-	if len(result.Peers) > 0 {
-		// 更新 Pool 的 peers
-		pool.Set(result.Peers...) // 使用解构赋值传入 slice
-		logger.Infof("从 API Server (%s) 更新 peer 列表: %v", peerURL, result.Peers)
-	} else {
-		// 空列表情况
-		pool.Set() // 设置为空列表
-		logger.Info("从 API Server 获取到空的 peer 列表")
+// nodeConfig 返回节点生效的配置：所有命令行参数、缓存组和签名配置，未脱敏
+func nodeConfig(groups []config.GroupConfig, authConfig config.AuthConfig) map[string]string {
+	cfg := admin.FlagConfig(flag.CommandLine)
+	names := make([]string, 0, len(groups))
+	for _, g := range groups {
+		names = append(names, g.Name)
+	}
+	cfg["groups"] = strings.Join(names, ",")
+	cfg["auth.keys"] = strings.Join(authConfig.Keys, ",")
+	cfg["auth.clock_skew"] = authConfig.ClockSkew.Std().String()
+	cfg["auth.nonce_cache_size"] = strconv.Itoa(authConfig.NonceCacheSize)
+	cfg["auth.max_body_bytes"] = strconv.FormatInt(authConfig.MaxBodyBytes, 10)
+	return cfg
+}
+
+// evictNotifyHealthCheck 在 /health 中报告淘汰通知的计数，最近一次发送失败时为 degraded；
+// 未开启淘汰通知时不增加检查项
+func evictNotifyHealthCheck(b *evictnotify.Batcher) httpserver.ServerOption {
+	if b == nil {
+		return func(*httpserver.Server) {}
+	}
+	return httpserver.WithHealthCheck("evict-notify", false, func() health.Result {
+		s := b.Stats()
+		details := map[string]interface{}{
+			"notified": s.Notified,
+			"sent":     s.Sent,
+			"dropped":  s.Dropped,
+			"depth":    s.Depth,
+		}
+		if s.LastError != "" {
+			return health.Degraded("发送淘汰通知失败: "+s.LastError, details)
+		}
+		return health.OK(details)
+	})
+}
+
+// closeEvictNotifier 在 timeout 内发出剩余的淘汰通知，并记录最终的计数
+func closeEvictNotifier(b *evictnotify.Batcher, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := b.Close(ctx); err != nil {
+		logger.Warnf("发送剩余的淘汰通知超时: %v", err)
 	}
+	s := b.Stats()
+	logger.Infof("淘汰通知已关闭: 通知 %d，发送 %d，丢弃 %d", s.Notified, s.Sent, s.Dropped)
 }
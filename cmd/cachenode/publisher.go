@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/pkg/logger"
+)
+
+// publishInterval 两次重新发布注册信息之间的最短间隔
+const publishInterval = time.Second
+
+// registration 注册信息的来源和写入方式，由 discovery.ServiceDiscovery 实现
+type registration interface {
+	Advertised() string
+	UpdateValue(newValue string) error
+}
+
+// registrationPublisher 在节点对外公布的状态（缓存组、模式等）变化时重新发布etcd中的注册值。
+// 每秒检查一次，Notify 可以让变化尽快发布；连续的变化合并，最多每秒写入一次
+type registrationPublisher struct {
+	reg    registration
+	notify chan struct{}
+}
+
+// newRegistrationPublisher 创建发布器，需要调用 Run 才开始工作
+func newRegistrationPublisher(reg registration) *registrationPublisher {
+	return &registrationPublisher{reg: reg, notify: make(chan struct{}, 1)}
+}
+
+// Notify 通知发布器状态可能已经变化，不会阻塞
+func (p *registrationPublisher) Notify() {
+	select {
+	case p.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Run 持续发布注册信息，直到 ctx 取消
+func (p *registrationPublisher) Run(ctx context.Context) {
+	ticker := time.NewTicker(publishInterval)
+	defer ticker.Stop()
+
+	var (
+		published string    // 上次成功写入的值
+		last      time.Time // 上次成功写入的时间
+		failing   bool
+	)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-p.notify:
+		case <-ticker.C:
+		}
+		// 距上次写入不足一秒时留给下一次检查，期间的变化合并为一次写入
+		if time.Since(last) < publishInterval {
+			continue
+		}
+
+		value := p.reg.Advertised()
+		if value == published {
+			continue
+		}
+		if err := p.reg.UpdateValue(value); err != nil {
+			if !failing {
+				logger.Warnf("重新发布注册信息失败，将在下次检查时重试: %v", err)
+			}
+			failing = true
+			continue
+		}
+		if failing {
+			logger.Info("重新发布注册信息已恢复")
+		}
+		failing = false
+		published, last = value, time.Now()
+	}
+}
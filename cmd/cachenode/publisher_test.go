@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRegistration 内存中的 registration，记录每次写入的值和时间
+type fakeRegistration struct {
+	mu         sync.Mutex
+	advertised string
+	fail       int // 之后的写入中失败的次数
+	writes     []string
+	times      []time.Time
+}
+
+func (r *fakeRegistration) Advertised() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.advertised
+}
+
+func (r *fakeRegistration) UpdateValue(v string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.fail > 0 {
+		r.fail--
+		return errors.New("etcd unavailable")
+	}
+	r.writes = append(r.writes, v)
+	r.times = append(r.times, time.Now())
+	return nil
+}
+
+func (r *fakeRegistration) set(v string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.advertised = v
+}
+
+// waitWrites 等待写入次数达到 n，返回写入的值和时间
+func (r *fakeRegistration) waitWrites(t *testing.T, n int) ([]string, []time.Time) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		r.mu.Lock()
+		if len(r.writes) >= n {
+			writes, times := append([]string(nil), r.writes...), append([]time.Time(nil), r.times...)
+			r.mu.Unlock()
+			return writes, times
+		}
+		r.mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("等待第 %d 次写入超时", n)
+	return nil, nil
+}
+
+// TestPublisherDebounces 第一次变化在 Notify 后立即发布，一秒内的后续变化合并为一次写入
+func TestPublisherDebounces(t *testing.T) {
+	reg := &fakeRegistration{advertised: "v1"}
+	p := newRegistrationPublisher(reg)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go p.Run(ctx)
+
+	p.Notify()
+	writes, _ := reg.waitWrites(t, 1)
+	if writes[0] != "v1" {
+		t.Fatalf("第一次写入 = %q, want v1", writes[0])
+	}
+
+	for _, v := range []string{"v2", "v3", "v4"} {
+		reg.set(v)
+		p.Notify()
+		time.Sleep(20 * time.Millisecond)
+	}
+	writes, times := reg.waitWrites(t, 2)
+	if writes[1] != "v4" {
+		t.Fatalf("合并后的写入 = %q, want v4", writes[1])
+	}
+	if gap := times[1].Sub(times[0]); gap < publishInterval {
+		t.Fatalf("两次写入间隔 %v，小于 %v", gap, publishInterval)
+	}
+
+	// 值不变时不再写入
+	time.Sleep(publishInterval + 200*time.Millisecond)
+	reg.mu.Lock()
+	n := len(reg.writes)
+	reg.mu.Unlock()
+	if n != 2 {
+		t.Fatalf("写入了 %d 次，want 2: %v", n, reg.writes)
+	}
+}
+
+// TestPublisherRetriesFailedWrite 写入失败后在下次检查时重试
+func TestPublisherRetriesFailedWrite(t *testing.T) {
+	reg := &fakeRegistration{advertised: "v1", fail: 1}
+	p := newRegistrationPublisher(reg)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go p.Run(ctx)
+
+	p.Notify()
+	if writes, _ := reg.waitWrites(t, 1); writes[0] != "v1" {
+		t.Fatalf("重试后的写入 = %q, want v1", writes[0])
+	}
+}
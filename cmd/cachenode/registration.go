@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/internal/cache"
+	httpserver "github.com/AdrianWangs/go-cache/internal/cachenode/http"
+	"github.com/AdrianWangs/go-cache/internal/cachenode/peers"
+	"github.com/AdrianWangs/go-cache/internal/health"
+	"github.com/AdrianWangs/go-cache/pkg/logger"
+)
+
+const (
+	registerInitialBackoff = 500 * time.Millisecond // 第一次注册失败后的等待时间
+	registerMaxBackoff     = 30 * time.Second       // 注册重试等待时间的上限
+)
+
+// registrar 注册到服务发现，由 discovery.ServiceDiscovery 实现
+type registrar interface {
+	RegisterWithContext(ctx context.Context) error
+}
+
+// registerBudget 启动时注册的重试预算，attempts 和 total 为 0 时不限制
+type registerBudget struct {
+	attempts int           // 最多尝试次数
+	total    time.Duration // 从第一次尝试算起的总时长
+	attempt  time.Duration // 单次尝试的超时
+	backoff  time.Duration // 第一次失败后的等待时间，0 时使用 registerInitialBackoff
+}
+
+// registrationState 启动时注册的进度，供 /health、/ready 和 /api/admin/info 查询
+type registrationState struct {
+	required bool // 是否要求注册成功才就绪，见 -require-registration
+
+	mu         sync.Mutex
+	registered bool
+	attempts   int
+	lastError  string
+}
+
+// Registered 报告是否已注册成功
+func (s *registrationState) Registered() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.registered
+}
+
+// record 记录一次尝试的结果
+func (s *registrationState) record(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attempts++
+	if err != nil {
+		s.lastError = err.Error()
+		return
+	}
+	s.registered, s.lastError = true, ""
+}
+
+// check 是 /health 的 registration 组件：注册成功前为 degraded；
+// 要求注册时是关键组件，同时 /ready 在注册成功前返回 503
+func (s *registrationState) check() health.Result {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	details := map[string]interface{}{
+		"registered": s.registered,
+		"required":   s.required,
+		"attempts":   s.attempts,
+	}
+	if s.registered {
+		return health.OK(details)
+	}
+	if s.lastError != "" {
+		details["last_error"] = s.lastError
+	}
+	if s.required {
+		return health.Degraded("尚未注册到etcd，正在重试", details)
+	}
+	return health.Degraded("尚未注册到etcd，按单机模式在本地提供服务并在后台重试", details)
+}
+
+// serverOptions 返回在 /health 和 /ready 中反映注册进度的选项；不要求注册时，
+// 注册成功前不检查节点列表，与单机运行相同。单机模式（s 为 nil）下不增加检查项
+func (s *registrationState) serverOptions() []httpserver.ServerOption {
+	if s == nil {
+		return nil
+	}
+	opts := []httpserver.ServerOption{httpserver.WithHealthCheck("registration", s.required, s.check)}
+	if s.required {
+		return append(opts, httpserver.WithReadyCheck(s.Registered))
+	}
+	return append(opts, httpserver.WithPeersActive(s.Registered))
+}
+
+// register 按预算重试注册，两次尝试之间按带抖动的指数退避等待。
+// 成功时返回 nil；预算用完时返回最后一次的错误；ctx 取消（收到停止信号）时立即返回 ctx.Err()
+func register(ctx context.Context, r registrar, budget registerBudget, state *registrationState) error {
+	var deadline time.Time
+	if budget.total > 0 {
+		deadline = time.Now().Add(budget.total)
+	}
+	backoff := budget.backoff
+	if backoff <= 0 {
+		backoff = registerInitialBackoff
+	}
+	for attempt := 1; ; attempt++ {
+		err := registerOnce(ctx, r, budget.attempt)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		state.record(err)
+		if err == nil {
+			return nil
+		}
+
+		wait := backoff/2 + rand.N(backoff/2) // 抖动避免整个集群重启时所有节点同时重试
+		if budget.attempts > 0 && attempt >= budget.attempts {
+			return fmt.Errorf("已尝试 %d 次: %w", attempt, err)
+		}
+		if !deadline.IsZero() {
+			left := time.Until(deadline)
+			if left <= 0 {
+				return fmt.Errorf("%v 内尝试了 %d 次: %w", budget.total, attempt, err)
+			}
+			wait = min(wait, left)
+		}
+		logger.Warnf("注册到etcd失败（第 %d 次），%v 后重试: %v", attempt, wait.Round(time.Millisecond), err)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		backoff = min(backoff*2, registerMaxBackoff)
+	}
+}
+
+// registerOnce 进行一次注册，timeout 大于 0 时限定这次尝试的时长
+func registerOnce(ctx context.Context, r registrar, timeout time.Duration) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return r.RegisterWithContext(ctx)
+}
+
+// runWhenRegistered 注册成功后运行节点列表更新器和注册信息发布器，直到 ctx 取消。
+// 要求注册而预算用完时把错误发到 failed，由 main 退出；收到停止信号时直接返回
+func runWhenRegistered(ctx context.Context, r registrar, budget registerBudget, state *registrationState,
+	updater *peers.Updater, publisher *registrationPublisher, failed chan<- error) {
+	if err := register(ctx, r, budget, state); err != nil {
+		if ctx.Err() == nil {
+			failed <- err
+		}
+		return
+	}
+	logger.Infof("缓存节点已注册到etcd，缓存组: %v，开始同步节点列表", cache.GroupNames())
+	go updater.Run(ctx)
+	publisher.Run(ctx)
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/internal/cache"
+	httpserver "github.com/AdrianWangs/go-cache/internal/cachenode/http"
+	"github.com/AdrianWangs/go-cache/internal/cachenode/peers"
+	"github.com/AdrianWangs/go-cache/internal/health"
+)
+
+var errEtcdDown = errors.New("etcd unavailable")
+
+// fakeRegistrar 前 failures 次注册失败，之后成功；block 为 true 时每次尝试都等到 ctx 结束
+type fakeRegistrar struct {
+	failures int
+	block    bool
+
+	mu    sync.Mutex
+	calls []time.Time
+}
+
+func (r *fakeRegistrar) RegisterWithContext(ctx context.Context) error {
+	r.mu.Lock()
+	r.calls = append(r.calls, time.Now())
+	n := len(r.calls)
+	r.mu.Unlock()
+	if r.block {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	if n <= r.failures {
+		return errEtcdDown
+	}
+	return nil
+}
+
+// attempts 返回注册的尝试次数
+func (r *fakeRegistrar) attempts() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.calls)
+}
+
+// gaps 返回相邻两次尝试之间的间隔
+func (r *fakeRegistrar) gaps() []time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var gaps []time.Duration
+	for i := 1; i < len(r.calls); i++ {
+		gaps = append(gaps, r.calls[i].Sub(r.calls[i-1]))
+	}
+	return gaps
+}
+
+// TestRegisterEventualSuccess 前几次失败后注册成功，两次尝试之间的等待按带抖动的指数退避增长
+func TestRegisterEventualSuccess(t *testing.T) {
+	r := &fakeRegistrar{failures: 3}
+	state := &registrationState{required: true}
+	if err := register(context.Background(), r, registerBudget{attempts: 10, backoff: 20 * time.Millisecond}, state); err != nil {
+		t.Fatalf("register = %v", err)
+	}
+	if r.attempts() != 4 || !state.Registered() {
+		t.Fatalf("尝试了 %d 次，已注册 = %v", r.attempts(), state.Registered())
+	}
+	// 第 n 次等待在 [backoff/2, backoff) 之间，backoff 从 20ms 开始逐次翻倍
+	for i, gap := range r.gaps() {
+		backoff := 20 * time.Millisecond << i
+		if gap < backoff/2 || gap > backoff+100*time.Millisecond {
+			t.Errorf("第 %d 次等待 %v, want [%v, %v)", i+1, gap, backoff/2, backoff)
+		}
+	}
+	if res := state.check(); res.Status != health.StatusOK || res.Details["attempts"] != 4 {
+		t.Fatalf("registration = %+v", res)
+	}
+}
+
+func TestRegisterExhausted(t *testing.T) {
+	tests := []struct {
+		name    string
+		budget  registerBudget
+		want    string // 错误信息的前缀
+		minCall int
+		maxCall int
+	}{
+		{"次数用完", registerBudget{attempts: 3, backoff: time.Millisecond}, "已尝试 3 次", 3, 3},
+		{"总时长用完", registerBudget{total: 100 * time.Millisecond, backoff: 10 * time.Millisecond}, "100ms 内尝试了", 2, 8},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &fakeRegistrar{failures: 1 << 30}
+			state := &registrationState{required: true}
+			start := time.Now()
+			err := register(context.Background(), r, tt.budget, state)
+			if !errors.Is(err, errEtcdDown) || !strings.HasPrefix(err.Error(), tt.want) {
+				t.Fatalf("register = %v, want %q 开头并包装最后一次的错误", err, tt.want)
+			}
+			if n := r.attempts(); n < tt.minCall || n > tt.maxCall {
+				t.Fatalf("尝试了 %d 次, want [%d, %d]", n, tt.minCall, tt.maxCall)
+			}
+			if tt.budget.total > 0 && time.Since(start) > tt.budget.total+time.Second {
+				t.Fatalf("总时长 %v 的预算在 %v 后才用完", tt.budget.total, time.Since(start))
+			}
+			res := state.check()
+			if state.Registered() || res.Status != health.StatusDegraded || res.Details["last_error"] != errEtcdDown.Error() {
+				t.Fatalf("registration = %+v", res)
+			}
+		})
+	}
+}
+
+// TestRegisterAttemptTimeout etcd 不响应时每次尝试在 budget.attempt 后放弃
+func TestRegisterAttemptTimeout(t *testing.T) {
+	r := &fakeRegistrar{block: true}
+	start := time.Now()
+	err := register(context.Background(), r, registerBudget{attempts: 2, attempt: 50 * time.Millisecond, backoff: time.Millisecond}, &registrationState{})
+	if !errors.Is(err, context.DeadlineExceeded) || r.attempts() != 2 {
+		t.Fatalf("register = %v，尝试了 %d 次", err, r.attempts())
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond || elapsed > 2*time.Second {
+		t.Fatalf("两次 50ms 的尝试用了 %v", elapsed)
+	}
+}
+
+// TestRegisterStopsOnCancel 收到停止信号时，无论在等待重试还是在尝试中都立即返回
+func TestRegisterStopsOnCancel(t *testing.T) {
+	for _, block := range []bool{false, true} {
+		t.Run(fmt.Sprintf("block=%v", block), func(t *testing.T) {
+			r := &fakeRegistrar{failures: 1 << 30, block: block}
+			state := &registrationState{}
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan error, 1)
+			go func() { done <- register(ctx, r, registerBudget{backoff: time.Hour}, state) }()
+
+			time.Sleep(50 * time.Millisecond)
+			cancel()
+			select {
+			case err := <-done:
+				if !errors.Is(err, context.Canceled) {
+					t.Fatalf("register = %v, want context.Canceled", err)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("取消后 register 没有返回")
+			}
+			if r.attempts() != 1 || state.Registered() {
+				t.Fatalf("尝试了 %d 次，已注册 = %v", r.attempts(), state.Registered())
+			}
+		})
+	}
+}
+
+// TestRunWhenRegisteredFailure 预算用完时把错误交给 main 退出，收到停止信号时不报告错误
+func TestRunWhenRegisteredFailure(t *testing.T) {
+	failed := make(chan error, 1)
+	runWhenRegistered(context.Background(), &fakeRegistrar{failures: 1 << 30}, registerBudget{attempts: 2, backoff: time.Millisecond},
+		&registrationState{required: true}, nil, nil, failed)
+	select {
+	case err := <-failed:
+		if !errors.Is(err, errEtcdDown) {
+			t.Fatalf("failed = %v", err)
+		}
+	default:
+		t.Fatal("预算用完后没有报告错误")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	runWhenRegistered(ctx, &fakeRegistrar{failures: 1 << 30}, registerBudget{attempts: 2, backoff: time.Millisecond},
+		&registrationState{required: true}, nil, nil, failed)
+	if len(failed) != 0 {
+		t.Fatalf("取消后报告了错误 %v", <-failed)
+	}
+}
+
+// TestRegistrationReady 要求注册时注册成功前 /ready 返回 503；不要求注册时注册成功前按单机模式就绪，
+// 注册成功后开始检查节点列表
+func TestRegistrationReady(t *testing.T) {
+	g := cache.NewGroup(fmt.Sprintf("registration-%d", time.Now().UnixNano()), 1<<20,
+		cache.GetterFunc(func(key string) ([]byte, error) { return []byte(key), nil }), 0)
+	defer g.Close()
+
+	for _, required := range []bool{true, false} {
+		t.Run(fmt.Sprintf("required=%v", required), func(t *testing.T) {
+			var synced bool
+			var mu sync.Mutex
+			peerStatus := func() peers.Status {
+				mu.Lock()
+				defer mu.Unlock()
+				if synced {
+					return peers.Status{LastSuccess: time.Now(), Peers: 2}
+				}
+				return peers.Status{}
+			}
+
+			state := &registrationState{required: required}
+			addr := freeAddr(t)
+			s := httpserver.NewServer(addr, append(state.serverOptions(), httpserver.WithPeerStatus(peerStatus))...)
+			if err := s.Start(); err != nil {
+				t.Fatal(err)
+			}
+			defer s.Stop()
+
+			ready := func() int {
+				t.Helper()
+				deadline := time.Now().Add(5 * time.Second)
+				for {
+					resp, err := http.Get("http://" + addr + "/ready")
+					if err == nil {
+						resp.Body.Close()
+						return resp.StatusCode
+					}
+					if time.Now().After(deadline) {
+						t.Fatal(err)
+					}
+					time.Sleep(10 * time.Millisecond)
+				}
+			}
+
+			wantBefore := http.StatusServiceUnavailable
+			if !required {
+				wantBefore = http.StatusOK
+			}
+			state.record(errEtcdDown)
+			if code := ready(); code != wantBefore {
+				t.Fatalf("注册前 /ready = %d, want %d", code, wantBefore)
+			}
+
+			// 注册成功、节点列表尚未同步：两种模式都等待节点列表
+			state.record(nil)
+			if code := ready(); code != http.StatusServiceUnavailable {
+				t.Fatalf("注册后、同步节点列表前 /ready = %d, want 503", code)
+			}
+			mu.Lock()
+			synced = true
+			mu.Unlock()
+			if code := ready(); code != http.StatusOK {
+				t.Fatalf("同步节点列表后 /ready = %d, want 200", code)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/internal/loadgen"
+	"github.com/AdrianWangs/go-cache/pkg/client"
+)
+
+// maxErrorSamples 每个 worker 保留的错误样例数
+const maxErrorSamples = 3
+
+// workerStats 单个 worker 的计数，压测结束后合并
+type workerStats struct {
+	reads    uint64
+	writes   uint64
+	notFound uint64
+	errors   uint64
+	samples  []string
+
+	readLatency  *loadgen.Histogram
+	writeLatency *loadgen.Histogram
+}
+
+// newWorkerStats 创建空的计数
+func newWorkerStats() *workerStats {
+	return &workerStats{readLatency: loadgen.NewHistogram(), writeLatency: loadgen.NewHistogram()}
+}
+
+// merge 将 o 合并进 s
+func (s *workerStats) merge(o *workerStats) {
+	s.reads += o.reads
+	s.writes += o.writes
+	s.notFound += o.notFound
+	s.errors += o.errors
+	for _, e := range o.samples {
+		if len(s.samples) < maxErrorSamples {
+			s.samples = append(s.samples, e)
+		}
+	}
+	s.readLatency.Merge(o.readLatency)
+	s.writeLatency.Merge(o.writeLatency)
+}
+
+// bench 先预热再压测，返回压测阶段的结果
+func bench(ctx context.Context, cfg *config, t target, log io.Writer) (*result, error) {
+	if cfg.warmup > 0 {
+		fmt.Fprintf(log, "预热 %s ...\n", cfg.warmup)
+		runPhase(ctx, cfg, t, cfg.warmup, cfg.seed)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+	}
+
+	hitsBefore, getsBefore, statsErr := t.Stats(ctx, cfg.group)
+
+	fmt.Fprintf(log, "压测 %s: %d 个 worker，持续 %s ...\n", t.Name(), cfg.workers, cfg.duration)
+	scrapeCtx, stopScrape := context.WithCancel(ctx)
+	scrapes := make(chan *scrapeStats, 1)
+	go func() { scrapes <- scrape(scrapeCtx, cfg, t) }()
+	start := time.Now()
+	stats := runPhase(ctx, cfg, t, cfg.duration, cfg.seed+int64(cfg.workers))
+	elapsed := time.Since(start)
+	stopScrape()
+
+	r := newResult(cfg, t.Name(), elapsed, stats)
+	r.setScrapes(<-scrapes)
+	if statsErr == nil {
+		hitsAfter, getsAfter, err := t.Stats(context.Background(), cfg.group)
+		statsErr = err
+		if err == nil {
+			r.setHitRate(hitsAfter-hitsBefore, getsAfter-getsBefore)
+		}
+	}
+	if statsErr != nil {
+		fmt.Fprintf(log, "获取缓存组统计失败，无法计算命中率: %v\n", statsErr)
+	}
+	return r, nil
+}
+
+// runPhase 启动 cfg.workers 个 worker 持续发送请求，直到 d 结束或 ctx 取消
+func runPhase(ctx context.Context, cfg *config, t target, d time.Duration, seed int64) *workerStats {
+	ctx, cancel := context.WithTimeout(ctx, d)
+	defer cancel()
+
+	results := make([]*workerStats, cfg.workers)
+	var wg sync.WaitGroup
+	for i := 0; i < cfg.workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = work(ctx, cfg, t, seed+int64(i))
+		}(i)
+	}
+	wg.Wait()
+
+	total := newWorkerStats()
+	for _, s := range results {
+		total.merge(s)
+	}
+	return total
+}
+
+// work 单个 worker 的请求循环
+func work(ctx context.Context, cfg *config, t target, seed int64) *workerStats {
+	// 参数已在 parseFlags 中校验，这里不会出错
+	keys, _ := loadgen.NewKeyGen(cfg.dist, cfg.keys, cfg.zipfS, seed)
+	r := rand.New(rand.NewSource(seed))
+	value := make([]byte, cfg.valueSize)
+	r.Read(value)
+
+	s := newWorkerStats()
+	for ctx.Err() == nil {
+		key := cfg.keyPrefix + strconv.FormatUint(keys.Next(), 10)
+		read := r.Float64() < cfg.readRatio
+
+		begin := time.Now()
+		var err error
+		if read {
+			_, err = t.Get(ctx, cfg.group, key)
+		} else {
+			err = t.Set(ctx, cfg.group, key, value, cfg.ttl)
+		}
+		latency := time.Since(begin)
+
+		// 压测结束时被取消的请求不计入结果
+		if ctx.Err() != nil {
+			break
+		}
+		if read {
+			s.reads++
+			s.readLatency.Record(latency)
+		} else {
+			s.writes++
+			s.writeLatency.Record(latency)
+		}
+		switch {
+		case err == nil:
+		case errors.Is(err, client.ErrNotFound):
+			s.notFound++
+		default:
+			s.errors++
+			if len(s.samples) < maxErrorSamples {
+				s.samples = append(s.samples, err.Error())
+			}
+		}
+	}
+	return s
+}
+
+// scrapeStats 压测期间抓取统计的计数
+type scrapeStats struct {
+	count   uint64
+	errors  uint64
+	latency *loadgen.Histogram
+}
+
+// scrape 按 cfg.statsRate 的频率抓取缓存组统计，直到 ctx 取消，模拟压测期间的监控抓取
+func scrape(ctx context.Context, cfg *config, t target) *scrapeStats {
+	s := &scrapeStats{latency: loadgen.NewHistogram()}
+	if cfg.statsRate <= 0 {
+		return s
+	}
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / cfg.statsRate))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return s
+		case <-ticker.C:
+		}
+		begin := time.Now()
+		_, _, err := t.Stats(ctx, cfg.group)
+		if ctx.Err() != nil {
+			return s
+		}
+		s.count++
+		s.latency.Record(time.Since(begin))
+		if err != nil {
+			s.errors++
+		}
+	}
+}
@@ -0,0 +1,162 @@
+// gocache-bench 对集群或单个缓存节点施加可配置的读写负载，
+// 报告吞吐量、延迟分位数、错误数以及压测期间观察到的缓存命中率。
+//
+// 命中率来自压测前后缓存组统计（API 服务器的 /api/groups 或节点的 Stats）的差值，
+// 因此同一时间有其他流量时结果会包含这些流量
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/internal/loadgen"
+	"github.com/AdrianWangs/go-cache/pkg/client"
+)
+
+// config 压测参数
+type config struct {
+	api     string
+	node    string
+	proto   string
+	token   string
+	timeout time.Duration
+
+	group     string
+	workers   int
+	duration  time.Duration
+	warmup    time.Duration
+	keys      uint64
+	keyPrefix string
+	dist      string
+	zipfS     float64
+	readRatio float64
+	valueSize int
+	ttl       time.Duration
+	seed      int64
+	statsRate float64
+
+	format string
+	out    string
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run 解析参数、执行压测并输出结果，返回退出码
+func run(args []string, stdout, stderr io.Writer) int {
+	cfg, err := parseFlags(args, stderr)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		fmt.Fprintf(stderr, "gocache-bench: %v\n", err)
+		return 2
+	}
+
+	target, err := newTarget(cfg)
+	if err != nil {
+		fmt.Fprintf(stderr, "gocache-bench: %v\n", err)
+		return 2
+	}
+	defer target.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	result, err := bench(ctx, cfg, target, stderr)
+	if err != nil {
+		fmt.Fprintf(stderr, "gocache-bench: %v\n", err)
+		return 2
+	}
+
+	w := stdout
+	if cfg.out != "" {
+		f, err := os.Create(cfg.out)
+		if err != nil {
+			fmt.Fprintf(stderr, "gocache-bench: %v\n", err)
+			return 2
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := writeResult(w, cfg.format, result); err != nil {
+		fmt.Fprintf(stderr, "gocache-bench: 输出结果失败: %v\n", err)
+		return 2
+	}
+	return 0
+}
+
+// parseFlags 解析并校验命令行参数
+func parseFlags(args []string, stderr io.Writer) (*config, error) {
+	cfg := &config{}
+	fs := flag.NewFlagSet("gocache-bench", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	fs.StringVar(&cfg.api, "api", "localhost:8080", "API 服务器地址")
+	fs.StringVar(&cfg.node, "node", "", "直接压测该节点，不经过 API 服务器")
+	fs.StringVar(&cfg.proto, "proto", string(client.ProtocolGRPC), "直接访问节点时使用的协议: grpc 或 http")
+	fs.StringVar(&cfg.token, "token", os.Getenv("GOCACHE_ADMIN_TOKEN"), "管理接口令牌，经 API 服务器写入时需要")
+	fs.DurationVar(&cfg.timeout, "timeout", 5*time.Second, "单次请求超时")
+
+	fs.StringVar(&cfg.group, "group", "scores", "压测的缓存组")
+	fs.IntVar(&cfg.workers, "workers", 16, "并发的 worker 数")
+	fs.DurationVar(&cfg.duration, "duration", 10*time.Second, "压测时长")
+	fs.DurationVar(&cfg.warmup, "warmup", 0, "预热时长，预热期间的请求不计入结果")
+	fs.Uint64Var(&cfg.keys, "keys", 10000, "key 空间大小")
+	fs.StringVar(&cfg.keyPrefix, "key-prefix", "bench-", "key 前缀，key 为前缀加序号")
+	fs.StringVar(&cfg.dist, "dist", loadgen.DistUniform, "key 分布: uniform 或 zipfian")
+	fs.Float64Var(&cfg.zipfS, "zipf-s", 1.1, "zipfian 分布的指数，必须大于 1")
+	fs.Float64Var(&cfg.readRatio, "read-ratio", 1, "读请求占比 (0-1)，其余为写请求")
+	fs.IntVar(&cfg.valueSize, "value-size", 64, "写入的值大小（字节）")
+	fs.DurationVar(&cfg.ttl, "ttl", 0, "写入的过期时间，0 表示不过期")
+	fs.Int64Var(&cfg.seed, "seed", time.Now().UnixNano(), "随机种子，相同的种子生成相同的 key 序列")
+	fs.Float64Var(&cfg.statsRate, "stats-rate", 0, "压测期间每秒抓取缓存组统计的次数，模拟监控抓取，用于对比抓取对吞吐量的影响 (0 表示不抓取)")
+
+	fs.StringVar(&cfg.format, "format", formatText, "结果格式: text、json 或 csv")
+	fs.StringVar(&cfg.out, "out", "", "结果写入该文件，默认输出到标准输出")
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("多余的参数: %v", fs.Args())
+	}
+
+	switch {
+	case cfg.group == "":
+		return nil, fmt.Errorf("-group 不能为空")
+	case cfg.workers <= 0:
+		return nil, fmt.Errorf("-workers 必须大于 0")
+	case cfg.duration <= 0:
+		return nil, fmt.Errorf("-duration 必须大于 0")
+	case cfg.warmup < 0:
+		return nil, fmt.Errorf("-warmup 不能为负数")
+	case cfg.keys == 0:
+		return nil, fmt.Errorf("-keys 必须大于 0")
+	case cfg.readRatio < 0 || cfg.readRatio > 1:
+		return nil, fmt.Errorf("-read-ratio 必须在 0 到 1 之间")
+	case cfg.valueSize <= 0:
+		return nil, fmt.Errorf("-value-size 必须大于 0")
+	case cfg.statsRate < 0:
+		return nil, fmt.Errorf("-stats-rate 不能为负数")
+	}
+	if _, err := loadgen.NewKeyGen(cfg.dist, cfg.keys, cfg.zipfS, 0); err != nil {
+		return nil, err
+	}
+	switch cfg.format {
+	case formatText, formatJSON, formatCSV:
+	default:
+		return nil, fmt.Errorf("不支持的结果格式 %q，只能是 text、json 或 csv", cfg.format)
+	}
+	if cfg.readRatio < 1 && cfg.node == "" && cfg.token == "" {
+		return nil, fmt.Errorf("经 API 服务器写入需要管理接口令牌，请设置 -token 或 -read-ratio=1")
+	}
+	return cfg, nil
+}
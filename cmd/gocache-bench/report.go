@@ -0,0 +1,216 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/internal/loadgen"
+)
+
+// 结果格式
+const (
+	formatText = "text"
+	formatJSON = "json"
+	formatCSV  = "csv"
+)
+
+// latency 一类请求的延迟分布，单位毫秒
+type latency struct {
+	Count uint64  `json:"count"`
+	Mean  float64 `json:"meanMs"`
+	P50   float64 `json:"p50Ms"`
+	P90   float64 `json:"p90Ms"`
+	P99   float64 `json:"p99Ms"`
+	P999  float64 `json:"p999Ms"`
+	Max   float64 `json:"maxMs"`
+}
+
+// newLatency 从直方图计算延迟分布
+func newLatency(h *loadgen.Histogram) latency {
+	return latency{
+		Count: h.Count(),
+		Mean:  ms(h.Mean()),
+		P50:   ms(h.Percentile(50)),
+		P90:   ms(h.Percentile(90)),
+		P99:   ms(h.Percentile(99)),
+		P999:  ms(h.Percentile(99.9)),
+		Max:   ms(h.Max()),
+	}
+}
+
+// ms 将耗时转换为毫秒
+func ms(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// result 一次压测的结果
+type result struct {
+	Target       string  `json:"target"`
+	Group        string  `json:"group"`
+	Workers      int     `json:"workers"`
+	Distribution string  `json:"distribution"`
+	Keys         uint64  `json:"keys"`
+	ReadRatio    float64 `json:"readRatio"`
+	ValueSize    int     `json:"valueSize"`
+
+	Duration   float64  `json:"durationSeconds"` // 实际压测时长
+	Requests   uint64   `json:"requests"`
+	Throughput float64  `json:"throughput"` // 每秒请求数
+	Reads      uint64   `json:"reads"`
+	Writes     uint64   `json:"writes"`
+	NotFound   uint64   `json:"notFound"` // 读取时键不存在，不计入错误
+	Errors     uint64   `json:"errors"`
+	ErrorTypes []string `json:"errorSamples,omitempty"`
+
+	Hits    int64   `json:"hits"`    // 压测期间缓存组命中次数的增量
+	Gets    int64   `json:"gets"`    // 压测期间缓存组请求次数的增量
+	HitRate float64 `json:"hitRate"` // 无法获取统计时为 -1
+
+	ReadLatency  latency `json:"readLatency"`
+	WriteLatency latency `json:"writeLatency"`
+
+	StatsScrapes      uint64  `json:"statsScrapes"`      // 压测期间抓取统计的次数，未设置 -stats-rate 时为 0
+	StatsScrapeErrors uint64  `json:"statsScrapeErrors"` // 失败的抓取次数
+	StatsLatency      latency `json:"statsLatency"`
+}
+
+// newResult 汇总压测阶段的计数
+func newResult(cfg *config, name string, elapsed time.Duration, s *workerStats) *result {
+	r := &result{
+		Target:       name,
+		Group:        cfg.group,
+		Workers:      cfg.workers,
+		Distribution: cfg.dist,
+		Keys:         cfg.keys,
+		ReadRatio:    cfg.readRatio,
+		ValueSize:    cfg.valueSize,
+		Duration:     elapsed.Seconds(),
+		Requests:     s.reads + s.writes,
+		Reads:        s.reads,
+		Writes:       s.writes,
+		NotFound:     s.notFound,
+		Errors:       s.errors,
+		ErrorTypes:   s.samples,
+		HitRate:      -1,
+		ReadLatency:  newLatency(s.readLatency),
+		WriteLatency: newLatency(s.writeLatency),
+	}
+	if elapsed > 0 {
+		r.Throughput = float64(r.Requests) / elapsed.Seconds()
+	}
+	return r
+}
+
+// setScrapes 记录压测期间的统计抓取
+func (r *result) setScrapes(s *scrapeStats) {
+	r.StatsScrapes = s.count
+	r.StatsScrapeErrors = s.errors
+	r.StatsLatency = newLatency(s.latency)
+}
+
+// setHitRate 根据统计增量设置命中率
+func (r *result) setHitRate(hits, gets int64) {
+	r.Hits, r.Gets = hits, gets
+	if gets > 0 {
+		r.HitRate = float64(hits) / float64(gets)
+	} else {
+		r.HitRate = 0
+	}
+}
+
+// writeResult 按格式输出结果
+func writeResult(w io.Writer, format string, r *result) error {
+	switch format {
+	case formatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	case formatCSV:
+		return writeCSV(w, r)
+	default:
+		return writeText(w, r)
+	}
+}
+
+// writeText 以便于阅读的表格输出
+func writeText(w io.Writer, r *result) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "目标\t%s\n", r.Target)
+	fmt.Fprintf(tw, "缓存组\t%s\n", r.Group)
+	fmt.Fprintf(tw, "负载\t%d workers, %s, %d keys, 读占比 %.2f, 值 %d 字节\n",
+		r.Workers, r.Distribution, r.Keys, r.ReadRatio, r.ValueSize)
+	fmt.Fprintf(tw, "时长\t%.2fs\n", r.Duration)
+	fmt.Fprintf(tw, "请求\t%d (读 %d, 写 %d)\n", r.Requests, r.Reads, r.Writes)
+	fmt.Fprintf(tw, "吞吐量\t%.1f req/s\n", r.Throughput)
+	fmt.Fprintf(tw, "键不存在\t%d\n", r.NotFound)
+	fmt.Fprintf(tw, "错误\t%d\n", r.Errors)
+	for _, e := range r.ErrorTypes {
+		fmt.Fprintf(tw, "\t%s\n", e)
+	}
+	if r.HitRate >= 0 {
+		fmt.Fprintf(tw, "命中率\t%.2f%% (%d/%d)\n", r.HitRate*100, r.Hits, r.Gets)
+	} else {
+		fmt.Fprintf(tw, "命中率\t未知\n")
+	}
+	if r.StatsScrapes > 0 {
+		fmt.Fprintf(tw, "统计抓取\t%d 次 (失败 %d)\n", r.StatsScrapes, r.StatsScrapeErrors)
+	}
+	fmt.Fprintln(tw)
+	fmt.Fprintln(tw, "延迟(ms)\t次数\t平均\tp50\tp90\tp99\tp99.9\t最大")
+	for _, l := range []struct {
+		name string
+		l    latency
+	}{{"读", r.ReadLatency}, {"写", r.WriteLatency}, {"统计", r.StatsLatency}} {
+		if l.l.Count == 0 {
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%.3f\t%.3f\t%.3f\t%.3f\t%.3f\t%.3f\n",
+			l.name, l.l.Count, l.l.Mean, l.l.P50, l.l.P90, l.l.P99, l.l.P999, l.l.Max)
+	}
+	return tw.Flush()
+}
+
+// csvHeader CSV 输出的列，与 csvRow 的顺序一致
+var csvHeader = []string{
+	"target", "group", "workers", "distribution", "keys", "read_ratio", "value_size",
+	"duration_s", "requests", "throughput", "reads", "writes", "not_found", "errors",
+	"hits", "gets", "hit_rate",
+	"read_mean_ms", "read_p50_ms", "read_p90_ms", "read_p99_ms", "read_p999_ms", "read_max_ms",
+	"write_mean_ms", "write_p50_ms", "write_p90_ms", "write_p99_ms", "write_p999_ms", "write_max_ms",
+	"stats_scrapes", "stats_scrape_errors",
+	"stats_mean_ms", "stats_p50_ms", "stats_p90_ms", "stats_p99_ms", "stats_p999_ms", "stats_max_ms",
+}
+
+// writeCSV 输出表头和一行结果，多次压测的结果可以去掉表头后拼接
+func writeCSV(w io.Writer, r *result) error {
+	f := func(v float64) string { return strconv.FormatFloat(v, 'f', 3, 64) }
+	u := func(v uint64) string { return strconv.FormatUint(v, 10) }
+	i := func(v int64) string { return strconv.FormatInt(v, 10) }
+
+	row := []string{
+		r.Target, r.Group, strconv.Itoa(r.Workers), r.Distribution, u(r.Keys), f(r.ReadRatio), strconv.Itoa(r.ValueSize),
+		f(r.Duration), u(r.Requests), f(r.Throughput), u(r.Reads), u(r.Writes), u(r.NotFound), u(r.Errors),
+		i(r.Hits), i(r.Gets), strconv.FormatFloat(r.HitRate, 'f', 4, 64),
+	}
+	for _, l := range []latency{r.ReadLatency, r.WriteLatency} {
+		row = append(row, f(l.Mean), f(l.P50), f(l.P90), f(l.P99), f(l.P999), f(l.Max))
+	}
+	row = append(row, u(r.StatsScrapes), u(r.StatsScrapeErrors))
+	l := r.StatsLatency
+	row = append(row, f(l.Mean), f(l.P50), f(l.P90), f(l.P99), f(l.P999), f(l.Max))
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	if err := cw.Write(row); err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
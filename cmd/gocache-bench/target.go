@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/pkg/client"
+)
+
+// target 压测对象：API 服务器或单个缓存节点
+type target interface {
+	Get(ctx context.Context, group, key string) ([]byte, error)
+	Set(ctx context.Context, group, key string, value []byte, ttl time.Duration) error
+	// Stats 返回缓存组当前累计的命中次数和请求次数
+	Stats(ctx context.Context, group string) (hits, gets int64, err error)
+	Name() string
+	Close() error
+}
+
+// newTarget 按配置创建压测对象，指定 -node 时直接压测节点
+func newTarget(cfg *config) (target, error) {
+	opts := []client.Option{client.WithTimeout(cfg.timeout), client.WithToken(cfg.token)}
+	if cfg.node == "" {
+		return &apiTarget{Client: client.New(cfg.api, opts...), addr: cfg.api}, nil
+	}
+
+	c, err := client.NewNodeClient(cfg.node, client.Protocol(cfg.proto), opts...)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.readRatio < 1 && c.Protocol() != client.ProtocolGRPC {
+		c.Close()
+		return nil, fmt.Errorf("直接写入节点只支持 gRPC 协议，请使用 -proto=grpc 或 -read-ratio=1")
+	}
+	return &nodeTarget{NodeClient: c, addr: cfg.node}, nil
+}
+
+// apiTarget 通过 API 服务器压测整个集群
+type apiTarget struct {
+	*client.Client
+	addr string
+}
+
+// Stats 汇总集群中该组的统计
+func (t *apiTarget) Stats(ctx context.Context, group string) (int64, int64, error) {
+	resp, err := t.Client.Stats(ctx, group)
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, g := range resp.Groups {
+		if g.Name == group {
+			return g.Hits, g.Gets, nil
+		}
+	}
+	return 0, 0, client.ErrGroupNotFound
+}
+
+// Name 返回压测对象的描述
+func (t *apiTarget) Name() string {
+	return "api " + t.addr
+}
+
+// Close 实现 target，API 客户端不持有需要释放的连接
+func (t *apiTarget) Close() error {
+	return nil
+}
+
+// nodeTarget 直接压测单个缓存节点
+type nodeTarget struct {
+	*client.NodeClient
+	addr string
+}
+
+// Stats 返回节点上该组的统计
+func (t *nodeTarget) Stats(ctx context.Context, group string) (int64, int64, error) {
+	resp, err := t.NodeClient.Stats(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, g := range resp.GetGroups() {
+		if g.GetName() == group {
+			return g.GetHits(), g.GetGets(), nil
+		}
+	}
+	return 0, 0, client.ErrGroupNotFound
+}
+
+// Name 返回压测对象的描述
+func (t *nodeTarget) Name() string {
+	return fmt.Sprintf("node %s (%s)", t.addr, t.Protocol())
+}
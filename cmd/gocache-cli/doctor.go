@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/config"
+	"github.com/AdrianWangs/go-cache/internal/discovery"
+	"github.com/AdrianWangs/go-cache/internal/selfcheck"
+)
+
+// doctor 在启动缓存节点之前，按节点的参数执行与 cachenode -self-check 相同的检查
+func (c *cli) doctor(args []string) int {
+	fs := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	endpoints := fs.String("etcd-endpoints", "localhost:2379", "etcd集群地址，多个用逗号分隔")
+	serviceName := fs.String("service-name", "go-cache-nodes", "服务名称")
+	host := fs.String("node-host", "", "节点主机名或IP地址（留空则与节点相同，自动检测）")
+	grpcPort := fs.Int("node-port", 9090, "节点gRPC监听端口")
+	httpPort := fs.Int("http-port", 9091, "节点HTTP监听端口")
+	dialTimeout := fs.Duration("etcd-dial-timeout", config.DefaultTimeouts().EtcdDial.Std(), "连接etcd的超时")
+	skip := fs.String("skip", "", "跳过的检查项，逗号分隔 (bind、etcd、dial、tls)")
+	timeout := fs.Duration("timeout", selfcheck.DefaultTimeout, "单项检查的超时")
+	var files selfcheck.TLSFiles
+	fs.StringVar(&files.Cert, "tls-cert", "", "要检查的证书文件")
+	fs.StringVar(&files.Key, "tls-key", "", "要检查的私钥文件")
+	fs.StringVar(&files.CA, "tls-ca", "", "要检查的 CA 文件")
+	fs.BoolVar(&c.json, "json", false, "以 JSON 输出")
+	if _, err := parse(fs, args, 0); err != nil {
+		return c.usageError("doctor", err)
+	}
+	skipped, err := selfcheck.ParseSkip(*skip)
+	if err != nil {
+		return c.usageError("doctor", err)
+	}
+
+	if *host == "" {
+		if *host, err = discovery.LocalIP(); err != nil {
+			return c.fail(fmt.Errorf("自动获取本地IP失败: %w，请使用 --node-host 指定", err))
+		}
+	}
+	grpcAddr := fmt.Sprintf("%s:%d", *host, *grpcPort)
+	var etcd []string
+	for _, e := range strings.Split(*endpoints, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			etcd = append(etcd, e)
+		}
+	}
+	checks := selfcheck.Checks(selfcheck.Config{
+		Addrs:           []string{grpcAddr, fmt.Sprintf("%s:%d", *host, *httpPort)},
+		EtcdEndpoints:   etcd,
+		EtcdDialTimeout: *dialTimeout,
+		ServiceName:     *serviceName,
+		NodeID:          "doctor-" + discovery.CanonicalAddr(grpcAddr),
+		TLS:             files,
+	})
+	results := selfcheck.Run(context.Background(), checks, skipped, *timeout)
+
+	code := exitOK
+	if len(selfcheck.Failed(results)) > 0 {
+		code = exitError
+	}
+	if c.json {
+		if rc := c.printJSON(results); rc != exitOK {
+			return rc
+		}
+		return code
+	}
+	w := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "CHECK\tTARGET\tSTATUS\tTIME\tDETAIL")
+	for _, r := range results {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", r.Name, r.Target, r.Status, r.Elapsed.Round(time.Millisecond), r.Error)
+	}
+	w.Flush()
+	for _, r := range selfcheck.Failed(results) {
+		fmt.Fprintf(c.stdout, "\n%s %s 失败: %s\n  建议: %s\n", r.Name, r.Target, r.Error, r.Hint)
+	}
+	fmt.Fprintf(c.stdout, "\n%s\n", selfcheck.Summary(results))
+	return code
+}
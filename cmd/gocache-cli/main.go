@@ -0,0 +1,487 @@
+// gocache-cli 是面向运维的命令行工具，默认通过 API 服务器访问集群，
+// 也可以用 --node 直接访问单个缓存节点。
+//
+// 退出码：0 成功，1 键或组不存在，2 参数错误、传输错误或其他错误
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/pkg/client"
+)
+
+const (
+	exitOK       = 0 // 成功
+	exitNotFound = 1 // 键或组不存在
+	exitError    = 2 // 参数错误、传输错误或其他错误
+
+	warmBatchSize = 1000 // warm 每批读取的 key 数，与 API 服务器的批量读取上限一致
+)
+
+const usage = `用法: gocache-cli <命令> [参数] [选项]
+
+命令:
+  get <group> <key>             读取 key
+  set <group> <key> <value>     写入 key（--ttl 设置过期时间，经 API 服务器写入时需要 --token）
+  del <group> <key>             删除 key
+  stats [--group g]             缓存组统计
+  nodes                         API 服务器当前使用的节点列表
+  warm <group> --file keys.txt  按文件中的 key（每行一个）预热缓存
+  doctor                        启动节点之前检查端口、etcd 和通告地址（选项与 cachenode 相同，--skip 跳过检查项）
+
+通用选项:
+  --api addr          API 服务器地址 (默认 localhost:8080)
+  --node addr         直接访问该节点，不经过 API 服务器
+  --proto grpc|http   直接访问节点时使用的协议 (默认 grpc)
+  --token token       管理接口令牌 (默认读取环境变量 GOCACHE_ADMIN_TOKEN)
+  --timeout d         单次请求超时 (默认 5s)
+  --json              以 JSON 输出，便于脚本处理
+`
+
+// cli 一次命令执行的上下文
+type cli struct {
+	stdout io.Writer
+	stderr io.Writer
+
+	api     string
+	node    string
+	proto   string
+	token   string
+	timeout time.Duration
+	json    bool
+}
+
+// store 读写单个 key 的操作，API 服务器和单个节点都支持
+type store interface {
+	Get(ctx context.Context, group, key string) ([]byte, error)
+	Set(ctx context.Context, group, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, group, key string) error
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run 执行命令并返回退出码
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+		fmt.Fprint(stderr, usage)
+		if len(args) == 0 {
+			return exitError
+		}
+		return exitOK
+	}
+
+	c := &cli{stdout: stdout, stderr: stderr}
+	cmd, args := args[0], args[1:]
+	switch cmd {
+	case "get":
+		return c.get(args)
+	case "set":
+		return c.set(args)
+	case "del", "delete":
+		return c.del(args)
+	case "stats":
+		return c.stats(args)
+	case "nodes":
+		return c.nodes(args)
+	case "warm":
+		return c.warm(args)
+	case "doctor":
+		return c.doctor(args)
+	default:
+		fmt.Fprintf(stderr, "未知命令: %s\n\n%s", cmd, usage)
+		return exitError
+	}
+}
+
+// flagSet 创建包含通用选项的 FlagSet
+func (c *cli) flagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	fs.StringVar(&c.api, "api", "localhost:8080", "API 服务器地址")
+	fs.StringVar(&c.node, "node", "", "直接访问该节点")
+	fs.StringVar(&c.proto, "proto", string(client.ProtocolGRPC), "直接访问节点时使用的协议 (grpc 或 http)")
+	fs.StringVar(&c.token, "token", os.Getenv("GOCACHE_ADMIN_TOKEN"), "管理接口令牌")
+	fs.DurationVar(&c.timeout, "timeout", 5*time.Second, "单次请求超时")
+	fs.BoolVar(&c.json, "json", false, "以 JSON 输出")
+	return fs
+}
+
+// parse 解析选项和位置参数，选项可以出现在位置参数之间
+func parse(fs *flag.FlagSet, args []string, want int) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+	if want >= 0 && len(positional) != want {
+		return nil, fmt.Errorf("需要 %d 个参数，实际 %d 个", want, len(positional))
+	}
+	return positional, nil
+}
+
+// options 返回创建客户端的选项
+func (c *cli) options() []client.Option {
+	return []client.Option{client.WithTimeout(c.timeout), client.WithToken(c.token)}
+}
+
+// store 返回 API 服务器或 --node 指定节点的客户端，release 释放连接
+func (c *cli) store() (s store, release func(), err error) {
+	if c.node == "" {
+		return client.New(c.api, c.options()...), func() {}, nil
+	}
+	nc, err := client.NewNodeClient(c.node, client.Protocol(c.proto), c.options()...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return nc, func() { nc.Close() }, nil
+}
+
+// context 返回整个命令的上下文
+func (c *cli) context() (context.Context, context.CancelFunc) {
+	return context.WithCancel(context.Background())
+}
+
+// fail 输出错误并返回对应的退出码
+func (c *cli) fail(err error) int {
+	fmt.Fprintf(c.stderr, "错误: %v\n", err)
+	if errors.Is(err, client.ErrNotFound) || errors.Is(err, client.ErrGroupNotFound) {
+		return exitNotFound
+	}
+	return exitError
+}
+
+// usageError 输出参数错误
+func (c *cli) usageError(cmd string, err error) int {
+	if !errors.Is(err, flag.ErrHelp) {
+		fmt.Fprintf(c.stderr, "%s: %v\n\n%s", cmd, err, usage)
+	}
+	return exitError
+}
+
+// printJSON 以缩进的 JSON 输出 v
+func (c *cli) printJSON(v interface{}) int {
+	enc := json.NewEncoder(c.stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return c.fail(err)
+	}
+	return exitOK
+}
+
+// get 读取 key
+func (c *cli) get(args []string) int {
+	pos, err := parse(c.flagSet("get"), args, 2)
+	if err != nil {
+		return c.usageError("get", err)
+	}
+	s, closeStore, err := c.store()
+	if err != nil {
+		return c.fail(err)
+	}
+	defer closeStore()
+
+	ctx, cancel := c.context()
+	defer cancel()
+	value, err := s.Get(ctx, pos[0], pos[1])
+	if err != nil {
+		return c.fail(err)
+	}
+
+	if c.json {
+		return c.printJSON(struct {
+			Group string `json:"group"`
+			Key   string `json:"key"`
+			Value string `json:"value"`
+		}{pos[0], pos[1], string(value)})
+	}
+	fmt.Fprintln(c.stdout, string(value))
+	return exitOK
+}
+
+// set 写入 key
+func (c *cli) set(args []string) int {
+	fs := c.flagSet("set")
+	ttl := fs.Duration("ttl", 0, "过期时间，0 表示不过期")
+	pos, err := parse(fs, args, 3)
+	if err != nil {
+		return c.usageError("set", err)
+	}
+	s, closeStore, err := c.store()
+	if err != nil {
+		return c.fail(err)
+	}
+	defer closeStore()
+
+	ctx, cancel := c.context()
+	defer cancel()
+	if err := s.Set(ctx, pos[0], pos[1], []byte(pos[2]), *ttl); err != nil {
+		return c.fail(err)
+	}
+
+	if c.json {
+		return c.printJSON(map[string]interface{}{"group": pos[0], "key": pos[1], "ttl": ttl.String(), "ok": true})
+	}
+	fmt.Fprintln(c.stdout, "OK")
+	return exitOK
+}
+
+// del 删除 key
+func (c *cli) del(args []string) int {
+	pos, err := parse(c.flagSet("del"), args, 2)
+	if err != nil {
+		return c.usageError("del", err)
+	}
+	s, closeStore, err := c.store()
+	if err != nil {
+		return c.fail(err)
+	}
+	defer closeStore()
+
+	ctx, cancel := c.context()
+	defer cancel()
+	if err := s.Delete(ctx, pos[0], pos[1]); err != nil {
+		return c.fail(err)
+	}
+
+	if c.json {
+		return c.printJSON(map[string]interface{}{"group": pos[0], "key": pos[1], "deleted": true})
+	}
+	fmt.Fprintln(c.stdout, "deleted")
+	return exitOK
+}
+
+// stats 输出缓存组统计
+func (c *cli) stats(args []string) int {
+	fs := c.flagSet("stats")
+	group := fs.String("group", "", "只输出该组")
+	if _, err := parse(fs, args, 0); err != nil {
+		return c.usageError("stats", err)
+	}
+	ctx, cancel := c.context()
+	defer cancel()
+
+	if c.node != "" {
+		nc, err := client.NewNodeClient(c.node, client.Protocol(c.proto), c.options()...)
+		if err != nil {
+			return c.fail(err)
+		}
+		defer nc.Close()
+		resp, err := nc.Stats(ctx)
+		if err != nil {
+			return c.fail(err)
+		}
+		if c.json {
+			return c.printJSON(resp)
+		}
+		w := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "GROUP\tGETS\tHITS\tMISSES\tHIT%\tENTRIES\tBYTES\tMAX BYTES\tFILL%")
+		found := *group == ""
+		for _, g := range resp.GetGroups() {
+			if *group != "" && g.GetName() != *group {
+				continue
+			}
+			found = true
+			cost := g.GetBytes()
+			if g.Cost != nil {
+				cost = g.GetCost()
+			}
+			fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%s\t%d\t%d\t%d\t%s\n", g.GetName(), g.GetGets(), g.GetHits(), g.GetMisses(),
+				hitRate(g.GetHits(), g.GetGets()), g.GetEntries(), g.GetBytes(), g.GetMaxBytes(), fillRate(cost, g.GetMaxBytes()))
+		}
+		w.Flush()
+		fmt.Fprintf(c.stdout, "uptime: %v\n", time.Duration(resp.GetUptimeSeconds())*time.Second)
+		if !found {
+			return c.fail(client.ErrGroupNotFound)
+		}
+		return exitOK
+	}
+
+	resp, err := client.New(c.api, c.options()...).Stats(ctx, *group)
+	if err != nil {
+		return c.fail(err)
+	}
+	if c.json {
+		return c.printJSON(resp)
+	}
+	w := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "GROUP\tGETS\tHITS\tMISSES\tHIT%\tENTRIES\tBYTES\tMAX BYTES\tFILL%\tNODES")
+	for _, g := range resp.Groups {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%s\t%d\t%d\t%d\t%s\t%d\n", g.Name, g.Gets, g.Hits, g.Misses,
+			hitRate(g.Hits, g.Gets), g.Entries, g.Bytes, g.MaxBytes, fillRate(g.Cost, g.MaxBytes), g.Nodes)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "NODE\tSTATUS\tMODE\tUPTIME\tERROR")
+	for _, n := range resp.Nodes {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%v\t%s\n", n.Node, n.Status, n.Mode, time.Duration(n.UptimeSeconds)*time.Second, n.Error)
+	}
+	w.Flush()
+	return exitOK
+}
+
+// hitRate 返回命中率的百分比字符串
+func hitRate(hits, gets int64) string {
+	if gets == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f", float64(hits)*100/float64(gets))
+}
+
+// fillRate 格式化填充率百分比，没有容量上限时输出 "-"
+func fillRate(cost, maxBytes int64) string {
+	if maxBytes <= 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f", float64(cost)*100/float64(maxBytes))
+}
+
+// nodes 输出 API 服务器当前使用的节点列表
+func (c *cli) nodes(args []string) int {
+	if _, err := parse(c.flagSet("nodes"), args, 0); err != nil {
+		return c.usageError("nodes", err)
+	}
+	if c.node != "" {
+		return c.fail(fmt.Errorf("%w: 节点列表只能通过 API 服务器查询", client.ErrUnsupported))
+	}
+	ctx, cancel := c.context()
+	defer cancel()
+
+	nodes, err := client.New(c.api, c.options()...).Nodes(ctx)
+	if err != nil {
+		return c.fail(err)
+	}
+	if c.json {
+		return c.printJSON(nodes)
+	}
+	w := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tGRPC\tHTTP\tMODE\tGROUPS")
+	for _, n := range nodes {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", n.Key(), n.GRPCAddr, n.HTTPAddr, n.Mode, strings.Join(n.Groups, ","))
+	}
+	w.Flush()
+	return exitOK
+}
+
+// warmResult warm 的结果
+type warmResult struct {
+	Group   string            `json:"group"`
+	Keys    int               `json:"keys"`
+	Loaded  int               `json:"loaded"`
+	Missing int               `json:"missing"`
+	Errors  map[string]string `json:"errors,omitempty"`
+}
+
+// warm 读取文件中的每个 key，使其加载进归属节点的缓存
+func (c *cli) warm(args []string) int {
+	fs := c.flagSet("warm")
+	file := fs.String("file", "", "key 列表文件，每行一个，空行和 # 开头的行被忽略；- 表示标准输入")
+	pos, err := parse(fs, args, 1)
+	if err == nil && *file == "" {
+		err = fmt.Errorf("缺少 --file")
+	}
+	if err != nil {
+		return c.usageError("warm", err)
+	}
+
+	keys, err := readKeys(*file)
+	if err != nil {
+		return c.fail(err)
+	}
+	ctx, cancel := c.context()
+	defer cancel()
+
+	result := warmResult{Group: pos[0], Keys: len(keys), Errors: make(map[string]string)}
+	if c.node == "" {
+		// 经 API 服务器批量读取，每批内的 key 由各自的归属节点并发加载
+		api := client.New(c.api, c.options()...)
+		for start := 0; start < len(keys); start += warmBatchSize {
+			end := start + warmBatchSize
+			if end > len(keys) {
+				end = len(keys)
+			}
+			resp, err := api.BatchGet(ctx, pos[0], keys[start:end])
+			if err != nil {
+				return c.fail(err)
+			}
+			result.Loaded += len(resp.Values)
+			result.Missing += len(resp.Missing)
+			for k, msg := range resp.Errors {
+				result.Errors[k] = msg
+			}
+		}
+	} else {
+		nc, err := client.NewNodeClient(c.node, client.Protocol(c.proto), c.options()...)
+		if err != nil {
+			return c.fail(err)
+		}
+		defer nc.Close()
+		for _, key := range keys {
+			_, err := nc.Get(ctx, pos[0], key)
+			switch {
+			case err == nil:
+				result.Loaded++
+			case errors.Is(err, client.ErrNotFound):
+				result.Missing++
+			case errors.Is(err, client.ErrGroupNotFound):
+				return c.fail(err)
+			default:
+				result.Errors[key] = err.Error()
+			}
+		}
+	}
+
+	if c.json {
+		c.printJSON(result)
+	} else {
+		fmt.Fprintf(c.stdout, "预热 %s: %d 个 key，加载 %d，不存在 %d，失败 %d\n",
+			result.Group, result.Keys, result.Loaded, result.Missing, len(result.Errors))
+		for k, msg := range result.Errors {
+			fmt.Fprintf(c.stderr, "  %s: %s\n", k, msg)
+		}
+	}
+	if len(result.Errors) > 0 {
+		return exitError
+	}
+	return exitOK
+}
+
+// readKeys 读取 key 列表，忽略空行和 # 开头的行
+func readKeys(path string) ([]string, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var keys []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		keys = append(keys, line)
+	}
+	return keys, scanner.Err()
+}
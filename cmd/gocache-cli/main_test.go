@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/AdrianWangs/go-cache/internal/testutil/cluster"
+)
+
+// startCluster 启动测试集群，测试结束时关闭
+func startCluster(t *testing.T) *cluster.Cluster {
+	t.Helper()
+	c, err := cluster.Start(cluster.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+// runCLI 执行一次命令，返回退出码和标准输出、标准错误
+func runCLI(args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestGetExitCodes(t *testing.T) {
+	c := startCluster(t)
+	c.Source("test").Set("Tom", "630")
+	node := c.Nodes()[0].Addr
+
+	tests := []struct {
+		name string
+		args []string
+		code int
+		out  string
+	}{
+		{"命中", []string{"get", "test", "Tom", "--api", c.APIURL()}, exitOK, "630\n"},
+		{"key 不存在", []string{"get", "test", "missing", "--api", c.APIURL()}, exitNotFound, ""},
+		{"组不存在", []string{"get", "nope", "Tom", "--api", c.APIURL()}, exitNotFound, ""},
+		{"直接访问节点", []string{"get", "--node", node, "--proto", "http", "test", "Tom"}, exitOK, "630\n"},
+		{"节点上 key 不存在", []string{"get", "--node", node, "--proto", "http", "test", "missing"}, exitNotFound, ""},
+		{"API 服务器不可达", []string{"get", "test", "Tom", "--api", "127.0.0.1:1", "--timeout", "1s"}, exitError, ""},
+		{"参数不足", []string{"get", "test", "--api", c.APIURL()}, exitError, ""},
+		{"不支持的协议", []string{"get", "--node", node, "--proto", "udp", "test", "Tom"}, exitError, ""},
+		{"未知命令", []string{"fetch"}, exitError, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, out, errOut := runCLI(tt.args...)
+			if code != tt.code {
+				t.Fatalf("退出码 %d, 期望 %d; stderr: %s", code, tt.code, errOut)
+			}
+			if tt.out != "" && out != tt.out {
+				t.Fatalf("输出 %q, 期望 %q", out, tt.out)
+			}
+		})
+	}
+}
+
+func TestDeleteAndSet(t *testing.T) {
+	c := startCluster(t)
+	c.Source("test").Set("Tom", "630")
+	if code, _, _ := runCLI("get", "test", "Tom", "--api", c.APIURL()); code != exitOK {
+		t.Fatal("get 失败")
+	}
+	owner := c.Owner("Tom").Group("test")
+	if _, _, ok := owner.Peek("Tom"); !ok {
+		t.Fatal("读取后 Tom 不在归属节点的缓存中")
+	}
+
+	code, out, errOut := runCLI("del", "test", "Tom", "--api", c.APIURL(), "--json")
+	if code != exitOK || !strings.Contains(out, `"deleted": true`) {
+		t.Fatalf("del = %d %q %s", code, out, errOut)
+	}
+	if _, _, ok := owner.Peek("Tom"); ok {
+		t.Fatal("删除后 Tom 仍在归属节点的缓存中")
+	}
+
+	// 直接写入节点只支持 gRPC，测试集群的节点只提供 HTTP
+	if code, _, errOut := runCLI("set", "--node", c.Nodes()[0].Addr, "--proto", "http", "test", "k", "v"); code != exitError || !strings.Contains(errOut, "不支持") {
+		t.Fatalf("HTTP 写入节点 = %d %s", code, errOut)
+	}
+	// 未开启管理接口时经 API 服务器写入失败
+	if code, _, _ := runCLI("set", "test", "k", "v", "--api", c.APIURL()); code != exitError {
+		t.Fatalf("管理接口关闭时 set 退出码 %d", code)
+	}
+}
+
+func TestStatsAndNodes(t *testing.T) {
+	c := startCluster(t)
+	c.Source("test").Set("Tom", "630")
+	if code, _, _ := runCLI("get", "test", "Tom", "--api", c.APIURL()); code != exitOK {
+		t.Fatal("get 失败")
+	}
+
+	code, out, errOut := runCLI("nodes", "--api", c.APIURL(), "--json")
+	var nodes []struct {
+		ID string `json:"id"`
+	}
+	if code != exitOK || json.Unmarshal([]byte(out), &nodes) != nil || len(nodes) != len(c.Nodes()) {
+		t.Fatalf("nodes = %d %q %s", code, out, errOut)
+	}
+	code, out, _ = runCLI("nodes", "--api", c.APIURL())
+	for _, n := range c.Nodes() {
+		if !strings.Contains(out, n.ID) {
+			t.Fatalf("nodes 输出缺少 %s:\n%s", n.ID, out)
+		}
+	}
+
+	code, out, errOut = runCLI("stats", "--api", c.APIURL(), "--group", "test")
+	if code != exitOK || !strings.Contains(out, "test") || !strings.Contains(out, "NODE") {
+		t.Fatalf("stats = %d %q %s", code, out, errOut)
+	}
+	code, out, errOut = runCLI("stats", "--node", c.Owner("Tom").Addr, "--proto", "http", "--group", "test")
+	if code != exitOK || !strings.Contains(out, "test") {
+		t.Fatalf("节点 stats = %d %q %s", code, out, errOut)
+	}
+	if code, _, _ := runCLI("stats", "--node", c.Owner("Tom").Addr, "--proto", "http", "--group", "nope"); code != exitNotFound {
+		t.Fatalf("不存在的组 stats 退出码 %d", code)
+	}
+	if code, _, _ := runCLI("nodes", "--node", c.Owner("Tom").Addr); code != exitError {
+		t.Fatalf("直接访问节点时 nodes 退出码 %d", code)
+	}
+}
+
+func TestWarm(t *testing.T) {
+	c := startCluster(t)
+	source := c.Source("test")
+	source.Set("a", "1")
+	source.Set("b", "2")
+	file := filepath.Join(t.TempDir(), "keys.txt")
+	if err := os.WriteFile(file, []byte("# 注释\na\n\nb\nmissing\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, extra := range [][]string{{"--api", c.APIURL()}, {"--node", c.Nodes()[0].Addr, "--proto", "http"}} {
+		code, out, errOut := runCLI(append([]string{"warm", "test", "--file", file, "--json"}, extra...)...)
+		var result warmResult
+		if code != exitOK || json.Unmarshal([]byte(out), &result) != nil {
+			t.Fatalf("warm %v = %d %q %s", extra, code, out, errOut)
+		}
+		if result.Keys != 3 || result.Loaded != 2 || result.Missing != 1 {
+			t.Fatalf("warm %v = %+v", extra, result)
+		}
+	}
+	for _, key := range []string{"a", "b"} {
+		if _, _, ok := c.Owner(key).Group("test").Peek(key); !ok {
+			t.Fatalf("预热后 %s 不在归属节点的缓存中", key)
+		}
+	}
+
+	if code, _, _ := runCLI("warm", "test", "--api", c.APIURL()); code != exitError {
+		t.Fatalf("缺少 --file 时退出码 %d", code)
+	}
+}
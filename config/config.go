@@ -3,9 +3,11 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // Config represents the application configuration
@@ -20,10 +22,248 @@ type Config struct {
 	Host          string   `json:"host"`
 	BasePath      string   `json:"base_path"`
 	PeerAddresses []string `json:"peer_addresses"`
+	// Static peers applied at startup until the first discovery update arrives,
+	// each as [id=]grpc_addr[|http_addr]
+	SeedPeers []string `json:"seed_peers"`
 
 	// Logging settings
 	LogLevel  string `json:"log_level"`
 	LogFormat string `json:"log_format"`
+	// Asynchronous logging: a background goroutine writes log lines from a
+	// buffer of log_buffer_size lines; log_overflow is drop-oldest or block
+	LogAsync      bool   `json:"log_async"`
+	LogBufferSize int    `json:"log_buffer_size"`
+	LogOverflow   string `json:"log_overflow"`
+	// How cache keys appear in logs: plain, hash (a short stable digest) or
+	// omit; empty means plain
+	LogKeyMode string `json:"log_key_mode"`
+
+	// Timeout settings
+	Timeouts TimeoutConfig `json:"timeouts"`
+
+	// Health check thresholds
+	Health HealthConfig `json:"health"`
+
+	// Signing of internal requests
+	Auth AuthConfig `json:"auth"`
+
+	// Client tokens of the API server and the groups they may use
+	Access AccessConfig `json:"access"`
+
+	// Upstreams the API server proxies to on /api/proxy/{group}/{path...}
+	Proxy []ProxyConfig `json:"proxy"`
+
+	// Per-group settings
+	Groups []GroupConfig `json:"groups"`
+
+	// Groups a cache node serves to peers and the API server, empty means all;
+	// the cache node reloads it on SIGHUP
+	ServableGroups []string `json:"servable_groups"`
+
+	// Aggregate Get requests per second over every group on a node, 0 means unlimited
+	NodeRateLimit float64 `json:"node_rate_limit"`
+	NodeRateBurst int     `json:"node_rate_burst"`
+}
+
+// GroupConfig describes a cache group, its data source, its entry lifetime limits and its QPS limit
+type GroupConfig struct {
+	Name          string       `json:"name"`
+	Source        SourceConfig `json:"source"`
+	MaxBytes      int64        `json:"max_bytes"`
+	TTL           Duration     `json:"ttl"`            // sliding ttl restarted on every write, 0 means none
+	MaxAge        Duration     `json:"max_age"`        // absolute lifetime from insertion, 0 means unlimited
+	MaxIdle       Duration     `json:"max_idle"`       // evict when not read or written for this long, 0 means unlimited
+	SweepInterval Duration     `json:"sweep_interval"` // background expiry sweep interval, 0 disables it
+	RateLimit     float64      `json:"rate_limit"`     // Get requests per second, 0 means unlimited
+	RateBurst     int          `json:"rate_burst"`     // token bucket size, defaults to ceil(rate_limit)
+	RefreshAhead  float64      `json:"refresh_ahead"`  // fraction of the ttl after which a hit refreshes the entry, 0 disables it
+	Eviction      string       `json:"eviction"`       // eviction policy: lru (default), clock or cost
+	MissPolicy    string       `json:"miss_policy"`    // origin-fallback (default), peer-only or origin-only-if-owner
+	DeleteMarker  Duration     `json:"delete_marker"`  // how long the owner makes Gets of a deleted key share one reload, 0 disables it
+	HotKeys       HotKeyConfig `json:"hot_keys"`       // hot-key tracking and replication
+
+	OriginBreaker BreakerConfig `json:"origin_breaker"` // circuit breaker around the data source
+
+	TombstoneRetention Duration `json:"tombstone_retention"` // how long a deleted key is answered as missing and loads begun before the delete are not stored, 0 disables tombstones
+	TombstoneCapacity  int      `json:"tombstone_capacity"`  // most tombstones kept per group, 10000 when 0
+
+	EvictionWarnAge Duration `json:"eviction_warn_age"` // track the age of evicted entries and warn when the median drops below it, 0 disables tracking
+
+	// Early eviction in the background: above high_watermark times max_bytes the
+	// group evicts down to low_watermark times max_bytes, both 0 disable it
+	HighWatermark float64 `json:"high_watermark"`
+	LowWatermark  float64 `json:"low_watermark"`
+
+	EncryptValues bool `json:"encrypt_values"` // keep values AES-GCM encrypted in memory and exports, with the node's value keys
+
+	// Total time a Get without a deadline may take, covering the owner fetch,
+	// the fallback and the origin load, 0 means unlimited; strict_deadline also
+	// caps later caller deadlines
+	DefaultDeadline Duration `json:"default_deadline"`
+	StrictDeadline  bool     `json:"strict_deadline"`
+
+	// Get requests per second each client host may send, on top of rate_limit,
+	// 0 means unlimited; the burst defaults to ceil(client_rate_limit)
+	ClientRateLimit float64 `json:"client_rate_limit"`
+	ClientRateBurst int     `json:"client_rate_burst"`
+}
+
+// BreakerConfig configures the circuit breaker around a group's data source. It
+// is on when FailureRate is positive.
+type BreakerConfig struct {
+	FailureRate float64  `json:"failure_rate"` // fraction of failed loads in a window that opens the breaker
+	Window      Duration `json:"window"`       // interval loads are counted over, 10s when 0
+	MinRequests int      `json:"min_requests"` // loads a window needs before it can open the breaker, 10 when 0
+	OpenFor     Duration `json:"open_for"`     // time spent open before probing the data source, 5s when 0
+	Probes      int      `json:"probes"`       // successful probes that close the breaker, 1 when 0
+}
+
+// HotKeyConfig configures hot-key tracking and replication of a group. Tracking is
+// on when Track is set or QPSThreshold is positive; replication additionally needs
+// Replicas to be positive.
+type HotKeyConfig struct {
+	Track        bool     `json:"track"`         // count reads per key for the hot-key report
+	QPSThreshold float64  `json:"qps_threshold"` // reads per second that replicate a key, 0 disables replication
+	Replicas     int      `json:"replicas"`      // nodes after the owner that receive a copy of a hot key
+	ReplicaTTL   Duration `json:"replica_ttl"`   // lifetime of a copy, 30s when 0
+	Window       Duration `json:"window"`        // interval reads are counted over, 1s when 0
+	MaxTracked   int      `json:"max_tracked"`   // distinct keys counted per window, 1024 when 0
+}
+
+// Enabled reports whether the group tracks hot keys
+func (c HotKeyConfig) Enabled() bool {
+	return c.Track || c.QPSThreshold > 0
+}
+
+// Data source types of a group
+const (
+	SourceNone  = "none"  // no data source, keys that are not cached are reported as not found
+	SourceDemo  = "demo"  // the built-in sample data, for demos and local testing only
+	SourceHTTP  = "http"  // GET {url}/{key}, 404 means not found
+	SourceExec  = "exec"  // run command with {key} substituted, stdout is the value, exit status 1 means not found
+	SourceRedis = "redis" // GET key from the redis server at url
+)
+
+// SourceConfig selects where a group loads keys that are not cached.
+// An empty type means SourceNone; the demo data is only used when asked for explicitly.
+type SourceConfig struct {
+	Type    string   `json:"type"`
+	URL     string   `json:"url"`     // base URL of the http source, redis://[user:password@]host:port[/db] of the redis source
+	Command string   `json:"command"` // command template of the exec source, e.g. "cat /data/{key}"
+	Timeout Duration `json:"timeout"` // timeout of one load, 5s when 0
+}
+
+// TimeoutConfig groups every network timeout used by the components
+type TimeoutConfig struct {
+	PeerRequest Duration `json:"peer_request"` // node-to-node request timeout
+	PeerDial    Duration `json:"peer_dial"`    // dial timeout for peer connections
+	APIRequest  Duration `json:"api_request"`  // API server to node request timeout
+	EtcdDial    Duration `json:"etcd_dial"`    // etcd client dial timeout
+	Shutdown    Duration `json:"shutdown"`     // graceful shutdown timeout
+}
+
+// DefaultTimeouts returns the timeouts that used to be hard-coded
+func DefaultTimeouts() TimeoutConfig {
+	return TimeoutConfig{
+		PeerRequest: Duration(5 * time.Second),
+		PeerDial:    Duration(2 * time.Second),
+		APIRequest:  Duration(3 * time.Second),
+		EtcdDial:    Duration(5 * time.Second),
+		Shutdown:    Duration(5 * time.Second),
+	}
+}
+
+// AuthConfig holds the shared keys used to sign and verify requests between the
+// API server and cache nodes. Signing is off while Keys is empty.
+type AuthConfig struct {
+	// Active keys as id:secret, at most two; the first signs outgoing requests
+	Keys []string `json:"keys"`
+	// Largest accepted difference between a request timestamp and the local clock,
+	// 0 means the default of 30s
+	ClockSkew Duration `json:"clock_skew"`
+	// Nonces remembered for replay detection, each for twice ClockSkew; requests
+	// beyond that get 503 until the oldest expire. 0 means the default of 1048576.
+	NonceCacheSize int `json:"nonce_cache_size"`
+	// Largest request body read to check a signature, 0 means the default of 64 MiB
+	MaxBodyBytes int64 `json:"max_body_bytes"`
+}
+
+// AccessConfig maps client tokens of the API server to the groups and operations
+// they may use. An access file must list at least one token; access control is
+// off only when the API server is started without one.
+type AccessConfig struct {
+	Tokens []AccessToken `json:"tokens"`
+}
+
+// AccessToken is one client token and its scope
+type AccessToken struct {
+	ID     string   `json:"id"`     // name written to audit logs instead of the token
+	Token  string   `json:"token"`  // bearer token presented by the client
+	Groups []string `json:"groups"` // group names the token may use, "*" for every group
+	Ops    []string `json:"ops"`    // read, write and/or admin, read if empty
+}
+
+// ProxyConfig is the upstream of a group the API server serves as a caching
+// reverse proxy: misses are fetched from UpstreamURL and stored on the key's owner
+type ProxyConfig struct {
+	Group          string   `json:"group"`
+	UpstreamURL    string   `json:"upstream_url"`    // request paths and queries are appended to it
+	Timeout        Duration `json:"timeout"`         // per upstream request, 10s when 0
+	ForwardHeaders []string `json:"forward_headers"` // request headers passed on to the upstream
+	MaxBodyBytes   int64    `json:"max_body_bytes"`  // larger responses are passed through uncached, 1MiB when 0
+	TTL            Duration `json:"ttl"`             // lifetime of stored responses, 5m when 0; a shorter max-age wins
+	NegativeTTL    Duration `json:"negative_ttl"`    // how long the API server remembers an upstream 404, 30s when 0, negative disables it
+	ContentType    string   `json:"content_type"`    // Content-Type of cache hits, sniffed when empty
+}
+
+// HealthConfig holds the thresholds after which /health reports a component as down
+type HealthConfig struct {
+	MaxPeerSyncAge  Duration `json:"max_peer_sync_age"` // cache node: longest time without a successful peer list update
+	MaxDiscoveryLag Duration `json:"max_discovery_lag"` // API server: longest time the etcd watch may stay disconnected
+}
+
+// DefaultHealth returns the default health check thresholds
+func DefaultHealth() HealthConfig {
+	return HealthConfig{
+		MaxPeerSyncAge:  Duration(time.Minute),
+		MaxDiscoveryLag: Duration(30 * time.Second),
+	}
+}
+
+// Duration is a time.Duration that reads from JSON either as a Go duration
+// string ("1.5s", "200ms") or as a number of seconds
+type Duration time.Duration
+
+// Std returns the value as a time.Duration
+func (d Duration) Std() time.Duration {
+	return time.Duration(d)
+}
+
+// MarshalJSON encodes the duration as a Go duration string
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON decodes a duration string or a number of seconds
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	switch value := v.(type) {
+	case float64:
+		*d = Duration(value * float64(time.Second))
+	case string:
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		*d = Duration(parsed)
+	default:
+		return fmt.Errorf("invalid duration: %s", string(data))
+	}
+	return nil
 }
 
 // DefaultConfig returns the default configuration
@@ -38,6 +278,8 @@ func DefaultConfig() *Config {
 		PeerAddresses:      []string{"http://localhost:8001", "http://localhost:8002", "http://localhost:8003"},
 		LogLevel:           "info",
 		LogFormat:          "text",
+		Timeouts:           DefaultTimeouts(),
+		Health:             DefaultHealth(),
 	}
 }
 
@@ -100,6 +342,10 @@ func LoadFromEnv() *Config {
 		config.PeerAddresses = strings.Split(val, ",")
 	}
 
+	if val := os.Getenv("GOCACHE_SEED_PEERS"); val != "" {
+		config.SeedPeers = strings.Split(val, ",")
+	}
+
 	// Logging settings
 	if val := os.Getenv("GOCACHE_LOG_LEVEL"); val != "" {
 		config.LogLevel = val
@@ -109,9 +355,65 @@ func LoadFromEnv() *Config {
 		config.LogFormat = val
 	}
 
+	if val := os.Getenv("GOCACHE_LOG_ASYNC"); val != "" {
+		if parsed, err := strconv.ParseBool(val); err == nil {
+			config.LogAsync = parsed
+		}
+	}
+
+	if val := os.Getenv("GOCACHE_LOG_BUFFER_SIZE"); val != "" {
+		if parsed, err := strconv.Atoi(val); err == nil {
+			config.LogBufferSize = parsed
+		}
+	}
+
+	if val := os.Getenv("GOCACHE_LOG_OVERFLOW"); val != "" {
+		config.LogOverflow = val
+	}
+
+	if val := os.Getenv("GOCACHE_LOG_KEY_MODE"); val != "" {
+		config.LogKeyMode = val
+	}
+
+	// Timeout settings
+	loadDurationEnv("GOCACHE_PEER_REQUEST_TIMEOUT", &config.Timeouts.PeerRequest)
+	loadDurationEnv("GOCACHE_PEER_DIAL_TIMEOUT", &config.Timeouts.PeerDial)
+	loadDurationEnv("GOCACHE_API_REQUEST_TIMEOUT", &config.Timeouts.APIRequest)
+	loadDurationEnv("GOCACHE_ETCD_DIAL_TIMEOUT", &config.Timeouts.EtcdDial)
+	loadDurationEnv("GOCACHE_SHUTDOWN_TIMEOUT", &config.Timeouts.Shutdown)
+
+	// Health check thresholds
+	loadDurationEnv("GOCACHE_MAX_PEER_SYNC_AGE", &config.Health.MaxPeerSyncAge)
+	loadDurationEnv("GOCACHE_MAX_DISCOVERY_LAG", &config.Health.MaxDiscoveryLag)
+
+	// Request signing
+	if val := os.Getenv("GOCACHE_AUTH_KEYS"); val != "" {
+		config.Auth.Keys = strings.Split(val, ",")
+	}
+	loadDurationEnv("GOCACHE_AUTH_CLOCK_SKEW", &config.Auth.ClockSkew)
+	if val := os.Getenv("GOCACHE_AUTH_NONCE_CACHE_SIZE"); val != "" {
+		if parsed, err := strconv.Atoi(val); err == nil {
+			config.Auth.NonceCacheSize = parsed
+		}
+	}
+	if val := os.Getenv("GOCACHE_AUTH_MAX_BODY_BYTES"); val != "" {
+		if parsed, err := strconv.ParseInt(val, 10, 64); err == nil {
+			config.Auth.MaxBodyBytes = parsed
+		}
+	}
+
 	return config
 }
 
+// loadDurationEnv overrides target with a duration parsed from the named environment variable
+func loadDurationEnv(name string, target *Duration) {
+	if val := os.Getenv(name); val != "" {
+		if parsed, err := time.ParseDuration(val); err == nil {
+			*target = Duration(parsed)
+		}
+	}
+}
+
 // SaveToFile saves configuration to a JSON file
 func (c *Config) SaveToFile(filepath string) error {
 	data, err := json.MarshalIndent(c, "", "  ")
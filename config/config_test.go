@@ -0,0 +1,61 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDefaultTimeouts(t *testing.T) {
+	want := TimeoutConfig{
+		PeerRequest: Duration(5 * time.Second),
+		PeerDial:    Duration(2 * time.Second),
+		APIRequest:  Duration(3 * time.Second),
+		EtcdDial:    Duration(5 * time.Second),
+		Shutdown:    Duration(5 * time.Second),
+	}
+	if got := DefaultConfig().Timeouts; got != want {
+		t.Fatalf("DefaultConfig().Timeouts = %+v, want %+v", got, want)
+	}
+}
+
+func TestTimeoutsFromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	// Strings and numbers of seconds are both accepted; omitted timeouts keep the defaults
+	data := `{"timeouts": {"peer_request": "750ms", "shutdown": 12}}`
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadFromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := cfg.Timeouts
+	if got.PeerRequest.Std() != 750*time.Millisecond || got.Shutdown.Std() != 12*time.Second {
+		t.Fatalf("timeouts = %+v", got)
+	}
+	if got.PeerDial != DefaultTimeouts().PeerDial || got.EtcdDial != DefaultTimeouts().EtcdDial {
+		t.Fatalf("omitted timeouts lost their defaults: %+v", got)
+	}
+}
+
+func TestTimeoutsFromEnv(t *testing.T) {
+	t.Setenv("GOCACHE_PEER_REQUEST_TIMEOUT", "1s")
+	t.Setenv("GOCACHE_PEER_DIAL_TIMEOUT", "2s")
+	t.Setenv("GOCACHE_API_REQUEST_TIMEOUT", "3s")
+	t.Setenv("GOCACHE_ETCD_DIAL_TIMEOUT", "4s")
+	t.Setenv("GOCACHE_SHUTDOWN_TIMEOUT", "not a duration")
+
+	got := LoadFromEnv().Timeouts
+	want := TimeoutConfig{
+		PeerRequest: Duration(time.Second),
+		PeerDial:    Duration(2 * time.Second),
+		APIRequest:  Duration(3 * time.Second),
+		EtcdDial:    Duration(4 * time.Second),
+		Shutdown:    DefaultTimeouts().Shutdown, // unparsable values are ignored
+	}
+	if got != want {
+		t.Fatalf("LoadFromEnv().Timeouts = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,178 @@
+// Package access 实现 API 服务器面向客户端的按缓存组授权：
+// 配置把每个令牌映射到允许访问的缓存组和操作（read/write/admin），
+// 中间件把解析出的授权范围放入请求上下文，处理器按请求的组和操作校验
+package access
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"github.com/AdrianWangs/go-cache/config"
+)
+
+// Op 受控的操作类型
+type Op string
+
+const (
+	OpRead  Op = "read"  // 读取缓存
+	OpWrite Op = "write" // 写入、删除缓存
+	OpAdmin Op = "admin" // 导出导入、抽样、哈希环等管理接口
+)
+
+// AllGroups 表示所有缓存组的通配符，也用于检查不针对单个组的集群级操作
+const AllGroups = "*"
+
+// ParseOp 解析操作名
+func ParseOp(s string) (Op, error) {
+	switch op := Op(strings.ToLower(strings.TrimSpace(s))); op {
+	case OpRead, OpWrite, OpAdmin:
+		return op, nil
+	default:
+		return "", fmt.Errorf("未知的操作 %q，只能是 read、write 或 admin", s)
+	}
+}
+
+// Scope 一个令牌的授权范围
+type Scope struct {
+	TokenID string // 令牌标识，写入审计日志
+
+	allGroups bool
+	groups    map[string]bool
+	ops       map[Op]bool
+}
+
+// Allows 判断范围是否允许对 group 执行 op。group 为 AllGroups 时要求令牌拥有通配符
+func (s *Scope) Allows(group string, op Op) bool {
+	if s == nil || !s.ops[op] {
+		return false
+	}
+	return s.allGroups || (group != AllGroups && s.groups[group])
+}
+
+// String 返回范围的可读描述，用于审计日志
+func (s *Scope) String() string {
+	if s == nil {
+		return "none"
+	}
+	groups := make([]string, 0, len(s.groups)+1)
+	if s.allGroups {
+		groups = append(groups, AllGroups)
+	}
+	for g := range s.groups {
+		groups = append(groups, g)
+	}
+	sort.Strings(groups)
+	ops := make([]string, 0, len(s.ops))
+	for _, op := range []Op{OpRead, OpWrite, OpAdmin} {
+		if s.ops[op] {
+			ops = append(ops, string(op))
+		}
+	}
+	return fmt.Sprintf("groups=%v ops=%v", groups, ops)
+}
+
+// Policy 令牌到授权范围的映射，创建后不再修改
+type Policy struct {
+	scopes map[[sha256.Size]byte]*Scope // 以令牌的摘要为键，查找时间与令牌内容无关
+}
+
+// NewPolicy 根据配置创建策略。配置中没有任何令牌时返回错误：没有令牌的策略不做
+// 任何限制，一个写错或被清空的配置文件不能悄悄关闭访问控制
+func NewPolicy(cfg config.AccessConfig) (*Policy, error) {
+	if len(cfg.Tokens) == 0 {
+		return nil, errors.New("访问控制配置中没有任何令牌")
+	}
+	p := &Policy{scopes: make(map[[sha256.Size]byte]*Scope, len(cfg.Tokens))}
+	ids := make(map[string]bool, len(cfg.Tokens))
+	for i, t := range cfg.Tokens {
+		if t.ID == "" {
+			return nil, fmt.Errorf("第 %d 个令牌缺少 id", i+1)
+		}
+		if ids[t.ID] {
+			return nil, fmt.Errorf("令牌 id %q 重复", t.ID)
+		}
+		ids[t.ID] = true
+		if t.Token == "" {
+			return nil, fmt.Errorf("令牌 %s 的 token 为空", t.ID)
+		}
+		if len(t.Groups) == 0 {
+			return nil, fmt.Errorf("令牌 %s 没有配置任何缓存组", t.ID)
+		}
+
+		scope := &Scope{TokenID: t.ID, groups: make(map[string]bool), ops: make(map[Op]bool)}
+		for _, g := range t.Groups {
+			if g == AllGroups {
+				scope.allGroups = true
+			} else if g != "" {
+				scope.groups[g] = true
+			}
+		}
+		ops := t.Ops
+		if len(ops) == 0 {
+			ops = []string{string(OpRead)}
+		}
+		for _, name := range ops {
+			op, err := ParseOp(name)
+			if err != nil {
+				return nil, fmt.Errorf("令牌 %s: %w", t.ID, err)
+			}
+			scope.ops[op] = true
+		}
+
+		sum := sha256.Sum256([]byte(t.Token))
+		if _, ok := p.scopes[sum]; ok {
+			return nil, fmt.Errorf("令牌 %s 的 token 与其他令牌相同", t.ID)
+		}
+		p.scopes[sum] = scope
+	}
+	return p, nil
+}
+
+// Enabled 判断策略是否启用了访问控制
+func (p *Policy) Enabled() bool {
+	return p != nil && len(p.scopes) > 0
+}
+
+// Lookup 返回 token 对应的授权范围，未知令牌返回 nil
+func (p *Policy) Lookup(token string) *Scope {
+	if p == nil || token == "" {
+		return nil
+	}
+	return p.scopes[sha256.Sum256([]byte(token))]
+}
+
+// Store 保存当前生效的策略，支持在运行时整体替换（热加载）
+type Store struct {
+	policy atomic.Pointer[Policy]
+}
+
+// NewStore 创建使用 p 的 Store，p 为 nil 时不做限制
+func NewStore(p *Policy) *Store {
+	s := &Store{}
+	s.policy.Store(p)
+	return s
+}
+
+// Policy 返回当前策略
+func (s *Store) Policy() *Policy {
+	return s.policy.Load()
+}
+
+// Replace 替换策略，之后开始的请求使用新策略
+func (s *Store) Replace(p *Policy) {
+	s.policy.Store(p)
+}
+
+// Reload 根据新的配置创建策略并替换。配置无效或没有令牌时保留原策略并返回错误
+func (s *Store) Reload(cfg config.AccessConfig) error {
+	p, err := NewPolicy(cfg)
+	if err != nil {
+		return err
+	}
+	s.Replace(p)
+	return nil
+}
@@ -0,0 +1,191 @@
+package access
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/AdrianWangs/go-cache/config"
+)
+
+// testConfig 三个令牌：只读两个组、读写所有组、管理所有组
+func testConfig() config.AccessConfig {
+	return config.AccessConfig{Tokens: []config.AccessToken{
+		{ID: "team-a", Token: "tok-a", Groups: []string{"users", "orders"}},
+		{ID: "writer", Token: "tok-w", Groups: []string{"*"}, Ops: []string{"read", "write"}},
+		{ID: "ops", Token: "tok-o", Groups: []string{"*"}, Ops: []string{"admin"}},
+	}}
+}
+
+func mustPolicy(t *testing.T, cfg config.AccessConfig) *Policy {
+	t.Helper()
+	p, err := NewPolicy(cfg)
+	if err != nil {
+		t.Fatalf("NewPolicy: %v", err)
+	}
+	return p
+}
+
+func TestScopeAllows(t *testing.T) {
+	p := mustPolicy(t, testConfig())
+
+	tests := []struct {
+		name  string
+		token string
+		group string
+		op    Op
+		want  bool
+	}{
+		{"指定组允许读", "tok-a", "users", OpRead, true},
+		{"指定组中的另一个组", "tok-a", "orders", OpRead, true},
+		{"未列出的组拒绝", "tok-a", "billing", OpRead, false},
+		{"未授权的操作拒绝", "tok-a", "users", OpWrite, false},
+		{"具体组令牌不能做集群级操作", "tok-a", AllGroups, OpRead, false},
+		{"通配符允许任意组", "tok-w", "billing", OpWrite, true},
+		{"通配符允许集群级操作", "tok-w", AllGroups, OpRead, true},
+		{"通配符不扩展操作", "tok-w", "billing", OpAdmin, false},
+		{"管理令牌不含读权限", "tok-o", "users", OpRead, false},
+		{"管理令牌允许管理", "tok-o", AllGroups, OpAdmin, true},
+		{"未知令牌", "tok-x", "users", OpRead, false},
+		{"空令牌", "", "users", OpRead, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := p.Lookup(tt.token).Allows(tt.group, tt.op); got != tt.want {
+				t.Fatalf("Allows(%q, %s) = %v, want %v", tt.group, tt.op, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewPolicyRejectsInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		tokens []config.AccessToken
+	}{
+		{"没有令牌", nil},
+		{"缺少 id", []config.AccessToken{{Token: "t", Groups: []string{"g"}}}},
+		{"id 重复", []config.AccessToken{{ID: "a", Token: "t1", Groups: []string{"g"}}, {ID: "a", Token: "t2", Groups: []string{"g"}}}},
+		{"token 为空", []config.AccessToken{{ID: "a", Groups: []string{"g"}}}},
+		{"没有组", []config.AccessToken{{ID: "a", Token: "t"}}},
+		{"未知操作", []config.AccessToken{{ID: "a", Token: "t", Groups: []string{"g"}, Ops: []string{"delete"}}}},
+		{"token 相同", []config.AccessToken{{ID: "a", Token: "t", Groups: []string{"g"}}, {ID: "b", Token: "t", Groups: []string{"g"}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewPolicy(config.AccessConfig{Tokens: tt.tokens}); err == nil {
+				t.Fatal("NewPolicy 成功，期望返回错误")
+			}
+		})
+	}
+}
+
+// serve 经过 store 的中间件调用只允许 group 执行 op 的处理器
+func serve(store *Store, token, group string, op Op) *httptest.ResponseRecorder {
+	h := store.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if Authorize(w, r, group, op) {
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/api/cache/"+group+"/k", nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAuthorize(t *testing.T) {
+	store := NewStore(mustPolicy(t, testConfig()))
+
+	tests := []struct {
+		name    string
+		token   string
+		group   string
+		op      Op
+		status  int
+		code    string
+		tokenID string
+	}{
+		{"允许", "tok-a", "users", OpRead, http.StatusNoContent, "", ""},
+		{"通配符允许", "tok-w", "anything", OpWrite, http.StatusNoContent, "", ""},
+		{"组不在范围内", "tok-a", "billing", OpRead, http.StatusForbidden, ErrorCodeForbidden, "team-a"},
+		{"操作不在范围内", "tok-a", "users", OpWrite, http.StatusForbidden, ErrorCodeForbidden, "team-a"},
+		{"管理接口需要管理范围", "tok-w", AllGroups, OpAdmin, http.StatusForbidden, ErrorCodeForbidden, "writer"},
+		{"未携带令牌", "", "users", OpRead, http.StatusUnauthorized, ErrorCodeUnauthorized, ""},
+		{"未知令牌", "tok-x", "users", OpRead, http.StatusUnauthorized, ErrorCodeUnauthorized, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(store, tt.token, tt.group, tt.op)
+			if rec.Code != tt.status {
+				t.Fatalf("状态码 = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			if tt.code == "" {
+				return
+			}
+			var resp ErrorResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("解析错误响应: %v", err)
+			}
+			if resp.Error != tt.code || resp.Group != tt.group || resp.Op != tt.op || resp.TokenID != tt.tokenID {
+				t.Fatalf("错误响应 = %+v, want error=%s group=%s op=%s token_id=%s", resp, tt.code, tt.group, tt.op, tt.tokenID)
+			}
+			if tt.status == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") != "Bearer" {
+				t.Fatalf("401 缺少 WWW-Authenticate 头")
+			}
+		})
+	}
+}
+
+func TestAuthorizeDisabledPolicy(t *testing.T) {
+	for name, store := range map[string]*Store{
+		"nil 策略": NewStore(nil),
+		"没有令牌":   NewStore(&Policy{}),
+	} {
+		t.Run(name, func(t *testing.T) {
+			if rec := serve(store, "", "users", OpAdmin); rec.Code != http.StatusNoContent {
+				t.Fatalf("未启用访问控制时状态码 = %d, want 204", rec.Code)
+			}
+		})
+	}
+}
+
+func TestStoreReload(t *testing.T) {
+	store := NewStore(mustPolicy(t, testConfig()))
+	if rec := serve(store, "tok-a", "billing", OpRead); rec.Code != http.StatusForbidden {
+		t.Fatalf("重新加载前状态码 = %d, want 403", rec.Code)
+	}
+
+	cfg := testConfig()
+	cfg.Tokens[0].Groups = append(cfg.Tokens[0].Groups, "billing")
+	if err := store.Reload(cfg); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if rec := serve(store, "tok-a", "billing", OpRead); rec.Code != http.StatusNoContent {
+		t.Fatalf("重新加载后状态码 = %d, want 204", rec.Code)
+	}
+
+	// 无效配置不替换当前策略
+	bad := testConfig()
+	bad.Tokens[0].Ops = []string{"delete"}
+	if err := store.Reload(bad); err == nil {
+		t.Fatal("无效配置的 Reload 成功，期望返回错误")
+	}
+	if rec := serve(store, "tok-a", "billing", OpRead); rec.Code != http.StatusNoContent {
+		t.Fatalf("无效配置后状态码 = %d, want 204", rec.Code)
+	}
+
+	// 没有令牌的配置（例如被清空的文件）不能关闭访问控制
+	if err := store.Reload(config.AccessConfig{}); err == nil {
+		t.Fatal("没有令牌的 Reload 成功，期望返回错误")
+	}
+	if rec := serve(store, "", "billing", OpRead); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("空配置后未携带令牌的状态码 = %d, want 401", rec.Code)
+	}
+	if rec := serve(store, "tok-a", "billing", OpRead); rec.Code != http.StatusNoContent {
+		t.Fatalf("空配置后状态码 = %d, want 204", rec.Code)
+	}
+}
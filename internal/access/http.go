@@ -0,0 +1,123 @@
+package access
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/AdrianWangs/go-cache/pkg/logger"
+)
+
+// 结构化错误响应中的错误码，与 API 服务器其他结构化错误的格式相同
+const (
+	ErrorCodeUnauthorized = "Unauthorized"
+	ErrorCodeForbidden    = "Forbidden"
+)
+
+// ErrorResponse 授权失败时的结构化错误响应
+type ErrorResponse struct {
+	Error   string `json:"error"`              // 错误码
+	Group   string `json:"group,omitempty"`    // 请求的缓存组
+	Op      Op     `json:"op,omitempty"`       // 请求的操作
+	TokenID string `json:"token_id,omitempty"` // 令牌标识
+	Message string `json:"message"`            // 错误描述
+}
+
+// requestScope 中间件放入请求上下文的授权信息
+type requestScope struct {
+	scope *Scope // 令牌的授权范围，未携带或未知令牌时为 nil
+}
+
+type contextKey struct{}
+
+// Middleware 解析 Authorization: Bearer <token>，把对应的授权范围放入请求上下文。
+// 策略未启用时请求原样通过，处理器中的 Authorize 不做限制；
+// 中间件本身不拒绝请求，健康检查、节点列表等不区分组的接口不受影响
+func (s *Store) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := s.Policy()
+		if !p.Enabled() {
+			next.ServeHTTP(w, r)
+			return
+		}
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		ctx := context.WithValue(r.Context(), contextKey{}, &requestScope{scope: p.Lookup(token)})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// Enforced 判断请求是否经过启用了访问控制的中间件
+func Enforced(ctx context.Context) bool {
+	_, ok := ctx.Value(contextKey{}).(*requestScope)
+	return ok
+}
+
+// ScopeFrom 返回请求令牌的授权范围，未启用访问控制或令牌未知时返回 nil
+func ScopeFrom(ctx context.Context) *Scope {
+	if rs, ok := ctx.Value(contextKey{}).(*requestScope); ok {
+		return rs.scope
+	}
+	return nil
+}
+
+// auditKeyPrefix 审计日志中路径按 前缀+组名/key 处理 key 的前缀，即单个 key 的读写删除路由
+const auditKeyPrefix = "/api/cache/"
+
+// TokenID 返回请求令牌的标识，用于审计日志；没有已知令牌时返回 "-"
+func TokenID(ctx context.Context) string {
+	if scope := ScopeFrom(ctx); scope != nil {
+		return scope.TokenID
+	}
+	return "-"
+}
+
+// Authorize 检查请求是否允许对 group 执行 op。未启用访问控制时总是允许；
+// 没有有效令牌返回 401，令牌范围不包含该组或操作返回 403，响应为结构化 JSON。
+// 写入和管理操作的每次判定以及所有拒绝都写入审计日志。返回 false 时响应已经写出
+func Authorize(w http.ResponseWriter, r *http.Request, group string, op Op) bool {
+	if !Enforced(r.Context()) {
+		return true
+	}
+
+	scope := ScopeFrom(r.Context())
+	allowed := scope.Allows(group, op)
+	if !allowed || op != OpRead {
+		decision := "allow"
+		if !allowed {
+			decision = "deny"
+		}
+		logger.Infof("[审计] token=%s scope=%s op=%s group=%s decision=%s %s %s from=%s",
+			TokenID(r.Context()), scope, op, group, decision, r.Method, logger.KeyPath(r.URL.Path, auditKeyPrefix), r.RemoteAddr)
+	}
+	if allowed {
+		return true
+	}
+
+	if scope == nil {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeError(w, http.StatusUnauthorized, ErrorResponse{
+			Error:   ErrorCodeUnauthorized,
+			Group:   group,
+			Op:      op,
+			Message: "missing or unknown access token",
+		})
+		return false
+	}
+	writeError(w, http.StatusForbidden, ErrorResponse{
+		Error:   ErrorCodeForbidden,
+		Group:   group,
+		Op:      op,
+		TokenID: scope.TokenID,
+		Message: fmt.Sprintf("token %s may not %s group %s", scope.TokenID, op, group),
+	})
+	return false
+}
+
+// writeError 写出结构化的错误响应
+func writeError(w http.ResponseWriter, status int, resp ErrorResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
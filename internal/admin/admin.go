@@ -0,0 +1,95 @@
+// Package admin 提供缓存节点与 API 服务器管理接口共用的鉴权和限流
+package admin
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Authorize 校验请求头 Authorization: Bearer <token>。
+// token 为空表示未开启管理接口，所有请求返回 403；校验失败返回 401。
+// 返回 false 时响应已经写出，调用方直接返回即可。
+func Authorize(w http.ResponseWriter, r *http.Request, token string) bool {
+	if token == "" {
+		http.Error(w, "Forbidden: admin API is disabled", http.StatusForbidden)
+		return false
+	}
+
+	got := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+// Limiter 限制同时进行的管理操作数量，并控制每个操作处理条目的速率
+type Limiter struct {
+	sem      chan struct{} // 并发操作令牌
+	interval time.Duration // 相邻两个条目的最小间隔，0 表示不限速
+
+	mu   sync.Mutex
+	next time.Time // 下一个条目最早的处理时间
+}
+
+// NewLimiter 创建限流器，concurrency 为最大并发操作数（至少为 1），
+// entriesPerSecond 为所有操作合计的条目速率上限，0 表示不限速
+func NewLimiter(concurrency, entriesPerSecond int) *Limiter {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	l := &Limiter{sem: make(chan struct{}, concurrency)}
+	if entriesPerSecond > 0 {
+		l.interval = time.Second / time.Duration(entriesPerSecond)
+	}
+	return l
+}
+
+// TryAcquire 尝试开始一个管理操作，达到并发上限时返回 false
+func (l *Limiter) TryAcquire() bool {
+	select {
+	case l.sem <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// Release 结束一个管理操作
+func (l *Limiter) Release() {
+	<-l.sem
+}
+
+// Wait 等待处理下一个条目的配额，ctx 取消时返回其错误
+func (l *Limiter) Wait(ctx context.Context) error {
+	if l.interval == 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	delay := l.next.Sub(now)
+	l.next = l.next.Add(l.interval)
+	l.mu.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,73 @@
+package admin
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAuthorize(t *testing.T) {
+	tests := []struct {
+		name   string
+		token  string
+		header string
+		want   int
+	}{
+		{"关闭", "", "Bearer secret", http.StatusForbidden},
+		{"缺少令牌", "secret", "", http.StatusUnauthorized},
+		{"令牌错误", "secret", "Bearer wrong", http.StatusUnauthorized},
+		{"令牌正确", "secret", "Bearer secret", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/admin/info", nil)
+			if tt.header != "" {
+				r.Header.Set("Authorization", tt.header)
+			}
+			w := httptest.NewRecorder()
+			ok := Authorize(w, r, tt.token)
+			if ok != (tt.want == http.StatusOK) || w.Code != tt.want {
+				t.Fatalf("Authorize = %v, status %d; want status %d", ok, w.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestLimiterConcurrency(t *testing.T) {
+	l := NewLimiter(0, 0)
+	if !l.TryAcquire() {
+		t.Fatal("第一个操作被拒绝")
+	}
+	if l.TryAcquire() {
+		t.Fatal("并发数为 0 时应按 1 处理")
+	}
+	l.Release()
+	if !l.TryAcquire() {
+		t.Fatal("释放后仍被拒绝")
+	}
+}
+
+func TestLimiterRate(t *testing.T) {
+	l := NewLimiter(1, 100)
+	start := time.Now()
+	for i := 0; i < 11; i++ {
+		if err := l.Wait(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 第一个条目不等待，之后每个条目间隔 10ms
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Fatalf("11 个条目用时 %v，期望至少 100ms", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for i := 0; i < 100; i++ {
+		if err := l.Wait(ctx); err != nil {
+			return
+		}
+	}
+	t.Fatal("ctx 取消后 Wait 没有返回错误")
+}
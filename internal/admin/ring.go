@@ -0,0 +1,39 @@
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/AdrianWangs/go-cache/internal/consistenthash"
+)
+
+const (
+	// DefaultRingSamples /api/admin/ring 默认哈希的模拟 key 数
+	DefaultRingSamples = 100000
+	// MaxRingSamples samples 参数的上限，避免一次请求占用过多 CPU
+	MaxRingSamples = 10000000
+)
+
+// RingHandler 处理 GET /api/admin/ring?samples=N：返回哈希环的虚拟节点倍数、哈希函数、
+// 弧长统计以及 N 个模拟 key 在各节点上的占比。鉴权由调用方完成
+func RingHandler(w http.ResponseWriter, r *http.Request, report func(samples int) consistenthash.Report) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	samples := DefaultRingSamples
+	if v := r.URL.Query().Get("samples"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > MaxRingSamples {
+			http.Error(w, fmt.Sprintf("Bad Request: samples must be between 1 and %d", MaxRingSamples), http.StatusBadRequest)
+			return
+		}
+		samples = n
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report(samples))
+}
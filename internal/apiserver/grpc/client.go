@@ -2,148 +2,194 @@ package grpc
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync"
 	"time"
 
+	"github.com/AdrianWangs/go-cache/internal/auth"
 	"github.com/AdrianWangs/go-cache/pkg/logger"
 	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
 	"google.golang.org/grpc/credentials/insecure"
 )
 
-// CacheClient gRPC缓存客户端
+const (
+	defaultRequestTimeout = 3 * time.Second // 默认请求超时
+	defaultDialTimeout    = 2 * time.Second // 默认建立连接超时
+)
+
+// ErrClientClosed 客户端已经关闭
+var ErrClientClosed = errors.New("grpc cache client is closed")
+
+// CacheClient gRPC缓存客户端，可以被多个 goroutine 同时使用。
+// 连接在第一次调用时才建立，断开后由 gRPC 在后台重连；
+// 调用等待连接就绪 (WaitForReady)，直到各自的请求超时为止
 type CacheClient struct {
-	addr    string
+	addr        string
+	dialTimeout time.Duration
+	signer      *auth.Signer // 请求签名，为 nil 时不签名
+
+	mu      sync.Mutex
 	conn    *grpc.ClientConn
 	client  pb.GroupCacheClient
 	timeout time.Duration
+	closed  bool // Close 之后为 true，之后的调用返回 ErrClientClosed
+
+	dialOpts []grpc.DialOption // 附加的连接选项，见 withDialOptions
+}
+
+// ClientOption 配置 CacheClient
+type ClientOption func(*CacheClient)
+
+// WithRequestTimeout 设置单次请求超时，包括等待连接就绪的时间
+func WithRequestTimeout(timeout time.Duration) ClientOption {
+	return func(c *CacheClient) {
+		if timeout > 0 {
+			c.timeout = timeout
+		}
+	}
+}
+
+// WithDialTimeout 设置每次尝试建立连接的超时，超时后按退避间隔重试
+func WithDialTimeout(timeout time.Duration) ClientOption {
+	return func(c *CacheClient) {
+		if timeout > 0 {
+			c.dialTimeout = timeout
+		}
+	}
+}
+
+// WithSigner 对每个请求签名，用于开启了签名校验的缓存节点
+func WithSigner(signer *auth.Signer) ClientOption {
+	return func(c *CacheClient) {
+		c.signer = signer
+	}
 }
 
-// NewCacheClient 创建一个新的gRPC缓存客户端
-func NewCacheClient(addr string) *CacheClient {
-	return &CacheClient{
-		addr:    addr,
-		timeout: 3 * time.Second, // 默认超时时间
+// withDialOptions 附加连接选项，测试用它替换拨号方式
+func withDialOptions(opts ...grpc.DialOption) ClientOption {
+	return func(c *CacheClient) {
+		c.dialOpts = append(c.dialOpts, opts...)
 	}
 }
 
-// Connect 连接到gRPC服务器
+// NewCacheClient 创建一个新的gRPC缓存客户端，不建立连接
+func NewCacheClient(addr string, opts ...ClientOption) *CacheClient {
+	c := &CacheClient{
+		addr:        addr,
+		timeout:     defaultRequestTimeout,
+		dialTimeout: defaultDialTimeout,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Connect 创建到gRPC服务器的连接。连接不阻塞：服务器暂时不可达时同样返回 nil，
+// 连接在后台建立，调用会等待它就绪。已有连接时不做任何事；Get 和 Delete 会自动调用它
 func (c *CacheClient) Connect() error {
-	// 如果已经有连接了，先关闭
-	if c.conn != nil {
-		c.conn.Close()
+	_, err := c.groupCache()
+	return err
+}
+
+// groupCache 返回连接的 GroupCacheClient，尚未连接时创建连接
+func (c *CacheClient) groupCache() (pb.GroupCacheClient, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil, ErrClientClosed
+	}
+	if c.client != nil {
+		return c.client, nil
 	}
 
-	// 创建新连接
-	conn, err := grpc.Dial(c.addr,
+	dialOpts := []grpc.DialOption{
 		grpc.WithTransportCredentials(insecure.NewCredentials()),
-		grpc.WithBlock(),
-		grpc.WithTimeout(2*time.Second),
-	)
+		grpc.WithConnectParams(grpc.ConnectParams{
+			Backoff:           backoff.DefaultConfig,
+			MinConnectTimeout: c.dialTimeout,
+		}),
+	}
+	if c.signer != nil {
+		dialOpts = append(dialOpts,
+			grpc.WithUnaryInterceptor(c.signer.UnaryClientInterceptor()),
+			grpc.WithStreamInterceptor(c.signer.StreamClientInterceptor()),
+		)
+	}
+	dialOpts = append(dialOpts, c.dialOpts...)
+	conn, err := grpc.Dial(c.addr, dialOpts...)
 	if err != nil {
-		return fmt.Errorf("无法连接到gRPC服务器 %s: %v", c.addr, err)
+		return nil, fmt.Errorf("无法连接到gRPC服务器 %s: %v", c.addr, err)
 	}
 
 	c.conn = conn
 	c.client = pb.NewGroupCacheClient(conn)
-	logger.Debugf("已连接到gRPC服务器: %s", c.addr)
-	return nil
+	logger.Debugf("已创建到gRPC服务器的连接: %s", c.addr)
+	return c.client, nil
+}
+
+// callContext 返回单次调用的上下文，截止时间为当前的请求超时
+func (c *CacheClient) callContext() (context.Context, context.CancelFunc) {
+	c.mu.Lock()
+	timeout := c.timeout
+	c.mu.Unlock()
+	return context.WithTimeout(context.Background(), timeout)
 }
 
-// Close 关闭连接
+// Close 关闭连接，之后的调用返回 ErrClientClosed。可以多次调用，只有第一次关闭连接
 func (c *CacheClient) Close() error {
-	if c.conn != nil {
-		err := c.conn.Close()
-		c.conn = nil
-		c.client = nil
-		return err
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closed = true
+	if c.conn == nil {
+		return nil
 	}
-	return nil
+	err := c.conn.Close()
+	c.conn = nil
+	c.client = nil
+	return err
 }
 
 // Get 通过gRPC获取缓存值
 func (c *CacheClient) Get(group string, key string) ([]byte, error) {
-	// 确保已连接
-	if c.client == nil {
-		if err := c.Connect(); err != nil {
-			return nil, err
-		}
+	client, err := c.groupCache()
+	if err != nil {
+		return nil, err
 	}
 
-	// 创建上下文
-	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	ctx, cancel := c.callContext()
 	defer cancel()
 
-	// 发送请求
-	req := &pb.Request{
-		Group: group,
-		Key:   key,
-	}
-
-	// 尝试请求
-	resp, err := c.client.Get(ctx, req)
+	// 连接断开时由 gRPC 重连，调用在截止时间内等待连接就绪，不需要手动重连重试
+	resp, err := client.Get(ctx, &pb.Request{Group: group, Key: key}, grpc.WaitForReady(true))
 	if err != nil {
-		// 连接错误时尝试重连
-		c.conn.Close()
-		c.conn = nil
-		c.client = nil
-
-		if err := c.Connect(); err != nil {
-			return nil, fmt.Errorf("重连失败: %v", err)
-		}
-
-		// 重试一次
-		resp, err = c.client.Get(ctx, req)
-		if err != nil {
-			return nil, err
-		}
+		return nil, err
 	}
-
 	return resp.Value, nil
 }
 
 // Delete 通过gRPC删除缓存值
 func (c *CacheClient) Delete(group string, key string) error {
-	// 确保已连接
-	if c.client == nil {
-		if err := c.Connect(); err != nil {
-			return err
-		}
+	client, err := c.groupCache()
+	if err != nil {
+		return err
 	}
 
-	// 创建上下文
-	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	ctx, cancel := c.callContext()
 	defer cancel()
 
-	// 发送请求
-	req := &pb.DeleteRequest{
-		Group: group,
-		Key:   key,
-	}
-
-	// 尝试请求
-	_, err := c.client.Delete(ctx, req)
-	if err != nil {
-		// 连接错误时尝试重连
-		c.conn.Close()
-		c.conn = nil
-		c.client = nil
-
-		if err := c.Connect(); err != nil {
-			return fmt.Errorf("重连失败: %v", err)
-		}
-
-		// 重试一次
-		_, err = c.client.Delete(ctx, req)
-		if err != nil {
-			return err
-		}
-	}
-
-	return nil
+	_, err = client.Delete(ctx, &pb.DeleteRequest{Group: group, Key: key}, grpc.WaitForReady(true))
+	return err
 }
 
-// SetTimeout 设置客户端请求超时
+// SetTimeout 设置客户端请求超时，对之后开始的调用生效
 func (c *CacheClient) SetTimeout(timeout time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	c.timeout = timeout
 }
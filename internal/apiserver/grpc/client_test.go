@@ -0,0 +1,242 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// echoServer 读取时返回 "v:" 加 key，并记录调用次数
+type echoServer struct {
+	pb.UnimplementedGroupCacheServer
+	gets    atomic.Int64
+	deletes atomic.Int64
+}
+
+func (s *echoServer) Get(ctx context.Context, req *pb.Request) (*pb.Response, error) {
+	s.gets.Add(1)
+	return &pb.Response{Value: []byte("v:" + req.GetKey())}, nil
+}
+
+func (s *echoServer) Delete(ctx context.Context, req *pb.DeleteRequest) (*pb.DeleteResponse, error) {
+	s.deletes.Add(1)
+	return &pb.DeleteResponse{}, nil
+}
+
+// bufnet 是内存中的网络，客户端拨号时连接当前的监听器；
+// 服务器可以在客户端创建之后才启动，也可以停止后在新的监听器上重启
+type bufnet struct {
+	t        *testing.T
+	listener atomic.Pointer[bufconn.Listener]
+	server   *echoServer
+}
+
+func newBufnet(t *testing.T) *bufnet {
+	n := &bufnet{t: t, server: &echoServer{}}
+	n.listener.Store(bufconn.Listen(1 << 16))
+	t.Cleanup(func() { n.listener.Load().Close() })
+	return n
+}
+
+// client 创建通过 n 拨号的客户端，测试结束时关闭
+func (n *bufnet) client(opts ...ClientOption) *CacheClient {
+	dialer := grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+		return n.listener.Load().Dial()
+	})
+	c := NewCacheClient("bufnet", append(opts, withDialOptions(dialer))...)
+	n.t.Cleanup(func() { c.Close() })
+	return c
+}
+
+// serve 在当前的监听器上启动服务器，返回停止函数
+func (n *bufnet) serve() (stop func()) {
+	s := grpc.NewServer()
+	pb.RegisterGroupCacheServer(s, n.server)
+	lis := n.listener.Load()
+	go s.Serve(lis)
+	n.t.Cleanup(s.Stop)
+	return s.Stop
+}
+
+// TestLazyConnect 服务器尚未启动时 Connect 立即返回，第一次调用等待服务器启动后成功
+func TestLazyConnect(t *testing.T) {
+	n := newBufnet(t)
+	c := n.client(WithRequestTimeout(5 * time.Second))
+
+	start := time.Now()
+	if err := c.Connect(); err != nil {
+		t.Fatalf("服务器未启动时 Connect = %v", err)
+	}
+	if err := c.Connect(); err != nil {
+		t.Fatalf("再次 Connect = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Connect 阻塞了 %v", elapsed)
+	}
+
+	got := make(chan error, 1)
+	go func() {
+		v, err := c.Get("scores", "Tom")
+		if err == nil && string(v) != "v:Tom" {
+			err = fmt.Errorf("值 = %q", v)
+		}
+		got <- err
+	}()
+	time.Sleep(100 * time.Millisecond)
+	n.serve()
+	select {
+	case err := <-got:
+		if err != nil {
+			t.Fatalf("服务器启动后 Get = %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("服务器启动后 Get 没有返回")
+	}
+	if err := c.Delete("scores", "Tom"); err != nil || n.server.deletes.Load() != 1 {
+		t.Fatalf("Delete = %v, 服务器收到 %d 次删除", err, n.server.deletes.Load())
+	}
+}
+
+// TestCallDeadline 服务器始终不可达时，调用在各自的请求超时后返回，SetTimeout 对之后的调用生效
+func TestCallDeadline(t *testing.T) {
+	n := newBufnet(t)
+	c := n.client(WithRequestTimeout(100*time.Millisecond), WithDialTimeout(50*time.Millisecond))
+
+	for _, timeout := range []time.Duration{100 * time.Millisecond, 300 * time.Millisecond} {
+		c.SetTimeout(timeout)
+		start := time.Now()
+		_, err := c.Get("scores", "Tom")
+		elapsed := time.Since(start)
+		if status.Code(err) != codes.DeadlineExceeded {
+			t.Fatalf("Get = %v, want DeadlineExceeded", err)
+		}
+		if elapsed < timeout-20*time.Millisecond || elapsed > timeout+500*time.Millisecond {
+			t.Fatalf("超时 %v 的调用在 %v 后返回", timeout, elapsed)
+		}
+	}
+}
+
+// TestReconnect 服务器重启后由 gRPC 在后台重连，不需要重新创建客户端
+func TestReconnect(t *testing.T) {
+	n := newBufnet(t)
+	c := n.client(WithRequestTimeout(5 * time.Second))
+	stop := n.serve()
+	if _, err := c.Get("scores", "Tom"); err != nil {
+		t.Fatal(err)
+	}
+
+	stop()
+	n.listener.Store(bufconn.Listen(1 << 16))
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		n.serve()
+	}()
+	if v, err := c.Get("scores", "Jack"); err != nil || string(v) != "v:Jack" {
+		t.Fatalf("重启后 Get = %q, %v", v, err)
+	}
+	if n.server.gets.Load() != 2 {
+		t.Fatalf("服务器收到 %d 次读取, want 2", n.server.gets.Load())
+	}
+}
+
+func TestClose(t *testing.T) {
+	n := newBufnet(t)
+	n.serve()
+
+	// 没有建立过连接时同样可以关闭
+	if err := n.client().Close(); err != nil {
+		t.Fatalf("未连接时 Close = %v", err)
+	}
+
+	c := n.client()
+	if _, err := c.Get("scores", "Tom"); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := c.Close(); err != nil {
+			t.Fatalf("第 %d 次 Close = %v", i+1, err)
+		}
+	}
+	if _, err := c.Get("scores", "Tom"); !errors.Is(err, ErrClientClosed) {
+		t.Fatalf("关闭后 Get = %v, want ErrClientClosed", err)
+	}
+	if err := c.Delete("scores", "Tom"); !errors.Is(err, ErrClientClosed) {
+		t.Fatalf("关闭后 Delete = %v, want ErrClientClosed", err)
+	}
+	if err := c.Connect(); !errors.Is(err, ErrClientClosed) {
+		t.Fatalf("关闭后 Connect = %v, want ErrClientClosed", err)
+	}
+}
+
+// TestConcurrentUse 多个 goroutine 同时使用同一个客户端，第一次调用在服务器启动前开始，
+// 最后与 Close 并发；需要在 -race 下运行
+func TestConcurrentUse(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+	n := newBufnet(t)
+	c := n.client(WithRequestTimeout(5 * time.Second))
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		n.serve()
+	}()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				key := fmt.Sprintf("k%d-%d", w, i)
+				v, err := c.Get("scores", key)
+				if err == nil && string(v) != "v:"+key {
+					err = fmt.Errorf("Get(%s) = %q", key, v)
+				}
+				if err == nil && i%10 == 0 {
+					err = c.Delete("scores", key)
+					c.SetTimeout(5 * time.Second)
+				}
+				if err != nil {
+					errs <- err
+					return
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+	if n.server.gets.Load() != 400 || n.server.deletes.Load() != 40 {
+		t.Fatalf("服务器收到 %d 次读取、%d 次删除", n.server.gets.Load(), n.server.deletes.Load())
+	}
+
+	// 与 Close 并发的调用要么完成，要么返回 ErrClientClosed 或 Canceled
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 20; i++ {
+				_, err := c.Get("scores", "Tom")
+				if err != nil && !errors.Is(err, ErrClientClosed) && status.Code(err) != codes.Canceled {
+					t.Errorf("与 Close 并发的 Get = %v", err)
+					return
+				}
+			}
+		}()
+	}
+	c.Close()
+	wg.Wait()
+}
@@ -11,9 +11,9 @@ type PeerGetter struct {
 }
 
 // NewPeerGetter 创建一个新的gRPC PeerGetter
-func NewPeerGetter(addr string) *PeerGetter {
+func NewPeerGetter(addr string, opts ...ClientOption) *PeerGetter {
 	return &PeerGetter{
-		client: NewCacheClient(addr),
+		client: NewCacheClient(addr, opts...),
 	}
 }
 
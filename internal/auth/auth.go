@@ -0,0 +1,324 @@
+// Package auth signs and verifies internal requests between the API server and
+// cache nodes and between nodes.
+//
+// The sender computes an HMAC-SHA256 over the key ID, the method, the path, the
+// SHA-256 of the body, a unix timestamp and a random nonce with a shared key. The receiver
+// recomputes it, rejects timestamps outside the allowed clock skew and nonces it
+// has seen within the window, so that a captured request cannot be replayed.
+// Up to two keys are active at once: senders sign with the first, receivers
+// accept either, which lets a new key be rolled out before the old one is retired.
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Header names of a signed HTTP request; gRPC carries the same values in metadata
+// under the lower-cased names
+const (
+	HeaderSignature = "X-GoCache-Signature"
+	HeaderTimestamp = "X-GoCache-Timestamp"
+	HeaderNonce     = "X-GoCache-Nonce"
+	HeaderKeyID     = "X-GoCache-Key-Id"
+)
+
+const (
+	// DefaultClockSkew is how far a request timestamp may be from the receiver's clock
+	DefaultClockSkew = 30 * time.Second
+	// DefaultNonceCacheSize bounds the nonces remembered for replay detection. Every
+	// nonce is kept for twice the clock skew, so the default admits about 17k signed
+	// requests per second with the default skew.
+	DefaultNonceCacheSize = 1 << 20
+	// DefaultMaxBodyBytes bounds the request body read to check a signature
+	DefaultMaxBodyBytes = 64 << 20
+	// MaxKeys is the number of keys that can be active at once
+	MaxKeys = 2
+)
+
+// Verification errors. Receivers answer the first five with 401 or
+// Unauthenticated; ErrNonceCacheFull with 503 or Unavailable, since the request
+// may be genuine and can be retried; ErrBodyTooLarge with 413.
+var (
+	ErrMissingSignature = errors.New("auth: request is not signed")
+	ErrUnknownKey       = errors.New("auth: unknown signing key")
+	ErrBadSignature     = errors.New("auth: signature mismatch")
+	ErrClockSkew        = errors.New("auth: timestamp outside the allowed clock skew")
+	ErrReplay           = errors.New("auth: nonce already used")
+	ErrNonceCacheFull   = errors.New("auth: too many signed requests within the replay window")
+	ErrBodyTooLarge     = errors.New("auth: request body too large")
+)
+
+// Key is a shared signing key
+type Key struct {
+	ID     string // sent with each request so the receiver picks the right secret
+	Secret []byte
+}
+
+// ParseKey parses a key in the form id:secret
+func ParseKey(spec string) (Key, error) {
+	id, secret, ok := strings.Cut(strings.TrimSpace(spec), ":")
+	if !ok || id == "" || secret == "" {
+		return Key{}, fmt.Errorf("invalid key %q, expected id:secret", redact(spec))
+	}
+	return Key{ID: id, Secret: []byte(secret)}, nil
+}
+
+// ParseKeys parses up to MaxKeys keys in the form id:secret. The first key signs
+// outgoing requests.
+func ParseKeys(specs []string) ([]Key, error) {
+	keys := make([]Key, 0, len(specs))
+	seen := make(map[string]bool, len(specs))
+	for _, spec := range specs {
+		if strings.TrimSpace(spec) == "" {
+			continue
+		}
+		key, err := ParseKey(spec)
+		if err != nil {
+			return nil, err
+		}
+		if seen[key.ID] {
+			return nil, fmt.Errorf("duplicate key id %q", key.ID)
+		}
+		seen[key.ID] = true
+		keys = append(keys, key)
+	}
+	if len(keys) > MaxKeys {
+		return nil, fmt.Errorf("%d keys configured, at most %d can be active", len(keys), MaxKeys)
+	}
+	return keys, nil
+}
+
+// redact hides the secret part of a key spec in error messages
+func redact(spec string) string {
+	if id, _, ok := strings.Cut(spec, ":"); ok {
+		return id + ":***"
+	}
+	return "***"
+}
+
+// Signature holds the values sent along with a signed request
+type Signature struct {
+	KeyID     string
+	Timestamp string // unix seconds
+	Nonce     string
+	Value     string // hex HMAC-SHA256
+}
+
+// Signer signs outgoing requests with a single key
+type Signer struct {
+	key Key
+	now func() time.Time
+
+	maxBody int64 // largest body Transport reads to sign
+}
+
+// SignerOption configures a Signer
+type SignerOption func(*Signer)
+
+// WithSignerClock sets the time source used for timestamps
+func WithSignerClock(now func() time.Time) SignerOption {
+	return func(s *Signer) {
+		if now != nil {
+			s.now = now
+		}
+	}
+}
+
+// WithSignerMaxBodyBytes bounds the request body Transport reads to sign it,
+// which should match the receivers' WithMaxBodyBytes
+func WithSignerMaxBodyBytes(n int64) SignerOption {
+	return func(s *Signer) {
+		if n > 0 {
+			s.maxBody = n
+		}
+	}
+}
+
+// NewSigner returns a signer using key
+func NewSigner(key Key, opts ...SignerOption) *Signer {
+	s := &Signer{key: key, now: time.Now, maxBody: DefaultMaxBodyBytes}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Sign signs a request with a fresh timestamp and nonce
+func (s *Signer) Sign(method, path string, body []byte) Signature {
+	sig := Signature{
+		KeyID:     s.key.ID,
+		Timestamp: strconv.FormatInt(s.now().Unix(), 10),
+		Nonce:     newNonce(),
+	}
+	sig.Value = compute(s.key, method, path, body, sig.Timestamp, sig.Nonce)
+	return sig
+}
+
+// Verifier checks signed requests against the active keys
+type Verifier struct {
+	keys   []Key
+	skew   time.Duration
+	now    func() time.Time
+	nonces *nonceCache
+
+	maxBody int64 // largest body VerifyRequest reads
+}
+
+// VerifierOption configures a Verifier
+type VerifierOption func(*verifierConfig)
+
+type verifierConfig struct {
+	skew      time.Duration
+	now       func() time.Time
+	nonceSize int
+	maxBody   int64
+}
+
+// WithClockSkew sets how far a request timestamp may be from the local clock
+func WithClockSkew(d time.Duration) VerifierOption {
+	return func(c *verifierConfig) {
+		if d > 0 {
+			c.skew = d
+		}
+	}
+}
+
+// WithVerifierClock sets the time source timestamps are checked against
+func WithVerifierClock(now func() time.Time) VerifierOption {
+	return func(c *verifierConfig) {
+		if now != nil {
+			c.now = now
+		}
+	}
+}
+
+// WithNonceCacheSize bounds the number of nonces remembered for replay detection.
+// Nonces are kept for twice the clock skew and never evicted early, so while the
+// cache is full Verify fails with ErrNonceCacheFull; size it for the peak rate of
+// signed requests times that window.
+func WithNonceCacheSize(n int) VerifierOption {
+	return func(c *verifierConfig) {
+		if n > 0 {
+			c.nonceSize = n
+		}
+	}
+}
+
+// WithMaxBodyBytes bounds the request body VerifyRequest reads before the
+// signature is checked; larger bodies fail with ErrBodyTooLarge
+func WithMaxBodyBytes(n int64) VerifierOption {
+	return func(c *verifierConfig) {
+		if n > 0 {
+			c.maxBody = n
+		}
+	}
+}
+
+// NewVerifier returns a verifier accepting requests signed with any of keys,
+// of which there must be one or MaxKeys
+func NewVerifier(keys []Key, opts ...VerifierOption) (*Verifier, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("auth: no verification keys")
+	}
+	if len(keys) > MaxKeys {
+		return nil, fmt.Errorf("auth: %d keys configured, at most %d can be active", len(keys), MaxKeys)
+	}
+	cfg := verifierConfig{
+		skew:      DefaultClockSkew,
+		now:       time.Now,
+		nonceSize: DefaultNonceCacheSize,
+		maxBody:   DefaultMaxBodyBytes,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return &Verifier{
+		keys: append([]Key(nil), keys...),
+		skew: cfg.skew,
+		now:  cfg.now,
+		// A nonce can only be replayed while its timestamp is inside the window
+		nonces: newNonceCache(cfg.nonceSize, 2*cfg.skew),
+
+		maxBody: cfg.maxBody,
+	}, nil
+}
+
+// Verify checks sig for the request described by method, path and body, and
+// records its nonce. A request without a key ID is checked against every key.
+func (v *Verifier) Verify(method, path string, body []byte, sig Signature) error {
+	if sig.Value == "" || sig.Timestamp == "" || sig.Nonce == "" {
+		return ErrMissingSignature
+	}
+
+	ts, err := strconv.ParseInt(sig.Timestamp, 10, 64)
+	if err != nil {
+		return ErrClockSkew
+	}
+	now := v.now()
+	if d := now.Sub(time.Unix(ts, 0)); d > v.skew || d < -v.skew {
+		return ErrClockSkew
+	}
+
+	matched := false
+	known := false
+	for _, key := range v.keys {
+		if sig.KeyID != "" && key.ID != sig.KeyID {
+			continue
+		}
+		known = true
+		expected := compute(key, method, path, body, sig.Timestamp, sig.Nonce)
+		if hmac.Equal([]byte(expected), []byte(sig.Value)) {
+			matched = true
+			break
+		}
+	}
+	if !known {
+		return ErrUnknownKey
+	}
+	if !matched {
+		return ErrBadSignature
+	}
+
+	// Only signatures that verify may claim a nonce, so forged requests cannot
+	// fill the cache or burn nonces of legitimate ones. The nonce is recorded on
+	// its own: the key ID is chosen by the sender, and a replay that drops or
+	// changes it must still find the nonce taken.
+	return v.nonces.add(sig.Nonce, now)
+}
+
+// compute returns the hex HMAC-SHA256 of the canonical request string. The key
+// ID is part of it, so a signature is only valid under the ID it was made with.
+func compute(key Key, method, path string, body []byte, timestamp, nonce string) string {
+	bodyHash := sha256.Sum256(body)
+	mac := hmac.New(sha256.New, key.Secret)
+	mac.Write([]byte(key.ID))
+	mac.Write([]byte{'\n'})
+	mac.Write([]byte(strings.ToUpper(method)))
+	mac.Write([]byte{'\n'})
+	mac.Write([]byte(path))
+	mac.Write([]byte{'\n'})
+	mac.Write([]byte(hex.EncodeToString(bodyHash[:])))
+	mac.Write([]byte{'\n'})
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte{'\n'})
+	mac.Write([]byte(nonce))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// newNonce returns 128 random bits in hex
+func newNonce() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		// crypto/rand does not fail on supported platforms
+		panic(fmt.Sprintf("auth: reading random nonce: %v", err))
+	}
+	return hex.EncodeToString(b[:])
+}
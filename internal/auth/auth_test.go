@@ -0,0 +1,487 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
+)
+
+var (
+	keyA = Key{ID: "a", Secret: []byte("secret-a")}
+	keyB = Key{ID: "b", Secret: []byte("secret-b")}
+)
+
+// fixedClock returns a clock stuck at t
+func fixedClock(t time.Time) func() time.Time {
+	return func() time.Time { return t }
+}
+
+func newTestVerifier(t *testing.T, now time.Time, keys ...Key) *Verifier {
+	t.Helper()
+	v, err := NewVerifier(keys, WithClockSkew(30*time.Second), WithVerifierClock(fixedClock(now)))
+	if err != nil {
+		t.Fatalf("NewVerifier: %v", err)
+	}
+	return v
+}
+
+func TestVerifyRoundTrip(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	s := NewSigner(keyA, WithSignerClock(fixedClock(now)))
+	v := newTestVerifier(t, now, keyA)
+
+	sig := s.Sign("get", "/_gocache/g/k?x=1", []byte("body"))
+	if sig.KeyID != keyA.ID {
+		t.Fatalf("KeyID = %q, want %q", sig.KeyID, keyA.ID)
+	}
+	// The method is compared case-insensitively
+	if err := v.Verify("GET", "/_gocache/g/k?x=1", []byte("body"), sig); err != nil {
+		t.Fatalf("Verify = %v, want nil", err)
+	}
+}
+
+func TestVerifyRejectsTampering(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	s := NewSigner(keyA, WithSignerClock(fixedClock(now)))
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		edit   func(*Signature)
+	}{
+		{name: "method", method: "POST", path: "/p", body: "b"},
+		{name: "path", method: "GET", path: "/q", body: "b"},
+		{name: "body", method: "GET", path: "/p", body: "c"},
+		{name: "timestamp", method: "GET", path: "/p", body: "b", edit: func(sig *Signature) {
+			sig.Timestamp = "1700000001"
+		}},
+		{name: "nonce", method: "GET", path: "/p", body: "b", edit: func(sig *Signature) {
+			sig.Nonce = newNonce()
+		}},
+		{name: "value", method: "GET", path: "/p", body: "b", edit: func(sig *Signature) {
+			sig.Value = strings.Repeat("0", len(sig.Value))
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestVerifier(t, now, keyA)
+			sig := s.Sign("GET", "/p", []byte("b"))
+			if tt.edit != nil {
+				tt.edit(&sig)
+			}
+			if err := v.Verify(tt.method, tt.path, []byte(tt.body), sig); !errors.Is(err, ErrBadSignature) {
+				t.Fatalf("Verify = %v, want ErrBadSignature", err)
+			}
+		})
+	}
+}
+
+func TestVerifyMissingSignature(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	v := newTestVerifier(t, now, keyA)
+	sig := NewSigner(keyA, WithSignerClock(fixedClock(now))).Sign("GET", "/p", nil)
+
+	for name, edit := range map[string]func(*Signature){
+		"value":     func(s *Signature) { s.Value = "" },
+		"timestamp": func(s *Signature) { s.Timestamp = "" },
+		"nonce":     func(s *Signature) { s.Nonce = "" },
+	} {
+		t.Run(name, func(t *testing.T) {
+			missing := sig
+			edit(&missing)
+			if err := v.Verify("GET", "/p", nil, missing); !errors.Is(err, ErrMissingSignature) {
+				t.Fatalf("Verify = %v, want ErrMissingSignature", err)
+			}
+		})
+	}
+}
+
+func TestVerifyClockSkew(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+
+	tests := []struct {
+		name   string
+		offset time.Duration // of the signer's clock from the verifier's
+		want   error
+	}{
+		{name: "in sync", offset: 0},
+		{name: "behind within skew", offset: -30 * time.Second},
+		{name: "ahead within skew", offset: 30 * time.Second},
+		{name: "behind beyond skew", offset: -31 * time.Second, want: ErrClockSkew},
+		{name: "ahead beyond skew", offset: 31 * time.Second, want: ErrClockSkew},
+		{name: "far past", offset: -24 * time.Hour, want: ErrClockSkew},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestVerifier(t, now, keyA)
+			sig := NewSigner(keyA, WithSignerClock(fixedClock(now.Add(tt.offset)))).Sign("GET", "/p", nil)
+			if err := v.Verify("GET", "/p", nil, sig); !errors.Is(err, tt.want) {
+				t.Fatalf("Verify = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	t.Run("malformed timestamp", func(t *testing.T) {
+		v := newTestVerifier(t, now, keyA)
+		sig := NewSigner(keyA, WithSignerClock(fixedClock(now))).Sign("GET", "/p", nil)
+		sig.Timestamp = "yesterday"
+		if err := v.Verify("GET", "/p", nil, sig); !errors.Is(err, ErrClockSkew) {
+			t.Fatalf("Verify = %v, want ErrClockSkew", err)
+		}
+	})
+}
+
+func TestVerifyRejectsReplay(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	s := NewSigner(keyA, WithSignerClock(fixedClock(now)))
+
+	tests := []struct {
+		name string
+		edit func(*Signature)
+		want error
+	}{
+		{name: "identical", want: ErrReplay},
+		// Without a key ID every key is tried; the nonce is still taken
+		{name: "key id dropped", edit: func(s *Signature) { s.KeyID = "" }, want: ErrReplay},
+		// The key ID is signed, so claiming another key breaks the signature
+		{name: "key id changed", edit: func(s *Signature) { s.KeyID = keyB.ID }, want: ErrBadSignature},
+		{name: "key id unknown", edit: func(s *Signature) { s.KeyID = "c" }, want: ErrUnknownKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestVerifier(t, now, keyA, keyB)
+			sig := s.Sign("GET", "/p", nil)
+			if err := v.Verify("GET", "/p", nil, sig); err != nil {
+				t.Fatalf("first Verify = %v, want nil", err)
+			}
+			replay := sig
+			if tt.edit != nil {
+				tt.edit(&replay)
+			}
+			if err := v.Verify("GET", "/p", nil, replay); !errors.Is(err, tt.want) {
+				t.Fatalf("replayed Verify = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyForgedSignatureDoesNotBurnNonce(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	v := newTestVerifier(t, now, keyA)
+	sig := NewSigner(keyA, WithSignerClock(fixedClock(now))).Sign("GET", "/p", nil)
+
+	forged := sig
+	forged.Value = strings.Repeat("0", len(sig.Value))
+	if err := v.Verify("GET", "/p", nil, forged); !errors.Is(err, ErrBadSignature) {
+		t.Fatalf("forged Verify = %v, want ErrBadSignature", err)
+	}
+	if err := v.Verify("GET", "/p", nil, sig); err != nil {
+		t.Fatalf("genuine Verify after forgery = %v, want nil", err)
+	}
+}
+
+func TestVerifyNonceExpiresWithWindow(t *testing.T) {
+	start := time.Unix(1_700_000_000, 0)
+	now := start
+	v, err := NewVerifier([]Key{keyA}, WithClockSkew(30*time.Second), WithVerifierClock(func() time.Time { return now }))
+	if err != nil {
+		t.Fatalf("NewVerifier: %v", err)
+	}
+	s := NewSigner(keyA, WithSignerClock(fixedClock(start)))
+	old := s.Sign("GET", "/p", nil)
+	if err := v.Verify("GET", "/p", nil, old); err != nil {
+		t.Fatalf("Verify = %v, want nil", err)
+	}
+
+	// Once the nonce is forgotten its timestamp is outside the skew, so the
+	// replay is still refused
+	now = start.Add(90 * time.Second)
+	if err := v.Verify("GET", "/p", nil, old); !errors.Is(err, ErrClockSkew) {
+		t.Fatalf("late replay = %v, want ErrClockSkew", err)
+	}
+	fresh := NewSigner(keyA, WithSignerClock(fixedClock(now))).Sign("GET", "/p", nil)
+	if err := v.Verify("GET", "/p", nil, fresh); err != nil {
+		t.Fatalf("fresh Verify = %v, want nil", err)
+	}
+	if got := len(v.nonces.seen); got != 1 {
+		t.Fatalf("nonces remembered = %d, want 1 after the first expired", got)
+	}
+}
+
+func TestVerifyNonceCacheFull(t *testing.T) {
+	start := time.Unix(1_700_000_000, 0)
+	now := start
+	v, err := NewVerifier([]Key{keyA}, WithClockSkew(30*time.Second), WithNonceCacheSize(3),
+		WithVerifierClock(func() time.Time { return now }))
+	if err != nil {
+		t.Fatalf("NewVerifier: %v", err)
+	}
+	s := NewSigner(keyA, WithSignerClock(func() time.Time { return now }))
+
+	var sigs []Signature
+	for i := 0; i < 3; i++ {
+		sig := s.Sign("GET", "/p", nil)
+		if err := v.Verify("GET", "/p", nil, sig); err != nil {
+			t.Fatalf("Verify %d = %v, want nil", i, err)
+		}
+		sigs = append(sigs, sig)
+		now = now.Add(time.Second)
+	}
+
+	// Every remembered nonce is still inside the window: a new request is refused
+	// rather than pushing the oldest nonce out
+	if err := v.Verify("GET", "/p", nil, s.Sign("GET", "/p", nil)); !errors.Is(err, ErrNonceCacheFull) {
+		t.Fatalf("Verify with a full cache = %v, want ErrNonceCacheFull", err)
+	}
+	for i, sig := range sigs {
+		if err := v.Verify("GET", "/p", nil, sig); !errors.Is(err, ErrReplay) {
+			t.Fatalf("replay %d with a full cache = %v, want ErrReplay", i, err)
+		}
+	}
+
+	// Once the first nonce leaves the window there is room again
+	now = start.Add(61 * time.Second)
+	if err := v.Verify("GET", "/p", nil, s.Sign("GET", "/p", nil)); err != nil {
+		t.Fatalf("Verify after the oldest nonce expired = %v, want nil", err)
+	}
+	if got := len(v.nonces.seen); got != 3 {
+		t.Fatalf("nonces remembered = %d, want 3", got)
+	}
+}
+
+func TestVerifyKeyRotation(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	v := newTestVerifier(t, now, keyB, keyA)
+
+	for _, key := range []Key{keyA, keyB} {
+		sig := NewSigner(key, WithSignerClock(fixedClock(now))).Sign("GET", "/p", nil)
+		if err := v.Verify("GET", "/p", nil, sig); err != nil {
+			t.Fatalf("Verify signed with %q = %v, want nil", key.ID, err)
+		}
+	}
+
+	retired := newTestVerifier(t, now, keyB)
+	sig := NewSigner(keyA, WithSignerClock(fixedClock(now))).Sign("GET", "/p", nil)
+	if err := retired.Verify("GET", "/p", nil, sig); !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("Verify with retired key = %v, want ErrUnknownKey", err)
+	}
+}
+
+func TestNewVerifierKeyCount(t *testing.T) {
+	if _, err := NewVerifier(nil); err == nil {
+		t.Fatal("NewVerifier(nil) succeeded, want error")
+	}
+	if _, err := NewVerifier([]Key{keyA, keyB, {ID: "c", Secret: []byte("c")}}); err == nil {
+		t.Fatalf("NewVerifier with %d keys succeeded, want error", MaxKeys+1)
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	keys, err := ParseKeys([]string{" a:one ", "", "b:two:three"})
+	if err != nil {
+		t.Fatalf("ParseKeys: %v", err)
+	}
+	if len(keys) != 2 || keys[0].ID != "a" || string(keys[0].Secret) != "one" || keys[1].ID != "b" || string(keys[1].Secret) != "two:three" {
+		t.Fatalf("ParseKeys = %+v", keys)
+	}
+
+	for _, specs := range [][]string{{"nosecret"}, {":secret"}, {"a:"}, {"a:x", "a:y"}} {
+		if _, err := ParseKeys(specs); err == nil {
+			t.Errorf("ParseKeys(%q) succeeded, want error", specs)
+		}
+	}
+	// The secret must not leak into the error
+	if _, err := ParseKeys([]string{"hunter2-no-colon"}); err == nil || strings.Contains(err.Error(), "hunter2") {
+		t.Errorf("ParseKeys error = %v, want it without the secret", err)
+	}
+}
+
+func TestMiddlewareRejectsReplay(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	v := newTestVerifier(t, now, keyA)
+	var served int
+	srv := httptest.NewServer(v.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served++
+	})))
+	defer srv.Close()
+
+	s := NewSigner(keyA, WithSignerClock(fixedClock(now)))
+	req, err := http.NewRequest(http.MethodPost, srv.URL+"/_gocache/g/k?x=1", strings.NewReader("payload"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: s.Transport(nil)}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("signed request: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("signed request status = %d, want 200", resp.StatusCode)
+	}
+
+	// Send one signature three times, the last time without the key ID
+	sig := s.Sign(http.MethodPost, "/_gocache/g/k?x=1", []byte("payload"))
+	for i, want := range []int{http.StatusOK, http.StatusUnauthorized, http.StatusUnauthorized} {
+		replay, err := http.NewRequest(http.MethodPost, srv.URL+"/_gocache/g/k?x=1", strings.NewReader("payload"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig.SetHeaders(replay.Header)
+		if i == 2 {
+			replay.Header.Del(HeaderKeyID)
+		}
+		resp, err := http.DefaultClient.Do(replay)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Fatalf("send %d: status = %d, want %d", i, resp.StatusCode, want)
+		}
+	}
+	if served != 2 {
+		t.Fatalf("handler ran %d times, want 2", served)
+	}
+
+	unsigned, err := http.Post(srv.URL+"/p", "text/plain", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unsigned.Body.Close()
+	if unsigned.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("unsigned request status = %d, want 401", unsigned.StatusCode)
+	}
+}
+
+func TestVerifyRequestBodyLimit(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	v, err := NewVerifier([]Key{keyA}, WithVerifierClock(fixedClock(now)), WithMaxBodyBytes(16))
+	if err != nil {
+		t.Fatalf("NewVerifier: %v", err)
+	}
+	var served int
+	srv := httptest.NewServer(v.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served++
+	})))
+	defer srv.Close()
+	s := NewSigner(keyA, WithSignerClock(fixedClock(now)))
+
+	for _, tt := range []struct {
+		body string
+		want int
+	}{
+		{strings.Repeat("x", 16), http.StatusOK},
+		{strings.Repeat("x", 17), http.StatusRequestEntityTooLarge},
+	} {
+		// Sign directly: the body is refused before its signature is looked at
+		req, err := http.NewRequest(http.MethodPost, srv.URL+"/p", strings.NewReader(tt.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.Sign(http.MethodPost, "/p", []byte(tt.body)).SetHeaders(req.Header)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.want {
+			t.Fatalf("%d byte body: status = %d, want %d", len(tt.body), resp.StatusCode, tt.want)
+		}
+	}
+	if served != 1 {
+		t.Fatalf("handler served %d requests, want 1", served)
+	}
+
+	// The signing transport applies its own limit before sending
+	limited := NewSigner(keyA, WithSignerMaxBodyBytes(16))
+	req, err := http.NewRequest(http.MethodPost, srv.URL+"/p", strings.NewReader(strings.Repeat("x", 17)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := limited.Transport(nil).RoundTrip(req); !errors.Is(err, ErrBodyTooLarge) {
+		t.Fatalf("RoundTrip with a large body = %v, want ErrBodyTooLarge", err)
+	}
+}
+
+func TestHTTPStatus(t *testing.T) {
+	for err, want := range map[error]int{
+		ErrReplay:         http.StatusUnauthorized,
+		ErrBadSignature:   http.StatusUnauthorized,
+		ErrNonceCacheFull: http.StatusServiceUnavailable,
+		ErrBodyTooLarge:   http.StatusRequestEntityTooLarge,
+	} {
+		if got := HTTPStatus(err); got != want {
+			t.Errorf("HTTPStatus(%v) = %d, want %d", err, got, want)
+		}
+	}
+	if got := grpcCode(ErrNonceCacheFull); got != codes.Unavailable {
+		t.Errorf("grpcCode(ErrNonceCacheFull) = %v, want Unavailable", got)
+	}
+	if got := grpcCode(ErrReplay); got != codes.Unauthenticated {
+		t.Errorf("grpcCode(ErrReplay) = %v, want Unauthenticated", got)
+	}
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	v := newTestVerifier(t, now, keyA)
+	s := NewSigner(keyA, WithSignerClock(fixedClock(now)))
+	info := &grpc.UnaryServerInfo{FullMethod: "/cache_server.GroupCache/Get"}
+	req := &pb.Request{Group: "g", Key: "k"}
+
+	// Capture the metadata the client interceptor attaches
+	var md metadata.MD
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		md, _ = metadata.FromOutgoingContext(ctx)
+		return nil
+	}
+	if err := s.UnaryClientInterceptor()(context.Background(), info.FullMethod, req, nil, nil, invoker); err != nil {
+		t.Fatalf("client interceptor: %v", err)
+	}
+
+	var handled int
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		handled++
+		return nil, nil
+	}
+	call := func(md metadata.MD, req interface{}) error {
+		_, err := v.UnaryServerInterceptor()(metadata.NewIncomingContext(context.Background(), md), req, info, handler)
+		return err
+	}
+
+	if err := call(md, req); err != nil {
+		t.Fatalf("signed call = %v, want nil", err)
+	}
+	stripped := md.Copy()
+	delete(stripped, strings.ToLower(HeaderKeyID))
+	for name, tt := range map[string]struct {
+		md  metadata.MD
+		req interface{}
+	}{
+		"replay":             {md, req},
+		"replay without key": {stripped, req},
+		"other request":      {md.Copy(), &pb.Request{Group: "g", Key: "other"}},
+		"unsigned":           {metadata.MD{}, req},
+	} {
+		if err := call(tt.md, tt.req); status.Code(err) != codes.Unauthenticated {
+			t.Errorf("%s: code = %v (%v), want Unauthenticated", name, status.Code(err), err)
+		}
+	}
+	if handled != 1 {
+		t.Fatalf("handler ran %d times, want 1", handled)
+	}
+}
@@ -0,0 +1,26 @@
+package auth
+
+import (
+	"github.com/AdrianWangs/go-cache/config"
+)
+
+// FromConfig builds the signer and verifier for cfg. Both are nil when no keys
+// are configured, which leaves internal requests unsigned and unchecked.
+func FromConfig(cfg config.AuthConfig) (*Signer, *Verifier, error) {
+	keys, err := ParseKeys(cfg.Keys)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(keys) == 0 {
+		return nil, nil, nil
+	}
+	verifier, err := NewVerifier(keys,
+		WithClockSkew(cfg.ClockSkew.Std()),
+		WithNonceCacheSize(cfg.NonceCacheSize),
+		WithMaxBodyBytes(cfg.MaxBodyBytes),
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	return NewSigner(keys[0], WithSignerMaxBodyBytes(cfg.MaxBodyBytes)), verifier, nil
+}
@@ -0,0 +1,121 @@
+package auth
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// SetHeaders writes sig into the request headers h
+func (sig Signature) SetHeaders(h http.Header) {
+	h.Set(HeaderSignature, sig.Value)
+	h.Set(HeaderTimestamp, sig.Timestamp)
+	h.Set(HeaderNonce, sig.Nonce)
+	if sig.KeyID != "" {
+		h.Set(HeaderKeyID, sig.KeyID)
+	}
+}
+
+// signatureFromHeaders reads a signature written by SetHeaders
+func signatureFromHeaders(h http.Header) Signature {
+	return Signature{
+		KeyID:     h.Get(HeaderKeyID),
+		Timestamp: h.Get(HeaderTimestamp),
+		Nonce:     h.Get(HeaderNonce),
+		Value:     h.Get(HeaderSignature),
+	}
+}
+
+// Transport returns a RoundTripper that signs every request before passing it to
+// base, or http.DefaultTransport if base is nil
+func (s *Signer) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &signingTransport{signer: s, base: base}
+}
+
+type signingTransport struct {
+	signer *Signer
+	base   http.RoundTripper
+}
+
+// RoundTrip signs a clone of req, leaving the caller's request untouched
+func (t *signingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		body, err = readBody(req.Body, t.signer.maxBody)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	signed := req.Clone(req.Context())
+	if body != nil {
+		signed.Body = io.NopCloser(bytes.NewReader(body))
+		signed.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body)), nil
+		}
+	}
+	t.signer.Sign(req.Method, req.URL.RequestURI(), body).SetHeaders(signed.Header)
+	return t.base.RoundTrip(signed)
+}
+
+// VerifyRequest checks the signature of r. The body is read for hashing and
+// replaced, so handlers can still read it. The body has to be read before the
+// signature can be checked, so at most the configured limit is read and a larger
+// body fails with ErrBodyTooLarge.
+func (v *Verifier) VerifyRequest(r *http.Request) error {
+	var body []byte
+	if r.Body != nil && r.Body != http.NoBody {
+		var err error
+		body, err = readBody(r.Body, v.maxBody)
+		r.Body.Close()
+		if err != nil {
+			return err
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	return v.Verify(r.Method, r.URL.RequestURI(), body, signatureFromHeaders(r.Header))
+}
+
+// Middleware rejects requests that fail VerifyRequest with the status from
+// HTTPStatus before they reach next
+func (v *Verifier) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := v.VerifyRequest(r); err != nil {
+			http.Error(w, err.Error(), HTTPStatus(err))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// HTTPStatus returns the status a receiver answers a VerifyRequest error with
+func HTTPStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrBodyTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, ErrNonceCacheFull):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusUnauthorized
+	}
+}
+
+// readBody reads body up to limit bytes; the caller closes it
+func readBody(body io.ReadCloser, limit int64) ([]byte, error) {
+	b, err := io.ReadAll(http.MaxBytesReader(nil, body, limit))
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		return nil, fmt.Errorf("%w: limit is %d bytes", ErrBodyTooLarge, limit)
+	case err != nil:
+		return nil, fmt.Errorf("auth: reading request body: %w", err)
+	}
+	return b, nil
+}
@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"sync"
+	"time"
+)
+
+// nonceCache remembers recently used nonces. Entries are kept in insertion order
+// and dropped from the front once older than ttl. A nonce is never forgotten
+// while it could still be replayed, so a cache full of live nonces refuses new
+// ones instead of evicting them.
+type nonceCache struct {
+	mu    sync.Mutex
+	size  int
+	ttl   time.Duration
+	seen  map[string]time.Time
+	order []nonceEntry
+}
+
+type nonceEntry struct {
+	nonce string
+	added time.Time
+}
+
+func newNonceCache(size int, ttl time.Duration) *nonceCache {
+	return &nonceCache{
+		size: size,
+		ttl:  ttl,
+		seen: make(map[string]time.Time, size),
+	}
+}
+
+// add records nonce at now. It returns ErrReplay if the nonce was already used
+// and ErrNonceCacheFull if every remembered nonce is still inside the window.
+func (c *nonceCache) add(nonce string, now time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.prune(now)
+	if _, ok := c.seen[nonce]; ok {
+		return ErrReplay
+	}
+	if len(c.order) >= c.size {
+		return ErrNonceCacheFull
+	}
+	c.seen[nonce] = now
+	c.order = append(c.order, nonceEntry{nonce: nonce, added: now})
+	return nil
+}
+
+// prune drops entries older than ttl
+func (c *nonceCache) prune(now time.Time) {
+	for len(c.order) > 0 && now.Sub(c.order[0].added) > c.ttl {
+		c.drop()
+	}
+}
+
+// drop removes the oldest entry
+func (c *nonceCache) drop() {
+	delete(c.seen, c.order[0].nonce)
+	c.order[0] = nonceEntry{}
+	c.order = c.order[1:]
+}
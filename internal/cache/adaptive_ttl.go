@@ -0,0 +1,186 @@
+package cache
+
+import (
+	"container/list"
+	"hash/fnv"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultAdaptiveTTLKeys bounds the keys WithAdaptiveTTL keeps a history for
+const DefaultAdaptiveTTLKeys = 10000
+
+// TTLBucket is a bucket of the distribution of adaptive ttls
+type TTLBucket struct {
+	LE   string `json:"le"`   // upper bound of the bucket
+	Keys int64  `json:"keys"` // tracked keys whose next ttl falls in the bucket, not cumulative
+}
+
+// ttlTracker is the history of one key: the digest of the value last loaded
+// and the ttl it was stored with
+type ttlTracker struct {
+	key  string
+	hash uint64
+	ttl  time.Duration
+}
+
+// adaptiveTTL is a small LRU of ttl trackers; the key loaded least recently is
+// forgotten when it is full and starts over from the group ttl
+type adaptiveTTL struct {
+	min, max time.Duration
+	bounds   []time.Duration // upper bounds of the distribution buckets: min, doublings of min, max
+
+	mu  sync.Mutex
+	ll  *list.List
+	m   map[string]*list.Element
+	cap int
+
+	keys      []atomic.Int64 // tracked keys per bucket of bounds, updated under mu
+	tracked   atomic.Int64   // ll.Len(), readable without mu
+	changed   atomic.Int64   // reloads that found a different value
+	unchanged atomic.Int64   // reloads that found the same value
+}
+
+// WithAdaptiveTTL adapts the ttl of every key to how often its value changes at
+// the data source. The group remembers a digest of the value last loaded for
+// each key; when a reload finds the same value the key's next ttl doubles, and
+// when the value changed it halves, always within [minTTL, maxTTL]. A key's first
+// load uses the group ttl clamped to the bounds. Over a few reloads the ttl
+// settles around half the interval at which the value actually changes, where
+// a reload finds a new value as often as the old one: keys that never change
+// are fetched rarely, and keys that change often are not served stale for long.
+//
+// Only loads from the data source on the owning node are adapted, including
+// refresh-ahead. Set, Warm and imports keep the ttl they are given or the
+// group ttl, and a group ttl of 0 (never expire) disables adaptation. At
+// most DefaultAdaptiveTTLKeys keys are tracked, about 120 bytes plus the key
+// each; the least recently loaded is forgotten first. minTTL <= 0 disables it, which is the
+// default; maxTTL is raised to minTTL when smaller.
+func WithAdaptiveTTL(minTTL, maxTTL time.Duration) GroupOption {
+	return func(g *Group) {
+		if minTTL <= 0 {
+			g.adaptive = nil
+			return
+		}
+		g.adaptive = newAdaptiveTTL(minTTL, maxTTL, DefaultAdaptiveTTLKeys)
+	}
+}
+
+// newAdaptiveTTL creates the tracker of WithAdaptiveTTL
+func newAdaptiveTTL(minTTL, maxTTL time.Duration, capacity int) *adaptiveTTL {
+	maxTTL = max(maxTTL, minTTL)
+	var bounds []time.Duration
+	for b := minTTL; b < maxTTL && b > 0; b *= 2 {
+		bounds = append(bounds, b)
+	}
+	bounds = append(bounds, maxTTL)
+	return &adaptiveTTL{
+		min:    minTTL,
+		max:    maxTTL,
+		bounds: bounds,
+		ll:     list.New(),
+		m:      make(map[string]*list.Element),
+		cap:    capacity,
+		keys:   make([]atomic.Int64, len(bounds)),
+	}
+}
+
+// valueDigest returns the digest reloads are compared by
+func valueDigest(b []byte) uint64 {
+	h := fnv.New64a()
+	h.Write(b)
+	return h.Sum64()
+}
+
+// observe records a value just loaded for key and returns the ttl to store it
+// with: base clamped to the bounds for a key without history, otherwise the
+// previous ttl doubled when the value is unchanged or halved when it changed
+func (a *adaptiveTTL) observe(key string, value []byte, base time.Duration) time.Duration {
+	digest := valueDigest(value)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	e, ok := a.m[key]
+	if !ok {
+		t := &ttlTracker{key: key, hash: digest, ttl: min(max(base, a.min), a.max)}
+		a.m[key] = a.ll.PushFront(t)
+		a.count(t.ttl, 1)
+		a.tracked.Add(1)
+		if a.ll.Len() > a.cap {
+			oldest := a.ll.Remove(a.ll.Back()).(*ttlTracker)
+			delete(a.m, oldest.key)
+			a.count(oldest.ttl, -1)
+			a.tracked.Add(-1)
+		}
+		return t.ttl
+	}
+
+	t := e.Value.(*ttlTracker)
+	a.ll.MoveToFront(e)
+	a.count(t.ttl, -1)
+	if t.hash == digest {
+		a.unchanged.Add(1)
+		if t.ttl > a.max/2 {
+			t.ttl = a.max
+		} else {
+			t.ttl = min(t.ttl*2, a.max)
+		}
+	} else {
+		a.changed.Add(1)
+		t.hash = digest
+		t.ttl = max(t.ttl/2, a.min)
+	}
+	a.count(t.ttl, 1)
+	return t.ttl
+}
+
+// current returns the ttl key was last stored with, or base without history
+func (a *adaptiveTTL) current(key string, base time.Duration) time.Duration {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if e, ok := a.m[key]; ok {
+		return e.Value.(*ttlTracker).ttl
+	}
+	return base
+}
+
+// count adds n to the distribution bucket of ttl
+func (a *adaptiveTTL) count(ttl time.Duration, n int64) {
+	i, _ := slices.BinarySearch(a.bounds, ttl)
+	a.keys[min(i, len(a.keys)-1)].Add(n)
+}
+
+// loadTTL returns the ttl for a value key just loaded from the data source,
+// the group ttl unless WithAdaptiveTTL adapts it
+func (g *Group) loadTTL(key string, value ByteView) time.Duration {
+	if g.adaptive == nil || g.ttl <= 0 {
+		return g.ttl
+	}
+	return g.adaptive.observe(key, value.bytes, g.ttl)
+}
+
+// storedTTL returns the ttl key was last loaded with, for storing the same
+// value again without counting it as a reload
+func (g *Group) storedTTL(key string) time.Duration {
+	if g.adaptive == nil || g.ttl <= 0 {
+		return g.ttl
+	}
+	return g.adaptive.current(key, g.ttl)
+}
+
+// adaptiveStats adds the adaptive ttl statistics to stats
+func (g *Group) adaptiveStats(stats *CacheStats) {
+	a := g.adaptive
+	if a == nil || g.ttl <= 0 {
+		return
+	}
+	stats.AdaptiveTTLKeys = a.tracked.Load()
+	stats.AdaptiveTTLChanged = a.changed.Load()
+	stats.AdaptiveTTLUnchanged = a.unchanged.Load()
+	stats.AdaptiveTTLs = make([]TTLBucket, len(a.bounds))
+	for i, b := range a.bounds {
+		stats.AdaptiveTTLs[i] = TTLBucket{LE: b.String(), Keys: a.keys[i].Load()}
+	}
+}
@@ -0,0 +1,240 @@
+package cache
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/pkg/lru"
+)
+
+// storedTTLOf returns the ttl key is cached with, failing when it is not cached
+func storedTTLOf(t *testing.T, g *Group, key string) time.Duration {
+	t.Helper()
+	_, expiry, ok := g.mainCache.get(key)
+	if !ok {
+		t.Fatalf("%s not cached", key)
+	}
+	return expiry.TTL
+}
+
+// TestAdaptiveTTLConverges simulates a client reading a key every second whose
+// value changes at the data source every period: once the history has built
+// up, the ttls of the reloads settle between a quarter of the period and the
+// period, growing with it
+func TestAdaptiveTTLConverges(t *testing.T) {
+	var medians []time.Duration
+	for _, period := range []time.Duration{20 * time.Second, time.Minute, 5 * time.Minute} {
+		clock := lru.NewFakeClock(time.Unix(1000, 0))
+		source := GetterFunc(func(key string) ([]byte, error) {
+			return []byte(strconv.FormatInt(clock.Now().Unix()/int64(period.Seconds()), 10)), nil
+		})
+		g := newTestGroup(t, source, 10*time.Second, WithClock(clock), WithAdaptiveTTL(time.Second, time.Hour))
+
+		var ttls []time.Duration
+		var version uint64
+		for elapsed := time.Duration(0); elapsed < 40*period; elapsed += time.Second {
+			mustGet(t, g, "k")
+			if _, expiry, _ := g.mainCache.get("k"); expiry.Version != version {
+				version = expiry.Version
+				ttls = append(ttls, expiry.TTL)
+			}
+			clock.Advance(time.Second)
+		}
+		if len(ttls) < 30 {
+			t.Fatalf("period %v: only %d reloads", period, len(ttls))
+		}
+		last := slices.Clone(ttls[len(ttls)-20:])
+		slices.Sort(last)
+		median := last[len(last)/2]
+		if median < period/4 || median > period {
+			t.Errorf("period %v: median ttl of the last 20 reloads %v, want within [%v, %v]; ttls %v",
+				period, median, period/4, period, ttls[len(ttls)-20:])
+		}
+		if len(medians) > 0 && median <= medians[len(medians)-1] {
+			t.Errorf("period %v: median ttl %v not above %v of the shorter period", period, median, medians[len(medians)-1])
+		}
+		medians = append(medians, median)
+
+		stats := g.Stats()
+		if stats.AdaptiveTTLKeys != 1 || stats.AdaptiveTTLChanged == 0 || stats.AdaptiveTTLUnchanged == 0 {
+			t.Errorf("period %v: stats %d keys, %d changed, %d unchanged", period,
+				stats.AdaptiveTTLKeys, stats.AdaptiveTTLChanged, stats.AdaptiveTTLUnchanged)
+		}
+	}
+}
+
+func TestAdaptiveTTLSteps(t *testing.T) {
+	a := newAdaptiveTTL(3*time.Second, 20*time.Second, 10)
+	steps := []struct {
+		value string
+		base  time.Duration
+		want  time.Duration
+	}{
+		{"v1", time.Second, 3 * time.Second}, // the first load clamps the group ttl
+		{"v1", time.Second, 6 * time.Second},
+		{"v1", time.Second, 12 * time.Second},
+		{"v1", time.Second, 20 * time.Second}, // doubling stops at max
+		{"v1", time.Second, 20 * time.Second},
+		{"v2", time.Second, 10 * time.Second},
+		{"v3", time.Second, 5 * time.Second},
+		{"v4", time.Second, 3 * time.Second}, // halving stops at min
+		{"v5", time.Second, 3 * time.Second},
+		{"v5", time.Second, 6 * time.Second},
+	}
+	for i, s := range steps {
+		if got := a.observe("k", []byte(s.value), s.base); got != s.want {
+			t.Fatalf("step %d (%s): ttl %v, want %v", i, s.value, got, s.want)
+		}
+	}
+	if got := a.observe("long", []byte("v"), time.Hour); got != 20*time.Second {
+		t.Fatalf("first ttl of a group ttl above max = %v", got)
+	}
+	if got := a.current("k", time.Minute); got != 6*time.Second {
+		t.Fatalf("current = %v, want the last ttl", got)
+	}
+	if got := a.current("unknown", time.Minute); got != time.Minute {
+		t.Fatalf("current of an untracked key = %v, want the base", got)
+	}
+	if a.changed.Load() != 4 || a.unchanged.Load() != 5 {
+		t.Fatalf("%d changed, %d unchanged, want 4 and 5", a.changed.Load(), a.unchanged.Load())
+	}
+
+	// maxTTL below minTTL is raised to it
+	if b := newAdaptiveTTL(time.Minute, time.Second, 10); b.max != time.Minute || len(b.bounds) != 1 {
+		t.Fatalf("max %v, bounds %v", b.max, b.bounds)
+	}
+}
+
+// TestAdaptiveTTLBypass checks the writes that keep their ttl: Set, a group
+// that never expires, and the option disabled
+func TestAdaptiveTTLBypass(t *testing.T) {
+	source := newCountingGetter(map[string]string{"a": "1", "b": "2"})
+	g := newTestGroup(t, source, 10*time.Second, WithAdaptiveTTL(time.Minute, time.Hour))
+	if err := g.Set("s", []byte("x"), 5*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Set("d", []byte("x"), 0); err != nil {
+		t.Fatal(err)
+	}
+	if got := storedTTLOf(t, g, "s"); got != 5*time.Second {
+		t.Fatalf("Set ttl %v, want the explicit 5s", got)
+	}
+	if got := storedTTLOf(t, g, "d"); got != 10*time.Second {
+		t.Fatalf("Set without ttl stored %v, want the group ttl", got)
+	}
+	mustGet(t, g, "a")
+	if got := storedTTLOf(t, g, "a"); got != time.Minute {
+		t.Fatalf("loaded ttl %v, want the group ttl raised to min", got)
+	}
+	if stats := g.Stats(); stats.AdaptiveTTLKeys != 1 {
+		t.Fatalf("%d keys tracked, want only the loaded one", stats.AdaptiveTTLKeys)
+	}
+
+	tests := []struct {
+		name string
+		ttl  time.Duration
+		opts []GroupOption
+	}{
+		{"never expire", 0, []GroupOption{WithAdaptiveTTL(time.Minute, time.Hour)}},
+		{"disabled", 10 * time.Second, []GroupOption{WithAdaptiveTTL(time.Minute, time.Hour), WithAdaptiveTTL(0, time.Hour)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newTestGroup(t, source, tt.ttl, tt.opts...)
+			mustGet(t, g, "b")
+			if got := storedTTLOf(t, g, "b"); got != tt.ttl {
+				t.Fatalf("loaded ttl %v, want the group ttl %v", got, tt.ttl)
+			}
+			if stats := g.Stats(); stats.AdaptiveTTLKeys != 0 || stats.AdaptiveTTLs != nil {
+				t.Fatalf("stats %d keys, distribution %v", stats.AdaptiveTTLKeys, stats.AdaptiveTTLs)
+			}
+		})
+	}
+}
+
+func TestAdaptiveTTLStats(t *testing.T) {
+	clock := lru.NewFakeClock(time.Unix(1000, 0))
+	var mu sync.Mutex
+	values := map[string]string{"a": "1", "b": "1", "c": "1"}
+	source := GetterFunc(func(key string) ([]byte, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return []byte(values[key]), nil
+	})
+	g := newTestGroup(t, source, time.Second, WithClock(clock), WithAdaptiveTTL(time.Second, 10*time.Second))
+
+	// a reloads unchanged three times (8s), b once (2s), c is loaded once (1s)
+	for i := 0; i < 4; i++ {
+		mustGet(t, g, "a")
+		if i < 2 {
+			mustGet(t, g, "b")
+		}
+		if i == 0 {
+			mustGet(t, g, "c")
+		}
+		clock.Advance(storedTTLOf(t, g, "a") + time.Millisecond)
+	}
+	stats := g.Stats()
+	want := []TTLBucket{{"1s", 1}, {"2s", 1}, {"4s", 0}, {"8s", 1}, {"10s", 0}}
+	if !slices.Equal(stats.AdaptiveTTLs, want) {
+		t.Fatalf("distribution %v, want %v", stats.AdaptiveTTLs, want)
+	}
+	if stats.AdaptiveTTLKeys != 3 || stats.AdaptiveTTLUnchanged != 4 || stats.AdaptiveTTLChanged != 0 {
+		t.Fatalf("%d keys, %d unchanged, %d changed", stats.AdaptiveTTLKeys, stats.AdaptiveTTLUnchanged, stats.AdaptiveTTLChanged)
+	}
+}
+
+// TestAdaptiveTTLCapacity checks the least recently loaded key is forgotten
+// when the trackers are full, and starts over from the group ttl
+func TestAdaptiveTTLCapacity(t *testing.T) {
+	a := newAdaptiveTTL(time.Second, time.Hour, 2)
+	for i := 0; i < 3; i++ {
+		a.observe("a", []byte("v"), time.Second)
+	}
+	a.observe("b", []byte("v"), time.Second)
+	a.observe("c", []byte("v"), time.Second) // forgets a
+	if got := a.observe("a", []byte("v"), time.Second); got != time.Second {
+		t.Fatalf("ttl of a forgotten key = %v, want the group ttl", got)
+	}
+	if a.tracked.Load() != 2 || len(a.m) != 2 {
+		t.Fatalf("%d keys tracked, want 2", a.tracked.Load())
+	}
+	var sum int64
+	for i := range a.keys {
+		sum += a.keys[i].Load()
+	}
+	if sum != 2 {
+		t.Fatalf("distribution counts %d keys, want 2", sum)
+	}
+	if _, ok := a.m["b"]; ok {
+		t.Fatal("b, loaded before c, should have been forgotten when a came back")
+	}
+}
+
+// TestAdaptiveTTLConcurrent loads many keys from several goroutines; run it
+// with -race
+func TestAdaptiveTTLConcurrent(t *testing.T) {
+	a := newAdaptiveTTL(time.Second, time.Hour, 50)
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				a.observe(fmt.Sprintf("k%d", i%80), []byte(strconv.Itoa(i%3)), time.Second)
+			}
+		}(w)
+	}
+	wg.Wait()
+	var sum int64
+	for i := range a.keys {
+		sum += a.keys[i].Load()
+	}
+	if a.tracked.Load() != 50 || sum != 50 {
+		t.Fatalf("%d keys tracked, distribution counts %d, want 50", a.tracked.Load(), sum)
+	}
+}
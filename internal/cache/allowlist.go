@@ -0,0 +1,71 @@
+package cache
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// GroupAllowlist is the set of groups a node serves to peers and to the API
+// server. A node may host internal-only groups that it reads itself but must
+// never hand out; the serving paths check the allowlist before looking the
+// group up. An empty allowlist allows every group.
+//
+// The allowlist can be replaced at runtime with Set; the HTTP pool and the gRPC
+// server may share one so that a reload updates both transports at once.
+type GroupAllowlist struct {
+	mu    sync.RWMutex
+	names map[string]bool // nil allows every group
+}
+
+// NewGroupAllowlist creates an allowlist of names; blank names are ignored and
+// no names allows every group
+func NewGroupAllowlist(names ...string) *GroupAllowlist {
+	a := &GroupAllowlist{}
+	a.Set(names...)
+	return a
+}
+
+// Set replaces the allowed groups; no names allows every group
+func (a *GroupAllowlist) Set(names ...string) {
+	var set map[string]bool
+	for _, name := range names {
+		if name = strings.TrimSpace(name); name != "" {
+			if set == nil {
+				set = make(map[string]bool)
+			}
+			set[name] = true
+		}
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.names = set
+}
+
+// Allows reports whether group may be served. A nil allowlist allows every group.
+func (a *GroupAllowlist) Allows(group string) bool {
+	if a == nil {
+		return true
+	}
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.names == nil || a.names[group]
+}
+
+// Names returns the allowed groups sorted by name, nil when every group is allowed
+func (a *GroupAllowlist) Names() []string {
+	if a == nil {
+		return nil
+	}
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.names == nil {
+		return nil
+	}
+	names := make([]string, 0, len(a.names))
+	for name := range a.names {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,32 @@
+package cache
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestGroupAllowlist(t *testing.T) {
+	var nilList *GroupAllowlist
+	if !nilList.Allows("any") || nilList.Names() != nil {
+		t.Fatal("nil allowlist restricts groups")
+	}
+
+	a := NewGroupAllowlist("", " ")
+	if !a.Allows("any") || a.Names() != nil {
+		t.Fatalf("blank names restrict groups: %v", a.Names())
+	}
+
+	a.Set("users", " scores ", "")
+	if !a.Allows("scores") || !a.Allows("users") || a.Allows("internal") {
+		t.Fatalf("allowlist %v", a.Names())
+	}
+	if got := a.Names(); !slices.Equal(got, []string{"scores", "users"}) {
+		t.Fatalf("Names() = %v", got)
+	}
+
+	// Set with no names lifts the restriction again
+	a.Set()
+	if !a.Allows("internal") || a.Names() != nil {
+		t.Fatalf("allowlist after Set() = %v", a.Names())
+	}
+}
@@ -0,0 +1,252 @@
+package cache
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/pkg/logger"
+	"github.com/AdrianWangs/go-cache/pkg/lru"
+)
+
+// Defaults of BreakerConfig
+const (
+	DefaultBreakerWindow      = 10 * time.Second
+	DefaultBreakerMinRequests = 10
+	DefaultBreakerOpenFor     = 5 * time.Second
+	DefaultBreakerProbes      = 1
+)
+
+// BreakerState is the state of a group's origin circuit breaker
+type BreakerState int
+
+const (
+	// BreakerClosed lets every load reach the data source
+	BreakerClosed BreakerState = iota
+	// BreakerOpen fails loads with ErrOriginUnavailable without calling the data source
+	BreakerOpen
+	// BreakerHalfOpen lets one probe load at a time through to test the data source
+	BreakerHalfOpen
+)
+
+// String returns the name of the state
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// BreakerConfig configures the circuit breaker around the group's data source,
+// see WithOriginBreaker.
+//
+// Loads are counted over fixed windows of Window. Once a window has seen at least
+// MinRequests loads and FailureRate of them failed, the breaker opens: misses fail
+// at once with ErrOriginUnavailable for OpenFor. It then turns half-open and lets
+// one load at a time through as a probe; Probes successful probes in a row close
+// it, a failed one opens it again. A key the data source reports missing counts as
+// a success.
+type BreakerConfig struct {
+	FailureRate float64       // fraction of failed loads in a window that opens the breaker, 0 disables it
+	Window      time.Duration // interval loads are counted over, DefaultBreakerWindow when 0
+	MinRequests int           // loads a window needs before it can open the breaker, DefaultBreakerMinRequests when 0
+	OpenFor     time.Duration // time spent open before probing, DefaultBreakerOpenFor when 0
+	Probes      int           // successful probes that close the breaker, DefaultBreakerProbes when 0
+}
+
+// BreakerStats describes the origin circuit breaker of a group
+type BreakerStats struct {
+	State    BreakerState
+	Opens    int64 // transitions to open, including reopening after a failed probe
+	Rejected int64 // loads failed with ErrOriginUnavailable without calling the data source
+}
+
+// WithOriginBreaker puts a circuit breaker around the group's data source, so a
+// failing origin is not hit by every miss while it is down, see BreakerConfig.
+// Values already cached keep being served while the breaker is open, and
+// refresh-ahead keeps serving an entry whose background reload was rejected.
+func WithOriginBreaker(cfg BreakerConfig) GroupOption {
+	return func(g *Group) {
+		g.breakerCfg = &cfg
+	}
+}
+
+// breaker is a failure-rate circuit breaker; a nil breaker lets every load through
+type breaker struct {
+	group string
+	cfg   BreakerConfig
+	clock lru.Clock
+
+	mu          sync.Mutex
+	state       BreakerState
+	windowStart time.Time // start of the current counting window while closed
+	requests    int       // loads finished in the current window
+	failures    int       // failed loads in the current window
+	openedAt    time.Time // when the breaker last opened
+	probing     bool      // a probe is in flight while half-open
+	successes   int       // successful probes in a row while half-open
+
+	// Written under mu and read atomically by stats, which does not wait for loads
+	current  atomic.Int32 // state
+	openedNs atomic.Int64 // openedAt in unix nanoseconds
+	opens    int64
+	rejected int64
+}
+
+func newBreaker(group string, cfg BreakerConfig, clock lru.Clock) *breaker {
+	if cfg.FailureRate > 1 {
+		cfg.FailureRate = 1
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = DefaultBreakerWindow
+	}
+	if cfg.MinRequests <= 0 {
+		cfg.MinRequests = DefaultBreakerMinRequests
+	}
+	if cfg.OpenFor <= 0 {
+		cfg.OpenFor = DefaultBreakerOpenFor
+	}
+	if cfg.Probes <= 0 {
+		cfg.Probes = DefaultBreakerProbes
+	}
+	return &breaker{group: group, cfg: cfg, clock: clock, windowStart: clock.Now()}
+}
+
+// allow reports whether a load may call the data source. When it returns true
+// the caller must report the outcome with done.
+func (b *breaker) allow() bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.clock.Now()
+	switch b.state {
+	case BreakerOpen:
+		if now.Sub(b.openedAt) < b.cfg.OpenFor {
+			atomic.AddInt64(&b.rejected, 1)
+			return false
+		}
+		b.setStateLocked(BreakerHalfOpen)
+		b.successes = 0
+		logger.Infof("[Cache] 数据源熔断器进入半开状态，开始探测: group=%s", b.group)
+		fallthrough
+	case BreakerHalfOpen:
+		if b.probing {
+			atomic.AddInt64(&b.rejected, 1)
+			return false
+		}
+		b.probing = true
+		return true
+	default:
+		if now.Sub(b.windowStart) >= b.cfg.Window {
+			b.windowStart, b.requests, b.failures = now, 0, 0
+		}
+		return true
+	}
+}
+
+// done records the outcome of a load allow let through
+func (b *breaker) done(failed bool) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.clock.Now()
+	switch b.state {
+	case BreakerHalfOpen:
+		b.probing = false
+		if failed {
+			b.open(now, "探测失败")
+			return
+		}
+		b.successes++
+		if b.successes >= b.cfg.Probes {
+			b.setStateLocked(BreakerClosed)
+			b.windowStart, b.requests, b.failures = now, 0, 0
+			logger.Infof("[Cache] 数据源熔断器已关闭，恢复回源: group=%s", b.group)
+		}
+	case BreakerClosed:
+		if now.Sub(b.windowStart) >= b.cfg.Window {
+			b.windowStart, b.requests, b.failures = now, 0, 0
+		}
+		b.requests++
+		if failed {
+			b.failures++
+		}
+		if b.requests >= b.cfg.MinRequests && float64(b.failures) >= b.cfg.FailureRate*float64(b.requests) {
+			b.open(now, "失败率超过阈值")
+		}
+	}
+	// An outcome arriving while open belongs to a load started before the
+	// breaker opened and changes nothing
+}
+
+// open moves the breaker to the open state; the caller holds b.mu
+// abandon ends a load allowed by allow that was cancelled before the data
+// source answered; it counts neither as a success nor as a failure
+func (b *breaker) abandon() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == BreakerHalfOpen {
+		b.probing = false
+	}
+}
+
+func (b *breaker) open(now time.Time, reason string) {
+	logger.Warnf("[Cache] 数据源熔断器打开（%s），%v 内未命中直接失败: group=%s, 窗口内失败 %d/%d",
+		reason, b.cfg.OpenFor, b.group, b.failures, b.requests)
+	b.setStateLocked(BreakerOpen)
+	b.openedAt = now
+	b.openedNs.Store(now.UnixNano())
+	b.successes = 0
+	atomic.AddInt64(&b.opens, 1)
+}
+
+// setStateLocked changes the state; the caller holds b.mu
+func (b *breaker) setStateLocked(s BreakerState) {
+	b.state = s
+	b.current.Store(int32(s))
+}
+
+// stats returns the state and counters of the breaker
+func (b *breaker) stats() BreakerStats {
+	if b == nil {
+		return BreakerStats{}
+	}
+	state := BreakerState(b.current.Load())
+	if state == BreakerOpen && b.clock.Now().Sub(time.Unix(0, b.openedNs.Load())) >= b.cfg.OpenFor {
+		// The next load will probe
+		state = BreakerHalfOpen
+	}
+	return BreakerStats{State: state, Opens: atomic.LoadInt64(&b.opens), Rejected: atomic.LoadInt64(&b.rejected)}
+}
+
+// Breaker reports the state of the group's origin circuit breaker; a group
+// without one is always closed
+func (g *Group) Breaker() BreakerStats {
+	return g.breaker.stats()
+}
+
+// breakerStats adds the breaker to stats
+func (g *Group) breakerStats(stats *CacheStats) {
+	if g.breaker == nil {
+		return
+	}
+	bs := g.breaker.stats()
+	stats.OriginBreaker = bs.State.String()
+	stats.OriginBreakerOpens = bs.Opens
+	stats.OriginRejected = bs.Rejected
+}
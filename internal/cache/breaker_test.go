@@ -0,0 +1,159 @@
+package cache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/pkg/lru"
+)
+
+// breakerGroup returns a group whose breaker opens after 4 loads in a 10s
+// window of which half failed, and stays open for 5s
+func breakerGroup(t *testing.T, getter Getter, opts ...GroupOption) (*Group, *lru.FakeClock) {
+	t.Helper()
+	clock := lru.NewFakeClock(time.Unix(1000, 0))
+	opts = append([]GroupOption{WithClock(clock), WithOriginBreaker(BreakerConfig{
+		FailureRate: 0.5,
+		Window:      10 * time.Second,
+		MinRequests: 4,
+		OpenFor:     5 * time.Second,
+	})}, opts...)
+	return newTestGroup(t, getter, time.Minute, opts...), clock
+}
+
+// missN reads n distinct keys that are not cached and returns the last error
+func missN(g *Group, prefix string, n int) error {
+	var err error
+	for i := 0; i < n; i++ {
+		_, err = g.Get(fmt.Sprintf("%s-%d", prefix, i))
+	}
+	return err
+}
+
+func TestBreakerOpensAndRecovers(t *testing.T) {
+	getter := &versionGetter{}
+	getter.fail.Store(true)
+	g, clock := breakerGroup(t, getter)
+
+	if err := missN(g, "a", 4); err == nil || IsOriginUnavailableError(err) {
+		t.Fatalf("loads before the breaker opened: %v", err)
+	}
+	if s := g.Breaker(); s.State != BreakerOpen || s.Opens != 1 {
+		t.Fatalf("breaker after 4 failures = %+v", s)
+	}
+
+	// Open: misses fail fast without reaching the data source
+	loads := getter.loads.Load()
+	if _, err := g.Get("b"); !IsOriginUnavailableError(err) {
+		t.Fatalf("Get while open = %v, want ErrOriginUnavailable", err)
+	}
+	if getter.loads.Load() != loads {
+		t.Fatal("data source called while the breaker was open")
+	}
+	stats := g.Stats()
+	if stats.OriginBreaker != "open" || stats.OriginBreakerOpens != 1 || stats.OriginRejected != 1 {
+		t.Fatalf("stats while open: %s, %d opens, %d rejected", stats.OriginBreaker, stats.OriginBreakerOpens, stats.OriginRejected)
+	}
+
+	// Half-open: a failed probe opens the breaker again
+	clock.Advance(5 * time.Second)
+	if s := g.Breaker(); s.State != BreakerHalfOpen {
+		t.Fatalf("state after OpenFor = %v, want half-open", s.State)
+	}
+	if _, err := g.Get("c"); err == nil || IsOriginUnavailableError(err) {
+		t.Fatalf("probe = %v, want the data source error", err)
+	}
+	if s := g.Breaker(); s.State != BreakerOpen || s.Opens != 2 {
+		t.Fatalf("breaker after a failed probe = %+v", s)
+	}
+	if _, err := g.Get("c"); !IsOriginUnavailableError(err) {
+		t.Fatalf("Get after a failed probe = %v", err)
+	}
+
+	// A successful probe closes it
+	clock.Advance(5 * time.Second)
+	getter.fail.Store(false)
+	if _, err := g.Get("d"); err != nil {
+		t.Fatalf("probe: %v", err)
+	}
+	if s := g.Breaker(); s.State != BreakerClosed {
+		t.Fatalf("state after a successful probe = %v", s.State)
+	}
+	if err := missN(g, "e", 10); err != nil {
+		t.Fatalf("loads after recovery: %v", err)
+	}
+}
+
+func TestBreakerCountsPerWindow(t *testing.T) {
+	getter := &versionGetter{}
+	getter.fail.Store(true)
+	g, clock := breakerGroup(t, getter)
+
+	// Three failures, then the window rolls over before the fourth load
+	missN(g, "a", 3)
+	clock.Advance(10 * time.Second)
+	missN(g, "b", 1)
+	if s := g.Breaker(); s.State != BreakerClosed {
+		t.Fatalf("breaker opened across windows: %+v", s)
+	}
+
+	// Enough loads, but below the failure rate
+	getter.fail.Store(false)
+	clock.Advance(10 * time.Second)
+	missN(g, "c", 3)
+	getter.fail.Store(true)
+	missN(g, "d", 1)
+	if s := g.Breaker(); s.State != BreakerClosed {
+		t.Fatalf("breaker opened at 1 failure in 5 loads: %+v", s)
+	}
+}
+
+func TestBreakerNotFoundIsSuccess(t *testing.T) {
+	g, _ := breakerGroup(t, newCountingGetter(map[string]string{}))
+	if err := missN(g, "missing", 20); !IsKeyNotFoundError(err) {
+		t.Fatalf("Get = %v, want ErrNotFound", err)
+	}
+	if s := g.Breaker(); s.State != BreakerClosed || s.Opens != 0 {
+		t.Fatalf("breaker after misses = %+v", s)
+	}
+}
+
+// TestBreakerServesCachedWhileOpen keeps serving cached entries while the
+// breaker is open, and keeps a refresh-ahead entry whose reload was rejected
+func TestBreakerServesCachedWhileOpen(t *testing.T) {
+	getter := &versionGetter{}
+	g, clock := breakerGroup(t, getter, WithRefreshAhead(0.5))
+	cached := mustGet(t, g, "k")
+
+	getter.fail.Store(true)
+	missN(g, "a", 4)
+	if s := g.Breaker(); s.State != BreakerOpen {
+		t.Fatalf("breaker = %+v, want open", s)
+	}
+
+	// Past half the ttl a hit triggers a refresh, which the breaker rejects
+	clock.Advance(40 * time.Second)
+	for i := 0; i < 3; i++ {
+		if v := mustGet(t, g, "k"); v != cached {
+			t.Fatalf("Get while open = %q, want the cached %q", v, cached)
+		}
+		waitFor(t, "refresh", func() bool { return refreshIdle(g) })
+		clock.Advance(time.Second)
+	}
+	if g.Stats().OriginRejected == 0 {
+		t.Fatal("refresh reached the data source while the breaker was open")
+	}
+}
+
+func TestBreakerDisabled(t *testing.T) {
+	getter := &versionGetter{}
+	getter.fail.Store(true)
+	g := newTestGroup(t, getter, time.Minute, WithOriginBreaker(BreakerConfig{}))
+	if err := missN(g, "a", 20); IsOriginUnavailableError(err) {
+		t.Fatal("breaker with FailureRate 0 opened")
+	}
+	if s := g.Stats(); s.OriginBreaker != "" {
+		t.Fatalf("OriginBreaker = %q for a disabled breaker", s.OriginBreaker)
+	}
+}
@@ -1,7 +1,6 @@
 package cache
 
 import (
-	"sync"
 	"sync/atomic"
 	"time"
 
@@ -10,75 +9,188 @@ import (
 
 // CacheStats 缓存统计信息
 type CacheStats struct {
-	Hits int64 // 缓存命中次数
-	Gets int64 // 缓存获取请求总数
+	Hits       int64 `json:"hits"`       // 缓存命中次数
+	Gets       int64 `json:"gets"`       // 缓存获取请求总数
+	Collisions int64 `json:"collisions"` // 键摘要冲突次数（仅在键摘要模式下统计）
+	Evictions  int64 `json:"evictions"`  // 因容量限制被淘汰的条目数（包括超过高水位后的提前淘汰）
+	Bytes      int64 `json:"bytes"`      // 当前占用字节数
+	Cost       int64 `json:"cost"`       // 计入容量上限的总成本，未设置 WithEntryCost 时等于 Bytes
+	Entries    int64 `json:"entries"`    // 当前条目数
+	MaxBytes   int64 `json:"max_bytes"`  // 容量上限，Cost 与它之比即填充率
+
+	RefreshAheads   int64 `json:"refresh_aheads"`   // 触发的后台提前刷新次数
+	RefreshFailures int64 `json:"refresh_failures"` // 后台提前刷新失败次数
+	Throttled       int64 `json:"throttled"`        // 因限流被拒绝的请求数
+
+	CancelledGets int64 `json:"cancelled_gets"` // 等待加载期间调用方放弃（例如客户端断开）的读取次数
+	AbortedLoads  int64 `json:"aborted_loads"`  // 等待的调用方都已放弃而取消的加载次数
+
+	LoadsExecuted   int64 `json:"loads_executed"`   // 实际执行的加载次数（回源或从对等节点获取）
+	LoadsDeduped    int64 `json:"loads_deduped"`    // 加入同一 key 进行中的加载、共享其结果而未重复执行的次数
+	CurrentInflight int64 `json:"current_inflight"` // 正在执行的加载数，包括调用方都已放弃、尚未返回的加载
+
+	HotKeyReplications    int64 `json:"hot_key_replications"`     // 热点 key 复制到副本节点的次数
+	HotKeyReplicaFailures int64 `json:"hot_key_replica_failures"` // 热点 key 复制全部失败的次数
+	HotKeyInvalidations   int64 `json:"hot_key_invalidations"`    // 写入或删除使副本失效的次数
+
+	DeleteMarkerHits int64 `json:"delete_marker_hits"` // 删除后的加载标记期间等待同一次重新加载的读取次数
+
+	Tombstones        int64 `json:"tombstones"`         // 当前保留的删除墓碑数
+	TombstoneHits     int64 `json:"tombstone_hits"`     // 因墓碑直接按未命中返回的读取次数
+	TombstoneRejects  int64 `json:"tombstone_rejects"`  // 加载期间 key 被删除而未写入缓存的加载结果数
+	TombstonesDropped int64 `json:"tombstones_dropped"` // 墓碑数达到上限时提前丢弃的墓碑数
+
+	OriginBreaker      string `json:"origin_breaker,omitempty"` // 数据源熔断器状态：closed、open 或 half-open，未开启时为空
+	OriginBreakerOpens int64  `json:"origin_breaker_opens"`     // 熔断器打开的次数（包括探测失败后重新打开）
+	OriginRejected     int64  `json:"origin_rejected"`          // 熔断器打开期间未访问数据源直接失败的加载次数
+
+	EvictionsPerMinute       int64       `json:"evictions_per_minute"`       // 最近一分钟内的容量淘汰数（仅在开启淘汰压力统计时）
+	EvictedAgeP50Ms          int64       `json:"evicted_age_p50_ms"`         // 最近一分钟内被淘汰条目自插入起存活时长的中位数（毫秒，按直方图估算）
+	EvictedAgeP90Ms          int64       `json:"evicted_age_p90_ms"`         // 同上的 90 分位数（毫秒）
+	EvictionPressureWarnings int64       `json:"eviction_pressure_warnings"` // 因淘汰过快输出的警告次数
+	EvictedAges              []AgeBucket `json:"evicted_ages,omitempty"`     // 启动以来容量淘汰时条目存活时长的直方图，未开启时为空
+
+	WatermarkRuns      int64 `json:"watermark_runs"`      // 超过高水位后台提前淘汰的次数（仅在设置水位时）
+	WatermarkEvictions int64 `json:"watermark_evictions"` // 后台提前淘汰的条目数，也计入 Evictions
+
+	AdaptiveTTLKeys      int64       `json:"adaptive_ttl_keys"`       // 自适应 TTL 记录了历史的 key 数（仅在开启自适应 TTL 时）
+	AdaptiveTTLChanged   int64       `json:"adaptive_ttl_changed"`    // 重新加载时值已改变、TTL 减半的次数
+	AdaptiveTTLUnchanged int64       `json:"adaptive_ttl_unchanged"`  // 重新加载时值未改变、TTL 加倍的次数
+	AdaptiveTTLs         []TTLBucket `json:"adaptive_ttls,omitempty"` // 记录的 key 下一次使用的 TTL 的分布，未开启时为空
+
+	Rates *GroupRates `json:"rates,omitempty"` // 最近 1、5、15 分钟的每分钟命中、未命中、加载、淘汰和错误数，关闭统计窗口时省略
 }
 
-// Cache is a concurrency-safe wrapper around an LRU cache
+// FillPercent returns the share of MaxBytes in use as a percentage, measured by
+// cost like the limit itself; 0 for a group without a limit
+func (s CacheStats) FillPercent() float64 {
+	if s.MaxBytes <= 0 {
+		return 0
+	}
+	return float64(s.Cost) / float64(s.MaxBytes) * 100
+}
+
+// Cache is a concurrency-safe wrapper around an LRU cache. It holds no lock of
+// its own: the lru is created up front and synchronizes every operation itself,
+// and the hit statistics are atomics updated outside of any lock.
 type Cache struct {
-	mutex      sync.RWMutex
 	lru        *lru.Cache
 	cacheBytes int64
-	stats      CacheStats // 缓存统计信息
+
+	gets       atomic.Int64 // 缓存获取请求总数
+	hits       atomic.Int64 // 缓存命中次数
+	collisions atomic.Int64 // 键摘要冲突次数
+
+	water *watermarks // early eviction thresholds, nil unless WithWatermarks
 }
 
 // newCache creates a new cache with size limit
-func newCache(cacheBytes int64) *Cache {
+func newCache(cacheBytes int64, opts ...lru.Option) *Cache {
 	return &Cache{
+		lru:        lru.New(cacheBytes, nil, opts...),
 		cacheBytes: cacheBytes,
 	}
 }
 
 // add adds a value to the cache
 func (c *Cache) add(key string, value ByteView, ttl time.Duration) {
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
+	c.addValue(key, value, ttl)
+}
 
-	// Lazy initialization
-	if c.lru == nil {
-		c.lru = lru.New(c.cacheBytes, nil)
-	}
+// addValue adds any lru.Value to the cache
+func (c *Cache) addValue(key string, value lru.Value, ttl time.Duration) {
 	c.lru.Add(key, value, ttl)
+	c.aboveHighWater()
 }
 
 // get looks up a key's value from the cache
-func (c *Cache) get(key string) (value ByteView, ok bool) {
-	c.mutex.RLock()
-	defer c.mutex.RUnlock()
-
-	// 增加获取计数
-	atomic.AddInt64(&c.stats.Gets, 1)
+func (c *Cache) get(key string) (value ByteView, expiry lru.Expiry, ok bool) {
+	c.gets.Add(1)
+	v, expiry, ok := c.lru.GetWithExpiry(key)
+	if !ok {
+		return ByteView{}, lru.Expiry{}, false
+	}
+	c.hits.Add(1)
+	return v.(ByteView), expiry, true
+}
 
-	if c.lru == nil {
-		return
+// getHashed looks up an entry stored under a key digest and verifies that it
+// belongs to key. A digest collision is treated as a miss.
+func (c *Cache) getHashed(digest, key string) (value ByteView, expiry lru.Expiry, ok bool) {
+	c.gets.Add(1)
+	v, expiry, ok := c.lru.GetWithExpiry(digest)
+	if !ok {
+		return ByteView{}, lru.Expiry{}, false
 	}
+	e, isHashed := v.(hashedEntry)
+	if !isHashed || !e.matches(key) {
+		c.collisions.Add(1)
+		return ByteView{}, lru.Expiry{}, false
+	}
+	c.hits.Add(1)
+	return e.view, expiry, true
+}
 
-	if v, ok := c.lru.Get(key); ok {
-		// 增加命中计数
-		atomic.AddInt64(&c.stats.Hits, 1)
-		return v.(ByteView), true
+// snapshot returns a copy of the statistics. It only makes atomic loads, so
+// frequent scraping does not hold up Gets and writes; the counters are read one
+// by one and may be a few operations apart.
+func (c *Cache) snapshot() CacheStats {
+	stats := CacheStats{
+		Hits:       c.hits.Load(),
+		Gets:       c.gets.Load(),
+		Collisions: c.collisions.Load(),
+		Evictions:  c.lru.Evictions(),
+		Bytes:      c.lru.Bytes(),
+		Cost:       c.lru.Cost(),
+		Entries:    int64(c.lru.Len()),
+		MaxBytes:   c.cacheBytes,
 	}
-	return
+	c.watermarkStats(&stats)
+	return stats
 }
 
-// clear empties the cache
-func (c *Cache) clear() {
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
+// peek reads an entry without touching its recency, access time or the hit statistics
+func (c *Cache) peek(key string) (lru.Value, lru.Expiry, bool) {
+	return c.lru.Peek(key)
+}
 
-	if c.lru != nil {
-		c.lru.Clear()
-	}
+// walk calls fn for the entries stored while writes continue, see lru.Cache.SnapshotWithExpiry
+func (c *Cache) walk(fn func(key string, value lru.Value, expiry lru.Expiry) error) error {
+	return c.lru.SnapshotWithExpiry(fn)
 }
 
-// delete removes a key from the cache
-func (c *Cache) delete(key string) {
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
+// rangeEntries calls fn for every live entry until fn returns false, see lru.Cache.Range
+func (c *Cache) rangeEntries(fn func(key string, value lru.Value, expiry lru.Expiry) bool) {
+	c.lru.Range(fn)
+}
 
-	if c.lru == nil {
-		return
-	}
+// cost returns the total cost accounted against cacheBytes, the memory used
+// unless the group sets WithEntryCost
+func (c *Cache) cost() int64 {
+	return c.lru.Cost()
+}
+
+// bytes returns the memory used by keys and values
+func (c *Cache) bytes() int64 {
+	return c.lru.Bytes()
+}
+
+// entries returns the number of entries stored
+func (c *Cache) entries() int {
+	return c.lru.Len()
+}
+
+// removeExpired drops entries past their ttl, max age or max idle time
+func (c *Cache) removeExpired() int {
+	return c.lru.RemoveExpired()
+}
+
+// clear empties the cache
+func (c *Cache) clear() {
+	c.lru.Clear()
+}
 
-	c.lru.Delete(key)
+// delete removes a key from the cache and reports whether it was present
+func (c *Cache) delete(key string) bool {
+	return c.lru.Delete(key)
 }
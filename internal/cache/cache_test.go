@@ -0,0 +1,114 @@
+package cache
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/pkg/logger"
+	"github.com/AdrianWangs/go-cache/pkg/lru"
+)
+
+// hammer runs fn from several goroutines until each made n calls
+func hammer(workers, n int, fn func(worker, i int)) {
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < n; i++ {
+				fn(w, i)
+			}
+		}(w)
+	}
+	wg.Wait()
+}
+
+// checkCache fails unless the counters of c match the entries it holds
+func checkCache(t *testing.T, c *Cache) {
+	t.Helper()
+	var n int
+	var bytes int64
+	c.rangeEntries(func(key string, value lru.Value, _ lru.Expiry) bool {
+		n++
+		bytes += int64(len(key) + value.Len())
+		return true
+	})
+	if c.entries() != n || c.bytes() != bytes {
+		t.Fatalf("entries %d, bytes %d; the cache holds %d entries of %d bytes", c.entries(), c.bytes(), n, bytes)
+	}
+	if st := c.snapshot(); st.Hits > st.Gets {
+		t.Fatalf("%d hits out of %d gets", st.Hits, st.Gets)
+	}
+}
+
+func TestCacheClearDuringAccess(t *testing.T) {
+	c := newCache(1 << 20)
+	hammer(4, 2000, func(w, i int) {
+		key := fmt.Sprintf("key-%d", i%64)
+		switch {
+		case w == 0 && i%200 == 0:
+			c.clear()
+		case w%2 == 0:
+			c.add(key, ByteView{bytes: []byte("value")}, time.Minute)
+		default:
+			if v, _, ok := c.get(key); ok && v.String() != "value" {
+				t.Errorf("get(%s) = %q", key, v)
+			}
+		}
+	})
+	checkCache(t, c)
+
+	c.clear()
+	if c.entries() != 0 || c.bytes() != 0 {
+		t.Fatalf("entries %d, bytes %d after clear", c.entries(), c.bytes())
+	}
+	if _, _, ok := c.get("key-1"); ok {
+		t.Fatal("hit after clear")
+	}
+}
+
+func TestCacheDeleteDuringAccess(t *testing.T) {
+	c := newCache(1 << 20)
+	hammer(4, 2000, func(w, i int) {
+		key := fmt.Sprintf("key-%d", i%64)
+		switch w {
+		case 0:
+			c.add(key, ByteView{bytes: []byte("value")}, time.Minute)
+		case 1:
+			c.delete(key)
+		default:
+			c.get(key)
+		}
+	})
+	checkCache(t, c)
+
+	// A deleted key stays gone until it is added again
+	c.add("k", ByteView{bytes: []byte("v")}, 0)
+	if !c.delete("k") || c.delete("k") {
+		t.Fatal("delete did not report whether the key was cached")
+	}
+	if _, _, ok := c.get("k"); ok {
+		t.Fatal("hit after delete")
+	}
+}
+
+// BenchmarkCacheGetParallel measures hits from parallel readers. The lru is
+// the only lock a get takes, where it used to sit behind the Cache's own mutex.
+func BenchmarkCacheGetParallel(b *testing.B) {
+	logger.SetLevel("error")
+	defer logger.SetLevel("debug")
+	c := newCache(1 << 20)
+	keys := make([]string, 1024)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key-%d", i)
+		c.add(keys[i], ByteView{bytes: []byte("value")}, time.Hour)
+	}
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			c.get(keys[i%len(keys)])
+		}
+	})
+}
@@ -0,0 +1,125 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/pkg/cacheerrors"
+	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
+)
+
+// capablePeer is a fakePeer implementing every optional capability. Fetches
+// block until ctx is done when block is set; deletes and sets are recorded and
+// fail with err.
+type capablePeer struct {
+	fakePeer
+	block bool
+
+	mu      sync.Mutex
+	deletes []*pb.DeleteRequest
+	sets    []*pb.SetRequest
+}
+
+func (p *capablePeer) GetByProtoContext(ctx context.Context, req *pb.Request, resp *pb.Response) error {
+	if p.block {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	return p.GetByProto(req, resp)
+}
+
+func (p *capablePeer) DeleteByProto(ctx context.Context, req *pb.DeleteRequest, resp *pb.DeleteResponse) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.deletes = append(p.deletes, req)
+	return p.err
+}
+
+func (p *capablePeer) SetByProto(ctx context.Context, req *pb.SetRequest, resp *pb.SetResponse) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.sets = append(p.sets, req)
+	return p.err
+}
+
+// TestBasePeerDegrades checks that a peer implementing only PeerGetter still
+// serves reads, and that writes and deletes fall back to this node
+func TestBasePeerDegrades(t *testing.T) {
+	source := newCountingGetter(map[string]string{"k": "source"})
+	g := newTestGroup(t, source, time.Hour)
+	peer := &fakePeer{}
+	g.RegisterPeers(&fakePicker{peer: peer})
+
+	if v, err := g.GetWithContext(context.Background(), "k"); err != nil || v.String() != "peer:k" {
+		t.Fatalf("GetWithContext = %q, %v", v, err)
+	}
+	if peer.calls.Load() != 1 || source.count("k") != 0 {
+		t.Fatalf("peer calls %d, source loads %d", peer.calls.Load(), source.count("k"))
+	}
+
+	if err := g.Set("w", []byte("local"), 0); err != nil {
+		t.Fatal(err)
+	}
+	if v, _, ok := g.Peek("w"); !ok || v.String() != "local" {
+		t.Fatalf("Set without PeerSetter stored %q, %v", v, ok)
+	}
+	if err := g.Delete("w"); err != nil {
+		t.Fatalf("Delete without PeerDeleter = %v", err)
+	}
+	if _, _, ok := g.Peek("w"); ok {
+		t.Fatal("Delete without PeerDeleter kept the local copy")
+	}
+}
+
+func TestCapablePeerUsed(t *testing.T) {
+	source := newCountingGetter(map[string]string{"k": "source"})
+	g := newTestGroup(t, source, time.Hour)
+	peer := &capablePeer{}
+	g.RegisterPeers(&fakePicker{peer: peer})
+
+	if err := g.Set("k", []byte("v"), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if len(peer.sets) != 1 || peer.sets[0].GetKey() != "k" || peer.sets[0].GetTtlMs() != 60000 {
+		t.Fatalf("sets on owner: %v", peer.sets)
+	}
+	if _, _, ok := g.Peek("k"); ok {
+		t.Fatal("a write routed to the owner was stored locally")
+	}
+
+	if err := g.Delete("k"); err != nil {
+		t.Fatal(err)
+	}
+	if len(peer.deletes) != 1 || peer.deletes[0].GetGroup() != g.Name() {
+		t.Fatalf("deletes on owner: %v", peer.deletes)
+	}
+
+	// the owner's failure is a network error, after the local copy is gone
+	peer.err = errors.New("connection refused")
+	if err := g.Delete("k"); cacheerrors.TypeOf(err) != ErrTypeNetworkError {
+		t.Fatalf("Delete with a failing owner = %v", err)
+	}
+	if err := g.Set("k", []byte("v"), 0); cacheerrors.TypeOf(err) != ErrTypeNetworkError {
+		t.Fatalf("Set with a failing owner = %v", err)
+	}
+}
+
+// TestCapablePeerCancel checks that a fetch the caller gives up on stops at the
+// peer and does not fall back to the data source
+func TestCapablePeerCancel(t *testing.T) {
+	source := newCountingGetter(map[string]string{"k": "source"})
+	g := newTestGroup(t, source, time.Hour)
+	g.RegisterPeers(&fakePicker{peer: &capablePeer{block: true}})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := g.GetWithContext(ctx, "k"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("GetWithContext = %v, want the ctx error", err)
+	}
+	if n := source.count("k"); n != 0 {
+		t.Fatalf("data source loaded %d times after the caller gave up", n)
+	}
+}
@@ -0,0 +1,34 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/pkg/codec"
+)
+
+// GetInto reads key like GetWithContext and decodes the value into dst with c.
+// Cache errors are returned as they are, so a missing key is still ErrNotFound
+// and never decoded; an error decoding the value, including a value written
+// with another codec or a dst that is not a non-nil pointer, matches
+// codec.ErrDecode instead.
+func (g *Group) GetInto(ctx context.Context, key string, dst interface{}, c codec.Codec) error {
+	if err := codec.CheckDestination(dst); err != nil {
+		return err
+	}
+	value, err := g.GetWithContext(ctx, key)
+	if err != nil {
+		return err
+	}
+	return codec.Decode(c, value.ByteSlice(), dst)
+}
+
+// SetFrom encodes src with c and stores it like SetWithContext. An error
+// encoding src matches codec.ErrEncode and leaves the cache untouched.
+func (g *Group) SetFrom(ctx context.Context, key string, src interface{}, c codec.Codec, ttl time.Duration) error {
+	value, err := codec.Encode(c, src)
+	if err != nil {
+		return err
+	}
+	return g.SetWithContext(ctx, key, value, ttl)
+}
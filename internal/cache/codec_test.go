@@ -0,0 +1,92 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/pkg/codec"
+	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
+)
+
+type codecUser struct {
+	Name string `json:"name"`
+	Age  int    `json:"age"`
+}
+
+func TestGetIntoRoundTrip(t *testing.T) {
+	getter := newCountingGetter(map[string]string{"loaded": `{"name":"Jack","age":7}`})
+	g := newTestGroup(t, getter, time.Hour)
+	ctx := context.Background()
+
+	if err := g.SetFrom(ctx, "u:42", codecUser{Name: "Tom", Age: 30}, codec.JSON, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	var u codecUser
+	if err := g.GetInto(ctx, "u:42", &u, codec.JSON); err != nil || u != (codecUser{Name: "Tom", Age: 30}) {
+		t.Fatalf("GetInto = %+v, %v", u, err)
+	}
+
+	// Values loaded from the getter decode the same way
+	if err := g.GetInto(ctx, "loaded", &u, codec.JSON); err != nil || u != (codecUser{Name: "Jack", Age: 7}) {
+		t.Fatalf("GetInto of a loaded value = %+v, %v", u, err)
+	}
+
+	msg := &pb.Request{Group: "scores", Key: "Tom"}
+	if err := g.SetFrom(ctx, "msg", msg, codec.Proto, 0); err != nil {
+		t.Fatal(err)
+	}
+	out := &pb.Request{}
+	if err := g.GetInto(ctx, "msg", out, codec.Proto); err != nil || out.Group != "scores" || out.Key != "Tom" {
+		t.Fatalf("GetInto with the proto codec = %v, %v", out, err)
+	}
+}
+
+// TestGetIntoErrors keeps cache errors and decode errors apart
+func TestGetIntoErrors(t *testing.T) {
+	getter := newCountingGetter(map[string]string{})
+	g := newTestGroup(t, getter, time.Hour)
+	ctx := context.Background()
+	if err := g.SetFrom(ctx, "msg", &pb.Request{Group: "scores", Key: "Tom"}, codec.Proto, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	var u codecUser
+	if err := g.GetInto(ctx, "missing", &u, codec.JSON); !errors.Is(err, ErrNotFound) || errors.Is(err, codec.ErrDecode) {
+		t.Fatalf("GetInto of a missing key = %v, want ErrNotFound", err)
+	}
+
+	// A value written with one codec and read with another fails cleanly
+	if err := g.GetInto(ctx, "msg", &u, codec.JSON); !errors.Is(err, codec.ErrDecode) || IsKeyNotFoundError(err) {
+		t.Fatalf("GetInto with another codec = %v, want ErrDecode", err)
+	}
+	if _, _, ok := g.Peek("msg"); !ok {
+		t.Fatal("a failed decode removed the value")
+	}
+
+	// Invalid destinations are rejected before the cache is read
+	before := getter.count("other")
+	for _, dst := range []interface{}{nil, u, (*codecUser)(nil)} {
+		if err := g.GetInto(ctx, "other", dst, codec.JSON); !errors.Is(err, codec.ErrDecode) {
+			t.Fatalf("GetInto into %T = %v, want ErrDecode", dst, err)
+		}
+	}
+	if n := getter.count("other"); n != before {
+		t.Fatalf("getter called %d times for invalid destinations", n-before)
+	}
+}
+
+func TestSetFromEncodeError(t *testing.T) {
+	g := newTestGroup(t, newCountingGetter(map[string]string{}), time.Hour)
+	ctx := context.Background()
+	if err := g.SetFrom(ctx, "c", make(chan int), codec.JSON, 0); !errors.Is(err, codec.ErrEncode) {
+		t.Fatalf("SetFrom of a channel = %v, want ErrEncode", err)
+	}
+	if err := g.SetFrom(ctx, "u", codecUser{Name: "Tom"}, codec.Proto, 0); !errors.Is(err, codec.ErrEncode) {
+		t.Fatalf("SetFrom of a struct with the proto codec = %v, want ErrEncode", err)
+	}
+	if s := g.Stats(); s.Entries != 0 {
+		t.Fatalf("%d entries stored after failed encodes", s.Entries)
+	}
+}
@@ -0,0 +1,64 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// loadDeadlineKey is the context key under which a Get passes its deadline to
+// the load it starts, see loadContext
+type loadDeadlineKey struct{}
+
+// WithDefaultDeadline bounds Gets whose context has no deadline: the group
+// gives them d, measured from the call, and the one budget covers the fetch
+// from the owning peer, the fallback to the data source after the peer fails
+// and the load from the data source, so the caller waits at most d however the
+// key is loaded. A caller's own deadline is kept, shorter or longer than d,
+// unless WithStrictDeadline caps it. Zero, the default, leaves contexts as they
+// are.
+func WithDefaultDeadline(d time.Duration) GroupOption {
+	return func(g *Group) {
+		g.defaultDeadline = d
+	}
+}
+
+// WithStrictDeadline makes the default deadline of WithDefaultDeadline also
+// cap caller deadlines later than it, so no Get waits longer than the default
+func WithStrictDeadline(strict bool) GroupOption {
+	return func(g *Group) {
+		g.strictDeadline = strict
+	}
+}
+
+// DefaultDeadline returns the deadline given to Gets without one, 0 if none
+func (g *Group) DefaultDeadline() time.Duration {
+	return g.defaultDeadline
+}
+
+// withDeadline applies the group's default deadline to the ctx of a Get that
+// misses the cache, and records the resulting deadline for loadContext. The
+// caller must call the returned cancel function.
+func (g *Group) withDeadline(ctx context.Context) (context.Context, context.CancelFunc) {
+	if g.defaultDeadline <= 0 {
+		return ctx, func() {}
+	}
+	cancel := context.CancelFunc(func() {})
+	dl, ok := ctx.Deadline()
+	// Context deadlines run on the wall clock, not on the group's clock
+	if limit := time.Now().Add(g.defaultDeadline); !ok || (g.strictDeadline && dl.After(limit)) {
+		ctx, cancel = context.WithDeadline(ctx, limit)
+		dl = limit
+	}
+	return context.WithValue(ctx, loadDeadlineKey{}, dl), cancel
+}
+
+// loadContext bounds a shared load by the deadline of the Get that started it.
+// The load runs under a context of its own that drops the caller's deadline;
+// this puts the budget back, so the fetch from the peer and the load from the
+// data source end with it. Callers that join the load share that budget.
+func loadContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if dl, ok := ctx.Value(loadDeadlineKey{}).(time.Time); ok {
+		return context.WithDeadline(ctx, dl)
+	}
+	return ctx, func() {}
+}
@@ -0,0 +1,187 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
+)
+
+// deadlines records the deadline of each context a fetch or load ran under
+type deadlines struct {
+	mu   sync.Mutex
+	seen []time.Time // zero when the context had no deadline
+}
+
+func (d *deadlines) record(ctx context.Context) {
+	dl, _ := ctx.Deadline()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.seen = append(d.seen, dl)
+}
+
+func (d *deadlines) last(t *testing.T) time.Time {
+	t.Helper()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.seen) == 0 {
+		t.Fatal("nothing ran")
+	}
+	return d.seen[len(d.seen)-1]
+}
+
+// failingPeer fails every fetch after delay, or earlier when ctx is done
+type failingPeer struct {
+	fakePeer
+	delay     time.Duration
+	deadlines deadlines
+}
+
+func (p *failingPeer) GetByProtoContext(ctx context.Context, req *pb.Request, resp *pb.Response) error {
+	p.calls.Add(1)
+	p.deadlines.record(ctx)
+	select {
+	case <-time.After(p.delay):
+		return errors.New("peer down")
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// slowGetter loads "v:"+key after delay unless ctx is done first
+func slowGetter(delay time.Duration, seen *deadlines) Getter {
+	return GetterCtxFunc(func(ctx context.Context, key string) ([]byte, error) {
+		seen.record(ctx)
+		select {
+		case <-time.After(delay):
+			return loadValue(key)
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	})
+}
+
+// newDeadlineGroup creates a group whose keys are owned by a peer failing
+// after peerDelay and whose data source takes loadDelay
+func newDeadlineGroup(t *testing.T, peerDelay, loadDelay time.Duration, opts ...GroupOption) (*Group, *failingPeer, *deadlines) {
+	peer := &failingPeer{delay: peerDelay}
+	var loads deadlines
+	g := newTestGroup(t, slowGetter(loadDelay, &loads), time.Hour, opts...)
+	g.RegisterPeers(&fakePicker{peer: peer})
+	return g, peer, &loads
+}
+
+// TestDefaultDeadlineSpansPeerAndLoad spends the budget on a failing peer and
+// then on a data source that never answers: the caller gets
+// DeadlineExceeded after the default deadline, not after the sum of both
+func TestDefaultDeadlineSpansPeerAndLoad(t *testing.T) {
+	const budget = 300 * time.Millisecond
+	g, peer, loads := newDeadlineGroup(t, 100*time.Millisecond, time.Hour, WithDefaultDeadline(budget))
+
+	start := time.Now()
+	_, err := g.GetWithContext(context.Background(), "k")
+	elapsed := time.Since(start)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Get = %v, want DeadlineExceeded", err)
+	}
+	if elapsed < budget-20*time.Millisecond || elapsed > budget+250*time.Millisecond {
+		t.Fatalf("Get returned after %v, want about %v", elapsed, budget)
+	}
+	if peer.calls.Load() != 1 {
+		t.Fatalf("peer called %d times", peer.calls.Load())
+	}
+
+	// The peer and the data source ran under the one deadline
+	peerDeadline, loadDeadline := peer.deadlines.last(t), loads.last(t)
+	if peerDeadline.IsZero() || !loadDeadline.Equal(peerDeadline) {
+		t.Fatalf("peer deadline %v, load deadline %v, want the same one", peerDeadline, loadDeadline)
+	}
+	if d := peerDeadline.Sub(start); d < budget-20*time.Millisecond || d > budget+50*time.Millisecond {
+		t.Fatalf("deadline %v after the call, want %v", d, budget)
+	}
+}
+
+// TestDefaultDeadlineLeavesTheRestToTheLoad succeeds when the data source
+// answers within what the failed peer left of the budget
+func TestDefaultDeadlineLeavesTheRestToTheLoad(t *testing.T) {
+	g, _, _ := newDeadlineGroup(t, 100*time.Millisecond, 50*time.Millisecond, WithDefaultDeadline(time.Second))
+	v, meta, err := g.GetWithMeta(context.Background(), "k")
+	if err != nil || v.String() != "v:k" || meta.Source != SourceLoader {
+		t.Fatalf("Get = %q %v %v", v.String(), meta.Source, err)
+	}
+	// A hit is served without touching the budget
+	if v := mustGet(t, g, "k"); v != "v:k" {
+		t.Fatalf("hit = %q", v)
+	}
+}
+
+func TestCallerDeadlines(t *testing.T) {
+	const budget = 200 * time.Millisecond
+	tests := []struct {
+		name     string
+		caller   time.Duration // 0 for a context without a deadline
+		strict   bool
+		deadline time.Duration // deadline the load runs under, measured from the call
+	}{
+		{"no caller deadline", 0, false, budget},
+		{"shorter caller deadline", 50 * time.Millisecond, false, 50 * time.Millisecond},
+		{"longer caller deadline", 2 * time.Second, false, 2 * time.Second},
+		{"longer caller deadline, strict", 2 * time.Second, true, budget},
+		{"shorter caller deadline, strict", 50 * time.Millisecond, true, 50 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var loads deadlines
+			g := newTestGroup(t, slowGetter(0, &loads), time.Hour, WithDefaultDeadline(budget), WithStrictDeadline(tt.strict))
+			ctx := context.Background()
+			start := time.Now()
+			if tt.caller > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.caller)
+				defer cancel()
+			}
+			if _, err := g.GetWithContext(ctx, "k"); err != nil {
+				t.Fatal(err)
+			}
+			if d := loads.last(t).Sub(start); d < tt.deadline-20*time.Millisecond || d > tt.deadline+50*time.Millisecond {
+				t.Fatalf("load deadline %v after the call, want %v", d, tt.deadline)
+			}
+		})
+	}
+}
+
+func TestNoDefaultDeadline(t *testing.T) {
+	var loads deadlines
+	g := newTestGroup(t, slowGetter(0, &loads), time.Hour)
+	mustGet(t, g, "k")
+	if dl := loads.last(t); !dl.IsZero() {
+		t.Fatalf("load ran with deadline %v, want none", dl)
+	}
+	if info := g.Info(); info.DefaultDeadline != 0 || info.StrictDeadline {
+		t.Fatalf("info = %+v", info)
+	}
+}
+
+func TestDefaultDeadlineGetChan(t *testing.T) {
+	const budget = 100 * time.Millisecond
+	g, _, _ := newDeadlineGroup(t, 30*time.Millisecond, time.Hour, WithDefaultDeadline(budget), WithStrictDeadline(true))
+	if info := g.Info(); info.DefaultDeadline != budget || !info.StrictDeadline || g.DefaultDeadline() != budget {
+		t.Fatalf("info = %+v", info)
+	}
+
+	start := time.Now()
+	select {
+	case res := <-g.GetChan(context.Background(), "k"):
+		if !errors.Is(res.Err, context.DeadlineExceeded) {
+			t.Fatalf("GetChan = %v, want DeadlineExceeded", res.Err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("GetChan did not return")
+	}
+	if elapsed := time.Since(start); elapsed > budget+250*time.Millisecond {
+		t.Fatalf("GetChan returned after %v, budget %v", elapsed, budget)
+	}
+}
@@ -0,0 +1,85 @@
+package cache
+
+import (
+	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
+	"google.golang.org/protobuf/proto"
+)
+
+// DeleteStatus is the per-key outcome of a batch delete.
+type DeleteStatus string
+
+const (
+	// DeleteDeleted means the key was cached and has been removed.
+	DeleteDeleted DeleteStatus = "deleted"
+	// DeleteNotFound means the key was not cached.
+	DeleteNotFound DeleteStatus = "not_found"
+	// DeleteError means the key could not be deleted; Error holds the reason.
+	DeleteError DeleteStatus = "error"
+)
+
+// DeleteResult is the outcome of deleting one key in a batch.
+type DeleteResult struct {
+	Key    string       `json:"key"`
+	Status DeleteStatus `json:"status"`
+	Error  string       `json:"error,omitempty"`
+}
+
+// DeleteBatch removes keys from the group's cache and returns one result per
+// key, in request order. Unlike Delete it reports whether each key was cached.
+// Read-only mode rejects the whole batch with ErrReadOnly; an empty key only
+// fails its own entry.
+func (g *Group) DeleteBatch(keys []string) ([]DeleteResult, error) {
+	if g.closed.Load() {
+		return nil, ErrGroupClosed
+	}
+	if g.Mode().ReadOnly() {
+		return nil, ErrReadOnly
+	}
+
+	results := make([]DeleteResult, len(keys))
+	deleted := 0
+	for i, key := range keys {
+		results[i].Key = key
+		if key != "" {
+			// Before the delete, as in DeleteLocally
+			g.placeMarker(key)
+		}
+		switch {
+		case key == "":
+			results[i].Status = DeleteError
+			results[i].Error = ErrEmptyKey.Error()
+		case g.deleteLocal(key):
+			// As in Delete, a digest collision may drop an unrelated entry.
+			results[i].Status = DeleteDeleted
+			deleted++
+		default:
+			results[i].Status = DeleteNotFound
+		}
+		if key != "" {
+			g.invalidateReplicas(key)
+		}
+	}
+	g.log.Debugf("[Cache] batch deleted %d/%d keys from group:%s", deleted, len(keys), g.name)
+	return results, nil
+}
+
+// DeleteResultsProto converts batch delete results into the DeleteBatch RPC response.
+func DeleteResultsProto(results []DeleteResult) *pb.DeleteBatchResponse {
+	resp := &pb.DeleteBatchResponse{Results: make([]*pb.DeleteBatchResult, len(results))}
+	for i, r := range results {
+		resp.Results[i] = &pb.DeleteBatchResult{Key: r.Key, Status: string(r.Status)}
+		if r.Error != "" {
+			resp.Results[i].Error = proto.String(r.Error)
+		}
+	}
+	return resp
+}
+
+// DeleteResultsFromProto converts a DeleteBatch RPC response back into results.
+func DeleteResultsFromProto(resp *pb.DeleteBatchResponse) []DeleteResult {
+	results := make([]DeleteResult, len(resp.GetResults()))
+	for i, r := range resp.GetResults() {
+		results[i] = DeleteResult{Key: r.GetKey(), Status: DeleteStatus(r.GetStatus()), Error: r.GetError()}
+	}
+	return results
+}
@@ -0,0 +1,178 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/internal/peers"
+	"github.com/AdrianWangs/go-cache/pkg/logger"
+)
+
+// DefaultDeleteMarkerWindow is a marker window that covers the propagation of a
+// delete through a cluster, for use with WithDeleteMarker
+const DefaultDeleteMarkerWindow = 300 * time.Millisecond
+
+// deleteMarker is the loading marker placed on a key its owner deleted. The first
+// Get that misses the key during the window reloads it; the others wait for that
+// reload instead of starting their own.
+type deleteMarker struct {
+	until    time.Time     // end of the window; a reload in flight outlives it
+	done     chan struct{} // closed when the reload finished and its result is set
+	started  bool          // a Get has started the reload, guarded by deleteMarkers.mu
+	finished bool          // the reload finished, guarded by deleteMarkers.mu
+
+	value ByteView
+	meta  ValueMeta
+	err   error
+}
+
+// markerSweepMin is the smallest marker count that triggers a sweep of expired markers
+const markerSweepMin = 1024
+
+// deleteMarkers holds the loading markers of a group
+type deleteMarkers struct {
+	mu   sync.Mutex
+	m    map[string]*deleteMarker
+	hits int64 // Gets that found a marker
+
+	sweepAt int // marker count at which placeMarker next drops expired markers
+}
+
+// WithDeleteMarker makes the owner of a key place a loading marker on it for
+// window after the key is deleted. Gets that miss the key while the marker is in
+// place share a single reload: the first one loads the key and the others block
+// until it completes, rather than each starting a load of its own as soon as the
+// previous one finished. The marker is cleared when the reload completes. The
+// reload keeps going when the caller that started it gives up, bounded by that
+// caller's deadline. A window <= 0 disables markers, which is the default.
+func WithDeleteMarker(window time.Duration) GroupOption {
+	return func(g *Group) {
+		g.markerWindow = window
+	}
+}
+
+// expired reports whether m no longer applies at now: its window is over and no
+// reload is in flight. Called with deleteMarkers.mu held.
+func (m *deleteMarker) expired(now time.Time) bool {
+	return (!m.started || m.finished) && !now.Before(m.until)
+}
+
+// placeMarker puts a loading marker on a key this node just deleted, provided it
+// owns the key. A marker left by an earlier delete is replaced.
+//
+// Markers of keys that are never read again would stay in the map, so whenever
+// it has doubled since the last sweep the expired ones are dropped. That keeps
+// the map within twice the markers still in their window, at a cost amortized
+// over the deletes.
+func (g *Group) placeMarker(key string) {
+	if g.markerWindow <= 0 {
+		return
+	}
+	if p := g.peerPicker(); p != nil && pickOwner(p, key).State == peers.PickRemote {
+		return
+	}
+	now := g.clock.Now()
+	g.markers.mu.Lock()
+	defer g.markers.mu.Unlock()
+	if g.markers.m == nil {
+		g.markers.m = make(map[string]*deleteMarker)
+	}
+	if len(g.markers.m) >= max(g.markers.sweepAt, markerSweepMin) {
+		for k, m := range g.markers.m {
+			if m.expired(now) {
+				delete(g.markers.m, k)
+			}
+		}
+		g.markers.sweepAt = 2 * len(g.markers.m)
+	}
+	g.markers.m[key] = &deleteMarker{until: now.Add(g.markerWindow), done: make(chan struct{})}
+}
+
+// clearMarker drops the marker of key, called when a write stores a new value
+func (g *Group) clearMarker(key string) {
+	if g.markerWindow <= 0 {
+		return
+	}
+	g.markers.mu.Lock()
+	delete(g.markers.m, key)
+	g.markers.mu.Unlock()
+}
+
+// marker returns the marker of key if it still applies: within its window, or
+// with its reload in flight. Expired markers are dropped.
+func (g *Group) marker(key string) *deleteMarker {
+	if g.markerWindow <= 0 {
+		return nil
+	}
+	g.markers.mu.Lock()
+	defer g.markers.mu.Unlock()
+	m := g.markers.m[key]
+	if m == nil {
+		return nil
+	}
+	if m.expired(g.clock.Now()) {
+		delete(g.markers.m, key)
+		return nil
+	}
+	return m
+}
+
+// awaitMarker serves a miss on a marked key: the first caller starts the reload
+// of the key, and every caller, the first included, waits for its result or for
+// ctx to be done. Like the loads of GetChan the reload is shared, so it runs
+// detached from the ctx of the caller that started it and ends only with that
+// ctx's deadline. The reload does not join a load of the key still in flight
+// from before the delete.
+//
+// A successful reload stores the value, so Gets stop reaching the marker; until
+// the window ends it only hands the result to callers that missed the cache just
+// before the value was stored. A failed reload removes the marker at once so the
+// next Get starts afresh.
+func (g *Group) awaitMarker(ctx context.Context, key string, m *deleteMarker) (ByteView, ValueMeta, error) {
+	atomic.AddInt64(&g.markers.hits, 1)
+
+	g.markers.mu.Lock()
+	start := !m.started
+	m.started = true
+	g.markers.mu.Unlock()
+
+	if start {
+		g.log.Debugf("[Cache] 删除后重新加载: group=%s, key=%s", g.name, logger.Key(key))
+		loadCtx, cancel := detachContext(ctx)
+		go func() {
+			defer cancel()
+			g.reloadMarked(loadCtx, key, m)
+		}()
+	} else {
+		g.log.Debugf("[Cache] 删除标记命中，等待重新加载: group=%s, key=%s", g.name, logger.Key(key))
+	}
+
+	select {
+	case <-m.done:
+		return m.value, m.meta, m.err
+	case <-ctx.Done():
+		return ByteView{}, ValueMeta{}, ctx.Err()
+	}
+}
+
+// reloadMarked runs the reload of a marked key and publishes its result to the
+// callers waiting on m
+func (g *Group) reloadMarked(ctx context.Context, key string, m *deleteMarker) {
+	// Not through g.loader: a load that started before the delete may return
+	// the deleted value
+	m.value, m.meta, m.err = loadResult(g.loadFunc(key)(ctx))
+	g.markers.mu.Lock()
+	m.finished = true
+	if m.err != nil && g.markers.m[key] == m {
+		delete(g.markers.m, key)
+	}
+	g.markers.mu.Unlock()
+	close(m.done)
+}
+
+// markerStats adds the marker hits to stats
+func (g *Group) markerStats(stats *CacheStats) {
+	stats.DeleteMarkerHits = atomic.LoadInt64(&g.markers.hits)
+}
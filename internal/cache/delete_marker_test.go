@@ -0,0 +1,191 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/pkg/lru"
+)
+
+// TestDeleteMarkerSingleReload deletes a hot key under concurrent reads and
+// checks that the reads after the delete share exactly one origin load
+func TestDeleteMarkerSingleReload(t *testing.T) {
+	getter := newCountingGetter(map[string]string{"hot": "v1"})
+	g := newTestGroup(t, GetterFunc(func(key string) ([]byte, error) {
+		time.Sleep(20 * time.Millisecond)
+		return getter.Get(key)
+	}), 0, WithDeleteMarker(time.Second))
+	if got := mustGet(t, g, "hot"); got != "v1" {
+		t.Fatalf("Get = %q", got)
+	}
+
+	getter.set("hot", "v2")
+	if err := g.DeleteLocally("hot"); err != nil {
+		t.Fatal(err)
+	}
+	var (
+		wg   sync.WaitGroup
+		errs atomic.Int64
+	)
+	for i := 0; i < 200; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				if v, err := g.Get("hot"); err != nil || v.String() != "v2" {
+					errs.Add(1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if n := errs.Load(); n != 0 {
+		t.Fatalf("%d reads failed or saw the deleted value", n)
+	}
+	if c := getter.count("hot"); c != 2 {
+		t.Fatalf("origin loads = %d, want 1 before and 1 after the delete", c)
+	}
+	if hits := g.Stats().DeleteMarkerHits; hits == 0 {
+		t.Fatal("no marker hits recorded")
+	}
+}
+
+// TestDeleteMarkerReloadOutlivesStarter cancels the Get that started the reload
+// of a marked key while another Get waits on it: the reload goes on detached
+// from the cancelled ctx and serves the remaining caller
+func TestDeleteMarkerReloadOutlivesStarter(t *testing.T) {
+	loader := newBlockingLoader()
+	g := newTestGroup(t, loader.getter(), 0, WithDeleteMarker(time.Second))
+	if err := g.SetLocally("k", []byte("old"), 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.DeleteLocally("k"); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, err := g.GetWithContext(ctx, "k")
+		first <- err
+	}()
+	loadCtx := loader.waitStarted(t)
+
+	second := make(chan GetResult, 1)
+	go func() {
+		v, err := g.GetWithContext(context.Background(), "k")
+		second <- GetResult{View: v, Err: err}
+	}()
+	waitFor(t, "the second Get to reach the marker", func() bool {
+		return g.Stats().DeleteMarkerHits == 2
+	})
+
+	cancel()
+	select {
+	case err := <-first:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("cancelled Get returned %v, want context.Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("cancelled Get did not return")
+	}
+	if err := loadCtx.Err(); err != nil {
+		t.Fatalf("reload cancelled with a caller still waiting: %v", err)
+	}
+
+	close(loader.release)
+	select {
+	case r := <-second:
+		if r.Err != nil || r.View.String() != "v:k" {
+			t.Fatalf("waiting Get got %+v", r)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("waiting Get got no result")
+	}
+}
+
+// TestDeleteMarkerFailedReload drops the marker when the reload fails, so the
+// next Get loads again
+func TestDeleteMarkerFailedReload(t *testing.T) {
+	var loads atomic.Int64
+	g := newTestGroup(t, GetterFunc(func(key string) ([]byte, error) {
+		if loads.Add(1) == 1 {
+			return nil, errors.New("origin unavailable")
+		}
+		return []byte("v"), nil
+	}), 0, WithDeleteMarker(time.Second))
+	if err := g.DeleteLocally("k"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.Get("k"); err == nil {
+		t.Fatal("Get succeeded although the reload failed")
+	}
+	if got := mustGet(t, g, "k"); got != "v" {
+		t.Fatalf("Get after the failed reload = %q", got)
+	}
+	if n := loads.Load(); n != 2 {
+		t.Fatalf("loads = %d, want 2", n)
+	}
+}
+
+// TestDeleteMarkerClearedBySet stops a write from being shadowed by the marker
+// left by an earlier delete
+func TestDeleteMarkerClearedBySet(t *testing.T) {
+	getter := newCountingGetter(map[string]string{})
+	g := newTestGroup(t, getter, 0, WithDeleteMarker(time.Second))
+	if err := g.DeleteLocally("k"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.SetLocally("k", []byte("set"), 0); err != nil {
+		t.Fatal(err)
+	}
+	if got := mustGet(t, g, "k"); got != "set" {
+		t.Fatalf("Get = %q, want set", got)
+	}
+	if hits := g.Stats().DeleteMarkerHits; hits != 0 || getter.count("k") != 0 {
+		t.Fatalf("marker hits = %d, loads = %d after a write", hits, getter.count("k"))
+	}
+}
+
+// TestDeleteMarkerSweep deletes keys that are never read again: markers past
+// their window are swept by later deletes instead of piling up
+func TestDeleteMarkerSweep(t *testing.T) {
+	const n = 5000
+	clock := lru.NewFakeClock(time.Unix(1000, 0))
+	g := newTestGroup(t, newCountingGetter(map[string]string{}), 0, WithDeleteMarker(time.Second), WithClock(clock))
+	markers := func() int {
+		g.markers.mu.Lock()
+		defer g.markers.mu.Unlock()
+		return len(g.markers.m)
+	}
+
+	for i := 0; i < n; i++ {
+		if err := g.DeleteLocally(fmt.Sprintf("old-%d", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := markers(); got != n {
+		t.Fatalf("markers = %d, want %d inside the window", got, n)
+	}
+
+	clock.Advance(time.Second + time.Millisecond)
+	for i := 0; i < n; i++ {
+		if err := g.DeleteLocally(fmt.Sprintf("new-%d", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := markers(); got >= 2*n {
+		t.Fatalf("markers = %d, want the %d expired ones swept", got, n)
+	}
+	// The markers still in their window survive the sweep
+	for i := 0; i < n; i++ {
+		if g.marker(fmt.Sprintf("new-%d", i)) == nil {
+			t.Fatalf("marker of new-%d was swept inside its window", i)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/AdrianWangs/go-cache/internal/deletequeue"
+	"github.com/AdrianWangs/go-cache/internal/peers"
+	"github.com/AdrianWangs/go-cache/pkg/cacheerrors"
+	"github.com/AdrianWangs/go-cache/pkg/logger"
+	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
+)
+
+// ErrDeleteQueued is returned by DeleteWithContext when the local copy is gone
+// and the delete on the owner failed but has been queued for retry, see
+// WithDeleteRetry. The delete is accepted, not yet complete.
+var ErrDeleteQueued = errors.New("delete on owner peer queued for retry")
+
+// DeleteQueue holds the deletes a group could not propagate to the owner of the
+// key. *deletequeue.Queue implements it.
+type DeleteQueue interface {
+	// Add queues the delete of key in group on target
+	Add(target, group, key string) error
+	// Forget drops the queued deletes of key in group
+	Forget(group, key string)
+}
+
+// WithDeleteRetry queues a delete that failed on the owner peer in q instead of
+// returning a network error, so that the owner drops its copy once it is
+// reachable again. Writing the key cancels its queued delete. The queue retries
+// with the SendFunc from DeleteRetrySender. A full queue still fails the delete.
+func WithDeleteRetry(q DeleteQueue) GroupOption {
+	return func(g *Group) {
+		g.deleteQueue = q
+	}
+}
+
+// queueDelete queues the delete of key after err failed it on the owner, and
+// returns the error DeleteWithContext reports. Only failures to reach the owner
+// are queued; a delete the owner refused, for example in read-only mode, is not.
+func (g *Group) queueDelete(key string, err error) error {
+	if g.deleteQueue == nil || !retryable(err) {
+		return WrapError(ErrTypeNetworkError, "failed to delete on owner peer", err)
+	}
+	// The owner is picked again on every retry, it may change in the meantime
+	if qerr := g.deleteQueue.Add("", g.name, key); qerr != nil {
+		g.log.Errorf("[Cache] 删除无法加入重试队列 group:%s key:%s: %v", g.name, logger.Key(key), qerr)
+		return WrapError(ErrTypeNetworkError, "failed to delete on owner peer", err)
+	}
+	g.log.Warnf("[Cache] 删除发往归属节点失败，已加入重试队列 group:%s key:%s: %v", g.name, logger.Key(key), err)
+	return ErrDeleteQueued
+}
+
+// retryable reports whether err is a failure to reach the owner, which a later
+// attempt may not hit, rather than an error the owner returned
+func retryable(err error) bool {
+	switch cacheerrors.TypeOf(err) {
+	case ErrTypeInternalError, ErrTypeNetworkError:
+		return true
+	}
+	return false
+}
+
+// forgetDelete drops a queued delete of key, called when a write stores a new value
+func (g *Group) forgetDelete(key string) {
+	if g.deleteQueue != nil {
+		g.deleteQueue.Forget(g.name, key)
+	}
+}
+
+// DeleteRetrySender returns the SendFunc of a queue given to WithDeleteRetry. It
+// looks the group up with lookup, GetGroup for groups in the default registry,
+// and deletes the key on its current owner. A key this node owns by now is done,
+// reads no longer go to the previous owner; an unknown group cannot succeed.
+func DeleteRetrySender(lookup func(name string) *Group) deletequeue.SendFunc {
+	return func(ctx context.Context, d deletequeue.Delete) error {
+		g := lookup(d.Group)
+		if g == nil {
+			return fmt.Errorf("%w: %v", deletequeue.ErrPermanent, ErrNoSuchGroup)
+		}
+		picker := g.peerPicker()
+		if picker == nil {
+			return nil
+		}
+		owner := pickOwner(picker, d.Key)
+		switch owner.State {
+		case peers.PickSelf:
+			return nil
+		case peers.PickNoPeer:
+			return ErrNoPeerAvailable
+		}
+		deleter, ok := owner.Peer.(peers.PeerDeleter)
+		if !ok {
+			return nil
+		}
+		err := deleter.DeleteByProto(ctx, &pb.DeleteRequest{Group: d.Group, Key: d.Key}, &pb.DeleteResponse{})
+		if err != nil && (IsGroupNotFoundError(err) || errors.Is(err, ErrNoSuchGroup)) {
+			return fmt.Errorf("%w: %v", deletequeue.ErrPermanent, err)
+		}
+		return err
+	}
+}
@@ -0,0 +1,81 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/internal/deletequeue"
+)
+
+// TestDeleteRetryLandsOnOwner queues a delete the unreachable owner missed and
+// retries it until the owner is back; a write cancels its queued delete
+func TestDeleteRetryLandsOnOwner(t *testing.T) {
+	peer := &capablePeer{fakePeer: fakePeer{err: errors.New("connection refused")}}
+	var g *Group
+	q, err := deletequeue.New(DeleteRetrySender(func(name string) *Group {
+		if name == g.Name() {
+			return g
+		}
+		return nil
+	}), deletequeue.WithBackoff(5*time.Millisecond, 20*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+	g = newTestGroup(t, newCountingGetter(nil), time.Hour, WithDeleteRetry(q))
+	g.RegisterPeers(&fakePicker{peer: peer})
+
+	ctx := context.Background()
+	if err := g.DeleteWithContext(ctx, "k"); !errors.Is(err, ErrDeleteQueued) {
+		t.Fatalf("delete with the owner down = %v, want ErrDeleteQueued", err)
+	}
+	if err := g.DeleteWithContext(ctx, "forgotten"); !errors.Is(err, ErrDeleteQueued) {
+		t.Fatal(err)
+	}
+	if err := g.SetLocally("forgotten", []byte("new"), 0); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "a few failed retries", func() bool { return q.Stats().Retries >= 2 })
+
+	peer.mu.Lock()
+	peer.err = nil
+	peer.deletes = nil
+	peer.mu.Unlock()
+	waitFor(t, "the queued delete to land", func() bool { return q.Len() == 0 })
+	peer.mu.Lock()
+	defer peer.mu.Unlock()
+	if len(peer.deletes) != 1 || peer.deletes[0].GetKey() != "k" {
+		t.Fatalf("deletes landed on the owner: %v", peer.deletes)
+	}
+	if st := q.Stats(); st.Succeeded != 1 || st.Forgotten != 1 {
+		t.Fatalf("stats = %+v", st)
+	}
+}
+
+// TestDeleteRetryNotForRefusals fails a delete the owner refused without queueing it
+func TestDeleteRetryNotForRefusals(t *testing.T) {
+	q, err := deletequeue.New(func(context.Context, deletequeue.Delete) error { return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+	g := newTestGroup(t, newCountingGetter(nil), time.Hour, WithDeleteRetry(q))
+	g.RegisterPeers(&fakePicker{peer: &capablePeer{fakePeer: fakePeer{err: ErrReadOnly}}})
+	if err := g.DeleteWithContext(context.Background(), "k"); err == nil || errors.Is(err, ErrDeleteQueued) {
+		t.Fatalf("refused delete = %v, want an error", err)
+	}
+	if q.Len() != 0 {
+		t.Fatalf("refused delete queued: %+v", q.Pending())
+	}
+}
+
+// TestDeleteRetrySenderUnknownGroup gives up the deletes of a group that is gone
+func TestDeleteRetrySenderUnknownGroup(t *testing.T) {
+	send := DeleteRetrySender(func(string) *Group { return nil })
+	err := send(context.Background(), deletequeue.Delete{Group: "gone", Key: "k"})
+	if !errors.Is(err, deletequeue.ErrPermanent) {
+		t.Fatalf("send = %v, want ErrPermanent", err)
+	}
+}
@@ -1,87 +1,80 @@
 package cache
 
-import (
-	"errors"
-	"fmt"
-)
+import "github.com/AdrianWangs/go-cache/pkg/cacheerrors"
+
+// 错误类型、预定义错误和错误码定义在 pkg/cacheerrors 中，供应用直接判断；
+// 这里保留同名的别名，包内和节点代码继续以 cache.* 引用
 
 // 错误类型枚举
 const (
-	// ErrTypeNone 无错误
-	ErrTypeNone = iota
-	// ErrTypeKeyEmpty 键为空
-	ErrTypeKeyEmpty
-	// ErrTypeKeyNotFound 键不存在
-	ErrTypeKeyNotFound
-	// ErrTypeGroupNotFound 组不存在
-	ErrTypeGroupNotFound
-	// ErrTypeInternalError 内部错误
-	ErrTypeInternalError
-	// ErrTypeNetworkError 网络错误
-	ErrTypeNetworkError
+	ErrTypeNone              = cacheerrors.ErrTypeNone
+	ErrTypeKeyEmpty          = cacheerrors.ErrTypeKeyEmpty
+	ErrTypeKeyNotFound       = cacheerrors.ErrTypeKeyNotFound
+	ErrTypeGroupNotFound     = cacheerrors.ErrTypeGroupNotFound
+	ErrTypeInternalError     = cacheerrors.ErrTypeInternalError
+	ErrTypeNetworkError      = cacheerrors.ErrTypeNetworkError
+	ErrTypeRateLimited       = cacheerrors.ErrTypeRateLimited
+	ErrTypeReadOnly          = cacheerrors.ErrTypeReadOnly
+	ErrTypeNoPeerAvailable   = cacheerrors.ErrTypeNoPeerAvailable
+	ErrTypeOriginUnavailable = cacheerrors.ErrTypeOriginUnavailable
+	ErrTypeGroupForbidden    = cacheerrors.ErrTypeGroupForbidden
+	ErrTypeValueTransform    = cacheerrors.ErrTypeValueTransform
+	ErrTypeGroupClosed       = cacheerrors.ErrTypeGroupClosed
 )
 
-// 预定义的错误
+// 预定义的错误，与 cacheerrors 中的是同一个值，errors.Is 可以互相匹配
 var (
-	// ErrEmptyKey 表示键为空
-	ErrEmptyKey = NewCacheError(ErrTypeKeyEmpty, "key is empty")
-	// ErrNotFound 表示键不存在
-	ErrNotFound = NewCacheError(ErrTypeKeyNotFound, "key not found")
-	// ErrNoSuchGroup 表示缓存组不存在
-	ErrNoSuchGroup = NewCacheError(ErrTypeGroupNotFound, "cache group not found")
+	ErrEmptyKey          = cacheerrors.ErrEmptyKey
+	ErrNotFound          = cacheerrors.ErrNotFound
+	ErrNoSuchGroup       = cacheerrors.ErrNoSuchGroup
+	ErrRateLimited       = cacheerrors.ErrRateLimited
+	ErrReadOnly          = cacheerrors.ErrReadOnly
+	ErrNoPeerAvailable   = cacheerrors.ErrNoPeerAvailable
+	ErrOriginUnavailable = cacheerrors.ErrOriginUnavailable
+	ErrGroupForbidden    = cacheerrors.ErrGroupForbidden
+	ErrValueTransform    = cacheerrors.ErrValueTransform
+	ErrGroupClosed       = cacheerrors.ErrGroupClosed
 )
 
 // CacheError 表示缓存错误
-type CacheError struct {
-	Type    int    // 错误类型
-	Message string // 错误信息
-	Cause   error  // 原始错误（可选）
-}
-
-// Error 实现error接口
-func (e *CacheError) Error() string {
-	if e.Cause != nil {
-		return fmt.Sprintf("%s: %v", e.Message, e.Cause)
-	}
-	return e.Message
-}
+type CacheError = cacheerrors.CacheError
 
-// Unwrap 实现errors.Unwrap接口，支持错误链
-func (e *CacheError) Unwrap() error {
-	return e.Cause
-}
+// RateLimitedError 表示带有建议重试间隔的限流错误
+type RateLimitedError = cacheerrors.RateLimitedError
 
-// NewCacheError 创建一个新的缓存错误
-func NewCacheError(errType int, message string) *CacheError {
-	return &CacheError{
-		Type:    errType,
-		Message: message,
-	}
-}
-
-// WrapError 包装一个错误
-func WrapError(errType int, message string, cause error) *CacheError {
-	return &CacheError{
-		Type:    errType,
-		Message: message,
-		Cause:   cause,
-	}
-}
-
-// IsKeyEmptyError 判断是否为键为空错误
-func IsKeyEmptyError(err error) bool {
-	var cacheErr *CacheError
-	return errors.As(err, &cacheErr) && cacheErr.Type == ErrTypeKeyEmpty
-}
+// 错误的构造与判断
+var (
+	NewCacheError            = cacheerrors.NewCacheError
+	WrapError                = cacheerrors.WrapError
+	IsKeyEmptyError          = cacheerrors.IsKeyEmptyError
+	IsKeyNotFoundError       = cacheerrors.IsKeyNotFoundError
+	IsGroupNotFoundError     = cacheerrors.IsGroupNotFoundError
+	IsRateLimitedError       = cacheerrors.IsRateLimitedError
+	IsReadOnlyError          = cacheerrors.IsReadOnlyError
+	IsNoPeerAvailableError   = cacheerrors.IsNoPeerAvailableError
+	IsOriginUnavailableError = cacheerrors.IsOriginUnavailableError
+	IsGroupForbiddenError    = cacheerrors.IsGroupForbiddenError
+	IsValueTransformError    = cacheerrors.IsValueTransformError
+	IsGroupClosedError       = cacheerrors.IsGroupClosedError
+)
 
-// IsKeyNotFoundError 判断是否为键不存在错误
-func IsKeyNotFoundError(err error) bool {
-	var cacheErr *CacheError
-	return errors.As(err, &cacheErr) && cacheErr.Type == ErrTypeKeyNotFound
-}
+// 跨节点传递的错误码，节点在纯 HTTP 接口的 X-GoCache-Error-Code 响应头中返回
+const (
+	ErrorCodeKeyEmpty       = cacheerrors.ErrorCodeKeyEmpty
+	ErrorCodeKeyNotFound    = cacheerrors.ErrorCodeKeyNotFound
+	ErrorCodeGroupNotFound  = cacheerrors.ErrorCodeGroupNotFound
+	ErrorCodeRateLimited    = cacheerrors.ErrorCodeRateLimited
+	ErrorCodeReadOnly       = cacheerrors.ErrorCodeReadOnly
+	ErrorCodeNoPeer         = cacheerrors.ErrorCodeNoPeer
+	ErrorCodeOrigin         = cacheerrors.ErrorCodeOrigin
+	ErrorCodeInternal       = cacheerrors.ErrorCodeInternal
+	ErrorCodeGroupForbidden = cacheerrors.ErrorCodeGroupForbidden
+	ErrorCodeValueTransform = cacheerrors.ErrorCodeValueTransform
+	ErrorCodeGroupClosed    = cacheerrors.ErrorCodeGroupClosed
+)
 
-// IsGroupNotFoundError 判断是否为组不存在错误
-func IsGroupNotFoundError(err error) bool {
-	var cacheErr *CacheError
-	return errors.As(err, &cacheErr) && cacheErr.Type == ErrTypeGroupNotFound
-}
+// 错误码的转换
+var (
+	ErrorCode     = cacheerrors.ErrorCode
+	ErrorFromCode = cacheerrors.ErrorFromCode
+)
@@ -0,0 +1,61 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/internal/cache"
+	"github.com/AdrianWangs/go-cache/pkg/codec"
+)
+
+// Without RegisterPeers a group is an in-process cache in front of its getter:
+// the first read loads from the getter, later ones are served from memory
+func ExampleNewGroup() {
+	loads := 0
+	getter := cache.GetterFunc(func(key string) ([]byte, error) {
+		loads++
+		return []byte("630"), nil
+	})
+
+	g := cache.NewGroup("scores", 64<<20, getter, time.Hour)
+	defer g.Close()
+	v, err := g.Get("Tom")
+
+	fmt.Println(v.String(), err)
+	g.Get("Tom")
+	s := g.Stats()
+	fmt.Println("loads:", loads, "hits:", s.Hits, "gets:", s.Gets)
+	// Output:
+	// 630 <nil>
+	// loads: 1 hits: 1 gets: 2
+}
+
+// GetInto and SetFrom store Go values through a codec; a value that does not
+// decode is reported as codec.ErrDecode, apart from cache errors
+func ExampleGroup_GetInto() {
+	type User struct {
+		Name string `json:"name"`
+		Age  int    `json:"age"`
+	}
+	g := cache.NewGroup("users", 64<<20, cache.GetterFunc(func(key string) ([]byte, error) {
+		return nil, cache.ErrNotFound
+	}), time.Hour)
+	defer g.Close()
+	ctx := context.Background()
+
+	err := g.SetFrom(ctx, "u:42", User{Name: "Tom", Age: 30}, codec.JSON, time.Hour)
+	fmt.Println(err)
+
+	var u User
+	err = g.GetInto(ctx, "u:42", &u, codec.JSON)
+	fmt.Println(u.Name, u.Age, err)
+
+	err = g.GetInto(ctx, "u:43", &u, codec.JSON)
+	fmt.Println(errors.Is(err, cache.ErrNotFound), errors.Is(err, codec.ErrDecode))
+	// Output:
+	// <nil>
+	// Tom 30 <nil>
+	// true false
+}
@@ -0,0 +1,193 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/pkg/lru"
+)
+
+// newExpiryGroup creates a group over a counting getter serving "k" with a fake clock
+func newExpiryGroup(t *testing.T, ttl time.Duration, opts ...GroupOption) (*Group, *countingGetter, *lru.FakeClock) {
+	t.Helper()
+	clock := lru.NewFakeClock(time.Unix(1000, 0))
+	getter := newCountingGetter(map[string]string{"k": "v1", "a": "a", "b": "b", "c": "c"})
+	g := newTestGroup(t, getter, ttl, append([]GroupOption{WithClock(clock)}, opts...)...)
+	return g, getter, clock
+}
+
+func TestMaxAge(t *testing.T) {
+	g, getter, clock := newExpiryGroup(t, 0, WithMaxAge(10*time.Second))
+	if g.MaxAge() != 10*time.Second || g.Info().MaxAge != 10*time.Second {
+		t.Fatalf("MaxAge() = %v, Info().MaxAge = %v", g.MaxAge(), g.Info().MaxAge)
+	}
+	mustGet(t, g, "k")
+
+	// Neither reads nor writes extend the lifetime from insertion
+	clock.Advance(5 * time.Second)
+	mustGet(t, g, "k")
+	if err := g.Set("k", []byte("written"), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(5*time.Second - time.Nanosecond)
+	if v := mustGet(t, g, "k"); v != "written" || getter.count("k") != 1 {
+		t.Fatalf("before max age: %q after %d loads", v, getter.count("k"))
+	}
+
+	clock.Advance(time.Nanosecond)
+	getter.set("k", "v2")
+	if v := mustGet(t, g, "k"); v != "v2" || getter.count("k") != 2 {
+		t.Fatalf("at max age: %q after %d loads, want a reload", v, getter.count("k"))
+	}
+
+	// The reloaded entry starts a lifetime of its own
+	clock.Advance(10*time.Second - time.Nanosecond)
+	mustGet(t, g, "k")
+	clock.Advance(time.Nanosecond)
+	mustGet(t, g, "k")
+	if n := getter.count("k"); n != 3 {
+		t.Fatalf("%d loads, want 3", n)
+	}
+}
+
+func TestMaxIdle(t *testing.T) {
+	g, getter, clock := newExpiryGroup(t, 0, WithMaxIdle(10*time.Second))
+	if g.MaxIdle() != 10*time.Second || g.Info().MaxIdle != 10*time.Second {
+		t.Fatalf("MaxIdle() = %v, Info().MaxIdle = %v", g.MaxIdle(), g.Info().MaxIdle)
+	}
+	mustGet(t, g, "k")
+
+	// Reads and writes each restart the idle window
+	for i := 0; i < 5; i++ {
+		clock.Advance(9 * time.Second)
+		mustGet(t, g, "k")
+	}
+	clock.Advance(9 * time.Second)
+	if err := g.Set("k", []byte("written"), 0); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(10*time.Second - time.Nanosecond)
+	if v := mustGet(t, g, "k"); v != "written" || getter.count("k") != 1 {
+		t.Fatalf("within the idle window: %q after %d loads", v, getter.count("k"))
+	}
+
+	clock.Advance(10 * time.Second)
+	if v := mustGet(t, g, "k"); v != "v1" || getter.count("k") != 2 {
+		t.Fatalf("after the idle window: %q after %d loads, want a reload", v, getter.count("k"))
+	}
+}
+
+// TestMaxAgeAndIdle reads an entry at the given offsets from its load: whichever
+// of the ttl, MaxAge and MaxIdle fires first expires it
+func TestMaxAgeAndIdle(t *testing.T) {
+	type read struct {
+		at     time.Duration
+		reload bool
+	}
+	tests := []struct {
+		name  string
+		ttl   time.Duration
+		opts  []GroupOption
+		reads []read
+	}{
+		{
+			name:  "idle before age when unread",
+			opts:  []GroupOption{WithMaxAge(time.Minute), WithMaxIdle(10 * time.Second)},
+			reads: []read{{9 * time.Second, false}, {19 * time.Second, true}},
+		},
+		{
+			name:  "age before idle when read often",
+			opts:  []GroupOption{WithMaxAge(30 * time.Second), WithMaxIdle(10 * time.Second)},
+			reads: []read{{9 * time.Second, false}, {18 * time.Second, false}, {27 * time.Second, false}, {30 * time.Second, true}},
+		},
+		{
+			name:  "ttl before both",
+			ttl:   5 * time.Second,
+			opts:  []GroupOption{WithMaxAge(time.Minute), WithMaxIdle(10 * time.Second)},
+			reads: []read{{4 * time.Second, false}, {6 * time.Second, true}},
+		},
+		{
+			name:  "age before a longer ttl",
+			ttl:   time.Hour,
+			opts:  []GroupOption{WithMaxAge(20 * time.Second)},
+			reads: []read{{19 * time.Second, false}, {20 * time.Second, true}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, getter, clock := newExpiryGroup(t, tt.ttl, tt.opts...)
+			start := clock.Now()
+			mustGet(t, g, "k")
+			loads := 1
+			for _, r := range tt.reads {
+				clock.Set(start.Add(r.at))
+				mustGet(t, g, "k")
+				if r.reload {
+					loads++
+				}
+				if got := getter.count("k"); got != loads {
+					t.Fatalf("after the read at %v: %d loads, want %d", r.at, got, loads)
+				}
+			}
+		})
+	}
+}
+
+func TestSweepMaxAgeAndIdle(t *testing.T) {
+	g, _, clock := newExpiryGroup(t, 0, WithMaxAge(10*time.Second), WithMaxIdle(5*time.Second))
+	for _, key := range []string{"a", "b", "c"} {
+		mustGet(t, g, key)
+	}
+
+	// b and c sit idle past 5s while a is read
+	clock.Advance(4 * time.Second)
+	mustGet(t, g, "a")
+	clock.Advance(2 * time.Second)
+	if n := g.mainCache.removeExpired(); n != 2 {
+		t.Fatalf("removed %d idle entries, want 2", n)
+	}
+	if n := g.Stats().Entries; n != 1 {
+		t.Fatalf("%d entries left, want the one read within the idle window", n)
+	}
+
+	// a is read within every idle window but reaches its max age
+	clock.Advance(2 * time.Second)
+	mustGet(t, g, "a")
+	clock.Advance(2 * time.Second)
+	if n := g.mainCache.removeExpired(); n != 1 {
+		t.Fatalf("removed %d entries at max age, want 1", n)
+	}
+	if stats := g.Stats(); stats.Entries != 0 || stats.Evictions != 0 {
+		t.Fatalf("stats after the sweeps = %+v, expiry is not eviction", stats)
+	}
+}
+
+func TestExportExpiryHonorsMaxAge(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		ttl  time.Duration
+		want time.Duration // after the load
+	}{
+		{"max age before the ttl", time.Minute, 10 * time.Second},
+		{"ttl before the max age", 5 * time.Second, 5 * time.Second},
+		{"no ttl", 0, 10 * time.Second},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			g, _, clock := newExpiryGroup(t, tt.ttl, WithMaxAge(10*time.Second))
+			start := clock.Now()
+			mustGet(t, g, "k")
+			clock.Advance(time.Second)
+
+			var entries []ExportEntry
+			if err := g.Export(func(e ExportEntry) error {
+				entries = append(entries, e)
+				return nil
+			}); err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 1 || entries[0].ExpiresAt != start.Add(tt.want).UnixNano() {
+				t.Fatalf("exported %+v, want expiry at %v", entries, start.Add(tt.want))
+			}
+		})
+	}
+}
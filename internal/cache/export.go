@@ -0,0 +1,210 @@
+package cache
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/pkg/logger"
+	"github.com/AdrianWangs/go-cache/pkg/lru"
+	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
+	"google.golang.org/protobuf/proto"
+)
+
+// ExportEntry is one cache entry in an export stream
+type ExportEntry struct {
+	Key       string `json:"key"`                  // original key
+	Value     []byte `json:"value"`                // value, base64 encoded in JSON
+	ExpiresAt int64  `json:"expires_at,omitempty"` // absolute expiry in unix nanoseconds, 0 means never
+	Version   uint64 `json:"version,omitempty"`    // write sequence number on the exporting node
+	Encoded   bool   `json:"encoded,omitempty"`    // Value is in the stored form of a group with WithValueTransform
+}
+
+// ImportResult counts what happened to the entries of an import stream
+type ImportResult struct {
+	Imported int64 `json:"imported"` // entries written to the cache
+	Expired  int64 `json:"expired"`  // entries already past their expiry
+	Skipped  int64 `json:"skipped"`  // entries that did not fit into cacheBytes or could not be decoded
+}
+
+// Add accumulates another result, e.g. from a different node
+func (r *ImportResult) Add(other ImportResult) {
+	r.Imported += other.Imported
+	r.Expired += other.Expired
+	r.Skipped += other.Skipped
+}
+
+// Export calls fn for every live entry of the group through lru.Cache.Snapshot:
+// only the key list is copied up front and values are read in small chunks, so
+// writes carry on during the export and a slow fn applies backpressure without
+// buffering the group in memory or holding the cache lock. Every entry that stays
+// in the group for the whole export is exported exactly once; entries written or
+// deleted meanwhile may be missing or carry their newer value, but no key is
+// exported twice. Entries stored in key-digest mode without their original key
+// cannot be exported and are skipped. With WithValueTransform values are exported
+// in their stored, encoded form.
+func (g *Group) Export(fn func(ExportEntry) error) error {
+	return g.mainCache.walk(func(k string, v lru.Value, expiry lru.Expiry) error {
+		e := ExportEntry{Key: k, Version: expiry.Version, Encoded: g.transform != nil}
+		switch val := v.(type) {
+		case ByteView:
+			e.Value = val.ByteSlice()
+		case hashedEntry:
+			if val.key == "" {
+				return nil
+			}
+			e.Key = val.key
+			e.Value = val.view.ByteSlice()
+		default:
+			return nil
+		}
+		e.ExpiresAt = g.exportExpiry(expiry)
+		return fn(e)
+	})
+}
+
+// exportExpiry returns the earliest of the entry's ttl expiry and its MaxAge deadline
+func (g *Group) exportExpiry(expiry lru.Expiry) int64 {
+	var deadline time.Time
+	if expiry.TTL > 0 {
+		deadline = expiry.Expires
+	}
+	if g.maxAge > 0 && !expiry.Created.IsZero() {
+		if ageLimit := expiry.Created.Add(g.maxAge); deadline.IsZero() || ageLimit.Before(deadline) {
+			deadline = ageLimit
+		}
+	}
+	if deadline.IsZero() {
+		return 0
+	}
+	return deadline.UnixNano()
+}
+
+// Import stores the entries returned by next until it returns io.EOF. Expired entries
+// are dropped and entries that would push the group past cacheBytes are skipped
+// instead of evicting what is already cached. Plain values are encoded with the
+// group's value transform; encoded ones are stored as they are, and skipped unless
+// the group can decode them. Import stops with ErrReadOnly once the group is in
+// read-only mode, and with ErrGroupClosed once it is closed.
+func (g *Group) Import(next func() (ExportEntry, error)) (ImportResult, error) {
+	var result ImportResult
+	for {
+		e, err := next()
+		if errors.Is(err, io.EOF) {
+			return result, nil
+		}
+		if err != nil {
+			return result, err
+		}
+		if g.closed.Load() {
+			return result, ErrGroupClosed
+		}
+		if g.Mode().ReadOnly() {
+			return result, ErrReadOnly
+		}
+		if e.Key == "" {
+			continue
+		}
+
+		var ttl time.Duration
+		if e.ExpiresAt != 0 {
+			ttl = time.Unix(0, e.ExpiresAt).Sub(g.clock.Now())
+			if ttl <= 0 {
+				result.Expired++
+				continue
+			}
+		}
+
+		stored, err := g.importValue(e)
+		if err != nil {
+			g.log.Warnf("[Cache] 跳过无法导入的条目: group=%s, key=%s: %v", g.name, logger.Key(e.Key), err)
+			result.Skipped++
+			continue
+		}
+		if limit := g.mainCache.cacheBytes; limit > 0 && g.mainCache.cost()+g.charge(e.Key, stored.bytes) > limit {
+			result.Skipped++
+			continue
+		}
+
+		g.storeLocally(e.Key, stored, ttl)
+		result.Imported++
+	}
+}
+
+// importValue returns the stored form of an imported value
+func (g *Group) importValue(e ExportEntry) (ByteView, error) {
+	value := ByteView{bytes: cloneBytes(e.Value)}
+	if !e.Encoded {
+		return g.encodeValue(g.cacheKey(e.Key), value)
+	}
+	if g.transform == nil {
+		return ByteView{}, WrapError(ErrTypeValueTransform, "encoded value imported into a group without value transform", nil)
+	}
+	if _, err := g.decodeValue(g.cacheKey(e.Key), value); err != nil {
+		return ByteView{}, err
+	}
+	return value, nil
+}
+
+// EntryEncoder writes export entries as newline-delimited JSON
+type EntryEncoder struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+// NewEntryEncoder creates an encoder writing to w
+func NewEntryEncoder(w io.Writer) *EntryEncoder {
+	bw := bufio.NewWriter(w)
+	return &EntryEncoder{w: bw, enc: json.NewEncoder(bw)}
+}
+
+// Encode writes one entry as a single line
+func (e *EntryEncoder) Encode(entry ExportEntry) error {
+	return e.enc.Encode(entry)
+}
+
+// Flush writes any buffered lines to the underlying writer
+func (e *EntryEncoder) Flush() error {
+	return e.w.Flush()
+}
+
+// EntryDecoder reads export entries written by an EntryEncoder
+type EntryDecoder struct {
+	dec *json.Decoder
+}
+
+// NewEntryDecoder creates a decoder reading from r
+func NewEntryDecoder(r io.Reader) *EntryDecoder {
+	return &EntryDecoder{dec: json.NewDecoder(r)}
+}
+
+// Next returns the next entry, or io.EOF at the end of the stream
+func (d *EntryDecoder) Next() (ExportEntry, error) {
+	var e ExportEntry
+	err := d.dec.Decode(&e)
+	return e, err
+}
+
+// Proto converts the entry to its peer-protocol form
+func (e ExportEntry) Proto() *pb.ExportEntry {
+	return &pb.ExportEntry{
+		Key:       e.Key,
+		Value:     e.Value,
+		ExpiresAt: proto.Int64(e.ExpiresAt),
+		Version:   proto.Uint64(e.Version),
+		Encoded:   proto.Bool(e.Encoded),
+	}
+}
+
+// ExportEntryFromProto converts a peer-protocol entry
+func ExportEntryFromProto(p *pb.ExportEntry) ExportEntry {
+	return ExportEntry{
+		Key:       p.GetKey(),
+		Value:     p.GetValue(),
+		ExpiresAt: p.GetExpiresAt(),
+		Version:   p.GetVersion(),
+		Encoded:   p.GetEncoded(),
+	}
+}
@@ -0,0 +1,194 @@
+package cache
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/pkg/lru"
+)
+
+// exportAll exports g into a map by key
+func exportAll(t *testing.T, g *Group) map[string]ExportEntry {
+	t.Helper()
+	entries := make(map[string]ExportEntry)
+	err := g.Export(func(e ExportEntry) error {
+		if _, dup := entries[e.Key]; dup {
+			t.Errorf("key %q exported twice", e.Key)
+		}
+		entries[e.Key] = e
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	return entries
+}
+
+// entriesOf returns a next function for Import over entries
+func entriesOf(entries ...ExportEntry) func() (ExportEntry, error) {
+	return func() (ExportEntry, error) {
+		if len(entries) == 0 {
+			return ExportEntry{}, io.EOF
+		}
+		e := entries[0]
+		entries = entries[1:]
+		return e, nil
+	}
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	clock := lru.NewFakeClock(time.Unix(1000, 0))
+	src := newTestGroup(t, GetterFunc(func(string) ([]byte, error) { return nil, ErrNotFound }), time.Hour, WithClock(clock))
+	for i := 0; i < 50; i++ {
+		if err := src.Set(fmt.Sprintf("key-%d", i), []byte(fmt.Sprintf("value-%d", i)), time.Duration(i+1)*time.Minute); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Set always applies a ttl; an import can store an entry that never expires
+	if _, err := src.Import(entriesOf(ExportEntry{Key: "forever", Value: []byte("v")})); err != nil {
+		t.Fatal(err)
+	}
+
+	// Through the ndjson stream the HTTP endpoints use
+	var buf bytes.Buffer
+	enc := NewEntryEncoder(&buf)
+	if err := src.Export(enc.Encode); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if lines := bytes.Count(buf.Bytes(), []byte("\n")); lines != 51 {
+		t.Fatalf("exported %d lines, want 51", lines)
+	}
+
+	dst := newTestGroup(t, GetterFunc(func(string) ([]byte, error) { return nil, ErrNotFound }), time.Hour, WithClock(clock))
+	result, err := dst.Import(NewEntryDecoder(&buf).Next)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != (ImportResult{Imported: 51}) {
+		t.Fatalf("result = %+v, want 51 imported", result)
+	}
+
+	want := exportAll(t, src)
+	got := exportAll(t, dst)
+	if len(got) != len(want) {
+		t.Fatalf("imported %d entries, want %d", len(got), len(want))
+	}
+	for key, w := range want {
+		g := got[key]
+		if !bytes.Equal(g.Value, w.Value) || g.ExpiresAt != w.ExpiresAt {
+			t.Errorf("%s: got %q expiring %d, want %q expiring %d", key, g.Value, g.ExpiresAt, w.Value, w.ExpiresAt)
+		}
+	}
+	if got["forever"].ExpiresAt != 0 {
+		t.Errorf("entry without ttl imported with expiry %d", got["forever"].ExpiresAt)
+	}
+
+	// Imported entries expire at the exported time, not a ttl later
+	clock.Advance(90 * time.Second)
+	if _, err := dst.Get("key-0"); err == nil {
+		t.Error("key-0 outlived its exported expiry")
+	}
+	if v := mustGet(t, dst, "key-1"); v != "value-1" {
+		t.Errorf("key-1 = %q", v)
+	}
+}
+
+func TestImportSkipsExpiredAndOversized(t *testing.T) {
+	clock := lru.NewFakeClock(time.Unix(1000, 0))
+	r := NewRegistry()
+	t.Cleanup(func() { r.Close() })
+	g := NewGroup("small", 100, GetterFunc(func(string) ([]byte, error) { return nil, ErrNotFound }), time.Hour,
+		WithRegistry(r), WithClock(clock))
+
+	past := clock.Now().Add(-time.Second).UnixNano()
+	result, err := g.Import(entriesOf(
+		ExportEntry{Key: "a", Value: bytes.Repeat([]byte("a"), 40)},
+		ExportEntry{Key: "expired", Value: []byte("v"), ExpiresAt: past},
+		ExportEntry{Key: "b", Value: bytes.Repeat([]byte("b"), 40)},
+		ExportEntry{Key: "too-big", Value: bytes.Repeat([]byte("c"), 40)},
+		ExportEntry{Key: "", Value: []byte("no key")},
+		ExportEntry{Key: "encoded", Value: []byte("v"), Encoded: true},
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (ImportResult{Imported: 2, Expired: 1, Skipped: 2}); result != want {
+		t.Fatalf("result = %+v, want %+v", result, want)
+	}
+	// Skipped entries did not evict what was imported before them
+	mustGet(t, g, "a")
+	mustGet(t, g, "b")
+}
+
+func TestImportStopsOnClosedGroup(t *testing.T) {
+	g := newTestGroup(t, GetterFunc(func(string) ([]byte, error) { return nil, ErrNotFound }), time.Hour)
+	g.Close()
+	_, err := g.Import(entriesOf(ExportEntry{Key: "k", Value: []byte("v")}))
+	if err != ErrGroupClosed {
+		t.Fatalf("Import on a closed group = %v, want ErrGroupClosed", err)
+	}
+}
+
+// TestExportDuringWrites exports while other goroutines write and delete other
+// keys and while the callback itself writes: every key left alone is exported
+// once and exportAll fails on duplicates; run it with -race
+func TestExportDuringWrites(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+	g := newTestGroup(t, GetterFunc(func(string) ([]byte, error) { return nil, ErrNotFound }), time.Hour)
+	const stable = 1000
+	for i := 0; i < stable; i++ {
+		if err := g.Set(fmt.Sprintf("stable-%d", i), []byte(fmt.Sprintf("value-%d", i)), time.Hour); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for w := 0; w < 2; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; ; i++ {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				key := fmt.Sprintf("churn-%d-%d", w, i%200)
+				if i%2 == 0 {
+					g.Set(key, []byte("v"), time.Hour)
+				} else {
+					g.Delete(key)
+				}
+			}
+		}(w)
+	}
+
+	// The callback runs without the cache lock and may write to the group
+	exported := 0
+	if err := g.Export(func(e ExportEntry) error {
+		exported++
+		return g.Set(fmt.Sprintf("during-%d", exported), []byte("v"), time.Hour)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	for round := 0; round < 3; round++ {
+		entries := exportAll(t, g)
+		for i := 0; i < stable; i++ {
+			key := fmt.Sprintf("stable-%d", i)
+			if e, ok := entries[key]; !ok || string(e.Value) != fmt.Sprintf("value-%d", i) {
+				t.Fatalf("round %d: %s exported %v with %q", round, key, ok, e.Value)
+			}
+		}
+	}
+	close(stop)
+	wg.Wait()
+}
@@ -0,0 +1,115 @@
+package cache
+
+import (
+	"context"
+
+	"github.com/AdrianWangs/go-cache/pkg/logger"
+)
+
+// GetResult is the outcome of a GetChan call
+type GetResult struct {
+	View ByteView  // the value, empty when Err is set
+	Meta ValueMeta // expiry, version and source of the value, see GetWithMeta
+	Err  error
+}
+
+// GetChan is the asynchronous form of GetWithMeta for callers that pipeline many
+// reads without a goroutine of their own per key. The returned channel receives
+// exactly one GetResult and is then closed.
+//
+// Cache hits and requests rejected up front are delivered before GetChan returns.
+// A miss joins the shared load of the key before GetChan returns; if ctx is done
+// first the result is ctx.Err(), while the load itself goes on for the other
+// callers waiting on it and still populates the cache. A load GetChan starts
+// keeps the values and the deadline of its caller's ctx but not its
+// cancellation, so it is bounded by that deadline rather than by the caller
+// staying, and a load it joins is not cancelled when the GetWithContext callers
+// waiting on it give up.
+//
+// The channel is buffered, so a caller may stop receiving without leaking the
+// goroutine that delivers the result. Each pending miss costs that goroutine plus
+// a channel of one GetResult until the load finishes or ctx is done; values held
+// in unreceived results stay reachable until the channel is dropped.
+func (g *Group) GetChan(ctx context.Context, key string) <-chan GetResult {
+	ch := make(chan GetResult, 1)
+	deliver := func(r GetResult) <-chan GetResult {
+		ch <- r
+		close(ch)
+		return ch
+	}
+
+	if key == "" {
+		return deliver(GetResult{Err: ErrEmptyKey})
+	}
+	if err := ctx.Err(); err != nil {
+		return deliver(GetResult{Err: err})
+	}
+	if g.closed.Load() {
+		return deliver(GetResult{Err: ErrGroupClosed})
+	}
+	if err := g.allow(ctx); err != nil {
+		return deliver(GetResult{Err: err})
+	}
+	v, expiry, ok, err := g.lookupCache(key)
+	if err != nil {
+		return deliver(GetResult{Err: err})
+	}
+	if ok {
+		g.log.Infof("[Cache] HIT - 从本地缓存命中: group:%s key:%s", g.name, logger.Key(key))
+		g.maybeRefresh(key, expiry)
+		return deliver(GetResult{View: v, Meta: g.trackHot(key, v, metaFromExpiry(expiry, SourceCache))})
+	}
+
+	g.log.Infof("[Cache] MISS - 本地缓存未命中: group:%s key:%s，将从远程或数据源加载", g.name, logger.Key(key))
+	if g.tombstoned(key) {
+		return deliver(GetResult{Err: ErrNotFound})
+	}
+	ctx, cancel := g.withDeadline(ctx)
+	if m := g.marker(key); m != nil {
+		go func() {
+			defer cancel()
+			defer close(ch)
+			v, meta, err := g.awaitMarker(ctx, key, m)
+			if err != nil {
+				ch <- GetResult{Err: err}
+				return
+			}
+			ch <- GetResult{View: v, Meta: g.trackHot(key, v, meta)}
+		}()
+		return ch
+	}
+	load := g.loadFunc(key)
+	loading := g.loader.DoChan(key, func() (interface{}, error) {
+		// The load is shared with callers that may still wait after this one
+		// gives up, so it runs detached from ctx and ends only with its deadline
+		loadCtx, cancel := detachContext(ctx)
+		defer cancel()
+		return load(loadCtx)
+	})
+	go func() {
+		defer cancel()
+		defer close(ch)
+		select {
+		case <-ctx.Done():
+			ch <- GetResult{Err: ctx.Err()}
+		case r := <-loading:
+			v, meta, err := loadResult(r.Val, r.Err)
+			if err != nil {
+				ch <- GetResult{Err: err}
+				return
+			}
+			ch <- GetResult{View: v, Meta: g.trackHot(key, v, meta)}
+		}
+	}()
+	return ch
+}
+
+// detachContext returns a context with the values and the deadline of ctx but
+// not its cancellation, for a load shared by callers that may outlast ctx
+func detachContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	detached := context.WithoutCancel(ctx)
+	if dl, ok := ctx.Deadline(); ok {
+		return context.WithDeadline(detached, dl)
+	}
+	return detached, func() {}
+}
@@ -0,0 +1,251 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// receiveOnce reads the single result of a GetChan channel and checks that the
+// channel is closed after it
+func receiveOnce(t *testing.T, ch <-chan GetResult) GetResult {
+	t.Helper()
+	var r GetResult
+	select {
+	case r = <-ch:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the GetChan result")
+	}
+	select {
+	case extra, ok := <-ch:
+		if ok {
+			t.Fatalf("second result on GetChan channel: %+v", extra)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("GetChan channel not closed after its result")
+	}
+	return r
+}
+
+func TestGetChanFanIn(t *testing.T) {
+	const n = 1000
+	values := make(map[string]string, n)
+	for i := 0; i < n; i++ {
+		values[fmt.Sprintf("k%d", i)] = fmt.Sprintf("v%d", i)
+	}
+	getter := newCountingGetter(values)
+	gate := make(chan struct{})
+	g := newTestGroup(t, GetterFunc(func(key string) ([]byte, error) {
+		<-gate
+		return getter.Get(key)
+	}), 0)
+
+	// Every key is asked for twice while the loads are held, so the second
+	// call joins the load of the first
+	chans := make([]<-chan GetResult, 0, 2*n)
+	for round := 0; round < 2; round++ {
+		for i := 0; i < n; i++ {
+			chans = append(chans, g.GetChan(context.Background(), fmt.Sprintf("k%d", i)))
+		}
+	}
+	close(gate)
+	for i, ch := range chans {
+		key := fmt.Sprintf("k%d", i%n)
+		r := receiveOnce(t, ch)
+		if r.Err != nil {
+			t.Fatalf("GetChan(%q): %v", key, r.Err)
+		}
+		if got := r.View.String(); got != values[key] {
+			t.Fatalf("GetChan(%q) = %q, want %q", key, got, values[key])
+		}
+	}
+	for key := range values {
+		if c := getter.count(key); c != 1 {
+			t.Fatalf("%q loaded %d times, want 1", key, c)
+		}
+	}
+
+	// Hits are delivered before GetChan returns
+	select {
+	case r := <-g.GetChan(context.Background(), "k0"):
+		if r.Err != nil || r.View.String() != "v0" || r.Meta.Source != SourceCache {
+			t.Fatalf("hit = %+v", r)
+		}
+	default:
+		t.Fatal("cache hit not delivered before GetChan returned")
+	}
+}
+
+func TestGetChanRejectsUpFront(t *testing.T) {
+	g := newTestGroup(t, newCountingGetter(nil), 0)
+	if r := receiveOnce(t, g.GetChan(context.Background(), "")); !errors.Is(r.Err, ErrEmptyKey) {
+		t.Fatalf("empty key: %v", r.Err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if r := receiveOnce(t, g.GetChan(ctx, "k")); !errors.Is(r.Err, context.Canceled) {
+		t.Fatalf("cancelled ctx: %v", r.Err)
+	}
+	if r := receiveOnce(t, g.GetChan(context.Background(), "missing")); !IsKeyNotFoundError(r.Err) {
+		t.Fatalf("missing key: %v", r.Err)
+	}
+}
+
+// blockingLoader is a GetterCtx that blocks until released and reports the
+// ctx of each load
+type blockingLoader struct {
+	started  chan context.Context
+	release  chan struct{}
+	finished chan error
+}
+
+func newBlockingLoader() *blockingLoader {
+	return &blockingLoader{
+		started:  make(chan context.Context, 16),
+		release:  make(chan struct{}),
+		finished: make(chan error, 16),
+	}
+}
+
+func (b *blockingLoader) getter() GetterCtxFunc {
+	return func(ctx context.Context, key string) ([]byte, error) {
+		b.started <- ctx
+		select {
+		case <-b.release:
+			b.finished <- nil
+			return []byte("v:" + key), nil
+		case <-ctx.Done():
+			b.finished <- ctx.Err()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (b *blockingLoader) waitStarted(t *testing.T) context.Context {
+	t.Helper()
+	select {
+	case ctx := <-b.started:
+		return ctx
+	case <-time.After(5 * time.Second):
+		t.Fatal("load did not start")
+		return nil
+	}
+}
+
+// TestGetChanCancelMidFlight cancels the GetChan call that started a load while
+// another caller still waits on it: the cancelled caller gets ctx.Err() at once
+// and the load finishes for the other one
+func TestGetChanCancelMidFlight(t *testing.T) {
+	for _, other := range []string{"GetChan", "GetWithContext"} {
+		t.Run(other, func(t *testing.T) {
+			loader := newBlockingLoader()
+			g := newTestGroup(t, loader.getter(), 0)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			first := g.GetChan(ctx, "k")
+			loadCtx := loader.waitStarted(t)
+
+			var second <-chan GetResult
+			if other == "GetChan" {
+				second = g.GetChan(context.Background(), "k")
+			} else {
+				ch := make(chan GetResult, 1)
+				go func() {
+					v, err := g.GetWithContext(context.Background(), "k")
+					ch <- GetResult{View: v, Err: err}
+				}()
+				second = ch
+			}
+			waitFor(t, "the second caller to join the load", func() bool {
+				return g.loader.Stats().Suppressed == 1
+			})
+
+			cancel()
+			if r := receiveOnce(t, first); !errors.Is(r.Err, context.Canceled) {
+				t.Fatalf("cancelled caller got %+v, want context.Canceled", r)
+			}
+			if err := loadCtx.Err(); err != nil {
+				t.Fatalf("load cancelled with a caller still waiting: %v", err)
+			}
+
+			close(loader.release)
+			select {
+			case r := <-second:
+				if r.Err != nil || r.View.String() != "v:k" {
+					t.Fatalf("remaining caller got %+v", r)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("remaining caller got no result")
+			}
+			if v, _, ok := g.Peek("k"); !ok || v.String() != "v:k" {
+				t.Fatal("value of the finished load not cached")
+			}
+		})
+	}
+}
+
+// TestGetChanLoadOutlivesLastCaller keeps a load GetChan started going after its
+// only caller gives up, so the value still reaches the cache
+func TestGetChanLoadOutlivesLastCaller(t *testing.T) {
+	loader := newBlockingLoader()
+	g := newTestGroup(t, loader.getter(), 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := g.GetChan(ctx, "k")
+	loadCtx := loader.waitStarted(t)
+	cancel()
+	if r := receiveOnce(t, ch); !errors.Is(r.Err, context.Canceled) {
+		t.Fatalf("got %+v, want context.Canceled", r)
+	}
+	if err := loadCtx.Err(); err != nil {
+		t.Fatalf("load cancelled with its caller: %v", err)
+	}
+	close(loader.release)
+	waitFor(t, "the load to populate the cache", func() bool {
+		_, _, ok := g.Peek("k")
+		return ok
+	})
+}
+
+// TestGetChanDeadlineBoundsLoad ends a detached load with the deadline of the
+// caller that started it, its own or the group default
+func TestGetChanDeadlineBoundsLoad(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []GroupOption
+		ctx  func() (context.Context, context.CancelFunc)
+	}{
+		{"caller deadline", nil, func() (context.Context, context.CancelFunc) {
+			return context.WithTimeout(context.Background(), 50*time.Millisecond)
+		}},
+		{"default deadline", []GroupOption{WithDefaultDeadline(50 * time.Millisecond)}, func() (context.Context, context.CancelFunc) {
+			return context.Background(), func() {}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loader := newBlockingLoader()
+			g := newTestGroup(t, loader.getter(), 0, tt.opts...)
+			ctx, cancel := tt.ctx()
+			defer cancel()
+
+			ch := g.GetChan(ctx, "k")
+			if _, ok := loader.waitStarted(t).Deadline(); !ok {
+				t.Fatal("shared load has no deadline")
+			}
+			if r := receiveOnce(t, ch); !errors.Is(r.Err, context.DeadlineExceeded) {
+				t.Fatalf("got %+v, want context.DeadlineExceeded", r)
+			}
+			select {
+			case err := <-loader.finished:
+				if !errors.Is(err, context.DeadlineExceeded) {
+					t.Fatalf("load ended with %v, want context.DeadlineExceeded", err)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("load outlived its deadline")
+			}
+		})
+	}
+}
@@ -1,5 +1,7 @@
 package cache
 
+import "context"
+
 // Getter loads data for a key
 type Getter interface {
 	// Get returns the value identified by key
@@ -13,3 +15,26 @@ type GetterFunc func(key string) ([]byte, error)
 func (f GetterFunc) Get(key string) ([]byte, error) {
 	return f(key)
 }
+
+// GetterCtx is implemented by getters that can abandon a load. A group calls
+// GetContext instead of Get; its ctx is cancelled once every caller waiting on
+// the load has given up, e.g. because their clients disconnected, and the
+// getter should then return ctx.Err() as soon as it can.
+type GetterCtx interface {
+	Getter
+	GetContext(ctx context.Context, key string) ([]byte, error)
+}
+
+// GetterCtxFunc implements GetterCtx with a function; Get calls it with a
+// background context
+type GetterCtxFunc func(ctx context.Context, key string) ([]byte, error)
+
+// Get implements the Getter interface
+func (f GetterCtxFunc) Get(key string) ([]byte, error) {
+	return f(context.Background(), key)
+}
+
+// GetContext implements the GetterCtx interface
+func (f GetterCtxFunc) GetContext(ctx context.Context, key string) ([]byte, error) {
+	return f(ctx, key)
+}
@@ -2,202 +2,719 @@ package cache
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/AdrianWangs/go-cache/internal/peers"
+	"github.com/AdrianWangs/go-cache/internal/ratewindow"
 	"github.com/AdrianWangs/go-cache/internal/singleflight"
 	"github.com/AdrianWangs/go-cache/pkg/logger"
+	"github.com/AdrianWangs/go-cache/pkg/lru"
 	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
+	"google.golang.org/protobuf/proto"
 )
 
 // Group is a cache namespace
 type Group struct {
-	name      string              // name of the cache namespace
-	getter    Getter              // the getter interface used when cache miss
-	mainCache *Cache              // main cache
-	peers     peers.PeerPicker    // peer picker interface
-	loader    *singleflight.Group // singleflight prevents redundant loads
-	ttl       time.Duration       // ttl of the cache
-}
+	name       string              // name of the cache namespace
+	getter     Getter              // the getter interface used when cache miss
+	mainCache  *Cache              // main cache
+	loader     *singleflight.Group // singleflight prevents redundant loads
+	ttl        time.Duration       // ttl of the cache
+	createdAt  time.Time           // when the group was created
+	clock      lru.Clock           // time source for expiry and refresh-ahead
+	missPolicy MissPolicy          // whether misses the owner cannot serve load from the data source
+	maxAge     time.Duration       // absolute lifetime of an entry from insertion, 0 means unlimited
+	maxIdle    time.Duration       // lifetime of an entry without reads or writes, 0 means unlimited
+	sweepEvery time.Duration       // interval of the background expiry sweeper, 0 disables it
+	untracked  bool                // disable per-entry access counting, see WithAccessTracking
+	policy     lru.Policy          // eviction policy of the lru, see WithEvictionPolicy
+	highWater  float64             // fraction of cacheBytes above which the sweeper evicts early, see WithWatermarks
+	lowWater   float64             // fraction of cacheBytes the sweeper evicts down to
 
-var (
-	mu     sync.RWMutex
-	groups = make(map[string]*Group)
-)
+	peers atomic.Pointer[registeredPeers] // peer picker, nil when standalone, see ReplacePeers
+
+	entryCost func(key string, value []byte) int64 // cost accounted per entry, nil for its byte size, see WithEntryCost
+
+	keyHashing bool        // store key digests instead of raw keys
+	keyHash    KeyHashFunc // digest function used when keyHashing is enabled
+	keepKeys   bool        // keep original keys next to values in key-digest mode
+
+	refreshFactor      float64       // fraction of the ttl after which a hit triggers refresh-ahead, 0 disables it
+	refreshConcurrency int           // max concurrent background refreshes
+	refreshWindow      time.Duration // max time since the previous read for an entry to be refreshed
+	refreshSem         chan struct{} // bounds background refreshes
+	refreshing         sync.Map      // keys with a refresh in flight
+	refreshAheads      int64         // refresh-ahead triggers
+	refreshFailures    int64         // failed background refreshes
+
+	rateLimit RateLimit                   // initial QPS limit set by WithRateLimit
+	limiter   atomic.Pointer[RateLimiter] // QPS limit, nil when unlimited
+	throttled int64                       // requests rejected by the group or node limit
+
+	clientRateLimit RateLimit                      // initial per-client QPS limit set by WithClientRateLimit
+	clientLimiter   atomic.Pointer[clientLimiters] // per-client QPS limits, nil when unlimited
+
+	cancelledGets int64 // Gets whose caller gave up while waiting for a load
+	abortedLoads  int64 // loads cancelled because every caller waiting on them gave up
+
+	mode int32 // the group's own Mode, see Group.Mode for the effective one
+
+	hotKeys *HotKeyConfig  // hot-key tracking set by WithHotKeys, nil disables it
+	hot     *hotKeyTracker // counts reads and keeps replication state, nil when disabled
+
+	markerWindow time.Duration // lifetime of the loading marker placed on delete, 0 disables it
+	markers      deleteMarkers // loading markers of deleted keys, see WithDeleteMarker
+
+	breakerCfg *BreakerConfig // origin circuit breaker set by WithOriginBreaker, nil disables it
+	breaker    *breaker       // guards calls to the getter, nil when disabled
+
+	tombstoneTTL time.Duration // how long a deleted key stays tombstoned, 0 disables tombstones
+	tombs        tombstones    // recently deleted keys, see WithTombstones
+
+	pressure *evictionPressure // age of capacity evictions, nil unless WithEvictionPressure
+	notifier Notifier          // receives the keys leaving the cache, nil unless WithEvictionNotifier
+
+	rateBuckets int         // one-minute buckets of the stats window, see WithStatsWindow
+	rates       *groupRates // recent hits, misses, loads, evictions and errors, nil when disabled
+
+	adaptive *adaptiveTTL // per-key ttl history set by WithAdaptiveTTL, nil disables it
+
+	deleteQueue DeleteQueue // retries deletes that failed on the owner, nil unless WithDeleteRetry
+
+	log logger.Logger // receives the group's own log lines, see WithLogger
 
-// NewGroup creates a new Group
-func NewGroup(name string, cacheBytes int64, getter Getter, ttl time.Duration) *Group {
+	transform *valueTransform // encodes stored values, nil unless WithValueTransform
+
+	defaultDeadline time.Duration // deadline given to Gets without one, 0 disables it
+	strictDeadline  bool          // also cap caller deadlines at defaultDeadline
+
+	registry *Registry    // registry the group is created in, see WithRegistry
+	lastUsed atomic.Int64 // unix nanos of the last registry lookup, kept for provided groups
+
+	life      sync.RWMutex       // orders starting background goroutines against Close
+	closed    atomic.Bool        // set by Close, see ErrGroupClosed
+	closeOnce sync.Once          // makes Close idempotent
+	bgCtx     context.Context    // context of background goroutines, cancelled by Close
+	bgCancel  context.CancelFunc // cancels bgCtx
+	bg        sync.WaitGroup     // background goroutines Close waits for, see goBackground
+}
+
+// NewGroup creates a new Group. Until RegisterPeers is called the group runs
+// standalone, as an in-process cache in front of getter:
+//
+//	g := cache.NewGroup("scores", 64<<20, getter, time.Hour)
+//	defer g.Close()
+//	v, err := g.Get("Tom")
+func NewGroup(name string, cacheBytes int64, getter Getter, ttl time.Duration, opts ...GroupOption) *Group {
 	if getter == nil {
 		logger.Fatal("nil Getter provided to NewGroup")
 	}
 
-	mu.Lock()
-	defer mu.Unlock()
-
 	g := &Group{
-		name:      name,
-		getter:    getter,
-		mainCache: newCache(cacheBytes),
-		loader:    &singleflight.Group{},
-		ttl:       ttl,
+		name:     name,
+		getter:   getter,
+		loader:   &singleflight.Group{},
+		ttl:      ttl,
+		clock:    lru.RealClock{},
+		keyHash:  DefaultKeyHash,
+		keepKeys: true,
+		registry: defaultRegistry,
+
+		rateBuckets: ratewindow.DefaultBuckets,
+	}
+
+	for _, opt := range opts {
+		opt(g)
+	}
+	g.log = logger.Or(g.log)
+	g.createdAt = g.clock.Now()
+	lruOpts := []lru.Option{
+		lru.WithClock(g.clock),
+		lru.WithMaxAge(g.maxAge),
+		lru.WithMaxIdle(g.maxIdle),
+		lru.WithAccessTracking(!g.untracked),
+		lru.WithPolicy(g.policy),
+	}
+	if g.entryCost != nil {
+		lruOpts = append(lruOpts, lru.WithCostFunc(g.lruCost))
+	}
+	if g.pressure != nil {
+		g.pressure.init(name, g.clock)
+	}
+	if fn := g.evictionCallback(); fn != nil {
+		lruOpts = append(lruOpts, lru.WithEvictionCallback(fn))
+	}
+	g.mainCache = newCache(cacheBytes, lruOpts...)
+	g.initRates()
+	g.initLifecycle()
+	g.initWatermarks()
+	g.SetRateLimit(g.rateLimit)
+	g.SetClientRateLimit(g.clientRateLimit)
+	g.startSweeper()
+	g.initRefreshAhead()
+	if g.hotKeys != nil {
+		g.hot = newHotKeyTracker(*g.hotKeys, g.clock)
+	}
+	if g.breakerCfg != nil && g.breakerCfg.FailureRate > 0 {
+		g.breaker = newBreaker(name, *g.breakerCfg, g.clock)
 	}
 
-	groups[name] = g
-	logger.Infof("Created cache group: %s, size: %d bytes", name, cacheBytes)
+	g.registry.add(g)
+	g.log.Infof("Created cache group: %s, size: %d bytes", name, cacheBytes)
 	return g
 }
 
-// GetGroup returns the named group previously created with NewGroup
+// GetGroup returns the named group previously created with NewGroup in the default
+// registry, or created on demand by the provider set with RegisterGroupProvider
 func GetGroup(name string) *Group {
-	mu.RLock()
-	g := groups[name]
-	mu.RUnlock()
-	return g
+	return defaultRegistry.Get(name)
 }
 
 // Get retrieves a key's value from the cache, loading it from the getter if needed
 func (g *Group) Get(key string) (ByteView, error) {
+	return g.GetWithContext(context.Background(), key)
+}
+
+// GetWithContext is like Get, but it returns ctx.Err() as soon as ctx is done.
+// Concurrent misses for the same key share one load, which goes on as long as
+// one of them still waits; when the last one gives up, the load is cancelled: a
+// fetch from the owning peer is aborted, provided the peer implements
+// peers.PeerGetterCtx, and so is a load from a getter implementing GetterCtx.
+func (g *Group) GetWithContext(ctx context.Context, key string) (ByteView, error) {
+	value, _, err := g.GetWithMeta(ctx, key)
+	return value, err
+}
+
+// GetWithMeta is like GetWithContext but also describes the value: its expiry and
+// version where this node or the owning peer knows them, and where it was found.
+func (g *Group) GetWithMeta(ctx context.Context, key string) (ByteView, ValueMeta, error) {
 	if key == "" {
-		return ByteView{}, ErrEmptyKey
+		return ByteView{}, ValueMeta{}, ErrEmptyKey
+	}
+	if g.closed.Load() {
+		return ByteView{}, ValueMeta{}, ErrGroupClosed
+	}
+	if err := g.allow(ctx); err != nil {
+		return ByteView{}, ValueMeta{}, err
 	}
 
 	// Try local cache first
-	if v, ok := g.mainCache.get(key); ok {
-		logger.Infof("[Cache] HIT - 从本地缓存命中: group:%s key:%s", g.name, key)
-		return v, nil
+	v, expiry, ok, err := g.lookupCache(key)
+	if err != nil {
+		return ByteView{}, ValueMeta{}, err
+	}
+	if ok {
+		g.log.Infof("[Cache] HIT - 从本地缓存命中: group:%s key:%s", g.name, logger.Key(key))
+		g.maybeRefresh(key, expiry)
+		return v, g.trackHot(key, v, metaFromExpiry(expiry, SourceCache)), nil
 	}
 
 	// Cache miss, load from remote or locally
-	logger.Infof("[Cache] MISS - 本地缓存未命中: group:%s key:%s，将从远程或数据源加载", g.name, key)
-	return g.load(key)
-}
-
-// GetWithContext retrieves a key's value with context
-func (g *Group) GetWithContext(ctx context.Context, key string) (ByteView, error) {
-	// Basic implementation - can be extended to use context for timeouts, etc.
-	return g.Get(key)
+	g.log.Infof("[Cache] MISS - 本地缓存未命中: group:%s key:%s，将从远程或数据源加载", g.name, logger.Key(key))
+	var meta ValueMeta
+	if g.tombstoned(key) {
+		return ByteView{}, ValueMeta{}, ErrNotFound
+	}
+	ctx, cancel := g.withDeadline(ctx)
+	defer cancel()
+	if m := g.marker(key); m != nil {
+		v, meta, err = g.awaitMarker(ctx, key, m)
+	} else {
+		v, meta, err = g.load(ctx, key)
+	}
+	if err != nil {
+		return ByteView{}, ValueMeta{}, err
+	}
+	return v, g.trackHot(key, v, meta), nil
 }
 
-// Clear clears the group's cache
-func (g *Group) Clear() {
+// Clear clears the group's cache. It fails with ErrReadOnly in read-only mode.
+func (g *Group) Clear() error {
+	if g.closed.Load() {
+		return ErrGroupClosed
+	}
+	if g.Mode().ReadOnly() {
+		return ErrReadOnly
+	}
 	g.mainCache.clear()
-	logger.Infof("Cleared cache for group: %s", g.name)
+	g.notifyCleared()
+	g.invalidateAllReplicas()
+	g.log.Infof("Cleared cache for group: %s", g.name)
+	return nil
 }
 
-// RegisterPeers registers a PeerPicker for choosing remote peer
-func (g *Group) RegisterPeers(peers peers.PeerPicker) {
-	if g.peers != nil {
-		logger.Warn("RegisterPeers called more than once")
+// RegisterPeers registers a PeerPicker for choosing remote peer. Without one
+// the group owns every key: misses load from the getter, deduplicated per key,
+// deletes and writes stay local, and nothing peer related runs in the
+// background. Hot-key replication and delete retries have no peers to act on,
+// and MissPeerOnly never loads, so standalone groups use the other policies.
+//
+// Only the first call takes effect; later ones are logged and ignored, so a
+// picker is not replaced by accident. Use ReplacePeers to swap it on purpose.
+func (g *Group) RegisterPeers(p peers.PeerPicker) {
+	if !g.peers.CompareAndSwap(nil, wrapPeers(p)) {
+		g.log.Warnf("RegisterPeers called more than once")
 		return
 	}
-	g.peers = peers
-	logger.Infof("RegisterPeers for group: %s", g.name)
-}
-
-// load loads key from remote peer or locally
-func (g *Group) load(key string) (value ByteView, err error) {
-	viewi, err := g.loader.Do(key, func() (interface{}, error) {
-		// Try to get from peer first
-		if g.peers != nil {
-			logger.Debugf("[Cache] 尝试从对等节点获取数据: group=%s, key=%s", g.name, key)
-			if peer, ok := g.peers.PickPeer(key); ok {
-				// Use protobuf for communication
-				value, err := g.getFromPeerWithProto(peer, key)
-				if err == nil {
-					logger.Infof("[Cache] 成功从对等节点获取数据: group=%s, key=%s", g.name, key)
-					return value, nil
-				}
-				logger.Warnf("[Cache] 从对等节点获取失败，将回退到本地数据源: %v", err)
-			} else {
-				logger.Debugf("[Cache] 没有找到合适的对等节点，将使用本地数据源: group=%s, key=%s", g.name, key)
+	g.log.Infof("RegisterPeers for group: %s", g.name)
+}
+
+// ReplacePeers swaps the group's PeerPicker for p, registered or not, for
+// example to move from a static pool to one fed by service discovery. A nil p
+// detaches the group from its peers and makes it standalone, as if
+// RegisterPeers had never been called.
+//
+// The swap is atomic. Every operation reads the picker once when it starts, so
+// a Get, Set, Delete or load already running finishes with the old picker,
+// including the fetch from a peer the old picker chose, and the next one starts
+// with p. The old picker is not closed; the caller owns it and may close it
+// once the operations using it have finished.
+func (g *Group) ReplacePeers(p peers.PeerPicker) {
+	g.peers.Store(wrapPeers(p))
+	g.log.Infof("ReplacePeers for group: %s, standalone: %v", g.name, p == nil)
+}
+
+// registeredPeers holds the PeerPicker of a group. The group keeps a pointer to
+// it rather than the interface, which cannot be swapped atomically.
+type registeredPeers struct {
+	picker peers.PeerPicker
+}
+
+// wrapPeers returns the registeredPeers for p, nil for a nil p
+func wrapPeers(p peers.PeerPicker) *registeredPeers {
+	if p == nil {
+		return nil
+	}
+	return &registeredPeers{picker: p}
+}
+
+// peerPicker returns the current PeerPicker, nil when the group is standalone.
+// Callers read it once and use that picker for the whole operation.
+func (g *Group) peerPicker() peers.PeerPicker {
+	if rp := g.peers.Load(); rp != nil {
+		return rp.picker
+	}
+	return nil
+}
+
+// loaded is the result shared by concurrent loads of a key
+type loaded struct {
+	value ByteView
+	meta  ValueMeta
+}
+
+// load loads key from remote peer or locally, sharing the load with concurrent
+// callers. The load is cancelled once none of them waits for it anymore.
+func (g *Group) load(ctx context.Context, key string) (ByteView, ValueMeta, error) {
+	v, meta, err := loadResult(g.loader.DoContext(ctx, key, g.loadFunc(key)))
+	if err != nil && ctx.Err() != nil {
+		atomic.AddInt64(&g.cancelledGets, 1)
+		g.log.Debugf("[Cache] 调用方已放弃等待加载: group=%s, key=%s: %v", g.name, logger.Key(key), ctx.Err())
+	}
+	return v, meta, err
+}
+
+// loadResult unpacks the result of a shared load
+func loadResult(li interface{}, err error) (ByteView, ValueMeta, error) {
+	if err != nil {
+		return ByteView{}, ValueMeta{}, err
+	}
+	l := li.(loaded)
+	return l.value, l.meta, nil
+}
+
+// loadFunc returns the singleflight function that loads key from the owning
+// peer or the data source; its result is a loaded. The load ends with the
+// budget of the Get that started it, see loadContext. The function counts the
+// loads whose ctx was cancelled before they finished.
+func (g *Group) loadFunc(key string) func(ctx context.Context) (interface{}, error) {
+	return func(ctx context.Context) (interface{}, error) {
+		loadCtx, cancel := loadContext(ctx)
+		defer cancel()
+		l, err := g.loadOnce(loadCtx, key)
+		g.countRate(rateLoads)
+		if err != nil && ctx.Err() != nil {
+			atomic.AddInt64(&g.abortedLoads, 1)
+			g.log.Infof("[Cache] 等待的调用方都已放弃，取消加载: group=%s, key=%s", g.name, logger.Key(key))
+		} else if err != nil && !errors.Is(err, ErrNotFound) {
+			g.countRate(rateErrors)
+		}
+		return l, err
+	}
+}
+
+// loadOnce loads key from the owning peer or the data source under ctx
+func (g *Group) loadOnce(ctx context.Context, key string) (interface{}, error) {
+	fwd := peers.ForwardingFrom(ctx)
+	started := g.clock.Now()
+	mode := g.Mode()
+
+	// Try to get from peer first
+	var owner peers.PickResult
+	var peerErr error
+	picker := g.peerPicker()
+	if mode == ModeReadOnlyLocal {
+		g.log.Debugf("[Cache] 只读模式（仅本地），不访问对等节点: group=%s, key=%s", g.name, logger.Key(key))
+	} else if picker != nil {
+		g.log.Debugf("[Cache] 尝试从对等节点获取数据: group=%s, key=%s", g.name, logger.Key(key))
+		owner = pickOwner(picker, key)
+		if owner.State == peers.PickRemote && fwd.LocalOnly {
+			// The forwarding node thinks we own the key while our ring points
+			// elsewhere; forwarding again could bounce the request forever
+			g.log.Warnf("[Cache] 哈希环不一致，停止转发并在本地应答: group=%s, key=%s, from=%s, self=%s, owner=%s, hops=%d",
+				g.name, logger.Key(key), fwd.From, fwd.Self, peerName(owner.Peer), fwd.Hops)
+		} else if owner.State == peers.PickRemote {
+			// Use protobuf for communication
+			value, meta, err := g.getFromPeerWithProto(ctx, owner.Peer, key)
+			if err == nil {
+				g.log.Infof("[Cache] 成功从对等节点获取数据: group=%s, key=%s", g.name, logger.Key(key))
+				return loaded{value, meta}, nil
+			}
+			if IsRateLimitedError(err) {
+				// The owner is throttling this group; loading from the data source
+				// here would bypass its limit
+				return nil, err
+			}
+			if IsOriginUnavailableError(err) {
+				// The owner's breaker found the data source down; loading it from
+				// here too would add to the load the breaker is shedding
+				return nil, err
 			}
+			if ctx.Err() != nil {
+				// The caller gave up; don't load from the data source on its behalf
+				return nil, ctx.Err()
+			}
+			g.log.Warnf("[Cache] 从对等节点获取失败: group=%s, key=%s: %v", g.name, logger.Key(key), err)
+			peerErr = err
 		} else {
-			logger.Debugf("[Cache] 未配置对等节点，直接使用本地数据源: group=%s, key=%s", g.name, key)
+			g.log.Debugf("[Cache] 没有找到合适的对等节点，将使用本地数据源: group=%s, key=%s", g.name, logger.Key(key))
 		}
+	} else {
+		g.log.Debugf("[Cache] 未配置对等节点，直接使用本地数据源: group=%s, key=%s", g.name, logger.Key(key))
+	}
 
-		// Fall back to local data source
-		logger.Infof("[Cache] 从本地数据源加载数据: group=%s, key=%s", g.name, key)
-		return g.getLocally(key)
-	})
+	// Read-only mode never touches the data source
+	if mode.ReadOnly() {
+		return nil, ErrNotFound
+	}
 
-	if err != nil {
-		return ByteView{}, err
+	if err := g.checkOriginLoad(picker, owner, peerErr); err != nil {
+		g.log.Warnf("[Cache] 缺失策略 %s 不允许从本地数据源加载: group=%s, key=%s: %v", g.missPolicy, g.name, logger.Key(key), err)
+		return nil, err
 	}
 
-	return viewi.(ByteView), nil
+	// Fall back to local data source
+	g.log.Infof("[Cache] 从本地数据源加载数据: group=%s, key=%s", g.name, logger.Key(key))
+	value, meta, err := g.getLocally(ctx, key, started)
+	return loaded{value, meta}, err
 }
 
-// getLocally loads key by calling the getter and stores it in the cache
-func (g *Group) getLocally(key string) (value ByteView, err error) {
-	logger.Debugf("从本地获取key: %s", key)
-	bytes, err := g.getter.Get(key)
+// getLocally loads key by calling the getter and stores it in the cache, unless
+// the key was deleted after the load started. A getter implementing GetterCtx
+// is called with ctx.
+func (g *Group) getLocally(ctx context.Context, key string, started time.Time) (value ByteView, meta ValueMeta, err error) {
+	g.log.Debugf("从本地获取key: %s", logger.Key(key))
+	if !g.breaker.allow() {
+		return ByteView{}, ValueMeta{}, ErrOriginUnavailable
+	}
+	var bytes []byte
+	if getter, ok := g.getter.(GetterCtx); ok {
+		bytes, err = getter.GetContext(ctx, key)
+	} else {
+		bytes, err = g.getter.Get(key)
+	}
+	if err != nil && ctx.Err() != nil {
+		// The load was cancelled, which says nothing about the data source
+		g.breaker.abandon()
+		return ByteView{}, ValueMeta{}, ctx.Err()
+	}
+	g.breaker.done(err != nil && !IsKeyNotFoundError(err))
+	if IsKeyNotFoundError(err) {
+		// The getter reported a miss; keep it a miss instead of a getter failure
+		g.log.Debugf("[Cache] getter has no value for key: %s", logger.Key(key))
+		return ByteView{}, ValueMeta{}, ErrNotFound
+	}
 	if err != nil {
-		logger.Errorf("[Cache] failed to get locally: %v", err)
-		return ByteView{}, WrapError(ErrTypeInternalError, "getter error", err)
+		g.log.Errorf("[Cache] failed to get locally: %v", err)
+		return ByteView{}, ValueMeta{}, WrapError(ErrTypeInternalError, "getter error", err)
 	}
 
 	// 如果bytes为nil或长度为0，认为是key不存在
 	if bytes == nil || len(bytes) == 0 {
-		logger.Warnf("[Cache] key not found: %s", key)
-		return ByteView{}, ErrNotFound
+		g.log.Warnf("[Cache] key not found: %s", logger.Key(key))
+		return ByteView{}, ValueMeta{}, ErrNotFound
 	}
 
 	value = ByteView{bytes: cloneBytes(bytes)}
-	g.populateCache(key, value, g.ttl)
-	return value, nil
-}
+	if g.populateCache(key, value, g.loadTTL(key, value), started) != nil {
+		return value, ValueMeta{Source: SourceLoader}, nil
+	}
 
-// populateCache adds a value to the cache
-func (g *Group) populateCache(key string, value ByteView, ttl time.Duration) {
-	g.mainCache.add(key, value, ttl)
-	logger.Infof("[Cache] 已缓存数据: group=%s, key=%s, 大小=%d字节, TTL=%v",
-		g.name, key, value.Len(), ttl)
+	// Read back the stored entry for its version and the expiry the cache applied
+	meta = ValueMeta{Source: SourceLoader}
+	if _, expiry, ok := g.mainCache.peek(g.cacheKey(key)); ok {
+		meta = metaFromExpiry(expiry, SourceLoader)
+	}
+	return value, meta, nil
 }
 
-// getFromPeerWithProto gets a value from a peer using protobuf
-func (g *Group) getFromPeerWithProto(peer peers.PeerGetter, key string) (ByteView, error) {
-	req := &pb.Request{
-		Group: g.name,
-		Key:   key,
+// lookupCache reads key from the local cache, honoring key-digest mode, and
+// decodes the value with the group's value transform
+func (g *Group) lookupCache(key string) (ByteView, lru.Expiry, bool, error) {
+	var v ByteView
+	var expiry lru.Expiry
+	var ok bool
+	if g.keyHashing {
+		v, expiry, ok = g.mainCache.getHashed(g.cacheKey(key), key)
+	} else {
+		v, expiry, ok = g.mainCache.get(key)
+	}
+	if !ok {
+		g.countRate(rateMisses)
+		return ByteView{}, lru.Expiry{}, false, nil
+	}
+	g.countRate(rateHits)
+	v, err := g.decodeCached(key, v)
+	if err != nil {
+		return ByteView{}, lru.Expiry{}, false, err
 	}
+	return v, expiry, true, nil
+}
 
-	res := &pb.Response{}
+// errLoadSuperseded is returned by populateCache for a value loaded before its
+// key was deleted
+var errLoadSuperseded = errors.New("key deleted while loading")
 
-	err := peer.GetByProto(req, res)
+// populateCache encodes a value and adds it to the cache. started is when the
+// load of value began: a value loaded before the key was deleted is refused with
+// errLoadSuperseded, see WithTombstones. Writes pass the zero time.
+func (g *Group) populateCache(key string, value ByteView, ttl time.Duration, started time.Time) error {
+	stored, err := g.encodeValue(g.cacheKey(key), value)
 	if err != nil {
-		return ByteView{}, err
+		g.log.Errorf("[Cache] 缓存值编码失败，未缓存: group=%s, key=%s: %v", g.name, logger.Key(key), err)
+		return err
+	}
+	if started.IsZero() {
+		g.storeLocally(key, stored, ttl)
+	} else if !g.storeLoaded(key, stored, ttl, started) {
+		return errLoadSuperseded
 	}
+	g.log.Infof("[Cache] 已缓存数据: group=%s, key=%s, 大小=%d字节, TTL=%v",
+		g.name, logger.Key(key), stored.Len(), ttl)
+	return nil
+}
 
-	return ByteView{bytes: res.Value}, nil
+// storeLocally writes a value in its stored form, see encodeValue, to the local
+// cache, honoring key-digest mode
+func (g *Group) storeLocally(key string, value ByteView, ttl time.Duration) {
+	if g.keyHashing {
+		g.mainCache.addValue(g.cacheKey(key), g.newHashedEntry(key, value), ttl)
+	} else {
+		g.mainCache.add(key, value, ttl)
+	}
 }
 
-// GetGroups returns all registered cache groups
-func GetGroups() map[string]*Group {
-	mu.RLock()
-	defer mu.RUnlock()
+// getFromPeerWithProto gets a value from a peer using protobuf.
+// The original key is always sent, even in key-digest mode: the owner may need it
+// to load from the data source, and owner selection on every node and on the API
+// server hashes the original key, so all parties agree on ownership.
+//
+// The request and response come from the pools in package peers and go back
+// before returning; the value bytes move into the returned ByteView.
+func (g *Group) getFromPeerWithProto(ctx context.Context, peer peers.PeerGetter, key string) (ByteView, ValueMeta, error) {
+	// Continue the hop count of a forwarded request; requests starting here go out with one hop
+	req := peers.GetRequest()
+	defer peers.PutRequest(req)
+	req.Group = g.name
+	req.Key = key
+	req.Hops = proto.Uint32(uint32(peers.ForwardingFrom(ctx).Hops) + 1)
+
+	res := peers.GetResponse()
+	defer peers.PutResponse(res)
+
+	var err error
+	if p, ok := peer.(peers.PeerGetterCtx); ok {
+		err = p.GetByProtoContext(ctx, req, res)
+	} else {
+		err = peer.GetByProto(req, res)
+	}
+	if err != nil {
+		return ByteView{}, ValueMeta{}, err
+	}
 
-	// 创建一个副本
-	result := make(map[string]*Group, len(groups))
-	for k, v := range groups {
-		result[k] = v
+	// Keep the owner's expiry and version; from here the value came from a peer
+	meta := MetaFromProto(res)
+	meta.Source = SourcePeer
+	return ByteView{bytes: res.Value}, meta, nil
+}
+
+// peerName identifies peer in log messages
+func peerName(peer peers.PeerGetter) string {
+	if s, ok := peer.(fmt.Stringer); ok {
+		return s.String()
 	}
+	return fmt.Sprintf("%T", peer)
+}
 
-	return result
+// ListGroups returns a snapshot of every group in the default registry, sorted by name
+func ListGroups() []GroupInfo {
+	return defaultRegistry.List()
 }
 
-// Stats returns statistics for this cache group
+// GroupNames returns the names of every group in the default registry, sorted
+func GroupNames() []string {
+	return defaultRegistry.Names()
+}
+
+// GetGroups returns all groups in the default registry
+func GetGroups() map[string]*Group {
+	return defaultRegistry.Groups()
+}
+
+// Stats returns a snapshot of the statistics for this cache group. It is built
+// from atomic loads only: counters and gauges are maintained as reads and writes
+// happen, and the eviction pressure figures, which need a lock to compute, are
+// published by the background sweeper every second. Scraping it never waits for
+// a lock held by Gets or writes.
 func (g *Group) Stats() CacheStats {
-	return g.mainCache.stats
+	stats := g.mainCache.snapshot()
+	stats.RefreshAheads = atomic.LoadInt64(&g.refreshAheads)
+	stats.RefreshFailures = atomic.LoadInt64(&g.refreshFailures)
+	stats.Throttled = atomic.LoadInt64(&g.throttled)
+	stats.CancelledGets = atomic.LoadInt64(&g.cancelledGets)
+	stats.AbortedLoads = atomic.LoadInt64(&g.abortedLoads)
+	loads := g.loader.Stats()
+	stats.LoadsExecuted, stats.LoadsDeduped, stats.CurrentInflight = loads.Executed, loads.Suppressed, loads.InFlight
+	g.hotKeyStats(&stats)
+	g.markerStats(&stats)
+	g.tombstoneStats(&stats)
+	g.breakerStats(&stats)
+	g.pressureStats(&stats)
+	g.ratesStats(&stats)
+	g.adaptiveStats(&stats)
+	return stats
+}
+
+// Name returns the name of the group
+func (g *Group) Name() string {
+	return g.name
+}
+
+// MaxBytes returns the configured size limit of the group's cache
+func (g *Group) MaxBytes() int64 {
+	return g.mainCache.cacheBytes
+}
+
+// Bytes returns the memory used by the keys and values in the group's cache.
+// It is kept up to date on every add, eviction, delete and expiry, so reading it
+// is cheap enough for a metrics scrape.
+func (g *Group) Bytes() int64 {
+	return g.mainCache.bytes()
+}
+
+// Entries returns the number of entries in the group's cache
+func (g *Group) Entries() int {
+	return g.mainCache.entries()
+}
+
+// TTL returns the default ttl applied to entries loaded by the group
+func (g *Group) TTL() time.Duration {
+	return g.ttl
+}
+
+// MaxAge returns the absolute lifetime limit of entries, 0 if unlimited
+func (g *Group) MaxAge() time.Duration {
+	return g.maxAge
+}
+
+// MaxIdle returns the idle lifetime limit of entries, 0 if unlimited
+func (g *Group) MaxIdle() time.Duration {
+	return g.maxIdle
 }
 
-// Delete removes a key from the cache
+// EvictionPolicy returns the policy used to evict entries over cacheBytes
+func (g *Group) EvictionPolicy() lru.Policy {
+	return g.policy
+}
+
+// Info returns a point-in-time description of the group
+func (g *Group) Info() GroupInfo {
+	return GroupInfo{
+		Name:      g.name,
+		MaxBytes:  g.MaxBytes(),
+		TTL:       g.ttl,
+		MaxAge:    g.maxAge,
+		MaxIdle:   g.maxIdle,
+		Eviction:  g.policy.String(),
+		Mode:      g.Mode().String(),
+		Stats:     g.Stats(),
+		CreatedAt: g.createdAt,
+
+		DefaultDeadline: g.defaultDeadline,
+		StrictDeadline:  g.strictDeadline,
+	}
+}
+
+// Delete removes a key from the cache, see DeleteWithContext. It fails with
+// ErrReadOnly in read-only mode.
 func (g *Group) Delete(key string) error {
+	return g.DeleteWithContext(context.Background(), key)
+}
+
+// DeleteWithContext removes key from the local cache and, when another peer owns
+// the key and implements peers.PeerDeleter, from the owner's cache as well. Peers
+// without that capability only get the local delete, as before. A failed delete on
+// the owner is returned as a network error after the local copy is already gone,
+// or queued for retry and reported as ErrDeleteQueued with WithDeleteRetry.
+func (g *Group) DeleteWithContext(ctx context.Context, key string) error {
+	if err := g.DeleteLocally(key); err != nil {
+		return err
+	}
+	picker := g.peerPicker()
+	if picker == nil {
+		return nil
+	}
+	peer, ok := picker.PickPeer(key)
+	if !ok {
+		return nil
+	}
+	deleter, ok := peer.(peers.PeerDeleter)
+	if !ok {
+		g.log.Debugf("[Cache] owner of key:%s does not accept deletes, deleted locally only", logger.Key(key))
+		return nil
+	}
+	req := &pb.DeleteRequest{Group: g.name, Key: key}
+	if err := deleter.DeleteByProto(ctx, req, &pb.DeleteResponse{}); err != nil {
+		return g.queueDelete(key, err)
+	}
+	return nil
+}
+
+// DeleteLocally removes key from this node's cache only. Handlers serving requests
+// from peers or the API server use it, since those are already routed to the owner
+// and forwarding again could bounce between nodes whose rings disagree.
+func (g *Group) DeleteLocally(key string) error {
 	if key == "" {
 		return ErrEmptyKey
 	}
+	if g.closed.Load() {
+		return ErrGroupClosed
+	}
+	if g.Mode().ReadOnly() {
+		return ErrReadOnly
+	}
 
-	g.mainCache.delete(key)
-	logger.Debugf("[Cache] deleted key:%s from group:%s", key, g.name)
+	// The marker goes in first: a Get that misses the key between the two steps
+	// must find it, or it starts a second reload alongside the marker's.
+	g.placeMarker(key)
+	// In key-digest mode a colliding key shares the slot, so this may also drop
+	// an unrelated entry; that only costs a reload and never serves wrong data.
+	g.deleteLocal(key)
+	g.invalidateReplicas(key)
+	g.log.Debugf("[Cache] deleted key:%s from group:%s", logger.Key(key), g.name)
 	return nil
 }
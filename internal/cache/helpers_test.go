@@ -0,0 +1,113 @@
+package cache
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/internal/peers"
+	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
+)
+
+// countingGetter serves values from a map and counts loads per key
+type countingGetter struct {
+	mu     sync.Mutex
+	values map[string]string
+	loads  map[string]int
+}
+
+func newCountingGetter(values map[string]string) *countingGetter {
+	return &countingGetter{values: values, loads: make(map[string]int)}
+}
+
+func (c *countingGetter) Get(key string) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.loads[key]++
+	v, ok := c.values[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return []byte(v), nil
+}
+
+func (c *countingGetter) set(key, value string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values[key] = value
+}
+
+func (c *countingGetter) count(key string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.loads[key]
+}
+
+// newTestGroup creates a group in its own registry and closes it with the test
+func newTestGroup(t testing.TB, getter Getter, ttl time.Duration, opts ...GroupOption) *Group {
+	t.Helper()
+	opts = append([]GroupOption{WithRegistry(NewRegistry())}, opts...)
+	g := NewGroup(fmt.Sprintf("%s-%d", t.Name(), time.Now().UnixNano()), 1<<20, getter, ttl, opts...)
+	t.Cleanup(func() { g.Close() })
+	return g
+}
+
+// mustGet reads key and fails the test on an error
+func mustGet(t testing.TB, g *Group, key string) string {
+	t.Helper()
+	v, err := g.Get(key)
+	if err != nil {
+		t.Fatalf("Get(%q): %v", key, err)
+	}
+	return v.String()
+}
+
+// waitFor polls cond until it holds or a few seconds have passed
+func waitFor(t testing.TB, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// fakePeer is a peer serving "peer:" followed by the key, or err when set
+type fakePeer struct {
+	calls atomic.Int64
+	err   error
+}
+
+func (p *fakePeer) Get(group, key string) ([]byte, error) {
+	p.calls.Add(1)
+	if p.err != nil {
+		return nil, p.err
+	}
+	return []byte("peer:" + key), nil
+}
+
+func (p *fakePeer) GetByProto(req *pb.Request, resp *pb.Response) error {
+	v, err := p.Get(req.GetGroup(), req.GetKey())
+	if err != nil {
+		return err
+	}
+	resp.Value = v
+	return nil
+}
+
+// fakePicker routes the keys for which owns returns true to peer, all keys when owns is nil
+type fakePicker struct {
+	peer peers.PeerGetter
+	owns func(key string) bool
+}
+
+func (p *fakePicker) PickPeer(key string) (peers.PeerGetter, bool) {
+	if p.owns != nil && !p.owns(key) {
+		return nil, false
+	}
+	return p.peer, true
+}
@@ -0,0 +1,504 @@
+package cache
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/internal/peers"
+	"github.com/AdrianWangs/go-cache/pkg/logger"
+	"github.com/AdrianWangs/go-cache/pkg/lru"
+	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
+	"google.golang.org/protobuf/proto"
+)
+
+// Defaults of HotKeyConfig
+const (
+	DefaultHotKeyWindow     = time.Second
+	DefaultHotKeyMaxTracked = 1024
+	DefaultHotKeyReplicaTTL = 30 * time.Second
+
+	// hotKeyEventLog is the number of recent replication events kept per group
+	hotKeyEventLog = 128
+	// replicaTimeout bounds each push to or delete from a replica
+	replicaTimeout = 2 * time.Second
+)
+
+// Hot-key replication events, see HotKeyEvent
+const (
+	HotKeyReplicated  = "replicated"  // the owner pushed the key to its replicas
+	HotKeyFailed      = "failed"      // no replica accepted the key
+	HotKeyInvalidated = "invalidated" // a write or delete removed the replicas
+	HotKeyExpired     = "expired"     // the replicas' ttl lapsed
+)
+
+// HotKeyConfig configures hot-key tracking and replication, see WithHotKeys.
+//
+// Every read this node serves from its cache or data source is counted per key.
+// When a key this node owns is read at Threshold per second or more, the owner
+// pushes its value to the Replicas nodes following it on the ring (through
+// peers.PeerSetter, with ReplicaTTL) and reports the replication in the response
+// metadata, so the API server can spread reads over owner and replicas. Writes and
+// deletes on the owner remove the replicas before they return.
+type HotKeyConfig struct {
+	Window     time.Duration // interval reads are counted over, DefaultHotKeyWindow when 0
+	MaxTracked int           // distinct keys counted per window, DefaultHotKeyMaxTracked when 0
+	Threshold  float64       // reads per second that trigger replication, 0 only tracks keys
+	Replicas   int           // nodes after the owner that receive a copy, 0 only tracks keys
+	ReplicaTTL time.Duration // lifetime of a copy, capped by the entry's expiry, DefaultHotKeyReplicaTTL when 0
+}
+
+// replicates reports whether the config replicates keys or only tracks them
+func (c HotKeyConfig) replicates() bool {
+	return c.Threshold > 0 && c.Replicas > 0
+}
+
+// HotKey is one key in a HotKeyReport
+type HotKey struct {
+	Key             string    `json:"key"`
+	Reads           int64     `json:"reads"`                      // reads in the last complete and the current window
+	Rate            float64   `json:"rate"`                       // reads per second in the last complete window
+	Replicas        []string  `json:"replicas,omitempty"`         // nodes holding a copy
+	ReplicatedUntil time.Time `json:"replicated_until,omitempty"` // when the copies expire
+}
+
+// HotKeyEvent is a replication event of one key
+type HotKeyEvent struct {
+	Time     time.Time `json:"time"`
+	Key      string    `json:"key"`
+	Event    string    `json:"event"` // HotKeyReplicated, HotKeyFailed, HotKeyInvalidated or HotKeyExpired
+	Replicas []string  `json:"replicas,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// HotKeyReport describes the hottest keys of a group and its recent replication events
+type HotKeyReport struct {
+	Group           string        `json:"group"`
+	Enabled         bool          `json:"enabled"` // false when the group does not track hot keys
+	Window          time.Duration `json:"window"`
+	Threshold       float64       `json:"threshold"`
+	Replicas        int           `json:"replicas"`
+	Keys            []HotKey      `json:"keys"`       // hottest keys, most read first
+	Replicated      []HotKey      `json:"replicated"` // keys currently replicated by this node
+	Events          []HotKeyEvent `json:"events"`     // recent events, newest first
+	Replications    int64         `json:"replications"`
+	ReplicaFailures int64         `json:"replica_failures"`
+	Invalidations   int64         `json:"invalidations"`
+}
+
+// hotReplica is the replication state of one key on its owner
+type hotReplica struct {
+	pending bool               // a replication is in flight
+	until   time.Time          // when the copies expire, or when to reconsider a suppressed key
+	peers   []peers.PeerGetter // replicas holding a copy, nil for a suppressed key
+	names   []string
+}
+
+// hotKeyTracker counts reads per key in fixed windows and keeps the replication state
+type hotKeyTracker struct {
+	cfg   HotKeyConfig
+	clock lru.Clock
+
+	mu          sync.Mutex
+	windowStart time.Time
+	counts      map[string]int64 // reads in the current window
+	previous    map[string]int64 // reads in the last complete window
+	replicated  map[string]*hotReplica
+	events      []HotKeyEvent // ring buffer of recent events
+	nextEvent   int
+
+	// Counters are changed under mu but read atomically, so that Stats does not
+	// contend with the reads counted under mu
+	replications    int64
+	replicaFailures int64
+	invalidations   int64
+}
+
+// newHotKeyTracker applies the defaults of cfg
+func newHotKeyTracker(cfg HotKeyConfig, clock lru.Clock) *hotKeyTracker {
+	if cfg.Window <= 0 {
+		cfg.Window = DefaultHotKeyWindow
+	}
+	if cfg.MaxTracked <= 0 {
+		cfg.MaxTracked = DefaultHotKeyMaxTracked
+	}
+	if cfg.ReplicaTTL <= 0 {
+		cfg.ReplicaTTL = DefaultHotKeyReplicaTTL
+	}
+	return &hotKeyTracker{
+		cfg:         cfg,
+		clock:       clock,
+		windowStart: clock.Now(),
+		counts:      make(map[string]int64),
+		previous:    make(map[string]int64),
+		replicated:  make(map[string]*hotReplica),
+	}
+}
+
+// roll starts a new window when the current one is over. The caller holds t.mu.
+func (t *hotKeyTracker) roll(now time.Time) {
+	elapsed := now.Sub(t.windowStart)
+	if elapsed < t.cfg.Window {
+		return
+	}
+	t.previous = t.counts
+	if elapsed >= 2*t.cfg.Window {
+		// No reads at all during the last complete window
+		t.previous = make(map[string]int64)
+	}
+	t.counts = make(map[string]int64, len(t.previous))
+	t.windowStart = now
+
+	for key, r := range t.replicated {
+		if !r.pending && !now.Before(r.until) {
+			t.expire(now, key, r)
+		}
+	}
+}
+
+// expire forgets the lapsed replication of key. The caller holds t.mu.
+func (t *hotKeyTracker) expire(now time.Time, key string, r *hotReplica) {
+	delete(t.replicated, key)
+	if len(r.names) > 0 {
+		t.logEvent(HotKeyEvent{Time: now, Key: key, Event: HotKeyExpired, Replicas: r.names})
+	}
+}
+
+// record counts a read of key and returns the replication to report with it.
+// start is true when the read pushed an unreplicated key over the threshold; the
+// caller then replicates it and reports the outcome with finish or suppress.
+func (t *hotKeyTracker) record(key string) (meta ValueMeta, start bool) {
+	now := t.clock.Now()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.roll(now)
+	n, tracked := t.counts[key]
+	if tracked || len(t.counts) < t.cfg.MaxTracked {
+		n++
+		t.counts[key] = n
+	}
+
+	if r := t.replicated[key]; r != nil {
+		switch {
+		case r.pending:
+			return ValueMeta{}, false
+		case now.Before(r.until):
+			if len(r.names) > 0 {
+				meta = ValueMeta{Replicas: len(r.names), ReplicatedUntil: r.until}
+			}
+			return meta, false
+		default:
+			t.expire(now, key, r)
+		}
+	}
+
+	if t.cfg.replicates() && float64(n) >= t.cfg.Threshold*t.cfg.Window.Seconds() {
+		t.replicated[key] = &hotReplica{pending: true}
+		return ValueMeta{}, true
+	}
+	return ValueMeta{}, false
+}
+
+// suppress stops key from being considered for replication for a replica ttl,
+// used when this node does not own it or has no replicas to push to
+func (t *hotKeyTracker) suppress(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.replicated[key] = &hotReplica{until: t.clock.Now().Add(t.cfg.ReplicaTTL)}
+}
+
+// finish records the outcome of a replication started by record. It returns the
+// replicas when a write invalidated the key while its copies were being pushed;
+// that invalidation could not reach copies it did not know about yet.
+func (t *hotKeyTracker) finish(key string, replicas []peers.PeerGetter, names []string, until time.Time, err error) (stale []peers.PeerGetter) {
+	now := t.clock.Now()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if r := t.replicated[key]; r == nil || !r.pending {
+		return replicas
+	}
+	if len(replicas) == 0 {
+		atomic.AddInt64(&t.replicaFailures, 1)
+		t.replicated[key] = &hotReplica{until: now.Add(t.cfg.ReplicaTTL)}
+		event := HotKeyEvent{Time: now, Key: key, Event: HotKeyFailed}
+		if err != nil {
+			event.Error = err.Error()
+		}
+		t.logEvent(event)
+		return nil
+	}
+	atomic.AddInt64(&t.replications, 1)
+	t.replicated[key] = &hotReplica{until: until, peers: replicas, names: names}
+	t.logEvent(HotKeyEvent{Time: now, Key: key, Event: HotKeyReplicated, Replicas: names})
+	return nil
+}
+
+// take removes the replication state of key and returns the replicas to invalidate
+func (t *hotKeyTracker) take(key string) []peers.PeerGetter {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	r := t.replicated[key]
+	if r == nil {
+		return nil
+	}
+	delete(t.replicated, key)
+	if len(r.names) == 0 || !t.clock.Now().Before(r.until) {
+		return nil
+	}
+	atomic.AddInt64(&t.invalidations, 1)
+	t.logEvent(HotKeyEvent{Time: t.clock.Now(), Key: key, Event: HotKeyInvalidated, Replicas: r.names})
+	return r.peers
+}
+
+// takeAll removes the replication state of every key and returns the replicas to
+// invalidate per key
+func (t *hotKeyTracker) takeAll() map[string][]peers.PeerGetter {
+	now := t.clock.Now()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	taken := make(map[string][]peers.PeerGetter)
+	for key, r := range t.replicated {
+		if len(r.names) > 0 && now.Before(r.until) {
+			taken[key] = r.peers
+			atomic.AddInt64(&t.invalidations, 1)
+			t.logEvent(HotKeyEvent{Time: now, Key: key, Event: HotKeyInvalidated, Replicas: r.names})
+		}
+	}
+	t.replicated = make(map[string]*hotReplica)
+	return taken
+}
+
+// logEvent appends to the event ring buffer. The caller holds t.mu.
+func (t *hotKeyTracker) logEvent(e HotKeyEvent) {
+	if len(t.events) < hotKeyEventLog {
+		t.events = append(t.events, e)
+		return
+	}
+	t.events[t.nextEvent] = e
+	t.nextEvent = (t.nextEvent + 1) % hotKeyEventLog
+}
+
+// report returns the n hottest keys, the replicated keys and the recent events
+func (t *hotKeyTracker) report(n int) HotKeyReport {
+	now := t.clock.Now()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.roll(now)
+	rep := HotKeyReport{
+		Enabled:         true,
+		Window:          t.cfg.Window,
+		Threshold:       t.cfg.Threshold,
+		Replicas:        t.cfg.Replicas,
+		Keys:            []HotKey{},
+		Replicated:      []HotKey{},
+		Replications:    atomic.LoadInt64(&t.replications),
+		ReplicaFailures: atomic.LoadInt64(&t.replicaFailures),
+		Invalidations:   atomic.LoadInt64(&t.invalidations),
+	}
+
+	hotKey := func(key string) HotKey {
+		k := HotKey{
+			Key:   key,
+			Reads: t.previous[key] + t.counts[key],
+			Rate:  float64(t.previous[key]) / t.cfg.Window.Seconds(),
+		}
+		if r := t.replicated[key]; r != nil && len(r.names) > 0 && now.Before(r.until) {
+			k.Replicas, k.ReplicatedUntil = r.names, r.until
+		}
+		return k
+	}
+
+	seen := make(map[string]bool, len(t.counts)+len(t.previous))
+	for _, counts := range []map[string]int64{t.previous, t.counts} {
+		for key := range counts {
+			if !seen[key] {
+				seen[key] = true
+				rep.Keys = append(rep.Keys, hotKey(key))
+			}
+		}
+	}
+	sort.Slice(rep.Keys, func(i, j int) bool {
+		if rep.Keys[i].Reads != rep.Keys[j].Reads {
+			return rep.Keys[i].Reads > rep.Keys[j].Reads
+		}
+		return rep.Keys[i].Key < rep.Keys[j].Key
+	})
+	if n > 0 && len(rep.Keys) > n {
+		rep.Keys = rep.Keys[:n]
+	}
+
+	for key, r := range t.replicated {
+		if len(r.names) > 0 && now.Before(r.until) {
+			rep.Replicated = append(rep.Replicated, hotKey(key))
+		}
+	}
+	sort.Slice(rep.Replicated, func(i, j int) bool { return rep.Replicated[i].Key < rep.Replicated[j].Key })
+
+	rep.Events = make([]HotKeyEvent, 0, len(t.events))
+	for i := len(t.events) - 1; i >= 0; i-- {
+		rep.Events = append(rep.Events, t.events[(t.nextEvent+i)%len(t.events)])
+	}
+	return rep
+}
+
+// stats returns the replication counters
+func (t *hotKeyTracker) stats() (replications, failures, invalidations int64) {
+	return atomic.LoadInt64(&t.replications), atomic.LoadInt64(&t.replicaFailures), atomic.LoadInt64(&t.invalidations)
+}
+
+// WithHotKeys enables hot-key tracking, and replication when cfg sets both
+// Threshold and Replicas, see HotKeyConfig
+func WithHotKeys(cfg HotKeyConfig) GroupOption {
+	return func(g *Group) {
+		g.hotKeys = &cfg
+	}
+}
+
+// HotKeys reports the n hottest keys of the group (all tracked keys when n <= 0),
+// the keys it currently replicates and its recent replication events
+func (g *Group) HotKeys(n int) HotKeyReport {
+	if g.hot == nil {
+		return HotKeyReport{Group: g.name, Keys: []HotKey{}, Replicated: []HotKey{}, Events: []HotKeyEvent{}}
+	}
+	rep := g.hot.report(n)
+	rep.Group = g.name
+	return rep
+}
+
+// trackHot counts a read this node served and adds the key's replication to meta.
+// A read that makes a key this node owns hot starts its replication in the background.
+// Values fetched from the owner are counted there.
+func (g *Group) trackHot(key string, value ByteView, meta ValueMeta) ValueMeta {
+	if g.hot == nil || meta.Source == SourcePeer {
+		return meta
+	}
+	replication, start := g.hot.record(key)
+	meta.Replicas, meta.ReplicatedUntil = replication.Replicas, replication.ReplicatedUntil
+	if start {
+		g.startReplication(key, value, meta.ExpiresAt)
+	}
+	return meta
+}
+
+// startReplication pushes a hot key to the replicas following this node on the ring
+func (g *Group) startReplication(key string, value ByteView, expiresAt time.Time) {
+	p := g.peerPicker()
+	picker, ok := p.(peers.ReplicaPicker)
+	if !ok || pickOwner(p, key).State != peers.PickSelf {
+		g.hot.suppress(key)
+		return
+	}
+	targets := picker.PickReplicas(key, g.hot.cfg.Replicas)
+	if len(targets) == 0 {
+		g.hot.suppress(key)
+		return
+	}
+
+	ttl := g.hot.cfg.ReplicaTTL
+	now := g.clock.Now()
+	if !expiresAt.IsZero() && expiresAt.Sub(now) < ttl {
+		ttl = expiresAt.Sub(now)
+	}
+	if ttl <= 0 {
+		g.hot.suppress(key)
+		return
+	}
+
+	started := g.goBackground(func(bgCtx context.Context) {
+		req := &pb.SetRequest{Group: g.name, Key: key, Value: value.ByteSlice(), TtlMs: proto.Int64(max(ttl.Milliseconds(), 1))}
+		var (
+			replicas []peers.PeerGetter
+			names    []string
+			lastErr  error
+		)
+		for _, target := range targets {
+			setter, ok := target.(peers.PeerSetter)
+			if !ok {
+				continue
+			}
+			ctx, cancel := context.WithTimeout(bgCtx, replicaTimeout)
+			err := setter.SetByProto(ctx, req, &pb.SetResponse{})
+			cancel()
+			if err != nil {
+				lastErr = err
+				g.log.Warnf("[Cache] 热点 key 复制到 %s 失败: group=%s, key=%s: %v", peerName(target), g.name, logger.Key(key), err)
+				continue
+			}
+			replicas = append(replicas, target)
+			names = append(names, peerName(target))
+		}
+		if len(replicas) > 0 {
+			g.log.Infof("[Cache] 热点 key 已复制: group=%s, key=%s, replicas=%v, ttl=%v", g.name, logger.Key(key), names, ttl)
+		}
+		if stale := g.hot.finish(key, replicas, names, now.Add(ttl), lastErr); len(stale) > 0 {
+			ctx, cancel := context.WithTimeout(bgCtx, replicaTimeout)
+			defer cancel()
+			deleteReplicas(ctx, stale, key, g.name)
+		}
+	})
+	if !started {
+		g.hot.suppress(key)
+	}
+}
+
+// invalidateReplicas removes the copies of a replicated key before a write or
+// delete on the owner returns, so replicas never serve the old value for long
+func (g *Group) invalidateReplicas(key string) {
+	if g.hot == nil {
+		return
+	}
+	if replicas := g.hot.take(key); len(replicas) > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), replicaTimeout)
+		defer cancel()
+		deleteReplicas(ctx, replicas, key, g.name)
+	}
+}
+
+// invalidateAllReplicas removes the copies of every replicated key in the
+// background, used when the group's cache is cleared
+func (g *Group) invalidateAllReplicas() {
+	if g.hot == nil {
+		return
+	}
+	for key, replicas := range g.hot.takeAll() {
+		g.goBackground(func(bgCtx context.Context) {
+			ctx, cancel := context.WithTimeout(bgCtx, replicaTimeout)
+			defer cancel()
+			deleteReplicas(ctx, replicas, key, g.name)
+		})
+	}
+}
+
+// deleteReplicas deletes key from every replica that accepts deletes; a replica
+// that cannot be reached keeps its copy until the replica ttl lapses
+func deleteReplicas(ctx context.Context, replicas []peers.PeerGetter, key, group string) {
+	for _, replica := range replicas {
+		deleter, ok := replica.(peers.PeerDeleter)
+		if !ok {
+			continue
+		}
+		if err := deleter.DeleteByProto(ctx, &pb.DeleteRequest{Group: group, Key: key}, &pb.DeleteResponse{}); err != nil {
+			logger.Warnf("[Cache] 删除热点 key 的副本失败，副本将在过期后失效: replica=%s, group=%s, key=%s: %v",
+				peerName(replica), group, logger.Key(key), err)
+		}
+	}
+}
+
+// hotKeyStats adds the replication counters to stats
+func (g *Group) hotKeyStats(stats *CacheStats) {
+	if g.hot == nil {
+		return
+	}
+	stats.HotKeyReplications, stats.HotKeyReplicaFailures, stats.HotKeyInvalidations = g.hot.stats()
+}
@@ -0,0 +1,259 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/internal/peers"
+	"github.com/AdrianWangs/go-cache/pkg/lru"
+)
+
+// replicaPeer is a capablePeer with a name, as HTTP and gRPC peers have
+type replicaPeer struct {
+	capablePeer
+	name string
+}
+
+func (p *replicaPeer) String() string { return p.name }
+
+// replicaPicker makes this node own every key and offers replicas as the nodes
+// following it on the ring
+type replicaPicker struct {
+	replicas []*replicaPeer
+}
+
+func (p *replicaPicker) PickPeer(key string) (peers.PeerGetter, bool) { return nil, false }
+
+func (p *replicaPicker) PickOwner(key string) peers.PickResult {
+	return peers.PickResult{State: peers.PickSelf}
+}
+
+func (p *replicaPicker) PickReplicas(key string, n int) []peers.PeerGetter {
+	var replicas []peers.PeerGetter
+	for _, r := range p.replicas[:min(n, len(p.replicas))] {
+		replicas = append(replicas, r)
+	}
+	return replicas
+}
+
+// newHotGroup creates a group owning every key with two replicas, replicating a
+// key read three times in a second for ten seconds
+func newHotGroup(t *testing.T, ttl time.Duration) (*Group, *lru.FakeClock, []*replicaPeer) {
+	t.Helper()
+	clock := lru.NewFakeClock(time.Unix(1000, 0))
+	g := newTestGroup(t, GetterFunc(loadValue), ttl, WithClock(clock), WithHotKeys(HotKeyConfig{
+		Threshold:  3,
+		Replicas:   2,
+		ReplicaTTL: 10 * time.Second,
+	}))
+	replicas := []*replicaPeer{{name: "replica-1"}, {name: "replica-2"}}
+	g.RegisterPeers(&replicaPicker{replicas: replicas})
+	return g, clock, replicas
+}
+
+// readMeta reads key and returns the metadata reported with it
+func readMeta(t *testing.T, g *Group, key string) ValueMeta {
+	t.Helper()
+	_, meta, err := g.GetWithMeta(context.Background(), key)
+	if err != nil {
+		t.Fatalf("Get(%q): %v", key, err)
+	}
+	return meta
+}
+
+// promote reads key until it is replicated and waits for the push to finish
+func promote(t *testing.T, g *Group, key string) {
+	t.Helper()
+	before := g.HotKeys(0).Replications
+	for i := 0; i < 3; i++ {
+		mustGet(t, g, key)
+	}
+	waitFor(t, "replication of "+key, func() bool { return g.HotKeys(0).Replications == before+1 })
+}
+
+// lastEvent returns the newest replication event of the group
+func lastEvent(t *testing.T, g *Group) HotKeyEvent {
+	t.Helper()
+	events := g.HotKeys(0).Events
+	if len(events) == 0 {
+		t.Fatal("no replication events")
+	}
+	return events[0]
+}
+
+func TestHotKeyPromotion(t *testing.T) {
+	g, clock, replicas := newHotGroup(t, 5*time.Second)
+
+	// Reads below the threshold in one window, then again in the next
+	for i := 0; i < 2; i++ {
+		mustGet(t, g, "k")
+	}
+	clock.Advance(time.Second)
+	for i := 0; i < 2; i++ {
+		if meta := readMeta(t, g, "k"); meta.Replicas != 0 {
+			t.Fatalf("key below the threshold reported %d replicas", meta.Replicas)
+		}
+	}
+	if rep := g.HotKeys(0); rep.Replications != 0 || len(rep.Replicated) != 0 {
+		t.Fatalf("key below the threshold replicated: %+v", rep)
+	}
+
+	// The third read in the window pushes the key to both replicas, with a ttl
+	// capped by the entry's remaining 4s
+	mustGet(t, g, "k")
+	waitFor(t, "replication", func() bool { return g.HotKeys(0).Replications == 1 })
+	for _, r := range replicas {
+		r.mu.Lock()
+		sets := r.sets
+		r.mu.Unlock()
+		if len(sets) != 1 || sets[0].GetKey() != "k" || string(sets[0].GetValue()) != "v:k" || sets[0].GetTtlMs() != 4000 {
+			t.Fatalf("%s received %v", r.name, sets)
+		}
+	}
+
+	rep := g.HotKeys(0)
+	if len(rep.Replicated) != 1 || rep.Replicated[0].Key != "k" || len(rep.Replicated[0].Replicas) != 2 {
+		t.Fatalf("replicated = %+v", rep.Replicated)
+	}
+	if e := lastEvent(t, g); e.Event != HotKeyReplicated || e.Key != "k" || e.Replicas[0] != "replica-1" || e.Replicas[1] != "replica-2" {
+		t.Fatalf("event = %+v", e)
+	}
+	if stats := g.Stats(); stats.HotKeyReplications != 1 {
+		t.Fatalf("stats = %+v", stats)
+	}
+
+	// Later reads report the replicas so the API server can spread them
+	meta := readMeta(t, g, "k")
+	if meta.Replicas != 2 || !meta.ReplicatedUntil.Equal(clock.Now().Add(4*time.Second)) {
+		t.Fatalf("meta = %+v", meta)
+	}
+	for _, r := range replicas {
+		r.mu.Lock()
+		n := len(r.sets)
+		r.mu.Unlock()
+		if n != 1 {
+			t.Fatalf("%s received %d pushes of a replicated key", r.name, n)
+		}
+	}
+}
+
+func TestHotKeyInvalidation(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		write func(g *Group) error
+	}{
+		{"delete", func(g *Group) error { return g.Delete("k") }},
+		{"set", func(g *Group) error { return g.Set("k", []byte("new"), 0) }},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			g, _, replicas := newHotGroup(t, time.Minute)
+			promote(t, g, "k")
+
+			if err := tt.write(g); err != nil {
+				t.Fatal(err)
+			}
+			// The replicas are deleted before the write returns
+			for _, r := range replicas {
+				r.mu.Lock()
+				deletes := r.deletes
+				r.mu.Unlock()
+				if len(deletes) != 1 || deletes[0].GetKey() != "k" || deletes[0].GetGroup() != g.Name() {
+					t.Fatalf("%s received deletes %v", r.name, deletes)
+				}
+			}
+
+			rep := g.HotKeys(0)
+			if len(rep.Replicated) != 0 || rep.Invalidations != 1 {
+				t.Fatalf("report = %+v", rep)
+			}
+			if e := lastEvent(t, g); e.Event != HotKeyInvalidated || e.Key != "k" || len(e.Replicas) != 2 {
+				t.Fatalf("event = %+v", e)
+			}
+			if meta := readMeta(t, g, "k"); meta.Replicas != 0 {
+				t.Fatalf("invalidated key reported %d replicas", meta.Replicas)
+			}
+
+			// A later write has no replicas left to delete
+			if err := tt.write(g); err != nil {
+				t.Fatal(err)
+			}
+			if rep := g.HotKeys(0); rep.Invalidations != 1 {
+				t.Fatalf("invalidations = %d", rep.Invalidations)
+			}
+		})
+	}
+}
+
+func TestHotKeyExpiry(t *testing.T) {
+	g, clock, replicas := newHotGroup(t, time.Minute)
+	promote(t, g, "k")
+
+	clock.Advance(10*time.Second - time.Millisecond)
+	if meta := readMeta(t, g, "k"); meta.Replicas != 2 {
+		t.Fatalf("replicas before the ttl = %d", meta.Replicas)
+	}
+
+	// Once the replica ttl lapses the key is demoted; the replicas drop their
+	// copies on their own, so nothing is deleted
+	clock.Advance(time.Millisecond)
+	if rep := g.HotKeys(0); len(rep.Replicated) != 0 {
+		t.Fatalf("replicated after the ttl = %+v", rep.Replicated)
+	}
+	if meta := readMeta(t, g, "k"); meta.Replicas != 0 {
+		t.Fatalf("demoted key reported %d replicas", meta.Replicas)
+	}
+	if e := lastEvent(t, g); e.Event != HotKeyExpired || e.Key != "k" || len(e.Replicas) != 2 {
+		t.Fatalf("event = %+v", e)
+	}
+	for _, r := range replicas {
+		r.mu.Lock()
+		n := len(r.deletes)
+		r.mu.Unlock()
+		if n != 0 {
+			t.Fatalf("%s received %d deletes for an expired key", r.name, n)
+		}
+	}
+	if rep := g.HotKeys(0); rep.Invalidations != 0 {
+		t.Fatalf("invalidations = %d", rep.Invalidations)
+	}
+
+	// A key that stays hot is replicated again
+	clock.Advance(time.Second)
+	promote(t, g, "k")
+	if rep := g.HotKeys(0); rep.Replications != 2 || len(rep.Replicated) != 1 {
+		t.Fatalf("report after promoting again = %+v", rep)
+	}
+}
+
+func TestHotKeyReplicaFailure(t *testing.T) {
+	g, clock, replicas := newHotGroup(t, time.Minute)
+	for _, r := range replicas {
+		r.err = errors.New("connection refused")
+	}
+
+	for i := 0; i < 3; i++ {
+		mustGet(t, g, "k")
+	}
+	waitFor(t, "failed replication", func() bool { return g.HotKeys(0).ReplicaFailures == 1 })
+	if e := lastEvent(t, g); e.Event != HotKeyFailed || e.Error != "connection refused" {
+		t.Fatalf("event = %+v", e)
+	}
+
+	// The key is not retried until a replica ttl has passed
+	clock.Advance(time.Second)
+	for i := 0; i < 3; i++ {
+		if meta := readMeta(t, g, "k"); meta.Replicas != 0 {
+			t.Fatalf("failed key reported %d replicas", meta.Replicas)
+		}
+	}
+	for _, r := range replicas {
+		r.mu.Lock()
+		n := len(r.sets)
+		r.mu.Unlock()
+		if n != 1 {
+			t.Fatalf("%s received %d pushes, want 1", r.name, n)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+package cache
+
+import "time"
+
+// GroupInfo describes a registered group: its configuration and a stats snapshot
+type GroupInfo struct {
+	Name      string        `json:"name"`       // group name
+	MaxBytes  int64         `json:"max_bytes"`  // cache size limit in bytes
+	TTL       time.Duration `json:"ttl"`        // default entry ttl
+	MaxAge    time.Duration `json:"max_age"`    // absolute entry lifetime, 0 if unlimited
+	MaxIdle   time.Duration `json:"max_idle"`   // idle entry lifetime, 0 if unlimited
+	Eviction  string        `json:"eviction"`   // eviction policy, see lru.Policy
+	Mode      string        `json:"mode"`       // effective mode, see Mode
+	Stats     CacheStats    `json:"stats"`      // statistics snapshot
+	CreatedAt time.Time     `json:"created_at"` // creation time of the group
+
+	DefaultDeadline time.Duration `json:"default_deadline"` // deadline given to Gets without one, 0 if none
+	StrictDeadline  bool          `json:"strict_deadline"`  // whether caller deadlines later than DefaultDeadline are capped
+}
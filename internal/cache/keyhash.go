@@ -0,0 +1,68 @@
+package cache
+
+import (
+	"hash/fnv"
+	"io"
+)
+
+// KeyHashFunc maps a key of arbitrary length to a fixed-size digest
+type KeyHashFunc func(key string) string
+
+// DigestSize is the size in bytes of the digests produced by DefaultKeyHash
+const DigestSize = 16
+
+// DefaultKeyHash returns the 16-byte FNV-1a digest of key
+func DefaultKeyHash(key string) string {
+	h := fnv.New128a()
+	io.WriteString(h, key)
+	return string(h.Sum(nil))
+}
+
+// keyFingerprint is a 64-bit check value computed with a different algorithm than
+// DefaultKeyHash, used to detect digest collisions when original keys are not kept
+func keyFingerprint(key string) uint64 {
+	h := fnv.New64()
+	io.WriteString(h, key)
+	return h.Sum64()
+}
+
+// hashedEntry is what the cache stores under a digested key: the value plus
+// enough information about the original key to detect collisions on lookup
+type hashedEntry struct {
+	key         string // original key, empty unless retained
+	fingerprint uint64 // fingerprint of the original key
+	view        ByteView
+}
+
+// Len implements lru.Value, accounting for the retained key and fingerprint
+func (e hashedEntry) Len() int {
+	return len(e.key) + 8 + e.view.Len()
+}
+
+// matches reports whether the entry was stored for key
+func (e hashedEntry) matches(key string) bool {
+	if e.key != "" {
+		return e.key == key
+	}
+	return e.fingerprint == keyFingerprint(key)
+}
+
+// cacheKey returns the key under which key is stored in the local cache
+func (g *Group) cacheKey(key string) string {
+	if !g.keyHashing {
+		return key
+	}
+	return g.keyHash(key)
+}
+
+// newHashedEntry wraps a value for storage under a digested key
+func (g *Group) newHashedEntry(key string, value ByteView) hashedEntry {
+	e := hashedEntry{
+		fingerprint: keyFingerprint(key),
+		view:        value,
+	}
+	if g.keepKeys {
+		e.key = key
+	}
+	return e
+}
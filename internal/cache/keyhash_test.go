@@ -0,0 +1,76 @@
+package cache
+
+import (
+	"strings"
+	"testing"
+)
+
+// collidingHash maps every key to the same digest
+func collidingHash(string) string { return "same-digest" }
+
+func TestKeyHashingDigestSize(t *testing.T) {
+	long := strings.Repeat("k", 4096)
+	if got := len(DefaultKeyHash(long)); got != DigestSize {
+		t.Fatalf("digest size = %d, want %d", got, DigestSize)
+	}
+	if DefaultKeyHash("a") == DefaultKeyHash("b") {
+		t.Fatal("distinct keys produced the same digest")
+	}
+}
+
+func TestKeyHashingCollisionIsAMiss(t *testing.T) {
+	for _, keep := range []bool{true, false} {
+		t.Run(map[bool]string{true: "original keys", false: "fingerprints"}[keep], func(t *testing.T) {
+			getter := newCountingGetter(map[string]string{"alpha": "A", "beta": "B"})
+			g := newTestGroup(t, getter, 0,
+				WithKeyHashing(true), WithKeyHashFunc(collidingHash), WithOriginalKeys(keep))
+
+			if v := mustGet(t, g, "alpha"); v != "A" {
+				t.Fatalf("alpha = %q, want A", v)
+			}
+			// beta shares alpha's digest: it must not be served alpha's value
+			if v := mustGet(t, g, "beta"); v != "B" {
+				t.Fatalf("beta = %q, want B", v)
+			}
+			if n := getter.count("beta"); n != 1 {
+				t.Fatalf("beta loaded %d times, want 1", n)
+			}
+			if c := g.Stats().Collisions; c != 1 {
+				t.Fatalf("collisions = %d, want 1", c)
+			}
+			// beta replaced alpha under the shared digest, so alpha now collides
+			if v := mustGet(t, g, "alpha"); v != "A" {
+				t.Fatalf("alpha after collision = %q, want A", v)
+			}
+			if n := getter.count("alpha"); n != 2 {
+				t.Fatalf("alpha loaded %d times, want 2", n)
+			}
+		})
+	}
+}
+
+func TestKeyHashingHitsWithoutCollision(t *testing.T) {
+	getter := newCountingGetter(map[string]string{"key": "v"})
+	g := newTestGroup(t, getter, 0, WithKeyHashing(true))
+	for i := 0; i < 3; i++ {
+		if v := mustGet(t, g, "key"); v != "v" {
+			t.Fatalf("key = %q, want v", v)
+		}
+	}
+	if n := getter.count("key"); n != 1 {
+		t.Fatalf("key loaded %d times, want 1", n)
+	}
+	if s := g.Stats(); s.Hits != 2 || s.Collisions != 0 {
+		t.Fatalf("hits = %d, collisions = %d, want 2 and 0", s.Hits, s.Collisions)
+	}
+}
+
+func TestKeyHashingDropsOriginalKeys(t *testing.T) {
+	long := strings.Repeat("x", 2048)
+	g := newTestGroup(t, newCountingGetter(map[string]string{long: "v"}), 0,
+		WithKeyHashing(true), WithOriginalKeys(false))
+	mustGet(t, g, long)
+	if b := g.Bytes(); b >= int64(len(long)) {
+		t.Fatalf("bytes = %d, the %d-byte key should not be stored", b, len(long))
+	}
+}
@@ -0,0 +1,55 @@
+package cache
+
+import "context"
+
+// initLifecycle sets up the context and bookkeeping of the group's background
+// goroutines; called by NewGroup before any of them start
+func (g *Group) initLifecycle() {
+	g.bgCtx, g.bgCancel = context.WithCancel(context.Background())
+}
+
+// goBackground runs fn in a goroutine owned by the group: fn gets a context that
+// Close cancels, and Close waits for fn to return. Once the group is closed fn is
+// not started and goBackground reports false.
+func (g *Group) goBackground(fn func(ctx context.Context)) bool {
+	g.life.RLock()
+	defer g.life.RUnlock()
+	if g.closed.Load() {
+		return false
+	}
+	g.bg.Add(1)
+	go func() {
+		defer g.bg.Done()
+		fn(g.bgCtx)
+	}()
+	return true
+}
+
+// Close stops everything the group runs in the background and removes it from
+// its registry. The expiry sweeper and watermark eviction stop, in-flight
+// refresh-ahead loads and hot-key replications are cancelled, and Close returns
+// once all of these goroutines have exited. Loads started by callers are theirs
+// to cancel and are not waited for.
+//
+// Afterwards reads, writes and deletes fail with ErrGroupClosed; the cached
+// entries stay readable through Stats, Export and the like until the group is
+// dropped. Closing a group more than once, including concurrently, is safe:
+// every call returns after the first has finished.
+func (g *Group) Close() error {
+	g.closeOnce.Do(func() {
+		g.life.Lock()
+		g.closed.Store(true)
+		g.life.Unlock()
+
+		g.bgCancel()
+		g.bg.Wait()
+		g.registry.remove(g)
+		g.log.Infof("Closed cache group: %s", g.name)
+	})
+	return nil
+}
+
+// Closed reports whether Close has been called
+func (g *Group) Closed() bool {
+	return g.closed.Load()
+}
@@ -0,0 +1,160 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/pkg/lru"
+)
+
+// TestCloseLeaksNoGoroutines creates groups running every kind of background
+// goroutine, uses and closes them, and checks that the number of goroutines
+// returns to where it started
+func TestCloseLeaksNoGoroutines(t *testing.T) {
+	before := runtime.NumGoroutine()
+	for i := 0; i < 50; i++ {
+		clock := lru.NewFakeClock(time.Unix(1000, 0))
+		g := NewGroup(fmt.Sprintf("leak-%d-%d", i, time.Now().UnixNano()), 1000, GetterFunc(loadValue), 10*time.Second,
+			WithRegistry(NewRegistry()), WithClock(clock), WithSweepInterval(time.Millisecond),
+			WithWatermarks(0.9, 0.8), WithRefreshAhead(0.5), WithEvictionPressure(time.Second))
+		for j := 0; j < 100; j++ {
+			mustGet(t, g, fmt.Sprintf("key-%d", j%20))
+		}
+		clock.Advance(6 * time.Second)
+		mustGet(t, g, "key-19") // starts a refresh-ahead
+		if err := g.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	waitFor(t, "the goroutines to exit", func() bool { return runtime.NumGoroutine() <= before })
+}
+
+// TestCloseCancelsRefresh closes a group while a refresh-ahead load is blocked
+// in the getter: Close returns once the refresh has exited, and the getter's
+// context is cancelled
+func TestCloseCancelsRefresh(t *testing.T) {
+	clock := lru.NewFakeClock(time.Unix(1000, 0))
+	var (
+		mu       sync.Mutex
+		loads    int
+		blocked  = make(chan struct{})
+		returned = make(chan struct{})
+	)
+	getter := GetterCtxFunc(func(ctx context.Context, key string) ([]byte, error) {
+		mu.Lock()
+		loads++
+		n := loads
+		mu.Unlock()
+		if n == 1 {
+			return []byte("v1"), nil
+		}
+		close(blocked)
+		<-ctx.Done()
+		close(returned)
+		return nil, ctx.Err()
+	})
+	g := NewGroup(fmt.Sprintf("close-refresh-%d", time.Now().UnixNano()), 1<<20, getter, 10*time.Second,
+		WithRegistry(NewRegistry()), WithClock(clock), WithRefreshAhead(0.5))
+
+	mustGet(t, g, "k")
+	mustGet(t, g, "k") // a refresh needs an earlier read
+	clock.Advance(6 * time.Second)
+	mustGet(t, g, "k")
+	select {
+	case <-blocked:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no refresh started")
+	}
+
+	g.Close()
+	if !refreshIdle(g) {
+		t.Fatal("Close returned before the refresh exited")
+	}
+	select {
+	case <-returned:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the getter's context was not cancelled")
+	}
+	if st := g.Stats(); st.RefreshFailures != 0 {
+		t.Fatalf("a cancelled refresh counted as a failure: %+v", st)
+	}
+}
+
+func TestCloseTwice(t *testing.T) {
+	reg := NewRegistry()
+	g := NewGroup(fmt.Sprintf("close-twice-%d", time.Now().UnixNano()), 1<<20, GetterFunc(loadValue), time.Hour,
+		WithRegistry(reg), WithSweepInterval(time.Millisecond))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := g.Close(); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if err := g.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !g.Closed() || reg.Get(g.Name()) != nil {
+		t.Fatalf("Closed = %v, registered = %v", g.Closed(), reg.Get(g.Name()) != nil)
+	}
+}
+
+func TestUseAfterClose(t *testing.T) {
+	g := newTestGroup(t, GetterFunc(loadValue), time.Hour)
+	g.Set("kept", []byte("v"), 0)
+	g.Close()
+
+	ctx := context.Background()
+	calls := []struct {
+		name string
+		call func() error
+	}{
+		{"Get", func() error { _, err := g.Get("k"); return err }},
+		{"GetWithContext", func() error { _, err := g.GetWithContext(ctx, "k"); return err }},
+		{"GetChan", func() error { return (<-g.GetChan(ctx, "k")).Err }},
+		{"Set", func() error { return g.Set("k", []byte("v"), 0) }},
+		{"SetLocally", func() error { return g.SetLocally("k", []byte("v"), 0) }},
+		{"Delete", func() error { return g.Delete("kept") }},
+		{"DeleteLocally", func() error { return g.DeleteLocally("kept") }},
+		{"DeleteBatch", func() error { _, err := g.DeleteBatch([]string{"kept"}); return err }},
+		{"Clear", g.Clear},
+	}
+	for _, c := range calls {
+		if err := c.call(); !errors.Is(err, ErrGroupClosed) {
+			t.Errorf("%s after Close = %v, want ErrGroupClosed", c.name, err)
+		}
+	}
+	if _, _, ok := g.Peek("kept"); !ok {
+		t.Fatal("closing dropped the cached entries")
+	}
+}
+
+func TestRegistryClose(t *testing.T) {
+	reg := NewRegistry()
+	var groups []*Group
+	for i := 0; i < 3; i++ {
+		groups = append(groups, NewGroup(fmt.Sprintf("registry-close-%d-%d", i, time.Now().UnixNano()), 1<<20,
+			GetterFunc(loadValue), time.Hour, WithRegistry(reg), WithSweepInterval(time.Millisecond)))
+	}
+	if err := reg.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for _, g := range groups {
+		if !g.Closed() {
+			t.Errorf("%s is still open", g.Name())
+		}
+	}
+	if names := reg.Names(); len(names) != 0 {
+		t.Fatalf("registry still lists %v", names)
+	}
+}
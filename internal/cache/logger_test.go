@@ -0,0 +1,75 @@
+package cache
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/AdrianWangs/go-cache/pkg/logger"
+)
+
+// lineRecorder is a logger.Logger that keeps the lines written through it
+type lineRecorder struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (r *lineRecorder) record(level, format string, args []interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lines = append(r.lines, level+" "+fmt.Sprintf(format, args...))
+}
+
+func (r *lineRecorder) Debugf(format string, args ...interface{}) { r.record("DEBUG", format, args) }
+func (r *lineRecorder) Infof(format string, args ...interface{})  { r.record("INFO", format, args) }
+func (r *lineRecorder) Warnf(format string, args ...interface{})  { r.record("WARN", format, args) }
+func (r *lineRecorder) Errorf(format string, args ...interface{}) { r.record("ERROR", format, args) }
+func (r *lineRecorder) WithFields(logger.Fields) logger.Logger    { return r }
+
+// recorded returns the lines containing substr
+func (r *lineRecorder) recorded(substr string) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var found []string
+	for _, line := range r.lines {
+		if strings.Contains(line, substr) {
+			found = append(found, line)
+		}
+	}
+	return found
+}
+
+func TestWithLogger(t *testing.T) {
+	rec := &lineRecorder{}
+	g := newTestGroup(t, newCountingGetter(map[string]string{"Tom": "630"}), 0, WithLogger(rec))
+	mustGet(t, g, "Tom")
+	mustGet(t, g, "Tom")
+	if err := g.Clear(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"INFO Created cache group: " + g.Name(),
+		"INFO [Cache] MISS",
+		"INFO [Cache] HIT",
+		"INFO Cleared cache for group: " + g.Name(),
+	} {
+		if found := rec.recorded(want); len(found) != 1 {
+			t.Errorf("%q recorded %d times, want once", want, len(found))
+		}
+	}
+
+	// Without the option the group follows logger.SetLogger
+	global := &lineRecorder{}
+	logger.SetLogger(global)
+	defer logger.SetLogger(nil)
+	other := newTestGroup(t, newCountingGetter(map[string]string{"Tom": "630"}), 0)
+	mustGet(t, other, "Tom")
+	if found := global.recorded("Created cache group: " + other.Name()); len(found) != 1 {
+		t.Fatalf("package logger recorded %q", global.recorded(""))
+	}
+	if found := rec.recorded(other.Name()); len(found) != 0 {
+		t.Fatalf("the other group's logger recorded %q", found)
+	}
+}
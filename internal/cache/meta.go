@@ -0,0 +1,92 @@
+package cache
+
+import (
+	"time"
+
+	"github.com/AdrianWangs/go-cache/pkg/lru"
+	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
+	"google.golang.org/protobuf/proto"
+)
+
+// Source tells where a value returned by GetWithMeta was found.
+type Source string
+
+const (
+	// SourceCache means the value was served from the local cache.
+	SourceCache Source = "cache"
+	// SourceLoader means the value was loaded from the group's data source.
+	SourceLoader Source = "loader"
+	// SourcePeer means the value was fetched from the peer owning the key.
+	SourcePeer Source = "peer"
+)
+
+// ValueMeta describes a value returned by GetWithMeta. It travels between nodes in
+// the protobuf Response and, on the plain HTTP path, in X-GoCache-* headers.
+type ValueMeta struct {
+	ExpiresAt time.Time // absolute expiry, zero when the value never expires or it is unknown
+	Version   uint64    // write sequence number on the node caching the value, 0 if unknown
+	Source    Source    // where the value was found, empty if unknown
+	Node      string    // ID of the node that found the value in its cache or loaded it, empty if unknown
+
+	// Replicas is the number of nodes following the owner on the ring that hold a
+	// copy of this hot key until ReplicatedUntil, 0 when the key is not replicated
+	Replicas        int
+	ReplicatedUntil time.Time
+}
+
+// metaFromExpiry describes a cached entry
+func metaFromExpiry(expiry lru.Expiry, source Source) ValueMeta {
+	meta := ValueMeta{Version: expiry.Version, Source: source}
+	if expiry.TTL > 0 {
+		meta.ExpiresAt = expiry.Expires
+	}
+	return meta
+}
+
+// FillProto copies the metadata into resp, leaving unknown fields unset.
+func (m ValueMeta) FillProto(resp *pb.Response) {
+	if !m.ExpiresAt.IsZero() {
+		resp.ExpiresAt = proto.Int64(m.ExpiresAt.UnixNano())
+	}
+	if m.Version != 0 {
+		resp.Version = proto.Uint64(m.Version)
+	}
+	if m.Source != "" {
+		resp.Source = proto.String(string(m.Source))
+	}
+	if m.Node != "" {
+		resp.Node = proto.String(m.Node)
+	}
+	if m.Replicas > 0 {
+		resp.Replicas = proto.Uint32(uint32(m.Replicas))
+		resp.ReplicatedUntil = proto.Int64(m.ReplicatedUntil.UnixNano())
+	}
+}
+
+// MetaFromProto reads the metadata carried by a peer-protocol response.
+func MetaFromProto(resp *pb.Response) ValueMeta {
+	meta := ValueMeta{Version: resp.GetVersion(), Source: Source(resp.GetSource()), Node: resp.GetNode()}
+	if resp.ExpiresAt != nil {
+		meta.ExpiresAt = time.Unix(0, resp.GetExpiresAt())
+	}
+	if resp.GetReplicas() > 0 {
+		meta.Replicas = int(resp.GetReplicas())
+		meta.ReplicatedUntil = time.Unix(0, resp.GetReplicatedUntil())
+	}
+	return meta
+}
+
+// Attribute returns m as the node self reports it: a value found in the local
+// cache or loaded by self is attributed to self, while a value fetched from a
+// peer keeps the peer's ID, or stays unattributed when the peer did not report
+// one. An empty self hides node identities and clears Node, so that a node
+// configured not to reveal the topology does not relay its peers' IDs either.
+func (m ValueMeta) Attribute(self string) ValueMeta {
+	switch {
+	case self == "":
+		m.Node = ""
+	case m.Source != SourcePeer:
+		m.Node = self
+	}
+	return m
+}
@@ -0,0 +1,23 @@
+package cache
+
+import "testing"
+
+func TestAttribute(t *testing.T) {
+	tests := []struct {
+		meta ValueMeta
+		self string
+		want string
+	}{
+		{ValueMeta{Source: SourceCache}, "a", "a"},
+		{ValueMeta{Source: SourceLoader, Node: "b"}, "a", "a"},
+		{ValueMeta{Source: SourcePeer, Node: "b"}, "a", "b"},
+		{ValueMeta{Source: SourcePeer}, "a", ""},
+		{ValueMeta{Source: SourcePeer, Node: "b"}, "", ""},
+		{ValueMeta{Source: SourceCache}, "", ""},
+	}
+	for _, tt := range tests {
+		if got := tt.meta.Attribute(tt.self).Node; got != tt.want {
+			t.Errorf("%+v.Attribute(%q).Node = %q, want %q", tt.meta, tt.self, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package cache
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/AdrianWangs/go-cache/internal/peers"
+)
+
+// MissPolicy decides whether a group may load a missed key from its data source
+// when the key's owner cannot serve it
+type MissPolicy int
+
+const (
+	// MissOriginFallback loads from the data source whenever no peer returned the
+	// value: the ring is empty, this node owns the key, or the owner failed
+	MissOriginFallback MissPolicy = iota
+	// MissPeerOnly loads from the data source only when the ring says this node owns
+	// the key. An unreachable owner, an empty ring or a group without a picker fails
+	// with ErrNoPeerAvailable, which suits proxy nodes without access to the origin.
+	MissPeerOnly
+	// MissOriginOnlyIfOwner loads from the data source only when this node owns the
+	// key. A group without a picker owns every key; an empty ring fails with
+	// ErrNoPeerAvailable and a failed owner with its own error.
+	MissOriginOnlyIfOwner
+)
+
+// String returns the name accepted by ParseMissPolicy
+func (p MissPolicy) String() string {
+	switch p {
+	case MissOriginFallback:
+		return "origin-fallback"
+	case MissPeerOnly:
+		return "peer-only"
+	case MissOriginOnlyIfOwner:
+		return "origin-only-if-owner"
+	default:
+		return fmt.Sprintf("MissPolicy(%d)", int(p))
+	}
+}
+
+// ParseMissPolicy parses a policy name as returned by MissPolicy.String; an empty
+// name is MissOriginFallback
+func ParseMissPolicy(s string) (MissPolicy, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "origin-fallback":
+		return MissOriginFallback, nil
+	case "peer-only":
+		return MissPeerOnly, nil
+	case "origin-only-if-owner":
+		return MissOriginOnlyIfOwner, nil
+	default:
+		return MissOriginFallback, fmt.Errorf("unknown miss policy %q", s)
+	}
+}
+
+// WithMissPolicy sets how the group treats misses the owner cannot serve.
+// It defaults to MissOriginFallback.
+func WithMissPolicy(policy MissPolicy) GroupOption {
+	return func(g *Group) {
+		g.missPolicy = policy
+	}
+}
+
+// pickOwner asks picker where key lives. Pickers that do not implement
+// peers.OwnerPicker cannot report PickSelf.
+func pickOwner(picker peers.PeerPicker, key string) peers.PickResult {
+	if p, ok := picker.(peers.OwnerPicker); ok {
+		return p.PickOwner(key)
+	}
+	if peer, ok := picker.PickPeer(key); ok {
+		return peers.PickResult{State: peers.PickRemote, Peer: peer}
+	}
+	return peers.PickResult{State: peers.PickNoPeer}
+}
+
+// checkOriginLoad returns nil if the miss policy allows loading key from the data
+// source after the owner lookup ended in owner, with peerErr the failure of the
+// owner if it was asked and picker the PeerPicker the lookup used
+func (g *Group) checkOriginLoad(picker peers.PeerPicker, owner peers.PickResult, peerErr error) error {
+	switch g.missPolicy {
+	case MissPeerOnly:
+		if owner.State == peers.PickSelf {
+			return nil
+		}
+	case MissOriginOnlyIfOwner:
+		if owner.State == peers.PickSelf || picker == nil {
+			return nil
+		}
+		if peerErr != nil {
+			return peerErr
+		}
+	default:
+		return nil
+	}
+	if IsKeyNotFoundError(peerErr) {
+		// The owner answered; the key does not exist
+		return peerErr
+	}
+	if peerErr != nil {
+		return WrapError(ErrTypeNoPeerAvailable, ErrNoPeerAvailable.Message, peerErr)
+	}
+	return ErrNoPeerAvailable
+}
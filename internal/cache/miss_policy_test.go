@@ -0,0 +1,54 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+// TestMissPolicyWithoutPeers covers the lookups the harness cannot produce: a
+// group without a picker and a picker that knows no peer
+func TestMissPolicyWithoutPeers(t *testing.T) {
+	tests := []struct {
+		policy   MissPolicy
+		picker   bool
+		wantLoad bool
+	}{
+		{MissOriginFallback, false, true},
+		{MissOriginFallback, true, true},
+		{MissPeerOnly, false, false},
+		{MissPeerOnly, true, false},
+		{MissOriginOnlyIfOwner, false, true},
+		{MissOriginOnlyIfOwner, true, false},
+	}
+	for _, tt := range tests {
+		source := newCountingGetter(map[string]string{"k": "v"})
+		g := newTestGroup(t, source, time.Hour, WithMissPolicy(tt.policy))
+		if tt.picker {
+			g.RegisterPeers(&fakePicker{peer: &fakePeer{}, owns: func(string) bool { return false }})
+		}
+		v, err := g.Get("k")
+		if tt.wantLoad {
+			if err != nil || v.String() != "v" {
+				t.Errorf("%s, picker %v: Get = %q, %v", tt.policy, tt.picker, v, err)
+			}
+			continue
+		}
+		if !IsNoPeerAvailableError(err) || source.count("k") != 0 {
+			t.Errorf("%s, picker %v: Get = %v after %d loads, want ErrNoPeerAvailable", tt.policy, tt.picker, err, source.count("k"))
+		}
+	}
+}
+
+func TestParseMissPolicy(t *testing.T) {
+	for _, p := range []MissPolicy{MissOriginFallback, MissPeerOnly, MissOriginOnlyIfOwner} {
+		if got, err := ParseMissPolicy(p.String()); err != nil || got != p {
+			t.Fatalf("ParseMissPolicy(%q) = %v, %v", p, got, err)
+		}
+	}
+	if p, err := ParseMissPolicy(""); err != nil || p != MissOriginFallback {
+		t.Fatalf("ParseMissPolicy(\"\") = %v, %v", p, err)
+	}
+	if _, err := ParseMissPolicy("origin"); err == nil {
+		t.Fatal("ParseMissPolicy accepted an unknown name")
+	}
+}
@@ -0,0 +1,87 @@
+package cache
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+// Mode controls which operations a group accepts. Modes are ordered: a group
+// runs in the stricter of its own mode and the node mode.
+type Mode int32
+
+const (
+	// ModeReadWrite accepts every operation
+	ModeReadWrite Mode = iota
+	// ModeReadOnly serves cached values and fetches misses from peers, but rejects
+	// writes and deletes and never loads from the data source
+	ModeReadOnly
+	// ModeReadOnlyLocal is ModeReadOnly without peer fetches: misses fail with ErrNotFound
+	ModeReadOnlyLocal
+)
+
+// String returns the name used by the admin API and the stats endpoints
+func (m Mode) String() string {
+	switch m {
+	case ModeReadWrite:
+		return "readwrite"
+	case ModeReadOnly:
+		return "readonly"
+	case ModeReadOnlyLocal:
+		return "readonly-local"
+	default:
+		return fmt.Sprintf("Mode(%d)", int32(m))
+	}
+}
+
+// ReadOnly reports whether writes are rejected in mode m
+func (m Mode) ReadOnly() bool {
+	return m >= ModeReadOnly
+}
+
+// ParseMode parses a mode name as returned by Mode.String; an empty name is ModeReadWrite
+func ParseMode(s string) (Mode, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "readwrite", "read-write":
+		return ModeReadWrite, nil
+	case "readonly", "read-only":
+		return ModeReadOnly, nil
+	case "readonly-local", "read-only-local":
+		return ModeReadOnlyLocal, nil
+	default:
+		return ModeReadWrite, fmt.Errorf("unknown mode %q", s)
+	}
+}
+
+// nodeMode is the mode applied to every group on this node
+var nodeMode int32
+
+// SetNodeMode sets the mode applied to every group on this node
+func SetNodeMode(m Mode) {
+	atomic.StoreInt32(&nodeMode, int32(m))
+}
+
+// NodeMode returns the mode applied to every group on this node
+func NodeMode() Mode {
+	return Mode(atomic.LoadInt32(&nodeMode))
+}
+
+// SetMode sets the group's own mode. The node mode still applies on top of it.
+func (g *Group) SetMode(m Mode) {
+	atomic.StoreInt32(&g.mode, int32(m))
+}
+
+// Mode returns the mode the group currently runs in: the stricter of its own
+// mode and the node mode
+func (g *Group) Mode() Mode {
+	m := Mode(atomic.LoadInt32(&g.mode))
+	if n := NodeMode(); n > m {
+		return n
+	}
+	return m
+}
+
+// GroupMode returns the group's own mode, ignoring the node mode
+func (g *Group) GroupMode() Mode {
+	return Mode(atomic.LoadInt32(&g.mode))
+}
@@ -0,0 +1,190 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseMode(t *testing.T) {
+	for _, m := range []Mode{ModeReadWrite, ModeReadOnly, ModeReadOnlyLocal} {
+		got, err := ParseMode(m.String())
+		if err != nil || got != m {
+			t.Errorf("ParseMode(%q) = %v, %v", m.String(), got, err)
+		}
+	}
+	for s, want := range map[string]Mode{"": ModeReadWrite, " Read-Only ": ModeReadOnly, "read-only-local": ModeReadOnlyLocal} {
+		if got, err := ParseMode(s); err != nil || got != want {
+			t.Errorf("ParseMode(%q) = %v, %v; want %v", s, got, err, want)
+		}
+	}
+	if _, err := ParseMode("maintenance"); err == nil {
+		t.Error("ParseMode accepted an unknown mode")
+	}
+}
+
+// resetNodeMode restores the node mode shared by every group in the process
+func resetNodeMode(t *testing.T) {
+	t.Cleanup(func() { SetNodeMode(ModeReadWrite) })
+}
+
+func TestReadOnlyRejectsWrites(t *testing.T) {
+	getter := newCountingGetter(map[string]string{"cached": "v", "missing": "v"})
+	g := newTestGroup(t, getter, time.Hour)
+	mustGet(t, g, "cached")
+	g.SetMode(ModeReadOnly)
+
+	if err := g.Set("k", []byte("v"), 0); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Set = %v, want ErrReadOnly", err)
+	}
+	if err := g.Delete("cached"); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Delete = %v, want ErrReadOnly", err)
+	}
+	if err := g.Clear(); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Clear = %v, want ErrReadOnly", err)
+	}
+
+	// Cached values are still served, misses do not reach the data source
+	if v := mustGet(t, g, "cached"); v != "v" {
+		t.Errorf("cached = %q", v)
+	}
+	if _, err := g.Get("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get(missing) = %v, want ErrNotFound", err)
+	}
+	if n := getter.count("missing"); n != 0 {
+		t.Errorf("data source called %d times in read-only mode", n)
+	}
+
+	g.SetMode(ModeReadWrite)
+	if v := mustGet(t, g, "missing"); v != "v" {
+		t.Errorf("after leaving read-only: %q", v)
+	}
+}
+
+func TestReadOnlyPeerFetches(t *testing.T) {
+	tests := []struct {
+		mode      Mode
+		wantPeer  bool
+		wantValue string
+	}{
+		{ModeReadOnly, true, "peer:k"},
+		{ModeReadOnlyLocal, false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode.String(), func(t *testing.T) {
+			peer := &fakePeer{}
+			g := newTestGroup(t, newCountingGetter(map[string]string{"k": "local"}), time.Hour)
+			g.RegisterPeers(&fakePicker{peer: peer})
+			g.SetMode(tt.mode)
+
+			v, err := g.Get("k")
+			if tt.wantPeer {
+				if err != nil || v.String() != tt.wantValue {
+					t.Fatalf("Get = %q, %v; want %q", v.String(), err, tt.wantValue)
+				}
+			} else if !errors.Is(err, ErrNotFound) {
+				t.Fatalf("Get = %q, %v; want ErrNotFound", v.String(), err)
+			}
+			if got := peer.calls.Load() > 0; got != tt.wantPeer {
+				t.Fatalf("peer called: %v, want %v", got, tt.wantPeer)
+			}
+		})
+	}
+}
+
+func TestNodeModeAppliesToEveryGroup(t *testing.T) {
+	resetNodeMode(t)
+	a := newTestGroup(t, newCountingGetter(map[string]string{}), time.Hour)
+	b := newTestGroup(t, newCountingGetter(map[string]string{}), time.Hour)
+	b.SetMode(ModeReadOnlyLocal)
+
+	SetNodeMode(ModeReadOnly)
+	if a.Mode() != ModeReadOnly || a.GroupMode() != ModeReadWrite {
+		t.Errorf("a: Mode = %v, GroupMode = %v", a.Mode(), a.GroupMode())
+	}
+	// The stricter of the group and node mode wins
+	if b.Mode() != ModeReadOnlyLocal {
+		t.Errorf("b: Mode = %v, want readonly-local", b.Mode())
+	}
+	if err := a.Set("k", []byte("v"), 0); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Set under node read-only = %v", err)
+	}
+
+	SetNodeMode(ModeReadWrite)
+	if err := a.Set("k", []byte("v"), 0); err != nil {
+		t.Errorf("Set after node mode reset = %v", err)
+	}
+}
+
+// TestModeFlipsUnderTraffic flips the mode while readers and writers run: cached
+// keys are always served and writes either succeed or fail with ErrReadOnly
+func TestModeFlipsUnderTraffic(t *testing.T) {
+	resetNodeMode(t)
+	getter := newCountingGetter(map[string]string{})
+	for i := 0; i < 100; i++ {
+		getter.set(fmt.Sprintf("key-%d", i), "v")
+	}
+	g := newTestGroup(t, getter, time.Hour)
+	for i := 0; i < 10; i++ {
+		mustGet(t, g, fmt.Sprintf("key-%d", i))
+	}
+
+	var (
+		wg       sync.WaitGroup
+		stop     atomic.Bool
+		rejected atomic.Int64
+		errs     = make(chan error, 100)
+	)
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; !stop.Load(); i++ {
+				// Keys 0-9 are cached and never written
+				if _, err := g.Get(fmt.Sprintf("key-%d", i%10)); err != nil {
+					errs <- fmt.Errorf("cached read: %w", err)
+					return
+				}
+				key := fmt.Sprintf("key-%d", 10+(w*1000+i)%90)
+				if _, err := g.Get(key); err != nil && !errors.Is(err, ErrNotFound) {
+					errs <- fmt.Errorf("read %s: %w", key, err)
+					return
+				}
+				switch err := g.Set(key, []byte("v"), 0); {
+				case errors.Is(err, ErrReadOnly):
+					rejected.Add(1)
+				case err != nil:
+					errs <- fmt.Errorf("write %s: %w", key, err)
+					return
+				}
+			}
+		}(w)
+	}
+
+	modes := []Mode{ModeReadOnly, ModeReadWrite, ModeReadOnlyLocal, ModeReadWrite}
+	for i := 0; i < 200; i++ {
+		if i%2 == 0 {
+			g.SetMode(modes[i/2%len(modes)])
+		} else {
+			SetNodeMode(modes[(i/2+1)%len(modes)])
+		}
+		time.Sleep(100 * time.Microsecond)
+	}
+	stop.Store(true)
+	wg.Wait()
+	close(errs)
+	var msgs []string
+	for err := range errs {
+		msgs = append(msgs, err.Error())
+	}
+	if len(msgs) > 0 {
+		t.Fatal(strings.Join(msgs, "\n"))
+	}
+	if rejected.Load() == 0 {
+		t.Fatal("no write was rejected while flipping modes")
+	}
+}
@@ -0,0 +1,75 @@
+package cache
+
+import (
+	"time"
+
+	"github.com/AdrianWangs/go-cache/pkg/lru"
+)
+
+// EvictionCleared is the Reason of the event Clear reports: the key is empty
+// and every key of the group was dropped
+const EvictionCleared = "cleared"
+
+// EvictionEvent reports a key the group dropped from its local cache
+type EvictionEvent struct {
+	Group  string    `json:"group"`
+	Key    string    `json:"key"`       // empty when Reason is EvictionCleared
+	Reason string    `json:"reason"`    // capacity, expired, deleted or cleared
+	Time   time.Time `json:"timestamp"` // when the key was dropped
+}
+
+// Notifier receives the keys a group drops, see WithEvictionNotifier.
+// *evictnotify.Batcher implements it.
+type Notifier interface {
+	// Notify is called for every dropped key while the cache is locked, so it
+	// must return quickly and must not call back into the group
+	Notify(e EvictionEvent)
+}
+
+// WithEvictionNotifier reports every key that leaves the group's local cache to
+// n, so that an origin tracking which keys are cached learns when one is gone:
+// entries evicted for capacity, expired or deleted are reported with the
+// reason, and Clear reports a single event with an empty key. Replacing a value
+// is not reported. Keys stored under a digest with WithOriginalKeys(false)
+// cannot be reported, since the original key is not kept.
+func WithEvictionNotifier(n Notifier) GroupOption {
+	return func(g *Group) {
+		g.notifier = n
+	}
+}
+
+// notifyEvicted is the part of the lru eviction callback that feeds the notifier
+func (g *Group) notifyEvicted(e lru.Eviction) {
+	key := e.Key
+	if h, ok := e.Value.(hashedEntry); ok {
+		if h.key == "" {
+			return
+		}
+		key = h.key
+	}
+	g.notifier.Notify(EvictionEvent{Group: g.name, Key: key, Reason: e.Reason.String(), Time: g.clock.Now()})
+}
+
+// notifyCleared reports a Clear to the notifier
+func (g *Group) notifyCleared() {
+	if g.notifier != nil {
+		g.notifier.Notify(EvictionEvent{Group: g.name, Reason: EvictionCleared, Time: g.clock.Now()})
+	}
+}
+
+// evictionCallback returns the lru eviction callback feeding the eviction
+// pressure statistics and the notifier, nil when neither is enabled
+func (g *Group) evictionCallback() func(lru.Eviction) {
+	switch {
+	case g.pressure != nil && g.notifier != nil:
+		return func(e lru.Eviction) {
+			g.pressure.evicted(e)
+			g.notifyEvicted(e)
+		}
+	case g.pressure != nil:
+		return g.pressure.evicted
+	case g.notifier != nil:
+		return g.notifyEvicted
+	}
+	return nil
+}
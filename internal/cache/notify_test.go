@@ -0,0 +1,72 @@
+package cache
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/pkg/lru"
+)
+
+// recordingNotifier keeps the events it receives
+type recordingNotifier struct {
+	mu     sync.Mutex
+	events []EvictionEvent
+}
+
+func (n *recordingNotifier) Notify(e EvictionEvent) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.events = append(n.events, e)
+}
+
+// take returns the events received since the last call as "reason:key"
+func (n *recordingNotifier) take() []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	var out []string
+	for _, e := range n.events {
+		out = append(out, e.Reason+":"+e.Key)
+	}
+	n.events = nil
+	return out
+}
+
+func TestEvictionNotifier(t *testing.T) {
+	for _, hashed := range []bool{false, true} {
+		clock := lru.NewFakeClock(time.Unix(1000, 0))
+		n := &recordingNotifier{}
+		// Room for two entries of keys "k0".."k2" and value "v"
+		entry := len("k0") + len("v")
+		if hashed {
+			entry = DigestSize + len("k0") + 8 + len("v")
+		}
+		g := NewGroup("notify", int64(2*entry), GetterFunc(loadValue), time.Hour,
+			WithRegistry(NewRegistry()), WithClock(clock), WithKeyHashing(hashed),
+			WithEvictionNotifier(n), WithEvictionPressure(time.Second))
+		defer g.Close()
+
+		g.Set("k0", []byte("v"), 0)
+		g.Set("k1", []byte("v"), time.Minute)
+		g.Set("k1", []byte("v"), time.Minute) // replacing a value is not reported
+		if got := n.take(); len(got) != 0 {
+			t.Fatalf("hashed=%v: events %v before anything left the cache", hashed, got)
+		}
+
+		g.Set("k2", []byte("v"), 0)
+		clock.Advance(2 * time.Minute)
+		g.mainCache.removeExpired() // what the sweeper does
+		g.Delete("k2")
+		g.Clear()
+		want := []string{"capacity:k0", "expired:k1", "deleted:k2", "cleared:"}
+		got := n.take()
+		if len(got) != len(want) {
+			t.Fatalf("hashed=%v: events %v, want %v", hashed, got, want)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Fatalf("hashed=%v: events %v, want %v", hashed, got, want)
+			}
+		}
+	}
+}
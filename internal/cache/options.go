@@ -0,0 +1,193 @@
+package cache
+
+import (
+	"time"
+
+	"github.com/AdrianWangs/go-cache/pkg/logger"
+	"github.com/AdrianWangs/go-cache/pkg/lru"
+)
+
+// GroupOption configures a Group
+type GroupOption func(*Group)
+
+// WithClock sets the time source used for entry expiry, which makes ttl
+// behavior deterministic under an lru.FakeClock
+func WithClock(clock lru.Clock) GroupOption {
+	return func(g *Group) {
+		if clock != nil {
+			g.clock = clock
+		}
+	}
+}
+
+// WithKeyHashing enables key-digest mode: the cache stores a fixed-size digest
+// of each key instead of the key itself, bounding the memory used by very long keys
+func WithKeyHashing(enabled bool) GroupOption {
+	return func(g *Group) {
+		g.keyHashing = enabled
+	}
+}
+
+// WithKeyHashFunc overrides the digest function used in key-digest mode
+func WithKeyHashFunc(fn KeyHashFunc) GroupOption {
+	return func(g *Group) {
+		if fn != nil {
+			g.keyHash = fn
+		}
+	}
+}
+
+// WithOriginalKeys controls whether the original key is stored next to the value
+// in key-digest mode. Keeping it (the default) makes collision checks exact; dropping
+// it saves the key bytes and verifies lookups with an independent 64-bit fingerprint.
+func WithOriginalKeys(keep bool) GroupOption {
+	return func(g *Group) {
+		g.keepKeys = keep
+	}
+}
+
+// WithRefreshAhead enables refresh-ahead: a hit on an entry that has consumed more
+// than factor (0 < factor < 1) of its ttl is served immediately and triggers a
+// background reload that resets the ttl on success
+func WithRefreshAhead(factor float64) GroupOption {
+	return func(g *Group) {
+		g.refreshFactor = factor
+	}
+}
+
+// WithRefreshAheadConcurrency limits the number of background refreshes a group
+// runs at once; hits beyond the limit do not trigger a refresh
+func WithRefreshAheadConcurrency(n int) GroupOption {
+	return func(g *Group) {
+		g.refreshConcurrency = n
+	}
+}
+
+// WithRefreshAheadWindow sets how recently an entry must have been read before the
+// triggering hit to be refreshed. It defaults to the entry's ttl.
+func WithRefreshAheadWindow(d time.Duration) GroupOption {
+	return func(g *Group) {
+		g.refreshWindow = d
+	}
+}
+
+// WithMaxAge bounds the lifetime of every entry from its first insertion. Unlike the
+// ttl it is not extended by rewrites or refresh-ahead, so no value outlives d.
+func WithMaxAge(d time.Duration) GroupOption {
+	return func(g *Group) {
+		g.maxAge = d
+	}
+}
+
+// WithMaxIdle removes entries that have been neither read nor written for d
+func WithMaxIdle(d time.Duration) GroupOption {
+	return func(g *Group) {
+		g.maxIdle = d
+	}
+}
+
+// WithAccessTracking controls whether cache hits record the entry's last access
+// time and access count (the default), see lru.WithAccessTracking
+func WithAccessTracking(enabled bool) GroupOption {
+	return func(g *Group) {
+		g.untracked = !enabled
+	}
+}
+
+// WithEvictionPolicy selects how entries are evicted once the group exceeds its
+// cacheBytes: strict LRU (the default), CLOCK, which lets cache hits run under
+// the read lock at the price of a slightly worse hit rate, see lru.PolicyClock, or
+// the cost-aware lru.PolicyCost, which keeps entries that are expensive for their
+// size (see WithEntryCost) and otherwise behaves like LRU
+func WithEvictionPolicy(p lru.Policy) GroupOption {
+	return func(g *Group) {
+		g.policy = p
+	}
+}
+
+// WithEntryCost accounts every entry at fn(key, value) against cacheBytes instead
+// of its byte size, so the limit bounds the total cost of the cached values, for
+// example the origin time needed to regenerate them; with lru.PolicyCost, entries
+// with a high cost per byte are evicted last. Stats report both totals. In
+// key-digest mode without original keys fn receives the digest. fn runs under the
+// cache lock on every write, must be cheap and must not retain value.
+func WithEntryCost(fn func(key string, value []byte) int64) GroupOption {
+	return func(g *Group) {
+		g.entryCost = fn
+	}
+}
+
+// lruCost adapts the group's entry cost function to the values stored in the lru
+func (g *Group) lruCost(key string, v lru.Value) int64 {
+	switch v := v.(type) {
+	case ByteView:
+		return g.entryCost(key, v.bytes)
+	case hashedEntry:
+		if v.key != "" {
+			key = v.key
+		}
+		return g.entryCost(key, v.view.bytes)
+	default:
+		return int64(len(key) + v.Len())
+	}
+}
+
+// charge returns what an entry of key and value is accounted against cacheBytes
+func (g *Group) charge(key string, value []byte) int64 {
+	if g.entryCost == nil {
+		return int64(len(key) + len(value))
+	}
+	return max(g.entryCost(key, value), 0)
+}
+
+// WithSweepInterval starts a background sweeper that removes entries past their
+// ttl, max age or max idle time every d, instead of only when they are next read
+func WithSweepInterval(d time.Duration) GroupOption {
+	return func(g *Group) {
+		g.sweepEvery = d
+	}
+}
+
+// WithRateLimit caps the group's Get rate with a token bucket; requests over the
+// limit fail with ErrRateLimited. The limit can be changed later with SetRateLimit.
+func WithRateLimit(limit RateLimit) GroupOption {
+	return func(g *Group) {
+		g.rateLimit = limit
+	}
+}
+
+// WithClientRateLimit gives every client of the group, as named by WithClient,
+// a token bucket of its own on top of the group limit, so that one client
+// cannot use up the group's share. Gets without a client only count against the
+// group and node limits. The limit can be changed later with SetClientRateLimit.
+func WithClientRateLimit(limit RateLimit) GroupOption {
+	return func(g *Group) {
+		g.clientRateLimit = limit
+	}
+}
+
+// WithMode sets the group's initial mode, see Mode
+func WithMode(m Mode) GroupOption {
+	return func(g *Group) {
+		g.mode = int32(m)
+	}
+}
+
+// WithRegistry creates the group in r instead of the default registry. Nodes
+// that share a process, such as the test cluster harness, each use their own.
+func WithRegistry(r *Registry) GroupOption {
+	return func(g *Group) {
+		if r != nil {
+			g.registry = r
+		}
+	}
+}
+
+// WithLogger sends the log lines of the group's own methods to l instead of the
+// package logger; lines written by the rest of the package still go through
+// the logger package functions, see logger.SetLogger
+func WithLogger(l logger.Logger) GroupOption {
+	return func(g *Group) {
+		g.log = l
+	}
+}
@@ -0,0 +1,125 @@
+package cache
+
+import (
+	"sort"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/pkg/logger"
+	"github.com/AdrianWangs/go-cache/pkg/lru"
+	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
+	"google.golang.org/protobuf/proto"
+)
+
+// OwnedKey is a cached entry listed for a node that took over its key, see OwnedKeys
+type OwnedKey struct {
+	Key      string
+	Size     int    // value size in bytes
+	Accesses uint64 // reads of the entry since it was stored
+}
+
+// OwnedKeys lists the live entries whose key owns reports true for, in key
+// order starting after cursor, at most limit of them (every one for limit <= 0).
+// next is the cursor of the following page, empty after the last page. Entries
+// stored in key-digest mode without their original key cannot be listed and
+// are skipped. Listing reads neither counts as a read nor touches recency.
+func (g *Group) OwnedKeys(owns func(key string) bool, cursor string, limit int) (keys []OwnedKey, next string) {
+	g.mainCache.rangeEntries(func(k string, v lru.Value, expiry lru.Expiry) bool {
+		key, size := k, v.Len()
+		if e, ok := v.(hashedEntry); ok {
+			if e.key == "" {
+				return true
+			}
+			key, size = e.key, e.view.Len()
+		}
+		if key > cursor && owns(key) {
+			keys = append(keys, OwnedKey{Key: key, Size: size, Accesses: expiry.Accesses})
+		}
+		return true
+	})
+
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Key < keys[j].Key })
+	if limit > 0 && len(keys) > limit {
+		keys = keys[:limit]
+		next = keys[limit-1].Key
+	}
+	return keys, next
+}
+
+// OwnedKeysProto converts a page of OwnedKeys to its ListOwnedBy response
+func OwnedKeysProto(keys []OwnedKey, next string) *pb.ListOwnedResponse {
+	resp := &pb.ListOwnedResponse{Keys: make([]*pb.OwnedKey, 0, len(keys))}
+	for _, k := range keys {
+		resp.Keys = append(resp.Keys, &pb.OwnedKey{
+			Key:      k.Key,
+			Accesses: proto.Uint64(k.Accesses),
+			Size:     proto.Int64(int64(k.Size)),
+		})
+	}
+	if next != "" {
+		resp.NextCursor = proto.String(next)
+	}
+	return resp
+}
+
+// Peek returns the cached value of key without counting a read, loading it or
+// asking peers. It serves cache-only reads, which a node taking over the key
+// uses to copy the value instead of loading it from the data source again.
+func (g *Group) Peek(key string) (ByteView, ValueMeta, bool) {
+	if key == "" {
+		return ByteView{}, ValueMeta{}, false
+	}
+	v, expiry, ok := g.mainCache.peek(g.cacheKey(key))
+	if !ok {
+		return ByteView{}, ValueMeta{}, false
+	}
+	var view ByteView
+	switch val := v.(type) {
+	case ByteView:
+		view = val
+	case hashedEntry:
+		if !val.matches(key) {
+			return ByteView{}, ValueMeta{}, false
+		}
+		view = val.view
+	default:
+		return ByteView{}, ValueMeta{}, false
+	}
+	view, err := g.decodeCached(key, view)
+	if err != nil {
+		return ByteView{}, ValueMeta{}, false
+	}
+	meta := metaFromExpiry(expiry, SourceCache)
+	if deadline := g.exportExpiry(expiry); deadline != 0 {
+		meta.ExpiresAt = time.Unix(0, deadline)
+	}
+	return view, meta, true
+}
+
+// Warm stores a value copied from another node's cache, typically the previous
+// owner of key, and reports whether it was stored. Unlike a write it never
+// replaces a value: keys already cached here are left alone, as are keys
+// deleted since started, values already past expiresAt and values that would
+// evict entries to fit. A zero expiresAt means the value never expires.
+func (g *Group) Warm(key string, value []byte, expiresAt, started time.Time) bool {
+	if key == "" || len(value) == 0 || g.Mode().ReadOnly() {
+		return false
+	}
+	if _, _, ok := g.Peek(key); ok {
+		return false
+	}
+	var ttl time.Duration
+	if !expiresAt.IsZero() {
+		if ttl = expiresAt.Sub(g.clock.Now()); ttl <= 0 {
+			return false
+		}
+	}
+	stored, err := g.encodeValue(g.cacheKey(key), ByteView{bytes: cloneBytes(value)})
+	if err != nil {
+		g.log.Errorf("[Cache] 缓存值编码失败，未预热: group=%s, key=%s: %v", g.name, logger.Key(key), err)
+		return false
+	}
+	if limit := g.mainCache.cacheBytes; limit > 0 && g.mainCache.cost()+g.charge(key, stored.bytes) > limit {
+		return false
+	}
+	return g.storeLoaded(key, stored, ttl, started)
+}
@@ -0,0 +1,69 @@
+package cache
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/pkg/logger"
+)
+
+// TestPeerFetchValuesOwned reads keys owned by a peer from many goroutines
+// and keeps every value: the pooled messages of later fetches never change
+// the bytes an earlier read returned
+func TestPeerFetchValuesOwned(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+	peer := &fakePeer{}
+	g := newTestGroup(t, GetterFunc(loadValue), time.Hour)
+	g.RegisterPeers(&fakePicker{peer: peer})
+
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var kept []ByteView
+			for i := 0; i < 200; i++ {
+				v, err := g.Get(fmt.Sprintf("%d-%d", w, i))
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				kept = append(kept, v)
+			}
+			for i, v := range kept {
+				if want := fmt.Sprintf("peer:%d-%d", w, i); v.String() != want {
+					t.Errorf("kept value = %q, want %q", v.String(), want)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if n := peer.calls.Load(); n != 8*200 {
+		t.Fatalf("peer called %d times, want %d", n, 8*200)
+	}
+}
+
+// BenchmarkGetFromPeer measures a read of a key owned by a peer, which goes
+// through getFromPeerWithProto on every call
+func BenchmarkGetFromPeer(b *testing.B) {
+	logger.SetLevel("error")
+	defer logger.SetLevel("debug")
+	peer := &fakePeer{}
+	g := newTestGroup(b, GetterFunc(loadValue), time.Hour)
+	g.RegisterPeers(&fakePicker{peer: peer})
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := g.Get("k"); err != nil {
+			b.Fatal(err)
+		}
+	}
+	if n := peer.calls.Load(); n < int64(b.N) {
+		b.Fatalf("%d reads reached the peer %d times", b.N, n)
+	}
+}
@@ -0,0 +1,218 @@
+package cache
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/pkg/logger"
+	"github.com/AdrianWangs/go-cache/pkg/lru"
+)
+
+// DefaultEvictionWarnAge is a warning threshold for WithEvictionPressure: an
+// entry evicted within 10 seconds of its insertion was barely used
+const DefaultEvictionWarnAge = 10 * time.Second
+
+const (
+	// evictionWindow is the interval capacity evictions are counted over
+	evictionWindow = time.Minute
+	// evictionMinSamples is the number of evictions a window needs before its
+	// median age is trusted enough to warn
+	evictionMinSamples = 20
+)
+
+// evictedAgeBounds are the upper bounds of the age-at-eviction histogram buckets;
+// a last bucket holds older entries
+var evictedAgeBounds = []time.Duration{
+	time.Second, 5 * time.Second, 10 * time.Second, 30 * time.Second,
+	time.Minute, 5 * time.Minute, 15 * time.Minute, time.Hour,
+}
+
+// AgeBucket is a bucket of the age-at-eviction histogram
+type AgeBucket struct {
+	LE    string `json:"le"`    // upper bound of the bucket, "+Inf" for the last one
+	Count int64  `json:"count"` // entries evicted at an age in the bucket, not cumulative
+}
+
+// WithEvictionPressure tracks how old entries are when they are evicted to make
+// room. A group whose entries are evicted shortly after insertion is thrashing:
+// it is too small for its working set and its hit rate is about to drop.
+//
+// The group keeps a histogram of the age of capacity evictions and counts them
+// per minute. When at least 20 entries were evicted in the current minute and
+// their median age is below warnAge, a warning is logged, at most once a minute.
+// warnAge <= 0 keeps the statistics without warning. Entries are stamped with
+// their insertion time for it, which costs a clock read on every write.
+func WithEvictionPressure(warnAge time.Duration) GroupOption {
+	return func(g *Group) {
+		g.pressure = &evictionPressure{warnAge: warnAge}
+	}
+}
+
+// evictionPressure records the age of entries evicted for capacity
+type evictionPressure struct {
+	group   string
+	warnAge time.Duration
+	clock   lru.Clock
+
+	mu          sync.Mutex
+	total       []int64   // lifetime histogram over evictedAgeBounds
+	window      []int64   // histogram of the current window
+	windowN     int64     // evictions in the current window
+	windowStart time.Time // start of the current window
+	prev        []int64   // histogram of the previous window, nil before the first full one
+	prevN       int64     // evictions in the previous window
+	lastWarn    time.Time
+	warnings    int64
+
+	published atomic.Pointer[CacheStats] // eviction fields of the stats, see refresh
+}
+
+// init binds the tracker to its group; called by NewGroup
+func (p *evictionPressure) init(group string, clock lru.Clock) {
+	p.group = group
+	p.clock = clock
+	p.total = make([]int64, len(evictedAgeBounds)+1)
+	p.window = make([]int64, len(evictedAgeBounds)+1)
+	p.windowStart = clock.Now()
+	p.refresh()
+}
+
+// evicted is the lru eviction callback; it runs under the lru lock
+func (p *evictionPressure) evicted(e lru.Eviction) {
+	if e.Reason != lru.EvictCapacity {
+		return
+	}
+	now := p.clock.Now()
+	i := ageBucket(e.Age)
+
+	p.mu.Lock()
+	p.rollLocked(now)
+	p.total[i]++
+	p.window[i]++
+	p.windowN++
+	warn := false
+	var median time.Duration
+	n, windowLen := p.windowN, now.Sub(p.windowStart)
+	if p.warnAge > 0 && n >= evictionMinSamples && (p.lastWarn.IsZero() || now.Sub(p.lastWarn) >= evictionWindow) {
+		median = ageQuantile(p.window, n, 0.5)
+		if median < p.warnAge {
+			warn = true
+			p.lastWarn = now
+			p.warnings++
+		}
+	}
+	p.mu.Unlock()
+
+	if warn {
+		logger.WithFields(logger.Fields{
+			"group":           p.group,
+			"evicted_age_p50": median.Round(time.Millisecond).String(),
+			"warn_age":        p.warnAge.String(),
+			"evictions":       n,
+			"window":          windowLen.Round(time.Second).String(),
+		}).Warnf("[Cache] 缓存组淘汰过快，条目插入后很快被淘汰，容量可能不足")
+	}
+}
+
+// rollLocked starts a new window once the current one is over; the caller holds p.mu
+func (p *evictionPressure) rollLocked(now time.Time) {
+	elapsed := now.Sub(p.windowStart)
+	if elapsed < evictionWindow {
+		return
+	}
+	if elapsed < 2*evictionWindow {
+		p.prev, p.prevN = append(p.prev[:0], p.window...), p.windowN
+	} else {
+		// A whole window passed without an eviction
+		p.prev, p.prevN = nil, 0
+	}
+	clear(p.window)
+	p.windowN = 0
+	p.windowStart = now
+}
+
+// refresh computes the histogram, the eviction rate and recent age percentiles
+// and publishes them for stats. The rate and the percentiles describe the last
+// full minute, or the current one during the first minute. It takes p.mu, which
+// evictions hold under the lru lock, so it runs in the background sweeper rather
+// than on every Stats call.
+func (p *evictionPressure) refresh() {
+	stats := &CacheStats{}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rollLocked(p.clock.Now())
+
+	recent, n := p.prev, p.prevN
+	if recent == nil {
+		recent, n = p.window, p.windowN
+	}
+	stats.EvictionsPerMinute = n
+	stats.EvictedAgeP50Ms = ageQuantile(recent, n, 0.5).Milliseconds()
+	stats.EvictedAgeP90Ms = ageQuantile(recent, n, 0.9).Milliseconds()
+	stats.EvictionPressureWarnings = p.warnings
+	stats.EvictedAges = make([]AgeBucket, len(p.total))
+	for i, count := range p.total {
+		le := "+Inf"
+		if i < len(evictedAgeBounds) {
+			le = evictedAgeBounds[i].String()
+		}
+		stats.EvictedAges[i] = AgeBucket{LE: le, Count: count}
+	}
+	p.published.Store(stats)
+}
+
+// stats adds the statistics last published by refresh to stats
+func (p *evictionPressure) stats(stats *CacheStats) {
+	s := p.published.Load()
+	stats.EvictionsPerMinute = s.EvictionsPerMinute
+	stats.EvictedAgeP50Ms = s.EvictedAgeP50Ms
+	stats.EvictedAgeP90Ms = s.EvictedAgeP90Ms
+	stats.EvictionPressureWarnings = s.EvictionPressureWarnings
+	stats.EvictedAges = slices.Clone(s.EvictedAges)
+}
+
+// ageBucket returns the histogram bucket of age
+func ageBucket(age time.Duration) int {
+	for i, bound := range evictedAgeBounds {
+		if age <= bound {
+			return i
+		}
+	}
+	return len(evictedAgeBounds)
+}
+
+// ageQuantile estimates the q-quantile of n ages from their histogram,
+// interpolating linearly within the bucket it falls in. Ages in the last bucket
+// are reported as its lower bound.
+func ageQuantile(counts []int64, n int64, q float64) time.Duration {
+	if n == 0 {
+		return 0
+	}
+	rank := q * float64(n)
+	var cum int64
+	for i, c := range counts {
+		if c == 0 || float64(cum+c) < rank {
+			cum += c
+			continue
+		}
+		if i == len(evictedAgeBounds) {
+			break
+		}
+		var lower time.Duration
+		if i > 0 {
+			lower = evictedAgeBounds[i-1]
+		}
+		upper := evictedAgeBounds[i]
+		return lower + time.Duration(float64(upper-lower)*(rank-float64(cum))/float64(c))
+	}
+	return evictedAgeBounds[len(evictedAgeBounds)-1]
+}
+
+// pressureStats adds the eviction pressure statistics to stats
+func (g *Group) pressureStats(stats *CacheStats) {
+	if g.pressure != nil {
+		g.pressure.stats(stats)
+	}
+}
@@ -0,0 +1,183 @@
+package cache
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/pkg/logger"
+	"github.com/AdrianWangs/go-cache/pkg/lru"
+)
+
+// warnRecorder is a logger.Logger keeping the warnings and their fields
+type warnRecorder struct {
+	mu       *sync.Mutex // shared with the loggers WithFields returns, as is warnings
+	fields   logger.Fields
+	warnings *[]logger.Fields
+}
+
+func newWarnRecorder(t *testing.T) *warnRecorder {
+	r := &warnRecorder{mu: new(sync.Mutex), warnings: new([]logger.Fields)}
+	logger.SetLogger(r)
+	t.Cleanup(func() { logger.SetLogger(nil) })
+	return r
+}
+
+func (r *warnRecorder) Debugf(string, ...interface{}) {}
+func (r *warnRecorder) Infof(string, ...interface{})  {}
+func (r *warnRecorder) Errorf(string, ...interface{}) {}
+
+func (r *warnRecorder) Warnf(format string, args ...interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	f := logger.Fields{"msg": fmt.Sprintf(format, args...)}
+	for k, v := range r.fields {
+		f[k] = v
+	}
+	*r.warnings = append(*r.warnings, f)
+}
+
+func (r *warnRecorder) WithFields(fields logger.Fields) logger.Logger {
+	merged := logger.Fields{}
+	for k, v := range r.fields {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	return &warnRecorder{mu: r.mu, fields: merged, warnings: r.warnings}
+}
+
+// thrashWarnings returns the eviction pressure warnings logged so far
+func (r *warnRecorder) thrashWarnings() []logger.Fields {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []logger.Fields
+	for _, w := range *r.warnings {
+		if _, ok := w["evicted_age_p50"]; ok {
+			out = append(out, w)
+		}
+	}
+	return out
+}
+
+// pressureGroup returns a group holding 5 entries of 20 bytes that tracks
+// eviction pressure with a 10s threshold
+func pressureGroup(t *testing.T) (*Group, *lru.FakeClock) {
+	t.Helper()
+	clock := lru.NewFakeClock(time.Unix(1000, 0))
+	r := NewRegistry()
+	t.Cleanup(func() { r.Close() })
+	g := NewGroup(fmt.Sprintf("pressure-%s", t.Name()), 100, GetterFunc(func(string) ([]byte, error) { return nil, ErrNotFound }),
+		time.Hour, WithRegistry(r), WithClock(clock), WithEvictionPressure(DefaultEvictionWarnAge))
+	return g, clock
+}
+
+// writeEvery stores n distinct entries, advancing the clock by every before each
+func writeEvery(t *testing.T, g *Group, clock *lru.FakeClock, prefix string, n int, every time.Duration) {
+	t.Helper()
+	value := []byte(strings.Repeat("v", 16))
+	for i := 0; i < n; i++ {
+		clock.Advance(every)
+		if err := g.Set(fmt.Sprintf("%s%03d", prefix, i), value, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// TestEvictionPressureWarnsOnThrash writes into a group far too small for the
+// working set and checks that the warning fires once a minute and the
+// statistics show entries evicted young
+func TestEvictionPressureWarnsOnThrash(t *testing.T) {
+	rec := newWarnRecorder(t)
+	g, clock := pressureGroup(t)
+
+	// Each entry lives five writes, 500ms, before it is evicted
+	writeEvery(t, g, clock, "a", 60, 100*time.Millisecond)
+	warnings := rec.thrashWarnings()
+	if len(warnings) != 1 {
+		t.Fatalf("%d warnings after thrashing for 6s, want 1", len(warnings))
+	}
+	if w := warnings[0]; w["group"] != g.Name() || w["warn_age"] != "10s" || w["evictions"] != int64(evictionMinSamples) {
+		t.Fatalf("warning fields = %v", w)
+	}
+
+	g.pressure.refresh()
+	stats := g.Stats()
+	if stats.EvictionsPerMinute != 55 || stats.Evictions != 55 {
+		t.Fatalf("EvictionsPerMinute = %d, Evictions = %d, want 55", stats.EvictionsPerMinute, stats.Evictions)
+	}
+	if p50 := time.Duration(stats.EvictedAgeP50Ms) * time.Millisecond; p50 <= 0 || p50 > time.Second {
+		t.Fatalf("EvictedAgeP50 = %v, want within the first bucket", p50)
+	}
+	if stats.EvictionPressureWarnings != 1 {
+		t.Fatalf("EvictionPressureWarnings = %d", stats.EvictionPressureWarnings)
+	}
+	if b := stats.EvictedAges[0]; b.LE != "1s" || b.Count != 55 {
+		t.Fatalf("first histogram bucket = %+v", b)
+	}
+	if b := stats.EvictedAges[len(stats.EvictedAges)-1]; b.LE != "+Inf" || b.Count != 0 {
+		t.Fatalf("last histogram bucket = %+v", b)
+	}
+
+	// Rate limited to once a minute
+	clock.Advance(time.Minute)
+	writeEvery(t, g, clock, "b", 30, 100*time.Millisecond)
+	if n := len(rec.thrashWarnings()); n != 2 {
+		t.Fatalf("%d warnings after a minute more of thrashing, want 2", n)
+	}
+}
+
+// TestEvictionPressureQuietWhenSized evicts entries older than the threshold,
+// which is ordinary turnover, and checks that nothing is logged
+func TestEvictionPressureQuietWhenSized(t *testing.T) {
+	rec := newWarnRecorder(t)
+	g, clock := pressureGroup(t)
+
+	// Each entry lives five writes, 30s, before it is evicted
+	writeEvery(t, g, clock, "a", 60, 6*time.Second)
+	if n := len(rec.thrashWarnings()); n != 0 {
+		t.Fatalf("%d warnings for entries evicted after 30s", n)
+	}
+	g.pressure.refresh()
+	if p50 := time.Duration(g.Stats().EvictedAgeP50Ms) * time.Millisecond; p50 < 10*time.Second {
+		t.Fatalf("EvictedAgeP50 = %v, want at least 10s", p50)
+	}
+
+	// Deletes and expiries are not capacity evictions
+	before := g.Stats().EvictedAges
+	if err := g.DeleteLocally("a059"); err != nil {
+		t.Fatal(err)
+	}
+	g.pressure.refresh()
+	after := g.Stats().EvictedAges
+	for i := range before {
+		if before[i] != after[i] {
+			t.Fatalf("delete counted in the histogram: %+v -> %+v", before[i], after[i])
+		}
+	}
+}
+
+func TestAgeQuantile(t *testing.T) {
+	counts := make([]int64, len(evictedAgeBounds)+1)
+	counts[ageBucket(500*time.Millisecond)] = 10 // [0, 1s]
+	counts[ageBucket(3*time.Second)] = 10        // (1s, 5s]
+	if got := ageQuantile(counts, 20, 0.5); got != time.Second {
+		t.Fatalf("p50 = %v, want 1s", got)
+	}
+	if got := ageQuantile(counts, 20, 0.75); got != 3*time.Second {
+		t.Fatalf("p75 = %v, want 3s", got)
+	}
+	if got := ageQuantile(counts, 0, 0.5); got != 0 {
+		t.Fatalf("p50 of nothing = %v", got)
+	}
+
+	// Ages past the last bound are reported as that bound
+	counts = make([]int64, len(evictedAgeBounds)+1)
+	counts[ageBucket(2*time.Hour)] = 5
+	if got := ageQuantile(counts, 5, 0.5); got != time.Hour {
+		t.Fatalf("p50 past the last bound = %v, want 1h", got)
+	}
+}
@@ -0,0 +1,57 @@
+package cache
+
+import (
+	"context"
+
+	"github.com/AdrianWangs/go-cache/internal/peers"
+)
+
+// PrimeOutcome is what Prime did with a key
+type PrimeOutcome int
+
+const (
+	// PrimeLoaded means the key was loaded from the data source into the cache
+	PrimeLoaded PrimeOutcome = iota
+	// PrimeCached means the key was already cached and was left alone
+	PrimeCached
+	// PrimeNotOwned means a peer owns the key; it is left to that peer
+	PrimeNotOwned
+)
+
+// String returns the name of the outcome used in logs
+func (o PrimeOutcome) String() string {
+	switch o {
+	case PrimeLoaded:
+		return "loaded"
+	case PrimeCached:
+		return "cached"
+	case PrimeNotOwned:
+		return "not-owned"
+	}
+	return "unknown"
+}
+
+// Prime loads key into the cache ahead of its first read, typically from a list
+// of important keys after a restart. Only keys this node owns are loaded, the
+// way a miss would load them, sharing the load with concurrent reads; keys a
+// peer owns are left to it, since reads through this node fetch them from the
+// owner anyway. Unlike a read it does not count towards hits or replicate hot
+// keys. A key the data source does not have fails with ErrNotFound.
+func (g *Group) Prime(ctx context.Context, key string) (PrimeOutcome, error) {
+	if key == "" {
+		return 0, ErrEmptyKey
+	}
+	if p := g.peerPicker(); p != nil && pickOwner(p, key).State == peers.PickRemote {
+		return PrimeNotOwned, nil
+	}
+	if _, _, ok := g.Peek(key); ok {
+		return PrimeCached, nil
+	}
+	if g.tombstoned(key) {
+		return 0, ErrNotFound
+	}
+	if _, _, err := g.load(ctx, key); err != nil {
+		return 0, err
+	}
+	return PrimeLoaded, nil
+}
@@ -0,0 +1,187 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/internal/singleflight"
+	"github.com/AdrianWangs/go-cache/pkg/logger"
+)
+
+// minReapInterval bounds how often the idle-group reaper scans the registry
+const minReapInterval = 10 * time.Millisecond
+
+// GroupProvider creates the named group on demand, e.g. from a per-tenant
+// template. It creates the group with NewGroup in the registry it is
+// registered with (WithRegistry for one other than the default) and returns it,
+// or reports false to leave the name unknown.
+type GroupProvider func(name string) (*Group, bool)
+
+// ProviderOption configures a GroupProvider, see RegisterGroupProvider
+type ProviderOption func(*providerConfig)
+
+// providerConfig limits the groups a provider creates
+type providerConfig struct {
+	maxGroups   int           // groups created by the provider at once, 0 means unlimited
+	idleTimeout time.Duration // how long a created group may go without a lookup, 0 keeps it
+}
+
+// WithMaxProvidedGroups caps how many groups created by the provider may exist at
+// once. Lookups of further unknown names miss until one of them is closed or
+// reaped. Groups created with NewGroup directly do not count.
+func WithMaxProvidedGroups(n int) ProviderOption {
+	return func(c *providerConfig) {
+		c.maxGroups = n
+	}
+}
+
+// WithIdleGroupTimeout closes groups created by the provider once they have not
+// been looked up for d, releasing their memory; the next lookup creates the
+// group afresh. Lookups are how the servers use groups, so a caller holding a
+// *Group should look it up again rather than keep it for longer than d.
+func WithIdleGroupTimeout(d time.Duration) ProviderOption {
+	return func(c *providerConfig) {
+		c.idleTimeout = d
+	}
+}
+
+// groupProvider is a registered GroupProvider and its idle-group reaper
+type groupProvider struct {
+	fn       GroupProvider
+	cfg      providerConfig
+	creating singleflight.Group // one creation per name at a time
+	stop     context.CancelFunc // stops the reaper, nil without WithIdleGroupTimeout
+	done     chan struct{}      // closed when the reaper has exited
+}
+
+// RegisterGroupProvider sets the provider the default registry consults when
+// GetGroup misses, see Registry.RegisterGroupProvider
+func RegisterGroupProvider(fn GroupProvider, opts ...ProviderOption) {
+	defaultRegistry.RegisterGroupProvider(fn, opts...)
+}
+
+// RegisterGroupProvider sets fn as the provider Get consults when the named
+// group is not registered. Concurrent lookups of the same unknown name call fn
+// once and share the group it creates. A nil fn removes the provider; the groups
+// it created stay until closed. Registering a provider replaces the previous
+// one and stops its reaper.
+func (r *Registry) RegisterGroupProvider(fn GroupProvider, opts ...ProviderOption) {
+	var p *groupProvider
+	if fn != nil {
+		p = &groupProvider{fn: fn}
+		for _, opt := range opts {
+			opt(&p.cfg)
+		}
+	}
+
+	r.mu.Lock()
+	old := r.provider
+	r.provider = p
+	r.mu.Unlock()
+
+	old.stopReaper()
+	if p != nil && p.cfg.idleTimeout > 0 {
+		ctx, cancel := context.WithCancel(context.Background())
+		p.stop, p.done = cancel, make(chan struct{})
+		go r.reapIdle(ctx, p)
+	}
+}
+
+// provide creates the named group with p, once for all concurrent callers
+func (r *Registry) provide(p *groupProvider, name string) *Group {
+	v, _ := p.creating.Do(name, func() (interface{}, error) {
+		r.mu.Lock()
+		// A caller that waited on an earlier creation finds the group here
+		if g := r.groups[name]; g != nil {
+			r.touch(g)
+			r.mu.Unlock()
+			return g, nil
+		}
+		if p.cfg.maxGroups > 0 && len(r.provided)+r.providing >= p.cfg.maxGroups {
+			r.mu.Unlock()
+			logger.Warnf("Not creating cache group %s: %d provided groups already exist", name, p.cfg.maxGroups)
+			return nil, nil
+		}
+		r.providing++
+		r.mu.Unlock()
+
+		g, ok := p.fn(name)
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.providing--
+		if !ok || g == nil {
+			return nil, nil
+		}
+		if r.groups[name] != g {
+			logger.Warnf("Group provider returned %s for %s outside the registry it serves", g.name, name)
+			return nil, nil
+		}
+		r.provided[g] = struct{}{}
+		r.touch(g)
+		return g, nil
+	})
+	g, _ := v.(*Group)
+	return g
+}
+
+// touch records a lookup of a provided group; the caller holds r.mu, so the
+// reaper never closes a group between a lookup and its use
+func (r *Registry) touch(g *Group) {
+	if _, ok := r.provided[g]; ok {
+		g.lastUsed.Store(time.Now().UnixNano())
+	}
+}
+
+// reapIdle closes provided groups idle for longer than the provider's timeout
+// until ctx is cancelled
+func (r *Registry) reapIdle(ctx context.Context, p *groupProvider) {
+	defer close(p.done)
+
+	interval := max(p.cfg.idleTimeout/2, minReapInterval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.reap(p.cfg.idleTimeout)
+		}
+	}
+}
+
+// reap closes the provided groups not looked up within idle and returns how many
+func (r *Registry) reap(idle time.Duration) int {
+	cutoff := time.Now().Add(-idle).UnixNano()
+
+	r.mu.Lock()
+	var idleGroups []*Group
+	for g := range r.provided {
+		if g.lastUsed.Load() < cutoff {
+			// Unregister under the lock so that no lookup can return the group
+			// once it is chosen; Close then finds it already removed
+			delete(r.provided, g)
+			if r.groups[g.name] == g {
+				delete(r.groups, g.name)
+			}
+			idleGroups = append(idleGroups, g)
+		}
+	}
+	r.mu.Unlock()
+
+	for _, g := range idleGroups {
+		logger.Infof("Reaping idle cache group: %s", g.name)
+		g.Close()
+	}
+	return len(idleGroups)
+}
+
+// stopReaper stops p's reaper and waits for it to exit; p may be nil
+func (p *groupProvider) stopReaper() {
+	if p == nil || p.stop == nil {
+		return
+	}
+	p.stop()
+	<-p.done
+}
@@ -0,0 +1,176 @@
+package cache
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// tenantProvider creates a group per tenant in r and counts the calls
+type tenantProvider struct {
+	r      *Registry
+	calls  atomic.Int64
+	delay  time.Duration // how long each creation takes
+	getter Getter        // the groups' getter, loadValue when nil
+}
+
+func (p *tenantProvider) provide(name string) (*Group, bool) {
+	p.calls.Add(1)
+	time.Sleep(p.delay)
+	if !strings.HasPrefix(name, "tenant-") {
+		return nil, false
+	}
+	getter := p.getter
+	if getter == nil {
+		getter = GetterFunc(loadValue)
+	}
+	return NewGroup(name, 64<<20, getter, time.Hour, WithRegistry(p.r)), true
+}
+
+func TestProviderConcurrentFirstAccess(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+	r := NewRegistry()
+	defer r.Close()
+	p := &tenantProvider{r: r, delay: 20 * time.Millisecond}
+	r.RegisterGroupProvider(p.provide)
+
+	const callers = 50
+	groups := make([]*Group, callers)
+	var wg sync.WaitGroup
+	for i := range groups {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			groups[i] = r.Get("tenant-a")
+		}()
+	}
+	wg.Wait()
+
+	if n := p.calls.Load(); n != 1 {
+		t.Fatalf("provider called %d times, want 1", n)
+	}
+	for _, g := range groups {
+		if g == nil || g != groups[0] {
+			t.Fatalf("callers got different groups: %p and %p", g, groups[0])
+		}
+	}
+	if v := mustGet(t, groups[0], "k"); v != "v:k" {
+		t.Fatalf("Get = %q", v)
+	}
+
+	// A declined name stays unknown
+	if g := r.Get("other"); g != nil {
+		t.Fatalf("Get of a declined name = %v", g.Name())
+	}
+}
+
+func TestProviderCap(t *testing.T) {
+	r := NewRegistry()
+	defer r.Close()
+	p := &tenantProvider{r: r}
+	r.RegisterGroupProvider(p.provide, WithMaxProvidedGroups(2))
+	NewGroup("static", 1<<20, GetterFunc(loadValue), time.Hour, WithRegistry(r)) // not counted
+
+	a, b := r.Get("tenant-a"), r.Get("tenant-b")
+	if a == nil || b == nil {
+		t.Fatalf("groups under the cap = %v, %v", a, b)
+	}
+	if g := r.Get("tenant-c"); g != nil {
+		t.Fatal("created a group over the cap")
+	}
+	if r.Get("tenant-a") != a || r.Get("static") == nil {
+		t.Fatal("existing groups are not returned at the cap")
+	}
+
+	// Closing a provided group makes room for another
+	a.Close()
+	if g := r.Get("tenant-c"); g == nil {
+		t.Fatal("no group created after one was closed")
+	}
+	if g := r.Get("tenant-a"); g != nil {
+		t.Fatal("recreated a closed group over the cap")
+	}
+}
+
+// TestProviderRejectsForeignGroup checks that a group the provider creates in
+// another registry is not returned, since it would not be found again
+func TestProviderRejectsForeignGroup(t *testing.T) {
+	r, other := NewRegistry(), NewRegistry()
+	defer r.Close()
+	defer other.Close()
+	r.RegisterGroupProvider((&tenantProvider{r: other}).provide)
+	if g := r.Get("tenant-a"); g != nil {
+		t.Fatal("returned a group from another registry")
+	}
+}
+
+func TestProviderReapsIdleGroups(t *testing.T) {
+	const idle = 100 * time.Millisecond
+	r := NewRegistry()
+	defer r.Close()
+	p := &tenantProvider{r: r, getter: GetterFunc(func(key string) ([]byte, error) {
+		return make([]byte, 64<<10), nil
+	})}
+	r.RegisterGroupProvider(p.provide, WithIdleGroupTimeout(idle))
+
+	idleGroup, busy := r.Get("tenant-idle"), r.Get("tenant-busy")
+	for i := 0; i < 128; i++ {
+		mustGet(t, r.Get("tenant-idle"), fmt.Sprintf("key-%d", i)) // each lookup keeps it
+		r.Get("tenant-busy")
+	}
+	var full runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&full)
+
+	// Looking a group up keeps it; the other one is closed and unregistered
+	deadline := time.Now().Add(5 * time.Second)
+	for !idleGroup.Closed() {
+		if time.Now().After(deadline) {
+			t.Fatal("idle group was not reaped")
+		}
+		r.Get("tenant-busy")
+		time.Sleep(idle / 10)
+	}
+	if busy.Closed() || r.Get("tenant-busy") != busy || r.lookup("tenant-idle") != nil {
+		t.Fatal("reaped the wrong group")
+	}
+
+	// Nothing else references the reaped group, so its 8MB of values are freed
+	idleGroup = nil
+	var reaped runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&reaped)
+	if freed := int64(full.HeapAlloc) - int64(reaped.HeapAlloc); freed < 6<<20 {
+		t.Fatalf("reaping freed %d bytes of the idle group's 8MB", freed)
+	}
+
+	// The next lookup creates the group afresh
+	if g := r.Get("tenant-idle"); g == nil || g.Closed() || g.Stats().Entries != 0 {
+		t.Fatal("lookup after reaping did not create a new group")
+	}
+	if n := p.calls.Load(); n != 3 {
+		t.Fatalf("provider called %d times, want 3", n)
+	}
+}
+
+// TestProviderReaperStops checks that replacing the provider and closing the
+// registry stop the reaper goroutines
+func TestProviderReaperStops(t *testing.T) {
+	before := runtime.NumGoroutine()
+	r := NewRegistry()
+	p := &tenantProvider{r: r}
+	r.RegisterGroupProvider(p.provide, WithIdleGroupTimeout(time.Minute))
+	r.RegisterGroupProvider(p.provide, WithIdleGroupTimeout(time.Minute))
+	r.Get("tenant-a")
+	r.Close()
+	if g := r.Get("tenant-b"); g != nil {
+		t.Fatal("closed registry still provides groups")
+	}
+	waitFor(t, "the reapers to exit", func() bool {
+		return runtime.NumGoroutine() <= before
+	})
+}
@@ -0,0 +1,269 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/pkg/lru"
+)
+
+// maxRateClients bounds the per-client buckets a group keeps, see WithClientRateLimit
+const maxRateClients = 4096
+
+// RateLimit describes a token bucket: Rate requests per second on average with
+// bursts of up to Burst requests. A zero Rate means unlimited.
+type RateLimit struct {
+	Rate  float64 `json:"rate"`  // requests per second
+	Burst int     `json:"burst"` // bucket capacity, defaults to ceil(Rate)
+}
+
+// withDefaults fills in the default burst
+func (r RateLimit) withDefaults() RateLimit {
+	if r.Burst <= 0 && r.Rate > 0 {
+		r.Burst = int(r.Rate)
+		if float64(r.Burst) < r.Rate {
+			r.Burst++
+		}
+	}
+	return r
+}
+
+// RateLimiter is a token-bucket limiter whose limit can be changed at runtime
+type RateLimiter struct {
+	clock lru.Clock
+
+	mu     sync.Mutex
+	limit  RateLimit
+	tokens float64   // tokens currently in the bucket
+	last   time.Time // last time tokens were added
+}
+
+// NewRateLimiter creates a limiter that starts with a full bucket.
+// A nil clock uses the wall clock.
+func NewRateLimiter(limit RateLimit, clock lru.Clock) *RateLimiter {
+	if clock == nil {
+		clock = lru.RealClock{}
+	}
+	l := &RateLimiter{clock: clock}
+	l.SetLimit(limit)
+	return l
+}
+
+// Allow reports whether a request may proceed now, consuming a token if so
+func (l *RateLimiter) Allow() bool {
+	return l.reserve() == 0
+}
+
+// reserve consumes a token and returns 0 if one is available; otherwise it
+// returns how long until the bucket holds one again
+func (l *RateLimiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.limit.Rate <= 0 {
+		return 0
+	}
+
+	now := l.clock.Now()
+	l.refill(now)
+	if l.tokens < 1 {
+		return max(time.Duration((1-l.tokens)/l.limit.Rate*float64(time.Second)), 1)
+	}
+	l.tokens--
+	return 0
+}
+
+// refill adds the tokens earned since the last call. The caller holds l.mu.
+func (l *RateLimiter) refill(now time.Time) {
+	if elapsed := now.Sub(l.last); elapsed > 0 {
+		l.tokens += elapsed.Seconds() * l.limit.Rate
+		if max := float64(l.limit.Burst); l.tokens > max {
+			l.tokens = max
+		}
+	}
+	l.last = now
+}
+
+// full reports whether the bucket has refilled completely, so that it behaves
+// like a new one
+func (l *RateLimiter) full() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refill(l.clock.Now())
+	return l.tokens >= float64(l.limit.Burst)
+}
+
+// SetLimit replaces the limit and refills the bucket
+func (l *RateLimiter) SetLimit(limit RateLimit) {
+	limit = limit.withDefaults()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.limit = limit
+	l.tokens = float64(limit.Burst)
+	l.last = l.clock.Now()
+}
+
+// Limit returns the current limit
+func (l *RateLimiter) Limit() RateLimit {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.limit
+}
+
+var (
+	nodeLimiter   atomic.Pointer[RateLimiter] // aggregate limit over every group on this node
+	nodeThrottled int64                       // requests rejected by the node limit
+)
+
+// SetNodeRateLimit sets the aggregate QPS limit over every group on this node.
+// A zero Rate removes the limit.
+func SetNodeRateLimit(limit RateLimit) {
+	if limit.Rate <= 0 {
+		nodeLimiter.Store(nil)
+		return
+	}
+	if l := nodeLimiter.Load(); l != nil {
+		l.SetLimit(limit)
+		return
+	}
+	nodeLimiter.Store(NewRateLimiter(limit, nil))
+}
+
+// NodeRateLimit returns the aggregate QPS limit of this node, zero if unlimited
+func NodeRateLimit() RateLimit {
+	if l := nodeLimiter.Load(); l != nil {
+		return l.Limit()
+	}
+	return RateLimit{}
+}
+
+// NodeThrottled returns the number of requests rejected by the node-level limit
+func NodeThrottled() int64 {
+	return atomic.LoadInt64(&nodeThrottled)
+}
+
+// SetRateLimit sets the group's QPS limit at runtime. A zero Rate removes the limit.
+func (g *Group) SetRateLimit(limit RateLimit) {
+	if limit.Rate <= 0 {
+		g.limiter.Store(nil)
+		return
+	}
+	if l := g.limiter.Load(); l != nil {
+		l.SetLimit(limit)
+		return
+	}
+	g.limiter.Store(NewRateLimiter(limit, g.clock))
+}
+
+// RateLimit returns the group's QPS limit, zero if unlimited
+func (g *Group) RateLimit() RateLimit {
+	if l := g.limiter.Load(); l != nil {
+		return l.Limit()
+	}
+	return RateLimit{}
+}
+
+// clientLimiters gives every client of a group a token bucket of its own, so
+// that one busy client cannot use up the limit of the others
+type clientLimiters struct {
+	limit RateLimit
+	clock lru.Clock
+
+	mu      sync.Mutex
+	buckets map[string]*RateLimiter
+}
+
+func newClientLimiters(limit RateLimit, clock lru.Clock) *clientLimiters {
+	return &clientLimiters{limit: limit, clock: clock, buckets: make(map[string]*RateLimiter)}
+}
+
+// reserve takes a token from client's bucket, see RateLimiter.reserve. Once
+// maxRateClients buckets exist the full ones are dropped, as a new bucket would
+// be full too; when none is full every bucket is dropped rather than turning
+// new clients away.
+func (c *clientLimiters) reserve(client string) time.Duration {
+	c.mu.Lock()
+	l := c.buckets[client]
+	if l == nil {
+		if len(c.buckets) >= maxRateClients {
+			for id, b := range c.buckets {
+				if b.full() {
+					delete(c.buckets, id)
+				}
+			}
+			if len(c.buckets) >= maxRateClients {
+				c.buckets = make(map[string]*RateLimiter)
+			}
+		}
+		l = NewRateLimiter(c.limit, c.clock)
+		c.buckets[client] = l
+	}
+	c.mu.Unlock()
+	return l.reserve()
+}
+
+// clientKey is the context key of the client set by WithClient
+type clientKey struct{}
+
+// WithClient returns a context whose Gets count against the bucket of client
+// id when the group has a per-client limit, see WithClientRateLimit. Servers set
+// it to the host the request came from, see peers.ClientHost.
+func WithClient(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, clientKey{}, id)
+}
+
+// ClientFrom returns the client set by WithClient, empty if none
+func ClientFrom(ctx context.Context) string {
+	id, _ := ctx.Value(clientKey{}).(string)
+	return id
+}
+
+// SetClientRateLimit sets the QPS limit each client of the group gets at
+// runtime, with every client starting from a full bucket. A zero Rate removes
+// the limit.
+func (g *Group) SetClientRateLimit(limit RateLimit) {
+	if limit.Rate <= 0 {
+		g.clientLimiter.Store(nil)
+		return
+	}
+	g.clientLimiter.Store(newClientLimiters(limit.withDefaults(), g.clock))
+}
+
+// ClientRateLimit returns the QPS limit of each client of the group, zero if unlimited
+func (g *Group) ClientRateLimit() RateLimit {
+	if c := g.clientLimiter.Load(); c != nil {
+		return c.limit
+	}
+	return RateLimit{}
+}
+
+// allow applies the limit of the calling client, the group limit and then the
+// node limit to one request. A rejected request fails with a RateLimitedError
+// telling when the bucket that refused it has a token again.
+func (g *Group) allow(ctx context.Context) error {
+	if c := g.clientLimiter.Load(); c != nil {
+		if client := ClientFrom(ctx); client != "" {
+			if wait := c.reserve(client); wait > 0 {
+				atomic.AddInt64(&g.throttled, 1)
+				return &RateLimitedError{RetryAfter: wait}
+			}
+		}
+	}
+	if l := g.limiter.Load(); l != nil {
+		if wait := l.reserve(); wait > 0 {
+			atomic.AddInt64(&g.throttled, 1)
+			return &RateLimitedError{RetryAfter: wait}
+		}
+	}
+	if l := nodeLimiter.Load(); l != nil {
+		if wait := l.reserve(); wait > 0 {
+			atomic.AddInt64(&g.throttled, 1)
+			atomic.AddInt64(&nodeThrottled, 1)
+			return &RateLimitedError{RetryAfter: wait}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/pkg/lru"
+)
+
+// getAs reads key on behalf of client and returns the error
+func getAs(g *Group, client, key string) error {
+	ctx := context.Background()
+	if client != "" {
+		ctx = WithClient(ctx, client)
+	}
+	_, _, err := g.GetWithMeta(ctx, key)
+	return err
+}
+
+// wantLimited fails the test unless err is a rate limit asking to retry after wait
+func wantLimited(t *testing.T, err error, wait time.Duration) {
+	t.Helper()
+	var limited *RateLimitedError
+	if !errors.Is(err, ErrRateLimited) || !errors.As(err, &limited) || limited.RetryAfter != wait {
+		t.Fatalf("err = %v, want rate limited with retry after %v", err, wait)
+	}
+}
+
+func TestRateLimitRetryAfter(t *testing.T) {
+	clock := lru.NewFakeClock(time.Unix(1000, 0))
+	g := newTestGroup(t, GetterFunc(loadValue), time.Minute, WithClock(clock),
+		WithRateLimit(RateLimit{Rate: 4, Burst: 2}))
+
+	for i := 0; i < 2; i++ {
+		if err := getAs(g, "", "k"); err != nil {
+			t.Fatalf("request %d within the burst: %v", i, err)
+		}
+	}
+	wantLimited(t, getAs(g, "", "k"), 250*time.Millisecond)
+
+	// Part of a token has come back, the rest is still to wait
+	clock.Advance(100 * time.Millisecond)
+	wantLimited(t, getAs(g, "", "k"), 150*time.Millisecond)
+	clock.Advance(150 * time.Millisecond)
+	if err := getAs(g, "", "k"); err != nil {
+		t.Fatalf("after the retry interval: %v", err)
+	}
+	if got := g.Stats().Throttled; got != 2 {
+		t.Fatalf("throttled = %d, want 2", got)
+	}
+}
+
+func TestClientRateLimit(t *testing.T) {
+	clock := lru.NewFakeClock(time.Unix(1000, 0))
+	g := newTestGroup(t, GetterFunc(loadValue), time.Minute, WithClock(clock),
+		WithRateLimit(RateLimit{Rate: 1, Burst: 5}),
+		WithClientRateLimit(RateLimit{Rate: 1, Burst: 2}))
+	if got := g.ClientRateLimit(); got != (RateLimit{Rate: 1, Burst: 2}) {
+		t.Fatalf("ClientRateLimit() = %+v", got)
+	}
+
+	// Each client has a bucket of its own
+	for _, client := range []string{"10.0.0.1", "10.0.0.2"} {
+		for i := 0; i < 2; i++ {
+			if err := getAs(g, client, "k"); err != nil {
+				t.Fatalf("%s request %d: %v", client, i, err)
+			}
+		}
+		wantLimited(t, getAs(g, client, "k"), time.Second)
+	}
+
+	// The group limit still applies: one token is left for everyone
+	if err := getAs(g, "10.0.0.3", "k"); err != nil {
+		t.Fatal(err)
+	}
+	wantLimited(t, getAs(g, "10.0.0.4", "k"), time.Second)
+
+	// Gets without a client only count against the group limit
+	clock.Advance(5 * time.Second)
+	for i := 0; i < 5; i++ {
+		if err := getAs(g, "", "k"); err != nil {
+			t.Fatalf("request %d without a client: %v", i, err)
+		}
+	}
+	if got := g.Stats().Throttled; got != 3 {
+		t.Fatalf("throttled = %d, want 3", got)
+	}
+}
+
+func TestSetClientRateLimit(t *testing.T) {
+	clock := lru.NewFakeClock(time.Unix(1000, 0))
+	g := newTestGroup(t, GetterFunc(loadValue), time.Minute, WithClock(clock))
+	if got := g.ClientRateLimit(); got != (RateLimit{}) {
+		t.Fatalf("ClientRateLimit() = %+v, want unlimited", got)
+	}
+
+	g.SetClientRateLimit(RateLimit{Rate: 0.5})
+	if got := g.ClientRateLimit(); got != (RateLimit{Rate: 0.5, Burst: 1}) {
+		t.Fatalf("ClientRateLimit() = %+v", got)
+	}
+	if err := getAs(g, "c", "k"); err != nil {
+		t.Fatal(err)
+	}
+	wantLimited(t, getAs(g, "c", "k"), 2*time.Second)
+
+	// A new limit starts every client from a full bucket
+	g.SetClientRateLimit(RateLimit{Rate: 1, Burst: 2})
+	for i := 0; i < 2; i++ {
+		if err := getAs(g, "c", "k"); err != nil {
+			t.Fatalf("request %d after raising the limit: %v", i, err)
+		}
+	}
+
+	// A zero rate removes the limit
+	g.SetClientRateLimit(RateLimit{})
+	for i := 0; i < 10; i++ {
+		if err := getAs(g, "c", "k"); err != nil {
+			t.Fatalf("request %d after removing the limit: %v", i, err)
+		}
+	}
+}
+
+func TestClientRateLimitBucketCap(t *testing.T) {
+	clock := lru.NewFakeClock(time.Unix(1000, 0))
+	g := newTestGroup(t, GetterFunc(loadValue), time.Minute, WithClock(clock),
+		WithClientRateLimit(RateLimit{Rate: 1}))
+	limiters := g.clientLimiter.Load()
+	clients := func() int {
+		limiters.mu.Lock()
+		defer limiters.mu.Unlock()
+		return len(limiters.buckets)
+	}
+
+	// Fill every bucket slot with clients that have used their token
+	for i := 0; i < maxRateClients; i++ {
+		if err := getAs(g, fmt.Sprintf("c%d", i), "k"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wantLimited(t, getAs(g, "c0", "k"), time.Second)
+
+	// With no full bucket to drop, every bucket is dropped rather than
+	// turning the new client away
+	if err := getAs(g, "new", "k"); err != nil {
+		t.Fatalf("new client past the cap: %v", err)
+	}
+	if n := clients(); n != 1 {
+		t.Fatalf("%d buckets after the reset, want 1", n)
+	}
+	if err := getAs(g, "c0", "k"); err != nil {
+		t.Fatalf("client whose bucket was dropped: %v", err)
+	}
+
+	// Once buckets have refilled they are dropped first and the rest are kept
+	for i := 2; i < maxRateClients; i++ {
+		if err := getAs(g, fmt.Sprintf("c%d", i), "k"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	clock.Advance(time.Second)
+	if err := getAs(g, "c2", "k"); err != nil {
+		t.Fatal(err)
+	}
+	if err := getAs(g, "newer", "k"); err != nil {
+		t.Fatal(err)
+	}
+	if n := clients(); n != 2 {
+		t.Fatalf("%d buckets after dropping full ones, want 2", n)
+	}
+	wantLimited(t, getAs(g, "c2", "k"), time.Second)
+}
@@ -0,0 +1,121 @@
+package cache
+
+import (
+	"sync/atomic"
+
+	"github.com/AdrianWangs/go-cache/internal/ratewindow"
+)
+
+// events counted by the stats window, see WithStatsWindow
+const (
+	rateHits = iota
+	rateMisses
+	rateLoads
+	rateEvictions
+	rateErrors
+	rateEvents
+)
+
+// GroupRates reports the recent rates of a group next to the lifetime totals of
+// CacheStats. Version is ratewindow.SchemaVersion.
+type GroupRates struct {
+	Version       int         `json:"version"`
+	BucketSeconds int         `json:"bucket_seconds"` // span of a bucket
+	Buckets       int         `json:"buckets"`        // buckets kept, see WithStatsWindow
+	Last1m        RateSummary `json:"last_1m"`
+	Last5m        RateSummary `json:"last_5m"`
+	Last15m       RateSummary `json:"last_15m"`
+}
+
+// RateSummary holds per-minute rates averaged over the completed minutes of a
+// span; the minute in progress is not included
+type RateSummary struct {
+	Minutes   int     `json:"minutes"`   // completed minutes covered, fewer than the span while the group is young
+	Hits      float64 `json:"hits"`      // local cache hits per minute
+	Misses    float64 `json:"misses"`    // local cache misses per minute
+	Loads     float64 `json:"loads"`     // loads executed per minute, deduplicated waiters not included
+	Evictions float64 `json:"evictions"` // capacity evictions per minute
+	Errors    float64 `json:"errors"`    // failed loads per minute, not-found and abandoned loads excluded
+	HitRatio  float64 `json:"hit_ratio"` // hits over hits plus misses, 0 without lookups
+}
+
+// groupRates counts the events of a group in one-minute buckets
+type groupRates struct {
+	window *ratewindow.Window
+	// evictionsSeen is the lru eviction count last added to the window. Capacity
+	// evictions are taken from the lru counter on the next counted event rather
+	// than from an eviction callback, which would stamp every Add with the time.
+	evictionsSeen atomic.Int64
+}
+
+// WithStatsWindow sets the number of one-minute buckets the group keeps for the
+// rates reported in CacheStats.Rates, 60 by default; buckets <= 0 disables
+// them. A bucket takes about 80 bytes, so the default costs about 5KB per
+// group. Counting adds an atomic increment to every Get.
+func WithStatsWindow(buckets int) GroupOption {
+	return func(g *Group) {
+		g.rateBuckets = buckets
+	}
+}
+
+// initRates creates the stats window unless WithStatsWindow disabled it
+func (g *Group) initRates() {
+	if g.rateBuckets <= 0 {
+		return
+	}
+	g.rates = &groupRates{window: ratewindow.New(rateEvents, g.rateBuckets, g.clock.Now)}
+	g.rates.evictionsSeen.Store(g.mainCache.lru.Evictions())
+}
+
+// countRate counts one event in the current minute, with the evictions that
+// happened since the last counted event
+func (g *Group) countRate(event int) {
+	if g.rates == nil {
+		return
+	}
+	g.rates.window.Add(event, 1)
+	g.syncEvictions()
+}
+
+// syncEvictions adds the capacity evictions of the lru since the last call to
+// the current minute
+func (g *Group) syncEvictions() {
+	total := g.mainCache.lru.Evictions()
+	seen := g.rates.evictionsSeen.Load()
+	if total > seen && g.rates.evictionsSeen.CompareAndSwap(seen, total) {
+		g.rates.window.Add(rateEvictions, total-seen)
+	}
+}
+
+// ratesStats adds the recent rates to stats
+func (g *Group) ratesStats(stats *CacheStats) {
+	if g.rates == nil {
+		return
+	}
+	g.syncEvictions()
+	w := g.rates.window
+	stats.Rates = &GroupRates{
+		Version:       ratewindow.SchemaVersion,
+		BucketSeconds: ratewindow.BucketSeconds,
+		Buckets:       w.Buckets(),
+		Last1m:        rateSummary(w.Last(1)),
+		Last5m:        rateSummary(w.Last(5)),
+		Last15m:       rateSummary(w.Last(15)),
+	}
+}
+
+// rateSummary converts window sums into per-minute rates
+func rateSummary(s ratewindow.Sums) RateSummary {
+	r := RateSummary{
+		Minutes:   s.Minutes,
+		Hits:      s.Rate(rateHits),
+		Misses:    s.Rate(rateMisses),
+		Loads:     s.Rate(rateLoads),
+		Evictions: s.Rate(rateEvictions),
+		Errors:    s.Rate(rateErrors),
+	}
+	if lookups := s.Counts[rateHits] + s.Counts[rateMisses]; lookups > 0 {
+		r.HitRatio = float64(s.Counts[rateHits]) / float64(lookups)
+	}
+	return r
+}
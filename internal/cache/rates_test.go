@@ -0,0 +1,142 @@
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/internal/ratewindow"
+	"github.com/AdrianWangs/go-cache/pkg/lru"
+)
+
+// ratesGroup creates a group of cacheBytes on a fake clock set at the start of
+// a minute; keys starting with "bad" fail to load, "missing" is not found and
+// any other key loads as 16 bytes
+func ratesGroup(t *testing.T, cacheBytes int64, opts ...GroupOption) (*Group, *lru.FakeClock) {
+	t.Helper()
+	clock := lru.NewFakeClock(time.Unix(100*ratewindow.BucketSeconds, 0))
+	getter := GetterFunc(func(key string) ([]byte, error) {
+		switch {
+		case strings.HasPrefix(key, "bad"):
+			return nil, errors.New("source down")
+		case key == "missing":
+			return nil, ErrNotFound
+		}
+		return []byte(strings.Repeat("v", 16)), nil
+	})
+	opts = append([]GroupOption{WithRegistry(NewRegistry()), WithClock(clock)}, opts...)
+	g := NewGroup(t.Name(), cacheBytes, getter, 0, opts...)
+	t.Cleanup(func() { g.Close() })
+	return g, clock
+}
+
+func TestGroupRates(t *testing.T) {
+	g, clock := ratesGroup(t, 1<<20, WithStatsWindow(20))
+	mustGet(t, g, "a")
+	for i := 0; i < 3; i++ {
+		mustGet(t, g, "a")
+	}
+	g.Get("missing")
+	g.Get("bad")
+
+	// The minute in progress is not reported
+	if r := g.Stats().Rates; r == nil || r.Last1m.Minutes != 0 || r.Last1m.Hits != 0 {
+		t.Fatalf("rates during the first minute = %+v", r)
+	}
+	clock.Advance(time.Minute)
+	r := g.Stats().Rates
+	want := RateSummary{Minutes: 1, Hits: 3, Misses: 3, Loads: 3, Errors: 1, HitRatio: 0.5}
+	if r.Last1m != want {
+		t.Fatalf("last 1m = %+v, want %+v", r.Last1m, want)
+	}
+	if r.Version != ratewindow.SchemaVersion || r.BucketSeconds != 60 || r.Buckets != 20 {
+		t.Fatalf("rates = %+v", r)
+	}
+
+	// Four more minutes of two hits each: the 5m span averages all five
+	for m := 0; m < 4; m++ {
+		mustGet(t, g, "a")
+		mustGet(t, g, "a")
+		clock.Advance(time.Minute)
+	}
+	r = g.Stats().Rates
+	if r.Last1m.Hits != 2 || r.Last1m.HitRatio != 1 {
+		t.Fatalf("last 1m = %+v", r.Last1m)
+	}
+	if r.Last5m.Minutes != 5 || r.Last5m.Hits != 11.0/5 || r.Last5m.Misses != 3.0/5 {
+		t.Fatalf("last 5m = %+v", r.Last5m)
+	}
+	if r.Last15m.Minutes != 5 || r.Last15m != r.Last5m {
+		t.Fatalf("last 15m = %+v, want the 5m summary while the group is 5 minutes old", r.Last15m)
+	}
+
+	// An idle stretch longer than the window reports nothing
+	clock.Advance(30 * time.Minute)
+	r = g.Stats().Rates
+	if r.Last15m != (RateSummary{Minutes: 15}) {
+		t.Fatalf("last 15m after an idle stretch = %+v", r.Last15m)
+	}
+	if total := g.Stats().Hits; total != 11 {
+		t.Fatalf("lifetime hits = %d, want 11", total)
+	}
+}
+
+func TestGroupRatesEvictions(t *testing.T) {
+	g, clock := ratesGroup(t, 100)
+	before := g.Stats().Evictions
+	for _, key := range []string{"k1", "k2", "k3", "k4", "k5", "k6", "k7", "k8"} {
+		mustGet(t, g, key)
+	}
+	clock.Advance(time.Minute)
+	stats := g.Stats()
+	evicted := stats.Evictions - before
+	if evicted == 0 {
+		t.Fatal("no key evicted")
+	}
+	if got := stats.Rates.Last1m.Evictions; got != float64(evicted) {
+		t.Fatalf("last 1m evictions = %v, want %d", got, evicted)
+	}
+
+	// Evictions are picked up when the stats are read, with no event since
+	for _, key := range []string{"k9", "k10", "k11"} {
+		mustGet(t, g, key)
+	}
+	evicted = g.Stats().Evictions - stats.Evictions
+	clock.Advance(time.Minute)
+	if got := g.Stats().Rates.Last1m.Evictions; got != float64(evicted) {
+		t.Fatalf("last 1m evictions = %v, want %d", got, evicted)
+	}
+}
+
+func TestGroupRatesJSON(t *testing.T) {
+	g, _ := ratesGroup(t, 1<<20)
+	out, err := json.Marshal(g.Stats())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stats struct {
+		Rates map[string]json.RawMessage `json:"rates"`
+	}
+	if err := json.Unmarshal(out, &stats); err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range []string{"version", "bucket_seconds", "buckets", "last_1m", "last_5m", "last_15m"} {
+		if _, ok := stats.Rates[field]; !ok {
+			t.Errorf("rates without %s: %s", field, out)
+		}
+	}
+	if string(stats.Rates["version"]) != "1" || string(stats.Rates["buckets"]) != "60" {
+		t.Errorf("rates = %s", out)
+	}
+
+	disabled, _ := ratesGroup(t, 1<<20, WithStatsWindow(0))
+	mustGet(t, disabled, "a")
+	if disabled.Stats().Rates != nil {
+		t.Fatal("rates reported with the window disabled")
+	}
+	if out, _ := json.Marshal(disabled.Stats()); strings.Contains(string(out), `"rates"`) {
+		t.Fatalf("rates in the JSON with the window disabled: %s", out)
+	}
+}
@@ -0,0 +1,95 @@
+package cache
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/AdrianWangs/go-cache/pkg/logger"
+	"github.com/AdrianWangs/go-cache/pkg/lru"
+)
+
+// defaultRefreshConcurrency is the default maximum number of background
+// refresh-ahead loads running at once in a group
+const defaultRefreshConcurrency = 4
+
+// initRefreshAhead validates the refresh-ahead options once all of them have been applied
+func (g *Group) initRefreshAhead() {
+	if g.refreshFactor <= 0 || g.refreshFactor >= 1 {
+		g.refreshFactor = 0
+		return
+	}
+	if g.refreshConcurrency <= 0 {
+		g.refreshConcurrency = defaultRefreshConcurrency
+	}
+	g.refreshSem = make(chan struct{}, g.refreshConcurrency)
+}
+
+// maybeRefresh starts a background refresh of key when the entry that was just
+// served has consumed more than refreshFactor of its ttl and is still being read.
+// The caller has already been answered from the cache, so the refresh never adds latency.
+func (g *Group) maybeRefresh(key string, expiry lru.Expiry) {
+	if g.refreshSem == nil || expiry.TTL <= 0 || g.Mode().ReadOnly() {
+		return
+	}
+
+	now := g.clock.Now()
+	if expiry.Consumed(now) < g.refreshFactor {
+		return
+	}
+
+	// Only refresh entries that were read before this hit within the window;
+	// keys touched once near the end of their life are left to expire
+	window := g.refreshWindow
+	if window <= 0 {
+		window = expiry.TTL
+	}
+	if expiry.LastAccess.IsZero() || now.Sub(expiry.LastAccess) > window {
+		return
+	}
+
+	// One refresh per key at a time
+	if _, running := g.refreshing.LoadOrStore(key, struct{}{}); running {
+		return
+	}
+
+	// Never queue: if the group is already at its concurrency limit the entry
+	// is simply served until a later hit or its expiry
+	select {
+	case g.refreshSem <- struct{}{}:
+	default:
+		g.refreshing.Delete(key)
+		return
+	}
+
+	release := func() {
+		<-g.refreshSem
+		g.refreshing.Delete(key)
+	}
+	ok := g.goBackground(func(ctx context.Context) {
+		defer release()
+
+		// Go through the normal load path so the refresh shares singleflight
+		// with foreground misses and asks the owning peer first; Close cancels it
+		started := g.clock.Now()
+		value, _, err := g.load(ctx, key)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			atomic.AddInt64(&g.refreshFailures, 1)
+			g.log.Warnf("[Cache] 提前刷新失败: group=%s, key=%s, err=%v", g.name, logger.Key(key), err)
+			return
+		}
+
+		// getLocally has already stored locally loaded values; storing again also
+		// covers values returned by a peer, so the local ttl restarts either way.
+		// The adaptive ttl getLocally chose is kept rather than adapted twice.
+		g.populateCache(key, value, g.storedTTL(key), started)
+	})
+	if !ok {
+		release()
+		return
+	}
+	atomic.AddInt64(&g.refreshAheads, 1)
+	g.log.Debugf("[Cache] 触发提前刷新: group=%s, key=%s", g.name, logger.Key(key))
+}
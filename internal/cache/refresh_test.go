@@ -0,0 +1,135 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/pkg/lru"
+)
+
+// versionGetter returns a new version of every key on each load
+type versionGetter struct {
+	loads atomic.Int64
+	fail  atomic.Bool
+}
+
+func (v *versionGetter) Get(key string) ([]byte, error) {
+	n := v.loads.Add(1)
+	if v.fail.Load() {
+		return nil, errors.New("origin down")
+	}
+	return []byte(fmt.Sprintf("%s@%d", key, n)), nil
+}
+
+// refreshIdle reports whether no background refresh is running
+func refreshIdle(g *Group) bool {
+	return len(g.refreshSem) == 0
+}
+
+func TestRefreshAheadKeepsHotEntryAlive(t *testing.T) {
+	clock := lru.NewFakeClock(time.Unix(1000, 0))
+	getter := &versionGetter{}
+	g := newTestGroup(t, getter, 10*time.Second, WithClock(clock), WithRefreshAhead(0.5))
+
+	mustGet(t, g, "k")
+	// Read every 3s for ten ttls: each read past half the ttl refreshes the
+	// entry in the background, so no read ever finds it expired
+	for i := 0; i < 35; i++ {
+		clock.Advance(3 * time.Second)
+		before := g.Stats().Gets - g.Stats().Hits
+		mustGet(t, g, "k")
+		if misses := g.Stats().Gets - g.Stats().Hits; misses != before {
+			t.Fatalf("read %d at %v missed the cache", i, clock.Now())
+		}
+		waitFor(t, "refresh", func() bool { return refreshIdle(g) })
+	}
+
+	stats := g.Stats()
+	if stats.RefreshAheads == 0 || stats.RefreshFailures != 0 {
+		t.Fatalf("RefreshAheads = %d, RefreshFailures = %d", stats.RefreshAheads, stats.RefreshFailures)
+	}
+	if got := getter.loads.Load(); got != stats.RefreshAheads+1 {
+		t.Fatalf("loads = %d, want the first load plus %d refreshes", got, stats.RefreshAheads)
+	}
+	if v := mustGet(t, g, "k"); v == "k@1" {
+		t.Fatalf("value was never refreshed: %q", v)
+	}
+}
+
+func TestRefreshAheadSkipsColdEntries(t *testing.T) {
+	clock := lru.NewFakeClock(time.Unix(1000, 0))
+	getter := &versionGetter{}
+	g := newTestGroup(t, getter, 10*time.Second, WithClock(clock), WithRefreshAhead(0.5), WithRefreshAheadWindow(2*time.Second))
+
+	mustGet(t, g, "k")
+	// Hits past half the ttl whose previous read is older than the window
+	clock.Advance(6 * time.Second)
+	mustGet(t, g, "k")
+	clock.Advance(3 * time.Second)
+	mustGet(t, g, "k")
+	waitFor(t, "refresh", func() bool { return refreshIdle(g) })
+	if n := g.Stats().RefreshAheads; n != 0 {
+		t.Fatalf("RefreshAheads = %d, want 0 for an entry read rarely", n)
+	}
+
+	// A read within the window of the previous one refreshes
+	clock.Advance(500 * time.Millisecond)
+	mustGet(t, g, "k")
+	waitFor(t, "refresh", func() bool { return refreshIdle(g) })
+	if n := g.Stats().RefreshAheads; n != 1 {
+		t.Fatalf("RefreshAheads = %d, want 1", n)
+	}
+}
+
+func TestRefreshAheadCountsFailures(t *testing.T) {
+	clock := lru.NewFakeClock(time.Unix(1000, 0))
+	getter := &versionGetter{}
+	g := newTestGroup(t, getter, 10*time.Second, WithClock(clock), WithRefreshAhead(0.5))
+
+	mustGet(t, g, "k")
+	getter.fail.Store(true)
+	clock.Advance(4 * time.Second)
+	mustGet(t, g, "k")
+	clock.Advance(2 * time.Second)
+	// A failed refresh leaves the cached value in place
+	if v := mustGet(t, g, "k"); v != "k@1" {
+		t.Fatalf("value = %q, want k@1", v)
+	}
+	waitFor(t, "failed refresh", func() bool { return g.Stats().RefreshFailures == 1 })
+	if n := g.Stats().RefreshAheads; n != 1 {
+		t.Fatalf("RefreshAheads = %d, want 1", n)
+	}
+}
+
+func TestRefreshAheadConcurrencyLimit(t *testing.T) {
+	clock := lru.NewFakeClock(time.Unix(1000, 0))
+	release := make(chan struct{})
+	var blocking atomic.Bool
+	g := newTestGroup(t, GetterFunc(func(key string) ([]byte, error) {
+		if blocking.Load() {
+			<-release
+		}
+		return []byte(key), nil
+	}), 10*time.Second, WithClock(clock), WithRefreshAhead(0.5), WithRefreshAheadConcurrency(1))
+
+	for _, key := range []string{"a", "b"} {
+		mustGet(t, g, key)
+	}
+	clock.Advance(time.Second)
+	for _, key := range []string{"a", "b"} {
+		mustGet(t, g, key)
+	}
+	blocking.Store(true)
+	clock.Advance(5 * time.Second)
+	// The refresh of a holds the only slot, so b is served without one
+	mustGet(t, g, "a")
+	mustGet(t, g, "b")
+	if n := g.Stats().RefreshAheads; n != 1 {
+		t.Fatalf("RefreshAheads = %d, want 1 with a concurrency of 1", n)
+	}
+	close(release)
+	waitFor(t, "refresh", func() bool { return refreshIdle(g) })
+}
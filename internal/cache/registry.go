@@ -0,0 +1,148 @@
+package cache
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
+)
+
+// Registry holds a set of groups by name. Package-level functions such as
+// NewGroup and GetGroup use the default registry; a separate Registry lets
+// several nodes run in one process, e.g. in the test cluster harness.
+type Registry struct {
+	mu     sync.RWMutex
+	groups map[string]*Group
+
+	provider  *groupProvider      // creates groups on a miss, nil unless RegisterGroupProvider
+	provided  map[*Group]struct{} // groups the provider created that are still registered
+	providing int                 // provider calls in progress, counted against the cap
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{groups: make(map[string]*Group), provided: make(map[*Group]struct{})}
+}
+
+// defaultRegistry backs NewGroup, GetGroup and the other package-level functions
+var defaultRegistry = NewRegistry()
+
+// DefaultRegistry returns the registry used by the package-level functions
+func DefaultRegistry() *Registry {
+	return defaultRegistry
+}
+
+// add registers g, replacing any group with the same name
+func (r *Registry) add(g *Group) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.groups[g.name] = g
+}
+
+// remove unregisters g, leaving a group that has since replaced it by name
+func (r *Registry) remove(g *Group) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.groups[g.name] == g {
+		delete(r.groups, g.name)
+	}
+	delete(r.provided, g)
+}
+
+// Close removes the group provider, closes every registered group, see
+// Group.Close, and leaves r empty
+func (r *Registry) Close() error {
+	r.mu.Lock()
+	p := r.provider
+	r.provider = nil
+	groups := make([]*Group, 0, len(r.groups))
+	for _, g := range r.groups {
+		groups = append(groups, g)
+	}
+	r.mu.Unlock()
+
+	p.stopReaper()
+	for _, g := range groups {
+		g.Close()
+	}
+	return nil
+}
+
+// Get returns the named group. When it is not registered, the provider set by
+// RegisterGroupProvider creates it; without one, or when the provider declines,
+// Get returns nil.
+func (r *Registry) Get(name string) *Group {
+	r.mu.RLock()
+	g, p := r.groups[name], r.provider
+	if g != nil {
+		r.touch(g)
+	}
+	r.mu.RUnlock()
+	if g != nil || p == nil {
+		return g
+	}
+	return r.provide(p, name)
+}
+
+// lookup returns the named group if it is registered, without consulting the provider
+func (r *Registry) lookup(name string) *Group {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.groups[name]
+}
+
+// List returns a snapshot of every registered group, sorted by name
+func (r *Registry) List() []GroupInfo {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	infos := make([]GroupInfo, 0, len(r.groups))
+	for _, g := range r.groups {
+		infos = append(infos, g.Info())
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name < infos[j].Name
+	})
+	return infos
+}
+
+// Names returns the names of every registered group, sorted
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.groups))
+	for name := range r.groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Groups returns a copy of the name to group map
+func (r *Registry) Groups() map[string]*Group {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make(map[string]*Group, len(r.groups))
+	for k, v := range r.groups {
+		result[k] = v
+	}
+	return result
+}
+
+// StatsResponse builds the Stats RPC response for the groups in r, see StatsResponse
+func (r *Registry) StatsResponse(name string, uptime time.Duration) (*pb.StatsResponse, error) {
+	var infos []GroupInfo
+	if name == "" {
+		infos = r.List()
+	} else {
+		g := r.lookup(name)
+		if g == nil {
+			return nil, ErrNoSuchGroup
+		}
+		infos = []GroupInfo{g.Info()}
+	}
+	return statsResponse(infos, uptime), nil
+}
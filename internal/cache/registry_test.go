@@ -0,0 +1,113 @@
+package cache
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/pkg/lru"
+)
+
+func TestRegistryList(t *testing.T) {
+	reg := NewRegistry()
+	t.Cleanup(func() { reg.Close() })
+	clock := lru.NewFakeClock(time.Unix(1000, 0))
+	newGroup := func(name string, ttl time.Duration, opts ...GroupOption) *Group {
+		return NewGroup(name, 1<<20, GetterFunc(loadValue), ttl, append([]GroupOption{WithRegistry(reg), WithClock(clock)}, opts...)...)
+	}
+	b := newGroup("b", time.Minute, WithMaxAge(10*time.Second), WithMaxIdle(5*time.Second),
+		WithEvictionPolicy(lru.PolicyClock), WithMode(ModeReadOnly), WithDefaultDeadline(time.Second))
+	clock.Advance(time.Second)
+	a := newGroup("a", time.Hour)
+	mustGet(t, a, "k")
+	mustGet(t, a, "k")
+
+	infos := reg.List()
+	if len(infos) != 2 || infos[0].Name != "a" || infos[1].Name != "b" {
+		t.Fatalf("List() = %+v, want a and b by name", infos)
+	}
+	if got := infos[0]; got.MaxBytes != 1<<20 || got.TTL != time.Hour || got.MaxAge != 0 || got.Eviction != "lru" ||
+		got.Mode != "readwrite" || !got.CreatedAt.Equal(time.Unix(1001, 0)) || got.Stats.Gets != 2 || got.Stats.Hits != 1 || got.Stats.Entries != 1 {
+		t.Fatalf("info of a = %+v", got)
+	}
+	if got := infos[1]; got.TTL != time.Minute || got.MaxAge != 10*time.Second || got.MaxIdle != 5*time.Second ||
+		got.Eviction != "clock" || got.Mode != "readonly" || got.DefaultDeadline != time.Second || !got.CreatedAt.Equal(time.Unix(1000, 0)) {
+		t.Fatalf("info of b = %+v", got)
+	}
+	if names := reg.Names(); len(names) != 2 || names[0] != "a" || names[1] != "b" {
+		t.Fatalf("Names() = %v", names)
+	}
+
+	// The Stats RPC answers for one group or fails for an unknown one
+	resp, err := reg.StatsResponse("a", time.Minute)
+	if err != nil || len(resp.GetGroups()) != 1 || resp.GetGroups()[0].GetHits() != 1 || resp.GetUptimeSeconds() != 60 {
+		t.Fatalf("StatsResponse(a) = %v, %v", resp, err)
+	}
+	if _, err := reg.StatsResponse("missing", time.Minute); !IsGroupNotFoundError(err) {
+		t.Fatalf("StatsResponse(missing) = %v, want ErrNoSuchGroup", err)
+	}
+	if reg.Get("missing") != nil {
+		t.Fatal("Get(missing) returned a group")
+	}
+
+	// A closed group is no longer listed
+	b.Close()
+	if infos := reg.List(); len(infos) != 1 || infos[0].Name != "a" {
+		t.Fatalf("List() after closing b = %+v", infos)
+	}
+}
+
+// TestRegistryListConcurrent lists groups while others are created, read and
+// closed; run with -race it checks that List takes the registry lock and
+// snapshots stats without racing the writers
+func TestRegistryListConcurrent(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+	reg := NewRegistry()
+	t.Cleanup(func() { reg.Close() })
+
+	const writers, groups = 4, 50
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < groups; i++ {
+				g := NewGroup(fmt.Sprintf("g-%d-%d", w, i), 1<<20, GetterFunc(loadValue), time.Minute, WithRegistry(reg))
+				g.Get("k")
+				if i%2 == 1 {
+					g.Close()
+				}
+			}
+		}(w)
+	}
+	listed := make(chan int)
+	go func() {
+		n := 0
+		for {
+			select {
+			case <-stop:
+				listed <- n
+				return
+			default:
+			}
+			for _, info := range reg.List() {
+				if info.Name == "" || info.MaxBytes != 1<<20 || info.Stats.Gets > 1 {
+					t.Errorf("listed %+v", info)
+				}
+			}
+			n++
+		}
+	}()
+	wg.Wait()
+	close(stop)
+	if n := <-listed; n == 0 {
+		t.Fatal("List never ran")
+	}
+
+	if infos := reg.List(); len(infos) != writers*groups/2 {
+		t.Fatalf("%d groups listed, want %d", len(infos), writers*groups/2)
+	}
+}
@@ -0,0 +1,184 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/internal/peers"
+	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
+)
+
+// namedPeer returns name+":"+key for every key, after release is closed when
+// it is not nil
+type namedPeer struct {
+	name    string
+	calls   atomic.Int64
+	entered chan struct{} // receives once per fetch when not nil
+	release chan struct{}
+}
+
+func (p *namedPeer) Get(group, key string) ([]byte, error) {
+	p.calls.Add(1)
+	if p.entered != nil {
+		p.entered <- struct{}{}
+	}
+	if p.release != nil {
+		<-p.release
+	}
+	return []byte(p.name + ":" + key), nil
+}
+
+func (p *namedPeer) GetByProto(req *pb.Request, resp *pb.Response) error {
+	v, err := p.Get(req.GetGroup(), req.GetKey())
+	resp.Value = v
+	return err
+}
+
+// countingPicker routes every key to peer and counts the PickPeer calls
+type countingPicker struct {
+	peer  peers.PeerGetter
+	picks atomic.Int64
+}
+
+func (p *countingPicker) PickPeer(key string) (peers.PeerGetter, bool) {
+	p.picks.Add(1)
+	return p.peer, true
+}
+
+func TestReplacePeers(t *testing.T) {
+	g := newTestGroup(t, GetterFunc(loadValue), time.Hour)
+	a := &countingPicker{peer: &namedPeer{name: "a"}}
+	b := &countingPicker{peer: &namedPeer{name: "b"}}
+
+	g.RegisterPeers(a)
+	if v := mustGet(t, g, "k1"); v != "a:k1" {
+		t.Fatalf("Get with picker a = %q", v)
+	}
+
+	// A second RegisterPeers is ignored
+	g.RegisterPeers(b)
+	if v := mustGet(t, g, "k2"); v != "a:k2" || b.picks.Load() != 0 {
+		t.Fatalf("Get after a second RegisterPeers = %q, b picked %d times", v, b.picks.Load())
+	}
+
+	// The swapped-in picker is asked on the very next Get
+	aPicks := a.picks.Load()
+	g.ReplacePeers(b)
+	if v := mustGet(t, g, "k3"); v != "b:k3" || b.picks.Load() != 1 || a.picks.Load() != aPicks {
+		t.Fatalf("Get after ReplacePeers = %q, a picked %d times, b %d", v, a.picks.Load()-aPicks, b.picks.Load())
+	}
+
+	// nil detaches the group: it loads from its data source and can register again
+	g.ReplacePeers(nil)
+	v, meta, err := g.GetWithMeta(context.Background(), "k4")
+	if err != nil || v.String() != "v:k4" || meta.Source != SourceLoader || b.picks.Load() != 1 {
+		t.Fatalf("Get after ReplacePeers(nil) = %q %v %v, b picked %d times", v.String(), meta.Source, err, b.picks.Load())
+	}
+	g.RegisterPeers(a)
+	if v := mustGet(t, g, "k5"); v != "a:k5" {
+		t.Fatalf("Get after registering again = %q", v)
+	}
+}
+
+// TestReplacePeersDuringLoad swaps the picker while a fetch from the old
+// picker's peer is running: the fetch completes with the old peer's value and
+// the next load uses the new picker
+func TestReplacePeersDuringLoad(t *testing.T) {
+	g := newTestGroup(t, GetterFunc(loadValue), time.Hour)
+	old := &namedPeer{name: "old", entered: make(chan struct{}, 1), release: make(chan struct{})}
+	g.RegisterPeers(&countingPicker{peer: old})
+
+	got := make(chan string, 1)
+	go func() {
+		v, err := g.Get("k")
+		if err != nil {
+			got <- err.Error()
+			return
+		}
+		got <- v.String()
+	}()
+	<-old.entered
+
+	next := &countingPicker{peer: &namedPeer{name: "new"}}
+	g.ReplacePeers(next)
+	if v := mustGet(t, g, "other"); v != "new:other" {
+		t.Fatalf("Get during the old fetch = %q", v)
+	}
+	close(old.release)
+	select {
+	case v := <-got:
+		if v != "old:k" {
+			t.Fatalf("in-flight Get = %q, want the old peer's value", v)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("in-flight Get did not return")
+	}
+	if v := mustGet(t, g, "k2"); v != "new:k2" || old.calls.Load() != 1 {
+		t.Fatalf("Get after the old fetch = %q, old peer called %d times", v, old.calls.Load())
+	}
+}
+
+// TestReplacePeersConcurrent swaps pickers, nil included, continuously while
+// Gets, Deletes and Sets run; run it with -race
+func TestReplacePeersConcurrent(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+	g := newTestGroup(t, GetterFunc(loadValue), time.Hour)
+	pickers := []peers.PeerPicker{
+		&countingPicker{peer: &namedPeer{name: "a"}},
+		&countingPicker{peer: &namedPeer{name: "b"}},
+		nil,
+	}
+
+	stop := make(chan struct{})
+	var swaps sync.WaitGroup
+	swaps.Add(1)
+	go func() {
+		defer swaps.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+				g.ReplacePeers(pickers[i%len(pickers)])
+				time.Sleep(100 * time.Microsecond)
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				key := fmt.Sprintf("k%d-%d", w, i)
+				v, err := g.Get(key)
+				if err != nil {
+					errs <- err
+					return
+				}
+				if s := v.String(); s != "a:"+key && s != "b:"+key && s != "v:"+key {
+					errs <- fmt.Errorf("Get(%s) = %q", key, s)
+					return
+				}
+				if i%10 == 0 {
+					g.Delete(key)
+					g.Set(key, []byte("set"), time.Minute)
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(stop)
+	swaps.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,85 @@
+package cache
+
+import (
+	"encoding/hex"
+	"math/rand"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/pkg/lru"
+)
+
+// SampleEntry is one entry of a random sample of a group, see Group.Sample
+type SampleEntry struct {
+	Key        string        // original key, empty in key-digest mode without retained keys
+	KeyDigest  string        // hex digest the entry is stored under, only set when Key is empty
+	Size       int           // stored value size in bytes, encoded with WithValueTransform
+	TTL        time.Duration // remaining lifetime including MaxAge, 0 means the entry never expires
+	LastAccess time.Time     // last read, zero if never read or untracked
+	Accesses   uint64        // number of reads, 0 when access tracking is disabled
+	Value      ByteView      // the cached value, shared with the cache and not copied unless decoded
+}
+
+// sampleSlot is a reservoir slot filled while the cache is locked; converting it
+// to a SampleEntry is left until after the walk
+type sampleSlot struct {
+	key    string
+	value  lru.Value
+	expiry lru.Expiry
+}
+
+// Sample returns up to n live entries of the group chosen uniformly at random,
+// together with the number of live entries seen. It walks the cache once with
+// reservoir sampling, so memory use is bounded by n rather than the cache size.
+// rnd may be nil to use the global source.
+func (g *Group) Sample(n int, rnd *rand.Rand) ([]SampleEntry, int) {
+	if n <= 0 {
+		return nil, 0
+	}
+	intn := rand.Intn
+	if rnd != nil {
+		intn = rnd.Intn
+	}
+
+	reservoir := make([]sampleSlot, 0, n)
+	seen := 0
+	g.mainCache.rangeEntries(func(key string, value lru.Value, expiry lru.Expiry) bool {
+		seen++
+		slot := sampleSlot{key: key, value: value, expiry: expiry}
+		if len(reservoir) < n {
+			reservoir = append(reservoir, slot)
+		} else if j := intn(seen); j < n {
+			reservoir[j] = slot
+		}
+		return true
+	})
+
+	now := g.clock.Now()
+	entries := make([]SampleEntry, 0, len(reservoir))
+	for _, slot := range reservoir {
+		e := SampleEntry{Key: slot.key, LastAccess: slot.expiry.LastAccess, Accesses: slot.expiry.Accesses}
+		switch val := slot.value.(type) {
+		case ByteView:
+			e.Value = val
+		case hashedEntry:
+			e.Key = val.key
+			if e.Key == "" {
+				e.KeyDigest = hex.EncodeToString([]byte(slot.key))
+			}
+			e.Value = val.view
+		default:
+			continue
+		}
+		e.Size = e.Value.Len()
+		var err error
+		if e.Value, err = g.decodeValue(slot.key, e.Value); err != nil {
+			continue // fails reads as well, and is dropped by the next one
+		}
+		if deadline := g.exportExpiry(slot.expiry); deadline != 0 {
+			if e.TTL = time.Unix(0, deadline).Sub(now); e.TTL <= 0 {
+				continue // expired since the walk
+			}
+		}
+		entries = append(entries, e)
+	}
+	return entries, seen
+}
@@ -0,0 +1,98 @@
+package cache
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// filledGroup returns a group holding n entries key-0 .. key-(n-1)
+func filledGroup(t testing.TB, n int) *Group {
+	t.Helper()
+	g := NewGroup(fmt.Sprintf("%s-%d", t.Name(), time.Now().UnixNano()), 1<<30, newCountingGetter(nil), time.Hour, WithRegistry(NewRegistry()))
+	t.Cleanup(func() { g.Close() })
+	for i := 0; i < n; i++ {
+		if err := g.Set(fmt.Sprintf("key-%d", i), []byte("value"), 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return g
+}
+
+func TestSampleSize(t *testing.T) {
+	g := filledGroup(t, 100)
+	for _, n := range []int{0, 1, 20, 100, 500} {
+		entries, seen := g.Sample(n, rand.New(rand.NewSource(1)))
+		want := min(n, 100)
+		if len(entries) != want || (n > 0 && seen != 100) {
+			t.Fatalf("Sample(%d) = %d entries of %d seen, want %d of 100", n, len(entries), seen, want)
+		}
+		keys := make(map[string]bool)
+		for _, e := range entries {
+			if keys[e.Key] {
+				t.Fatalf("Sample(%d) returned %s twice", n, e.Key)
+			}
+			keys[e.Key] = true
+			if e.Size != len("value") || e.TTL <= 0 || e.TTL > time.Hour || e.Value.String() != "value" {
+				t.Fatalf("Sample(%d) entry %+v", n, e)
+			}
+		}
+	}
+}
+
+func TestSampleRandomness(t *testing.T) {
+	g := filledGroup(t, 100)
+
+	// separate calls return different samples
+	first, _ := g.Sample(10, nil)
+	differs := false
+	for i := 0; i < 10 && !differs; i++ {
+		next, _ := g.Sample(10, nil)
+		for j := range next {
+			if next[j].Key != first[j].Key {
+				differs = true
+				break
+			}
+		}
+	}
+	if !differs {
+		t.Fatal("10 calls returned the same sample")
+	}
+
+	// every entry is picked with probability n/size: 10 of 100 over 2000 calls is 200 each
+	rnd := rand.New(rand.NewSource(42))
+	picks := make(map[string]int)
+	for i := 0; i < 2000; i++ {
+		entries, _ := g.Sample(10, rnd)
+		for _, e := range entries {
+			picks[e.Key]++
+		}
+	}
+	if len(picks) != 100 {
+		t.Fatalf("only %d of 100 keys were ever sampled", len(picks))
+	}
+	for key, n := range picks {
+		if n < 120 || n > 280 {
+			t.Fatalf("%s sampled %d times, want about 200", key, n)
+		}
+	}
+}
+
+// TestSampleDoesNotMaterialize checks that sampling allocates the same for a
+// cache a hundred times larger: the walk streams and only the reservoir is kept.
+func TestSampleDoesNotMaterialize(t *testing.T) {
+	allocs := func(size int) float64 {
+		g := filledGroup(t, size)
+		rnd := rand.New(rand.NewSource(1))
+		return testing.AllocsPerRun(5, func() {
+			if _, seen := g.Sample(20, rnd); seen != size {
+				t.Fatalf("saw %d of %d entries", seen, size)
+			}
+		})
+	}
+	small, large := allocs(1000), allocs(100000)
+	if large > small+5 {
+		t.Fatalf("Sample allocates %.0f times for 100000 entries, %.0f for 1000", large, small)
+	}
+}
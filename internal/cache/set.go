@@ -0,0 +1,81 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/internal/peers"
+	"github.com/AdrianWangs/go-cache/pkg/logger"
+	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
+	"google.golang.org/protobuf/proto"
+)
+
+// Set stores value under key, see SetWithContext.
+func (g *Group) Set(key string, value []byte, ttl time.Duration) error {
+	return g.SetWithContext(context.Background(), key, value, ttl)
+}
+
+// SetWithContext stores value under key for ttl, or the group's default ttl when
+// ttl <= 0. When another peer owns the key and implements peers.PeerSetter the
+// write goes to the owner, which is where reads for the key are routed. Peers
+// without that capability degrade to a local write on this node.
+func (g *Group) SetWithContext(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if key == "" {
+		return ErrEmptyKey
+	}
+	if g.closed.Load() {
+		return ErrGroupClosed
+	}
+	if g.Mode().ReadOnly() {
+		return ErrReadOnly
+	}
+	if picker := g.peerPicker(); picker != nil {
+		if peer, ok := picker.PickPeer(key); ok {
+			if setter, ok := peer.(peers.PeerSetter); ok {
+				req := &pb.SetRequest{Group: g.name, Key: key, Value: value}
+				if ttl > 0 {
+					req.TtlMs = proto.Int64(max(ttl.Milliseconds(), 1))
+				}
+				if err := setter.SetByProto(ctx, req, &pb.SetResponse{}); err != nil {
+					if errors.Is(err, peers.ErrUnsupported) {
+						// Writing locally would not be seen by reads, which go to the owner
+						g.log.Warnf("[Cache] owner of key:%s cannot accept writes: %v", logger.Key(key), err)
+						return WrapError(ErrTypeInternalError, "owner peer does not support set", err)
+					}
+					return WrapError(ErrTypeNetworkError, "failed to set on owner peer", err)
+				}
+				g.forgetDelete(key)
+				return nil
+			}
+			g.log.Warnf("[Cache] owner of key:%s does not accept writes, storing locally in group:%s", logger.Key(key), g.name)
+		}
+	}
+	return g.SetLocally(key, value, ttl)
+}
+
+// SetLocally stores value under key in this node's cache only, with the group's
+// default ttl when ttl <= 0. Like DeleteLocally it serves writes already routed to
+// this node. It fails with ErrReadOnly in read-only mode.
+func (g *Group) SetLocally(key string, value []byte, ttl time.Duration) error {
+	if key == "" {
+		return ErrEmptyKey
+	}
+	if g.closed.Load() {
+		return ErrGroupClosed
+	}
+	if g.Mode().ReadOnly() {
+		return ErrReadOnly
+	}
+	if ttl <= 0 {
+		ttl = g.ttl
+	}
+	g.clearTombstone(key)
+	if err := g.populateCache(key, ByteView{bytes: cloneBytes(value)}, ttl, time.Time{}); err != nil {
+		return err
+	}
+	g.invalidateReplicas(key)
+	g.clearMarker(key)
+	g.forgetDelete(key)
+	return nil
+}
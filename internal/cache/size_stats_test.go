@@ -0,0 +1,87 @@
+package cache
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/pkg/lru"
+)
+
+// checkSize fails the test unless the group reports exactly bytes and entries,
+// through its accessors and its stats alike
+func checkSize(t *testing.T, g *Group, what string, bytes int64, entries int) {
+	t.Helper()
+	s := g.Stats()
+	if g.Bytes() != bytes || s.Bytes != bytes || g.Entries() != entries || s.Entries != int64(entries) {
+		t.Fatalf("%s: Bytes() %d, Stats.Bytes %d, Entries() %d, Stats.Entries %d; want %d bytes in %d entries",
+			what, g.Bytes(), s.Bytes, g.Entries(), s.Entries, bytes, entries)
+	}
+	if s.MaxBytes != g.MaxBytes() || s.Cost != bytes {
+		t.Fatalf("%s: MaxBytes %d (group %d), Cost %d", what, s.MaxBytes, g.MaxBytes(), s.Cost)
+	}
+}
+
+func TestSizeStatsTrackContents(t *testing.T) {
+	clock := lru.NewFakeClock(time.Unix(1000, 0))
+	r := NewRegistry()
+	t.Cleanup(func() { r.Close() })
+	g := NewGroup("sized", 100, GetterFunc(func(string) ([]byte, error) { return nil, ErrNotFound }), time.Hour,
+		WithRegistry(r), WithClock(clock), WithSweepInterval(5*time.Millisecond))
+	checkSize(t, g, "empty", 0, 0)
+
+	// Each entry is a 2 byte key and an 18 byte value
+	value := []byte(strings.Repeat("v", 18))
+	for i := 0; i < 5; i++ {
+		if err := g.Set(fmt.Sprintf("k%d", i), value, time.Hour); err != nil {
+			t.Fatal(err)
+		}
+	}
+	checkSize(t, g, "full", 100, 5)
+	if fill := g.Stats().FillPercent(); fill != 100 {
+		t.Fatalf("FillPercent = %v, want 100", fill)
+	}
+
+	// One more entry evicts the oldest
+	if err := g.Set("k5", value, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	checkSize(t, g, "after an eviction", 100, 5)
+	if g.Stats().Evictions != 1 {
+		t.Fatalf("Evictions = %d, want 1", g.Stats().Evictions)
+	}
+
+	// Overwriting with a shorter value shrinks the entry in place
+	if err := g.Set("k5", []byte("short"), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	checkSize(t, g, "after a rewrite", 87, 5)
+
+	if err := g.DeleteLocally("k1"); err != nil {
+		t.Fatal(err)
+	}
+	checkSize(t, g, "after a delete", 67, 4)
+
+	// Entries past their ttl are removed by the sweeper without being read
+	if err := g.Set("k2", value, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(2 * time.Second)
+	waitFor(t, "the sweeper to remove the expired entry", func() bool { return g.Entries() == 3 })
+	checkSize(t, g, "after the sweep", 47, 3)
+	if fill := g.Stats().FillPercent(); fill != 47 {
+		t.Fatalf("FillPercent = %v, want 47", fill)
+	}
+
+	if err := g.Clear(); err != nil {
+		t.Fatal(err)
+	}
+	checkSize(t, g, "after Clear", 0, 0)
+}
+
+func TestFillPercentWithoutLimit(t *testing.T) {
+	if fill := (CacheStats{Cost: 10}).FillPercent(); fill != 0 {
+		t.Fatalf("FillPercent without MaxBytes = %v", fill)
+	}
+}
@@ -0,0 +1,119 @@
+package cache
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/pkg/lru"
+)
+
+// TestStandaloneLoadsOnce reads one key from many callers of a group without
+// peers: they share a single load from the getter and all get its value
+func TestStandaloneLoadsOnce(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+	const callers = 50
+	release := make(chan struct{})
+	getter := newCountingGetter(map[string]string{"k": "v"})
+	g := newTestGroup(t, GetterFunc(func(key string) ([]byte, error) {
+		<-release
+		return getter.Get(key)
+	}), time.Hour)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := g.Get("k")
+			if err == nil && v.String() != "v" {
+				err = errors.New("got " + v.String())
+			}
+			errs <- err
+		}()
+	}
+	waitFor(t, "every caller to join the load", func() bool {
+		return g.Stats().LoadsDeduped == callers-1
+	})
+	close(release)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if n := getter.count("k"); n != 1 {
+		t.Fatalf("getter called %d times, want 1", n)
+	}
+	if s := g.Stats(); s.LoadsExecuted != 1 || s.Gets != callers || s.Entries != 1 {
+		t.Fatalf("stats = %+v", s)
+	}
+}
+
+// TestStandaloneTTLAndDelete checks that hits, expiry and deletes behave the
+// same without peers as with them
+func TestStandaloneTTLAndDelete(t *testing.T) {
+	clock := lru.NewFakeClock(time.Unix(1000, 0))
+	getter := newCountingGetter(map[string]string{"k": "v1"})
+	g := newTestGroup(t, getter, time.Minute, WithClock(clock))
+
+	mustGet(t, g, "k")
+	getter.set("k", "v2")
+	if v := mustGet(t, g, "k"); v != "v1" || getter.count("k") != 1 {
+		t.Fatalf("second read = %q after %d loads, want a hit on v1", v, getter.count("k"))
+	}
+	if s := g.Stats(); s.Hits != 1 || s.Gets != 2 {
+		t.Fatalf("stats = %+v", s)
+	}
+
+	clock.Advance(time.Minute + time.Second)
+	if v := mustGet(t, g, "k"); v != "v2" || getter.count("k") != 2 {
+		t.Fatalf("read after the ttl = %q after %d loads, want a reload of v2", v, getter.count("k"))
+	}
+
+	getter.set("k", "v3")
+	if err := g.Delete("k"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, ok := g.Peek("k"); ok {
+		t.Fatal("key still cached after Delete")
+	}
+	if v := mustGet(t, g, "k"); v != "v3" {
+		t.Fatalf("read after Delete = %q, want v3", v)
+	}
+
+	if err := g.Set("w", []byte("written"), time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if v := mustGet(t, g, "w"); v != "written" {
+		t.Fatalf("read after Set = %q", v)
+	}
+	clock.Advance(2 * time.Second)
+	if _, err := g.Get("w"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("read after the Set ttl = %v, want ErrNotFound", err)
+	}
+}
+
+// TestStandaloneStartsNoGoroutines checks that a group with default options
+// and no peers does all of its work on the callers' goroutines
+func TestStandaloneStartsNoGoroutines(t *testing.T) {
+	before := runtime.NumGoroutine()
+	g := newTestGroup(t, newCountingGetter(map[string]string{"a": "1", "b": "2"}), time.Hour)
+	for i := 0; i < 10; i++ {
+		mustGet(t, g, "a")
+		mustGet(t, g, "b")
+		g.Get("missing")
+	}
+	if err := g.Delete("a"); err != nil {
+		t.Fatal(err)
+	}
+	g.Stats()
+	waitFor(t, "loads to finish", func() bool {
+		return runtime.NumGoroutine() <= before
+	})
+}
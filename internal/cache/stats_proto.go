@@ -0,0 +1,55 @@
+package cache
+
+import (
+	"time"
+
+	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
+	"google.golang.org/protobuf/proto"
+)
+
+// StatsResponse builds the peer-protocol stats message for the named group,
+// or for every group when name is empty. uptime is the serving node's uptime.
+func StatsResponse(name string, uptime time.Duration) (*pb.StatsResponse, error) {
+	return defaultRegistry.StatsResponse(name, uptime)
+}
+
+// statsResponse converts group snapshots into the Stats RPC response
+func statsResponse(infos []GroupInfo, uptime time.Duration) *pb.StatsResponse {
+	resp := &pb.StatsResponse{
+		Groups:        make([]*pb.GroupStats, 0, len(infos)),
+		UptimeSeconds: proto.Int64(int64(uptime / time.Second)),
+		NodeThrottled: proto.Int64(NodeThrottled()),
+		Mode:          proto.String(NodeMode().String()),
+	}
+	for _, info := range infos {
+		s := info.Stats
+		resp.Groups = append(resp.Groups, &pb.GroupStats{
+			Name:      info.Name,
+			Hits:      proto.Int64(s.Hits),
+			Misses:    proto.Int64(s.Gets - s.Hits),
+			Evictions: proto.Int64(s.Evictions),
+			Bytes:     proto.Int64(s.Bytes),
+			Entries:   proto.Int64(s.Entries),
+			Gets:      proto.Int64(s.Gets),
+			MaxBytes:  proto.Int64(info.MaxBytes),
+			Throttled: proto.Int64(s.Throttled),
+			Mode:      proto.String(info.Mode),
+			Cost:      proto.Int64(s.Cost),
+
+			EvictionsPerMinute:       proto.Int64(s.EvictionsPerMinute),
+			EvictedAgeP50Ms:          proto.Int64(s.EvictedAgeP50Ms),
+			EvictedAgeP90Ms:          proto.Int64(s.EvictedAgeP90Ms),
+			EvictionPressureWarnings: proto.Int64(s.EvictionPressureWarnings),
+
+			CancelledGets: proto.Int64(s.CancelledGets),
+			AbortedLoads:  proto.Int64(s.AbortedLoads),
+
+			LoadsExecuted:   proto.Int64(s.LoadsExecuted),
+			LoadsDeduped:    proto.Int64(s.LoadsDeduped),
+			CurrentInflight: proto.Int64(s.CurrentInflight),
+
+			WatermarkEvictions: proto.Int64(s.WatermarkEvictions),
+		})
+	}
+	return resp
+}
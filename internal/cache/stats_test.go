@@ -0,0 +1,158 @@
+package cache
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/pkg/logger"
+)
+
+// TestStatsDoNotWaitForLocks scrapes the stats of a group while a write holds
+// the lru lock: Stats only makes atomic loads, so it returns at once
+func TestStatsDoNotWaitForLocks(t *testing.T) {
+	var block atomic.Bool
+	held := make(chan struct{})
+	release := make(chan struct{})
+	g := newTestGroup(t, GetterFunc(loadValue), time.Hour,
+		WithEvictionPressure(time.Second),
+		WithHotKeys(HotKeyConfig{}),
+		// The cost of an entry is computed under the lru lock
+		WithEntryCost(func(key string, value []byte) int64 {
+			if block.Load() && key == "blocked" {
+				close(held)
+				<-release
+			}
+			return int64(len(value))
+		}))
+	mustGet(t, g, "k")
+
+	block.Store(true)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		g.Set("blocked", []byte("v"), 0)
+	}()
+	<-held
+
+	scraped := make(chan CacheStats, 1)
+	go func() { scraped <- g.Stats() }()
+	select {
+	case st := <-scraped:
+		if st.Entries != 1 || st.Gets != 1 {
+			t.Errorf("stats = %+v", st)
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("Stats waited for the lru lock")
+	}
+	if g.Bytes() == 0 {
+		t.Error("Bytes = 0 with an entry cached")
+	}
+	close(release)
+	<-done
+}
+
+// benchmarkGetAdd runs the Get/Add mix of 90% reads over 10000 keys, with
+// scrapers calling Stats scrapeHz times a second meanwhile
+func benchmarkGetAdd(b *testing.B, scrapeHz int) {
+	g := newTestGroup(b, GetterFunc(loadValue), time.Hour, WithEvictionPressure(time.Second))
+	keys := make([]string, 10000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key-%d", i)
+		g.Set(keys[i], []byte("value"), 0)
+	}
+
+	var scrapes atomic.Int64
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		if scrapeHz == 0 {
+			return
+		}
+		ticker := time.NewTicker(time.Second / time.Duration(scrapeHz))
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				g.Stats()
+				scrapes.Add(1)
+			}
+		}
+	}()
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			key := keys[i%len(keys)]
+			if i%10 == 0 {
+				g.Set(key, []byte("value"), 0)
+			} else {
+				g.Get(key)
+			}
+		}
+	})
+	b.StopTimer()
+	close(stop)
+	<-stopped
+	b.ReportMetric(float64(scrapes.Load()), "scrapes")
+}
+
+// BenchmarkGetAddWhileScraping compares the Get/Add throughput without scraping
+// and with the stats scraped 100 times a second; the two should match
+func BenchmarkGetAddWhileScraping(b *testing.B) {
+	// Hits are logged at info level, which would dominate the measurement
+	logger.SetLevel("error")
+	defer logger.SetLevel("debug")
+	for _, hz := range []int{0, 100} {
+		b.Run(fmt.Sprintf("scrape=%dHz", hz), func(b *testing.B) {
+			benchmarkGetAdd(b, hz)
+		})
+	}
+}
+
+// TestLoadStats fires 1000 concurrent Gets for one missing key: the group
+// reports one executed load and 999 deduplicated ones, in Stats and in the
+// stats response the endpoints serve
+func TestLoadStats(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+	const callers = 1000
+	release := make(chan struct{})
+	r := NewRegistry()
+	defer r.Close()
+	g := newTestGroup(t, GetterFunc(func(key string) ([]byte, error) {
+		<-release
+		return loadValue(key)
+	}), time.Hour, WithRegistry(r))
+
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			mustGet(t, g, "k")
+		}()
+	}
+	waitFor(t, "every caller to join the load", func() bool {
+		return g.Stats().LoadsDeduped == callers-1
+	})
+	if s := g.Stats(); s.LoadsExecuted != 1 || s.CurrentInflight != 1 {
+		t.Fatalf("stats while loading = %+v", s)
+	}
+	close(release)
+	wg.Wait()
+
+	res, err := r.StatsResponse(g.Name(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gs := res.GetGroups()[0]
+	if gs.GetLoadsExecuted() != 1 || gs.GetLoadsDeduped() != callers-1 || gs.GetCurrentInflight() != 0 {
+		t.Fatalf("stats response = %v", gs)
+	}
+}
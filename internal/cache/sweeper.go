@@ -0,0 +1,54 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// statsRefreshInterval is how often the sweeper recomputes the statistics that
+// need a lock, so that Group.Stats itself only makes atomic loads
+const statsRefreshInterval = time.Second
+
+// startSweeper launches the background sweeper: the periodic expiry sweep when
+// WithSweepInterval is set, the refresh of the eviction pressure statistics
+// when WithEvictionPressure is, and the early eviction down to the low
+// watermark when WithWatermarks is. It runs until the group is closed.
+func (g *Group) startSweeper() {
+	water := g.mainCache.water
+	if g.sweepEvery <= 0 && g.pressure == nil && water == nil {
+		return
+	}
+
+	g.goBackground(func(ctx context.Context) {
+		var sweep, refresh <-chan time.Time
+		var wake <-chan struct{}
+		if water != nil {
+			wake = water.wake
+		}
+		if g.sweepEvery > 0 {
+			ticker := time.NewTicker(g.sweepEvery)
+			defer ticker.Stop()
+			sweep = ticker.C
+		}
+		if g.pressure != nil {
+			ticker := time.NewTicker(statsRefreshInterval)
+			defer ticker.Stop()
+			refresh = ticker.C
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-sweep:
+				if n := g.mainCache.removeExpired(); n > 0 {
+					g.log.Debugf("[Cache] 清理过期条目: group=%s, 数量=%d", g.name, n)
+				}
+			case <-refresh:
+				g.pressure.refresh()
+			case <-wake:
+				g.sweepWatermark()
+			}
+		}
+	})
+}
@@ -0,0 +1,173 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/pkg/logger"
+)
+
+// Defaults of WithTombstones
+const (
+	// DefaultTombstoneRetention is twice the default peer request timeout, long
+	// enough for any load that started before a delete to finish
+	DefaultTombstoneRetention = 10 * time.Second
+	// DefaultTombstoneCapacity bounds the tombstones a group keeps
+	DefaultTombstoneCapacity = 10000
+)
+
+// tombstone records when a key was deleted
+type tombstone struct {
+	key       string
+	deletedAt time.Time
+}
+
+// tombstones is a small LRU of recently deleted keys; the least recently
+// deleted tombstone is dropped when it is full
+type tombstones struct {
+	mu  sync.Mutex
+	ll  *list.List
+	m   map[string]*list.Element
+	max int
+
+	live     int64 // tombstones kept, ll.Len() readable without mu
+	hits     int64 // Gets answered as misses because of a tombstone
+	rejected int64 // loaded values not stored because the key was deleted meanwhile
+	dropped  int64 // tombstones removed early to stay under the capacity
+}
+
+// WithTombstones makes deletes win races against loads in flight. Deleting a key
+// records a tombstone kept for retention: a value whose load began before the
+// delete is not stored in the cache, and Gets of the key fail with ErrNotFound
+// without asking the owner or the data source until the tombstone expires or a
+// Set stores a new value. At most capacity tombstones are kept, the oldest
+// deletes are forgotten first. A retention <= 0 disables tombstones, which is the
+// default; capacity <= 0 means DefaultTombstoneCapacity.
+//
+// Tombstones take precedence over WithDeleteMarker: a deleted key is not
+// reloaded at all while its tombstone lasts.
+func WithTombstones(retention time.Duration, capacity int) GroupOption {
+	return func(g *Group) {
+		g.tombstoneTTL = retention
+		if capacity <= 0 {
+			capacity = DefaultTombstoneCapacity
+		}
+		g.tombs.max = capacity
+	}
+}
+
+// deleteLocal removes key from the local cache and, with tombstones enabled,
+// records its tombstone. Both happen under the tombstone lock, so a load that
+// checked for a tombstone before the delete cannot store its value after it.
+func (g *Group) deleteLocal(key string) bool {
+	if g.tombstoneTTL <= 0 {
+		return g.mainCache.delete(g.cacheKey(key))
+	}
+	t := &g.tombs
+	now := g.clock.Now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.m == nil {
+		t.ll = list.New()
+		t.m = make(map[string]*list.Element)
+	}
+	if e, ok := t.m[key]; ok {
+		e.Value.(*tombstone).deletedAt = now
+		t.ll.MoveToFront(e)
+	} else {
+		t.m[key] = t.ll.PushFront(&tombstone{key: key, deletedAt: now})
+		atomic.AddInt64(&t.live, 1)
+		for t.ll.Len() > t.max {
+			t.removeLocked(t.ll.Back())
+			atomic.AddInt64(&t.dropped, 1)
+		}
+	}
+	return g.mainCache.delete(g.cacheKey(key))
+}
+
+// storeLoaded stores a value in its stored form whose load began at started,
+// unless the key was deleted after that, and reports whether it was stored
+func (g *Group) storeLoaded(key string, value ByteView, ttl time.Duration, started time.Time) bool {
+	if g.tombstoneTTL <= 0 {
+		g.storeLocally(key, value, ttl)
+		return true
+	}
+	t := &g.tombs
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if at, ok := t.liveLocked(key, g.clock.Now(), g.tombstoneTTL); ok && !at.Before(started) {
+		atomic.AddInt64(&t.rejected, 1)
+		g.log.Infof("[Cache] 加载期间 key 被删除，丢弃加载结果: group=%s, key=%s", g.name, logger.Key(key))
+		return false
+	}
+	g.storeLocally(key, value, ttl)
+	return true
+}
+
+// clearTombstone drops the tombstone of key, called when a write stores a new value
+func (g *Group) clearTombstone(key string) {
+	if g.tombstoneTTL <= 0 {
+		return
+	}
+	t := &g.tombs
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if e, ok := t.m[key]; ok {
+		t.removeLocked(e)
+	}
+}
+
+// tombstoned reports whether a Get of key must be answered as a miss because its
+// tombstone is still live
+func (g *Group) tombstoned(key string) bool {
+	if g.tombstoneTTL <= 0 {
+		return false
+	}
+	t := &g.tombs
+	t.mu.Lock()
+	_, ok := t.liveLocked(key, g.clock.Now(), g.tombstoneTTL)
+	t.mu.Unlock()
+	if !ok {
+		return false
+	}
+	atomic.AddInt64(&t.hits, 1)
+	g.log.Debugf("[Cache] key 已删除，墓碑有效期内不加载: group=%s, key=%s", g.name, logger.Key(key))
+	return true
+}
+
+// liveLocked returns when key was deleted if its tombstone is younger than
+// retention, dropping it otherwise. The caller holds t.mu.
+func (t *tombstones) liveLocked(key string, now time.Time, retention time.Duration) (time.Time, bool) {
+	e, ok := t.m[key]
+	if !ok {
+		return time.Time{}, false
+	}
+	ts := e.Value.(*tombstone)
+	if now.Sub(ts.deletedAt) >= retention {
+		t.removeLocked(e)
+		return time.Time{}, false
+	}
+	return ts.deletedAt, true
+}
+
+// removeLocked drops the tombstone e; the caller holds t.mu
+func (t *tombstones) removeLocked(e *list.Element) {
+	t.ll.Remove(e)
+	delete(t.m, e.Value.(*tombstone).key)
+	atomic.AddInt64(&t.live, -1)
+}
+
+// tombstoneStats adds the tombstone counters to stats without taking the
+// tombstone lock, which deletes and loads hold
+func (g *Group) tombstoneStats(stats *CacheStats) {
+	if g.tombstoneTTL <= 0 {
+		return
+	}
+	t := &g.tombs
+	stats.Tombstones = atomic.LoadInt64(&t.live)
+	stats.TombstonesDropped = atomic.LoadInt64(&t.dropped)
+	stats.TombstoneHits = atomic.LoadInt64(&t.hits)
+	stats.TombstoneRejects = atomic.LoadInt64(&t.rejected)
+}
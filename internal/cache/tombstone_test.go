@@ -0,0 +1,172 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/pkg/lru"
+)
+
+func TestTombstoneRejectsInFlightLoad(t *testing.T) {
+	loader := newBlockingLoader()
+	g := newTestGroup(t, loader.getter(), 0, WithTombstones(time.Hour, 0))
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := g.GetWithContext(context.Background(), "k")
+		done <- err
+	}()
+	loader.waitStarted(t)
+	if err := g.DeleteLocally("k"); err != nil {
+		t.Fatal(err)
+	}
+	close(loader.release)
+	if err := <-done; err != nil {
+		t.Fatalf("Get in flight during the delete: %v", err)
+	}
+
+	if _, _, ok := g.Peek("k"); ok {
+		t.Fatal("value loaded before the delete was stored after it")
+	}
+	if _, err := g.Get("k"); !IsKeyNotFoundError(err) {
+		t.Fatalf("Get of a tombstoned key = %v, want ErrNotFound", err)
+	}
+	select {
+	case <-loader.started:
+		t.Fatal("Get of a tombstoned key reached the data source")
+	default:
+	}
+	if s := g.Stats(); s.Tombstones != 1 || s.TombstoneRejects != 1 || s.TombstoneHits != 1 {
+		t.Fatalf("stats: %d tombstones, %d rejects, %d hits", s.Tombstones, s.TombstoneRejects, s.TombstoneHits)
+	}
+}
+
+func TestTombstoneExpiresAndSetClears(t *testing.T) {
+	clock := lru.NewFakeClock(time.Unix(1000, 0))
+	getter := newCountingGetter(map[string]string{"a": "origin", "b": "origin"})
+	g := newTestGroup(t, getter, 0, WithClock(clock), WithTombstones(time.Second, 0))
+
+	for _, key := range []string{"a", "b"} {
+		if err := g.DeleteLocally(key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := g.Get("a"); !IsKeyNotFoundError(err) || getter.count("a") != 0 {
+		t.Fatalf("Get within the retention = %v after %d loads", err, getter.count("a"))
+	}
+
+	// A Set stores a newer value, which the tombstone does not hide
+	if err := g.Set("b", []byte("set"), 0); err != nil {
+		t.Fatal(err)
+	}
+	if got := mustGet(t, g, "b"); got != "set" {
+		t.Fatalf("Get after Set = %q", got)
+	}
+
+	clock.Advance(time.Second)
+	if got := mustGet(t, g, "a"); got != "origin" || getter.count("a") != 1 {
+		t.Fatalf("Get after the retention = %q after %d loads", got, getter.count("a"))
+	}
+	if s := g.Stats(); s.Tombstones != 0 {
+		t.Fatalf("Tombstones = %d after expiry and Set", s.Tombstones)
+	}
+}
+
+func TestTombstonesBounded(t *testing.T) {
+	getter := newCountingGetter(map[string]string{"k0": "v"})
+	g := newTestGroup(t, getter, 0, WithTombstones(time.Hour, 3))
+	for i := 0; i < 5; i++ {
+		if err := g.DeleteLocally(fmt.Sprintf("k%d", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if s := g.Stats(); s.Tombstones != 3 || s.TombstonesDropped != 2 {
+		t.Fatalf("%d tombstones, %d dropped; want 3 and 2", s.Tombstones, s.TombstonesDropped)
+	}
+	// The oldest deletes are forgotten first
+	if got := mustGet(t, g, "k0"); got != "v" {
+		t.Fatalf("Get of a dropped tombstone = %q", got)
+	}
+	if _, err := g.Get("k4"); !IsKeyNotFoundError(err) || getter.count("k4") != 0 {
+		t.Fatalf("Get of a kept tombstone = %v", err)
+	}
+}
+
+// TestTombstoneDeleteRacesSlowLoads deletes a key again and again while slow
+// loads of it are in flight; once a delete returns, no read may see a value the
+// data source held before it. The retention covers the slowest load, as it must.
+func TestTombstoneDeleteRacesSlowLoads(t *testing.T) {
+	var version atomic.Int64
+	loading := make(chan struct{}, 1)
+	g := newTestGroup(t, GetterFunc(func(key string) ([]byte, error) {
+		v := version.Load()
+		select {
+		case loading <- struct{}{}:
+		default:
+		}
+		time.Sleep(5 * time.Millisecond)
+		return []byte(fmt.Sprintf("v%d", v)), nil
+	}), 0, WithTombstones(200*time.Millisecond, 0))
+
+	var (
+		deleted atomic.Int64 // version of the data source when the last delete returned
+		stop    = make(chan struct{})
+		wg      sync.WaitGroup
+		stale   atomic.Int64
+		served  atomic.Int64
+	)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				floor := deleted.Load()
+				v, err := g.Get("k")
+				if err != nil {
+					time.Sleep(time.Millisecond)
+					continue
+				}
+				served.Add(1)
+				if n, _ := strconv.ParseInt(strings.TrimPrefix(v.String(), "v"), 10, 64); n < floor {
+					stale.Add(1)
+				}
+			}
+		}()
+	}
+	for i := 0; i < 5; i++ {
+		// Delete while a load of the previous version is in flight
+		select {
+		case <-loading:
+		case <-time.After(5 * time.Second):
+			t.Fatal("no load started")
+		}
+		version.Add(1)
+		if err := g.DeleteLocally("k"); err != nil {
+			t.Fatal(err)
+		}
+		deleted.Store(version.Load())
+	}
+	close(stop)
+	wg.Wait()
+
+	if n := stale.Load(); n != 0 {
+		t.Fatalf("%d reads saw a value from before a completed delete", n)
+	}
+	if served.Load() == 0 {
+		t.Fatal("no read was served")
+	}
+	if g.Stats().TombstoneRejects == 0 {
+		t.Fatal("no load in flight during a delete was rejected")
+	}
+}
@@ -0,0 +1,91 @@
+package cache
+
+import (
+	"github.com/AdrianWangs/go-cache/pkg/logger"
+)
+
+// KeyedTransform encodes or decodes value, the value of key in group
+type KeyedTransform func(group, key string, value []byte) ([]byte, error)
+
+// valueTransform is the pair of functions set by WithKeyedValueTransform
+type valueTransform struct {
+	encode KeyedTransform
+	decode KeyedTransform
+}
+
+// WithValueTransform stores every value of the group as encode(value) and
+// reverses it with decode before the value leaves the cache, so that memory
+// dumps, exports and snapshots only hold the encoded form; pkg/ciphers provides
+// AES-GCM encryption for it. Values are encoded when loaded, written or imported
+// and decoded on every read, including the reads served to peers: values travel
+// between nodes in plaintext unless the peer transport uses TLS.
+//
+// The encoded size is what is accounted against cacheBytes, reported in stats
+// and passed to WithEntryCost. A value that cannot be encoded is not cached and
+// fails its write; a cached value that cannot be decoded, e.g. after its key was
+// rotated out, is dropped and the read fails with ErrValueTransform. Both
+// functions must be safe for concurrent use and must not retain their argument.
+//
+// The encoded value is not tied to its key: one copied under another key or into
+// another group decodes there. Encryption should use WithKeyedValueTransform.
+func WithValueTransform(encode, decode func([]byte) ([]byte, error)) GroupOption {
+	if encode == nil || decode == nil {
+		return func(*Group) {}
+	}
+	return WithKeyedValueTransform(
+		func(_, _ string, value []byte) ([]byte, error) { return encode(value) },
+		func(_, _ string, value []byte) ([]byte, error) { return decode(value) },
+	)
+}
+
+// WithKeyedValueTransform is WithValueTransform with the group name and the key
+// passed to both functions, so that an encoding can be bound to them: with
+// ciphers.AESGCM's EncryptFor and DecryptFor an encrypted value moved to another
+// key, by a bug or by someone with write access to a snapshot or an export,
+// fails to decode instead of being served for the wrong key. The key is the one
+// the value is stored under, its digest in key-digest mode (see WithKeyHashing).
+func WithKeyedValueTransform(encode, decode KeyedTransform) GroupOption {
+	return func(g *Group) {
+		if encode != nil && decode != nil {
+			g.transform = &valueTransform{encode: encode, decode: decode}
+		}
+	}
+}
+
+// encodeValue returns value in the form it is stored in under slot, the cache
+// key from cacheKey
+func (g *Group) encodeValue(slot string, value ByteView) (ByteView, error) {
+	if g.transform == nil {
+		return value, nil
+	}
+	b, err := g.transform.encode(g.name, slot, value.bytes)
+	if err != nil {
+		return ByteView{}, WrapError(ErrTypeValueTransform, "failed to encode value", err)
+	}
+	return ByteView{bytes: b}, nil
+}
+
+// decodeValue returns the plaintext of a value stored under slot, the cache key
+// from cacheKey
+func (g *Group) decodeValue(slot string, stored ByteView) (ByteView, error) {
+	if g.transform == nil {
+		return stored, nil
+	}
+	b, err := g.transform.decode(g.name, slot, stored.bytes)
+	if err != nil {
+		return ByteView{}, WrapError(ErrTypeValueTransform, "failed to decode value", err)
+	}
+	return ByteView{bytes: b}, nil
+}
+
+// decodeCached decodes a value read from the cache under key. A value that
+// cannot be decoded would fail every read until it expires, so it is dropped.
+func (g *Group) decodeCached(key string, stored ByteView) (ByteView, error) {
+	v, err := g.decodeValue(g.cacheKey(key), stored)
+	if err != nil {
+		g.log.Errorf("[Cache] 缓存值解码失败，已删除: group=%s, key=%s: %v", g.name, logger.Key(key), err)
+		g.mainCache.delete(g.cacheKey(key))
+		return ByteView{}, err
+	}
+	return v, nil
+}
@@ -0,0 +1,253 @@
+package cache
+
+import (
+	"bytes"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/pkg/cacheerrors"
+	"github.com/AdrianWangs/go-cache/pkg/ciphers"
+)
+
+// sealTag is appended by sealTransform, so an encoded value is 8 bytes longer
+const sealTag = "!sealed!"
+
+// sealTransform reverses a value and appends sealTag; decode fails while broken
+// is set, and encode while failEncode is
+type sealTransform struct {
+	broken     atomic.Bool
+	failEncode atomic.Bool
+}
+
+func (s *sealTransform) encode(b []byte) ([]byte, error) {
+	if s.failEncode.Load() {
+		return nil, errors.New("encode failed")
+	}
+	out := make([]byte, 0, len(b)+len(sealTag))
+	for i := len(b) - 1; i >= 0; i-- {
+		out = append(out, b[i])
+	}
+	return append(out, sealTag...), nil
+}
+
+func (s *sealTransform) decode(b []byte) ([]byte, error) {
+	if s.broken.Load() || !bytes.HasSuffix(b, []byte(sealTag)) {
+		return nil, errors.New("decode failed")
+	}
+	b = b[:len(b)-len(sealTag)]
+	out := make([]byte, 0, len(b))
+	for i := len(b) - 1; i >= 0; i-- {
+		out = append(out, b[i])
+	}
+	return out, nil
+}
+
+func (s *sealTransform) option() GroupOption {
+	return WithValueTransform(s.encode, s.decode)
+}
+
+func loadValue(key string) ([]byte, error) {
+	return []byte("v:" + key), nil
+}
+
+func TestValueTransformRoundTrip(t *testing.T) {
+	seal := &sealTransform{}
+	g := newTestGroup(t, GetterFunc(loadValue), time.Hour, seal.option())
+
+	if v := mustGet(t, g, "loaded"); v != "v:loaded" {
+		t.Fatalf("loaded value = %q", v)
+	}
+	if v := mustGet(t, g, "loaded"); v != "v:loaded" {
+		t.Fatalf("cached value = %q", v)
+	}
+	if err := g.Set("written", []byte("hello"), 0); err != nil {
+		t.Fatal(err)
+	}
+	if v := mustGet(t, g, "written"); v != "hello" {
+		t.Fatalf("written value = %q", v)
+	}
+	if v, _, ok := g.Peek("written"); !ok || v.String() != "hello" {
+		t.Fatalf("Peek = %q, %v", v, ok)
+	}
+
+	// Exports hold the stored form only
+	entries := exportAll(t, g)
+	if e := entries["written"]; !e.Encoded || string(e.Value) != "olleh"+sealTag {
+		t.Fatalf("exported entry = %+v", e)
+	}
+
+	// A group with the same transform takes them as they are
+	dst := newTestGroup(t, GetterFunc(loadValue), time.Hour, seal.option())
+	result, err := dst.Import(entriesOf(entries["written"], entries["loaded"]))
+	if err != nil || result.Imported != 2 {
+		t.Fatalf("Import = %+v, %v", result, err)
+	}
+	if v, _, ok := dst.Peek("written"); !ok || v.String() != "hello" {
+		t.Fatalf("imported value = %q, %v", v, ok)
+	}
+
+	// A group without the transform cannot decode them
+	plain := newTestGroup(t, GetterFunc(loadValue), time.Hour)
+	result, err = plain.Import(entriesOf(entries["written"]))
+	if err != nil || result.Skipped != 1 || plain.Entries() != 0 {
+		t.Fatalf("Import into a plain group = %+v, %v", result, err)
+	}
+}
+
+func TestValueTransformAccounting(t *testing.T) {
+	seal := &sealTransform{}
+	var charged []byte
+	g := newTestGroup(t, GetterFunc(loadValue), time.Hour, seal.option(),
+		WithEntryCost(func(key string, value []byte) int64 {
+			charged = append([]byte(nil), value...)
+			return int64(len(value))
+		}))
+	if err := g.Set("k", []byte("12345"), 0); err != nil {
+		t.Fatal(err)
+	}
+	if string(charged) != "54321"+sealTag {
+		t.Fatalf("entry cost charged %q, want the encoded value", charged)
+	}
+	if st := g.Stats(); st.Cost != int64(5+len(sealTag)) {
+		t.Fatalf("cost = %d, want %d", st.Cost, 5+len(sealTag))
+	}
+	if got, want := g.Bytes(), int64(len("k")+5+len(sealTag)); got != want {
+		t.Fatalf("Bytes = %d, want %d with the encoded size", got, want)
+	}
+}
+
+func TestValueTransformDecodeFailure(t *testing.T) {
+	seal := &sealTransform{}
+	g := newTestGroup(t, GetterFunc(loadValue), time.Hour, seal.option())
+	mustGet(t, g, "k")
+
+	seal.broken.Store(true)
+	_, err := g.Get("k")
+	if !IsValueTransformError(err) || cacheerrors.ErrorCode(err) != cacheerrors.ErrorCodeValueTransform {
+		t.Fatalf("Get with a broken decode = %v, want ErrValueTransform", err)
+	}
+	if g.Entries() != 0 {
+		t.Fatal("undecodable value kept in the cache")
+	}
+
+	// The next read loads the value again
+	seal.broken.Store(false)
+	if v := mustGet(t, g, "k"); v != "v:k" {
+		t.Fatalf("reloaded value = %q", v)
+	}
+}
+
+func TestValueTransformEncodeFailure(t *testing.T) {
+	seal := &sealTransform{}
+	seal.failEncode.Store(true)
+	g := newTestGroup(t, GetterFunc(loadValue), time.Hour, seal.option())
+
+	if err := g.Set("k", []byte("v"), 0); !IsValueTransformError(err) {
+		t.Fatalf("Set with a failing encode = %v, want ErrValueTransform", err)
+	}
+	// A loaded value is still returned, only not cached
+	if v := mustGet(t, g, "loaded"); v != "v:loaded" {
+		t.Fatalf("loaded value = %q", v)
+	}
+	if g.Entries() != 0 {
+		t.Fatalf("%d entries cached after failed encodes", g.Entries())
+	}
+}
+
+// TestValueTransformKeyRotation encrypts a group with pkg/ciphers and rotates
+// its key: values written under the old key stay readable while the old key is
+// kept for decryption, and fail with ErrValueTransform once it is removed
+func TestValueTransformKeyRotation(t *testing.T) {
+	k1 := ciphers.Key{ID: "k1", Secret: bytes.Repeat([]byte{1}, 32)}
+	k2 := ciphers.Key{ID: "k2", Secret: bytes.Repeat([]byte{2}, 32)}
+	var current atomic.Pointer[ciphers.AESGCM]
+	use := func(keys ...ciphers.Key) {
+		c, err := ciphers.NewAESGCM(keys...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		current.Store(c)
+	}
+	use(k1)
+	g := newTestGroup(t, GetterFunc(loadValue), time.Hour, WithValueTransform(
+		func(b []byte) ([]byte, error) { return current.Load().Encrypt(b) },
+		func(b []byte) ([]byte, error) { return current.Load().Decrypt(b) },
+	))
+
+	if err := g.Set("old", []byte("secret"), 0); err != nil {
+		t.Fatal(err)
+	}
+	if e := exportAll(t, g)["old"]; bytes.Contains(e.Value, []byte("secret")) {
+		t.Fatal("value stored in plaintext")
+	}
+
+	use(k2, k1)
+	if err := g.Set("new", []byte("fresh"), 0); err != nil {
+		t.Fatal(err)
+	}
+	if v := mustGet(t, g, "old"); v != "secret" {
+		t.Fatalf("value under the old key = %q", v)
+	}
+
+	use(k2)
+	if v := mustGet(t, g, "new"); v != "fresh" {
+		t.Fatalf("value under the new key = %q", v)
+	}
+	if _, err := g.Get("old"); !IsValueTransformError(err) || !errors.Is(err, ciphers.ErrDecrypt) {
+		t.Fatalf("value under a removed key: %v, want ErrValueTransform wrapping ciphers.ErrDecrypt", err)
+	}
+}
+
+// TestKeyedValueTransformMovedValue encrypts with ciphers.AESGCM bound to the
+// group and key: a ciphertext moved to another key, in memory or through an
+// export, fails to decode instead of being served as that key's value
+func TestKeyedValueTransformMovedValue(t *testing.T) {
+	c, err := ciphers.NewAESGCM(ciphers.Key{ID: "k1", Secret: bytes.Repeat([]byte{1}, 32)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	newGroup := func() *Group {
+		return newTestGroup(t, GetterFunc(loadValue), time.Hour, WithKeyedValueTransform(c.EncryptFor, c.DecryptFor))
+	}
+	g := newGroup()
+	if err := g.Set("alice", []byte("secret"), 0); err != nil {
+		t.Fatal(err)
+	}
+	exported := exportAll(t, g)["alice"]
+	if !exported.Encoded || bytes.Contains(exported.Value, []byte("secret")) {
+		t.Fatalf("exported entry = %+v, want it encrypted", exported)
+	}
+
+	// Copied in memory under another key
+	stored, _, ok := g.mainCache.get(g.cacheKey("alice"))
+	if !ok {
+		t.Fatal("alice not cached")
+	}
+	g.storeLocally("bob", stored, 0)
+	if v, err := g.Get("bob"); !IsValueTransformError(err) || !errors.Is(err, ciphers.ErrDecrypt) {
+		t.Fatalf("Get(bob) with alice's ciphertext = %q, %v; want ErrValueTransform wrapping ciphers.ErrDecrypt", v, err)
+	}
+	if v := mustGet(t, g, "bob"); v != "v:bob" {
+		t.Fatalf("Get(bob) after the bad value was dropped = %q, want it reloaded", v)
+	}
+
+	// Imported under another key, or into another group with the same cipher
+	moved := exported
+	moved.Key = "carol"
+	res, err := g.Import(entriesOf(moved))
+	if err != nil || res.Imported != 0 || res.Skipped != 1 {
+		t.Fatalf("Import of a moved entry = %+v, %v; want it skipped", res, err)
+	}
+	other := newGroup()
+	if res, err := other.Import(entriesOf(exported)); err != nil || res.Skipped != 1 {
+		t.Fatalf("Import into another group = %+v, %v; want it skipped", res, err)
+	}
+	if res, err := g.Import(entriesOf(exported)); err != nil || res.Imported != 1 {
+		t.Fatalf("Import under its own key = %+v, %v", res, err)
+	}
+	if v := mustGet(t, g, "alice"); v != "secret" {
+		t.Fatalf("Get(alice) = %q", v)
+	}
+}
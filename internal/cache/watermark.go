@@ -0,0 +1,110 @@
+package cache
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// watermarkBatch is the number of entries the sweeper evicts per lru lock hold
+// when bringing a group down to its low watermark
+const watermarkBatch = 128
+
+// WithWatermarks evicts early, in the background, instead of on the write that
+// fills the cache. Once the cost of the cache rises above high times the group's
+// size limit, the sweeper evicts entries in small batches, by the group's
+// eviction policy, until it is at most low times the limit; for example 0.95
+// and 0.9. Writes at capacity then rarely pay for evictions themselves and
+// bursts find room, while the limit itself still holds: a write that would
+// exceed it evicts inline as without watermarks.
+//
+// Both are fractions of the limit with 0 < low < high <= 1; other values, or a
+// group without a limit, leave watermarks off.
+func WithWatermarks(high, low float64) GroupOption {
+	return func(g *Group) {
+		g.highWater, g.lowWater = high, low
+	}
+}
+
+// watermarks holds the thresholds of a Cache with WithWatermarks and the wake-up
+// of the sweeper that enforces them
+type watermarks struct {
+	high int64         // cost above which the sweeper is woken
+	low  int64         // cost the sweeper evicts down to
+	wake chan struct{} // signalled by writes crossing high, buffered so they never block
+
+	runs      atomic.Int64 // sweeps started by crossing high
+	evictions atomic.Int64 // entries evicted by those sweeps
+}
+
+// initWatermarks sets up the watermarks of g's cache; called by NewGroup once
+// the cache exists
+func (g *Group) initWatermarks() {
+	if g.highWater == 0 && g.lowWater == 0 {
+		return
+	}
+	limit := g.mainCache.cacheBytes
+	if limit <= 0 || g.lowWater <= 0 || g.lowWater >= g.highWater || g.highWater > 1 {
+		g.log.Warnf("[Cache] 缓存组水位配置无效，未开启提前淘汰: group=%s, high=%v, low=%v, max_bytes=%d",
+			g.name, g.highWater, g.lowWater, limit)
+		return
+	}
+	g.mainCache.water = &watermarks{
+		high: int64(g.highWater * float64(limit)),
+		low:  int64(g.lowWater * float64(limit)),
+		wake: make(chan struct{}, 1),
+	}
+}
+
+// aboveHighWater wakes the sweeper when the cache has grown past its high
+// watermark; it only makes an atomic load otherwise
+func (c *Cache) aboveHighWater() {
+	if c.water == nil || c.lru.Cost() <= c.water.high {
+		return
+	}
+	select {
+	case c.water.wake <- struct{}{}:
+	default:
+	}
+}
+
+// evictToLowWater evicts batches of entries until the cost is at most the low
+// watermark. It stops after as many entries as the cache held when it started,
+// so that writes keeping pace with it cannot keep the sweeper busy forever.
+func (c *Cache) evictToLowWater() int {
+	w := c.water
+	if c.lru.Cost() <= w.high {
+		return 0
+	}
+	w.runs.Add(1)
+	budget := c.lru.Len()
+	removed := 0
+	for removed < budget {
+		n := c.lru.EvictTo(w.low, min(watermarkBatch, budget-removed))
+		removed += n
+		if n == 0 {
+			break
+		}
+	}
+	w.evictions.Add(int64(removed))
+	return removed
+}
+
+// watermarkStats adds the watermark statistics to stats
+func (c *Cache) watermarkStats(stats *CacheStats) {
+	if c.water == nil {
+		return
+	}
+	stats.WatermarkRuns = c.water.runs.Load()
+	stats.WatermarkEvictions = c.water.evictions.Load()
+}
+
+// sweepWatermark is the sweeper's work after a write crossed the high watermark
+func (g *Group) sweepWatermark() {
+	start := time.Now()
+	if n := g.mainCache.evictToLowWater(); n > 0 {
+		g.log.Debugf("[Cache] 超过高水位，提前淘汰: group=%s, 数量=%d, 耗时=%v", g.name, n, time.Since(start))
+		if g.rates != nil {
+			g.syncEvictions()
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package cache
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/pkg/logger"
+)
+
+// watermarkValue makes every entry "key-%05d" plus this value cost 100 bytes
+var watermarkValue = bytes.Repeat([]byte("v"), 100-len("key-00000"))
+
+func TestWatermarks(t *testing.T) {
+	g := NewGroup(fmt.Sprintf("watermarks-%d", time.Now().UnixNano()), 10000, GetterFunc(loadValue), time.Hour,
+		WithRegistry(NewRegistry()), WithWatermarks(0.95, 0.9))
+	defer g.Close()
+
+	// Up to the high watermark nothing is evicted
+	for i := 0; i < 95; i++ {
+		g.Set(fmt.Sprintf("key-%05d", i), watermarkValue, 0)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if st := g.Stats(); st.Cost != 9500 || st.WatermarkRuns != 0 || st.Evictions != 0 {
+		t.Fatalf("stats at the high watermark = %+v", st)
+	}
+
+	// Crossing it wakes the sweeper, which evicts the oldest entries down to the low one
+	g.Set("key-00095", watermarkValue, 0)
+	waitFor(t, "the sweeper to reach the low watermark", func() bool { return g.Stats().Cost <= 9000 })
+	st := g.Stats()
+	if st.Cost != 9000 || st.WatermarkRuns != 1 || st.WatermarkEvictions != 6 || st.Evictions != 6 {
+		t.Fatalf("stats after the sweep = %+v", st)
+	}
+	if _, _, ok := g.Peek("key-00000"); ok {
+		t.Fatal("the oldest entry survived the sweep")
+	}
+	if _, _, ok := g.Peek("key-00095"); !ok {
+		t.Fatal("the newest entry was evicted")
+	}
+}
+
+// TestWatermarksHardCap fills the cache faster than the sweeper can keep up:
+// the size limit still holds on every write
+func TestWatermarksHardCap(t *testing.T) {
+	g := NewGroup(fmt.Sprintf("watermarks-cap-%d", time.Now().UnixNano()), 10000, GetterFunc(loadValue), time.Hour,
+		WithRegistry(NewRegistry()), WithWatermarks(0.95, 0.9))
+	defer g.Close()
+	for i := 0; i < 1000; i++ {
+		g.Set(fmt.Sprintf("key-%05d", i), watermarkValue, 0)
+		if cost := g.Stats().Cost; cost > 10000 {
+			t.Fatalf("cost %d above the limit after write %d", cost, i)
+		}
+	}
+	waitFor(t, "the sweeper to reach the low watermark", func() bool { return g.Stats().Cost <= 9000 })
+	if st := g.Stats(); st.Evictions != 910 || st.WatermarkEvictions > st.Evictions {
+		t.Fatalf("stats = %+v", st)
+	}
+}
+
+func TestWatermarksInvalid(t *testing.T) {
+	tests := []struct {
+		name      string
+		limit     int64
+		high, low float64
+	}{
+		{"low above high", 10000, 0.9, 0.95},
+		{"equal", 10000, 0.9, 0.9},
+		{"high above 1", 10000, 1.1, 0.9},
+		{"low zero", 10000, 0.95, 0},
+		{"no limit", 0, 0.95, 0.9},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewGroup(fmt.Sprintf("watermarks-invalid-%d", time.Now().UnixNano()), tt.limit, GetterFunc(loadValue), time.Hour,
+				WithRegistry(NewRegistry()), WithWatermarks(tt.high, tt.low))
+			defer g.Close()
+			if g.mainCache.water != nil {
+				t.Fatalf("watermarks %v/%v enabled", tt.high, tt.low)
+			}
+		})
+	}
+}
+
+// benchmarkAddAtCapacity writes new keys into a full group and reports the
+// percentiles of the write latency
+func benchmarkAddAtCapacity(b *testing.B, opts ...GroupOption) {
+	const limit = 1 << 20
+	g := NewGroup(fmt.Sprintf("capacity-%d", time.Now().UnixNano()), limit, GetterFunc(loadValue), time.Hour,
+		append([]GroupOption{WithRegistry(NewRegistry())}, opts...)...)
+	defer g.Close()
+	value := bytes.Repeat([]byte("v"), 1000)
+	for i := 0; g.Stats().Evictions == 0; i++ {
+		g.Set(fmt.Sprintf("fill-%d", i), value, 0)
+	}
+
+	keys := make([]string, b.N)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key-%d", i)
+	}
+	latencies := make([]time.Duration, b.N)
+	b.ResetTimer()
+	for i, key := range keys {
+		start := time.Now()
+		g.Set(key, value, 0)
+		latencies[i] = time.Since(start)
+		// Writes arrive with gaps, in which the sweeper gets to run
+		if i%16 == 0 {
+			runtime.Gosched()
+		}
+	}
+	b.StopTimer()
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	b.ReportMetric(float64(latencies[b.N/2].Nanoseconds()), "p50-ns")
+	b.ReportMetric(float64(latencies[b.N*99/100].Nanoseconds()), "p99-ns")
+	st := g.Stats()
+	if st.Evictions > 0 {
+		b.ReportMetric(float64(st.WatermarkEvictions)/float64(st.Evictions)*100, "%background")
+	}
+}
+
+// BenchmarkAddAtCapacity compares the write latency of a full group evicting on
+// every write with one evicting in the background between watermarks
+func BenchmarkAddAtCapacity(b *testing.B) {
+	logger.SetLevel("error")
+	defer logger.SetLevel("debug")
+	b.Run("inline", func(b *testing.B) { benchmarkAddAtCapacity(b) })
+	b.Run("watermarks", func(b *testing.B) { benchmarkAddAtCapacity(b, WithWatermarks(0.95, 0.9)) })
+}
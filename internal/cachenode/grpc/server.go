@@ -2,27 +2,161 @@ package grpc
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net"
+	"strconv"
+	"time"
 
+	"github.com/AdrianWangs/go-cache/internal/admin"
+	"github.com/AdrianWangs/go-cache/internal/auth"
 	"github.com/AdrianWangs/go-cache/internal/cache"
+	"github.com/AdrianWangs/go-cache/internal/peers"
+	"github.com/AdrianWangs/go-cache/pkg/cacheerrors"
 	"github.com/AdrianWangs/go-cache/pkg/logger"
 	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 )
 
 // CacheServer 实现缓存节点的gRPC服务
 type CacheServer struct {
 	pb.UnimplementedGroupCacheServer
-	server *grpc.Server
-	addr   string
+	server    *grpc.Server
+	addr      string
+	startTime time.Time // 服务创建时间，用于计算运行时长
+
+	peerStats func() map[string]peers.Stats // 本节点发往各对等节点的请求统计，可为空
+	nodeID    string                        // 本节点在哈希环上的标识，默认为监听地址
+	hideID    bool                          // 响应中不返回节点标识，见 WithExposeIdentity
+	maxHops   int                           // 请求已被转发达到该次数时不再转发，只在本地应答
+	verifier  *auth.Verifier                // 校验请求签名，为 nil 时不校验
+	info      func() admin.Info             // Info 返回的组件信息
+
+	servable *cache.GroupAllowlist // Get 允许读取的组，为 nil 时不限制
+
+	ownedLister func(*pb.ListOwnedRequest) (*pb.ListOwnedResponse, error) // ListOwnedBy 的实现，为空时返回 Unimplemented
+	warmupStats func() *pb.WarmupStats                                    // Stats 响应中预热进度的来源，可为空
+	primeStats  func() *pb.PrimeStats                                     // Stats 响应中按清单预加载进度的来源，可为空
+
+	ringGeneration func() uint64 // Stats 响应中哈希环代数的来源，可为空
+}
+
+// ServerOption 配置 CacheServer
+type ServerOption func(*CacheServer)
+
+// WithPeerStats 设置 Stats 响应中对等节点请求统计的来源，通常为 HTTPPool.PeerStats
+func WithPeerStats(fn func() map[string]peers.Stats) ServerOption {
+	return func(s *CacheServer) {
+		s.peerStats = fn
+	}
+}
+
+// WithNodeID 设置本节点在哈希环上的标识，用于转发环路的告警日志
+func WithNodeID(id string) ServerOption {
+	return func(s *CacheServer) {
+		s.nodeID = id
+	}
+}
+
+// WithExposeIdentity 设置 Get 是否在响应的 node 字段中返回产生该值的节点：本节点缓存或加载的值为本节点标识，
+// 取自对等节点的值为该对等节点的标识。默认开启；把拓扑信息视为敏感的部署在所有节点上关闭
+func WithExposeIdentity(expose bool) ServerOption {
+	return func(s *CacheServer) {
+		s.hideID = !expose
+	}
+}
+
+// WithMaxHops 设置请求最多可被转发的次数，达到后本节点不再转发，直接从本地缓存或数据源应答。
+// 默认为 peers.DefaultMaxHops
+func WithMaxHops(n int) ServerOption {
+	return func(s *CacheServer) {
+		if n > 0 {
+			s.maxHops = n
+		}
+	}
+}
+
+// WithVerifier 要求每个请求带有能通过 v 校验的签名，校验失败返回 Unauthenticated
+func WithVerifier(v *auth.Verifier) ServerOption {
+	return func(s *CacheServer) {
+		s.verifier = v
+	}
+}
+
+// WithInfo 设置 Info 返回的配置与构建信息，与 HTTP 服务器的 /api/admin/info 相同。
+// 未设置时只报告构建信息和服务器的创建时间
+func WithInfo(fn func() admin.Info) ServerOption {
+	return func(s *CacheServer) {
+		s.info = fn
+	}
+}
+
+// WithServableGroups 限制 Get 只能读取 names 中的组，其他组无论是否存在都返回 PermissionDenied，
+// 使只供节点内部使用的组不会经对等节点或 API 服务器被读取。names 为空时不限制
+func WithServableGroups(names ...string) ServerOption {
+	return WithGroupAllowlist(cache.NewGroupAllowlist(names...))
+}
+
+// WithGroupAllowlist 与 WithServableGroups 相同，但使用调用方持有的允许列表，
+// 以便运行时替换，或与 HTTPPool 共用同一份列表
+func WithGroupAllowlist(a *cache.GroupAllowlist) ServerOption {
+	return func(s *CacheServer) {
+		s.servable = a
+	}
+}
+
+// WithOwnedLister 设置 ListOwnedBy 的实现，通常为 HTTPPool.ListOwned：key 的归属由节点间路由的
+// 哈希环决定，gRPC 服务器自身没有哈希环
+func WithOwnedLister(fn func(*pb.ListOwnedRequest) (*pb.ListOwnedResponse, error)) ServerOption {
+	return func(s *CacheServer) {
+		s.ownedLister = fn
+	}
+}
+
+// WithWarmupStats 设置 Stats 响应中预热进度的来源，通常为 HTTPPool.WarmupStats
+func WithWarmupStats(fn func() *pb.WarmupStats) ServerOption {
+	return func(s *CacheServer) {
+		s.warmupStats = fn
+	}
+}
+
+// WithPrimeStats 设置 Stats 响应中按清单预加载进度的来源，通常为 HTTPPool.PrimeStats
+func WithPrimeStats(fn func() *pb.PrimeStats) ServerOption {
+	return func(s *CacheServer) {
+		s.primeStats = fn
+	}
+}
+
+// WithRingGeneration 设置 Stats 响应中哈希环代数的来源，通常为 HTTPPool.RingGeneration
+func WithRingGeneration(fn func() uint64) ServerOption {
+	return func(s *CacheServer) {
+		s.ringGeneration = fn
+	}
 }
 
 // NewCacheServer 创建一个新的gRPC缓存服务器
-func NewCacheServer(addr string) *CacheServer {
-	return &CacheServer{
-		addr: addr,
+func NewCacheServer(addr string, opts ...ServerOption) *CacheServer {
+	s := &CacheServer{
+		addr:      addr,
+		startTime: time.Now(),
+		maxHops:   peers.DefaultMaxHops,
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
+	if s.nodeID == "" {
+		s.nodeID = addr
+	}
+	if s.info == nil {
+		s.info = (&admin.InfoSource{Component: "cachenode", StartTime: s.startTime}).Info
+	}
+	return s
 }
 
 // Start 启动gRPC服务器
@@ -32,7 +166,17 @@ func (s *CacheServer) Start() error {
 		return fmt.Errorf("无法监听地址 %s: %v", s.addr, err)
 	}
 
-	s.server = grpc.NewServer()
+	opts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(protoVersionUnary),
+		grpc.ChainStreamInterceptor(protoVersionStream),
+	}
+	if s.verifier != nil {
+		opts = append(opts,
+			grpc.UnaryInterceptor(s.verifier.UnaryServerInterceptor()),
+			grpc.StreamInterceptor(s.verifier.StreamServerInterceptor()),
+		)
+	}
+	s.server = grpc.NewServer(opts...)
 	pb.RegisterGroupCacheServer(s.server, s)
 
 	logger.Infof("gRPC缓存服务器正在监听：%s", s.addr)
@@ -45,6 +189,21 @@ func (s *CacheServer) Start() error {
 	return nil
 }
 
+// protoVersionHeader 每个响应头中携带的协议版本，客户端据此判断节点支持的操作
+var protoVersionHeader = metadata.Pairs(peers.MetadataProtoVersion, strconv.Itoa(peers.ProtocolVersion))
+
+// protoVersionUnary 在一元调用的响应头中声明协议版本
+func protoVersionUnary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	grpc.SetHeader(ctx, protoVersionHeader)
+	return handler(ctx, req)
+}
+
+// protoVersionStream 在流式调用的响应头中声明协议版本
+func protoVersionStream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ss.SetHeader(protoVersionHeader)
+	return handler(srv, ss)
+}
+
 // Stop 停止gRPC服务器
 func (s *CacheServer) Stop() {
 	if s.server != nil {
@@ -55,36 +214,213 @@ func (s *CacheServer) Stop() {
 
 // Get 实现gRPC的Get方法，从缓存中获取值
 func (s *CacheServer) Get(ctx context.Context, req *pb.Request) (*pb.Response, error) {
+	// 先检查允许列表，不在列表中的组无论是否存在都同样拒绝
+	if !s.servable.Allows(req.Group) {
+		return nil, statusError(fmt.Errorf("%w: %s", cache.ErrGroupForbidden, req.Group))
+	}
 	group := cache.GetGroup(req.Group)
 	if group == nil {
-		return nil, fmt.Errorf("未找到组: %s", req.Group)
+		return nil, groupNotFound(req.Group)
 	}
 
-	// 从缓存获取值
-	val, err := group.Get(req.Key)
+	if req.GetCacheOnly() {
+		// 只读取本地缓存，供接管 key 的节点预热，不转发也不回源
+		val, meta, ok := group.Peek(req.Key)
+		if !ok {
+			return nil, statusError(fmt.Errorf("%w: %s", cache.ErrNotFound, req.Key))
+		}
+		resp := &pb.Response{Value: val.ByteSlice()}
+		meta.Attribute(s.identity()).FillProto(resp)
+		return resp, nil
+	}
+
+	// 从缓存获取值；未携带跳数的请求视为来自客户端
+	ctx = peers.WithForwarding(ctx, peers.NewForwarding(s.nodeID, req.GetHops(), req.GetFrom(), s.maxHops))
+	if p, ok := peer.FromContext(ctx); ok {
+		ctx = cache.WithClient(ctx, peers.ClientHost(p.Addr.String()))
+	}
+	val, meta, err := group.GetWithMeta(ctx, req.Key)
 	if err != nil {
-		return nil, err
+		// 限流、没有可用节点和数据源熔断各有状态码，调用方据此决定是否重连重试
+		return nil, statusError(err)
 	}
 
-	return &pb.Response{
+	resp := &pb.Response{
 		Value: val.ByteSlice(),
-	}, nil
+	}
+	meta.Attribute(s.identity()).FillProto(resp)
+	return resp, nil
+}
+
+// identity 返回响应中报告的本节点标识，不公开标识时为空
+func (s *CacheServer) identity() string {
+	if s.hideID {
+		return ""
+	}
+	return s.nodeID
 }
 
 // Delete 实现gRPC的Delete方法，从缓存中删除值
 func (s *CacheServer) Delete(ctx context.Context, req *pb.DeleteRequest) (*pb.DeleteResponse, error) {
 	group := cache.GetGroup(req.Group)
 	if group == nil {
-		return nil, fmt.Errorf("未找到组: %s", req.Group)
+		return nil, groupNotFound(req.Group)
 	}
 
-	// 从缓存删除值
-	err := group.Delete(req.Key)
+	// 从本节点缓存删除值：请求已由调用方路由到归属节点，不再转发
+	err := group.DeleteLocally(req.Key)
 	if err != nil {
-		return nil, err
+		return nil, statusError(err)
 	}
 
 	return &pb.DeleteResponse{
 		Success: true,
 	}, nil
 }
+
+// DeleteBatch 实现gRPC的DeleteBatch方法，按请求顺序返回每个键的删除结果
+func (s *CacheServer) DeleteBatch(ctx context.Context, req *pb.DeleteBatchRequest) (*pb.DeleteBatchResponse, error) {
+	group := cache.GetGroup(req.GetGroup())
+	if group == nil {
+		return nil, groupNotFound(req.GetGroup())
+	}
+
+	results, err := group.DeleteBatch(req.GetKeys())
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return cache.DeleteResultsProto(results), nil
+}
+
+// Stats 实现gRPC的Stats方法，返回指定组（为空时为全部组）的统计信息
+func (s *CacheServer) Stats(ctx context.Context, req *pb.StatsRequest) (*pb.StatsResponse, error) {
+	resp, err := cache.StatsResponse(req.GetGroup(), time.Since(s.startTime))
+	if err != nil {
+		if cache.IsGroupNotFoundError(err) {
+			return nil, groupNotFound(req.GetGroup())
+		}
+		return nil, err
+	}
+	if s.peerStats != nil {
+		resp.Peers = peers.StatsProto(s.peerStats())
+	}
+	if s.primeStats != nil {
+		resp.Prime = s.primeStats()
+	}
+	if s.warmupStats != nil {
+		resp.Warmup = s.warmupStats()
+	}
+	if s.ringGeneration != nil {
+		resp.RingGeneration = proto.Uint64(s.ringGeneration())
+	}
+	return resp, nil
+}
+
+// ListOwnedBy 实现gRPC的ListOwnedBy方法，列出本节点缓存的、哈希环上归属请求节点的 key，
+// 供新加入哈希环的节点预热。调用方的哈希环版本与本节点不同时返回 FailedPrecondition
+func (s *CacheServer) ListOwnedBy(ctx context.Context, req *pb.ListOwnedRequest) (*pb.ListOwnedResponse, error) {
+	if s.ownedLister == nil {
+		return nil, status.Error(codes.Unimplemented, "本节点未提供 ListOwnedBy")
+	}
+	resp, err := s.ownedLister(req)
+	if err != nil {
+		switch {
+		case cache.IsGroupForbiddenError(err), cache.IsGroupNotFoundError(err):
+			return nil, statusError(fmt.Errorf("%w: %s", err, req.GetGroup()))
+		case errors.Is(err, peers.ErrRingMismatch):
+			return nil, status.Error(codes.FailedPrecondition, err.Error())
+		}
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return resp, nil
+}
+
+// Info 实现gRPC的Info方法，返回节点生效的配置（已脱敏）与构建信息，供只使用 gRPC 的部署排查问题
+func (s *CacheServer) Info(ctx context.Context, req *pb.InfoRequest) (*pb.InfoResponse, error) {
+	info := s.info()
+	return &pb.InfoResponse{
+		Component:         info.Component,
+		Version:           proto.String(info.Build.Version),
+		Commit:            proto.String(info.Build.Commit),
+		BuildDate:         proto.String(info.Build.Date),
+		GoVersion:         proto.String(info.Build.GoVersion),
+		StartTimeUnixNano: proto.Int64(info.StartTime.UnixNano()),
+		Config:            info.Config,
+		DiscoveryMode:     proto.String(info.Discovery.Mode),
+		DiscoveryState:    proto.String(info.Discovery.State),
+		ProtocolVersion:   proto.Int32(int32(info.ProtocolVersion)),
+		ServableGroups:    info.ServableGroups,
+	}, nil
+}
+
+// Export 实现gRPC的Export方法，以流的形式返回组内所有未过期的条目
+func (s *CacheServer) Export(req *pb.ExportRequest, stream pb.GroupCache_ExportServer) error {
+	group := cache.GetGroup(req.Group)
+	if group == nil {
+		return groupNotFound(req.Group)
+	}
+
+	// Send 在发送窗口满时阻塞，从而对导出施加背压
+	err := group.Export(func(e cache.ExportEntry) error {
+		return stream.Send(e.Proto())
+	})
+	if err != nil {
+		logger.Warnf("导出组 %s 失败: %v", req.Group, err)
+		return err
+	}
+	return nil
+}
+
+// Import 实现gRPC的Import方法，将流中的条目写入对应的组
+func (s *CacheServer) Import(stream pb.GroupCache_ImportServer) error {
+	// 第一条消息确定目标组
+	first, err := stream.Recv()
+	if err == io.EOF {
+		return stream.SendAndClose(&pb.ImportResponse{})
+	}
+	if err != nil {
+		return err
+	}
+
+	group := cache.GetGroup(first.Group)
+	if group == nil {
+		return groupNotFound(first.Group)
+	}
+
+	pending := first
+	result, err := group.Import(func() (cache.ExportEntry, error) {
+		req := pending
+		if req == nil {
+			if req, err = stream.Recv(); err != nil {
+				return cache.ExportEntry{}, err
+			}
+		}
+		pending = nil
+
+		if req.Group != first.Group {
+			return cache.ExportEntry{}, status.Errorf(codes.InvalidArgument, "同一导入流中的组不一致: %s", req.Group)
+		}
+		return cache.ExportEntryFromProto(req.GetEntry()), nil
+	})
+	if err != nil {
+		logger.Warnf("导入组 %s 失败: %v", first.Group, err)
+		return statusError(err)
+	}
+
+	logger.Infof("导入组 %s 完成: 导入 %d, 过期 %d, 跳过 %d", first.Group, result.Imported, result.Expired, result.Skipped)
+	return stream.SendAndClose(&pb.ImportResponse{
+		Imported: proto.Int64(result.Imported),
+		Expired:  proto.Int64(result.Expired),
+		Skipped:  proto.Int64(result.Skipped),
+	})
+}
+
+// statusError 把缓存错误转换为 gRPC 状态错误，状态码和信息见 cacheerrors.GRPCStatus
+func statusError(err error) error {
+	return cacheerrors.GRPCStatus(err).Err()
+}
+
+// groupNotFound 返回组不存在的 gRPC 状态错误
+func groupNotFound(name string) error {
+	return statusError(fmt.Errorf("%w: %s", cache.ErrNoSuchGroup, name))
+}
@@ -0,0 +1,147 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/internal/cache"
+	"github.com/AdrianWangs/go-cache/pkg/cacheerrors"
+	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// newServableGroup 在默认注册表中创建组，测试结束时关闭
+func newServableGroup(t *testing.T, name string) {
+	t.Helper()
+	g := cache.NewGroup(name, 1<<20, cache.GetterFunc(func(key string) ([]byte, error) {
+		return []byte("v:" + key), nil
+	}), time.Hour)
+	t.Cleanup(func() { g.Close() })
+}
+
+// TestGetServableGroups 允许列表中的组正常读取，列表外的组无论是否存在都返回 PermissionDenied，
+// 列表中不存在的组返回 NotFound；替换列表后下一次请求即生效
+func TestGetServableGroups(t *testing.T) {
+	newServableGroup(t, "servable-public")
+	newServableGroup(t, "servable-internal")
+	servable := cache.NewGroupAllowlist("servable-public", "servable-ghost")
+	s := NewCacheServer("127.0.0.1:0", WithGroupAllowlist(servable))
+
+	get := func(group string) (*pb.Response, error) {
+		return s.Get(context.Background(), &pb.Request{Group: group, Key: "k"})
+	}
+	tests := []struct {
+		name  string
+		group string
+		code  codes.Code
+	}{
+		{"允许的组", "servable-public", codes.OK},
+		{"列表外的组", "servable-internal", codes.PermissionDenied},
+		{"列表外且不存在的组", "servable-missing", codes.PermissionDenied},
+		{"列表中不存在的组", "servable-ghost", codes.NotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := get(tt.group)
+			if status.Code(err) != tt.code {
+				t.Fatalf("Get(%s) = %v, want %s", tt.group, err, tt.code)
+			}
+			if tt.code == codes.OK && string(resp.GetValue()) != "v:k" {
+				t.Fatalf("值 = %q", resp.GetValue())
+			}
+			if tt.code == codes.PermissionDenied && !cacheerrors.IsGroupForbiddenError(cacheerrors.ErrorFromGRPC(err)) {
+				t.Fatalf("拒绝的错误不能识别为 ErrGroupForbidden: %v", err)
+			}
+		})
+	}
+
+	servable.Set("servable-internal")
+	if _, err := get("servable-internal"); err != nil {
+		t.Fatalf("重新加载后允许的组: %v", err)
+	}
+	if _, err := get("servable-public"); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("重新加载后列表外的组 = %v", err)
+	}
+
+	// 不设置允许列表时不限制
+	if _, err := NewCacheServer("127.0.0.1:0").Get(context.Background(), &pb.Request{Group: "servable-internal", Key: "k"}); err != nil {
+		t.Fatalf("没有允许列表: %v", err)
+	}
+}
+
+// TestGetReportsNode 响应的 node 字段为本节点标识，关闭 WithExposeIdentity 后不返回
+func TestGetReportsNode(t *testing.T) {
+	newServableGroup(t, "identity")
+	tests := []struct {
+		name string
+		opts []ServerOption
+		want string
+	}{
+		{"公开标识", []ServerOption{WithNodeID("node-1")}, "node-1"},
+		{"不公开标识", []ServerOption{WithNodeID("node-1"), WithExposeIdentity(false)}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewCacheServer("127.0.0.1:0", tt.opts...)
+			// 第一次读取回源，第二次命中缓存，仅读取缓存的请求同样报告标识
+			for _, req := range []*pb.Request{
+				{Group: "identity", Key: "k"},
+				{Group: "identity", Key: "k"},
+				{Group: "identity", Key: "k", CacheOnly: proto.Bool(true)},
+			} {
+				resp, err := s.Get(context.Background(), req)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if resp.GetNode() != tt.want {
+					t.Fatalf("来源 %s 的 node = %q, want %q", resp.GetSource(), resp.GetNode(), tt.want)
+				}
+			}
+		})
+	}
+}
+
+// TestStats 按组过滤时只返回该组的计数，组不存在时返回 NotFound；设置了对应选项时附带环代数
+func TestStats(t *testing.T) {
+	newServableGroup(t, "stats-a")
+	newServableGroup(t, "stats-b")
+	s := NewCacheServer("127.0.0.1:0", WithRingGeneration(func() uint64 { return 7 }))
+	ctx := context.Background()
+	if _, err := s.Get(ctx, &pb.Request{Group: "stats-a", Key: "k"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get(ctx, &pb.Request{Group: "stats-a", Key: "k"}); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := s.Stats(ctx, &pb.StatsRequest{Group: proto.String("stats-a")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.GetGroups()) != 1 || resp.GetRingGeneration() != 7 || resp.UptimeSeconds == nil {
+		t.Fatalf("Stats = %v", resp)
+	}
+	gs := resp.GetGroups()[0]
+	if gs.GetName() != "stats-a" || gs.GetGets() != 2 || gs.GetHits() != 1 || gs.GetMisses() != 1 || gs.GetEntries() != 1 {
+		t.Fatalf("组统计 = %v", gs)
+	}
+
+	all, err := s.Stats(ctx, &pb.StatsRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make(map[string]bool)
+	for _, gs := range all.GetGroups() {
+		names[gs.GetName()] = true
+	}
+	if !names["stats-a"] || !names["stats-b"] {
+		t.Fatalf("不过滤时的组 = %v", names)
+	}
+
+	if _, err := s.Stats(ctx, &pb.StatsRequest{Group: proto.String("stats-missing")}); status.Code(err) != codes.NotFound {
+		t.Fatalf("不存在的组 = %v, want NotFound", err)
+	}
+}
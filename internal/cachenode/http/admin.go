@@ -0,0 +1,279 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/AdrianWangs/go-cache/internal/admin"
+	"github.com/AdrianWangs/go-cache/internal/cache"
+	"github.com/AdrianWangs/go-cache/pkg/logger"
+)
+
+// exportFlushEvery 导出时每写出多少条目刷新一次响应
+const exportFlushEvery = 100
+
+// adminGroupHandler 处理 /api/admin/groups/{group}/{action} 请求
+func (s *Server) adminGroupHandler(w http.ResponseWriter, r *http.Request) {
+	if !admin.Authorize(w, r, s.adminToken) {
+		return
+	}
+
+	groupName, action, ok := parseAdminGroupPath(r.URL.EscapedPath())
+	if !ok {
+		http.Error(w, "Bad Request: expected /api/admin/groups/{group}/{export|import|ratelimit|clientratelimit|hotkeys}", http.StatusBadRequest)
+		return
+	}
+
+	group := cache.GetGroup(groupName)
+	if group == nil {
+		http.Error(w, fmt.Sprintf("Group not found: %s", groupName), http.StatusNotFound)
+		return
+	}
+
+	switch {
+	case action == "ratelimit":
+		rateLimitHandler(w, r, group.RateLimit, group.SetRateLimit)
+	case action == "clientratelimit":
+		rateLimitHandler(w, r, group.ClientRateLimit, group.SetClientRateLimit)
+	case action == "hotkeys":
+		hotKeysHandler(w, r, group)
+	case action == "export" && r.Method == http.MethodGet:
+		s.withAdminSlot(w, func() { s.exportGroup(w, r, group) })
+	case action == "import" && r.Method == http.MethodPost:
+		s.withAdminSlot(w, func() { s.importGroup(w, r, group) })
+	case action == "export" || action == "import":
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	default:
+		http.Error(w, fmt.Sprintf("Unknown action: %s", action), http.StatusNotFound)
+	}
+}
+
+// adminRateLimitHandler 处理 /api/admin/ratelimit 请求，查看或调整节点级的合计限流
+func (s *Server) adminRateLimitHandler(w http.ResponseWriter, r *http.Request) {
+	if !admin.Authorize(w, r, s.adminToken) {
+		return
+	}
+	rateLimitHandler(w, r, cache.NodeRateLimit, cache.SetNodeRateLimit)
+}
+
+// adminRingHandler 处理 /api/admin/ring 请求，报告节点间路由使用的哈希环
+func (s *Server) adminRingHandler(w http.ResponseWriter, r *http.Request) {
+	if !admin.Authorize(w, r, s.adminToken) {
+		return
+	}
+	admin.RingHandler(w, r, s.ring)
+}
+
+// adminInfoHandler 处理 /api/admin/info 请求，返回节点生效的配置（已脱敏）与构建信息
+func (s *Server) adminInfoHandler(w http.ResponseWriter, r *http.Request) {
+	if !admin.Authorize(w, r, s.adminToken) {
+		return
+	}
+	admin.InfoHandler(w, r, s.info)
+}
+
+// modeRequest /api/admin/mode 的请求与响应
+type modeRequest struct {
+	Mode  string `json:"mode"`            // readwrite / readonly / readonly-local
+	Group string `json:"group,omitempty"` // 为空时设置节点级模式
+}
+
+// modeResponse /api/admin/mode 的响应
+type modeResponse struct {
+	Mode   string            `json:"mode"`   // 节点级模式
+	Groups map[string]string `json:"groups"` // 各组当前生效的模式
+}
+
+// adminModeHandler 处理 /api/admin/mode 请求：GET 返回节点及各组的模式，
+// PUT {"mode":"readonly"} 切换节点级模式，带 group 字段时只切换该组
+func (s *Server) adminModeHandler(w http.ResponseWriter, r *http.Request) {
+	if !admin.Authorize(w, r, s.adminToken) {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var req modeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("Bad Request: %v", err), http.StatusBadRequest)
+			return
+		}
+		mode, err := cache.ParseMode(req.Mode)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Bad Request: %v", err), http.StatusBadRequest)
+			return
+		}
+		if req.Group == "" {
+			cache.SetNodeMode(mode)
+			logger.Infof("节点模式已切换为 %s", mode)
+		} else {
+			group := cache.GetGroup(req.Group)
+			if group == nil {
+				http.Error(w, fmt.Sprintf("Group not found: %s", req.Group), http.StatusNotFound)
+				return
+			}
+			group.SetMode(mode)
+			logger.Infof("缓存组 %s 的模式已切换为 %s", req.Group, mode)
+		}
+		if s.onModeChange != nil {
+			s.onModeChange()
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	resp := modeResponse{
+		Mode:   cache.NodeMode().String(),
+		Groups: make(map[string]string),
+	}
+	for _, info := range cache.ListGroups() {
+		resp.Groups[info.Name] = info.Mode
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// rateLimitHandler GET 返回当前限流配置，PUT 以 JSON {"rate":..,"burst":..} 替换配置，rate 为 0 表示取消限流
+func rateLimitHandler(w http.ResponseWriter, r *http.Request, get func() cache.RateLimit, set func(cache.RateLimit)) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var limit cache.RateLimit
+		if err := json.NewDecoder(r.Body).Decode(&limit); err != nil {
+			http.Error(w, fmt.Sprintf("Bad Request: %v", err), http.StatusBadRequest)
+			return
+		}
+		if limit.Rate < 0 || limit.Burst < 0 {
+			http.Error(w, "Bad Request: rate and burst must not be negative", http.StatusBadRequest)
+			return
+		}
+		set(limit)
+		logger.Infof("限流配置已更新: %s rate=%.2f burst=%d", r.URL.Path, limit.Rate, limit.Burst)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(get())
+}
+
+// defaultHotKeys /api/admin/groups/{group}/hotkeys 未指定 n 时返回的热点 key 数量
+const defaultHotKeys = 20
+
+// hotKeysHandler 处理 GET /api/admin/groups/{group}/hotkeys?n=，返回组内读取最多的 n 个 key、
+// 当前复制到其他节点的热点 key 以及最近的复制事件
+func hotKeysHandler(w http.ResponseWriter, r *http.Request, group *cache.Group) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	n := defaultHotKeys
+	if v := r.URL.Query().Get("n"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "Bad Request: n must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		n = parsed
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(group.HotKeys(n))
+}
+
+// withAdminSlot 在限流器允许时执行管理操作，否则返回 429
+func (s *Server) withAdminSlot(w http.ResponseWriter, fn func()) {
+	if !s.adminLimiter.TryAcquire() {
+		http.Error(w, "Too Many Requests: another admin operation is running", http.StatusTooManyRequests)
+		return
+	}
+	defer s.adminLimiter.Release()
+	fn()
+}
+
+// exportGroup 以 ndjson 流式导出组内条目，写出阻塞时自然形成背压
+func (s *Server) exportGroup(w http.ResponseWriter, r *http.Request, group *cache.Group) {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	flusher, _ := w.(http.Flusher)
+
+	enc := cache.NewEntryEncoder(w)
+	count := 0
+	err := group.Export(func(e cache.ExportEntry) error {
+		if err := s.adminLimiter.Wait(r.Context()); err != nil {
+			return err
+		}
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+		count++
+		if count%exportFlushEvery == 0 {
+			if err := enc.Flush(); err != nil {
+				return err
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		return nil
+	})
+	if err == nil {
+		err = enc.Flush()
+	}
+	if err != nil {
+		// 响应头已经发出，只能中断流并记录日志
+		logger.Warnf("导出组 %s 中断: %v", group.Name(), err)
+		return
+	}
+	logger.Infof("导出组 %s 完成，共 %d 个条目", group.Name(), count)
+}
+
+// importGroup 读取 ndjson 条目并写入组，返回导入结果
+func (s *Server) importGroup(w http.ResponseWriter, r *http.Request, group *cache.Group) {
+	dec := cache.NewEntryDecoder(r.Body)
+	result, err := group.Import(func() (cache.ExportEntry, error) {
+		if err := s.adminLimiter.Wait(r.Context()); err != nil {
+			return cache.ExportEntry{}, err
+		}
+		return dec.Next()
+	})
+	if err != nil {
+		logger.Warnf("导入组 %s 失败: %v", group.Name(), err)
+		if cache.IsReadOnlyError(err) {
+			http.Error(w, fmt.Sprintf("Service Unavailable: %v (imported %d entries before the error)", err, result.Imported),
+				http.StatusServiceUnavailable)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Bad Request: %v (imported %d entries before the error)", err, result.Imported),
+			http.StatusBadRequest)
+		return
+	}
+
+	logger.Infof("导入组 %s 完成: 导入 %d, 过期 %d, 跳过 %d", group.Name(), result.Imported, result.Expired, result.Skipped)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// parseAdminGroupPath 从转义后的路径中解析 /api/admin/groups/{group}/{action}
+func parseAdminGroupPath(escapedPath string) (group, action string, ok bool) {
+	const prefix = "/api/admin/groups/"
+	if !strings.HasPrefix(escapedPath, prefix) {
+		return "", "", false
+	}
+
+	parts := strings.Split(escapedPath[len(prefix):], "/")
+	if len(parts) != 2 || parts[0] == "" {
+		return "", "", false
+	}
+
+	group, err := url.PathUnescape(parts[0])
+	if err != nil {
+		return "", "", false
+	}
+	return group, parts[1], true
+}
@@ -0,0 +1,140 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/internal/cache"
+)
+
+const testAdminToken = "secret"
+
+// newTestGroup 在默认注册表中创建组，测试结束时关闭
+func newTestGroup(t *testing.T, name string) *cache.Group {
+	t.Helper()
+	name = fmt.Sprintf("%s-%s-%d", t.Name(), name, time.Now().UnixNano())
+	g := cache.NewGroup(name, 1<<20, cache.GetterFunc(func(string) ([]byte, error) {
+		return nil, cache.ErrNotFound
+	}), time.Hour)
+	t.Cleanup(func() { g.Close() })
+	return g
+}
+
+// adminRequest 带令牌发出管理请求
+func adminRequest(t *testing.T, method, url string, body io.Reader) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+testAdminToken)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestExportImportOverHTTP(t *testing.T) {
+	s := NewServer(":0", WithAdminToken(testAdminToken))
+	srv := httptest.NewServer(s.adminMux)
+	t.Cleanup(srv.Close)
+
+	src := newTestGroup(t, "src")
+	for i := 0; i < 250; i++ {
+		if err := src.Set(fmt.Sprintf("key-%d", i), []byte(fmt.Sprintf("value-%d", i)), 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dst := newTestGroup(t, "dst")
+
+	resp := adminRequest(t, http.MethodGet, srv.URL+"/api/admin/groups/"+src.Name()+"/export", nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("export status = %d", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Fatalf("export Content-Type = %q", ct)
+	}
+	exported, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp = adminRequest(t, http.MethodPost, srv.URL+"/api/admin/groups/"+dst.Name()+"/import", bytes.NewReader(exported))
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		t.Fatalf("import status = %d: %s", resp.StatusCode, body)
+	}
+	var result cache.ImportResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatal(err)
+	}
+	if result != (cache.ImportResult{Imported: 250}) {
+		t.Fatalf("import result = %+v", result)
+	}
+	for i := 0; i < 250; i++ {
+		v, err := dst.Get(fmt.Sprintf("key-%d", i))
+		if err != nil || v.String() != fmt.Sprintf("value-%d", i) {
+			t.Fatalf("key-%d = %q, %v", i, v.String(), err)
+		}
+	}
+}
+
+func TestAdminGroupAuthAndLimits(t *testing.T) {
+	g := newTestGroup(t, "g")
+	exportPath := "/api/admin/groups/" + g.Name() + "/export"
+
+	// 未配置令牌时管理接口关闭
+	disabled := httptest.NewServer(NewServer(":0").adminMux)
+	t.Cleanup(disabled.Close)
+	resp := adminRequest(t, http.MethodGet, disabled.URL+exportPath, nil)
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("未配置令牌: status = %d, want 403", resp.StatusCode)
+	}
+
+	s := NewServer(":0", WithAdminToken(testAdminToken))
+	srv := httptest.NewServer(s.adminMux)
+	t.Cleanup(srv.Close)
+
+	resp, err := http.Get(srv.URL + exportPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("缺少令牌: status = %d, want 401", resp.StatusCode)
+	}
+
+	tests := []struct {
+		method string
+		path   string
+		want   int
+	}{
+		{http.MethodGet, "/api/admin/groups/no-such-group/export", http.StatusNotFound},
+		{http.MethodPost, exportPath, http.StatusMethodNotAllowed},
+		{http.MethodGet, "/api/admin/groups/" + g.Name() + "/import", http.StatusMethodNotAllowed},
+		{http.MethodGet, "/api/admin/groups/" + g.Name(), http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if resp := adminRequest(t, tt.method, srv.URL+tt.path, nil); resp.StatusCode != tt.want {
+			t.Errorf("%s %s: status = %d, want %d", tt.method, tt.path, resp.StatusCode, tt.want)
+		}
+	}
+
+	// 同一时间只允许一个导入或导出
+	if !s.adminLimiter.TryAcquire() {
+		t.Fatal("限流器没有空位")
+	}
+	resp = adminRequest(t, http.MethodGet, srv.URL+exportPath, nil)
+	s.adminLimiter.Release()
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("并发导出: status = %d, want 429", resp.StatusCode)
+	}
+}
@@ -0,0 +1,164 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/internal/admin"
+	"github.com/AdrianWangs/go-cache/internal/cache"
+)
+
+const (
+	// samplePathPrefix 抽样接口的路径前缀，完整路径为 /api/debug/sample/{group}
+	samplePathPrefix = "/api/debug/sample/"
+
+	defaultSampleSize   = 20        // 默认抽样条目数
+	maxSampleSize       = 1000      // 单次最多抽样的条目数
+	defaultSampleMaxLen = 256       // 返回值时默认截断到的字节数
+	maxSampleMaxLen     = 64 * 1024 // maxlen 的上限
+)
+
+// sampleResponse /api/debug/sample/{group} 的响应
+type sampleResponse struct {
+	Group   string        `json:"group"`   // 缓存组
+	Scanned int           `json:"scanned"` // 遍历到的未过期条目数
+	Entries []sampleEntry `json:"entries"` // 随机抽取的条目
+}
+
+// sampleEntry 抽样结果中的一个条目
+type sampleEntry struct {
+	Key        string     `json:"key,omitempty"`         // 原始 key
+	KeyDigest  string     `json:"key_digest,omitempty"`  // 键摘要模式下未保留原始 key 时的摘要（十六进制）
+	Size       int        `json:"size"`                  // 值的字节数
+	TTL        string     `json:"ttl,omitempty"`         // 剩余有效期，例如 4m12s，为空表示永不过期
+	LastAccess *time.Time `json:"last_access,omitempty"` // 最近一次读取的时间，未读取或未记录时为空
+	Accesses   uint64     `json:"accesses"`              // 读取次数，关闭访问统计时为 0
+	Value      []byte     `json:"value,omitempty"`       // 值（base64），只在 values=1 时返回
+	Truncated  bool       `json:"truncated,omitempty"`   // 值是否被截断到 maxlen
+}
+
+// sampleOptions 抽样请求的查询参数
+type sampleOptions struct {
+	n      int  // 抽样条目数
+	values bool // 是否返回值
+	maxLen int  // 返回值的最大字节数
+}
+
+// debugSampleHandler 处理 GET /api/debug/sample/{group}?n=20[&values=1&maxlen=256]，
+// 随机抽取组内的条目用于排查缓存内容，默认不返回值本身
+func (s *Server) debugSampleHandler(w http.ResponseWriter, r *http.Request) {
+	if !admin.Authorize(w, r, s.adminToken) {
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	groupName, ok := parseSamplePath(r.URL.EscapedPath())
+	if !ok {
+		http.Error(w, "Bad Request: expected /api/debug/sample/{group}", http.StatusBadRequest)
+		return
+	}
+	opts, err := parseSampleOptions(r.URL.Query())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Bad Request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	group := cache.GetGroup(groupName)
+	if group == nil {
+		http.Error(w, fmt.Sprintf("Group not found: %s", groupName), http.StatusNotFound)
+		return
+	}
+
+	// 抽样需要遍历整个组，与导入导出共用管理接口的并发限制
+	s.withAdminSlot(w, func() {
+		entries, scanned := group.Sample(opts.n, nil)
+		resp := sampleResponse{
+			Group:   groupName,
+			Scanned: scanned,
+			Entries: make([]sampleEntry, 0, len(entries)),
+		}
+		for _, e := range entries {
+			resp.Entries = append(resp.Entries, newSampleEntry(e, opts))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	})
+}
+
+// newSampleEntry 转换抽样条目，按需附带截断后的值
+func newSampleEntry(e cache.SampleEntry, opts sampleOptions) sampleEntry {
+	out := sampleEntry{
+		Key:       e.Key,
+		KeyDigest: e.KeyDigest,
+		Size:      e.Size,
+		Accesses:  e.Accesses,
+	}
+	if e.TTL > 0 {
+		out.TTL = e.TTL.String()
+	}
+	if !e.LastAccess.IsZero() {
+		t := e.LastAccess
+		out.LastAccess = &t
+	}
+	if opts.values {
+		value := e.Value.ByteSlice()
+		if len(value) > opts.maxLen {
+			value = value[:opts.maxLen]
+			out.Truncated = true
+		}
+		out.Value = value
+	}
+	return out
+}
+
+// parseSampleOptions 解析 n、values 和 maxlen 参数
+func parseSampleOptions(q url.Values) (sampleOptions, error) {
+	opts := sampleOptions{n: defaultSampleSize, maxLen: defaultSampleMaxLen}
+	if v := q.Get("n"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxSampleSize {
+			return opts, fmt.Errorf("n must be between 1 and %d", maxSampleSize)
+		}
+		opts.n = n
+	}
+	if v := q.Get("values"); v != "" {
+		values, err := strconv.ParseBool(v)
+		if err != nil {
+			return opts, fmt.Errorf("invalid values: %q", v)
+		}
+		opts.values = values
+	}
+	if v := q.Get("maxlen"); v != "" {
+		maxLen, err := strconv.Atoi(v)
+		if err != nil || maxLen <= 0 || maxLen > maxSampleMaxLen {
+			return opts, fmt.Errorf("maxlen must be between 1 and %d", maxSampleMaxLen)
+		}
+		opts.maxLen = maxLen
+	}
+	return opts, nil
+}
+
+// parseSamplePath 从转义后的路径中解析 /api/debug/sample/{group}
+func parseSamplePath(escapedPath string) (string, bool) {
+	if !strings.HasPrefix(escapedPath, samplePathPrefix) {
+		return "", false
+	}
+	rest := escapedPath[len(samplePathPrefix):]
+	if rest == "" || strings.Contains(rest, "/") {
+		return "", false
+	}
+	group, err := url.PathUnescape(rest)
+	if err != nil {
+		return "", false
+	}
+	return group, true
+}
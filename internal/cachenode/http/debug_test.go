@@ -0,0 +1,77 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDebugSample(t *testing.T) {
+	s := NewServer(":0", WithAdminToken(testAdminToken))
+	srv := httptest.NewServer(s.adminMux)
+	t.Cleanup(srv.Close)
+
+	g := newTestGroup(t, "sample")
+	for i := 0; i < 50; i++ {
+		if err := g.Set(fmt.Sprintf("key-%d", i), []byte(strings.Repeat("v", 100)), 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	base := srv.URL + samplePathPrefix + g.Name()
+
+	tests := []struct {
+		name    string
+		query   string
+		code    int
+		entries int
+		values  bool
+	}{
+		{"默认", "", http.StatusOK, 20, false},
+		{"指定数量", "?n=5", http.StatusOK, 5, false},
+		{"多于条目数", "?n=80", http.StatusOK, 50, false},
+		{"返回截断的值", "?n=3&values=1&maxlen=10", http.StatusOK, 3, true},
+		{"n 超出上限", "?n=1001", http.StatusBadRequest, 0, false},
+		{"maxlen 无效", "?maxlen=0", http.StatusBadRequest, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := adminRequest(t, http.MethodGet, base+tt.query, nil)
+			if resp.StatusCode != tt.code {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.code)
+			}
+			if tt.code != http.StatusOK {
+				return
+			}
+			var body sampleResponse
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			if body.Scanned != 50 || len(body.Entries) != tt.entries {
+				t.Fatalf("scanned %d, %d entries", body.Scanned, len(body.Entries))
+			}
+			for _, e := range body.Entries {
+				if e.Size != 100 || e.TTL == "" {
+					t.Fatalf("entry %+v", e)
+				}
+				if tt.values != (len(e.Value) == 10 && e.Truncated) || (!tt.values && e.Value != nil) {
+					t.Fatalf("entry value %q truncated=%v", e.Value, e.Truncated)
+				}
+			}
+		})
+	}
+
+	if resp := adminRequest(t, http.MethodGet, srv.URL+samplePathPrefix+"missing-group", nil); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("不存在的组 status = %d", resp.StatusCode)
+	}
+	resp, err := http.Get(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("无令牌 status = %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,90 @@
+package http
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/internal/cache"
+	"github.com/AdrianWangs/go-cache/internal/health"
+)
+
+// newHealthChecker 创建节点的健康检查：
+// 缓存组已初始化（关键）、节点列表（关键，单机运行时跳过）以及通过 WithHealthCheck 注册的组件。
+// 缓存组已创建、节点列表至少成功更新一次（或已应用种子列表）且 WithReadyCheck 的条件都满足后就绪
+func (s *Server) newHealthChecker() *health.Checker {
+	checker := health.NewChecker()
+
+	checker.Add("groups", true, func() health.Result {
+		names := cache.GroupNames()
+		details := map[string]interface{}{"groups": names}
+		if len(names) == 0 {
+			return health.Down("没有已初始化的缓存组", details)
+		}
+		return health.OK(details)
+	})
+
+	checker.Add("peers", true, s.checkPeers)
+
+	for _, chk := range s.healthChecks {
+		checker.Add(chk.name, chk.critical, chk.fn)
+	}
+
+	checker.AddReady(func() bool {
+		return len(cache.GroupNames()) > 0
+	})
+	checker.AddReady(func() bool {
+		if !s.peersInUse() {
+			return true
+		}
+		ps := s.peerStatus()
+		return ps.Seeded || !ps.LastSuccess.IsZero()
+	})
+	for _, fn := range s.readyChecks {
+		checker.AddReady(fn)
+	}
+	return checker
+}
+
+// checkPeers 检查节点列表：单机运行或节点列表尚未启用时总是正常；从未成功更新时降级（由 /ready 控制启动期间的流量）；
+// 超过 maxPeerSyncAge 未成功更新或节点列表为空时不可用
+func (s *Server) checkPeers() health.Result {
+	if !s.peersInUse() {
+		return health.OK(map[string]interface{}{"standalone": true})
+	}
+
+	ps := s.peerStatus()
+	details := map[string]interface{}{
+		"peers":                ps.Peers,
+		"seeded":               ps.Seeded,
+		"consecutive_failures": ps.ConsecutiveFailures,
+	}
+	if ps.LastError != "" {
+		details["last_error"] = ps.LastError
+	}
+
+	if ps.LastSuccess.IsZero() {
+		if ps.Seeded {
+			return health.Degraded("使用种子节点列表，尚未收到服务发现的结果", details)
+		}
+		return health.Degraded("尚未成功获取节点列表", details)
+	}
+
+	lag := ps.Lag(time.Now())
+	details["last_success"] = ps.LastSuccess
+	details["lag"] = lag.Round(time.Millisecond).String()
+	switch {
+	case lag > s.maxPeerSyncAge:
+		return health.Down(fmt.Sprintf("节点列表已 %v 未更新，超过 %v", lag.Round(time.Second), s.maxPeerSyncAge), details)
+	case ps.Peers == 0:
+		return health.Down("节点列表为空", details)
+	case ps.ConsecutiveFailures > 0:
+		return health.Degraded("最近的节点列表更新失败", details)
+	default:
+		return health.OK(details)
+	}
+}
+
+// peersInUse 报告是否检查节点列表：单机运行或节点列表尚未启用（见 WithPeersActive）时不检查
+func (s *Server) peersInUse() bool {
+	return s.peerStatus != nil && (s.peersActive == nil || s.peersActive())
+}
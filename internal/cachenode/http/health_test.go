@@ -0,0 +1,105 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/internal/cache"
+	"github.com/AdrianWangs/go-cache/internal/cachenode/peers"
+	"github.com/AdrianWangs/go-cache/internal/health"
+)
+
+// getHealth 请求节点的 path（/health 或 /ready），返回状态码和报告
+func getHealth(t *testing.T, s *Server, path string) (int, health.Report) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	s.mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	var report health.Report
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+		t.Fatalf("%v: %s", err, w.Body)
+	}
+	return w.Code, report
+}
+
+func TestNodeHealthGroups(t *testing.T) {
+	if len(cache.GroupNames()) > 0 {
+		t.Skip("其他测试留下了缓存组")
+	}
+	s := NewServer(":0")
+	if code, report := getHealth(t, s, "/health"); code != http.StatusServiceUnavailable || report.Components["groups"].Status != health.StatusDown {
+		t.Fatalf("没有缓存组时 /health = %d %+v", code, report)
+	}
+	if code, _ := getHealth(t, s, "/ready"); code != http.StatusServiceUnavailable {
+		t.Fatalf("没有缓存组时 /ready = %d", code)
+	}
+
+	newTestGroup(t, "scores")
+	if code, report := getHealth(t, s, "/health"); code != http.StatusOK || report.Status != health.StatusOK {
+		t.Fatalf("单机运行时 /health = %d %+v", code, report)
+	}
+	if code, _ := getHealth(t, s, "/ready"); code != http.StatusOK {
+		t.Fatalf("单机运行时 /ready = %d", code)
+	}
+}
+
+func TestNodeHealthPeers(t *testing.T) {
+	newTestGroup(t, "scores")
+	now := time.Now()
+	tests := []struct {
+		name      string
+		status    peers.Status
+		active    bool
+		want      health.Status
+		wantCode  int
+		wantReady bool
+	}{
+		{"尚未启用", peers.Status{}, false, health.StatusOK, http.StatusOK, true},
+		{"尚未获取", peers.Status{}, true, health.StatusDegraded, http.StatusOK, false},
+		{"种子节点", peers.Status{Seeded: true, Peers: 2}, true, health.StatusDegraded, http.StatusOK, true},
+		{"正常", peers.Status{LastSuccess: now, Peers: 2}, true, health.StatusOK, http.StatusOK, true},
+		{"最近失败", peers.Status{LastSuccess: now, Peers: 2, ConsecutiveFailures: 1, LastError: "timeout"}, true, health.StatusDegraded, http.StatusOK, true},
+		{"节点列表为空", peers.Status{LastSuccess: now}, true, health.StatusDown, http.StatusServiceUnavailable, true},
+		{"过期", peers.Status{LastSuccess: now.Add(-2 * time.Minute), Peers: 2}, true, health.StatusDown, http.StatusServiceUnavailable, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewServer(":0",
+				WithPeerStatus(func() peers.Status { return tt.status }),
+				WithPeersActive(func() bool { return tt.active }),
+				WithMaxPeerSyncAge(time.Minute),
+			)
+			code, report := getHealth(t, s, "/health")
+			if code != tt.wantCode || report.Components["peers"].Status != tt.want {
+				t.Fatalf("/health = %d %+v", code, report.Components["peers"])
+			}
+			if report.Ready != tt.wantReady {
+				t.Fatalf("Ready = %v, want %v", report.Ready, tt.wantReady)
+			}
+		})
+	}
+}
+
+func TestNodeHealthExtraChecks(t *testing.T) {
+	newTestGroup(t, "scores")
+	loaded := false
+	s := NewServer(":0",
+		WithHealthCheck("snapshot", false, func() health.Result {
+			if !loaded {
+				return health.Down("快照加载中", nil)
+			}
+			return health.OK(nil)
+		}),
+		WithReadyCheck(func() bool { return loaded }),
+	)
+	// 非关键组件不可用只使整体状态降级
+	if code, report := getHealth(t, s, "/health"); code != http.StatusOK || report.Status != health.StatusDegraded || report.Ready {
+		t.Fatalf("快照加载中 /health = %d %+v", code, report)
+	}
+	loaded = true
+	if code, report := getHealth(t, s, "/health"); code != http.StatusOK || report.Status != health.StatusOK || !report.Ready {
+		t.Fatalf("快照加载后 /health = %d %+v", code, report)
+	}
+}
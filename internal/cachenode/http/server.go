@@ -1,12 +1,22 @@
 package http
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
-	"strings"
+	"sync"
+	"time"
 
+	"github.com/AdrianWangs/go-cache/internal/admin"
 	"github.com/AdrianWangs/go-cache/internal/cache"
+	"github.com/AdrianWangs/go-cache/internal/cachenode/peers"
+	"github.com/AdrianWangs/go-cache/internal/consistenthash"
+	"github.com/AdrianWangs/go-cache/internal/health"
+	peerproto "github.com/AdrianWangs/go-cache/internal/peers"
+	"github.com/AdrianWangs/go-cache/pkg/cacheerrors"
 	"github.com/AdrianWangs/go-cache/pkg/logger"
+	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
 )
 
 // Server HTTP缓存服务器
@@ -14,20 +24,188 @@ type Server struct {
 	addr       string         // 服务器地址
 	httpServer *http.Server   // HTTP服务器
 	mux        *http.ServeMux // HTTP路由
+
+	adminAddr   string         // 管理类路由的监听地址，为空时与数据路由共用 addr
+	adminServer *http.Server   // 管理类路由的HTTP服务器，adminAddr 为空时为 nil
+	adminMux    *http.ServeMux // 管理类路由，adminAddr 为空时与 mux 相同
+
+	adminToken     string         // 管理接口的访问令牌，为空时管理接口关闭
+	adminRateLimit int            // 管理接口每秒处理的条目上限，0 表示不限速
+	adminLimiter   *admin.Limiter // 管理接口限流器
+
+	extraHandlers map[string]http.Handler                 // 额外挂载的数据路由，例如节点间通信的 HTTPPool
+	adminHandlers map[string]http.Handler                 // 额外挂载的管理类路由，例如 /metrics 和 /debug/
+	onModeChange  func()                                  // 通过管理接口切换模式后调用
+	peerStatus    func() peers.Status                     // 节点列表更新状态，可为 nil；为 nil 表示单机运行
+	ring          func(samples int) consistenthash.Report // 节点间路由使用的哈希环，为 nil 时不提供 /api/admin/ring
+	info          func() admin.Info                       // /api/admin/info 返回的组件信息
+	nodeID        string                                  // X-GoCache-Node 响应头中本节点的标识，为空时不返回节点标识
+	peersActive   func() bool                             // 节点列表是否已在使用，可为 nil；返回 false 时与单机运行相同
+
+	maxPeerSyncAge time.Duration   // 节点列表超过该时长未成功更新时 /health 返回 503
+	healthChecks   []healthCheck   // 额外注册的组件检查
+	readyChecks    []func() bool   // 额外的就绪条件，全部满足时 /ready 才返回 200
+	health         *health.Checker // /health 与 /ready 使用的健康检查
+}
+
+// healthCheck 通过 WithHealthCheck 注册的组件检查
+type healthCheck struct {
+	name     string
+	critical bool
+	fn       func() health.Result
+}
+
+// defaultMaxPeerSyncAge 节点列表允许的最长未更新时间
+const defaultMaxPeerSyncAge = time.Minute
+
+// ServerOption 配置 Server
+type ServerOption func(*Server)
+
+// WithAdminToken 设置管理接口 (/api/admin/) 的访问令牌
+func WithAdminToken(token string) ServerOption {
+	return func(s *Server) {
+		s.adminToken = token
+	}
+}
+
+// WithAdminRateLimit 设置导入导出每秒处理的条目上限
+func WithAdminRateLimit(entriesPerSecond int) ServerOption {
+	return func(s *Server) {
+		s.adminRateLimit = entriesPerSecond
+	}
+}
+
+// WithHandler 在服务器上额外挂载一个处理器，pattern 语义与 http.ServeMux 相同。
+// 缓存节点用它把 HTTPPool 挂载在 basePath 下，使 HTTP 协议的 API 服务器可以通过节点的 HTTP 地址访问
+func WithHandler(pattern string, handler http.Handler) ServerOption {
+	return func(s *Server) {
+		if s.extraHandlers == nil {
+			s.extraHandlers = make(map[string]http.Handler)
+		}
+		s.extraHandlers[pattern] = handler
+	}
+}
+
+// WithAdminHandler 在管理类路由中额外挂载一个处理器，例如 /metrics 或 /debug/pprof/，
+// 设置了 WithAdminAddr 时只在管理端口上提供
+func WithAdminHandler(pattern string, handler http.Handler) ServerOption {
+	return func(s *Server) {
+		if s.adminHandlers == nil {
+			s.adminHandlers = make(map[string]http.Handler)
+		}
+		s.adminHandlers[pattern] = handler
+	}
+}
+
+// WithAdminAddr 把管理类路由（/status、/health、/ready、/api/admin/、/api/debug/ 以及 WithAdminHandler
+// 挂载的路由）移到单独的监听地址，数据路由（/api/cache/ 以及 WithHandler 挂载的路由）仍在主地址上，
+// 从而可以只对内网开放管理端口。分开后主地址上的管理类路由返回 404。addr 为空时所有路由共用一个监听地址
+func WithAdminAddr(addr string) ServerOption {
+	return func(s *Server) {
+		s.adminAddr = addr
+	}
+}
+
+// WithModeChangeHook 设置通过管理接口切换模式后的回调，
+// 缓存节点用它立即刷新 etcd 中的注册信息
+func WithModeChangeHook(fn func()) ServerOption {
+	return func(s *Server) {
+		s.onModeChange = fn
+	}
+}
+
+// WithPeerStatus 设置节点列表更新状态的来源，在 /status 中展示最近一次成功更新的时间和延迟
+func WithPeerStatus(fn func() peers.Status) ServerOption {
+	return func(s *Server) {
+		s.peerStatus = fn
+	}
+}
+
+// WithPeersActive 设置节点列表是否已在使用，fn 返回 false 期间 /status、/health 和 /ready 与单机运行相同，
+// 不检查节点列表，例如不要求注册的节点在注册成功之前
+func WithPeersActive(fn func() bool) ServerOption {
+	return func(s *Server) {
+		s.peersActive = fn
+	}
+}
+
+// WithRing 设置 /api/admin/ring 报告的哈希环，缓存节点传入 HTTPPool.Ring
+func WithRing(fn func(samples int) consistenthash.Report) ServerOption {
+	return func(s *Server) {
+		s.ring = fn
+	}
+}
+
+// WithInfo 设置 /api/admin/info 返回的配置与构建信息，缓存节点传入 admin.InfoSource.Info。
+// 未设置时只报告构建信息和服务器的创建时间
+func WithInfo(fn func() admin.Info) ServerOption {
+	return func(s *Server) {
+		s.info = fn
+	}
+}
+
+// WithNodeID 设置读取响应的 X-GoCache-Node 响应头中本节点的标识，与注册的节点标识一致。
+// 未设置时不返回该响应头，取自对等节点的值也不转述对等节点的标识
+func WithNodeID(id string) ServerOption {
+	return func(s *Server) {
+		s.nodeID = id
+	}
+}
+
+// WithMaxPeerSyncAge 设置节点列表允许的最长未更新时间，超过后 /health 返回 503，默认 1m
+func WithMaxPeerSyncAge(d time.Duration) ServerOption {
+	return func(s *Server) {
+		if d > 0 {
+			s.maxPeerSyncAge = d
+		}
+	}
+}
+
+// WithHealthCheck 在 /health 中增加一个组件检查，例如启动时的数据加载
+func WithHealthCheck(name string, critical bool, fn func() health.Result) ServerOption {
+	return func(s *Server) {
+		s.healthChecks = append(s.healthChecks, healthCheck{name: name, critical: critical, fn: fn})
+	}
+}
+
+// WithReadyCheck 增加一个就绪条件，fn 返回 false 期间 /ready 返回 503，例如启动时按清单预加载缓存
+func WithReadyCheck(fn func() bool) ServerOption {
+	return func(s *Server) {
+		s.readyChecks = append(s.readyChecks, fn)
+	}
 }
 
 // NewServer 创建一个新的HTTP缓存服务器
-func NewServer(addr string) *Server {
+func NewServer(addr string, opts ...ServerOption) *Server {
 	mux := http.NewServeMux()
 
 	server := &Server{
 		addr: addr,
 		httpServer: &http.Server{
-			Addr:    addr,
-			Handler: mux,
+			Addr: addr,
+			// 路径中字面量的 "." 和 ".." 返回 400，而不是由 ServeMux 重定向到清理后的路径
+			Handler: peerproto.RejectDotSegments(mux),
 		},
-		mux: mux,
+		mux:            mux,
+		adminMux:       mux,
+		maxPeerSyncAge: defaultMaxPeerSyncAge,
+	}
+	for _, opt := range opts {
+		opt(server)
 	}
+	if server.adminAddr != "" {
+		server.adminMux = http.NewServeMux()
+		server.adminServer = &http.Server{
+			Addr:    server.adminAddr,
+			Handler: peerproto.RejectDotSegments(server.adminMux),
+		}
+	}
+	if server.info == nil {
+		server.info = (&admin.InfoSource{Component: "cachenode", StartTime: time.Now()}).Info
+	}
+	server.health = server.newHealthChecker()
+	// 同一时间只允许一个导入或导出操作
+	server.adminLimiter = admin.NewLimiter(1, server.adminRateLimit)
 
 	// 注册默认路由处理程序
 	server.registerHandlers()
@@ -35,50 +213,129 @@ func NewServer(addr string) *Server {
 	return server
 }
 
-// registerHandlers 注册HTTP路由处理程序
+// registerHandlers 注册HTTP路由处理程序。数据路由注册在 mux 上，管理类路由注册在 adminMux 上，
+// 未设置 WithAdminAddr 时两者是同一个路由
 func (s *Server) registerHandlers() {
+	s.registerDataHandlers(s.mux)
+	s.registerAdminHandlers(s.adminMux)
+}
+
+// registerDataHandlers 注册数据路由：缓存读取和额外挂载的路由
+func (s *Server) registerDataHandlers(mux *http.ServeMux) {
 	// API路由: /api/cache/{group}/{key}
-	s.mux.HandleFunc("/api/cache/", s.cacheHandler)
+	mux.HandleFunc("/api/cache/", s.cacheHandler)
+
+	// 额外挂载的路由
+	for pattern, handler := range s.extraHandlers {
+		mux.Handle(pattern, handler)
+	}
+}
 
+// registerAdminHandlers 注册管理类路由：状态、健康检查、管理接口和调试接口
+func (s *Server) registerAdminHandlers(mux *http.ServeMux) {
 	// 状态检查路由
-	s.mux.HandleFunc("/status", s.statusHandler)
+	mux.HandleFunc("/status", s.statusHandler)
+
+	// 健康检查与就绪检查路由
+	mux.HandleFunc("/health", s.health.HealthHandler)
+	mux.HandleFunc("/ready", s.health.ReadyHandler)
+
+	// 管理路由: /api/admin/groups/{group}/export、import、ratelimit、clientratelimit 和 hotkeys
+	mux.HandleFunc("/api/admin/groups/", s.adminGroupHandler)
+
+	// 节点级限流: /api/admin/ratelimit
+	mux.HandleFunc("/api/admin/ratelimit", s.adminRateLimitHandler)
+
+	// 只读维护模式: /api/admin/mode
+	mux.HandleFunc("/api/admin/mode", s.adminModeHandler)
+
+	// 配置与构建信息: /api/admin/info
+	mux.HandleFunc("/api/admin/info", s.adminInfoHandler)
+
+	// 缓存内容抽样: /api/debug/sample/{group}
+	mux.HandleFunc(samplePathPrefix, s.debugSampleHandler)
 
-	// 健康检查路由
-	s.mux.HandleFunc("/health", s.healthHandler)
+	// 哈希环分布: /api/admin/ring
+	if s.ring != nil {
+		mux.HandleFunc("/api/admin/ring", s.adminRingHandler)
+	}
+
+	// 额外挂载的管理类路由
+	for pattern, handler := range s.adminHandlers {
+		mux.Handle(pattern, handler)
+	}
 }
 
-// Start 启动HTTP服务器
+// servers 返回需要启动的HTTP服务器，设置了 WithAdminAddr 时包括管理端口的服务器
+func (s *Server) servers() []*http.Server {
+	if s.adminServer == nil {
+		return []*http.Server{s.httpServer}
+	}
+	return []*http.Server{s.httpServer, s.adminServer}
+}
+
+// Start 启动HTTP服务器，设置了 WithAdminAddr 时同时启动管理端口的服务器
 func (s *Server) Start() error {
-	logger.Infof("HTTP缓存服务器正在监听: %s", s.addr)
-	go func() {
-		if err := s.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			logger.Errorf("HTTP服务器运行错误: %v", err)
-		}
-	}()
+	if s.adminServer == nil {
+		logger.Infof("HTTP缓存服务器正在监听: %s", s.addr)
+	} else {
+		logger.Infof("HTTP缓存服务器正在监听: %s，管理接口监听: %s", s.addr, s.adminAddr)
+	}
+	for _, srv := range s.servers() {
+		go func(srv *http.Server) {
+			if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				logger.Errorf("HTTP服务器 %s 运行错误: %v", srv.Addr, err)
+			}
+		}(srv)
+	}
 	return nil
 }
 
-// Stop 停止HTTP服务器
+// Stop 立即关闭HTTP服务器及管理端口的服务器
 func (s *Server) Stop() error {
 	logger.Info("HTTP缓存服务器正在关闭")
-	return s.httpServer.Close()
+	var errs []error
+	for _, srv := range s.servers() {
+		if err := srv.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("关闭 %s: %w", srv.Addr, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Shutdown 优雅关闭HTTP服务器及管理端口的服务器：各服务器同时停止接受新连接并等待进行中的请求完成，
+// 互不等待，直到 ctx 结束。数据端口上的长请求不会推迟管理端口的关闭，反之亦然
+func (s *Server) Shutdown(ctx context.Context) error {
+	logger.Info("HTTP缓存服务器正在优雅关闭")
+	servers := s.servers()
+	errs := make([]error, len(servers))
+	var wg sync.WaitGroup
+	for i, srv := range servers {
+		wg.Add(1)
+		go func(i int, srv *http.Server) {
+			defer wg.Done()
+			if err := srv.Shutdown(ctx); err != nil {
+				errs[i] = fmt.Errorf("关闭 %s: %w", srv.Addr, err)
+			}
+		}(i, srv)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
 }
 
 // cacheHandler 处理缓存请求
 func (s *Server) cacheHandler(w http.ResponseWriter, r *http.Request) {
 	// 解析路径: /api/cache/{group}/{key}
-	parts := strings.SplitN(r.URL.Path[len("/api/cache/"):], "/", 2)
-	if len(parts) != 2 {
+	groupName, key, ok := peerproto.SplitGroupKey(r.URL.EscapedPath(), "/api/cache/")
+	if !ok {
 		http.Error(w, "Bad Request: expected /api/cache/{group}/{key}", http.StatusBadRequest)
 		return
 	}
 
-	groupName, key := parts[0], parts[1]
-
 	// 获取对应的缓存组
 	group := cache.GetGroup(groupName)
 	if group == nil {
-		http.Error(w, fmt.Sprintf("Group not found: %s", groupName), http.StatusNotFound)
+		writeError(w, cache.ErrNoSuchGroup, fmt.Sprintf("Group not found: %s", groupName))
 		return
 	}
 
@@ -86,29 +343,32 @@ func (s *Server) cacheHandler(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet, "": // 默认为GET
 		// 从缓存获取值
-		view, err := group.Get(key)
+		ctx := cache.WithClient(r.Context(), peerproto.ClientHost(r.RemoteAddr))
+		view, meta, err := group.GetWithMeta(ctx, key)
 		if err != nil {
-			status := http.StatusInternalServerError
-			if err == cache.ErrNotFound {
-				status = http.StatusNotFound
-			}
-			http.Error(w, err.Error(), status)
+			writeError(w, err, err.Error())
 			return
 		}
 
-		// 设置响应头
+		// 设置响应头，元数据与 Protobuf 响应中的字段一致
+		resp := &pb.Response{}
+		meta.Attribute(s.nodeID).FillProto(resp)
+		peerproto.WriteMetaHeaders(w.Header(), resp)
 		w.Header().Set("Content-Type", "application/octet-stream")
-		w.Write(view.ByteSlice())
+		// ETag 只由值的内容计算，与 API 服务器返回的相同；匹配时返回 304，仍计为一次命中
+		peerproto.ServeValue(w, r, view.ByteSlice())
 
 	case http.MethodDelete:
 		// 从缓存删除值
 		err := group.Delete(key)
+		if errors.Is(err, cache.ErrDeleteQueued) {
+			// 本地已删除，归属节点暂时不可达，删除已进入重试队列
+			w.WriteHeader(http.StatusAccepted)
+			w.Write([]byte("Delete accepted, pending retry"))
+			return
+		}
 		if err != nil {
-			status := http.StatusInternalServerError
-			if err == cache.ErrEmptyKey {
-				status = http.StatusBadRequest
-			}
-			http.Error(w, err.Error(), status)
+			writeError(w, err, err.Error())
 			return
 		}
 
@@ -121,26 +381,92 @@ func (s *Server) cacheHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// writeError 以 err 对应的状态码返回错误，并在响应头中给出错误码，映射见 cacheerrors；
+// 限流时另外给出 Retry-After
+func writeError(w http.ResponseWriter, err error, msg string) {
+	w.Header().Set(peerproto.HeaderErrorCode, cacheerrors.ErrorCode(err))
+	if retry := cacheerrors.RetryAfterHeader(err); retry != "" {
+		w.Header().Set(cacheerrors.HeaderRetryAfter, retry)
+	}
+	http.Error(w, msg, cacheerrors.HTTPStatus(err))
+}
+
 // statusHandler 处理状态请求
 func (s *Server) statusHandler(w http.ResponseWriter, r *http.Request) {
-	// 获取所有缓存组信息
-	groups := cache.GetGroups()
+	// 获取所有缓存组的快照
+	infos := cache.ListGroups()
 
 	// 构建响应
 	fmt.Fprintln(w, "Cache Status:")
-	for name, group := range groups {
-		stats := group.Stats()
-		fmt.Fprintf(w, "Group: %s\n", name)
+	fmt.Fprintf(w, "Node Mode: %s\n", cache.NodeMode())
+	if ls := logger.GetStats(); ls.Async {
+		fmt.Fprintf(w, "Log: async (%s), buffered %d, dropped %d\n", ls.Overflow, ls.Buffered, ls.Dropped)
+	}
+	if s.peersInUse() {
+		ps := s.peerStatus()
+		fmt.Fprintln(w, "Peer List:")
+		fmt.Fprintf(w, "  - Peers: %d\n", ps.Peers)
+		if ps.Seeded {
+			fmt.Fprintln(w, "  - Source: seed list (waiting for discovery)")
+		}
+		if ps.LastSuccess.IsZero() {
+			fmt.Fprintln(w, "  - Last Success: never")
+		} else {
+			fmt.Fprintf(w, "  - Last Success: %s\n", ps.LastSuccess.Format(time.RFC3339))
+			fmt.Fprintf(w, "  - Lag: %v\n", ps.Lag(time.Now()).Round(time.Millisecond))
+		}
+		fmt.Fprintf(w, "  - Consecutive Failures: %d\n", ps.ConsecutiveFailures)
+		if ps.LastError != "" {
+			fmt.Fprintf(w, "  - Last Error: %s\n", ps.LastError)
+		}
+	}
+	for _, info := range infos {
+		stats := info.Stats
+		fmt.Fprintf(w, "Group: %s\n", info.Name)
+		fmt.Fprintf(w, "  - Max Bytes: %d\n", info.MaxBytes)
+		fmt.Fprintf(w, "  - Bytes: %d\n", stats.Bytes)
+		fmt.Fprintf(w, "  - Entries: %d\n", stats.Entries)
+		if info.MaxBytes > 0 {
+			fmt.Fprintf(w, "  - Fill: %.2f%%\n", stats.FillPercent())
+		}
+		fmt.Fprintf(w, "  - TTL: %v\n", info.TTL)
+		fmt.Fprintf(w, "  - Max Age: %v\n", info.MaxAge)
+		fmt.Fprintf(w, "  - Max Idle: %v\n", info.MaxIdle)
+		fmt.Fprintf(w, "  - Eviction: %s\n", info.Eviction)
+		if info.DefaultDeadline > 0 {
+			fmt.Fprintf(w, "  - Default Deadline: %v (strict %v)\n", info.DefaultDeadline, info.StrictDeadline)
+		}
+		fmt.Fprintf(w, "  - Mode: %s\n", info.Mode)
+		fmt.Fprintf(w, "  - Created At: %s\n", info.CreatedAt.Format(time.RFC3339))
 		fmt.Fprintf(w, "  - Hits: %d\n", stats.Hits)
 		fmt.Fprintf(w, "  - Gets: %d\n", stats.Gets)
+		fmt.Fprintf(w, "  - Throttled: %d\n", stats.Throttled)
+		if stats.EvictedAges != nil {
+			fmt.Fprintf(w, "  - Evictions/min: %d (evicted age p50 %v, p90 %v, warnings %d)\n",
+				stats.EvictionsPerMinute, time.Duration(stats.EvictedAgeP50Ms)*time.Millisecond,
+				time.Duration(stats.EvictedAgeP90Ms)*time.Millisecond, stats.EvictionPressureWarnings)
+		}
+		if stats.LoadsExecuted > 0 {
+			fmt.Fprintf(w, "  - Loads: %d executed, %d deduped, %d in flight\n", stats.LoadsExecuted, stats.LoadsDeduped, stats.CurrentInflight)
+		}
+		if stats.AdaptiveTTLs != nil {
+			fmt.Fprintf(w, "  - Adaptive TTL: %d keys, %d reloads changed, %d unchanged\n",
+				stats.AdaptiveTTLKeys, stats.AdaptiveTTLChanged, stats.AdaptiveTTLUnchanged)
+		}
+		if stats.WatermarkRuns > 0 {
+			fmt.Fprintf(w, "  - Watermark Evictions: %d (%d runs)\n", stats.WatermarkEvictions, stats.WatermarkRuns)
+		}
 		if stats.Gets > 0 {
 			fmt.Fprintf(w, "  - Hit Rate: %.2f%%\n", float64(stats.Hits)/float64(stats.Gets)*100)
 		}
+		if r := stats.Rates; r != nil && r.Last1m.Minutes > 0 {
+			for _, span := range []struct {
+				name string
+				sum  cache.RateSummary
+			}{{"1m", r.Last1m}, {"5m", r.Last5m}, {"15m", r.Last15m}} {
+				fmt.Fprintf(w, "  - Last %s (per min): hits %.1f, misses %.1f, loads %.1f, evictions %.1f, errors %.1f, hit ratio %.2f%%\n",
+					span.name, span.sum.Hits, span.sum.Misses, span.sum.Loads, span.sum.Evictions, span.sum.Errors, span.sum.HitRatio*100)
+			}
+		}
 	}
 }
-
-// healthHandler 处理健康检查请求
-func (s *Server) healthHandler(w http.ResponseWriter, r *http.Request) {
-	w.WriteHeader(http.StatusOK)
-	fmt.Fprintln(w, "OK")
-}
@@ -0,0 +1,280 @@
+package http
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/internal/cache"
+	peerproto "github.com/AdrianWangs/go-cache/internal/peers"
+	"github.com/AdrianWangs/go-cache/pkg/cacheerrors"
+)
+
+// okHandler 返回 200 和 name 的处理器，用于区分挂载的路由
+func okHandler(name string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(name))
+	})
+}
+
+// routeStatus 返回 mux 对 path 的 GET 请求的状态码
+func routeStatus(mux *http.ServeMux, path string) int {
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	return w.Code
+}
+
+// TestRoutePartitioning 设置管理地址后管理类路由只在管理端口上、数据路由只在主端口上提供；
+// 未设置时所有路由都在同一个端口上
+func TestRoutePartitioning(t *testing.T) {
+	g := newTestGroup(t, "data")
+	dataRoutes := []string{"/api/cache/" + g.Name() + "/k", "/_gocache/peer"}
+	adminRoutes := []string{
+		"/status", "/health", "/ready", "/api/admin/info", "/api/admin/mode",
+		"/api/admin/groups/" + g.Name() + "/hotkeys", "/api/debug/sample/" + g.Name(), "/metrics", "/debug/pprof/",
+	}
+	opts := []ServerOption{
+		WithAdminToken(testAdminToken),
+		WithHandler("/_gocache/", okHandler("peer")),
+		WithAdminHandler("/metrics", okHandler("metrics")),
+		WithAdminHandler("/debug/pprof/", okHandler("pprof")),
+	}
+
+	// 同一个端口：所有路由都存在（管理接口缺少令牌时返回 401/403，而不是 404）
+	s := NewServer(":0", opts...)
+	if s.adminMux != s.mux {
+		t.Fatal("未设置管理地址时使用了单独的路由")
+	}
+	for _, path := range append(dataRoutes, adminRoutes...) {
+		if code := routeStatus(s.mux, path); code == http.StatusNotFound && !strings.HasPrefix(path, "/api/cache/") {
+			t.Errorf("%s = 404", path)
+		}
+	}
+
+	s = NewServer(":0", append(opts, WithAdminAddr("127.0.0.1:0"))...)
+	for _, path := range adminRoutes {
+		if code := routeStatus(s.mux, path); code != http.StatusNotFound {
+			t.Errorf("数据端口上 %s = %d, want 404", path, code)
+		}
+		if code := routeStatus(s.adminMux, path); code == http.StatusNotFound {
+			t.Errorf("管理端口上 %s = 404", path)
+		}
+	}
+	for _, path := range dataRoutes {
+		if code := routeStatus(s.adminMux, path); code != http.StatusNotFound {
+			t.Errorf("管理端口上 %s = %d, want 404", path, code)
+		}
+	}
+	if code := routeStatus(s.mux, "/_gocache/peer"); code != http.StatusOK {
+		t.Errorf("数据端口上节点间通信路由 = %d", code)
+	}
+}
+
+// freeAddr 返回一个当前空闲的本地地址
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	return l.Addr().String()
+}
+
+// TestSeparateListenersShutdown 两个端口各自监听；数据端口上未完成的请求不推迟管理端口的关闭
+func TestSeparateListenersShutdown(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	dataAddr, adminAddr := freeAddr(t), freeAddr(t)
+	s := NewServer(dataAddr, WithAdminAddr(adminAddr), WithHandler("/slow", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	})))
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(url string) int {
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			res, err := http.Get(url)
+			if err == nil {
+				res.Body.Close()
+				return res.StatusCode
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("GET %s: %v", url, err)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	if code := get("http://" + adminAddr + "/status"); code != http.StatusOK {
+		t.Fatalf("管理端口 /status = %d", code)
+	}
+	if code := get("http://" + dataAddr + "/status"); code != http.StatusNotFound {
+		t.Fatalf("数据端口 /status = %d, want 404", code)
+	}
+	go http.Get("http://" + dataAddr + "/slow")
+	time.Sleep(50 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if err := s.Shutdown(ctx); err == nil || !strings.Contains(err.Error(), dataAddr) || strings.Contains(err.Error(), adminAddr) {
+		t.Fatalf("Shutdown = %v, want only the data port to time out", err)
+	}
+	if _, err := http.Get("http://" + adminAddr + "/status"); err == nil {
+		t.Fatal("管理端口关闭后仍在响应")
+	}
+}
+
+// TestCacheHandlerConditionalGet 数据接口返回只由值计算的 ETag，匹配时返回 304 并计为一次命中，
+// 值改变后旧的 ETag 不再匹配
+func TestCacheHandlerConditionalGet(t *testing.T) {
+	s := NewServer(":0")
+	g := newTestGroup(t, "scores")
+	if err := g.Set("Tom", []byte("630"), 0); err != nil {
+		t.Fatal(err)
+	}
+	get := func(ifNoneMatch string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/api/cache/"+g.Name()+"/Tom", nil)
+		if ifNoneMatch != "" {
+			r.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		w := httptest.NewRecorder()
+		s.mux.ServeHTTP(w, r)
+		return w
+	}
+
+	w := get("")
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || w.Body.String() != "630" || etag != peerproto.ValueETag([]byte("630")) {
+		t.Fatalf("读取 = %d %q, ETag %q", w.Code, w.Body.String(), etag)
+	}
+	hits := g.Stats().Hits
+	if w := get(etag); w.Code != http.StatusNotModified || w.Body.Len() != 0 || w.Header().Get(peerproto.HeaderVersion) == "" {
+		t.Fatalf("校验器匹配时 = %d %q, 元数据 %v", w.Code, w.Body.String(), w.Header())
+	}
+	if got := g.Stats().Hits; got != hits+1 {
+		t.Fatalf("304 后命中数 = %d, want %d", got, hits+1)
+	}
+
+	if err := g.Set("Tom", []byte("631"), 0); err != nil {
+		t.Fatal(err)
+	}
+	if w := get(etag); w.Code != http.StatusOK || w.Body.String() != "631" || w.Header().Get("ETag") == etag {
+		t.Fatalf("值改变后 = %d %q, ETag %q", w.Code, w.Body.String(), w.Header().Get("ETag"))
+	}
+}
+
+// TestCacheHandlerRateLimited 超出限流的请求返回 429 和 Retry-After；按客户端限流时
+// 每个来源主机各有一个令牌桶，同一主机的不同端口共用
+func TestCacheHandlerRateLimited(t *testing.T) {
+	s := NewServer(":0")
+	g := newTestGroup(t, "scores")
+	if err := g.Set("Tom", []byte("630"), 0); err != nil {
+		t.Fatal(err)
+	}
+	g.SetClientRateLimit(cache.RateLimit{Rate: 0.5})
+	get := func(remoteAddr string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/api/cache/"+g.Name()+"/Tom", nil)
+		r.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		s.mux.ServeHTTP(w, r)
+		return w
+	}
+
+	if w := get("192.0.2.1:1234"); w.Code != http.StatusOK {
+		t.Fatalf("第一次请求: %d %s", w.Code, w.Body.String())
+	}
+	w := get("192.0.2.1:5678")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get(cacheerrors.HeaderRetryAfter) != "2" {
+		t.Fatalf("超出限流: %d, Retry-After %q", w.Code, w.Header().Get(cacheerrors.HeaderRetryAfter))
+	}
+	if code := w.Header().Get(peerproto.HeaderErrorCode); code != cacheerrors.ErrorCodeRateLimited {
+		t.Fatalf("错误码 = %q", code)
+	}
+	if w := get("192.0.2.2:1234"); w.Code != http.StatusOK || w.Header().Get(cacheerrors.HeaderRetryAfter) != "" {
+		t.Fatalf("另一个客户端: %d, Retry-After %q", w.Code, w.Header().Get(cacheerrors.HeaderRetryAfter))
+	}
+
+	// 组限流由所有客户端共享
+	g.SetClientRateLimit(cache.RateLimit{})
+	g.SetRateLimit(cache.RateLimit{Rate: 1})
+	if w := get("192.0.2.1:1234"); w.Code != http.StatusOK {
+		t.Fatalf("组限流内: %d", w.Code)
+	}
+	if w := get("192.0.2.2:1234"); w.Code != http.StatusTooManyRequests || w.Header().Get(cacheerrors.HeaderRetryAfter) != "1" {
+		t.Fatalf("超出组限流: %d, Retry-After %q", w.Code, w.Header().Get(cacheerrors.HeaderRetryAfter))
+	}
+}
+
+// TestStatusListsGroups 状态页按 ListGroups 列出每个组的配置和统计；读取未注册的组返回 404
+func TestStatusListsGroups(t *testing.T) {
+	s := NewServer(":0")
+	g := newTestGroup(t, "status")
+	if err := g.Set("Tom", []byte("630"), 0); err != nil {
+		t.Fatal(err)
+	}
+	serve := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		s.mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	body := serve("/status").Body.String()
+	start := strings.Index(body, "Group: "+g.Name()+"\n")
+	if start < 0 {
+		t.Fatalf("状态页中没有组 %s:\n%s", g.Name(), body)
+	}
+	section := body[start:]
+	if next := strings.Index(section[1:], "Group: "); next >= 0 {
+		section = section[:next+1]
+	}
+	for _, want := range []string{"Max Bytes: 1048576", "Entries: 1", "TTL: 1h0m0s", "Eviction: lru", "Mode: readwrite"} {
+		if !strings.Contains(section, want) {
+			t.Fatalf("状态页中组 %s 缺少 %q:\n%s", g.Name(), want, section)
+		}
+	}
+
+	w := serve("/api/cache/status-missing/Tom")
+	if w.Code != http.StatusNotFound || w.Header().Get(peerproto.HeaderErrorCode) != cacheerrors.ErrorCode(cache.ErrNoSuchGroup) {
+		t.Fatalf("未注册的组: %d, 错误码 %q", w.Code, w.Header().Get(peerproto.HeaderErrorCode))
+	}
+}
+
+// TestCacheHandlerDotKeys 键 "." 和 ".." 写成 %2E 时可以读取；路径中字面量的 "." 和 ".." 返回 400，
+// 而不是由 ServeMux 重定向到清理后的路径
+func TestCacheHandlerDotKeys(t *testing.T) {
+	s := NewServer(":0")
+	g := newTestGroup(t, "scores")
+	for _, key := range []string{".", ".."} {
+		if err := g.Set(key, []byte("v:"+key), 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		s.httpServer.Handler.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+
+	for _, key := range []string{".", ".."} {
+		w := serve(http.MethodGet, "/api/cache/"+g.Name()+"/"+peerproto.EscapePathSegment(key))
+		if w.Code != http.StatusOK || w.Body.String() != "v:"+key {
+			t.Fatalf("读取 %q = %d %q", key, w.Code, w.Body.String())
+		}
+	}
+	for _, path := range []string{"/api/cache/" + g.Name() + "/.", "/api/cache/" + g.Name() + "/..", "/api/cache/" + g.Name() + "/a/../b"} {
+		for _, method := range []string{http.MethodGet, http.MethodDelete} {
+			if w := serve(method, path); w.Code != http.StatusBadRequest {
+				t.Errorf("%s %s = %d, want 400", method, path, w.Code)
+			}
+		}
+	}
+	if _, _, ok := g.Peek(".."); !ok {
+		t.Fatal("被拒绝的请求删除了键")
+	}
+}
@@ -0,0 +1,101 @@
+package peers
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/AdrianWangs/go-cache/internal/discovery"
+	"github.com/AdrianWangs/go-cache/internal/server"
+)
+
+// churnNodes 返回第 round 轮的节点列表：节点轮流离开、重新加入，每轮有一个节点更换 HTTP 地址
+func churnNodes(round int) []discovery.NodeInfo {
+	var nodes []discovery.NodeInfo
+	for i := 0; i < 12; i++ {
+		if (i+round)%4 == 0 {
+			continue
+		}
+		n := discovery.NodeInfo{ID: fmt.Sprintf("node-%02d", i), GRPCAddr: fmt.Sprintf("10.0.0.%d:9090", i+1)}
+		n.HTTPAddr = fmt.Sprintf("10.0.0.%d:8001", i+1)
+		if i == round%12 {
+			n.HTTPAddr = fmt.Sprintf("10.0.1.%d:8001", i+1)
+		}
+		nodes = append(nodes, n)
+	}
+	return nodes
+}
+
+// sameRouting 比较两个 HTTPPool 对一批 key 选择的节点
+func sameRouting(t *testing.T, a, b *server.HTTPPool) {
+	t.Helper()
+	for i := 0; i < 200; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		pa, oka := a.PickPeer(key)
+		pb, okb := b.PickPeer(key)
+		if oka != okb || (oka && fmt.Sprint(pa) != fmt.Sprint(pb)) {
+			t.Fatalf("%s: 增量更新的节点选择 %v，完整列表的节点选择 %v", key, pa, pb)
+		}
+	}
+}
+
+// TestUpdaterAppliesDeltas 第一次获取完整列表，之后只请求并应用增量；
+// 按增量更新的 HTTPPool 与每次设置完整列表的 HTTPPool 路由相同
+func TestUpdaterAppliesDeltas(t *testing.T) {
+	api, source, notModified := newNodesAPI(t, churnNodes(0)...)
+	pool := server.NewHTTPPool("http://10.9.9.9:8001")
+	reference := server.NewHTTPPool("http://10.9.9.9:8001")
+	full := &recordingApply{}
+	var deltas []Delta
+	u := NewUpdater(source, func(nodes []discovery.NodeInfo) {
+		full.apply(nodes)
+		PoolApplier(pool)(nodes)
+	}, WithDeltaApplier(func(d Delta) {
+		deltas = append(deltas, d)
+		PoolDeltaApplier(pool)(d)
+	}))
+	ctx := context.Background()
+
+	for round := 0; round < 10; round++ {
+		api.UpdateNodes(churnNodes(round))
+		if err := u.Update(ctx); err != nil {
+			t.Fatal(err)
+		}
+		PoolApplier(reference)(churnNodes(round))
+		sameRouting(t, pool, reference)
+		if s := u.Status(); s.Peers != len(churnNodes(round)) {
+			t.Fatalf("第 %d 轮: 状态中的节点数 %d", round, s.Peers)
+		}
+
+		// 没有变化时服务端返回 304，不调用任何 apply
+		if err := u.Update(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if full.count() != 1 || len(deltas) != 9 || notModified.Load() != 10 {
+		t.Fatalf("完整列表 %d 次，增量 %d 次，304 %d 次", full.count(), len(deltas), notModified.Load())
+	}
+	if d := deltas[0]; len(d.Added) == 0 || len(d.Removed) == 0 || len(d.Added) == len(churnNodes(1)) {
+		t.Fatalf("增量 = %+v，应只包含变化的节点", d)
+	}
+}
+
+// TestUpdaterDeltaOldServer 不支持增量的旧 API 服务器总是返回完整列表，Updater 照常应用完整列表
+func TestUpdaterDeltaOldServer(t *testing.T) {
+	api, source := newFakeAPI(t, node1, node2)
+	full := &recordingApply{}
+	var deltas int
+	u := NewUpdater(source, full.apply, WithDeltaApplier(func(Delta) { deltas++ }))
+	ctx := context.Background()
+
+	if err := u.Update(ctx); err != nil {
+		t.Fatal(err)
+	}
+	api.setNodes(node1)
+	if err := u.Update(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if full.count() != 2 || deltas != 0 || len(full.calls[1]) != 1 {
+		t.Fatalf("完整列表 %d 次，增量 %d 次", full.count(), deltas)
+	}
+}
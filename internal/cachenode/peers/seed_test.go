@@ -0,0 +1,83 @@
+package peers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/AdrianWangs/go-cache/internal/cache"
+	"github.com/AdrianWangs/go-cache/internal/discovery"
+	"github.com/AdrianWangs/go-cache/internal/server"
+)
+
+// lateSource 在 ready 之前获取失败，模拟尚未收敛的服务发现
+type lateSource struct {
+	ready atomic.Bool
+	nodes []discovery.NodeInfo
+}
+
+func (s *lateSource) Peers(ctx context.Context) ([]discovery.NodeInfo, error) {
+	if !s.ready.Load() {
+		return nil, errors.New("etcd 尚未同步")
+	}
+	return s.nodes, nil
+}
+
+// routedTo 返回 pool 把 100 个 key 路由到的对等节点标识，以逗号分隔并排序
+func routedTo(pool *server.HTTPPool) string {
+	var ids []string
+	for i := 0; i < 100; i++ {
+		if peer, ok := pool.PickPeer(fmt.Sprintf("key-%d", i)); ok {
+			ids = append(ids, fmt.Sprint(peer))
+		}
+	}
+	slices.Sort(ids)
+	return strings.Join(slices.Compact(ids), ",")
+}
+
+func TestSeedsRoutableBeforeDiscovery(t *testing.T) {
+	self := discovery.NodeInfo{ID: "node-1", GRPCAddr: "10.0.0.1:9090", HTTPAddr: "10.0.0.1:8001"}
+	seed := discovery.NodeInfo{ID: "node-2", GRPCAddr: "10.0.0.2:9090", HTTPAddr: "10.0.0.2:8001"}
+	pool := server.NewHTTPPool("http://10.0.0.1:8001", server.WithSelfID("node-1"), server.WithRegistry(cache.NewRegistry()))
+
+	source := &lateSource{nodes: []discovery.NodeInfo{self,
+		{ID: "node-3", GRPCAddr: "10.0.0.3:9090", HTTPAddr: "10.0.0.3:8001"}}}
+	u := NewUpdater(source, PoolApplier(pool))
+	u.Seed([]discovery.NodeInfo{self, seed})
+
+	// 服务发现收敛之前，种子节点已经可以路由
+	if err := u.Update(context.Background()); err == nil {
+		t.Fatal("服务发现尚未就绪时 Update 应失败")
+	}
+	if got := routedTo(pool); got != "node-2" {
+		t.Fatalf("种子节点阶段路由到 %q", got)
+	}
+	if s := u.Status(); s.State() != "seeded" || s.Peers != 2 {
+		t.Fatalf("状态 = %+v", s)
+	}
+
+	// 第一份服务发现结果替换种子列表
+	source.ready.Store(true)
+	if err := u.Update(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if s := u.Status(); s.State() != "synced" || s.Seeded {
+		t.Fatalf("同步后状态 = %+v", s)
+	}
+	if got := routedTo(pool); got != "node-3" {
+		t.Fatalf("同步后路由到 %q", got)
+	}
+
+	// 已收到服务发现的结果后，种子列表被忽略
+	applied := 0
+	u2 := NewUpdater(source, func([]discovery.NodeInfo) { applied++ })
+	u2.Update(context.Background())
+	u2.Seed([]discovery.NodeInfo{seed})
+	if applied != 1 || u2.Status().Seeded {
+		t.Fatalf("迟到的种子列表被应用: applied=%d, %+v", applied, u2.Status())
+	}
+}
@@ -0,0 +1,246 @@
+package peers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/AdrianWangs/go-cache/internal/discovery"
+	"github.com/AdrianWangs/go-cache/pkg/logger"
+)
+
+// ErrNotModified 表示节点列表自上次获取以来没有变化
+var ErrNotModified = errors.New("peer list not modified")
+
+// Source 提供当前的节点列表，Updater 通过它获取节点
+type Source interface {
+	// Peers 返回当前的节点列表，ctx 携带单次获取的超时；
+	// 列表自上次成功返回后没有变化时可以返回 ErrNotModified
+	Peers(ctx context.Context) ([]discovery.NodeInfo, error)
+}
+
+// Delta 节点列表相对上一次获取的变化，见 DeltaSource
+type Delta struct {
+	Added   []discovery.NodeInfo // 新增或注册信息变化的节点
+	Removed []string             // 离开的节点标识
+}
+
+// Empty 报告节点列表是否没有变化
+func (d *Delta) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0
+}
+
+// DeltaSource 是还能返回节点列表变化的 Source。Updater 在已有节点列表且设置了
+// WithDeltaApplier 时使用它，只把变化应用到 HTTPPool
+type DeltaSource interface {
+	Source
+	// PeersDelta 返回相对该 Source 上一次成功返回的列表的变化（delta 不为 nil），
+	// 或在无法计算变化时返回完整列表（delta 为 nil）；列表没有变化时可以返回 ErrNotModified
+	PeersDelta(ctx context.Context) (nodes []discovery.NodeInfo, delta *Delta, err error)
+}
+
+// HTTPSource 从 API 服务器的 /peers 接口获取节点列表，
+// 使用 ETag 条件请求，节点列表未变化时服务端返回 304 而不必传输完整列表；
+// PeersDelta 请求增量响应，列表变化时也只传输变化的节点
+type HTTPSource struct {
+	url    string
+	client *http.Client
+
+	mu   sync.Mutex
+	etag string // 上次成功响应的 ETag
+}
+
+// NewHTTPSource 创建从 apiAddr (host:port) 的 /peers 接口获取节点的 Source
+func NewHTTPSource(apiAddr string) *HTTPSource {
+	return &HTTPSource{
+		url:    fmt.Sprintf("http://%s/peers", apiAddr),
+		client: &http.Client{},
+	}
+}
+
+// peersResponse /peers 的响应，兼容只返回 peers 的旧版本 API 服务器
+type peersResponse struct {
+	Peers []string             `json:"peers"`
+	Nodes []discovery.NodeInfo `json:"nodes"`
+}
+
+// deltaResponse 增量响应，与 handlers.NodesDelta 相同
+type deltaResponse struct {
+	Version string               `json:"version"`
+	Added   []discovery.NodeInfo `json:"added"`
+	Removed []string             `json:"removed"`
+}
+
+// mediaTypeDelta 增量响应的 Content-Type，与 handlers.MediaTypeNodesDelta 相同
+const mediaTypeDelta = "application/vnd.gocache.nodes-delta+json"
+
+// Peers 实现 Source
+func (s *HTTPSource) Peers(ctx context.Context) ([]discovery.NodeInfo, error) {
+	nodes, _, err := s.fetch(ctx, false)
+	return nodes, err
+}
+
+// PeersDelta 实现 DeltaSource：带上次响应的 ETag 请求增量，API 服务器仍保留该版本时
+// 只返回变化的节点；旧版本的 API 服务器或版本过旧时返回完整列表
+func (s *HTTPSource) PeersDelta(ctx context.Context) ([]discovery.NodeInfo, *Delta, error) {
+	return s.fetch(ctx, true)
+}
+
+// fetch 请求一次 /peers，delta 为 true 时请求增量响应。
+// http.Client 会自动请求并解压 gzip，无需在这里处理
+func (s *HTTPSource) fetch(ctx context.Context, delta bool) ([]discovery.NodeInfo, *Delta, error) {
+	u := s.url
+	if delta {
+		u += "?delta=1"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("创建请求失败: %w", err)
+	}
+	s.mu.Lock()
+	if s.etag != "" {
+		req.Header.Set("If-None-Match", s.etag)
+	}
+	s.mu.Unlock()
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("从 API Server (%s) 获取 peers 失败: %w", s.url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, nil, ErrNotModified
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("从 API Server (%s) 获取 peers 失败，状态码: %d", s.url, resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("读取 API Server (%s) 响应失败: %w", s.url, err)
+	}
+
+	var nodes []discovery.NodeInfo
+	var change *Delta
+	if delta && strings.HasPrefix(resp.Header.Get("Content-Type"), mediaTypeDelta) {
+		var d deltaResponse
+		if err := json.Unmarshal(body, &d); err != nil {
+			return nil, nil, fmt.Errorf("解析 API Server (%s) 的增量响应失败: %w", s.url, err)
+		}
+		change = &Delta{Added: d.Added, Removed: d.Removed}
+	} else if nodes, err = parsePeers(body); err != nil {
+		return nil, nil, err
+	}
+
+	s.mu.Lock()
+	s.etag = resp.Header.Get("ETag")
+	s.mu.Unlock()
+	return nodes, change, nil
+}
+
+// parsePeers 解析 /peers 响应。新版本返回完整注册信息，旧版本只返回 gRPC 地址
+func parsePeers(body []byte) ([]discovery.NodeInfo, error) {
+	var result peersResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		logger.Warnf("解析JSON响应失败: %v，尝试使用旧的解析方式", err)
+
+		// 兼容旧的解析逻辑
+		s := strings.TrimPrefix(string(body), `{"peers": ["`)
+		s = strings.TrimSuffix(strings.TrimSpace(s), `"]}`)
+		if s == "" {
+			return []discovery.NodeInfo{}, nil
+		}
+		result.Peers = strings.Split(s, `", "`)
+	}
+
+	if len(result.Nodes) > 0 {
+		return result.Nodes, nil
+	}
+	nodes := make([]discovery.NodeInfo, 0, len(result.Peers))
+	for _, p := range result.Peers {
+		nodes = append(nodes, discovery.ParseNodeInfo(p))
+	}
+	return nodes, nil
+}
+
+// EtcdSource 通过 etcd 直接监视节点列表，不依赖 API 服务器
+type EtcdSource struct {
+	watcher *discovery.ServiceWatcher
+
+	once    sync.Once
+	mu      sync.RWMutex
+	nodes   []discovery.NodeInfo
+	synced  chan struct{} // 收到第一份节点列表后关闭
+	lastErr error
+}
+
+// NewEtcdSource 创建基于 ServiceWatcher 的 Source，首次调用 Peers 时开始监视，直到 ctx 取消
+func NewEtcdSource(watcher *discovery.ServiceWatcher) *EtcdSource {
+	return &EtcdSource{
+		watcher: watcher,
+		synced:  make(chan struct{}),
+	}
+}
+
+// Start 开始监视，watchCtx 取消时停止；未显式调用时首次 Peers 以 context.Background 启动
+func (s *EtcdSource) Start(watchCtx context.Context) {
+	s.once.Do(func() {
+		updates, errs := s.watcher.Watch(watchCtx)
+		go func() {
+			var syncOnce sync.Once
+			for updates != nil || errs != nil {
+				select {
+				case nodes, ok := <-updates:
+					if !ok {
+						updates = nil
+						continue
+					}
+					s.mu.Lock()
+					s.nodes, s.lastErr = nodes, nil
+					s.mu.Unlock()
+					syncOnce.Do(func() { close(s.synced) })
+				case err, ok := <-errs:
+					if !ok {
+						errs = nil
+						continue
+					}
+					s.mu.Lock()
+					s.lastErr = err
+					s.mu.Unlock()
+				}
+			}
+		}()
+	})
+}
+
+// Peers 实现 Source，返回最近一次监视到的节点列表
+func (s *EtcdSource) Peers(ctx context.Context) ([]discovery.NodeInfo, error) {
+	s.Start(context.Background())
+
+	select {
+	case <-s.synced:
+	case <-ctx.Done():
+		s.mu.RLock()
+		err := s.lastErr
+		s.mu.RUnlock()
+		if err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("等待 etcd 节点列表超时: %w", ctx.Err())
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.lastErr != nil {
+		return nil, s.lastErr
+	}
+	nodes := make([]discovery.NodeInfo, len(s.nodes))
+	copy(nodes, s.nodes)
+	return nodes, nil
+}
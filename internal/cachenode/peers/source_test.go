@@ -0,0 +1,77 @@
+package peers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/AdrianWangs/go-cache/api/handlers"
+	"github.com/AdrianWangs/go-cache/internal/discovery"
+)
+
+// newNodesAPI 以真实的 NodeHandler 提供 /peers，记录 304 响应数
+func newNodesAPI(t *testing.T, nodes ...discovery.NodeInfo) (*handlers.NodeHandler, *HTTPSource, *atomic.Int64) {
+	h := handlers.NewNodeHandler()
+	h.UpdateNodes(nodes)
+	var notModified atomic.Int64
+	serve := h.NodesHandler(handlers.NodesSchemaLegacy)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := httptest.NewRecorder()
+		serve(rec, r)
+		if rec.Code == http.StatusNotModified {
+			notModified.Add(1)
+		}
+		for k, v := range rec.Header() {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(rec.Code)
+		w.Write(rec.Body.Bytes())
+	}))
+	t.Cleanup(srv.Close)
+	return h, NewHTTPSource(strings.TrimPrefix(srv.URL, "http://")), &notModified
+}
+
+func TestHTTPSourceConditionalRequests(t *testing.T) {
+	api, source, notModified := newNodesAPI(t, node1, node2)
+	ctx := context.Background()
+
+	nodes, err := source.Peers(ctx)
+	if err != nil || len(nodes) != 2 {
+		t.Fatalf("Peers = %v, %v", nodes, err)
+	}
+	if _, err := source.Peers(ctx); !errors.Is(err, ErrNotModified) {
+		t.Fatalf("未变化时 Peers = %v, want ErrNotModified", err)
+	}
+	if notModified.Load() != 1 {
+		t.Fatalf("304 响应 %d 次", notModified.Load())
+	}
+
+	api.UpdateNodes([]discovery.NodeInfo{node1})
+	if nodes, err := source.Peers(ctx); err != nil || len(nodes) != 1 {
+		t.Fatalf("变化后 Peers = %v, %v", nodes, err)
+	}
+}
+
+// TestUpdaterSkipsApplyOnNotModified 304 计为一次成功的获取，但不调用 apply
+func TestUpdaterSkipsApplyOnNotModified(t *testing.T) {
+	_, source, notModified := newNodesAPI(t, node1, node2)
+	rec := &recordingApply{}
+	u := NewUpdater(source, rec.apply)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if err := u.Update(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if rec.count() != 1 || notModified.Load() != 2 {
+		t.Fatalf("apply %d 次, 304 %d 次", rec.count(), notModified.Load())
+	}
+	if s := u.Status(); s.State() != "synced" || s.Peers != 2 {
+		t.Fatalf("状态 = %+v", s)
+	}
+}
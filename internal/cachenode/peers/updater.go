@@ -0,0 +1,317 @@
+// Package peers 维护缓存节点本地的节点列表：定期从 API 服务器或 etcd 获取，
+// 只在列表变化时更新 HTTPPool，并记录最近一次成功的时间以便发现过期的节点列表
+package peers
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/internal/discovery"
+	"github.com/AdrianWangs/go-cache/internal/server"
+	"github.com/AdrianWangs/go-cache/pkg/logger"
+)
+
+const (
+	defaultInterval   = 5 * time.Second
+	defaultTimeout    = 3 * time.Second
+	defaultMaxBackoff = time.Minute
+)
+
+// Status 节点列表更新的状态
+type Status struct {
+	LastSuccess         time.Time `json:"last_success"`         // 最近一次成功获取的时间
+	LastAttempt         time.Time `json:"last_attempt"`         // 最近一次尝试的时间
+	ConsecutiveFailures int       `json:"consecutive_failures"` // 连续失败次数
+	LastError           string    `json:"last_error,omitempty"` // 最近一次失败的原因
+	Peers               int       `json:"peers"`                // 当前节点数
+	Updates             int64     `json:"updates"`              // 节点列表实际变化的次数
+	Seeded              bool      `json:"seeded"`               // 当前使用的是种子节点列表，尚未收到服务发现的结果
+}
+
+// Lag 返回距最近一次成功获取的时间，从未成功时返回 -1
+func (s Status) Lag(now time.Time) time.Duration {
+	if s.LastSuccess.IsZero() {
+		return -1
+	}
+	return now.Sub(s.LastSuccess)
+}
+
+// State 返回节点列表同步状态的简短描述：pending（尚未成功获取）、seeded（使用种子节点）、
+// failing（最近的获取失败）或 synced
+func (s Status) State() string {
+	switch {
+	case s.Seeded:
+		return "seeded"
+	case s.LastSuccess.IsZero():
+		return "pending"
+	case s.ConsecutiveFailures > 0:
+		return "failing"
+	default:
+		return "synced"
+	}
+}
+
+// Option 配置 Updater
+type Option func(*Updater)
+
+// WithInterval 设置正常情况下两次获取的间隔，默认 5s
+func WithInterval(d time.Duration) Option {
+	return func(u *Updater) {
+		if d > 0 {
+			u.interval = d
+		}
+	}
+}
+
+// WithTimeout 设置单次获取的超时，默认 3s
+func WithTimeout(d time.Duration) Option {
+	return func(u *Updater) {
+		if d > 0 {
+			u.timeout = d
+		}
+	}
+}
+
+// WithMaxBackoff 设置连续失败时退避间隔的上限，默认 1m
+func WithMaxBackoff(d time.Duration) Option {
+	return func(u *Updater) {
+		if d > 0 {
+			u.maxBackoff = d
+		}
+	}
+}
+
+// WithDeltaApplier 设置只接收节点列表变化的 apply 函数，通常为 PoolDeltaApplier。
+// Source 实现 DeltaSource 时，第一次获取之后 Updater 请求增量并调用 fn，不再调用完整列表的 apply
+func WithDeltaApplier(fn func(Delta)) Option {
+	return func(u *Updater) {
+		u.applyDelta = fn
+	}
+}
+
+// Updater 定期从 Source 获取节点列表，列表变化时调用 apply
+type Updater struct {
+	source     Source
+	apply      func([]discovery.NodeInfo)
+	applyDelta func(Delta) // 只接收变化的 apply，可为 nil
+	interval   time.Duration
+	timeout    time.Duration
+	maxBackoff time.Duration
+
+	mu      sync.RWMutex
+	current []string             // 当前节点列表的排序编码，用于比较
+	nodes   []discovery.NodeInfo // 当前节点列表，增量在它之上应用
+	status  Status
+}
+
+// NewUpdater 创建 Updater，apply 在节点列表变化时被调用（包括第一次成功获取）
+func NewUpdater(source Source, apply func([]discovery.NodeInfo), opts ...Option) *Updater {
+	u := &Updater{
+		source:     source,
+		apply:      apply,
+		interval:   defaultInterval,
+		timeout:    defaultTimeout,
+		maxBackoff: defaultMaxBackoff,
+	}
+	for _, opt := range opts {
+		opt(u)
+	}
+	return u
+}
+
+// PoolApplier 返回将节点列表设置到 HTTPPool 的 apply 函数：
+// 以节点标识为环 key，通过登记的 HTTP 地址访问，旧版本节点回退为 gRPC 地址
+func PoolApplier(pool *server.HTTPPool) func([]discovery.NodeInfo) {
+	return func(nodes []discovery.NodeInfo) {
+		list := make([]server.Peer, 0, len(nodes))
+		for _, n := range nodes {
+			list = append(list, poolPeer(n))
+		}
+		pool.SetPeers(list...)
+	}
+}
+
+// PoolDeltaApplier 返回将节点列表的变化应用到 HTTPPool 的函数，与 PoolApplier 配合使用
+func PoolDeltaApplier(pool *server.HTTPPool) func(Delta) {
+	return func(d Delta) {
+		list := make([]server.Peer, 0, len(d.Added))
+		for _, n := range d.Added {
+			list = append(list, poolPeer(n))
+		}
+		pool.UpdatePeers(list, d.Removed)
+	}
+}
+
+// poolPeer 以节点标识为环 key，通过登记的 HTTP 地址访问，旧版本节点回退为 gRPC 地址
+func poolPeer(n discovery.NodeInfo) server.Peer {
+	addr := n.HTTPAddr
+	if addr == "" {
+		addr = n.GRPCAddr
+	}
+	return server.Peer{ID: n.Key(), Addr: "http://" + addr}
+}
+
+// Seed 立即应用静态的种子节点列表，使节点在服务发现收敛之前就能路由请求。
+// 种子列表不计入当前列表，第一次成功获取的结果总会替换它
+func (u *Updater) Seed(nodes []discovery.NodeInfo) {
+	if len(nodes) == 0 {
+		return
+	}
+
+	u.mu.Lock()
+	if u.current != nil {
+		// 已经收到服务发现的结果，种子列表已无意义
+		u.mu.Unlock()
+		return
+	}
+	u.status.Peers = len(nodes)
+	u.status.Seeded = true
+	u.mu.Unlock()
+
+	u.apply(nodes)
+	logger.Infof("已应用种子节点列表，共 %d 个节点", len(nodes))
+}
+
+// Run 立即获取一次，之后按间隔获取，直到 ctx 取消。
+// 连续失败时间隔按指数增长，直到 maxBackoff；成功后恢复正常间隔
+func (u *Updater) Run(ctx context.Context) {
+	for {
+		wait := u.interval
+		if err := u.Update(ctx); err != nil {
+			wait = u.backoff()
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// Update 获取一次节点列表，变化时调用 apply
+func (u *Updater) Update(ctx context.Context) error {
+	attemptCtx, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+
+	u.mu.RLock()
+	ds, useDelta := u.source.(DeltaSource)
+	useDelta = useDelta && u.applyDelta != nil && u.current != nil
+	u.mu.RUnlock()
+
+	now := time.Now()
+	var nodes []discovery.NodeInfo
+	var delta *Delta
+	var err error
+	if useDelta {
+		nodes, delta, err = ds.PeersDelta(attemptCtx)
+	} else {
+		nodes, err = u.source.Peers(attemptCtx)
+	}
+
+	u.mu.Lock()
+	u.status.LastAttempt = now
+	if errors.Is(err, ErrNotModified) && u.current != nil {
+		// 节点列表没有变化，视为一次成功的获取
+		u.status.LastSuccess = now
+		u.status.ConsecutiveFailures = 0
+		u.status.LastError = ""
+		u.mu.Unlock()
+		return nil
+	}
+	if err != nil {
+		u.status.ConsecutiveFailures++
+		u.status.LastError = err.Error()
+		failures := u.status.ConsecutiveFailures
+		u.mu.Unlock()
+		logger.Errorf("更新节点列表失败（连续 %d 次）: %v", failures, err)
+		return err
+	}
+	u.status.LastSuccess = now
+	u.status.ConsecutiveFailures = 0
+	u.status.LastError = ""
+
+	var encoded []string
+	var changed bool
+	if delta != nil {
+		// 增量在当前列表之上应用，没有变化时不必比较完整列表
+		if changed = !delta.Empty(); changed {
+			nodes = discovery.ApplyNodesDiff(u.nodes, delta.Added, delta.Removed)
+			encoded = encodeSorted(nodes)
+		}
+	} else {
+		encoded = encodeSorted(nodes)
+		changed = u.current == nil || !equalStrings(u.current, encoded)
+	}
+	if changed {
+		u.current = encoded
+		u.nodes = nodes
+		u.status.Peers = len(nodes)
+		u.status.Updates++
+		u.status.Seeded = false
+	}
+	u.mu.Unlock()
+
+	if !changed {
+		return nil
+	}
+	if delta != nil {
+		u.applyDelta(*delta)
+		logger.Infof("节点列表已更新，新增或变化 %d 个，移除 %d 个，共 %d 个节点", len(delta.Added), len(delta.Removed), len(nodes))
+		return nil
+	}
+	u.apply(nodes)
+	logger.Infof("节点列表已更新，共 %d 个节点", len(nodes))
+	return nil
+}
+
+// Status 返回当前的更新状态
+func (u *Updater) Status() Status {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+	return u.status
+}
+
+// backoff 根据连续失败次数计算下一次尝试前的等待时间
+func (u *Updater) backoff() time.Duration {
+	u.mu.RLock()
+	failures := u.status.ConsecutiveFailures
+	u.mu.RUnlock()
+
+	wait := u.interval
+	for i := 1; i < failures && wait < u.maxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > u.maxBackoff {
+		wait = u.maxBackoff
+	}
+	return wait
+}
+
+// encodeSorted 将节点列表编码并排序，使顺序不同的相同列表比较结果相等
+func encodeSorted(nodes []discovery.NodeInfo) []string {
+	encoded := make([]string, 0, len(nodes))
+	for _, n := range nodes {
+		encoded = append(encoded, n.Encode())
+	}
+	sort.Strings(encoded)
+	return encoded
+}
+
+// equalStrings 比较两个字符串切片是否相同
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
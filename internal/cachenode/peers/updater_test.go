@@ -0,0 +1,206 @@
+package peers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/internal/discovery"
+)
+
+// fakeAPI 模拟 API 服务器的 /peers 接口，可以切换为挂起或返回错误
+type fakeAPI struct {
+	mode     atomic.Value // "ok"、"hang" 或 "error"
+	requests atomic.Int64
+
+	mu    sync.Mutex
+	nodes []discovery.NodeInfo
+}
+
+func newFakeAPI(t *testing.T, nodes ...discovery.NodeInfo) (*fakeAPI, *HTTPSource) {
+	api := &fakeAPI{nodes: nodes}
+	api.mode.Store("ok")
+	srv := httptest.NewServer(api)
+	t.Cleanup(srv.Close)
+	return api, NewHTTPSource(strings.TrimPrefix(srv.URL, "http://"))
+}
+
+func (a *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.requests.Add(1)
+	switch a.mode.Load() {
+	case "hang":
+		<-r.Context().Done()
+		return
+	case "error":
+		http.Error(w, "etcd unavailable", http.StatusInternalServerError)
+		return
+	}
+	a.mu.Lock()
+	nodes := a.nodes
+	a.mu.Unlock()
+	json.NewEncoder(w).Encode(peersResponse{Nodes: nodes})
+}
+
+func (a *fakeAPI) setNodes(nodes ...discovery.NodeInfo) {
+	a.mu.Lock()
+	a.nodes = nodes
+	a.mu.Unlock()
+}
+
+// recordingApply 记录每次 apply 收到的节点列表
+type recordingApply struct {
+	mu    sync.Mutex
+	calls [][]discovery.NodeInfo
+}
+
+func (r *recordingApply) apply(nodes []discovery.NodeInfo) {
+	r.mu.Lock()
+	r.calls = append(r.calls, nodes)
+	r.mu.Unlock()
+}
+
+func (r *recordingApply) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.calls)
+}
+
+var (
+	node1 = discovery.NodeInfo{GRPCAddr: "10.0.0.1:9090", HTTPAddr: "10.0.0.1:8001"}
+	node2 = discovery.NodeInfo{GRPCAddr: "10.0.0.2:9090", HTTPAddr: "10.0.0.2:8001"}
+)
+
+func TestUpdaterHangErrorRecover(t *testing.T) {
+	api, source := newFakeAPI(t, node1)
+	rec := &recordingApply{}
+	u := NewUpdater(source, rec.apply, WithTimeout(50*time.Millisecond))
+	ctx := context.Background()
+
+	// 挂起的 API 服务器在单次超时后返回错误，而不是阻塞更新
+	api.mode.Store("hang")
+	start := time.Now()
+	if err := u.Update(ctx); err == nil {
+		t.Fatal("挂起的请求应当超时")
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("超时用了 %v", d)
+	}
+	if s := u.Status(); s.State() != "pending" || s.ConsecutiveFailures != 1 || s.Lag(time.Now()) != -1 {
+		t.Fatalf("挂起后状态 = %+v", s)
+	}
+
+	api.mode.Store("error")
+	if err := u.Update(ctx); err == nil || !strings.Contains(err.Error(), "500") {
+		t.Fatalf("错误响应 = %v", err)
+	}
+	if s := u.Status(); s.ConsecutiveFailures != 2 || s.LastError == "" {
+		t.Fatalf("错误后状态 = %+v", s)
+	}
+
+	api.mode.Store("ok")
+	if err := u.Update(ctx); err != nil {
+		t.Fatalf("恢复后更新失败: %v", err)
+	}
+	s := u.Status()
+	if s.State() != "synced" || s.ConsecutiveFailures != 0 || s.LastError != "" || s.Peers != 1 || s.Updates != 1 {
+		t.Fatalf("恢复后状态 = %+v", s)
+	}
+	if lag := s.Lag(time.Now()); lag < 0 || lag > time.Second {
+		t.Fatalf("Lag = %v", lag)
+	}
+	if rec.count() != 1 {
+		t.Fatalf("apply 调用 %d 次", rec.count())
+	}
+
+	// 之后的失败保留最近一次成功的时间，状态为 failing
+	api.mode.Store("error")
+	u.Update(ctx)
+	if got := u.Status(); got.State() != "failing" || !got.LastSuccess.Equal(s.LastSuccess) {
+		t.Fatalf("再次失败后状态 = %+v", got)
+	}
+}
+
+func TestUpdaterAppliesOnlyChanges(t *testing.T) {
+	api, source := newFakeAPI(t, node1, node2)
+	rec := &recordingApply{}
+	u := NewUpdater(source, rec.apply)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if err := u.Update(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if rec.count() != 1 {
+		t.Fatalf("列表未变化时 apply 调用 %d 次", rec.count())
+	}
+
+	// 顺序不同的相同列表不算变化
+	api.setNodes(node2, node1)
+	u.Update(ctx)
+	if rec.count() != 1 {
+		t.Fatalf("顺序变化时 apply 调用 %d 次", rec.count())
+	}
+
+	api.setNodes(node1)
+	u.Update(ctx)
+	if rec.count() != 2 || len(rec.calls[1]) != 1 {
+		t.Fatalf("列表变化后 apply 调用 %d 次", rec.count())
+	}
+	if s := u.Status(); s.Updates != 2 || s.Peers != 1 {
+		t.Fatalf("状态 = %+v", s)
+	}
+}
+
+func TestUpdaterBackoff(t *testing.T) {
+	u := NewUpdater(nil, nil, WithInterval(time.Second), WithMaxBackoff(10*time.Second))
+	for failures, want := range []time.Duration{time.Second, time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second} {
+		u.status.ConsecutiveFailures = failures
+		if got := u.backoff(); got != want {
+			t.Errorf("%d 次失败后等待 %v, want %v", failures, got, want)
+		}
+	}
+}
+
+// TestUpdaterRunRecovers Run 在 API 服务器挂起期间按退避重试，恢复后更新节点列表
+func TestUpdaterRunRecovers(t *testing.T) {
+	api, source := newFakeAPI(t, node1)
+	api.mode.Store("hang")
+	rec := &recordingApply{}
+	u := NewUpdater(source, rec.apply, WithInterval(10*time.Millisecond), WithTimeout(20*time.Millisecond), WithMaxBackoff(40*time.Millisecond))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		u.Run(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	waitUntil(t, func() bool { return u.Status().ConsecutiveFailures >= 3 })
+	api.mode.Store("ok")
+	waitUntil(t, func() bool { return u.Status().State() == "synced" })
+	if rec.count() != 1 {
+		t.Fatalf("apply 调用 %d 次", rec.count())
+	}
+}
+
+func waitUntil(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("等待超时")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
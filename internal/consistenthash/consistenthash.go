@@ -10,31 +10,91 @@ import (
 	"github.com/AdrianWangs/go-cache/pkg/logger"
 )
 
-// Hash maps bytes to uint32
-type Hash func(data []byte) uint32
+// maxResalts bounds the attempts to find a free slot for a colliding virtual node
+const maxResalts = 64
 
 // Map is a thread-safe implementation of a consistent hash map
 type Map struct {
 	mutex    sync.RWMutex
-	hash     Hash           // hash function
-	replicas int            // number of virtual nodes per real node
-	keys     []int          // sorted hash keys
-	hashMap  map[int]string // hash key -> real node mapping
+	hash     Hash64            // hash function, 32-bit hashes are widened
+	hashName string            // name of the hash function, reported by Describe
+	bits     uint              // width of the hash space, 32 or 64
+	replicas int               // number of virtual nodes per real node
+	keys     []uint64          // sorted hash keys
+	hashMap  map[uint64]string // hash key -> real node mapping
+
+	// compat keeps the original behaviour: virtual nodes are added in Add order
+	// and a colliding one silently takes over the earlier node's slot.
+	// Otherwise the ring is rebuilt from the sorted node set on every change and
+	// colliding virtual nodes are re-salted, so ownership only depends on membership.
+	compat bool
+	nodes  map[string]struct{} // real nodes, maintained when !compat
+
+	// generation counts the topology changes applied by Add and Remove, see Generation
+	generation uint64
 }
 
-// New creates a Map instance with the given replicas count and hash function
-func New(replicas int, fn Hash) *Map {
+// New creates a Map instance with the given replicas count and hash function.
+// fn defaults to crc32; WithHash64 switches to a 64-bit hash. Virtual node
+// collisions are logged and the later node (in name order) is re-salted.
+func New(replicas int, fn Hash, opts ...Option) *Map {
 	m := &Map{
 		replicas: replicas,
-		hash:     fn,
-		hashMap:  make(map[int]string),
+		hashName: HashCustom,
+		bits:     32,
+		hashMap:  make(map[uint64]string),
+		nodes:    make(map[string]struct{}),
+	}
+	if fn == nil {
+		fn = crc32.ChecksumIEEE
+		m.hashName = HashCRC32
 	}
-	if m.hash == nil {
-		m.hash = crc32.ChecksumIEEE
+	m.hash = func(data []byte) uint64 { return uint64(fn(data)) }
+	for _, opt := range opts {
+		opt(m)
 	}
 	return m
 }
 
+// NewCompat creates a crc32 Map that places keys exactly like earlier releases,
+// including letting a colliding virtual node overwrite an earlier one. Use it
+// wherever the ring must agree with processes that have not been upgraded.
+func NewCompat(replicas int, opts ...Option) *Map {
+	m := New(replicas, nil, opts...)
+	m.compat = true
+	return m
+}
+
+// Replicas returns the number of virtual nodes per real node
+func (m *Map) Replicas() int {
+	return m.replicas
+}
+
+// HashName returns the name of the hash function, HashCustom for user supplied ones
+func (m *Map) HashName() string {
+	return m.hashName
+}
+
+// Generation returns the number of topology changes applied to the ring: every
+// Add and Remove increments it, starting from the value set by WithGeneration.
+// Owners of a ring that recreate it on membership changes carry the generation
+// over, so it increases for as long as they run.
+func (m *Map) Generation() uint64 {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return m.generation
+}
+
+// virtualHash returns the hash of the i-th virtual node of key; salt > 0 is
+// used when the unsalted position is already taken.
+func (m *Map) virtualHash(key string, i, salt int) uint64 {
+	name := strconv.Itoa(i) + key
+	if salt > 0 {
+		name += "#" + strconv.Itoa(salt)
+	}
+	return m.hash([]byte(name))
+}
+
 // Add 用于往一致性哈希环中添加节点
 //
 // 传入参数:
@@ -43,16 +103,73 @@ func (m *Map) Add(keys ...string) {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 
+	m.generation++
+
+	if !m.compat {
+		for _, key := range keys {
+			m.nodes[key] = struct{}{}
+		}
+		m.rebuild()
+		return
+	}
+
 	for _, key := range keys {
 		// Create 'replicas' virtual nodes for each real node
 		for i := 0; i < m.replicas; i++ {
 			// Calculate hash for virtual node
-			hash := int(m.hash([]byte(strconv.Itoa(i) + key)))
-			m.keys = append(m.keys, hash)
+			hash := m.virtualHash(key, i, 0)
+			if owner, ok := m.hashMap[hash]; !ok {
+				m.keys = append(m.keys, hash)
+			} else if owner != key {
+				logger.Warnf("一致性哈希: 节点 %s 的虚拟节点 %d 与节点 %s 冲突 (hash=%d)，兼容模式下覆盖原节点", key, i, owner, hash)
+			}
 			m.hashMap[hash] = key
 		}
 	}
-	sort.Ints(m.keys)
+	sortKeys(m.keys)
+}
+
+// rebuild places the virtual nodes of all nodes in name order, re-salting any
+// virtual node whose position is already taken. The caller must hold the lock.
+func (m *Map) rebuild() {
+	names := make([]string, 0, len(m.nodes))
+	for name := range m.nodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	keys := make([]uint64, 0, len(names)*m.replicas)
+	hashMap := make(map[uint64]string, len(names)*m.replicas)
+	for _, name := range names {
+		for i := 0; i < m.replicas; i++ {
+			hash := m.virtualHash(name, i, 0)
+			for salt := 1; ; salt++ {
+				owner, taken := hashMap[hash]
+				if !taken {
+					break
+				}
+				if salt > maxResalts {
+					logger.Errorf("一致性哈希: 节点 %s 的虚拟节点 %d 多次重新加盐仍然冲突，已跳过", name, i)
+					break
+				}
+				logger.Warnf("一致性哈希: 节点 %s 的虚拟节点 %d 与节点 %s 冲突 (hash=%d)，重新加盐", name, i, owner, hash)
+				hash = m.virtualHash(name, i, salt)
+			}
+			if _, taken := hashMap[hash]; taken {
+				continue
+			}
+			keys = append(keys, hash)
+			hashMap[hash] = name
+		}
+	}
+	sortKeys(keys)
+	m.keys = keys
+	m.hashMap = hashMap
+}
+
+// sortKeys sorts hash keys in ascending order
+func sortKeys(keys []uint64) {
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
 }
 
 // Get gets the closest node in the hash to the provided key
@@ -65,21 +182,47 @@ func (m *Map) Get(key string) string {
 	}
 
 	// Calculate hash for the key
-	hash := int(m.hash([]byte(key)))
+	hash := m.hash([]byte(key))
+	node := m.hashMap[m.keys[m.search(hash)]]
+	logger.Debugf("一致性哈希: key=%s, hash=%d, 选中节点=%s", logger.Key(key), hash, node)
+	return node
+}
 
-	// Binary search for the first hash >= hash
+// search returns the index of the first virtual node whose hash is >= hash,
+// wrapping around to the first one. The caller must hold the lock and the ring
+// must not be empty.
+func (m *Map) search(hash uint64) int {
 	idx := sort.Search(len(m.keys), func(i int) bool {
 		return m.keys[i] >= hash
 	})
-
-	// If we reached the end, wrap around to the first replica
 	if idx == len(m.keys) {
 		idx = 0
 	}
+	return idx
+}
+
+// GetN returns up to n distinct nodes for key, walking the ring clockwise from
+// the key's position. The first node is the one Get would return.
+func (m *Map) GetN(key string, n int) []string {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	if len(m.keys) == 0 || n <= 0 {
+		return nil
+	}
 
-	node := m.hashMap[m.keys[idx]]
-	logger.Debugf("一致性哈希: key=%s, hash=%d, 选中节点=%s", key, hash, node)
-	return node
+	idx := m.search(m.hash([]byte(key)))
+	nodes := make([]string, 0, n)
+	seen := make(map[string]struct{}, n)
+	for i := 0; i < len(m.keys) && len(nodes) < n; i++ {
+		node := m.hashMap[m.keys[(idx+i)%len(m.keys)]]
+		if _, ok := seen[node]; ok {
+			continue
+		}
+		seen[node] = struct{}{}
+		nodes = append(nodes, node)
+	}
+	return nodes
 }
 
 // Remove removes a node from the hash
@@ -87,9 +230,17 @@ func (m *Map) Remove(key string) {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 
+	m.generation++
+
+	if !m.compat {
+		delete(m.nodes, key)
+		m.rebuild()
+		return
+	}
+
 	// Create a new keys slice and hashMap
-	newKeys := make([]int, 0, len(m.keys)-m.replicas)
-	newHashMap := make(map[int]string, len(m.hashMap)-m.replicas)
+	newKeys := make([]uint64, 0, len(m.keys))
+	newHashMap := make(map[uint64]string, len(m.hashMap))
 
 	// Copy over entries not related to the removed key
 	for hash, k := range m.hashMap {
@@ -100,7 +251,7 @@ func (m *Map) Remove(key string) {
 	}
 
 	// Sort the new keys
-	sort.Ints(newKeys)
+	sortKeys(newKeys)
 
 	// Update the map
 	m.keys = newKeys
@@ -0,0 +1,116 @@
+package consistenthash
+
+import (
+	"math"
+	"strconv"
+)
+
+// Description summarizes the shape of the ring. Arc sizes are the distance from
+// the previous virtual node to each virtual node, i.e. the slice of the hash
+// space that virtual node owns, expressed as a fraction of the whole ring.
+type Description struct {
+	Hash         string  `json:"hash"`         // name of the hash function
+	Replicas     int     `json:"replicas"`     // virtual nodes per real node
+	Nodes        int     `json:"nodes"`        // number of real nodes
+	VirtualNodes int     `json:"virtualNodes"` // number of points on the ring
+	MinArc       float64 `json:"minArc"`       // smallest arc
+	MaxArc       float64 `json:"maxArc"`       // largest arc
+	MeanArc      float64 `json:"meanArc"`      // average arc, 1/VirtualNodes
+	StdDevArc    float64 `json:"stddevArc"`    // population standard deviation of the arcs
+
+	Generation uint64 `json:"generation"` // topology changes applied to the ring, see Map.Generation
+}
+
+// Report is a Description together with the key distribution measured by
+// Distribution, served by the ring admin endpoints of the API server and nodes.
+type Report struct {
+	Description
+	Samples int                `json:"samples"` // number of synthetic keys hashed
+	Shares  map[string]float64 `json:"shares"`  // node -> fraction of the sampled keys
+}
+
+// Describe returns node count, virtual node count and arc statistics of the ring
+func (m *Map) Describe() Description {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	d := Description{
+		Hash:         m.hashName,
+		Replicas:     m.replicas,
+		VirtualNodes: len(m.keys),
+		Generation:   m.generation,
+	}
+	nodes := make(map[string]struct{})
+	for _, node := range m.hashMap {
+		nodes[node] = struct{}{}
+	}
+	d.Nodes = len(nodes)
+	if len(m.keys) == 0 {
+		return d
+	}
+
+	ringSize := math.Pow(2, float64(m.bits))
+	arcs := make([]float64, len(m.keys))
+	for i, k := range m.keys {
+		// the first virtual node also owns the wrap-around arc, which unsigned
+		// subtraction yields modulo 2^64 and the mask reduces to the hash width
+		arc := k - m.keys[(i+len(m.keys)-1)%len(m.keys)]
+		if m.bits < 64 {
+			arc &= 1<<m.bits - 1
+		}
+		if len(m.keys) == 1 {
+			arcs[i] = 1
+			continue
+		}
+		arcs[i] = float64(arc) / ringSize
+	}
+
+	d.MinArc, d.MaxArc = arcs[0], arcs[0]
+	var sum float64
+	for _, a := range arcs {
+		sum += a
+		d.MinArc = math.Min(d.MinArc, a)
+		d.MaxArc = math.Max(d.MaxArc, a)
+	}
+	d.MeanArc = sum / float64(len(arcs))
+	var variance float64
+	for _, a := range arcs {
+		variance += (a - d.MeanArc) * (a - d.MeanArc)
+	}
+	d.StdDevArc = math.Sqrt(variance / float64(len(arcs)))
+	return d
+}
+
+// Distribution hashes samples synthetic keys and returns the fraction of them
+// each node receives. Nodes that receive no keys are reported with a share of 0.
+func (m *Map) Distribution(samples int) map[string]float64 {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	shares := make(map[string]float64)
+	for _, node := range m.hashMap {
+		shares[node] = 0
+	}
+	if len(m.keys) == 0 || samples <= 0 {
+		return shares
+	}
+
+	counts := make(map[string]int, len(shares))
+	for i := 0; i < samples; i++ {
+		hash := m.hash([]byte("key-" + strconv.Itoa(i)))
+		counts[m.hashMap[m.keys[m.search(hash)]]]++
+	}
+	for node, n := range counts {
+		shares[node] = float64(n) / float64(samples)
+	}
+	return shares
+}
+
+// Report returns Describe and Distribution(samples) together
+func (m *Map) Report(samples int) Report {
+	return Report{
+		Description: m.Describe(),
+		Samples:     samples,
+		Shares:      m.Distribution(samples),
+	}
+}
@@ -0,0 +1,94 @@
+package consistenthash
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// nodes returns n node names
+func nodes(n int) []string {
+	names := make([]string, n)
+	for i := range names {
+		names[i] = fmt.Sprintf("10.0.0.%d:9090", i+1)
+	}
+	return names
+}
+
+// TestStdDevShrinksWithReplicas guards the hashing of virtual nodes: more
+// replicas must spread the arcs more evenly for every hash function.
+func TestStdDevShrinksWithReplicas(t *testing.T) {
+	for _, name := range []string{HashCRC32, HashXXHash64} {
+		t.Run(name, func(t *testing.T) {
+			var prev Description
+			for _, replicas := range []int{1, 10, 50, 200} {
+				m, err := NewByName(replicas, name)
+				if err != nil {
+					t.Fatal(err)
+				}
+				m.Add(nodes(8)...)
+				d := m.Describe()
+				if d.Nodes != 8 || d.VirtualNodes != 8*replicas || d.Hash != name {
+					t.Fatalf("replicas %d: %+v", replicas, d)
+				}
+				if math.Abs(d.MeanArc-1/float64(d.VirtualNodes)) > 1e-9 {
+					t.Fatalf("replicas %d: mean arc %v, want %v", replicas, d.MeanArc, 1/float64(d.VirtualNodes))
+				}
+				if prev.VirtualNodes > 0 && d.StdDevArc >= prev.StdDevArc {
+					t.Fatalf("stddev %v with %d replicas, %v with %d", d.StdDevArc, replicas, prev.StdDevArc, prev.Replicas)
+				}
+				prev = d
+			}
+		})
+	}
+}
+
+// TestDistributionBalances checks that the share of the most loaded node
+// approaches 1/n as replicas increase.
+func TestDistributionBalances(t *testing.T) {
+	spread := func(replicas int) float64 {
+		m := New(replicas, nil)
+		m.Add(nodes(5)...)
+		shares := m.Distribution(100000)
+		if len(shares) != 5 {
+			t.Fatalf("shares of %d nodes: %v", len(shares), shares)
+		}
+		var sum, worst float64
+		for _, s := range shares {
+			sum += s
+			worst = math.Max(worst, math.Abs(s-0.2))
+		}
+		if math.Abs(sum-1) > 1e-9 {
+			t.Fatalf("shares sum to %v", sum)
+		}
+		return worst
+	}
+	few, many := spread(2), spread(200)
+	if many >= few || many > 0.05 {
+		t.Fatalf("largest deviation from 1/n: %v with 2 replicas, %v with 200", few, many)
+	}
+}
+
+func TestDescribeEdgeCases(t *testing.T) {
+	m := New(3, nil)
+	if d := m.Describe(); d.Nodes != 0 || d.VirtualNodes != 0 || d.StdDevArc != 0 {
+		t.Fatalf("empty ring: %+v", d)
+	}
+	if shares := m.Distribution(100); len(shares) != 0 {
+		t.Fatalf("empty ring shares: %v", shares)
+	}
+
+	m = New(1, nil)
+	m.Add("only")
+	if d := m.Describe(); d.MinArc != 1 || d.MaxArc != 1 || d.StdDevArc != 0 {
+		t.Fatalf("single virtual node: %+v", d)
+	}
+	if shares := m.Distribution(0); shares["only"] != 0 {
+		t.Fatalf("no samples: %v", shares)
+	}
+
+	r := m.Report(10)
+	if r.Samples != 10 || r.Shares["only"] != 1 || r.Nodes != 1 {
+		t.Fatalf("report: %+v", r)
+	}
+}
@@ -0,0 +1,59 @@
+package consistenthash
+
+import "testing"
+
+// TestGeneration checks that every Add and Remove call counts once, whatever
+// the number of keys, on both ring kinds.
+func TestGeneration(t *testing.T) {
+	for _, name := range []string{HashCRC32, HashXXHash64} {
+		t.Run(name, func(t *testing.T) {
+			m, err := NewByName(10, name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if g := m.Generation(); g != 0 {
+				t.Fatalf("new ring at generation %d", g)
+			}
+			m.Add(nodes(3)...)
+			if g := m.Generation(); g != 1 {
+				t.Fatalf("after one Add of 3 nodes: generation %d, want 1", g)
+			}
+			m.Add("10.0.0.9:9090")
+			m.Remove("10.0.0.1:9090")
+			if g := m.Generation(); g != 3 {
+				t.Fatalf("after Add and Remove: generation %d, want 3", g)
+			}
+			if d := m.Describe(); d.Generation != 3 {
+				t.Fatalf("Describe().Generation = %d, want 3", d.Generation)
+			}
+		})
+	}
+}
+
+// TestWithGeneration rebuilds a ring the way its owners do on a membership
+// change: the replacement continues from the old generation.
+func TestWithGeneration(t *testing.T) {
+	old := New(10, nil)
+	old.Add(nodes(3)...)
+	old.Add("10.0.0.9:9090")
+
+	for _, m := range []*Map{
+		New(10, nil, WithGeneration(old.Generation())),
+		NewCompat(10, WithGeneration(old.Generation())),
+	} {
+		if g := m.Generation(); g != 2 {
+			t.Fatalf("ring started at generation %d, want 2", g)
+		}
+		m.Add(nodes(2)...)
+		if g := m.Generation(); g != 3 {
+			t.Fatalf("rebuilt ring at generation %d, want 3", g)
+		}
+	}
+	m, err := NewByName(10, HashXXHash64, WithGeneration(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g := m.Generation(); g != 10 || m.HashName() != HashXXHash64 {
+		t.Fatalf("NewByName with WithGeneration(10): generation %d, hash %s", g, m.HashName())
+	}
+}
@@ -0,0 +1,78 @@
+package consistenthash
+
+import "fmt"
+
+// Names reported by HashName and Describe, and accepted by ParseHash
+const (
+	HashCRC32    = "crc32"    // the default hash/crc32.ChecksumIEEE
+	HashXXHash64 = "xxhash64" // 64-bit xxHash64, see XXHash64
+	HashCustom   = "custom"   // a 32-bit hash function passed to New
+	HashCustom64 = "custom64" // a 64-bit hash function passed to WithHash64 without a name
+)
+
+// Hash maps bytes to uint32
+type Hash func(data []byte) uint32
+
+// Hash64 maps bytes to uint64
+type Hash64 func(data []byte) uint64
+
+// Option configures a Map created by New
+type Option func(*Map)
+
+// WithHash64 places virtual nodes and keys with a 64-bit hash instead of the
+// 32-bit one passed to New. name is reported by Describe; empty means HashCustom64.
+func WithHash64(name string, fn Hash64) Option {
+	return func(m *Map) {
+		if fn == nil {
+			return
+		}
+		if name == "" {
+			name = HashCustom64
+		}
+		m.hash = fn
+		m.hashName = name
+		m.bits = 64
+	}
+}
+
+// WithXXHash64 uses XXHash64 as the ring hash
+func WithXXHash64() Option {
+	return WithHash64(HashXXHash64, XXHash64)
+}
+
+// WithGeneration starts the ring at generation g instead of 0. Pass the
+// Generation of the ring being replaced so the count survives the rebuild.
+func WithGeneration(g uint64) Option {
+	return func(m *Map) {
+		m.generation = g
+	}
+}
+
+// ParseHash validates a ring hash name as used in configuration and returns its
+// canonical form; the empty string means HashCRC32.
+func ParseHash(name string) (string, error) {
+	switch name {
+	case "", HashCRC32:
+		return HashCRC32, nil
+	case HashXXHash64:
+		return HashXXHash64, nil
+	default:
+		return "", fmt.Errorf("unknown ring hash %q, must be %s or %s", name, HashCRC32, HashXXHash64)
+	}
+}
+
+// NewByName creates the ring used for routing between the API server and cache
+// nodes. HashCRC32 (or "") returns NewCompat, so deployments that never set a
+// hash keep their key ownership; HashXXHash64 returns a 64-bit ring with
+// collision re-salting. Every process of a cluster must use the same name.
+// opts, such as WithGeneration, apply to either ring.
+func NewByName(replicas int, name string, opts ...Option) (*Map, error) {
+	name, err := ParseHash(name)
+	if err != nil {
+		return nil, err
+	}
+	if name == HashXXHash64 {
+		return New(replicas, nil, append([]Option{WithXXHash64()}, opts...)...), nil
+	}
+	return NewCompat(replicas, opts...), nil
+}
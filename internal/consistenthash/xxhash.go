@@ -0,0 +1,10 @@
+package consistenthash
+
+import "github.com/cespare/xxhash/v2"
+
+// XXHash64 hashes data with xxHash64 (seed 0). Unlike FNV it mixes every input
+// byte into all output bits, so the short, similar virtual node names spread
+// evenly over the ring.
+func XXHash64(data []byte) uint64 {
+	return xxhash.Sum64(data)
+}
@@ -0,0 +1,91 @@
+package consistenthash
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+// TestXXHash64KnownAnswers checks XXHash64 against the reference xxHash64 outputs
+// for seed 0, covering the short path and the 32-byte stripe loop
+func TestXXHash64KnownAnswers(t *testing.T) {
+	tests := []struct {
+		in   string
+		want uint64
+	}{
+		{"", 0xef46db3751d8e999},
+		{"a", 0xd24ec4f1a98c6e5b},
+		{"abc", 0x44bc2cf5ad770999},
+		{"Nobody inspects the spammish repetition", 0xfbcea83c8a378bf1},
+	}
+	for _, tt := range tests {
+		if got := XXHash64([]byte(tt.in)); got != tt.want {
+			t.Errorf("XXHash64(%q) = %#x, want %#x", tt.in, got, tt.want)
+		}
+	}
+}
+
+// collidingHash is XXHash64 except that the first virtual node of node-b lands
+// exactly on the first virtual node of node-a
+func collidingHash(data []byte) uint64 {
+	if string(data) == "0node-b" {
+		return XXHash64([]byte("0node-a"))
+	}
+	return XXHash64(data)
+}
+
+// TestCollisionResaltDeterministic forces a virtual node collision: the later
+// node in name order is re-salted, every node keeps all its virtual nodes, and
+// nodes that add the members in different orders build the same ring
+func TestCollisionResaltDeterministic(t *testing.T) {
+	const replicas = 3
+	orders := [][][]string{
+		{{"node-a", "node-b", "node-c"}},
+		{{"node-c"}, {"node-b"}, {"node-a"}},
+		{{"node-b", "node-c"}, {"node-a"}},
+		{{"node-b"}, {"node-a", "node-c", "node-x"}, {}},
+	}
+	var rings []*Map
+	for _, adds := range orders {
+		m := New(replicas, nil, WithHash64("colliding", collidingHash))
+		for _, nodes := range adds {
+			m.Add(nodes...)
+		}
+		m.Remove("node-x")
+		rings = append(rings, m)
+	}
+
+	first := rings[0]
+	if got := len(first.keys); got != 3*replicas {
+		t.Fatalf("ring has %d virtual nodes, want %d", got, 3*replicas)
+	}
+	collided := XXHash64([]byte("0node-a"))
+	if owner := first.hashMap[collided]; owner != "node-a" {
+		t.Fatalf("contested slot owned by %q, want node-a, the first in name order", owner)
+	}
+	if owner := first.hashMap[XXHash64([]byte("0node-b#1"))]; owner != "node-b" {
+		t.Fatal("node-b's colliding virtual node was not re-salted")
+	}
+
+	for i, m := range rings[1:] {
+		if !slices.Equal(m.keys, first.keys) {
+			t.Fatalf("ring %d has virtual nodes %v, want %v", i+1, m.keys, first.keys)
+		}
+		for j := 0; j < 1000; j++ {
+			key := fmt.Sprintf("key-%d", j)
+			if got, want := m.Get(key), first.Get(key); got != want {
+				t.Fatalf("ring %d: Get(%s) = %s, want %s", i+1, key, got, want)
+			}
+		}
+	}
+
+	// The compat ring keeps the old behaviour: the later Add takes the slot over
+	compat := NewCompat(replicas, WithHash64("colliding", collidingHash))
+	compat.Add("node-a", "node-b")
+	if got := len(compat.keys); got != 2*replicas-1 {
+		t.Fatalf("compat ring has %d virtual nodes, want %d", got, 2*replicas-1)
+	}
+	if owner := compat.hashMap[collided]; owner != "node-b" {
+		t.Fatalf("compat ring: contested slot owned by %q, want node-b", owner)
+	}
+}
@@ -0,0 +1,189 @@
+package deletequeue
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/AdrianWangs/go-cache/pkg/logger"
+)
+
+// minCompact is the number of done records the journal tolerates before it is
+// rewritten, however small the queue
+const minCompact = 64
+
+// record is one line of the journal: a delete added to, or removed from, the queue
+type record struct {
+	Op string `json:"op"` // "add" or "done"
+	Delete
+}
+
+// journal is an append-only file of records. Replaying it yields the queued
+// deletes; it is rewritten with only those once removals outnumber them.
+type journal struct {
+	path string
+	file *os.File
+	w    *bufio.Writer
+	done int // done records since the last rewrite
+}
+
+// loadJournal replays the journal at path and returns the deletes still queued,
+// oldest first. A missing file is an empty queue; a truncated last line, left
+// by a crash mid-write, is ignored.
+func loadJournal(path string) ([]Delete, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open delete journal: %w", err)
+	}
+	defer f.Close()
+
+	pending := make(map[id]Delete)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		var rec record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			logger.Warnf("[DeleteQueue] 跳过删除日志 %s 第 %d 行无法解析的记录: %v", path, line, err)
+			continue
+		}
+		switch rec.Op {
+		case "add":
+			pending[rec.id()] = rec.Delete
+		case "done":
+			delete(pending, rec.id())
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read delete journal: %w", err)
+	}
+	return sortDeletes(pending), nil
+}
+
+// openJournal rewrites the journal at path with pending and opens it for appending
+func openJournal(path string, pending []Delete) (*journal, error) {
+	j := &journal{path: path}
+	if err := j.rewrite(pending); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// rewrite replaces the file with add records for pending, through a temporary
+// file so that a crash leaves either the old journal or the new one
+func (j *journal) rewrite(pending []Delete) error {
+	tmp := j.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return fmt.Errorf("create delete journal: %w", err)
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, d := range pending {
+		if err := enc.Encode(record{Op: "add", Delete: d}); err != nil {
+			f.Close()
+			return fmt.Errorf("write delete journal: %w", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return fmt.Errorf("write delete journal: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("sync delete journal: %w", err)
+	}
+	if err := os.Rename(tmp, j.path); err != nil {
+		f.Close()
+		return fmt.Errorf("replace delete journal: %w", err)
+	}
+	if j.file != nil {
+		j.file.Close()
+	}
+	j.file = f
+	j.w = bufio.NewWriter(f)
+	j.done = 0
+	return nil
+}
+
+// append writes rec and flushes it to the file. A delete already acknowledged
+// to the caller must not be lost with the process, so it is not buffered.
+func (j *journal) append(rec record) error {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	if _, err := j.w.Write(b); err != nil {
+		return err
+	}
+	return j.w.Flush()
+}
+
+// close syncs and closes the file
+func (j *journal) close() error {
+	if err := j.w.Flush(); err != nil {
+		j.file.Close()
+		return err
+	}
+	if err := j.file.Sync(); err != nil {
+		j.file.Close()
+		return err
+	}
+	return j.file.Close()
+}
+
+// journalAddLocked records an added delete; the caller holds q.mu. A journal
+// that cannot be written is logged and the delete stays queued in memory.
+func (q *Queue) journalAddLocked(d Delete) {
+	if q.journal == nil {
+		return
+	}
+	if err := q.journal.append(record{Op: "add", Delete: d}); err != nil {
+		logger.Errorf("[DeleteQueue] 写入删除日志 %s 失败: %v", q.journal.path, err)
+	}
+}
+
+// journalDoneLocked records a removed delete, and rewrites the journal once
+// removals outnumber the queued deletes; the caller holds q.mu
+func (q *Queue) journalDoneLocked(d Delete) {
+	if q.journal == nil {
+		return
+	}
+	q.journal.done++
+	if q.journal.done >= max(minCompact, len(q.entries)) {
+		if err := q.journal.rewrite(q.pendingLocked()); err != nil {
+			logger.Errorf("[DeleteQueue] 压缩删除日志 %s 失败: %v", q.journal.path, err)
+		}
+		return
+	}
+	if err := q.journal.append(record{Op: "done", Delete: d}); err != nil {
+		logger.Errorf("[DeleteQueue] 写入删除日志 %s 失败: %v", q.journal.path, err)
+	}
+}
+
+// pendingLocked returns the queued deletes, oldest first; the caller holds q.mu
+func (q *Queue) pendingLocked() []Delete {
+	pending := make(map[id]Delete, len(q.entries))
+	for k, e := range q.entries {
+		pending[k] = e.Delete
+	}
+	return sortDeletes(pending)
+}
+
+// sortDeletes returns the deletes in m ordered by the time they were queued
+func sortDeletes(m map[id]Delete) []Delete {
+	out := make([]Delete, 0, len(m))
+	for _, d := range m {
+		out = append(out, d)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Queued.Before(out[j].Queued) })
+	return out
+}
@@ -0,0 +1,402 @@
+// Package deletequeue retries deletes that could not reach the node owning the
+// key. A failed delete leaves the owner serving a stale value until it expires,
+// which is wrong for data that was deleted on purpose; queueing it and retrying
+// with exponential backoff closes that gap once the owner is reachable again.
+//
+// The queue is bounded in size, and a delete that keeps failing is given up
+// after a maximum age. It lives in memory; WithJournal additionally records it
+// in a small append-only file so that queued deletes survive a restart.
+package deletequeue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/pkg/logger"
+)
+
+const (
+	// DefaultMaxSize is the number of deletes a queue holds by default
+	DefaultMaxSize = 10000
+	// DefaultMaxAge is how long a delete is retried by default
+	DefaultMaxAge = 10 * time.Minute
+	// DefaultInitialBackoff is the wait before the first retry by default
+	DefaultInitialBackoff = 500 * time.Millisecond
+	// DefaultMaxBackoff caps the wait between retries by default
+	DefaultMaxBackoff = 30 * time.Second
+	// DefaultConcurrency is the number of retries in flight by default
+	DefaultConcurrency = 4
+)
+
+var (
+	// ErrFull is returned by Add when the queue holds its maximum number of deletes
+	ErrFull = errors.New("delete queue is full")
+	// ErrPermanent is wrapped by a SendFunc error that retrying cannot fix, such
+	// as an unknown group; the delete is dropped as failed
+	ErrPermanent = errors.New("delete cannot succeed")
+	// ErrClosed is returned by Add after Close
+	ErrClosed = errors.New("delete queue is closed")
+)
+
+// Delete is a queued delete
+type Delete struct {
+	Target   string    `json:"target"`             // node the delete is retried on, empty to let the SendFunc pick it
+	Group    string    `json:"group"`              // group of the key
+	Key      string    `json:"key"`                // key to delete
+	Queued   time.Time `json:"queued"`             // when the delete was queued
+	Attempts int       `json:"attempts,omitempty"` // retries made so far
+}
+
+// id identifies the deletes Add merges: one per target, group and key
+type id struct {
+	target, group, key string
+}
+
+func (d Delete) id() id {
+	return id{d.Target, d.Group, d.Key}
+}
+
+// SendFunc makes one attempt at a queued delete. It returns nil once the delete
+// landed, or the key is known to be gone, and an error wrapping ErrPermanent
+// when retrying cannot succeed.
+type SendFunc func(ctx context.Context, d Delete) error
+
+// Stats describes the queue
+type Stats struct {
+	Depth     int   `json:"depth"`     // deletes waiting for a retry
+	Queued    int64 `json:"queued"`    // deletes added
+	Retries   int64 `json:"retries"`   // retry attempts made
+	Succeeded int64 `json:"succeeded"` // deletes that landed on a retry
+	Failed    int64 `json:"failed"`    // deletes given up: past the maximum age or failed permanently
+	Rejected  int64 `json:"rejected"`  // deletes refused because the queue was full
+	Forgotten int64 `json:"forgotten"` // deletes dropped by Forget because the key was written again
+}
+
+// Option configures a Queue
+type Option func(*Queue)
+
+// WithMaxSize bounds the number of queued deletes; Add fails with ErrFull beyond it
+func WithMaxSize(n int) Option {
+	return func(q *Queue) {
+		if n > 0 {
+			q.maxSize = n
+		}
+	}
+}
+
+// WithMaxAge sets how long a delete is retried before it is given up as failed
+func WithMaxAge(d time.Duration) Option {
+	return func(q *Queue) {
+		if d > 0 {
+			q.maxAge = d
+		}
+	}
+}
+
+// WithBackoff sets the wait before the first retry, doubled after every failed
+// retry up to max
+func WithBackoff(initial, max time.Duration) Option {
+	return func(q *Queue) {
+		if initial > 0 {
+			q.initialBackoff = initial
+		}
+		if max > 0 {
+			q.maxBackoff = max
+		}
+	}
+}
+
+// WithConcurrency bounds the number of retries in flight, so that a dead node
+// timing out does not hold up the deletes of the others
+func WithConcurrency(n int) Option {
+	return func(q *Queue) {
+		if n > 0 {
+			q.concurrency = n
+		}
+	}
+}
+
+// WithJournal records the queue in the file at path, and New loads the deletes
+// a previous process left there. Deletes that reached the maximum age while the
+// process was down are counted as failed.
+func WithJournal(path string) Option {
+	return func(q *Queue) {
+		q.journalPath = path
+	}
+}
+
+// entry is a queued delete with its retry state
+type entry struct {
+	Delete
+	next     time.Time // earliest time of the next attempt
+	inFlight bool
+	removed  bool // dropped by Forget while in flight
+}
+
+// Queue retries deletes in the background until they land or expire. It is
+// safe for concurrent use.
+type Queue struct {
+	send           SendFunc
+	maxSize        int
+	maxAge         time.Duration
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	concurrency    int
+	journalPath    string
+
+	mu       sync.Mutex
+	entries  map[id]*entry
+	inFlight int
+	journal  *journal // nil without WithJournal
+	stats    Stats
+	closed   bool
+
+	wake   chan struct{}
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New creates a queue that retries deletes with send and starts its worker.
+// It fails only when the journal cannot be loaded.
+func New(send SendFunc, opts ...Option) (*Queue, error) {
+	q := &Queue{
+		send:           send,
+		maxSize:        DefaultMaxSize,
+		maxAge:         DefaultMaxAge,
+		initialBackoff: DefaultInitialBackoff,
+		maxBackoff:     DefaultMaxBackoff,
+		concurrency:    DefaultConcurrency,
+		entries:        make(map[id]*entry),
+		wake:           make(chan struct{}, 1),
+	}
+	for _, opt := range opts {
+		opt(q)
+	}
+	if q.journalPath != "" {
+		pending, err := loadJournal(q.journalPath)
+		if err != nil {
+			return nil, err
+		}
+		now := time.Now()
+		for _, d := range pending {
+			if now.Sub(d.Queued) >= q.maxAge {
+				q.stats.Failed++
+				logger.Warnf("[DeleteQueue] 删除在重启期间超过最长重试时间，放弃: target=%s group=%s key=%s", d.Target, d.Group, logger.Key(d.Key))
+				continue
+			}
+			if len(q.entries) < q.maxSize {
+				q.entries[d.id()] = &entry{Delete: d, next: now}
+			}
+		}
+		if q.journal, err = openJournal(q.journalPath, q.pendingLocked()); err != nil {
+			return nil, err
+		}
+		if len(q.entries) > 0 {
+			logger.Infof("[DeleteQueue] 从 %s 恢复 %d 个待重试的删除", q.journalPath, len(q.entries))
+		}
+	}
+	q.ctx, q.cancel = context.WithCancel(context.Background())
+	q.wg.Add(1)
+	go q.run()
+	return q, nil
+}
+
+// Add queues the delete of key in group on target, to be retried after the
+// initial backoff. A delete already queued for the same target and key is kept
+// as is. It fails with ErrFull when the queue holds its maximum size.
+func (q *Queue) Add(target, group, key string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return ErrClosed
+	}
+	d := Delete{Target: target, Group: group, Key: key, Queued: time.Now()}
+	if _, ok := q.entries[d.id()]; ok {
+		return nil
+	}
+	if len(q.entries) >= q.maxSize {
+		q.stats.Rejected++
+		return ErrFull
+	}
+	q.entries[d.id()] = &entry{Delete: d, next: d.Queued.Add(q.initialBackoff)}
+	q.stats.Queued++
+	q.journalAddLocked(d)
+	q.signal()
+	return nil
+}
+
+// Forget drops the queued deletes of key in group on every target. Call it when
+// the key is written again, so that a late retry does not delete the new value;
+// a retry already in flight still completes.
+func (q *Queue) Forget(group, key string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for k, e := range q.entries {
+		if k.group == group && k.key == key {
+			q.removeLocked(e)
+			e.removed = true
+			q.stats.Forgotten++
+		}
+	}
+}
+
+// Len returns the number of queued deletes
+func (q *Queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.entries)
+}
+
+// Pending returns the queued deletes, oldest first
+func (q *Queue) Pending() []Delete {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.pendingLocked()
+}
+
+// Stats returns the queue's counters
+func (q *Queue) Stats() Stats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	s := q.stats
+	s.Depth = len(q.entries)
+	return s
+}
+
+// Close stops retrying and waits for the attempts in flight. Queued deletes are
+// dropped, or left in the journal for the next process when there is one.
+func (q *Queue) Close() error {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return nil
+	}
+	q.closed = true
+	q.mu.Unlock()
+
+	q.cancel()
+	q.wg.Wait()
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.journal != nil {
+		return q.journal.close()
+	}
+	return nil
+}
+
+// signal wakes the worker without blocking
+func (q *Queue) signal() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run starts the attempts that are due and sleeps until the next one is
+func (q *Queue) run() {
+	defer q.wg.Done()
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+	for {
+		wait := q.dispatch()
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(wait)
+		select {
+		case <-q.ctx.Done():
+			return
+		case <-q.wake:
+		case <-timer.C:
+		}
+	}
+}
+
+// dispatch starts the due attempts that fit in the concurrency limit and
+// returns how long until the next one is due
+func (q *Queue) dispatch() time.Duration {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	now := time.Now()
+	wait := time.Hour
+	for _, e := range q.entries {
+		if e.inFlight {
+			continue
+		}
+		if e.next.After(now) {
+			wait = min(wait, e.next.Sub(now))
+			continue
+		}
+		if q.inFlight >= q.concurrency {
+			// Woken again when an attempt finishes
+			continue
+		}
+		e.inFlight = true
+		q.inFlight++
+		q.wg.Add(1)
+		go q.attempt(e)
+	}
+	return wait
+}
+
+// attempt retries one delete and records the outcome
+func (q *Queue) attempt(e *entry) {
+	defer q.wg.Done()
+	err := q.send(q.ctx, e.Delete)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	defer q.signal()
+	q.inFlight--
+	e.inFlight = false
+	q.stats.Retries++
+	if e.removed {
+		return
+	}
+	switch {
+	case err == nil:
+		q.stats.Succeeded++
+		q.removeLocked(e)
+		logger.Infof("[DeleteQueue] 重试删除成功: target=%s group=%s key=%s attempts=%d", e.Target, e.Group, logger.Key(e.Key), e.Attempts+1)
+	case q.ctx.Err() != nil:
+		// Closing; the delete stays in the journal
+	case errors.Is(err, ErrPermanent):
+		q.stats.Failed++
+		q.removeLocked(e)
+		logger.Warnf("[DeleteQueue] 删除无法完成，放弃: target=%s group=%s key=%s: %v", e.Target, e.Group, logger.Key(e.Key), err)
+	case time.Since(e.Queued) >= q.maxAge:
+		q.stats.Failed++
+		q.removeLocked(e)
+		logger.Errorf("[DeleteQueue] 删除超过最长重试时间 %v 仍未成功，放弃，旧值将保留到过期: target=%s group=%s key=%s attempts=%d: %v",
+			q.maxAge, e.Target, e.Group, logger.Key(e.Key), e.Attempts+1, err)
+	default:
+		e.Attempts++
+		e.next = time.Now().Add(q.backoff(e.Attempts))
+		logger.Debugf("[DeleteQueue] 重试删除失败，%v 后再试: target=%s group=%s key=%s: %v", e.next.Sub(time.Now()).Round(time.Millisecond), e.Target, e.Group, logger.Key(e.Key), err)
+	}
+}
+
+// backoff returns the wait after the given number of failed retries
+func (q *Queue) backoff(attempts int) time.Duration {
+	d := q.initialBackoff
+	for i := 0; i < attempts && d < q.maxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, q.maxBackoff)
+}
+
+// removeLocked drops e from the queue and the journal; the caller holds q.mu
+func (q *Queue) removeLocked(e *entry) {
+	if q.entries[e.id()] != e {
+		return
+	}
+	delete(q.entries, e.id())
+	q.journalDoneLocked(e.Delete)
+}
@@ -0,0 +1,239 @@
+package deletequeue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// flakySender fails every delete while down is set and records the ones that land
+type flakySender struct {
+	mu     sync.Mutex
+	down   bool
+	err    error // returned while down, a network-like error when nil
+	landed []Delete
+	tries  int
+}
+
+func (s *flakySender) send(ctx context.Context, d Delete) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tries++
+	if s.down {
+		if s.err != nil {
+			return s.err
+		}
+		return errors.New("connection refused")
+	}
+	s.landed = append(s.landed, d)
+	return nil
+}
+
+func (s *flakySender) setDown(down bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.down = down
+}
+
+func (s *flakySender) landedKeys() map[string]bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := make(map[string]bool, len(s.landed))
+	for _, d := range s.landed {
+		keys[d.Key] = true
+	}
+	return keys
+}
+
+// newTestQueue returns a queue retrying with s every few milliseconds, closed
+// when the test ends
+func newTestQueue(t *testing.T, s *flakySender, opts ...Option) *Queue {
+	t.Helper()
+	opts = append([]Option{WithBackoff(5*time.Millisecond, 20*time.Millisecond)}, opts...)
+	q, err := New(s.send, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { q.Close() })
+	return q
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestRetryUntilOwnerRecovers(t *testing.T) {
+	s := &flakySender{down: true}
+	q := newTestQueue(t, s)
+	for i := 0; i < 3; i++ {
+		if err := q.Add("node-a", "scores", fmt.Sprintf("k%d", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A delete already queued is merged
+	if err := q.Add("node-a", "scores", "k0"); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "a few failed retries", func() bool { return q.Stats().Retries >= 6 })
+	if st := q.Stats(); st.Depth != 3 || st.Queued != 3 || st.Succeeded != 0 {
+		t.Fatalf("stats while the owner is down: %+v", st)
+	}
+
+	s.setDown(false)
+	waitFor(t, "the deletes to land", func() bool { return q.Len() == 0 })
+	if keys := s.landedKeys(); len(keys) != 3 {
+		t.Fatalf("landed %v, want k0, k1 and k2", keys)
+	}
+	if st := q.Stats(); st.Succeeded != 3 || st.Failed != 0 || st.Depth != 0 {
+		t.Fatalf("stats after recovery: %+v", st)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	q := &Queue{initialBackoff: 100 * time.Millisecond, maxBackoff: time.Second}
+	want := []time.Duration{100, 200, 400, 800, 1000, 1000}
+	for attempts, w := range want {
+		if got := q.backoff(attempts); got != w*time.Millisecond {
+			t.Errorf("backoff(%d) = %v, want %v", attempts, got, w*time.Millisecond)
+		}
+	}
+}
+
+func TestAddFullQueue(t *testing.T) {
+	s := &flakySender{down: true}
+	q := newTestQueue(t, s, WithMaxSize(2))
+	for _, key := range []string{"a", "b"} {
+		if err := q.Add("node-a", "scores", key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := q.Add("node-a", "scores", "c"); !errors.Is(err, ErrFull) {
+		t.Fatalf("Add on a full queue = %v, want ErrFull", err)
+	}
+	// Merging into a queued delete needs no room
+	if err := q.Add("node-a", "scores", "a"); err != nil {
+		t.Fatalf("Add of a queued delete on a full queue = %v", err)
+	}
+	if st := q.Stats(); st.Rejected != 1 || st.Depth != 2 {
+		t.Fatalf("stats = %+v", st)
+	}
+
+	q.Close()
+	if err := q.Add("node-a", "scores", "d"); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Add after Close = %v, want ErrClosed", err)
+	}
+}
+
+func TestGiveUp(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		opts []Option
+	}{
+		{"max age", nil, []Option{WithMaxAge(30 * time.Millisecond)}},
+		{"permanent error", fmt.Errorf("%w: no such group", ErrPermanent), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &flakySender{down: true, err: tt.err}
+			q := newTestQueue(t, s, tt.opts...)
+			if err := q.Add("node-a", "scores", "k"); err != nil {
+				t.Fatal(err)
+			}
+			waitFor(t, "the delete to be given up", func() bool { return q.Len() == 0 })
+			if st := q.Stats(); st.Failed != 1 || st.Succeeded != 0 {
+				t.Fatalf("stats = %+v", st)
+			}
+		})
+	}
+}
+
+func TestForget(t *testing.T) {
+	s := &flakySender{down: true}
+	q := newTestQueue(t, s)
+	q.Add("node-a", "scores", "k")
+	q.Add("node-b", "scores", "k")
+	q.Add("node-a", "scores", "other")
+	q.Forget("scores", "k")
+	if pending := q.Pending(); len(pending) != 1 || pending[0].Key != "other" {
+		t.Fatalf("pending after Forget = %+v", pending)
+	}
+	s.setDown(false)
+	waitFor(t, "the remaining delete to land", func() bool { return q.Len() == 0 })
+	if keys := s.landedKeys(); keys["k"] {
+		t.Fatalf("forgotten delete landed: %v", keys)
+	}
+	if st := q.Stats(); st.Forgotten != 2 {
+		t.Fatalf("stats = %+v", st)
+	}
+}
+
+func TestJournalSurvivesRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "deletes.journal")
+	s := &flakySender{down: true}
+	q, err := New(s.send, WithJournal(path), WithBackoff(time.Hour, time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"a", "b", "c"} {
+		q.Add("node-a", "scores", key)
+	}
+	q.Forget("scores", "b")
+	if err := q.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A crash mid-write leaves a truncated last line
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"op":"add","target":"node-a","gro`)
+	f.Close()
+
+	s.setDown(false)
+	q2 := newTestQueue(t, s, WithJournal(path))
+	waitFor(t, "the recovered deletes to land", func() bool { return q2.Len() == 0 })
+	if keys := s.landedKeys(); len(keys) != 2 || !keys["a"] || !keys["c"] {
+		t.Fatalf("landed %v after restart, want a and c", keys)
+	}
+	q2.Close()
+
+	// Everything landed, so the next start has nothing to retry
+	pending, err := loadJournal(path)
+	if err != nil || len(pending) != 0 {
+		t.Fatalf("journal after the deletes landed: %+v, %v", pending, err)
+	}
+}
+
+func TestJournalDropsExpired(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "deletes.journal")
+	old := Delete{Target: "node-a", Group: "scores", Key: "old", Queued: time.Now().Add(-time.Hour)}
+	fresh := Delete{Target: "node-a", Group: "scores", Key: "fresh", Queued: time.Now()}
+	j, err := openJournal(path, []Delete{old, fresh})
+	if err != nil {
+		t.Fatal(err)
+	}
+	j.close()
+
+	s := &flakySender{down: true}
+	q := newTestQueue(t, s, WithJournal(path), WithMaxAge(time.Minute))
+	if pending := q.Pending(); len(pending) != 1 || pending[0].Key != "fresh" {
+		t.Fatalf("pending = %+v, want only fresh", pending)
+	}
+	if st := q.Stats(); st.Failed != 1 {
+		t.Fatalf("stats = %+v, want the expired delete counted as failed", st)
+	}
+}
@@ -0,0 +1,62 @@
+package discovery
+
+// DiffNodes 比较两份节点列表，返回 nodes 相对 old 新增或注册信息发生变化的节点，
+// 以及 old 中有而 nodes 中没有的节点标识。节点按 Key 对应，与顺序无关
+func DiffNodes(old, nodes []NodeInfo) (added []NodeInfo, removed []string) {
+	before := make(map[string]string, len(old))
+	for _, n := range old {
+		before[n.Key()] = n.Encode()
+	}
+
+	added = make([]NodeInfo, 0)
+	current := make(map[string]bool, len(nodes))
+	for _, n := range nodes {
+		key := n.Key()
+		current[key] = true
+		if enc, ok := before[key]; !ok || enc != n.Encode() {
+			added = append(added, n)
+		}
+	}
+
+	removed = make([]string, 0)
+	for _, n := range old {
+		if key := n.Key(); !current[key] {
+			removed = append(removed, key)
+			current[key] = true // 重复的标识只报告一次
+		}
+	}
+	return added, removed
+}
+
+// ApplyNodesDiff 将 DiffNodes 的结果应用到 nodes，返回新的列表：先移除 removed 中的节点，
+// 再用 added 替换标识相同的节点，其余追加在末尾。nodes 本身不会被修改
+func ApplyNodesDiff(nodes, added []NodeInfo, removed []string) []NodeInfo {
+	drop := make(map[string]bool, len(removed))
+	for _, key := range removed {
+		drop[key] = true
+	}
+	replace := make(map[string]NodeInfo, len(added))
+	for _, n := range added {
+		replace[n.Key()] = n
+	}
+
+	result := make([]NodeInfo, 0, len(nodes)+len(added))
+	for _, n := range nodes {
+		key := n.Key()
+		if drop[key] {
+			continue
+		}
+		if r, ok := replace[key]; ok {
+			n = r
+			delete(replace, key)
+		}
+		result = append(result, n)
+	}
+	for _, n := range added {
+		if r, ok := replace[n.Key()]; ok {
+			result = append(result, r)
+			delete(replace, n.Key())
+		}
+	}
+	return result
+}
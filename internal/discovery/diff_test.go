@@ -0,0 +1,80 @@
+package discovery
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// encodeSet 返回节点列表与顺序无关的编码，用于比较
+func encodeSet(nodes []NodeInfo) string {
+	enc := make([]string, len(nodes))
+	for i, n := range nodes {
+		enc[i] = n.Encode()
+	}
+	sort.Strings(enc)
+	return strings.Join(enc, "\n")
+}
+
+func TestDiffNodes(t *testing.T) {
+	a := NodeInfo{ID: "a", GRPCAddr: "10.0.0.1:9090"}
+	b := NodeInfo{ID: "b", GRPCAddr: "10.0.0.2:9090"}
+	c := NodeInfo{ID: "c", GRPCAddr: "10.0.0.3:9090"}
+	b2 := b
+	b2.Groups = []string{"scores"}
+
+	added, removed := DiffNodes([]NodeInfo{a, b}, []NodeInfo{b2, c})
+	if len(added) != 2 || added[0].Encode() != b2.Encode() || added[1].ID != "c" {
+		t.Fatalf("added = %+v", added)
+	}
+	if len(removed) != 1 || removed[0] != "a" {
+		t.Fatalf("removed = %v", removed)
+	}
+
+	// 相同的列表没有变化，顺序不同也一样；结果不为 nil，JSON 中是空数组
+	added, removed = DiffNodes([]NodeInfo{a, b}, []NodeInfo{b, a})
+	if added == nil || removed == nil || len(added) != 0 || len(removed) != 0 {
+		t.Fatalf("相同列表的变化 = %v, %v", added, removed)
+	}
+}
+
+// TestDiffApplyChurn 随机增删和修改节点，每一步把变化应用到上一份列表都得到新的列表
+func TestDiffApplyChurn(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	var nodes []NodeInfo
+	next := 0
+	for round := 0; round < 300; round++ {
+		changed := append([]NodeInfo(nil), nodes...)
+		for ops := rng.Intn(5); ops >= 0; ops-- {
+			switch op := rng.Intn(3); {
+			case op == 0 || len(changed) == 0:
+				next++
+				changed = append(changed, NodeInfo{ID: fmt.Sprintf("node-%d", next), GRPCAddr: fmt.Sprintf("10.0.%d.%d:9090", next/250, next%250)})
+			case op == 1:
+				i := rng.Intn(len(changed))
+				changed = append(changed[:i], changed[i+1:]...)
+			default:
+				i := rng.Intn(len(changed))
+				changed[i].HTTPAddr = fmt.Sprintf("10.1.0.%d:8001", rng.Intn(250))
+			}
+		}
+		rng.Shuffle(len(changed), func(i, j int) { changed[i], changed[j] = changed[j], changed[i] })
+
+		added, removed := DiffNodes(nodes, changed)
+		got := ApplyNodesDiff(nodes, added, removed)
+		if encodeSet(got) != encodeSet(changed) {
+			t.Fatalf("第 %d 轮: 应用变化后\n%s\n期望\n%s", round, encodeSet(got), encodeSet(changed))
+		}
+		nodes = changed
+	}
+}
+
+func TestApplyNodesDiffDoesNotModifyInput(t *testing.T) {
+	nodes := []NodeInfo{{ID: "a", GRPCAddr: "10.0.0.1:9090"}, {ID: "b", GRPCAddr: "10.0.0.2:9090"}}
+	ApplyNodesDiff(nodes, []NodeInfo{{ID: "a", GRPCAddr: "10.0.0.9:9090"}}, []string{"b"})
+	if nodes[0].GRPCAddr != "10.0.0.1:9090" || nodes[1].ID != "b" {
+		t.Fatalf("输入被修改: %+v", nodes)
+	}
+}
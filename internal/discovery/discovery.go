@@ -6,45 +6,229 @@ import (
 	"sync"
 	"time"
 
+	"github.com/AdrianWangs/go-cache/internal/peers"
 	"github.com/AdrianWangs/go-cache/pkg/logger"
 	clientv3 "go.etcd.io/etcd/client/v3"
 )
 
+// defaultDialTimeout 连接etcd的默认超时
+const defaultDialTimeout = 5 * time.Second
+
+const (
+	initialSyncBackoff    = time.Second      // 同步失败或监视中断后的初始重试间隔
+	maxInitialSyncBackoff = 30 * time.Second // 重试间隔的上限
+	syncTimeout           = 5 * time.Second  // 单次同步节点列表的超时
+)
+
+// Option 配置 ServiceDiscovery / ServiceWatcher
+type Option func(*options)
+
+// options 服务注册与发现的公共配置
+type options struct {
+	dialTimeout time.Duration   // 连接etcd的超时
+	httpAddr    string          // 注册时携带的 HTTP 地址
+	nodeID      string          // 注册时携带的节点标识
+	groups      func() []string // 注册时携带的缓存组列表来源
+	mode        func() string   // 注册时携带的节点模式来源
+	protocols   []string        // 注册时携带的节点提供的协议
+
+	stateHook func(WatchStatus) // 监视状态变化的回调
+
+	log logger.Logger // 注册和监视的日志写入的 Logger，见 WithLogger
+}
+
+// newOptions 使用默认值创建配置并应用选项
+func newOptions(opts ...Option) options {
+	o := options{dialTimeout: defaultDialTimeout}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithDialTimeout 设置连接etcd的超时
+func WithDialTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		if timeout > 0 {
+			o.dialTimeout = timeout
+		}
+	}
+}
+
+// WithHTTPAddr 注册时一并登记节点的 HTTP 地址，使用结构化的 NodeInfo 格式写入etcd
+func WithHTTPAddr(addr string) Option {
+	return func(o *options) {
+		o.httpAddr = addr
+	}
+}
+
+// WithNodeID 注册时一并登记稳定的节点标识，使用结构化的 NodeInfo 格式写入etcd
+func WithNodeID(id string) Option {
+	return func(o *options) {
+		o.nodeID = id
+	}
+}
+
+// WithGroups 注册时一并登记节点提供的缓存组，使用结构化的 NodeInfo 格式写入etcd。
+// groups 在注册时以及之后每次注册刷新（租约TTL的1/3）时调用，组列表变化时重新写入，
+// API 服务器据此维护集群的组注册表
+func WithGroups(groups func() []string) Option {
+	return func(o *options) {
+		o.groups = groups
+	}
+}
+
+// WithMode 注册时一并登记节点的模式（例如只读维护模式），使用结构化的 NodeInfo 格式写入etcd。
+// 与 WithGroups 一样在每次注册刷新时重新读取，变化时重新写入
+func WithMode(mode func() string) Option {
+	return func(o *options) {
+		o.mode = mode
+	}
+}
+
+// WithProtocols 注册时一并登记节点对 API 服务器提供的协议（grpc、http），使用结构化的 NodeInfo 格式写入etcd。
+// API 服务器据此为每个节点选择协议，双方都支持时优先使用 gRPC
+func WithProtocols(protocols ...string) Option {
+	return func(o *options) {
+		o.protocols = append([]string(nil), protocols...)
+	}
+}
+
+// WithLogger 把注册和监视的日志写入 l，而不是 logger 包的全局日志（见 logger.SetLogger），
+// 用于把缓存的日志接入嵌入方自己的日志库
+func WithLogger(l logger.Logger) Option {
+	return func(o *options) {
+		o.log = l
+	}
+}
+
+// registerClient ServiceDiscovery 使用的 etcd 客户端操作，由 *clientv3.Client 实现，测试中以内存实现替换
+type registerClient interface {
+	Grant(ctx context.Context, ttl int64) (*clientv3.LeaseGrantResponse, error)
+	Revoke(ctx context.Context, id clientv3.LeaseID) (*clientv3.LeaseRevokeResponse, error)
+	KeepAlive(ctx context.Context, id clientv3.LeaseID) (<-chan *clientv3.LeaseKeepAliveResponse, error)
+	Put(ctx context.Context, key, val string, opts ...clientv3.OpOption) (*clientv3.PutResponse, error)
+	Delete(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.DeleteResponse, error)
+	Close() error
+}
+
 // ServiceDiscovery 用于向etcd注册服务和维持心跳
 type ServiceDiscovery struct {
-	cli        *clientv3.Client // etcd客户端
+	cli        registerClient   // etcd客户端
 	leaseID    clientv3.LeaseID // 租约ID
 	leaseTTL   int64            // 租约TTL（秒）
 	key        string           // 服务注册的键
-	value      string           // 服务注册的值（通常是地址）
+	value      string           // 服务注册的值（gRPC 地址或 NodeInfo JSON）
+	info       NodeInfo         // 结构化注册信息
+	structured bool             // 是否使用结构化的 NodeInfo 格式
+	groups     func() []string  // 缓存组列表来源，可为 nil
+	mode       func() string    // 节点模式来源，可为 nil
 	stopChan   chan struct{}    // 用于停止心跳的通道
 	mu         sync.Mutex       // 保护对leaseID的访问
 	registered bool             // 标记是否已成功注册
+	log        logger.Logger    // 日志写入的 Logger
 }
 
 // NewServiceDiscovery 创建一个新的ServiceDiscovery实例
-func NewServiceDiscovery(endpoints []string, serviceName, nodeAddr string, leaseTTL int64) (*ServiceDiscovery, error) {
+func NewServiceDiscovery(endpoints []string, serviceName, nodeAddr string, leaseTTL int64, opts ...Option) (*ServiceDiscovery, error) {
+	o := newOptions(opts...)
 	cli, err := clientv3.New(clientv3.Config{
 		Endpoints:   endpoints,
-		DialTimeout: 5 * time.Second,
+		DialTimeout: o.dialTimeout,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("连接etcd失败: %w", err)
 	}
+	return newServiceDiscovery(cli, serviceName, nodeAddr, leaseTTL, o), nil
+}
 
+// newServiceDiscovery 以 cli 创建 ServiceDiscovery
+func newServiceDiscovery(cli registerClient, serviceName, nodeAddr string, leaseTTL int64, o options) *ServiceDiscovery {
 	sd := &ServiceDiscovery{
 		cli:      cli,
 		leaseTTL: leaseTTL,
 		key:      fmt.Sprintf("/%s/%s", serviceName, nodeAddr), // 使用 /serviceName/nodeAddr 作为key
-		value:    nodeAddr,
-		stopChan: make(chan struct{}),
+		info:     NodeInfo{ID: o.nodeID, GRPCAddr: nodeAddr, HTTPAddr: o.httpAddr, ProtoVersion: peers.ProtocolVersion, Protocols: o.protocols},
+		// 只登记了 gRPC 地址时保持旧格式，便于旧版本的 API 服务器解析
+		structured: o.httpAddr != "" || o.nodeID != "" || o.groups != nil || o.mode != nil || len(o.protocols) > 0,
+		groups:     o.groups,
+		mode:       o.mode,
+		stopChan:   make(chan struct{}),
+		log:        logger.Or(o.log),
+	}
+	sd.value = sd.encode()
+	return sd
+}
+
+// encode 生成当前的注册值，组列表来源存在时重新读取组列表
+func (sd *ServiceDiscovery) encode() string {
+	if !sd.structured {
+		return sd.info.GRPCAddr
+	}
+	info := sd.info
+	if sd.groups != nil {
+		info.Groups = append([]string{}, sd.groups()...)
+	}
+	if sd.mode != nil {
+		info.Mode = sd.mode()
+	}
+	return info.Encode()
+}
+
+// Advertised 返回根据当前组列表和模式生成的注册值，与已写入etcd的值相同时说明无需刷新
+func (sd *ServiceDiscovery) Advertised() string {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+	return sd.encode()
+}
+
+// UpdateValue 在现有租约下重新写入注册值，不创建新租约，key 在更新过程中不会消失；
+// 监视方在 PUT 事件中读到新值。值未变化时不访问etcd，未注册时返回错误。
+// 配置了 WithGroups 或 WithMode 时，之后的自动刷新仍以它们生成的值为准
+func (sd *ServiceDiscovery) UpdateValue(newValue string) error {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+	return sd.updateValueLocked(newValue)
+}
+
+// updateValueLocked 是 UpdateValue 的实现，调用方须持有 sd.mu
+func (sd *ServiceDiscovery) updateValueLocked(value string) error {
+	if !sd.registered {
+		return fmt.Errorf("服务 %s 未注册", sd.key)
 	}
+	if value == sd.value {
+		return nil
+	}
+	if _, err := sd.cli.Put(context.Background(), sd.key, value, clientv3.WithLease(sd.leaseID)); err != nil {
+		return fmt.Errorf("更新服务 %s 的注册信息失败: %w", sd.key, err)
+	}
+	sd.value = value
+	sd.log.Infof("服务 %s 的注册信息已更新: %s", sd.key, value)
+	return nil
+}
 
-	return sd, nil
+// Refresh 在注册值变化（例如新增了缓存组）时立即重新写入etcd，未注册时不做任何事。
+// 配置了 WithGroups 或 WithMode 时也会在每个注册刷新周期自动调用
+func (sd *ServiceDiscovery) Refresh() {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+
+	if !sd.registered {
+		return
+	}
+	if err := sd.updateValueLocked(sd.encode()); err != nil {
+		sd.log.Warnf("刷新注册信息失败，将在下次刷新时重试: %v", err)
+	}
 }
 
 // Register 注册服务并启动心跳续约
 func (sd *ServiceDiscovery) Register() error {
+	return sd.RegisterWithContext(context.Background())
+}
+
+// RegisterWithContext 与 Register 相同，ctx 限定创建租约和写入注册信息的时间，
+// etcd 不可用时调用方可以据此给每次尝试设置超时或中途放弃；心跳续约不受 ctx 影响
+func (sd *ServiceDiscovery) RegisterWithContext(ctx context.Context) error {
 	sd.mu.Lock()
 	defer sd.mu.Unlock()
 
@@ -53,20 +237,23 @@ func (sd *ServiceDiscovery) Register() error {
 	}
 
 	// 1. 创建租约
-	leaseResp, err := sd.cli.Grant(context.Background(), sd.leaseTTL)
+	leaseResp, err := sd.cli.Grant(ctx, sd.leaseTTL)
 	if err != nil {
 		return fmt.Errorf("创建etcd租约失败: %w", err)
 	}
 	sd.leaseID = leaseResp.ID
-	logger.Infof("成功获取etcd租约，LeaseID: %x, TTL: %ds", sd.leaseID, sd.leaseTTL)
+	sd.log.Infof("成功获取etcd租约，LeaseID: %x, TTL: %ds", sd.leaseID, sd.leaseTTL)
 
 	// 2. 将服务信息与租约绑定并写入etcd
-	_, err = sd.cli.Put(context.Background(), sd.key, sd.value, clientv3.WithLease(sd.leaseID))
+	sd.value = sd.encode()
+	_, err = sd.cli.Put(ctx, sd.key, sd.value, clientv3.WithLease(sd.leaseID))
 	if err != nil {
-		// 如果put失败，尝试撤销租约
-		_, revokeErr := sd.cli.Revoke(context.Background(), sd.leaseID)
+		// 如果put失败，尝试撤销租约；ctx 可能已经超时，撤销单独计时
+		revokeCtx, cancel := context.WithTimeout(context.Background(), syncTimeout)
+		_, revokeErr := sd.cli.Revoke(revokeCtx, sd.leaseID)
+		cancel()
 		if revokeErr != nil {
-			logger.Warnf("警告：注册失败后撤销租约 %x 也失败: %v", sd.leaseID, revokeErr)
+			sd.log.Warnf("警告：注册失败后撤销租约 %x 也失败: %v", sd.leaseID, revokeErr)
 		}
 		return fmt.Errorf("写入服务信息到etcd失败: %w", err)
 	}
@@ -75,25 +262,40 @@ func (sd *ServiceDiscovery) Register() error {
 	keepAliveChan, err := sd.cli.KeepAlive(context.Background(), sd.leaseID)
 	if err != nil {
 		// 如果启动keepalive失败，尝试撤销租约和删除key
-		logger.Errorf("启动etcd KeepAlive失败: %v。尝试清理...", err)
+		sd.log.Errorf("启动etcd KeepAlive失败: %v。尝试清理...", err)
 		sd.cleanupRegistration()
 		return fmt.Errorf("启动etcd KeepAlive失败: %w", err)
 	}
 
-	go sd.keepAlive(keepAliveChan)
+	go sd.keepAlive(keepAliveChan, sd.leaseID, sd.stopChan)
 	sd.registered = true
-	logger.Infof("服务 %s (value: %s) 已成功注册到etcd，LeaseID: %x", sd.key, sd.value, sd.leaseID)
+	sd.log.Infof("服务 %s (value: %s) 已成功注册到etcd，LeaseID: %x", sd.key, sd.value, sd.leaseID)
 	return nil
 }
 
-// keepAlive 处理续约响应
-func (sd *ServiceDiscovery) keepAlive(keepAliveChan <-chan *clientv3.LeaseKeepAliveResponse) {
-	logger.Infof("心跳续约 goroutine 启动，监控 LeaseID: %x", sd.leaseID)
+// keepAlive 处理续约响应。leaseID 和 stop 在启动时传入，注销时重置 sd 的字段不影响运行中的 goroutine
+func (sd *ServiceDiscovery) keepAlive(keepAliveChan <-chan *clientv3.LeaseKeepAliveResponse, leaseID clientv3.LeaseID, stop <-chan struct{}) {
+	sd.log.Infof("心跳续约 goroutine 启动，监控 LeaseID: %x", leaseID)
+
+	// 注册刷新与续约同频，组列表或模式变化最迟在一个刷新周期后写入etcd
+	var refreshC <-chan time.Time
+	if sd.groups != nil || sd.mode != nil {
+		interval := time.Duration(sd.leaseTTL) * time.Second / 3
+		if interval <= 0 {
+			interval = time.Second
+		}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		refreshC = ticker.C
+	}
+
 	for {
 		select {
+		case <-refreshC:
+			sd.Refresh()
 		case kaResp, ok := <-keepAliveChan:
 			if !ok {
-				logger.Warnf("KeepAlive通道关闭，LeaseID: %x 可能已过期或被撤销", sd.leaseID)
+				sd.log.Warnf("KeepAlive通道关闭，LeaseID: %x 可能已过期或被撤销", leaseID)
 				// 可以在这里触发重新注册逻辑
 				sd.mu.Lock()
 				sd.registered = false // 标记为未注册
@@ -101,10 +303,10 @@ func (sd *ServiceDiscovery) keepAlive(keepAliveChan <-chan *clientv3.LeaseKeepAl
 				return // 结束goroutine
 			}
 			// 打印续约确认信息（可选，避免日志过多）
-			// logger.Debugf("租约 %x 续约成功, TTL: %d", kaResp.ID, kaResp.TTL)
+			// sd.log.Debugf("租约 %x 续约成功, TTL: %d", kaResp.ID, kaResp.TTL)
 			_ = kaResp // 避免未使用变量错误
-		case <-sd.stopChan:
-			logger.Infof("收到停止信号，停止对 LeaseID: %x 的心跳续约", sd.leaseID)
+		case <-stop:
+			sd.log.Infof("收到停止信号，停止对 LeaseID: %x 的心跳续约", leaseID)
 			return // 结束goroutine
 		}
 	}
@@ -116,7 +318,7 @@ func (sd *ServiceDiscovery) Unregister() error {
 	defer sd.mu.Unlock()
 
 	if !sd.registered {
-		logger.Info("服务未注册或已注销，无需操作")
+		sd.log.Infof("服务未注册或已注销，无需操作")
 		return nil // 或者返回错误，取决于业务逻辑
 	}
 
@@ -126,16 +328,16 @@ func (sd *ServiceDiscovery) Unregister() error {
 	// 撤销租约，etcd会自动删除关联的key
 	_, err := sd.cli.Revoke(context.Background(), sd.leaseID)
 	if err != nil {
-		logger.Errorf("撤销etcd租约 %x 失败: %v", sd.leaseID, err)
+		sd.log.Errorf("撤销etcd租约 %x 失败: %v", sd.leaseID, err)
 		// 即使撤销失败，也标记为未注册，避免重复尝试
 		sd.registered = false
 		return fmt.Errorf("撤销etcd租约失败: %w", err)
 	}
 
+	sd.log.Infof("服务 %s (原 LeaseID: %x) 已成功注销", sd.key, sd.leaseID)
 	sd.registered = false
 	sd.leaseID = 0                    // 重置LeaseID
 	sd.stopChan = make(chan struct{}) // 创建新的stopChan供下次注册使用
-	logger.Infof("服务 %s (原 LeaseID: %x) 已成功注销", sd.key, sd.leaseID)
 	return nil
 }
 
@@ -144,14 +346,14 @@ func (sd *ServiceDiscovery) cleanupRegistration() {
 	if sd.leaseID != 0 {
 		_, err := sd.cli.Revoke(context.Background(), sd.leaseID)
 		if err != nil {
-			logger.Errorf("清理：撤销租约 %x 失败: %v", sd.leaseID, err)
+			sd.log.Errorf("清理：撤销租约 %x 失败: %v", sd.leaseID, err)
 		}
 		sd.leaseID = 0
 	}
 	// 尝试删除key，以防万一Revoke未完全生效或之前有残留
 	_, err := sd.cli.Delete(context.Background(), sd.key)
 	if err != nil {
-		logger.Errorf("清理：删除etcd key %s 失败: %v", sd.key, err)
+		sd.log.Errorf("清理：删除etcd key %s 失败: %v", sd.key, err)
 	}
 }
 
@@ -165,118 +367,248 @@ func (sd *ServiceDiscovery) Close() error {
 
 // --- Service Watcher --- //
 
+// watchClient ServiceWatcher 使用的 etcd 客户端操作，由 *clientv3.Client 实现，测试中以内存实现替换
+type watchClient interface {
+	Get(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error)
+	Watch(ctx context.Context, key string, opts ...clientv3.OpOption) clientv3.WatchChan
+	Close() error
+}
+
 // ServiceWatcher 用于监视etcd中特定服务下的节点变化
 type ServiceWatcher struct {
-	cli         *clientv3.Client
+	cli         watchClient
 	serviceName string
 	watchPrefix string
+	stateHook   func(WatchStatus)
+	log         logger.Logger
+	backoff     time.Duration // 同步失败或监视中断后的初始重试间隔
+	maxBackoff  time.Duration // 重试间隔的上限
+
+	mu     sync.RWMutex
+	status WatchStatus
 }
 
 // NewServiceWatcher 创建一个新的ServiceWatcher实例
-func NewServiceWatcher(endpoints []string, serviceName string) (*ServiceWatcher, error) {
+func NewServiceWatcher(endpoints []string, serviceName string, opts ...Option) (*ServiceWatcher, error) {
+	o := newOptions(opts...)
 	cli, err := clientv3.New(clientv3.Config{
 		Endpoints:   endpoints,
-		DialTimeout: 5 * time.Second,
+		DialTimeout: o.dialTimeout,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("连接etcd失败: %w", err)
 	}
 
-	sw := &ServiceWatcher{
+	return newServiceWatcher(cli, serviceName, o), nil
+}
+
+// newServiceWatcher 以 cli 创建 ServiceWatcher
+func newServiceWatcher(cli watchClient, serviceName string, o options) *ServiceWatcher {
+	return &ServiceWatcher{
 		cli:         cli,
 		serviceName: serviceName,
 		watchPrefix: fmt.Sprintf("/%s/", serviceName), // 监视 /serviceName/ 前缀
+		stateHook:   o.stateHook,
+		log:         logger.Or(o.log),
+		backoff:     initialSyncBackoff,
+		maxBackoff:  maxInitialSyncBackoff,
+		status:      WatchStatus{State: WatchDisconnected, Since: time.Now()},
+	}
+}
+
+// Status 返回当前的监视状态
+func (sw *ServiceWatcher) Status() WatchStatus {
+	sw.mu.RLock()
+	defer sw.mu.RUnlock()
+	return sw.status
+}
+
+// setState 切换监视状态，状态变化时调用回调
+func (sw *ServiceWatcher) setState(state WatchState) {
+	sw.mu.Lock()
+	if sw.status.State == state {
+		sw.mu.Unlock()
+		return
+	}
+	sw.status.State = state
+	sw.status.Since = time.Now()
+	if state == WatchConnected {
+		sw.status.LastError = ""
+	}
+	status := sw.status
+	sw.mu.Unlock()
+
+	if sw.stateHook != nil {
+		sw.stateHook(status)
+	}
+}
+
+// reportErr 记录错误并尝试发送到 errChan，没有接收方时丢弃，不阻塞监视
+func (sw *ServiceWatcher) reportErr(errChan chan<- error, err error) {
+	sw.mu.Lock()
+	sw.status.LastError = err.Error()
+	sw.mu.Unlock()
+
+	select {
+	case errChan <- err:
+	default:
 	}
-	return sw, nil
 }
 
 // Watch 启动对服务节点的监视
-// 返回一个通道用于接收更新后的节点列表，以及一个错误通道
-func (sw *ServiceWatcher) Watch(ctx context.Context) (<-chan []string, <-chan error) {
-	updatesChan := make(chan []string)
+// 返回一个通道用于接收更新后的节点列表，以及一个错误通道。
+// 同步失败时按指数退避重试；etcd 的监视通道关闭（例如 etcd 重启或失去 leader）后，
+// 退避一段时间重新同步完整的节点列表并重新建立监视，直到 ctx 取消
+func (sw *ServiceWatcher) Watch(ctx context.Context) (<-chan []NodeInfo, <-chan error) {
+	updatesChan := make(chan []NodeInfo)
 	errChan := make(chan error, 1) // 带缓冲的错误通道，避免阻塞
 
 	go func() {
 		defer close(updatesChan)
 		defer close(errChan)
+		defer sw.setState(WatchDisconnected)
+
+		wait := sw.backoff
+		for {
+			// 1. 获取一次当前所有节点，失败时按指数退避重试，直到成功或 ctx 取消
+			sw.setState(WatchResyncing)
+			if !sw.resync(ctx, updatesChan, errChan) {
+				return
+			}
+
+			// 2. 监视指定前缀，直到监视中断
+			established := time.Now()
+			sw.setState(WatchConnected)
+			sw.watch(ctx, updatesChan, errChan)
+			if ctx.Err() != nil {
+				sw.log.Infof("Watch监视被取消 (context done)，停止监视前缀 '%s'", sw.watchPrefix)
+				return
+			}
+			sw.setState(WatchDisconnected)
+
+			// 监视持续了较长时间说明连接曾经恢复正常，退避重新开始计算
+			if time.Since(established) > sw.maxBackoff {
+				wait = sw.backoff
+			}
+			sw.log.Warnf("Etcd Watch中断，%v 后重新同步节点列表", wait)
+			timer := time.NewTimer(wait)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			}
+			if wait *= 2; wait > sw.maxBackoff {
+				wait = sw.maxBackoff
+			}
 
-		// 1. 先获取一次当前所有节点
-		if err := sw.syncPeers(ctx, updatesChan); err != nil {
-			errChan <- fmt.Errorf("首次同步节点列表失败: %w", err)
-			return // 首次同步失败，直接退出goroutine
+			sw.mu.Lock()
+			sw.status.Resyncs++
+			sw.mu.Unlock()
 		}
+	}()
 
-		// 2. 创建Watch通道，监视指定前缀
-		// 使用传入的ctx，以便外部可以取消watch
-		wch := sw.cli.Watch(ctx, sw.watchPrefix, clientv3.WithPrefix())
+	return updatesChan, errChan
+}
 
-		logger.Infof("开始监视etcd前缀 '%s' 的变化...", sw.watchPrefix)
+// watch 监视前缀的变化并在变化时发送完整的节点列表，监视通道关闭或被取消时返回
+func (sw *ServiceWatcher) watch(ctx context.Context, updatesChan chan<- []NodeInfo, errChan chan<- error) {
+	// 失去 leader 时关闭监视通道，而不是无限期地挂起，以便感知 etcd 故障
+	watchCtx, cancel := context.WithCancel(clientv3.WithRequireLeader(ctx))
+	defer cancel()
+	wch := sw.cli.Watch(watchCtx, sw.watchPrefix, clientv3.WithPrefix())
 
-		for {
-			select {
-			case wresp, ok := <-wch:
-				if !ok {
-					logger.Warn("Etcd Watch通道已关闭 (可能是上下文取消或连接问题)")
-					// 如果通道关闭，通常意味着上下文被取消或连接断开
-					// 可以在这里尝试发送一个错误信号，或者依赖上下文取消来处理
-					// errChan <- fmt.Errorf("etcd watch channel closed")
-					return // 结束goroutine
-				}
-				if wresp.Err() != nil {
-					logger.Errorf("Etcd Watch收到错误: %v", wresp.Err())
-					errChan <- fmt.Errorf("etcd watch error: %w", wresp.Err())
-					// 考虑是否需要在这里return或尝试重连
-					continue // 继续等待下一个事件或错误
-				}
+	sw.log.Infof("开始监视etcd前缀 '%s' 的变化...", sw.watchPrefix)
 
-				// 检查是否有事件发生 (PUT或DELETE)
-				hasChanges := false
-				for _, ev := range wresp.Events {
-					// 只需要知道有变化即可，无需区分具体类型
-					// logger.Debugf("Watch Event: Type: %s Key:%s Value:%s\n", ev.Type, ev.Kv.Key, ev.Kv.Value)
-					_ = ev // 避免未使用变量错误
-					hasChanges = true
+	for {
+		select {
+		case wresp, ok := <-wch:
+			if !ok {
+				sw.log.Warnf("Etcd Watch通道已关闭 (可能是上下文取消或连接问题)")
+				return
+			}
+			if err := wresp.Err(); err != nil {
+				sw.log.Errorf("Etcd Watch收到错误: %v", err)
+				sw.reportErr(errChan, fmt.Errorf("etcd watch error: %w", err))
+				if wresp.Canceled {
+					return // 监视已被服务端取消（例如版本已被压缩），需要重新同步
 				}
+				continue
+			}
 
-				// 如果检测到变化，重新获取完整的节点列表并发送
-				if hasChanges {
-					logger.Info("检测到etcd变化，重新同步节点列表...")
-					if err := sw.syncPeers(ctx, updatesChan); err != nil {
-						logger.Errorf("同步节点列表失败: %v", err)
-						errChan <- fmt.Errorf("同步节点列表失败: %w", err)
-						// 考虑是否需要在这里return或尝试重连
-					}
-				}
+			// 只需要知道有变化即可，无需区分事件类型
+			if len(wresp.Events) == 0 {
+				continue
+			}
 
-			case <-ctx.Done():
-				logger.Infof("Watch监视被取消 (context done)，停止监视前缀 '%s'", sw.watchPrefix)
-				return // 结束goroutine
+			// 检测到变化，重新获取完整的节点列表并发送
+			sw.log.Infof("检测到etcd变化，重新同步节点列表...")
+			if err := sw.syncPeers(ctx, updatesChan); err != nil {
+				sw.log.Errorf("同步节点列表失败: %v", err)
+				sw.reportErr(errChan, fmt.Errorf("同步节点列表失败: %w", err))
+				return // 重新同步并重建监视，避免漏掉这次变化
 			}
+
+		case <-ctx.Done():
+			return
 		}
-	}()
+	}
+}
 
-	return updatesChan, errChan
+// resync 同步完整的节点列表，失败时按指数退避重试。
+// 每次失败都尝试报告到 errChan（不阻塞），ctx 取消时返回 false
+func (sw *ServiceWatcher) resync(ctx context.Context, updatesChan chan<- []NodeInfo, errChan chan<- error) bool {
+	wait := sw.backoff
+	for {
+		err := sw.syncPeers(ctx, updatesChan)
+		if err == nil {
+			return true
+		}
+		if ctx.Err() != nil {
+			return false
+		}
+
+		sw.log.Errorf("同步节点列表失败，%v 后重试: %v", wait, err)
+		sw.reportErr(errChan, fmt.Errorf("同步节点列表失败: %w", err))
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return false
+		}
+		if wait *= 2; wait > sw.maxBackoff {
+			wait = sw.maxBackoff
+		}
+	}
 }
 
 // syncPeers 获取当前所有节点并发送到updatesChan
-func (sw *ServiceWatcher) syncPeers(ctx context.Context, updatesChan chan<- []string) error {
-	resp, err := sw.cli.Get(ctx, sw.watchPrefix, clientv3.WithPrefix())
+func (sw *ServiceWatcher) syncPeers(ctx context.Context, updatesChan chan<- []NodeInfo) error {
+	getCtx, cancel := context.WithTimeout(ctx, syncTimeout) // etcd 不可用时不无限期等待
+	resp, err := sw.cli.Get(getCtx, sw.watchPrefix, clientv3.WithPrefix())
+	cancel()
 	if err != nil {
 		return fmt.Errorf("从etcd获取服务列表失败: %w", err)
 	}
 
-	peers := make([]string, 0, len(resp.Kvs))
+	peers := make([]NodeInfo, 0, len(resp.Kvs))
 	for _, kv := range resp.Kvs {
-		peers = append(peers, string(kv.Value)) // 使用Value作为节点地址
+		peers = append(peers, ParseNodeInfo(string(kv.Value))) // 兼容旧格式的纯地址与结构化 NodeInfo
 	}
 
 	// 发送更新后的列表到通道
 	select {
 	case updatesChan <- peers:
-		logger.Infof("已同步节点列表: %v", peers)
+		sw.log.Infof("已同步节点列表: %v", peers)
 	case <-ctx.Done():
 		return ctx.Err() // 上下文被取消
 	}
+
+	sw.mu.Lock()
+	sw.status.LastSync = time.Now()
+	sw.mu.Unlock()
 	return nil
 }
 
@@ -0,0 +1,83 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/AdrianWangs/go-cache/pkg/logger"
+)
+
+// lineRecorder 是记录写入的每一行的 logger.Logger
+type lineRecorder struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (r *lineRecorder) record(level, format string, args []interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lines = append(r.lines, level+" "+fmt.Sprintf(format, args...))
+}
+
+func (r *lineRecorder) Debugf(format string, args ...interface{}) { r.record("DEBUG", format, args) }
+func (r *lineRecorder) Infof(format string, args ...interface{})  { r.record("INFO", format, args) }
+func (r *lineRecorder) Warnf(format string, args ...interface{})  { r.record("WARN", format, args) }
+func (r *lineRecorder) Errorf(format string, args ...interface{}) { r.record("ERROR", format, args) }
+func (r *lineRecorder) WithFields(logger.Fields) logger.Logger    { return r }
+
+// recorded 返回包含 substr 的行
+func (r *lineRecorder) recorded(substr string) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var found []string
+	for _, line := range r.lines {
+		if strings.Contains(line, substr) {
+			found = append(found, line)
+		}
+	}
+	return found
+}
+
+// TestWithLogger 注册、注销和监视的日志写入选项传入的 Logger，不经过 logger 包的全局日志
+func TestWithLogger(t *testing.T) {
+	global := &lineRecorder{}
+	logger.SetLogger(global)
+	defer logger.SetLogger(nil)
+
+	etcd := newLeaseEtcd()
+	rec := &lineRecorder{}
+	sd := newServiceDiscovery(etcd, "cache", "10.0.0.1:9090", 30, newOptions(WithLogger(rec)))
+	if err := sd.Register(); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	sw := newServiceWatcher(etcd.fakeEtcd, "cache", newOptions(WithLogger(rec)))
+	ctx, cancel := context.WithCancel(context.Background())
+	updates, _ := sw.Watch(ctx)
+	if nodes := nextNodes(t, updates); len(nodes) != 1 {
+		t.Fatalf("节点列表 = %+v", nodes)
+	}
+	cancel()
+	if err := sd.Unregister(); err != nil {
+		t.Fatalf("Unregister: %v", err)
+	}
+
+	for _, want := range []string{
+		"INFO 成功获取etcd租约",
+		"INFO 服务 /cache/10.0.0.1:9090 (value: 10.0.0.1:9090) 已成功注册到etcd",
+		"INFO 已同步节点列表",
+		"INFO 服务 /cache/10.0.0.1:9090 (原 LeaseID: 1) 已成功注销",
+	} {
+		if found := rec.recorded(want); len(found) != 1 {
+			t.Errorf("%q 记录了 %d 次, want 1 次; 记录 = %q", want, len(found), rec.recorded(""))
+		}
+	}
+	for _, s := range []string{"etcd", "节点列表", "注销"} {
+		if found := global.recorded(s); len(found) != 0 {
+			t.Fatalf("全局日志收到了 %q", found)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package discovery
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// NodeInfo 缓存节点在etcd中注册的信息
+type NodeInfo struct {
+	ID       string   `json:"id,omitempty"`        // 稳定的节点标识，可选
+	GRPCAddr string   `json:"grpc_addr"`           // gRPC 服务地址 (host:port)
+	HTTPAddr string   `json:"http_addr,omitempty"` // HTTP 服务地址 (host:port)，旧版本节点没有该字段
+	Groups   []string `json:"groups"`              // 节点提供的缓存组，为 nil 表示节点未登记组信息
+	Mode     string   `json:"mode,omitempty"`      // 节点级模式（readwrite / readonly / readonly-local），为空表示未登记
+
+	ProtoVersion int `json:"proto_version,omitempty"` // 节点间通信协议版本，为 0 表示旧版本节点未登记
+
+	Protocols []string `json:"protocols,omitempty"` // 节点对 API 服务器提供的协议 (grpc / http)，为空表示未登记，由 API 服务器使用默认协议
+}
+
+// 节点登记的协议名称，与 API 服务器 -protocol 的取值相同
+const (
+	ProtocolGRPC = "grpc"
+	ProtocolHTTP = "http"
+)
+
+// Key 返回节点在一致性哈希环上的标识。
+// 优先使用 ID，否则使用 gRPC 地址；与通信协议无关，切换协议不会改变 key 的归属
+func (n NodeInfo) Key() string {
+	if n.ID != "" {
+		return n.ID
+	}
+	return n.GRPCAddr
+}
+
+// Encode 将节点信息编码为注册到etcd的值
+func (n NodeInfo) Encode() string {
+	data, err := json.Marshal(n)
+	if err != nil {
+		// NodeInfo 只包含字符串及字符串切片字段，不会序列化失败
+		return n.GRPCAddr
+	}
+	return string(data)
+}
+
+// ParseNodeInfo 解析etcd中注册的值，兼容两种格式：
+// 结构化的 JSON NodeInfo，以及旧版本节点直接注册的 gRPC 地址字符串
+func ParseNodeInfo(value string) NodeInfo {
+	trimmed := strings.TrimSpace(value)
+	if strings.HasPrefix(trimmed, "{") {
+		var info NodeInfo
+		if err := json.Unmarshal([]byte(trimmed), &info); err == nil && info.GRPCAddr != "" {
+			return info
+		}
+	}
+	return NodeInfo{GRPCAddr: trimmed}
+}
+
+// NodeKeys 返回节点列表对应的环标识
+func NodeKeys(nodes []NodeInfo) []string {
+	keys := make([]string, 0, len(nodes))
+	for _, n := range nodes {
+		keys = append(keys, n.Key())
+	}
+	return keys
+}
+
+// CanonicalNodes 规范化节点列表：去掉标识两端的空白，地址按 CanonicalAddr 规范化，
+// 去掉没有环标识的节点，按环标识排序并只保留每个标识的第一次出现。
+// 来源顺序不同（例如从 map 组装）的同一组成员得到相同的结果，所有节点因此构建出相同的哈希环。
+// duplicates 返回被去掉的重复标识，例如节点重新注册期间被列出两次
+func CanonicalNodes(nodes []NodeInfo) (list []NodeInfo, duplicates []string) {
+	list = make([]NodeInfo, 0, len(nodes))
+	for _, n := range nodes {
+		n.ID = strings.TrimSpace(n.ID)
+		n.GRPCAddr = CanonicalAddr(n.GRPCAddr)
+		if n.HTTPAddr != "" {
+			n.HTTPAddr = CanonicalAddr(n.HTTPAddr)
+		}
+		if n.Key() == "" {
+			continue
+		}
+		list = append(list, n)
+	}
+	slices.SortStableFunc(list, func(a, b NodeInfo) int { return strings.Compare(a.Key(), b.Key()) })
+	return slices.CompactFunc(list, func(a, b NodeInfo) bool {
+		if a.Key() != b.Key() {
+			return false
+		}
+		duplicates = append(duplicates, b.Key())
+		return true
+	}), duplicates
+}
+
+// HasGroupInfo 报告节点是否登记了组信息；旧版本节点或未配置组来源的节点返回 false
+func (n NodeInfo) HasGroupInfo() bool {
+	return n.Groups != nil
+}
+
+// SameAddrs 报告两个注册信息的标识和地址是否相同，组列表不参与比较
+func (n NodeInfo) SameAddrs(o NodeInfo) bool {
+	return n.ID == o.ID && n.GRPCAddr == o.GRPCAddr && n.HTTPAddr == o.HTTPAddr
+}
+
+// HasProtocolInfo 报告节点是否登记了所提供的协议；旧版本节点返回 false
+func (n NodeInfo) HasProtocolInfo() bool {
+	return len(n.Protocols) > 0
+}
+
+// Supports 报告节点是否登记了协议 protocol
+func (n NodeInfo) Supports(protocol string) bool {
+	for _, p := range n.Protocols {
+		if p == protocol {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseProtocols 解析逗号分隔的协议列表，只接受 grpc 和 http，去除重复项并保持顺序
+func ParseProtocols(s string) ([]string, error) {
+	var protocols []string
+	for _, p := range strings.Split(s, ",") {
+		p = strings.ToLower(strings.TrimSpace(p))
+		switch p {
+		case "":
+			continue
+		case ProtocolGRPC, ProtocolHTTP:
+		default:
+			return nil, fmt.Errorf("不支持的协议: %s，只能是 grpc 或 http", p)
+		}
+		if !slices.Contains(protocols, p) {
+			protocols = append(protocols, p)
+		}
+	}
+	if len(protocols) == 0 {
+		return nil, fmt.Errorf("协议列表为空")
+	}
+	return protocols, nil
+}
+
+// ReadOnly 报告节点是否登记为只读模式，只读节点仍可提供读取但会拒绝写操作
+func (n NodeInfo) ReadOnly() bool {
+	return strings.HasPrefix(n.Mode, "readonly")
+}
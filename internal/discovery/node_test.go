@@ -0,0 +1,139 @@
+package discovery
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseNodeInfo(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  NodeInfo
+	}{
+		{"旧格式", "10.0.0.1:9090", NodeInfo{GRPCAddr: "10.0.0.1:9090"}},
+		{"旧格式带空白", " 10.0.0.1:9090\n", NodeInfo{GRPCAddr: "10.0.0.1:9090"}},
+		{
+			"结构化",
+			`{"id":"node-1","grpc_addr":"10.0.0.1:9090","http_addr":"10.0.0.1:8001","groups":["scores"]}`,
+			NodeInfo{ID: "node-1", GRPCAddr: "10.0.0.1:9090", HTTPAddr: "10.0.0.1:8001", Groups: []string{"scores"}},
+		},
+		{
+			"结构化无 HTTP 地址",
+			`{"grpc_addr":"10.0.0.1:9090","groups":null}`,
+			NodeInfo{GRPCAddr: "10.0.0.1:9090"},
+		},
+		// 无法识别的 JSON 按旧格式处理，不会得到空地址
+		{"缺少 gRPC 地址", `{"http_addr":"10.0.0.1:8001"}`, NodeInfo{GRPCAddr: `{"http_addr":"10.0.0.1:8001"}`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseNodeInfo(tt.value); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("ParseNodeInfo(%q) = %+v, want %+v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNodeInfoEncodeRoundTrip(t *testing.T) {
+	info := NodeInfo{
+		ID: "node-1", GRPCAddr: "10.0.0.1:9090", HTTPAddr: "10.0.0.1:8001",
+		Groups: []string{"scores"}, ProtoVersion: 2, Protocols: []string{ProtocolGRPC, ProtocolHTTP},
+	}
+	if got := ParseNodeInfo(info.Encode()); !reflect.DeepEqual(got, info) {
+		t.Fatalf("round trip = %+v, want %+v", got, info)
+	}
+}
+
+// TestNodeKeyIgnoresHTTPAddr 环标识与 HTTP 地址无关，切换协议不改变 key 的归属
+func TestNodeKeyIgnoresHTTPAddr(t *testing.T) {
+	legacy := ParseNodeInfo("10.0.0.1:9090")
+	structured := ParseNodeInfo(`{"grpc_addr":"10.0.0.1:9090","http_addr":"10.0.0.1:8001"}`)
+	if legacy.Key() != structured.Key() || legacy.Key() != "10.0.0.1:9090" {
+		t.Fatalf("Key() = %q / %q, want the gRPC address", legacy.Key(), structured.Key())
+	}
+	withID := ParseNodeInfo(`{"id":"node-1","grpc_addr":"10.0.0.1:9090","http_addr":"10.0.0.1:8001"}`)
+	if withID.Key() != "node-1" {
+		t.Fatalf("Key() = %q, want the node ID", withID.Key())
+	}
+}
+
+func TestParseProtocols(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    []string
+		wantErr bool
+	}{
+		{"grpc,http", []string{"grpc", "http"}, false},
+		{" HTTP , grpc ,http", []string{"http", "grpc"}, false},
+		{"grpc,,", []string{"grpc"}, false},
+		{"", nil, true},
+		{"grpc,quic", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseProtocols(tt.input)
+		if (err != nil) != tt.wantErr || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseProtocols(%q) = %v, %v; want %v", tt.input, got, err, tt.want)
+		}
+	}
+
+	info := NodeInfo{Protocols: []string{ProtocolHTTP}}
+	if !info.HasProtocolInfo() || !info.Supports(ProtocolHTTP) || info.Supports(ProtocolGRPC) {
+		t.Fatalf("Supports = %v", info.Protocols)
+	}
+	if (NodeInfo{}).HasProtocolInfo() {
+		t.Fatal("旧版本节点报告登记了协议")
+	}
+}
+
+// TestCanonicalNodes 同一组成员无论顺序、重复和地址写法如何，都得到相同的列表
+func TestCanonicalNodes(t *testing.T) {
+	members := []NodeInfo{
+		{GRPCAddr: "10.0.0.1:9090"},
+		{GRPCAddr: "10.0.0.2:9090", HTTPAddr: "10.0.0.2:8001"},
+		{ID: "node-1", GRPCAddr: "10.0.0.3:9090"},
+	}
+	tests := []struct {
+		name       string
+		nodes      []NodeInfo
+		want       []NodeInfo
+		duplicates []string
+	}{
+		{"已规范", members, members, nil},
+		{"乱序", []NodeInfo{members[2], members[0], members[1]}, members, nil},
+		{
+			"重复的节点保留第一条",
+			[]NodeInfo{members[1], members[2], {ID: "node-1", GRPCAddr: "10.0.0.9:9090"}, members[0], members[1]},
+			members,
+			[]string{"10.0.0.2:9090", "node-1"},
+		},
+		{
+			"空白和地址写法",
+			[]NodeInfo{
+				{GRPCAddr: " grpc://10.0.0.2:9090/", HTTPAddr: "http://10.0.0.2:8001/"},
+				{ID: " node-1\n", GRPCAddr: "10.0.0.3:9090"},
+				{GRPCAddr: "10.0.0.1:9090"},
+				{GRPCAddr: " "},
+			},
+			members,
+			nil,
+		},
+		{
+			"大小写不同的主机名是同一个节点",
+			[]NodeInfo{{GRPCAddr: "Node-2:9090"}, {GRPCAddr: "node-2:9090"}},
+			[]NodeInfo{{GRPCAddr: "node-2:9090"}},
+			[]string{"node-2:9090"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, duplicates := CanonicalNodes(tt.nodes)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("节点 = %+v, want %+v", got, tt.want)
+			}
+			if !reflect.DeepEqual(duplicates, tt.duplicates) {
+				t.Fatalf("重复的节点 = %v, want %v", duplicates, tt.duplicates)
+			}
+		})
+	}
+}
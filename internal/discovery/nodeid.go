@@ -0,0 +1,107 @@
+package discovery
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// NodeIDMode 决定节点标识的来源
+type NodeIDMode string
+
+const (
+	// NodeIDFromAddress 迁移模式：节点标识为规范化后的 gRPC 地址，与过去以地址为环 key 的部署保持相同的 key 归属
+	NodeIDFromAddress NodeIDMode = "address"
+	// NodeIDPersistent 节点标识随机生成并保存到文件，重启、换 IP 或换端口后保持不变
+	NodeIDPersistent NodeIDMode = "persistent"
+)
+
+// CanonicalAddr 规范化节点地址：去掉协议前缀和路径，主机名转为小写，
+// 使同一地址的不同写法（例如 "HTTP://Host:9090/" 与 "host:9090"）得到相同的结果
+func CanonicalAddr(addr string) string {
+	addr = strings.TrimSpace(addr)
+	if i := strings.Index(addr, "://"); i >= 0 {
+		addr = addr[i+3:]
+	}
+	if i := strings.Index(addr, "/"); i >= 0 {
+		addr = addr[:i]
+	}
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return strings.ToLower(addr)
+	}
+	return net.JoinHostPort(strings.ToLower(host), port)
+}
+
+// LocalIP 获取本地非环回IP地址，节点未指定主机时使用。
+// 有多块网卡时返回第一个找到的地址，不一定是其他节点能访问的那个，可以用节点的 -self-check 确认
+func LocalIP() (string, error) {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return "", err
+	}
+	for _, address := range addrs {
+		// 检查ip地址判断是否回环地址
+		if ipnet, ok := address.(*net.IPNet); ok && !ipnet.IP.IsLoopback() {
+			if ipnet.IP.To4() != nil {
+				return ipnet.IP.String(), nil
+			}
+		}
+	}
+	return "", fmt.Errorf("无法找到本地非环回IP地址")
+}
+
+// ResolveNodeID 按模式确定节点标识。
+// explicit 非空时直接使用；迁移模式返回规范化的 gRPC 地址；
+// 持久化模式从 idFile 读取标识，文件不存在时生成新的标识并写入
+func ResolveNodeID(mode NodeIDMode, explicit, grpcAddr, idFile string) (string, error) {
+	if explicit != "" {
+		return explicit, nil
+	}
+
+	switch mode {
+	case "", NodeIDFromAddress:
+		return CanonicalAddr(grpcAddr), nil
+	case NodeIDPersistent:
+		return loadOrCreateNodeID(idFile)
+	default:
+		return "", fmt.Errorf("未知的节点标识模式: %s", mode)
+	}
+}
+
+// loadOrCreateNodeID 读取 path 中保存的节点标识，不存在时生成新的标识并保存
+func loadOrCreateNodeID(path string) (string, error) {
+	if path == "" {
+		return "", fmt.Errorf("持久化节点标识需要指定保存文件")
+	}
+
+	data, err := os.ReadFile(path)
+	if err == nil {
+		if id := strings.TrimSpace(string(data)); id != "" {
+			return id, nil
+		}
+	} else if !os.IsNotExist(err) {
+		return "", fmt.Errorf("读取节点标识文件失败: %w", err)
+	}
+
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("生成节点标识失败: %w", err)
+	}
+	id := "node-" + hex.EncodeToString(buf)
+
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return "", fmt.Errorf("创建节点标识目录失败: %w", err)
+		}
+	}
+	if err := os.WriteFile(path, []byte(id+"\n"), 0o644); err != nil {
+		return "", fmt.Errorf("保存节点标识失败: %w", err)
+	}
+	return id, nil
+}
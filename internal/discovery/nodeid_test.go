@@ -0,0 +1,85 @@
+package discovery
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCanonicalAddr(t *testing.T) {
+	tests := map[string]string{
+		"10.0.0.1:9090":           "10.0.0.1:9090",
+		"HTTP://Host:9090/":       "host:9090",
+		"http://host:9090/_cache": "host:9090",
+		" host:9090\n":            "host:9090",
+		"[::1]:9090":              "[::1]:9090",
+		"Host":                    "host",
+	}
+	for in, want := range tests {
+		if got := CanonicalAddr(in); got != want {
+			t.Errorf("CanonicalAddr(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestResolveNodeID(t *testing.T) {
+	idFile := filepath.Join(t.TempDir(), "data", "node-id")
+
+	if id, err := ResolveNodeID(NodeIDPersistent, "node-x", "10.0.0.1:9090", idFile); err != nil || id != "node-x" {
+		t.Fatalf("显式标识 = %q, %v", id, err)
+	}
+	for _, mode := range []NodeIDMode{"", NodeIDFromAddress} {
+		if id, err := ResolveNodeID(mode, "", "Node1:9090", idFile); err != nil || id != "node1:9090" {
+			t.Fatalf("迁移模式 %q = %q, %v", mode, id, err)
+		}
+	}
+
+	// 持久化模式第一次生成标识，之后重启（地址改变）读到同一个标识
+	first, err := ResolveNodeID(NodeIDPersistent, "", "10.0.0.1:9090", idFile)
+	if err != nil || !strings.HasPrefix(first, "node-") {
+		t.Fatalf("生成标识 = %q, %v", first, err)
+	}
+	second, err := ResolveNodeID(NodeIDPersistent, "", "10.0.0.9:9191", idFile)
+	if err != nil || second != first {
+		t.Fatalf("重启后标识 = %q, %v; want %q", second, err, first)
+	}
+	data, _ := os.ReadFile(idFile)
+	if strings.TrimSpace(string(data)) != first {
+		t.Fatalf("标识文件内容 = %q", data)
+	}
+
+	if _, err := ResolveNodeID(NodeIDPersistent, "", "10.0.0.1:9090", ""); err == nil {
+		t.Fatal("持久化模式未指定文件时应返回错误")
+	}
+	if _, err := ResolveNodeID("random", "", "10.0.0.1:9090", idFile); err == nil {
+		t.Fatal("未知模式应返回错误")
+	}
+}
+
+func TestParseSeeds(t *testing.T) {
+	seeds, err := ParseSeeds([]string{
+		"Node1:9090",
+		"node-2=10.0.0.2:9090|10.0.0.2:8001",
+		" ",
+		`{"id":"node-3","grpc_addr":"10.0.0.3:9090"}`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []NodeInfo{
+		{ID: "node1:9090", GRPCAddr: "Node1:9090"},
+		{ID: "node-2", GRPCAddr: "10.0.0.2:9090", HTTPAddr: "10.0.0.2:8001"},
+		{ID: "node-3", GRPCAddr: "10.0.0.3:9090"},
+	}
+	if !reflect.DeepEqual(seeds, want) {
+		t.Fatalf("ParseSeeds = %+v, want %+v", seeds, want)
+	}
+
+	for _, bad := range []string{"node-1=", "|10.0.0.1:8001", `{"http_addr":"10.0.0.1:8001"}`} {
+		if _, err := ParseSeeds([]string{bad}); err == nil {
+			t.Errorf("ParseSeeds(%q) 应返回错误", bad)
+		}
+	}
+}
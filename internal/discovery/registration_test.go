@@ -0,0 +1,177 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// leaseEtcd 在 fakeEtcd 上加入租约操作，记录创建和撤销租约、写入和删除 key 的次数
+type leaseEtcd struct {
+	*fakeEtcd
+
+	countMu                        sync.Mutex
+	grants, revokes, puts, deletes int
+	keepAlive                      chan *clientv3.LeaseKeepAliveResponse
+}
+
+func newLeaseEtcd() *leaseEtcd {
+	return &leaseEtcd{fakeEtcd: newFakeEtcd(), keepAlive: make(chan *clientv3.LeaseKeepAliveResponse)}
+}
+
+func (e *leaseEtcd) Grant(ctx context.Context, ttl int64) (*clientv3.LeaseGrantResponse, error) {
+	e.countMu.Lock()
+	defer e.countMu.Unlock()
+	e.grants++
+	return &clientv3.LeaseGrantResponse{ID: clientv3.LeaseID(e.grants), TTL: ttl}, nil
+}
+
+func (e *leaseEtcd) Revoke(ctx context.Context, id clientv3.LeaseID) (*clientv3.LeaseRevokeResponse, error) {
+	e.countMu.Lock()
+	defer e.countMu.Unlock()
+	e.revokes++
+	return &clientv3.LeaseRevokeResponse{}, nil
+}
+
+func (e *leaseEtcd) KeepAlive(ctx context.Context, id clientv3.LeaseID) (<-chan *clientv3.LeaseKeepAliveResponse, error) {
+	return e.keepAlive, nil
+}
+
+func (e *leaseEtcd) Put(ctx context.Context, key, val string, opts ...clientv3.OpOption) (*clientv3.PutResponse, error) {
+	e.countMu.Lock()
+	e.puts++
+	e.countMu.Unlock()
+	if e.fakeEtcd.isDown() {
+		return nil, errors.New("etcd unavailable")
+	}
+	e.put(key, val)
+	return &clientv3.PutResponse{}, nil
+}
+
+func (e *leaseEtcd) Delete(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.DeleteResponse, error) {
+	e.countMu.Lock()
+	defer e.countMu.Unlock()
+	e.deletes++
+	return &clientv3.DeleteResponse{}, nil
+}
+
+// counts 返回创建租约、撤销租约、写入和删除的次数
+func (e *leaseEtcd) counts() (grants, revokes, puts, deletes int) {
+	e.countMu.Lock()
+	defer e.countMu.Unlock()
+	return e.grants, e.revokes, e.puts, e.deletes
+}
+
+// isDown 判断 etcd 是否已停止
+func (e *fakeEtcd) isDown() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.down
+}
+
+// TestUpdateValueKeepsLease 注册后更新元数据：在原租约下重新写入，监视方读到新的组列表，
+// 期间 key 一直存在
+func TestUpdateValueKeepsLease(t *testing.T) {
+	etcd := newLeaseEtcd()
+	var (
+		mu     sync.Mutex
+		groups = []string{"users"}
+	)
+	sd := newServiceDiscovery(etcd, "cache", "10.0.0.1:9090", 30, newOptions(
+		WithNodeID("node-1"),
+		WithGroups(func() []string {
+			mu.Lock()
+			defer mu.Unlock()
+			return groups
+		}),
+	))
+
+	if err := sd.UpdateValue(sd.Advertised()); err == nil {
+		t.Fatal("未注册时 UpdateValue 应返回错误")
+	}
+	if err := sd.Register(); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	defer sd.Unregister()
+
+	sw, _ := newTestWatcher(etcd.fakeEtcd)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	updates, _ := sw.Watch(ctx)
+
+	// node 返回节点列表中唯一的节点，列表中没有它说明 key 消失过
+	node := func(nodes []NodeInfo) NodeInfo {
+		t.Helper()
+		if len(nodes) != 1 || nodes[0].ID != "node-1" {
+			t.Fatalf("节点列表 = %+v, want 只有 node-1", nodes)
+		}
+		return nodes[0]
+	}
+	if got := node(nextNodes(t, updates)).Groups; !slices.Equal(got, []string{"users"}) {
+		t.Fatalf("注册时的组列表 = %v", got)
+	}
+
+	mu.Lock()
+	groups = []string{"users", "orders"}
+	mu.Unlock()
+	if err := sd.UpdateValue(sd.Advertised()); err != nil {
+		t.Fatalf("UpdateValue: %v", err)
+	}
+	if got := node(nextNodes(t, updates)).Groups; !slices.Equal(got, []string{"users", "orders"}) {
+		t.Fatalf("更新后的组列表 = %v", got)
+	}
+
+	grants, revokes, puts, deletes := etcd.counts()
+	if grants != 1 || revokes != 0 || deletes != 0 || puts != 2 {
+		t.Fatalf("grants=%d revokes=%d puts=%d deletes=%d, want 1 0 2 0", grants, revokes, puts, deletes)
+	}
+
+	// 值未变化时不访问 etcd
+	if err := sd.UpdateValue(sd.Advertised()); err != nil {
+		t.Fatalf("重复 UpdateValue: %v", err)
+	}
+	if _, _, puts, _ := etcd.counts(); puts != 2 {
+		t.Fatalf("值未变化时写入了 etcd，puts=%d", puts)
+	}
+}
+
+// TestUpdateValueRetriesAfterFailure 写入失败时保留旧值，下次更新重新写入
+func TestUpdateValueRetriesAfterFailure(t *testing.T) {
+	etcd := newLeaseEtcd()
+	mode := "normal"
+	var mu sync.Mutex
+	sd := newServiceDiscovery(etcd, "cache", "10.0.0.1:9090", 30, newOptions(WithMode(func() string {
+		mu.Lock()
+		defer mu.Unlock()
+		return mode
+	})))
+	if err := sd.Register(); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	defer sd.Unregister()
+
+	mu.Lock()
+	mode = "readonly"
+	mu.Unlock()
+	etcd.stop()
+	if err := sd.UpdateValue(sd.Advertised()); err == nil {
+		t.Fatal("etcd 不可用时 UpdateValue 应返回错误")
+	}
+	etcd.start()
+	if err := sd.UpdateValue(sd.Advertised()); err != nil {
+		t.Fatalf("恢复后 UpdateValue: %v", err)
+	}
+	etcd.mu.Lock()
+	value := etcd.kvs["/cache/10.0.0.1:9090"]
+	etcd.mu.Unlock()
+	if info := ParseNodeInfo(value); info.Mode != "readonly" {
+		t.Fatalf("etcd 中的注册值 = %q, want mode=readonly", value)
+	}
+	if grants, _, _, _ := etcd.counts(); grants != 1 {
+		t.Fatalf("grants = %d, want 1", grants)
+	}
+}
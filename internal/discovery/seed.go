@@ -0,0 +1,51 @@
+package discovery
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseSeed 解析一个种子节点。格式为 [id=]grpc_addr[|http_addr]：
+// 未指定 id 时使用规范化的 gRPC 地址，与 address 模式下节点登记的标识一致
+func ParseSeed(entry string) (NodeInfo, error) {
+	entry = strings.TrimSpace(entry)
+	if strings.HasPrefix(entry, "{") {
+		info := ParseNodeInfo(entry)
+		if info.GRPCAddr == "" || strings.HasPrefix(info.GRPCAddr, "{") {
+			return NodeInfo{}, fmt.Errorf("无效的种子节点: %s", entry)
+		}
+		return info, nil
+	}
+
+	var info NodeInfo
+	if i := strings.Index(entry, "="); i >= 0 {
+		info.ID = strings.TrimSpace(entry[:i])
+		entry = entry[i+1:]
+	}
+	grpcAddr, httpAddr, _ := strings.Cut(entry, "|")
+	info.GRPCAddr = strings.TrimSpace(grpcAddr)
+	info.HTTPAddr = strings.TrimSpace(httpAddr)
+	if info.GRPCAddr == "" {
+		return NodeInfo{}, fmt.Errorf("种子节点缺少 gRPC 地址: %s", entry)
+	}
+	if info.ID == "" {
+		info.ID = CanonicalAddr(info.GRPCAddr)
+	}
+	return info, nil
+}
+
+// ParseSeeds 解析种子节点列表，忽略空项
+func ParseSeeds(entries []string) ([]NodeInfo, error) {
+	seeds := make([]NodeInfo, 0, len(entries))
+	for _, entry := range entries {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		info, err := ParseSeed(entry)
+		if err != nil {
+			return nil, err
+		}
+		seeds = append(seeds, info)
+	}
+	return seeds, nil
+}
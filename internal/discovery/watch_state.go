@@ -0,0 +1,74 @@
+package discovery
+
+import (
+	"fmt"
+	"time"
+)
+
+// WatchState ServiceWatcher 与 etcd 之间的连接状态
+type WatchState int32
+
+const (
+	// WatchDisconnected 与 etcd 的监视已断开，节点列表可能已经过期
+	WatchDisconnected WatchState = iota
+	// WatchResyncing 正在（重新）同步完整的节点列表
+	WatchResyncing
+	// WatchConnected 节点列表已同步，正在监视变化
+	WatchConnected
+)
+
+// String 返回状态名称
+func (s WatchState) String() string {
+	switch s {
+	case WatchDisconnected:
+		return "disconnected"
+	case WatchResyncing:
+		return "resyncing"
+	case WatchConnected:
+		return "connected"
+	default:
+		return fmt.Sprintf("WatchState(%d)", int32(s))
+	}
+}
+
+// MarshalText 以状态名称序列化，用于 JSON 响应
+func (s WatchState) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText 解析 MarshalText 输出的状态名称
+func (s *WatchState) UnmarshalText(text []byte) error {
+	for _, state := range []WatchState{WatchDisconnected, WatchResyncing, WatchConnected} {
+		if string(text) == state.String() {
+			*s = state
+			return nil
+		}
+	}
+	return fmt.Errorf("未知的服务发现状态: %s", text)
+}
+
+// WatchStatus ServiceWatcher 的当前状态
+type WatchStatus struct {
+	State     WatchState `json:"state"`                // 当前状态
+	Since     time.Time  `json:"since"`                // 进入当前状态的时间
+	LastSync  time.Time  `json:"last_sync"`            // 最近一次成功同步完整节点列表的时间，从未成功时为零值
+	LastError string     `json:"last_error,omitempty"` // 最近一次错误
+	Resyncs   int64      `json:"resyncs"`              // 监视中断后重新同步的次数
+}
+
+// Synced 报告是否至少成功同步过一次节点列表
+func (s WatchStatus) Synced() bool {
+	return !s.LastSync.IsZero()
+}
+
+// Degraded 报告节点列表是否可能已经过期，即当前没有处于正常监视状态
+func (s WatchStatus) Degraded() bool {
+	return s.State != WatchConnected
+}
+
+// WithStateHook 设置 ServiceWatcher 状态变化时的回调，回调在监视 goroutine 中同步执行
+func WithStateHook(hook func(WatchStatus)) Option {
+	return func(o *options) {
+		o.stateHook = hook
+	}
+}
@@ -0,0 +1,205 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// fakeEtcd 内存中的 watchClient，可以停止和重启以模拟 etcd 故障
+type fakeEtcd struct {
+	mu       sync.Mutex
+	kvs      map[string]string
+	down     bool
+	watchers []chan clientv3.WatchResponse
+}
+
+func newFakeEtcd() *fakeEtcd {
+	return &fakeEtcd{kvs: make(map[string]string)}
+}
+
+func (e *fakeEtcd) Get(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.down {
+		return nil, errors.New("etcd unavailable")
+	}
+	keys := make([]string, 0, len(e.kvs))
+	for k := range e.kvs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	resp := &clientv3.GetResponse{}
+	for _, k := range keys {
+		resp.Kvs = append(resp.Kvs, &mvccpb.KeyValue{Key: []byte(k), Value: []byte(e.kvs[k])})
+	}
+	return resp, nil
+}
+
+func (e *fakeEtcd) Watch(ctx context.Context, key string, opts ...clientv3.OpOption) clientv3.WatchChan {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	ch := make(chan clientv3.WatchResponse, 16)
+	if e.down {
+		close(ch)
+		return ch
+	}
+	e.watchers = append(e.watchers, ch)
+	return ch
+}
+
+func (e *fakeEtcd) Close() error { return nil }
+
+// put 写入 key 并通知所有监视
+func (e *fakeEtcd) put(key, value string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.kvs[key] = value
+	for _, ch := range e.watchers {
+		ch <- clientv3.WatchResponse{Events: []*clientv3.Event{{Type: mvccpb.PUT}}}
+	}
+}
+
+// stop 停止 etcd：关闭所有监视通道，之后的请求失败
+func (e *fakeEtcd) stop() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.down = true
+	for _, ch := range e.watchers {
+		close(ch)
+	}
+	e.watchers = nil
+}
+
+func (e *fakeEtcd) start() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.down = false
+}
+
+// newTestWatcher 创建使用 etcd 的 ServiceWatcher，重试间隔缩短到毫秒级；
+// 返回的通道依次收到每次状态变化
+func newTestWatcher(etcd *fakeEtcd) (*ServiceWatcher, <-chan WatchState) {
+	states := make(chan WatchState, 64)
+	sw := newServiceWatcher(etcd, "cache", newOptions(WithStateHook(func(s WatchStatus) { states <- s.State })))
+	sw.backoff = 5 * time.Millisecond
+	sw.maxBackoff = 20 * time.Millisecond
+	return sw, states
+}
+
+// nextNodes 等待下一份节点列表
+func nextNodes(t *testing.T, updates <-chan []NodeInfo) []NodeInfo {
+	t.Helper()
+	select {
+	case nodes := <-updates:
+		return nodes
+	case <-time.After(5 * time.Second):
+		t.Fatal("等待节点列表超时")
+		return nil
+	}
+}
+
+// waitState 等待进入 want 状态
+func waitState(t *testing.T, states <-chan WatchState, want WatchState) {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case s := <-states:
+			if s == want {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("等待状态 %s 超时", want)
+		}
+	}
+}
+
+func TestWatchRetriesInitialSync(t *testing.T) {
+	etcd := newFakeEtcd()
+	etcd.kvs["/cache/a"] = "10.0.0.1:9090"
+	etcd.stop()
+	sw, states := newTestWatcher(etcd)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	updates, errs := sw.Watch(ctx)
+
+	// 首次同步失败后监视 goroutine 继续重试，而不是关闭通道退出
+	select {
+	case err, ok := <-errs:
+		if !ok || err == nil {
+			t.Fatal("错误通道被关闭")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("未报告首次同步失败")
+	}
+	if s := sw.Status(); s.Synced() || !s.Degraded() || s.LastError == "" {
+		t.Fatalf("etcd 不可用时状态 = %+v", s)
+	}
+
+	etcd.start()
+	if nodes := nextNodes(t, updates); len(nodes) != 1 || nodes[0].GRPCAddr != "10.0.0.1:9090" {
+		t.Fatalf("节点列表 = %+v", nodes)
+	}
+	waitState(t, states, WatchConnected)
+	if s := sw.Status(); !s.Synced() || s.Degraded() || s.LastError != "" {
+		t.Fatalf("恢复后状态 = %+v", s)
+	}
+}
+
+// TestWatchSurvivesEtcdRestart etcd 运行中重启：监视中断、重新同步完整列表，
+// 重启期间的变化不会丢失，之后的变化继续送达
+func TestWatchSurvivesEtcdRestart(t *testing.T) {
+	etcd := newFakeEtcd()
+	etcd.kvs["/cache/a"] = "10.0.0.1:9090"
+	sw, states := newTestWatcher(etcd)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	updates, _ := sw.Watch(ctx)
+	nextNodes(t, updates)
+	waitState(t, states, WatchConnected)
+
+	etcd.put("/cache/b", "10.0.0.2:9090")
+	if nodes := nextNodes(t, updates); len(nodes) != 2 {
+		t.Fatalf("变化后节点列表 = %+v", nodes)
+	}
+
+	etcd.stop()
+	waitState(t, states, WatchDisconnected)
+	if !sw.Status().Degraded() {
+		t.Fatal("etcd 停止后状态未降级")
+	}
+	// etcd 停止期间注册的节点在重新同步时送达
+	etcd.mu.Lock()
+	etcd.kvs["/cache/c"] = "10.0.0.3:9090"
+	etcd.mu.Unlock()
+	etcd.start()
+
+	if nodes := nextNodes(t, updates); len(nodes) != 3 {
+		t.Fatalf("重新同步后节点列表 = %+v", nodes)
+	}
+	waitState(t, states, WatchConnected)
+	if s := sw.Status(); s.Resyncs < 1 {
+		t.Fatalf("Resyncs = %d", s.Resyncs)
+	}
+
+	etcd.put("/cache/d", "10.0.0.4:9090")
+	if nodes := nextNodes(t, updates); len(nodes) != 4 {
+		t.Fatalf("重新监视后节点列表 = %+v", nodes)
+	}
+
+	// ctx 取消后关闭两个通道
+	cancel()
+	for range updates {
+	}
+	if s := sw.Status(); s.State != WatchDisconnected {
+		t.Fatalf("取消后状态 = %s", s.State)
+	}
+}
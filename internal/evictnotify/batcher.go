@@ -0,0 +1,318 @@
+// Package evictnotify delivers the keys a cache group drops to an outside
+// sink, typically the origin, which can then stop pushing updates for keys
+// that are no longer cached. A Batcher is the group's cache.Notifier: it
+// queues events without blocking the cache, groups them into batches and sends
+// each batch to a Sink, retrying a failed send a few times. HTTPSink posts
+// batches as JSON; other transports such as Kafka implement Sink.
+//
+// Accounting is exact: every event passed to Notify is eventually counted once,
+// either as sent or as dropped, never both. An event is dropped when the queue
+// is full or when its batch still fails after the last attempt. Delivery within
+// those attempts is at least once: a sink that processed a batch but reported
+// an error receives it again, so sinks should apply events idempotently.
+package evictnotify
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/internal/cache"
+	"github.com/AdrianWangs/go-cache/pkg/logger"
+)
+
+const (
+	// DefaultQueueSize is the number of events waiting to be sent a Batcher holds by default
+	DefaultQueueSize = 10000
+	// DefaultMaxBatch is the largest batch sent by default
+	DefaultMaxBatch = 500
+	// DefaultWindow is how long a batch collects events by default
+	DefaultWindow = time.Second
+	// DefaultAttempts is the number of times a batch is sent by default before it is dropped
+	DefaultAttempts = 3
+	// DefaultBackoff is the wait before the first retry by default, doubled after every failure
+	DefaultBackoff = 200 * time.Millisecond
+	// DefaultSendTimeout bounds a single send by default
+	DefaultSendTimeout = 5 * time.Second
+)
+
+// Sink delivers a batch of events. The batch is reused once Send returns, so a
+// sink that keeps the events must copy them.
+type Sink interface {
+	Send(ctx context.Context, events []cache.EvictionEvent) error
+}
+
+// SinkFunc adapts a function to a Sink
+type SinkFunc func(ctx context.Context, events []cache.EvictionEvent) error
+
+// Send implements Sink
+func (f SinkFunc) Send(ctx context.Context, events []cache.EvictionEvent) error {
+	return f(ctx, events)
+}
+
+// Stats describes a Batcher. Notified equals Sent plus Dropped plus the events
+// still queued or being sent.
+type Stats struct {
+	Notified  int64  `json:"notified"`             // events passed to Notify
+	Sent      int64  `json:"sent"`                 // events the sink accepted
+	Batches   int64  `json:"batches"`              // batches the sink accepted
+	Retries   int64  `json:"retries"`              // sends retried after a failure
+	Dropped   int64  `json:"dropped"`              // events dropped: queue full, batch failed on every attempt, or left over at Close
+	Depth     int    `json:"depth"`                // events waiting in the queue
+	LastError string `json:"last_error,omitempty"` // error of the last failed send, cleared by a successful one
+}
+
+// Option configures a Batcher
+type Option func(*Batcher)
+
+// WithQueueSize bounds the events waiting to be sent; events notified beyond it are dropped
+func WithQueueSize(n int) Option {
+	return func(b *Batcher) {
+		if n > 0 {
+			b.queueSize = n
+		}
+	}
+}
+
+// WithMaxBatch sets the largest number of events sent at once
+func WithMaxBatch(n int) Option {
+	return func(b *Batcher) {
+		if n > 0 {
+			b.maxBatch = n
+		}
+	}
+}
+
+// WithWindow sets how long a batch collects events after its first one before
+// it is sent; a batch reaching the maximum size is sent at once
+func WithWindow(d time.Duration) Option {
+	return func(b *Batcher) {
+		if d > 0 {
+			b.window = d
+		}
+	}
+}
+
+// WithRetry sets how many times a batch is sent before its events are dropped,
+// and the wait before the first retry, doubled after every failure
+func WithRetry(attempts int, backoff time.Duration) Option {
+	return func(b *Batcher) {
+		if attempts > 0 {
+			b.attempts = attempts
+		}
+		if backoff > 0 {
+			b.backoff = backoff
+		}
+	}
+}
+
+// WithSendTimeout bounds a single call to the sink
+func WithSendTimeout(d time.Duration) Option {
+	return func(b *Batcher) {
+		if d > 0 {
+			b.sendTimeout = d
+		}
+	}
+}
+
+// Batcher queues eviction events and sends them to a sink in batches. It
+// implements cache.Notifier and is safe for concurrent use; several groups may
+// share one.
+type Batcher struct {
+	sink        Sink
+	queueSize   int
+	maxBatch    int
+	window      time.Duration
+	attempts    int
+	backoff     time.Duration
+	sendTimeout time.Duration
+
+	mu     sync.RWMutex // held for writing by Close so that no event is queued after it
+	closed bool
+	events chan cache.EvictionEvent
+
+	stop   chan struct{} // closed by Close: flush the queue and exit
+	done   chan struct{} // closed when the sender exits
+	ctx    context.Context
+	cancel context.CancelFunc // aborts sends when Close runs out of time
+
+	notified atomic.Int64
+	sent     atomic.Int64
+	batches  atomic.Int64
+	retries  atomic.Int64
+	dropped  atomic.Int64
+	lastErr  atomic.Pointer[string]
+}
+
+// New creates a Batcher sending to sink and starts its sender
+func New(sink Sink, opts ...Option) *Batcher {
+	b := &Batcher{
+		sink:        sink,
+		queueSize:   DefaultQueueSize,
+		maxBatch:    DefaultMaxBatch,
+		window:      DefaultWindow,
+		attempts:    DefaultAttempts,
+		backoff:     DefaultBackoff,
+		sendTimeout: DefaultSendTimeout,
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	b.events = make(chan cache.EvictionEvent, b.queueSize)
+	b.ctx, b.cancel = context.WithCancel(context.Background())
+	go b.run()
+	return b
+}
+
+// Notify queues e without blocking; it implements cache.Notifier. The event is
+// dropped when the queue is full or the Batcher is closed.
+func (b *Batcher) Notify(e cache.EvictionEvent) {
+	b.notified.Add(1)
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.closed {
+		b.dropped.Add(1)
+		return
+	}
+	select {
+	case b.events <- e:
+	default:
+		b.dropped.Add(1)
+	}
+}
+
+// Stats returns the Batcher's counters
+func (b *Batcher) Stats() Stats {
+	s := Stats{
+		Notified: b.notified.Load(),
+		Sent:     b.sent.Load(),
+		Batches:  b.batches.Load(),
+		Retries:  b.retries.Load(),
+		Dropped:  b.dropped.Load(),
+		Depth:    len(b.events),
+	}
+	if err := b.lastErr.Load(); err != nil {
+		s.LastError = *err
+	}
+	return s
+}
+
+// Close stops accepting events and sends the queued ones, retrying as usual.
+// When ctx ends first the sends in progress are aborted and the events left
+// are counted as dropped. It returns ctx's error in that case.
+func (b *Batcher) Close(ctx context.Context) error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		<-b.done
+		return nil
+	}
+	b.closed = true
+	b.mu.Unlock()
+	close(b.stop)
+
+	select {
+	case <-b.done:
+		return nil
+	case <-ctx.Done():
+		b.cancel()
+		<-b.done
+		return ctx.Err()
+	}
+}
+
+// run collects batches and sends them until Close, then flushes the queue
+func (b *Batcher) run() {
+	defer close(b.done)
+	defer b.cancel()
+	batch := make([]cache.EvictionEvent, 0, b.maxBatch)
+	for {
+		select {
+		case e := <-b.events:
+			batch = append(batch[:0], e)
+		case <-b.stop:
+			b.flush(batch)
+			return
+		}
+		b.collect(&batch)
+		b.deliver(batch)
+	}
+}
+
+// collect adds events to batch until it is full or the window has passed
+func (b *Batcher) collect(batch *[]cache.EvictionEvent) {
+	timer := time.NewTimer(b.window)
+	defer timer.Stop()
+	for len(*batch) < b.maxBatch {
+		select {
+		case e := <-b.events:
+			*batch = append(*batch, e)
+		case <-timer.C:
+			return
+		case <-b.stop:
+			return
+		}
+	}
+}
+
+// flush sends the events left in the queue at Close without waiting for windows
+func (b *Batcher) flush(batch []cache.EvictionEvent) {
+	for {
+		batch = batch[:0]
+	fill:
+		for len(batch) < b.maxBatch {
+			select {
+			case e := <-b.events:
+				batch = append(batch, e)
+			default:
+				break fill
+			}
+		}
+		if len(batch) == 0 {
+			return
+		}
+		b.deliver(batch)
+	}
+}
+
+// deliver sends batch, retrying with backoff, and counts it as sent or dropped
+func (b *Batcher) deliver(batch []cache.EvictionEvent) {
+	wait := b.backoff
+	for attempt := 1; ; attempt++ {
+		err := b.send(batch)
+		if err == nil {
+			b.sent.Add(int64(len(batch)))
+			b.batches.Add(1)
+			b.lastErr.Store(nil)
+			return
+		}
+		msg := err.Error()
+		b.lastErr.Store(&msg)
+		if attempt >= b.attempts || b.ctx.Err() != nil {
+			b.dropped.Add(int64(len(batch)))
+			logger.Errorf("[EvictNotify] 发送淘汰通知失败，已尝试 %d 次，丢弃 %d 个事件: %v", attempt, len(batch), err)
+			return
+		}
+		b.retries.Add(1)
+		logger.Warnf("[EvictNotify] 发送淘汰通知失败，%v 后重试: %v", wait, err)
+		select {
+		case <-time.After(wait):
+		case <-b.ctx.Done():
+		}
+		wait *= 2
+	}
+}
+
+// send makes one attempt at delivering batch
+func (b *Batcher) send(batch []cache.EvictionEvent) error {
+	if err := b.ctx.Err(); err != nil {
+		return errors.New("closing, send aborted")
+	}
+	ctx, cancel := context.WithTimeout(b.ctx, b.sendTimeout)
+	defer cancel()
+	return b.sink.Send(ctx, batch)
+}
@@ -0,0 +1,249 @@
+package evictnotify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/internal/cache"
+)
+
+// webhook is an httptest sink recording the batches it accepted. While fail is
+// above zero it answers 503 and decrements it; failing forever answers 503 to
+// every request.
+type webhook struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	batches  [][]cache.EvictionEvent
+	requests int
+	fail     int
+	failing  bool
+	block    chan struct{} // when set, requests wait for it to be closed
+}
+
+func newWebhook(t *testing.T) *webhook {
+	w := &webhook{}
+	w.Server = httptest.NewServer(http.HandlerFunc(w.serve))
+	t.Cleanup(w.Close)
+	return w
+}
+
+func (w *webhook) serve(rw http.ResponseWriter, r *http.Request) {
+	w.mu.Lock()
+	block := w.block
+	w.mu.Unlock()
+	if block != nil {
+		select {
+		case <-block:
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	var p Payload
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.requests++
+	if w.failing || w.fail > 0 {
+		w.fail--
+		http.Error(rw, "origin busy", http.StatusServiceUnavailable)
+		return
+	}
+	w.batches = append(w.batches, p.Events)
+}
+
+// received returns the sizes of the accepted batches and the number of events in them
+func (w *webhook) received() (sizes []int, events int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, b := range w.batches {
+		sizes = append(sizes, len(b))
+		events += len(b)
+	}
+	return sizes, events
+}
+
+func (w *webhook) requestCount() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.requests
+}
+
+func event(i int) cache.EvictionEvent {
+	return cache.EvictionEvent{Group: "scores", Key: fmt.Sprintf("key-%d", i), Reason: "capacity", Time: time.Unix(int64(i), 0).UTC()}
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func closeBatcher(t *testing.T, b *Batcher) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := b.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestBatching sends a burst larger than the maximum batch: full batches go out
+// at once, the rest after the window
+func TestBatching(t *testing.T) {
+	hook := newWebhook(t)
+	b := New(NewHTTPSink(hook.URL), WithMaxBatch(4), WithWindow(100*time.Millisecond))
+	defer closeBatcher(t, b)
+
+	for i := 0; i < 10; i++ {
+		b.Notify(event(i))
+	}
+	waitFor(t, "the full batches", func() bool { _, n := hook.received(); return n >= 8 })
+	if sizes, _ := hook.received(); len(sizes) != 2 || sizes[0] != 4 || sizes[1] != 4 {
+		t.Fatalf("batches %v before the window ended, want [4 4]", sizes)
+	}
+	// The sink records a batch before deliver counts it, so wait for the counters
+	waitFor(t, "the last batch", func() bool { st := b.Stats(); return st.Sent == 10 && st.Batches == 3 })
+	if _, n := hook.received(); n != 10 {
+		t.Fatalf("sink received %d events, want 10", n)
+	}
+	if st := b.Stats(); st.Sent != 10 || st.Batches != 3 || st.Dropped != 0 || st.Notified != 10 {
+		t.Fatalf("stats = %+v", st)
+	}
+
+	// The events arrive as they were notified
+	hook.mu.Lock()
+	got := hook.batches[0][1]
+	hook.mu.Unlock()
+	if want := event(1); got != want {
+		t.Fatalf("event = %+v, want %+v", got, want)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	hook := newWebhook(t)
+	hook.fail = 2
+	b := New(NewHTTPSink(hook.URL), WithWindow(10*time.Millisecond), WithRetry(3, 10*time.Millisecond))
+	defer closeBatcher(t, b)
+
+	b.Notify(event(1))
+	b.Notify(event(2))
+	waitFor(t, "the batch to land", func() bool { st := b.Stats(); return st.Sent == 2 && st.Batches == 1 })
+	st := b.Stats()
+	if st.Retries != 2 || st.Batches != 1 || st.Dropped != 0 || st.LastError != "" {
+		t.Fatalf("stats = %+v", st)
+	}
+	if hook.requestCount() != 3 {
+		t.Fatalf("%d requests, want 3", hook.requestCount())
+	}
+}
+
+// TestDropAccounting checks that every notified event is counted once, as sent
+// or dropped
+func TestDropAccounting(t *testing.T) {
+	t.Run("attempts exhausted", func(t *testing.T) {
+		hook := newWebhook(t)
+		hook.failing = true
+		b := New(NewHTTPSink(hook.URL), WithWindow(10*time.Millisecond), WithRetry(2, 10*time.Millisecond))
+		defer closeBatcher(t, b)
+
+		for i := 0; i < 3; i++ {
+			b.Notify(event(i))
+		}
+		waitFor(t, "the batch to be dropped", func() bool { return b.Stats().Dropped == 3 })
+		st := b.Stats()
+		if st.Sent != 0 || st.Retries != 1 || st.LastError == "" {
+			t.Fatalf("stats = %+v", st)
+		}
+
+		// The sink recovers: later events go out, the dropped ones are not resent
+		hook.mu.Lock()
+		hook.failing = false
+		hook.mu.Unlock()
+		b.Notify(event(3))
+		waitFor(t, "the next batch", func() bool { st := b.Stats(); return st.Sent == 1 && st.LastError == "" })
+		if st := b.Stats(); st.Dropped != 3 || st.LastError != "" {
+			t.Fatalf("stats after recovery = %+v", st)
+		}
+	})
+
+	t.Run("queue full", func(t *testing.T) {
+		hook := newWebhook(t)
+		hook.block = make(chan struct{})
+		b := New(NewHTTPSink(hook.URL), WithQueueSize(2), WithMaxBatch(1), WithWindow(time.Millisecond))
+
+		// The first event is being sent and blocks, two more fill the queue
+		b.Notify(event(0))
+		waitFor(t, "the first send", func() bool { return b.Stats().Depth == 0 })
+		for i := 1; i <= 5; i++ {
+			b.Notify(event(i))
+		}
+		if st := b.Stats(); st.Dropped != 3 || st.Depth != 2 {
+			t.Fatalf("stats with a full queue = %+v", st)
+		}
+		close(hook.block)
+		closeBatcher(t, b)
+		if st := b.Stats(); st.Sent != 3 || st.Dropped != 3 || st.Notified != 6 {
+			t.Fatalf("stats = %+v", st)
+		}
+	})
+}
+
+// TestCloseFlushes sends the queued events at Close without waiting for the window
+func TestCloseFlushes(t *testing.T) {
+	hook := newWebhook(t)
+	b := New(NewHTTPSink(hook.URL), WithMaxBatch(3), WithWindow(time.Hour))
+	for i := 0; i < 7; i++ {
+		b.Notify(event(i))
+	}
+	start := time.Now()
+	closeBatcher(t, b)
+	if time.Since(start) > time.Minute {
+		t.Fatal("Close waited for the window")
+	}
+	if _, n := hook.received(); n != 7 {
+		t.Fatalf("sink received %d events, want 7", n)
+	}
+
+	// Events notified after Close are dropped
+	b.Notify(event(8))
+	if st := b.Stats(); st.Sent != 7 || st.Dropped != 1 || st.Notified != 8 {
+		t.Fatalf("stats = %+v", st)
+	}
+}
+
+// TestCloseTimeout gives up on a sink that does not answer: Close returns the
+// context's error and the events in flight are dropped
+func TestCloseTimeout(t *testing.T) {
+	hook := newWebhook(t)
+	hook.block = make(chan struct{})
+	defer close(hook.block)
+	b := New(NewHTTPSink(hook.URL), WithWindow(time.Millisecond), WithSendTimeout(time.Minute))
+	b.Notify(event(0))
+	b.Notify(event(1))
+	waitFor(t, "the send", func() bool { return b.Stats().Depth == 0 })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := b.Close(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("Close = %v, want context.DeadlineExceeded", err)
+	}
+	if st := b.Stats(); st.Sent != 0 || st.Dropped != 2 {
+		t.Fatalf("stats = %+v", st)
+	}
+}
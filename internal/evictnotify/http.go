@@ -0,0 +1,87 @@
+package evictnotify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/AdrianWangs/go-cache/internal/cache"
+)
+
+// maxErrorBody is how much of an error response HTTPSink includes in its error
+const maxErrorBody = 512
+
+// Payload is the JSON body HTTPSink posts
+type Payload struct {
+	Events []cache.EvictionEvent `json:"events"`
+}
+
+// HTTPSink posts each batch as a JSON Payload to a webhook. Any 2xx status is
+// success; other statuses and transport errors fail the send, so the batch is
+// retried.
+type HTTPSink struct {
+	url    string
+	client *http.Client
+	header http.Header
+}
+
+// HTTPOption configures an HTTPSink
+type HTTPOption func(*HTTPSink)
+
+// WithHTTPClient sends with client instead of http.DefaultClient; the Batcher
+// bounds every send with its own timeout either way
+func WithHTTPClient(client *http.Client) HTTPOption {
+	return func(s *HTTPSink) {
+		if client != nil {
+			s.client = client
+		}
+	}
+}
+
+// WithHeader adds a header to every request, for example Authorization
+func WithHeader(key, value string) HTTPOption {
+	return func(s *HTTPSink) {
+		s.header.Add(key, value)
+	}
+}
+
+// NewHTTPSink creates a sink posting batches to url
+func NewHTTPSink(url string, opts ...HTTPOption) *HTTPSink {
+	s := &HTTPSink{url: url, client: http.DefaultClient, header: make(http.Header)}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Send implements Sink
+func (s *HTTPSink) Send(ctx context.Context, events []cache.EvictionEvent) error {
+	body, err := json.Marshal(Payload{Events: events})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for key, values := range s.header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return fmt.Errorf("webhook returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	io.Copy(io.Discard, resp.Body)
+	return nil
+}
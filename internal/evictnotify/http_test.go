@@ -0,0 +1,48 @@
+package evictnotify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/AdrianWangs/go-cache/internal/cache"
+)
+
+func TestHTTPSink(t *testing.T) {
+	var body map[string][]map[string]any
+	var header http.Header
+	status := http.StatusNoContent
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+		data, _ := io.ReadAll(r.Body)
+		json.Unmarshal(data, &body)
+		if status != http.StatusNoContent {
+			http.Error(w, "no room", status)
+			return
+		}
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	sink := NewHTTPSink(srv.URL, WithHeader("Authorization", "Bearer secret"))
+	events := []cache.EvictionEvent{event(1), {Group: "scores", Reason: cache.EvictionCleared}}
+	if err := sink.Send(context.Background(), events); err != nil {
+		t.Fatal(err)
+	}
+	if header.Get("Authorization") != "Bearer secret" || header.Get("Content-Type") != "application/json" {
+		t.Fatalf("headers = %v", header)
+	}
+	got := body["events"]
+	if len(got) != 2 || got[0]["key"] != "key-1" || got[0]["reason"] != "capacity" || got[0]["timestamp"] == nil || got[1]["reason"] != "cleared" {
+		t.Fatalf("body = %v", body)
+	}
+
+	status = http.StatusInsufficientStorage
+	if err := sink.Send(context.Background(), events); err == nil || !strings.Contains(err.Error(), "no room") {
+		t.Fatalf("Send to a failing webhook = %v, want the status and body", err)
+	}
+}
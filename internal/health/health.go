@@ -0,0 +1,162 @@
+// Package health 提供缓存节点与 API 服务器共用的健康检查与就绪检查。
+// 每个子组件注册一个检查函数，/health 汇总所有检查的结果，关键组件不可用时返回 503；
+// /ready 在启动完成（例如首次同步节点列表）之前返回 503，用于启动期间的流量控制
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+)
+
+// Status 组件或整体的健康状态
+type Status string
+
+const (
+	// StatusOK 组件正常
+	StatusOK Status = "ok"
+	// StatusDegraded 组件异常但仍可提供服务，不影响 HTTP 状态码
+	StatusDegraded Status = "degraded"
+	// StatusDown 组件不可用；关键组件不可用时 /health 返回 503
+	StatusDown Status = "down"
+)
+
+// Result 一次组件检查的结果
+type Result struct {
+	Status  Status                 `json:"status"`
+	Message string                 `json:"message,omitempty"` // 异常原因
+	Details map[string]interface{} `json:"details,omitempty"` // 组件的附加信息
+}
+
+// OK 返回正常结果
+func OK(details map[string]interface{}) Result {
+	return Result{Status: StatusOK, Details: details}
+}
+
+// Degraded 返回降级结果
+func Degraded(message string, details map[string]interface{}) Result {
+	return Result{Status: StatusDegraded, Message: message, Details: details}
+}
+
+// Down 返回不可用结果
+func Down(message string, details map[string]interface{}) Result {
+	return Result{Status: StatusDown, Message: message, Details: details}
+}
+
+// ComponentResult 汇总报告中的单个组件
+type ComponentResult struct {
+	Result
+	Critical bool `json:"critical"` // 是否为关键组件
+}
+
+// Report 健康检查报告
+type Report struct {
+	Status     Status                     `json:"status"`     // 整体状态
+	Ready      bool                       `json:"ready"`      // 是否已完成启动
+	Components map[string]ComponentResult `json:"components"` // 各组件的检查结果
+}
+
+// check 注册的组件检查
+type check struct {
+	name     string
+	critical bool
+	fn       func() Result
+}
+
+// Checker 汇总各组件的健康状态
+type Checker struct {
+	mu     sync.RWMutex
+	checks []check
+	ready  []func() bool
+}
+
+// NewChecker 创建 Checker
+func NewChecker() *Checker {
+	return &Checker{}
+}
+
+// Add 注册一个组件检查。critical 组件返回 StatusDown 时整体状态为 down，/health 返回 503；
+// 非关键组件的 StatusDown 只使整体状态降级
+func (c *Checker) Add(name string, critical bool, fn func() Result) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks = append(c.checks, check{name: name, critical: critical, fn: fn})
+}
+
+// AddReady 注册一个就绪条件，所有条件满足后 /ready 才返回 200。
+// 条件一般只在启动阶段从 false 变为 true，例如首次成功同步节点列表
+func (c *Checker) AddReady(fn func() bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ready = append(c.ready, fn)
+}
+
+// Ready 报告所有就绪条件是否都已满足
+func (c *Checker) Ready() bool {
+	c.mu.RLock()
+	ready := c.ready
+	c.mu.RUnlock()
+
+	for _, fn := range ready {
+		if !fn() {
+			return false
+		}
+	}
+	return true
+}
+
+// Report 执行所有检查并汇总结果
+func (c *Checker) Report() Report {
+	c.mu.RLock()
+	checks := c.checks
+	c.mu.RUnlock()
+
+	report := Report{
+		Status:     StatusOK,
+		Ready:      c.Ready(),
+		Components: make(map[string]ComponentResult, len(checks)),
+	}
+	for _, chk := range checks {
+		res := chk.fn()
+		report.Components[chk.name] = ComponentResult{Result: res, Critical: chk.critical}
+
+		switch {
+		case res.Status == StatusDown && chk.critical:
+			report.Status = StatusDown
+		case res.Status != StatusOK && report.Status == StatusOK:
+			report.Status = StatusDegraded
+		}
+	}
+	return report
+}
+
+// HealthHandler 处理 /health：以 JSON 返回报告，整体状态为 down 时返回 503
+func (c *Checker) HealthHandler(w http.ResponseWriter, r *http.Request) {
+	report := c.Report()
+	code := http.StatusOK
+	if report.Status == StatusDown {
+		code = http.StatusServiceUnavailable
+	}
+	writeJSON(w, code, report)
+}
+
+// ReadyHandler 处理 /ready：启动完成且没有关键组件不可用时返回 200，否则返回 503
+func (c *Checker) ReadyHandler(w http.ResponseWriter, r *http.Request) {
+	report := c.Report()
+	code := http.StatusOK
+	if !report.Ready || report.Status == StatusDown {
+		code = http.StatusServiceUnavailable
+	}
+	writeJSON(w, code, struct {
+		Ready  bool   `json:"ready"`
+		Status Status `json:"status"`
+	}{report.Ready, report.Status})
+}
+
+// writeJSON 以 JSON 写出响应
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,96 @@
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestReportStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		critical bool
+		result   Result
+		want     Status
+	}{
+		{"关键组件正常", true, OK(nil), StatusOK},
+		{"关键组件降级", true, Degraded("slow", nil), StatusDegraded},
+		{"关键组件不可用", true, Down("gone", nil), StatusDown},
+		{"非关键组件不可用", false, Down("gone", nil), StatusDegraded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewChecker()
+			c.Add("base", true, func() Result { return OK(nil) })
+			c.Add("component", tt.critical, func() Result { return tt.result })
+			report := c.Report()
+			if report.Status != tt.want {
+				t.Fatalf("Status = %s, want %s", report.Status, tt.want)
+			}
+			if got := report.Components["component"]; got.Status != tt.result.Status || got.Critical != tt.critical {
+				t.Fatalf("component = %+v", got)
+			}
+		})
+	}
+
+	// 关键组件不可用时，之后的降级不会覆盖整体状态
+	c := NewChecker()
+	c.Add("a", true, func() Result { return Down("gone", nil) })
+	c.Add("b", false, func() Result { return Degraded("slow", nil) })
+	if got := c.Report().Status; got != StatusDown {
+		t.Fatalf("Status = %s, want down", got)
+	}
+}
+
+// serve 请求 handler 并解析 JSON 响应
+func serve(t *testing.T, handler http.HandlerFunc, v interface{}) int {
+	t.Helper()
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+		t.Fatalf("%v: %s", err, w.Body)
+	}
+	if w.Header().Get("Cache-Control") != "no-store" {
+		t.Fatalf("Cache-Control = %q", w.Header().Get("Cache-Control"))
+	}
+	return w.Code
+}
+
+func TestHandlers(t *testing.T) {
+	c := NewChecker()
+	status := OK(nil)
+	ready := false
+	c.Add("component", true, func() Result { return status })
+	c.AddReady(func() bool { return ready })
+
+	var report Report
+	if code := serve(t, c.HealthHandler, &report); code != http.StatusOK || report.Ready {
+		t.Fatalf("启动期间 /health = %d %+v", code, report)
+	}
+	var r struct{ Ready bool }
+	if code := serve(t, c.ReadyHandler, &r); code != http.StatusServiceUnavailable || r.Ready {
+		t.Fatalf("启动期间 /ready = %d %+v", code, r)
+	}
+
+	ready = true
+	if code := serve(t, c.ReadyHandler, &r); code != http.StatusOK || !r.Ready {
+		t.Fatalf("就绪后 /ready = %d %+v", code, r)
+	}
+
+	status = Degraded("slow", map[string]interface{}{"lag": "10s"})
+	if code := serve(t, c.HealthHandler, &report); code != http.StatusOK || report.Status != StatusDegraded {
+		t.Fatalf("降级时 /health = %d %+v", code, report)
+	}
+	if got := report.Components["component"]; got.Message != "slow" || got.Details["lag"] != "10s" {
+		t.Fatalf("组件详情 = %+v", got)
+	}
+
+	status = Down("gone", nil)
+	if code := serve(t, c.HealthHandler, &report); code != http.StatusServiceUnavailable || report.Status != StatusDown {
+		t.Fatalf("不可用时 /health = %d %+v", code, report)
+	}
+	if code := serve(t, c.ReadyHandler, &r); code != http.StatusServiceUnavailable {
+		t.Fatalf("关键组件不可用时 /ready = %d", code)
+	}
+}
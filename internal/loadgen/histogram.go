@@ -0,0 +1,138 @@
+package loadgen
+
+import (
+	"math"
+	"math/bits"
+	"time"
+)
+
+const (
+	subBucketBits = 5                  // 每个 2 的幂区间再分为 32 个子桶，相对误差约 3%
+	subBuckets    = 1 << subBucketBits // 每个区间的子桶数
+	maxExponent   = 64 - subBucketBits // 区间数
+	bucketCount   = (maxExponent + 1) * subBuckets
+)
+
+// Histogram 记录延迟分布，内存占用固定，分位数的相对误差约 3%。
+// 不是并发安全的，每个 goroutine 记录到自己的 Histogram，结束后用 Merge 合并
+type Histogram struct {
+	counts [bucketCount]uint64
+	count  uint64
+	sum    time.Duration
+	min    time.Duration
+	max    time.Duration
+}
+
+// NewHistogram 创建空的 Histogram
+func NewHistogram() *Histogram {
+	return &Histogram{min: math.MaxInt64}
+}
+
+// bucketOf 返回纳秒值 v 所在的桶
+func bucketOf(v uint64) int {
+	if v < subBuckets {
+		return int(v)
+	}
+	exp := bits.Len64(v) - subBucketBits // 右移 exp 位后落在 [subBuckets/2, subBuckets)
+	return exp*subBuckets + int(v>>uint(exp))
+}
+
+// bucketUpper 返回桶内的最大纳秒值
+func bucketUpper(b int) uint64 {
+	if b < subBuckets {
+		return uint64(b)
+	}
+	exp := b / subBuckets
+	sub := uint64(b % subBuckets)
+	if exp == 0 {
+		return sub
+	}
+	return (sub+1)<<uint(exp) - 1
+}
+
+// Record 记录一次耗时
+func (h *Histogram) Record(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	h.counts[bucketOf(uint64(d))]++
+	h.count++
+	h.sum += d
+	if d < h.min {
+		h.min = d
+	}
+	if d > h.max {
+		h.max = d
+	}
+}
+
+// Merge 将 other 的记录合并进 h
+func (h *Histogram) Merge(other *Histogram) {
+	for i, c := range other.counts {
+		h.counts[i] += c
+	}
+	h.count += other.count
+	h.sum += other.sum
+	if other.min < h.min {
+		h.min = other.min
+	}
+	if other.max > h.max {
+		h.max = other.max
+	}
+}
+
+// Count 返回记录次数
+func (h *Histogram) Count() uint64 {
+	return h.count
+}
+
+// Mean 返回平均耗时
+func (h *Histogram) Mean() time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+	return h.sum / time.Duration(h.count)
+}
+
+// Min 返回最小耗时
+func (h *Histogram) Min() time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+	return h.min
+}
+
+// Max 返回最大耗时
+func (h *Histogram) Max() time.Duration {
+	return h.max
+}
+
+// Percentile 返回第 p 百分位 (0-100) 的耗时，结果为所在桶的上界，不超过最大值
+func (h *Histogram) Percentile(p float64) time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+	if p <= 0 {
+		return h.Min()
+	}
+	rank := uint64(math.Ceil(p / 100 * float64(h.count)))
+	if rank > h.count {
+		rank = h.count
+	}
+
+	var seen uint64
+	for b, c := range h.counts {
+		seen += c
+		if seen >= rank {
+			v := time.Duration(bucketUpper(b))
+			if v > h.max {
+				v = h.max
+			}
+			if v < h.min {
+				v = h.min
+			}
+			return v
+		}
+	}
+	return h.max
+}
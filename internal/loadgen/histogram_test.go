@@ -0,0 +1,76 @@
+package loadgen
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// TestBucketBounds 每个值都不超过所在桶的上界，上界与值的相对误差不超过 1/16
+func TestBucketBounds(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	values := []uint64{0, 1, 31, 32, 33, 63, 64, 1000, 1 << 40, 1<<63 - 1, 1<<64 - 1}
+	for i := 0; i < 10000; i++ {
+		values = append(values, r.Uint64()>>uint(r.Intn(64)))
+	}
+	for _, v := range values {
+		b := bucketOf(v)
+		if b < 0 || b >= bucketCount {
+			t.Fatalf("%d 落在桶 %d 之外", v, b)
+		}
+		upper := bucketUpper(b)
+		if upper < v || float64(upper-v) > float64(v)/16 {
+			t.Fatalf("%d 所在桶 %d 的上界为 %d", v, b, upper)
+		}
+	}
+}
+
+func TestHistogramPercentiles(t *testing.T) {
+	h := NewHistogram()
+	if h.Count() != 0 || h.Percentile(50) != 0 || h.Mean() != 0 || h.Min() != 0 || h.Max() != 0 {
+		t.Fatal("空的 Histogram 应全部返回 0")
+	}
+
+	// 1µs 到 10ms，打乱顺序记录
+	const n = 10000
+	for _, i := range rand.New(rand.NewSource(1)).Perm(n) {
+		h.Record(time.Duration(i+1) * time.Microsecond)
+	}
+	h.Record(-time.Second) // 负值按 0 记录
+	if h.Count() != n+1 || h.Min() != 0 || h.Max() != 10*time.Millisecond {
+		t.Fatalf("count=%d min=%v max=%v", h.Count(), h.Min(), h.Max())
+	}
+	if mean := h.Mean(); mean < 4990*time.Microsecond || mean > 5010*time.Microsecond {
+		t.Fatalf("平均值 = %v", mean)
+	}
+	for _, p := range []float64{50, 90, 99, 99.9} {
+		want := time.Duration(p/100*(n+1)) * time.Microsecond
+		got := h.Percentile(p)
+		if got < want-want/32 || got > want+want/16 {
+			t.Errorf("p%v = %v, want 约 %v", p, got, want)
+		}
+	}
+	if h.Percentile(100) != h.Max() || h.Percentile(200) != h.Max() || h.Percentile(0) != h.Min() {
+		t.Fatalf("p100=%v p0=%v", h.Percentile(100), h.Percentile(0))
+	}
+}
+
+// TestHistogramMerge 各 goroutine 分别记录后合并，与记录到同一个 Histogram 相同
+func TestHistogramMerge(t *testing.T) {
+	whole, parts := NewHistogram(), []*Histogram{NewHistogram(), NewHistogram(), NewHistogram()}
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 3000; i++ {
+		d := time.Duration(r.Int63n(int64(time.Second)))
+		whole.Record(d)
+		parts[i%3].Record(d)
+	}
+	merged := NewHistogram()
+	merged.Merge(NewHistogram()) // 合并空的 Histogram 不改变最小值
+	for _, p := range parts {
+		merged.Merge(p)
+	}
+	if *merged != *whole {
+		t.Fatalf("合并结果 count=%d min=%v max=%v, want count=%d min=%v max=%v",
+			merged.Count(), merged.Min(), merged.Max(), whole.Count(), whole.Min(), whole.Max())
+	}
+}
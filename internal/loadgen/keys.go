@@ -0,0 +1,75 @@
+// Package loadgen 提供压测和测试共用的负载生成工具：按均匀或 Zipf 分布生成 key，
+// 以及记录延迟分布的直方图
+package loadgen
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// 支持的 key 分布
+const (
+	DistUniform = "uniform" // 每个 key 被选中的概率相同
+	DistZipfian = "zipfian" // 少数 key 被频繁访问，模拟热点
+)
+
+// defaultZipfS Zipf 分布默认的指数，越大热点越集中
+const defaultZipfS = 1.1
+
+// KeyGen 生成 [0, n) 范围内的 key 序号。实现不是并发安全的，每个 goroutine 应使用自己的 KeyGen
+type KeyGen interface {
+	Next() uint64
+}
+
+// uniform 均匀分布
+type uniform struct {
+	r *rand.Rand
+	n uint64
+}
+
+// Next 实现 KeyGen
+func (u *uniform) Next() uint64 {
+	return uint64(u.r.Int63n(int64(u.n)))
+}
+
+// NewUniform 创建在 [0, n) 内均匀分布的 KeyGen
+func NewUniform(n uint64, seed int64) KeyGen {
+	if n == 0 {
+		n = 1
+	}
+	return &uniform{r: rand.New(rand.NewSource(seed)), n: n}
+}
+
+// zipf Zipf 分布，序号越小被选中的概率越大
+type zipf struct {
+	z *rand.Zipf
+}
+
+// Next 实现 KeyGen
+func (z *zipf) Next() uint64 {
+	return z.z.Uint64()
+}
+
+// NewZipf 创建在 [0, n) 内服从指数为 s 的 Zipf 分布的 KeyGen，s 必须大于 1，<=1 时使用 1.1
+func NewZipf(n uint64, s float64, seed int64) KeyGen {
+	if n == 0 {
+		n = 1
+	}
+	if s <= 1 {
+		s = defaultZipfS
+	}
+	r := rand.New(rand.NewSource(seed))
+	return &zipf{z: rand.NewZipf(r, s, 1, n-1)}
+}
+
+// NewKeyGen 按分布名称创建 KeyGen，s 只对 Zipf 分布有效
+func NewKeyGen(dist string, n uint64, s float64, seed int64) (KeyGen, error) {
+	switch dist {
+	case DistUniform, "":
+		return NewUniform(n, seed), nil
+	case DistZipfian, "zipf":
+		return NewZipf(n, s, seed), nil
+	default:
+		return nil, fmt.Errorf("不支持的 key 分布 %q，只能是 %s 或 %s", dist, DistUniform, DistZipfian)
+	}
+}
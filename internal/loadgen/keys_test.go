@@ -0,0 +1,66 @@
+package loadgen
+
+import (
+	"testing"
+)
+
+func TestKeyGenRange(t *testing.T) {
+	for _, dist := range []string{DistUniform, DistZipfian} {
+		for _, n := range []uint64{0, 1, 7, 1000} {
+			g, err := NewKeyGen(dist, n, 0, 42)
+			if err != nil {
+				t.Fatal(err)
+			}
+			again, _ := NewKeyGen(dist, n, 0, 42)
+			limit := max(n, 1)
+			for i := 0; i < 10000; i++ {
+				k := g.Next()
+				if k >= limit {
+					t.Fatalf("%s n=%d: 序号 %d 超出范围", dist, n, k)
+				}
+				if k2 := again.Next(); k2 != k {
+					t.Fatalf("%s n=%d: 相同种子的第 %d 个序号 %d != %d", dist, n, i, k, k2)
+				}
+			}
+		}
+	}
+}
+
+// TestZipfSkew Zipf 分布的访问集中在少数序号小的 key 上，均匀分布不集中
+func TestZipfSkew(t *testing.T) {
+	const n, draws = 10000, 200000
+	top := func(g KeyGen) float64 {
+		hits := 0
+		for i := 0; i < draws; i++ {
+			if g.Next() < n/100 {
+				hits++
+			}
+		}
+		return float64(hits) / draws
+	}
+	if share := top(NewZipf(n, 1.1, 1)); share < 0.5 {
+		t.Fatalf("Zipf 分布前 1%% 的 key 只占 %.1f%% 的访问", share*100)
+	}
+	if share := top(NewUniform(n, 1)); share < 0.005 || share > 0.015 {
+		t.Fatalf("均匀分布前 1%% 的 key 占 %.1f%% 的访问", share*100)
+	}
+
+	// s <= 1 时使用默认指数，与 1.1 相同
+	a, b := NewZipf(n, 0.5, 3), NewZipf(n, defaultZipfS, 3)
+	for i := 0; i < 100; i++ {
+		if a.Next() != b.Next() {
+			t.Fatal("s <= 1 时没有使用默认指数")
+		}
+	}
+}
+
+func TestNewKeyGen(t *testing.T) {
+	for _, dist := range []string{"", "uniform", "zipf", "zipfian"} {
+		if _, err := NewKeyGen(dist, 10, 0, 1); err != nil {
+			t.Errorf("NewKeyGen(%q) = %v", dist, err)
+		}
+	}
+	if _, err := NewKeyGen("gaussian", 10, 0, 1); err == nil {
+		t.Error("不支持的分布没有返回错误")
+	}
+}
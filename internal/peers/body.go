@@ -0,0 +1,47 @@
+package peers
+
+import (
+	"io"
+	"strings"
+	"sync"
+)
+
+// MaxErrorBody is how much of a failure response the getters read for the error
+// message. A peer or proxy answering with a large error page during an outage
+// then costs a bounded amount per failed request.
+const MaxErrorBody = 4 << 10
+
+// maxDrain is how much of an unread response CloseBody discards so that the
+// connection goes back to the pool; a longer body closes the connection instead
+const maxDrain = 64 << 10
+
+// truncatedSuffix marks an error message cut at MaxErrorBody
+const truncatedSuffix = " ...(truncated)"
+
+var errorBodyBufs = sync.Pool{New: func() any { return new([MaxErrorBody]byte) }}
+
+// ErrorBody reads at most MaxErrorBody bytes of a failure response for an error
+// message, trimmed of surrounding space, with a marker appended when the body
+// was longer. Read errors end the message early and are otherwise ignored.
+func ErrorBody(body io.Reader) string {
+	buf := errorBodyBufs.Get().(*[MaxErrorBody]byte)
+	defer errorBodyBufs.Put(buf)
+
+	n, _ := io.ReadFull(body, buf[:])
+	msg := strings.TrimSpace(string(buf[:n]))
+	if n == len(buf) {
+		var more [1]byte
+		if m, _ := io.ReadFull(body, more[:]); m > 0 {
+			msg += truncatedSuffix
+		}
+	}
+	return msg
+}
+
+// CloseBody discards what is left of a response body, up to a limit, and
+// closes it. Getters defer it instead of Body.Close so that responses they did
+// not read to the end, such as error pages, do not cost a new connection.
+func CloseBody(body io.ReadCloser) {
+	io.CopyN(io.Discard, body, maxDrain)
+	body.Close()
+}
@@ -0,0 +1,82 @@
+package peers
+
+import (
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// endless is a body of the byte 'x' that never ends and allocates nothing per read
+type endless struct{}
+
+func (endless) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 'x'
+	}
+	return len(p), nil
+}
+
+func TestErrorBody(t *testing.T) {
+	full := strings.Repeat("x", MaxErrorBody)
+	tests := []struct {
+		body string
+		want string
+	}{
+		{"", ""},
+		{"  key not found\n", "key not found"},
+		{full, full},
+		{full + "y", full + truncatedSuffix},
+		{full + strings.Repeat("y", 1<<20), full + truncatedSuffix},
+	}
+	for _, tt := range tests {
+		if got := ErrorBody(strings.NewReader(tt.body)); got != tt.want {
+			t.Errorf("ErrorBody(%d bytes) = %d bytes %q..., want %d bytes", len(tt.body), len(got), got[:min(len(got), 20)], len(tt.want))
+		}
+	}
+}
+
+// TestErrorBodyBoundedAllocation reads the message of a 10MB error body and
+// checks that memory use depends on MaxErrorBody, not on the body
+func TestErrorBodyBoundedAllocation(t *testing.T) {
+	ErrorBody(strings.NewReader("warm the buffer pool"))
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	msg := ErrorBody(io.LimitReader(endless{}, 10<<20))
+	runtime.ReadMemStats(&after)
+
+	if !strings.HasSuffix(msg, truncatedSuffix) {
+		t.Fatalf("message of a 10MB body is not marked as truncated")
+	}
+	if n := after.TotalAlloc - before.TotalAlloc; n > 4*MaxErrorBody {
+		t.Fatalf("reading a 10MB error body allocated %d bytes", n)
+	}
+}
+
+// countingBody records how much of it was read and whether it was closed
+type countingBody struct {
+	io.Reader
+	read   int64
+	closed bool
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.Reader.Read(p)
+	b.read += int64(n)
+	return n, err
+}
+
+func (b *countingBody) Close() error {
+	b.closed = true
+	return nil
+}
+
+func TestCloseBody(t *testing.T) {
+	for _, size := range []int64{0, 100, maxDrain, 10 << 20} {
+		b := &countingBody{Reader: io.LimitReader(endless{}, size)}
+		CloseBody(b)
+		if want := min(size, maxDrain); b.read != want || !b.closed {
+			t.Errorf("CloseBody of %d bytes read %d, closed %v; want %d read and closed", size, b.read, b.closed, want)
+		}
+	}
+}
@@ -0,0 +1,14 @@
+package peers
+
+import "net"
+
+// ClientHost returns the host of a request's remote address, which names the
+// client for per-client rate limits, see cache.WithClient. The address is used
+// rather than the From a forwarding node reports, which a client could change
+// to get a fresh bucket.
+func ClientHost(remoteAddr string) string {
+	if host, _, err := net.SplitHostPort(remoteAddr); err == nil {
+		return host
+	}
+	return remoteAddr
+}
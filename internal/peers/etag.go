@@ -0,0 +1,60 @@
+package peers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ValueDigest returns the hex digest ETags of a value are built from. It
+// depends on the bytes alone, not on versions or expiry times, which are
+// node-local: every replica holding the same value yields the same digest.
+func ValueDigest(value []byte) string {
+	sum := sha256.Sum256(value)
+	return hex.EncodeToString(sum[:16])
+}
+
+// ValueETag returns the strong ETag of a value served as raw bytes
+func ValueETag(value []byte) string {
+	return `"` + ValueDigest(value) + `"`
+}
+
+// ETagMatches reports whether an If-None-Match header lists etag. It accepts a
+// comma-separated list and "*", and compares weakly, ignoring the W/ prefix on
+// either side, as RFC 9110 prescribes for If-None-Match.
+func ETagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// ServeValue writes value as raw bytes with its ETag and reports whether the
+// request's If-None-Match matched, in which case the response is a 304 with no
+// body. Otherwise Range, If-Range and HEAD requests are served by
+// http.ServeContent, so a range is honoured only while the client's validator
+// is still the current ETag. Headers set before the call, such as the metadata
+// headers, are sent with either response.
+func ServeValue(w http.ResponseWriter, r *http.Request, value []byte) (notModified bool) {
+	etag := ValueETag(value)
+	w.Header().Set("ETag", etag)
+	if ETagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return true
+	}
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "application/octet-stream")
+	}
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(value))
+	return false
+}
@@ -0,0 +1,117 @@
+package peers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestETagMatches(t *testing.T) {
+	etag := ValueETag([]byte("v1"))
+	other := ValueETag([]byte("v2"))
+	tests := []struct {
+		ifNoneMatch string
+		etag        string
+		want        bool
+	}{
+		{"", etag, false},
+		{etag, etag, true},
+		{other, etag, false},
+		{"*", etag, true},
+		{other + ", " + etag, etag, true},
+		{other + ",*", etag, true},
+		{"W/" + etag, etag, true},
+		{etag, "W/" + etag, true},
+		{" W/" + etag + " ", "W/" + etag, true},
+		{`"` + ValueDigest([]byte("v1")), etag, false},
+	}
+	for _, tt := range tests {
+		if got := ETagMatches(tt.ifNoneMatch, tt.etag); got != tt.want {
+			t.Errorf("ETagMatches(%q, %q) = %v, want %v", tt.ifNoneMatch, tt.etag, got, tt.want)
+		}
+	}
+}
+
+func TestValueETagDependsOnBytesOnly(t *testing.T) {
+	if ValueETag([]byte("v")) != ValueETag([]byte("v")) {
+		t.Fatal("equal values have different ETags")
+	}
+	if ValueETag([]byte("v")) == ValueETag([]byte("w")) {
+		t.Fatal("different values share an ETag")
+	}
+	if ValueETag(nil) != ValueETag([]byte{}) {
+		t.Fatal("nil and empty values have different ETags")
+	}
+}
+
+// serveValue serves value for a request carrying the given headers
+func serveValue(method string, value []byte, headers map[string]string) (*httptest.ResponseRecorder, bool) {
+	r := httptest.NewRequest(method, "/_go_cache/g/k", nil)
+	for name, v := range headers {
+		r.Header.Set(name, v)
+	}
+	w := httptest.NewRecorder()
+	w.Header().Set(HeaderSource, "cache")
+	notModified := ServeValue(w, r, value)
+	return w, notModified
+}
+
+func TestServeValue(t *testing.T) {
+	value := []byte("0123456789")
+	etag := ValueETag(value)
+	stale := ValueETag([]byte("old value"))
+	tests := []struct {
+		name        string
+		method      string
+		headers     map[string]string
+		status      int
+		body        string
+		notModified bool
+	}{
+		{"no validator", http.MethodGet, nil, http.StatusOK, "0123456789", false},
+		{"matching validator", http.MethodGet, map[string]string{"If-None-Match": etag}, http.StatusNotModified, "", true},
+		{"one of several validators", http.MethodGet, map[string]string{"If-None-Match": stale + ", " + etag}, http.StatusNotModified, "", true},
+		{"stale validator", http.MethodGet, map[string]string{"If-None-Match": stale}, http.StatusOK, "0123456789", false},
+		{"range", http.MethodGet, map[string]string{"Range": "bytes=2-4"}, http.StatusPartialContent, "234", false},
+		{"range with current If-Range", http.MethodGet, map[string]string{"Range": "bytes=2-4", "If-Range": etag}, http.StatusPartialContent, "234", false},
+		{"range with stale If-Range", http.MethodGet, map[string]string{"Range": "bytes=2-4", "If-Range": stale}, http.StatusOK, "0123456789", false},
+		{"unsatisfiable range", http.MethodGet, map[string]string{"Range": "bytes=20-30"}, http.StatusRequestedRangeNotSatisfiable, "", false},
+		{"head", http.MethodHead, nil, http.StatusOK, "", false},
+		{"head with matching validator", http.MethodHead, map[string]string{"If-None-Match": etag}, http.StatusNotModified, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, notModified := serveValue(tt.method, value, tt.headers)
+			if w.Code != tt.status || notModified != tt.notModified {
+				t.Fatalf("status = %d, notModified = %v, want %d, %v", w.Code, notModified, tt.status, tt.notModified)
+			}
+			if tt.status != http.StatusRequestedRangeNotSatisfiable && w.Body.String() != tt.body {
+				t.Fatalf("body = %q, want %q", w.Body.String(), tt.body)
+			}
+			if got := w.Header().Get("ETag"); got != etag {
+				t.Fatalf("ETag = %q, want %q", got, etag)
+			}
+			if got := w.Header().Get(HeaderSource); got != "cache" {
+				t.Fatalf("headers set before the call were dropped: %s = %q", HeaderSource, got)
+			}
+		})
+	}
+}
+
+// TestServeValueAfterChange replays a client's validator after the value
+// changed: the stale ETag no longer matches and the new value is served
+func TestServeValueAfterChange(t *testing.T) {
+	w, _ := serveValue(http.MethodGet, []byte("v1"), nil)
+	etag := w.Header().Get("ETag")
+	if w, notModified := serveValue(http.MethodGet, []byte("v1"), map[string]string{"If-None-Match": etag}); !notModified || w.Code != http.StatusNotModified {
+		t.Fatalf("revalidation of an unchanged value = %d", w.Code)
+	}
+
+	w, notModified := serveValue(http.MethodGet, []byte("v2"), map[string]string{"If-None-Match": etag})
+	if notModified || w.Code != http.StatusOK || w.Body.String() != "v2" {
+		t.Fatalf("revalidation after a change = %d %q", w.Code, w.Body.String())
+	}
+	if w.Header().Get("ETag") == etag {
+		t.Fatal("the ETag did not change with the value")
+	}
+}
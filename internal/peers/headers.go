@@ -0,0 +1,97 @@
+package peers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
+	"google.golang.org/protobuf/proto"
+)
+
+// Response headers of the plain HTTP peer protocol. They carry the metadata the
+// protobuf Response holds in its fields, so that both protocols deliver the same
+// information; a header is omitted when the corresponding field is unset.
+const (
+	// HeaderExpiresAt is the absolute expiry of the value in RFC 3339 with nanoseconds
+	HeaderExpiresAt = "X-GoCache-Expires-At"
+	// HeaderVersion is the decimal write sequence number of the value
+	HeaderVersion = "X-GoCache-Version"
+	// HeaderSource tells where the value was found: cache, loader or peer
+	HeaderSource = "X-GoCache-Source"
+	// HeaderReplicas is the number of nodes holding a replica of a hot key
+	HeaderReplicas = "X-GoCache-Replicas"
+	// HeaderReplicatedUntil is when the replicas expire, in RFC 3339 with nanoseconds
+	HeaderReplicatedUntil = "X-GoCache-Replicated-Until"
+	// HeaderErrorCode is set on error responses to one of the cache.ErrorCode values
+	HeaderErrorCode = "X-GoCache-Error-Code"
+	// HeaderNode is the ID of the node that produced the value: the node that
+	// found it in its cache or loaded it, not one that fetched it from a peer
+	HeaderNode = "X-GoCache-Node"
+	// HeaderRoutedBy is set by the API server to its own identity
+	HeaderRoutedBy = "X-GoCache-Routed-By"
+	// HeaderDeleteTargets is set by the API server on delete responses to the
+	// number of nodes the delete was sent to
+	HeaderDeleteTargets = "X-GoCache-Delete-Targets"
+	// HeaderDeleteAcked is the number of those nodes that confirmed the delete
+	HeaderDeleteAcked = "X-GoCache-Delete-Acked"
+)
+
+// WriteMetaHeaders sets the metadata headers for the fields set in resp.
+func WriteMetaHeaders(h http.Header, resp *pb.Response) {
+	if resp.ExpiresAt != nil {
+		h.Set(HeaderExpiresAt, time.Unix(0, resp.GetExpiresAt()).UTC().Format(time.RFC3339Nano))
+	}
+	if resp.Version != nil {
+		h.Set(HeaderVersion, strconv.FormatUint(resp.GetVersion(), 10))
+	}
+	if resp.Source != nil {
+		h.Set(HeaderSource, resp.GetSource())
+	}
+	if resp.Node != nil {
+		h.Set(HeaderNode, resp.GetNode())
+	}
+	if resp.GetReplicas() > 0 {
+		h.Set(HeaderReplicas, strconv.FormatUint(uint64(resp.GetReplicas()), 10))
+		h.Set(HeaderReplicatedUntil, time.Unix(0, resp.GetReplicatedUntil()).UTC().Format(time.RFC3339Nano))
+	}
+}
+
+// ReadMetaHeaders fills the metadata fields of resp from the headers present in h.
+// Responses from peers predating the headers leave the fields unset.
+func ReadMetaHeaders(h http.Header, resp *pb.Response) error {
+	if v := h.Get(HeaderExpiresAt); v != "" {
+		t, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return fmt.Errorf("invalid %s header %q: %w", HeaderExpiresAt, v, err)
+		}
+		resp.ExpiresAt = proto.Int64(t.UnixNano())
+	}
+	if v := h.Get(HeaderVersion); v != "" {
+		version, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid %s header %q: %w", HeaderVersion, v, err)
+		}
+		resp.Version = proto.Uint64(version)
+	}
+	if v := h.Get(HeaderSource); v != "" {
+		resp.Source = proto.String(v)
+	}
+	if v := h.Get(HeaderNode); v != "" {
+		resp.Node = proto.String(v)
+	}
+	if v := h.Get(HeaderReplicas); v != "" {
+		replicas, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			return fmt.Errorf("invalid %s header %q: %w", HeaderReplicas, v, err)
+		}
+		until, err := time.Parse(time.RFC3339Nano, h.Get(HeaderReplicatedUntil))
+		if err != nil {
+			return fmt.Errorf("invalid %s header %q: %w", HeaderReplicatedUntil, h.Get(HeaderReplicatedUntil), err)
+		}
+		resp.Replicas = proto.Uint32(uint32(replicas))
+		resp.ReplicatedUntil = proto.Int64(until.UnixNano())
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package peers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
+)
+
+// Request headers of the plain HTTP peer protocol carrying the forwarding state
+// the protobuf Request holds in its hops, from and ring_generation fields, and
+// its cache_only flag
+const (
+	// HeaderHops is the decimal number of times the request has been forwarded
+	HeaderHops = "X-GoCache-Hops"
+	// HeaderFrom is the ID of the node that forwarded the request
+	HeaderFrom = "X-GoCache-From"
+	// HeaderCacheOnly set to "1" asks for the receiver's cached value only,
+	// like the cache_only field of the protobuf Request
+	HeaderCacheOnly = "X-GoCache-Cache-Only"
+	// HeaderRingGeneration is the decimal generation of the forwarding node's
+	// ring, see consistenthash.Map.Generation. The API server also sets it on
+	// its responses to the generation of its routing ring.
+	HeaderRingGeneration = "X-GoCache-Ring-Generation"
+)
+
+// DefaultMaxHops is the number of forwards after which a node stops forwarding
+// and answers from its own cache or data source. With consistent rings a key is
+// forwarded at most once, from the receiving node to its owner, so a request
+// arriving with one hop that would be forwarded again means the rings disagree.
+const DefaultMaxHops = 1
+
+// Forwarding describes how a request reached the serving node
+type Forwarding struct {
+	Hops      int    // times the request was forwarded, 0 if it did not come from a peer
+	From      string // ID of the forwarding node, empty if unknown
+	Self      string // ID of the serving node
+	LocalOnly bool   // the forwarding budget is spent; do not forward again
+}
+
+type forwardingKey struct{}
+
+// WithForwarding returns a context carrying f. Servers attach it to requests from
+// peers so that the group's peer fetch can continue the count or stop forwarding.
+func WithForwarding(ctx context.Context, f Forwarding) context.Context {
+	return context.WithValue(ctx, forwardingKey{}, f)
+}
+
+// ForwardingFrom returns the forwarding state attached to ctx; requests that did
+// not arrive from a peer report the zero value
+func ForwardingFrom(ctx context.Context) Forwarding {
+	f, _ := ctx.Value(forwardingKey{}).(Forwarding)
+	return f
+}
+
+// NewForwarding builds the forwarding state of a received request. Requests with
+// maxHops or more hops are marked LocalOnly; maxHops <= 0 selects DefaultMaxHops.
+func NewForwarding(self string, hops uint32, from string, maxHops int) Forwarding {
+	if maxHops <= 0 {
+		maxHops = DefaultMaxHops
+	}
+	return Forwarding{
+		Hops:      int(hops),
+		From:      from,
+		Self:      self,
+		LocalOnly: int(hops) >= maxHops,
+	}
+}
+
+// WriteHopHeaders sets the forwarding headers for the fields set in req
+func WriteHopHeaders(h http.Header, req *pb.Request) {
+	if req.Hops != nil {
+		h.Set(HeaderHops, strconv.FormatUint(uint64(req.GetHops()), 10))
+	}
+	if req.From != nil {
+		h.Set(HeaderFrom, req.GetFrom())
+	}
+	if req.GetCacheOnly() {
+		h.Set(HeaderCacheOnly, "1")
+	}
+	if req.RingGeneration != nil {
+		h.Set(HeaderRingGeneration, strconv.FormatUint(req.GetRingGeneration(), 10))
+	}
+}
+
+// ReadCacheOnly reports whether h asks for a cache-only read
+func ReadCacheOnly(h http.Header) bool {
+	return h.Get(HeaderCacheOnly) == "1"
+}
+
+// ReadHopHeaders returns the hop count and forwarding node from h. Requests from
+// peers predating the headers, or with a malformed count, report zero hops.
+func ReadHopHeaders(h http.Header) (hops uint32, from string) {
+	if v := h.Get(HeaderHops); v != "" {
+		if n, err := strconv.ParseUint(v, 10, 32); err == nil {
+			hops = uint32(n)
+		}
+	}
+	return hops, h.Get(HeaderFrom)
+}
+
+// ReadRingGeneration returns the ring generation the forwarding node sent in h,
+// 0 if the header is absent or malformed
+func ReadRingGeneration(h http.Header) uint64 {
+	n, _ := strconv.ParseUint(h.Get(HeaderRingGeneration), 10, 64)
+	return n
+}
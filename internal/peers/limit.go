@@ -0,0 +1,96 @@
+package peers
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrPeerBusy is returned without contacting a peer that already has
+// Limit.MaxInFlight requests in flight. The peer is slow rather than the key
+// missing, so callers fail over to the next replica or the data source.
+var ErrPeerBusy = errors.New("peer busy: too many requests in flight")
+
+// Limit caps the requests in flight to one peer, so that a slow peer cannot tie
+// up an unbounded number of goroutines each waiting for the full timeout. The
+// zero value is unlimited.
+type Limit struct {
+	MaxInFlight int           // requests in flight at once, 0 means unlimited
+	QueueWait   time.Duration // how long a request over the cap waits for a slot, 0 fails it at once
+}
+
+// Acquire takes a slot for one request to the peer and returns the function
+// that gives it back once the request has finished; the time between the two is
+// recorded as the request's latency. Above limit.MaxInFlight
+// it waits up to limit.QueueWait for a slot and then fails with ErrPeerBusy,
+// or with ctx.Err() if ctx ends first. The in-flight count is kept with the
+// counters so it carries over getter replacement like the other stats; a nil
+// *Counters neither counts nor limits.
+func (c *Counters) Acquire(ctx context.Context, limit Limit) (release func(), err error) {
+	if c == nil {
+		return func() {}, nil
+	}
+
+	var timer *time.Timer
+	for {
+		n := c.inFlight.Load()
+		if limit.MaxInFlight <= 0 || n < int64(limit.MaxInFlight) {
+			if c.inFlight.CompareAndSwap(n, n+1) {
+				if timer != nil {
+					timer.Stop()
+				}
+				start := time.Now()
+				return func() {
+					c.completed.Add(1)
+					c.latency.Add(int64(time.Since(start)))
+					c.release()
+				}, nil
+			}
+			continue
+		}
+		if limit.QueueWait <= 0 {
+			c.busy.Add(1)
+			return nil, ErrPeerBusy
+		}
+
+		// Wait for a release, checking the count again once subscribed so that
+		// a release in between is not missed
+		freed := c.freedChan()
+		if c.inFlight.Load() < int64(limit.MaxInFlight) {
+			continue
+		}
+		if timer == nil {
+			timer = time.NewTimer(limit.QueueWait)
+		}
+		select {
+		case <-freed:
+		case <-timer.C:
+			c.busy.Add(1)
+			return nil, ErrPeerBusy
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// release gives back a slot taken by Acquire and wakes the requests waiting for one
+func (c *Counters) release() {
+	c.inFlight.Add(-1)
+	c.waitMu.Lock()
+	if c.freed != nil {
+		close(c.freed)
+		c.freed = nil
+	}
+	c.waitMu.Unlock()
+}
+
+// freedChan returns a channel closed by the next release
+func (c *Counters) freedChan() <-chan struct{} {
+	c.waitMu.Lock()
+	defer c.waitMu.Unlock()
+	if c.freed == nil {
+		c.freed = make(chan struct{})
+	}
+	return c.freed
+}
@@ -0,0 +1,140 @@
+package peers
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAcquireFailsFast(t *testing.T) {
+	c := new(Counters)
+	limit := Limit{MaxInFlight: 2}
+	r1, err1 := c.Acquire(context.Background(), limit)
+	r2, err2 := c.Acquire(context.Background(), limit)
+	if err1 != nil || err2 != nil {
+		t.Fatalf("Acquire below the cap = %v, %v", err1, err2)
+	}
+	if _, err := c.Acquire(context.Background(), limit); !errors.Is(err, ErrPeerBusy) {
+		t.Fatalf("Acquire over the cap = %v, want ErrPeerBusy", err)
+	}
+	if s := c.Snapshot(); s.InFlight != 2 || s.Busy != 1 || s.Requests != 0 {
+		t.Fatalf("stats = %+v", s)
+	}
+
+	r1()
+	r3, err := c.Acquire(context.Background(), limit)
+	if err != nil {
+		t.Fatalf("Acquire after a release = %v", err)
+	}
+	r2()
+	r3()
+	if s := c.Snapshot(); s.InFlight != 0 {
+		t.Fatalf("InFlight = %d after every release", s.InFlight)
+	}
+}
+
+func TestAcquireUnlimited(t *testing.T) {
+	c := new(Counters)
+	for i := 0; i < 1000; i++ {
+		if _, err := c.Acquire(context.Background(), Limit{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if s := c.Snapshot(); s.InFlight != 1000 || s.Busy != 0 {
+		t.Fatalf("stats = %+v", s)
+	}
+
+	// A nil *Counters neither counts nor limits
+	var nilCounters *Counters
+	release, err := nilCounters.Acquire(context.Background(), Limit{MaxInFlight: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+}
+
+func TestAcquireQueues(t *testing.T) {
+	c := new(Counters)
+	limit := Limit{MaxInFlight: 1, QueueWait: 5 * time.Second}
+	release, _ := c.Acquire(context.Background(), limit)
+
+	// A queued request gets the slot as soon as it is released
+	got := make(chan error, 1)
+	go func() {
+		r, err := c.Acquire(context.Background(), limit)
+		if err == nil {
+			r()
+		}
+		got <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	start := time.Now()
+	release()
+	if err := <-got; err != nil || time.Since(start) > time.Second {
+		t.Fatalf("queued Acquire = %v after %v", err, time.Since(start))
+	}
+
+	// It fails with ErrPeerBusy once QueueWait has passed
+	release, _ = c.Acquire(context.Background(), limit)
+	defer release()
+	start = time.Now()
+	if _, err := c.Acquire(context.Background(), Limit{MaxInFlight: 1, QueueWait: 30 * time.Millisecond}); !errors.Is(err, ErrPeerBusy) {
+		t.Fatalf("Acquire after QueueWait = %v, want ErrPeerBusy", err)
+	}
+	if d := time.Since(start); d < 30*time.Millisecond {
+		t.Fatalf("Acquire gave up after %v, before QueueWait", d)
+	}
+
+	// And with the context's error when the caller gives up first
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := c.Acquire(ctx, limit); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Acquire with an expired context = %v", err)
+	}
+	if s := c.Snapshot(); s.Busy != 1 || s.InFlight != 1 {
+		t.Fatalf("stats = %+v", s)
+	}
+}
+
+// TestAcquireNeverExceedsCap races many requests queueing for a few slots:
+// no more than MaxInFlight hold one at a time and every slot is given back
+func TestAcquireNeverExceedsCap(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+	c := new(Counters)
+	limit := Limit{MaxInFlight: 3, QueueWait: time.Millisecond}
+	var holding, peak, ok, busy atomic.Int64
+	var wg sync.WaitGroup
+	for g := 0; g < 16; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				release, err := c.Acquire(context.Background(), limit)
+				if err != nil {
+					busy.Add(1)
+					continue
+				}
+				n := holding.Add(1)
+				for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+				}
+				runtime.Gosched()
+				holding.Add(-1)
+				ok.Add(1)
+				release()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if peak.Load() > 3 {
+		t.Fatalf("%d requests held a slot at once, cap 3", peak.Load())
+	}
+	s := c.Snapshot()
+	if s.InFlight != 0 || s.Busy != busy.Load() || ok.Load()+busy.Load() != 16*500 {
+		t.Fatalf("stats = %+v, %d acquired, %d busy", s, ok.Load(), busy.Load())
+	}
+}
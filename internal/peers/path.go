@@ -0,0 +1,74 @@
+package peers
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// EscapePathSegment escapes a group name or key as one segment of a cache URL
+// path such as /api/cache/{group}/{key}. It is url.PathEscape, except that the
+// segments "." and ".." are written as %2E and %2E%2E: left literal, path
+// cleaning (http.ServeMux, proxies, clients) would drop them along with the
+// segment before, so those keys could not be addressed.
+func EscapePathSegment(s string) string {
+	if s == "." || s == ".." {
+		return strings.Repeat("%2E", len(s))
+	}
+	return url.PathEscape(s)
+}
+
+// SplitGroupKey parses the escaped path prefix+"{group}/{key}" as built with
+// EscapePathSegment. Extra slashes after prefix are ignored, and everything
+// after the group segment, including slashes, is the key. Both segments are
+// unescaped. ok is false when the path is not under prefix, has no key
+// segment, a segment does not unescape, or the path has a literal "." or ".."
+// segment, which is ambiguous once cleaned; see HasDotSegment. An empty key
+// is returned as is for the caller to reject with its own error.
+func SplitGroupKey(escapedPath, prefix string) (group, key string, ok bool) {
+	rest, found := strings.CutPrefix(escapedPath, prefix)
+	if !found || HasDotSegment(rest) {
+		return "", "", false
+	}
+	rest = strings.TrimLeft(rest, "/")
+
+	parts := strings.SplitN(rest, "/", 2)
+	if len(parts) != 2 {
+		return "", "", false
+	}
+
+	group, err := url.PathUnescape(parts[0])
+	if err != nil {
+		return "", "", false
+	}
+	key, err = url.PathUnescape(parts[1])
+	if err != nil {
+		return "", "", false
+	}
+	return group, key, true
+}
+
+// HasDotSegment reports whether the escaped path has a literal "." or ".."
+// segment. Escaped dots (%2E) do not count.
+func HasDotSegment(escapedPath string) bool {
+	for _, segment := range strings.Split(escapedPath, "/") {
+		if segment == "." || segment == ".." {
+			return true
+		}
+	}
+	return false
+}
+
+// RejectDotSegments answers requests whose path has a literal "." or ".."
+// segment with 400 and passes the others to next. Placed in front of an
+// http.ServeMux it replaces the redirect to the cleaned path, which for a
+// cache URL names a different key or no key at all.
+func RejectDotSegments(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if HasDotSegment(r.URL.EscapedPath()) {
+			http.Error(w, "bad request: literal . or .. path segment, escape it as %2E", http.StatusBadRequest)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
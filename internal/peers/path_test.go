@@ -0,0 +1,73 @@
+package peers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/AdrianWangs/go-cache/internal/testutil/keys"
+)
+
+func TestSplitGroupKey(t *testing.T) {
+	// Every routing key survives EscapePathSegment and SplitGroupKey
+	for _, key := range keys.Routing {
+		for _, group := range []string{"my group", "."} {
+			path := "/api/cache/" + EscapePathSegment(group) + "/" + EscapePathSegment(key)
+			if g, k, ok := SplitGroupKey(path, "/api/cache/"); !ok || g != group || k != key {
+				t.Errorf("SplitGroupKey(%q) = %q, %q, %v; want %q, %q", path, g, k, ok, group, key)
+			}
+		}
+	}
+
+	tests := []struct {
+		path       string
+		group, key string
+		ok         bool
+	}{
+		{"/api/cache/scores/users/42", "scores", "users/42", true},
+		{"/api/cache//scores/Tom", "scores", "Tom", true},
+		{"/api/cache/scores/%2E%2E", "scores", "..", true},
+		{"/api/cache/scores/a%2F..%2Fb", "scores", "a/../b", true},
+		{"/api/cache/scores", "", "", false},
+		{"/api/cache/scores/", "scores", "", true},
+		{"/api/cache/", "", "", false},
+		{"/api/cache/scores/%zz", "", "", false},
+		{"/api/cache/%zz/k", "", "", false},
+		{"/other/scores/k", "", "", false},
+		{"/api/cache/scores/.", "", "", false},
+		{"/api/cache/scores/..", "", "", false},
+		{"/api/cache/scores/a/../b", "", "", false},
+		{"/api/cache/../k", "", "", false},
+		{"/api/cache/scores/.hidden", "scores", ".hidden", true},
+		{"/api/cache/scores/...", "scores", "...", true},
+	}
+	for _, tt := range tests {
+		group, key, ok := SplitGroupKey(tt.path, "/api/cache/")
+		if group != tt.group || key != tt.key || ok != tt.ok {
+			t.Errorf("SplitGroupKey(%q) = %q, %q, %v; want %q, %q, %v", tt.path, group, key, ok, tt.group, tt.key, tt.ok)
+		}
+	}
+}
+
+func TestRejectDotSegments(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/cache/", func(w http.ResponseWriter, r *http.Request) {})
+	handler := RejectDotSegments(mux)
+
+	for path, want := range map[string]int{
+		"/api/cache/scores/Tom":     http.StatusOK,
+		"/api/cache/scores/%2E%2E":  http.StatusOK,
+		"/api/cache/scores/..":      http.StatusBadRequest,
+		"/api/cache/scores/.":       http.StatusBadRequest,
+		"/api/cache/scores/a/./b":   http.StatusBadRequest,
+		"/api/cache/scores/a%2F..b": http.StatusOK,
+	} {
+		for _, method := range []string{http.MethodGet, http.MethodDelete} {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+			if rec.Code != want {
+				t.Errorf("%s %s = %d, want %d", method, path, rec.Code, want)
+			}
+		}
+	}
+}
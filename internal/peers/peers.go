@@ -2,6 +2,8 @@
 package peers
 
 import (
+	"context"
+
 	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
 )
 
@@ -14,6 +16,11 @@ type PeerPicker interface {
 }
 
 // PeerGetter is the interface that must be implemented by a peer.
+//
+// The fetch path passes pooled messages, see GetRequest: an implementation must
+// not keep req or resp once GetByProto (or GetByProtoContext) returns, and
+// resp.Value must be a slice the caller can keep, not one the implementation
+// reuses.
 type PeerGetter interface {
 	// Get returns the value for the specified group and key.
 	Get(group string, key string) ([]byte, error)
@@ -21,3 +28,82 @@ type PeerGetter interface {
 	// GetByProto returns the value for the specified request using protobuf.
 	GetByProto(req *pb.Request, resp *pb.Response) error
 }
+
+// Optional peer capabilities.
+//
+// PeerGetter stays minimal so that existing implementations keep compiling. A peer
+// that can do more also implements one or more of the interfaces below, and callers
+// discover them with a type assertion on the PeerGetter returned by PickPeer:
+//
+//	if d, ok := peer.(peers.PeerDeleter); ok {
+//		err = d.DeleteByProto(ctx, req, resp)
+//	} else {
+//		// degrade: skip the remote step, or use the base methods
+//	}
+//
+// New capabilities should follow the same pattern: add a small interface here,
+// implement it on the peers that support it, and keep a fallback in the caller.
+
+// PeerGetterCtx is implemented by peers whose fetches can be cancelled through ctx.
+// Without it the caller falls back to GetByProto, which only honors the peer's
+// own request timeout.
+type PeerGetterCtx interface {
+	// GetByProtoContext is like GetByProto but aborts when ctx is done.
+	GetByProtoContext(ctx context.Context, req *pb.Request, resp *pb.Response) error
+}
+
+// PeerDeleter is implemented by peers that accept deletes for keys they own.
+type PeerDeleter interface {
+	// DeleteByProto removes the requested key from the peer's cache.
+	DeleteByProto(ctx context.Context, req *pb.DeleteRequest, resp *pb.DeleteResponse) error
+}
+
+// PeerSetter is implemented by peers that accept writes for keys they own.
+type PeerSetter interface {
+	// SetByProto stores the requested value in the peer's cache.
+	SetByProto(ctx context.Context, req *pb.SetRequest, resp *pb.SetResponse) error
+}
+
+// ReplicaPicker is implemented by pickers that can name the nodes following a
+// key's owner on the ring, which is where hot keys are replicated. The API server
+// derives the same nodes from its own ring, so both agree without coordination.
+type ReplicaPicker interface {
+	// PickReplicas returns up to n peers that follow the owner of key on the ring,
+	// in ring order, excluding the owner and this node.
+	PickReplicas(key string, n int) []PeerGetter
+}
+
+// PickState tells where a picker's ring places a key.
+type PickState int
+
+const (
+	// PickNoPeer means the ring is empty or the owner has no client
+	PickNoPeer PickState = iota
+	// PickSelf means the ring maps the key to this node
+	PickSelf
+	// PickRemote means another node owns the key and Peer reaches it
+	PickRemote
+)
+
+// PickResult is the outcome of OwnerPicker.PickOwner.
+type PickResult struct {
+	State PickState
+	Peer  PeerGetter // the owner when State is PickRemote, nil otherwise
+}
+
+// OwnerPicker is implemented by pickers that tell "this node owns the key" apart
+// from "no peer is known". A false PickPeer covers both, so callers without this
+// capability must treat it as PickNoPeer.
+type OwnerPicker interface {
+	// PickOwner reports where the ring places key.
+	PickOwner(key string) PickResult
+}
+
+// PeerOwnedLister is implemented by peers that can list the keys they cache
+// which the ring assigns to another node, so that a node joining the ring can
+// warm up from the previous owners of its keys.
+type PeerOwnedLister interface {
+	// ListOwnedBy returns a page of the peer's cached keys owned by req.NodeId.
+	// It fails with ErrRingMismatch when the peer's ring differs from req.RingVersion.
+	ListOwnedBy(ctx context.Context, req *pb.ListOwnedRequest, resp *pb.ListOwnedResponse) error
+}
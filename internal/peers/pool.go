@@ -0,0 +1,72 @@
+package peers
+
+import (
+	"bytes"
+	"sync"
+
+	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
+)
+
+// Pooled peer messages.
+//
+// A node under load fetches from its peers at a high rate, and allocating a
+// Request, a Response and a body buffer for every fetch shows up as GC work.
+// The fetch path takes them from the pools below instead, under these rules:
+//
+//   - A PeerGetter must not keep req or resp, or anything reachable from them,
+//     once GetByProto returns; the caller puts them back right after.
+//   - The value bytes in Response.Value belong to the caller that asked for
+//     them. PutResponse clears Value before resetting the message, so a pooled
+//     Response never hands the backing array of one fetch to the next.
+//   - A buffer goes back only after every slice of its contents is dead. The
+//     getters unmarshal from it, and proto.Unmarshal copies bytes fields, so
+//     the decoded message does not share the buffer.
+
+// maxPooledBuffer is the largest buffer PutBuffer keeps; the buffers of large
+// values are left to the garbage collector rather than pinned in the pool
+const maxPooledBuffer = 64 << 10
+
+var (
+	requests  = sync.Pool{New: func() any { return new(pb.Request) }}
+	responses = sync.Pool{New: func() any { return new(pb.Response) }}
+	buffers   = sync.Pool{New: func() any { return new(bytes.Buffer) }}
+)
+
+// GetRequest returns an empty Request from the pool
+func GetRequest() *pb.Request {
+	return requests.Get().(*pb.Request)
+}
+
+// PutRequest resets req and returns it to the pool; the caller must not use it afterwards
+func PutRequest(req *pb.Request) {
+	req.Reset()
+	requests.Put(req)
+}
+
+// GetResponse returns an empty Response from the pool
+func GetResponse() *pb.Response {
+	return responses.Get().(*pb.Response)
+}
+
+// PutResponse returns resp to the pool. Value is dropped first, never reused, so
+// a caller that took resp.Value keeps sole ownership of those bytes.
+func PutResponse(resp *pb.Response) {
+	resp.Value = nil
+	resp.Reset()
+	responses.Put(resp)
+}
+
+// GetBuffer returns an empty buffer from the pool
+func GetBuffer() *bytes.Buffer {
+	return buffers.Get().(*bytes.Buffer)
+}
+
+// PutBuffer returns buf to the pool unless it grew past maxPooledBuffer. Neither
+// buf nor any slice of its contents may be used afterwards.
+func PutBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBuffer {
+		return
+	}
+	buf.Reset()
+	buffers.Put(buf)
+}
@@ -0,0 +1,87 @@
+package peers
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"sync"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// TestPutResponseKeepsValue checks that the caller keeps the value bytes of a
+// response it put back, however the next user of the pooled message fills it
+func TestPutResponseKeepsValue(t *testing.T) {
+	resp := GetResponse()
+	resp.Value = []byte("first")
+	resp.Source = proto.String("loader")
+	value := resp.Value
+	PutResponse(resp)
+
+	for i := 0; i < 100; i++ {
+		next := GetResponse()
+		if next.Value != nil || next.Source != nil {
+			t.Fatalf("pooled Response not reset: %v", next)
+		}
+		next.Value = append(next.Value, "overwritten"...)
+		PutResponse(next)
+	}
+	if string(value) != "first" {
+		t.Fatalf("value = %q after the Response was reused", value)
+	}
+}
+
+func TestPutBuffer(t *testing.T) {
+	buf := GetBuffer()
+	buf.WriteString("data")
+	PutBuffer(buf)
+	if buf := GetBuffer(); buf.Len() != 0 {
+		t.Fatalf("pooled buffer holds %q", buf.String())
+	}
+
+	// A buffer grown past maxPooledBuffer is left to the garbage collector
+	large := bytes.NewBuffer(make([]byte, 0, 2*maxPooledBuffer))
+	PutBuffer(large)
+	for i := 0; i < 100; i++ {
+		if GetBuffer() == large {
+			t.Fatal("a large buffer went back to the pool")
+		}
+	}
+}
+
+// TestPoolsConcurrent fills and returns pooled messages from many goroutines;
+// under the race detector it shows that no message is shared between users
+func TestPoolsConcurrent(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var kept [][]byte
+			for i := 0; i < 1000; i++ {
+				key := fmt.Sprintf("%d-%d", g, i)
+				req, resp, buf := GetRequest(), GetResponse(), GetBuffer()
+				req.Key = key
+				buf.WriteString(key)
+				resp.Value = []byte(key)
+				if req.Key != key || buf.String() != key || string(resp.Value) != key {
+					t.Errorf("pooled message changed while in use: %q %q %q", req.Key, buf.String(), resp.Value)
+					return
+				}
+				kept = append(kept, resp.Value)
+				PutRequest(req)
+				PutResponse(resp)
+				PutBuffer(buf)
+			}
+			for i, v := range kept {
+				if want := fmt.Sprintf("%d-%d", g, i); string(v) != want {
+					t.Errorf("kept value = %q, want %q", v, want)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
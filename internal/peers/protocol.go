@@ -0,0 +1,144 @@
+package peers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// Versions of the peer protocol spoken between API servers and cache nodes.
+//
+// A node advertises ProtocolVersion in its discovery registration, in the
+// HeaderProtoVersion header of every HTTP pool response, in the
+// MetadataProtoVersion header of every gRPC response and in the Info RPC.
+// Clients record the version each peer advertised and consult Supports before
+// using an optional operation, so an operation a peer is known to lack fails at
+// once with an ErrUnsupported error instead of an opaque 404 or Unimplemented.
+//
+// Nodes predating negotiation advertise nothing; their version is
+// ProtocolUnknown. What they support depends on the build, so clients still try
+// optional operations on them and map the "no such route" answers of old builds
+// to ErrUnsupported, or to the operation's fallback.
+const (
+	// ProtocolUnknown is the version of a peer that has not advertised one
+	ProtocolUnknown = 0
+	// ProtocolV1 is the first advertised version: Get and Delete over plain HTTP,
+	// protobuf and gRPC, plus Set, DeleteBatch, Stats, Export, Import and Info
+	ProtocolV1 = 1
+	// ProtocolV2 adds ListOwnedBy and cache-only reads, used by warm-up
+	ProtocolV2 = 2
+
+	// ProtocolVersion is the version this build speaks
+	ProtocolVersion = ProtocolV2
+)
+
+const (
+	// HeaderProtoVersion carries the protocol version on HTTP pool responses
+	HeaderProtoVersion = "X-GoCache-Proto-Version"
+	// MetadataProtoVersion carries the protocol version in gRPC response headers
+	MetadataProtoVersion = "x-gocache-proto-version"
+)
+
+// Feature is an operation of the peer protocol that not every version supports
+type Feature string
+
+// Optional operations of the peer protocol
+const (
+	FeatureSet         Feature = "set"
+	FeatureDeleteBatch Feature = "delete-batch"
+	FeatureStats       Feature = "stats"
+	FeatureExport      Feature = "export"
+	FeatureImport      Feature = "import"
+	FeatureInfo        Feature = "info"
+	FeatureListOwned   Feature = "list-owned"
+)
+
+// featureSince is the compatibility matrix: the first protocol version that
+// supports each optional operation. A new operation gets the version that
+// introduces it, and that version becomes ProtocolVersion.
+var featureSince = map[Feature]int{
+	FeatureSet:         ProtocolV1,
+	FeatureDeleteBatch: ProtocolV1,
+	FeatureStats:       ProtocolV1,
+	FeatureExport:      ProtocolV1,
+	FeatureImport:      ProtocolV1,
+	FeatureInfo:        ProtocolV1,
+	FeatureListOwned:   ProtocolV2,
+}
+
+// Supports reports whether a peer speaking version may be asked for f. Peers of
+// unknown version may be: only trying tells.
+func Supports(version int, f Feature) bool {
+	since, ok := featureSince[f]
+	if !ok {
+		return false
+	}
+	return version == ProtocolUnknown || version >= since
+}
+
+// ErrUnsupported is returned for an operation the peer does not support
+var ErrUnsupported = errors.New("operation not supported by peer")
+
+// ErrRingMismatch is returned by ListOwnedBy when the caller and the peer see
+// different rings, e.g. because the peer has not learned of a new node yet.
+// Ownership is only meaningful on a shared ring; callers retry later.
+var ErrRingMismatch = errors.New("ring version mismatch")
+
+// Unsupported returns an ErrUnsupported error explaining that a peer speaking
+// version lacks f
+func Unsupported(f Feature, version int) error {
+	if version == ProtocolUnknown {
+		return fmt.Errorf("%w: %s (peer predates protocol versioning, upgrade it)", ErrUnsupported, f)
+	}
+	return fmt.Errorf("%w: %s needs protocol v%d, peer speaks v%d", ErrUnsupported, f, featureSince[f], version)
+}
+
+// ParseProtoVersion parses an advertised version, ProtocolUnknown when it is
+// missing or malformed
+func ParseProtoVersion(s string) int {
+	v, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || v < 0 {
+		return ProtocolUnknown
+	}
+	return v
+}
+
+// WriteProtoVersion advertises ProtocolVersion on an HTTP response
+func WriteProtoVersion(h http.Header) {
+	h.Set(HeaderProtoVersion, strconv.Itoa(ProtocolVersion))
+}
+
+// PeerVersion records the protocol version a peer advertised. The zero value
+// is ProtocolUnknown and ready to use.
+type PeerVersion struct {
+	v atomic.Int32
+}
+
+// Get returns the last version the peer advertised
+func (p *PeerVersion) Get() int {
+	return int(p.v.Load())
+}
+
+// Set records an advertised version. ProtocolUnknown is ignored, so a response
+// without the header, e.g. from a proxy, does not erase what the peer advertised.
+func (p *PeerVersion) Set(version int) {
+	if version != ProtocolUnknown {
+		p.v.Store(int32(version))
+	}
+}
+
+// Observe records the version advertised in the headers of an HTTP response
+func (p *PeerVersion) Observe(h http.Header) {
+	p.Set(ParseProtoVersion(h.Get(HeaderProtoVersion)))
+}
+
+// Check returns an ErrUnsupported error when the peer is known not to support f
+func (p *PeerVersion) Check(f Feature) error {
+	if v := p.Get(); !Supports(v, f) {
+		return Unsupported(f, v)
+	}
+	return nil
+}
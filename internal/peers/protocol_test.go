@@ -0,0 +1,78 @@
+package peers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestSupports(t *testing.T) {
+	tests := []struct {
+		version int
+		feature Feature
+		want    bool
+	}{
+		{ProtocolUnknown, FeatureListOwned, true},
+		{ProtocolUnknown, FeatureSet, true},
+		{ProtocolV1, FeatureSet, true},
+		{ProtocolV1, FeatureDeleteBatch, true},
+		{ProtocolV1, FeatureListOwned, false},
+		{ProtocolV2, FeatureListOwned, true},
+		{ProtocolVersion + 1, FeatureStats, true},
+		{ProtocolV2, Feature("teleport"), false},
+	}
+	for _, tt := range tests {
+		if got := Supports(tt.version, tt.feature); got != tt.want {
+			t.Errorf("Supports(%d, %s) = %v, want %v", tt.version, tt.feature, got, tt.want)
+		}
+	}
+
+	// Every feature is supported by the version this build speaks
+	for f := range featureSince {
+		if !Supports(ProtocolVersion, f) {
+			t.Errorf("ProtocolVersion lacks %s", f)
+		}
+	}
+}
+
+func TestUnsupported(t *testing.T) {
+	err := Unsupported(FeatureListOwned, ProtocolV1)
+	if !errors.Is(err, ErrUnsupported) || !strings.Contains(err.Error(), "needs protocol v2, peer speaks v1") {
+		t.Fatalf("Unsupported(v1) = %v", err)
+	}
+	err = Unsupported(FeatureSet, ProtocolUnknown)
+	if !errors.Is(err, ErrUnsupported) || !strings.Contains(err.Error(), "upgrade") {
+		t.Fatalf("Unsupported(unknown) = %v", err)
+	}
+}
+
+func TestPeerVersion(t *testing.T) {
+	for in, want := range map[string]int{"2": 2, " 1 ": 1, "": ProtocolUnknown, "v2": ProtocolUnknown, "-1": ProtocolUnknown} {
+		if got := ParseProtoVersion(in); got != want {
+			t.Errorf("ParseProtoVersion(%q) = %d, want %d", in, got, want)
+		}
+	}
+
+	var v PeerVersion
+	if v.Get() != ProtocolUnknown || v.Check(FeatureListOwned) != nil {
+		t.Fatal("zero PeerVersion is not an unknown version")
+	}
+	h := http.Header{}
+	h.Set(HeaderProtoVersion, "1")
+	v.Observe(h)
+	if err := v.Check(FeatureListOwned); !errors.Is(err, ErrUnsupported) {
+		t.Fatalf("Check after v1 = %v", err)
+	}
+	// A response without the header keeps what the peer advertised
+	v.Observe(http.Header{})
+	if v.Get() != ProtocolV1 {
+		t.Fatalf("version after a response without the header = %d", v.Get())
+	}
+
+	WriteProtoVersion(h)
+	v.Observe(h)
+	if v.Get() != ProtocolVersion || v.Check(FeatureListOwned) != nil {
+		t.Fatalf("version after WriteProtoVersion = %d", v.Get())
+	}
+}
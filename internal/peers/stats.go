@@ -0,0 +1,269 @@
+package peers
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// ErrorClass classifies a failed request to a peer
+type ErrorClass int
+
+const (
+	// ErrNone means the request succeeded, including "not found" answers
+	ErrNone ErrorClass = iota
+	// ErrTimeout means the request hit its deadline
+	ErrTimeout
+	// ErrConnRefused means the peer could not be reached: connection refused
+	// or, for gRPC, the Unavailable status
+	ErrConnRefused
+	// ErrStatus means the peer answered with a failure status: a non-200 HTTP
+	// response other than 404, or a gRPC status other than NotFound
+	ErrStatus
+	// ErrOther covers everything else, e.g. malformed responses
+	ErrOther
+)
+
+// Classify maps a transport error to its ErrorClass. A nil error and a
+// cancellation by the caller (context.Canceled) are not counted as errors.
+func Classify(err error) ErrorClass {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return ErrNone
+	}
+	if s, ok := status.FromError(err); ok {
+		switch s.Code() {
+		case codes.OK, codes.NotFound, codes.Canceled:
+			return ErrNone
+		case codes.DeadlineExceeded:
+			return ErrTimeout
+		case codes.Unavailable:
+			return ErrConnRefused
+		default:
+			return ErrStatus
+		}
+	}
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, os.ErrDeadlineExceeded),
+		errors.As(err, &netErr) && netErr.Timeout():
+		return ErrTimeout
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return ErrConnRefused
+	default:
+		return ErrOther
+	}
+}
+
+// StatusClass maps an HTTP status code to its ErrorClass; 200 and 404 are not errors
+func StatusClass(code int) ErrorClass {
+	if code == http.StatusOK || code == http.StatusNotFound {
+		return ErrNone
+	}
+	return ErrStatus
+}
+
+// Stats is a snapshot of the traffic sent to one peer
+type Stats struct {
+	Requests    int64      `json:"requests"`            // requests sent
+	BytesOut    int64      `json:"bytesOut"`            // request payload bytes sent
+	BytesIn     int64      `json:"bytesIn"`             // response payload bytes received
+	Timeouts    int64      `json:"timeouts"`            // requests that timed out
+	ConnRefused int64      `json:"connRefused"`         // requests that could not reach the peer
+	BadStatus   int64      `json:"badStatus"`           // failure statuses returned by the peer
+	OtherErrors int64      `json:"otherErrors"`         // all other failures
+	LastError   *time.Time `json:"lastError,omitempty"` // time of the most recent failure, nil if none
+	InFlight    int64      `json:"inFlight"`            // requests currently in flight
+	Busy        int64      `json:"busy"`                // requests refused with ErrPeerBusy, not sent and not counted in Requests
+
+	Completed    int64 `json:"completed"`    // requests that finished, successfully or not
+	LatencyNanos int64 `json:"latencyNanos"` // total time the completed requests took
+}
+
+// AvgLatency returns the mean time of the completed requests, 0 if there are none
+func (s Stats) AvgLatency() time.Duration {
+	if s.Completed == 0 {
+		return 0
+	}
+	return time.Duration(s.LatencyNanos / s.Completed)
+}
+
+// Errors returns the number of failed requests
+func (s Stats) Errors() int64 {
+	return s.Timeouts + s.ConnRefused + s.BadStatus + s.OtherErrors
+}
+
+// Counters accumulates Stats for one peer. All methods are safe for concurrent
+// use and a nil *Counters records nothing. Owners keep the same Counters for a
+// peer across peer-list refreshes so the numbers survive getter replacement.
+type Counters struct {
+	requests    atomic.Int64
+	bytesOut    atomic.Int64
+	bytesIn     atomic.Int64
+	timeouts    atomic.Int64
+	connRefused atomic.Int64
+	badStatus   atomic.Int64
+	otherErrors atomic.Int64
+	lastError   atomic.Int64 // unix nanoseconds, 0 if never
+	inFlight    atomic.Int64 // requests holding a slot from Acquire
+	busy        atomic.Int64 // requests refused by Acquire
+
+	completed atomic.Int64 // requests whose slot from Acquire was released
+	latency   atomic.Int64 // nanoseconds between Acquire and release, summed over completed
+
+	waitMu sync.Mutex
+	freed  chan struct{} // closed by the next release, nil while nobody waits for a slot
+}
+
+// Start records a request with out payload bytes and returns the Call used to
+// record its outcome
+func (c *Counters) Start(out int) Call {
+	if c != nil {
+		c.requests.Add(1)
+		c.bytesOut.Add(int64(out))
+	}
+	return Call{counters: c}
+}
+
+// fail records one failure of the given class
+func (c *Counters) fail(class ErrorClass) {
+	if c == nil || class == ErrNone {
+		return
+	}
+	switch class {
+	case ErrTimeout:
+		c.timeouts.Add(1)
+	case ErrConnRefused:
+		c.connRefused.Add(1)
+	case ErrStatus:
+		c.badStatus.Add(1)
+	default:
+		c.otherErrors.Add(1)
+	}
+	c.lastError.Store(time.Now().UnixNano())
+}
+
+// Snapshot returns the current values
+func (c *Counters) Snapshot() Stats {
+	if c == nil {
+		return Stats{}
+	}
+	s := Stats{
+		Requests:    c.requests.Load(),
+		BytesOut:    c.bytesOut.Load(),
+		BytesIn:     c.bytesIn.Load(),
+		Timeouts:    c.timeouts.Load(),
+		ConnRefused: c.connRefused.Load(),
+		BadStatus:   c.badStatus.Load(),
+		OtherErrors: c.otherErrors.Load(),
+		InFlight:    c.inFlight.Load(),
+		Busy:        c.busy.Load(),
+
+		Completed:    c.completed.Load(),
+		LatencyNanos: c.latency.Load(),
+	}
+	if ns := c.lastError.Load(); ns != 0 {
+		t := time.Unix(0, ns)
+		s.LastError = &t
+	}
+	return s
+}
+
+// Call records the outcome of one request started with Counters.Start. Only
+// the first failure is counted.
+type Call struct {
+	counters *Counters
+	failed   bool
+}
+
+// Received records n response payload bytes
+func (c *Call) Received(n int) {
+	if c.counters != nil {
+		c.counters.bytesIn.Add(int64(n))
+	}
+}
+
+// Fail records err, classified with Classify
+func (c *Call) Fail(err error) {
+	c.FailClass(Classify(err))
+}
+
+// Status records an HTTP response status, classified with StatusClass
+func (c *Call) Status(code int) {
+	c.FailClass(StatusClass(code))
+}
+
+// FailClass records a failure of class unless one was already recorded
+func (c *Call) FailClass(class ErrorClass) {
+	if c.failed || class == ErrNone {
+		return
+	}
+	c.failed = true
+	c.counters.fail(class)
+}
+
+// StatsProto converts per-peer stats to the Stats RPC representation, sorted by peer
+func StatsProto(stats map[string]Stats) []*pb.PeerStats {
+	out := make([]*pb.PeerStats, 0, len(stats))
+	for peer, s := range stats {
+		ps := &pb.PeerStats{
+			Peer:        peer,
+			Requests:    proto.Int64(s.Requests),
+			BytesOut:    proto.Int64(s.BytesOut),
+			BytesIn:     proto.Int64(s.BytesIn),
+			Timeouts:    proto.Int64(s.Timeouts),
+			ConnRefused: proto.Int64(s.ConnRefused),
+			BadStatus:   proto.Int64(s.BadStatus),
+			OtherErrors: proto.Int64(s.OtherErrors),
+			InFlight:    proto.Int64(s.InFlight),
+			Busy:        proto.Int64(s.Busy),
+
+			Completed:    proto.Int64(s.Completed),
+			LatencyNanos: proto.Int64(s.LatencyNanos),
+		}
+		if s.LastError != nil {
+			ps.LastErrorUnixNano = proto.Int64(s.LastError.UnixNano())
+		}
+		out = append(out, ps)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Peer < out[j].Peer })
+	return out
+}
+
+// StatsFromProto converts the Stats RPC representation back to per-peer stats
+func StatsFromProto(list []*pb.PeerStats) map[string]Stats {
+	out := make(map[string]Stats, len(list))
+	for _, ps := range list {
+		s := Stats{
+			Requests:    ps.GetRequests(),
+			BytesOut:    ps.GetBytesOut(),
+			BytesIn:     ps.GetBytesIn(),
+			Timeouts:    ps.GetTimeouts(),
+			ConnRefused: ps.GetConnRefused(),
+			BadStatus:   ps.GetBadStatus(),
+			OtherErrors: ps.GetOtherErrors(),
+			InFlight:    ps.GetInFlight(),
+			Busy:        ps.GetBusy(),
+
+			Completed:    ps.GetCompleted(),
+			LatencyNanos: ps.GetLatencyNanos(),
+		}
+		if ns := ps.GetLastErrorUnixNano(); ns != 0 {
+			t := time.Unix(0, ns)
+			s.LastError = &t
+		}
+		out[ps.GetPeer()] = s
+	}
+	return out
+}
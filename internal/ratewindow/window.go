@@ -0,0 +1,139 @@
+// Package ratewindow counts events in a ring of one-minute buckets, so that
+// stats can report rates over the last few minutes next to lifetime totals.
+package ratewindow
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// BucketSeconds is the span of a bucket
+	BucketSeconds = 60
+	// DefaultBuckets is the number of buckets kept when New is given none
+	DefaultBuckets = 60
+	// SchemaVersion is the version of the JSON the stats endpoints report rates
+	// in; it changes whenever a field changes meaning or is removed
+	SchemaVersion = 1
+)
+
+// bucket holds the counts of one minute. A bucket is never reused: when its
+// slot moves on to a later minute, a fresh bucket is swapped in.
+type bucket struct {
+	minute int64          // minutes since the Unix epoch
+	counts []atomic.Int64 // indexed by event
+}
+
+// Window counts a fixed set of events per minute over the last buckets
+// minutes. Adding takes no lock: the bucket of the current minute is found by
+// an atomic load, and the first write of a minute swaps a fresh bucket into
+// its slot with a compare-and-swap, so the ring advances lazily and idle
+// minutes cost nothing. Memory is fixed at creation, about 40 bytes plus 8
+// per event for every bucket.
+type Window struct {
+	now    func() time.Time
+	events int
+	start  int64 // minute the window was created in
+	slots  []atomic.Pointer[bucket]
+}
+
+// New returns a window counting events kinds of events, numbered from 0, over
+// buckets minutes; buckets <= 0 keeps DefaultBuckets and at least 2 are kept.
+// now is the clock, time.Now when nil.
+func New(events, buckets int, now func() time.Time) *Window {
+	if buckets <= 0 {
+		buckets = DefaultBuckets
+	}
+	if now == nil {
+		now = time.Now
+	}
+	w := &Window{
+		now:    now,
+		events: events,
+		slots:  make([]atomic.Pointer[bucket], max(buckets, 2)),
+	}
+	w.start = w.minute()
+	return w
+}
+
+// Buckets returns the number of buckets of the ring
+func (w *Window) Buckets() int {
+	return len(w.slots)
+}
+
+// minute returns the current minute since the Unix epoch
+func (w *Window) minute() int64 {
+	return w.now().Unix() / BucketSeconds
+}
+
+// slot returns the slot of minute m
+func (w *Window) slot(m int64) *atomic.Pointer[bucket] {
+	i := m % int64(len(w.slots))
+	if i < 0 {
+		i += int64(len(w.slots))
+	}
+	return &w.slots[i]
+}
+
+// Add counts n occurrences of event in the current minute. A nil window counts
+// nothing.
+func (w *Window) Add(event int, n int64) {
+	if w == nil || n == 0 {
+		return
+	}
+	w.current(w.minute()).counts[event].Add(n)
+}
+
+// current returns the bucket of minute m, swapping a fresh one into its slot
+// when the slot still holds an earlier minute. A writer that read the clock
+// just before a concurrent writer moved the slot on counts into the newer
+// bucket rather than resurrecting the old one.
+func (w *Window) current(m int64) *bucket {
+	slot := w.slot(m)
+	for {
+		b := slot.Load()
+		if b != nil && b.minute >= m {
+			return b
+		}
+		fresh := &bucket{minute: m, counts: make([]atomic.Int64, w.events)}
+		if slot.CompareAndSwap(b, fresh) {
+			return fresh
+		}
+	}
+}
+
+// Sums is the number of each event over a span of completed minutes
+type Sums struct {
+	Minutes int     // completed minutes covered
+	Counts  []int64 // indexed by event
+}
+
+// Rate returns the occurrences of event per minute, 0 when no minute is covered
+func (s Sums) Rate(event int) float64 {
+	if s.Minutes == 0 {
+		return 0
+	}
+	return float64(s.Counts[event]) / float64(s.Minutes)
+}
+
+// Last sums the last n completed minutes; the current minute, still being
+// counted, is left out. Fewer minutes are covered while the window is younger
+// than n minutes, and at most Buckets()-1, since the current minute takes a
+// slot. Buckets left behind by an idle stretch are recognized by their minute
+// and count as zero, so a window idle for longer than its span reports no
+// events rather than stale ones.
+func (w *Window) Last(n int) Sums {
+	now := w.minute()
+	n = int(max(min(int64(n), int64(len(w.slots)-1), now-w.start), 0))
+	sums := Sums{Minutes: n, Counts: make([]int64, w.events)}
+	for m := now - int64(n); m < now; m++ {
+		b := w.slot(m).Load()
+		if b == nil || b.minute != m {
+			continue
+		}
+		for i := range sums.Counts {
+			sums.Counts[i] += b.counts[i].Load()
+		}
+	}
+	return sums
+}
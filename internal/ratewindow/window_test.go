@@ -0,0 +1,169 @@
+package ratewindow
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/pkg/lru"
+)
+
+const (
+	evA = iota
+	evB
+	testEvents
+)
+
+// newTestWindow returns a window of buckets buckets on a fake clock set at
+// the start of a minute
+func newTestWindow(buckets int) (*Window, *lru.FakeClock) {
+	clock := lru.NewFakeClock(time.Unix(100*BucketSeconds, 0))
+	return New(testEvents, buckets, clock.Now), clock
+}
+
+// checkLast fails unless Last(n) covers minutes minutes with counts a and b
+func checkLast(t *testing.T, w *Window, n, minutes int, a, b int64) {
+	t.Helper()
+	s := w.Last(n)
+	if s.Minutes != minutes || s.Counts[evA] != a || s.Counts[evB] != b {
+		t.Fatalf("Last(%d) = %d minutes %v, want %d minutes [%d %d]", n, s.Minutes, s.Counts, minutes, a, b)
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		buckets, want int
+	}{
+		{0, DefaultBuckets},
+		{-1, DefaultBuckets},
+		{1, 2},
+		{15, 15},
+	}
+	for _, tt := range tests {
+		if got := New(testEvents, tt.buckets, nil).Buckets(); got != tt.want {
+			t.Errorf("New(%d).Buckets() = %d, want %d", tt.buckets, got, tt.want)
+		}
+	}
+
+	// A nil window counts nothing
+	var w *Window
+	w.Add(evA, 1)
+}
+
+func TestRotation(t *testing.T) {
+	w, clock := newTestWindow(5)
+	w.Add(evA, 1)
+	w.Add(evB, 2)
+	clock.Advance(59 * time.Second)
+	w.Add(evA, 1)
+	// The minute in progress is not reported
+	checkLast(t, w, 1, 0, 0, 0)
+
+	clock.Advance(time.Second)
+	checkLast(t, w, 1, 1, 2, 2)
+	w.Add(evA, 5)
+	clock.Advance(time.Minute)
+	checkLast(t, w, 1, 1, 5, 0)
+	checkLast(t, w, 5, 2, 7, 2) // the window is two minutes old
+
+	// Once the window is older than its span, Last covers Buckets()-1 minutes
+	for i := 0; i < 6; i++ {
+		w.Add(evB, 1)
+		clock.Advance(time.Minute)
+	}
+	checkLast(t, w, 15, 4, 0, 4)
+	checkLast(t, w, 2, 2, 0, 2)
+	checkLast(t, w, 0, 0, 0, 0)
+}
+
+// TestRates checks the per-minute rates of a steady load
+func TestRates(t *testing.T) {
+	w, clock := newTestWindow(DefaultBuckets)
+	for m := 0; m < 20; m++ {
+		for i := 0; i < 10; i++ {
+			w.Add(evA, 1)
+			clock.Advance(time.Second)
+		}
+		w.Add(evB, int64(m%2)*4) // 4 every other minute
+		clock.Advance(50 * time.Second)
+	}
+	for _, n := range []int{1, 5, 15} {
+		s := w.Last(n)
+		if s.Minutes != n || s.Rate(evA) != 10 {
+			t.Errorf("Last(%d): %d minutes, rate %v, want %d minutes, 10", n, s.Minutes, s.Rate(evA), n)
+		}
+	}
+	if r := w.Last(4).Rate(evB); r != 2 {
+		t.Errorf("rate of evB over 4 minutes = %v, want 2", r)
+	}
+	if r := (Sums{Counts: make([]int64, testEvents)}).Rate(evA); r != 0 {
+		t.Errorf("rate over no minute = %v", r)
+	}
+}
+
+// TestIdleGap leaves the window idle for longer than its span: the buckets of
+// the old minutes still sit in their slots and must not be reported again
+func TestIdleGap(t *testing.T) {
+	for _, gap := range []time.Duration{5 * time.Minute, 6 * time.Minute, 7*time.Minute + 30*time.Second, 24 * time.Hour} {
+		t.Run(gap.String(), func(t *testing.T) {
+			w, clock := newTestWindow(5)
+			for i := 0; i < 5; i++ {
+				w.Add(evA, 10)
+				clock.Advance(time.Minute)
+			}
+			checkLast(t, w, 5, 4, 40, 0)
+
+			clock.Advance(gap)
+			checkLast(t, w, 5, 4, 0, 0)
+			w.Add(evB, 1)
+			checkLast(t, w, 5, 4, 0, 0)
+			clock.Advance(time.Minute)
+			checkLast(t, w, 1, 1, 0, 1)
+			checkLast(t, w, 5, 4, 0, 1)
+		})
+	}
+}
+
+// TestBackwardClock checks a clock stepping back a minute counts into the
+// bucket of the later minute already in the slot rather than a stale one
+func TestBackwardClock(t *testing.T) {
+	w, clock := newTestWindow(2)
+	clock.Advance(2 * time.Minute) // same slot as the start minute
+	w.Add(evA, 1)
+	clock.Advance(-2 * time.Minute)
+	w.Add(evA, 1)
+	clock.Advance(3 * time.Minute)
+	checkLast(t, w, 1, 1, 2, 0)
+	clock.Advance(-time.Minute)
+	checkLast(t, w, 1, 1, 0, 0)
+}
+
+// TestConcurrentAdds adds from several goroutines while the clock crosses
+// minute boundaries: no count is lost to a bucket swap. Run it with -race.
+func TestConcurrentAdds(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+	w, clock := newTestWindow(DefaultBuckets)
+	const writers, adds = 4, 5000
+
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < adds; j++ {
+				w.Add(evA, 1)
+			}
+		}()
+	}
+	for i := 0; i < 20; i++ {
+		clock.Advance(time.Minute)
+		runtime.Gosched()
+	}
+	wg.Wait()
+	clock.Advance(time.Minute)
+
+	if s := w.Last(DefaultBuckets); s.Counts[evA] != writers*adds {
+		t.Fatalf("counted %d adds, want %d", s.Counts[evA], writers*adds)
+	}
+}
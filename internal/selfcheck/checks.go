@@ -0,0 +1,284 @@
+package selfcheck
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// Network 检查使用的网络操作，System 为真实实现
+type Network interface {
+	Listen(network, addr string) (net.Listener, error)
+	DialContext(ctx context.Context, network, addr string) (net.Conn, error)
+}
+
+// systemNetwork 使用本机网络
+type systemNetwork struct {
+	net.Dialer
+}
+
+func (systemNetwork) Listen(network, addr string) (net.Listener, error) {
+	return net.Listen(network, addr)
+}
+
+// System 本机网络
+var System Network = &systemNetwork{}
+
+// Bind 检查能否在 addr 上监听。节点在通告的地址上监听，自动检测的 IP 不属于本机、
+// 端口被占用或没有权限时都在这里失败
+func Bind(n Network, addr string) Check {
+	return Check{
+		Name:   CheckBind,
+		Target: addr,
+		Hint:   "确认端口未被其他进程占用；地址中的主机不属于本机时用 -node-host 指定本机的地址",
+		Run: func(ctx context.Context) error {
+			l, err := n.Listen("tcp", addr)
+			if err != nil {
+				return err
+			}
+			return l.Close()
+		},
+	}
+}
+
+// Dial 检查经通告的地址能否连回本机：在 addr 上临时监听，再从外部接口拨号 addr，
+// 双方交换随机数确认连上的是本机而不是恰好使用该地址的其他主机。自动检测的 IP
+// 选错了网卡、地址被转发到其他主机或被防火墙拦截时在这里失败
+func Dial(n Network, addr string) Check {
+	return Check{
+		Name:   CheckDial,
+		Target: addr,
+		Hint:   "其他节点和 API 服务器按这个地址访问本节点；自动检测的 IP 选错了网卡时用 -node-host 指定，并检查防火墙",
+		Run: func(ctx context.Context) error {
+			l, err := n.Listen("tcp", addr)
+			if err != nil {
+				return fmt.Errorf("临时监听失败: %w", err)
+			}
+			defer l.Close()
+
+			nonce := make([]byte, 16)
+			if _, err := rand.Read(nonce); err != nil {
+				return err
+			}
+			go echo(l, len(nonce))
+
+			conn, err := n.DialContext(ctx, "tcp", addr)
+			if err != nil {
+				return fmt.Errorf("拨号失败: %w", err)
+			}
+			defer conn.Close()
+			if deadline, ok := ctx.Deadline(); ok {
+				conn.SetDeadline(deadline)
+			}
+			if _, err := conn.Write(nonce); err != nil {
+				return fmt.Errorf("发送探测数据失败: %w", err)
+			}
+			got := make([]byte, len(nonce))
+			if _, err := io.ReadFull(conn, got); err != nil {
+				return fmt.Errorf("未收到本机的应答，连接可能到达了其他主机: %w", err)
+			}
+			if !bytes.Equal(got, nonce) {
+				return fmt.Errorf("应答与探测数据不符，连接到达的不是本机")
+			}
+			return nil
+		},
+	}
+}
+
+// echo 接受一个连接并原样返回它发来的 n 个字节
+func echo(l net.Listener, n int) {
+	conn, err := l.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(DefaultTimeout))
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		return
+	}
+	conn.Write(buf)
+}
+
+// KV 检查 etcd 使用的读写操作
+type KV interface {
+	Put(ctx context.Context, key, value string) error
+	// Get 返回 key 的值，key 不存在时 ok 为 false
+	Get(ctx context.Context, key string) (value string, ok bool, err error)
+	Delete(ctx context.Context, key string) error
+	Close() error
+}
+
+// Connector 连接 etcd
+type Connector func(ctx context.Context) (KV, error)
+
+// EtcdConnector 按节点连接 etcd 的方式返回 Connector
+func EtcdConnector(endpoints []string, dialTimeout time.Duration) Connector {
+	return func(ctx context.Context) (KV, error) {
+		cli, err := clientv3.New(clientv3.Config{
+			Endpoints:   endpoints,
+			DialTimeout: dialTimeout,
+			Context:     ctx,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return etcdKV{cli}, nil
+	}
+}
+
+// etcdKV 用 etcd 客户端实现 KV
+type etcdKV struct {
+	cli *clientv3.Client
+}
+
+func (e etcdKV) Put(ctx context.Context, key, value string) error {
+	_, err := e.cli.Put(ctx, key, value)
+	return err
+}
+
+func (e etcdKV) Get(ctx context.Context, key string) (string, bool, error) {
+	resp, err := e.cli.Get(ctx, key)
+	if err != nil || len(resp.Kvs) == 0 {
+		return "", false, err
+	}
+	return string(resp.Kvs[0].Value), true, nil
+}
+
+func (e etcdKV) Delete(ctx context.Context, key string) error {
+	_, err := e.cli.Delete(ctx, key)
+	return err
+}
+
+func (e etcdKV) Close() error {
+	return e.cli.Close()
+}
+
+// ProbeKey 返回探测 key。它不在 /serviceName/ 前缀下，监视节点列表的 API 服务器和
+// 节点不会把它当成节点
+func ProbeKey(serviceName, nodeID string) string {
+	return fmt.Sprintf("/_gocache_selfcheck/%s/%s", serviceName, nodeID)
+}
+
+// Etcd 检查能否连接 etcd，并写入、读回和删除探测 key
+func Etcd(connect Connector, endpoints []string, key string) Check {
+	return Check{
+		Name:   CheckEtcd,
+		Target: fmt.Sprintf("%v", endpoints),
+		Hint:   "确认 -etcd-endpoints 正确、etcd 可以从本机访问，并且节点的账号可以写入；无法访问 etcd 的环境可以跳过 etcd",
+		Run: func(ctx context.Context) error {
+			if len(endpoints) == 0 {
+				return ErrNotConfigured
+			}
+			kv, err := connect(ctx)
+			if err != nil {
+				return fmt.Errorf("连接etcd失败: %w", err)
+			}
+			defer kv.Close()
+
+			value := fmt.Sprintf("selfcheck %d", time.Now().UnixNano())
+			if err := kv.Put(ctx, key, value); err != nil {
+				return fmt.Errorf("写入探测 key 失败: %w", err)
+			}
+			got, ok, err := kv.Get(ctx, key)
+			if err != nil {
+				return fmt.Errorf("读取探测 key 失败: %w", err)
+			}
+			if !ok || got != value {
+				return fmt.Errorf("读回的探测 key 与写入的不一致")
+			}
+			if err := kv.Delete(ctx, key); err != nil {
+				return fmt.Errorf("删除探测 key 失败: %w", err)
+			}
+			return nil
+		},
+	}
+}
+
+// TLSFiles TLS 证书、私钥和 CA 的文件路径
+type TLSFiles struct {
+	Cert string
+	Key  string
+	CA   string
+}
+
+// Configured 是否配置了任何 TLS 文件
+func (f TLSFiles) Configured() bool {
+	return f.Cert != "" || f.Key != "" || f.CA != ""
+}
+
+// TLS 检查证书与私钥能否加载、是否匹配且在有效期内，以及 CA 文件能否解析。
+// 没有配置任何文件时记为跳过
+func TLS(files TLSFiles) Check {
+	return Check{
+		Name:   CheckTLS,
+		Target: files.Cert,
+		Hint:   "确认证书与私钥是配对的 PEM 文件、进程有权限读取，并且证书未过期",
+		Run: func(ctx context.Context) error {
+			if !files.Configured() {
+				return ErrNotConfigured
+			}
+			if (files.Cert == "") != (files.Key == "") {
+				return fmt.Errorf("证书和私钥必须同时配置")
+			}
+			if files.Cert != "" {
+				pair, err := tls.LoadX509KeyPair(files.Cert, files.Key)
+				if err != nil {
+					return fmt.Errorf("加载证书失败: %w", err)
+				}
+				leaf, err := x509.ParseCertificate(pair.Certificate[0])
+				if err != nil {
+					return fmt.Errorf("解析证书失败: %w", err)
+				}
+				now := time.Now()
+				if now.Before(leaf.NotBefore) || now.After(leaf.NotAfter) {
+					return fmt.Errorf("证书不在有效期内 (%s 至 %s)",
+						leaf.NotBefore.Format(time.RFC3339), leaf.NotAfter.Format(time.RFC3339))
+				}
+			}
+			if files.CA != "" {
+				pem, err := os.ReadFile(files.CA)
+				if err != nil {
+					return fmt.Errorf("读取 CA 失败: %w", err)
+				}
+				if !x509.NewCertPool().AppendCertsFromPEM(pem) {
+					return fmt.Errorf("CA 文件 %s 中没有可用的证书", files.CA)
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// Config 节点自检的对象
+type Config struct {
+	Addrs           []string // 节点通告并监听的地址
+	EtcdEndpoints   []string
+	EtcdDialTimeout time.Duration
+	ServiceName     string
+	NodeID          string
+	TLS             TLSFiles
+}
+
+// Checks 返回节点启动前的全部检查，依次为各地址的 bind、etcd、各地址的 dial 和 tls
+func Checks(cfg Config) []Check {
+	var checks []Check
+	for _, addr := range cfg.Addrs {
+		checks = append(checks, Bind(System, addr))
+	}
+	checks = append(checks, Etcd(EtcdConnector(cfg.EtcdEndpoints, cfg.EtcdDialTimeout),
+		cfg.EtcdEndpoints, ProbeKey(cfg.ServiceName, cfg.NodeID)))
+	for _, addr := range cfg.Addrs {
+		checks = append(checks, Dial(System, addr))
+	}
+	return append(checks, TLS(cfg.TLS))
+}
@@ -0,0 +1,158 @@
+// Package selfcheck 实现缓存节点启动前的自检：通告的端口能否绑定、etcd 能否读写、
+// 通告的地址能否连回本机、TLS 证书能否加载。cachenode 的 -self-check 在注册到 etcd
+// 之前运行这些检查，gocache-cli doctor 在启动节点之前单独运行。
+//
+// 每项检查都有名称，可以按名称跳过，用于无法访问 etcd 等隔离网络的部署；
+// 检查依赖的网络和 etcd 都通过接口注入，便于用假实现替换。
+package selfcheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/pkg/logger"
+)
+
+// DefaultTimeout 单项检查的默认超时
+const DefaultTimeout = 5 * time.Second
+
+// 检查项名称，用于跳过
+const (
+	CheckBind = "bind" // 通告的地址可以绑定
+	CheckEtcd = "etcd" // etcd 可以连接并读写探测 key
+	CheckDial = "dial" // 经通告的地址可以连回本机
+	CheckTLS  = "tls"  // TLS 证书、私钥和 CA 可以加载
+)
+
+// names 所有检查项的名称
+var names = []string{CheckBind, CheckEtcd, CheckDial, CheckTLS}
+
+// ErrNotConfigured 检查对象未配置，该项记为跳过而不是失败
+var ErrNotConfigured = errors.New("未配置")
+
+// Status 一项检查的结果
+type Status string
+
+const (
+	StatusOK      Status = "ok"      // 检查通过
+	StatusFailed  Status = "failed"  // 检查失败
+	StatusSkipped Status = "skipped" // 按参数跳过，或检查对象未配置
+)
+
+// Check 一项检查
+type Check struct {
+	Name   string                          // 检查项名称，CheckBind 等
+	Target string                          // 检查的对象，例如地址或文件
+	Hint   string                          // 失败时给出的处理建议
+	Run    func(ctx context.Context) error // 执行检查
+}
+
+// Result 一项检查的结果
+type Result struct {
+	Name    string        `json:"name"`
+	Target  string        `json:"target,omitempty"`
+	Status  Status        `json:"status"`
+	Error   string        `json:"error,omitempty"` // 失败原因或跳过原因
+	Hint    string        `json:"hint,omitempty"`  // 失败时的处理建议
+	Elapsed time.Duration `json:"elapsed"`
+}
+
+// ParseSkip 解析逗号分隔的跳过列表，未知的名称返回错误
+func ParseSkip(s string) (map[string]bool, error) {
+	skip := make(map[string]bool)
+	for _, name := range strings.Split(s, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		known := false
+		for _, n := range names {
+			known = known || n == name
+		}
+		if !known {
+			return nil, fmt.Errorf("未知的自检项 %q，只能是 %s", name, strings.Join(names, "、"))
+		}
+		skip[name] = true
+	}
+	return skip, nil
+}
+
+// Run 依次执行检查，每项最长 timeout（<= 0 时为 DefaultTimeout），名称在 skip 中的检查不执行
+func Run(ctx context.Context, checks []Check, skip map[string]bool, timeout time.Duration) []Result {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	results := make([]Result, 0, len(checks))
+	for _, c := range checks {
+		r := Result{Name: c.Name, Target: c.Target}
+		if skip[c.Name] {
+			r.Status, r.Error = StatusSkipped, "按参数跳过"
+			results = append(results, r)
+			continue
+		}
+		checkCtx, cancel := context.WithTimeout(ctx, timeout)
+		start := time.Now()
+		err := c.Run(checkCtx)
+		r.Elapsed = time.Since(start)
+		cancel()
+		switch {
+		case err == nil:
+			r.Status = StatusOK
+		case errors.Is(err, ErrNotConfigured):
+			r.Status, r.Error = StatusSkipped, err.Error()
+		default:
+			r.Status, r.Error, r.Hint = StatusFailed, err.Error(), c.Hint
+		}
+		results = append(results, r)
+	}
+	return results
+}
+
+// Failed 返回失败的检查
+func Failed(results []Result) []Result {
+	var failed []Result
+	for _, r := range results {
+		if r.Status == StatusFailed {
+			failed = append(failed, r)
+		}
+	}
+	return failed
+}
+
+// Summary 返回各状态的检查数，例如 "通过 3，跳过 1，失败 0"
+func Summary(results []Result) string {
+	counts := make(map[Status]int)
+	for _, r := range results {
+		counts[r.Status]++
+	}
+	return fmt.Sprintf("通过 %d，跳过 %d，失败 %d", counts[StatusOK], counts[StatusSkipped], counts[StatusFailed])
+}
+
+// Log 以结构化日志逐项输出检查结果，最后输出汇总
+func Log(results []Result) {
+	for _, r := range results {
+		entry := logger.WithFields(logger.Fields{
+			"check":   r.Name,
+			"target":  r.Target,
+			"status":  r.Status,
+			"elapsed": r.Elapsed.Round(time.Millisecond).String(),
+		})
+		switch r.Status {
+		case StatusOK:
+			entry.Infof("[自检] 通过")
+		case StatusSkipped:
+			entry.WithFields(logger.Fields{"reason": r.Error}).Infof("[自检] 跳过")
+		default:
+			entry.WithFields(logger.Fields{"error": r.Error, "hint": r.Hint}).Errorf("[自检] 失败")
+		}
+	}
+	logger.Infof("[自检] 完成: %s", Summary(results))
+}
+
+// Names 返回所有检查项的名称
+func Names() []string {
+	return append([]string(nil), names...)
+}
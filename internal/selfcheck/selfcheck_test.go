@@ -0,0 +1,294 @@
+package selfcheck
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeNetwork 监听时在回环地址的随机端口上监听，拨号时由 dial 决定连到哪里
+type fakeNetwork struct {
+	listenErr error
+	dial      func(ctx context.Context, l net.Listener) (net.Conn, error)
+
+	mu sync.Mutex
+	l  net.Listener // 最近一次监听
+}
+
+func (n *fakeNetwork) Listen(network, addr string) (net.Listener, error) {
+	if n.listenErr != nil {
+		return nil, n.listenErr
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	n.mu.Lock()
+	n.l = l
+	n.mu.Unlock()
+	return l, err
+}
+
+func (n *fakeNetwork) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	n.mu.Lock()
+	l := n.l
+	n.mu.Unlock()
+	return n.dial(ctx, l)
+}
+
+// dialListener 连到检查临时监听的地址，即本机
+func dialListener(ctx context.Context, l net.Listener) (net.Conn, error) {
+	var d net.Dialer
+	return d.DialContext(ctx, "tcp", l.Addr().String())
+}
+
+// dialOtherHost 连到另一台主机：对端读取探测数据后返回不同的内容
+func dialOtherHost(ctx context.Context, l net.Listener) (net.Conn, error) {
+	client, server := net.Pipe()
+	go func() {
+		defer server.Close()
+		buf := make([]byte, 16)
+		if _, err := server.Read(buf); err == nil {
+			server.Write(make([]byte, 16))
+		}
+	}()
+	return client, nil
+}
+
+// runOne 执行一项检查并返回结果
+func runOne(c Check) Result {
+	return Run(context.Background(), []Check{c}, nil, time.Second)[0]
+}
+
+func TestBind(t *testing.T) {
+	if r := runOne(Bind(&fakeNetwork{}, "10.0.0.1:9090")); r.Status != StatusOK || r.Target != "10.0.0.1:9090" {
+		t.Fatalf("可以绑定时 = %+v", r)
+	}
+	r := runOne(Bind(&fakeNetwork{listenErr: errors.New("address already in use")}, "10.0.0.1:9090"))
+	if r.Status != StatusFailed || !strings.Contains(r.Error, "address already in use") || r.Hint == "" {
+		t.Fatalf("端口被占用时 = %+v", r)
+	}
+}
+
+func TestDial(t *testing.T) {
+	tests := []struct {
+		name    string
+		network *fakeNetwork
+		want    Status
+		errHas  string
+	}{
+		{"连回本机", &fakeNetwork{dial: dialListener}, StatusOK, ""},
+		{"连到其他主机", &fakeNetwork{dial: dialOtherHost}, StatusFailed, "不是本机"},
+		{"拨号被拒绝", &fakeNetwork{dial: func(context.Context, net.Listener) (net.Conn, error) {
+			return nil, errors.New("connection refused")
+		}}, StatusFailed, "拨号失败"},
+		{"无法临时监听", &fakeNetwork{listenErr: errors.New("cannot assign requested address")}, StatusFailed, "临时监听失败"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := runOne(Dial(tt.network, "10.0.0.1:9090"))
+			if r.Status != tt.want || !strings.Contains(r.Error, tt.errHas) {
+				t.Fatalf("结果 = %+v, want %s 且错误包含 %q", r, tt.want, tt.errHas)
+			}
+		})
+	}
+}
+
+// fakeKV 内存中的 KV，可以让某个操作失败或读回错误的值
+type fakeKV struct {
+	data             map[string]string
+	putErr, getErr   error
+	corrupt, closed  bool
+	deletedAfterRead bool
+}
+
+func (kv *fakeKV) Put(ctx context.Context, key, value string) error {
+	if kv.putErr != nil {
+		return kv.putErr
+	}
+	kv.data[key] = value
+	return nil
+}
+
+func (kv *fakeKV) Get(ctx context.Context, key string) (string, bool, error) {
+	if kv.getErr != nil {
+		return "", false, kv.getErr
+	}
+	v, ok := kv.data[key]
+	if kv.corrupt {
+		v += "x"
+	}
+	return v, ok, nil
+}
+
+func (kv *fakeKV) Delete(ctx context.Context, key string) error {
+	_, kv.deletedAfterRead = kv.data[key]
+	delete(kv.data, key)
+	return nil
+}
+
+func (kv *fakeKV) Close() error {
+	kv.closed = true
+	return nil
+}
+
+func TestEtcd(t *testing.T) {
+	endpoints := []string{"10.0.0.5:2379"}
+	key := ProbeKey("cache", "node-1")
+	connect := func(kv *fakeKV) Connector {
+		return func(context.Context) (KV, error) { return kv, nil }
+	}
+
+	kv := &fakeKV{data: map[string]string{}}
+	if r := runOne(Etcd(connect(kv), endpoints, key)); r.Status != StatusOK {
+		t.Fatalf("etcd 正常时 = %+v", r)
+	}
+	if !kv.deletedAfterRead || len(kv.data) != 0 || !kv.closed {
+		t.Fatalf("探测 key 未清理或连接未关闭: %+v", kv)
+	}
+	if strings.HasPrefix(key, "/cache/") {
+		t.Fatalf("探测 key %q 在节点列表的前缀下", key)
+	}
+
+	tests := []struct {
+		name    string
+		connect Connector
+		errHas  string
+	}{
+		{"无法连接", func(context.Context) (KV, error) { return nil, errors.New("context deadline exceeded") }, "连接etcd失败"},
+		{"无法写入", connect(&fakeKV{data: map[string]string{}, putErr: errors.New("permission denied")}), "写入探测 key 失败"},
+		{"无法读取", connect(&fakeKV{data: map[string]string{}, getErr: errors.New("timeout")}), "读取探测 key 失败"},
+		{"读回的值不一致", connect(&fakeKV{data: map[string]string{}, corrupt: true}), "不一致"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := runOne(Etcd(tt.connect, endpoints, key))
+			if r.Status != StatusFailed || !strings.Contains(r.Error, tt.errHas) {
+				t.Fatalf("结果 = %+v, want 错误包含 %q", r, tt.errHas)
+			}
+		})
+	}
+
+	if r := runOne(Etcd(connect(kv), nil, key)); r.Status != StatusSkipped {
+		t.Fatalf("未配置 etcd 时 = %+v, want skipped", r)
+	}
+}
+
+// writeCert 在 dir 中生成有效期为 [notBefore, notAfter] 的自签名证书和私钥，返回两个文件的路径
+func writeCert(t *testing.T, dir, name string, notBefore, notAfter time.Time) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile = filepath.Join(dir, name+".pem"), filepath.Join(dir, name+"-key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func TestTLS(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	cert, key := writeCert(t, dir, "node", now.Add(-time.Hour), now.Add(time.Hour))
+	expired, expiredKey := writeCert(t, dir, "expired", now.Add(-2*time.Hour), now.Add(-time.Hour))
+	_, otherKey := writeCert(t, dir, "other", now.Add(-time.Hour), now.Add(time.Hour))
+	garbage := filepath.Join(dir, "garbage.pem")
+	if err := os.WriteFile(garbage, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		files  TLSFiles
+		want   Status
+		errHas string
+	}{
+		{"未配置", TLSFiles{}, StatusSkipped, ""},
+		{"有效的证书和 CA", TLSFiles{Cert: cert, Key: key, CA: cert}, StatusOK, ""},
+		{"只有 CA", TLSFiles{CA: cert}, StatusOK, ""},
+		{"缺少私钥", TLSFiles{Cert: cert}, StatusFailed, "同时配置"},
+		{"证书过期", TLSFiles{Cert: expired, Key: expiredKey}, StatusFailed, "有效期"},
+		{"私钥与证书不匹配", TLSFiles{Cert: cert, Key: otherKey}, StatusFailed, "加载证书失败"},
+		{"证书文件不存在", TLSFiles{Cert: filepath.Join(dir, "missing.pem"), Key: key}, StatusFailed, "加载证书失败"},
+		{"CA 中没有证书", TLSFiles{CA: garbage}, StatusFailed, "没有可用的证书"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := runOne(TLS(tt.files))
+			if r.Status != tt.want || !strings.Contains(r.Error, tt.errHas) {
+				t.Fatalf("结果 = %+v, want %s 且错误包含 %q", r, tt.want, tt.errHas)
+			}
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	var deadline bool
+	checks := []Check{
+		{Name: CheckBind, Run: func(ctx context.Context) error {
+			_, deadline = ctx.Deadline()
+			return nil
+		}},
+		{Name: CheckEtcd, Run: func(context.Context) error { t.Fatal("跳过的检查被执行"); return nil }},
+		{Name: CheckDial, Hint: "检查防火墙", Run: func(context.Context) error { return errors.New("connection refused") }},
+		{Name: CheckTLS, Run: func(context.Context) error { return ErrNotConfigured }},
+	}
+	results := Run(context.Background(), checks, map[string]bool{CheckEtcd: true}, 0)
+	if !deadline {
+		t.Fatal("检查的 ctx 没有超时")
+	}
+	want := []Status{StatusOK, StatusSkipped, StatusFailed, StatusSkipped}
+	for i, r := range results {
+		if r.Status != want[i] {
+			t.Fatalf("结果 %d = %+v, want %s", i, r, want[i])
+		}
+	}
+	failed := Failed(results)
+	if len(failed) != 1 || failed[0].Name != CheckDial || failed[0].Hint != "检查防火墙" {
+		t.Fatalf("失败的检查 = %+v", failed)
+	}
+	if s := Summary(results); s != "通过 1，跳过 2，失败 1" {
+		t.Fatalf("汇总 = %q", s)
+	}
+}
+
+func TestParseSkip(t *testing.T) {
+	skip, err := ParseSkip(" etcd, TLS ,,")
+	if err != nil || len(skip) != 2 || !skip[CheckEtcd] || !skip[CheckTLS] {
+		t.Fatalf("ParseSkip = %v, %v", skip, err)
+	}
+	if _, err := ParseSkip("etcd,firewall"); err == nil || !strings.Contains(err.Error(), "firewall") {
+		t.Fatalf("未知的检查项: %v", err)
+	}
+}
@@ -0,0 +1,61 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/AdrianWangs/go-cache/internal/cache"
+	"github.com/AdrianWangs/go-cache/internal/peers"
+	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
+)
+
+// TestServableGroups reads allowed, excluded and unknown groups from a node
+// with an allowlist over both protocols, then reloads the allowlist
+func TestServableGroups(t *testing.T) {
+	servable := cache.NewGroupAllowlist("public", "ghost")
+	node := newTestNode(t, WithGroupAllowlist(servable))
+	node.group("public", echoGetter)
+	node.group("internal", echoGetter)
+
+	read := func(p Protocol, group string) error {
+		return node.getter(WithGetterProtocol(p)).GetByProto(&pb.Request{Group: group, Key: "k"}, &pb.Response{})
+	}
+	for _, p := range []Protocol{ProtocolHTTP, ProtocolProtobuf} {
+		if err := read(p, "public"); err != nil {
+			t.Fatalf("%s: allowed group: %v", p, err)
+		}
+		// an excluded group is refused whether or not it exists
+		for _, group := range []string{"internal", "missing"} {
+			if err := read(p, group); !cache.IsGroupForbiddenError(err) || cache.IsGroupNotFoundError(err) {
+				t.Fatalf("%s: excluded group %q = %v, want ErrGroupForbidden", p, group, err)
+			}
+		}
+		if err := read(p, "ghost"); !cache.IsGroupNotFoundError(err) {
+			t.Fatalf("%s: allowed unknown group = %v, want ErrNoSuchGroup", p, err)
+		}
+	}
+
+	resp, err := http.Get(node.server.URL + node.pool.BasePath() + "internal/k")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden || resp.Header.Get(peers.HeaderErrorCode) != cache.ErrorCodeGroupForbidden {
+		t.Fatalf("excluded group: status %d, code %q", resp.StatusCode, resp.Header.Get(peers.HeaderErrorCode))
+	}
+
+	// a reload takes effect on the next request
+	servable.Set("internal")
+	for _, p := range []Protocol{ProtocolHTTP, ProtocolProtobuf} {
+		if err := read(p, "internal"); err != nil {
+			t.Fatalf("%s: group allowed after reload: %v", p, err)
+		}
+		if err := read(p, "public"); !cache.IsGroupForbiddenError(err) {
+			t.Fatalf("%s: group excluded after reload = %v", p, err)
+		}
+	}
+	servable.Set()
+	if err := read(ProtocolHTTP, "public"); err != nil {
+		t.Fatalf("empty allowlist: %v", err)
+	}
+}
@@ -0,0 +1,41 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/AdrianWangs/go-cache/internal/cache"
+	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
+)
+
+// TestOriginUnavailableCrossesPeers checks that a node whose breaker is open
+// answers 503, and that both getter protocols turn it back into
+// ErrOriginUnavailable rather than a miss
+func TestOriginUnavailableCrossesPeers(t *testing.T) {
+	node := newTestNode(t)
+	node.group("scores", cache.GetterFunc(func(string) ([]byte, error) {
+		return nil, errors.New("database down")
+	}), cache.WithOriginBreaker(cache.BreakerConfig{FailureRate: 1, MinRequests: 1}))
+
+	// The first load fails and opens the breaker
+	if _, err := node.getter().Get("scores", "a"); err == nil || cache.IsOriginUnavailableError(err) {
+		t.Fatalf("first load = %v, want the data source error", err)
+	}
+
+	resp, err := http.Get(node.server.URL + node.pool.BasePath() + "scores/b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("status with the breaker open = %d, want 503", resp.StatusCode)
+	}
+
+	for _, p := range []Protocol{ProtocolHTTP, ProtocolProtobuf} {
+		err := node.getter(WithGetterProtocol(p)).GetByProto(&pb.Request{Group: "scores", Key: "c"}, &pb.Response{})
+		if !cache.IsOriginUnavailableError(err) || cache.IsKeyNotFoundError(err) {
+			t.Fatalf("%s getter = %v, want ErrOriginUnavailable", p, err)
+		}
+	}
+}
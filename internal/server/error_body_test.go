@@ -0,0 +1,87 @@
+package server
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/internal/peers"
+	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
+)
+
+// errorPagePeer starts a peer that answers the key "huge" with a 10MB error
+// page, "page" with an 8KB one and any other key with its name as the value.
+// conns counts the connections the peer accepted.
+func errorPagePeer(t *testing.T) (url string, conns *atomic.Int64) {
+	t.Helper()
+	chunk := []byte(strings.Repeat("x", 32<<10))
+	conns = new(atomic.Int64)
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+		switch key {
+		case "huge":
+			w.WriteHeader(http.StatusBadGateway)
+			for i := 0; i < (10<<20)/len(chunk); i++ {
+				if _, err := w.Write(chunk); err != nil {
+					return
+				}
+			}
+		case "page":
+			w.WriteHeader(http.StatusBadGateway)
+			w.Write(chunk[:8<<10])
+		default:
+			w.Write([]byte(key))
+		}
+	}))
+	srv.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	srv.Start()
+	t.Cleanup(srv.Close)
+	return srv.URL + "/_go_cache/", conns
+}
+
+// TestHugeErrorBody reads a key from a peer answering with a 10MB error page:
+// the error message is truncated, the read allocates a small fraction of the
+// page, and the connections used afterwards are reused
+func TestHugeErrorBody(t *testing.T) {
+	url, conns := errorPagePeer(t)
+	h := NewHTTPGetter(url, WithGetterProtocol(ProtocolHTTP), WithGetterTimeout(10*time.Second))
+	get := func(key string) error {
+		return h.GetByProtoContext(context.Background(), &pb.Request{Group: "scores", Key: key}, &pb.Response{})
+	}
+	get("warm")
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	err := get("huge")
+	runtime.ReadMemStats(&after)
+	if err == nil || !strings.Contains(err.Error(), "truncated") || len(err.Error()) > 2*peers.MaxErrorBody {
+		t.Fatalf("Get of a 10MB error page = %.100v (%d bytes), want a truncated error", err, len(err.Error()))
+	}
+	if n := after.TotalAlloc - before.TotalAlloc; n > 1<<20 {
+		t.Fatalf("reading a 10MB error page allocated %d bytes", n)
+	}
+
+	// Error pages that fit in the drain limit leave the connection reusable
+	opened := conns.Load()
+	for i := 0; i < 20; i++ {
+		if err := get("page"); err == nil || !strings.Contains(err.Error(), "truncated") {
+			t.Fatalf("Get of an 8KB error page = %.100v", err)
+		}
+		if err := get("ok"); err != nil {
+			t.Fatalf("Get after an error page: %v", err)
+		}
+	}
+	if n := conns.Load() - opened; n > 1 {
+		t.Fatalf("%d connections opened for 40 sequential reads, want them reused", n)
+	}
+}
@@ -0,0 +1,49 @@
+package server
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/internal/cache"
+)
+
+// echoGetter returns "v:" followed by the key, so a response shows which key
+// the server decoded
+var echoGetter = cache.GetterFunc(func(key string) ([]byte, error) {
+	return []byte("v:" + key), nil
+})
+
+// testNode is an HTTPPool serving its own registry over httptest
+type testNode struct {
+	registry *cache.Registry
+	pool     *HTTPPool
+	server   *httptest.Server
+}
+
+// newTestNode starts a pool with an empty registry; opts are applied after the registry
+func newTestNode(t testing.TB, opts ...HTTPPoolOption) *testNode {
+	t.Helper()
+	n := &testNode{registry: cache.NewRegistry()}
+	n.server = httptest.NewUnstartedServer(nil)
+	self := "http://" + n.server.Listener.Addr().String()
+	n.pool = NewHTTPPool(self, append([]HTTPPoolOption{WithRegistry(n.registry)}, opts...)...)
+	n.server.Config.Handler = n.pool
+	n.server.Start()
+	t.Cleanup(func() {
+		n.server.Close()
+		n.registry.Close()
+	})
+	return n
+}
+
+// group creates a group on the node
+func (n *testNode) group(name string, getter cache.Getter, opts ...cache.GroupOption) *cache.Group {
+	return cache.NewGroup(name, 1<<20, getter, time.Hour, append([]cache.GroupOption{cache.WithRegistry(n.registry)}, opts...)...)
+}
+
+// getter returns an HTTPGetter for the node
+func (n *testNode) getter(opts ...HTTPGetterOption) *HTTPGetter {
+	return NewHTTPGetter(fmt.Sprintf("%s%s", n.server.URL, n.pool.BasePath()), opts...)
+}
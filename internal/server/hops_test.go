@@ -0,0 +1,58 @@
+package server
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/AdrianWangs/go-cache/internal/cache"
+)
+
+// countingLoader loads "name:key" and counts the loads
+func countingLoader(name string, loads *atomic.Int64) cache.Getter {
+	return cache.GetterFunc(func(key string) ([]byte, error) {
+		loads.Add(1)
+		return []byte(name + ":" + key), nil
+	})
+}
+
+// TestConflictingRingsCutLoop gives two nodes rings that disagree on every key:
+// a routes some keys to b, while b routes all keys to a. Without a hop count b
+// would forward a's request back to a; with it b answers from its own loader.
+func TestConflictingRingsCutLoop(t *testing.T) {
+	for _, protocol := range []Protocol{ProtocolHTTP, ProtocolProtobuf} {
+		t.Run(string(protocol), func(t *testing.T) {
+			a := newTestNode(t, WithProtocol(protocol))
+			b := newTestNode(t, WithProtocol(protocol))
+			var loadsA, loadsB atomic.Int64
+			ga := a.group("scores", countingLoader("a", &loadsA))
+			gb := b.group("scores", countingLoader("b", &loadsB))
+			ga.RegisterPeers(a.pool)
+			gb.RegisterPeers(b.pool)
+
+			a.pool.Set(a.server.URL, b.server.URL)
+			b.pool.Set(a.server.URL) // b does not know it is on the ring
+
+			for i, fetched := 0, 0; fetched < 5; i++ {
+				key := fmt.Sprintf("key-%d", i)
+				if !keyOwnedByNode(a.pool, b, key) {
+					continue
+				}
+				fetched++
+				v, err := ga.Get(key)
+				if err != nil || v.String() != "b:"+key {
+					t.Fatalf("Get(%s) = %q, %v; want b's value", key, v, err)
+				}
+			}
+			if loadsA.Load() != 0 || loadsB.Load() == 0 {
+				t.Fatalf("a loaded %d times, b %d times; the request bounced back to a", loadsA.Load(), loadsB.Load())
+			}
+
+			// a request from a client to b is still forwarded once, to a
+			key := "from-client"
+			if v, err := gb.Get(key); err != nil || v.String() != "a:"+key {
+				t.Fatalf("b.Get(%s) = %q, %v; want a's value", key, v, err)
+			}
+		})
+	}
+}
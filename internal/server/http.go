@@ -2,15 +2,24 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"hash/fnv"
 	"io"
 	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/AdrianWangs/go-cache/internal/auth"
 	"github.com/AdrianWangs/go-cache/internal/cache"
 	"github.com/AdrianWangs/go-cache/internal/consistenthash"
 	"github.com/AdrianWangs/go-cache/internal/peers"
+	"github.com/AdrianWangs/go-cache/pkg/cacheerrors"
 	"github.com/AdrianWangs/go-cache/pkg/logger"
 	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
 	"google.golang.org/protobuf/proto"
@@ -19,6 +28,24 @@ import (
 const (
 	defaultBasePath = "/_gocache/"
 	defaultReplicas = 50
+
+	// StatsPath is appended to the base path to form the protobuf stats route
+	StatsPath = "_stats"
+
+	// DeleteBatchPath is appended to the base path to form the protobuf batch
+	// delete route. Clients use DELETE so that nodes predating the route answer
+	// 400 from the plain delete handler instead of treating it as a read.
+	DeleteBatchPath = "_delete_batch"
+
+	// SetPath is appended to the base path to form the protobuf write route,
+	// used with PUT by peers implementing peers.PeerSetter
+	SetPath = "_set"
+
+	// ListOwnedPath is appended to the base path to form the route answering
+	// ListOwnedBy with a protobuf ListOwnedResponse. The request fields travel
+	// in the query string of a GET, so that nodes predating the route answer
+	// 400 from the plain read handler instead of loading a key.
+	ListOwnedPath = "_list_owned"
 )
 
 // Protocol defines the communication protocol for peer communication
@@ -34,13 +61,40 @@ const (
 
 // HTTPPool implements the server side of the distributed cache protocol
 type HTTPPool struct {
-	self          string                 // this peer's URL (host:port)
-	basePath      string                 // base path of HTTP requests
-	mu            sync.RWMutex           // guards peers and httpGetters
-	peers         *consistenthash.Map    // consistent hash map for peer selection
-	httpGetters   map[string]*HTTPGetter // keyed by peer URL
-	protocol      Protocol               // communication protocol
-	serverCancels []context.CancelFunc   // list of cancel functions for server shutdown
+	self          string                   // this peer's URL (host:port)
+	selfID        string                   // this peer's ring key, defaults to self
+	basePath      string                   // base path of HTTP requests
+	mu            sync.RWMutex             // guards peers, peerList and httpGetters
+	peers         *consistenthash.Map      // consistent hash map keyed by peer ID
+	ringHash      string                   // ring hash name, see consistenthash.NewByName
+	ringVersion   string                   // identifies the current ring, see RingVersion
+	peerList      []Peer                   // canonical peer list of the last applied update
+	httpGetters   map[string]*HTTPGetter   // keyed by peer ID
+	peerCounters  map[string]*peerCounters // traffic counters keyed by peer ID, kept while the peer stays
+	protocol      Protocol                 // protocol used by outgoing getters; serving accepts both
+	serverCancels []context.CancelFunc     // list of cancel functions for server shutdown
+
+	peerTimeout     time.Duration // request timeout of the getters created for peers
+	peerLimit       peers.Limit   // cap on the requests in flight to each peer
+	shutdownTimeout time.Duration // graceful shutdown timeout of started servers
+	startTime       time.Time     // creation time, reported as uptime by the stats route
+	maxHops         int           // hops after which received requests are answered locally
+	hideIdentity    bool          // leave the node ID out of responses, see WithExposeIdentity
+
+	signer   *auth.Signer   // signs requests to peers, nil leaves them unsigned
+	verifier *auth.Verifier // checks incoming requests, nil accepts unsigned ones
+
+	registry *cache.Registry // groups served by the pool, defaults to cache.DefaultRegistry
+
+	servable *cache.GroupAllowlist // groups reads may ask for, nil serves every group
+
+	log logger.Logger // receives the pool's log lines, see WithLogger
+
+	warmup warmupProgress // progress of the last WarmUp
+	prime  primeProgress  // progress of the last Prime
+
+	ringGeneration atomic.Uint64 // generation of peers, readable without mu, see RingGeneration
+	staleRings     sync.Map      // forwarding node ID -> last ring generation logged as stale
 }
 
 // NewHTTPPool initializes an HTTP pool of peers
@@ -50,11 +104,23 @@ func NewHTTPPool(self string, opts ...HTTPPoolOption) *HTTPPool {
 		basePath:    defaultBasePath,
 		protocol:    ProtocolProtobuf, // Use protobuf by default
 		httpGetters: make(map[string]*HTTPGetter),
+
+		peerCounters: make(map[string]*peerCounters),
+
+		peerTimeout:     defaultClientTimeout,
+		shutdownTimeout: defaultShutdownTimeout,
+		startTime:       time.Now(),
+		maxHops:         peers.DefaultMaxHops,
+		registry:        cache.DefaultRegistry(),
 	}
 
 	for _, opt := range opts {
 		opt(pool)
 	}
+	pool.log = logger.Or(pool.log)
+	if pool.selfID == "" {
+		pool.selfID = canonicalBaseURL(self)
+	}
 
 	return pool
 }
@@ -69,17 +135,146 @@ func WithBasePath(basePath string) HTTPPoolOption {
 	}
 }
 
-// WithProtocol configures the HTTPPool protocol
+// WithProtocol configures the protocol used when fetching from peers.
+// The pool always serves both plain HTTP and protobuf requests.
 func WithProtocol(protocol Protocol) HTTPPoolOption {
 	return func(p *HTTPPool) {
 		p.protocol = protocol
 	}
 }
 
+// WithSelfID sets the ID under which this node appears on the ring. It must match
+// the ID the node registers, so that the pool recognizes keys it owns itself.
+func WithSelfID(id string) HTTPPoolOption {
+	return func(p *HTTPPool) {
+		p.selfID = id
+	}
+}
+
+// WithRegistry serves the groups of r instead of the default registry
+func WithRegistry(r *cache.Registry) HTTPPoolOption {
+	return func(p *HTTPPool) {
+		if r != nil {
+			p.registry = r
+		}
+	}
+}
+
+// WithServableGroups restricts the groups the pool serves reads for to names.
+// Reads of any other group are answered with 403 and the group_forbidden error
+// code, whether or not the group exists, so internal-only groups stay
+// unreadable from peers and the API server. No names serves every group.
+func WithServableGroups(names ...string) HTTPPoolOption {
+	return WithGroupAllowlist(cache.NewGroupAllowlist(names...))
+}
+
+// WithGroupAllowlist is WithServableGroups with an allowlist the caller keeps,
+// to replace the groups at runtime or share them with the gRPC server
+func WithGroupAllowlist(a *cache.GroupAllowlist) HTTPPoolOption {
+	return func(p *HTTPPool) {
+		p.servable = a
+	}
+}
+
+// WithPeerTimeout configures the request timeout used when talking to peers
+func WithPeerTimeout(timeout time.Duration) HTTPPoolOption {
+	return func(p *HTTPPool) {
+		if timeout > 0 {
+			p.peerTimeout = timeout
+		}
+	}
+}
+
+// WithPeerLimit caps the requests in flight to each peer, see WithGetterLimit
+func WithPeerLimit(limit peers.Limit) HTTPPoolOption {
+	return func(p *HTTPPool) {
+		p.peerLimit = limit
+	}
+}
+
+// WithShutdownTimeout configures how long Stop waits for in-flight requests
+func WithShutdownTimeout(timeout time.Duration) HTTPPoolOption {
+	return func(p *HTTPPool) {
+		if timeout > 0 {
+			p.shutdownTimeout = timeout
+		}
+	}
+}
+
+// WithRingHash selects the ring hash by name: consistenthash.HashCRC32 (the
+// default, compatible with earlier releases) or consistenthash.HashXXHash64.
+// Every node and the API server must use the same hash, otherwise they
+// disagree on which node owns a key.
+func WithRingHash(name string) HTTPPoolOption {
+	return func(p *HTTPPool) {
+		p.ringHash = name
+	}
+}
+
+// WithMaxHops sets how many times a request may have been forwarded before the
+// pool stops forwarding it and answers from the local cache or data source.
+// The default of peers.DefaultMaxHops cuts loops between nodes whose rings
+// disagree after a single bounce.
+func WithMaxHops(n int) HTTPPoolOption {
+	return func(p *HTTPPool) {
+		if n > 0 {
+			p.maxHops = n
+		}
+	}
+}
+
+// WithExposeIdentity controls whether reads report the node that produced the
+// value, in the node field of the protobuf Response and the X-GoCache-Node
+// header: the pool's own ID for values it cached or loaded, the peer's for
+// values fetched from a peer. It is on by default; deployments that consider
+// the topology sensitive turn it off on every node.
+func WithExposeIdentity(expose bool) HTTPPoolOption {
+	return func(p *HTTPPool) {
+		p.hideIdentity = !expose
+	}
+}
+
+// WithSigner signs the requests the pool sends to peers
+func WithSigner(signer *auth.Signer) HTTPPoolOption {
+	return func(p *HTTPPool) {
+		p.signer = signer
+	}
+}
+
+// WithVerifier makes the pool reject requests whose signature does not verify
+// with 401. Every peer and the API server must then sign with a key v accepts.
+func WithVerifier(v *auth.Verifier) HTTPPoolOption {
+	return func(p *HTTPPool) {
+		p.verifier = v
+	}
+}
+
+// WithLogger sends the log lines of the pool to l instead of the package
+// logger, see logger.SetLogger
+func WithLogger(l logger.Logger) HTTPPoolOption {
+	return func(p *HTTPPool) {
+		p.log = l
+	}
+}
+
+// identity returns the node ID reported in responses, empty when it is hidden
+func (p *HTTPPool) identity() string {
+	if p.hideIdentity {
+		return ""
+	}
+	return p.selfID
+}
+
+// BasePath returns the path prefix the pool serves, for mounting it on another mux
+func (p *HTTPPool) BasePath() string {
+	return p.basePath
+}
+
 // ServeHTTP handles all HTTP requests
 func (p *HTTPPool) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// Log the request
-	logger.Debugf("[Server %s] %s %s", p.self, r.Method, r.URL.Path)
+	p.log.Debugf("[Server %s] %s %s", p.self, r.Method, logger.KeyPath(r.URL.Path, p.basePath))
+	peers.WriteProtoVersion(w.Header())
 
 	// Check if the request path starts with the expected base path
 	if !strings.HasPrefix(r.URL.Path, p.basePath) {
@@ -87,14 +282,59 @@ func (p *HTTPPool) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	switch p.protocol {
-	case ProtocolHTTP:
+	if p.verifier != nil {
+		if err := p.verifier.VerifyRequest(r); err != nil {
+			p.log.Warnf("[Server %s] rejected %s %s from %s: %v", p.self, r.Method, logger.KeyPath(r.URL.Path, p.basePath), r.RemoteAddr, err)
+			http.Error(w, err.Error(), auth.HTTPStatus(err))
+			return
+		}
+	}
+
+	// The stats route speaks protobuf regardless of the configured protocol
+	if r.URL.Path == p.basePath+StatsPath {
+		p.handleStats(w, r)
+		return
+	}
+	if r.URL.Path == p.basePath+DeleteBatchPath {
+		p.handleDeleteBatch(w, r)
+		return
+	}
+	if r.URL.Path == p.basePath+SetPath {
+		p.handleSet(w, r)
+		return
+	}
+	if r.URL.Path == p.basePath+ListOwnedPath {
+		p.handleListOwned(w, r)
+		return
+	}
+
+	// Dispatch per request so that peers configured for different protocols
+	// can talk to each other, e.g. during a protocol migration
+	switch r.Method {
+	case http.MethodGet:
 		p.handleHTTP(w, r)
-	case ProtocolProtobuf:
+	case http.MethodDelete:
+		p.handleDelete(w, r)
+	case http.MethodPost:
+		if !isProtobufContentType(r.Header.Get("Content-Type")) {
+			http.Error(w, "unsupported content type: "+r.Header.Get("Content-Type"), http.StatusUnsupportedMediaType)
+			return
+		}
 		p.handleProtobuf(w, r)
 	default:
-		http.Error(w, "unsupported protocol", http.StatusInternalServerError)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// isProtobufContentType reports whether a POST body should be decoded as protobuf.
+// A missing content type is accepted for clients that predate the header.
+func isProtobufContentType(contentType string) bool {
+	mediaType := strings.TrimSpace(strings.SplitN(contentType, ";", 2)[0])
+	switch mediaType {
+	case "", "application/protobuf", "application/x-protobuf", "application/octet-stream":
+		return true
 	}
+	return false
 }
 
 // handleHTTP handles traditional HTTP GET requests
@@ -105,41 +345,122 @@ func (p *HTTPPool) handleHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Parse the request path: /<basepath>/<groupname>/<key>
-	parts := strings.SplitN(r.URL.Path[len(p.basePath):], "/", 2)
-	if len(parts) != 2 {
+	groupName, key, ok := peers.SplitGroupKey(r.URL.EscapedPath(), p.basePath)
+	if !ok {
 		http.Error(w, "bad request format", http.StatusBadRequest)
 		return
 	}
 
-	groupName := parts[0]
-	key := parts[1]
-
 	// Get the cache group
-	group := cache.GetGroup(groupName)
+	group := p.servableGroup(w, groupName)
 	if group == nil {
-		http.Error(w, "no such group: "+groupName, http.StatusNotFound)
 		return
 	}
 
-	// Get the value
-	view, err := group.Get(key)
+	// Get the value; absent hop headers count as a request from a client
+	var view cache.ByteView
+	var meta cache.ValueMeta
+	var err error
+	if peers.ReadCacheOnly(r.Header) {
+		view, meta, err = peekGroup(group, key)
+	} else {
+		hops, from := peers.ReadHopHeaders(r.Header)
+		p.checkRingGeneration(key, from, peers.ReadRingGeneration(r.Header))
+		ctx := peers.WithForwarding(r.Context(), peers.NewForwarding(p.selfID, hops, from, p.maxHops))
+		ctx = cache.WithClient(ctx, peers.ClientHost(r.RemoteAddr))
+		view, meta, err = group.GetWithMeta(ctx, key)
+	}
 	if err != nil {
-		if cache.IsKeyEmptyError(err) {
-			http.Error(w, "key is empty", http.StatusBadRequest)
-		} else if cache.IsKeyNotFoundError(err) {
-			http.Error(w, fmt.Sprintf("key '%s' not found", key), http.StatusNotFound)
-		} else {
-			logger.Errorf("获取数据错误: %v", err)
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		}
+		writeGetError(w, key, err)
 		return
 	}
 
+	// The metadata the protobuf path returns in Response travels in headers here
+	resp := &pb.Response{}
+	meta.Attribute(p.identity()).FillProto(resp)
+	peers.WriteMetaHeaders(w.Header(), resp)
+
 	// Set Content-Type and write response
 	w.Header().Set("Content-Type", "application/octet-stream")
 	w.Write(view.ByteSlice())
 }
 
+// servableGroup returns the group a read asks for. It answers the request
+// itself and returns nil when the allowlist excludes the group (403) or the
+// group does not exist (404); the allowlist is checked first so that the
+// answer does not reveal whether an excluded group exists.
+func (p *HTTPPool) servableGroup(w http.ResponseWriter, name string) *cache.Group {
+	if !p.servable.Allows(name) {
+		writeError(w, cache.ErrGroupForbidden, "group not servable: "+name)
+		return nil
+	}
+	group := p.registry.Get(name)
+	if group == nil {
+		writeError(w, cache.ErrNoSuchGroup, "no such group: "+name)
+	}
+	return group
+}
+
+// peekGroup answers a cache-only read from the group's local cache
+func peekGroup(group *cache.Group, key string) (cache.ByteView, cache.ValueMeta, error) {
+	if key == "" {
+		return cache.ByteView{}, cache.ValueMeta{}, cache.ErrEmptyKey
+	}
+	view, meta, ok := group.Peek(key)
+	if !ok {
+		return cache.ByteView{}, cache.ValueMeta{}, cache.ErrNotFound
+	}
+	return view, meta, nil
+}
+
+// writeError answers a failed request with the status cacheerrors maps err to,
+// plus the error code header so that clients need not parse msg, and when
+// rate limited the time to retry after
+func writeError(w http.ResponseWriter, err error, msg string) {
+	w.Header().Set(peers.HeaderErrorCode, cacheerrors.ErrorCode(err))
+	if retry := cacheerrors.RetryAfterHeader(err); retry != "" {
+		w.Header().Set(cacheerrors.HeaderRetryAfter, retry)
+	}
+	http.Error(w, msg, cacheerrors.HTTPStatus(err))
+}
+
+// writeGetError answers a failed read, see writeError
+func writeGetError(w http.ResponseWriter, key string, err error) {
+	msg := err.Error()
+	switch {
+	case cache.IsKeyNotFoundError(err):
+		msg = fmt.Sprintf("key '%s' not found", key)
+	case errors.Is(err, context.Canceled):
+		// The client went away; the group counted it and nobody reads the answer
+		logger.Debugf("客户端已断开，放弃读取: key=%s", logger.Key(key))
+	case cacheerrors.HTTPStatus(err) == http.StatusInternalServerError:
+		logger.Errorf("获取数据错误: %v", err)
+	}
+	writeError(w, err, msg)
+}
+
+// handleDelete removes /<basepath>/<group>/<key> from this node's cache
+func (p *HTTPPool) handleDelete(w http.ResponseWriter, r *http.Request) {
+	groupName, key, ok := peers.SplitGroupKey(r.URL.EscapedPath(), p.basePath)
+	if !ok {
+		http.Error(w, "bad request format", http.StatusBadRequest)
+		return
+	}
+
+	group := p.registry.Get(groupName)
+	if group == nil {
+		writeError(w, cache.ErrNoSuchGroup, "no such group: "+groupName)
+		return
+	}
+
+	// Requests reaching the pool are already routed to this node
+	if err := group.DeleteLocally(key); err != nil {
+		writeError(w, err, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
 // handleProtobuf handles protobuf requests
 func (p *HTTPPool) handleProtobuf(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -161,23 +482,24 @@ func (p *HTTPPool) handleProtobuf(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get the cache group
-	group := cache.GetGroup(req.Group)
+	group := p.servableGroup(w, req.Group)
 	if group == nil {
-		http.Error(w, "no such group: "+req.Group, http.StatusNotFound)
 		return
 	}
 
-	// Get the value
-	view, err := group.Get(req.Key)
+	// Get the value; an absent hop count is a request from a client
+	var view cache.ByteView
+	var meta cache.ValueMeta
+	if req.GetCacheOnly() {
+		view, meta, err = peekGroup(group, req.Key)
+	} else {
+		p.checkRingGeneration(req.Key, req.GetFrom(), req.GetRingGeneration())
+		ctx := peers.WithForwarding(r.Context(), peers.NewForwarding(p.selfID, req.GetHops(), req.GetFrom(), p.maxHops))
+		ctx = cache.WithClient(ctx, peers.ClientHost(r.RemoteAddr))
+		view, meta, err = group.GetWithMeta(ctx, req.Key)
+	}
 	if err != nil {
-		if cache.IsKeyEmptyError(err) {
-			http.Error(w, "key is empty", http.StatusBadRequest)
-		} else if cache.IsKeyNotFoundError(err) {
-			http.Error(w, fmt.Sprintf("key '%s' not found", req.Key), http.StatusNotFound)
-		} else {
-			logger.Errorf("获取数据错误: %v", err)
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		}
+		writeGetError(w, req.Key, err)
 		return
 	}
 
@@ -185,6 +507,7 @@ func (p *HTTPPool) handleProtobuf(w http.ResponseWriter, r *http.Request) {
 	resp := &pb.Response{
 		Value: view.ByteSlice(),
 	}
+	meta.Attribute(p.identity()).FillProto(resp)
 
 	data, err := proto.Marshal(resp)
 	if err != nil {
@@ -197,23 +520,405 @@ func (p *HTTPPool) handleProtobuf(w http.ResponseWriter, r *http.Request) {
 	w.Write(data)
 }
 
-// Set updates the pool's peers
+// handleStats answers a protobuf StatsRequest with the node's group statistics
+func (p *HTTPPool) handleStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "error reading request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	req := &pb.StatsRequest{}
+	if err := proto.Unmarshal(body, req); err != nil {
+		http.Error(w, "error unmarshaling request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp, err := p.registry.StatsResponse(req.GetGroup(), time.Since(p.startTime))
+	if err != nil {
+		if cache.IsGroupNotFoundError(err) {
+			writeError(w, err, "no such group: "+req.GetGroup())
+		} else {
+			writeError(w, err, err.Error())
+		}
+		return
+	}
+	resp.Peers = peers.StatsProto(p.PeerStats())
+	resp.Warmup = p.WarmupStats()
+	resp.Prime = p.PrimeStats()
+	resp.RingGeneration = proto.Uint64(p.RingGeneration())
+
+	data, err := proto.Marshal(resp)
+	if err != nil {
+		http.Error(w, "error marshaling response: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/protobuf")
+	w.Write(data)
+}
+
+// handleDeleteBatch answers a protobuf DeleteBatchRequest with one result per key
+func (p *HTTPPool) handleDeleteBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "error reading request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	req := &pb.DeleteBatchRequest{}
+	if err := proto.Unmarshal(body, req); err != nil {
+		http.Error(w, "error unmarshaling request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	group := p.registry.Get(req.GetGroup())
+	if group == nil {
+		writeError(w, cache.ErrNoSuchGroup, "no such group: "+req.GetGroup())
+		return
+	}
+
+	results, err := group.DeleteBatch(req.GetKeys())
+	if err != nil {
+		writeError(w, err, err.Error())
+		return
+	}
+
+	data, err := proto.Marshal(cache.DeleteResultsProto(results))
+	if err != nil {
+		http.Error(w, "error marshaling response: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/protobuf")
+	w.Write(data)
+}
+
+// handleSet answers a protobuf SetRequest by storing the value in this node's cache
+func (p *HTTPPool) handleSet(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "error reading request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	req := &pb.SetRequest{}
+	if err := proto.Unmarshal(body, req); err != nil {
+		http.Error(w, "error unmarshaling request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	group := p.registry.Get(req.GetGroup())
+	if group == nil {
+		writeError(w, cache.ErrNoSuchGroup, "no such group: "+req.GetGroup())
+		return
+	}
+
+	ttl := time.Duration(req.GetTtlMs()) * time.Millisecond
+	if err := group.SetLocally(req.GetKey(), req.GetValue(), ttl); err != nil {
+		writeError(w, err, err.Error())
+		return
+	}
+
+	data, err := proto.Marshal(&pb.SetResponse{Success: true})
+	if err != nil {
+		http.Error(w, "error marshaling response: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/protobuf")
+	w.Write(data)
+}
+
+// handleListOwned answers a ListOwnedBy request, see ListOwned and ListOwnedPath
+func (p *HTTPPool) handleListOwned(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	req, err := listOwnedFromQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp, err := p.ListOwned(req)
+	if err != nil {
+		switch {
+		case cache.IsGroupForbiddenError(err):
+			writeError(w, err, "group not servable: "+req.GetGroup())
+		case cache.IsGroupNotFoundError(err):
+			writeError(w, err, "no such group: "+req.GetGroup())
+		case errors.Is(err, peers.ErrRingMismatch):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+		return
+	}
+
+	data, err := proto.Marshal(resp)
+	if err != nil {
+		http.Error(w, "error marshaling response: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/protobuf")
+	w.Write(data)
+}
+
+// listOwnedFromQuery parses the query string of a ListOwnedBy request
+func listOwnedFromQuery(q url.Values) (*pb.ListOwnedRequest, error) {
+	req := &pb.ListOwnedRequest{
+		Group:       q.Get("group"),
+		RingVersion: q.Get("ring_version"),
+		NodeId:      q.Get("node_id"),
+	}
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.ParseInt(v, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid limit %q", v)
+		}
+		req.Limit = proto.Int32(int32(limit))
+	}
+	if q.Has("cursor") {
+		req.Cursor = proto.String(q.Get("cursor"))
+	}
+	return req, nil
+}
+
+// listOwnedQuery encodes a ListOwnedBy request as a query string
+func listOwnedQuery(req *pb.ListOwnedRequest) url.Values {
+	q := url.Values{}
+	q.Set("group", req.GetGroup())
+	q.Set("ring_version", req.GetRingVersion())
+	q.Set("node_id", req.GetNodeId())
+	if req.Limit != nil {
+		q.Set("limit", strconv.Itoa(int(req.GetLimit())))
+	}
+	if req.Cursor != nil {
+		q.Set("cursor", req.GetCursor())
+	}
+	return q
+}
+
+// Peer identifies a node on the ring and the address used to reach it
+type Peer struct {
+	ID   string // stable ring key, see discovery.NodeInfo.Key
+	Addr string // base URL of the node's HTTP server, e.g. http://10.0.0.1:9091
+}
+
+// Set updates the pool's list of peers, each string being both the peer's ID and
+// its base URL. The URLs are canonicalized first (see canonicalBaseURL), so
+// "http://Node1:8001/" and "http://node1:8001" are the same peer. Use SetPeers
+// when IDs and addresses differ.
 func (p *HTTPPool) Set(peers ...string) {
+	list := make([]Peer, 0, len(peers))
+	for _, peer := range peers {
+		peer = canonicalBaseURL(peer)
+		list = append(list, Peer{ID: peer, Addr: peer})
+	}
+	p.SetPeers(list...)
+}
+
+// SetPeers updates the pool's list of peers. The ring is keyed by peer ID so key
+// ownership survives address changes; the address is only used for dialing.
+// Updates that leave the canonical peer set unchanged are ignored, and getters of
+// peers whose address did not change are kept along with their connections.
+// Traffic counters survive for every peer that stays in the set.
+//
+// The input order does not matter: every node handed the same membership builds
+// the same ring. Duplicate IDs would give a peer twice its virtual nodes, so only
+// the first occurrence of an ID is kept and a warning names the dropped ones.
+func (p *HTTPPool) SetPeers(peers ...Peer) {
+	list, duplicates := canonicalPeers(peers)
+	if len(duplicates) > 0 {
+		p.log.Warnf("Dropped duplicate peers from the peer list: %v", duplicates)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.setPeersLocked(list)
+}
+
+// UpdatePeers applies an incremental change to the pool's peers, as received
+// from a peer list delta: the peers with IDs in removed leave, and each peer in
+// added joins or replaces the peer with the same ID. The result is the same as
+// SetPeers with the full list, without the caller having to keep one.
+func (p *HTTPPool) UpdatePeers(added []Peer, removed []string) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	// Create consistent hash map
-	p.peers = consistenthash.New(defaultReplicas, nil)
-	p.peers.Add(peers...)
+	byID := make(map[string]Peer, len(p.peerList)+len(added))
+	for _, peer := range p.peerList {
+		byID[peer.ID] = peer
+	}
+	for _, id := range removed {
+		delete(byID, id)
+	}
+	for _, peer := range added {
+		byID[peer.ID] = peer
+	}
+	list := make([]Peer, 0, len(byID))
+	for _, peer := range byID {
+		list = append(list, peer)
+	}
+	list, _ = canonicalPeers(list)
+	p.setPeersLocked(list)
+}
+
+// setPeersLocked installs a canonical peer list; p.mu must be held
+func (p *HTTPPool) setPeersLocked(list []Peer) {
+	if p.peers != nil && samePeers(p.peerList, list) {
+		p.log.Debugf("Cache pool peers unchanged (%d peers), skipping update", len(list))
+		return
+	}
+
+	ids := make([]string, 0, len(list))
+	getters := make(map[string]*HTTPGetter, len(list))
+	counters := make(map[string]*peerCounters, len(list))
+	for _, peer := range list {
+		ids = append(ids, peer.ID)
+		if peer.ID == p.selfID { // Don't create a client to ourselves
+			continue
+		}
+		c, ok := p.peerCounters[peer.ID]
+		if !ok {
+			c = new(peerCounters)
+		}
+		counters[peer.ID] = c
+		if g, ok := p.httpGetters[peer.ID]; ok && g.baseURL == peer.Addr+p.basePath {
+			getters[peer.ID] = g
+			continue
+		}
+		getters[peer.ID] = NewHTTPGetter(peer.Addr+p.basePath,
+			WithGetterTimeout(p.peerTimeout),
+			WithGetterLimit(p.peerLimit),
+			WithGetterProtocol(p.protocol),
+			withGetterCounters(c),
+			withGetterIdentity(peer.ID, p.selfID),
+			withGetterRingGeneration(p.RingGeneration),
+			WithGetterSigner(p.signer),
+		)
+	}
+
+	// Create consistent hash map, continuing the generation of the replaced one
+	var generation uint64
+	if p.peers != nil {
+		generation = p.peers.Generation()
+	}
+	p.peers = p.newRing(consistenthash.WithGeneration(generation))
+	p.peers.Add(ids...)
+	p.ringGeneration.Store(p.peers.Generation())
+	p.ringVersion = ringVersion(p.peers, ids)
+	p.httpGetters = getters
+	p.peerCounters = counters
+	p.peerList = list
+
+	p.log.Infof("Cache pool set %d peers: %v", len(list), list)
+}
+
+// Peers returns the IDs of the current peers, including this node, sorted
+func (p *HTTPPool) Peers() []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	ids := make([]string, 0, len(p.peerList))
+	for _, peer := range p.peerList {
+		ids = append(ids, peer.ID)
+	}
+	return ids
+}
+
+// PeerStats returns the traffic and error counters of every current peer other
+// than this node, keyed by peer ID
+func (p *HTTPPool) PeerStats() map[string]PeerStats {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
 
-	// Create HTTP clients for each peer
+	stats := make(map[string]PeerStats, len(p.peerCounters))
+	for id, c := range p.peerCounters {
+		stats[id] = c.Snapshot()
+	}
+	return stats
+}
+
+// canonicalPeers trims peer IDs, canonicalizes addresses, drops peers without
+// an ID and later duplicates of an ID, and sorts by ID, so that the same set in
+// a different order compares equal. The dropped duplicate IDs are returned.
+func canonicalPeers(peers []Peer) (list []Peer, duplicates []string) {
+	list = make([]Peer, 0, len(peers))
+	seen := make(map[string]struct{}, len(peers))
 	for _, peer := range peers {
-		if peer != p.self { // Don't create a client to ourselves
-			p.httpGetters[peer] = NewHTTPGetter(peer + p.basePath)
+		peer.ID = strings.TrimSpace(peer.ID)
+		peer.Addr = canonicalBaseURL(peer.Addr)
+		if peer.ID == "" {
+			continue
+		}
+		if _, ok := seen[peer.ID]; ok {
+			duplicates = append(duplicates, peer.ID)
+			continue
+		}
+		seen[peer.ID] = struct{}{}
+		list = append(list, peer)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list, duplicates
+}
+
+// canonicalBaseURL trims whitespace and trailing slashes from a peer's base URL
+// and lowercases its scheme and host, which are case-insensitive
+func canonicalBaseURL(addr string) string {
+	addr = strings.TrimRight(strings.TrimSpace(addr), "/")
+	scheme, rest, ok := strings.Cut(addr, "://")
+	if !ok {
+		return addr
+	}
+	host, path, _ := strings.Cut(rest, "/")
+	if path != "" {
+		path = "/" + path
+	}
+	return strings.ToLower(scheme) + "://" + strings.ToLower(host) + path
+}
+
+// samePeers reports whether two canonical peer lists are equal
+func samePeers(a, b []Peer) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
 		}
 	}
+	return true
+}
 
-	logger.Infof("Cache pool set %d peers: %v", len(peers), peers)
+// newRing creates an empty ring with the configured hash, falling back to the
+// crc32 compat ring if the name is invalid
+func (p *HTTPPool) newRing(opts ...consistenthash.Option) *consistenthash.Map {
+	ring, err := consistenthash.NewByName(defaultReplicas, p.ringHash, opts...)
+	if err != nil {
+		p.log.Errorf("Invalid ring hash, using %s: %v", consistenthash.HashCRC32, err)
+		return consistenthash.NewCompat(defaultReplicas, opts...)
+	}
+	return ring
 }
 
 // PickPeer picks a peer according to key
@@ -225,14 +930,117 @@ func (p *HTTPPool) PickPeer(key string) (peers.PeerGetter, bool) {
 		return nil, false
 	}
 
-	if peer := p.peers.Get(key); peer != "" && peer != p.self {
-		logger.Debugf("Pick peer %s for key %s", peer, key)
+	if peer := p.peers.Get(key); peer != "" && peer != p.selfID {
+		p.log.Debugf("Pick peer %s for key %s", peer, logger.Key(key))
 		return p.httpGetters[peer], true
 	}
 
 	return nil, false
 }
 
+// PickOwner implements peers.OwnerPicker, telling keys this node owns apart from
+// keys that have no owner because no peers were set yet
+func (p *HTTPPool) PickOwner(key string) peers.PickResult {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.peers == nil {
+		return peers.PickResult{State: peers.PickNoPeer}
+	}
+
+	switch peer := p.peers.Get(key); {
+	case peer == "":
+		return peers.PickResult{State: peers.PickNoPeer}
+	case peer == p.selfID:
+		return peers.PickResult{State: peers.PickSelf}
+	default:
+		return peers.PickResult{State: peers.PickRemote, Peer: p.httpGetters[peer]}
+	}
+}
+
+// PickReplicas implements peers.ReplicaPicker with the nodes following the owner
+// of key on the pool's ring
+func (p *HTTPPool) PickReplicas(key string, n int) []peers.PeerGetter {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.peers == nil || n <= 0 {
+		return nil
+	}
+	var replicas []peers.PeerGetter
+	for i, id := range p.peers.GetN(key, n+1) {
+		if getter, ok := p.httpGetters[id]; ok && i > 0 && id != p.selfID {
+			replicas = append(replicas, getter)
+		}
+	}
+	return replicas
+}
+
+// RingVersion identifies the pool's current ring: nodes with equal versions
+// assign every key to the same owner. It is empty before the first SetPeers.
+func (p *HTTPPool) RingVersion() string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.ringVersion
+}
+
+// RingGeneration returns the generation of the pool's ring: 0 before the first
+// SetPeers, then incremented by every update that changes the peer set. It is
+// local to the node; nodes on the same ring may report different generations.
+func (p *HTTPPool) RingGeneration() uint64 {
+	return p.ringGeneration.Load()
+}
+
+// checkRingGeneration logs when a peer forwarded key to this node although this
+// node's ring, of a newer generation than the peer's, assigns it elsewhere: the
+// peer has not applied the latest membership change yet. Each peer is logged
+// once per generation it is seen on.
+func (p *HTTPPool) checkRingGeneration(key, from string, generation uint64) {
+	if from == "" || generation == 0 {
+		return
+	}
+	p.mu.RLock()
+	var owner string
+	if p.peers != nil {
+		owner = p.peers.Get(key)
+	}
+	p.mu.RUnlock()
+
+	current := p.RingGeneration()
+	if owner == "" || owner == p.selfID || generation >= current {
+		return
+	}
+	if last, ok := p.staleRings.Swap(from, generation); ok && last.(uint64) == generation {
+		return
+	}
+	p.log.Warnf("Peer %s forwarded key %s on ring generation %d, ours is %d and assigns it to %s", from, logger.Key(key), generation, current, owner)
+}
+
+// ringVersion hashes what determines key ownership: the ring's hash function,
+// its virtual node count and the sorted member IDs
+func ringVersion(ring *consistenthash.Map, ids []string) string {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%s/%d", ring.HashName(), ring.Replicas())
+	for _, id := range ids {
+		h.Write([]byte{0})
+		io.WriteString(h, id)
+	}
+	return strconv.FormatUint(h.Sum64(), 16)
+}
+
+// Ring reports the shape of the pool's hash ring and how samples synthetic keys
+// are distributed across peers. Before the first SetPeers the ring is empty.
+func (p *HTTPPool) Ring(samples int) consistenthash.Report {
+	p.mu.RLock()
+	ring := p.peers
+	p.mu.RUnlock()
+
+	if ring == nil {
+		ring = p.newRing()
+	}
+	return ring.Report(samples)
+}
+
 // Start starts the HTTP server
 func (p *HTTPPool) Start(host string, port int) error {
 	addr := fmt.Sprintf("%s:%d", host, port)
@@ -247,22 +1055,22 @@ func (p *HTTPPool) Start(host string, port int) error {
 	p.serverCancels = append(p.serverCancels, cancel)
 	p.mu.Unlock()
 
-	logger.Infof("Cache server started on %s", addr)
+	p.log.Infof("Cache server started on %s", addr)
 
 	go func() {
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			logger.Errorf("Cache server error: %v", err)
+			p.log.Errorf("Cache server error: %v", err)
 		}
 	}()
 
 	go func() {
 		<-ctx.Done()
-		logger.Info("Shutting down cache server...")
-		shutdownCtx, cancel := context.WithTimeout(context.Background(), defaultClientTimeout)
+		p.log.Infof("Shutting down cache server...")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), p.shutdownTimeout)
 		defer cancel()
 
 		if err := server.Shutdown(shutdownCtx); err != nil {
-			logger.Errorf("Error shutting down cache server: %v", err)
+			p.log.Errorf("Error shutting down cache server: %v", err)
 		}
 	}()
 
@@ -281,5 +1089,9 @@ func (p *HTTPPool) Stop() {
 	p.serverCancels = nil
 }
 
-// Ensure HTTPPool implements peers.PeerPicker
-var _ peers.PeerPicker = (*HTTPPool)(nil)
+// Ensure HTTPPool implements peers.PeerPicker, peers.OwnerPicker and peers.ReplicaPicker
+var (
+	_ peers.PeerPicker    = (*HTTPPool)(nil)
+	_ peers.OwnerPicker   = (*HTTPPool)(nil)
+	_ peers.ReplicaPicker = (*HTTPPool)(nil)
+)
@@ -6,84 +6,267 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"net/url"
+	"strings"
 	"time"
 
+	"github.com/AdrianWangs/go-cache/internal/auth"
 	"github.com/AdrianWangs/go-cache/internal/cache"
+	"github.com/AdrianWangs/go-cache/internal/peers"
+	"github.com/AdrianWangs/go-cache/pkg/cacheerrors"
 	"github.com/AdrianWangs/go-cache/pkg/logger"
 	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
 	"google.golang.org/protobuf/proto"
 )
 
 const (
-	defaultClientTimeout = 5 * time.Second
+	defaultClientTimeout   = 5 * time.Second
+	defaultShutdownTimeout = 5 * time.Second
+
+	// maxPresizedValue caps the allocation made up front from a peer's
+	// Content-Length; longer bodies are read incrementally
+	maxPresizedValue = 32 << 20
 )
 
 // HTTPGetter is a client to fetch cache data from peer
 type HTTPGetter struct {
-	baseURL string        // base URL of the remote server
-	client  *http.Client  // HTTP client for making requests
-	timeout time.Duration // timeout for HTTP requests
+	baseURL  string        // base URL of the remote server
+	client   *http.Client  // HTTP client for making requests
+	timeout  time.Duration // timeout for HTTP requests
+	protocol Protocol      // wire format used by GetByProto
+	counters *peerCounters // traffic and error counters of the peer, shared across getter replacement
+	limit    peers.Limit   // cap on the requests in flight to the peer, kept with counters
+	id       string        // ring ID of the peer, used in logs
+	self     string        // ring ID of the node owning the getter, sent as the forwarding node
+
+	version peers.PeerVersion // protocol version the peer advertised in its responses
+
+	ringGeneration func() uint64 // generation of the owning pool's ring, sent with forwarded requests
+}
+
+// HTTPGetter implements the base peer interface and every optional capability
+var (
+	_ peers.PeerGetter      = (*HTTPGetter)(nil)
+	_ peers.PeerGetterCtx   = (*HTTPGetter)(nil)
+	_ peers.PeerDeleter     = (*HTTPGetter)(nil)
+	_ peers.PeerSetter      = (*HTTPGetter)(nil)
+	_ peers.PeerOwnedLister = (*HTTPGetter)(nil)
+)
+
+// HTTPGetterOption configures an HTTPGetter
+type HTTPGetterOption func(*HTTPGetter)
+
+// WithGetterTimeout configures the per-request timeout of an HTTPGetter
+func WithGetterTimeout(timeout time.Duration) HTTPGetterOption {
+	return func(h *HTTPGetter) {
+		if timeout > 0 {
+			h.SetTimeout(timeout)
+		}
+	}
+}
+
+// WithGetterProtocol selects the wire format used by GetByProto: protobuf POSTs
+// (the default) or plain HTTP GETs for peers that only speak the plain protocol
+func WithGetterProtocol(protocol Protocol) HTTPGetterOption {
+	return func(h *HTTPGetter) {
+		if protocol != "" {
+			h.protocol = protocol
+		}
+	}
+}
+
+// WithGetterSigner signs every request of the getter with signer, for peers
+// that verify internal requests
+func WithGetterSigner(signer *auth.Signer) HTTPGetterOption {
+	return func(h *HTTPGetter) {
+		if signer != nil {
+			h.client.Transport = signer.Transport(nil)
+		}
+	}
+}
+
+// WithGetterLimit caps the requests the getter has in flight to its peer; the
+// ones over the cap fail with peers.ErrPeerBusy, after waiting up to
+// limit.QueueWait for a slot, instead of waiting on a slow peer
+func WithGetterLimit(limit peers.Limit) HTTPGetterOption {
+	return func(h *HTTPGetter) {
+		h.limit = limit
+	}
+}
+
+// withGetterCounters makes the getter record its traffic into counters, which
+// the pool keeps per peer so they outlive the getter
+func withGetterCounters(counters *peerCounters) HTTPGetterOption {
+	return func(h *HTTPGetter) {
+		h.counters = counters
+	}
+}
+
+// withGetterIdentity records the IDs of the peer and of the node forwarding to it
+func withGetterIdentity(id, self string) HTTPGetterOption {
+	return func(h *HTTPGetter) {
+		h.id = id
+		h.self = self
+	}
+}
+
+// withGetterRingGeneration makes forwarded requests carry the generation fn
+// reports, so the receiver can tell a requester on an older ring
+func withGetterRingGeneration(fn func() uint64) HTTPGetterOption {
+	return func(h *HTTPGetter) {
+		h.ringGeneration = fn
+	}
 }
 
 // NewHTTPGetter creates a new HTTP client for fetching cache data
-func NewHTTPGetter(baseURL string) *HTTPGetter {
-	return &HTTPGetter{
+func NewHTTPGetter(baseURL string, opts ...HTTPGetterOption) *HTTPGetter {
+	h := &HTTPGetter{
 		baseURL: baseURL,
 		client: &http.Client{
 			Timeout: defaultClientTimeout,
 		},
-		timeout: defaultClientTimeout,
+		timeout:  defaultClientTimeout,
+		protocol: ProtocolProtobuf,
+		counters: new(peerCounters),
+	}
+
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	return h
+}
+
+// Compile-time checks for the optional peer capabilities
+var (
+	_ peers.PeerGetter    = (*HTTPGetter)(nil)
+	_ peers.PeerGetterCtx = (*HTTPGetter)(nil)
+	_ peers.PeerDeleter   = (*HTTPGetter)(nil)
+	_ peers.PeerSetter    = (*HTTPGetter)(nil)
+
+	_ peers.PeerOwnedLister = (*HTTPGetter)(nil)
+)
+
+// String identifies the peer in logs by its ring ID, or its base URL if unknown
+func (h *HTTPGetter) String() string {
+	if h.id != "" {
+		return h.id
 	}
+	return h.baseURL
 }
 
-// Get fetches data from a peer using HTTP
-func (h *HTTPGetter) Get(group string, key string) ([]byte, error) {
-	u := fmt.Sprintf(
+// keyURL returns the plain HTTP URL of group/key on the peer
+func (h *HTTPGetter) keyURL(group, key string) string {
+	return fmt.Sprintf(
 		"%v/%v/%v",
-		h.baseURL,
-		url.QueryEscape(group),
-		url.QueryEscape(key),
+		strings.TrimSuffix(h.baseURL, "/"),
+		peers.EscapePathSegment(group),
+		peers.EscapePathSegment(key),
 	)
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), h.timeout)
+// Get fetches data from a peer using HTTP
+func (h *HTTPGetter) Get(group string, key string) ([]byte, error) {
+	req, resp := peers.GetRequest(), peers.GetResponse()
+	defer peers.PutRequest(req)
+	defer peers.PutResponse(resp)
+
+	req.Group, req.Key = group, key
+	if err := h.get(context.Background(), req, resp); err != nil {
+		return nil, err
+	}
+	return resp.Value, nil
+}
+
+// get fetches data from a peer with a plain HTTP GET, aborting when ctx is done.
+// The hop headers carry the forwarding fields of req and the metadata headers
+// fill the same Response fields the protobuf path sets.
+func (h *HTTPGetter) get(ctx context.Context, req *pb.Request, resp *pb.Response) error {
+	u := h.keyURL(req.Group, req.Key)
+
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
 	defer cancel()
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	peers.WriteHopHeaders(httpReq.Header, req)
+
+	release, err := h.counters.Acquire(ctx, h.limit)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return err
 	}
+	defer release()
 
-	res, err := h.client.Do(req)
+	call := h.counters.Start(0)
+	res, err := h.client.Do(httpReq)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get from peer: %w", err)
+		call.Fail(err)
+		return fmt.Errorf("failed to get from peer: %w", err)
 	}
-	defer res.Body.Close()
+	defer peers.CloseBody(res.Body)
 
-	if res.StatusCode == http.StatusNotFound {
-		return nil, cache.ErrNotFound
-	} else if res.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("peer returned non-200 status: %v", res.Status)
+	h.version.Observe(res.Header)
+	call.Status(res.StatusCode)
+	if err := readStatusError(res); err != nil {
+		return err
 	}
 
-	bytes, err := io.ReadAll(res.Body)
+	value, err := readValue(res)
+	call.Received(len(value))
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		call.Fail(err)
+		return fmt.Errorf("failed to read response body: %w", err)
 	}
 
-	return bytes, nil
+	resp.Value = value
+	return peers.ReadMetaHeaders(res.Header, resp)
+}
+
+// readValue reads a plain GET response body, which becomes the caller's value.
+// A known length is read into one exactly sized slice instead of the doubling
+// buffers of io.ReadAll.
+func readValue(res *http.Response) ([]byte, error) {
+	if res.ContentLength <= 0 || res.ContentLength > maxPresizedValue {
+		return io.ReadAll(res.Body)
+	}
+	value := make([]byte, res.ContentLength)
+	n, err := io.ReadFull(res.Body, value)
+	return value[:n], err
 }
 
-// GetByProto fetches data from peer using Protocol Buffers
+// GetByProto fetches data from peer using Protocol Buffers, or with a plain
+// HTTP GET when the getter is configured for ProtocolHTTP
 func (h *HTTPGetter) GetByProto(req *pb.Request, resp *pb.Response) error {
+	return h.GetByProtoContext(context.Background(), req, resp)
+}
+
+// GetByProtoContext is like GetByProto but aborts when ctx is done. The getter's
+// timeout applies as a deadline on top of ctx.
+func (h *HTTPGetter) GetByProtoContext(ctx context.Context, req *pb.Request, resp *pb.Response) error {
+	if req.Hops != nil && req.From == nil && h.self != "" {
+		// Tell the peer who forwarded, without modifying the caller's request
+		fwd := peers.GetRequest()
+		defer peers.PutRequest(fwd)
+		proto.Merge(fwd, req)
+		fwd.From = proto.String(h.self)
+		if h.ringGeneration != nil {
+			fwd.RingGeneration = proto.Uint64(h.ringGeneration())
+		}
+		req = fwd
+	}
+	if h.protocol == ProtocolHTTP {
+		return h.get(ctx, req, resp)
+	}
+
 	// Serialize the request to protobuf
 	data, err := proto.Marshal(req)
 	if err != nil {
 		return fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	// Create a context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), h.timeout)
+	// Apply the request timeout on top of the caller's context
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
 	defer cancel()
 
 	// Create HTTP request
@@ -94,28 +277,41 @@ func (h *HTTPGetter) GetByProto(req *pb.Request, resp *pb.Response) error {
 	}
 	httpReq.Header.Set("Content-Type", "application/protobuf")
 
+	release, err := h.counters.Acquire(ctx, h.limit)
+	if err != nil {
+		return err
+	}
+	defer release()
+
 	// Execute request
+	call := h.counters.Start(len(data))
 	httpResp, err := h.client.Do(httpReq)
 	if err != nil {
+		call.Fail(err)
 		return fmt.Errorf("failed to get from peer: %w", err)
 	}
-	defer httpResp.Body.Close()
+	defer peers.CloseBody(httpResp.Body)
 
 	// Check response status
-	if httpResp.StatusCode == http.StatusNotFound {
-		return cache.ErrNotFound
-	} else if httpResp.StatusCode != http.StatusOK {
-		return fmt.Errorf("peer returned non-200 status: %v", httpResp.Status)
+	h.version.Observe(httpResp.Header)
+	call.Status(httpResp.StatusCode)
+	if err := readStatusError(httpResp); err != nil {
+		return err
 	}
 
-	// Read and parse response
-	respBody, err := io.ReadAll(httpResp.Body)
+	// Read the response into a pooled buffer; Unmarshal copies the value out of it
+	buf := peers.GetBuffer()
+	defer peers.PutBuffer(buf)
+	n, err := buf.ReadFrom(httpResp.Body)
+	call.Received(int(n))
 	if err != nil {
+		call.Fail(err)
 		return fmt.Errorf("failed to read response body: %w", err)
 	}
 
 	// Unmarshal response
-	if err = proto.Unmarshal(respBody, resp); err != nil {
+	if err = proto.Unmarshal(buf.Bytes(), resp); err != nil {
+		call.Fail(err)
 		logger.Errorf("Failed to unmarshal response: %v", err)
 		return fmt.Errorf("failed to unmarshal response: %w", err)
 	}
@@ -123,6 +319,214 @@ func (h *HTTPGetter) GetByProto(req *pb.Request, resp *pb.Response) error {
 	return nil
 }
 
+// DeleteByProto removes the key from the peer's cache with a plain HTTP DELETE,
+// which every version of the pool serves
+func (h *HTTPGetter) DeleteByProto(ctx context.Context, req *pb.DeleteRequest, resp *pb.DeleteResponse) error {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodDelete, h.keyURL(req.Group, req.Key), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	release, err := h.counters.Acquire(ctx, h.limit)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	call := h.counters.Start(0)
+	httpResp, err := h.client.Do(httpReq)
+	if err != nil {
+		call.Fail(err)
+		return fmt.Errorf("failed to delete on peer: %w", err)
+	}
+	defer peers.CloseBody(httpResp.Body)
+
+	h.version.Observe(httpResp.Header)
+	call.Status(httpResp.StatusCode)
+	if err := writeStatusError(httpResp); err != nil {
+		return err
+	}
+	resp.Success = true
+	return nil
+}
+
+// SetByProto stores the value in the peer's cache through the protobuf set route.
+// Peers predating the route answer 405, which is reported as peers.ErrUnsupported,
+// as is a peer whose advertised protocol version lacks the route.
+func (h *HTTPGetter) SetByProto(ctx context.Context, req *pb.SetRequest, resp *pb.SetResponse) error {
+	if err := h.version.Check(peers.FeatureSet); err != nil {
+		return err
+	}
+	data, err := proto.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	u := strings.TrimSuffix(h.baseURL, "/") + "/" + SetPath
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPut, u, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/protobuf")
+
+	release, err := h.counters.Acquire(ctx, h.limit)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	call := h.counters.Start(len(data))
+	httpResp, err := h.client.Do(httpReq)
+	if err != nil {
+		call.Fail(err)
+		return fmt.Errorf("failed to set on peer: %w", err)
+	}
+	defer peers.CloseBody(httpResp.Body)
+
+	h.version.Observe(httpResp.Header)
+	call.Status(httpResp.StatusCode)
+	if httpResp.StatusCode == http.StatusMethodNotAllowed && h.version.Get() == peers.ProtocolUnknown {
+		return peers.Unsupported(peers.FeatureSet, peers.ProtocolUnknown)
+	}
+	if err := writeStatusError(httpResp); err != nil {
+		return err
+	}
+
+	respBody, err := io.ReadAll(httpResp.Body)
+	call.Received(len(respBody))
+	if err != nil {
+		call.Fail(err)
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+	if err := proto.Unmarshal(respBody, resp); err != nil {
+		call.Fail(err)
+		return fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return nil
+}
+
+// ListOwnedBy implements peers.PeerOwnedLister through the list route, see
+// ListOwnedPath. A 409 answer means the peer's ring differs and is reported as
+// peers.ErrRingMismatch. Peers whose advertised version lacks the route, and
+// peers predating negotiation that answer 400 or 405, report peers.ErrUnsupported.
+func (h *HTTPGetter) ListOwnedBy(ctx context.Context, req *pb.ListOwnedRequest, resp *pb.ListOwnedResponse) error {
+	if err := h.version.Check(peers.FeatureListOwned); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	u := strings.TrimSuffix(h.baseURL, "/") + "/" + ListOwnedPath + "?" + listOwnedQuery(req).Encode()
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	release, err := h.counters.Acquire(ctx, h.limit)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	call := h.counters.Start(0)
+	httpResp, err := h.client.Do(httpReq)
+	if err != nil {
+		call.Fail(err)
+		return fmt.Errorf("failed to list owned keys on peer: %w", err)
+	}
+	defer peers.CloseBody(httpResp.Body)
+
+	h.version.Observe(httpResp.Header)
+	call.Status(httpResp.StatusCode)
+	switch code := httpResp.StatusCode; {
+	case code == http.StatusConflict:
+		return fmt.Errorf("%w: peer %s is on another ring", peers.ErrRingMismatch, h)
+	case (code == http.StatusBadRequest || code == http.StatusMethodNotAllowed) && h.version.Get() < peers.ProtocolV2:
+		return peers.Unsupported(peers.FeatureListOwned, h.version.Get())
+	}
+	if err := writeStatusError(httpResp); err != nil {
+		return err
+	}
+
+	respBody, err := io.ReadAll(httpResp.Body)
+	call.Received(len(respBody))
+	if err != nil {
+		call.Fail(err)
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+	if err := proto.Unmarshal(respBody, resp); err != nil {
+		call.Fail(err)
+		return fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return nil
+}
+
+// readStatusError maps the status of a read response to an error, by its error
+// code when the peer sent one
+func readStatusError(res *http.Response) error {
+	if res.StatusCode == http.StatusOK {
+		return nil
+	}
+	if err := cacheerrors.ErrorFromHTTP(res.StatusCode, res.Header.Get(peers.HeaderErrorCode)); err != nil {
+		return cacheerrors.WithRetryAfter(err, res.Header.Get(cacheerrors.HeaderRetryAfter))
+	}
+	// Peers predating error codes tell the errors apart only in the body
+	body := peers.ErrorBody(res.Body)
+	switch res.StatusCode {
+	case http.StatusNotFound:
+		if strings.Contains(body, "no such group") {
+			return cache.ErrNoSuchGroup
+		}
+		return cache.ErrNotFound
+	case http.StatusBadRequest:
+		if strings.Contains(body, "key is empty") {
+			return cache.ErrEmptyKey
+		}
+	}
+	return fmt.Errorf("peer returned non-200 status: %v: %s", res.Status, body)
+}
+
+// writeStatusError maps the status of a delete, set or list response to an
+// error, by its error code when the peer sent one
+func writeStatusError(res *http.Response) error {
+	if res.StatusCode == http.StatusOK {
+		return nil
+	}
+	if err := cacheerrors.ErrorFromHTTP(res.StatusCode, res.Header.Get(peers.HeaderErrorCode)); err != nil {
+		return err
+	}
+	// Peers predating error codes
+	switch res.StatusCode {
+	case http.StatusNotFound:
+		return cache.ErrNoSuchGroup
+	case http.StatusServiceUnavailable:
+		return cache.ErrReadOnly
+	}
+	body := peers.ErrorBody(res.Body)
+	if res.StatusCode == http.StatusBadRequest && strings.Contains(body, "key is empty") {
+		return cache.ErrEmptyKey
+	}
+	return fmt.Errorf("peer returned non-200 status: %v: %s", res.Status, body)
+}
+
+// Stats returns the traffic and error counters recorded by the getter
+func (h *HTTPGetter) Stats() PeerStats {
+	return h.counters.Snapshot()
+}
+
+// ProtoVersion returns the protocol version the peer advertised, or
+// peers.ProtocolUnknown before its first response or for peers predating it
+func (h *HTTPGetter) ProtoVersion() int {
+	return h.version.Get()
+}
+
 // SetTimeout sets the HTTP client timeout
 func (h *HTTPGetter) SetTimeout(timeout time.Duration) {
 	h.timeout = timeout
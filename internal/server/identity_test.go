@@ -0,0 +1,50 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/AdrianWangs/go-cache/internal/cache"
+	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
+)
+
+// TestResponsesCarryNode reads through node a over both protocols: a key a
+// loads itself is attributed to a, a key a fetched from b to b, and a node
+// that hides its identity reports neither
+func TestResponsesCarryNode(t *testing.T) {
+	for _, protocol := range []Protocol{ProtocolHTTP, ProtocolProtobuf} {
+		for _, expose := range []bool{true, false} {
+			a := newTestNode(t, WithSelfID("a"), WithProtocol(protocol), WithExposeIdentity(expose))
+			b := newTestNode(t, WithSelfID("b"), WithProtocol(protocol))
+			ga, gb := a.group("scores", echoGetter), b.group("scores", echoGetter)
+			ring := []Peer{{ID: "a", Addr: a.server.URL}, {ID: "b", Addr: b.server.URL}}
+			a.pool.SetPeers(ring...)
+			b.pool.SetPeers(ring...)
+			ga.RegisterPeers(a.pool)
+			gb.RegisterPeers(b.pool)
+
+			owned := map[string]string{"a": ownedBy(a.pool, "a", 50)[0], "b": ownedBy(a.pool, "b", 50)[0]}
+			client := a.getter(WithGetterProtocol(protocol))
+			for owner, key := range owned {
+				want := owner
+				if !expose {
+					want = ""
+				}
+				// The first read loads the key, the second finds it cached
+				for i := 0; i < 2; i++ {
+					resp := &pb.Response{}
+					if err := client.GetByProtoContext(context.Background(), &pb.Request{Group: "scores", Key: key}, resp); err != nil {
+						t.Fatalf("%s, expose %v: Get(%s): %v", protocol, expose, key, err)
+					}
+					if resp.GetNode() != want {
+						t.Errorf("%s, expose %v: %s owned by %s from %q reported node %q, want %q",
+							protocol, expose, key, owner, resp.GetSource(), resp.GetNode(), want)
+					}
+					if owner == "a" && i == 1 && resp.GetSource() != string(cache.SourceCache) {
+						t.Errorf("%s: second read of %s from %q", protocol, key, resp.GetSource())
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/AdrianWangs/go-cache/internal/testutil/keys"
+	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
+)
+
+func TestKeysRoundTripOverHTTP(t *testing.T) {
+	node := newTestNode(t)
+	node.group("scores", echoGetter)
+	getter := node.getter()
+
+	for _, key := range keys.Routing {
+		t.Run(key, func(t *testing.T) {
+			v, err := getter.Get("scores", key)
+			if err != nil {
+				t.Fatalf("Get: %v", err)
+			}
+			if string(v) != "v:"+key {
+				t.Fatalf("value = %q, want %q", v, "v:"+key)
+			}
+		})
+	}
+}
+
+func TestKeysRoundTripOverProtobuf(t *testing.T) {
+	node := newTestNode(t)
+	node.group("scores", echoGetter)
+	getter := node.getter(WithGetterProtocol(ProtocolProtobuf))
+
+	for _, key := range keys.Routing {
+		t.Run(key, func(t *testing.T) {
+			resp := &pb.Response{}
+			if err := getter.GetByProtoContext(context.Background(), &pb.Request{Group: "scores", Key: key}, resp); err != nil {
+				t.Fatalf("GetByProto: %v", err)
+			}
+			if string(resp.Value) != "v:"+key {
+				t.Fatalf("value = %q, want %q", resp.Value, "v:"+key)
+			}
+		})
+	}
+}
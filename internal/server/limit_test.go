@@ -0,0 +1,79 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/internal/peers"
+	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
+)
+
+// hangingPeer starts a peer that never answers a request until the caller
+// gives up or the test ends
+func hangingPeer(t *testing.T) string {
+	t.Helper()
+	done := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-done:
+		}
+	}))
+	t.Cleanup(func() {
+		close(done)
+		srv.Close()
+	})
+	return srv.URL + "/_go_cache/"
+}
+
+// TestPeerLimitBoundsGoroutines sends many reads to a peer that never answers:
+// only MaxInFlight of them wait on it, the rest fail at once with ErrPeerBusy,
+// so the number of goroutines stays bounded however many reads arrive
+func TestPeerLimitBoundsGoroutines(t *testing.T) {
+	const maxInFlight, callers = 4, 200
+	for _, protocol := range []Protocol{ProtocolHTTP, ProtocolProtobuf} {
+		t.Run(string(protocol), func(t *testing.T) {
+			url := hangingPeer(t)
+			h := NewHTTPGetter(url, WithGetterProtocol(protocol), WithGetterTimeout(time.Minute),
+				WithGetterLimit(peers.Limit{MaxInFlight: maxInFlight}))
+			before := runtime.NumGoroutine()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			errs := make(chan error, callers)
+			for i := 0; i < callers; i++ {
+				go func() {
+					errs <- h.GetByProtoContext(ctx, &pb.Request{Group: "scores", Key: "k"}, &pb.Response{})
+				}()
+			}
+			for i := 0; i < callers-maxInFlight; i++ {
+				if err := <-errs; !errors.Is(err, peers.ErrPeerBusy) {
+					t.Fatalf("read over the cap = %v, want ErrPeerBusy", err)
+				}
+			}
+
+			// The waiting reads plus the connections serving them, not one per caller
+			waitFor(t, "the refused callers to exit", func() bool {
+				return runtime.NumGoroutine()-before <= maxInFlight*8
+			})
+			if s := h.Stats(); s.InFlight != maxInFlight || s.Busy != callers-maxInFlight || s.Requests != maxInFlight {
+				t.Fatalf("stats = %+v", s)
+			}
+
+			cancel()
+			for i := 0; i < maxInFlight; i++ {
+				if err := <-errs; !errors.Is(err, context.Canceled) {
+					t.Fatalf("cancelled read = %v", err)
+				}
+			}
+			if s := h.Stats(); s.InFlight != 0 {
+				t.Fatalf("InFlight = %d after every read returned", s.InFlight)
+			}
+		})
+	}
+}
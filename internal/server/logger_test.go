@@ -0,0 +1,77 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/AdrianWangs/go-cache/internal/cache"
+	"github.com/AdrianWangs/go-cache/pkg/logger"
+)
+
+// lineRecorder is a logger.Logger that keeps the lines written through it
+type lineRecorder struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (r *lineRecorder) record(level, format string, args []interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lines = append(r.lines, level+" "+fmt.Sprintf(format, args...))
+}
+
+func (r *lineRecorder) Debugf(format string, args ...interface{}) { r.record("DEBUG", format, args) }
+func (r *lineRecorder) Infof(format string, args ...interface{})  { r.record("INFO", format, args) }
+func (r *lineRecorder) Warnf(format string, args ...interface{})  { r.record("WARN", format, args) }
+func (r *lineRecorder) Errorf(format string, args ...interface{}) { r.record("ERROR", format, args) }
+func (r *lineRecorder) WithFields(logger.Fields) logger.Logger    { return r }
+
+// recorded returns the lines containing substr
+func (r *lineRecorder) recorded(substr string) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var found []string
+	for _, line := range r.lines {
+		if strings.Contains(line, substr) {
+			found = append(found, line)
+		}
+	}
+	return found
+}
+
+func TestPoolWithLogger(t *testing.T) {
+	global := &lineRecorder{}
+	logger.SetLogger(global)
+	defer logger.SetLogger(nil)
+
+	rec := &lineRecorder{}
+	pool := NewHTTPPool("http://10.0.0.1:8001", WithRegistry(cache.NewRegistry()), WithLogger(rec))
+	pool.Set("http://10.0.0.1:8001", "http://10.0.0.2:8001", "http://10.0.0.2:8001")
+	pool.Set("http://10.0.0.2:8001", "http://10.0.0.1:8001")
+	w := httptest.NewRecorder()
+	pool.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/elsewhere", nil))
+
+	for _, want := range []string{
+		"WARN Dropped duplicate peers from the peer list: [http://10.0.0.2:8001]",
+		"INFO Cache pool set 2 peers",
+		"DEBUG Cache pool peers unchanged (2 peers)",
+		"DEBUG [Server http://10.0.0.1:8001] GET /elsewhere",
+	} {
+		if found := rec.recorded(want); len(found) != 1 {
+			t.Errorf("%q recorded %d times, want once; recorded %q", want, len(found), rec.recorded(""))
+		}
+	}
+	if found := global.recorded("Cache pool"); len(found) != 0 {
+		t.Fatalf("package logger recorded the pool's lines %q", found)
+	}
+
+	// Without the option the pool follows logger.SetLogger
+	NewHTTPPool("http://10.0.0.1:8001", WithRegistry(cache.NewRegistry())).Set("http://10.0.0.1:8001")
+	if found := global.recorded("INFO Cache pool set 1 peers"); len(found) != 1 {
+		t.Fatalf("package logger recorded %q", global.recorded(""))
+	}
+}
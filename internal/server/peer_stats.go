@@ -0,0 +1,10 @@
+package server
+
+import "github.com/AdrianWangs/go-cache/internal/peers"
+
+// PeerStats is a snapshot of the traffic this node sent to one peer, see
+// HTTPPool.PeerStats
+type PeerStats = peers.Stats
+
+// peerCounters accumulates PeerStats for one peer
+type peerCounters = peers.Counters
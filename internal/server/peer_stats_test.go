@@ -0,0 +1,120 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// peerDelay is how long the fake peer takes to answer
+const peerDelay = 20 * time.Millisecond
+
+// keysOwnedBy returns n keys with prefix that pool routes to the peer with ID id
+func keysOwnedBy(pool *HTTPPool, id, prefix string, n int) []string {
+	var keys []string
+	for i := 0; len(keys) < n; i++ {
+		key := fmt.Sprintf("%s-%d", prefix, i)
+		if peer, ok := pool.PickPeer(key); ok && peer.(*HTTPGetter).String() == id {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+func TestPeerStats(t *testing.T) {
+	// Peer b answers after peerDelay and fails keys starting with "bad"; peer c
+	// refuses connections
+	b := newTestNode(t)
+	b.group("scores", echoGetter)
+	b.server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(peerDelay)
+		// The key is in the path of plain requests and in the body of protobuf ones
+		body, _ := io.ReadAll(r.Body)
+		if strings.Contains(r.URL.Path, "/bad-") || bytes.Contains(body, []byte("bad-")) {
+			http.Error(w, "boom", http.StatusInternalServerError)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		b.pool.ServeHTTP(w, r)
+	})
+
+	a := newTestNode(t, WithSelfID("a"))
+	g := a.group("scores", echoGetter)
+	g.RegisterPeers(a.pool)
+	peersOf := func(ids ...string) []Peer {
+		addrs := map[string]string{"a": a.server.URL, "b": b.server.URL, "c": "http://127.0.0.1:1"}
+		var list []Peer
+		for _, id := range ids {
+			list = append(list, Peer{ID: id, Addr: addrs[id]})
+		}
+		return list
+	}
+	a.pool.SetPeers(peersOf("a", "b", "c")...)
+
+	good := keysOwnedBy(a.pool, "b", "key", 3)
+	bad := keysOwnedBy(a.pool, "b", "bad", 2)
+	refused := keysOwnedBy(a.pool, "c", "key", 2)
+	for _, key := range append(append(good, bad...), refused...) {
+		// Failed fetches fall back to the local getter
+		if v, err := g.Get(key); err != nil || v.String() != "v:"+key {
+			t.Fatalf("Get(%s) = %q, %v", key, v, err)
+		}
+	}
+
+	stats := a.pool.PeerStats()
+	if _, ok := stats["a"]; ok || len(stats) != 2 {
+		t.Fatalf("PeerStats has %d peers: %+v", len(stats), stats)
+	}
+
+	sb := stats["b"]
+	if sb.Requests != 5 || sb.Completed != 5 || sb.BadStatus != 2 || sb.Errors() != 2 || sb.InFlight != 0 {
+		t.Fatalf("stats of b = %+v", sb)
+	}
+	if sb.BytesIn == 0 || sb.LastError == nil {
+		t.Fatalf("stats of b = %+v", sb)
+	}
+	if avg := sb.AvgLatency(); avg < peerDelay || avg > peerDelay+5*time.Second {
+		t.Fatalf("average latency of b = %v, peer takes %v", avg, peerDelay)
+	}
+
+	sc := stats["c"]
+	if sc.Requests != 2 || sc.Completed != 2 || sc.ConnRefused != 2 || sc.Errors() != 2 || sc.BytesIn != 0 || sc.LastError == nil {
+		t.Fatalf("stats of c = %+v", sc)
+	}
+	if sc.AvgLatency() >= sb.AvgLatency() {
+		t.Fatalf("refused connections took %v, b took %v", sc.AvgLatency(), sb.AvgLatency())
+	}
+
+	// The Stats RPC reports the same numbers
+	resp := fetchStats(t, a)
+	if len(resp.Peers) != 2 || resp.Peers[0].GetPeer() != "b" || resp.Peers[0].GetCompleted() != 5 ||
+		resp.Peers[0].GetLatencyNanos() != sb.LatencyNanos || resp.Peers[1].GetConnRefused() != 2 {
+		t.Fatalf("Stats RPC peers = %v", resp.Peers)
+	}
+
+	// Removing c drops its counters; b keeps its own
+	a.pool.SetPeers(peersOf("a", "b")...)
+	stats = a.pool.PeerStats()
+	if _, ok := stats["c"]; ok || len(stats) != 1 {
+		t.Fatalf("PeerStats after removing c = %+v", stats)
+	}
+	if got := stats["b"]; got.Requests != sb.Requests || got.LatencyNanos != sb.LatencyNanos {
+		t.Fatalf("stats of b after removing c = %+v, want %+v", got, sb)
+	}
+
+	// A peer that joins again starts from zero
+	a.pool.SetPeers(peersOf("a", "b", "c")...)
+	if sc := a.pool.PeerStats()["c"]; sc != (PeerStats{}) {
+		t.Fatalf("stats of c after joining again = %+v", sc)
+	}
+	if _, err := g.Get(keysOwnedBy(a.pool, "c", "again", 1)[0]); err != nil {
+		t.Fatal(err)
+	}
+	if sc := a.pool.PeerStats()["c"]; sc.Requests != 1 || sc.ConnRefused != 1 {
+		t.Fatalf("stats of c after one more request = %+v", sc)
+	}
+}
@@ -0,0 +1,277 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/internal/cache"
+)
+
+// owners maps each of n keys to the address of the peer owning it, "" for keys
+// the pool owns itself
+func owners(pool *HTTPPool, n int) map[string]string {
+	m := make(map[string]string, n)
+	for i := 0; i < n; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		if peer, ok := pool.PickPeer(key); ok {
+			m[key] = peer.(*HTTPGetter).baseURL
+		} else {
+			m[key] = ""
+		}
+	}
+	return m
+}
+
+func TestOwnershipSurvivesAddressChange(t *testing.T) {
+	pool := NewHTTPPool("http://10.0.0.1:8001", WithSelfID("node-a"), WithRegistry(cache.NewRegistry()))
+	pool.SetPeers(
+		Peer{ID: "node-a", Addr: "http://10.0.0.1:8001"},
+		Peer{ID: "node-b", Addr: "http://10.0.0.2:8001"},
+		Peer{ID: "node-c", Addr: "http://10.0.0.3:8001"},
+	)
+	before := owners(pool, 1000)
+
+	// node-b comes back on a new IP and port with the same ID
+	pool.SetPeers(
+		Peer{ID: "node-a", Addr: "http://10.0.0.1:8001"},
+		Peer{ID: "node-b", Addr: "http://10.0.0.9:9001"},
+		Peer{ID: "node-c", Addr: "http://10.0.0.3:8001"},
+	)
+	after := owners(pool, 1000)
+
+	moved := 0
+	for key, was := range before {
+		want := was
+		if was == "http://10.0.0.2:8001"+pool.BasePath() {
+			want = "http://10.0.0.9:9001" + pool.BasePath()
+			moved++
+		}
+		if after[key] != want {
+			t.Errorf("%s: owner %q, want %q", key, after[key], want)
+		}
+	}
+	if moved == 0 {
+		t.Fatal("node-b owns no key")
+	}
+}
+
+func TestOwnershipChangesWithAddressBasedIDs(t *testing.T) {
+	pool := NewHTTPPool("http://10.0.0.1:8001", WithRegistry(cache.NewRegistry()))
+	pool.Set("http://10.0.0.1:8001", "http://10.0.0.2:8001", "http://10.0.0.3:8001")
+	before := owners(pool, 1000)
+	pool.Set("http://10.0.0.1:8001", "http://10.0.0.9:9001", "http://10.0.0.3:8001")
+	after := owners(pool, 1000)
+
+	// Without stable IDs the ring changes, and some keys move between the other nodes
+	reshuffled := 0
+	for key, was := range before {
+		if was != "http://10.0.0.2:8001"+pool.BasePath() && after[key] != was {
+			reshuffled++
+		}
+	}
+	if reshuffled == 0 {
+		t.Fatal("no key moved although the ring changed")
+	}
+}
+
+// TestSetCanonicalizesAddresses checks spellings of the same address build the same ring
+func TestSetCanonicalizesAddresses(t *testing.T) {
+	a := NewHTTPPool("http://10.0.0.1:8001", WithRegistry(cache.NewRegistry()))
+	a.Set("http://10.0.0.1:8001", "http://Node2:8001/", "http://10.0.0.3:8001")
+	b := NewHTTPPool("http://10.0.0.1:8001", WithRegistry(cache.NewRegistry()))
+	b.Set("http://10.0.0.3:8001", "http://10.0.0.1:8001/", "http://node2:8001")
+
+	ownersA, ownersB := owners(a, 1000), owners(b, 1000)
+	for key, owner := range ownersA {
+		if ownersB[key] != owner {
+			t.Fatalf("%s: owner %q vs %q", key, owner, ownersB[key])
+		}
+	}
+}
+
+func TestIdenticalSetKeepsGetters(t *testing.T) {
+	// the remote node counts the connections it accepts
+	var conns atomic.Int64
+	remote := cache.NewRegistry()
+	defer remote.Close()
+	srv := httptest.NewUnstartedServer(nil)
+	srv.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	remoteURL := "http://" + srv.Listener.Addr().String()
+	srv.Config.Handler = NewHTTPPool(remoteURL, WithRegistry(remote))
+	srv.Start()
+	defer srv.Close()
+	cache.NewGroup("scores", 1<<20, echoGetter, time.Hour, cache.WithRegistry(remote))
+
+	self := "http://10.0.0.1:8001"
+	pool := NewHTTPPool(self, WithRegistry(cache.NewRegistry()))
+	pool.Set(self, remoteURL)
+	getters, ring := pool.httpGetters, pool.peers
+
+	var key string
+	for i := 0; key == ""; i++ {
+		if _, ok := pool.PickPeer(fmt.Sprintf("key-%d", i)); ok {
+			key = fmt.Sprintf("key-%d", i)
+		}
+	}
+
+	for i := 0; i < 10; i++ {
+		// the same peers in another order and spelling are the same set
+		pool.Set(strings.ToUpper(remoteURL)+"/", self)
+		if pool.peers != ring {
+			t.Fatal("identical Set rebuilt the ring")
+		}
+		for id, g := range pool.httpGetters {
+			if getters[id] != g {
+				t.Fatalf("identical Set replaced the getter of %s", id)
+			}
+		}
+		peer, _ := pool.PickPeer(key)
+		if v, err := peer.Get("scores", key); err != nil || string(v) != "v:"+key {
+			t.Fatalf("Get(%s) = %q, %v", key, v, err)
+		}
+	}
+	if n := conns.Load(); n != 1 {
+		t.Fatalf("remote accepted %d connections for 10 requests, want 1", n)
+	}
+
+	// a changed set keeps the getters of the peers that remain
+	pool.Set(self, remoteURL, "http://10.0.0.3:8001")
+	if pool.peers == ring {
+		t.Fatal("changed Set kept the old ring")
+	}
+	if pool.httpGetters[remoteURL] != getters[remoteURL] {
+		t.Fatal("changed Set replaced the getter of a remaining peer")
+	}
+	if got := pool.Peers(); len(got) != 3 {
+		t.Fatalf("Peers() = %v", got)
+	}
+}
+
+// TestSetOrderAndDuplicates feeds the same membership permuted, duplicated and
+// respelled: every pool builds the same ring, with one set of virtual nodes per
+// peer, and hands every key to the same owner
+func TestSetOrderAndDuplicates(t *testing.T) {
+	const self = "http://10.0.0.1:8001"
+	lists := [][]string{
+		{"http://10.0.0.1:8001", "http://10.0.0.2:8001", "http://10.0.0.3:8001", "http://10.0.0.4:8001"},
+		{"http://10.0.0.4:8001", "http://10.0.0.2:8001", "http://10.0.0.1:8001", "http://10.0.0.3:8001"},
+		{"http://10.0.0.3:8001", "http://10.0.0.4:8001", "http://10.0.0.3:8001", "http://10.0.0.2:8001", "http://10.0.0.1:8001"},
+		{" http://10.0.0.2:8001/", "HTTP://10.0.0.4:8001", "http://10.0.0.1:8001", "http://10.0.0.2:8001", "http://10.0.0.3:8001\n", "http://10.0.0.4:8001/"},
+	}
+
+	var pools []*HTTPPool
+	for _, list := range lists {
+		pool := NewHTTPPool(self, WithRegistry(cache.NewRegistry()))
+		pool.Set(list...)
+		pools = append(pools, pool)
+	}
+	want := owners(pools[0], 2000)
+	for i, pool := range pools {
+		if got := strings.Join(pool.Peers(), " "); got != strings.Join(lists[0], " ") {
+			t.Errorf("list %d: peers %s", i, got)
+		}
+		if pool.RingVersion() != pools[0].RingVersion() {
+			t.Errorf("list %d: ring version %s, want %s", i, pool.RingVersion(), pools[0].RingVersion())
+		}
+		pool.mu.RLock()
+		d := pool.peers.Describe()
+		pool.mu.RUnlock()
+		if d.Nodes != 4 || d.VirtualNodes != 4*d.Replicas {
+			t.Errorf("list %d: %d nodes with %d virtual nodes, want 4 with %d", i, d.Nodes, d.VirtualNodes, 4*d.Replicas)
+		}
+		for key, owner := range owners(pool, 2000) {
+			if owner != want[key] {
+				t.Fatalf("list %d: %s owned by %q, want %q", i, key, owner, want[key])
+			}
+		}
+	}
+
+	// A permuted or duplicated Set of the same membership leaves the ring alone
+	pool := pools[0]
+	generation := pool.RingGeneration()
+	for _, list := range lists[1:] {
+		pool.Set(list...)
+	}
+	if pool.RingGeneration() != generation {
+		t.Fatalf("ring generation %d after permuted Sets, want %d", pool.RingGeneration(), generation)
+	}
+}
+
+// TestSetPeersOrderAndDuplicates is TestSetOrderAndDuplicates for peers with
+// IDs: a duplicated ID keeps its first address
+func TestSetPeersOrderAndDuplicates(t *testing.T) {
+	a := NewHTTPPool("http://10.0.0.1:8001", WithSelfID("node-a"), WithRegistry(cache.NewRegistry()))
+	a.SetPeers(
+		Peer{ID: "node-a", Addr: "http://10.0.0.1:8001"},
+		Peer{ID: "node-b", Addr: "http://10.0.0.2:8001"},
+		Peer{ID: "node-c", Addr: "http://10.0.0.3:8001"},
+	)
+	b := NewHTTPPool("http://10.0.0.1:8001", WithSelfID("node-a"), WithRegistry(cache.NewRegistry()))
+	b.SetPeers(
+		Peer{ID: " node-c", Addr: "http://10.0.0.3:8001/"},
+		Peer{ID: "node-b", Addr: "http://10.0.0.2:8001"},
+		Peer{ID: "node-a", Addr: "http://10.0.0.1:8001"},
+		Peer{ID: "node-b", Addr: "http://10.0.0.9:8001"},
+		Peer{ID: "", Addr: "http://10.0.0.8:8001"},
+	)
+
+	ownersA, ownersB := owners(a, 2000), owners(b, 2000)
+	for key, owner := range ownersA {
+		if ownersB[key] != owner {
+			t.Fatalf("%s: owner %q vs %q", key, owner, ownersB[key])
+		}
+	}
+	if got := strings.Join(b.Peers(), " "); got != "node-a node-b node-c" {
+		t.Fatalf("peers %s", got)
+	}
+}
+
+func TestCanonicalPeers(t *testing.T) {
+	list, duplicates := canonicalPeers([]Peer{
+		{ID: "node-c ", Addr: " HTTP://Node3:8001/"},
+		{ID: "node-a", Addr: "http://10.0.0.1:8001"},
+		{ID: "node-c", Addr: "http://10.0.0.9:8001"},
+		{ID: " ", Addr: "http://10.0.0.8:8001"},
+		{ID: "node-b", Addr: "http://10.0.0.2:8001/cache"},
+		{ID: "node-a", Addr: "http://10.0.0.1:8001"},
+	})
+	want := []Peer{
+		{ID: "node-a", Addr: "http://10.0.0.1:8001"},
+		{ID: "node-b", Addr: "http://10.0.0.2:8001/cache"},
+		{ID: "node-c", Addr: "http://node3:8001"},
+	}
+	if !samePeers(list, want) {
+		t.Fatalf("list %v, want %v", list, want)
+	}
+	if got := strings.Join(duplicates, " "); got != "node-c node-a" {
+		t.Fatalf("duplicates %q, want node-c node-a", got)
+	}
+}
+
+func TestCanonicalBaseURL(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"http://10.0.0.1:8001", "http://10.0.0.1:8001"},
+		{" http://10.0.0.1:8001/\n", "http://10.0.0.1:8001"},
+		{"HTTP://Node1:8001//", "http://node1:8001"},
+		{"https://Node1:8443/Prefix/", "https://node1:8443/Prefix"},
+		{"Node1:8001/", "Node1:8001"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := canonicalBaseURL(tt.in); got != tt.want {
+			t.Errorf("canonicalBaseURL(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
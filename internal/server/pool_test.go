@@ -0,0 +1,79 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/AdrianWangs/go-cache/internal/peers"
+	"github.com/AdrianWangs/go-cache/pkg/logger"
+)
+
+// TestConcurrentPooledFetches fetches distinct keys from many goroutines over
+// both protocols with pooled messages, as Group does, and checks every value
+// the caller kept after its messages went back to the pools
+func TestConcurrentPooledFetches(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+	node := newTestNode(t)
+	node.group("scores", echoGetter)
+
+	for _, protocol := range []Protocol{ProtocolHTTP, ProtocolProtobuf} {
+		t.Run(string(protocol), func(t *testing.T) {
+			h := node.getter(WithGetterProtocol(protocol))
+			var wg sync.WaitGroup
+			for w := 0; w < 8; w++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					var kept [][]byte
+					for i := 0; i < 100; i++ {
+						req, resp := peers.GetRequest(), peers.GetResponse()
+						req.Group, req.Key = "scores", fmt.Sprintf("%d-%d", w, i)
+						err := h.GetByProtoContext(context.Background(), req, resp)
+						kept = append(kept, resp.Value)
+						peers.PutRequest(req)
+						peers.PutResponse(resp)
+						if err != nil {
+							t.Error(err)
+							return
+						}
+					}
+					for i, v := range kept {
+						if want := fmt.Sprintf("v:%d-%d", w, i); string(v) != want {
+							t.Errorf("kept value = %q, want %q", v, want)
+							return
+						}
+					}
+				}()
+			}
+			wg.Wait()
+		})
+	}
+}
+
+// BenchmarkPeerFetch measures allocations of a fetch from a peer over each
+// protocol, with the pooled messages the fetch path uses
+func BenchmarkPeerFetch(b *testing.B) {
+	logger.SetLevel("error")
+	defer logger.SetLevel("debug")
+	node := newTestNode(b)
+	node.group("scores", echoGetter)
+
+	for _, protocol := range []Protocol{ProtocolHTTP, ProtocolProtobuf} {
+		b.Run(string(protocol), func(b *testing.B) {
+			h := node.getter(WithGetterProtocol(protocol))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				req, resp := peers.GetRequest(), peers.GetResponse()
+				req.Group, req.Key = "scores", "k"
+				if err := h.GetByProtoContext(context.Background(), req, resp); err != nil {
+					b.Fatal(err)
+				}
+				peers.PutRequest(req)
+				peers.PutResponse(resp)
+			}
+		})
+	}
+}
@@ -0,0 +1,359 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/internal/cache"
+	"github.com/AdrianWangs/go-cache/pkg/logger"
+	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// DefaultPrimeTimeout is the time budget of Prime by default
+	DefaultPrimeTimeout = time.Minute
+	// DefaultPrimeConcurrency is the number of keys Prime loads at once by default
+	DefaultPrimeConcurrency = 8
+
+	// primeLogEvery is how often a running Prime logs its progress
+	primeLogEvery = 5 * time.Second
+)
+
+// PrimeKey is an entry of a priming manifest
+type PrimeKey struct {
+	Group string
+	Key   string
+}
+
+// ReadPrimeManifest parses a priming manifest: one key per line, or a group
+// and a key separated by a tab. Keys without a group belong to defaultGroup.
+// Surrounding whitespace is trimmed; blank lines and lines starting with '#'
+// are ignored. Repeated entries are kept once.
+func ReadPrimeManifest(r io.Reader, defaultGroup string) ([]PrimeKey, error) {
+	var keys []PrimeKey
+	seen := make(map[PrimeKey]bool)
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		raw := scanner.Text()
+		text := strings.TrimSpace(raw)
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		k := PrimeKey{Group: defaultGroup, Key: text}
+		if group, key, ok := strings.Cut(raw, "\t"); ok {
+			k = PrimeKey{Group: strings.TrimSpace(group), Key: strings.TrimSpace(key)}
+		}
+		if k.Group == "" || k.Key == "" {
+			return nil, fmt.Errorf("line %d: want key or group<TAB>key, got %q", line, raw)
+		}
+		if !seen[k] {
+			seen[k] = true
+			keys = append(keys, k)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// LoadPrimeManifest reads the priming manifest at path, see ReadPrimeManifest
+func LoadPrimeManifest(path, defaultGroup string) ([]PrimeKey, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	keys, err := ReadPrimeManifest(f, defaultGroup)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return keys, nil
+}
+
+// PrimeOptions bounds a Prime. The zero value loads DefaultPrimeConcurrency
+// keys at a time within DefaultPrimeTimeout.
+type PrimeOptions struct {
+	Timeout     time.Duration // budget of the whole priming, DefaultPrimeTimeout when <= 0
+	Concurrency int           // keys loaded at once, DefaultPrimeConcurrency when <= 0
+}
+
+// withDefaults fills the unset options
+func (o PrimeOptions) withDefaults() PrimeOptions {
+	if o.Timeout <= 0 {
+		o.Timeout = DefaultPrimeTimeout
+	}
+	if o.Concurrency <= 0 {
+		o.Concurrency = DefaultPrimeConcurrency
+	}
+	return o
+}
+
+// PrimeStatus reports the progress of the last Prime; its State takes the
+// same values as a warm-up's
+type PrimeStatus struct {
+	State    WarmupState
+	Started  time.Time
+	Elapsed  time.Duration // so far while running, the total afterwards
+	Total    int64         // keys in the manifest
+	Done     int64         // keys processed so far
+	Loaded   int64         // keys loaded into the cache
+	Cached   int64         // keys already cached
+	NotOwned int64         // keys owned by a peer, left to it
+	Missing  int64         // keys the data source does not have
+	Failed   int64         // keys whose load failed, or whose group does not exist
+	Error    string        // why the priming stopped early, empty if it did not
+}
+
+// Proto converts the status for StatsResponse.prime
+func (s PrimeStatus) Proto() *pb.PrimeStats {
+	stats := &pb.PrimeStats{
+		State:           string(s.State),
+		StartedUnixNano: proto.Int64(s.Started.UnixNano()),
+		ElapsedMs:       proto.Int64(s.Elapsed.Milliseconds()),
+		Total:           proto.Int64(s.Total),
+		Done:            proto.Int64(s.Done),
+		Loaded:          proto.Int64(s.Loaded),
+		Cached:          proto.Int64(s.Cached),
+		NotOwned:        proto.Int64(s.NotOwned),
+		Missing:         proto.Int64(s.Missing),
+		Failed:          proto.Int64(s.Failed),
+	}
+	if s.Error != "" {
+		stats.Error = proto.String(s.Error)
+	}
+	return stats
+}
+
+// primeProgress records the status of the last Prime
+type primeProgress struct {
+	mu     sync.Mutex
+	status PrimeStatus // zero State until the first Prime
+}
+
+// start begins a priming of total keys, reporting false when one is already running
+func (w *primeProgress) start(total int) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.status.State == WarmupRunning {
+		return false
+	}
+	w.status = PrimeStatus{State: WarmupRunning, Started: time.Now(), Total: int64(total)}
+	return true
+}
+
+// record counts a processed key
+func (w *primeProgress) record(fn func(s *PrimeStatus)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.status.Done++
+	fn(&w.status)
+}
+
+// finish records the outcome of the priming
+func (w *primeProgress) finish(state WarmupState, reason string) PrimeStatus {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.status.State = state
+	w.status.Error = reason
+	w.status.Elapsed = time.Since(w.status.Started)
+	return w.status
+}
+
+// snapshot returns the status with the elapsed time of a running priming
+func (w *primeProgress) snapshot() PrimeStatus {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	s := w.status
+	if s.State == WarmupRunning {
+		s.Elapsed = time.Since(s.Started)
+	}
+	return s
+}
+
+// PrimeStatus returns the progress of the last Prime; State is empty if none
+// was started
+func (p *HTTPPool) PrimeStatus() PrimeStatus {
+	return p.prime.snapshot()
+}
+
+// PrimeStats returns the progress of the last Prime for StatsResponse, nil if
+// none was started
+func (p *HTTPPool) PrimeStats() *pb.PrimeStats {
+	s := p.prime.snapshot()
+	if s.State == "" {
+		return nil
+	}
+	return s.Proto()
+}
+
+// errPrimeRunning is returned by Prime while another priming runs
+var errPrimeRunning = errors.New("priming already running")
+
+// Prime loads the keys of a manifest into the groups of the registry with
+// Group.Prime, Concurrency at a time, so that the keys this node owns are
+// cached before it takes traffic. Call it once the pool's ring includes this
+// node, otherwise every key looks owned by this node.
+//
+// A key that fails, is missing from the data source or belongs to an unknown
+// group is counted and skipped. Running out of time ends the priming early
+// without an error; cancelling ctx stops it and returns ctx's error. Progress
+// is logged every few seconds and reported by PrimeStatus and in the stats
+// route.
+func (p *HTTPPool) Prime(ctx context.Context, keys []PrimeKey, opts PrimeOptions) error {
+	opts = opts.withDefaults()
+	if !p.prime.start(len(keys)) {
+		return errPrimeRunning
+	}
+	p.log.Infof("[Server %s] 开始按清单预加载 %d 个 key，并发 %d，时间预算 %v", p.selfID, len(keys), opts.Concurrency, opts.Timeout)
+
+	budgetCtx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+	stopLog := p.logPrimeProgress()
+	p.primeAll(budgetCtx, keys, opts.Concurrency)
+	stopLog()
+
+	var status PrimeStatus
+	var err error
+	switch {
+	case ctx.Err() != nil:
+		status = p.prime.finish(WarmupCancelled, ctx.Err().Error())
+		err = ctx.Err()
+	case budgetCtx.Err() != nil && p.prime.snapshot().Done < int64(len(keys)):
+		status = p.prime.finish(WarmupDone, "time budget exhausted")
+	default:
+		status = p.prime.finish(WarmupDone, "")
+	}
+	p.log.WithFields(logger.Fields{
+		"state":     status.State,
+		"total":     status.Total,
+		"done":      status.Done,
+		"loaded":    status.Loaded,
+		"cached":    status.Cached,
+		"not_owned": status.NotOwned,
+		"missing":   status.Missing,
+		"failed":    status.Failed,
+		"elapsed":   status.Elapsed.Round(time.Millisecond).String(),
+		"reason":    status.Error,
+	}).Infof("[Server %s] 清单预加载结束", p.selfID)
+	return err
+}
+
+// logPrimeProgress logs the progress of the running priming every
+// primeLogEvery until the returned function is called
+func (p *HTTPPool) logPrimeProgress() func() {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(primeLogEvery)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s := p.prime.snapshot()
+				p.log.Infof("[Server %s] 清单预加载进度 %d/%d：加载 %d，已缓存 %d，归属其他节点 %d，不存在 %d，失败 %d",
+					p.selfID, s.Done, s.Total, s.Loaded, s.Cached, s.NotOwned, s.Missing, s.Failed)
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// primeAll primes keys, concurrency at a time, until all are primed or ctx is done
+func (p *HTTPPool) primeAll(ctx context.Context, keys []PrimeKey, concurrency int) {
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for _, k := range keys {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			return
+		}
+		wg.Add(1)
+		go func(k PrimeKey) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			p.primeKey(ctx, k)
+		}(k)
+	}
+}
+
+// primeKey primes one key and records the outcome
+func (p *HTTPPool) primeKey(ctx context.Context, k PrimeKey) {
+	group := p.registry.Get(k.Group)
+	if group == nil {
+		p.log.Warnf("[Server %s] 预加载清单中的缓存组 %s 不存在，跳过 key %s", p.selfID, k.Group, logger.Key(k.Key))
+		p.prime.record(func(s *PrimeStatus) { s.Failed++ })
+		return
+	}
+	outcome, err := group.Prime(ctx, k.Key)
+	if err != nil && ctx.Err() != nil {
+		// Stopped by the budget or the caller, not a failure of the key
+		return
+	}
+	p.prime.record(func(s *PrimeStatus) {
+		switch {
+		case cache.IsKeyNotFoundError(err):
+			s.Missing++
+		case err != nil:
+			s.Failed++
+		case outcome == cache.PrimeLoaded:
+			s.Loaded++
+		case outcome == cache.PrimeCached:
+			s.Cached++
+		case outcome == cache.PrimeNotOwned:
+			s.NotOwned++
+		}
+	})
+	if err != nil && !cache.IsKeyNotFoundError(err) {
+		p.log.Warnf("[Server %s] 预加载 %s/%s 失败: %v", p.selfID, k.Group, logger.Key(k.Key), err)
+	}
+}
+
+// PrimeReadiness decides when a node priming its cache reports ready
+type PrimeReadiness string
+
+const (
+	// PrimeReadyOff does not hold /ready back for priming
+	PrimeReadyOff PrimeReadiness = "off"
+	// PrimeReadyWait holds /ready back until priming ends, including when it
+	// runs out of time
+	PrimeReadyWait PrimeReadiness = "wait"
+	// PrimeReadyStrict holds /ready back until every key of the manifest was
+	// primed within the budget; a node whose priming runs out of time stays not
+	// ready. Keys that fail or are missing do not count against it.
+	PrimeReadyStrict PrimeReadiness = "strict"
+)
+
+// ParsePrimeReadiness parses off, wait or strict
+func ParsePrimeReadiness(s string) (PrimeReadiness, error) {
+	switch r := PrimeReadiness(strings.ToLower(strings.TrimSpace(s))); r {
+	case PrimeReadyOff, PrimeReadyWait, PrimeReadyStrict:
+		return r, nil
+	}
+	return "", fmt.Errorf("unknown prime readiness %q, want off, wait or strict", s)
+}
+
+// PrimeReady reports whether priming allows the node to report ready under r.
+// Before the first Prime starts it is false unless r is PrimeReadyOff.
+func (p *HTTPPool) PrimeReady(r PrimeReadiness) bool {
+	if r == PrimeReadyOff {
+		return true
+	}
+	s := p.prime.snapshot()
+	switch s.State {
+	case WarmupDone:
+		return r == PrimeReadyWait || s.Error == ""
+	}
+	return false
+}
@@ -0,0 +1,199 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/internal/cache"
+)
+
+func TestReadPrimeManifest(t *testing.T) {
+	manifest := strings.Join([]string{
+		"# most read keys",
+		"Tom",
+		"",
+		"   ",
+		"  Jack  ",
+		"users\tann",
+		"Tom",
+		"users\tann",
+		"  # indented comment",
+	}, "\n")
+	keys, err := ReadPrimeManifest(strings.NewReader(manifest), "scores")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []PrimeKey{
+		{"scores", "Tom"},
+		{"scores", "Jack"},
+		{"users", "ann"},
+	}
+	if !reflect.DeepEqual(keys, want) {
+		t.Fatalf("keys = %+v, want %+v", keys, want)
+	}
+
+	for _, bad := range []string{"users\t", "users\t  "} {
+		if _, err := ReadPrimeManifest(strings.NewReader("Tom\n"+bad+"\n"), "scores"); err == nil || !strings.Contains(err.Error(), "line 2") {
+			t.Errorf("manifest with %q: %v, want an error on line 2", bad, err)
+		}
+	}
+	if _, err := ReadPrimeManifest(strings.NewReader("Tom\n"), ""); err == nil {
+		t.Error("key without a group accepted without a default group")
+	}
+}
+
+// primeGetter serves "v:"+key, fails "broken" and does not have keys starting with "missing"
+var primeGetter = cache.GetterFunc(func(key string) ([]byte, error) {
+	switch {
+	case key == "broken":
+		return nil, errors.New("origin down")
+	case strings.HasPrefix(key, "missing"):
+		return nil, cache.ErrNotFound
+	}
+	return []byte("v:" + key), nil
+})
+
+// TestPrime primes a manifest with cached, missing and failing keys and an
+// unknown group: every key is counted, none aborts the priming
+func TestPrime(t *testing.T) {
+	n := newTestNode(t, WithSelfID("a"))
+	scores := n.group("scores", primeGetter)
+	if err := scores.SetLocally("cached", []byte("old"), 0); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := ReadPrimeManifest(strings.NewReader(
+		"# keys\nTom\n\nmissing-1\nbroken\ncached\nmissing-2\nnope\tTom\nJack\n"), "scores")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n.pool.PrimeReady(PrimeReadyWait) || n.pool.PrimeReady(PrimeReadyStrict) || !n.pool.PrimeReady(PrimeReadyOff) {
+		t.Fatal("ready before priming started")
+	}
+	if n.pool.PrimeStats() != nil {
+		t.Fatal("prime stats before priming started")
+	}
+	if err := n.pool.Prime(context.Background(), keys, PrimeOptions{Concurrency: 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	s := n.pool.PrimeStatus()
+	want := PrimeStatus{State: WarmupDone, Total: 7, Done: 7, Loaded: 2, Cached: 1, Missing: 2, Failed: 2}
+	s.Started, s.Elapsed = time.Time{}, 0
+	if s != want {
+		t.Fatalf("status = %+v, want %+v", s, want)
+	}
+	for _, key := range []string{"Tom", "Jack"} {
+		if v, _, ok := scores.Peek(key); !ok || v.String() != "v:"+key {
+			t.Fatalf("%s not primed", key)
+		}
+	}
+	if v, _, _ := scores.Peek("cached"); v.String() != "old" {
+		t.Fatalf("cached key reloaded: %q", v)
+	}
+	if !n.pool.PrimeReady(PrimeReadyWait) || !n.pool.PrimeReady(PrimeReadyStrict) {
+		t.Fatal("not ready after priming finished")
+	}
+	if st := n.pool.PrimeStats(); st.GetState() != string(WarmupDone) || st.GetLoaded() != 2 || st.GetMissing() != 2 || st.Error != nil {
+		t.Fatalf("prime stats = %v", st)
+	}
+}
+
+func TestPrimeSkipsKeysOfPeers(t *testing.T) {
+	a, b, _ := warmupPair(t, 0)
+	group := b.registry.Get("scores")
+	group.RegisterPeers(b.pool)
+	var keys []PrimeKey
+	for i := 0; i < 20; i++ {
+		keys = append(keys, PrimeKey{"scores", fmt.Sprintf("key-%03d", i)})
+	}
+	if err := b.pool.Prime(context.Background(), keys, PrimeOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	owned := ownedBy(b.pool, "b", 20)
+	s := b.pool.PrimeStatus()
+	if s.Loaded != int64(len(owned)) || s.NotOwned != int64(20-len(owned)) {
+		t.Fatalf("status = %+v, b owns %d keys", s, len(owned))
+	}
+	if group.Entries() != len(owned) {
+		t.Fatalf("b cached %d keys, owns %d", group.Entries(), len(owned))
+	}
+	if a.registry.Get("scores").Entries() != 0 {
+		t.Fatal("priming on b loaded keys on a")
+	}
+}
+
+// TestPrimeBudget ends a priming that runs out of time: wait readiness reports
+// ready, strict readiness does not
+func TestPrimeBudget(t *testing.T) {
+	n := newTestNode(t, WithSelfID("a"))
+	n.group("scores", cache.GetterCtxFunc(func(ctx context.Context, key string) ([]byte, error) {
+		if key == "slow" {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		return []byte("v"), nil
+	}))
+	keys := []PrimeKey{{"scores", "fast"}, {"scores", "slow"}, {"scores", "never"}}
+	if err := n.pool.Prime(context.Background(), keys, PrimeOptions{Timeout: 100 * time.Millisecond, Concurrency: 1}); err != nil {
+		t.Fatalf("Prime = %v, want nil when the budget runs out", err)
+	}
+	s := n.pool.PrimeStatus()
+	if s.State != WarmupDone || s.Error != "time budget exhausted" || s.Done != 1 || s.Loaded != 1 {
+		t.Fatalf("status = %+v", s)
+	}
+	if !n.pool.PrimeReady(PrimeReadyWait) || n.pool.PrimeReady(PrimeReadyStrict) {
+		t.Fatal("wait should be ready and strict not after the budget ran out")
+	}
+}
+
+func TestPrimeCancelled(t *testing.T) {
+	n := newTestNode(t, WithSelfID("a"))
+	started := make(chan struct{}, 1)
+	n.group("scores", cache.GetterCtxFunc(func(ctx context.Context, key string) ([]byte, error) {
+		started <- struct{}{}
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- n.pool.Prime(ctx, []PrimeKey{{"scores", "k"}}, PrimeOptions{})
+	}()
+	<-started
+
+	// A second priming is refused while one runs
+	if err := n.pool.Prime(context.Background(), nil, PrimeOptions{}); !errors.Is(err, errPrimeRunning) {
+		t.Fatalf("concurrent Prime = %v", err)
+	}
+	if n.pool.PrimeReady(PrimeReadyWait) {
+		t.Fatal("ready while priming runs")
+	}
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Prime = %v, want context.Canceled", err)
+	}
+	if s := n.pool.PrimeStatus(); s.State != WarmupCancelled || s.Failed != 0 {
+		t.Fatalf("status = %+v", s)
+	}
+	if n.pool.PrimeReady(PrimeReadyWait) {
+		t.Fatal("ready after priming was cancelled")
+	}
+}
+
+func TestParsePrimeReadiness(t *testing.T) {
+	for in, want := range map[string]PrimeReadiness{"off": PrimeReadyOff, " Wait ": PrimeReadyWait, "STRICT": PrimeReadyStrict} {
+		if got, err := ParsePrimeReadiness(in); err != nil || got != want {
+			t.Errorf("ParsePrimeReadiness(%q) = %q, %v", in, got, err)
+		}
+	}
+	if _, err := ParsePrimeReadiness("eventually"); err == nil {
+		t.Error("unknown readiness accepted")
+	}
+}
@@ -0,0 +1,193 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/AdrianWangs/go-cache/internal/cache"
+	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestPoolServesBothProtocols(t *testing.T) {
+	for _, protocol := range []Protocol{ProtocolHTTP, ProtocolProtobuf} {
+		t.Run(string(protocol), func(t *testing.T) {
+			node := newTestNode(t, WithProtocol(protocol))
+			node.group("scores", echoGetter)
+			base := node.server.URL + node.pool.BasePath()
+
+			// Plain GET
+			resp, err := http.Get(base + "scores/" + url.PathEscape("Tom"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK || string(body) != "v:Tom" {
+				t.Fatalf("GET: %d %q", resp.StatusCode, body)
+			}
+
+			// Protobuf POST
+			req, _ := proto.Marshal(&pb.Request{Group: "scores", Key: "Tom"})
+			resp, err = http.Post(base, "application/protobuf", bytes.NewReader(req))
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ = io.ReadAll(resp.Body)
+			resp.Body.Close()
+			out := &pb.Response{}
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("POST: %d %q", resp.StatusCode, body)
+			}
+			if err := proto.Unmarshal(body, out); err != nil || string(out.Value) != "v:Tom" {
+				t.Fatalf("POST: %q, %v", out.Value, err)
+			}
+
+			// Both getters reach the same pool
+			for _, p := range []Protocol{ProtocolHTTP, ProtocolProtobuf} {
+				v, err := node.getter(WithGetterProtocol(p)).Get("scores", "Jack")
+				if err != nil || string(v) != "v:Jack" {
+					t.Fatalf("%s getter: %q, %v", p, v, err)
+				}
+			}
+
+			// Anything else is refused
+			resp, err = http.Post(base, "text/plain", strings.NewReader("Tom"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusUnsupportedMediaType {
+				t.Fatalf("POST text/plain: %d, want 415", resp.StatusCode)
+			}
+			put, _ := http.NewRequest(http.MethodPut, base+"scores/Tom", nil)
+			resp, err = http.DefaultClient.Do(put)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusMethodNotAllowed {
+				t.Fatalf("PUT: %d, want 405", resp.StatusCode)
+			}
+		})
+	}
+}
+
+// TestMixedProtocolCluster a node sending plain HTTP and a node sending protobuf
+// fetch keys from each other
+func TestMixedProtocolCluster(t *testing.T) {
+	nodes := map[string]*testNode{
+		"http":     newTestNode(t, WithProtocol(ProtocolHTTP)),
+		"protobuf": newTestNode(t, WithProtocol(ProtocolProtobuf)),
+	}
+	groups := make(map[string]*cache.Group)
+	for name, node := range nodes {
+		name := name
+		g := node.group("scores", cache.GetterFunc(func(key string) ([]byte, error) {
+			return []byte(name + ":" + key), nil
+		}))
+		g.RegisterPeers(node.pool)
+		groups[name] = g
+	}
+	for _, node := range nodes {
+		node.pool.Set(nodes["http"].server.URL, nodes["protobuf"].server.URL)
+	}
+
+	for from, to := range map[string]string{"http": "protobuf", "protobuf": "http"} {
+		key := keyOwnedBy(t, nodes[from].pool, nodes[to])
+		v, err := groups[from].Get(key)
+		if err != nil {
+			t.Fatalf("%s node Get(%q): %v", from, key, err)
+		}
+		if want := to + ":" + key; v.String() != want {
+			t.Fatalf("%s node Get(%q) = %q, want %q loaded by the owner", from, key, v.String(), want)
+		}
+	}
+}
+
+// keyOwnedBy returns a key that pool routes to owner
+func keyOwnedBy(t *testing.T, pool *HTTPPool, owner *testNode) string {
+	t.Helper()
+	for i := 0; i < 1000; i++ {
+		if key := fmt.Sprintf("key-%d", i); keyOwnedByNode(pool, owner, key) {
+			return key
+		}
+	}
+	t.Fatal("no key is owned by the peer")
+	return ""
+}
+
+// TestMetadataParity runs the same fetches over both protocols and checks that
+// the metadata and errors reaching the group are the same
+func TestMetadataParity(t *testing.T) {
+	type outcome struct {
+		expiresMatch bool
+		versionMatch bool
+		source       cache.Source
+		nodeIsOwner  bool
+		missing      bool
+	}
+	results := make(map[Protocol]outcome)
+	for _, protocol := range []Protocol{ProtocolHTTP, ProtocolProtobuf} {
+		a := newTestNode(t, WithProtocol(protocol))
+		b := newTestNode(t, WithProtocol(protocol))
+		getter := cache.GetterFunc(func(key string) ([]byte, error) {
+			if strings.HasPrefix(key, "missing") {
+				return nil, cache.ErrNotFound
+			}
+			return []byte("v:" + key), nil
+		})
+		ga, gb := a.group("scores", getter), b.group("scores", getter)
+		for _, n := range []*testNode{a, b} {
+			n.pool.Set(a.server.URL, b.server.URL)
+		}
+		ga.RegisterPeers(a.pool)
+		gb.RegisterPeers(b.pool)
+
+		key := keyOwnedBy(t, a.pool, b)
+		v, meta, err := ga.GetWithMeta(context.Background(), key)
+		if err != nil || v.String() != "v:"+key {
+			t.Fatalf("%s: GetWithMeta(%s) = %q, %v", protocol, key, v, err)
+		}
+		_, owner, ok := gb.Peek(key)
+		if !ok {
+			t.Fatalf("%s: the owner did not cache %s", protocol, key)
+		}
+
+		var missing string
+		for i := 0; missing == ""; i++ {
+			if k := fmt.Sprintf("missing-%d", i); keyOwnedByNode(a.pool, b, k) {
+				missing = k
+			}
+		}
+		_, _, err = ga.GetWithMeta(context.Background(), missing)
+
+		results[protocol] = outcome{
+			expiresMatch: !meta.ExpiresAt.IsZero() && meta.ExpiresAt.Equal(owner.ExpiresAt),
+			versionMatch: meta.Version != 0 && meta.Version == owner.Version,
+			source:       meta.Source,
+			nodeIsOwner:  meta.Node == b.pool.selfID,
+			missing:      cache.IsKeyNotFoundError(err) || errors.Is(err, cache.ErrNotFound),
+		}
+	}
+
+	want := outcome{expiresMatch: true, versionMatch: true, source: cache.SourcePeer, nodeIsOwner: true, missing: true}
+	for protocol, got := range results {
+		if got != want {
+			t.Errorf("%s: %+v, want %+v", protocol, got, want)
+		}
+	}
+}
+
+// keyOwnedByNode reports whether pool routes key to owner
+func keyOwnedByNode(pool *HTTPPool, owner *testNode, key string) bool {
+	peer, ok := pool.PickPeer(key)
+	return ok && peer.(*HTTPGetter).baseURL == owner.server.URL+owner.pool.BasePath()
+}
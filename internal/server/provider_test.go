@@ -0,0 +1,45 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/internal/cache"
+	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
+)
+
+// TestProvidedGroups reads groups the registry's provider creates on demand
+// over both protocols: the first read creates the group, unknown names still
+// answer "no such group"
+func TestProvidedGroups(t *testing.T) {
+	node := newTestNode(t)
+	var created atomic.Int64
+	node.registry.RegisterGroupProvider(func(name string) (*cache.Group, bool) {
+		if !strings.HasPrefix(name, "tenant-") {
+			return nil, false
+		}
+		created.Add(1)
+		return cache.NewGroup(name, 1<<20, echoGetter, time.Hour, cache.WithRegistry(node.registry)), true
+	})
+
+	for _, protocol := range []Protocol{ProtocolHTTP, ProtocolProtobuf} {
+		h := node.getter(WithGetterProtocol(protocol))
+		for i := 0; i < 2; i++ {
+			resp := &pb.Response{}
+			if err := h.GetByProtoContext(context.Background(), &pb.Request{Group: "tenant-a", Key: "k"}, resp); err != nil || string(resp.Value) != "v:k" {
+				t.Fatalf("%s: Get from a provided group = %q, %v", protocol, resp.Value, err)
+			}
+		}
+		err := h.GetByProtoContext(context.Background(), &pb.Request{Group: "other", Key: "k"}, &pb.Response{})
+		if !errors.Is(err, cache.ErrNoSuchGroup) {
+			t.Fatalf("%s: Get from an unknown group = %v, want ErrNoSuchGroup", protocol, err)
+		}
+	}
+	if n := created.Load(); n != 1 {
+		t.Fatalf("provider created %d groups, want 1", n)
+	}
+}
@@ -0,0 +1,149 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/AdrianWangs/go-cache/internal/cache"
+	"github.com/AdrianWangs/go-cache/internal/peers"
+	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
+	"google.golang.org/protobuf/proto"
+)
+
+// fetchStats asks n's stats route for the statistics of all groups
+func fetchStats(t *testing.T, n *testNode) *pb.StatsResponse {
+	t.Helper()
+	body, err := proto.Marshal(&pb.StatsRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.Post(n.server.URL+n.pool.BasePath()+StatsPath, "application/protobuf", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("stats: %d %s", resp.StatusCode, data)
+	}
+	stats := &pb.StatsResponse{}
+	if err := proto.Unmarshal(data, stats); err != nil {
+		t.Fatal(err)
+	}
+	return stats
+}
+
+// TestRingGeneration counts the updates that change the peer set, whichever
+// of Set, SetPeers and UpdatePeers applies them, and reports the count in the
+// stats response
+func TestRingGeneration(t *testing.T) {
+	n := newTestNode(t)
+	if g := n.pool.RingGeneration(); g != 0 {
+		t.Fatalf("generation before the first update = %d", g)
+	}
+
+	steps := []struct {
+		name   string
+		update func(p *HTTPPool)
+		want   uint64
+	}{
+		{"first set", func(p *HTTPPool) { p.Set("http://a:1", "http://b:1") }, 1},
+		{"same set in another order", func(p *HTTPPool) { p.Set("http://B:1/", "http://a:1") }, 1},
+		{"peer joins", func(p *HTTPPool) { p.Set("http://a:1", "http://b:1", "http://c:1") }, 2},
+		{"IDs apart from addresses", func(p *HTTPPool) {
+			p.SetPeers(Peer{ID: "a", Addr: "http://a:1"}, Peer{ID: "b", Addr: "http://b:1"}, Peer{ID: "c", Addr: "http://c:1"})
+		}, 3},
+		{"address change", func(p *HTTPPool) {
+			p.SetPeers(Peer{ID: "a", Addr: "http://a:2"}, Peer{ID: "b", Addr: "http://b:1"}, Peer{ID: "c", Addr: "http://c:1"})
+		}, 4},
+		{"delta removing a peer", func(p *HTTPPool) { p.UpdatePeers(nil, []string{"c"}) }, 5},
+		{"empty delta", func(p *HTTPPool) { p.UpdatePeers(nil, nil) }, 5},
+		{"delta re-adding a known peer", func(p *HTTPPool) { p.UpdatePeers([]Peer{{ID: "b", Addr: "http://b:1"}}, nil) }, 5},
+	}
+	for _, step := range steps {
+		step.update(n.pool)
+		if g := n.pool.RingGeneration(); g != step.want {
+			t.Fatalf("%s: generation %d, want %d", step.name, g, step.want)
+		}
+	}
+	if g := fetchStats(t, n).GetRingGeneration(); g != 5 {
+		t.Fatalf("stats ring generation = %d, want 5", g)
+	}
+}
+
+// generationReceiver is a node recording the ring generation sent with each
+// request before serving it
+type generationReceiver struct {
+	*testNode
+	requests atomic.Int64
+	last     atomic.Uint64
+}
+
+func newGenerationReceiver(t *testing.T) *generationReceiver {
+	t.Helper()
+	r := &generationReceiver{testNode: &testNode{registry: cache.NewRegistry()}}
+	r.server = httptest.NewUnstartedServer(nil)
+	r.pool = NewHTTPPool("http://"+r.server.Listener.Addr().String(), WithRegistry(r.registry))
+	r.server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		generation := peers.ReadRingGeneration(req.Header)
+		if req.Method == http.MethodPost {
+			body, _ := io.ReadAll(req.Body)
+			decoded := &pb.Request{}
+			if err := proto.Unmarshal(body, decoded); err == nil {
+				generation = decoded.GetRingGeneration()
+			}
+			req.Body = io.NopCloser(bytes.NewReader(body))
+		}
+		r.requests.Add(1)
+		r.last.Store(generation)
+		r.pool.ServeHTTP(w, req)
+	})
+	r.server.Start()
+	t.Cleanup(func() {
+		r.server.Close()
+		r.registry.Close()
+	})
+	return r
+}
+
+// TestRingGenerationForwarded checks that a forwarded request carries the
+// sender's current generation over either protocol, including after a change
+func TestRingGenerationForwarded(t *testing.T) {
+	for _, protocol := range []Protocol{ProtocolHTTP, ProtocolProtobuf} {
+		t.Run(string(protocol), func(t *testing.T) {
+			a := newTestNode(t, WithProtocol(protocol))
+			b := newGenerationReceiver(t)
+			g := a.group("scores", countingLoader("a", new(atomic.Int64)))
+			g.RegisterPeers(a.pool)
+			b.group("scores", countingLoader("b", new(atomic.Int64)))
+
+			a.pool.Set(a.server.URL)
+			a.pool.Set(a.server.URL, b.server.URL)
+			for round, i := 0, 0; round < 2; i++ {
+				key := fmt.Sprintf("key-%d", i)
+				if !keyOwnedByNode(a.pool, b.testNode, key) {
+					continue
+				}
+				want := a.pool.RingGeneration()
+				v, err := g.Get(key)
+				if err != nil || v.String() != "b:"+key {
+					t.Fatalf("Get(%s) = %q, %v; want b's value", key, v, err)
+				}
+				if got := b.last.Load(); got != want {
+					t.Fatalf("b received generation %d, a is at %d", got, want)
+				}
+				// a membership change that keeps b on the ring
+				a.pool.Set(a.server.URL, b.server.URL, "http://c:1")
+				round++
+			}
+			if b.requests.Load() != 2 {
+				t.Fatalf("b received %d requests, want 2", b.requests.Load())
+			}
+		})
+	}
+}
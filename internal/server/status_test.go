@@ -0,0 +1,176 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/AdrianWangs/go-cache/internal/cache"
+	"github.com/AdrianWangs/go-cache/internal/peers"
+	"github.com/AdrianWangs/go-cache/pkg/cacheerrors"
+	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
+	"google.golang.org/protobuf/proto"
+)
+
+// TestReadErrorStatuses reads every class of error over the plain and the
+// protobuf path: the pool answers with the status and error code cacheerrors
+// maps the error to, and an HTTPGetter of either protocol turns the answer
+// back into the same sentinel
+func TestReadErrorStatuses(t *testing.T) {
+	node := newTestNode(t)
+	node.group("missing-keys", cache.GetterFunc(func(key string) ([]byte, error) {
+		return nil, cache.ErrNotFound
+	}))
+	node.group("broken", cache.GetterFunc(func(key string) ([]byte, error) {
+		return nil, errors.New("database is down")
+	}))
+	limited := node.group("limited", echoGetter, cache.WithRateLimit(cache.RateLimit{Rate: 0.001, Burst: 1}))
+	limited.Get("first") // uses up the only token
+
+	tests := []struct {
+		name       string
+		group, key string
+		status     int
+		want       error // nil for an internal error, which maps to no sentinel
+	}{
+		{"getter miss", "missing-keys", "k", http.StatusNotFound, cache.ErrNotFound},
+		{"empty key", "missing-keys", "", http.StatusBadRequest, cache.ErrEmptyKey},
+		{"no such group", "nope", "k", http.StatusNotFound, cache.ErrNoSuchGroup},
+		{"rate limited", "limited", "k", http.StatusTooManyRequests, cache.ErrRateLimited},
+		{"getter failure", "broken", "k", http.StatusInternalServerError, nil},
+	}
+	internalCode := cacheerrors.ErrorCode(errors.New("database is down"))
+	for _, tt := range tests {
+		wantCode := internalCode
+		if tt.want != nil {
+			wantCode = cacheerrors.ErrorCode(tt.want)
+		}
+		// The only token comes back after 1000s
+		wantRetry := ""
+		if tt.want == cache.ErrRateLimited {
+			wantRetry = "1000"
+		}
+		check := func(t *testing.T, res *http.Response) {
+			t.Helper()
+			defer res.Body.Close()
+			if res.StatusCode != tt.status || res.Header.Get(peers.HeaderErrorCode) != wantCode {
+				t.Fatalf("status %d, code %q; want %d, %q", res.StatusCode, res.Header.Get(peers.HeaderErrorCode), tt.status, wantCode)
+			}
+			if got := res.Header.Get(cacheerrors.HeaderRetryAfter); got != wantRetry {
+				t.Fatalf("Retry-After %q, want %q", got, wantRetry)
+			}
+		}
+
+		t.Run(tt.name+"/plain", func(t *testing.T) {
+			res, err := http.Get(node.server.URL + node.pool.BasePath() + tt.group + "/" + tt.key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			check(t, res)
+		})
+		t.Run(tt.name+"/protobuf", func(t *testing.T) {
+			body, _ := proto.Marshal(&pb.Request{Group: tt.group, Key: tt.key})
+			res, err := http.Post(node.server.URL+node.pool.BasePath(), "application/protobuf", bytes.NewReader(body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			check(t, res)
+		})
+		for _, protocol := range []Protocol{ProtocolHTTP, ProtocolProtobuf} {
+			t.Run(tt.name+"/getter/"+string(protocol), func(t *testing.T) {
+				err := node.getter(WithGetterProtocol(protocol)).GetByProto(&pb.Request{Group: tt.group, Key: tt.key}, &pb.Response{})
+				switch {
+				case tt.want != nil && !errors.Is(err, tt.want):
+					t.Fatalf("got %v, want %v", err, tt.want)
+				case tt.want == nil && (err == nil || cacheerrors.ErrorCode(err) != internalCode):
+					t.Fatalf("got %v, want an internal error", err)
+				case wantRetry != "" && cacheerrors.RetryAfterHeader(err) != wantRetry:
+					t.Fatalf("got %v retrying after %v, want %ss", err, cacheerrors.RetryAfter(err), wantRetry)
+				}
+			})
+		}
+	}
+}
+
+// TestLegacyReadStatuses reads from peers predating error codes, which tell
+// the errors apart only by status and body
+func TestLegacyReadStatuses(t *testing.T) {
+	tests := []struct {
+		status int
+		body   string
+		want   error
+	}{
+		{http.StatusNotFound, "key 'k' not found", cache.ErrNotFound},
+		{http.StatusNotFound, "no such group: scores", cache.ErrNoSuchGroup},
+		{http.StatusBadRequest, "key is empty", cache.ErrEmptyKey},
+		{http.StatusTooManyRequests, "rate limited", cache.ErrRateLimited},
+		{http.StatusBadRequest, "bad request format", nil},
+		{http.StatusInternalServerError, "getter error", nil},
+	}
+	for _, tt := range tests {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, tt.body, tt.status)
+		}))
+		for _, protocol := range []Protocol{ProtocolHTTP, ProtocolProtobuf} {
+			t.Run(tt.body+"/"+string(protocol), func(t *testing.T) {
+				h := NewHTTPGetter(srv.URL+"/_go_cache/", WithGetterProtocol(protocol))
+				err := h.GetByProto(&pb.Request{Group: "scores", Key: "k"}, &pb.Response{})
+				if tt.want != nil {
+					if !errors.Is(err, tt.want) {
+						t.Fatalf("got %v, want %v", err, tt.want)
+					}
+					return
+				}
+				if err == nil || cacheerrors.ErrorCode(err) != cacheerrors.ErrorCode(errors.New(tt.body)) || !strings.Contains(err.Error(), tt.body) {
+					t.Fatalf("got %v, want a plain error carrying the body", err)
+				}
+			})
+		}
+		srv.Close()
+	}
+}
+
+// TestClientRateLimit reads over both paths from two hosts: each host has a
+// bucket of its own, whatever port it connects from, and a refused read says
+// when to retry
+func TestClientRateLimit(t *testing.T) {
+	node := newTestNode(t)
+	g := node.group("scores", echoGetter, cache.WithClientRateLimit(cache.RateLimit{Rate: 0.5}))
+	requests := map[string]func() *http.Request{
+		"plain": func() *http.Request {
+			return httptest.NewRequest(http.MethodGet, node.pool.BasePath()+g.Name()+"/k", nil)
+		},
+		"protobuf": func() *http.Request {
+			body, _ := proto.Marshal(&pb.Request{Group: g.Name(), Key: "k"})
+			r := httptest.NewRequest(http.MethodPost, node.pool.BasePath(), bytes.NewReader(body))
+			r.Header.Set("Content-Type", "application/protobuf")
+			return r
+		},
+	}
+	for name, newRequest := range requests {
+		t.Run(name, func(t *testing.T) {
+			g.SetClientRateLimit(g.ClientRateLimit()) // start from full buckets
+			get := func(remoteAddr string) *httptest.ResponseRecorder {
+				r := newRequest()
+				r.RemoteAddr = remoteAddr
+				w := httptest.NewRecorder()
+				node.pool.ServeHTTP(w, r)
+				return w
+			}
+
+			if w := get("192.0.2.1:1234"); w.Code != http.StatusOK {
+				t.Fatalf("first read = %d %s", w.Code, w.Body.String())
+			}
+			w := get("192.0.2.1:5678")
+			if w.Code != http.StatusTooManyRequests || w.Header().Get(cacheerrors.HeaderRetryAfter) != "2" {
+				t.Fatalf("second read = %d, Retry-After %q; want 429, 2", w.Code, w.Header().Get(cacheerrors.HeaderRetryAfter))
+			}
+			if w := get("192.0.2.2:1234"); w.Code != http.StatusOK {
+				t.Fatalf("read from another host = %d %s", w.Code, w.Body.String())
+			}
+		})
+	}
+}
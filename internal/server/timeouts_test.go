@@ -0,0 +1,63 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newSlowServer starts a server that answers only after the client gives up
+func newSlowServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestHTTPGetterDefaultTimeout(t *testing.T) {
+	g := NewHTTPGetter("http://peer/_gocache/")
+	if g.timeout != defaultClientTimeout || g.client.Timeout != defaultClientTimeout {
+		t.Fatalf("timeout = %v/%v, want %v", g.timeout, g.client.Timeout, defaultClientTimeout)
+	}
+	g = NewHTTPGetter("http://peer/_gocache/", WithGetterTimeout(0))
+	if g.timeout != defaultClientTimeout {
+		t.Fatalf("WithGetterTimeout(0) changed the timeout to %v", g.timeout)
+	}
+}
+
+func TestHTTPGetterHonorsTimeout(t *testing.T) {
+	srv := newSlowServer(t)
+	const timeout = 100 * time.Millisecond
+
+	for _, protocol := range []Protocol{ProtocolHTTP, ProtocolProtobuf} {
+		t.Run(string(protocol), func(t *testing.T) {
+			g := NewHTTPGetter(srv.URL+defaultBasePath, WithGetterTimeout(timeout), WithGetterProtocol(protocol))
+			start := time.Now()
+			_, err := g.Get("scores", "Tom")
+			elapsed := time.Since(start)
+			if err == nil {
+				t.Fatal("Get against a slow peer succeeded")
+			}
+			if elapsed < timeout || elapsed > timeout+time.Second {
+				t.Fatalf("Get returned after %v, want about %v", elapsed, timeout)
+			}
+		})
+	}
+}
+
+func TestHTTPPoolShutdownTimeout(t *testing.T) {
+	p := NewHTTPPool("http://self")
+	if p.shutdownTimeout != defaultShutdownTimeout {
+		t.Fatalf("shutdownTimeout = %v, want %v", p.shutdownTimeout, defaultShutdownTimeout)
+	}
+	p = NewHTTPPool("http://self", WithShutdownTimeout(time.Second))
+	if p.shutdownTimeout != time.Second {
+		t.Fatalf("shutdownTimeout = %v, want 1s", p.shutdownTimeout)
+	}
+}
@@ -0,0 +1,104 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/AdrianWangs/go-cache/internal/peers"
+	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
+)
+
+// staticPeer answers every request with status, advertising version when set,
+// and counts the requests
+func staticPeer(t *testing.T, status int, version string) (*HTTPGetter, *atomic.Int64) {
+	t.Helper()
+	var requests atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if version != "" {
+			w.Header().Set(peers.HeaderProtoVersion, version)
+		}
+		http.Error(w, http.StatusText(status), status)
+	}))
+	t.Cleanup(srv.Close)
+	return NewHTTPGetter(srv.URL + "/_go_cache/"), &requests
+}
+
+// TestOldPeerDegrades runs the optional operations of this build against peers
+// that predate them and checks each fails with peers.ErrUnsupported, not with
+// the peer's raw status
+func TestOldPeerDegrades(t *testing.T) {
+	listOwned := func(h *HTTPGetter) error {
+		return h.ListOwnedBy(context.Background(), &pb.ListOwnedRequest{}, &pb.ListOwnedResponse{})
+	}
+	set := func(h *HTTPGetter) error {
+		return h.SetByProto(context.Background(), &pb.SetRequest{Group: "g", Key: "k"}, &pb.SetResponse{})
+	}
+	tests := []struct {
+		name    string
+		status  int
+		version string
+		call    func(*HTTPGetter) error
+	}{
+		{"ListOwnedBy on an unversioned peer answering 400", http.StatusBadRequest, "", listOwned},
+		{"ListOwnedBy on an unversioned peer answering 405", http.StatusMethodNotAllowed, "", listOwned},
+		{"ListOwnedBy on a v1 peer", http.StatusBadRequest, "1", listOwned},
+		{"SetByProto on an unversioned peer", http.StatusMethodNotAllowed, "", set},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, _ := staticPeer(t, tt.status, tt.version)
+			if err := tt.call(h); !errors.Is(err, peers.ErrUnsupported) {
+				t.Fatalf("got %v, want peers.ErrUnsupported", err)
+			}
+		})
+	}
+}
+
+// TestKnownVersionGatesRequests stops sending a request the peer is known not to
+// support once it advertised its version
+func TestKnownVersionGatesRequests(t *testing.T) {
+	h, requests := staticPeer(t, http.StatusNotFound, "1")
+	h.Get("g", "k")
+	if h.ProtoVersion() != peers.ProtocolV1 {
+		t.Fatalf("ProtoVersion = %d after a v1 response", h.ProtoVersion())
+	}
+
+	before := requests.Load()
+	err := h.ListOwnedBy(context.Background(), &pb.ListOwnedRequest{}, &pb.ListOwnedResponse{})
+	if !errors.Is(err, peers.ErrUnsupported) || !strings.Contains(err.Error(), "v1") {
+		t.Fatalf("ListOwnedBy on a known v1 peer = %v", err)
+	}
+	if requests.Load() != before {
+		t.Fatal("ListOwnedBy sent a request to a peer known to lack the route")
+	}
+}
+
+// TestPoolAdvertisesVersion checks that a current node advertises its version on
+// success and error responses alike
+func TestPoolAdvertisesVersion(t *testing.T) {
+	node := newTestNode(t)
+	node.group("scores", echoGetter)
+	for _, path := range []string{"scores/k", "missing/k"} {
+		resp, err := http.Get(node.server.URL + node.pool.BasePath() + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if v := peers.ParseProtoVersion(resp.Header.Get(peers.HeaderProtoVersion)); v != peers.ProtocolVersion {
+			t.Fatalf("%s: advertised version %d, want %d", path, v, peers.ProtocolVersion)
+		}
+	}
+	h := node.getter()
+	if _, err := h.Get("scores", "k"); err != nil {
+		t.Fatal(err)
+	}
+	if h.ProtoVersion() != peers.ProtocolVersion {
+		t.Fatalf("getter recorded version %d", h.ProtoVersion())
+	}
+}
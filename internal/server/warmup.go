@@ -0,0 +1,489 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/internal/admin"
+	"github.com/AdrianWangs/go-cache/internal/cache"
+	"github.com/AdrianWangs/go-cache/internal/peers"
+	"github.com/AdrianWangs/go-cache/pkg/logger"
+	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// DefaultListOwnedLimit is the page size of ListOwnedBy when the request sets none
+	DefaultListOwnedLimit = 1000
+	// maxListOwnedLimit caps the page size a request may ask for
+	maxListOwnedLimit = 10000
+
+	// DefaultWarmupKeys is the number of keys WarmUp pulls per group by default
+	DefaultWarmupKeys = 1000
+	// DefaultWarmupTimeout is the time budget of WarmUp by default
+	DefaultWarmupTimeout = 30 * time.Second
+	// DefaultWarmupConcurrency is the number of keys WarmUp pulls at once by default
+	DefaultWarmupConcurrency = 8
+
+	// warmupRingRetry is how long WarmUp waits before asking a peer whose ring
+	// differs again; the peer applies the new ring on its next peer update
+	warmupRingRetry = 500 * time.Millisecond
+)
+
+// ListOwned lists the keys of req.Group cached on this node that the ring
+// assigns to req.NodeId, one page at a time. It serves the ListOwnedBy RPC of
+// both transports. Ownership is decided on this node's ring, so a request made
+// on a different ring, as identified by RingVersion, fails with
+// peers.ErrRingMismatch. Groups excluded by the allowlist fail with
+// cache.ErrGroupForbidden and unknown groups with cache.ErrNoSuchGroup.
+func (p *HTTPPool) ListOwned(req *pb.ListOwnedRequest) (*pb.ListOwnedResponse, error) {
+	if !p.servable.Allows(req.GetGroup()) {
+		return nil, cache.ErrGroupForbidden
+	}
+	group := p.registry.Get(req.GetGroup())
+	if group == nil {
+		return nil, cache.ErrNoSuchGroup
+	}
+	if req.GetNodeId() == "" {
+		return nil, errors.New("node_id is required")
+	}
+
+	p.mu.RLock()
+	ring, version := p.peers, p.ringVersion
+	p.mu.RUnlock()
+	if ring == nil || req.GetRingVersion() != version {
+		return nil, fmt.Errorf("%w: caller has %q, %s has %q", peers.ErrRingMismatch, req.GetRingVersion(), p.selfID, version)
+	}
+
+	limit := int(req.GetLimit())
+	if limit <= 0 {
+		limit = DefaultListOwnedLimit
+	}
+	limit = min(limit, maxListOwnedLimit)
+	keys, next := group.OwnedKeys(func(key string) bool {
+		return ring.Get(key) == req.GetNodeId()
+	}, req.GetCursor(), limit)
+	return cache.OwnedKeysProto(keys, next), nil
+}
+
+// WarmupOptions bounds a WarmUp. The zero value pulls the DefaultWarmupKeys
+// hottest keys of every group within DefaultWarmupTimeout.
+type WarmupOptions struct {
+	Groups      []string      // groups to warm up, every group of the registry when empty
+	MaxKeys     int           // hottest keys pulled per group, DefaultWarmupKeys when <= 0
+	MaxBytes    int64         // total size of the pulled values across groups, unlimited when <= 0
+	Timeout     time.Duration // budget of the whole warm-up, DefaultWarmupTimeout when <= 0
+	Rate        int           // keys pulled per second, unlimited when <= 0
+	Concurrency int           // keys pulled at once, DefaultWarmupConcurrency when <= 0
+	PageSize    int           // keys per ListOwnedBy page, DefaultListOwnedLimit when <= 0
+}
+
+// withDefaults fills the unset options
+func (o WarmupOptions) withDefaults() WarmupOptions {
+	if o.MaxKeys <= 0 {
+		o.MaxKeys = DefaultWarmupKeys
+	}
+	if o.Timeout <= 0 {
+		o.Timeout = DefaultWarmupTimeout
+	}
+	if o.Concurrency <= 0 {
+		o.Concurrency = DefaultWarmupConcurrency
+	}
+	if o.PageSize <= 0 {
+		o.PageSize = DefaultListOwnedLimit
+	}
+	return o
+}
+
+// WarmupState is the phase of the last WarmUp
+type WarmupState string
+
+const (
+	// WarmupRunning means a warm-up is in progress
+	WarmupRunning WarmupState = "running"
+	// WarmupDone means the warm-up finished, possibly stopped early by its
+	// time or byte budget, see WarmupStatus.Error
+	WarmupDone WarmupState = "done"
+	// WarmupCancelled means the caller cancelled the warm-up, e.g. on shutdown
+	WarmupCancelled WarmupState = "cancelled"
+	// WarmupFailed means the warm-up could not run, e.g. because this node is
+	// not on its own ring yet
+	WarmupFailed WarmupState = "failed"
+)
+
+// WarmupStatus reports the progress of the last WarmUp
+type WarmupStatus struct {
+	State      WarmupState
+	Started    time.Time
+	Elapsed    time.Duration // so far while running, the total afterwards
+	Candidates int64         // keys peers listed as owned by this node
+	Selected   int64         // keys chosen to pull by access count and budget
+	Pulled     int64         // keys stored in the local cache
+	Bytes      int64         // size of the pulled values
+	Skipped    int64         // keys gone from the peer or already cached here
+	Failed     int64         // keys whose pull failed
+	Error      string        // why the warm-up stopped early, empty if it did not
+}
+
+// Proto converts the status for StatsResponse.warmup
+func (s WarmupStatus) Proto() *pb.WarmupStats {
+	stats := &pb.WarmupStats{
+		State:           string(s.State),
+		StartedUnixNano: proto.Int64(s.Started.UnixNano()),
+		ElapsedMs:       proto.Int64(s.Elapsed.Milliseconds()),
+		Candidates:      proto.Int64(s.Candidates),
+		Selected:        proto.Int64(s.Selected),
+		Pulled:          proto.Int64(s.Pulled),
+		Bytes:           proto.Int64(s.Bytes),
+		Skipped:         proto.Int64(s.Skipped),
+		Failed:          proto.Int64(s.Failed),
+	}
+	if s.Error != "" {
+		stats.Error = proto.String(s.Error)
+	}
+	return stats
+}
+
+// warmupProgress records the status of the last WarmUp
+type warmupProgress struct {
+	mu     sync.Mutex
+	status WarmupStatus // zero State until the first WarmUp
+}
+
+// start begins a warm-up, reporting false when one is already running
+func (w *warmupProgress) start() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.status.State == WarmupRunning {
+		return false
+	}
+	w.status = WarmupStatus{State: WarmupRunning, Started: time.Now()}
+	return true
+}
+
+// update applies fn to the status under the lock
+func (w *warmupProgress) update(fn func(s *WarmupStatus)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	fn(&w.status)
+}
+
+// finish records the outcome of the warm-up
+func (w *warmupProgress) finish(state WarmupState, reason string) WarmupStatus {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.status.State = state
+	w.status.Error = reason
+	w.status.Elapsed = time.Since(w.status.Started)
+	return w.status
+}
+
+// snapshot returns the status with the elapsed time of a running warm-up
+func (w *warmupProgress) snapshot() WarmupStatus {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	s := w.status
+	if s.State == WarmupRunning {
+		s.Elapsed = time.Since(s.Started)
+	}
+	return s
+}
+
+// WarmupStatus returns the progress of the last WarmUp; State is empty if
+// none was started
+func (p *HTTPPool) WarmupStatus() WarmupStatus {
+	return p.warmup.snapshot()
+}
+
+// WarmupStats returns the progress of the last WarmUp for StatsResponse, nil
+// if none was started
+func (p *HTTPPool) WarmupStats() *pb.WarmupStats {
+	s := p.warmup.snapshot()
+	if s.State == "" {
+		return nil
+	}
+	return s.Proto()
+}
+
+var (
+	// errWarmupRunning is returned by WarmUp while another warm-up runs
+	errWarmupRunning = errors.New("warm-up already running")
+	// errByteBudget stops a warm-up whose MaxBytes is used up
+	errByteBudget = errors.New("byte budget exhausted")
+)
+
+// warmCandidate is a key a peer listed as owned by this node
+type warmCandidate struct {
+	key      string
+	size     int64
+	accesses uint64
+	sources  []*HTTPGetter // peers caching the key, in listing order
+}
+
+// WarmUp copies the hottest keys this node owns from the peers that cached
+// them before it joined the ring, so that it does not start cold and send
+// every miss to the data source. Call it once the pool's ring includes this
+// node, e.g. after the first peer update.
+//
+// For every group, each peer lists the keys it caches that now hash to this
+// node (ListOwnedBy). Peers whose ring differs, because they have not applied
+// the new ring yet, are asked again until they have or the time budget runs
+// out. The keys are ranked by the number of reads the peers counted and the
+// top MaxKeys that fit in what remains of MaxBytes are pulled with cache-only
+// reads, Concurrency at a time and at most Rate per second. Pulled values keep
+// their expiry and never replace a value this node already caches.
+//
+// Peers that fail or do not support ListOwnedBy are skipped. Running out of
+// time or bytes ends the warm-up early without an error; cancelling ctx stops
+// it and returns ctx's error. Progress is reported by WarmupStatus and in the
+// stats route.
+func (p *HTTPPool) WarmUp(ctx context.Context, opts WarmupOptions) error {
+	opts = opts.withDefaults()
+	if !p.warmup.start() {
+		return errWarmupRunning
+	}
+	p.log.Infof("[Server %s] 开始预热，每组最多 %d 个 key，时间预算 %v", p.selfID, opts.MaxKeys, opts.Timeout)
+
+	budgetCtx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+	err := p.warmUp(budgetCtx, opts)
+
+	var status WarmupStatus
+	switch {
+	case err == nil:
+		status = p.warmup.finish(WarmupDone, "")
+	case ctx.Err() != nil:
+		status = p.warmup.finish(WarmupCancelled, ctx.Err().Error())
+		err = ctx.Err()
+	case errors.Is(err, context.DeadlineExceeded):
+		status = p.warmup.finish(WarmupDone, "time budget exhausted")
+		err = nil
+	case errors.Is(err, errByteBudget):
+		status = p.warmup.finish(WarmupDone, err.Error())
+		err = nil
+	default:
+		status = p.warmup.finish(WarmupFailed, err.Error())
+	}
+	p.log.WithFields(logger.Fields{
+		"state":      status.State,
+		"candidates": status.Candidates,
+		"selected":   status.Selected,
+		"pulled":     status.Pulled,
+		"bytes":      status.Bytes,
+		"skipped":    status.Skipped,
+		"failed":     status.Failed,
+		"elapsed":    status.Elapsed.Round(time.Millisecond).String(),
+		"reason":     status.Error,
+	}).Infof("[Server %s] 预热结束", p.selfID)
+	return err
+}
+
+// warmUp runs the phases of WarmUp group by group
+func (p *HTTPPool) warmUp(ctx context.Context, opts WarmupOptions) error {
+	p.mu.RLock()
+	onRing := false
+	for _, peer := range p.peerList {
+		onRing = onRing || peer.ID == p.selfID
+	}
+	sources := make([]*HTTPGetter, 0, len(p.peerList))
+	for _, peer := range p.peerList {
+		if g, ok := p.httpGetters[peer.ID]; ok {
+			sources = append(sources, g)
+		}
+	}
+	p.mu.RUnlock()
+	if !onRing {
+		return fmt.Errorf("node %s is not on the ring yet", p.selfID)
+	}
+
+	groups := opts.Groups
+	if len(groups) == 0 {
+		groups = p.registry.Names()
+	}
+	limiter := admin.NewLimiter(1, opts.Rate)
+	remaining := opts.MaxBytes
+
+	for _, name := range groups {
+		group := p.registry.Get(name)
+		if group == nil {
+			continue
+		}
+		candidates, err := p.ownedCandidates(ctx, name, sources, opts.PageSize)
+		if err != nil {
+			return err
+		}
+		selected := selectWarmup(candidates, opts.MaxKeys, &remaining, opts.MaxBytes > 0)
+		p.warmup.update(func(s *WarmupStatus) {
+			s.Candidates += int64(len(candidates))
+			s.Selected += int64(len(selected))
+		})
+		if err := p.pullAll(ctx, group, selected, opts.Concurrency, limiter); err != nil {
+			return err
+		}
+		if opts.MaxBytes > 0 && remaining <= 0 {
+			return errByteBudget
+		}
+	}
+	return nil
+}
+
+// ownedCandidates asks every peer for the keys of group it caches that this
+// node owns, merging keys several peers cache
+func (p *HTTPPool) ownedCandidates(ctx context.Context, group string, sources []*HTTPGetter, pageSize int) ([]*warmCandidate, error) {
+	byKey := make(map[string]*warmCandidate)
+	var candidates []*warmCandidate
+	for _, source := range sources {
+		err := p.listOwnedFrom(ctx, source, group, pageSize, func(k *pb.OwnedKey) {
+			c, ok := byKey[k.GetKey()]
+			if !ok {
+				c = &warmCandidate{key: k.GetKey()}
+				byKey[k.GetKey()] = c
+				candidates = append(candidates, c)
+			}
+			c.size = max(c.size, k.GetSize())
+			c.accesses = max(c.accesses, k.GetAccesses())
+			c.sources = append(c.sources, source)
+		})
+		switch {
+		case err == nil:
+		case ctx.Err() != nil:
+			return nil, ctx.Err()
+		case errors.Is(err, peers.ErrUnsupported):
+			p.log.Debugf("[Server %s] 对等节点 %s 不支持列出归属的 key，跳过: %v", p.selfID, source, err)
+		default:
+			p.log.Warnf("[Server %s] 从对等节点 %s 列出组 %s 归属本节点的 key 失败，跳过: %v", p.selfID, source, group, err)
+		}
+	}
+	return candidates, nil
+}
+
+// listOwnedFrom pages through the keys of group source caches that this node
+// owns, waiting for source to apply this node's ring while it differs
+func (p *HTTPPool) listOwnedFrom(ctx context.Context, source *HTTPGetter, group string, pageSize int, fn func(*pb.OwnedKey)) error {
+	var cursor *string
+	for {
+		req := &pb.ListOwnedRequest{
+			Group:       group,
+			RingVersion: p.RingVersion(),
+			NodeId:      p.selfID,
+			Limit:       proto.Int32(int32(pageSize)),
+			Cursor:      cursor,
+		}
+		resp := &pb.ListOwnedResponse{}
+		err := source.ListOwnedBy(ctx, req, resp)
+		if errors.Is(err, peers.ErrRingMismatch) {
+			p.log.Debugf("[Server %s] 对等节点 %s 的哈希环与本节点不同，稍后重试: %v", p.selfID, source, err)
+			if err := sleepContext(ctx, warmupRingRetry); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		for _, k := range resp.GetKeys() {
+			fn(k)
+		}
+		if resp.NextCursor == nil {
+			return nil
+		}
+		cursor = resp.NextCursor
+	}
+}
+
+// selectWarmup ranks candidates by access count, most read first, and returns
+// the first maxKeys that fit in *remaining bytes, charging their size to it
+func selectWarmup(candidates []*warmCandidate, maxKeys int, remaining *int64, limitBytes bool) []*warmCandidate {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].accesses != candidates[j].accesses {
+			return candidates[i].accesses > candidates[j].accesses
+		}
+		return candidates[i].key < candidates[j].key
+	})
+	var selected []*warmCandidate
+	for _, c := range candidates {
+		if len(selected) == maxKeys {
+			break
+		}
+		if limitBytes {
+			if c.size > *remaining {
+				continue
+			}
+			*remaining -= c.size
+		}
+		selected = append(selected, c)
+	}
+	return selected
+}
+
+// pullAll pulls the selected keys into group, concurrency at a time and paced
+// by limiter, until all are pulled or ctx is done
+func (p *HTTPPool) pullAll(ctx context.Context, group *cache.Group, selected []*warmCandidate, concurrency int, limiter *admin.Limiter) error {
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for _, c := range selected {
+		if err := limiter.Wait(ctx); err != nil {
+			return err
+		}
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		wg.Add(1)
+		go func(c *warmCandidate) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			p.pull(ctx, group, c)
+		}(c)
+	}
+	return nil
+}
+
+// pull copies one key from the first of its sources that still caches it
+func (p *HTTPPool) pull(ctx context.Context, group *cache.Group, c *warmCandidate) {
+	started := time.Now()
+	for _, source := range c.sources {
+		req := &pb.Request{Group: group.Name(), Key: c.key, CacheOnly: proto.Bool(true)}
+		resp := &pb.Response{}
+		err := source.GetByProtoContext(ctx, req, resp)
+		if cache.IsKeyNotFoundError(err) {
+			// Evicted or deleted since it was listed; another source may have it
+			continue
+		}
+		if err != nil {
+			if ctx.Err() == nil {
+				p.log.Debugf("[Server %s] 预热时从 %s 拉取 %s/%s 失败: %v", p.selfID, source, group.Name(), logger.Key(c.key), err)
+			}
+			p.warmup.update(func(s *WarmupStatus) { s.Failed++ })
+			return
+		}
+		meta := cache.MetaFromProto(resp)
+		if !group.Warm(c.key, resp.GetValue(), meta.ExpiresAt, started) {
+			break
+		}
+		p.warmup.update(func(s *WarmupStatus) {
+			s.Pulled++
+			s.Bytes += int64(len(resp.GetValue()))
+		})
+		return
+	}
+	p.warmup.update(func(s *WarmupStatus) { s.Skipped++ })
+}
+
+// sleepContext waits for d or until ctx is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,219 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/internal/cache"
+	"github.com/AdrianWangs/go-cache/internal/peers"
+	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
+	"google.golang.org/protobuf/proto"
+)
+
+// warmupPair starts node a caching n keys, key-0 read most and key-(n-1)
+// least, and an empty node b, then puts both on one ring. It returns a's group.
+func warmupPair(t *testing.T, n int, bOpts ...HTTPPoolOption) (a, b *testNode, cached *cache.Group) {
+	t.Helper()
+	a = newTestNode(t, WithSelfID("a"), WithServableGroups("scores"))
+	b = newTestNode(t, append([]HTTPPoolOption{WithSelfID("b")}, bOpts...)...)
+	cached = a.group("scores", echoGetter)
+	a.group("internal", echoGetter)
+	b.group("scores", echoGetter)
+	for i := 0; i < n; i++ {
+		key := fmt.Sprintf("key-%03d", i)
+		if err := cached.SetLocally(key, []byte("v:"+key), 0); err != nil {
+			t.Fatal(err)
+		}
+		for r := 0; r < n-i; r++ {
+			mustGet(t, cached, key)
+		}
+	}
+
+	ring := []Peer{
+		{ID: "a", Addr: a.server.URL},
+		{ID: "b", Addr: b.server.URL},
+	}
+	a.pool.SetPeers(ring...)
+	b.pool.SetPeers(ring...)
+	return a, b, cached
+}
+
+// mustGet reads key from g
+func mustGet(t *testing.T, g *cache.Group, key string) {
+	t.Helper()
+	if _, err := g.Get(key); err != nil {
+		t.Fatalf("Get(%s): %v", key, err)
+	}
+}
+
+// ownedBy returns the keys of key-000..key-(n-1) pool's ring assigns to id
+func ownedBy(pool *HTTPPool, id string, n int) []string {
+	var keys []string
+	for i := 0; i < n; i++ {
+		key := fmt.Sprintf("key-%03d", i)
+		if pool.peers.Get(key) == id {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+func TestListOwned(t *testing.T) {
+	const n = 60
+	a, b, _ := warmupPair(t, n)
+	want := ownedBy(a.pool, "b", n)
+	if len(want) == 0 {
+		t.Fatal("b owns no key")
+	}
+
+	// page through the keys over the wire
+	var got []string
+	var cursor *string
+	for pages := 0; ; pages++ {
+		resp := &pb.ListOwnedResponse{}
+		err := a.getter().ListOwnedBy(context.Background(), &pb.ListOwnedRequest{
+			Group: "scores", RingVersion: b.pool.RingVersion(), NodeId: "b",
+			Limit: proto.Int32(7), Cursor: cursor,
+		}, resp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, k := range resp.GetKeys() {
+			got = append(got, k.GetKey())
+			if k.GetSize() != int64(len("v:"+k.GetKey())) || k.GetAccesses() == 0 {
+				t.Fatalf("listed %v", k)
+			}
+		}
+		if resp.NextCursor == nil {
+			break
+		}
+		if pages > n {
+			t.Fatal("listing does not end")
+		}
+		cursor = resp.NextCursor
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("listed %v, want %v", got, want)
+	}
+
+	version := b.pool.RingVersion()
+	tests := []struct {
+		name  string
+		node  *testNode
+		group string
+		ring  string
+		check func(error) bool
+	}{
+		{"other ring", a, "scores", "stale", func(err error) bool { return errors.Is(err, peers.ErrRingMismatch) }},
+		{"excluded group", a, "internal", version, cache.IsGroupForbiddenError},
+		{"excluded unknown group", a, "missing", version, cache.IsGroupForbiddenError},
+		{"unknown group", b, "missing", version, cache.IsGroupNotFoundError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &pb.ListOwnedRequest{Group: tt.group, RingVersion: tt.ring, NodeId: "b"}
+			if err := tt.node.getter().ListOwnedBy(context.Background(), req, &pb.ListOwnedResponse{}); !tt.check(err) {
+				t.Fatalf("ListOwnedBy = %v", err)
+			}
+		})
+	}
+}
+
+func TestWarmUpBudgets(t *testing.T) {
+	const n = 60
+	tests := []struct {
+		name string
+		opts WarmupOptions
+		want int // hottest owned keys pulled, -1 for all of them
+	}{
+		{"defaults", WarmupOptions{}, -1},
+		{"key budget", WarmupOptions{MaxKeys: 5, PageSize: 4}, 5},
+		{"byte budget", WarmupOptions{MaxBytes: 3*int64(len("v:key-000")) + 1}, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b, _ := warmupPair(t, n)
+			owned := ownedBy(a.pool, "b", n)
+			want := owned
+			if tt.want >= 0 {
+				want = owned[:tt.want]
+			}
+			if err := b.pool.WarmUp(context.Background(), tt.opts); err != nil {
+				t.Fatalf("WarmUp: %v", err)
+			}
+
+			warmed := b.pool.registry.Get("scores")
+			for i, key := range owned {
+				if _, _, ok := warmed.Peek(key); ok != (i < len(want)) {
+					t.Fatalf("%s cached on b: %v, want the %d hottest keys", key, ok, len(want))
+				}
+			}
+			s := b.pool.WarmupStatus()
+			if s.State != WarmupDone || s.Candidates != int64(len(owned)) || s.Selected != int64(len(want)) ||
+				s.Pulled != int64(len(want)) || s.Bytes != int64(len(want)*len("v:key-000")) {
+				t.Fatalf("status %+v, want %d of %d keys pulled", s, len(want), len(owned))
+			}
+		})
+	}
+}
+
+func TestWarmUpNotOnRing(t *testing.T) {
+	node := newTestNode(t, WithSelfID("c"))
+	node.pool.SetPeers(Peer{ID: "a", Addr: "http://10.0.0.1:8001"})
+	if err := node.pool.WarmUp(context.Background(), WarmupOptions{}); err == nil {
+		t.Fatal("WarmUp succeeded off the ring")
+	}
+	if s := node.pool.WarmupStatus(); s.State != WarmupFailed || s.Error == "" {
+		t.Fatalf("status %+v", s)
+	}
+}
+
+func TestWarmUpCancelled(t *testing.T) {
+	const n = 60
+	a, b, _ := warmupPair(t, n)
+	if b.pool.WarmupStats() != nil {
+		t.Fatal("warm-up stats before the first warm-up")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- b.pool.WarmUp(ctx, WarmupOptions{Rate: 1}) }()
+	waitFor(t, "the first key to be pulled", func() bool {
+		return b.pool.WarmupStatus().Pulled == 1
+	})
+	if err := b.pool.WarmUp(context.Background(), WarmupOptions{}); !errors.Is(err, errWarmupRunning) {
+		t.Fatalf("second WarmUp = %v", err)
+	}
+	if stats := b.pool.WarmupStats(); stats.GetState() != string(WarmupRunning) || stats.GetElapsedMs() < 0 {
+		t.Fatalf("stats while running: %v", stats)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("WarmUp = %v, want context.Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("WarmUp not stopped by its ctx")
+	}
+	s := b.pool.WarmupStatus()
+	if s.State != WarmupCancelled || s.Pulled >= int64(len(ownedBy(a.pool, "b", n))) {
+		t.Fatalf("status %+v", s)
+	}
+}
+
+// waitFor polls cond until it holds or 5 seconds pass
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -4,6 +4,7 @@ package singleflight
 import (
 	"context"
 	"sync"
+	"sync/atomic"
 )
 
 // call represents an in-flight or completed Do call
@@ -13,6 +14,9 @@ type call struct {
 	err   error          // error from the call
 	ctx   context.Context
 	ready chan struct{} // closed when val is ready
+
+	waiters int                // callers waiting on the call, guarded by Group.mu
+	cancel  context.CancelFunc // cancels the ctx of a DoContext call, nil for Do and DoChan
 }
 
 // Group represents a class of work and forms a namespace in which
@@ -20,6 +24,34 @@ type call struct {
 type Group struct {
 	mu sync.Mutex       // protects m
 	m  map[string]*call // lazily initialized
+
+	executed   atomic.Int64 // calls that ran fn
+	suppressed atomic.Int64 // calls that joined one already in flight
+	inflight   atomic.Int64 // executions of fn not yet returned
+}
+
+// Stats counts the calls made on a Group since it was created
+type Stats struct {
+	Executed   int64 // calls that ran fn
+	Suppressed int64 // calls that joined a call already in flight and shared its result
+	InFlight   int64 // executions of fn still running, including abandoned DoContext calls
+}
+
+// Stats returns the counts of executed and suppressed calls. Every call of Do,
+// DoContext and DoChan counts as exactly one of the two, so Suppressed out of
+// Executed+Suppressed is the share of work saved.
+func (g *Group) Stats() Stats {
+	return Stats{
+		Executed:   g.executed.Load(),
+		Suppressed: g.suppressed.Load(),
+		InFlight:   g.inflight.Load(),
+	}
+}
+
+// start counts a call that runs fn
+func (g *Group) start() {
+	g.executed.Add(1)
+	g.inflight.Add(1)
 }
 
 // Result holds the results of a Do call
@@ -39,6 +71,9 @@ func (g *Group) Do(key string, fn func() (interface{}, error)) (interface{}, err
 		g.m = make(map[string]*call)
 	}
 	if c, ok := g.m[key]; ok {
+		// Do callers never leave, so the call can no longer be cancelled
+		c.waiters++
+		g.suppressed.Add(1)
 		g.mu.Unlock()
 		c.wg.Wait()
 		return c.val, c.err
@@ -46,7 +81,9 @@ func (g *Group) Do(key string, fn func() (interface{}, error)) (interface{}, err
 	c := new(call)
 	c.wg.Add(1)
 	c.ready = make(chan struct{})
+	c.waiters = 1
 	g.m[key] = c
+	g.start()
 	g.mu.Unlock()
 
 	// Execute the function
@@ -60,10 +97,15 @@ func (g *Group) Do(key string, fn func() (interface{}, error)) (interface{}, err
 // doCall executes the call and signals completion to any waiting callers
 func (g *Group) doCall(key string, c *call, fn func() (interface{}, error)) {
 	defer func() {
-		// Remove the call from the map when done
+		// Remove the call from the map when done, unless a cancelled call was
+		// already replaced by a new one
 		g.mu.Lock()
-		delete(g.m, key)
+		if g.m[key] == c {
+			delete(g.m, key)
+		}
 		g.mu.Unlock()
+		g.inflight.Add(-1)
+		close(c.ready)
 		c.wg.Done()
 	}()
 
@@ -71,6 +113,66 @@ func (g *Group) doCall(key string, c *call, fn func() (interface{}, error)) {
 	c.val, c.err = fn()
 }
 
+// DoContext is like Do, but fn runs under a context of its own that is
+// cancelled once every caller waiting on the call has given up. A caller whose
+// ctx is done before the result is ready returns ctx.Err() at once; the call
+// goes on for the remaining callers. When the last one leaves, fn's context is
+// cancelled and the key is released, so the next caller starts a new call
+// instead of joining the abandoned one. Callers of Do and DoChan never leave,
+// so a call they wait on is never cancelled.
+//
+// fn's context carries the values of the ctx of the caller that started the
+// call, but not its deadline or cancellation.
+func (g *Group) DoContext(ctx context.Context, key string, fn func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	g.mu.Lock()
+	if g.m == nil {
+		g.m = make(map[string]*call)
+	}
+	c, ok := g.m[key]
+	if ok {
+		c.waiters++
+		g.suppressed.Add(1)
+	} else {
+		c = new(call)
+		c.wg.Add(1)
+		c.ready = make(chan struct{})
+		c.waiters = 1
+		c.ctx, c.cancel = context.WithCancel(context.WithoutCancel(ctx))
+		g.m[key] = c
+		g.start()
+	}
+	g.mu.Unlock()
+
+	if !ok {
+		go g.doCall(key, c, func() (interface{}, error) {
+			defer c.cancel()
+			return fn(c.ctx)
+		})
+	}
+
+	select {
+	case <-c.ready:
+		return c.val, c.err
+	case <-ctx.Done():
+		g.leave(key, c)
+		return nil, ctx.Err()
+	}
+}
+
+// leave removes a waiter from c and cancels c when it was the last one
+func (g *Group) leave(key string, c *call) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	c.waiters--
+	if c.waiters > 0 || c.cancel == nil {
+		return
+	}
+	c.cancel()
+	if g.m[key] == c {
+		delete(g.m, key)
+	}
+}
+
 // DoChan is like Do but returns a channel that will receive the
 // results when they are ready.
 func (g *Group) DoChan(key string, fn func() (interface{}, error)) <-chan Result {
@@ -80,6 +182,8 @@ func (g *Group) DoChan(key string, fn func() (interface{}, error)) <-chan Result
 		g.m = make(map[string]*call)
 	}
 	if c, ok := g.m[key]; ok {
+		c.waiters++
+		g.suppressed.Add(1)
 		g.mu.Unlock()
 		go func() {
 			c.wg.Wait()
@@ -90,15 +194,21 @@ func (g *Group) DoChan(key string, fn func() (interface{}, error)) <-chan Result
 	c := new(call)
 	c.wg.Add(1)
 	c.ready = make(chan struct{})
+	c.waiters = 1
 	g.m[key] = c
+	g.start()
 	g.mu.Unlock()
 
 	go func() {
 		c.val, c.err = fn()
+		g.inflight.Add(-1)
+		close(c.ready)
 		c.wg.Done()
 		ch <- Result{c.val, c.err, false}
 		g.mu.Lock()
-		delete(g.m, key)
+		if g.m[key] == c {
+			delete(g.m, key)
+		}
 		g.mu.Unlock()
 	}()
 
@@ -0,0 +1,172 @@
+package singleflight
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// waitCtx waits for ctx to be done and returns its error
+func waitCtx(t *testing.T, ctx context.Context) error {
+	t.Helper()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(5 * time.Second):
+		t.Fatal("call context not cancelled")
+		return nil
+	}
+}
+
+// waitWaiters waits until n callers wait on the call for key
+func waitWaiters(t *testing.T, g *Group, key string, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		g.mu.Lock()
+		waiters := g.m[key].waiters
+		g.mu.Unlock()
+		if waiters == n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d callers wait on %q, want %d", waiters, key, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// TestDoContextCancelsWithLastWaiter keeps the call going while one caller
+// waits and cancels it when the last one leaves
+func TestDoContextCancelsWithLastWaiter(t *testing.T) {
+	var g Group
+	started := make(chan context.Context, 1)
+	release := make(chan struct{})
+	fn := func(ctx context.Context) (interface{}, error) {
+		started <- ctx
+		select {
+		case <-release:
+			return "v", nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	errs := make(chan error, 2)
+	go func() { _, err := g.DoContext(ctx1, "k", fn); errs <- err }()
+	callCtx := <-started
+	go func() { _, err := g.DoContext(ctx2, "k", fn); errs <- err }()
+	waitWaiters(t, &g, "k", 2)
+
+	cancel1()
+	if err := <-errs; !errors.Is(err, context.Canceled) {
+		t.Fatalf("first caller: %v", err)
+	}
+	if callCtx.Err() != nil {
+		t.Fatal("call cancelled while a caller still waits")
+	}
+	cancel2()
+	if err := <-errs; !errors.Is(err, context.Canceled) {
+		t.Fatalf("second caller: %v", err)
+	}
+	if err := waitCtx(t, callCtx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("call context: %v", err)
+	}
+
+	// the abandoned call is released, so the next caller starts a new one
+	go func() { close(release) }()
+	v, err := g.DoContext(context.Background(), "k", fn)
+	if err != nil || v != "v" {
+		t.Fatalf("new call = %v, %v", v, err)
+	}
+}
+
+// TestDoContextPinnedByDo never cancels a call a Do caller waits on
+func TestDoContextPinnedByDo(t *testing.T) {
+	var g Group
+	started := make(chan context.Context, 1)
+	release := make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error, 1)
+	go func() {
+		_, err := g.DoContext(ctx, "k", func(ctx context.Context) (interface{}, error) {
+			started <- ctx
+			<-release
+			return "v", ctx.Err()
+		})
+		errs <- err
+	}()
+	callCtx := <-started
+
+	vals := make(chan interface{}, 1)
+	go func() {
+		v, _ := g.Do("k", func() (interface{}, error) { return "other", nil })
+		vals <- v
+	}()
+	waitWaiters(t, &g, "k", 2)
+
+	cancel()
+	<-errs
+	if callCtx.Err() != nil {
+		t.Fatal("call cancelled while a Do caller waits")
+	}
+	close(release)
+	if v := <-vals; v != "v" {
+		t.Fatalf("Do = %v, want the shared result", v)
+	}
+}
+
+// TestStats fires 1000 concurrent calls for one key across Do, DoChan and
+// DoContext: one runs fn, the other 999 share its result
+func TestStats(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+	var g Group
+	const calls = 1000
+	release := make(chan struct{})
+	var runs atomic.Int64
+	fn := func() (interface{}, error) {
+		runs.Add(1)
+		<-release
+		return "v", nil
+	}
+
+	results := make(chan interface{}, calls)
+	for i := 0; i < calls; i++ {
+		go func() {
+			switch i % 3 {
+			case 0:
+				v, _ := g.Do("k", fn)
+				results <- v
+			case 1:
+				results <- (<-g.DoChan("k", fn)).Val
+			default:
+				v, _ := g.DoContext(context.Background(), "k", func(context.Context) (interface{}, error) { return fn() })
+				results <- v
+			}
+		}()
+	}
+	waitWaiters(t, &g, "k", calls)
+	if s := g.Stats(); s.Executed != 1 || s.Suppressed != calls-1 || s.InFlight != 1 {
+		t.Fatalf("stats while the call runs = %+v", s)
+	}
+	close(release)
+	for i := 0; i < calls; i++ {
+		if v := <-results; v != "v" {
+			t.Fatalf("result = %v", v)
+		}
+	}
+	if s := g.Stats(); s.Executed != 1 || s.Suppressed != calls-1 || s.InFlight != 0 || runs.Load() != 1 {
+		t.Fatalf("stats = %+v after %d runs", s, runs.Load())
+	}
+
+	// A later call for the same key runs fn again
+	g.Do("k", func() (interface{}, error) { return nil, errors.New("boom") })
+	if s := g.Stats(); s.Executed != 2 || s.Suppressed != calls-1 {
+		t.Fatalf("stats = %+v", s)
+	}
+}
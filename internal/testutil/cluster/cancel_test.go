@@ -0,0 +1,77 @@
+package cluster_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/internal/testutil/cluster"
+)
+
+// waitUntil 轮询 cond 直到为真，5 秒内未满足则失败
+func waitUntil(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("等待%s超时", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// TestClientDisconnectCancelsLoad 客户端在读取中途断开：API 服务器放弃请求，归属节点取消
+// 没有其他调用方等待的加载，数据源观察到取消，各层的计数器记录这次断开
+func TestClientDisconnectCancelsLoad(t *testing.T) {
+	c := startCluster(t, cluster.Options{})
+	source := c.Source("test")
+	source.Set("slow", "v-slow")
+	source.SetDelay(time.Minute)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, _, err := c.GetContext(ctx, "test", "slow")
+		done <- err
+	}()
+	waitUntil(t, "数据源开始加载", func() bool { return source.Loads("slow") == 1 })
+	cancel()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatal("客户端断开后请求仍然成功")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("客户端断开后请求没有返回")
+	}
+
+	waitUntil(t, "数据源观察到取消", func() bool { return source.Cancels("slow") == 1 })
+	group := c.Owner("slow").Group("test")
+	waitUntil(t, "归属节点计入取消的加载", func() bool {
+		stats := group.Stats()
+		return stats.AbortedLoads == 1 && stats.CancelledGets >= 1
+	})
+	waitUntil(t, "API 服务器计入客户端断开", func() bool {
+		resp, err := http.Get(c.APIURL() + "/api/metrics")
+		if err != nil {
+			return false
+		}
+		defer resp.Body.Close()
+		var metrics struct {
+			ClientCancelledCount int64 `json:"clientCancelledCount"`
+		}
+		return json.NewDecoder(resp.Body).Decode(&metrics) == nil && metrics.ClientCancelledCount == 1
+	})
+	if _, _, ok := group.Peek("slow"); ok {
+		t.Fatal("取消的加载写入了缓存")
+	}
+
+	// 放弃的加载不会被之后的读取加入，新的读取重新加载并成功
+	source.SetDelay(0)
+	mustGet(t, c, "slow", "v-slow")
+	if n := source.Loads("slow"); n != 2 {
+		t.Fatalf("slow 加载了 %d 次, want 2", n)
+	}
+}
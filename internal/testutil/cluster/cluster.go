@@ -0,0 +1,391 @@
+// Package cluster 在进程内启动一个完整的测试集群：N 个缓存节点（HTTPPool 加缓存组）
+// 和一个 API 服务器，全部监听 127.0.0.1 的随机端口，节点列表由不依赖 etcd 的 Discovery 提供。
+// 用于验证路由、节点增减、删除和 singleflight 等分布式行为，无需手动启动多个进程。
+//
+// 每个节点的缓存组创建在独立的 cache.Registry 中，互不影响；
+// 但节点级的全局状态（节点模式 cache.SetNodeMode、节点级限流）在同一进程内由所有节点共享。
+// 节点之间以及 API 服务器到节点都使用 HTTP 协议
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/api"
+	"github.com/AdrianWangs/go-cache/api/handlers"
+	"github.com/AdrianWangs/go-cache/internal/cache"
+	"github.com/AdrianWangs/go-cache/internal/consistenthash"
+	"github.com/AdrianWangs/go-cache/internal/discovery"
+	"github.com/AdrianWangs/go-cache/internal/peers"
+)
+
+const (
+	// ringReplicas 哈希环的虚拟节点倍数，与 HTTPPool 使用的倍数一致，
+	// 使 API 服务器和各节点对 key 的归属判断相同
+	ringReplicas = 50
+
+	defaultNodes      = 3
+	defaultCacheBytes = 1 << 20
+	defaultTTL        = time.Hour
+	defaultWait       = 5 * time.Second // 等待节点列表收敛的时长
+	basePath          = "/_gocache/"
+	apiIdentity       = "apiserver" // API 服务器在 X-GoCache-Routed-By 中的标识
+)
+
+// GroupSpec 在每个节点上创建的缓存组
+type GroupSpec struct {
+	Name       string              // 组名
+	CacheBytes int64               // 缓存容量，默认 1MB
+	TTL        time.Duration       // 缓存时间，默认 1h
+	Getter     cache.Getter        // 数据源，为 nil 时使用集群为该组创建的 DataSource，所有节点共用
+	Options    []cache.GroupOption // 额外的组选项
+}
+
+// Options 配置测试集群
+type Options struct {
+	Nodes  int         // 节点数，默认 3
+	Groups []GroupSpec // 每个节点上的缓存组，默认一个名为 "test" 的组
+
+	RingHash          string              // 节点和 API 服务器使用的一致性哈希函数，默认 crc32
+	RequestTimeout    time.Duration       // API 服务器访问节点的请求超时，默认与 API 服务器相同
+	FanOutConcurrency int                 // API 服务器聚合接口的并发数，默认与 API 服务器相同
+	BaseURLPrefix     string              // API 服务器所有路由的路径前缀，APIURL 包含该前缀，默认挂载在根路径
+	DeleteMode        handlers.DeleteMode // API 服务器单个 key 删除发往的节点，默认只发往归属节点
+	ReadRepairRate    float64             // API 服务器每秒最多发起的读修复数，默认关闭读修复
+
+	CachePolicies handlers.CachePolicies // API 服务器读取响应的按组 HTTP 缓存策略，默认所有组都不可缓存
+	Purger        handlers.Purger        // API 服务器删除 key 后清除 CDN 缓存，默认不清除
+
+	Proxy handlers.ProxyUpstreams // API 服务器按组配置的上游，用于测试 /api/proxy/，默认没有
+
+	HideIdentity bool // API 服务器和节点都不在读取响应中返回标识，默认返回
+
+	AdminToken string // API 服务器和节点管理接口的访问令牌，默认关闭管理接口
+}
+
+// Cluster 进程内的测试集群
+type Cluster struct {
+	groups    []GroupSpec
+	ringHash  string
+	hideID    bool
+	admin     string
+	sources   map[string]*DataSource
+	discovery *Discovery
+	api       *api.ApiServer
+	apiURL    string
+	client    *http.Client
+	serveErr  chan error
+
+	mu     sync.Mutex
+	nodes  []*Node
+	nextID int
+}
+
+// Start 启动集群，返回时所有节点和 API 服务器都已使用相同的节点列表
+func Start(opts Options) (*Cluster, error) {
+	if opts.Nodes <= 0 {
+		opts.Nodes = defaultNodes
+	}
+	if len(opts.Groups) == 0 {
+		opts.Groups = []GroupSpec{{Name: "test"}}
+	}
+
+	ringHash, err := consistenthash.ParseHash(opts.RingHash)
+	if err != nil {
+		return nil, err
+	}
+	c := &Cluster{
+		ringHash:  ringHash,
+		hideID:    opts.HideIdentity,
+		admin:     opts.AdminToken,
+		sources:   make(map[string]*DataSource),
+		discovery: NewDiscovery(),
+		client:    &http.Client{Timeout: 10 * time.Second},
+		serveErr:  make(chan error, 1),
+	}
+	for _, spec := range opts.Groups {
+		if spec.Name == "" {
+			return nil, fmt.Errorf("缓存组名不能为空")
+		}
+		if spec.CacheBytes <= 0 {
+			spec.CacheBytes = defaultCacheBytes
+		}
+		if spec.TTL <= 0 {
+			spec.TTL = defaultTTL
+		}
+		if spec.Getter == nil {
+			source := NewDataSource()
+			c.sources[spec.Name] = source
+			spec.Getter = source
+		}
+		c.groups = append(c.groups, spec)
+	}
+
+	for i := 0; i < opts.Nodes; i++ {
+		c.nodes = append(c.nodes, c.newNode())
+	}
+	c.discovery.Set(c.nodeInfos())
+	if err := c.syncNodes(); err != nil {
+		c.closeNodes()
+		return nil, err
+	}
+
+	server, err := api.NewApiServer(&api.ApiServerConfig{
+		Replicas:          ringReplicas,
+		BasePath:          basePath,
+		Protocol:          handlers.ProtocolHTTP,
+		RingHash:          c.ringHash,
+		RequestTimeout:    opts.RequestTimeout,
+		FanOutConcurrency: opts.FanOutConcurrency,
+		BaseURLPrefix:     opts.BaseURLPrefix,
+		DeleteMode:        opts.DeleteMode,
+		ReadRepairRate:    opts.ReadRepairRate,
+		CachePolicies:     opts.CachePolicies,
+		Purger:            opts.Purger,
+		Proxy:             opts.Proxy,
+		Watcher:           c.discovery,
+		Identity:          apiIdentity,
+		HideIdentity:      opts.HideIdentity,
+		AdminToken:        opts.AdminToken,
+	})
+	if err != nil {
+		c.closeNodes()
+		return nil, fmt.Errorf("创建API服务器失败: %w", err)
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		c.closeNodes()
+		return nil, fmt.Errorf("API服务器监听失败: %w", err)
+	}
+	c.api = server
+	c.apiURL = "http://" + l.Addr().String() + strings.TrimRight(opts.BaseURLPrefix, "/")
+	go func() {
+		c.serveErr <- server.Serve(l)
+	}()
+
+	if err := c.waitForAPI(); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// newNode 创建并启动下一个节点，调用方需持有锁或处于启动阶段
+func (c *Cluster) newNode() *Node {
+	c.nextID++
+	return startNode(fmt.Sprintf("node-%d", c.nextID), c.groups, c.discovery, c.ringHash, c.hideID, c.admin)
+}
+
+// APIURL 返回 API 服务器的基础 URL，例如 http://127.0.0.1:1234，设置了 BaseURLPrefix 时包含前缀
+func (c *Cluster) APIURL() string {
+	return c.apiURL
+}
+
+// API 返回 API 服务器
+func (c *Cluster) API() *api.ApiServer {
+	return c.api
+}
+
+// Discovery 返回集群使用的服务发现
+func (c *Cluster) Discovery() *Discovery {
+	return c.discovery
+}
+
+// Source 返回集群为 name 组创建的数据源；组指定了自己的 Getter 时返回 nil
+func (c *Cluster) Source(name string) *DataSource {
+	return c.sources[name]
+}
+
+// Nodes 返回当前的节点，按创建顺序排列
+func (c *Cluster) Nodes() []*Node {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]*Node(nil), c.nodes...)
+}
+
+// Node 返回标识为 id 的节点，不存在时返回 nil
+func (c *Cluster) Node(id string) *Node {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, n := range c.nodes {
+		if n.ID == id {
+			return n
+		}
+	}
+	return nil
+}
+
+// Owner 返回 key 在当前哈希环上的归属节点
+func (c *Cluster) Owner(key string) *Node {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// 哈希名称已在 Start 中校验
+	ring, _ := consistenthash.NewByName(ringReplicas, c.ringHash)
+	for _, n := range c.nodes {
+		ring.Add(n.ID)
+	}
+	id := ring.Get(key)
+	for _, n := range c.nodes {
+		if n.ID == id {
+			return n
+		}
+	}
+	return nil
+}
+
+// AddNode 启动一个新节点并加入集群，返回时所有节点和 API 服务器都已使用新的节点列表
+func (c *Cluster) AddNode() (*Node, error) {
+	c.mu.Lock()
+	n := c.newNode()
+	c.nodes = append(c.nodes, n)
+	c.mu.Unlock()
+
+	if err := c.publish(); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// RemoveNode 关闭节点 id 并将其移出集群，返回时其余节点和 API 服务器都已使用新的节点列表
+func (c *Cluster) RemoveNode(id string) error {
+	c.mu.Lock()
+	var removed *Node
+	for i, n := range c.nodes {
+		if n.ID == id {
+			removed = n
+			c.nodes = append(c.nodes[:i], c.nodes[i+1:]...)
+			break
+		}
+	}
+	c.mu.Unlock()
+
+	if removed == nil {
+		return fmt.Errorf("节点不存在: %s", id)
+	}
+	removed.close()
+	return c.publish()
+}
+
+// publish 将当前节点列表发布到 Discovery，并等待节点和 API 服务器收敛
+func (c *Cluster) publish() error {
+	c.discovery.Set(c.nodeInfos())
+	if err := c.syncNodes(); err != nil {
+		return err
+	}
+	return c.waitForAPI()
+}
+
+// nodeInfos 返回当前各节点登记的信息
+func (c *Cluster) nodeInfos() []discovery.NodeInfo {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	infos := make([]discovery.NodeInfo, 0, len(c.nodes))
+	for _, n := range c.nodes {
+		infos = append(infos, n.info)
+	}
+	return infos
+}
+
+// syncNodes 让每个节点从 Discovery 获取一次节点列表
+func (c *Cluster) syncNodes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultWait)
+	defer cancel()
+	for _, n := range c.Nodes() {
+		if err := n.sync(ctx); err != nil {
+			return fmt.Errorf("节点 %s 更新节点列表失败: %w", n.ID, err)
+		}
+	}
+	return nil
+}
+
+// waitForAPI 等待 API 服务器使用与 Discovery 相同的节点列表
+func (c *Cluster) waitForAPI() error {
+	want := discovery.NodeKeys(c.discovery.Nodes())
+	sort.Strings(want)
+
+	deadline := time.Now().Add(defaultWait)
+	for {
+		got := discovery.NodeKeys(c.api.Nodes())
+		sort.Strings(got)
+		if strings.Join(got, ",") == strings.Join(want, ",") {
+			return nil
+		}
+
+		select {
+		case err := <-c.serveErr:
+			return fmt.Errorf("API服务器已退出: %v", err)
+		default:
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("等待API服务器更新节点列表超时: 期望 %v，实际 %v", want, got)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// Get 通过 API 服务器读取 group 中的 key，返回响应体和状态码；
+// 非 200 的响应不视为错误，err 只表示请求本身失败
+func (c *Cluster) Get(group, key string) ([]byte, int, error) {
+	return c.do(context.Background(), http.MethodGet, group, key)
+}
+
+// GetContext 与 Get 相同，ctx 取消时关闭连接，用于模拟客户端中途断开
+func (c *Cluster) GetContext(ctx context.Context, group, key string) ([]byte, int, error) {
+	return c.do(ctx, http.MethodGet, group, key)
+}
+
+// Delete 通过 API 服务器删除 group 中的 key，返回状态码
+func (c *Cluster) Delete(group, key string) (int, error) {
+	_, code, err := c.do(context.Background(), http.MethodDelete, group, key)
+	return code, err
+}
+
+// do 向 API 服务器的 /api/cache/{group}/{key} 发送请求
+func (c *Cluster) do(ctx context.Context, method, group, key string) ([]byte, int, error) {
+	u := fmt.Sprintf("%s/api/cache/%s/%s", c.apiURL, peers.EscapePathSegment(group), peers.EscapePathSegment(key))
+	req, err := http.NewRequestWithContext(ctx, method, u, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, resp.StatusCode, err
+	}
+	return body, resp.StatusCode, nil
+}
+
+// Close 停止 API 服务器和所有节点
+func (c *Cluster) Close() error {
+	var err error
+	if c.api != nil {
+		err = c.api.Stop()
+	}
+	c.closeNodes()
+	return err
+}
+
+// closeNodes 关闭所有节点
+func (c *Cluster) closeNodes() {
+	c.mu.Lock()
+	nodes := c.nodes
+	c.nodes = nil
+	c.mu.Unlock()
+
+	for _, n := range nodes {
+		n.close()
+	}
+}
@@ -0,0 +1,166 @@
+package cluster_test
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/internal/testutil/cluster"
+)
+
+// startCluster 启动测试集群，测试结束时关闭
+func startCluster(t *testing.T, opts cluster.Options) *cluster.Cluster {
+	t.Helper()
+	c, err := cluster.Start(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+// mustGet 通过 API 服务器读取 key，期望返回 200 和 want
+func mustGet(t *testing.T, c *cluster.Cluster, key, want string) {
+	t.Helper()
+	body, code, err := c.Get("test", key)
+	if err != nil || code != http.StatusOK || string(body) != want {
+		t.Fatalf("Get(%s) = %d %q, %v; want %q", key, code, body, err, want)
+	}
+}
+
+// cachedOn 返回本地缓存了 key 的节点标识
+func cachedOn(c *cluster.Cluster, key string) []string {
+	var ids []string
+	for _, n := range c.Nodes() {
+		if _, _, ok := n.Group("test").Peek(key); ok {
+			ids = append(ids, n.ID)
+		}
+	}
+	return ids
+}
+
+func TestKeyOwnershipRouting(t *testing.T) {
+	c := startCluster(t, cluster.Options{})
+	source := c.Source("test")
+
+	owners := make(map[string]int)
+	for i := 0; i < 30; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		source.Set(key, "v-"+key)
+		mustGet(t, c, key, "v-"+key)
+		mustGet(t, c, key, "v-"+key)
+
+		// 只有归属节点缓存了 key，数据源只加载一次
+		owner := c.Owner(key).ID
+		if got := cachedOn(c, key); len(got) != 1 || got[0] != owner {
+			t.Fatalf("%s 缓存在 %v, 归属节点 %s", key, got, owner)
+		}
+		if n := source.Loads(key); n != 1 {
+			t.Fatalf("%s 加载了 %d 次", key, n)
+		}
+		owners[owner]++
+	}
+	if len(owners) != 3 {
+		t.Fatalf("30 个 key 只分布在 %v", owners)
+	}
+
+	if _, code, _ := c.Get("test", "missing"); code != http.StatusNotFound {
+		t.Fatalf("不存在的 key 返回 %d", code)
+	}
+}
+
+func TestNodeRemovalReroutes(t *testing.T) {
+	c := startCluster(t, cluster.Options{})
+	source := c.Source("test")
+	for i := 0; i < 30; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		source.Set(key, "v-"+key)
+		mustGet(t, c, key, "v-"+key)
+	}
+
+	removed := c.Owner("key-0").ID
+	if err := c.RemoveNode(removed); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Nodes()) != 2 {
+		t.Fatalf("移除后有 %d 个节点", len(c.Nodes()))
+	}
+
+	for i := 0; i < 30; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		mustGet(t, c, key, "v-"+key)
+		owner := c.Owner(key).ID
+		if owner == removed {
+			t.Fatalf("%s 仍归属被移除的节点", key)
+		}
+		if got := cachedOn(c, key); len(got) != 1 || got[0] != owner {
+			t.Fatalf("%s 缓存在 %v, 归属节点 %s", key, got, owner)
+		}
+	}
+	// 被移除节点的 key 重新从数据源加载
+	if n := source.Loads("key-0"); n != 2 {
+		t.Fatalf("key-0 加载了 %d 次, want 2", n)
+	}
+
+	// 新加入的节点分担 key
+	added, err := c.AddNode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 30; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		mustGet(t, c, key, "v-"+key)
+		if c.Owner(key) == added {
+			return
+		}
+	}
+	t.Fatal("新节点没有分到任何 key")
+}
+
+func TestDeletePropagation(t *testing.T) {
+	c := startCluster(t, cluster.Options{})
+	source := c.Source("test")
+	source.Set("k", "old")
+	mustGet(t, c, "k", "old")
+
+	source.Set("k", "new")
+	mustGet(t, c, "k", "old") // 仍是缓存的值
+	if code, err := c.Delete("test", "k"); err != nil || code != http.StatusOK {
+		t.Fatalf("Delete = %d, %v", code, err)
+	}
+	if got := cachedOn(c, "k"); len(got) != 0 {
+		t.Fatalf("删除后仍缓存在 %v", got)
+	}
+	mustGet(t, c, "k", "new")
+}
+
+// TestSingleflightAcrossAPI 同一个 key 的并发请求经过 API 服务器和对等节点后只加载一次
+func TestSingleflightAcrossAPI(t *testing.T) {
+	c := startCluster(t, cluster.Options{})
+	source := c.Source("test")
+	source.Set("hot", "v")
+	source.SetDelay(100 * time.Millisecond)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			body, code, err := c.Get("test", "hot")
+			if err != nil || code != http.StatusOK || string(body) != "v" {
+				errs <- fmt.Errorf("Get = %d %q, %v", code, body, err)
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+	if n := source.Loads("hot"); n != 1 {
+		t.Fatalf("并发请求加载了 %d 次", n)
+	}
+}
@@ -0,0 +1,79 @@
+package cluster_test
+
+import (
+	"fmt"
+	"net/http"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/internal/cache"
+	"github.com/AdrianWangs/go-cache/internal/testutil/cluster"
+)
+
+// TestDeleteMarkerUnderLoad 在持续的并发读取中经 API 服务器删除热点 key：
+// 删除后的读取共享归属节点上的一次重新加载，数据源只被加载一次，且没有读取失败
+func TestDeleteMarkerUnderLoad(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+	c := startCluster(t, cluster.Options{Groups: []cluster.GroupSpec{{
+		Name:    "test",
+		Options: []cache.GroupOption{cache.WithDeleteMarker(time.Second)},
+	}}})
+	source := c.Source("test")
+	source.Set("hot", "v1")
+	mustGet(t, c, "hot", "v1")
+
+	var (
+		wg     sync.WaitGroup
+		stop   atomic.Bool
+		reads  atomic.Int64
+		sawNew atomic.Bool
+		errs   = make(chan error, 50)
+	)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for !stop.Load() {
+				body, code, err := c.Get("test", "hot")
+				switch {
+				case err != nil || code != http.StatusOK:
+					errs <- fmt.Errorf("Get = %d %q, %v", code, body, err)
+					return
+				case string(body) == "v2":
+					sawNew.Store(true)
+				case string(body) != "v1":
+					errs <- fmt.Errorf("Get = %q", body)
+					return
+				}
+				reads.Add(1)
+			}
+		}()
+	}
+	waitUntil(t, "读取开始", func() bool { return reads.Load() >= 100 })
+
+	source.Set("hot", "v2")
+	source.SetDelay(50 * time.Millisecond)
+	source.ResetLoads()
+	if code, err := c.Delete("test", "hot"); err != nil || code != http.StatusOK {
+		t.Fatalf("Delete = %d, %v", code, err)
+	}
+	waitUntil(t, "读到新值", sawNew.Load)
+	after := reads.Load()
+	waitUntil(t, "删除后继续读取", func() bool { return reads.Load() >= after+200 })
+	stop.Store(true)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+
+	if n := source.Loads("hot"); n != 1 {
+		t.Fatalf("删除后数据源加载了 %d 次，want 1", n)
+	}
+	if hits := c.Owner("hot").Group("test").Stats().DeleteMarkerHits; hits == 0 {
+		t.Fatal("归属节点没有记录删除标记命中")
+	}
+}
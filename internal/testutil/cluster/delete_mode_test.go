@@ -0,0 +1,90 @@
+package cluster_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"testing"
+
+	"github.com/AdrianWangs/go-cache/api/handlers"
+	"github.com/AdrianWangs/go-cache/internal/peers"
+	"github.com/AdrianWangs/go-cache/internal/testutil/cluster"
+)
+
+// deleteAcked 通过 API 服务器删除 key，返回响应中的目标节点数和确认节点数
+func deleteAcked(t *testing.T, c *cluster.Cluster, key string) (targets, acked string) {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodDelete, c.APIURL()+"/api/cache/test/"+url.PathEscape(key), nil)
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("Delete(%s) = %d", key, res.StatusCode)
+	}
+	return res.Header.Get(peers.HeaderDeleteTargets), res.Header.Get(peers.HeaderDeleteAcked)
+}
+
+// TestDeleteModesDuringScaleUp 扩容后归属转到新节点的 key 在原归属节点上仍有缓存：
+// owner 只删除新归属节点，缩容回去后原归属节点继续提供旧值；owner+previous 同时删除原归属节点，
+// broadcast 删除所有节点，之后都读到新值
+func TestDeleteModesDuringScaleUp(t *testing.T) {
+	tests := []struct {
+		mode    handlers.DeleteMode
+		targets string // 删除发往的节点数，扩容后共 4 个节点
+		stale   bool   // 原归属节点是否仍缓存旧值
+	}{
+		{handlers.DeleteOwner, "1", true},
+		{handlers.DeleteOwnerPrevious, "2", false},
+		{handlers.DeleteBroadcast, "4", false},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.mode), func(t *testing.T) {
+			c := startCluster(t, cluster.Options{DeleteMode: tt.mode})
+			source := c.Source("test")
+			previous := make(map[string]string) // key 到扩容前归属节点
+			for i := 0; i < 50; i++ {
+				key := fmt.Sprintf("key-%d", i)
+				source.Set(key, "old")
+				mustGet(t, c, key, "old")
+				previous[key] = c.Owner(key).ID
+			}
+
+			added, err := c.AddNode()
+			if err != nil {
+				t.Fatal(err)
+			}
+			key := ""
+			for i := 0; i < 50 && key == ""; i++ {
+				if k := fmt.Sprintf("key-%d", i); c.Owner(k) == added {
+					key = k
+				}
+			}
+			if key == "" {
+				t.Fatal("新节点没有分到任何 key")
+			}
+			old := previous[key]
+
+			source.Set(key, "new")
+			targets, acked := deleteAcked(t, c, key)
+			if targets != tt.targets || acked != tt.targets {
+				t.Fatalf("删除发往 %s 个节点，%s 个确认; want %s", targets, acked, tt.targets)
+			}
+			if got := cachedOn(c, key); slices.Contains(got, old) != tt.stale {
+				t.Fatalf("删除后 %s 缓存在 %v, 原归属节点 %s", key, got, old)
+			}
+
+			// 缩容回去，key 重新归属原节点
+			if err := c.RemoveNode(added.ID); err != nil {
+				t.Fatal(err)
+			}
+			want := "new"
+			if tt.stale {
+				want = "old"
+			}
+			mustGet(t, c, key, want)
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package cluster
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/internal/discovery"
+)
+
+// Discovery 不依赖 etcd 的服务发现：节点列表由测试直接设置。
+// 它同时实现 api.NodeWatcher（供 API 服务器监视）和 peers.Source（供缓存节点获取节点列表）
+type Discovery struct {
+	mu       sync.Mutex
+	nodes    []discovery.NodeInfo
+	since    time.Time
+	lastSync time.Time
+	watchers []chan []discovery.NodeInfo
+}
+
+// NewDiscovery 创建节点列表为空的 Discovery
+func NewDiscovery() *Discovery {
+	now := time.Now()
+	return &Discovery{since: now, lastSync: now}
+}
+
+// Set 替换节点列表并通知所有监视者
+func (d *Discovery) Set(nodes []discovery.NodeInfo) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.nodes = append([]discovery.NodeInfo(nil), nodes...)
+	d.lastSync = time.Now()
+	for _, ch := range d.watchers {
+		publish(ch, d.snapshot())
+	}
+}
+
+// Nodes 返回当前的节点列表
+func (d *Discovery) Nodes() []discovery.NodeInfo {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.snapshot()
+}
+
+// snapshot 返回节点列表的副本，调用方需持有锁
+func (d *Discovery) snapshot() []discovery.NodeInfo {
+	return append([]discovery.NodeInfo{}, d.nodes...)
+}
+
+// publish 向容量为 1 的通道发送最新的列表，未被读取的旧列表被丢弃
+func publish(ch chan []discovery.NodeInfo, nodes []discovery.NodeInfo) {
+	select {
+	case <-ch:
+	default:
+	}
+	ch <- nodes
+}
+
+// Watch 实现 api.NodeWatcher：立即推送当前列表，之后每次 Set 推送新列表，ctx 取消时关闭通道
+func (d *Discovery) Watch(ctx context.Context) (<-chan []discovery.NodeInfo, <-chan error) {
+	updates := make(chan []discovery.NodeInfo, 1)
+	errs := make(chan error)
+
+	d.mu.Lock()
+	d.watchers = append(d.watchers, updates)
+	publish(updates, d.snapshot())
+	d.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		for i, ch := range d.watchers {
+			if ch == updates {
+				d.watchers = append(d.watchers[:i], d.watchers[i+1:]...)
+				break
+			}
+		}
+		close(updates)
+		close(errs)
+	}()
+	return updates, errs
+}
+
+// Status 实现 api.NodeWatcher，总是处于已连接状态
+func (d *Discovery) Status() discovery.WatchStatus {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return discovery.WatchStatus{
+		State:    discovery.WatchConnected,
+		Since:    d.since,
+		LastSync: d.lastSync,
+	}
+}
+
+// Close 实现 api.NodeWatcher
+func (d *Discovery) Close() error {
+	return nil
+}
+
+// Peers 实现 peers.Source
+func (d *Discovery) Peers(ctx context.Context) ([]discovery.NodeInfo, error) {
+	return d.Nodes(), nil
+}
@@ -0,0 +1,198 @@
+package cluster_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/api/handlers"
+	"github.com/AdrianWangs/go-cache/internal/cache"
+	"github.com/AdrianWangs/go-cache/internal/testutil/cluster"
+)
+
+// adminToken 测试集群管理接口的访问令牌
+const adminToken = "secret"
+
+// clusterHotKeys 通过 API 服务器获取组的集群热点 key
+func clusterHotKeys(t *testing.T, c *cluster.Cluster, group string, n int) handlers.ClusterHotKeysResponse {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/api/admin/hotkeys?group=%s&n=%d", c.APIURL(), group, n), nil)
+	req.Header.Set("Authorization", "Bearer "+adminToken)
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	var resp handlers.ClusterHotKeysResponse
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil || res.StatusCode != http.StatusOK {
+		t.Fatalf("状态码 %d, %v", res.StatusCode, err)
+	}
+	return resp
+}
+
+// TestClusterHotKeys 倾斜的读取流量分布在各节点上，API 服务器汇总出的前 n 个 key
+// 按读取次数排序，计数与实际读取次数相同，并给出读取所在的节点
+func TestClusterHotKeys(t *testing.T) {
+	c := startCluster(t, cluster.Options{AdminToken: adminToken, Groups: []cluster.GroupSpec{
+		{Name: "test", Options: []cache.GroupOption{cache.WithHotKeys(cache.HotKeyConfig{Window: time.Minute})}},
+		{Name: "plain"},
+	}})
+
+	// key-i 被读取 40/(i+1) 次，其余 20 个 key 各读取一次
+	source := c.Source("test")
+	reads := make(map[string]int64)
+	for i := 0; i < 5; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		source.Set(key, "value-"+key)
+		for j := 0; j < 40/(i+1); j++ {
+			mustGet(t, c, key, "value-"+key)
+			reads[key]++
+		}
+	}
+	for i := 0; i < 20; i++ {
+		key := fmt.Sprintf("cold-%d", i)
+		source.Set(key, "value-"+key)
+		mustGet(t, c, key, "value-"+key)
+	}
+
+	resp := clusterHotKeys(t, c, "test", 3)
+	if len(resp.Keys) != 3 {
+		t.Fatalf("热点 key = %+v", resp.Keys)
+	}
+	for i, hk := range resp.Keys {
+		key := fmt.Sprintf("key-%d", i)
+		owner := c.Owner(key).ID
+		if hk.Key != key || hk.Reads != reads[key] || hk.Nodes[owner] != reads[key] {
+			t.Fatalf("第 %d 个热点 key = %+v, want %s 在 %s 上读取 %d 次", i, hk, key, owner, reads[key])
+		}
+	}
+	if len(resp.Nodes) != 3 {
+		t.Fatalf("节点状态 = %+v", resp.Nodes)
+	}
+	for _, n := range resp.Nodes {
+		if n.Status != handlers.NodeHotKeysOK {
+			t.Fatalf("节点 %s: %+v", n.Node, n)
+		}
+	}
+
+	// 未开启热点统计的组在每个节点上都被跳过
+	resp = clusterHotKeys(t, c, "plain", 3)
+	if len(resp.Keys) != 0 {
+		t.Fatalf("未开启热点统计的组返回了 %+v", resp.Keys)
+	}
+	for _, n := range resp.Nodes {
+		if n.Status != handlers.NodeHotKeysSkipped {
+			t.Fatalf("节点 %s: %+v, want skipped", n.Node, n)
+		}
+	}
+
+	// 无法访问的节点记为失败，其余节点的结果照常合并
+	stopped := c.Owner("key-0")
+	stopped.Stop()
+	resp = clusterHotKeys(t, c, "test", 3)
+	for _, n := range resp.Nodes {
+		if (n.Node == stopped.ID) != (n.Status == handlers.NodeHotKeysError) {
+			t.Fatalf("停止 %s 后节点 %s: %+v", stopped.ID, n.Node, n)
+		}
+	}
+	for _, hk := range resp.Keys {
+		if hk.Key == "key-0" {
+			t.Fatalf("停止的节点上的 key 仍被报告: %+v", hk)
+		}
+	}
+
+	// 没有令牌的请求被拒绝
+	res, err := http.Get(c.APIURL() + "/api/admin/hotkeys?group=test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("没有令牌时状态码 %d", res.StatusCode)
+	}
+}
+
+// hotKeyStats 从 API 服务器的 /api/metrics 读取热点 key 分散读取统计
+func hotKeyStats(t *testing.T, c *cluster.Cluster) handlers.HotKeyStats {
+	t.Helper()
+	res, err := http.Get(c.APIURL() + "/api/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	var m handlers.MetricsResponse
+	if err := json.NewDecoder(res.Body).Decode(&m); err != nil {
+		t.Fatal(err)
+	}
+	if m.HotKeys == nil {
+		t.Fatal("/api/metrics 没有热点 key 统计")
+	}
+	return *m.HotKeys
+}
+
+// TestHotKeyReplicaReads 热点 key 被归属节点复制到环上的下一个节点后，API 服务器把读请求
+// 分给副本节点；删除时副本随之失效，之后的读取不会得到旧值
+func TestHotKeyReplicaReads(t *testing.T) {
+	c := startCluster(t, cluster.Options{Groups: []cluster.GroupSpec{
+		{Name: "test", Options: []cache.GroupOption{cache.WithHotKeys(cache.HotKeyConfig{
+			Window:     time.Minute,
+			Threshold:  0.1, // 一个窗口内读取 6 次
+			Replicas:   1,
+			ReplicaTTL: time.Minute,
+		})}},
+	}})
+	source := c.Source("test")
+	source.Set("hot", "v1")
+	owner := c.Owner("hot")
+
+	// 提升：读取达到阈值后归属节点把 key 推送到一个副本节点
+	for i := 0; i < 6; i++ {
+		mustGet(t, c, "hot", "v1")
+	}
+	waitUntil(t, "热点 key 被复制", func() bool { return len(owner.Group("test").HotKeys(0).Replicated) == 1 })
+	holders := cachedOn(c, "hot")
+	if len(holders) != 2 {
+		t.Fatalf("复制后缓存了 key 的节点 = %v", holders)
+	}
+	var replica *cluster.Node
+	for _, id := range holders {
+		if id != owner.ID {
+			replica = c.Node(id)
+		}
+	}
+	if replica == nil {
+		t.Fatalf("归属节点 %s 不在 %v 中", owner.ID, holders)
+	}
+
+	// 副本读取：API 服务器从归属节点的响应得知副本后，轮流把读请求发给副本节点
+	for i := 0; i < 10; i++ {
+		mustGet(t, c, "hot", "v1")
+	}
+	stats := hotKeyStats(t, c)
+	if stats.Routes != 1 || stats.ReplicaReads == 0 || stats.ReplicaFallbacks != 0 {
+		t.Fatalf("分散读取统计 = %+v", stats)
+	}
+	if keys := replica.Group("test").HotKeys(0).Keys; len(keys) != 1 || keys[0].Reads != stats.ReplicaReads {
+		t.Fatalf("副本节点的读取 = %+v, want %d 次", keys, stats.ReplicaReads)
+	}
+	if n := source.Loads("hot"); n != 1 {
+		t.Fatalf("数据源加载了 %d 次", n)
+	}
+
+	// 失效：删除在返回前删掉副本，之后的读取都得到新值
+	if code, err := c.Delete("test", "hot"); err != nil || code != http.StatusOK {
+		t.Fatalf("Delete = %d, %v", code, err)
+	}
+	if ids := cachedOn(c, "hot"); len(ids) != 0 {
+		t.Fatalf("删除后仍缓存了 key 的节点 = %v", ids)
+	}
+	if rep := owner.Group("test").HotKeys(0); rep.Invalidations != 1 || len(rep.Replicated) != 0 {
+		t.Fatalf("归属节点的复制报告 = %+v", rep)
+	}
+	source.Set("hot", "v2")
+	for i := 0; i < 4; i++ {
+		mustGet(t, c, "hot", "v2")
+	}
+}
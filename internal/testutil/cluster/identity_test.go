@@ -0,0 +1,59 @@
+package cluster_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/AdrianWangs/go-cache/internal/server"
+	"github.com/AdrianWangs/go-cache/internal/testutil/cluster"
+	"github.com/AdrianWangs/go-cache/pkg/client"
+)
+
+// TestIdentityHeaders 读取响应经过 API 服务器→节点→对等节点后仍带着产生值的节点标识
+// (X-GoCache-Node) 和 API 服务器的标识 (X-GoCache-Routed-By)；关闭后两者都不返回
+func TestIdentityHeaders(t *testing.T) {
+	for _, hide := range []bool{false, true} {
+		c := startCluster(t, cluster.Options{HideIdentity: hide})
+		source := c.Source("test")
+		source.Set("direct", "v1")
+		source.Set("forwarded", "v2")
+		api := client.New(c.APIURL())
+
+		expect := func(key, node, source string) {
+			t.Helper()
+			res, err := api.GetWithMeta(context.Background(), "test", key)
+			if err != nil {
+				t.Fatalf("GetWithMeta(%s): %v", key, err)
+			}
+			wantNode, wantRoutedBy := node, "apiserver"
+			if hide {
+				wantNode, wantRoutedBy = "", ""
+			}
+			if res.Node != wantNode || res.RoutedBy != wantRoutedBy || res.Source != source {
+				t.Fatalf("hide=%v %s: node %q, routed by %q, source %q; want %q, %q, %q",
+					hide, key, res.Node, res.RoutedBy, res.Source, wantNode, wantRoutedBy, source)
+			}
+		}
+
+		// 归属节点自己回源，之后命中缓存
+		owner := c.Owner("direct").ID
+		expect("direct", owner, "loader")
+		expect("direct", owner, "cache")
+
+		// 让归属节点认为 key 属于另一个节点：API 服务器把请求发往它，它再转发给对等节点，
+		// 响应报告的是回源的对等节点
+		first := c.Owner("forwarded")
+		var peer *cluster.Node
+		for _, n := range c.Nodes() {
+			if n != first {
+				peer = n
+				break
+			}
+		}
+		first.Pool.SetPeers(server.Peer{ID: peer.ID, Addr: "http://" + peer.Addr})
+		expect("forwarded", peer.ID, "peer")
+		if _, _, ok := peer.Group("test").Peek("forwarded"); !ok {
+			t.Fatalf("hide=%v: 对等节点 %s 没有缓存转发来的 key", hide, peer.ID)
+		}
+	}
+}
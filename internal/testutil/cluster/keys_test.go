@@ -0,0 +1,46 @@
+package cluster_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/AdrianWangs/go-cache/internal/testutil/cluster"
+	"github.com/AdrianWangs/go-cache/internal/testutil/keys"
+)
+
+// TestRoutingKeys 路由时容易出错的键经 API 服务器和节点之间的 HTTP 协议原样到达归属节点的数据源，
+// 也能原样删除；路径中字面量的 "." 和 ".." 返回 400，而不是重定向到清理后的路径
+func TestRoutingKeys(t *testing.T) {
+	c := startCluster(t, cluster.Options{})
+	source := c.Source("test")
+
+	for _, key := range keys.Routing {
+		t.Run(key, func(t *testing.T) {
+			source.Set(key, "v:"+key)
+			mustGet(t, c, key, "v:"+key)
+			if _, _, ok := c.Owner(key).Group("test").Peek(key); !ok {
+				t.Fatal("归属节点没有缓存该键")
+			}
+			if code, err := c.Delete("test", key); err != nil || code != http.StatusOK {
+				t.Fatalf("Delete = %d, %v", code, err)
+			}
+			if got := cachedOn(c, key); len(got) != 0 {
+				t.Fatalf("删除后仍缓存在 %v", got)
+			}
+		})
+	}
+
+	for _, path := range []string{"/api/cache/test/.", "/api/cache/test/..", "/api/cache/test/a/../b"} {
+		for _, method := range []string{http.MethodGet, http.MethodDelete} {
+			req, _ := http.NewRequest(method, c.APIURL()+path, nil)
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusBadRequest {
+				t.Errorf("%s %s = %d, want 400", method, path, resp.StatusCode)
+			}
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package cluster_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/AdrianWangs/go-cache/internal/cache"
+	"github.com/AdrianWangs/go-cache/internal/testutil/cluster"
+)
+
+// TestMissPolicies 每种缺失策略下，归属节点自己、其他节点和归属节点故障时的读取行为
+func TestMissPolicies(t *testing.T) {
+	policies := []cache.MissPolicy{cache.MissOriginFallback, cache.MissPeerOnly, cache.MissOriginOnlyIfOwner}
+	var groups []cluster.GroupSpec
+	for _, p := range policies {
+		groups = append(groups, cluster.GroupSpec{Name: p.String(), Options: []cache.GroupOption{cache.WithMissPolicy(p)}})
+	}
+	c := startCluster(t, cluster.Options{Groups: groups})
+
+	nodes := c.Nodes()
+	down := nodes[0]
+	// 找一个由 down 拥有的 key 和一个由其余节点拥有的 key
+	var downKey, upKey string
+	for i := 0; downKey == "" || upKey == ""; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		if c.Owner(key) == down {
+			downKey = key
+		} else if upKey == "" {
+			upKey = key
+		}
+	}
+	for _, p := range policies {
+		for _, key := range []string{downKey, upKey} {
+			c.Source(p.String()).Set(key, "v-"+key)
+		}
+	}
+	down.Stop()
+	up := c.Owner(upKey)
+	var other *cluster.Node // 既不是 up 也不是 down 的节点
+	for _, n := range nodes[1:] {
+		if n != up {
+			other = n
+		}
+	}
+
+	tests := []struct {
+		policy    cache.MissPolicy
+		ownerDown func(err error) bool // 归属节点故障时非归属节点读取返回的错误，nil 表示从数据源加载成功
+	}{
+		{cache.MissOriginFallback, nil},
+		{cache.MissPeerOnly, cache.IsNoPeerAvailableError},
+		{cache.MissOriginOnlyIfOwner, func(err error) bool { return err != nil && !cache.IsNoPeerAvailableError(err) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy.String(), func(t *testing.T) {
+			name := tt.policy.String()
+			source := c.Source(name)
+
+			// 归属节点自己总是从数据源加载
+			if v, err := up.Group(name).Get(upKey); err != nil || v.String() != "v-"+upKey {
+				t.Fatalf("归属节点 Get = %q, %v", v, err)
+			}
+			// 其他节点从归属节点读取，不访问数据源
+			if v, err := other.Group(name).Get(upKey); err != nil || v.String() != "v-"+upKey {
+				t.Fatalf("非归属节点 Get = %q, %v", v, err)
+			}
+			if n := source.Loads(upKey); n != 1 {
+				t.Fatalf("%s 加载了 %d 次", upKey, n)
+			}
+
+			// 归属节点故障
+			v, err := other.Group(name).Get(downKey)
+			loads := source.Loads(downKey)
+			if tt.ownerDown == nil {
+				if err != nil || v.String() != "v-"+downKey || loads != 1 {
+					t.Fatalf("归属节点故障时 Get = %q, %v, 加载 %d 次", v, err, loads)
+				}
+				return
+			}
+			if !tt.ownerDown(err) || loads != 0 {
+				t.Fatalf("归属节点故障时 Get 返回 %v, 加载 %d 次", err, loads)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+
+	"github.com/AdrianWangs/go-cache/internal/admin"
+	"github.com/AdrianWangs/go-cache/internal/cache"
+	"github.com/AdrianWangs/go-cache/internal/cachenode/peers"
+	"github.com/AdrianWangs/go-cache/internal/discovery"
+	peerproto "github.com/AdrianWangs/go-cache/internal/peers"
+	"github.com/AdrianWangs/go-cache/internal/server"
+)
+
+// Node 集群中的一个缓存节点：一个 HTTPPool 和在独立注册表中创建的缓存组，
+// 在随机端口上提供节点间通信接口和热点 key 管理接口
+type Node struct {
+	ID       string             // 节点标识，即哈希环上的 key
+	Addr     string             // 监听地址 (host:port)
+	URL      string             // 节点间通信接口的基础 URL，例如 http://127.0.0.1:1234/_gocache/
+	Pool     *server.HTTPPool   // 节点的 HTTPPool
+	Registry *cache.Registry    // 节点上的缓存组
+	server   *httptest.Server   // 提供服务的 HTTP 服务器
+	updater  *peers.Updater     // 从 Discovery 更新 Pool 的节点列表
+	info     discovery.NodeInfo // 登记到 Discovery 的信息
+	groups   map[string]*cache.Group
+	admin    string // 管理接口的访问令牌，为空时管理接口关闭
+}
+
+// startNode 创建节点的缓存组和 HTTPPool，并在随机端口上启动；hideID 为 true 时节点不公开标识，
+// adminToken 为管理接口的访问令牌
+func startNode(id string, groups []GroupSpec, source peers.Source, ringHash string, hideID bool, adminToken string) *Node {
+	mux := http.NewServeMux()
+	srv := httptest.NewUnstartedServer(mux)
+	addr := srv.Listener.Addr().String()
+
+	n := &Node{
+		ID:       id,
+		Addr:     addr,
+		Registry: cache.NewRegistry(),
+		server:   srv,
+		admin:    adminToken,
+		groups:   make(map[string]*cache.Group, len(groups)),
+	}
+	n.Pool = server.NewHTTPPool("http://"+addr,
+		server.WithSelfID(id),
+		server.WithRegistry(n.Registry),
+		server.WithProtocol(server.ProtocolProtobuf),
+		server.WithRingHash(ringHash),
+		server.WithExposeIdentity(!hideID),
+	)
+	n.URL = "http://" + addr + n.Pool.BasePath()
+	mux.Handle(n.Pool.BasePath(), n.Pool)
+	mux.HandleFunc("/api/admin/groups/", n.hotKeysHandler)
+
+	names := make([]string, 0, len(groups))
+	for _, spec := range groups {
+		opts := append([]cache.GroupOption{cache.WithRegistry(n.Registry)}, spec.Options...)
+		g := cache.NewGroup(spec.Name, spec.CacheBytes, spec.Getter, spec.TTL, opts...)
+		g.RegisterPeers(n.Pool)
+		n.groups[spec.Name] = g
+		names = append(names, spec.Name)
+	}
+
+	// 测试节点只提供 HTTP 服务，登记为仅支持 HTTP，API 服务器不会尝试 gRPC
+	n.info = discovery.NodeInfo{
+		ID: id, GRPCAddr: addr, HTTPAddr: addr, Groups: names,
+		ProtoVersion: peerproto.ProtocolVersion, Protocols: []string{discovery.ProtocolHTTP},
+	}
+	n.updater = peers.NewUpdater(source, peers.PoolApplier(n.Pool))
+	srv.Start()
+	return n
+}
+
+// hotKeysHandler 处理 GET /api/admin/groups/{group}/hotkeys?n=，与缓存节点管理接口的同名路由相同，
+// 供 API 服务器的 /api/admin/hotkeys 汇总；节点上没有该组时返回 404
+func (n *Node) hotKeysHandler(w http.ResponseWriter, r *http.Request) {
+	if !admin.Authorize(w, r, n.admin) {
+		return
+	}
+	name, ok := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/api/admin/groups/"), "/hotkeys")
+	group := n.groups[name]
+	if !ok || group == nil {
+		http.NotFound(w, r)
+		return
+	}
+	limit, err := strconv.Atoi(r.URL.Query().Get("n"))
+	if err != nil || limit <= 0 {
+		http.Error(w, "Bad Request: n must be a positive integer", http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(group.HotKeys(limit))
+}
+
+// Group 返回节点上的缓存组，不存在时返回 nil
+func (n *Node) Group(name string) *cache.Group {
+	return n.groups[name]
+}
+
+// Info 返回节点登记的信息
+func (n *Node) Info() discovery.NodeInfo {
+	return n.info
+}
+
+// PeerStatus 返回节点列表的更新状态
+func (n *Node) PeerStatus() peers.Status {
+	return n.updater.Status()
+}
+
+// sync 从 Discovery 获取一次节点列表并更新 Pool
+func (n *Node) sync(ctx context.Context) error {
+	return n.updater.Update(ctx)
+}
+
+// Stop 关闭节点的 HTTP 服务器但不将其移出集群，模拟尚未被发现的节点故障：
+// 其余节点和 API 服务器仍按原来的哈希环把请求发往该节点
+func (n *Node) Stop() {
+	n.server.CloseClientConnections()
+	n.server.Close()
+}
+
+// close 关闭节点的 HTTP 服务器和缓存组，之后访问该节点的请求都会失败
+func (n *Node) close() {
+	n.server.CloseClientConnections()
+	n.server.Close()
+	n.Registry.Close()
+}
@@ -0,0 +1,47 @@
+package cluster_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/AdrianWangs/go-cache/internal/testutil/cluster"
+)
+
+// TestBaseURLPrefix API 服务器挂载在 /cache 之下：读写和删除经由前缀访问，
+// 根路径下只保留健康检查和就绪检查
+func TestBaseURLPrefix(t *testing.T) {
+	c := startCluster(t, cluster.Options{BaseURLPrefix: "/cache/"})
+	if !strings.HasSuffix(c.APIURL(), "/cache") {
+		t.Fatalf("APIURL = %s, want 以 /cache 结尾", c.APIURL())
+	}
+	c.Source("test").Set("k", "v")
+	mustGet(t, c, "k", "v")
+	if code, err := c.Delete("test", "k"); err != nil || code != http.StatusOK {
+		t.Fatalf("经由前缀删除: %d, %v", code, err)
+	}
+
+	root := strings.TrimSuffix(c.APIURL(), "/cache")
+	tests := []struct {
+		path   string
+		status int
+	}{
+		{"/cache/health", http.StatusOK},
+		{"/cache/ready", http.StatusOK},
+		{"/cache/api/nodes", http.StatusOK},
+		{"/health", http.StatusOK},
+		{"/ready", http.StatusOK},
+		{"/api/cache/test/k", http.StatusNotFound},
+		{"/api/nodes", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		resp, err := http.Get(root + tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.status {
+			t.Errorf("GET %s = %d, want %d", tt.path, resp.StatusCode, tt.status)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package cluster_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/api/handlers"
+	"github.com/AdrianWangs/go-cache/internal/cache"
+	"github.com/AdrianWangs/go-cache/internal/testutil/cluster"
+)
+
+// readRepairStats 从 API 服务器的 /api/metrics 读取读修复统计
+func readRepairStats(t *testing.T, c *cluster.Cluster) handlers.ReadRepairStats {
+	t.Helper()
+	res, err := http.Get(c.APIURL() + "/api/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	var m handlers.MetricsResponse
+	if err := json.NewDecoder(res.Body).Decode(&m); err != nil {
+		t.Fatal(err)
+	}
+	if m.ReadRepair == nil {
+		t.Fatal("/api/metrics 没有读修复统计")
+	}
+	return *m.ReadRepair
+}
+
+// localValue 返回节点本地缓存的 key 的值，没有缓存时为空
+func localValue(n *cluster.Node, key string) string {
+	v, _, ok := n.Group("test").Peek(key)
+	if !ok {
+		return ""
+	}
+	return v.String()
+}
+
+// TestReadRepairConverges 热点 key 复制到两个副本节点后，让一个副本留下旧值（模拟错过的失效）、
+// 另一个丢失副本（模拟节点重启），继续读取后两个副本都恢复为归属节点的值
+func TestReadRepairConverges(t *testing.T) {
+	c := startCluster(t, cluster.Options{
+		Groups: []cluster.GroupSpec{{Name: "test", Options: []cache.GroupOption{
+			cache.WithHotKeys(cache.HotKeyConfig{Window: 100 * time.Millisecond, Threshold: 1, Replicas: 2}),
+		}}},
+		ReadRepairRate: 1000,
+	})
+	c.Source("test").Set("hot", "v1")
+	waitUntil(t, "热点 key 复制到所有节点", func() bool {
+		mustGet(t, c, "hot", "v1")
+		return len(cachedOn(c, "hot")) == 3
+	})
+
+	owner := c.Owner("hot")
+	var stale, lost *cluster.Node
+	for _, n := range c.Nodes() {
+		switch {
+		case n == owner:
+		case stale == nil:
+			stale = n
+		default:
+			lost = n
+		}
+	}
+	if err := stale.Group("test").SetLocally("hot", []byte("stale"), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err := lost.Group("test").DeleteLocally("hot"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 读取分散到各节点，副本节点提供的结果触发修复；在修复之前可能读到一次旧值
+	staleReads := 0
+	waitUntil(t, "副本收敛", func() bool {
+		body, code, err := c.Get("test", "hot")
+		if err != nil || code != http.StatusOK {
+			t.Fatalf("Get = %d, %v", code, err)
+		}
+		if string(body) == "stale" {
+			staleReads++
+		}
+		return localValue(stale, "hot") == "v1" && localValue(lost, "hot") == "v1"
+	})
+	if staleReads > 1 {
+		t.Fatalf("修复前后共读到 %d 次旧值", staleReads)
+	}
+	for i := 0; i < 10; i++ {
+		mustGet(t, c, "hot", "v1")
+	}
+
+	waitUntil(t, "修复完成", func() bool { return readRepairStats(t, c).Pending == 0 })
+	s := readRepairStats(t, c)
+	if s.Diverged != 1 || s.Missing != 1 || s.Repaired != 2 || s.Failed != 0 {
+		t.Fatalf("读修复统计 = %+v", s)
+	}
+}
@@ -0,0 +1,11 @@
+package cluster
+
+import "github.com/AdrianWangs/go-cache/pkg/cachetest"
+
+// DataSource 集群中各节点共用的内存数据源，记录每个 key 被加载的次数
+type DataSource = cachetest.Getter
+
+// NewDataSource 创建空的数据源
+func NewDataSource() *DataSource {
+	return cachetest.NewGetter(nil)
+}
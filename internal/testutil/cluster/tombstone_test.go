@@ -0,0 +1,99 @@
+package cluster_test
+
+import (
+	"fmt"
+	"net/http"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/internal/cache"
+	"github.com/AdrianWangs/go-cache/internal/testutil/cluster"
+)
+
+// TestTombstoneDeleteDuringSlowLoad 归属节点从数据源慢速加载 key 期间，数据源中的值被更新，
+// 同时经 API 服务器删除该 key：删除返回之后发起的读取不会读到删除前加载的旧值，
+// 墓碑保留期内按不存在返回且不回源，保留期过后读到新值
+func TestTombstoneDeleteDuringSlowLoad(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+	const retention, delay = 300 * time.Millisecond, 200 * time.Millisecond
+	c := startCluster(t, cluster.Options{Groups: []cluster.GroupSpec{{
+		Name:    "test",
+		Options: []cache.GroupOption{cache.WithTombstones(retention, 0)},
+	}}})
+	source := c.Source("test")
+	source.Set("k", "old")
+	source.SetDelay(delay)
+	owner := c.Owner("k").Group("test")
+
+	var (
+		wg      sync.WaitGroup
+		stop    atomic.Bool
+		deleted atomic.Bool
+		sawNew  atomic.Bool
+		errs    = make(chan error, 8)
+	)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for !stop.Load() {
+				after := deleted.Load()
+				body, code, err := c.Get("test", "k")
+				switch {
+				case err != nil:
+					errs <- err
+					return
+				case code == http.StatusOK && string(body) == "new":
+					sawNew.Store(true)
+				case code == http.StatusOK && string(body) == "old":
+					if after {
+						errs <- fmt.Errorf("删除返回之后发起的读取读到了旧值")
+						return
+					}
+				case code == http.StatusNotFound && after:
+					// 墓碑保留期内按不存在返回
+				default:
+					errs <- fmt.Errorf("Get = %d %q (删除已返回: %v)", code, body, after)
+					return
+				}
+			}
+		}()
+	}
+
+	// 在加载进行中更新数据源并删除 key
+	waitUntil(t, "开始加载", func() bool { return source.Loads("k") > 0 })
+	source.Set("k", "new")
+	if code, err := c.Delete("test", "k"); err != nil || code != http.StatusOK {
+		t.Fatalf("Delete = %d, %v", code, err)
+	}
+	deleted.Store(true)
+	if _, _, ok := owner.Peek("k"); ok {
+		t.Fatal("删除返回时归属节点缓存了旧值")
+	}
+
+	waitUntil(t, "墓碑过期后读到新值", func() bool { return sawNew.Load() || len(errs) > 0 })
+	stop.Store(true)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+
+	if v, _, ok := owner.Peek("k"); !ok || v.String() != "new" {
+		t.Fatalf("归属节点缓存的值 = %q, %v; want new", v, ok)
+	}
+	stats := owner.Stats()
+	if stats.TombstoneRejects == 0 {
+		t.Fatal("删除时进行中的加载结果被写入了缓存")
+	}
+	if stats.TombstoneHits == 0 {
+		t.Fatal("墓碑保留期内的读取没有按不存在返回")
+	}
+	// 删除前的一次加载和墓碑过期后的一次加载，保留期内的读取不回源
+	if n := source.Loads("k"); n != 2 {
+		t.Fatalf("数据源加载了 %d 次，want 2", n)
+	}
+}
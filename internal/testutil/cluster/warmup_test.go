@@ -0,0 +1,69 @@
+package cluster_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/AdrianWangs/go-cache/internal/server"
+	"github.com/AdrianWangs/go-cache/internal/testutil/cluster"
+)
+
+// scaleUpMisses 启动三个节点的集群并读取 n 个 key，加入第四个节点，warm 为真时在新节点上预热，
+// 返回之后再读取一遍所有 key 时数据源的加载次数、新节点归属的 key 数和新节点
+func scaleUpMisses(t *testing.T, n int, warm bool) (loads, owned int, added *cluster.Node) {
+	t.Helper()
+	c := startCluster(t, cluster.Options{})
+	source := c.Source("test")
+	for i := 0; i < n; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		source.Set(key, "v-"+key)
+		mustGet(t, c, key, "v-"+key)
+	}
+
+	added, err := c.AddNode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if warm {
+		if err := added.Pool.WarmUp(context.Background(), server.WarmupOptions{}); err != nil {
+			t.Fatalf("WarmUp: %v", err)
+		}
+	}
+
+	source.ResetLoads()
+	for i := 0; i < n; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		mustGet(t, c, key, "v-"+key)
+		if c.Owner(key) == added {
+			owned++
+		}
+	}
+	return source.TotalLoads(), owned, added
+}
+
+// TestWarmupAfterScaleUp 扩容后新节点归属的 key 在没有预热时都要回源，预热后直接命中
+func TestWarmupAfterScaleUp(t *testing.T) {
+	const n = 200
+	cold, owned, _ := scaleUpMisses(t, n, false)
+	if owned == 0 {
+		t.Fatal("新节点没有分到任何 key")
+	}
+	if cold != owned {
+		t.Fatalf("未预热时回源 %d 次, want 新节点归属的 %d 个 key", cold, owned)
+	}
+
+	warm, owned, added := scaleUpMisses(t, n, true)
+	t.Logf("新节点归属 %d 个 key：未预热回源 %d 次，预热后回源 %d 次", owned, cold, warm)
+	if warm != 0 {
+		t.Fatalf("预热后仍回源 %d 次", warm)
+	}
+	status := added.Pool.WarmupStatus()
+	if status.State != server.WarmupDone || status.Pulled != int64(owned) || status.Failed != 0 {
+		t.Fatalf("预热状态 = %+v, want done 且拉取 %d 个 key", status, owned)
+	}
+	stats := added.Pool.WarmupStats()
+	if stats.GetState() != string(server.WarmupDone) || stats.GetPulled() != int64(owned) {
+		t.Fatalf("stats 中的预热进度 = %v", stats)
+	}
+}
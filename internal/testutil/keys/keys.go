@@ -0,0 +1,20 @@
+// Package keys 提供各层路由测试共用的键表
+package keys
+
+// Routing 经 URL 路径传递时容易出错的键：含斜杠、空格、保留字符、字面量 %、非 ASCII 字符，
+// 以及会被路径清理改写的 "." 和 ".."
+var Routing = []string{
+	"plain",
+	"users/42",
+	"a/b/c/",
+	"with space",
+	"hash#fragment",
+	"question?mark=1",
+	"percent%2Fliteral",
+	"plus+sign",
+	"中文键/値",
+	".",
+	"..",
+	"a/../b",
+	"./x",
+}
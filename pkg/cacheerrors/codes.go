@@ -0,0 +1,180 @@
+package cacheerrors
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// 跨节点传递的错误码，节点在纯 HTTP 接口的 X-GoCache-Error-Code 响应头中返回
+const (
+	ErrorCodeKeyEmpty      = "key_empty"
+	ErrorCodeKeyNotFound   = "key_not_found"
+	ErrorCodeGroupNotFound = "group_not_found"
+	ErrorCodeRateLimited   = "rate_limited"
+	ErrorCodeReadOnly      = "read_only"
+	ErrorCodeNoPeer        = "no_peer_available"
+	ErrorCodeOrigin        = "origin_unavailable"
+	ErrorCodeInternal      = "internal"
+
+	ErrorCodeGroupForbidden = "group_forbidden"
+	ErrorCodeValueTransform = "value_transform"
+	ErrorCodeGroupClosed    = "group_closed"
+)
+
+// mapping 一个错误类型在三种表示之间的对应关系
+type mapping struct {
+	code   string     // 错误码
+	status int        // HTTP 状态码
+	grpc   codes.Code // gRPC 状态码
+}
+
+// mappings 错误类型到错误码、HTTP 状态码和 gRPC 状态码的唯一映射表。
+// 没有对应预定义错误的类型（内部错误、网络错误）只在正向映射中使用
+var mappings = map[int]mapping{
+	ErrTypeKeyEmpty:          {ErrorCodeKeyEmpty, http.StatusBadRequest, codes.InvalidArgument},
+	ErrTypeKeyNotFound:       {ErrorCodeKeyNotFound, http.StatusNotFound, codes.NotFound},
+	ErrTypeGroupNotFound:     {ErrorCodeGroupNotFound, http.StatusNotFound, codes.NotFound},
+	ErrTypeRateLimited:       {ErrorCodeRateLimited, http.StatusTooManyRequests, codes.ResourceExhausted},
+	ErrTypeReadOnly:          {ErrorCodeReadOnly, http.StatusServiceUnavailable, codes.FailedPrecondition},
+	ErrTypeNoPeerAvailable:   {ErrorCodeNoPeer, http.StatusServiceUnavailable, codes.FailedPrecondition},
+	ErrTypeOriginUnavailable: {ErrorCodeOrigin, http.StatusServiceUnavailable, codes.Unavailable},
+	ErrTypeGroupForbidden:    {ErrorCodeGroupForbidden, http.StatusForbidden, codes.PermissionDenied},
+	ErrTypeValueTransform:    {ErrorCodeValueTransform, http.StatusInternalServerError, codes.DataLoss},
+	ErrTypeGroupClosed:       {ErrorCodeGroupClosed, http.StatusServiceUnavailable, codes.Unavailable},
+	ErrTypeInternalError:     {ErrorCodeInternal, http.StatusInternalServerError, codes.Internal},
+	ErrTypeNetworkError:      {ErrorCodeInternal, http.StatusInternalServerError, codes.Internal},
+}
+
+// reverseOrder 反向映射时依次尝试的错误类型，保证结果与 map 的遍历顺序无关
+var reverseOrder = []int{
+	ErrTypeKeyEmpty,
+	ErrTypeKeyNotFound,
+	ErrTypeGroupNotFound,
+	ErrTypeRateLimited,
+	ErrTypeReadOnly,
+	ErrTypeNoPeerAvailable,
+	ErrTypeOriginUnavailable,
+	ErrTypeGroupForbidden,
+	ErrTypeValueTransform,
+	ErrTypeGroupClosed,
+}
+
+// ErrorCode 返回 err 对应的错误码，err 为 nil 时返回空字符串
+func ErrorCode(err error) string {
+	if err == nil {
+		return ""
+	}
+	if m, ok := mappings[TypeOf(err)]; ok {
+		return m.code
+	}
+	return ErrorCodeInternal
+}
+
+// ErrorFromCode 返回错误码对应的预定义错误，未知的错误码和 internal 返回 nil，由调用方按状态码处理
+func ErrorFromCode(code string) error {
+	if code == "" {
+		return nil
+	}
+	for _, t := range reverseOrder {
+		if mappings[t].code == code {
+			return sentinels[t]
+		}
+	}
+	return nil
+}
+
+// HTTPStatus 返回节点响应 err 时使用的 HTTP 状态码：err 为 nil 时为 200，调用方已取消
+// 为 503，不是 CacheError 时为 500
+func HTTPStatus(err error) int {
+	if err == nil {
+		return http.StatusOK
+	}
+	if errors.Is(err, context.Canceled) {
+		return http.StatusServiceUnavailable
+	}
+	if m, ok := mappings[TypeOf(err)]; ok {
+		return m.status
+	}
+	return http.StatusInternalServerError
+}
+
+// ErrorFromHTTP 把节点的 HTTP 响应映射为预定义错误。code 是 X-GoCache-Error-Code 响应头，
+// 存在时优先按错误码映射；没有错误码的旧节点只按含义唯一的状态码（429、403）映射。
+// 404、503 等对应多种错误的状态码返回 nil，由调用方结合请求类型或响应内容判断
+func ErrorFromHTTP(statusCode int, code string) error {
+	if statusCode >= 200 && statusCode < 300 {
+		return nil
+	}
+	if err := ErrorFromCode(code); err != nil {
+		return err
+	}
+	switch statusCode {
+	case http.StatusTooManyRequests:
+		return ErrRateLimited
+	case http.StatusForbidden:
+		return ErrGroupForbidden
+	}
+	return nil
+}
+
+// GRPCCode 返回节点响应 err 时使用的 gRPC 状态码：err 为 nil 时为 OK，已是 gRPC 状态的
+// 错误保留其状态码，上下文取消和超时分别为 Canceled 和 DeadlineExceeded，不是 CacheError 时为 Unknown
+func GRPCCode(err error) codes.Code {
+	if err == nil {
+		return codes.OK
+	}
+	if s, ok := status.FromError(err); ok {
+		return s.Code()
+	}
+	switch {
+	case errors.Is(err, context.Canceled):
+		return codes.Canceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return codes.DeadlineExceeded
+	}
+	var cacheErr *CacheError
+	if !errors.As(err, &cacheErr) {
+		return codes.Unknown
+	}
+	if m, ok := mappings[cacheErr.Type]; ok {
+		return m.grpc
+	}
+	return codes.Unknown
+}
+
+// GRPCStatus 把 err 转换为节点返回给调用方的 gRPC 状态。同一状态码对应多种错误
+// （例如 NotFound 对应键不存在和组不存在），因此状态信息总是以对应预定义错误的信息开头，
+// 调用方据此用 ErrorFromGRPC 还原出唯一的错误。err 为 nil 时返回 nil
+func GRPCStatus(err error) *status.Status {
+	if err == nil {
+		return nil
+	}
+	if s, ok := status.FromError(err); ok {
+		return s
+	}
+	msg := err.Error()
+	if sentinel, ok := sentinels[TypeOf(err)]; ok && !strings.HasPrefix(msg, sentinel.Message) {
+		msg = sentinel.Message + ": " + msg
+	}
+	return status.New(GRPCCode(err), msg)
+}
+
+// ErrorFromGRPC 把节点返回的 gRPC 错误映射为预定义错误：状态码一致且状态信息以
+// 预定义错误的信息开头时返回该错误。连接失败等其他错误返回 nil，由调用方处理
+func ErrorFromGRPC(err error) error {
+	s, ok := status.FromError(err)
+	if !ok || s.Code() == codes.OK {
+		return nil
+	}
+	for _, t := range reverseOrder {
+		if sentinel := sentinels[t]; mappings[t].grpc == s.Code() && strings.HasPrefix(s.Message(), sentinel.Message) {
+			return sentinel
+		}
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package cacheerrors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TestMappingsExhaustive 每个错误类型都有映射和预定义错误，错误码互不相同，
+// 反向映射按 reverseOrder 覆盖所有预定义错误
+func TestMappingsExhaustive(t *testing.T) {
+	seen := make(map[string]int)
+	for typ := ErrTypeKeyEmpty; typ <= ErrTypeGroupClosed; typ++ {
+		m, ok := mappings[typ]
+		if !ok {
+			t.Fatalf("错误类型 %d 没有映射", typ)
+		}
+		if typ == ErrTypeInternalError || typ == ErrTypeNetworkError {
+			continue
+		}
+		if _, ok := sentinels[typ]; !ok {
+			t.Fatalf("错误类型 %d 没有预定义错误", typ)
+		}
+		if other, dup := seen[m.code]; dup {
+			t.Fatalf("错误类型 %d 与 %d 使用同一个错误码 %s", typ, other, m.code)
+		}
+		seen[m.code] = typ
+	}
+	if len(reverseOrder) != len(sentinels) {
+		t.Fatalf("reverseOrder 有 %d 项，预定义错误有 %d 个", len(reverseOrder), len(sentinels))
+	}
+}
+
+// TestRoundTrip 每个预定义错误（以及包装后的错误）经错误码、HTTP 响应和 gRPC 状态转换后都还原为同一个错误
+func TestRoundTrip(t *testing.T) {
+	for _, typ := range reverseOrder {
+		sentinel := sentinels[typ]
+		for _, err := range []error{sentinel, fmt.Errorf("%w: users/Tom", sentinel), WrapError(typ, sentinel.Message, errors.New("cause"))} {
+			t.Run(err.Error(), func(t *testing.T) {
+				m := mappings[typ]
+				if code := ErrorCode(err); code != m.code {
+					t.Fatalf("ErrorCode = %q, want %q", code, m.code)
+				}
+				if got := ErrorFromCode(ErrorCode(err)); got != sentinel {
+					t.Fatalf("ErrorFromCode = %v, want %v", got, sentinel)
+				}
+
+				if s := HTTPStatus(err); s != m.status {
+					t.Fatalf("HTTPStatus = %d, want %d", s, m.status)
+				}
+				if got := ErrorFromHTTP(HTTPStatus(err), ErrorCode(err)); got != sentinel {
+					t.Fatalf("ErrorFromHTTP = %v, want %v", got, sentinel)
+				}
+
+				if c := GRPCCode(err); c != m.grpc {
+					t.Fatalf("GRPCCode = %s, want %s", c, m.grpc)
+				}
+				wire := GRPCStatus(err).Err()
+				if got := ErrorFromGRPC(wire); got != sentinel {
+					t.Fatalf("ErrorFromGRPC(%v) = %v, want %v", wire, got, sentinel)
+				}
+				// 已是 gRPC 状态的错误原样保留
+				if GRPCStatus(wire).Code() != m.grpc {
+					t.Fatalf("再次转换后状态码为 %s", GRPCStatus(wire).Code())
+				}
+			})
+		}
+	}
+}
+
+func TestNonCacheErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		code   string
+		status int
+		grpc   codes.Code
+	}{
+		{"nil", nil, "", http.StatusOK, codes.OK},
+		{"普通错误", errors.New("boom"), ErrorCodeInternal, http.StatusInternalServerError, codes.Unknown},
+		{"内部错误", NewCacheError(ErrTypeInternalError, "boom"), ErrorCodeInternal, http.StatusInternalServerError, codes.Internal},
+		{"网络错误", NewCacheError(ErrTypeNetworkError, "dial"), ErrorCodeInternal, http.StatusInternalServerError, codes.Internal},
+		{"调用方取消", fmt.Errorf("load: %w", context.Canceled), ErrorCodeInternal, http.StatusServiceUnavailable, codes.Canceled},
+		{"超时", fmt.Errorf("load: %w", context.DeadlineExceeded), ErrorCodeInternal, http.StatusInternalServerError, codes.DeadlineExceeded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := ErrorCode(tt.err); code != tt.code {
+				t.Fatalf("ErrorCode = %q, want %q", code, tt.code)
+			}
+			if s := HTTPStatus(tt.err); s != tt.status {
+				t.Fatalf("HTTPStatus = %d, want %d", s, tt.status)
+			}
+			if c := GRPCCode(tt.err); c != tt.grpc {
+				t.Fatalf("GRPCCode = %s, want %s", c, tt.grpc)
+			}
+			// 不能还原为任何预定义错误
+			if got := ErrorFromHTTP(HTTPStatus(tt.err), ErrorCode(tt.err)); got != nil {
+				t.Fatalf("ErrorFromHTTP = %v", got)
+			}
+			if got := ErrorFromGRPC(GRPCStatus(tt.err).Err()); got != nil {
+				t.Fatalf("ErrorFromGRPC = %v", got)
+			}
+		})
+	}
+}
+
+// TestFromLegacyResponses 没有错误码的旧节点只按含义唯一的状态码映射，其他情况交给调用方
+func TestFromLegacyResponses(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		code   string
+		want   error
+	}{
+		{"限流", http.StatusTooManyRequests, "", ErrRateLimited},
+		{"组不允许读取", http.StatusForbidden, "", ErrGroupForbidden},
+		{"404 含义不唯一", http.StatusNotFound, "", nil},
+		{"503 含义不唯一", http.StatusServiceUnavailable, "", nil},
+		{"未知错误码", http.StatusNotFound, "gone_fishing", nil},
+		{"内部错误码", http.StatusInternalServerError, ErrorCodeInternal, nil},
+		{"成功时忽略错误码", http.StatusOK, ErrorCodeKeyNotFound, nil},
+		{"错误码优先于状态码", http.StatusTooManyRequests, ErrorCodeReadOnly, ErrReadOnly},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ErrorFromHTTP(tt.status, tt.code); got != tt.want {
+				t.Fatalf("ErrorFromHTTP(%d, %q) = %v, want %v", tt.status, tt.code, got, tt.want)
+			}
+		})
+	}
+
+	// 状态码相同时按状态信息的前缀区分，连接错误等非状态错误不映射
+	if got := ErrorFromGRPC(status.Error(codes.NotFound, "未找到组: users")); got != nil {
+		t.Fatalf("不以预定义错误信息开头的状态 = %v", got)
+	}
+	if got := ErrorFromGRPC(status.Error(codes.NotFound, ErrNoSuchGroup.Message+": users")); got != ErrNoSuchGroup {
+		t.Fatalf("组不存在的状态 = %v", got)
+	}
+	if got := ErrorFromGRPC(errors.New("connection refused")); got != nil {
+		t.Fatalf("非状态错误 = %v", got)
+	}
+}
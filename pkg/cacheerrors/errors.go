@@ -0,0 +1,187 @@
+// Package cacheerrors 定义缓存的错误分类：预定义错误、跨节点传递的错误码，以及它们与
+// HTTP 状态码、gRPC 状态码之间的映射。节点、API 服务器、对等节点客户端和客户端 SDK
+// 都使用这里的定义，应用可以直接用 errors.Is 或 Is* 判断 SDK 和 Group API 返回的错误
+package cacheerrors
+
+import (
+	"errors"
+	"fmt"
+)
+
+// 错误类型枚举
+const (
+	// ErrTypeNone 无错误
+	ErrTypeNone = iota
+	// ErrTypeKeyEmpty 键为空
+	ErrTypeKeyEmpty
+	// ErrTypeKeyNotFound 键不存在
+	ErrTypeKeyNotFound
+	// ErrTypeGroupNotFound 组不存在
+	ErrTypeGroupNotFound
+	// ErrTypeInternalError 内部错误
+	ErrTypeInternalError
+	// ErrTypeNetworkError 网络错误
+	ErrTypeNetworkError
+	// ErrTypeRateLimited 超出限流配额
+	ErrTypeRateLimited
+	// ErrTypeReadOnly 只读模式下拒绝写操作
+	ErrTypeReadOnly
+	// ErrTypeNoPeerAvailable 未命中时没有可用的归属节点，且缺失策略不允许回源
+	ErrTypeNoPeerAvailable
+	// ErrTypeOriginUnavailable 数据源熔断器打开，未命中时不访问数据源
+	ErrTypeOriginUnavailable
+	// ErrTypeGroupForbidden 组不在节点允许对外提供的组中
+	ErrTypeGroupForbidden
+	// ErrTypeValueTransform 缓存值的编码或解码（例如加密、解密）失败
+	ErrTypeValueTransform
+	// ErrTypeGroupClosed 缓存组已关闭
+	ErrTypeGroupClosed
+)
+
+// 预定义的错误
+var (
+	// ErrEmptyKey 表示键为空
+	ErrEmptyKey = NewCacheError(ErrTypeKeyEmpty, "key is empty")
+	// ErrNotFound 表示键不存在
+	ErrNotFound = NewCacheError(ErrTypeKeyNotFound, "key not found")
+	// ErrNoSuchGroup 表示缓存组不存在
+	ErrNoSuchGroup = NewCacheError(ErrTypeGroupNotFound, "cache group not found")
+	// ErrRateLimited 表示请求超出组或节点的限流配额
+	ErrRateLimited = NewCacheError(ErrTypeRateLimited, "rate limit exceeded")
+	// ErrReadOnly 表示节点或缓存组处于只读模式
+	ErrReadOnly = NewCacheError(ErrTypeReadOnly, "cache group is read-only")
+	// ErrNoPeerAvailable 表示归属节点不可用且缺失策略禁止从本地数据源加载
+	ErrNoPeerAvailable = NewCacheError(ErrTypeNoPeerAvailable, "no peer available")
+	// ErrOriginUnavailable 表示数据源熔断器处于打开状态，未命中的 key 暂时无法加载
+	ErrOriginUnavailable = NewCacheError(ErrTypeOriginUnavailable, "origin unavailable")
+	// ErrGroupForbidden 表示缓存组只供节点内部使用，不允许经对等节点或 API 服务器读取
+	ErrGroupForbidden = NewCacheError(ErrTypeGroupForbidden, "cache group is not servable")
+	// ErrValueTransform 表示组的值变换无法编码写入的值，或无法解码缓存中的值，例如解密密钥已轮换掉
+	ErrValueTransform = NewCacheError(ErrTypeValueTransform, "value transform failed")
+	// ErrGroupClosed 表示缓存组已经调用过 Close，不再提供读写，通常发生在节点关闭期间
+	ErrGroupClosed = NewCacheError(ErrTypeGroupClosed, "cache group is closed")
+)
+
+// sentinels 按错误类型索引的预定义错误，供反向映射使用
+var sentinels = map[int]*CacheError{
+	ErrTypeKeyEmpty:          ErrEmptyKey,
+	ErrTypeKeyNotFound:       ErrNotFound,
+	ErrTypeGroupNotFound:     ErrNoSuchGroup,
+	ErrTypeRateLimited:       ErrRateLimited,
+	ErrTypeReadOnly:          ErrReadOnly,
+	ErrTypeNoPeerAvailable:   ErrNoPeerAvailable,
+	ErrTypeOriginUnavailable: ErrOriginUnavailable,
+	ErrTypeGroupForbidden:    ErrGroupForbidden,
+	ErrTypeValueTransform:    ErrValueTransform,
+	ErrTypeGroupClosed:       ErrGroupClosed,
+}
+
+// CacheError 表示缓存错误
+type CacheError struct {
+	Type    int    // 错误类型
+	Message string // 错误信息
+	Cause   error  // 原始错误（可选）
+}
+
+// Error 实现error接口
+func (e *CacheError) Error() string {
+	if e.Cause != nil {
+		return fmt.Sprintf("%s: %v", e.Message, e.Cause)
+	}
+	return e.Message
+}
+
+// Unwrap 实现errors.Unwrap接口，支持错误链
+func (e *CacheError) Unwrap() error {
+	return e.Cause
+}
+
+// NewCacheError 创建一个新的缓存错误
+func NewCacheError(errType int, message string) *CacheError {
+	return &CacheError{
+		Type:    errType,
+		Message: message,
+	}
+}
+
+// WrapError 包装一个错误
+func WrapError(errType int, message string, cause error) *CacheError {
+	return &CacheError{
+		Type:    errType,
+		Message: message,
+		Cause:   cause,
+	}
+}
+
+// TypeOf 返回 err 链上第一个 CacheError 的错误类型，err 为 nil 时返回 ErrTypeNone，
+// 不是 CacheError 时返回 ErrTypeInternalError
+func TypeOf(err error) int {
+	if err == nil {
+		return ErrTypeNone
+	}
+	var cacheErr *CacheError
+	if !errors.As(err, &cacheErr) {
+		return ErrTypeInternalError
+	}
+	return cacheErr.Type
+}
+
+// IsKeyEmptyError 判断是否为键为空错误
+func IsKeyEmptyError(err error) bool {
+	var cacheErr *CacheError
+	return errors.As(err, &cacheErr) && cacheErr.Type == ErrTypeKeyEmpty
+}
+
+// IsKeyNotFoundError 判断是否为键不存在错误
+func IsKeyNotFoundError(err error) bool {
+	var cacheErr *CacheError
+	return errors.As(err, &cacheErr) && cacheErr.Type == ErrTypeKeyNotFound
+}
+
+// IsGroupNotFoundError 判断是否为组不存在错误
+func IsGroupNotFoundError(err error) bool {
+	var cacheErr *CacheError
+	return errors.As(err, &cacheErr) && cacheErr.Type == ErrTypeGroupNotFound
+}
+
+// IsRateLimitedError 判断是否为限流错误
+func IsRateLimitedError(err error) bool {
+	var cacheErr *CacheError
+	return errors.As(err, &cacheErr) && cacheErr.Type == ErrTypeRateLimited
+}
+
+// IsReadOnlyError 判断是否为只读模式错误
+func IsReadOnlyError(err error) bool {
+	var cacheErr *CacheError
+	return errors.As(err, &cacheErr) && cacheErr.Type == ErrTypeReadOnly
+}
+
+// IsNoPeerAvailableError 判断是否为没有可用对等节点错误
+func IsNoPeerAvailableError(err error) bool {
+	var cacheErr *CacheError
+	return errors.As(err, &cacheErr) && cacheErr.Type == ErrTypeNoPeerAvailable
+}
+
+// IsOriginUnavailableError 判断是否为数据源不可用（熔断器打开）错误
+func IsOriginUnavailableError(err error) bool {
+	var cacheErr *CacheError
+	return errors.As(err, &cacheErr) && cacheErr.Type == ErrTypeOriginUnavailable
+}
+
+// IsGroupForbiddenError 判断是否为组不允许对外提供错误
+func IsGroupForbiddenError(err error) bool {
+	var cacheErr *CacheError
+	return errors.As(err, &cacheErr) && cacheErr.Type == ErrTypeGroupForbidden
+}
+
+// IsValueTransformError 判断是否为值变换（编码、解码）失败错误
+func IsValueTransformError(err error) bool {
+	var cacheErr *CacheError
+	return errors.As(err, &cacheErr) && cacheErr.Type == ErrTypeValueTransform
+}
+
+// IsGroupClosedError 判断是否为缓存组已关闭错误
+func IsGroupClosedError(err error) bool {
+	var cacheErr *CacheError
+	return errors.As(err, &cacheErr) && cacheErr.Type == ErrTypeGroupClosed
+}
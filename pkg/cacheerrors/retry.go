@@ -0,0 +1,58 @@
+package cacheerrors
+
+import (
+	"errors"
+	"strconv"
+	"time"
+)
+
+// HeaderRetryAfter 限流响应中建议的重试间隔（秒）
+const HeaderRetryAfter = "Retry-After"
+
+// RateLimitedError 是带有建议重试间隔的 ErrRateLimited，errors.Is(err, ErrRateLimited)
+// 和 IsRateLimitedError 对它同样成立
+type RateLimitedError struct {
+	RetryAfter time.Duration // 拒绝请求的令牌桶再次有可用令牌的时间
+}
+
+// Error 实现error接口
+func (e *RateLimitedError) Error() string {
+	return ErrRateLimited.Error()
+}
+
+// Unwrap 返回 ErrRateLimited
+func (e *RateLimitedError) Unwrap() error {
+	return ErrRateLimited
+}
+
+// RetryAfter 返回限流错误建议的重试间隔，err 不带间隔时返回 0
+func RetryAfter(err error) time.Duration {
+	var limited *RateLimitedError
+	if errors.As(err, &limited) {
+		return limited.RetryAfter
+	}
+	return 0
+}
+
+// RetryAfterHeader 返回限流错误对应的 Retry-After 响应头：重试间隔向上取整到秒，至少为 1；
+// 不知道间隔（例如从 gRPC 或旧节点得到的限流错误）时为 1。err 不是限流错误时返回空字符串
+func RetryAfterHeader(err error) string {
+	if !IsRateLimitedError(err) {
+		return ""
+	}
+	seconds := (RetryAfter(err) + time.Second - 1) / time.Second
+	return strconv.FormatInt(int64(max(seconds, 1)), 10)
+}
+
+// WithRetryAfter 在 err 为限流错误且 header（Retry-After 响应头）是正的秒数时，返回带有该
+// 重试间隔的 RateLimitedError，否则原样返回 err
+func WithRetryAfter(err error, header string) error {
+	if !IsRateLimitedError(err) {
+		return err
+	}
+	seconds, perr := strconv.Atoi(header)
+	if perr != nil || seconds <= 0 {
+		return err
+	}
+	return &RateLimitedError{RetryAfter: time.Duration(seconds) * time.Second}
+}
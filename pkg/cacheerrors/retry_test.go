@@ -0,0 +1,54 @@
+package cacheerrors
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// TestRateLimitedError 带重试间隔的限流错误与 ErrRateLimited 同样映射，Retry-After 向上取整到秒
+func TestRateLimitedError(t *testing.T) {
+	err := fmt.Errorf("group scores: %w", &RateLimitedError{RetryAfter: 1500 * time.Millisecond})
+	if !errors.Is(err, ErrRateLimited) || !IsRateLimitedError(err) || HTTPStatus(err) != http.StatusTooManyRequests || ErrorCode(err) != ErrorCodeRateLimited {
+		t.Fatalf("%v 没有按限流错误映射", err)
+	}
+	if d := RetryAfter(err); d != 1500*time.Millisecond {
+		t.Fatalf("RetryAfter = %v", d)
+	}
+
+	for _, tt := range []struct {
+		err  error
+		want string
+	}{
+		{err, "2"},
+		{&RateLimitedError{RetryAfter: time.Millisecond}, "1"},
+		{&RateLimitedError{RetryAfter: 3 * time.Second}, "3"},
+		{ErrRateLimited, "1"}, // 不知道间隔
+		{ErrNotFound, ""},
+		{nil, ""},
+	} {
+		if got := RetryAfterHeader(tt.err); got != tt.want {
+			t.Errorf("RetryAfterHeader(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
+
+// TestWithRetryAfter 从响应头还原重试间隔，只作用于限流错误
+func TestWithRetryAfter(t *testing.T) {
+	if d := RetryAfter(WithRetryAfter(ErrRateLimited, "3")); d != 3*time.Second {
+		t.Fatalf("Retry-After: 3 还原为 %v", d)
+	}
+	for _, header := range []string{"", "0", "-1", "soon", "Wed, 21 Oct 2015 07:28:00 GMT"} {
+		if err := WithRetryAfter(ErrRateLimited, header); err != ErrRateLimited {
+			t.Fatalf("Retry-After: %q 得到 %v", header, err)
+		}
+	}
+	if err := WithRetryAfter(ErrNotFound, "3"); err != ErrNotFound {
+		t.Fatalf("非限流错误被改写为 %v", err)
+	}
+	if err := WithRetryAfter(nil, "3"); err != nil {
+		t.Fatalf("nil 被改写为 %v", err)
+	}
+}
@@ -0,0 +1,128 @@
+package cachetest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/internal/cache"
+	"github.com/AdrianWangs/go-cache/internal/peers"
+	"github.com/AdrianWangs/go-cache/pkg/cachetest"
+	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
+)
+
+func TestGetter(t *testing.T) {
+	g := cachetest.NewGetter(map[string]string{"a": "1"})
+	if v, err := g.Get("a"); err != nil || string(v) != "1" {
+		t.Fatalf("Get(a) = %q, %v", v, err)
+	}
+	if _, err := g.Get("b"); !errors.Is(err, cache.ErrNotFound) {
+		t.Fatalf("Get(b) = %v, want ErrNotFound", err)
+	}
+	boom := errors.New("boom")
+	g.SetError("a", boom)
+	if _, err := g.Get("a"); !errors.Is(err, boom) {
+		t.Fatalf("SetError 后 Get(a) = %v", err)
+	}
+	g.SetError("a", nil)
+	g.Set("b", "2")
+	g.Get("b")
+	if g.Loads("a") != 2 || g.Loads("b") != 2 || g.TotalLoads() != 4 {
+		t.Fatalf("Loads = %d/%d/%d", g.Loads("a"), g.Loads("b"), g.TotalLoads())
+	}
+
+	g.SetDelay(time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := g.GetContext(ctx, "a"); !errors.Is(err, context.DeadlineExceeded) || g.Cancels("a") != 1 {
+		t.Fatalf("取消的加载 = %v, Cancels = %d", err, g.Cancels("a"))
+	}
+	g.ResetLoads()
+	if g.TotalLoads() != 0 || g.Cancels("a") != 0 {
+		t.Fatal("ResetLoads 未清空计数")
+	}
+}
+
+func TestRingPeerFetches(t *testing.T) {
+	source := cachetest.NewGetter(map[string]string{"k": "v"})
+	ring := cachetest.NewRing(3)
+	defer ring.Close()
+	ring.NewGroup("g", 1<<20, source, time.Hour)
+
+	owner := ring.Owner("k")
+	var other *cachetest.RingNode
+	for _, n := range ring.Nodes() {
+		if n != owner {
+			other = n
+		}
+		if res := n.PickOwner("k"); (n == owner) != (res.State == peers.PickSelf) {
+			t.Fatalf("%s PickOwner = %v", n.ID, res.State)
+		}
+	}
+
+	if v, err := other.Group("g").Get("k"); err != nil || v.String() != "v" {
+		t.Fatalf("Get = %q, %v", v.String(), err)
+	}
+	if owner.Fetches() != 1 || source.Loads("k") != 1 {
+		t.Fatalf("Fetches = %d, Loads = %d", owner.Fetches(), source.Loads("k"))
+	}
+	if _, _, ok := owner.Group("g").Peek("k"); !ok {
+		t.Fatal("归属节点没有缓存 key")
+	}
+
+	// 归属节点不可用时回退到本地数据源
+	owner.SetDown(true)
+	var resp pb.Response
+	if err := owner.GetByProto(&pb.Request{Group: "g", Key: "k"}, &resp); !errors.Is(err, cachetest.ErrPeerDown) {
+		t.Fatalf("GetByProto = %v, want ErrPeerDown", err)
+	}
+	third := ring.Nodes()[0]
+	for _, n := range ring.Nodes() {
+		if n != owner && n != other {
+			third = n
+		}
+	}
+	if v, err := third.Group("g").Get("k"); err != nil || v.String() != "v" {
+		t.Fatalf("归属节点不可用时 Get = %q, %v", v.String(), err)
+	}
+	if source.Loads("k") != 2 {
+		t.Fatalf("Loads = %d, want 2", source.Loads("k"))
+	}
+	if _, err := other.Get("missing-group", "k"); !errors.Is(err, cache.ErrNoSuchGroup) {
+		t.Fatalf("不存在的组 = %v", err)
+	}
+}
+
+func TestNodeGetter(t *testing.T) {
+	g := cachetest.NewNodeGetter()
+	boom := errors.New("boom")
+	g.SetValue("g", "a", "1").SetError("g", "b", boom).On("g", "slow", cachetest.Response{Value: []byte("s"), Delay: time.Second})
+	ctx := context.Background()
+
+	if v, err := g.Get(ctx, "g", "a"); err != nil || string(v) != "1" {
+		t.Fatalf("Get(a) = %q, %v", v, err)
+	}
+	var resp pb.Response
+	if err := g.GetByProto(ctx, &pb.Request{Group: "g", Key: "b"}, &resp); !errors.Is(err, boom) {
+		t.Fatalf("GetByProto(b) = %v", err)
+	}
+	if _, err := g.Get(ctx, "g", "c"); !errors.Is(err, cache.ErrNotFound) {
+		t.Fatalf("未预设的 key = %v", err)
+	}
+
+	timeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, err := g.Get(timeout, "g", "slow"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("超时的读取 = %v", err)
+	}
+
+	g.SetDefault(cachetest.Response{Value: []byte("default")})
+	if v, _ := g.Get(ctx, "g", "c"); string(v) != "default" {
+		t.Fatalf("SetDefault 后 = %q", v)
+	}
+	g.Delete(ctx, "g", "a")
+	if g.Calls("g", "a") != 1 || g.Calls("g", "c") != 2 || g.TotalCalls() != 5 || g.Deletes("g", "a") != 1 {
+		t.Fatalf("Calls = %d/%d/%d, Deletes = %d", g.Calls("g", "a"), g.Calls("g", "c"), g.TotalCalls(), g.Deletes("g", "a"))
+	}
+}
@@ -0,0 +1,32 @@
+// Package cachetest 提供不依赖网络的测试替身，用于对基于 cache.Group 或 api/handlers 的代码做单元测试：
+//
+//   - Getter：内存数据源，实现 cache.Getter 和 cache.GetterCtx，记录每个 key 的加载与取消次数，可预设错误和加载耗时；
+//   - Ring：进程内的 N 个节点，每个节点有独立的 cache.Registry，节点之间通过一致性哈希环
+//     直接调用对方的缓存组，对等节点读取与使用 HTTPPool 时的行为相同；
+//   - NodeGetter：可编程的 handlers.NodeGetter，按 key 预设值、错误和延迟；
+//     NodeGetters 按节点地址管理多个 NodeGetter，通过 Factory 注入 CacheHandler。
+//
+// 在多个节点上创建缓存组，检查 key 只在归属节点加载：
+//
+//	source := cachetest.NewGetter(map[string]string{"Tom": "630"})
+//	ring := cachetest.NewRing(3)
+//	defer ring.Close()
+//	groups := ring.NewGroup("scores", 1<<20, source, time.Hour)
+//	for _, g := range groups {
+//		g.Get("Tom") // 每个节点都能读到
+//	}
+//	source.Loads("Tom")       // 1：只有归属节点访问了数据源
+//	ring.Owner("Tom").Fetches() // 其他节点向归属节点发起的读取次数
+//
+// 用预设的节点响应测试 API 处理器：
+//
+//	getters := cachetest.NewNodeGetters()
+//	getters.Node("10.0.0.1:8001").SetValue("scores", "Tom", "630")
+//	h := handlers.NewCacheHandler("/_gocache/", 50, handlers.CacheHandlerOptions{Getters: getters.Factory()})
+//	h.UpdatePeers([]discovery.NodeInfo{{ID: "a", GRPCAddr: "10.0.0.1:9001", HTTPAddr: "10.0.0.1:8001"}})
+//	rec := httptest.NewRecorder()
+//	h.GetCacheHandler(rec, httptest.NewRequest(http.MethodGet, "/api/cache/scores/Tom", nil))
+//	// rec.Code == 200，rec.Body == "630"
+//
+// 需要真实 HTTP 通信的端到端测试请使用 internal/testutil/cluster
+package cachetest
@@ -0,0 +1,59 @@
+package cachetest_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/api/handlers"
+	"github.com/AdrianWangs/go-cache/internal/discovery"
+	"github.com/AdrianWangs/go-cache/pkg/cachetest"
+)
+
+// 在三个节点上创建缓存组：每个节点都能读到 key，但只有归属节点访问数据源
+func ExampleRing() {
+	source := cachetest.NewGetter(map[string]string{"Tom": "630"})
+	ring := cachetest.NewRing(3)
+	defer ring.Close()
+
+	groups := ring.NewGroup("scores", 1<<20, source, time.Hour)
+	for _, g := range groups {
+		v, err := g.Get("Tom")
+		fmt.Println(v.String(), err)
+	}
+	fmt.Println("loads:", source.Loads("Tom"))
+	fmt.Println("peer fetches:", ring.Owner("Tom").Fetches())
+	// Output:
+	// 630 <nil>
+	// 630 <nil>
+	// 630 <nil>
+	// loads: 1
+	// peer fetches: 2
+}
+
+// 用预设的节点响应测试 API 处理器
+func ExampleNodeGetters() {
+	getters := cachetest.NewNodeGetters()
+	getters.Node("10.0.0.1:8001").SetValue("scores", "Tom", "630")
+	getters.Node("10.0.0.1:8001").SetError("scores", "Jack", errors.New("boom"))
+
+	h := handlers.NewCacheHandler("/_gocache/", 50, handlers.CacheHandlerOptions{
+		Protocol: handlers.ProtocolHTTP,
+		Getters:  getters.Factory(),
+	})
+	h.UpdatePeers([]discovery.NodeInfo{{ID: "a", GRPCAddr: "10.0.0.1:9001", HTTPAddr: "10.0.0.1:8001"}})
+
+	for _, key := range []string{"Tom", "Jack", "Sam"} {
+		rec := httptest.NewRecorder()
+		h.GetCacheHandler(rec, httptest.NewRequest(http.MethodGet, "/api/cache/scores/"+key, nil))
+		fmt.Println(key, rec.Code)
+	}
+	fmt.Println("calls:", getters.Node("10.0.0.1:8001").TotalCalls())
+	// Output:
+	// Tom 200
+	// Jack 500
+	// Sam 404
+	// calls: 3
+}
@@ -0,0 +1,137 @@
+package cachetest
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/internal/cache"
+)
+
+// Getter 内存中的数据源，实现 cache.Getter 和 cache.GetterCtx，记录每个 key 被加载和被取消的次数，
+// 用于检查请求是否只在归属节点加载、singleflight 是否合并了并发加载、客户端断开后加载是否被取消等
+type Getter struct {
+	mu     sync.Mutex
+	values map[string][]byte
+	errs   map[string]error
+	loads  map[string]int
+	delay  time.Duration
+
+	cancels map[string]int // 加载期间 ctx 被取消的次数
+}
+
+// NewGetter 创建数据源，values 为初始数据，可以为 nil
+func NewGetter(values map[string]string) *Getter {
+	g := &Getter{
+		values: make(map[string][]byte, len(values)),
+		errs:   make(map[string]error),
+		loads:  make(map[string]int),
+
+		cancels: make(map[string]int),
+	}
+	for k, v := range values {
+		g.values[k] = []byte(v)
+	}
+	return g
+}
+
+// Get 实现 cache.Getter。设置了错误的 key 返回该错误，不存在的 key 返回 cache.ErrNotFound
+func (g *Getter) Get(key string) ([]byte, error) {
+	return g.GetContext(context.Background(), key)
+}
+
+// GetContext 实现 cache.GetterCtx，与 Get 相同，但 SetDelay 设置的耗时内 ctx 被取消时
+// 立即返回 ctx.Err()，并计入 Cancels
+func (g *Getter) GetContext(ctx context.Context, key string) ([]byte, error) {
+	g.mu.Lock()
+	g.loads[key]++
+	delay := g.delay
+	value, ok := g.values[key]
+	err := g.errs[key]
+	g.mu.Unlock()
+
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			g.mu.Lock()
+			g.cancels[key]++
+			g.mu.Unlock()
+			return nil, ctx.Err()
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, cache.ErrNotFound
+	}
+	return append([]byte(nil), value...), nil
+}
+
+// Set 设置 key 的值，已缓存的旧值不受影响
+func (g *Getter) Set(key, value string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.values[key] = []byte(value)
+}
+
+// Delete 从数据源中删除 key
+func (g *Getter) Delete(key string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.values, key)
+}
+
+// SetError 使加载 key 时返回 err，err 为 nil 时恢复正常
+func (g *Getter) SetError(key string, err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if err == nil {
+		delete(g.errs, key)
+		return
+	}
+	g.errs[key] = err
+}
+
+// SetDelay 设置每次加载的耗时，用于构造并发加载
+func (g *Getter) SetDelay(d time.Duration) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.delay = d
+}
+
+// Loads 返回 key 被加载的次数
+func (g *Getter) Loads(key string) int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.loads[key]
+}
+
+// Cancels 返回加载 key 期间 ctx 被取消的次数
+func (g *Getter) Cancels(key string) int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.cancels[key]
+}
+
+// TotalLoads 返回所有 key 的加载次数之和
+func (g *Getter) TotalLoads() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	total := 0
+	for _, n := range g.loads {
+		total += n
+	}
+	return total
+}
+
+// ResetLoads 清空加载计数和取消计数
+func (g *Getter) ResetLoads() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.loads = make(map[string]int)
+	g.cancels = make(map[string]int)
+}
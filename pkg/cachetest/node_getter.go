@@ -0,0 +1,228 @@
+package cachetest
+
+import (
+	"context"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/api/handlers"
+	"github.com/AdrianWangs/go-cache/internal/cache"
+	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
+)
+
+// Response NodeGetter 对某个 key 的预设响应
+type Response struct {
+	Value []byte        // 返回的值，Err 不为 nil 时忽略
+	Err   error         // 返回的错误
+	Delay time.Duration // 返回前的等待时间；ctx 取消时提前返回 ctx.Err()
+}
+
+// NodeGetter 可编程的 handlers.NodeGetter，按 group/key 返回预设的值、错误和延迟，
+// 并记录每个 key 的调用次数。未预设的 key 返回 cache.ErrNotFound
+type NodeGetter struct {
+	mu        sync.Mutex
+	responses map[string]Response
+	fallback  Response
+	calls     map[string]int
+	deletes   map[string]int
+	stats     *pb.StatsResponse
+	statsErr  error
+}
+
+var _ handlers.NodeGetter = (*NodeGetter)(nil)
+
+// NewNodeGetter 创建没有预设响应的 NodeGetter
+func NewNodeGetter() *NodeGetter {
+	return &NodeGetter{
+		responses: make(map[string]Response),
+		fallback:  Response{Err: cache.ErrNotFound},
+		calls:     make(map[string]int),
+		deletes:   make(map[string]int),
+		stats:     &pb.StatsResponse{},
+	}
+}
+
+// requestKey 返回 group/key 在内部映射中的 key
+func requestKey(group, key string) string {
+	return group + "/" + key
+}
+
+// On 预设 group/key 的响应，返回 g 以便链式调用
+func (g *NodeGetter) On(group, key string, resp Response) *NodeGetter {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.responses[requestKey(group, key)] = resp
+	return g
+}
+
+// SetValue 预设 group/key 返回 value
+func (g *NodeGetter) SetValue(group, key, value string) *NodeGetter {
+	return g.On(group, key, Response{Value: []byte(value)})
+}
+
+// SetError 预设 group/key 返回 err
+func (g *NodeGetter) SetError(group, key string, err error) *NodeGetter {
+	return g.On(group, key, Response{Err: err})
+}
+
+// SetDefault 设置未预设的 key 的响应，默认返回 cache.ErrNotFound
+func (g *NodeGetter) SetDefault(resp Response) *NodeGetter {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.fallback = resp
+	return g
+}
+
+// SetStats 设置 Stats 的返回值
+func (g *NodeGetter) SetStats(stats *pb.StatsResponse, err error) *NodeGetter {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.stats, g.statsErr = stats, err
+	return g
+}
+
+// Calls 返回 group/key 被读取的次数
+func (g *NodeGetter) Calls(group, key string) int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.calls[requestKey(group, key)]
+}
+
+// TotalCalls 返回所有读取的次数
+func (g *NodeGetter) TotalCalls() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	total := 0
+	for _, n := range g.calls {
+		total += n
+	}
+	return total
+}
+
+// Deletes 返回 group/key 被删除的次数
+func (g *NodeGetter) Deletes(group, key string) int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.deletes[requestKey(group, key)]
+}
+
+// respond 记录一次读取并返回预设的响应
+func (g *NodeGetter) respond(group, key string) Response {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	k := requestKey(group, key)
+	g.calls[k]++
+	if resp, ok := g.responses[k]; ok {
+		return resp
+	}
+	return g.fallback
+}
+
+// wait 等待 d，ctx 先取消时返回 ctx.Err()
+func wait(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Get 实现 handlers.NodeGetter
+func (g *NodeGetter) Get(ctx context.Context, group, key string) ([]byte, error) {
+	resp := &pb.Response{}
+	if err := g.GetByProto(ctx, &pb.Request{Group: group, Key: key}, resp); err != nil {
+		return nil, err
+	}
+	return resp.Value, nil
+}
+
+// GetByProto 实现 handlers.NodeGetter，预设的延迟期间 ctx 取消时返回 ctx.Err()
+func (g *NodeGetter) GetByProto(ctx context.Context, req *pb.Request, resp *pb.Response) error {
+	r := g.respond(req.GetGroup(), req.GetKey())
+	if err := wait(ctx, r.Delay); err != nil {
+		return err
+	}
+	if r.Err != nil {
+		return r.Err
+	}
+	resp.Value = append([]byte(nil), r.Value...)
+	return nil
+}
+
+// Delete 实现 handlers.NodeGetter，记录删除次数并移除预设的值
+func (g *NodeGetter) Delete(ctx context.Context, group, key string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	k := requestKey(group, key)
+	g.deletes[k]++
+	delete(g.responses, k)
+	return nil
+}
+
+// DeleteBatch 实现 handlers.NodeGetter，记录每个键的删除次数；
+// 预设过值的键报告为 deleted 并移除预设，其余报告为 not_found
+func (g *NodeGetter) DeleteBatch(ctx context.Context, group string, keys []string) ([]cache.DeleteResult, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	results := make([]cache.DeleteResult, len(keys))
+	for i, key := range keys {
+		k := requestKey(group, key)
+		g.deletes[k]++
+		results[i] = cache.DeleteResult{Key: key, Status: cache.DeleteNotFound}
+		if resp, ok := g.responses[k]; ok && resp.Err == nil {
+			results[i].Status = cache.DeleteDeleted
+		}
+		delete(g.responses, k)
+	}
+	return results, nil
+}
+
+// Stats 实现 handlers.NodeGetter
+func (g *NodeGetter) Stats(ctx context.Context) (*pb.StatsResponse, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.stats, g.statsErr
+}
+
+// NodeGetters 按节点地址管理的一组 NodeGetter，通过 Factory 注入 CacheHandler
+type NodeGetters struct {
+	mu     sync.Mutex
+	byAddr map[string]*NodeGetter
+}
+
+// NewNodeGetters 创建空的 NodeGetters
+func NewNodeGetters() *NodeGetters {
+	return &NodeGetters{byAddr: make(map[string]*NodeGetter)}
+}
+
+// Node 返回地址为 addr 的节点的 NodeGetter，不存在时创建。addr 为 CacheHandler 访问节点所用的 host:port：
+// 使用 HTTP 时为 NodeInfo.HTTPAddr，使用 gRPC 时为 NodeInfo.GRPCAddr
+func (s *NodeGetters) Node(addr string) *NodeGetter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, ok := s.byAddr[addr]
+	if !ok {
+		g = NewNodeGetter()
+		s.byAddr[addr] = g
+	}
+	return g
+}
+
+// Factory 返回放入 CacheHandlerOptions.Getters 的 getter 工厂，按 getter 访问的地址选择 NodeGetter：
+// HTTP 协议取基础 URL 中的 host:port，gRPC 协议直接使用地址。
+// 节点所用的协议可通过 CacheHandler.NodeProtocols 查看
+func (s *NodeGetters) Factory() handlers.GetterFactory {
+	return handlers.GetterFactoryFunc(func(protocol handlers.ProtocolType, addr string) handlers.NodeGetter {
+		if u, err := url.Parse(addr); err == nil && u.Host != "" {
+			addr = u.Host
+		}
+		return s.Node(addr)
+	})
+}
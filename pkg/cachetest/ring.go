@@ -0,0 +1,174 @@
+package cachetest
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/internal/cache"
+	"github.com/AdrianWangs/go-cache/internal/consistenthash"
+	"github.com/AdrianWangs/go-cache/internal/peers"
+	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
+)
+
+// ringReplicas 哈希环的虚拟节点倍数，与 HTTPPool 一致
+const ringReplicas = 50
+
+// ErrPeerDown 访问被 SetDown 标记为不可用的节点时返回的错误
+var ErrPeerDown = errors.New("cachetest: peer is down")
+
+// Ring 进程内的 N 个"节点"，每个节点有独立的 cache.Registry，
+// 节点之间通过一致性哈希环直接调用对方的缓存组，模拟 HTTPPool 的节点间读取。
+// 节点标识为 node-0 ... node-(N-1)，key 的归属是确定的
+type Ring struct {
+	ring  *consistenthash.Map
+	nodes []*RingNode
+	byID  map[string]*RingNode
+}
+
+// RingNode Ring 中的一个节点，实现 peers.PeerPicker、peers.OwnerPicker 和 peers.PeerGetter
+type RingNode struct {
+	ID       string          // 节点标识
+	Registry *cache.Registry // 节点上的缓存组
+
+	ring    *Ring
+	down    atomic.Bool
+	fetches atomic.Int64 // 作为对等节点处理的读取次数
+
+	mu     sync.Mutex
+	groups map[string]*cache.Group
+}
+
+var (
+	_ peers.PeerPicker  = (*RingNode)(nil)
+	_ peers.OwnerPicker = (*RingNode)(nil)
+	_ peers.PeerGetter  = (*RingNode)(nil)
+)
+
+// NewRing 创建包含 n 个节点的 Ring
+func NewRing(n int) *Ring {
+	r := &Ring{
+		ring: consistenthash.New(ringReplicas, nil),
+		byID: make(map[string]*RingNode, n),
+	}
+	for i := 0; i < n; i++ {
+		node := &RingNode{
+			ID:       fmt.Sprintf("node-%d", i),
+			Registry: cache.NewRegistry(),
+			ring:     r,
+			groups:   make(map[string]*cache.Group),
+		}
+		r.nodes = append(r.nodes, node)
+		r.byID[node.ID] = node
+		r.ring.Add(node.ID)
+	}
+	return r
+}
+
+// NewGroup 在每个节点上创建名为 name 的缓存组并注册对等节点，按节点顺序返回。
+// 所有节点共用同一个 getter，与真实集群中各节点访问同一个数据源相同
+func (r *Ring) NewGroup(name string, cacheBytes int64, getter cache.Getter, ttl time.Duration, opts ...cache.GroupOption) []*cache.Group {
+	groups := make([]*cache.Group, 0, len(r.nodes))
+	for _, node := range r.nodes {
+		groupOpts := append([]cache.GroupOption{cache.WithRegistry(node.Registry)}, opts...)
+		g := cache.NewGroup(name, cacheBytes, getter, ttl, groupOpts...)
+		g.RegisterPeers(node)
+
+		node.mu.Lock()
+		node.groups[name] = g
+		node.mu.Unlock()
+		groups = append(groups, g)
+	}
+	return groups
+}
+
+// Close 关闭所有节点上的缓存组，停止它们的后台 goroutine，测试结束时调用
+func (r *Ring) Close() {
+	for _, node := range r.nodes {
+		node.Registry.Close()
+	}
+}
+
+// Nodes 返回所有节点
+func (r *Ring) Nodes() []*RingNode {
+	return append([]*RingNode(nil), r.nodes...)
+}
+
+// Node 返回第 i 个节点
+func (r *Ring) Node(i int) *RingNode {
+	return r.nodes[i]
+}
+
+// Owner 返回 key 的归属节点
+func (r *Ring) Owner(key string) *RingNode {
+	return r.byID[r.ring.Get(key)]
+}
+
+// Group 返回节点上的缓存组，不存在时返回 nil
+func (n *RingNode) Group(name string) *cache.Group {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.groups[name]
+}
+
+// SetDown 标记节点是否不可用。不可用的节点作为对等节点被访问时返回 ErrPeerDown，
+// 请求方随之回退到本地数据源
+func (n *RingNode) SetDown(down bool) {
+	n.down.Store(down)
+}
+
+// Fetches 返回节点作为对等节点处理的读取次数
+func (n *RingNode) Fetches() int64 {
+	return n.fetches.Load()
+}
+
+// PickPeer 实现 peers.PeerPicker：key 归属其他节点时返回该节点，归属自己时返回 false
+func (n *RingNode) PickPeer(key string) (peers.PeerGetter, bool) {
+	owner := n.ring.Owner(key)
+	if owner == nil || owner == n {
+		return nil, false
+	}
+	return owner, true
+}
+
+// PickOwner 实现 peers.OwnerPicker，区分 key 归属自己与环上没有节点
+func (n *RingNode) PickOwner(key string) peers.PickResult {
+	switch owner := n.ring.Owner(key); owner {
+	case nil:
+		return peers.PickResult{State: peers.PickNoPeer}
+	case n:
+		return peers.PickResult{State: peers.PickSelf}
+	default:
+		return peers.PickResult{State: peers.PickRemote, Peer: owner}
+	}
+}
+
+// Get 实现 peers.PeerGetter，读取本节点上的缓存组
+func (n *RingNode) Get(group, key string) ([]byte, error) {
+	if n.down.Load() {
+		return nil, ErrPeerDown
+	}
+	n.fetches.Add(1)
+
+	g := n.Registry.Get(group)
+	if g == nil {
+		return nil, cache.ErrNoSuchGroup
+	}
+	view, err := g.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	return view.ByteSlice(), nil
+}
+
+// GetByProto 实现 peers.PeerGetter
+func (n *RingNode) GetByProto(req *pb.Request, resp *pb.Response) error {
+	value, err := n.Get(req.GetGroup(), req.GetKey())
+	if err != nil {
+		return err
+	}
+	resp.Value = value
+	return nil
+}
@@ -0,0 +1,185 @@
+// Package ciphers 提供缓存值的静态加密，把 EncryptFor 和 DecryptFor 传给
+// cache.WithKeyedValueTransform 使用：缓存中、导出流和快照里保存的都是密文，值离开缓存前才解密，
+// 密文与所属的组和 key 绑定。
+//
+// 密文带有加密所用密钥的标识，因此密钥可以轮换：把新密钥放在密钥列表首位用于加密，
+// 旧密钥保留在后面只用于解密，等旧密文全部过期或被重写后再移除旧密钥
+package ciphers
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// 密文格式版本，位于密文首字节
+const (
+	formatVersion = 1 // Encrypt 生成，不绑定组和 key
+	boundVersion  = 2 // EncryptFor 生成，组名和 key 参与认证
+)
+
+var (
+	// ErrNoKeys 表示没有提供任何密钥
+	ErrNoKeys = errors.New("ciphers: no keys")
+	// ErrMalformed 表示密文格式不正确，例如被截断或不是本包加密的数据
+	ErrMalformed = errors.New("ciphers: malformed ciphertext")
+	// ErrDecrypt 表示没有密钥能解密密文：密钥已被移除，或密文被篡改
+	ErrDecrypt = errors.New("ciphers: cannot decrypt value")
+)
+
+// Key 一个带标识的 AES 密钥
+type Key struct {
+	ID     string // 密钥标识，写入每个密文，1 到 255 字节，不能包含 ':'、','、'#' 和空白字符
+	Secret []byte // 密钥，16、24 或 32 字节，分别对应 AES-128、AES-192、AES-256
+}
+
+// keyedAEAD 一个密钥及其 AEAD
+type keyedAEAD struct {
+	id   string
+	aead cipher.AEAD
+}
+
+// AESGCM 用 AES-GCM 加密缓存值。密文格式为：
+//
+//	版本(1 字节) | 密钥标识长度(1 字节) | 密钥标识 | nonce(12 字节) | 密文和认证标签
+//
+// 版本和密钥标识作为附加数据参与认证。EncryptFor 生成的密文还把组名和 key 加入附加数据
+// （不写入密文），被复制到其他 key 或组下的密文无法解密。AESGCM 可以并发使用
+type AESGCM struct {
+	keys []keyedAEAD // 第一个是加密用的主密钥，其余只用于解密
+	byID map[string]int
+}
+
+// NewAESGCM 用给定的密钥创建 AESGCM：第一个密钥加密，所有密钥都可用于解密。
+// 轮换时通常有两个密钥：新密钥在前，旧密钥在后
+func NewAESGCM(keys ...Key) (*AESGCM, error) {
+	if len(keys) == 0 {
+		return nil, ErrNoKeys
+	}
+	c := &AESGCM{byID: make(map[string]int, len(keys))}
+	for _, k := range keys {
+		if err := validateID(k.ID); err != nil {
+			return nil, err
+		}
+		if _, ok := c.byID[k.ID]; ok {
+			return nil, fmt.Errorf("ciphers: duplicate key id %q", k.ID)
+		}
+		block, err := aes.NewCipher(k.Secret)
+		if err != nil {
+			return nil, fmt.Errorf("ciphers: key %q: %w", k.ID, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("ciphers: key %q: %w", k.ID, err)
+		}
+		c.byID[k.ID] = len(c.keys)
+		c.keys = append(c.keys, keyedAEAD{id: k.ID, aead: aead})
+	}
+	return c, nil
+}
+
+// PrimaryID 返回加密所用密钥的标识
+func (c *AESGCM) PrimaryID() string {
+	return c.keys[0].id
+}
+
+// Encrypt 用主密钥加密 plaintext，每次使用新的随机 nonce。密文不绑定所属的 key，
+// 加密缓存值时使用 EncryptFor
+func (c *AESGCM) Encrypt(plaintext []byte) ([]byte, error) {
+	return c.seal(formatVersion, nil, plaintext)
+}
+
+// EncryptFor 加密组 group 中 key 的值 plaintext，组名和 key 作为附加数据参与认证，
+// 只有 DecryptFor 以相同的组名和 key 才能解密。签名与 cache.WithKeyedValueTransform 一致
+func (c *AESGCM) EncryptFor(group, key string, plaintext []byte) ([]byte, error) {
+	return c.seal(boundVersion, binding(group, key), plaintext)
+}
+
+// seal 用主密钥加密，附加数据为头部加上 bound
+func (c *AESGCM) seal(version byte, bound, plaintext []byte) ([]byte, error) {
+	k := c.keys[0]
+	header := makeHeader(version, k.id)
+	nonceSize := k.aead.NonceSize()
+	out := make([]byte, len(header)+nonceSize, len(header)+nonceSize+len(plaintext)+k.aead.Overhead())
+	copy(out, header)
+	nonce := out[len(header):]
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("ciphers: read nonce: %w", err)
+	}
+	return k.aead.Seal(out, nonce, plaintext, append(header, bound...)), nil
+}
+
+// Decrypt 解密 Encrypt 生成的密文。先用密文标识对应的密钥解密；标识未知或该密钥解密失败时
+// 依次尝试其余密钥，因此密钥改名后旧密文仍可读。都失败时返回 ErrDecrypt
+func (c *AESGCM) Decrypt(data []byte) ([]byte, error) {
+	return c.open(formatVersion, nil, data)
+}
+
+// DecryptFor 解密 EncryptFor 为组 group 中的 key 生成的密文。密文属于其他 key 或组时
+// 认证失败，返回 ErrDecrypt；Encrypt 生成的不绑定 key 的密文返回 ErrMalformed
+func (c *AESGCM) DecryptFor(group, key string, data []byte) ([]byte, error) {
+	return c.open(boundVersion, binding(group, key), data)
+}
+
+// open 解密 version 格式的密文，附加数据为头部加上 bound，密钥的选择见 Decrypt
+func (c *AESGCM) open(version byte, bound, data []byte) ([]byte, error) {
+	if len(data) < 2 || data[0] != version {
+		return nil, ErrMalformed
+	}
+	headerLen := 2 + int(data[1])
+	if len(data) < headerLen {
+		return nil, ErrMalformed
+	}
+	header, body := data[:headerLen], data[headerLen:]
+	ad := append(header[:len(header):len(header)], bound...)
+	// 所有密钥的 nonce 和认证标签长度相同，过短的密文不必逐个密钥尝试
+	if aead := c.keys[0].aead; len(body) < aead.NonceSize()+aead.Overhead() {
+		return nil, ErrMalformed
+	}
+
+	tagged, ok := c.byID[string(header[2:])]
+	if ok {
+		if plaintext, err := openBody(c.keys[tagged].aead, ad, body); err == nil {
+			return plaintext, nil
+		}
+	}
+	for i, k := range c.keys {
+		if ok && i == tagged {
+			continue
+		}
+		if plaintext, err := openBody(k.aead, ad, body); err == nil {
+			return plaintext, nil
+		}
+	}
+	return nil, fmt.Errorf("%w with key id %q", ErrDecrypt, header[2:])
+}
+
+// openBody 用 aead 解密 nonce 和密文组成的 body，ad 为附加数据
+func openBody(aead cipher.AEAD, ad, body []byte) ([]byte, error) {
+	nonceSize := aead.NonceSize()
+	if len(body) < nonceSize+aead.Overhead() {
+		return nil, ErrMalformed
+	}
+	return aead.Open(nil, body[:nonceSize], body[nonceSize:], ad)
+}
+
+// makeHeader 返回 version 格式下密钥 id 加密的密文的头部
+func makeHeader(version byte, id string) []byte {
+	header := make([]byte, 0, 2+len(id))
+	header = append(header, version, byte(len(id)))
+	return append(header, id...)
+}
+
+// binding 返回 EncryptFor 附加在头部之后的认证数据：带长度前缀的组名和 key，
+// 组名和 key 的不同切分不会得到相同的字节
+func binding(group, key string) []byte {
+	b := make([]byte, 0, 2*binary.MaxVarintLen64+len(group)+len(key))
+	b = binary.AppendUvarint(b, uint64(len(group)))
+	b = append(b, group...)
+	b = binary.AppendUvarint(b, uint64(len(key)))
+	return append(b, key...)
+}
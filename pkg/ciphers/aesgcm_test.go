@@ -0,0 +1,169 @@
+package ciphers
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func testKey(id string, b byte) Key {
+	return Key{ID: id, Secret: bytes.Repeat([]byte{b}, 32)}
+}
+
+func mustAESGCM(t *testing.T, keys ...Key) *AESGCM {
+	t.Helper()
+	c, err := NewAESGCM(keys...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// TestRoundTrip 加密后能解密回原值，相同的明文每次加密结果不同，密文带有密钥标识
+func TestRoundTrip(t *testing.T) {
+	c := mustAESGCM(t, testKey("k1", 1))
+	for _, plaintext := range [][]byte{nil, []byte("v"), bytes.Repeat([]byte("value"), 1000)} {
+		a, err := c.Encrypt(plaintext)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := c.Encrypt(plaintext)
+		if bytes.Equal(a, b) {
+			t.Fatal("相同明文的两次加密结果相同")
+		}
+		if len(a) != 2+len("k1")+12+len(plaintext)+16 || !bytes.Equal(a[:4], []byte{formatVersion, 2, 'k', '1'}) {
+			t.Fatalf("密文格式不正确: %x", a[:4])
+		}
+		got, err := c.Decrypt(a)
+		if err != nil || !bytes.Equal(got, plaintext) {
+			t.Fatalf("Decrypt = %q, %v", got, err)
+		}
+	}
+}
+
+// TestEncryptForBinding EncryptFor 的密文只能以相同的组名和 key 解密：移到其他 key 或组下，
+// 或者组名和 key 的切分不同，都解密失败；两种格式的密文不能混用
+func TestEncryptForBinding(t *testing.T) {
+	c := mustAESGCM(t, testKey("k1", 1))
+	sealed, err := c.EncryptFor("users", "alice", []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sealed[0] != boundVersion || len(sealed) != 2+len("k1")+12+len("secret")+16 {
+		t.Fatalf("密文格式不正确: %x", sealed[:4])
+	}
+	if bytes.Contains(sealed, []byte("alice")) || bytes.Contains(sealed, []byte("users")) {
+		t.Fatal("组名或 key 被写入了密文")
+	}
+	if got, err := c.DecryptFor("users", "alice", sealed); err != nil || string(got) != "secret" {
+		t.Fatalf("DecryptFor = %q, %v", got, err)
+	}
+
+	for _, tt := range []struct{ name, group, key string }{
+		{"其他 key", "users", "bob"},
+		{"其他组", "admins", "alice"},
+		{"切分不同", "usersa", "lice"},
+		{"空 key", "users", ""},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := c.DecryptFor(tt.group, tt.key, sealed); !errors.Is(err, ErrDecrypt) {
+				t.Fatalf("DecryptFor(%q, %q) = %q, %v; want ErrDecrypt", tt.group, tt.key, got, err)
+			}
+		})
+	}
+
+	if _, err := c.Decrypt(sealed); !errors.Is(err, ErrMalformed) {
+		t.Fatalf("Decrypt 绑定的密文 = %v, want ErrMalformed", err)
+	}
+	unbound, _ := c.Encrypt([]byte("secret"))
+	if _, err := c.DecryptFor("users", "alice", unbound); !errors.Is(err, ErrMalformed) {
+		t.Fatalf("DecryptFor 不绑定的密文 = %v, want ErrMalformed", err)
+	}
+
+	// 轮换后旧密钥加密的绑定密文仍可解密，但同样不能移到其他 key 下
+	rotated := mustAESGCM(t, testKey("k2", 2), testKey("k1", 1))
+	if got, err := rotated.DecryptFor("users", "alice", sealed); err != nil || string(got) != "secret" {
+		t.Fatalf("轮换后 DecryptFor = %q, %v", got, err)
+	}
+	if _, err := rotated.DecryptFor("users", "bob", sealed); !errors.Is(err, ErrDecrypt) {
+		t.Fatalf("轮换后移到其他 key 的密文 = %v, want ErrDecrypt", err)
+	}
+}
+
+// TestRotation 新密钥在前时用新密钥加密，旧密文仍可解密；移除旧密钥后旧密文无法解密
+func TestRotation(t *testing.T) {
+	old := mustAESGCM(t, testKey("k1", 1))
+	rotated := mustAESGCM(t, testKey("k2", 2), testKey("k1", 1))
+	retired := mustAESGCM(t, testKey("k2", 2))
+
+	oldCiphertext, _ := old.Encrypt([]byte("old"))
+	newCiphertext, _ := rotated.Encrypt([]byte("new"))
+	if rotated.PrimaryID() != "k2" || newCiphertext[2] != 'k' || newCiphertext[3] != '2' {
+		t.Fatalf("轮换后未使用新密钥加密: %q", rotated.PrimaryID())
+	}
+	if got, err := rotated.Decrypt(oldCiphertext); err != nil || string(got) != "old" {
+		t.Fatalf("轮换期间解密旧密文 = %q, %v", got, err)
+	}
+	if got, err := retired.Decrypt(newCiphertext); err != nil || string(got) != "new" {
+		t.Fatalf("解密新密文 = %q, %v", got, err)
+	}
+	if _, err := retired.Decrypt(oldCiphertext); !errors.Is(err, ErrDecrypt) {
+		t.Fatalf("移除旧密钥后解密旧密文 = %v, want ErrDecrypt", err)
+	}
+
+	// 密钥改名后按标识找不到密钥，仍会依次尝试
+	renamed := mustAESGCM(t, testKey("k2", 2), testKey("k1-renamed", 1))
+	if got, err := renamed.Decrypt(oldCiphertext); err != nil || string(got) != "old" {
+		t.Fatalf("密钥改名后解密 = %q, %v", got, err)
+	}
+}
+
+// TestDecryptErrors 格式不正确和被篡改的密文返回对应的错误
+func TestDecryptErrors(t *testing.T) {
+	c := mustAESGCM(t, testKey("k1", 1))
+	valid, _ := c.Encrypt([]byte("value"))
+	tampered := append([]byte(nil), valid...)
+	tampered[len(tampered)-1] ^= 1
+	retagged := append([]byte(nil), valid...)
+	retagged[3] = '2' // 标识参与认证
+
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"空", nil, ErrMalformed},
+		{"未知版本", append([]byte{9}, valid[1:]...), ErrMalformed},
+		{"头部被截断", valid[:3], ErrMalformed},
+		{"nonce 被截断", valid[:10], ErrMalformed},
+		{"密文被篡改", tampered, ErrDecrypt},
+		{"标识被篡改", retagged, ErrDecrypt},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := c.Decrypt(tt.data); !errors.Is(err, tt.want) {
+				t.Fatalf("Decrypt = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewAESGCMRejectsBadKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		keys []Key
+	}{
+		{"没有密钥", nil},
+		{"密钥长度不正确", []Key{{ID: "k1", Secret: []byte("short")}}},
+		{"标识为空", []Key{testKey("", 1)}},
+		{"标识包含冒号", []Key{testKey("k:1", 1)}},
+		{"标识重复", []Key{testKey("k1", 1), testKey("k1", 2)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewAESGCM(tt.keys...); err == nil {
+				t.Fatal("创建成功, want 错误")
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package ciphers
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// EnvKeys 默认保存值加密密钥的环境变量
+const EnvKeys = "GOCACHE_VALUE_KEYS"
+
+// ParseKeys 解析密钥列表。每个密钥写作 "标识:base64 编码的密钥"，密钥之间用逗号或换行分隔，
+// 空行和以 '#' 开头的行被忽略。第一个密钥用于加密，例如：
+//
+//	k2:q5ZcJ0m0bVd0...,k1:Xb3u9Z1f2Qw...
+func ParseKeys(s string) ([]Key, error) {
+	var keys []Key
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		for _, field := range strings.Split(line, ",") {
+			field = strings.TrimSpace(field)
+			if field == "" {
+				continue
+			}
+			id, secret, ok := strings.Cut(field, ":")
+			if !ok {
+				return nil, fmt.Errorf("ciphers: key %q: want id:base64-secret", field)
+			}
+			id = strings.TrimSpace(id)
+			if err := validateID(id); err != nil {
+				return nil, err
+			}
+			raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(secret))
+			if err != nil {
+				return nil, fmt.Errorf("ciphers: key %q: decode secret: %w", id, err)
+			}
+			keys = append(keys, Key{ID: id, Secret: raw})
+		}
+	}
+	if len(keys) == 0 {
+		return nil, ErrNoKeys
+	}
+	return keys, nil
+}
+
+// KeysFromEnv 从环境变量 name 读取密钥列表，格式见 ParseKeys；name 为空时使用 EnvKeys。
+// 环境变量未设置时返回 ErrNoKeys
+func KeysFromEnv(name string) ([]Key, error) {
+	if name == "" {
+		name = EnvKeys
+	}
+	return ParseKeys(os.Getenv(name))
+}
+
+// KeysFromFile 从文件读取密钥列表，格式见 ParseKeys。密钥文件应只对运行节点的用户可读
+func KeysFromFile(path string) ([]Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("ciphers: read key file: %w", err)
+	}
+	keys, err := ParseKeys(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w (file %s)", err, path)
+	}
+	return keys, nil
+}
+
+// Load 按文件、环境变量的顺序加载密钥并创建 AESGCM：path 非空时从文件读取，否则读取
+// 环境变量 EnvKeys
+func Load(path string) (*AESGCM, error) {
+	var keys []Key
+	var err error
+	if path != "" {
+		keys, err = KeysFromFile(path)
+	} else {
+		keys, err = KeysFromEnv(EnvKeys)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return NewAESGCM(keys...)
+}
+
+// validateID 检查密钥标识能写入密文头部和密钥列表
+func validateID(id string) error {
+	if id == "" || len(id) > 255 {
+		return fmt.Errorf("ciphers: key id %q must be 1 to 255 bytes", id)
+	}
+	if strings.ContainsAny(id, ":,#") || strings.IndexFunc(id, unicode.IsSpace) >= 0 {
+		return fmt.Errorf("ciphers: key id %q must not contain ':', ',', '#' or spaces", id)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package ciphers
+
+import (
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var (
+	secret1 = base64.StdEncoding.EncodeToString([]byte(strings.Repeat("a", 32)))
+	secret2 = base64.StdEncoding.EncodeToString([]byte(strings.Repeat("b", 16)))
+)
+
+func TestParseKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		ids     []string
+		wantErr bool
+	}{
+		{"逗号分隔", "k2:" + secret2 + ",k1:" + secret1, []string{"k2", "k1"}, false},
+		{"换行分隔和注释", "# 新密钥\nk2: " + secret2 + "\n\n  k1:" + secret1 + "\n", []string{"k2", "k1"}, false},
+		{"空", " \n# 只有注释\n", nil, true},
+		{"缺少标识", secret1, nil, true},
+		{"密钥不是 base64", "k1:not-base64!", nil, true},
+		{"标识包含空白", "k 1:" + secret1, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, err := ParseKeys(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(keys) != len(tt.ids) {
+				t.Fatalf("解析出 %d 个密钥, want %d", len(keys), len(tt.ids))
+			}
+			for i, id := range tt.ids {
+				if keys[i].ID != id {
+					t.Fatalf("第 %d 个密钥标识 = %q, want %q", i, keys[i].ID, id)
+				}
+			}
+		})
+	}
+	if keys, _ := ParseKeys("k1:" + secret1); len(keys[0].Secret) != 32 {
+		t.Fatalf("密钥长度 = %d, want 32", len(keys[0].Secret))
+	}
+}
+
+// TestLoad 指定文件时从文件加载，否则从环境变量加载
+func TestLoad(t *testing.T) {
+	t.Setenv(EnvKeys, "env:"+secret1)
+	c, err := Load("")
+	if err != nil || c.PrimaryID() != "env" {
+		t.Fatalf("从环境变量加载: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "keys")
+	if err := os.WriteFile(path, []byte("file2:"+secret2+"\nfile1:"+secret1+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	c, err = Load(path)
+	if err != nil || c.PrimaryID() != "file2" {
+		t.Fatalf("从文件加载: %v", err)
+	}
+
+	if _, err := Load(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Fatal("密钥文件不存在时加载成功")
+	}
+	t.Setenv(EnvKeys, "")
+	if _, err := Load(""); !errors.Is(err, ErrNoKeys) {
+		t.Fatalf("环境变量为空: %v, want ErrNoKeys", err)
+	}
+}
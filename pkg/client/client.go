@@ -0,0 +1,424 @@
+// Package client 是 go-cache 的 Go SDK：Client 通过 API 服务器访问集群，
+// NodeClient 通过 gRPC 或 HTTP 直接访问单个缓存节点，用于排查某个节点上的数据
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/api/handlers"
+	"github.com/AdrianWangs/go-cache/internal/cache"
+	"github.com/AdrianWangs/go-cache/internal/discovery"
+	"github.com/AdrianWangs/go-cache/internal/peers"
+	"github.com/AdrianWangs/go-cache/pkg/cacheerrors"
+	"github.com/AdrianWangs/go-cache/pkg/codec"
+	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
+)
+
+const (
+	defaultTimeout = 5 * time.Second // 默认请求超时
+	maxErrorBody   = 4 << 10         // 错误响应最多读取的字节数
+)
+
+var (
+	// ErrNotFound 键不存在，与 cacheerrors.ErrNotFound 是同一个错误
+	ErrNotFound = cacheerrors.ErrNotFound
+	// ErrGroupNotFound 缓存组不存在，与 cacheerrors.ErrNoSuchGroup 是同一个错误
+	ErrGroupNotFound = cacheerrors.ErrNoSuchGroup
+	// ErrUnsupported 当前连接方式不支持该操作
+	ErrUnsupported = errors.New("gocache: operation not supported")
+)
+
+// StatusError 服务端返回了非预期的状态码
+type StatusError struct {
+	Code    int    // HTTP 状态码
+	Message string // 响应内容
+}
+
+// Error 实现 error
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("gocache: %d %s: %s", e.Code, http.StatusText(e.Code), e.Message)
+}
+
+// Result 读取到的值及其元数据，由 GetWithMeta 返回
+type Result struct {
+	Value     []byte
+	ExpiresAt time.Time // 绝对过期时间，永不过期或未知时为零值
+	Version   uint64    // 节点上的写入版本，未知时为 0
+	Source    string    // 值的来源: cache、loader 或 peer，未知时为空
+	Node      string    // 产生该值的缓存节点标识，未知或节点不公开标识时为空
+	RoutedBy  string    // 处理请求的 API 服务器标识，直接访问节点或 API 服务器不公开标识时为空
+}
+
+// newResult 从节点协议的响应创建 Result
+func newResult(resp *pb.Response) *Result {
+	r := &Result{Value: resp.GetValue(), Version: resp.GetVersion(), Source: resp.GetSource(), Node: resp.GetNode()}
+	if resp.ExpiresAt != nil {
+		r.ExpiresAt = time.Unix(0, resp.GetExpiresAt())
+	}
+	return r
+}
+
+// Option 配置 Client 和 NodeClient
+type Option func(*options)
+
+// options 客户端的公共配置
+type options struct {
+	timeout    time.Duration
+	token      string
+	httpClient *http.Client
+	basePath   string
+
+	onRingChange func(generation uint64)
+}
+
+// newOptions 使用默认值创建配置并应用选项
+func newOptions(opts ...Option) options {
+	o := options{timeout: defaultTimeout, basePath: "/_gocache/"}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.httpClient == nil {
+		o.httpClient = &http.Client{}
+	}
+	return o
+}
+
+// WithTimeout 设置单次请求的超时，默认 5s
+func WithTimeout(d time.Duration) Option {
+	return func(o *options) {
+		if d > 0 {
+			o.timeout = d
+		}
+	}
+}
+
+// WithToken 设置管理接口的访问令牌，Set 通过管理接口写入，需要令牌
+func WithToken(token string) Option {
+	return func(o *options) {
+		o.token = token
+	}
+}
+
+// WithHTTPClient 使用自定义的 http.Client
+func WithHTTPClient(c *http.Client) Option {
+	return func(o *options) {
+		o.httpClient = c
+	}
+}
+
+// WithBasePath 设置缓存节点的内部通信路径，只影响 HTTP 协议的 NodeClient，默认 /_gocache/
+func WithBasePath(basePath string) Option {
+	return func(o *options) {
+		if basePath != "" {
+			o.basePath = basePath
+		}
+	}
+}
+
+// WithRingChange 设置 Client 观察到 API 服务器哈希环代数增大时调用的函数，包括第一次观察到代数时。
+// API 服务器在每个响应的 X-GoCache-Ring-Generation 头中返回其路由所用哈希环的代数，
+// 代数增大说明节点成员发生了变化，持有节点列表的调用方可以在 fn 中调用 Nodes 刷新。
+// fn 在发出请求的 goroutine 中同步调用，不应阻塞；只影响 Client
+func WithRingChange(fn func(generation uint64)) Option {
+	return func(o *options) {
+		o.onRingChange = fn
+	}
+}
+
+// Client 通过 API 服务器访问集群
+type Client struct {
+	baseURL string
+	opts    options
+
+	ringGeneration atomic.Uint64 // 观察到的最大哈希环代数，见 RingGeneration
+
+	nodesMu   sync.Mutex
+	nodes     []discovery.NodeInfo // 上次 Nodes 得到的节点列表，下一次只请求它之后的变化
+	nodesETag string               // 与 nodes 对应的 ETag
+}
+
+// New 创建访问 addr 处 API 服务器的 Client，addr 可以是 host:port 或完整的 URL
+func New(addr string, opts ...Option) *Client {
+	if !strings.Contains(addr, "://") {
+		addr = "http://" + addr
+	}
+	return &Client{baseURL: strings.TrimSuffix(addr, "/"), opts: newOptions(opts...)}
+}
+
+// cacheURL 返回 /api/cache/{group}/{key} 的完整 URL
+func (c *Client) cacheURL(group, key string) string {
+	return fmt.Sprintf("%s/api/cache/%s/%s", c.baseURL, peers.EscapePathSegment(group), peers.EscapePathSegment(key))
+}
+
+// do 发送请求，返回 2xx 响应的内容；404 按响应内容映射为 ErrNotFound 或 ErrGroupNotFound
+func (c *Client) do(ctx context.Context, method, u string, body io.Reader, contentType string) ([]byte, error) {
+	data, _, err := c.send(ctx, method, u, body, contentType)
+	return data, err
+}
+
+// send 与 do 相同，同时返回响应头
+func (c *Client) send(ctx context.Context, method, u string, body io.Reader, contentType string) ([]byte, http.Header, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.opts.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return nil, nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.opts.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.opts.token)
+	}
+
+	resp, err := c.opts.httpClient.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	c.observeRing(resp.Header)
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return nil, nil, statusError(resp.StatusCode, msg)
+	}
+	data, err := io.ReadAll(resp.Body)
+	return data, resp.Header, err
+}
+
+// RingGeneration 返回从 API 服务器响应中观察到的最大哈希环代数，尚未观察到时为 0
+func (c *Client) RingGeneration() uint64 {
+	return c.ringGeneration.Load()
+}
+
+// observeRing 记录响应头中的哈希环代数，大于已观察到的代数时调用 WithRingChange 设置的函数。
+// 旧版本 API 服务器不返回该头，代数保持为 0
+func (c *Client) observeRing(h http.Header) {
+	generation, err := strconv.ParseUint(h.Get(peers.HeaderRingGeneration), 10, 64)
+	if err != nil {
+		return
+	}
+	for {
+		seen := c.ringGeneration.Load()
+		if generation <= seen {
+			return
+		}
+		if c.ringGeneration.CompareAndSwap(seen, generation) {
+			break
+		}
+	}
+	if c.opts.onRingChange != nil {
+		c.opts.onRingChange(generation)
+	}
+}
+
+// statusError 将非 2xx 响应转换为错误
+func statusError(code int, body []byte) error {
+	if code == http.StatusNotFound {
+		var e handlers.ErrorResponse
+		if json.Unmarshal(body, &e) == nil && e.Error == handlers.ErrorCodeGroupNotFound {
+			return ErrGroupNotFound
+		}
+		return ErrNotFound
+	}
+	return &StatusError{Code: code, Message: strings.TrimSpace(string(body))}
+}
+
+// Get 读取 group 中 key 的值，键不存在时返回 ErrNotFound
+func (c *Client) Get(ctx context.Context, group, key string) ([]byte, error) {
+	return c.do(ctx, http.MethodGet, c.cacheURL(group, key), nil, "")
+}
+
+// GetWithMeta 与 Get 相同，同时返回节点提供的过期时间、版本、来源，以及产生该值的节点和处理请求的 API 服务器，
+// 用于排查数据由哪个节点提供
+func (c *Client) GetWithMeta(ctx context.Context, group, key string) (*Result, error) {
+	value, header, err := c.send(ctx, http.MethodGet, c.cacheURL(group, key), nil, "")
+	if err != nil {
+		return nil, err
+	}
+	resp := &pb.Response{Value: value}
+	if err := peers.ReadMetaHeaders(header, resp); err != nil {
+		return nil, fmt.Errorf("gocache: %w", err)
+	}
+	result := newResult(resp)
+	result.RoutedBy = header.Get(peers.HeaderRoutedBy)
+	return result, nil
+}
+
+// GetInto 读取 group 中 key 的值并用 c 解码到 dst。读取的错误原样返回，键不存在时为 ErrNotFound，
+// 不会把错误响应当作值解码；值无法解码（例如由另一种 Codec 写入，或 dst 不是非 nil 的指针）时返回匹配 codec.ErrDecode 的错误
+func (c *Client) GetInto(ctx context.Context, group, key string, dst any, cd codec.Codec) error {
+	if err := codec.CheckDestination(dst); err != nil {
+		return err
+	}
+	value, err := c.Get(ctx, group, key)
+	if err != nil {
+		return err
+	}
+	return codec.Decode(cd, value, dst)
+}
+
+// SetFrom 用 c 编码 src 后像 Set 一样写入，编码失败时返回匹配 codec.ErrEncode 的错误，不发出请求
+func (c *Client) SetFrom(ctx context.Context, group, key string, src any, cd codec.Codec, ttl time.Duration) error {
+	value, err := codec.Encode(cd, src)
+	if err != nil {
+		return err
+	}
+	return c.Set(ctx, group, key, value, ttl)
+}
+
+// Delete 从 key 的归属节点删除 key，键不存在时返回 ErrNotFound。
+// API 服务器开启删除重试时，归属节点暂时不可达的删除进入重试队列，同样返回 nil
+func (c *Client) Delete(ctx context.Context, group, key string) error {
+	_, err := c.do(ctx, http.MethodDelete, c.cacheURL(group, key), nil, "")
+	return err
+}
+
+// Set 写入 key 的值，ttl 为 0 表示不设置过期时间。
+// API 服务器没有单独的写接口，Set 通过管理接口的导入写入归属节点，需要 WithToken
+func (c *Client) Set(ctx context.Context, group, key string, value []byte, ttl time.Duration) error {
+	var buf bytes.Buffer
+	enc := cache.NewEntryEncoder(&buf)
+	if err := enc.Encode(newEntry(key, value, ttl)); err != nil {
+		return err
+	}
+	if err := enc.Flush(); err != nil {
+		return err
+	}
+
+	u := fmt.Sprintf("%s/api/admin/groups/%s/import", c.baseURL, url.PathEscape(group))
+	body, err := c.do(ctx, http.MethodPost, u, &buf, "application/x-ndjson")
+	if err != nil {
+		return err
+	}
+	var result handlers.ClusterImportResult
+	if err := json.Unmarshal(body, &result); err != nil {
+		return fmt.Errorf("gocache: 解析导入结果失败: %w", err)
+	}
+	return importError(result.Total)
+}
+
+// newEntry 创建导入条目
+func newEntry(key string, value []byte, ttl time.Duration) cache.ExportEntry {
+	e := cache.ExportEntry{Key: key, Value: value}
+	if ttl > 0 {
+		e.ExpiresAt = time.Now().Add(ttl).UnixNano()
+	}
+	return e
+}
+
+// importError 检查单个条目的导入结果
+func importError(result cache.ImportResult) error {
+	switch {
+	case result.Imported > 0:
+		return nil
+	case result.Expired > 0:
+		return fmt.Errorf("gocache: 条目已过期，未写入")
+	default:
+		return fmt.Errorf("gocache: 条目未写入（超出缓存容量或没有可用节点）")
+	}
+}
+
+// Stats 返回集群中各缓存组的统计，group 不为空时只返回该组
+func (c *Client) Stats(ctx context.Context, group string) (*handlers.GroupsResponse, error) {
+	u := c.baseURL + "/api/groups"
+	if group != "" {
+		u += "?group=" + url.QueryEscape(group)
+	}
+	body, err := c.do(ctx, http.MethodGet, u, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	var resp handlers.GroupsResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("gocache: 解析统计结果失败: %w", err)
+	}
+	return &resp, nil
+}
+
+// Nodes 返回 API 服务器当前使用的节点列表。Client 记住上次的结果，之后的调用带上其 ETag
+// 请求增量：列表未变时服务端返回 304，变化时只返回变化的节点（见 handlers.NodesDelta）
+func (c *Client) Nodes(ctx context.Context) ([]discovery.NodeInfo, error) {
+	c.nodesMu.Lock()
+	defer c.nodesMu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, c.opts.timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/api/nodes?delta=1", nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.nodesETag != "" {
+		req.Header.Set("If-None-Match", c.nodesETag)
+	}
+	if c.opts.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.opts.token)
+	}
+
+	resp, err := c.opts.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	c.observeRing(resp.Header)
+
+	if resp.StatusCode == http.StatusNotModified {
+		return append([]discovery.NodeInfo(nil), c.nodes...), nil
+	}
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return nil, statusError(resp.StatusCode, msg)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var nodes []discovery.NodeInfo
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), handlers.MediaTypeNodesDelta) {
+		var delta handlers.NodesDelta
+		if err := json.Unmarshal(body, &delta); err != nil {
+			return nil, fmt.Errorf("gocache: 解析节点列表增量失败: %w", err)
+		}
+		nodes = discovery.ApplyNodesDiff(c.nodes, delta.Added, delta.Removed)
+	} else {
+		var full handlers.NodeResponse
+		if err := json.Unmarshal(body, &full); err != nil {
+			return nil, fmt.Errorf("gocache: 解析节点列表失败: %w", err)
+		}
+		nodes = full.Details
+	}
+	c.nodes, c.nodesETag = nodes, resp.Header.Get("ETag")
+	return append([]discovery.NodeInfo(nil), nodes...), nil
+}
+
+// BatchGet 一次读取多个 key，部分 key 失败不影响其余结果
+func (c *Client) BatchGet(ctx context.Context, group string, keys []string) (*handlers.BatchGetResponse, error) {
+	reqBody, err := json.Marshal(handlers.BatchGetRequest{Keys: keys})
+	if err != nil {
+		return nil, err
+	}
+	u := fmt.Sprintf("%s/api/batch/%s", c.baseURL, url.PathEscape(group))
+	body, err := c.do(ctx, http.MethodPost, u, bytes.NewReader(reqBody), "application/json")
+	if err != nil {
+		return nil, err
+	}
+	var resp handlers.BatchGetResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("gocache: 解析批量读取结果失败: %w", err)
+	}
+	return &resp, nil
+}
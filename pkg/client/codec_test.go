@@ -0,0 +1,112 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/api/handlers"
+	"github.com/AdrianWangs/go-cache/internal/cache"
+	"github.com/AdrianWangs/go-cache/pkg/codec"
+)
+
+// valuesAPI 提供 /api/cache 读取和 /api/admin/groups/{group}/import 写入的假 API 服务器，值保存在内存中
+type valuesAPI struct {
+	mu       sync.Mutex
+	values   map[string][]byte
+	requests atomic.Int64
+}
+
+func newValuesAPI(t *testing.T) (*valuesAPI, *Client) {
+	api := &valuesAPI{values: make(map[string][]byte)}
+	srv := httptest.NewServer(api)
+	t.Cleanup(srv.Close)
+	return api, New(srv.URL, WithToken("secret"))
+}
+
+func (a *valuesAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.requests.Add(1)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if key, ok := strings.CutPrefix(r.URL.Path, "/api/cache/users/"); ok && r.Method == http.MethodGet {
+		value, ok := a.values[key]
+		if !ok {
+			http.Error(w, "Key not found: "+key, http.StatusNotFound)
+			return
+		}
+		w.Write(value)
+		return
+	}
+	if r.URL.Path == "/api/admin/groups/users/import" && r.Method == http.MethodPost {
+		dec := cache.NewEntryDecoder(r.Body)
+		var result handlers.ClusterImportResult
+		for {
+			e, err := dec.Next()
+			if err != nil {
+				break
+			}
+			a.values[e.Key] = e.Value
+			result.Total.Imported++
+		}
+		json.NewEncoder(w).Encode(result)
+		return
+	}
+	http.NotFound(w, r)
+}
+
+type sdkUser struct {
+	Name string `json:"name"`
+	Age  int    `json:"age"`
+}
+
+func TestClientGetIntoSetFrom(t *testing.T) {
+	api, c := newValuesAPI(t)
+	ctx := context.Background()
+
+	if err := c.SetFrom(ctx, "users", "u:42", sdkUser{Name: "Tom", Age: 30}, codec.JSON, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if got := string(api.values["u:42"]); got != `{"name":"Tom","age":30}` {
+		t.Fatalf("写入的值 = %s", got)
+	}
+	var u sdkUser
+	if err := c.GetInto(ctx, "users", "u:42", &u, codec.JSON); err != nil || u != (sdkUser{Name: "Tom", Age: 30}) {
+		t.Fatalf("GetInto = %+v, %v", u, err)
+	}
+
+	// 键不存在时返回 ErrNotFound，不会把错误响应当作值解码
+	if err := c.GetInto(ctx, "users", "u:43", &u, codec.JSON); !errors.Is(err, ErrNotFound) || errors.Is(err, codec.ErrDecode) {
+		t.Fatalf("读取不存在的 key = %v, want ErrNotFound", err)
+	}
+
+	// 另一种 Codec 写入的值解码失败
+	api.values["raw"] = []byte{0x0a, 0x03, 'a', 'b', 'c'}
+	if err := c.GetInto(ctx, "users", "raw", &u, codec.JSON); !errors.Is(err, codec.ErrDecode) {
+		t.Fatalf("读取另一种 Codec 写入的值 = %v, want ErrDecode", err)
+	}
+}
+
+// TestClientCodecErrorsSendNothing 目标无效或编码失败时不发出请求
+func TestClientCodecErrorsSendNothing(t *testing.T) {
+	api, c := newValuesAPI(t)
+	ctx := context.Background()
+	if err := c.GetInto(ctx, "users", "u:42", nil, codec.JSON); !errors.Is(err, codec.ErrDecode) {
+		t.Fatalf("GetInto 到 nil = %v, want ErrDecode", err)
+	}
+	if err := c.GetInto(ctx, "users", "u:42", sdkUser{}, codec.JSON); !errors.Is(err, codec.ErrDecode) {
+		t.Fatalf("GetInto 到非指针 = %v, want ErrDecode", err)
+	}
+	if err := c.SetFrom(ctx, "users", "u:42", make(chan int), codec.JSON, 0); !errors.Is(err, codec.ErrEncode) {
+		t.Fatalf("SetFrom 无法编码的值 = %v, want ErrEncode", err)
+	}
+	if n := api.requests.Load(); n != 0 {
+		t.Fatalf("发出了 %d 个请求", n)
+	}
+}
@@ -0,0 +1,138 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/api/handlers"
+	"github.com/AdrianWangs/go-cache/internal/cache"
+	"github.com/AdrianWangs/go-cache/pkg/cacheerrors"
+	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
+)
+
+// Protocol 直接访问节点时使用的协议
+type Protocol string
+
+const (
+	// ProtocolGRPC 通过节点的 gRPC 地址访问
+	ProtocolGRPC Protocol = "grpc"
+	// ProtocolHTTP 通过节点 HTTP 地址上的 Protobuf over HTTP 接口访问
+	ProtocolHTTP Protocol = "http"
+)
+
+// NodeClient 直接访问单个缓存节点，不经过 API 服务器的路由：
+// 读取不属于该节点的 key 时，节点会转发给归属节点或回源
+type NodeClient struct {
+	protocol Protocol
+	getter   handlers.NodeGetter
+	grpc     *handlers.GRPCGetter // gRPC 协议时与 getter 相同，用于导入和关闭连接
+}
+
+// NewNodeClient 创建访问 addr (host:port) 处节点的 NodeClient
+func NewNodeClient(addr string, protocol Protocol, opts ...Option) (*NodeClient, error) {
+	o := newOptions(opts...)
+	getterOpts := []handlers.GetterOption{handlers.WithRequestTimeout(o.timeout)}
+
+	switch protocol {
+	case ProtocolGRPC, "":
+		g := handlers.NewGRPCGetter(addr, getterOpts...)
+		return &NodeClient{protocol: ProtocolGRPC, getter: g, grpc: g}, nil
+	case ProtocolHTTP:
+		addr = strings.TrimSuffix(addr, "/")
+		if !strings.Contains(addr, "://") {
+			addr = "http://" + addr
+		}
+		return &NodeClient{protocol: ProtocolHTTP, getter: handlers.NewHTTPGetter(addr+o.basePath, getterOpts...)}, nil
+	default:
+		return nil, fmt.Errorf("gocache: 不支持的协议 %q，只能是 %s 或 %s", protocol, ProtocolGRPC, ProtocolHTTP)
+	}
+}
+
+// nodeError 将节点 getter 返回的错误映射为本包的错误；cacheerrors 中的其他错误原样返回
+func nodeError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case cacheerrors.IsGroupNotFoundError(err):
+		return ErrGroupNotFound
+	case cacheerrors.IsKeyNotFoundError(err):
+		return ErrNotFound
+	case cacheerrors.TypeOf(err) != cacheerrors.ErrTypeInternalError:
+		return err
+	}
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "未找到组") || strings.Contains(msg, "no such group") || strings.Contains(msg, "group not found"):
+		return ErrGroupNotFound
+	case strings.Contains(msg, "not found") || strings.Contains(msg, "未找到"):
+		return ErrNotFound
+	}
+	return err
+}
+
+// Get 读取 group 中 key 的值，键不存在时返回 ErrNotFound
+func (c *NodeClient) Get(ctx context.Context, group, key string) ([]byte, error) {
+	resp := &pb.Response{}
+	if err := c.getter.GetByProto(ctx, &pb.Request{Group: group, Key: key}, resp); err != nil {
+		return nil, nodeError(err)
+	}
+	return resp.Value, nil
+}
+
+// GetWithMeta 与 Get 相同，同时返回过期时间、版本、来源和产生该值的节点：
+// 该节点从归属节点取得的值，Node 为归属节点的标识
+func (c *NodeClient) GetWithMeta(ctx context.Context, group, key string) (*Result, error) {
+	resp := &pb.Response{}
+	if err := c.getter.GetByProto(ctx, &pb.Request{Group: group, Key: key}, resp); err != nil {
+		return nil, nodeError(err)
+	}
+	return newResult(resp), nil
+}
+
+// Delete 从该节点的缓存中删除 key
+func (c *NodeClient) Delete(ctx context.Context, group, key string) error {
+	return nodeError(c.getter.Delete(ctx, group, key))
+}
+
+// Set 通过导入接口将 key 写入该节点，只支持 gRPC 协议
+func (c *NodeClient) Set(ctx context.Context, group, key string, value []byte, ttl time.Duration) error {
+	if c.grpc == nil {
+		return fmt.Errorf("%w: HTTP 协议不支持写入，请使用 gRPC", ErrUnsupported)
+	}
+	stream, err := c.grpc.ImportGroup(ctx)
+	if err != nil {
+		return err
+	}
+	if err := stream.Send(&pb.ImportRequest{Group: group, Entry: newEntry(key, value, ttl).Proto()}); err != nil {
+		return nodeError(err)
+	}
+	resp, err := stream.CloseAndRecv()
+	if err != nil {
+		return nodeError(err)
+	}
+	return importError(cache.ImportResult{
+		Imported: resp.GetImported(),
+		Expired:  resp.GetExpired(),
+		Skipped:  resp.GetSkipped(),
+	})
+}
+
+// Stats 返回节点上各缓存组的统计
+func (c *NodeClient) Stats(ctx context.Context) (*pb.StatsResponse, error) {
+	return c.getter.Stats(ctx)
+}
+
+// Protocol 返回访问节点使用的协议
+func (c *NodeClient) Protocol() Protocol {
+	return c.protocol
+}
+
+// Close 关闭到节点的连接
+func (c *NodeClient) Close() error {
+	if c.grpc != nil {
+		return c.grpc.Close()
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/AdrianWangs/go-cache/api/handlers"
+	"github.com/AdrianWangs/go-cache/internal/discovery"
+)
+
+// nodesAPI 以真实的 NodeHandler 提供 /api/nodes，按类型记录响应数
+type nodesAPI struct {
+	*handlers.NodeHandler
+
+	mu        sync.Mutex
+	responses map[string]int // full、delta 或 304
+}
+
+func newNodesAPI(t *testing.T) (*nodesAPI, *Client) {
+	api := &nodesAPI{NodeHandler: handlers.NewNodeHandler(), responses: make(map[string]int)}
+	serve := api.NodesHandler(handlers.NodesSchemaCurrent)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := httptest.NewRecorder()
+		serve(rec, r)
+		kind := "full"
+		switch {
+		case rec.Code == http.StatusNotModified:
+			kind = "304"
+		case rec.Header().Get("Content-Type") == handlers.MediaTypeNodesDelta:
+			kind = "delta"
+		}
+		api.mu.Lock()
+		api.responses[kind]++
+		api.mu.Unlock()
+		for k, v := range rec.Header() {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(rec.Code)
+		w.Write(rec.Body.Bytes())
+	}))
+	t.Cleanup(srv.Close)
+	return api, New(srv.URL)
+}
+
+func (a *nodesAPI) counts() map[string]int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	counts := make(map[string]int, len(a.responses))
+	for k, v := range a.responses {
+		counts[k] = v
+	}
+	return counts
+}
+
+// membership 返回第 round 轮的节点列表：60 个节点中每轮有一部分离开或重新加入
+func membership(round int) []discovery.NodeInfo {
+	var nodes []discovery.NodeInfo
+	for i := 0; i < 60; i++ {
+		if (i*7+round)%5 == 0 {
+			continue
+		}
+		nodes = append(nodes, discovery.NodeInfo{ID: fmt.Sprintf("node-%02d", i), GRPCAddr: fmt.Sprintf("10.0.0.%d:9090", i+1)})
+	}
+	return nodes
+}
+
+func nodeKeys(nodes []discovery.NodeInfo) string {
+	keys := discovery.NodeKeys(nodes)
+	sort.Strings(keys)
+	return strings.Join(keys, ",")
+}
+
+// TestNodesDeltas 第一次得到完整列表，之后节点变化时只接收增量，未变化时服务端返回 304，
+// 每次返回的都是服务端的当前列表
+func TestNodesDeltas(t *testing.T) {
+	api, c := newNodesAPI(t)
+	ctx := context.Background()
+	for round := 0; round < 8; round++ {
+		api.UpdateNodes(membership(round))
+		for i := 0; i < 2; i++ {
+			nodes, err := c.Nodes(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if nodeKeys(nodes) != nodeKeys(membership(round)) {
+				t.Fatalf("第 %d 轮: 节点 %s, want %s", round, nodeKeys(nodes), nodeKeys(membership(round)))
+			}
+		}
+	}
+	if got := api.counts(); got["full"] != 1 || got["delta"] != 7 || got["304"] != 8 {
+		t.Fatalf("响应 = %v, want 1 个完整列表、7 个增量和 8 个 304", got)
+	}
+
+	// 返回的是副本，调用方修改不影响下一次的增量
+	nodes, _ := c.Nodes(ctx)
+	nodes[0].ID = "changed"
+	api.UpdateNodes(membership(8))
+	if nodes, _ := c.Nodes(ctx); nodeKeys(nodes) != nodeKeys(membership(8)) {
+		t.Fatalf("修改返回值后的增量结果 %s", nodeKeys(nodes))
+	}
+}
@@ -0,0 +1,69 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/AdrianWangs/go-cache/internal/peers"
+)
+
+// TestRingChange Client 记录响应头中最大的哈希环代数，只在代数增大时调用 WithRingChange 设置的函数
+func TestRingChange(t *testing.T) {
+	var mu sync.Mutex
+	var header string // 下一个响应的代数头，为空时不返回该头，与旧版本 API 服务器一致
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if header != "" {
+			w.Header().Set(peers.HeaderRingGeneration, header)
+		}
+		if r.URL.Path == "/api/cache/scores/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("630"))
+	}))
+	defer srv.Close()
+
+	var changes []uint64
+	c := New(srv.URL, WithRingChange(func(generation uint64) {
+		changes = append(changes, generation)
+	}))
+	steps := []struct {
+		name   string
+		header string
+		key    string
+		want   uint64
+	}{
+		{"旧版本 API 服务器", "", "Tom", 0},
+		{"首次观察到代数", "2", "Tom", 2},
+		{"代数不变", "2", "Tom", 2},
+		{"另一台 API 服务器的代数更小", "1", "Tom", 2},
+		{"无效的头", "x", "Tom", 2},
+		{"错误响应中的代数", "5", "missing", 5},
+	}
+	for _, step := range steps {
+		mu.Lock()
+		header = step.header
+		mu.Unlock()
+		_, err := c.Get(context.Background(), "scores", step.key)
+		if step.key == "missing" {
+			if !errors.Is(err, ErrNotFound) {
+				t.Fatalf("%s: Get = %v, want ErrNotFound", step.name, err)
+			}
+		} else if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if g := c.RingGeneration(); g != step.want {
+			t.Fatalf("%s: RingGeneration = %d, want %d", step.name, g, step.want)
+		}
+	}
+	if want := []uint64{2, 5}; !reflect.DeepEqual(changes, want) {
+		t.Fatalf("WithRingChange 收到 %v, want %v", changes, want)
+	}
+}
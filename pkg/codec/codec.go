@@ -0,0 +1,121 @@
+// Package codec 定义缓存值与 Go 值之间的编解码，供 Group.GetInto/SetFrom 和 SDK 的同名方法使用。
+// 编解码失败的错误分别匹配 ErrEncode 和 ErrDecode，与缓存本身的错误（cacheerrors）区分
+package codec
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// Codec 把 Go 值编码为缓存值，以及把缓存值解码到 Go 值
+type Codec interface {
+	// Marshal 编码 v
+	Marshal(v any) ([]byte, error)
+	// Unmarshal 把 data 解码到 v 指向的值
+	Unmarshal(data []byte, v any) error
+}
+
+var (
+	// JSON 使用 encoding/json 的 Codec
+	JSON Codec = jsonCodec{}
+	// Proto 使用 protobuf 二进制格式的 Codec，值必须实现 proto.Message
+	Proto Codec = protoCodec{}
+)
+
+var (
+	// ErrDecode 缓存值无法解码到目标值，例如值由另一种 Codec 写入，或目标为 nil；用 errors.Is 判断
+	ErrDecode = errors.New("codec: decode failed")
+	// ErrEncode Go 值无法编码为缓存值；用 errors.Is 判断
+	ErrEncode = errors.New("codec: encode failed")
+)
+
+// Error 编解码失败的错误，errors.Is 按 Decode 匹配 ErrDecode 或 ErrEncode
+type Error struct {
+	Decode bool  // 解码失败为 true，编码失败为 false
+	Err    error // 编解码器返回的原始错误
+}
+
+// Error 实现 error
+func (e *Error) Error() string {
+	if e.Decode {
+		return fmt.Sprintf("%v: %v", ErrDecode, e.Err)
+	}
+	return fmt.Sprintf("%v: %v", ErrEncode, e.Err)
+}
+
+// Unwrap 返回原始错误
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is 使 errors.Is(err, ErrDecode) 和 errors.Is(err, ErrEncode) 按失败的方向成立
+func (e *Error) Is(target error) bool {
+	return (e.Decode && target == ErrDecode) || (!e.Decode && target == ErrEncode)
+}
+
+// Encode 用 c 编码 v，失败时返回匹配 ErrEncode 的错误
+func Encode(c Codec, v any) ([]byte, error) {
+	data, err := c.Marshal(v)
+	if err != nil {
+		return nil, &Error{Err: err}
+	}
+	return data, nil
+}
+
+// Decode 用 c 把 data 解码到 dst，dst 必须是非 nil 的指针；失败时返回匹配 ErrDecode 的错误
+func Decode(c Codec, data []byte, dst any) error {
+	if err := CheckDestination(dst); err != nil {
+		return err
+	}
+	if err := c.Unmarshal(data, dst); err != nil {
+		return &Error{Decode: true, Err: err}
+	}
+	return nil
+}
+
+// CheckDestination 检查 dst 能否作为解码目标，不是非 nil 的指针时返回匹配 ErrDecode 的错误。
+// 调用方可以在读取缓存之前调用它，避免为注定失败的解码读取值
+func CheckDestination(dst any) error {
+	if rv := reflect.ValueOf(dst); rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return &Error{Decode: true, Err: fmt.Errorf("destination must be a non-nil pointer, got %T", dst)}
+	}
+	return nil
+}
+
+// jsonCodec 使用 encoding/json 的 Codec
+type jsonCodec struct{}
+
+// Marshal 实现 Codec
+func (jsonCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Unmarshal 实现 Codec
+func (jsonCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+// protoCodec 使用 protobuf 二进制格式的 Codec
+type protoCodec struct{}
+
+// Marshal 实现 Codec
+func (protoCodec) Marshal(v any) ([]byte, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("proto codec needs a proto.Message, got %T", v)
+	}
+	return proto.Marshal(m)
+}
+
+// Unmarshal 实现 Codec
+func (protoCodec) Unmarshal(data []byte, v any) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("proto codec needs a proto.Message, got %T", v)
+	}
+	return proto.Unmarshal(data, m)
+}
@@ -0,0 +1,114 @@
+package codec
+
+import (
+	"errors"
+	"testing"
+
+	pb "github.com/AdrianWangs/go-cache/proto/cache_server"
+	"google.golang.org/protobuf/proto"
+)
+
+type user struct {
+	Name  string   `json:"name"`
+	Age   int      `json:"age"`
+	Roles []string `json:"roles"`
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	in := user{Name: "Tom", Age: 30, Roles: []string{"admin"}}
+	data, err := Encode(JSON, in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out user
+	if err := Decode(JSON, data, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Name != in.Name || out.Age != in.Age || len(out.Roles) != 1 || out.Roles[0] != "admin" {
+		t.Fatalf("解码结果 = %+v, want %+v", out, in)
+	}
+}
+
+func TestProtoRoundTrip(t *testing.T) {
+	in := &pb.Request{Group: "scores", Key: "Tom"}
+	data, err := Encode(Proto, in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := &pb.Request{}
+	if err := Decode(Proto, data, out); err != nil {
+		t.Fatal(err)
+	}
+	if !proto.Equal(in, out) {
+		t.Fatalf("解码结果 = %v, want %v", out, in)
+	}
+}
+
+// TestDecodeErrors 解码失败的错误匹配 ErrDecode，不匹配 ErrEncode
+func TestDecodeErrors(t *testing.T) {
+	jsonValue, _ := Encode(JSON, user{Name: "Tom"})
+	protoValue, _ := Encode(Proto, &pb.Request{Group: "scores", Key: "Tom"})
+	var u user
+	var nilUser *user
+	tests := []struct {
+		name  string
+		codec Codec
+		data  []byte
+		dst   any
+	}{
+		{"nil 目标", JSON, jsonValue, nil},
+		{"nil 指针", JSON, jsonValue, nilUser},
+		{"不是指针", JSON, jsonValue, u},
+		{"JSON 读取 protobuf 写入的值", JSON, protoValue, &u},
+		{"protobuf 读取 JSON 写入的值", Proto, jsonValue, &pb.Request{}},
+		{"protobuf 解码到非 proto.Message", Proto, protoValue, &u},
+		{"格式错误的 JSON", JSON, []byte(`{"name":`), &u},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Decode(tt.codec, tt.data, tt.dst)
+			if !errors.Is(err, ErrDecode) || errors.Is(err, ErrEncode) {
+				t.Fatalf("Decode = %v, want ErrDecode", err)
+			}
+			var e *Error
+			if !errors.As(err, &e) || !e.Decode || e.Err == nil {
+				t.Fatalf("错误 = %#v", err)
+			}
+		})
+	}
+}
+
+func TestEncodeErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		codec Codec
+		src   any
+	}{
+		{"JSON 无法编码的值", JSON, make(chan int)},
+		{"protobuf 编码非 proto.Message", Proto, user{Name: "Tom"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := Encode(tt.codec, tt.src)
+			if data != nil || !errors.Is(err, ErrEncode) || errors.Is(err, ErrDecode) {
+				t.Fatalf("Encode = %q, %v, want ErrEncode", data, err)
+			}
+		})
+	}
+}
+
+func TestCheckDestination(t *testing.T) {
+	var u user
+	if err := CheckDestination(&u); err != nil {
+		t.Fatalf("有效的目标 = %v", err)
+	}
+	var m map[string]int
+	if err := CheckDestination(&m); err != nil {
+		t.Fatalf("指向 nil map 的指针 = %v", err)
+	}
+	for _, dst := range []any{nil, u, (*user)(nil), m} {
+		if err := CheckDestination(dst); !errors.Is(err, ErrDecode) {
+			t.Fatalf("CheckDestination(%T) = %v, want ErrDecode", dst, err)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package codec_test
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/AdrianWangs/go-cache/pkg/codec"
+)
+
+func Example() {
+	type User struct {
+		Name string `json:"name"`
+		Age  int    `json:"age"`
+	}
+	value, _ := codec.Encode(codec.JSON, User{Name: "Tom", Age: 30})
+	fmt.Println(string(value))
+
+	var u User
+	err := codec.Decode(codec.JSON, value, &u)
+	fmt.Println(u.Name, u.Age, err)
+
+	// 值不能按所用的 Codec 解码时，错误匹配 ErrDecode
+	err = codec.Decode(codec.JSON, []byte("not json"), &u)
+	fmt.Println(errors.Is(err, codec.ErrDecode))
+	// Output:
+	// {"name":"Tom","age":30}
+	// Tom 30 <nil>
+	// true
+}
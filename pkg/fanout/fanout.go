@@ -0,0 +1,111 @@
+// Package fanout 并发地向一组目标（例如缓存节点）发出同样的调用并收集结果，
+// 限制同时进行的调用数，单个目标超时或失败不影响其他目标
+package fanout
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Options 配置一次扇出调用
+type Options struct {
+	Concurrency    int           // 同时进行的调用数上限，<=0 表示不限制
+	PerCallTimeout time.Duration // 单个目标的超时，<=0 表示只受 ctx 约束
+}
+
+// Result 单个目标的调用结果
+type Result[T any] struct {
+	Target   string        // 目标
+	Value    T             // 调用返回的值，Err 不为 nil 时为零值
+	Err      error         // 调用错误；ctx 在调用开始前被取消时为 ctx.Err()
+	Duration time.Duration // 调用耗时，未开始的调用为 0
+}
+
+// FanOut 对每个目标调用 fn，返回以目标为 key 的结果，每个目标（去重后）都有一个结果。
+// 调用 fn 时传入的 ctx 在 PerCallTimeout 后超时；ctx 取消后尚未开始的目标不再调用，
+// 其结果的 Err 为 ctx.Err()，已开始的调用由 fn 自行响应取消
+func FanOut[T any](ctx context.Context, targets []string, fn func(ctx context.Context, target string) (T, error), opts Options) map[string]Result[T] {
+	unique := make([]string, 0, len(targets))
+	seen := make(map[string]bool, len(targets))
+	for _, t := range targets {
+		if !seen[t] {
+			seen[t] = true
+			unique = append(unique, t)
+		}
+	}
+
+	workers := opts.Concurrency
+	if workers <= 0 || workers > len(unique) {
+		workers = len(unique)
+	}
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		results = make(map[string]Result[T], len(unique))
+		queue   = make(chan string)
+	)
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for target := range queue {
+				res := call(ctx, target, fn, opts.PerCallTimeout)
+				mu.Lock()
+				results[target] = res
+				mu.Unlock()
+			}
+		}()
+	}
+
+	// 按顺序分发目标，ctx 取消后剩余的目标直接记录取消错误
+dispatch:
+	for i, target := range unique {
+		select {
+		case queue <- target:
+		case <-ctx.Done():
+			mu.Lock()
+			for _, t := range unique[i:] {
+				results[t] = Result[T]{Target: t, Err: ctx.Err()}
+			}
+			mu.Unlock()
+			break dispatch
+		}
+	}
+	close(queue)
+	wg.Wait()
+	return results
+}
+
+// call 以单个目标的超时执行一次调用并记录耗时
+func call[T any](ctx context.Context, target string, fn func(ctx context.Context, target string) (T, error), timeout time.Duration) Result[T] {
+	if err := ctx.Err(); err != nil {
+		return Result[T]{Target: target, Err: err}
+	}
+
+	callCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		callCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	value, err := fn(callCtx, target)
+	return Result[T]{Target: target, Value: value, Err: err, Duration: time.Since(start)}
+}
+
+// Ordered 按 targets 的顺序返回结果，缺少结果的目标被跳过，重复的目标只返回一次
+func Ordered[T any](targets []string, results map[string]Result[T]) []Result[T] {
+	ordered := make([]Result[T], 0, len(results))
+	seen := make(map[string]bool, len(targets))
+	for _, t := range targets {
+		if res, ok := results[t]; ok && !seen[t] {
+			seen[t] = true
+			ordered = append(ordered, res)
+		}
+	}
+	return ordered
+}
@@ -0,0 +1,136 @@
+package fanout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func targets(n int) []string {
+	t := make([]string, n)
+	for i := range t {
+		t[i] = fmt.Sprintf("node-%d", i)
+	}
+	return t
+}
+
+func TestFanOutCompleteAndOrdered(t *testing.T) {
+	nodes := append(targets(20), "node-3", "node-7") // 重复的目标只调用一次
+	var calls atomic.Int64
+	results := FanOut(context.Background(), nodes, func(ctx context.Context, target string) (string, error) {
+		calls.Add(1)
+		if target == "node-5" {
+			return "", errors.New("boom")
+		}
+		return "v:" + target, nil
+	}, Options{Concurrency: 4})
+
+	if len(results) != 20 || calls.Load() != 20 {
+		t.Fatalf("%d 个结果, %d 次调用", len(results), calls.Load())
+	}
+	ordered := Ordered(nodes, results)
+	if len(ordered) != 20 {
+		t.Fatalf("Ordered 返回 %d 个结果", len(ordered))
+	}
+	for i, res := range ordered {
+		want := fmt.Sprintf("node-%d", i)
+		if res.Target != want {
+			t.Fatalf("第 %d 个结果是 %s, want %s", i, res.Target, want)
+		}
+		if want == "node-5" {
+			if res.Err == nil || res.Value != "" {
+				t.Fatalf("失败的目标 = %+v", res)
+			}
+			continue
+		}
+		if res.Err != nil || res.Value != "v:"+want {
+			t.Fatalf("%s = %+v", want, res)
+		}
+	}
+
+	if got := FanOut(context.Background(), nil, func(context.Context, string) (int, error) { return 0, nil }, Options{}); len(got) != 0 {
+		t.Fatalf("没有目标时返回 %v", got)
+	}
+}
+
+func TestFanOutConcurrencyLimit(t *testing.T) {
+	var running, peak atomic.Int64
+	FanOut(context.Background(), targets(20), func(ctx context.Context, target string) (int, error) {
+		n := running.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		running.Add(-1)
+		return 0, nil
+	}, Options{Concurrency: 3})
+	if p := peak.Load(); p != 3 {
+		t.Fatalf("同时进行的调用最多 %d 个, want 3", p)
+	}
+}
+
+// TestFanOutPerCallTimeout 部分目标超时不影响其他目标，结果带有各自的耗时
+func TestFanOutPerCallTimeout(t *testing.T) {
+	start := time.Now()
+	results := FanOut(context.Background(), targets(6), func(ctx context.Context, target string) (int, error) {
+		if target == "node-1" || target == "node-4" {
+			<-ctx.Done()
+			return 0, ctx.Err()
+		}
+		time.Sleep(10 * time.Millisecond)
+		return 1, nil
+	}, Options{PerCallTimeout: 50 * time.Millisecond})
+
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("扇出用了 %v", d)
+	}
+	for target, res := range results {
+		slow := target == "node-1" || target == "node-4"
+		if slow != errors.Is(res.Err, context.DeadlineExceeded) {
+			t.Fatalf("%s: err = %v", target, res.Err)
+		}
+		if slow && res.Duration < 50*time.Millisecond {
+			t.Fatalf("%s: 超时的调用耗时 %v", target, res.Duration)
+		}
+		if !slow && (res.Value != 1 || res.Duration < 10*time.Millisecond) {
+			t.Fatalf("%s = %+v", target, res)
+		}
+	}
+}
+
+// TestFanOutCancelMidFlight ctx 取消后已开始的调用收到取消，尚未开始的目标不再调用
+func TestFanOutCancelMidFlight(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan struct{}, 10)
+	var calls atomic.Int64
+	go func() {
+		<-started
+		<-started
+		cancel()
+	}()
+
+	results := FanOut(ctx, targets(10), func(ctx context.Context, target string) (int, error) {
+		calls.Add(1)
+		started <- struct{}{}
+		<-ctx.Done()
+		return 0, ctx.Err()
+	}, Options{Concurrency: 2})
+
+	if len(results) != 10 {
+		t.Fatalf("%d 个结果, want 10", len(results))
+	}
+	if n := calls.Load(); n != 2 {
+		t.Fatalf("取消后仍调用了 %d 个目标", n)
+	}
+	for target, res := range results {
+		if !errors.Is(res.Err, context.Canceled) {
+			t.Fatalf("%s: err = %v", target, res.Err)
+		}
+	}
+}
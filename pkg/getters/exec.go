@@ -0,0 +1,116 @@
+package getters
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/pkg/cacheerrors"
+)
+
+// KeyPlaceholder 命令模板中被替换为 key 的占位符
+const KeyPlaceholder = "{key}"
+
+// ExitNotFound 命令以该退出码退出表示 key 不存在，与 grep 未匹配时的约定相同
+const ExitNotFound = 1
+
+// execWaitDelay 命令被终止后等待其输出关闭的时间，防止子进程持有管道使加载无法返回
+const execWaitDelay = time.Second
+
+// ErrUnsafeKey 表示 key 不能安全地代入命令参数，命令不会被执行
+var ErrUnsafeKey = errors.New("key 不能用于数据源命令")
+
+// Exec 执行外部命令加载 key：命令模板按空白拆分为程序和参数，每个参数中的 {key}
+// 替换为 key。命令不经过 shell，key 总是作为参数的一部分传入，不会被解释；
+// 需要管道等 shell 功能时把命令写成脚本。
+//
+// key 来自客户端且可以包含 "/"，代入 "cat /data/{key}" 这样的路径时可以跳出
+// 目录，以 "-" 开头时会被命令当作选项。因此包含 "/"、反斜杠、".."、NUL 或以 "-"
+// 开头的 key 一律以 ErrUnsafeKey 拒绝；程序名中不允许出现占位符。
+//
+// 退出码 0 时标准输出即值，ExitNotFound 表示 key 不存在，其他退出码、超时和
+// 无法启动都是错误，错误信息包含标准错误输出
+type Exec struct {
+	args    []string
+	timeout time.Duration
+}
+
+// NewExec 创建命令数据源，例如 NewExec("cat /data/{key}", time.Second)，
+// timeout <= 0 时使用 DefaultTimeout
+func NewExec(template string, timeout time.Duration) (*Exec, error) {
+	args := strings.Fields(template)
+	if len(args) == 0 {
+		return nil, errors.New("数据源命令为空")
+	}
+	if !strings.Contains(template, KeyPlaceholder) {
+		return nil, fmt.Errorf("数据源命令中没有 %s 占位符: %q", KeyPlaceholder, template)
+	}
+	if strings.Contains(args[0], KeyPlaceholder) {
+		return nil, fmt.Errorf("数据源命令的程序名中不能有 %s 占位符: %q", KeyPlaceholder, template)
+	}
+	return &Exec{args: args, timeout: timeout}, nil
+}
+
+// Get 实现 cache.Getter
+func (e *Exec) Get(key string) ([]byte, error) {
+	return e.GetContext(context.Background(), key)
+}
+
+// GetContext 实现 cache.GetterCtx，ctx 取消或超时时终止命令
+func (e *Exec) GetContext(ctx context.Context, key string) ([]byte, error) {
+	if err := checkExecKey(key); err != nil {
+		return nil, err
+	}
+	ctx, cancel := withTimeout(ctx, e.timeout)
+	defer cancel()
+
+	args := make([]string, len(e.args))
+	for i, arg := range e.args {
+		args[i] = strings.ReplaceAll(arg, KeyPlaceholder, key)
+	}
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.WaitDelay = execWaitDelay
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return stdout.Bytes(), nil
+	case errors.As(err, &exitErr) && exitErr.ExitCode() == ExitNotFound:
+		return nil, cacheerrors.ErrNotFound
+	case stderr.Len() > 0:
+		return nil, fmt.Errorf("数据源命令失败: %v: %s", err, strings.TrimSpace(stderr.String()))
+	default:
+		return nil, fmt.Errorf("数据源命令失败: %v", err)
+	}
+}
+
+// checkExecKey 拒绝代入命令参数后可能跳出目录或被当作选项的 key
+func checkExecKey(key string) error {
+	switch {
+	case key == "":
+		return cacheerrors.ErrEmptyKey
+	case strings.ContainsAny(key, "/\\\x00"):
+		return fmt.Errorf("%w: %q 包含路径分隔符或 NUL", ErrUnsafeKey, key)
+	case strings.Contains(key, ".."):
+		return fmt.Errorf("%w: %q 包含 \"..\"", ErrUnsafeKey, key)
+	case strings.HasPrefix(key, "-"):
+		return fmt.Errorf("%w: %q 以 \"-\" 开头", ErrUnsafeKey, key)
+	}
+	return nil
+}
+
+// String 返回命令模板，用于日志
+func (e *Exec) String() string {
+	return "exec " + strings.Join(e.args, " ")
+}
@@ -0,0 +1,168 @@
+package getters
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/pkg/cacheerrors"
+)
+
+// helperEnv 设置后测试二进制作为桩命令运行，见 TestExecHelperProcess
+const helperEnv = "GETTERS_WANT_HELPER_PROCESS"
+
+// TestExecHelperProcess 不是真正的测试：Exec 以 helperEnv 运行测试二进制时，
+// 它按 "--" 之后的 key 模拟数据源命令
+func TestExecHelperProcess(t *testing.T) {
+	if os.Getenv(helperEnv) != "1" {
+		return
+	}
+	args := os.Args
+	for len(args) > 0 && args[0] != "--" {
+		args = args[1:]
+	}
+	key := strings.Join(args[1:], "|")
+	switch key {
+	case "missing":
+		os.Exit(ExitNotFound)
+	case "broken":
+		fmt.Fprint(os.Stderr, "database is down")
+		os.Exit(2)
+	case "slow":
+		time.Sleep(time.Minute)
+	default:
+		fmt.Printf("value of %s", key)
+	}
+	os.Exit(0)
+}
+
+// stubExec 返回运行桩命令的 Exec
+func stubExec(t *testing.T, timeout time.Duration) *Exec {
+	t.Setenv(helperEnv, "1")
+	e, err := NewExec(os.Args[0]+" -test.run=^TestExecHelperProcess$ -- {key}", timeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func TestExec(t *testing.T) {
+	e := stubExec(t, 10*time.Second)
+	tests := []struct {
+		key     string
+		want    string
+		wantErr string // 为空时期望成功；"not found" 期望 cacheerrors.ErrNotFound
+	}{
+		{"alice", "value of alice", ""},
+		{"a b; rm -rf ~", "value of a b; rm -rf ~", ""}, // key 作为一个参数传入，不被拆分或解释
+		{"missing", "", "not found"},
+		{"broken", "", "database is down"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			v, err := e.Get(tt.key)
+			switch {
+			case tt.wantErr == "":
+				if err != nil || string(v) != tt.want {
+					t.Fatalf("Get = %q, %v; want %q", v, err, tt.want)
+				}
+			case tt.wantErr == "not found":
+				if !errors.Is(err, cacheerrors.ErrNotFound) {
+					t.Fatalf("Get = %v, want ErrNotFound", err)
+				}
+			case err == nil || !strings.Contains(err.Error(), tt.wantErr) || errors.Is(err, cacheerrors.ErrNotFound):
+				t.Fatalf("Get = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExecTimeout(t *testing.T) {
+	e := stubExec(t, 100*time.Millisecond)
+	start := time.Now()
+	if _, err := e.Get("slow"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Get = %v, want DeadlineExceeded", err)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Fatalf("Get returned after %v", d)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+	e = stubExec(t, time.Minute)
+	if _, err := e.GetContext(ctx, "slow"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("GetContext = %v, want Canceled", err)
+	}
+}
+
+// TestExecPathTraversal 用真实的 cat 读取数据目录：跳出目录的 key 必须被拒绝，
+// 不能读到目录外的文件
+func TestExecPathTraversal(t *testing.T) {
+	cat, err := exec.LookPath("cat")
+	if err != nil {
+		t.Skip("没有 cat 命令")
+	}
+	root := t.TempDir()
+	data := filepath.Join(root, "data")
+	if err := os.Mkdir(data, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(data, "alice"), []byte("alice's value"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "secret"), []byte("secret"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	e, err := NewExec(cat+" "+data+"/{key}", 10*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err := e.Get("alice"); err != nil || string(v) != "alice's value" {
+		t.Fatalf("Get(alice) = %q, %v", v, err)
+	}
+	for _, key := range []string{"../secret", "a/../../secret", "..", "..secret", "x/y", `..\secret`, "alice\x00"} {
+		v, err := e.Get(key)
+		if !errors.Is(err, ErrUnsafeKey) {
+			t.Errorf("Get(%q) = %q, %v; want ErrUnsafeKey", key, v, err)
+		}
+	}
+}
+
+// TestExecOptionInjection 以 "-" 开头的 key 会被命令当作选项，必须在执行前被拒绝
+func TestExecOptionInjection(t *testing.T) {
+	e := stubExec(t, 10*time.Second)
+	for _, key := range []string{"-n", "--help", "-test.run=.*", "-"} {
+		v, err := e.Get(key)
+		if !errors.Is(err, ErrUnsafeKey) {
+			t.Errorf("Get(%q) = %q, %v; want ErrUnsafeKey", key, v, err)
+		}
+	}
+	// "-" 不在开头时只是普通字符
+	if v, err := e.Get("a-b"); err != nil || string(v) != "value of a-b" {
+		t.Fatalf("Get(a-b) = %q, %v", v, err)
+	}
+	if _, err := e.Get(""); !errors.Is(err, cacheerrors.ErrEmptyKey) {
+		t.Fatalf("Get(\"\") = %v, want ErrEmptyKey", err)
+	}
+}
+
+func TestNewExecInvalid(t *testing.T) {
+	for _, template := range []string{"", "   ", "cat /data/file", "{key} --version", "/bin/{key}.sh x"} {
+		if _, err := NewExec(template, 0); err == nil {
+			t.Errorf("NewExec(%q) succeeded", template)
+		}
+	}
+	e, err := NewExec("/nonexistent/command {key}", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.Get("k"); err == nil || errors.Is(err, cacheerrors.ErrNotFound) {
+		t.Fatalf("Get with a missing program = %v", err)
+	}
+}
@@ -0,0 +1,31 @@
+// Package getters 提供可复用的数据源，实现 cache.Getter 和 cache.GetterCtx：
+//
+//   - HTTP：GET {url}/{key}，响应体即值，404 表示 key 不存在；
+//   - Exec：按命令模板执行外部命令，标准输出即值，退出码 1 表示 key 不存在；
+//   - Redis：向 Redis 发送 GET，nil 回复表示 key 不存在。
+//
+// key 不存在时返回 cacheerrors.ErrNotFound，缓存组按未命中处理，不计入熔断器的失败。
+// 每次加载都受超时限制，调用方取消时立即返回 ctx.Err()。
+//
+// 缓存节点通过 -source、-source-url、-source-cmd 和 -source-timeout 参数，
+// 或配置文件中组的 source 选择数据源：
+//
+//	getter, err := getters.NewHTTP("http://backend:8000/scores", 2*time.Second)
+//	group := cache.NewGroup("scores", 64<<20, getter, time.Hour)
+package getters
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultTimeout 未指定超时时每次加载的超时
+const DefaultTimeout = 5 * time.Second
+
+// withTimeout 为一次加载设置超时，timeout <= 0 时使用 DefaultTimeout
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return context.WithTimeout(ctx, timeout)
+}
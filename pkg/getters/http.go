@@ -0,0 +1,76 @@
+package getters
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/pkg/cacheerrors"
+)
+
+// maxErrorBody 数据源返回错误时读取的响应体长度上限
+const maxErrorBody = 512
+
+// HTTP 从 HTTP 服务加载 key：GET {baseURL}/{key}，key 经过路径转义
+type HTTP struct {
+	baseURL string
+	timeout time.Duration
+	client  *http.Client
+}
+
+// NewHTTP 创建 HTTP 数据源，baseURL 必须是 http 或 https 地址，timeout <= 0 时使用 DefaultTimeout
+func NewHTTP(baseURL string, timeout time.Duration) (*HTTP, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("数据源地址无效: %v", err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("数据源地址必须是 http(s)://host[/path]: %q", baseURL)
+	}
+	return &HTTP{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		timeout: timeout,
+		client:  &http.Client{},
+	}, nil
+}
+
+// Get 实现 cache.Getter
+func (h *HTTP) Get(key string) ([]byte, error) {
+	return h.GetContext(context.Background(), key)
+}
+
+// GetContext 实现 cache.GetterCtx。200 返回响应体，404 返回 cacheerrors.ErrNotFound，
+// 其他状态码返回错误
+func (h *HTTP) GetContext(ctx context.Context, key string) ([]byte, error) {
+	ctx, cancel := withTimeout(ctx, h.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.baseURL+"/"+url.PathEscape(key), nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := h.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	switch res.StatusCode {
+	case http.StatusOK:
+		return io.ReadAll(res.Body)
+	case http.StatusNotFound:
+		return nil, cacheerrors.ErrNotFound
+	default:
+		body, _ := io.ReadAll(io.LimitReader(res.Body, maxErrorBody))
+		return nil, fmt.Errorf("数据源返回 %s: %s", res.Status, strings.TrimSpace(string(body)))
+	}
+}
+
+// String 返回数据源地址，用于日志
+func (h *HTTP) String() string {
+	return "http " + h.baseURL
+}
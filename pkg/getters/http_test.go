@@ -0,0 +1,99 @@
+package getters
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/pkg/cacheerrors"
+)
+
+// newBackend 启动按路径返回值的 HTTP 服务：/scores/{key} 在 values 中时返回值，
+// "broken" 返回 500，"slow" 等到请求取消，其他 key 返回 404
+func newBackend(t *testing.T, values map[string]string) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimPrefix(r.URL.EscapedPath(), "/scores/")
+		switch key {
+		case "broken":
+			http.Error(w, "database is down", http.StatusInternalServerError)
+		case "slow":
+			<-r.Context().Done()
+		default:
+			if v, ok := values[key]; ok {
+				w.Write([]byte(v))
+				return
+			}
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestHTTP(t *testing.T) {
+	srv := newBackend(t, map[string]string{"alice": "90", "a%2Fb%20c": "escaped"})
+	getter, err := NewHTTP(srv.URL+"/scores/", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		key     string
+		want    string
+		wantErr string // 为空时期望成功；"not found" 期望 cacheerrors.ErrNotFound
+	}{
+		{"alice", "90", ""},
+		{"a/b c", "escaped", ""},
+		{"bob", "", "not found"},
+		{"broken", "", "database is down"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			v, err := getter.Get(tt.key)
+			switch {
+			case tt.wantErr == "":
+				if err != nil || string(v) != tt.want {
+					t.Fatalf("Get = %q, %v; want %q", v, err, tt.want)
+				}
+			case tt.wantErr == "not found":
+				if !errors.Is(err, cacheerrors.ErrNotFound) {
+					t.Fatalf("Get = %v, want ErrNotFound", err)
+				}
+			case err == nil || !strings.Contains(err.Error(), tt.wantErr) || errors.Is(err, cacheerrors.ErrNotFound):
+				t.Fatalf("Get = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHTTPTimeout(t *testing.T) {
+	srv := newBackend(t, nil)
+	getter, _ := NewHTTP(srv.URL+"/scores", 50*time.Millisecond)
+	start := time.Now()
+	if _, err := getter.Get("slow"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Get = %v, want DeadlineExceeded", err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("Get returned after %v", d)
+	}
+
+	// 调用方取消时立即返回
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	getter, _ = NewHTTP(srv.URL+"/scores", time.Minute)
+	if _, err := getter.GetContext(ctx, "slow"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("GetContext = %v, want Canceled", err)
+	}
+}
+
+func TestNewHTTPInvalid(t *testing.T) {
+	for _, u := range []string{"", "backend:8000", "ftp://backend/scores", "http://", "http://%zz"} {
+		if _, err := NewHTTP(u, 0); err == nil {
+			t.Errorf("NewHTTP(%q) succeeded", u)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package getters
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/pkg/cacheerrors"
+)
+
+// redisMaxIdle Redis 数据源保留的空闲连接数
+const redisMaxIdle = 8
+
+// errRedisNil 表示 Redis 返回了 nil 回复
+var errRedisNil = errors.New("redis: nil reply")
+
+// Redis 从 Redis 加载 key：发送 GET key，nil 回复表示 key 不存在。
+// 只实现了 RESP 协议中 GET、AUTH 和 SELECT 用到的部分，连接在加载之间复用
+type Redis struct {
+	addr     string
+	username string
+	password string
+	db       int
+	timeout  time.Duration
+	idle     chan *redisConn
+}
+
+// redisConn 一个 Redis 连接及其读缓冲
+type redisConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+// NewRedis 创建 Redis 数据源，rawURL 形如 redis://[user:password@]host:port[/db]，
+// 省略端口时使用 6379。timeout <= 0 时使用 DefaultTimeout，连接在第一次加载时建立
+func NewRedis(rawURL string, timeout time.Duration) (*Redis, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("数据源地址无效: %v", err)
+	}
+	if u.Scheme != "redis" || u.Host == "" {
+		return nil, fmt.Errorf("数据源地址必须是 redis://[user:password@]host:port[/db]: %q", rawURL)
+	}
+
+	r := &Redis{addr: u.Host, timeout: timeout, idle: make(chan *redisConn, redisMaxIdle)}
+	if u.Port() == "" {
+		r.addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		r.password, _ = u.User.Password()
+		r.username = u.User.Username()
+		if r.password == "" {
+			// redis://:password@host 与 redis://password@host 都表示只有密码
+			r.password, r.username = r.username, ""
+		}
+	}
+	if db := strings.Trim(u.Path, "/"); db != "" {
+		if r.db, err = strconv.Atoi(db); err != nil || r.db < 0 {
+			return nil, fmt.Errorf("数据源地址中的数据库编号无效: %q", db)
+		}
+	}
+	return r, nil
+}
+
+// Get 实现 cache.Getter
+func (r *Redis) Get(key string) ([]byte, error) {
+	return r.GetContext(context.Background(), key)
+}
+
+// GetContext 实现 cache.GetterCtx，ctx 取消或超时时中断正在进行的读写
+func (r *Redis) GetContext(ctx context.Context, key string) ([]byte, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	conn, err := r.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	value, err := r.do(ctx, conn, "GET", key)
+	if err != nil && !errors.Is(err, errRedisNil) {
+		// 连接的状态未知，不再复用
+		conn.Close()
+		return nil, err
+	}
+	r.release(conn)
+	if err != nil {
+		return nil, cacheerrors.ErrNotFound
+	}
+	return value, nil
+}
+
+// Close 关闭空闲连接，正在使用的连接在加载结束后关闭
+func (r *Redis) Close() error {
+	for {
+		select {
+		case c := <-r.idle:
+			c.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+// String 返回 Redis 地址，用于日志，不包含密码
+func (r *Redis) String() string {
+	return fmt.Sprintf("redis %s/%d", r.addr, r.db)
+}
+
+// conn 取出一个空闲连接，没有时建立新连接并完成认证和选库
+func (r *Redis) conn(ctx context.Context) (*redisConn, error) {
+	select {
+	case c := <-r.idle:
+		return c, nil
+	default:
+	}
+
+	var d net.Dialer
+	nc, err := d.DialContext(ctx, "tcp", r.addr)
+	if err != nil {
+		return nil, err
+	}
+	c := &redisConn{Conn: nc, r: bufio.NewReader(nc)}
+	if r.password != "" {
+		args := []string{"AUTH", r.password}
+		if r.username != "" {
+			args = []string{"AUTH", r.username, r.password}
+		}
+		if _, err := r.do(ctx, c, args...); err != nil {
+			c.Close()
+			return nil, fmt.Errorf("redis 认证失败: %v", err)
+		}
+	}
+	if r.db != 0 {
+		if _, err := r.do(ctx, c, "SELECT", strconv.Itoa(r.db)); err != nil {
+			c.Close()
+			return nil, fmt.Errorf("redis 选择数据库 %d 失败: %v", r.db, err)
+		}
+	}
+	return c, nil
+}
+
+// release 放回连接，空闲连接已满时关闭它
+func (r *Redis) release(c *redisConn) {
+	select {
+	case r.idle <- c:
+	default:
+		c.Close()
+	}
+}
+
+// do 发送一条命令并读取回复，bulk 回复的 nil 返回 errRedisNil
+func (r *Redis) do(ctx context.Context, c *redisConn, args ...string) ([]byte, error) {
+	// 不直接使用 ctx 的截止时间作为连接的 deadline：两者同时到期时读写可能先于 ctx 失败，
+	// 返回的就是 i/o timeout 而不是 ctx.Err()
+	stop := context.AfterFunc(ctx, func() { c.SetDeadline(time.Now()) })
+	defer stop()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(c, b.String()); err != nil {
+		return nil, ctxErr(ctx, err)
+	}
+	value, err := readRedisReply(c.r)
+	return value, ctxErr(ctx, err)
+}
+
+// ctxErr 在 ctx 已结束时用 ctx.Err() 代替读写超时的错误
+func ctxErr(ctx context.Context, err error) error {
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// readRedisReply 读取一条回复，只支持简单字符串、错误、整数和 bulk 字符串
+func readRedisReply(r *bufio.Reader) ([]byte, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("redis: empty reply")
+	}
+
+	switch line[0] {
+	case '+', ':':
+		return []byte(line[1:]), nil
+	case '-':
+		return nil, fmt.Errorf("redis: %s", line[1:])
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("redis: invalid bulk length %q", line[1:])
+		}
+		if n < 0 {
+			return nil, errRedisNil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	default:
+		return nil, fmt.Errorf("redis: unexpected reply %q", line)
+	}
+}
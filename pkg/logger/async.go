@@ -0,0 +1,318 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultAsyncBufferSize is the number of lines an async logger buffers when
+// AsyncOptions.BufferSize is not set
+const DefaultAsyncBufferSize = 8192
+
+// Overflow decides what an async logger does with a new line when its buffer is full
+type Overflow int
+
+const (
+	// DropOldest discards the oldest buffered line, so logging never blocks the caller
+	DropOldest Overflow = iota
+	// Block makes the caller wait until the writer has made room, losing no lines
+	Block
+)
+
+// String returns the name of the overflow policy
+func (o Overflow) String() string {
+	switch o {
+	case DropOldest:
+		return "drop-oldest"
+	case Block:
+		return "block"
+	default:
+		return fmt.Sprintf("Overflow(%d)", int(o))
+	}
+}
+
+// ParseOverflow parses an overflow policy name; an empty name means DropOldest
+func ParseOverflow(s string) (Overflow, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "drop-oldest":
+		return DropOldest, nil
+	case "block":
+		return Block, nil
+	default:
+		return DropOldest, fmt.Errorf("unknown log overflow policy %q, must be drop-oldest or block", s)
+	}
+}
+
+// AsyncOptions configures EnableAsync
+type AsyncOptions struct {
+	BufferSize int      // lines buffered before Overflow applies, DefaultAsyncBufferSize when <= 0
+	Overflow   Overflow // what to do when the buffer is full
+}
+
+// Stats describes the state of the logger
+type Stats struct {
+	Async    bool   `json:"async"`              // lines are written by a background goroutine
+	Overflow string `json:"overflow,omitempty"` // overflow policy in async mode
+	Buffered int    `json:"buffered"`           // lines waiting to be written
+	Dropped  int64  `json:"dropped"`            // lines discarded because the buffer was full
+}
+
+// async is the background writer while async mode is on, nil otherwise
+var async atomic.Pointer[asyncWriter]
+
+// asyncMu serializes switching async mode on and off
+var asyncMu sync.Mutex
+
+// EnableAsync takes formatting and writing log lines off the caller: each line is
+// copied into a bounded ring buffer and a dedicated goroutine formats and writes
+// it with the current formatter and output. Lines logged from one goroutine keep
+// their order. Call Flush before exiting so buffered lines are not lost; Fatal
+// flushes on its own. Calling EnableAsync again replaces the options.
+func EnableAsync(opts AsyncOptions) {
+	asyncMu.Lock()
+	defer asyncMu.Unlock()
+
+	if prev := async.Load(); prev != nil {
+		restoreSync(prev)
+	}
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = DefaultAsyncBufferSize
+	}
+	a := newAsyncWriter(opts, defaultLogger.Out, defaultLogger.Formatter)
+	async.Store(a)
+
+	// Switch the formatter first: until the output is replaced as well, the
+	// real output only receives the empty lines the async formatter returns
+	defaultLogger.SetFormatter(&asyncFormatter{w: a})
+	defaultLogger.SetOutput(io.Discard)
+	defaultLogger.ExitFunc = func(code int) {
+		Flush()
+		os.Exit(code)
+	}
+}
+
+// DisableAsync writes the buffered lines and returns to logging on the caller
+func DisableAsync() {
+	asyncMu.Lock()
+	defer asyncMu.Unlock()
+	if a := async.Load(); a != nil {
+		restoreSync(a)
+	}
+}
+
+// restoreSync hands the output and formatter of a back to the logger and stops
+// its goroutine once the buffered lines are written. Callers hold asyncMu.
+func restoreSync(a *asyncWriter) {
+	out, formatter := a.target()
+	// The reverse order of EnableAsync, so no line is lost or written twice
+	defaultLogger.SetOutput(out)
+	defaultLogger.SetFormatter(formatter)
+	defaultLogger.ExitFunc = nil
+	async.Store(nil)
+	a.close()
+}
+
+// Flush waits until every line logged before the call is written or dropped.
+// It returns at once when async mode is off.
+func Flush() {
+	if a := async.Load(); a != nil {
+		a.flush()
+	}
+}
+
+// GetStats returns the state of the logger
+func GetStats() Stats {
+	a := async.Load()
+	if a == nil {
+		return Stats{}
+	}
+	return a.stats()
+}
+
+// asyncFormatter queues entries for the writer instead of formatting them; the
+// logger writes the empty result to io.Discard
+type asyncFormatter struct {
+	w *asyncWriter
+}
+
+func (f *asyncFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	// The entry is reused by logrus once Format returns; keep what formatters read
+	f.w.push(&logrus.Entry{
+		Data:    entry.Data,
+		Time:    entry.Time,
+		Level:   entry.Level,
+		Caller:  entry.Caller,
+		Message: entry.Message,
+		Context: entry.Context,
+	})
+	return nil, nil
+}
+
+// asyncWriter is a bounded ring buffer of entries drained by one goroutine
+type asyncWriter struct {
+	mu    sync.Mutex
+	ready *sync.Cond // signalled when entries are queued or the writer is closed
+	room  *sync.Cond // broadcast when entries are taken, written or dropped
+
+	ring     []*logrus.Entry
+	head, n  int
+	overflow Overflow
+	queued   uint64 // entries accepted, including dropped ones
+	handled  uint64 // entries written or dropped
+	closed   bool
+	dropped  int64
+
+	sink *logrus.Logger // output and formatter the writer uses, replaced rather than modified
+	done chan struct{}  // closed when the goroutine exits
+}
+
+func newAsyncWriter(opts AsyncOptions, out io.Writer, formatter logrus.Formatter) *asyncWriter {
+	a := &asyncWriter{
+		ring:     make([]*logrus.Entry, opts.BufferSize),
+		overflow: opts.Overflow,
+		sink:     newSink(out, formatter),
+		done:     make(chan struct{}),
+	}
+	a.ready = sync.NewCond(&a.mu)
+	a.room = sync.NewCond(&a.mu)
+	go a.run()
+	return a
+}
+
+// newSink returns the logger the writer formats entries with; formatters read
+// its output to decide whether to color lines for a terminal
+func newSink(out io.Writer, formatter logrus.Formatter) *logrus.Logger {
+	sink := logrus.New()
+	sink.SetOutput(out)
+	sink.SetFormatter(formatter)
+	return sink
+}
+
+// push queues an entry, applying the overflow policy when the buffer is full
+func (a *asyncWriter) push(e *logrus.Entry) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for a.n == len(a.ring) && !a.closed {
+		if a.overflow == DropOldest {
+			a.ring[a.head] = nil
+			a.head = (a.head + 1) % len(a.ring)
+			a.n--
+			a.handled++
+			a.dropped++
+			a.room.Broadcast()
+			break
+		}
+		a.room.Wait()
+	}
+	if a.closed {
+		// Raced with DisableAsync; write it here rather than lose it
+		a.write(a.sink, e)
+		return
+	}
+	a.ring[(a.head+a.n)%len(a.ring)] = e
+	a.n++
+	a.queued++
+	a.ready.Signal()
+}
+
+// run writes queued entries in batches until the writer is closed and drained
+func (a *asyncWriter) run() {
+	defer close(a.done)
+	batch := make([]*logrus.Entry, 0, 256)
+	for {
+		a.mu.Lock()
+		for a.n == 0 && !a.closed {
+			a.ready.Wait()
+		}
+		if a.n == 0 {
+			a.mu.Unlock()
+			return
+		}
+		for a.n > 0 && len(batch) < cap(batch) {
+			batch = append(batch, a.ring[a.head])
+			a.ring[a.head] = nil
+			a.head = (a.head + 1) % len(a.ring)
+			a.n--
+		}
+		sink := a.sink
+		a.room.Broadcast()
+		a.mu.Unlock()
+
+		for _, e := range batch {
+			a.write(sink, e)
+		}
+
+		a.mu.Lock()
+		a.handled += uint64(len(batch))
+		a.room.Broadcast()
+		a.mu.Unlock()
+		clear(batch)
+		batch = batch[:0]
+	}
+}
+
+// write formats and writes one entry, reporting failures like logrus does
+func (a *asyncWriter) write(sink *logrus.Logger, e *logrus.Entry) {
+	e.Logger = sink
+	serialized, err := sink.Formatter.Format(e)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to obtain reader, %v\n", err)
+		return
+	}
+	if _, err := sink.Out.Write(serialized); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write to log, %v\n", err)
+	}
+}
+
+// flush waits until the entries queued so far are handled
+func (a *asyncWriter) flush() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	target := a.queued
+	for a.handled < target {
+		a.room.Wait()
+	}
+}
+
+// close stops accepting entries and waits for the goroutine to write the rest
+func (a *asyncWriter) close() {
+	a.mu.Lock()
+	a.closed = true
+	a.ready.Signal()
+	a.room.Broadcast()
+	a.mu.Unlock()
+	<-a.done
+}
+
+// target returns the output and formatter lines are written with
+func (a *asyncWriter) target() (io.Writer, logrus.Formatter) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.sink.Out, a.sink.Formatter
+}
+
+// setTarget replaces the output or formatter, nil keeps the current one
+func (a *asyncWriter) setTarget(out io.Writer, formatter logrus.Formatter) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if out == nil {
+		out = a.sink.Out
+	}
+	if formatter == nil {
+		formatter = a.sink.Formatter
+	}
+	a.sink = newSink(out, formatter)
+}
+
+func (a *asyncWriter) stats() Stats {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return Stats{Async: true, Overflow: a.overflow.String(), Buffered: a.n, Dropped: a.dropped}
+}
@@ -0,0 +1,232 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// useOutput points the default logger at w and restores its output, formatter
+// and mode when the test ends
+func useOutput(t testing.TB, w io.Writer) {
+	t.Helper()
+	out, formatter := defaultLogger.Out, defaultLogger.Formatter
+	SetOutput(w)
+	t.Cleanup(func() {
+		DisableAsync()
+		defaultLogger.SetOutput(out)
+		defaultLogger.SetFormatter(formatter)
+	})
+}
+
+// syncBuffer is a bytes.Buffer safe for the writer goroutine and the test
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) lines() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return strings.Split(strings.TrimSuffix(b.buf.String(), "\n"), "\n")
+}
+
+// gateWriter blocks every write until open is closed
+type gateWriter struct {
+	open chan struct{}
+	syncBuffer
+}
+
+func (g *gateWriter) Write(p []byte) (int, error) {
+	<-g.open
+	return g.syncBuffer.Write(p)
+}
+
+// TestAsyncKeepsOrderPerGoroutine logs from several goroutines at once and
+// checks that the lines of each goroutine come out in the order they were logged
+func TestAsyncKeepsOrderPerGoroutine(t *testing.T) {
+	var out syncBuffer
+	useOutput(t, &out)
+	UseJSONFormat()
+	EnableAsync(AsyncOptions{BufferSize: 64, Overflow: Block})
+
+	const writers, lines = 8, 2000
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < lines; i++ {
+				WithFields(Fields{"writer": w, "seq": i}).Infof("line %d", i)
+			}
+		}(w)
+	}
+	wg.Wait()
+	Flush()
+
+	next := make([]int, writers)
+	for _, line := range out.lines() {
+		var e struct {
+			Writer, Seq int
+			Msg         string
+		}
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("unparsable line %q: %v", line, err)
+		}
+		if e.Seq != next[e.Writer] || e.Msg != fmt.Sprintf("line %d", e.Seq) {
+			t.Fatalf("writer %d: got seq %d (%q), want %d", e.Writer, e.Seq, e.Msg, next[e.Writer])
+		}
+		next[e.Writer]++
+	}
+	for w, n := range next {
+		if n != lines {
+			t.Fatalf("writer %d: %d lines written, want %d", w, n, lines)
+		}
+	}
+	if s := GetStats(); !s.Async || s.Dropped != 0 || s.Overflow != "block" {
+		t.Fatalf("stats = %+v", s)
+	}
+}
+
+// TestAsyncDropOldest fills the buffer while the output is stuck and checks that
+// the oldest lines are dropped and counted, and the newest survive
+func TestAsyncDropOldest(t *testing.T) {
+	out := &gateWriter{open: make(chan struct{})}
+	useOutput(t, out)
+	UseJSONFormat()
+	EnableAsync(AsyncOptions{BufferSize: 4, Overflow: DropOldest})
+
+	// The first line is taken by the writer, which then blocks on the output
+	Infof("line 0")
+	for GetStats().Buffered != 0 {
+		time.Sleep(time.Millisecond)
+	}
+	for i := 1; i <= 10; i++ {
+		Infof("line %d", i)
+	}
+	if s := GetStats(); s.Dropped != 6 || s.Buffered != 4 {
+		t.Fatalf("stats with the output stuck = %+v, want 6 dropped and 4 buffered", s)
+	}
+	close(out.open)
+	Flush()
+
+	var got []string
+	for _, line := range out.lines() {
+		var e struct{ Msg string }
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, e.Msg)
+	}
+	want := []string{"line 0", "line 7", "line 8", "line 9", "line 10"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("written lines = %q, want %q", got, want)
+	}
+}
+
+// TestAsyncMatchesSync writes the same lines in both modes and compares them
+// without their timestamps
+func TestAsyncMatchesSync(t *testing.T) {
+	log := func() {
+		WithFields(Fields{"group": "users", "node": 3}).Infof("loaded %s", "k1")
+		Warnf("slow peer %s", "10.0.0.2:9090")
+		Debug("plain", " args")
+	}
+	strip := func(lines []string) []string {
+		for i, line := range lines {
+			// Text lines start with time="..."
+			if _, rest, ok := strings.Cut(line, "\" "); ok {
+				lines[i] = rest
+			}
+		}
+		return lines
+	}
+
+	var syncOut, asyncOut syncBuffer
+	useOutput(t, &syncOut)
+	log()
+	SetOutput(&asyncOut)
+	EnableAsync(AsyncOptions{})
+	log()
+	Flush()
+
+	want, got := strip(syncOut.lines()), strip(asyncOut.lines())
+	if len(got) != 3 || fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("async lines:\n%s\nsync lines:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+// TestDisableAsyncWritesTail checks that switching back to sync mode writes the
+// buffered lines before the next synchronous one
+func TestDisableAsyncWritesTail(t *testing.T) {
+	var out syncBuffer
+	useOutput(t, &out)
+	EnableAsync(AsyncOptions{})
+	for i := 0; i < 100; i++ {
+		Infof("async %d", i)
+	}
+	DisableAsync()
+	Infof("sync")
+	if s := GetStats(); s.Async {
+		t.Fatalf("stats after DisableAsync = %+v", s)
+	}
+
+	lines := out.lines()
+	if len(lines) != 101 || !strings.Contains(lines[99], "async 99") || !strings.Contains(lines[100], "msg=sync") {
+		t.Fatalf("%d lines, last two %q", len(lines), lines[len(lines)-2:])
+	}
+}
+
+func TestParseOverflow(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want Overflow
+		ok   bool
+	}{{"", DropOldest, true}, {"drop-oldest", DropOldest, true}, {" Block ", Block, true}, {"drop", DropOldest, false}} {
+		got, err := ParseOverflow(tt.in)
+		if got != tt.want || (err == nil) != tt.ok {
+			t.Errorf("ParseOverflow(%q) = %v, %v", tt.in, got, err)
+		}
+	}
+}
+
+// BenchmarkInfof measures the cost of Infof for the caller, to a file with the
+// text formatter, with async mode on and off
+func BenchmarkInfof(b *testing.B) {
+	for _, mode := range []string{"sync", "async"} {
+		b.Run(mode, func(b *testing.B) {
+			f, err := os.CreateTemp(b.TempDir(), "log")
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer f.Close()
+			w := bufio.NewWriter(f)
+			useOutput(b, w)
+			if mode == "async" {
+				EnableAsync(AsyncOptions{Overflow: Block})
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					Infof("[Cache] HIT - 从本地缓存命中: group:%s key:%s", "users", "user:42")
+				}
+			})
+			b.StopTimer()
+			Flush()
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package logger
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+// KeyMode decides how Key writes cache keys into log lines
+type KeyMode int32
+
+const (
+	// KeyPlain logs keys as they are
+	KeyPlain KeyMode = iota
+	// KeyHash logs a short stable digest of each key, equal on every process,
+	// so lines about the same key can still be correlated
+	KeyHash
+	// KeyOmit leaves keys out of log lines entirely
+	KeyOmit
+)
+
+// keyDigestLen is the number of hex digits of the SHA-256 digest KeyHash logs
+const keyDigestLen = 12
+
+// omittedKey replaces keys in KeyOmit mode
+const omittedKey = "<omitted>"
+
+var keyMode atomic.Int32
+
+// String returns the name of the key mode as accepted by ParseKeyMode
+func (m KeyMode) String() string {
+	switch m {
+	case KeyPlain:
+		return "plain"
+	case KeyHash:
+		return "hash"
+	case KeyOmit:
+		return "omit"
+	default:
+		return fmt.Sprintf("KeyMode(%d)", int(m))
+	}
+}
+
+// ParseKeyMode parses a key mode name; an empty name means KeyPlain
+func ParseKeyMode(s string) (KeyMode, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "plain":
+		return KeyPlain, nil
+	case "hash":
+		return KeyHash, nil
+	case "omit":
+		return KeyOmit, nil
+	default:
+		return KeyPlain, fmt.Errorf("unknown log key mode %q, must be plain, hash or omit", s)
+	}
+}
+
+// SetKeyMode sets how Key writes cache keys, KeyPlain by default
+func SetKeyMode(m KeyMode) {
+	keyMode.Store(int32(m))
+}
+
+// GetKeyMode returns the current key mode
+func GetKeyMode() KeyMode {
+	return KeyMode(keyMode.Load())
+}
+
+// Key returns key as it should appear in a log line under the current key
+// mode. Every log statement that mentions a cache key passes it through Key.
+func Key(key string) string {
+	switch GetKeyMode() {
+	case KeyHash:
+		sum := sha256.Sum256([]byte(key))
+		return "#" + hex.EncodeToString(sum[:])[:keyDigestLen]
+	case KeyOmit:
+		return omittedKey
+	default:
+		return key
+	}
+}
+
+// KeyPath returns a request path of the form prefix+group/key with the key
+// passed through Key. Paths outside prefix, or without a key after the group,
+// are returned unchanged.
+func KeyPath(path, prefix string) string {
+	if GetKeyMode() == KeyPlain || !strings.HasPrefix(path, prefix) {
+		return path
+	}
+	group, key, ok := strings.Cut(path[len(prefix):], "/")
+	if !ok || key == "" {
+		return path
+	}
+	return prefix + group + "/" + Key(key)
+}
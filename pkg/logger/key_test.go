@@ -0,0 +1,90 @@
+package logger
+
+import (
+	"strings"
+	"testing"
+)
+
+// useKeyMode sets the key mode for the rest of the test
+func useKeyMode(t *testing.T, m KeyMode) {
+	t.Helper()
+	old := GetKeyMode()
+	SetKeyMode(m)
+	t.Cleanup(func() { SetKeyMode(old) })
+}
+
+func TestParseKeyMode(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    KeyMode
+		wantErr bool
+	}{
+		{"", KeyPlain, false},
+		{"plain", KeyPlain, false},
+		{" Hash ", KeyHash, false},
+		{"OMIT", KeyOmit, false},
+		{"redact", KeyPlain, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseKeyMode(tt.in)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("ParseKeyMode(%q) = %v, %v, want %v", tt.in, got, err, tt.want)
+		}
+		if err == nil {
+			if back, _ := ParseKeyMode(got.String()); back != got {
+				t.Errorf("%v does not round-trip through String", got)
+			}
+		}
+	}
+}
+
+func TestKey(t *testing.T) {
+	if GetKeyMode() != KeyPlain {
+		t.Fatalf("default key mode %v, want plain", GetKeyMode())
+	}
+	if got := Key("user:42"); got != "user:42" {
+		t.Fatalf("plain Key = %q", got)
+	}
+
+	useKeyMode(t, KeyHash)
+	a, b := Key("user:42"), Key("user:43")
+	if !strings.HasPrefix(a, "#") || len(a) != 1+keyDigestLen || strings.Contains(a, "42") {
+		t.Fatalf("hashed Key = %q", a)
+	}
+	if a == b || Key("user:42") != a {
+		t.Fatalf("digests of user:42 and user:43: %q, %q; want stable and distinct", a, b)
+	}
+	// the digest is the SHA-256 prefix, equal on every process
+	if got := Key("abc"); got != "#ba7816bf8f01" {
+		t.Fatalf("hashed Key(abc) = %q", got)
+	}
+
+	useKeyMode(t, KeyOmit)
+	if got := Key("user:42"); got != omittedKey {
+		t.Fatalf("omitted Key = %q", got)
+	}
+}
+
+func TestKeyPath(t *testing.T) {
+	const prefix = "/api/cache/"
+	paths := []string{"/api/cache/users/user:42", "/api/cache/users/a/b", "/api/cache/users", "/api/cache/users/", "/health"}
+
+	for _, path := range paths {
+		if got := KeyPath(path, prefix); got != path {
+			t.Errorf("plain KeyPath(%q) = %q", path, got)
+		}
+	}
+
+	useKeyMode(t, KeyOmit)
+	want := []string{"/api/cache/users/" + omittedKey, "/api/cache/users/" + omittedKey, "/api/cache/users", "/api/cache/users/", "/health"}
+	for i, path := range paths {
+		if got := KeyPath(path, prefix); got != want[i] {
+			t.Errorf("omitted KeyPath(%q) = %q, want %q", path, got, want[i])
+		}
+	}
+
+	useKeyMode(t, KeyHash)
+	if got := KeyPath("/_gocache/users/user:42", "/_gocache/"); got != "/_gocache/users/"+Key("user:42") {
+		t.Errorf("hashed KeyPath = %q", got)
+	}
+}
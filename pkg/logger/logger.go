@@ -2,6 +2,7 @@
 package logger
 
 import (
+	"fmt"
 	"io"
 	"os"
 	"strings"
@@ -29,6 +30,12 @@ func init() {
 
 // SetOutput sets the output destination for the default logger
 func SetOutput(output io.Writer) {
+	asyncMu.Lock()
+	defer asyncMu.Unlock()
+	if a := async.Load(); a != nil {
+		a.setTarget(output, nil)
+		return
+	}
 	defaultLogger.SetOutput(output)
 }
 
@@ -52,62 +59,110 @@ func SetLevel(level string) {
 
 // UseJSONFormat configures the logger to use JSON formatting
 func UseJSONFormat() {
-	defaultLogger.SetFormatter(&logrus.JSONFormatter{
+	formatter := &logrus.JSONFormatter{
 		TimestampFormat: "2006-01-02 15:04:05",
-	})
+	}
+	asyncMu.Lock()
+	defer asyncMu.Unlock()
+	if a := async.Load(); a != nil {
+		a.setTarget(nil, formatter)
+		return
+	}
+	defaultLogger.SetFormatter(formatter)
 }
 
-// WithFields returns a log entry with pre-populated fields
-func WithFields(fields Fields) *logrus.Entry {
-	return defaultLogger.WithFields(logrus.Fields(fields))
+// WithFields returns a logger that adds fields to every line it writes
+func WithFields(fields Fields) Logger {
+	if l := current(); l != nil {
+		return l.WithFields(fields)
+	}
+	return logrusLogger{entry: defaultLogger.WithFields(logrus.Fields(fields))}
 }
 
 // Debug logs a message at the debug level
 func Debug(args ...interface{}) {
+	if l := current(); l != nil {
+		l.Debugf("%s", fmt.Sprint(args...))
+		return
+	}
 	defaultLogger.Debug(args...)
 }
 
 // Debugf logs a formatted message at the debug level
 func Debugf(format string, args ...interface{}) {
+	if l := current(); l != nil {
+		l.Debugf(format, args...)
+		return
+	}
 	defaultLogger.Debugf(format, args...)
 }
 
 // Info logs a message at the info level
 func Info(args ...interface{}) {
+	if l := current(); l != nil {
+		l.Infof("%s", fmt.Sprint(args...))
+		return
+	}
 	defaultLogger.Info(args...)
 }
 
 // Infof logs a formatted message at the info level
 func Infof(format string, args ...interface{}) {
+	if l := current(); l != nil {
+		l.Infof(format, args...)
+		return
+	}
 	defaultLogger.Infof(format, args...)
 }
 
 // Warn logs a message at the warn level
 func Warn(args ...interface{}) {
+	if l := current(); l != nil {
+		l.Warnf("%s", fmt.Sprint(args...))
+		return
+	}
 	defaultLogger.Warn(args...)
 }
 
 // Warnf logs a formatted message at the warn level
 func Warnf(format string, args ...interface{}) {
+	if l := current(); l != nil {
+		l.Warnf(format, args...)
+		return
+	}
 	defaultLogger.Warnf(format, args...)
 }
 
 // Error logs a message at the error level
 func Error(args ...interface{}) {
+	if l := current(); l != nil {
+		l.Errorf("%s", fmt.Sprint(args...))
+		return
+	}
 	defaultLogger.Error(args...)
 }
 
 // Errorf logs a formatted message at the error level
 func Errorf(format string, args ...interface{}) {
+	if l := current(); l != nil {
+		l.Errorf(format, args...)
+		return
+	}
 	defaultLogger.Errorf(format, args...)
 }
 
 // Fatal logs a message at the fatal level and then exits
 func Fatal(args ...interface{}) {
+	if l := current(); l != nil {
+		fatal(l, fmt.Sprint(args...))
+	}
 	defaultLogger.Fatal(args...)
 }
 
 // Fatalf logs a formatted message at the fatal level and then exits
 func Fatalf(format string, args ...interface{}) {
+	if l := current(); l != nil {
+		fatal(l, fmt.Sprintf(format, args...))
+	}
 	defaultLogger.Fatalf(format, args...)
 }
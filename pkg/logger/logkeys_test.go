@@ -0,0 +1,171 @@
+package logger
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// keyCheckDirs are the directories, relative to the module root, whose log
+// statements must pass cache keys through Key
+var keyCheckDirs = []string{"internal", "api", "cmd", "pkg"}
+
+// rawKeyExemptions lists log statements whose key argument is not a cache key,
+// by file and a fragment of the format string
+var rawKeyExemptions = []struct {
+	file, format string
+}{
+	// the consistent hash logs node names, which it calls keys
+	{"internal/consistenthash/consistenthash.go", "的虚拟节点"},
+}
+
+// isLogReceiver reports whether x is something log statements are called on:
+// the logger package, a component's log field, or a chain such as
+// logger.WithFields(f) built from either
+func isLogReceiver(x ast.Expr) bool {
+	switch x := x.(type) {
+	case *ast.Ident:
+		return x.Name == "logger"
+	case *ast.SelectorExpr:
+		return x.Sel.Name == "log" || isLogReceiver(x.X)
+	case *ast.CallExpr:
+		return isLogReceiver(x.Fun)
+	}
+	return false
+}
+
+// isRawKey reports whether arg is a cache key written as is: key, k, x.Key or
+// x.GetKey(). A key passed through Key is a call to Key and not reported.
+func isRawKey(arg ast.Expr) bool {
+	switch arg := arg.(type) {
+	case *ast.Ident:
+		return arg.Name == "key" || arg.Name == "k"
+	case *ast.SelectorExpr:
+		return arg.Sel.Name == "Key"
+	case *ast.CallExpr:
+		sel, ok := arg.Fun.(*ast.SelectorExpr)
+		return ok && sel.Sel.Name == "GetKey" && len(arg.Args) == 0
+	}
+	return false
+}
+
+// rawKeyLogs returns the positions of the log statements in f that write a
+// cache key without Key; name is the file's path relative to the module root
+func rawKeyLogs(fset *token.FileSet, name string, f *ast.File) []string {
+	var found []string
+	ast.Inspect(f, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok || !strings.HasSuffix(sel.Sel.Name, "f") || !isLogReceiver(sel.X) || len(call.Args) < 2 {
+			return true
+		}
+		format := ""
+		if lit, ok := call.Args[0].(*ast.BasicLit); ok && lit.Kind == token.STRING {
+			format, _ = strconv.Unquote(lit.Value)
+		}
+		for _, e := range rawKeyExemptions {
+			if e.file == name && strings.Contains(format, e.format) {
+				return true
+			}
+		}
+		for _, arg := range call.Args[1:] {
+			if isRawKey(arg) {
+				pos := fset.Position(arg.Pos())
+				found = append(found, name+":"+strconv.Itoa(pos.Line)+": "+sel.Sel.Name+"("+strconv.Quote(format)+", ...)")
+			}
+		}
+		return true
+	})
+	return found
+}
+
+// TestLogCallsWrapKeys fails when a log statement writes a cache key without
+// Key, so that -log-key-mode=hash|omit covers every log line. Statements are
+// matched on the syntax tree, including calls spanning several lines.
+func TestLogCallsWrapKeys(t *testing.T) {
+	root := filepath.Join("..", "..")
+	fset := token.NewFileSet()
+	files := 0
+	for _, dir := range keyCheckDirs {
+		err := filepath.WalkDir(filepath.Join(root, dir), func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if d.Name() == "testdata" {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+				return nil
+			}
+			f, err := parser.ParseFile(fset, path, nil, 0)
+			if err != nil {
+				return err
+			}
+			files++
+			name, _ := filepath.Rel(root, path)
+			for _, found := range rawKeyLogs(fset, filepath.ToSlash(name), f) {
+				t.Errorf("%s writes a cache key without logger.Key", found)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if files == 0 {
+		t.Fatal("no Go files checked")
+	}
+}
+
+// TestRawKeyLogs guards the checker itself
+func TestRawKeyLogs(t *testing.T) {
+	const src = `package p
+
+func f() {
+	logger.Infof("get %s/%s", group, key)
+	logger.Warnf("delete %s/%s failed: %v",
+		req.GetGroup(),
+		req.GetKey(),
+		err)
+	p.log.Debugf("set %s", e.Key)
+	logger.WithFields(fields).Errorf("load %s: %v", k, err)
+
+	logger.Infof("get %s/%s", group, logger.Key(key))
+	logger.Warnf("delete %s/%s failed: %v",
+		req.GetGroup(), logger.Key(req.GetKey()), err)
+	logger.Infof("%d keys", len(keys))
+	fmt.Printf("not a log %s", key)
+	logger.Warnf("一致性哈希: 节点 %s 的虚拟节点 %d 冲突", key, i)
+}
+`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "p.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := rawKeyLogs(fset, "p.go", f)
+	lines := make([]string, len(found))
+	for i, s := range found {
+		lines[i], _, _ = strings.Cut(s, ": ")
+	}
+	want := []string{"p.go:4", "p.go:7", "p.go:9", "p.go:10", "p.go:17"}
+	if strings.Join(lines, " ") != strings.Join(want, " ") {
+		t.Fatalf("found %q, want lines %v", found, want)
+	}
+
+	// the exemption applies to its file only
+	if found := rawKeyLogs(fset, "internal/consistenthash/consistenthash.go", f); len(found) != 4 {
+		t.Fatalf("found %q in the exempt file, want 4", found)
+	}
+}
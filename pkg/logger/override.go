@@ -0,0 +1,93 @@
+package logger
+
+import (
+	"os"
+	"sync/atomic"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Logger is what the cache logs through. The default implementation writes with
+// logrus; programs that embed the cache and log with another library install an
+// adapter with SetLogger, or hand one to a single component through its options.
+type Logger interface {
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+	// WithFields returns a Logger that adds fields to every line it writes
+	WithFields(fields Fields) Logger
+}
+
+// loggerBox lets an interface value be stored in an atomic.Pointer
+type loggerBox struct {
+	l Logger
+}
+
+// custom is the Logger installed by SetLogger, nil while logrus is used
+var custom atomic.Pointer[loggerBox]
+
+// SetLogger routes every line logged through the package functions to l; nil
+// restores the default logrus logger. SetLevel, SetOutput, UseJSONFormat and
+// EnableAsync only configure the default logger, so l filters levels and
+// formats lines itself. Fatal and Fatalf write through l at the error level,
+// then exit.
+func SetLogger(l Logger) {
+	if l == nil {
+		custom.Store(nil)
+		return
+	}
+	custom.Store(&loggerBox{l: l})
+}
+
+// current returns the Logger installed by SetLogger, or nil
+func current() Logger {
+	if b := custom.Load(); b != nil {
+		return b.l
+	}
+	return nil
+}
+
+// Default returns a Logger that writes through the package functions, and so
+// follows SetLogger. Components use it when no logger is passed in their options.
+func Default() Logger {
+	return packageLogger{}
+}
+
+// Or returns l, or Default when l is nil
+func Or(l Logger) Logger {
+	if l == nil {
+		return Default()
+	}
+	return l
+}
+
+// packageLogger is the Logger returned by Default
+type packageLogger struct{}
+
+func (packageLogger) Debugf(format string, args ...interface{}) { Debugf(format, args...) }
+func (packageLogger) Infof(format string, args ...interface{})  { Infof(format, args...) }
+func (packageLogger) Warnf(format string, args ...interface{})  { Warnf(format, args...) }
+func (packageLogger) Errorf(format string, args ...interface{}) { Errorf(format, args...) }
+func (packageLogger) WithFields(fields Fields) Logger           { return WithFields(fields) }
+
+// logrusLogger adapts a logrus entry to Logger
+type logrusLogger struct {
+	entry *logrus.Entry
+}
+
+func (l logrusLogger) Debugf(format string, args ...interface{}) { l.entry.Debugf(format, args...) }
+func (l logrusLogger) Infof(format string, args ...interface{})  { l.entry.Infof(format, args...) }
+func (l logrusLogger) Warnf(format string, args ...interface{})  { l.entry.Warnf(format, args...) }
+func (l logrusLogger) Errorf(format string, args ...interface{}) { l.entry.Errorf(format, args...) }
+
+func (l logrusLogger) WithFields(fields Fields) Logger {
+	return logrusLogger{entry: l.entry.WithFields(logrus.Fields(fields))}
+}
+
+// fatal writes a fatal line through l, which has no fatal level, and exits
+func fatal(l Logger, msg string) {
+	l.Errorf("%s", msg)
+	Flush()
+	os.Exit(1)
+}
@@ -0,0 +1,141 @@
+package logger
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// recordingLogger records every line as "LEVEL message" followed by its
+// fields in key order; loggers made by WithFields share the record
+type recordingLogger struct {
+	mu     *sync.Mutex
+	lines  *[]string
+	fields Fields
+}
+
+func newRecordingLogger() *recordingLogger {
+	return &recordingLogger{mu: new(sync.Mutex), lines: new([]string)}
+}
+
+func (l *recordingLogger) record(level, format string, args []interface{}) {
+	line := level + " " + fmt.Sprintf(format, args...)
+	keys := make([]string, 0, len(l.fields))
+	for k := range l.fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		line += fmt.Sprintf(" %s=%v", k, l.fields[k])
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	*l.lines = append(*l.lines, line)
+}
+
+func (l *recordingLogger) Debugf(format string, args ...interface{}) { l.record("DEBUG", format, args) }
+func (l *recordingLogger) Infof(format string, args ...interface{})  { l.record("INFO", format, args) }
+func (l *recordingLogger) Warnf(format string, args ...interface{})  { l.record("WARN", format, args) }
+func (l *recordingLogger) Errorf(format string, args ...interface{}) { l.record("ERROR", format, args) }
+
+func (l *recordingLogger) WithFields(fields Fields) Logger {
+	merged := make(Fields, len(l.fields)+len(fields))
+	for k, v := range l.fields {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	return &recordingLogger{mu: l.mu, lines: l.lines, fields: merged}
+}
+
+// recorded returns the lines recorded so far
+func (l *recordingLogger) recorded() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), *l.lines...)
+}
+
+// useLogger installs l with SetLogger for the rest of the test
+func useLogger(t *testing.T, l Logger) {
+	t.Helper()
+	SetLogger(l)
+	t.Cleanup(func() { SetLogger(nil) })
+}
+
+func TestSetLogger(t *testing.T) {
+	out := new(syncBuffer)
+	useOutput(t, out)
+	rec := newRecordingLogger()
+	useLogger(t, rec)
+
+	Debugf("debug %d", 1)
+	Debug("debug ", 2)
+	Infof("info %s", "a")
+	Info("info b")
+	Warnf("warn %v", true)
+	Warn("warn")
+	Errorf("error %q", "x")
+	Error("error")
+	WithFields(Fields{"group": "scores"}).WithFields(Fields{"node": "n1"}).Infof("with %s", "fields")
+	Default().Warnf("default")
+	Default().WithFields(Fields{"k": 1}).Errorf("default with fields")
+
+	want := []string{
+		"DEBUG debug 1",
+		"DEBUG debug 2",
+		"INFO info a",
+		"INFO info b",
+		"WARN warn true",
+		"WARN warn",
+		`ERROR error "x"`,
+		"ERROR error",
+		"INFO with fields group=scores node=n1",
+		"WARN default",
+		"ERROR default with fields k=1",
+	}
+	if got := rec.recorded(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("recorded\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if out.buf.Len() != 0 {
+		t.Fatalf("the default logger wrote %q while overridden", out.lines())
+	}
+
+	// nil restores logrus
+	SetLogger(nil)
+	Infof("back to logrus")
+	if lines := out.lines(); len(lines) != 1 || !strings.Contains(lines[0], "back to logrus") {
+		t.Fatalf("default logger wrote %q after SetLogger(nil)", lines)
+	}
+	if n := len(rec.recorded()); n != len(want) {
+		t.Fatalf("the removed logger recorded %d lines, want %d", n, len(want))
+	}
+}
+
+// TestDefaultFollowsSetLogger checks a logger taken from Default before
+// SetLogger, as components do when they are created, switches with it
+func TestDefaultFollowsSetLogger(t *testing.T) {
+	useOutput(t, new(syncBuffer))
+	l := Default()
+	fields := Default().WithFields(Fields{"k": "v"}) // bound to logrus when made
+
+	rec := newRecordingLogger()
+	useLogger(t, rec)
+	l.Infof("after SetLogger")
+	fields.Infof("made before SetLogger")
+	if got := rec.recorded(); len(got) != 1 || got[0] != "INFO after SetLogger" {
+		t.Fatalf("recorded %q", got)
+	}
+}
+
+func TestOr(t *testing.T) {
+	rec := newRecordingLogger()
+	if Or(rec) != Logger(rec) {
+		t.Fatal("Or(l) is not l")
+	}
+	if _, ok := Or(nil).(packageLogger); !ok {
+		t.Fatalf("Or(nil) = %T, want the package logger", Or(nil))
+	}
+}
@@ -0,0 +1,66 @@
+package lru
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAccessInfo(t *testing.T) {
+	for _, policy := range allPolicies {
+		t.Run(policy.String(), func(t *testing.T) {
+			clock := NewFakeClock(time.Unix(1000, 0))
+			c := New(0, nil, WithClock(clock), WithPolicy(policy))
+			c.Add("forever", testValue("v"), 0)
+			c.Add("short", testValue("v"), time.Minute)
+
+			for _, key := range []string{"forever", "short"} {
+				if last, n, ok := c.AccessInfo(key); !ok || n != 0 || !last.IsZero() {
+					t.Fatalf("%s before any Get: %v %d %v", key, last, n, ok)
+				}
+				for i := 1; i <= 3; i++ {
+					clock.Advance(time.Second)
+					_, expiry, ok := c.GetWithExpiry(key)
+					if !ok || expiry.Accesses != uint64(i) {
+						t.Fatalf("%s Get %d: accesses %d", key, i, expiry.Accesses)
+					}
+				}
+				// Peek, Range and AccessInfo are not reads
+				c.Peek(key)
+				c.Range(func(string, Value, Expiry) bool { return true })
+				last, n, ok := c.AccessInfo(key)
+				if !ok || n != 3 || !last.Equal(clock.Now()) {
+					t.Fatalf("%s after 3 Gets: %v %d %v, want %v 3", key, last, n, ok, clock.Now())
+				}
+				_, expiry, _ := c.Peek(key)
+				if expiry.Accesses != 3 || !expiry.LastAccess.Equal(last) {
+					t.Fatalf("%s Peek expiry %+v", key, expiry)
+				}
+			}
+
+			clock.Advance(time.Hour)
+			if _, _, ok := c.AccessInfo("short"); ok {
+				t.Fatal("AccessInfo reported an expired entry")
+			}
+			if _, _, ok := c.AccessInfo("missing"); ok {
+				t.Fatal("AccessInfo reported a missing entry")
+			}
+		})
+	}
+}
+
+func TestAccessTrackingDisabled(t *testing.T) {
+	for _, policy := range allPolicies {
+		t.Run(policy.String(), func(t *testing.T) {
+			c := New(0, nil, WithPolicy(policy), WithAccessTracking(false))
+			c.Add("forever", testValue("v"), 0)
+			for i := 0; i < 10; i++ {
+				if _, expiry, ok := c.GetWithExpiry("forever"); !ok || expiry.Accesses != 0 {
+					t.Fatalf("Get %d: accesses %d", i, expiry.Accesses)
+				}
+			}
+			if last, n, ok := c.AccessInfo("forever"); !ok || n != 0 || !last.IsZero() {
+				t.Fatalf("AccessInfo = %v %d %v", last, n, ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package lru
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock supplies the current time to the cache
+type Clock interface {
+	Now() time.Time
+}
+
+// RealClock is a Clock backed by time.Now
+type RealClock struct{}
+
+// Now returns the current wall-clock time
+func (RealClock) Now() time.Time {
+	return time.Now()
+}
+
+// FakeClock is a manually driven Clock for deterministic expiry behavior
+type FakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewFakeClock creates a FakeClock starting at now
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+// Now returns the clock's current time
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance moves the clock forward by d
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// Set moves the clock to t
+func (c *FakeClock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = t
+}
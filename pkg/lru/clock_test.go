@@ -0,0 +1,181 @@
+package lru
+
+import (
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testValue is a string value for tests
+type testValue string
+
+func (v testValue) Len() int { return len(v) }
+
+// countingClock is a FakeClock that counts how often it is read
+type countingClock struct {
+	*FakeClock
+	reads atomic.Int64
+}
+
+func newCountingClock() *countingClock {
+	return &countingClock{FakeClock: NewFakeClock(time.Unix(1000, 0))}
+}
+
+func (c *countingClock) Now() time.Time {
+	c.reads.Add(1)
+	return c.FakeClock.Now()
+}
+
+var allPolicies = []Policy{PolicyLRU, PolicyClock, PolicyCost}
+
+func TestExpiryWithFakeClock(t *testing.T) {
+	for _, policy := range allPolicies {
+		t.Run(policy.String(), func(t *testing.T) {
+			clock := NewFakeClock(time.Unix(1000, 0))
+			c := New(0, nil, WithClock(clock), WithPolicy(policy))
+			c.Add("short", testValue("v"), time.Second)
+			c.Add("long", testValue("v"), time.Minute)
+			c.Add("forever", testValue("v"), 0)
+
+			// An entry is served up to and including its expiry time
+			clock.Advance(time.Second)
+			if _, ok := c.Get("short"); !ok {
+				t.Fatal("entry expired at its expiry time")
+			}
+			clock.Advance(time.Nanosecond)
+			if _, ok := c.Get("short"); ok {
+				t.Fatal("entry served after its expiry time")
+			}
+			if c.Len() != 2 {
+				t.Fatalf("Len = %d, want the expired entry removed", c.Len())
+			}
+
+			clock.Advance(24 * time.Hour)
+			if _, ok := c.Get("long"); ok {
+				t.Fatal("long entry served after its expiry time")
+			}
+			if _, ok := c.Get("forever"); !ok {
+				t.Fatal("entry without ttl expired")
+			}
+		})
+	}
+}
+
+func TestRewriteRestartsTTL(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1000, 0))
+	c := New(0, nil, WithClock(clock))
+	c.Add("k", testValue("v1"), 10*time.Second)
+	clock.Advance(8 * time.Second)
+	c.Add("k", testValue("v2"), 10*time.Second)
+	clock.Advance(8 * time.Second)
+
+	v, expiry, ok := c.GetWithExpiry("k")
+	if !ok || v != testValue("v2") {
+		t.Fatalf("Get = %v, %v; want v2", v, ok)
+	}
+	if want := time.Unix(1018, 0); !expiry.Expires.Equal(want) {
+		t.Fatalf("Expires = %v, want %v", expiry.Expires, want)
+	}
+	if got := expiry.Consumed(clock.Now()); got != 0.8 {
+		t.Fatalf("Consumed = %v, want 0.8", got)
+	}
+}
+
+// TestMaxIdleSlides reads keep an idle-limited entry alive, while MaxAge is not extended by them
+func TestMaxIdleSlides(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1000, 0))
+	c := New(0, nil, WithClock(clock), WithMaxIdle(10*time.Second), WithMaxAge(time.Minute))
+	c.Add("read", testValue("v"), 0)
+	c.Add("idle", testValue("v"), 0)
+
+	for i := 0; i < 5; i++ {
+		clock.Advance(9 * time.Second)
+		if _, ok := c.Get("read"); !ok {
+			t.Fatalf("entry read every 9s expired after %d reads", i)
+		}
+	}
+	if _, ok := c.Get("idle"); ok {
+		t.Fatal("entry idle for 45s was served")
+	}
+
+	clock.Advance(9 * time.Second)
+	c.Get("read")
+	clock.Advance(9 * time.Second)
+	if _, ok := c.Get("read"); ok {
+		t.Fatal("entry served past MaxAge")
+	}
+}
+
+func TestRemoveExpiredWithFakeClock(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1000, 0))
+	c := New(0, nil, WithClock(clock))
+	for i := 0; i < 10; i++ {
+		c.Add(strconv.Itoa(i), testValue("v"), time.Duration(i+1)*time.Second)
+	}
+	c.Add("forever", testValue("v"), 0)
+
+	clock.Advance(5*time.Second + time.Nanosecond)
+	if n := c.RemoveExpired(); n != 5 {
+		t.Fatalf("RemoveExpired = %d, want 5", n)
+	}
+	if c.Len() != 6 {
+		t.Fatalf("Len = %d, want 6", c.Len())
+	}
+}
+
+// TestNoClockReadsWithoutTTL entries without a ttl are served without reading
+// the clock once access tracking is off
+func TestNoClockReadsWithoutTTL(t *testing.T) {
+	for _, policy := range allPolicies {
+		t.Run(policy.String(), func(t *testing.T) {
+			clock := newCountingClock()
+			c := New(0, nil, WithClock(clock), WithPolicy(policy), WithAccessTracking(false))
+			c.Add("k", testValue("v"), 0)
+			for i := 0; i < 100; i++ {
+				c.Get("k")
+			}
+			if n := clock.reads.Load(); n != 0 {
+				t.Fatalf("clock read %d times", n)
+			}
+
+			// With a ttl every Get has to check the clock
+			c.Add("ttl", testValue("v"), time.Minute)
+			before := clock.reads.Load()
+			c.Get("ttl")
+			if clock.reads.Load() == before {
+				t.Fatal("Get of an entry with a ttl did not read the clock")
+			}
+		})
+	}
+}
+
+func benchmarkGet(b *testing.B, ttl time.Duration, opts ...Option) {
+	clock := newCountingClock()
+	c := New(0, nil, append([]Option{WithClock(clock)}, opts...)...)
+	keys := make([]string, 1024)
+	for i := range keys {
+		keys[i] = strconv.Itoa(i)
+		c.Add(keys[i], testValue("value"), ttl)
+	}
+	before := clock.reads.Load()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.Get(keys[i%len(keys)])
+	}
+	b.ReportMetric(float64(clock.reads.Load()-before)/float64(b.N), "clockreads/op")
+}
+
+// BenchmarkGetNoTTL reports 0 clockreads/op: the never-expires sentinel is
+// recognized without reading the clock
+func BenchmarkGetNoTTL(b *testing.B) {
+	benchmarkGet(b, 0, WithAccessTracking(false))
+}
+
+func BenchmarkGetNoTTLTracked(b *testing.B) {
+	benchmarkGet(b, 0)
+}
+
+func BenchmarkGetTTL(b *testing.B) {
+	benchmarkGet(b, time.Hour)
+}
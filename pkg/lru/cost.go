@@ -0,0 +1,130 @@
+package lru
+
+import (
+	"container/heap"
+	"container/list"
+)
+
+// CostFunc returns the cost of keeping value under key, accounted against the
+// cache's limit instead of len(key)+value.Len(). Negative costs count as 0.
+type CostFunc func(key string, value Value) int64
+
+// WithCostFunc replaces the byte size of entries with fn in the accounting
+// against maxBytes, so the limit bounds the total cost rather than memory. The
+// byte size is still tracked and reported by Bytes; Cost reports the total cost.
+// fn is called under the cache's write lock on every Add and must be cheap.
+func WithCostFunc(fn CostFunc) Option {
+	return func(c *Cache) {
+		c.costFunc = fn
+	}
+}
+
+// charge returns the byte size of an entry and the cost accounted for it
+func (c *Cache) charge(key string, value Value) (size, cost int64) {
+	size = int64(len(key)) + int64(value.Len())
+	if c.costFunc == nil {
+		return size, size
+	}
+	cost = c.costFunc(key, value)
+	if cost < 0 {
+		cost = 0
+	}
+	return size, cost
+}
+
+// account records the byte size and cost of kv, replacing what it was charged before
+func (c *Cache) account(kv *entry, size, cost int64) {
+	c.nbytes.Add(size - kv.size)
+	c.ncost.Add(cost - kv.cost)
+	kv.size, kv.cost = size, cost
+}
+
+// release removes the charge of an entry leaving the cache
+func (c *Cache) release(kv *entry) {
+	c.nbytes.Add(-kv.size)
+	c.ncost.Add(-kv.cost)
+}
+
+// Cost returns the total cost accounted against maxBytes, equal to Bytes unless
+// WithCostFunc is set
+func (c *Cache) Cost() int64 {
+	return c.ncost.Load()
+}
+
+// costHeap orders the entries of a PolicyCost cache by GreedyDual-Size priority,
+// breaking ties by the least recent use
+type costHeap []*entry
+
+func (h costHeap) Len() int { return len(h) }
+
+func (h costHeap) Less(i, j int) bool {
+	if h[i].priority != h[j].priority {
+		return h[i].priority < h[j].priority
+	}
+	return h[i].tick < h[j].tick
+}
+
+func (h costHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *costHeap) Push(x any) {
+	kv := x.(*entry)
+	kv.index = len(*h)
+	*h = append(*h, kv)
+}
+
+func (h *costHeap) Pop() any {
+	old := *h
+	kv := old[len(old)-1]
+	old[len(old)-1] = nil
+	kv.index = -1
+	*h = old[:len(old)-1]
+	return kv
+}
+
+// prioritize sets the GreedyDual-Size priority of kv after an insert, rewrite or
+// read: the current inflation plus its cost per byte, so entries that are
+// expensive for their size outlive cheap ones, and entries not used for a while
+// fall behind as the inflation rises with every eviction
+func (c *Cache) prioritize(kv *entry) {
+	size := kv.size
+	if size <= 0 {
+		size = 1
+	}
+	c.ticks++
+	kv.tick = c.ticks
+	kv.priority = c.inflation + float64(kv.cost)/float64(size)
+}
+
+// costInsert adds a new entry to the priority heap
+func (c *Cache) costInsert(kv *entry) {
+	c.prioritize(kv)
+	heap.Push(&c.costs, kv)
+}
+
+// costTouch refreshes the priority of an entry that was read or rewritten
+func (c *Cache) costTouch(kv *entry) {
+	c.prioritize(kv)
+	heap.Fix(&c.costs, kv.index)
+}
+
+// costRemove drops an entry leaving the cache from the priority heap
+func (c *Cache) costRemove(kv *entry) {
+	if kv.index >= 0 && kv.index < len(c.costs) && c.costs[kv.index] == kv {
+		heap.Remove(&c.costs, kv.index)
+	}
+}
+
+// evictCost returns the entry with the lowest priority and raises the inflation
+// to it, or nil for an empty cache
+func (c *Cache) evictCost() *list.Element {
+	if len(c.costs) == 0 {
+		return nil
+	}
+	kv := c.costs[0]
+	c.inflation = kv.priority
+	return c.cache[kv.key]
+}
@@ -0,0 +1,109 @@
+package lru
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// prefixCost charges entries whose key starts with "exp-" 500 and all others 95
+func prefixCost(key string, _ Value) int64 {
+	if strings.HasPrefix(key, "exp-") {
+		return 500
+	}
+	return 95
+}
+
+func TestCostFuncAccounting(t *testing.T) {
+	for _, policy := range allPolicies {
+		t.Run(policy.String(), func(t *testing.T) {
+			c := New(1000, nil, WithPolicy(policy), WithCostFunc(prefixCost))
+			c.Add("a", testValue("12345"), 0)
+			c.Add("exp-b", testValue("12345"), 0)
+			if c.Bytes() != 6+10 || c.Cost() != 95+500 {
+				t.Fatalf("Bytes %d, Cost %d after two adds", c.Bytes(), c.Cost())
+			}
+
+			// rewriting an entry replaces its charge rather than adding to it
+			c.Add("a", testValue("1234567890"), 0)
+			if c.Bytes() != 11+10 || c.Cost() != 95+500 {
+				t.Fatalf("Bytes %d, Cost %d after a rewrite", c.Bytes(), c.Cost())
+			}
+
+			// the limit bounds the cost: a third expensive entry evicts
+			c.Add("exp-c", testValue("x"), 0)
+			if c.Cost() > 1000 || c.Evictions() == 0 {
+				t.Fatalf("Cost %d over the limit, %d evictions", c.Cost(), c.Evictions())
+			}
+
+			c.Delete("exp-c")
+			if c.Bytes() < 0 || c.Cost() < 0 {
+				t.Fatalf("negative totals: Bytes %d, Cost %d", c.Bytes(), c.Cost())
+			}
+			c.Clear()
+			if c.Bytes() != 0 || c.Cost() != 0 || c.Len() != 0 {
+				t.Fatalf("Bytes %d, Cost %d, Len %d after Clear", c.Bytes(), c.Cost(), c.Len())
+			}
+		})
+	}
+}
+
+func TestCostFuncNegativeAndDefault(t *testing.T) {
+	c := New(0, nil, WithCostFunc(func(string, Value) int64 { return -5 }))
+	c.Add("k", testValue("v"), 0)
+	if c.Cost() != 0 || c.Bytes() != 2 {
+		t.Fatalf("Cost %d, Bytes %d with a negative cost", c.Cost(), c.Bytes())
+	}
+
+	// without a cost function the cost is the byte size
+	d := New(0, nil)
+	d.Add("k", testValue("value"), 0)
+	if d.Cost() != d.Bytes() || d.Bytes() != 6 {
+		t.Fatalf("Cost %d, Bytes %d without a cost function", d.Cost(), d.Bytes())
+	}
+}
+
+// costSimulation replays a trace where 98% of reads go to 10000 cheap keys and
+// 2% to 20 expensive ones against a cache holding a cost of 20000, and returns
+// the hits on expensive keys and the cost the hits saved the data source
+func costSimulation(policy Policy) (expensiveHits int, saved int64) {
+	c := New(20000, nil, WithPolicy(policy), WithCostFunc(prefixCost), WithAccessTracking(false))
+	value := testValue(strings.Repeat("v", 90))
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 200000; i++ {
+		var key string
+		if rng.Intn(100) < 2 {
+			key = "exp-" + traceKey(uint64(rng.Intn(20)))
+		} else {
+			key = traceKey(uint64(rng.Intn(10000)))
+		}
+		if _, ok := c.Get(key); ok {
+			saved += prefixCost(key, value)
+			if strings.HasPrefix(key, "exp-") {
+				expensiveHits++
+			}
+			continue
+		}
+		c.Add(key, value, 0)
+	}
+	return expensiveHits, saved
+}
+
+// TestCostPolicyRetainsExpensiveEntries shows that under pressure from cheap
+// keys the cost-aware policy keeps the expensive ones that LRU and CLOCK evict
+func TestCostPolicyRetainsExpensiveEntries(t *testing.T) {
+	hits := make(map[Policy]int)
+	saved := make(map[Policy]int64)
+	for _, policy := range allPolicies {
+		hits[policy], saved[policy] = costSimulation(policy)
+		t.Logf("%s: %d expensive hits, %d cost saved", policy, hits[policy], saved[policy])
+	}
+	for _, other := range []Policy{PolicyLRU, PolicyClock} {
+		if hits[PolicyCost] < 2*hits[other] {
+			t.Errorf("cost policy: %d expensive hits, %s: %d", hits[PolicyCost], other, hits[other])
+		}
+		if saved[PolicyCost] <= saved[other] {
+			t.Errorf("cost policy saved %d, %s saved %d", saved[PolicyCost], other, saved[other])
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package lru
+
+import (
+	"fmt"
+	"time"
+)
+
+// EvictReason tells why an entry left the cache
+type EvictReason int
+
+const (
+	// EvictCapacity removed the entry to stay under maxBytes
+	EvictCapacity EvictReason = iota
+	// EvictExpired removed the entry because its ttl, MaxAge or MaxIdle passed
+	EvictExpired
+	// EvictDeleted removed the entry on Delete
+	EvictDeleted
+)
+
+// String returns the name of the reason
+func (r EvictReason) String() string {
+	switch r {
+	case EvictCapacity:
+		return "capacity"
+	case EvictExpired:
+		return "expired"
+	case EvictDeleted:
+		return "deleted"
+	default:
+		return fmt.Sprintf("EvictReason(%d)", int(r))
+	}
+}
+
+// Eviction describes an entry leaving the cache, see WithEvictionCallback
+type Eviction struct {
+	Key    string
+	Value  Value
+	Reason EvictReason
+	Age    time.Duration // time since the key was first inserted
+}
+
+// WithEvictionCallback calls fn for every entry that leaves the cache, except
+// on Clear, with the reason and the age of the entry. Entries are stamped with
+// their insertion time for it, which costs a clock read on every Add. fn runs
+// under the cache lock, so it must be cheap and must not call back into the cache.
+func WithEvictionCallback(fn func(Eviction)) Option {
+	return func(c *Cache) {
+		c.onEvict = fn
+	}
+}
+
+// stampsCreated reports whether Add must record the insertion time of entries
+// that never expire
+func (c *Cache) stampsCreated() bool {
+	return c.tracksAge() || c.onEvict != nil
+}
+
+// evicted reports the removal of kv to the eviction callback
+func (c *Cache) evicted(kv *entry, reason EvictReason) {
+	if c.onEvict == nil {
+		return
+	}
+	c.onEvict(Eviction{Key: kv.key, Value: kv.value, Reason: reason, Age: c.clock.Now().Sub(kv.created)})
+}
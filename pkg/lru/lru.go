@@ -5,6 +5,7 @@ import (
 	"container/list"
 	"math"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/AdrianWangs/go-cache/pkg/logger"
@@ -19,32 +20,145 @@ type Value interface {
 // Cache is a thread-safe LRU (Least Recently Used) cache implementation
 type Cache struct {
 	mutex     sync.RWMutex
-	maxBytes  int64                    // maximum memory limit (0 means no limit)
-	nbytes    int64                    // current memory usage in bytes
+	maxBytes  int64                    // limit on the accounted cost, the byte size unless WithCostFunc (0 means no limit)
+	nbytes    atomic.Int64             // current memory usage in bytes
+	ncost     atomic.Int64             // current cost accounted against maxBytes
+	length    atomic.Int64             // number of entries, ll.Len() readable without the lock
+	costFunc  CostFunc                 // cost of an entry, nil to use its byte size
 	ll        *list.List               // doubly linked list for LRU order tracking
 	cache     map[string]*list.Element // hashmap for O(1) lookups
+	evictions atomic.Int64             // number of entries removed to respect maxBytes
+	clock     Clock                    // time source for expiry
+	maxAge    time.Duration            // absolute lifetime from insertion, 0 means unlimited
+	maxIdle   time.Duration            // max time without a read or write, 0 means unlimited
+	writes    uint64                   // number of Adds so far, used as entry version
+	untracked bool                     // skip access counting and the clock read it needs, see WithAccessTracking
+	policy    Policy                   // eviction policy, see WithPolicy
+	hand      *list.Element            // next entry the CLOCK sweep examines, nil to start at the front
+	costs     costHeap                 // entries by GreedyDual-Size priority, only for PolicyCost
+	inflation float64                  // GreedyDual-Size inflation, the priority of the last evicted entry
+	ticks     uint64                   // use counter breaking priority ties for PolicyCost
 	OnEvicted func(key string, value Value)
+
+	onEvict func(Eviction) // called with the reason of every removal, see WithEvictionCallback
+}
+
+// Option configures a Cache
+type Option func(*Cache)
+
+// WithClock sets the time source used for expiry, defaulting to RealClock
+func WithClock(clock Clock) Option {
+	return func(c *Cache) {
+		if clock != nil {
+			c.clock = clock
+		}
+	}
+}
+
+// WithMaxAge bounds how long an entry may live after it was first inserted,
+// regardless of how often it is read or rewritten
+func WithMaxAge(d time.Duration) Option {
+	return func(c *Cache) {
+		c.maxAge = d
+	}
+}
+
+// WithAccessTracking controls whether every Get stamps the entry's last access
+// time and increments its access count, which is the default. Disabling it saves
+// a clock read and a counter update per Get on entries that never expire; the
+// last access time is then only recorded when Get reads the clock anyway (ttl,
+// MaxAge or MaxIdle) and the access count stays 0.
+func WithAccessTracking(enabled bool) Option {
+	return func(c *Cache) {
+		c.untracked = !enabled
+	}
+}
+
+// WithMaxIdle removes entries that have been neither read nor written for d
+func WithMaxIdle(d time.Duration) Option {
+	return func(c *Cache) {
+		c.maxIdle = d
+	}
 }
 
-// entry represents a key-value pair stored in the cache
+// neverExpires is the expiry sentinel for entries added with ttl <= 0; such
+// entries are recognized by comparison and never consult the clock on Get.
+// It must not be compared with Before or After: the seconds overflow time's
+// internal representation, so it sorts before any real time.
+var neverExpires = time.Unix(math.MaxInt64, 0)
+
+// entry represents a key-value pair stored in the cache. Access tracking costs
+// 16 bytes per entry (lastAccess and accesses) and the CLOCK reference bit 4
+// more; like the rest of the entry bookkeeping they are not included in the
+// bytes accounted against maxBytes, which only covers keys and values.
+// size and cost are what the entry was charged when last written, so removing
+// it always gives back exactly that, even if a CostFunc is not deterministic.
 type entry struct {
-	key   string
-	value Value
-	exp   time.Time
+	key        string
+	value      Value
+	exp        time.Time
+	ttl        time.Duration // ttl the entry was last written with, 0 means no expiry
+	lastAccess atomic.Int64  // unix nanoseconds of the last successful Get, 0 if never read or untracked
+	accesses   atomic.Uint64 // number of successful Gets, 0 when access tracking is disabled
+	referenced atomic.Bool   // CLOCK reference bit, set by Get and cleared by the eviction sweep
+	created    time.Time     // first insertion, only set when MaxAge, MaxIdle or an eviction callback is configured or ttl > 0
+	written    time.Time     // last Add, set under the same conditions as created
+	version    uint64        // write sequence number of the last Add
+	size       int64         // len(key) + value.Len() when last written
+	cost       int64         // cost accounted against maxBytes, see WithCostFunc
+	priority   float64       // GreedyDual-Size priority, only for PolicyCost
+	tick       uint64        // last use, breaks priority ties for PolicyCost
+	index      int           // position in the cost heap, only for PolicyCost
+}
+
+// Expiry describes the lifetime of an entry as seen by a Get
+type Expiry struct {
+	TTL        time.Duration // ttl the entry was last written with, 0 means no expiry
+	Expires    time.Time     // absolute expiry time
+	LastAccess time.Time     // previous successful Get before this one, zero if none or untracked
+	Accesses   uint64        // successful Gets so far, including this one for GetWithExpiry
+	Created    time.Time     // first insertion of the key, zero if untracked
+	Version    uint64        // write sequence number, increases with every Add to the cache
+}
+
+// Consumed returns the fraction of the ttl that has elapsed at now,
+// or 0 for entries that never expire
+func (e Expiry) Consumed(now time.Time) float64 {
+	if e.TTL <= 0 {
+		return 0
+	}
+	remaining := e.Expires.Sub(now)
+	return 1 - float64(remaining)/float64(e.TTL)
 }
 
 // New creates a new LRU cache with the specified memory limit and eviction callback
-func New(maxBytes int64, onEvicted func(key string, value Value)) *Cache {
-	return &Cache{
+func New(maxBytes int64, onEvicted func(key string, value Value), opts ...Option) *Cache {
+	c := &Cache{
 		maxBytes:  maxBytes,
 		ll:        list.New(),
 		cache:     make(map[string]*list.Element),
+		clock:     RealClock{},
 		OnEvicted: onEvicted,
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
 }
 
 // Get retrieves a value from the cache, moving it to the front (most recently used)
 func (c *Cache) Get(key string) (value Value, ok bool) {
+	value, _, ok = c.GetWithExpiry(key)
+	return value, ok
+}
+
+// GetWithExpiry is like Get but also reports the entry's ttl, expiry time,
+// the time it was previously read and how often it has been read
+func (c *Cache) GetWithExpiry(key string) (value Value, expiry Expiry, ok bool) {
+	if c.policy == PolicyClock {
+		return c.getClock(key)
+	}
+
 	c.mutex.RLock()
 	if ele, ok := c.cache[key]; ok {
 		c.mutex.RUnlock()
@@ -52,29 +166,170 @@ func (c *Cache) Get(key string) (value Value, ok bool) {
 		c.mutex.Lock()
 		defer c.mutex.Unlock()
 
-		// 获取条目并检查过期时间
+		// 释放读锁后条目可能已被删除、淘汰或替换，此时按未命中处理
+		if c.cache[key] != ele {
+			return nil, Expiry{}, false
+		}
 		kv := ele.Value.(*entry)
-		now := time.Now()
+
+		// 永不过期且未配置 MaxAge/MaxIdle 的条目只在记录访问信息时读取时钟
+		if kv.exp == neverExpires && !c.tracksAge() {
+			c.used(ele)
+			if c.untracked {
+				return kv.value, kv.expiry(), true
+			}
+			expiry = kv.expiry()
+			kv.lastAccess.Store(c.clock.Now().UnixNano())
+			expiry.Accesses = kv.accesses.Add(1)
+			return kv.value, expiry, true
+		}
+
+		// 获取条目并检查过期时间
+		now := c.clock.Now()
 
 		// 过期就删除
-		if kv.exp.Before(now) {
-			logger.Infof("缓存项已过期: key=%s, 过期时间=%v, 当前时间=%v, 过期差=%v",
-				key, kv.exp.Format(time.RFC3339), now.Format(time.RFC3339), now.Sub(kv.exp))
-			c.ll.Remove(ele)
-			delete(c.cache, key)
-			c.nbytes -= int64(len(key)) + int64(kv.value.Len())
-			return nil, false
+		if reason, expired := c.expired(kv, now); expired {
+			logger.Infof("缓存项已过期: key=%s, 原因=%s, 当前时间=%v", logger.Key(key), reason, now.Format(time.RFC3339))
+			c.removeElement(ele)
+			return nil, Expiry{}, false
 		}
 
 		// 输出剩余过期时间
 		remaining := kv.exp.Sub(now)
-		logger.Debugf("缓存命中: key=%s, 剩余有效时间=%v", key, remaining)
+		logger.Debugf("缓存命中: key=%s, 剩余有效时间=%v", logger.Key(key), remaining)
 
-		c.ll.MoveToBack(ele)
-		return kv.value, true
+		expiry = kv.expiry()
+		kv.lastAccess.Store(now.UnixNano())
+		if !c.untracked {
+			expiry.Accesses = kv.accesses.Add(1)
+		}
+		c.used(ele)
+		return kv.value, expiry, true
 	}
 	c.mutex.RUnlock()
-	return nil, false
+	return nil, Expiry{}, false
+}
+
+// expiry describes the entry's lifetime as of the last read
+func (kv *entry) expiry() Expiry {
+	return Expiry{
+		TTL:        kv.ttl,
+		Expires:    kv.exp,
+		LastAccess: kv.lastAccessTime(),
+		Accesses:   kv.accesses.Load(),
+		Created:    kv.created,
+		Version:    kv.version,
+	}
+}
+
+// lastAccessTime returns the time of the last successful Get, zero if none
+func (kv *entry) lastAccessTime() time.Time {
+	return timeFromNanos(kv.lastAccess.Load())
+}
+
+// Peek returns the value of key without updating its recency or access time.
+// Expired entries are reported as missing but left for Get or RemoveExpired to remove.
+func (c *Cache) Peek(key string) (value Value, expiry Expiry, ok bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	ele, ok := c.cache[key]
+	if !ok {
+		return nil, Expiry{}, false
+	}
+	kv := ele.Value.(*entry)
+	if kv.exp != neverExpires || c.tracksAge() {
+		if _, expired := c.expired(kv, c.clock.Now()); expired {
+			return nil, Expiry{}, false
+		}
+	}
+	return kv.value, kv.expiry(), true
+}
+
+// AccessInfo returns when key was last read and how many times it has been read,
+// without counting as a read itself. Expired entries are reported as missing.
+// The count is 0 when access tracking is disabled, see WithAccessTracking.
+func (c *Cache) AccessInfo(key string) (lastAccess time.Time, count uint64, ok bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	ele, ok := c.cache[key]
+	if !ok {
+		return time.Time{}, 0, false
+	}
+	kv := ele.Value.(*entry)
+	if kv.exp != neverExpires || c.tracksAge() {
+		if _, expired := c.expired(kv, c.clock.Now()); expired {
+			return time.Time{}, 0, false
+		}
+	}
+	return kv.lastAccessTime(), kv.accesses.Load(), true
+}
+
+// Keys returns the keys currently in the cache, from least to most recently used
+// (for PolicyClock in ring order, which only approximates recency).
+// Only the keys are copied, so callers can walk large caches entry by entry with
+// Peek; Snapshot does that in chunks with a defined guarantee.
+func (c *Cache) Keys() []string {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	keys := make([]string, 0, c.ll.Len())
+	for ele := c.ll.Front(); ele != nil; ele = ele.Next() {
+		keys = append(keys, ele.Value.(*entry).key)
+	}
+	return keys
+}
+
+// Range calls fn for every live entry, in the order of Keys, until fn
+// returns false. Expired entries are skipped, and recency and access times are
+// left untouched. The cache is read-locked for the whole walk, so fn must be
+// cheap and must not call back into the cache; use Snapshot for slow walks.
+func (c *Cache) Range(fn func(key string, value Value, expiry Expiry) bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	var now time.Time
+	if c.ll.Len() > 0 {
+		now = c.clock.Now()
+	}
+	for ele := c.ll.Front(); ele != nil; ele = ele.Next() {
+		kv := ele.Value.(*entry)
+		if kv.exp != neverExpires || c.tracksAge() {
+			if _, expired := c.expired(kv, now); expired {
+				continue
+			}
+		}
+		if !fn(kv.key, kv.value, kv.expiry()) {
+			return
+		}
+	}
+}
+
+// tracksAge reports whether entries carry timestamps for MaxAge or MaxIdle
+func (c *Cache) tracksAge() bool {
+	return c.maxAge > 0 || c.maxIdle > 0
+}
+
+// expired reports whether kv is no longer servable at now and which limit fired:
+// its ttl, the absolute MaxAge since insertion or the MaxIdle since the last read or write
+func (c *Cache) expired(kv *entry, now time.Time) (string, bool) {
+	if kv.exp != neverExpires && kv.exp.Before(now) {
+		return "ttl", true
+	}
+	if c.maxAge > 0 && now.Sub(kv.created) >= c.maxAge {
+		return "max_age", true
+	}
+	if c.maxIdle > 0 {
+		idleSince := kv.written
+		if lastAccess := kv.lastAccessTime(); lastAccess.After(idleSince) {
+			idleSince = lastAccess
+		}
+		if now.Sub(idleSince) >= c.maxIdle {
+			return "max_idle", true
+		}
+	}
+	return "", false
 }
 
 // Add adds a value to the cache, replacing an existing value if the key exists
@@ -82,63 +337,121 @@ func (c *Cache) Add(key string, value Value, ttl time.Duration) {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
+	// 只有需要时才读取时钟
+	var now time.Time
+	if ttl > 0 || c.stampsCreated() {
+		now = c.clock.Now()
+	}
+
+	var exp time.Time
+	if ttl > 0 {
+		exp = now.Add(ttl)
+	} else {
+		// 如果ttl为0，则设置为time的max
+		exp = neverExpires
+	}
+
+	size, cost := c.charge(key, value)
 	if ele, ok := c.cache[key]; ok {
 		// Update existing entry
-		c.ll.MoveToBack(ele)
 		kv := ele.Value.(*entry)
-		c.nbytes += int64(value.Len()) - int64(kv.value.Len())
+		c.account(kv, size, cost)
+		c.touch(ele)
 		kv.value = value
 
-		// 更新过期时间
-		var exp time.Time
-		if ttl > 0 {
-			exp = time.Now().Add(ttl)
-			logger.Debugf("更新缓存项过期时间: key=%s, TTL=%v, 过期时间=%v",
-				key, ttl, exp.Format(time.RFC3339))
-		} else {
-			// 如果ttl为0，则设置为time的max
-			exp = time.Unix(math.MaxInt64, 0)
-			logger.Debugf("更新缓存项永不过期: key=%s", key)
-		}
+		// 更新过期时间；created 保持首次插入的时间，MaxAge 不会因更新而延长
 		kv.exp = exp
+		kv.ttl = ttl
+		kv.written = now
+		c.writes++
+		kv.version = c.writes
+		logger.Debugf("更新缓存项: key=%s, TTL=%v", logger.Key(key), ttl)
 	} else {
 		// Add new entry
-		var exp time.Time
-		if ttl > 0 {
-			exp = time.Now().Add(ttl)
-			logger.Debugf("添加新缓存项: key=%s, TTL=%v, 过期时间=%v",
-				key, ttl, exp.Format(time.RFC3339))
-		} else {
-			// 如果ttl为0，则设置为time的max
-			exp = time.Unix(math.MaxInt64, 0)
-			logger.Debugf("添加永不过期的缓存项: key=%s", key)
-		}
-		ele := c.ll.PushBack(&entry{key, value, exp})
-		c.cache[key] = ele
-		c.nbytes += int64(len(key)) + int64(value.Len())
+		c.writes++
+		kv := &entry{key: key, value: value, exp: exp, ttl: ttl, created: now, written: now, version: c.writes}
+		c.account(kv, size, cost)
+		c.cache[key] = c.insert(kv)
+		logger.Debugf("添加新缓存项: key=%s, TTL=%v", logger.Key(key), ttl)
 	}
 
-	// Evict oldest entries if memory limit exceeded
-	for c.maxBytes != 0 && c.nbytes > c.maxBytes {
+	// Evict entries while the accounted cost exceeds the limit
+	for c.maxBytes != 0 && c.ncost.Load() > c.maxBytes && c.ll.Len() > 0 {
 		c.removeOldest()
 	}
 }
 
-// Len returns the number of items in the cache
+// RemoveExpired deletes every entry whose ttl, MaxAge or MaxIdle has passed
+// and returns how many were removed. It is meant to be called periodically by a sweeper.
+func (c *Cache) RemoveExpired() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.ll.Len() == 0 {
+		return 0
+	}
+
+	now := c.clock.Now()
+	removed := 0
+	for ele := c.ll.Front(); ele != nil; {
+		next := ele.Next()
+		kv := ele.Value.(*entry)
+		if kv.exp != neverExpires || c.tracksAge() {
+			if _, expired := c.expired(kv, now); expired {
+				c.removeElement(ele)
+				removed++
+			}
+		}
+		ele = next
+	}
+	return removed
+}
+
+// removeElement unlinks an expired entry without counting it as an eviction
+func (c *Cache) removeElement(ele *list.Element) {
+	kv := ele.Value.(*entry)
+	c.unlink(ele)
+	delete(c.cache, kv.key)
+	c.release(kv)
+	c.evicted(kv, EvictExpired)
+}
+
+// Len returns the number of items in the cache. Like Bytes, Cost and Evictions
+// it is an atomic load and does not wait for the cache lock, so statistics can
+// be read as often as needed without holding up reads and writes.
 func (c *Cache) Len() int {
-	c.mutex.RLock()
-	defer c.mutex.RUnlock()
-	return c.ll.Len()
+	return int(c.length.Load())
+}
+
+// Bytes returns the memory currently used by keys and values, see Cost for
+// what is accounted against the limit
+func (c *Cache) Bytes() int64 {
+	return c.nbytes.Load()
 }
 
-// removeOldest removes the oldest (least recently used) item from the cache
+// Evictions returns the number of entries removed to stay under maxBytes
+func (c *Cache) Evictions() int64 {
+	return c.evictions.Load()
+}
+
+// removeOldest removes the entry chosen by the eviction policy: the least recently
+// used one, for CLOCK the first unreferenced one after the hand, and for
+// PolicyCost the one with the lowest GreedyDual-Size priority
 func (c *Cache) removeOldest() {
 	element := c.ll.Front()
+	switch c.policy {
+	case PolicyClock:
+		element = c.evictClock()
+	case PolicyCost:
+		element = c.evictCost()
+	}
 	if element != nil {
-		c.ll.Remove(element)
+		c.evictions.Add(1)
+		c.unlink(element)
 		kv := element.Value.(*entry)
 		delete(c.cache, kv.key)
-		c.nbytes -= int64(len(kv.key)) + int64(kv.value.Len())
+		c.release(kv)
+		c.evicted(kv, EvictCapacity)
 
 		if c.OnEvicted != nil {
 			c.OnEvicted(kv.key, kv.value)
@@ -146,14 +459,37 @@ func (c *Cache) removeOldest() {
 	}
 }
 
+// EvictTo removes entries chosen by the eviction policy, the way Add does at
+// capacity, until the accounted cost is at most target or limit entries were
+// removed (limit <= 0 means no bound). It holds the write lock for the whole
+// call, so a caller freeing a lot of room should call it repeatedly with a small
+// limit to let Gets and Adds through between batches. The entries count as
+// capacity evictions. It returns how many were removed.
+func (c *Cache) EvictTo(target int64, limit int) int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	removed := 0
+	for c.ncost.Load() > target && c.ll.Len() > 0 && (limit <= 0 || removed < limit) {
+		c.removeOldest()
+		removed++
+	}
+	return removed
+}
+
 // Clear empties the cache
 func (c *Cache) Clear() {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
 	c.ll = list.New()
+	c.hand = nil
 	c.cache = make(map[string]*list.Element)
-	c.nbytes = 0
+	c.nbytes.Store(0)
+	c.ncost.Store(0)
+	c.length.Store(0)
+	c.costs = nil
+	c.inflation = 0
 }
 
 // Delete removes a key from the cache
@@ -162,10 +498,11 @@ func (c *Cache) Delete(key string) bool {
 	defer c.mutex.Unlock()
 
 	if ele, ok := c.cache[key]; ok {
-		c.ll.Remove(ele)
+		c.unlink(ele)
 		kv := ele.Value.(*entry)
 		delete(c.cache, key)
-		c.nbytes -= int64(len(key)) + int64(kv.value.Len())
+		c.release(kv)
+		c.evicted(kv, EvictDeleted)
 
 		if c.OnEvicted != nil {
 			c.OnEvicted(key, kv.value)
@@ -0,0 +1,134 @@
+package lru
+
+import (
+	"runtime"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/pkg/logger"
+)
+
+// checkAccounting fails unless Len and Bytes match the entries the cache holds
+func checkAccounting(t *testing.T, c *Cache) {
+	t.Helper()
+	var n int
+	var bytes int64
+	c.Range(func(key string, value Value, _ Expiry) bool {
+		n++
+		bytes += int64(len(key) + value.Len())
+		return true
+	})
+	if keys := c.Keys(); len(keys) != n {
+		t.Fatalf("%d keys in the index, %d entries in the list", len(keys), n)
+	}
+	if c.Len() != n || c.Bytes() != bytes {
+		t.Fatalf("Len %d, Bytes %d, entries %d holding %d bytes", c.Len(), c.Bytes(), n, bytes)
+	}
+}
+
+// TestGetRacesWithWriters reads expiring entries while other goroutines delete,
+// replace and clear them. A Get that found an entry under the read lock must not
+// remove it once it holds the write lock if it has been replaced meanwhile, or it
+// would drop the new entry from the index and release its bytes twice.
+func TestGetRacesWithWriters(t *testing.T) {
+	// The window between the two locks is only hit with goroutines running in parallel
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+	for _, policy := range []Policy{PolicyLRU, PolicyCost} {
+		t.Run(policy.String(), func(t *testing.T) {
+			clock := NewFakeClock(time.Unix(1000, 0))
+			c := New(0, nil, WithClock(clock), WithPolicy(policy))
+			keys := make([]string, 8)
+			for i := range keys {
+				keys[i] = strconv.Itoa(i)
+			}
+
+			var wg sync.WaitGroup
+			run := func(fn func(i int)) {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for i := 0; i < 20000; i++ {
+						fn(i)
+					}
+				}()
+			}
+			for r := 0; r < 4; r++ {
+				run(func(i int) { c.GetWithExpiry(keys[i%len(keys)]) })
+			}
+			run(func(i int) { c.Add(keys[i%len(keys)], testValue("value"), time.Second) })
+			run(func(i int) { c.Delete(keys[(i*3)%len(keys)]) })
+			run(func(i int) {
+				// Entries expire all the time, so Gets take the removal path
+				clock.Advance(300 * time.Millisecond)
+				if i%100 == 0 {
+					c.Clear()
+				}
+			})
+			wg.Wait()
+			checkAccounting(t, c)
+		})
+	}
+}
+
+func TestGetAfterReplace(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1000, 0))
+	c := New(0, nil, WithClock(clock))
+	c.Add("k", testValue("old"), time.Second)
+	clock.Advance(2 * time.Second)
+	c.Add("k", testValue("new"), time.Second)
+	if v, _, ok := c.GetWithExpiry("k"); !ok || v != testValue("new") {
+		t.Fatalf("GetWithExpiry = %v, %v after the expired entry was replaced", v, ok)
+	}
+	checkAccounting(t, c)
+}
+
+// BenchmarkGetParallel reads from several goroutines while one in ten
+// operations replaces an entry, contending for the write lock a hit takes
+func BenchmarkGetParallel(b *testing.B) {
+	// Hits are logged at debug level, which would dominate the measurement
+	logger.SetLevel("error")
+	defer logger.SetLevel("debug")
+	c := New(0, nil)
+	keys := make([]string, 1024)
+	for i := range keys {
+		keys[i] = strconv.Itoa(i)
+		c.Add(keys[i], testValue("value"), time.Hour)
+	}
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			key := keys[i%len(keys)]
+			if i%10 == 0 {
+				c.Add(key, testValue("value"), time.Hour)
+			} else {
+				c.GetWithExpiry(key)
+			}
+		}
+	})
+}
+
+func TestEvictTo(t *testing.T) {
+	var evicted []string
+	c := New(0, func(key string, _ Value) { evicted = append(evicted, key) })
+	for i := uint64(0); i < 10; i++ {
+		c.Add(traceKey(i), testValue("value"), 0)
+	}
+	c.Get(traceKey(0))
+
+	// The limit bounds a call, the least recently used entries go first
+	if n := c.EvictTo(0, 2); n != 2 || evicted[0] != traceKey(1) || evicted[1] != traceKey(2) {
+		t.Fatalf("EvictTo(0, 2) = %d, evicted %v", n, evicted)
+	}
+	if n := c.EvictTo(int64(5*entryBytes), 0); n != 3 || c.Bytes() != int64(5*entryBytes) {
+		t.Fatalf("EvictTo(5 entries) = %d, Bytes %d", n, c.Bytes())
+	}
+	if n := c.EvictTo(int64(5*entryBytes), 0); n != 0 {
+		t.Fatalf("EvictTo below the target = %d", n)
+	}
+	if _, ok := c.Get(traceKey(0)); !ok || c.Evictions() != 5 {
+		t.Fatalf("recently used entry evicted or Evictions %d != 5", c.Evictions())
+	}
+	checkAccounting(t, c)
+}
@@ -0,0 +1,195 @@
+package lru
+
+import (
+	"container/list"
+	"fmt"
+	"time"
+
+	"github.com/AdrianWangs/go-cache/pkg/logger"
+)
+
+// Policy selects how a Cache picks entries to evict when it is over maxBytes
+type Policy int
+
+const (
+	// PolicyLRU evicts the least recently used entry. Every Get moves the entry
+	// to the back of the list and therefore takes the write lock.
+	PolicyLRU Policy = iota
+	// PolicyClock approximates LRU with the CLOCK (second-chance) algorithm. A Get
+	// only sets the entry's reference bit under the read lock; eviction sweeps the
+	// ring from a hand, clearing set bits and evicting the first unreferenced entry.
+	PolicyClock
+	// PolicyCost evicts the entry with the lowest GreedyDual-Size priority: its
+	// cost (see WithCostFunc) per byte plus an inflation that rises to the
+	// priority of every evicted entry, so cheap and long unused entries go first.
+	// Like PolicyLRU every Get takes the write lock, and reorders a heap as well.
+	PolicyCost
+)
+
+// Policy names accepted by ParsePolicy
+const (
+	PolicyNameLRU   = "lru"
+	PolicyNameClock = "clock"
+	PolicyNameCost  = "cost"
+)
+
+// String returns the policy name
+func (p Policy) String() string {
+	switch p {
+	case PolicyLRU:
+		return PolicyNameLRU
+	case PolicyClock:
+		return PolicyNameClock
+	case PolicyCost:
+		return PolicyNameCost
+	default:
+		return fmt.Sprintf("Policy(%d)", int(p))
+	}
+}
+
+// ParsePolicy parses a policy name; the empty string selects PolicyLRU
+func ParsePolicy(name string) (Policy, error) {
+	switch name {
+	case "", PolicyNameLRU:
+		return PolicyLRU, nil
+	case PolicyNameClock:
+		return PolicyClock, nil
+	case PolicyNameCost:
+		return PolicyCost, nil
+	default:
+		return PolicyLRU, fmt.Errorf("unknown eviction policy %q, expected %s, %s or %s", name, PolicyNameLRU, PolicyNameClock, PolicyNameCost)
+	}
+}
+
+// WithPolicy sets the eviction policy, defaulting to PolicyLRU
+func WithPolicy(p Policy) Option {
+	return func(c *Cache) {
+		c.policy = p
+	}
+}
+
+// getClock implements GetWithExpiry for PolicyClock. Hits only take the read
+// lock; the write lock is needed only to remove an entry found expired.
+func (c *Cache) getClock(key string) (value Value, expiry Expiry, ok bool) {
+	c.mutex.RLock()
+	ele, ok := c.cache[key]
+	if !ok {
+		c.mutex.RUnlock()
+		return nil, Expiry{}, false
+	}
+	kv := ele.Value.(*entry)
+
+	var now time.Time
+	if kv.exp != neverExpires || c.tracksAge() {
+		now = c.clock.Now()
+		if reason, expired := c.expired(kv, now); expired {
+			c.mutex.RUnlock()
+			c.removeExpiredElement(key, ele, reason)
+			return nil, Expiry{}, false
+		}
+	} else if !c.untracked {
+		now = c.clock.Now()
+	}
+
+	expiry = kv.expiry()
+	if !now.IsZero() {
+		expiry.LastAccess = timeFromNanos(kv.lastAccess.Swap(now.UnixNano()))
+	}
+	if !c.untracked {
+		expiry.Accesses = kv.accesses.Add(1)
+	}
+	if !kv.referenced.Load() {
+		kv.referenced.Store(true)
+	}
+	value = kv.value
+	c.mutex.RUnlock()
+	return value, expiry, true
+}
+
+// removeExpiredElement removes ele, found expired under the read lock, unless it
+// was replaced or refreshed before the write lock was taken
+func (c *Cache) removeExpiredElement(key string, ele *list.Element, reason string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.cache[key] != ele {
+		return
+	}
+	now := c.clock.Now()
+	if _, expired := c.expired(ele.Value.(*entry), now); expired {
+		logger.Infof("缓存项已过期: key=%s, 原因=%s, 当前时间=%v", logger.Key(key), reason, now.Format(time.RFC3339))
+		c.removeElement(ele)
+	}
+}
+
+// evictClock advances the hand until it finds an unreferenced entry, clearing the
+// reference bits it passes, and returns that entry. It returns nil for an empty
+// cache. The write lock keeps Gets from setting bits during the sweep, so it ends
+// within one full turn of the ring.
+func (c *Cache) evictClock() *list.Element {
+	for c.ll.Len() > 0 {
+		if c.hand == nil {
+			c.hand = c.ll.Front()
+		}
+		kv := c.hand.Value.(*entry)
+		if kv.referenced.Load() {
+			kv.referenced.Store(false)
+			c.hand = c.hand.Next()
+			continue
+		}
+		return c.hand
+	}
+	return nil
+}
+
+// insert adds a new entry to the list: at the back for LRU, and just behind the
+// hand for CLOCK so that the entry is the last one the next sweep reaches.
+// PolicyCost also adds it to the priority heap.
+func (c *Cache) insert(kv *entry) *list.Element {
+	if c.policy == PolicyClock && c.hand != nil {
+		c.length.Add(1)
+		return c.ll.InsertBefore(kv, c.hand)
+	}
+	c.length.Add(1)
+	if c.policy == PolicyCost {
+		c.costInsert(kv)
+	}
+	return c.ll.PushBack(kv)
+}
+
+// touch records a rewrite of an existing entry in the eviction order
+func (c *Cache) touch(ele *list.Element) {
+	if c.policy == PolicyClock {
+		ele.Value.(*entry).referenced.Store(true)
+		return
+	}
+	c.used(ele)
+}
+
+// used records a read or rewrite of ele under the write lock, for every policy but CLOCK
+func (c *Cache) used(ele *list.Element) {
+	c.ll.MoveToBack(ele)
+	if c.policy == PolicyCost {
+		c.costTouch(ele.Value.(*entry))
+	}
+}
+
+// unlink removes ele from the list, moving the CLOCK hand off it first
+func (c *Cache) unlink(ele *list.Element) {
+	if c.hand == ele {
+		c.hand = ele.Next()
+	}
+	if c.policy == PolicyCost {
+		c.costRemove(ele.Value.(*entry))
+	}
+	c.length.Add(-1)
+	c.ll.Remove(ele)
+}
+
+// timeFromNanos converts unix nanoseconds to a time, 0 meaning the zero time
+func timeFromNanos(ns int64) time.Time {
+	if ns == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, ns)
+}
@@ -0,0 +1,119 @@
+package lru
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/AdrianWangs/go-cache/internal/loadgen"
+)
+
+// entryBytes is the accounted size of every entry added by traceKey
+const entryBytes = len("key-000000") + len("value")
+
+// traceKey returns the fixed-width key of index i
+func traceKey(i uint64) string {
+	return fmt.Sprintf("key-%06d", i)
+}
+
+func TestClockSecondChance(t *testing.T) {
+	var evicted []string
+	c := New(int64(3*entryBytes), func(key string, _ Value) {
+		evicted = append(evicted, key)
+	}, WithPolicy(PolicyClock))
+	for i := uint64(0); i < 3; i++ {
+		c.Add(traceKey(i), testValue("value"), 0)
+	}
+
+	// the referenced oldest entry gets a second chance, the next one is evicted
+	c.Get(traceKey(0))
+	c.Add(traceKey(3), testValue("value"), 0)
+	if len(evicted) != 1 || evicted[0] != traceKey(1) {
+		t.Fatalf("evicted %v, want [%s]", evicted, traceKey(1))
+	}
+	if _, ok := c.Get(traceKey(0)); !ok {
+		t.Fatal("referenced entry was evicted")
+	}
+	if c.Len() != 3 || c.Bytes() != int64(3*entryBytes) || c.Evictions() != 1 {
+		t.Fatalf("Len %d, Bytes %d, Evictions %d after one eviction", c.Len(), c.Bytes(), c.Evictions())
+	}
+
+	// growing an entry evicts another one and keeps the accounting exact
+	c.Add(traceKey(2), testValue("longer value"), 0)
+	var bytes int64
+	c.Range(func(key string, value Value, _ Expiry) bool {
+		bytes += int64(len(key) + value.Len())
+		return true
+	})
+	if len(evicted) != 2 || c.Bytes() != bytes || bytes > int64(3*entryBytes) {
+		t.Fatalf("Bytes %d, entries hold %d, evicted %v", c.Bytes(), bytes, evicted)
+	}
+}
+
+// hitRate replays a zipfian trace against a cache holding capacity entries
+func hitRate(policy Policy, capacity, requests int) float64 {
+	c := New(int64(capacity*entryBytes), nil, WithPolicy(policy), WithAccessTracking(false))
+	keys := loadgen.NewZipf(100*uint64(capacity), 1.1, 1)
+	hits := 0
+	for i := 0; i < requests; i++ {
+		key := traceKey(keys.Next())
+		if _, ok := c.Get(key); ok {
+			hits++
+			continue
+		}
+		c.Add(key, testValue("value"), 0)
+	}
+	return float64(hits) / float64(requests)
+}
+
+// TestPolicyHitRates documents the trade-off of CLOCK: on a zipfian trace it
+// keeps nearly the hit rate of strict LRU while taking only read locks on hits.
+func TestPolicyHitRates(t *testing.T) {
+	rates := make(map[Policy]float64)
+	for _, policy := range allPolicies {
+		rates[policy] = hitRate(policy, 1000, 100000)
+		t.Logf("%s: hit rate %.1f%%", policy, rates[policy]*100)
+	}
+	if rates[PolicyLRU] < 0.5 {
+		t.Fatalf("LRU hit rate %.3f on a zipfian trace", rates[PolicyLRU])
+	}
+	if diff := rates[PolicyLRU] - rates[PolicyClock]; diff > 0.03 || diff < -0.03 {
+		t.Fatalf("CLOCK hit rate %.3f, LRU %.3f", rates[PolicyClock], rates[PolicyLRU])
+	}
+}
+
+// benchmarkMix runs a read/write mix with reads in every 100 operations against
+// a full cache of each policy. Run with -cpu 8 or more to see the contention
+// CLOCK avoids: its hits never take the write lock.
+func benchmarkMix(b *testing.B, reads int) {
+	for _, policy := range []Policy{PolicyLRU, PolicyClock} {
+		b.Run(policy.String(), func(b *testing.B) {
+			const capacity = 10000
+			c := New(int64(capacity*entryBytes), nil, WithPolicy(policy))
+			for i := uint64(0); i < capacity; i++ {
+				c.Add(traceKey(i), testValue("value"), 0)
+			}
+			var seed atomic.Int64
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				keys := loadgen.NewZipf(2*capacity, 1.1, seed.Add(1))
+				for i := 0; pb.Next(); i++ {
+					key := traceKey(keys.Next())
+					if i%100 < reads {
+						c.Get(key)
+					} else {
+						c.Add(key, testValue("value"), 0)
+					}
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkPolicyRead90Write10(b *testing.B) {
+	benchmarkMix(b, 90)
+}
+
+func BenchmarkPolicyRead99Write1(b *testing.B) {
+	benchmarkMix(b, 99)
+}
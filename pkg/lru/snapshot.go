@@ -0,0 +1,82 @@
+package lru
+
+import "time"
+
+// snapshotChunk is the number of entries Snapshot reads per acquisition of the
+// read lock; writers wait for at most one chunk instead of the whole walk
+const snapshotChunk = 256
+
+// snapshotEntry is an entry copied out of the cache by readChunk
+type snapshotEntry struct {
+	key    string
+	value  Value
+	expiry Expiry
+}
+
+// Snapshot calls fn for the entries of the cache while reads and writes carry on,
+// stopping at and returning the first error of fn. The key index is copied up
+// front under the read lock; values are then read in chunks of snapshotChunk
+// keys, and fn runs without any lock held, so it may be slow and may call back
+// into the cache. The walk guarantees:
+//
+//   - every entry present and live from the start to the end of the call is
+//     visited exactly once, with the value it holds when its chunk is read;
+//   - no key is visited twice, whatever happens to it during the walk;
+//   - keys added during the walk are not visited, and keys that are deleted,
+//     evicted or expire before their chunk is read are skipped.
+//
+// exp is when the entry stops being servable by its ttl or MaxAge, whichever
+// comes first, and zero if neither applies. Recency and access times are left
+// untouched.
+func (c *Cache) Snapshot(fn func(key string, value Value, exp time.Time) error) error {
+	return c.SnapshotWithExpiry(func(key string, value Value, expiry Expiry) error {
+		var exp time.Time
+		if expiry.TTL > 0 {
+			exp = expiry.Expires
+		}
+		if c.maxAge > 0 && !expiry.Created.IsZero() {
+			if ageLimit := expiry.Created.Add(c.maxAge); exp.IsZero() || ageLimit.Before(exp) {
+				exp = ageLimit
+			}
+		}
+		return fn(key, value, exp)
+	})
+}
+
+// SnapshotWithExpiry is like Snapshot but reports the full Expiry of every entry,
+// including its write version
+func (c *Cache) SnapshotWithExpiry(fn func(key string, value Value, expiry Expiry) error) error {
+	keys := c.Keys()
+	batch := make([]snapshotEntry, 0, min(len(keys), snapshotChunk))
+	for start := 0; start < len(keys); start += snapshotChunk {
+		batch = c.readChunk(keys[start:min(start+snapshotChunk, len(keys))], batch[:0])
+		for _, e := range batch {
+			if err := fn(e.key, e.value, e.expiry); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// readChunk appends the live entries among keys to batch under a single read lock
+func (c *Cache) readChunk(keys []string, batch []snapshotEntry) []snapshotEntry {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	now := c.clock.Now()
+	for _, key := range keys {
+		ele, ok := c.cache[key]
+		if !ok {
+			continue
+		}
+		kv := ele.Value.(*entry)
+		if kv.exp != neverExpires || c.tracksAge() {
+			if _, expired := c.expired(kv, now); expired {
+				continue
+			}
+		}
+		batch = append(batch, snapshotEntry{key: kv.key, value: kv.value, expiry: kv.expiry()})
+	}
+	return batch
+}
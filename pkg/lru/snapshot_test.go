@@ -0,0 +1,255 @@
+package lru
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// snapshotAll records what Snapshot visits, failing on a key visited twice
+func snapshotAll(t *testing.T, c *Cache) map[string]testValue {
+	t.Helper()
+	seen := make(map[string]testValue)
+	err := c.Snapshot(func(key string, value Value, _ time.Time) error {
+		if _, dup := seen[key]; dup {
+			t.Errorf("key %q visited twice", key)
+		}
+		seen[key] = value.(testValue)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+	return seen
+}
+
+func TestSnapshotVisitsEveryEntryOnce(t *testing.T) {
+	for _, policy := range allPolicies {
+		t.Run(policy.String(), func(t *testing.T) {
+			c := New(0, nil, WithPolicy(policy))
+			const n = 3*snapshotChunk + 10
+			for i := 0; i < n; i++ {
+				c.Add(strconv.Itoa(i), testValue("v"+strconv.Itoa(i)), 0)
+			}
+			seen := snapshotAll(t, c)
+			if len(seen) != n {
+				t.Fatalf("visited %d entries, want %d", len(seen), n)
+			}
+			for i := 0; i < n; i++ {
+				if v := seen[strconv.Itoa(i)]; v != testValue("v"+strconv.Itoa(i)) {
+					t.Fatalf("entry %d visited with %q", i, v)
+				}
+			}
+			if err := New(0, nil, WithPolicy(policy)).Snapshot(func(string, Value, time.Time) error {
+				t.Fatal("visited an entry of an empty cache")
+				return nil
+			}); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestSnapshotExpiry(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1000, 0))
+	start := clock.Now()
+	c := New(0, nil, WithClock(clock), WithMaxAge(time.Hour))
+	c.Add("ttl", testValue("v"), time.Minute)
+	c.Add("long-ttl", testValue("v"), 2*time.Hour)
+	c.Add("no-ttl", testValue("v"), 0)
+	c.Add("expired", testValue("v"), time.Second)
+	clock.Advance(2 * time.Second)
+
+	want := map[string]time.Time{
+		"ttl":      start.Add(time.Minute),
+		"long-ttl": start.Add(time.Hour), // MaxAge comes first
+		"no-ttl":   start.Add(time.Hour),
+	}
+	got := make(map[string]time.Time)
+	if err := c.Snapshot(func(key string, _ Value, exp time.Time) error {
+		got[key] = exp
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("visited %v, want %v", got, want)
+	}
+	for key, exp := range want {
+		if !got[key].Equal(exp) {
+			t.Errorf("%s: exp %v, want %v", key, got[key], exp)
+		}
+	}
+
+	// Without MaxAge an entry without ttl reports a zero exp
+	plain := New(0, nil)
+	plain.Add("k", testValue("v"), 0)
+	plain.Snapshot(func(key string, _ Value, exp time.Time) error {
+		if !exp.IsZero() {
+			t.Errorf("exp of an entry that never expires = %v", exp)
+		}
+		return nil
+	})
+
+	// SnapshotWithExpiry reports the write version
+	var versions []uint64
+	c.SnapshotWithExpiry(func(key string, _ Value, expiry Expiry) error {
+		versions = append(versions, expiry.Version)
+		return nil
+	})
+	if len(versions) != 3 || versions[0] == 0 {
+		t.Fatalf("versions = %v", versions)
+	}
+}
+
+func TestSnapshotStopsOnError(t *testing.T) {
+	c := New(0, nil)
+	for i := 0; i < 2*snapshotChunk; i++ {
+		c.Add(strconv.Itoa(i), testValue("v"), 0)
+	}
+	stop := errors.New("stop")
+	calls := 0
+	err := c.Snapshot(func(string, Value, time.Time) error {
+		calls++
+		if calls == 10 {
+			return stop
+		}
+		return nil
+	})
+	if !errors.Is(err, stop) || calls != 10 {
+		t.Fatalf("Snapshot = %v after %d calls, want the callback's error after 10", err, calls)
+	}
+}
+
+// TestSnapshotCallbackWrites mutates the cache from the callback, which runs
+// without the lock: keys deleted or expired before their chunk are skipped,
+// keys added are not visited, and a replaced key is visited once with its new
+// value
+func TestSnapshotCallbackWrites(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1000, 0))
+	c := New(0, nil, WithClock(clock))
+	const n = 3 * snapshotChunk
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("k%04d", i)
+		ttl := time.Duration(0)
+		if i%7 == 0 {
+			ttl = time.Minute
+		}
+		c.Add(keys[i], testValue("old"), ttl)
+	}
+	// Keys come in the order of Keys, so everything past the first chunk is
+	// read after the callback has run on the first chunk
+	order := c.Keys()
+	later := order[snapshotChunk:]
+
+	seen := make(map[string]testValue)
+	first := true
+	err := c.Snapshot(func(key string, value Value, _ time.Time) error {
+		if _, dup := seen[key]; dup {
+			t.Errorf("key %q visited twice", key)
+		}
+		seen[key] = value.(testValue)
+		if !first {
+			return nil
+		}
+		first = false
+		for i, k := range later {
+			switch i % 3 {
+			case 0:
+				c.Delete(k)
+			case 1:
+				c.Add(k, testValue("new"), 0)
+			}
+			c.Add("added-"+k, testValue("v"), 0)
+		}
+		clock.Advance(2 * time.Minute) // the keys with a ttl expire
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, k := range later {
+		v, ok := seen[k]
+		var wantOK bool
+		var want testValue
+		switch {
+		case i%3 == 0:
+		case i%3 == 1:
+			wantOK, want = true, "new"
+		default:
+			if idx, _ := strconv.Atoi(k[1:]); idx%7 != 0 {
+				wantOK, want = true, "old"
+			}
+		}
+		if ok != wantOK || v != want {
+			t.Errorf("%s: visited %v with %q, want %v with %q", k, ok, v, wantOK, want)
+		}
+	}
+	for k := range seen {
+		if strings.HasPrefix(k, "added-") {
+			t.Errorf("visited %s, added during the walk", k)
+		}
+	}
+	for _, k := range order[:snapshotChunk] {
+		if _, ok := seen[k]; !ok {
+			t.Errorf("%s of the first chunk not visited", k)
+		}
+	}
+}
+
+// TestSnapshotConcurrentWriters walks the cache while writers churn other keys:
+// every key left alone is visited exactly once with its value and no key is
+// visited twice; run it with -race
+func TestSnapshotConcurrentWriters(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+	for _, policy := range allPolicies {
+		t.Run(policy.String(), func(t *testing.T) {
+			c := New(0, nil, WithPolicy(policy))
+			const stable = 2*snapshotChunk + 50
+			for i := 0; i < stable; i++ {
+				c.Add("stable-"+strconv.Itoa(i), testValue("s"+strconv.Itoa(i)), 0)
+			}
+
+			stop := make(chan struct{})
+			var wg sync.WaitGroup
+			for w := 0; w < 4; w++ {
+				wg.Add(1)
+				go func(w int) {
+					defer wg.Done()
+					for i := 0; ; i++ {
+						select {
+						case <-stop:
+							return
+						default:
+						}
+						k := fmt.Sprintf("churn-%d-%d", w, i%300)
+						if i%2 == 0 {
+							c.Add(k, testValue("c"), 0)
+						} else {
+							c.Delete(k)
+						}
+						c.Get("stable-" + strconv.Itoa(i%stable))
+					}
+				}(w)
+			}
+
+			for round := 0; round < 5; round++ {
+				seen := snapshotAll(t, c)
+				for i := 0; i < stable; i++ {
+					if v, ok := seen["stable-"+strconv.Itoa(i)]; !ok || v != testValue("s"+strconv.Itoa(i)) {
+						t.Fatalf("round %d: stable-%d visited %v with %q", round, i, ok, v)
+					}
+				}
+			}
+			close(stop)
+			wg.Wait()
+		})
+	}
+}
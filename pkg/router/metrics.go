@@ -0,0 +1,166 @@
+package router
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// UnmatchedRoute 没有匹配任何已注册路由的请求（404，以及挂载前缀之外的路径）使用的路由标签
+const UnmatchedRoute = "unmatched"
+
+// Recorder 接收每个请求的统计。route 为请求匹配的注册路由（例如 /api/cache/），而不是请求路径，
+// 标签的取值因此只有已注册的路由数加一个；没有匹配时为 UnmatchedRoute
+type Recorder interface {
+	RecordRequest(route, method string, status int, duration time.Duration)
+}
+
+// SetRecorder 设置请求统计的接收者，为 nil 时不统计。必须在开始处理请求之前调用
+func (r *Router) SetRecorder(rec Recorder) {
+	r.recorder = rec
+}
+
+// statusRecorder 记录请求匹配的路由和响应状态码
+type statusRecorder struct {
+	responseWriterWrapper
+	route string // 匹配的注册路由，由 routeLabel 设置，为空表示没有匹配
+}
+
+// serveRecorded 处理请求并把匹配的路由、状态码和耗时交给 recorder
+func (r *Router) serveRecorded(w http.ResponseWriter, req *http.Request, serve func(http.ResponseWriter, *http.Request)) {
+	start := time.Now()
+	rw := &statusRecorder{responseWriterWrapper: responseWriterWrapper{ResponseWriter: w, statusCode: http.StatusOK}}
+	serve(rw, req)
+	route := rw.route
+	if route == "" {
+		route = UnmatchedRoute
+	}
+	r.recorder.RecordRequest(route, methodLabel(req.Method), rw.statusCode, time.Since(start))
+}
+
+// methodLabel 返回请求方法的统计标签，非标准的方法统一为 OTHER，避免客户端构造任意方法撑大标签取值
+func methodLabel(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodOptions, http.MethodConnect, http.MethodTrace:
+		return method
+	}
+	return "OTHER"
+}
+
+// routeLabel 包装注册的处理器，在统计中记下请求匹配的路由 pattern。
+// 它位于所有中间件之外，因此拿到的是 serveRecorded 创建的 statusRecorder
+func routeLabel(pattern string, next Handler) Handler {
+	return HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if rw, ok := w.(*statusRecorder); ok {
+			rw.route = pattern
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+// RouteStats 单个路由和方法的请求统计
+type RouteStats struct {
+	Route      string        `json:"route"`      // 注册的路由，未匹配的请求为 unmatched
+	Method     string        `json:"method"`     // 请求方法
+	Requests   int64         `json:"requests"`   // 请求数
+	Statuses   map[int]int64 `json:"statuses"`   // 各状态码的请求数
+	TotalMs    float64       `json:"totalMs"`    // 处理耗时之和（毫秒）
+	MaxMs      float64       `json:"maxMs"`      // 最长处理耗时（毫秒）
+	AverageMs  float64       `json:"averageMs"`  // 平均处理耗时（毫秒）
+	ErrorCount int64         `json:"errorCount"` // 状态码为 5xx 的请求数
+}
+
+// routeKey 内存统计的键
+type routeKey struct {
+	route, method string
+}
+
+// MemoryRecorder 在内存中按路由和方法累计请求数、状态码和耗时，供 /api/metrics 展示
+type MemoryRecorder struct {
+	mu     sync.Mutex
+	routes map[routeKey]*RouteStats
+	total  int64
+}
+
+// NewMemoryRecorder 创建一个空的 MemoryRecorder
+func NewMemoryRecorder() *MemoryRecorder {
+	return &MemoryRecorder{routes: make(map[routeKey]*RouteStats)}
+}
+
+// RecordRequest 实现 Recorder
+func (m *MemoryRecorder) RecordRequest(route, method string, status int, duration time.Duration) {
+	ms := float64(duration) / float64(time.Millisecond)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	k := routeKey{route, method}
+	s := m.routes[k]
+	if s == nil {
+		s = &RouteStats{Route: route, Method: method, Statuses: make(map[int]int64)}
+		m.routes[k] = s
+	}
+	s.Requests++
+	s.Statuses[status]++
+	s.TotalMs += ms
+	s.MaxMs = max(s.MaxMs, ms)
+	if status >= http.StatusInternalServerError {
+		s.ErrorCount++
+	}
+	m.total++
+}
+
+// Total 返回记录的请求总数
+func (m *MemoryRecorder) Total() int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.total
+}
+
+// Routes 返回各路由和方法的统计副本，按路由和方法排序
+func (m *MemoryRecorder) Routes() []RouteStats {
+	m.mu.Lock()
+	routes := make([]RouteStats, 0, len(m.routes))
+	for _, s := range m.routes {
+		c := *s
+		c.Statuses = make(map[int]int64, len(s.Statuses))
+		for code, n := range s.Statuses {
+			c.Statuses[code] = n
+		}
+		c.AverageMs = c.TotalMs / float64(c.Requests)
+		routes = append(routes, c)
+	}
+	m.mu.Unlock()
+
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Route != routes[j].Route {
+			return routes[i].Route < routes[j].Route
+		}
+		return routes[i].Method < routes[j].Method
+	})
+	return routes
+}
+
+// multiRecorder 把每个请求交给多个 Recorder
+type multiRecorder []Recorder
+
+// RecordRequest 实现 Recorder
+func (m multiRecorder) RecordRequest(route, method string, status int, duration time.Duration) {
+	for _, rec := range m {
+		rec.RecordRequest(route, method, status, duration)
+	}
+}
+
+// MultiRecorder 返回把每个请求依次交给 recs 的 Recorder，忽略其中的 nil
+func MultiRecorder(recs ...Recorder) Recorder {
+	var m multiRecorder
+	for _, rec := range recs {
+		if rec != nil {
+			m = append(m, rec)
+		}
+	}
+	if len(m) == 1 {
+		return m[0]
+	}
+	return m
+}
@@ -0,0 +1,197 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+// statusRoute 返回固定状态码的处理器
+func statusRoute(status int) HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}
+}
+
+// serveAll 依次以 method 请求 targets
+func serveAll(r *Router, method string, targets ...string) {
+	for _, target := range targets {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, target, nil))
+	}
+}
+
+// routeStats 返回 m 中 route 和 method 的统计，没有时返回 nil
+func routeStats(m *MemoryRecorder, route, method string) *RouteStats {
+	for _, s := range m.Routes() {
+		if s.Route == route && s.Method == method {
+			return &s
+		}
+	}
+	return nil
+}
+
+// TestRouteLabels 带参数的路径按注册的路由归并，未匹配的请求（404、405）归入 unmatched
+func TestRouteLabels(t *testing.T) {
+	r := New()
+	stats := NewMemoryRecorder()
+	r.SetRecorder(stats)
+	r.RegisterFunc("/api/cache/", statusRoute(http.StatusOK))
+	r.RegisterFunc("/health", statusRoute(http.StatusServiceUnavailable))
+	r.RegisterFunc("GET /api/nodes", statusRoute(http.StatusOK))
+	r.Group("/api/admin").RegisterFunc("/groups/", statusRoute(http.StatusNoContent))
+
+	serveAll(r, http.MethodGet, "/api/cache/scores/Tom", "/api/cache/scores/Jack", "/api/cache/users/a%2Fb", "/health", "/api/nodes",
+		"/api/admin/groups/scores", "/api/admin/groups/users", "/nope", "/api/cachex")
+	serveAll(r, http.MethodDelete, "/api/cache/scores/Tom")
+	serveAll(r, http.MethodPost, "/api/nodes")
+	serveAll(r, "PURGE", "/api/cache/scores/Tom")
+
+	tests := []struct {
+		route    string
+		method   string
+		requests int64
+		status   int
+	}{
+		{"/api/cache/", http.MethodGet, 3, http.StatusOK},
+		{"/api/cache/", http.MethodDelete, 1, http.StatusOK},
+		{"/api/cache/", "OTHER", 1, http.StatusOK},
+		{"/health", http.MethodGet, 1, http.StatusServiceUnavailable},
+		{"GET /api/nodes", http.MethodGet, 1, http.StatusOK},
+		{"/api/admin/groups/", http.MethodGet, 2, http.StatusNoContent},
+		{UnmatchedRoute, http.MethodGet, 2, http.StatusNotFound},
+		{UnmatchedRoute, http.MethodPost, 1, http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		s := routeStats(stats, tt.route, tt.method)
+		if s == nil || s.Requests != tt.requests || s.Statuses[tt.status] != tt.requests {
+			t.Errorf("%s %s 的统计 = %+v, want %d 个 %d", tt.method, tt.route, s, tt.requests, tt.status)
+		}
+	}
+	if n := len(stats.Routes()); n != len(tests) {
+		t.Errorf("统计了 %d 个路由和方法, want %d: %+v", n, len(tests), stats.Routes())
+	}
+	if stats.Total() != 12 {
+		t.Errorf("Total = %d, want 12", stats.Total())
+	}
+	if s := routeStats(stats, "/health", http.MethodGet); s == nil || s.ErrorCount != 1 {
+		t.Errorf("/health 的 5xx 数 = %+v", s)
+	}
+}
+
+// TestRouteLabelsMounted 挂载前缀后按去掉前缀的注册路由统计，前缀之外的请求归入 unmatched
+func TestRouteLabelsMounted(t *testing.T) {
+	r := newMountedRouter("/cache")
+	stats := NewMemoryRecorder()
+	r.SetRecorder(stats)
+	serveAll(r, http.MethodGet, "/cache/api/cache/scores/Tom", "/health", "/cache/health", "/api/cache/scores/Tom")
+
+	for route, want := range map[string]int64{"/api/cache/": 1, "/health": 2, UnmatchedRoute: 1} {
+		if s := routeStats(stats, route, http.MethodGet); s == nil || s.Requests != want {
+			t.Errorf("%s 的统计 = %+v, want %d 个请求", route, s, want)
+		}
+	}
+}
+
+// TestRouteLabelsMiddlewareStatus 统计的是中间件写出的状态码，中间件提前返回时仍按注册的路由统计
+func TestRouteLabelsMiddlewareStatus(t *testing.T) {
+	r := New()
+	stats := NewMemoryRecorder()
+	r.SetRecorder(stats)
+	r.Use(RecoveryMiddleware())
+	r.Use(func(next Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if req.Header.Get("Authorization") == "" {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, req)
+		})
+	})
+	r.RegisterFunc("/api/cache/", func(w http.ResponseWriter, req *http.Request) {
+		panic("boom")
+	})
+
+	serveAll(r, http.MethodGet, "/api/cache/scores/Tom")
+	req := httptest.NewRequest(http.MethodGet, "/api/cache/scores/Tom", nil)
+	req.Header.Set("Authorization", "Bearer x")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	s := routeStats(stats, "/api/cache/", http.MethodGet)
+	if s == nil || s.Statuses[http.StatusUnauthorized] != 1 || s.Statuses[http.StatusInternalServerError] != 1 || s.ErrorCount != 1 {
+		t.Fatalf("统计 = %+v", s)
+	}
+}
+
+func TestWithoutRecorder(t *testing.T) {
+	r := New()
+	wrapped := false
+	r.RegisterFunc("/health", func(w http.ResponseWriter, req *http.Request) {
+		_, wrapped = w.(*statusRecorder)
+	})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if w.Code != http.StatusOK || wrapped {
+		t.Fatalf("状态码 = %d, ResponseWriter 被包装: %v", w.Code, wrapped)
+	}
+}
+
+func TestMemoryRecorder(t *testing.T) {
+	m := NewMemoryRecorder()
+	m.RecordRequest("/b", http.MethodGet, http.StatusOK, 10*time.Millisecond)
+	m.RecordRequest("/b", http.MethodGet, http.StatusBadGateway, 30*time.Millisecond)
+	m.RecordRequest("/a", http.MethodPost, http.StatusCreated, time.Millisecond)
+	m.RecordRequest("/a", http.MethodDelete, http.StatusOK, time.Millisecond)
+
+	routes := m.Routes()
+	if len(routes) != 3 || routes[0].Method != http.MethodDelete || routes[1].Method != http.MethodPost || routes[2].Route != "/b" {
+		t.Fatalf("Routes 未按路由和方法排序: %+v", routes)
+	}
+	b := routes[2]
+	if b.Requests != 2 || b.ErrorCount != 1 || b.TotalMs != 40 || b.MaxMs != 30 || b.AverageMs != 20 {
+		t.Fatalf("/b 的统计 = %+v", b)
+	}
+
+	// 返回的是副本
+	b.Statuses[http.StatusOK] = 100
+	if s := routeStats(m, "/b", http.MethodGet); s.Statuses[http.StatusOK] != 1 {
+		t.Fatalf("修改 Routes 的结果影响了统计: %+v", s)
+	}
+}
+
+func TestMemoryRecorderConcurrent(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+	r := New()
+	stats := NewMemoryRecorder()
+	r.SetRecorder(stats)
+	r.RegisterFunc("/api/cache/", statusRoute(http.StatusOK))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				serveAll(r, http.MethodGet, "/api/cache/scores/Tom")
+				stats.Routes()
+			}
+		}()
+	}
+	wg.Wait()
+	if s := routeStats(stats, "/api/cache/", http.MethodGet); s == nil || s.Requests != 800 || stats.Total() != 800 {
+		t.Fatalf("统计 = %+v, 总数 %d", s, stats.Total())
+	}
+}
+
+func TestMultiRecorder(t *testing.T) {
+	a, b := NewMemoryRecorder(), NewMemoryRecorder()
+	if rec := MultiRecorder(nil, a, nil); rec != Recorder(a) {
+		t.Fatalf("只有一个非 nil 的 recorder 时应直接返回它, got %T", rec)
+	}
+	MultiRecorder(a, nil, b).RecordRequest("/health", http.MethodGet, http.StatusOK, time.Millisecond)
+	if a.Total() != 1 || b.Total() != 1 {
+		t.Fatalf("a = %d, b = %d, want 1, 1", a.Total(), b.Total())
+	}
+}
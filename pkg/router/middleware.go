@@ -3,13 +3,15 @@ package router
 import (
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/AdrianWangs/go-cache/pkg/logger"
 )
 
-// LoggingMiddleware 创建一个记录请求日志的中间件
-func LoggingMiddleware() MiddlewareFunc {
+// LoggingMiddleware 创建一个记录请求日志的中间件。keyPrefixes 是路径形如 前缀+组名/key 的路由前缀，
+// 这些路径中的 key 按 logger.Key 的模式写入日志
+func LoggingMiddleware(keyPrefixes ...string) MiddlewareFunc {
 	return func(next Handler) Handler {
 		return HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			start := time.Now()
@@ -26,10 +28,10 @@ func LoggingMiddleware() MiddlewareFunc {
 			// 计算请求处理时间
 			duration := time.Since(start)
 
-			// 记录请求信息
+			// 记录请求信息，路径包含挂载前缀
 			logger.Infof("%s %s %d %s",
 				r.Method,
-				r.URL.Path,
+				PrefixFrom(r.Context())+logPath(r.URL.Path, keyPrefixes),
 				wrapper.statusCode,
 				duration,
 			)
@@ -37,14 +39,14 @@ func LoggingMiddleware() MiddlewareFunc {
 	}
 }
 
-// RecoveryMiddleware 创建一个恢复中间件，防止程序崩溃
-func RecoveryMiddleware() MiddlewareFunc {
+// RecoveryMiddleware 创建一个恢复中间件，防止程序崩溃。keyPrefixes 与 LoggingMiddleware 相同
+func RecoveryMiddleware(keyPrefixes ...string) MiddlewareFunc {
 	return func(next Handler) Handler {
 		return HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			defer func() {
 				if err := recover(); err != nil {
 					// 记录错误
-					logger.Errorf("处理请求 %s 时发生错误: %v", r.URL.Path, err)
+					logger.Errorf("处理请求 %s 时发生错误: %v", PrefixFrom(r.Context())+logPath(r.URL.Path, keyPrefixes), err)
 
 					// 返回500错误
 					http.Error(w,
@@ -83,3 +85,20 @@ func (w *responseWriterWrapper) WriteHeader(statusCode int) {
 	w.statusCode = statusCode
 	w.ResponseWriter.WriteHeader(statusCode)
 }
+
+// Flush 透传到底层 ResponseWriter，保证流式响应在中间件之后仍能及时刷新
+func (w *responseWriterWrapper) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// logPath 返回写入日志的请求路径，位于 keyPrefixes 之下的路径中的 key 经过 logger.KeyPath 处理
+func logPath(path string, keyPrefixes []string) string {
+	for _, prefix := range keyPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return logger.KeyPath(path, prefix)
+		}
+	}
+	return path
+}
@@ -0,0 +1,49 @@
+// Package promrecorder 提供把 pkg/router 的请求统计导出为 Prometheus 指标的 Recorder
+package promrecorder
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Recorder 以 Prometheus 指标记录请求，实现 router.Recorder：
+//
+//	<namespace>_http_requests_total{route,method,status}          请求数
+//	<namespace>_http_request_duration_seconds{route,method}      处理耗时分布
+//
+// route 为注册的路由 pattern，标签的取值数量由注册的路由决定，不随请求路径增长
+type Recorder struct {
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+}
+
+// New 创建 Recorder 并把它的指标注册到 reg，namespace 为指标名前缀，可为空
+func New(reg prometheus.Registerer, namespace string) (*Recorder, error) {
+	r := &Recorder{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by registered route, method and status code.",
+		}, []string{"route", "method", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request handling time by registered route and method.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method"}),
+	}
+	for _, c := range []prometheus.Collector{r.requests, r.duration} {
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// RecordRequest 实现 router.Recorder
+func (r *Recorder) RecordRequest(route, method string, status int, duration time.Duration) {
+	r.requests.WithLabelValues(route, method, strconv.Itoa(status)).Inc()
+	r.duration.WithLabelValues(route, method).Observe(duration.Seconds())
+}
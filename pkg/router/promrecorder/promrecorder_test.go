@@ -0,0 +1,64 @@
+package promrecorder
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/AdrianWangs/go-cache/pkg/router"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// TestRecorder 带参数的路径按注册的路由导出，标签取值不随请求路径增长
+func TestRecorder(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	rec, err := New(reg, "gocache_api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := router.New()
+	r.SetRecorder(rec)
+	r.RegisterFunc("/api/cache/", func(w http.ResponseWriter, req *http.Request) {
+		if strings.HasSuffix(req.URL.Path, "/missing") {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	for _, target := range []string{"/api/cache/scores/Tom", "/api/cache/scores/Jack", "/api/cache/users/1", "/api/cache/scores/missing", "/nope"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+	}
+
+	tests := []struct {
+		route, status string
+		want          float64
+	}{
+		{"/api/cache/", "200", 3},
+		{"/api/cache/", "404", 1},
+		{router.UnmatchedRoute, "404", 1},
+	}
+	for _, tt := range tests {
+		if got := testutil.ToFloat64(rec.requests.WithLabelValues(tt.route, http.MethodGet, tt.status)); got != tt.want {
+			t.Errorf("%s %s 的请求数 = %v, want %v", tt.route, tt.status, got, tt.want)
+		}
+	}
+	if n := testutil.CollectAndCount(rec.requests); n != len(tests) {
+		t.Errorf("请求数指标有 %d 组标签, want %d", n, len(tests))
+	}
+	if n := testutil.CollectAndCount(rec.duration); n != 2 {
+		t.Errorf("耗时指标有 %d 组标签, want 2", n)
+	}
+	if n, err := testutil.GatherAndCount(reg, "gocache_api_http_requests_total", "gocache_api_http_request_duration_seconds"); err != nil || n != 5 {
+		t.Errorf("注册表中的指标 = %d, %v", n, err)
+	}
+}
+
+func TestNewDuplicateRegistration(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	if _, err := New(reg, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := New(reg, ""); err == nil {
+		t.Fatal("在同一个注册表中重复注册应返回错误")
+	}
+}
@@ -2,6 +2,7 @@
 package router
 
 import (
+	"context"
 	"net/http"
 	"strings"
 
@@ -27,6 +28,11 @@ type Router struct {
 	mux         *http.ServeMux
 	routes      map[string]Handler
 	middlewares []MiddlewareFunc
+
+	prefix    string          // 挂载路径前缀，为空表示挂载在根路径
+	rootPaths map[string]bool // 挂载前缀后仍在根路径下可用的路径
+
+	recorder Recorder // 请求统计的接收者，为 nil 表示不统计
 }
 
 // MiddlewareFunc 是一个中间件函数类型
@@ -54,8 +60,8 @@ func (r *Router) Register(pattern string, handler Handler) {
 		finalHandler = r.middlewares[i](finalHandler)
 	}
 
-	// 注册到标准ServeMux
-	r.mux.Handle(pattern, finalHandler)
+	// 注册到标准ServeMux，最外层记下匹配的路由供请求统计使用
+	r.mux.Handle(pattern, routeLabel(pattern, finalHandler))
 	r.routes[pattern] = handler
 
 	logger.Infof("已注册路由: %s", pattern)
@@ -74,9 +80,100 @@ func (r *Router) Group(prefix string) *RouterGroup {
 	}
 }
 
+// Mount 把所有路由挂载到路径前缀 prefix 之下，用于反向代理把服务映射到子路径（例如 /cache）的部署。
+// 以 prefix 开头的请求去掉前缀后再分发，路由和处理器看到的路径与不挂载时相同，
+// 原始前缀可以用 PrefixFrom 取得；其他请求返回 404，rootPaths 中的路径除外，
+// 它们同时在根路径下可用（例如无法配置路径的负载均衡器使用的健康检查）。
+// prefix 为空或 "/" 时不挂载。必须在开始处理请求之前调用
+func (r *Router) Mount(prefix string, rootPaths ...string) {
+	prefix = "/" + strings.Trim(prefix, "/")
+	if prefix == "/" {
+		r.prefix, r.rootPaths = "", nil
+		return
+	}
+	r.prefix = prefix
+	r.rootPaths = make(map[string]bool, len(rootPaths))
+	for _, p := range rootPaths {
+		r.rootPaths[p] = true
+	}
+	logger.Infof("所有路由挂载在 %s 之下，根路径下保留: %v", prefix, rootPaths)
+}
+
+// Prefix 返回 Mount 设置的路径前缀，未挂载时为空
+func (r *Router) Prefix() string {
+	return r.prefix
+}
+
 // ServeHTTP 实现http.Handler接口，将请求转发给ServeMux
 func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	r.mux.ServeHTTP(w, req)
+	if r.recorder != nil {
+		r.serveRecorded(w, req, r.serve)
+		return
+	}
+	r.serve(w, req)
+}
+
+// serve 按挂载前缀分发请求
+func (r *Router) serve(w http.ResponseWriter, req *http.Request) {
+	if r.prefix == "" {
+		r.mux.ServeHTTP(w, req)
+		return
+	}
+	if stripped, ok := stripPrefix(req, r.prefix); ok {
+		r.mux.ServeHTTP(w, stripped)
+		return
+	}
+	if r.rootPaths[req.URL.Path] {
+		r.mux.ServeHTTP(w, req)
+		return
+	}
+	http.NotFound(w, req)
+}
+
+// prefixKey 是保存挂载前缀的 context key
+type prefixKey struct{}
+
+// PrefixFrom 返回请求去掉的挂载前缀，请求不是经由挂载前缀到达时为空。
+// 处理器生成指向本服务的链接时应加上它
+func PrefixFrom(ctx context.Context) string {
+	prefix, _ := ctx.Value(prefixKey{}).(string)
+	return prefix
+}
+
+// stripPrefix 返回去掉前缀 prefix 的请求副本，路径不在 prefix 之下时返回 false。
+// 只匹配完整的路径段：/cache 匹配 /cache 和 /cache/...，不匹配 /cachex
+func stripPrefix(req *http.Request, prefix string) (*http.Request, bool) {
+	path, ok := cutPathPrefix(req.URL.Path, prefix)
+	if !ok {
+		return nil, false
+	}
+	rawPath := ""
+	if req.URL.RawPath != "" {
+		// 前缀本身不含需要转义的字符，转义后的路径以同样的前缀开头
+		if rawPath, ok = cutPathPrefix(req.URL.RawPath, prefix); !ok {
+			return nil, false
+		}
+	}
+
+	stripped := req.Clone(context.WithValue(req.Context(), prefixKey{}, prefix))
+	stripped.URL.Path = path
+	stripped.URL.RawPath = rawPath
+	return stripped, true
+}
+
+// cutPathPrefix 去掉 path 开头的 prefix，prefix 之后必须是路径结尾或 "/"
+func cutPathPrefix(path, prefix string) (string, bool) {
+	rest, ok := strings.CutPrefix(path, prefix)
+	if !ok {
+		return "", false
+	}
+	if rest == "" {
+		return "/", true
+	}
+	if !strings.HasPrefix(rest, "/") {
+		return "", false
+	}
+	return rest, true
 }
 
 // RouterGroup 表示一个路由组，所有注册的路由都将添加相同的前缀
@@ -0,0 +1,84 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// echoRoute 返回处理器看到的路径、转义路径和挂载前缀
+func echoRoute(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("X-Path", r.URL.Path)
+	w.Header().Set("X-Raw-Path", r.URL.RawPath)
+	w.Header().Set("X-Prefix", PrefixFrom(r.Context()))
+}
+
+func newMountedRouter(prefix string) *Router {
+	r := New()
+	r.RegisterFunc("/api/cache/", echoRoute)
+	r.RegisterFunc("/health", echoRoute)
+	r.RegisterFunc("/ready", echoRoute)
+	r.Mount(prefix, "/health", "/ready")
+	return r
+}
+
+// TestMount 挂载前缀后路由只在前缀之下可用，处理器看到的路径与不挂载时相同；
+// 健康检查在根路径下同样可用
+func TestMount(t *testing.T) {
+	tests := []struct {
+		name    string
+		target  string
+		status  int
+		path    string
+		rawPath string
+		prefix  string
+	}{
+		{"前缀下的路由", "/cache/api/cache/scores/Tom", http.StatusOK, "/api/cache/scores/Tom", "", "/cache"},
+		{"转义的路径", "/cache/api/cache/scores/a%2Fb", http.StatusOK, "/api/cache/scores/a/b", "/api/cache/scores/a%2Fb", "/cache"},
+		{"前缀下的健康检查", "/cache/health", http.StatusOK, "/health", "", "/cache"},
+		{"根路径下的健康检查", "/health", http.StatusOK, "/health", "", ""},
+		{"根路径下的就绪检查", "/ready", http.StatusOK, "/ready", "", ""},
+		{"根路径下的其他路由", "/api/cache/scores/Tom", http.StatusNotFound, "", "", ""},
+		{"只匹配完整的路径段", "/cachex/api/cache/scores/Tom", http.StatusNotFound, "", "", ""},
+		{"前缀本身", "/cache", http.StatusNotFound, "", "", ""},
+	}
+	for _, prefix := range []string{"/cache", "cache/", "/cache/"} {
+		r := newMountedRouter(prefix)
+		if r.Prefix() != "/cache" {
+			t.Fatalf("Mount(%q): Prefix = %q", prefix, r.Prefix())
+		}
+		for _, tt := range tests {
+			t.Run(prefix+" "+tt.name, func(t *testing.T) {
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.target, nil))
+				if w.Code != tt.status {
+					t.Fatalf("状态码 = %d, want %d", w.Code, tt.status)
+				}
+				if tt.status != http.StatusOK {
+					return
+				}
+				if got := w.Header().Get("X-Path"); got != tt.path {
+					t.Errorf("路径 = %q, want %q", got, tt.path)
+				}
+				if got := w.Header().Get("X-Raw-Path"); got != tt.rawPath {
+					t.Errorf("转义路径 = %q, want %q", got, tt.rawPath)
+				}
+				if got := w.Header().Get("X-Prefix"); got != tt.prefix {
+					t.Errorf("前缀 = %q, want %q", got, tt.prefix)
+				}
+			})
+		}
+	}
+}
+
+// TestMountRoot 前缀为空或 "/" 时不挂载，路由在根路径下可用
+func TestMountRoot(t *testing.T) {
+	for _, prefix := range []string{"", "/"} {
+		r := newMountedRouter(prefix)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/cache/scores/Tom", nil))
+		if w.Code != http.StatusOK || r.Prefix() != "" || w.Header().Get("X-Prefix") != "" {
+			t.Fatalf("Mount(%q): %d, Prefix = %q", prefix, w.Code, r.Prefix())
+		}
+	}
+}
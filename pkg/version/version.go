@@ -0,0 +1,41 @@
+// Package version 保存构建时注入的版本信息，通过 -ldflags 设置，例如：
+//
+//	go build -ldflags "-X github.com/AdrianWangs/go-cache/pkg/version.Version=v1.2.0 \
+//	  -X github.com/AdrianWangs/go-cache/pkg/version.Commit=$(git rev-parse --short HEAD) \
+//	  -X github.com/AdrianWangs/go-cache/pkg/version.Date=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+package version
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// 构建时通过 -ldflags "-X" 注入，未注入时保持默认值
+var (
+	Version = "dev"     // 版本号
+	Commit  = "unknown" // 提交哈希
+	Date    = "unknown" // 构建时间
+)
+
+// Info 构建信息
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	Date      string `json:"date"`
+	GoVersion string `json:"go_version"` // 编译使用的 Go 版本
+}
+
+// Get 返回当前二进制的构建信息
+func Get() Info {
+	return Info{
+		Version:   Version,
+		Commit:    Commit,
+		Date:      Date,
+		GoVersion: runtime.Version(),
+	}
+}
+
+// String 返回单行的构建信息，用于启动日志
+func (i Info) String() string {
+	return fmt.Sprintf("%s (commit %s, built %s, %s)", i.Version, i.Commit, i.Date, i.GoVersion)
+}
@@ -22,11 +22,15 @@ const (
 )
 
 type Request struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Group         string                 `protobuf:"bytes,1,opt,name=group,proto3" json:"group,omitempty"` // 组名
-	Key           string                 `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`     // 键
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Group          string                 `protobuf:"bytes,1,opt,name=group,proto3" json:"group,omitempty"`                                                // 组名
+	Key            string                 `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`                                                    // 键
+	Hops           *uint32                `protobuf:"varint,3,opt,name=hops,proto3,oneof" json:"hops,omitempty"`                                           // 请求已被节点转发的次数，缺省为 0
+	From           *string                `protobuf:"bytes,4,opt,name=from,proto3,oneof" json:"from,omitempty"`                                            // 转发该请求的节点标识
+	CacheOnly      *bool                  `protobuf:"varint,5,opt,name=cache_only,json=cacheOnly,proto3,oneof" json:"cache_only,omitempty"`                // 只读取接收节点本地缓存中的值：不转发、不回源，未缓存时返回不存在
+	RingGeneration *uint64                `protobuf:"varint,6,opt,name=ring_generation,json=ringGeneration,proto3,oneof" json:"ring_generation,omitempty"` // 转发节点哈希环的代数，见 consistenthash.Map.Generation
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
 }
 
 func (x *Request) Reset() {
@@ -73,11 +77,45 @@ func (x *Request) GetKey() string {
 	return ""
 }
 
+func (x *Request) GetHops() uint32 {
+	if x != nil && x.Hops != nil {
+		return *x.Hops
+	}
+	return 0
+}
+
+func (x *Request) GetFrom() string {
+	if x != nil && x.From != nil {
+		return *x.From
+	}
+	return ""
+}
+
+func (x *Request) GetCacheOnly() bool {
+	if x != nil && x.CacheOnly != nil {
+		return *x.CacheOnly
+	}
+	return false
+}
+
+func (x *Request) GetRingGeneration() uint64 {
+	if x != nil && x.RingGeneration != nil {
+		return *x.RingGeneration
+	}
+	return 0
+}
+
 type Response struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Value         []byte                 `protobuf:"bytes,1,opt,name=value,proto3" json:"value,omitempty"` // 值
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Value           []byte                 `protobuf:"bytes,1,opt,name=value,proto3" json:"value,omitempty"`                                                   // 值
+	ExpiresAt       *int64                 `protobuf:"varint,2,opt,name=expires_at,json=expiresAt,proto3,oneof" json:"expires_at,omitempty"`                   // 绝对过期时间（Unix 纳秒），缺省表示永不过期或未知
+	Version         *uint64                `protobuf:"varint,3,opt,name=version,proto3,oneof" json:"version,omitempty"`                                        // 条目在提供它的节点上的写入序号，缺省表示未知
+	Source          *string                `protobuf:"bytes,4,opt,name=source,proto3,oneof" json:"source,omitempty"`                                           // 值的来源：cache / loader / peer
+	Replicas        *uint32                `protobuf:"varint,5,opt,name=replicas,proto3,oneof" json:"replicas,omitempty"`                                      // 热点 key 被归属节点复制到的节点数（哈希环上紧随归属节点的节点），缺省表示未复制
+	ReplicatedUntil *int64                 `protobuf:"varint,6,opt,name=replicated_until,json=replicatedUntil,proto3,oneof" json:"replicated_until,omitempty"` // 热点复制的到期时间（Unix 纳秒）
+	Node            *string                `protobuf:"bytes,7,opt,name=node,proto3,oneof" json:"node,omitempty"`                                               // 产生该值的节点标识：值取自对等节点时为该对等节点，缺省表示未知或节点不公开标识
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
 }
 
 func (x *Response) Reset() {
@@ -117,6 +155,48 @@ func (x *Response) GetValue() []byte {
 	return nil
 }
 
+func (x *Response) GetExpiresAt() int64 {
+	if x != nil && x.ExpiresAt != nil {
+		return *x.ExpiresAt
+	}
+	return 0
+}
+
+func (x *Response) GetVersion() uint64 {
+	if x != nil && x.Version != nil {
+		return *x.Version
+	}
+	return 0
+}
+
+func (x *Response) GetSource() string {
+	if x != nil && x.Source != nil {
+		return *x.Source
+	}
+	return ""
+}
+
+func (x *Response) GetReplicas() uint32 {
+	if x != nil && x.Replicas != nil {
+		return *x.Replicas
+	}
+	return 0
+}
+
+func (x *Response) GetReplicatedUntil() int64 {
+	if x != nil && x.ReplicatedUntil != nil {
+		return *x.ReplicatedUntil
+	}
+	return 0
+}
+
+func (x *Response) GetNode() string {
+	if x != nil && x.Node != nil {
+		return *x.Node
+	}
+	return ""
+}
+
 type DeleteRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Group         string                 `protobuf:"bytes,1,opt,name=group,proto3" json:"group,omitempty"` // 组名
@@ -213,25 +293,1873 @@ func (x *DeleteResponse) GetSuccess() bool {
 	return false
 }
 
-var File_cache_server_proto protoreflect.FileDescriptor
+type SetRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Group         string                 `protobuf:"bytes,1,opt,name=group,proto3" json:"group,omitempty"`                     // 组名
+	Key           string                 `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`                         // 键
+	Value         []byte                 `protobuf:"bytes,3,opt,name=value,proto3" json:"value,omitempty"`                     // 值
+	TtlMs         *int64                 `protobuf:"varint,4,opt,name=ttl_ms,json=ttlMs,proto3,oneof" json:"ttl_ms,omitempty"` // 过期时间（毫秒），缺省或不大于 0 时使用组的默认 TTL
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
 
-const file_cache_server_proto_rawDesc = "" +
-	"\n" +
-	"\x12cache_server.proto\x12\bgo_cache\"1\n" +
-	"\aRequest\x12\x14\n" +
-	"\x05group\x18\x01 \x01(\tR\x05group\x12\x10\n" +
-	"\x03key\x18\x02 \x01(\tR\x03key\" \n" +
-	"\bResponse\x12\x14\n" +
-	"\x05value\x18\x01 \x01(\fR\x05value\"7\n" +
-	"\rDeleteRequest\x12\x14\n" +
-	"\x05group\x18\x01 \x01(\tR\x05group\x12\x10\n" +
-	"\x03key\x18\x02 \x01(\tR\x03key\"*\n" +
-	"\x0eDeleteResponse\x12\x18\n" +
-	"\asuccess\x18\x01 \x01(\bR\asuccess2w\n" +
+func (x *SetRequest) Reset() {
+	*x = SetRequest{}
+	mi := &file_cache_server_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetRequest) ProtoMessage() {}
+
+func (x *SetRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_cache_server_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetRequest.ProtoReflect.Descriptor instead.
+func (*SetRequest) Descriptor() ([]byte, []int) {
+	return file_cache_server_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *SetRequest) GetGroup() string {
+	if x != nil {
+		return x.Group
+	}
+	return ""
+}
+
+func (x *SetRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *SetRequest) GetValue() []byte {
+	if x != nil {
+		return x.Value
+	}
+	return nil
+}
+
+func (x *SetRequest) GetTtlMs() int64 {
+	if x != nil && x.TtlMs != nil {
+		return *x.TtlMs
+	}
+	return 0
+}
+
+type SetResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"` // 是否成功
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetResponse) Reset() {
+	*x = SetResponse{}
+	mi := &file_cache_server_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetResponse) ProtoMessage() {}
+
+func (x *SetResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_cache_server_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetResponse.ProtoReflect.Descriptor instead.
+func (*SetResponse) Descriptor() ([]byte, []int) {
+	return file_cache_server_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *SetResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+type DeleteBatchRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Group         string                 `protobuf:"bytes,1,opt,name=group,proto3" json:"group,omitempty"` // 组名
+	Keys          []string               `protobuf:"bytes,2,rep,name=keys,proto3" json:"keys,omitempty"`   // 要删除的键
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteBatchRequest) Reset() {
+	*x = DeleteBatchRequest{}
+	mi := &file_cache_server_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteBatchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteBatchRequest) ProtoMessage() {}
+
+func (x *DeleteBatchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_cache_server_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteBatchRequest.ProtoReflect.Descriptor instead.
+func (*DeleteBatchRequest) Descriptor() ([]byte, []int) {
+	return file_cache_server_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *DeleteBatchRequest) GetGroup() string {
+	if x != nil {
+		return x.Group
+	}
+	return ""
+}
+
+func (x *DeleteBatchRequest) GetKeys() []string {
+	if x != nil {
+		return x.Keys
+	}
+	return nil
+}
+
+type DeleteBatchResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`           // 键
+	Status        string                 `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`     // 结果：deleted / not_found / error
+	Error         *string                `protobuf:"bytes,3,opt,name=error,proto3,oneof" json:"error,omitempty"` // status 为 error 时的错误信息
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteBatchResult) Reset() {
+	*x = DeleteBatchResult{}
+	mi := &file_cache_server_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteBatchResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteBatchResult) ProtoMessage() {}
+
+func (x *DeleteBatchResult) ProtoReflect() protoreflect.Message {
+	mi := &file_cache_server_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteBatchResult.ProtoReflect.Descriptor instead.
+func (*DeleteBatchResult) Descriptor() ([]byte, []int) {
+	return file_cache_server_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *DeleteBatchResult) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *DeleteBatchResult) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *DeleteBatchResult) GetError() string {
+	if x != nil && x.Error != nil {
+		return *x.Error
+	}
+	return ""
+}
+
+type DeleteBatchResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Results       []*DeleteBatchResult   `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"` // 与请求中的键一一对应
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteBatchResponse) Reset() {
+	*x = DeleteBatchResponse{}
+	mi := &file_cache_server_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteBatchResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteBatchResponse) ProtoMessage() {}
+
+func (x *DeleteBatchResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_cache_server_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteBatchResponse.ProtoReflect.Descriptor instead.
+func (*DeleteBatchResponse) Descriptor() ([]byte, []int) {
+	return file_cache_server_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *DeleteBatchResponse) GetResults() []*DeleteBatchResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+type StatsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Group         *string                `protobuf:"bytes,1,opt,name=group,proto3,oneof" json:"group,omitempty"` // 组名，为空时返回所有组
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StatsRequest) Reset() {
+	*x = StatsRequest{}
+	mi := &file_cache_server_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StatsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatsRequest) ProtoMessage() {}
+
+func (x *StatsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_cache_server_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatsRequest.ProtoReflect.Descriptor instead.
+func (*StatsRequest) Descriptor() ([]byte, []int) {
+	return file_cache_server_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *StatsRequest) GetGroup() string {
+	if x != nil && x.Group != nil {
+		return *x.Group
+	}
+	return ""
+}
+
+type GroupStats struct {
+	state                    protoimpl.MessageState `protogen:"open.v1"`
+	Name                     string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`                                                                                   // 组名
+	Hits                     *int64                 `protobuf:"varint,2,opt,name=hits,proto3,oneof" json:"hits,omitempty"`                                                                            // 命中次数
+	Misses                   *int64                 `protobuf:"varint,3,opt,name=misses,proto3,oneof" json:"misses,omitempty"`                                                                        // 未命中次数
+	Evictions                *int64                 `protobuf:"varint,4,opt,name=evictions,proto3,oneof" json:"evictions,omitempty"`                                                                  // 容量淘汰次数
+	Bytes                    *int64                 `protobuf:"varint,5,opt,name=bytes,proto3,oneof" json:"bytes,omitempty"`                                                                          // 当前占用字节数
+	Entries                  *int64                 `protobuf:"varint,6,opt,name=entries,proto3,oneof" json:"entries,omitempty"`                                                                      // 当前条目数
+	Gets                     *int64                 `protobuf:"varint,7,opt,name=gets,proto3,oneof" json:"gets,omitempty"`                                                                            // 请求总数
+	MaxBytes                 *int64                 `protobuf:"varint,8,opt,name=max_bytes,json=maxBytes,proto3,oneof" json:"max_bytes,omitempty"`                                                    // 容量上限
+	Throttled                *int64                 `protobuf:"varint,9,opt,name=throttled,proto3,oneof" json:"throttled,omitempty"`                                                                  // 因限流被拒绝的请求数
+	Mode                     *string                `protobuf:"bytes,10,opt,name=mode,proto3,oneof" json:"mode,omitempty"`                                                                            // 当前生效的模式：readwrite / readonly / readonly-local
+	Cost                     *int64                 `protobuf:"varint,11,opt,name=cost,proto3,oneof" json:"cost,omitempty"`                                                                           // 计入容量上限的总成本，未设置条目成本函数时等于 bytes
+	EvictionsPerMinute       *int64                 `protobuf:"varint,12,opt,name=evictions_per_minute,json=evictionsPerMinute,proto3,oneof" json:"evictions_per_minute,omitempty"`                   // 最近一分钟内的容量淘汰数，未开启淘汰压力统计时为 0
+	EvictedAgeP50Ms          *int64                 `protobuf:"varint,13,opt,name=evicted_age_p50_ms,json=evictedAgeP50Ms,proto3,oneof" json:"evicted_age_p50_ms,omitempty"`                          // 最近一分钟内被淘汰条目存活时长的中位数（毫秒）
+	EvictedAgeP90Ms          *int64                 `protobuf:"varint,14,opt,name=evicted_age_p90_ms,json=evictedAgeP90Ms,proto3,oneof" json:"evicted_age_p90_ms,omitempty"`                          // 最近一分钟内被淘汰条目存活时长的 90 分位数（毫秒）
+	EvictionPressureWarnings *int64                 `protobuf:"varint,15,opt,name=eviction_pressure_warnings,json=evictionPressureWarnings,proto3,oneof" json:"eviction_pressure_warnings,omitempty"` // 因淘汰过快输出的警告次数
+	CancelledGets            *int64                 `protobuf:"varint,16,opt,name=cancelled_gets,json=cancelledGets,proto3,oneof" json:"cancelled_gets,omitempty"`                                    // 等待加载期间调用方放弃（例如客户端断开）的读取次数
+	AbortedLoads             *int64                 `protobuf:"varint,17,opt,name=aborted_loads,json=abortedLoads,proto3,oneof" json:"aborted_loads,omitempty"`                                       // 等待的调用方都已放弃而取消的加载次数
+	WatermarkEvictions       *int64                 `protobuf:"varint,18,opt,name=watermark_evictions,json=watermarkEvictions,proto3,oneof" json:"watermark_evictions,omitempty"`                     // 超过高水位后台提前淘汰的条目数，也计入 evictions
+	LoadsExecuted            *int64                 `protobuf:"varint,19,opt,name=loads_executed,json=loadsExecuted,proto3,oneof" json:"loads_executed,omitempty"`                                    // 实际执行的加载次数
+	LoadsDeduped             *int64                 `protobuf:"varint,20,opt,name=loads_deduped,json=loadsDeduped,proto3,oneof" json:"loads_deduped,omitempty"`                                       // 加入同一 key 进行中的加载而未重复执行的次数
+	CurrentInflight          *int64                 `protobuf:"varint,21,opt,name=current_inflight,json=currentInflight,proto3,oneof" json:"current_inflight,omitempty"`                              // 正在执行的加载数
+	unknownFields            protoimpl.UnknownFields
+	sizeCache                protoimpl.SizeCache
+}
+
+func (x *GroupStats) Reset() {
+	*x = GroupStats{}
+	mi := &file_cache_server_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GroupStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GroupStats) ProtoMessage() {}
+
+func (x *GroupStats) ProtoReflect() protoreflect.Message {
+	mi := &file_cache_server_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GroupStats.ProtoReflect.Descriptor instead.
+func (*GroupStats) Descriptor() ([]byte, []int) {
+	return file_cache_server_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *GroupStats) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *GroupStats) GetHits() int64 {
+	if x != nil && x.Hits != nil {
+		return *x.Hits
+	}
+	return 0
+}
+
+func (x *GroupStats) GetMisses() int64 {
+	if x != nil && x.Misses != nil {
+		return *x.Misses
+	}
+	return 0
+}
+
+func (x *GroupStats) GetEvictions() int64 {
+	if x != nil && x.Evictions != nil {
+		return *x.Evictions
+	}
+	return 0
+}
+
+func (x *GroupStats) GetBytes() int64 {
+	if x != nil && x.Bytes != nil {
+		return *x.Bytes
+	}
+	return 0
+}
+
+func (x *GroupStats) GetEntries() int64 {
+	if x != nil && x.Entries != nil {
+		return *x.Entries
+	}
+	return 0
+}
+
+func (x *GroupStats) GetGets() int64 {
+	if x != nil && x.Gets != nil {
+		return *x.Gets
+	}
+	return 0
+}
+
+func (x *GroupStats) GetMaxBytes() int64 {
+	if x != nil && x.MaxBytes != nil {
+		return *x.MaxBytes
+	}
+	return 0
+}
+
+func (x *GroupStats) GetThrottled() int64 {
+	if x != nil && x.Throttled != nil {
+		return *x.Throttled
+	}
+	return 0
+}
+
+func (x *GroupStats) GetMode() string {
+	if x != nil && x.Mode != nil {
+		return *x.Mode
+	}
+	return ""
+}
+
+func (x *GroupStats) GetCost() int64 {
+	if x != nil && x.Cost != nil {
+		return *x.Cost
+	}
+	return 0
+}
+
+func (x *GroupStats) GetEvictionsPerMinute() int64 {
+	if x != nil && x.EvictionsPerMinute != nil {
+		return *x.EvictionsPerMinute
+	}
+	return 0
+}
+
+func (x *GroupStats) GetEvictedAgeP50Ms() int64 {
+	if x != nil && x.EvictedAgeP50Ms != nil {
+		return *x.EvictedAgeP50Ms
+	}
+	return 0
+}
+
+func (x *GroupStats) GetEvictedAgeP90Ms() int64 {
+	if x != nil && x.EvictedAgeP90Ms != nil {
+		return *x.EvictedAgeP90Ms
+	}
+	return 0
+}
+
+func (x *GroupStats) GetEvictionPressureWarnings() int64 {
+	if x != nil && x.EvictionPressureWarnings != nil {
+		return *x.EvictionPressureWarnings
+	}
+	return 0
+}
+
+func (x *GroupStats) GetCancelledGets() int64 {
+	if x != nil && x.CancelledGets != nil {
+		return *x.CancelledGets
+	}
+	return 0
+}
+
+func (x *GroupStats) GetAbortedLoads() int64 {
+	if x != nil && x.AbortedLoads != nil {
+		return *x.AbortedLoads
+	}
+	return 0
+}
+
+func (x *GroupStats) GetWatermarkEvictions() int64 {
+	if x != nil && x.WatermarkEvictions != nil {
+		return *x.WatermarkEvictions
+	}
+	return 0
+}
+
+func (x *GroupStats) GetLoadsExecuted() int64 {
+	if x != nil && x.LoadsExecuted != nil {
+		return *x.LoadsExecuted
+	}
+	return 0
+}
+
+func (x *GroupStats) GetLoadsDeduped() int64 {
+	if x != nil && x.LoadsDeduped != nil {
+		return *x.LoadsDeduped
+	}
+	return 0
+}
+
+func (x *GroupStats) GetCurrentInflight() int64 {
+	if x != nil && x.CurrentInflight != nil {
+		return *x.CurrentInflight
+	}
+	return 0
+}
+
+type StatsResponse struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Groups         []*GroupStats          `protobuf:"bytes,1,rep,name=groups,proto3" json:"groups,omitempty"`                                              // 各组统计
+	UptimeSeconds  *int64                 `protobuf:"varint,2,opt,name=uptime_seconds,json=uptimeSeconds,proto3,oneof" json:"uptime_seconds,omitempty"`    // 节点运行时间（秒）
+	NodeThrottled  *int64                 `protobuf:"varint,3,opt,name=node_throttled,json=nodeThrottled,proto3,oneof" json:"node_throttled,omitempty"`    // 因节点级限流被拒绝的请求数
+	Mode           *string                `protobuf:"bytes,4,opt,name=mode,proto3,oneof" json:"mode,omitempty"`                                            // 节点级模式
+	Peers          []*PeerStats           `protobuf:"bytes,5,rep,name=peers,proto3" json:"peers,omitempty"`                                                // 本节点发往各对等节点的请求统计
+	Warmup         *WarmupStats           `protobuf:"bytes,6,opt,name=warmup,proto3,oneof" json:"warmup,omitempty"`                                        // 启动预热的进度，未开启预热时缺省
+	Prime          *PrimeStats            `protobuf:"bytes,7,opt,name=prime,proto3,oneof" json:"prime,omitempty"`                                          // 按清单预加载的进度，未配置清单时缺省
+	RingGeneration *uint64                `protobuf:"varint,8,opt,name=ring_generation,json=ringGeneration,proto3,oneof" json:"ring_generation,omitempty"` // 节点哈希环的代数，见 consistenthash.Map.Generation
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *StatsResponse) Reset() {
+	*x = StatsResponse{}
+	mi := &file_cache_server_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StatsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatsResponse) ProtoMessage() {}
+
+func (x *StatsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_cache_server_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatsResponse.ProtoReflect.Descriptor instead.
+func (*StatsResponse) Descriptor() ([]byte, []int) {
+	return file_cache_server_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *StatsResponse) GetGroups() []*GroupStats {
+	if x != nil {
+		return x.Groups
+	}
+	return nil
+}
+
+func (x *StatsResponse) GetUptimeSeconds() int64 {
+	if x != nil && x.UptimeSeconds != nil {
+		return *x.UptimeSeconds
+	}
+	return 0
+}
+
+func (x *StatsResponse) GetNodeThrottled() int64 {
+	if x != nil && x.NodeThrottled != nil {
+		return *x.NodeThrottled
+	}
+	return 0
+}
+
+func (x *StatsResponse) GetMode() string {
+	if x != nil && x.Mode != nil {
+		return *x.Mode
+	}
+	return ""
+}
+
+func (x *StatsResponse) GetPeers() []*PeerStats {
+	if x != nil {
+		return x.Peers
+	}
+	return nil
+}
+
+func (x *StatsResponse) GetWarmup() *WarmupStats {
+	if x != nil {
+		return x.Warmup
+	}
+	return nil
+}
+
+func (x *StatsResponse) GetPrime() *PrimeStats {
+	if x != nil {
+		return x.Prime
+	}
+	return nil
+}
+
+func (x *StatsResponse) GetRingGeneration() uint64 {
+	if x != nil && x.RingGeneration != nil {
+		return *x.RingGeneration
+	}
+	return 0
+}
+
+type PrimeStats struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	State           string                 `protobuf:"bytes,1,opt,name=state,proto3" json:"state,omitempty"`                                                     // 预加载状态：running / done / cancelled
+	StartedUnixNano *int64                 `protobuf:"varint,2,opt,name=started_unix_nano,json=startedUnixNano,proto3,oneof" json:"started_unix_nano,omitempty"` // 开始时间（Unix 纳秒）
+	ElapsedMs       *int64                 `protobuf:"varint,3,opt,name=elapsed_ms,json=elapsedMs,proto3,oneof" json:"elapsed_ms,omitempty"`                     // 已用时间（毫秒），结束后为总用时
+	Total           *int64                 `protobuf:"varint,4,opt,name=total,proto3,oneof" json:"total,omitempty"`                                              // 清单中的 key 数
+	Done            *int64                 `protobuf:"varint,5,opt,name=done,proto3,oneof" json:"done,omitempty"`                                                // 已处理的 key 数
+	Loaded          *int64                 `protobuf:"varint,6,opt,name=loaded,proto3,oneof" json:"loaded,omitempty"`                                            // 加载到本地缓存的 key 数
+	Cached          *int64                 `protobuf:"varint,7,opt,name=cached,proto3,oneof" json:"cached,omitempty"`                                            // 本地已有值而跳过的 key 数
+	NotOwned        *int64                 `protobuf:"varint,8,opt,name=not_owned,json=notOwned,proto3,oneof" json:"not_owned,omitempty"`                        // 归属其他节点而跳过的 key 数
+	Missing         *int64                 `protobuf:"varint,9,opt,name=missing,proto3,oneof" json:"missing,omitempty"`                                          // 数据源中不存在的 key 数
+	Failed          *int64                 `protobuf:"varint,10,opt,name=failed,proto3,oneof" json:"failed,omitempty"`                                           // 加载失败或缓存组不存在的 key 数
+	Error           *string                `protobuf:"bytes,11,opt,name=error,proto3,oneof" json:"error,omitempty"`                                              // 预加载未完成的原因
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *PrimeStats) Reset() {
+	*x = PrimeStats{}
+	mi := &file_cache_server_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PrimeStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PrimeStats) ProtoMessage() {}
+
+func (x *PrimeStats) ProtoReflect() protoreflect.Message {
+	mi := &file_cache_server_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PrimeStats.ProtoReflect.Descriptor instead.
+func (*PrimeStats) Descriptor() ([]byte, []int) {
+	return file_cache_server_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *PrimeStats) GetState() string {
+	if x != nil {
+		return x.State
+	}
+	return ""
+}
+
+func (x *PrimeStats) GetStartedUnixNano() int64 {
+	if x != nil && x.StartedUnixNano != nil {
+		return *x.StartedUnixNano
+	}
+	return 0
+}
+
+func (x *PrimeStats) GetElapsedMs() int64 {
+	if x != nil && x.ElapsedMs != nil {
+		return *x.ElapsedMs
+	}
+	return 0
+}
+
+func (x *PrimeStats) GetTotal() int64 {
+	if x != nil && x.Total != nil {
+		return *x.Total
+	}
+	return 0
+}
+
+func (x *PrimeStats) GetDone() int64 {
+	if x != nil && x.Done != nil {
+		return *x.Done
+	}
+	return 0
+}
+
+func (x *PrimeStats) GetLoaded() int64 {
+	if x != nil && x.Loaded != nil {
+		return *x.Loaded
+	}
+	return 0
+}
+
+func (x *PrimeStats) GetCached() int64 {
+	if x != nil && x.Cached != nil {
+		return *x.Cached
+	}
+	return 0
+}
+
+func (x *PrimeStats) GetNotOwned() int64 {
+	if x != nil && x.NotOwned != nil {
+		return *x.NotOwned
+	}
+	return 0
+}
+
+func (x *PrimeStats) GetMissing() int64 {
+	if x != nil && x.Missing != nil {
+		return *x.Missing
+	}
+	return 0
+}
+
+func (x *PrimeStats) GetFailed() int64 {
+	if x != nil && x.Failed != nil {
+		return *x.Failed
+	}
+	return 0
+}
+
+func (x *PrimeStats) GetError() string {
+	if x != nil && x.Error != nil {
+		return *x.Error
+	}
+	return ""
+}
+
+type WarmupStats struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	State           string                 `protobuf:"bytes,1,opt,name=state,proto3" json:"state,omitempty"`                                                     // 预热状态：running / done / cancelled / failed
+	StartedUnixNano *int64                 `protobuf:"varint,2,opt,name=started_unix_nano,json=startedUnixNano,proto3,oneof" json:"started_unix_nano,omitempty"` // 开始时间（Unix 纳秒）
+	ElapsedMs       *int64                 `protobuf:"varint,3,opt,name=elapsed_ms,json=elapsedMs,proto3,oneof" json:"elapsed_ms,omitempty"`                     // 已用时间（毫秒），结束后为总用时
+	Candidates      *int64                 `protobuf:"varint,4,opt,name=candidates,proto3,oneof" json:"candidates,omitempty"`                                    // 对等节点列出的、现归本节点所有的 key 数
+	Selected        *int64                 `protobuf:"varint,5,opt,name=selected,proto3,oneof" json:"selected,omitempty"`                                        // 按访问次数和预算选中要拉取的 key 数
+	Pulled          *int64                 `protobuf:"varint,6,opt,name=pulled,proto3,oneof" json:"pulled,omitempty"`                                            // 已拉取并写入本地缓存的 key 数
+	Bytes           *int64                 `protobuf:"varint,7,opt,name=bytes,proto3,oneof" json:"bytes,omitempty"`                                              // 已拉取的值的总字节数
+	Skipped         *int64                 `protobuf:"varint,8,opt,name=skipped,proto3,oneof" json:"skipped,omitempty"`                                          // 拉取时已不在对等节点缓存中、或本地已有值而跳过的 key 数
+	Failed          *int64                 `protobuf:"varint,9,opt,name=failed,proto3,oneof" json:"failed,omitempty"`                                            // 拉取失败的 key 数
+	Error           *string                `protobuf:"bytes,10,opt,name=error,proto3,oneof" json:"error,omitempty"`                                              // 预热未完成的原因
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *WarmupStats) Reset() {
+	*x = WarmupStats{}
+	mi := &file_cache_server_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WarmupStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WarmupStats) ProtoMessage() {}
+
+func (x *WarmupStats) ProtoReflect() protoreflect.Message {
+	mi := &file_cache_server_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WarmupStats.ProtoReflect.Descriptor instead.
+func (*WarmupStats) Descriptor() ([]byte, []int) {
+	return file_cache_server_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *WarmupStats) GetState() string {
+	if x != nil {
+		return x.State
+	}
+	return ""
+}
+
+func (x *WarmupStats) GetStartedUnixNano() int64 {
+	if x != nil && x.StartedUnixNano != nil {
+		return *x.StartedUnixNano
+	}
+	return 0
+}
+
+func (x *WarmupStats) GetElapsedMs() int64 {
+	if x != nil && x.ElapsedMs != nil {
+		return *x.ElapsedMs
+	}
+	return 0
+}
+
+func (x *WarmupStats) GetCandidates() int64 {
+	if x != nil && x.Candidates != nil {
+		return *x.Candidates
+	}
+	return 0
+}
+
+func (x *WarmupStats) GetSelected() int64 {
+	if x != nil && x.Selected != nil {
+		return *x.Selected
+	}
+	return 0
+}
+
+func (x *WarmupStats) GetPulled() int64 {
+	if x != nil && x.Pulled != nil {
+		return *x.Pulled
+	}
+	return 0
+}
+
+func (x *WarmupStats) GetBytes() int64 {
+	if x != nil && x.Bytes != nil {
+		return *x.Bytes
+	}
+	return 0
+}
+
+func (x *WarmupStats) GetSkipped() int64 {
+	if x != nil && x.Skipped != nil {
+		return *x.Skipped
+	}
+	return 0
+}
+
+func (x *WarmupStats) GetFailed() int64 {
+	if x != nil && x.Failed != nil {
+		return *x.Failed
+	}
+	return 0
+}
+
+func (x *WarmupStats) GetError() string {
+	if x != nil && x.Error != nil {
+		return *x.Error
+	}
+	return ""
+}
+
+type PeerStats struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	Peer              string                 `protobuf:"bytes,1,opt,name=peer,proto3" json:"peer,omitempty"`                                                               // 对等节点标识
+	Requests          *int64                 `protobuf:"varint,2,opt,name=requests,proto3,oneof" json:"requests,omitempty"`                                                // 发出的请求数
+	BytesOut          *int64                 `protobuf:"varint,3,opt,name=bytes_out,json=bytesOut,proto3,oneof" json:"bytes_out,omitempty"`                                // 发送的请求负载字节数
+	BytesIn           *int64                 `protobuf:"varint,4,opt,name=bytes_in,json=bytesIn,proto3,oneof" json:"bytes_in,omitempty"`                                   // 收到的响应负载字节数
+	Timeouts          *int64                 `protobuf:"varint,5,opt,name=timeouts,proto3,oneof" json:"timeouts,omitempty"`                                                // 超时的请求数
+	ConnRefused       *int64                 `protobuf:"varint,6,opt,name=conn_refused,json=connRefused,proto3,oneof" json:"conn_refused,omitempty"`                       // 无法连接（连接被拒绝或 gRPC Unavailable）的请求数
+	BadStatus         *int64                 `protobuf:"varint,7,opt,name=bad_status,json=badStatus,proto3,oneof" json:"bad_status,omitempty"`                             // 返回失败状态（HTTP 非 200/404，gRPC 非 NotFound）的请求数
+	OtherErrors       *int64                 `protobuf:"varint,8,opt,name=other_errors,json=otherErrors,proto3,oneof" json:"other_errors,omitempty"`                       // 其他错误数
+	LastErrorUnixNano *int64                 `protobuf:"varint,9,opt,name=last_error_unix_nano,json=lastErrorUnixNano,proto3,oneof" json:"last_error_unix_nano,omitempty"` // 最近一次错误的时间（Unix 纳秒）
+	InFlight          *int64                 `protobuf:"varint,10,opt,name=in_flight,json=inFlight,proto3,oneof" json:"in_flight,omitempty"`                               // 当前未完成的请求数
+	Busy              *int64                 `protobuf:"varint,11,opt,name=busy,proto3,oneof" json:"busy,omitempty"`                                                       // 因未完成的请求达到上限而未发出的请求数
+	Completed         *int64                 `protobuf:"varint,12,opt,name=completed,proto3,oneof" json:"completed,omitempty"`                                             // 已完成（成功或失败）的请求数
+	LatencyNanos      *int64                 `protobuf:"varint,13,opt,name=latency_nanos,json=latencyNanos,proto3,oneof" json:"latency_nanos,omitempty"`                   // 已完成的请求的总耗时（纳秒）
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *PeerStats) Reset() {
+	*x = PeerStats{}
+	mi := &file_cache_server_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PeerStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PeerStats) ProtoMessage() {}
+
+func (x *PeerStats) ProtoReflect() protoreflect.Message {
+	mi := &file_cache_server_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PeerStats.ProtoReflect.Descriptor instead.
+func (*PeerStats) Descriptor() ([]byte, []int) {
+	return file_cache_server_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *PeerStats) GetPeer() string {
+	if x != nil {
+		return x.Peer
+	}
+	return ""
+}
+
+func (x *PeerStats) GetRequests() int64 {
+	if x != nil && x.Requests != nil {
+		return *x.Requests
+	}
+	return 0
+}
+
+func (x *PeerStats) GetBytesOut() int64 {
+	if x != nil && x.BytesOut != nil {
+		return *x.BytesOut
+	}
+	return 0
+}
+
+func (x *PeerStats) GetBytesIn() int64 {
+	if x != nil && x.BytesIn != nil {
+		return *x.BytesIn
+	}
+	return 0
+}
+
+func (x *PeerStats) GetTimeouts() int64 {
+	if x != nil && x.Timeouts != nil {
+		return *x.Timeouts
+	}
+	return 0
+}
+
+func (x *PeerStats) GetConnRefused() int64 {
+	if x != nil && x.ConnRefused != nil {
+		return *x.ConnRefused
+	}
+	return 0
+}
+
+func (x *PeerStats) GetBadStatus() int64 {
+	if x != nil && x.BadStatus != nil {
+		return *x.BadStatus
+	}
+	return 0
+}
+
+func (x *PeerStats) GetOtherErrors() int64 {
+	if x != nil && x.OtherErrors != nil {
+		return *x.OtherErrors
+	}
+	return 0
+}
+
+func (x *PeerStats) GetLastErrorUnixNano() int64 {
+	if x != nil && x.LastErrorUnixNano != nil {
+		return *x.LastErrorUnixNano
+	}
+	return 0
+}
+
+func (x *PeerStats) GetInFlight() int64 {
+	if x != nil && x.InFlight != nil {
+		return *x.InFlight
+	}
+	return 0
+}
+
+func (x *PeerStats) GetBusy() int64 {
+	if x != nil && x.Busy != nil {
+		return *x.Busy
+	}
+	return 0
+}
+
+func (x *PeerStats) GetCompleted() int64 {
+	if x != nil && x.Completed != nil {
+		return *x.Completed
+	}
+	return 0
+}
+
+func (x *PeerStats) GetLatencyNanos() int64 {
+	if x != nil && x.LatencyNanos != nil {
+		return *x.LatencyNanos
+	}
+	return 0
+}
+
+type ExportRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Group         string                 `protobuf:"bytes,1,opt,name=group,proto3" json:"group,omitempty"` // 组名
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExportRequest) Reset() {
+	*x = ExportRequest{}
+	mi := &file_cache_server_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExportRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExportRequest) ProtoMessage() {}
+
+func (x *ExportRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_cache_server_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExportRequest.ProtoReflect.Descriptor instead.
+func (*ExportRequest) Descriptor() ([]byte, []int) {
+	return file_cache_server_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *ExportRequest) GetGroup() string {
+	if x != nil {
+		return x.Group
+	}
+	return ""
+}
+
+type ExportEntry struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`                                     // 原始键
+	Value         []byte                 `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`                                 // 值
+	ExpiresAt     *int64                 `protobuf:"varint,3,opt,name=expires_at,json=expiresAt,proto3,oneof" json:"expires_at,omitempty"` // 绝对过期时间（Unix 纳秒），0 表示永不过期
+	Version       *uint64                `protobuf:"varint,4,opt,name=version,proto3,oneof" json:"version,omitempty"`                      // 条目写入序号
+	Encoded       *bool                  `protobuf:"varint,5,opt,name=encoded,proto3,oneof" json:"encoded,omitempty"`                      // 值是导出组经值变换（例如加密）后的存储形式，导入方须能解码
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExportEntry) Reset() {
+	*x = ExportEntry{}
+	mi := &file_cache_server_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExportEntry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExportEntry) ProtoMessage() {}
+
+func (x *ExportEntry) ProtoReflect() protoreflect.Message {
+	mi := &file_cache_server_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExportEntry.ProtoReflect.Descriptor instead.
+func (*ExportEntry) Descriptor() ([]byte, []int) {
+	return file_cache_server_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *ExportEntry) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *ExportEntry) GetValue() []byte {
+	if x != nil {
+		return x.Value
+	}
+	return nil
+}
+
+func (x *ExportEntry) GetExpiresAt() int64 {
+	if x != nil && x.ExpiresAt != nil {
+		return *x.ExpiresAt
+	}
+	return 0
+}
+
+func (x *ExportEntry) GetVersion() uint64 {
+	if x != nil && x.Version != nil {
+		return *x.Version
+	}
+	return 0
+}
+
+func (x *ExportEntry) GetEncoded() bool {
+	if x != nil && x.Encoded != nil {
+		return *x.Encoded
+	}
+	return false
+}
+
+type ImportRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Group         string                 `protobuf:"bytes,1,opt,name=group,proto3" json:"group,omitempty"` // 组名，每条消息都需携带
+	Entry         *ExportEntry           `protobuf:"bytes,2,opt,name=entry,proto3" json:"entry,omitempty"` // 导入的条目
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ImportRequest) Reset() {
+	*x = ImportRequest{}
+	mi := &file_cache_server_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ImportRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ImportRequest) ProtoMessage() {}
+
+func (x *ImportRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_cache_server_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ImportRequest.ProtoReflect.Descriptor instead.
+func (*ImportRequest) Descriptor() ([]byte, []int) {
+	return file_cache_server_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *ImportRequest) GetGroup() string {
+	if x != nil {
+		return x.Group
+	}
+	return ""
+}
+
+func (x *ImportRequest) GetEntry() *ExportEntry {
+	if x != nil {
+		return x.Entry
+	}
+	return nil
+}
+
+type ImportResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Imported      *int64                 `protobuf:"varint,1,opt,name=imported,proto3,oneof" json:"imported,omitempty"` // 成功导入的条目数
+	Expired       *int64                 `protobuf:"varint,2,opt,name=expired,proto3,oneof" json:"expired,omitempty"`   // 因已过期被跳过的条目数
+	Skipped       *int64                 `protobuf:"varint,3,opt,name=skipped,proto3,oneof" json:"skipped,omitempty"`   // 因容量不足等原因被跳过的条目数
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ImportResponse) Reset() {
+	*x = ImportResponse{}
+	mi := &file_cache_server_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ImportResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ImportResponse) ProtoMessage() {}
+
+func (x *ImportResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_cache_server_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ImportResponse.ProtoReflect.Descriptor instead.
+func (*ImportResponse) Descriptor() ([]byte, []int) {
+	return file_cache_server_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *ImportResponse) GetImported() int64 {
+	if x != nil && x.Imported != nil {
+		return *x.Imported
+	}
+	return 0
+}
+
+func (x *ImportResponse) GetExpired() int64 {
+	if x != nil && x.Expired != nil {
+		return *x.Expired
+	}
+	return 0
+}
+
+func (x *ImportResponse) GetSkipped() int64 {
+	if x != nil && x.Skipped != nil {
+		return *x.Skipped
+	}
+	return 0
+}
+
+type ListOwnedRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Group         string                 `protobuf:"bytes,1,opt,name=group,proto3" json:"group,omitempty"`                                // 组名
+	RingVersion   string                 `protobuf:"bytes,2,opt,name=ring_version,json=ringVersion,proto3" json:"ring_version,omitempty"` // 调用方的哈希环版本，与接收节点不一致时拒绝
+	NodeId        string                 `protobuf:"bytes,3,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`                // 只列出哈希环上归属该节点的 key
+	Limit         *int32                 `protobuf:"varint,4,opt,name=limit,proto3,oneof" json:"limit,omitempty"`                         // 每页最多返回的 key 数，缺省为 1000
+	Cursor        *string                `protobuf:"bytes,5,opt,name=cursor,proto3,oneof" json:"cursor,omitempty"`                        // 从该 key 之后开始列出，缺省从头开始
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListOwnedRequest) Reset() {
+	*x = ListOwnedRequest{}
+	mi := &file_cache_server_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListOwnedRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListOwnedRequest) ProtoMessage() {}
+
+func (x *ListOwnedRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_cache_server_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListOwnedRequest.ProtoReflect.Descriptor instead.
+func (*ListOwnedRequest) Descriptor() ([]byte, []int) {
+	return file_cache_server_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *ListOwnedRequest) GetGroup() string {
+	if x != nil {
+		return x.Group
+	}
+	return ""
+}
+
+func (x *ListOwnedRequest) GetRingVersion() string {
+	if x != nil {
+		return x.RingVersion
+	}
+	return ""
+}
+
+func (x *ListOwnedRequest) GetNodeId() string {
+	if x != nil {
+		return x.NodeId
+	}
+	return ""
+}
+
+func (x *ListOwnedRequest) GetLimit() int32 {
+	if x != nil && x.Limit != nil {
+		return *x.Limit
+	}
+	return 0
+}
+
+func (x *ListOwnedRequest) GetCursor() string {
+	if x != nil && x.Cursor != nil {
+		return *x.Cursor
+	}
+	return ""
+}
+
+type OwnedKey struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`                  // 键
+	Accesses      *uint64                `protobuf:"varint,2,opt,name=accesses,proto3,oneof" json:"accesses,omitempty"` // 条目写入后被读取的次数
+	Size          *int64                 `protobuf:"varint,3,opt,name=size,proto3,oneof" json:"size,omitempty"`         // 值的字节数
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *OwnedKey) Reset() {
+	*x = OwnedKey{}
+	mi := &file_cache_server_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OwnedKey) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OwnedKey) ProtoMessage() {}
+
+func (x *OwnedKey) ProtoReflect() protoreflect.Message {
+	mi := &file_cache_server_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OwnedKey.ProtoReflect.Descriptor instead.
+func (*OwnedKey) Descriptor() ([]byte, []int) {
+	return file_cache_server_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *OwnedKey) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *OwnedKey) GetAccesses() uint64 {
+	if x != nil && x.Accesses != nil {
+		return *x.Accesses
+	}
+	return 0
+}
+
+func (x *OwnedKey) GetSize() int64 {
+	if x != nil && x.Size != nil {
+		return *x.Size
+	}
+	return 0
+}
+
+type ListOwnedResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Keys          []*OwnedKey            `protobuf:"bytes,1,rep,name=keys,proto3" json:"keys,omitempty"`                                     // 按 key 排序
+	NextCursor    *string                `protobuf:"bytes,2,opt,name=next_cursor,json=nextCursor,proto3,oneof" json:"next_cursor,omitempty"` // 下一页的游标，缺省表示已列完
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListOwnedResponse) Reset() {
+	*x = ListOwnedResponse{}
+	mi := &file_cache_server_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListOwnedResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListOwnedResponse) ProtoMessage() {}
+
+func (x *ListOwnedResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_cache_server_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListOwnedResponse.ProtoReflect.Descriptor instead.
+func (*ListOwnedResponse) Descriptor() ([]byte, []int) {
+	return file_cache_server_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *ListOwnedResponse) GetKeys() []*OwnedKey {
+	if x != nil {
+		return x.Keys
+	}
+	return nil
+}
+
+func (x *ListOwnedResponse) GetNextCursor() string {
+	if x != nil && x.NextCursor != nil {
+		return *x.NextCursor
+	}
+	return ""
+}
+
+type InfoRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *InfoRequest) Reset() {
+	*x = InfoRequest{}
+	mi := &file_cache_server_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *InfoRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InfoRequest) ProtoMessage() {}
+
+func (x *InfoRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_cache_server_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InfoRequest.ProtoReflect.Descriptor instead.
+func (*InfoRequest) Descriptor() ([]byte, []int) {
+	return file_cache_server_proto_rawDescGZIP(), []int{22}
+}
+
+type InfoResponse struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	Component         string                 `protobuf:"bytes,1,opt,name=component,proto3" json:"component,omitempty"`                                                                     // 组件名称
+	Version           *string                `protobuf:"bytes,2,opt,name=version,proto3,oneof" json:"version,omitempty"`                                                                   // 构建版本
+	Commit            *string                `protobuf:"bytes,3,opt,name=commit,proto3,oneof" json:"commit,omitempty"`                                                                     // 构建提交
+	BuildDate         *string                `protobuf:"bytes,4,opt,name=build_date,json=buildDate,proto3,oneof" json:"build_date,omitempty"`                                              // 构建时间
+	GoVersion         *string                `protobuf:"bytes,5,opt,name=go_version,json=goVersion,proto3,oneof" json:"go_version,omitempty"`                                              // Go 运行时版本
+	StartTimeUnixNano *int64                 `protobuf:"varint,6,opt,name=start_time_unix_nano,json=startTimeUnixNano,proto3,oneof" json:"start_time_unix_nano,omitempty"`                 // 启动时间（Unix 纳秒）
+	Config            map[string]string      `protobuf:"bytes,7,rep,name=config,proto3" json:"config,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // 生效的配置，敏感值已脱敏
+	DiscoveryMode     *string                `protobuf:"bytes,8,opt,name=discovery_mode,json=discoveryMode,proto3,oneof" json:"discovery_mode,omitempty"`                                  // 服务发现方式
+	DiscoveryState    *string                `protobuf:"bytes,9,opt,name=discovery_state,json=discoveryState,proto3,oneof" json:"discovery_state,omitempty"`                               // 服务发现状态
+	ProtocolVersion   *int32                 `protobuf:"varint,10,opt,name=protocol_version,json=protocolVersion,proto3,oneof" json:"protocol_version,omitempty"`                          // 节点间通信协议版本，见 peers.ProtocolVersion
+	ServableGroups    []string               `protobuf:"bytes,11,rep,name=servable_groups,json=servableGroups,proto3" json:"servable_groups,omitempty"`                                    // 允许对外读取的组，为空表示不限制
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *InfoResponse) Reset() {
+	*x = InfoResponse{}
+	mi := &file_cache_server_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *InfoResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InfoResponse) ProtoMessage() {}
+
+func (x *InfoResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_cache_server_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InfoResponse.ProtoReflect.Descriptor instead.
+func (*InfoResponse) Descriptor() ([]byte, []int) {
+	return file_cache_server_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *InfoResponse) GetComponent() string {
+	if x != nil {
+		return x.Component
+	}
+	return ""
+}
+
+func (x *InfoResponse) GetVersion() string {
+	if x != nil && x.Version != nil {
+		return *x.Version
+	}
+	return ""
+}
+
+func (x *InfoResponse) GetCommit() string {
+	if x != nil && x.Commit != nil {
+		return *x.Commit
+	}
+	return ""
+}
+
+func (x *InfoResponse) GetBuildDate() string {
+	if x != nil && x.BuildDate != nil {
+		return *x.BuildDate
+	}
+	return ""
+}
+
+func (x *InfoResponse) GetGoVersion() string {
+	if x != nil && x.GoVersion != nil {
+		return *x.GoVersion
+	}
+	return ""
+}
+
+func (x *InfoResponse) GetStartTimeUnixNano() int64 {
+	if x != nil && x.StartTimeUnixNano != nil {
+		return *x.StartTimeUnixNano
+	}
+	return 0
+}
+
+func (x *InfoResponse) GetConfig() map[string]string {
+	if x != nil {
+		return x.Config
+	}
+	return nil
+}
+
+func (x *InfoResponse) GetDiscoveryMode() string {
+	if x != nil && x.DiscoveryMode != nil {
+		return *x.DiscoveryMode
+	}
+	return ""
+}
+
+func (x *InfoResponse) GetDiscoveryState() string {
+	if x != nil && x.DiscoveryState != nil {
+		return *x.DiscoveryState
+	}
+	return ""
+}
+
+func (x *InfoResponse) GetProtocolVersion() int32 {
+	if x != nil && x.ProtocolVersion != nil {
+		return *x.ProtocolVersion
+	}
+	return 0
+}
+
+func (x *InfoResponse) GetServableGroups() []string {
+	if x != nil {
+		return x.ServableGroups
+	}
+	return nil
+}
+
+var File_cache_server_proto protoreflect.FileDescriptor
+
+const file_cache_server_proto_rawDesc = "" +
+	"\n" +
+	"\x12cache_server.proto\x12\bgo_cache\"\xea\x01\n" +
+	"\aRequest\x12\x14\n" +
+	"\x05group\x18\x01 \x01(\tR\x05group\x12\x10\n" +
+	"\x03key\x18\x02 \x01(\tR\x03key\x12\x17\n" +
+	"\x04hops\x18\x03 \x01(\rH\x00R\x04hops\x88\x01\x01\x12\x17\n" +
+	"\x04from\x18\x04 \x01(\tH\x01R\x04from\x88\x01\x01\x12\"\n" +
+	"\n" +
+	"cache_only\x18\x05 \x01(\bH\x02R\tcacheOnly\x88\x01\x01\x12,\n" +
+	"\x0fring_generation\x18\x06 \x01(\x04H\x03R\x0eringGeneration\x88\x01\x01B\a\n" +
+	"\x05_hopsB\a\n" +
+	"\x05_fromB\r\n" +
+	"\v_cache_onlyB\x12\n" +
+	"\x10_ring_generation\"\xbb\x02\n" +
+	"\bResponse\x12\x14\n" +
+	"\x05value\x18\x01 \x01(\fR\x05value\x12\"\n" +
+	"\n" +
+	"expires_at\x18\x02 \x01(\x03H\x00R\texpiresAt\x88\x01\x01\x12\x1d\n" +
+	"\aversion\x18\x03 \x01(\x04H\x01R\aversion\x88\x01\x01\x12\x1b\n" +
+	"\x06source\x18\x04 \x01(\tH\x02R\x06source\x88\x01\x01\x12\x1f\n" +
+	"\breplicas\x18\x05 \x01(\rH\x03R\breplicas\x88\x01\x01\x12.\n" +
+	"\x10replicated_until\x18\x06 \x01(\x03H\x04R\x0freplicatedUntil\x88\x01\x01\x12\x17\n" +
+	"\x04node\x18\a \x01(\tH\x05R\x04node\x88\x01\x01B\r\n" +
+	"\v_expires_atB\n" +
+	"\n" +
+	"\b_versionB\t\n" +
+	"\a_sourceB\v\n" +
+	"\t_replicasB\x13\n" +
+	"\x11_replicated_untilB\a\n" +
+	"\x05_node\"7\n" +
+	"\rDeleteRequest\x12\x14\n" +
+	"\x05group\x18\x01 \x01(\tR\x05group\x12\x10\n" +
+	"\x03key\x18\x02 \x01(\tR\x03key\"*\n" +
+	"\x0eDeleteResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\"q\n" +
+	"\n" +
+	"SetRequest\x12\x14\n" +
+	"\x05group\x18\x01 \x01(\tR\x05group\x12\x10\n" +
+	"\x03key\x18\x02 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x03 \x01(\fR\x05value\x12\x1a\n" +
+	"\x06ttl_ms\x18\x04 \x01(\x03H\x00R\x05ttlMs\x88\x01\x01B\t\n" +
+	"\a_ttl_ms\"'\n" +
+	"\vSetResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\">\n" +
+	"\x12DeleteBatchRequest\x12\x14\n" +
+	"\x05group\x18\x01 \x01(\tR\x05group\x12\x12\n" +
+	"\x04keys\x18\x02 \x03(\tR\x04keys\"b\n" +
+	"\x11DeleteBatchResult\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x16\n" +
+	"\x06status\x18\x02 \x01(\tR\x06status\x12\x19\n" +
+	"\x05error\x18\x03 \x01(\tH\x00R\x05error\x88\x01\x01B\b\n" +
+	"\x06_error\"L\n" +
+	"\x13DeleteBatchResponse\x125\n" +
+	"\aresults\x18\x01 \x03(\v2\x1b.go_cache.DeleteBatchResultR\aresults\"3\n" +
+	"\fStatsRequest\x12\x19\n" +
+	"\x05group\x18\x01 \x01(\tH\x00R\x05group\x88\x01\x01B\b\n" +
+	"\x06_group\"\xff\b\n" +
+	"\n" +
+	"GroupStats\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x17\n" +
+	"\x04hits\x18\x02 \x01(\x03H\x00R\x04hits\x88\x01\x01\x12\x1b\n" +
+	"\x06misses\x18\x03 \x01(\x03H\x01R\x06misses\x88\x01\x01\x12!\n" +
+	"\tevictions\x18\x04 \x01(\x03H\x02R\tevictions\x88\x01\x01\x12\x19\n" +
+	"\x05bytes\x18\x05 \x01(\x03H\x03R\x05bytes\x88\x01\x01\x12\x1d\n" +
+	"\aentries\x18\x06 \x01(\x03H\x04R\aentries\x88\x01\x01\x12\x17\n" +
+	"\x04gets\x18\a \x01(\x03H\x05R\x04gets\x88\x01\x01\x12 \n" +
+	"\tmax_bytes\x18\b \x01(\x03H\x06R\bmaxBytes\x88\x01\x01\x12!\n" +
+	"\tthrottled\x18\t \x01(\x03H\aR\tthrottled\x88\x01\x01\x12\x17\n" +
+	"\x04mode\x18\n" +
+	" \x01(\tH\bR\x04mode\x88\x01\x01\x12\x17\n" +
+	"\x04cost\x18\v \x01(\x03H\tR\x04cost\x88\x01\x01\x125\n" +
+	"\x14evictions_per_minute\x18\f \x01(\x03H\n" +
+	"R\x12evictionsPerMinute\x88\x01\x01\x120\n" +
+	"\x12evicted_age_p50_ms\x18\r \x01(\x03H\vR\x0fevictedAgeP50Ms\x88\x01\x01\x120\n" +
+	"\x12evicted_age_p90_ms\x18\x0e \x01(\x03H\fR\x0fevictedAgeP90Ms\x88\x01\x01\x12A\n" +
+	"\x1aeviction_pressure_warnings\x18\x0f \x01(\x03H\rR\x18evictionPressureWarnings\x88\x01\x01\x12*\n" +
+	"\x0ecancelled_gets\x18\x10 \x01(\x03H\x0eR\rcancelledGets\x88\x01\x01\x12(\n" +
+	"\raborted_loads\x18\x11 \x01(\x03H\x0fR\fabortedLoads\x88\x01\x01\x124\n" +
+	"\x13watermark_evictions\x18\x12 \x01(\x03H\x10R\x12watermarkEvictions\x88\x01\x01\x12*\n" +
+	"\x0eloads_executed\x18\x13 \x01(\x03H\x11R\rloadsExecuted\x88\x01\x01\x12(\n" +
+	"\rloads_deduped\x18\x14 \x01(\x03H\x12R\floadsDeduped\x88\x01\x01\x12.\n" +
+	"\x10current_inflight\x18\x15 \x01(\x03H\x13R\x0fcurrentInflight\x88\x01\x01B\a\n" +
+	"\x05_hitsB\t\n" +
+	"\a_missesB\f\n" +
+	"\n" +
+	"_evictionsB\b\n" +
+	"\x06_bytesB\n" +
+	"\n" +
+	"\b_entriesB\a\n" +
+	"\x05_getsB\f\n" +
+	"\n" +
+	"_max_bytesB\f\n" +
+	"\n" +
+	"_throttledB\a\n" +
+	"\x05_modeB\a\n" +
+	"\x05_costB\x17\n" +
+	"\x15_evictions_per_minuteB\x15\n" +
+	"\x13_evicted_age_p50_msB\x15\n" +
+	"\x13_evicted_age_p90_msB\x1d\n" +
+	"\x1b_eviction_pressure_warningsB\x11\n" +
+	"\x0f_cancelled_getsB\x10\n" +
+	"\x0e_aborted_loadsB\x16\n" +
+	"\x14_watermark_evictionsB\x11\n" +
+	"\x0f_loads_executedB\x10\n" +
+	"\x0e_loads_dedupedB\x13\n" +
+	"\x11_current_inflight\"\xc4\x03\n" +
+	"\rStatsResponse\x12,\n" +
+	"\x06groups\x18\x01 \x03(\v2\x14.go_cache.GroupStatsR\x06groups\x12*\n" +
+	"\x0euptime_seconds\x18\x02 \x01(\x03H\x00R\ruptimeSeconds\x88\x01\x01\x12*\n" +
+	"\x0enode_throttled\x18\x03 \x01(\x03H\x01R\rnodeThrottled\x88\x01\x01\x12\x17\n" +
+	"\x04mode\x18\x04 \x01(\tH\x02R\x04mode\x88\x01\x01\x12)\n" +
+	"\x05peers\x18\x05 \x03(\v2\x13.go_cache.PeerStatsR\x05peers\x122\n" +
+	"\x06warmup\x18\x06 \x01(\v2\x15.go_cache.WarmupStatsH\x03R\x06warmup\x88\x01\x01\x12/\n" +
+	"\x05prime\x18\a \x01(\v2\x14.go_cache.PrimeStatsH\x04R\x05prime\x88\x01\x01\x12,\n" +
+	"\x0fring_generation\x18\b \x01(\x04H\x05R\x0eringGeneration\x88\x01\x01B\x11\n" +
+	"\x0f_uptime_secondsB\x11\n" +
+	"\x0f_node_throttledB\a\n" +
+	"\x05_modeB\t\n" +
+	"\a_warmupB\b\n" +
+	"\x06_primeB\x12\n" +
+	"\x10_ring_generation\"\xdb\x03\n" +
+	"\n" +
+	"PrimeStats\x12\x14\n" +
+	"\x05state\x18\x01 \x01(\tR\x05state\x12/\n" +
+	"\x11started_unix_nano\x18\x02 \x01(\x03H\x00R\x0fstartedUnixNano\x88\x01\x01\x12\"\n" +
+	"\n" +
+	"elapsed_ms\x18\x03 \x01(\x03H\x01R\telapsedMs\x88\x01\x01\x12\x19\n" +
+	"\x05total\x18\x04 \x01(\x03H\x02R\x05total\x88\x01\x01\x12\x17\n" +
+	"\x04done\x18\x05 \x01(\x03H\x03R\x04done\x88\x01\x01\x12\x1b\n" +
+	"\x06loaded\x18\x06 \x01(\x03H\x04R\x06loaded\x88\x01\x01\x12\x1b\n" +
+	"\x06cached\x18\a \x01(\x03H\x05R\x06cached\x88\x01\x01\x12 \n" +
+	"\tnot_owned\x18\b \x01(\x03H\x06R\bnotOwned\x88\x01\x01\x12\x1d\n" +
+	"\amissing\x18\t \x01(\x03H\aR\amissing\x88\x01\x01\x12\x1b\n" +
+	"\x06failed\x18\n" +
+	" \x01(\x03H\bR\x06failed\x88\x01\x01\x12\x19\n" +
+	"\x05error\x18\v \x01(\tH\tR\x05error\x88\x01\x01B\x14\n" +
+	"\x12_started_unix_nanoB\r\n" +
+	"\v_elapsed_msB\b\n" +
+	"\x06_totalB\a\n" +
+	"\x05_doneB\t\n" +
+	"\a_loadedB\t\n" +
+	"\a_cachedB\f\n" +
+	"\n" +
+	"_not_ownedB\n" +
+	"\n" +
+	"\b_missingB\t\n" +
+	"\a_failedB\b\n" +
+	"\x06_error\"\xc4\x03\n" +
+	"\vWarmupStats\x12\x14\n" +
+	"\x05state\x18\x01 \x01(\tR\x05state\x12/\n" +
+	"\x11started_unix_nano\x18\x02 \x01(\x03H\x00R\x0fstartedUnixNano\x88\x01\x01\x12\"\n" +
+	"\n" +
+	"elapsed_ms\x18\x03 \x01(\x03H\x01R\telapsedMs\x88\x01\x01\x12#\n" +
+	"\n" +
+	"candidates\x18\x04 \x01(\x03H\x02R\n" +
+	"candidates\x88\x01\x01\x12\x1f\n" +
+	"\bselected\x18\x05 \x01(\x03H\x03R\bselected\x88\x01\x01\x12\x1b\n" +
+	"\x06pulled\x18\x06 \x01(\x03H\x04R\x06pulled\x88\x01\x01\x12\x19\n" +
+	"\x05bytes\x18\a \x01(\x03H\x05R\x05bytes\x88\x01\x01\x12\x1d\n" +
+	"\askipped\x18\b \x01(\x03H\x06R\askipped\x88\x01\x01\x12\x1b\n" +
+	"\x06failed\x18\t \x01(\x03H\aR\x06failed\x88\x01\x01\x12\x19\n" +
+	"\x05error\x18\n" +
+	" \x01(\tH\bR\x05error\x88\x01\x01B\x14\n" +
+	"\x12_started_unix_nanoB\r\n" +
+	"\v_elapsed_msB\r\n" +
+	"\v_candidatesB\v\n" +
+	"\t_selectedB\t\n" +
+	"\a_pulledB\b\n" +
+	"\x06_bytesB\n" +
+	"\n" +
+	"\b_skippedB\t\n" +
+	"\a_failedB\b\n" +
+	"\x06_error\"\x8b\x05\n" +
+	"\tPeerStats\x12\x12\n" +
+	"\x04peer\x18\x01 \x01(\tR\x04peer\x12\x1f\n" +
+	"\brequests\x18\x02 \x01(\x03H\x00R\brequests\x88\x01\x01\x12 \n" +
+	"\tbytes_out\x18\x03 \x01(\x03H\x01R\bbytesOut\x88\x01\x01\x12\x1e\n" +
+	"\bbytes_in\x18\x04 \x01(\x03H\x02R\abytesIn\x88\x01\x01\x12\x1f\n" +
+	"\btimeouts\x18\x05 \x01(\x03H\x03R\btimeouts\x88\x01\x01\x12&\n" +
+	"\fconn_refused\x18\x06 \x01(\x03H\x04R\vconnRefused\x88\x01\x01\x12\"\n" +
+	"\n" +
+	"bad_status\x18\a \x01(\x03H\x05R\tbadStatus\x88\x01\x01\x12&\n" +
+	"\fother_errors\x18\b \x01(\x03H\x06R\votherErrors\x88\x01\x01\x124\n" +
+	"\x14last_error_unix_nano\x18\t \x01(\x03H\aR\x11lastErrorUnixNano\x88\x01\x01\x12 \n" +
+	"\tin_flight\x18\n" +
+	" \x01(\x03H\bR\binFlight\x88\x01\x01\x12\x17\n" +
+	"\x04busy\x18\v \x01(\x03H\tR\x04busy\x88\x01\x01\x12!\n" +
+	"\tcompleted\x18\f \x01(\x03H\n" +
+	"R\tcompleted\x88\x01\x01\x12(\n" +
+	"\rlatency_nanos\x18\r \x01(\x03H\vR\flatencyNanos\x88\x01\x01B\v\n" +
+	"\t_requestsB\f\n" +
+	"\n" +
+	"_bytes_outB\v\n" +
+	"\t_bytes_inB\v\n" +
+	"\t_timeoutsB\x0f\n" +
+	"\r_conn_refusedB\r\n" +
+	"\v_bad_statusB\x0f\n" +
+	"\r_other_errorsB\x17\n" +
+	"\x15_last_error_unix_nanoB\f\n" +
+	"\n" +
+	"_in_flightB\a\n" +
+	"\x05_busyB\f\n" +
+	"\n" +
+	"_completedB\x10\n" +
+	"\x0e_latency_nanos\"%\n" +
+	"\rExportRequest\x12\x14\n" +
+	"\x05group\x18\x01 \x01(\tR\x05group\"\xbe\x01\n" +
+	"\vExportEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\fR\x05value\x12\"\n" +
+	"\n" +
+	"expires_at\x18\x03 \x01(\x03H\x00R\texpiresAt\x88\x01\x01\x12\x1d\n" +
+	"\aversion\x18\x04 \x01(\x04H\x01R\aversion\x88\x01\x01\x12\x1d\n" +
+	"\aencoded\x18\x05 \x01(\bH\x02R\aencoded\x88\x01\x01B\r\n" +
+	"\v_expires_atB\n" +
+	"\n" +
+	"\b_versionB\n" +
+	"\n" +
+	"\b_encoded\"R\n" +
+	"\rImportRequest\x12\x14\n" +
+	"\x05group\x18\x01 \x01(\tR\x05group\x12+\n" +
+	"\x05entry\x18\x02 \x01(\v2\x15.go_cache.ExportEntryR\x05entry\"\x94\x01\n" +
+	"\x0eImportResponse\x12\x1f\n" +
+	"\bimported\x18\x01 \x01(\x03H\x00R\bimported\x88\x01\x01\x12\x1d\n" +
+	"\aexpired\x18\x02 \x01(\x03H\x01R\aexpired\x88\x01\x01\x12\x1d\n" +
+	"\askipped\x18\x03 \x01(\x03H\x02R\askipped\x88\x01\x01B\v\n" +
+	"\t_importedB\n" +
+	"\n" +
+	"\b_expiredB\n" +
+	"\n" +
+	"\b_skipped\"\xb1\x01\n" +
+	"\x10ListOwnedRequest\x12\x14\n" +
+	"\x05group\x18\x01 \x01(\tR\x05group\x12!\n" +
+	"\fring_version\x18\x02 \x01(\tR\vringVersion\x12\x17\n" +
+	"\anode_id\x18\x03 \x01(\tR\x06nodeId\x12\x19\n" +
+	"\x05limit\x18\x04 \x01(\x05H\x00R\x05limit\x88\x01\x01\x12\x1b\n" +
+	"\x06cursor\x18\x05 \x01(\tH\x01R\x06cursor\x88\x01\x01B\b\n" +
+	"\x06_limitB\t\n" +
+	"\a_cursor\"l\n" +
+	"\bOwnedKey\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x1f\n" +
+	"\baccesses\x18\x02 \x01(\x04H\x00R\baccesses\x88\x01\x01\x12\x17\n" +
+	"\x04size\x18\x03 \x01(\x03H\x01R\x04size\x88\x01\x01B\v\n" +
+	"\t_accessesB\a\n" +
+	"\x05_size\"q\n" +
+	"\x11ListOwnedResponse\x12&\n" +
+	"\x04keys\x18\x01 \x03(\v2\x12.go_cache.OwnedKeyR\x04keys\x12$\n" +
+	"\vnext_cursor\x18\x02 \x01(\tH\x00R\n" +
+	"nextCursor\x88\x01\x01B\x0e\n" +
+	"\f_next_cursor\"\r\n" +
+	"\vInfoRequest\"\x9a\x05\n" +
+	"\fInfoResponse\x12\x1c\n" +
+	"\tcomponent\x18\x01 \x01(\tR\tcomponent\x12\x1d\n" +
+	"\aversion\x18\x02 \x01(\tH\x00R\aversion\x88\x01\x01\x12\x1b\n" +
+	"\x06commit\x18\x03 \x01(\tH\x01R\x06commit\x88\x01\x01\x12\"\n" +
+	"\n" +
+	"build_date\x18\x04 \x01(\tH\x02R\tbuildDate\x88\x01\x01\x12\"\n" +
+	"\n" +
+	"go_version\x18\x05 \x01(\tH\x03R\tgoVersion\x88\x01\x01\x124\n" +
+	"\x14start_time_unix_nano\x18\x06 \x01(\x03H\x04R\x11startTimeUnixNano\x88\x01\x01\x12:\n" +
+	"\x06config\x18\a \x03(\v2\".go_cache.InfoResponse.ConfigEntryR\x06config\x12*\n" +
+	"\x0ediscovery_mode\x18\b \x01(\tH\x05R\rdiscoveryMode\x88\x01\x01\x12,\n" +
+	"\x0fdiscovery_state\x18\t \x01(\tH\x06R\x0ediscoveryState\x88\x01\x01\x12.\n" +
+	"\x10protocol_version\x18\n" +
+	" \x01(\x05H\aR\x0fprotocolVersion\x88\x01\x01\x12'\n" +
+	"\x0fservable_groups\x18\v \x03(\tR\x0eservableGroups\x1a9\n" +
+	"\vConfigEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01B\n" +
+	"\n" +
+	"\b_versionB\t\n" +
+	"\a_commitB\r\n" +
+	"\v_build_dateB\r\n" +
+	"\v_go_versionB\x17\n" +
+	"\x15_start_time_unix_nanoB\x11\n" +
+	"\x0f_discovery_modeB\x12\n" +
+	"\x10_discovery_stateB\x13\n" +
+	"\x11_protocol_version2\xf7\x03\n" +
 	"\n" +
 	"GroupCache\x12,\n" +
 	"\x03Get\x12\x11.go_cache.Request\x1a\x12.go_cache.Response\x12;\n" +
-	"\x06Delete\x12\x17.go_cache.DeleteRequest\x1a\x18.go_cache.DeleteResponseB\x10Z\x0e./cache_serverb\x06proto3"
+	"\x06Delete\x12\x17.go_cache.DeleteRequest\x1a\x18.go_cache.DeleteResponse\x12J\n" +
+	"\vDeleteBatch\x12\x1c.go_cache.DeleteBatchRequest\x1a\x1d.go_cache.DeleteBatchResponse\x128\n" +
+	"\x05Stats\x12\x16.go_cache.StatsRequest\x1a\x17.go_cache.StatsResponse\x12:\n" +
+	"\x06Export\x12\x17.go_cache.ExportRequest\x1a\x15.go_cache.ExportEntry0\x01\x12=\n" +
+	"\x06Import\x12\x17.go_cache.ImportRequest\x1a\x18.go_cache.ImportResponse(\x01\x125\n" +
+	"\x04Info\x12\x15.go_cache.InfoRequest\x1a\x16.go_cache.InfoResponse\x12F\n" +
+	"\vListOwnedBy\x12\x1a.go_cache.ListOwnedRequest\x1a\x1b.go_cache.ListOwnedResponseB\x10Z\x0e./cache_serverb\x06proto3"
 
 var (
 	file_cache_server_proto_rawDescOnce sync.Once
@@ -245,23 +2173,64 @@ func file_cache_server_proto_rawDescGZIP() []byte {
 	return file_cache_server_proto_rawDescData
 }
 
-var file_cache_server_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_cache_server_proto_msgTypes = make([]protoimpl.MessageInfo, 25)
 var file_cache_server_proto_goTypes = []any{
-	(*Request)(nil),        // 0: go_cache.Request
-	(*Response)(nil),       // 1: go_cache.Response
-	(*DeleteRequest)(nil),  // 2: go_cache.DeleteRequest
-	(*DeleteResponse)(nil), // 3: go_cache.DeleteResponse
+	(*Request)(nil),             // 0: go_cache.Request
+	(*Response)(nil),            // 1: go_cache.Response
+	(*DeleteRequest)(nil),       // 2: go_cache.DeleteRequest
+	(*DeleteResponse)(nil),      // 3: go_cache.DeleteResponse
+	(*SetRequest)(nil),          // 4: go_cache.SetRequest
+	(*SetResponse)(nil),         // 5: go_cache.SetResponse
+	(*DeleteBatchRequest)(nil),  // 6: go_cache.DeleteBatchRequest
+	(*DeleteBatchResult)(nil),   // 7: go_cache.DeleteBatchResult
+	(*DeleteBatchResponse)(nil), // 8: go_cache.DeleteBatchResponse
+	(*StatsRequest)(nil),        // 9: go_cache.StatsRequest
+	(*GroupStats)(nil),          // 10: go_cache.GroupStats
+	(*StatsResponse)(nil),       // 11: go_cache.StatsResponse
+	(*PrimeStats)(nil),          // 12: go_cache.PrimeStats
+	(*WarmupStats)(nil),         // 13: go_cache.WarmupStats
+	(*PeerStats)(nil),           // 14: go_cache.PeerStats
+	(*ExportRequest)(nil),       // 15: go_cache.ExportRequest
+	(*ExportEntry)(nil),         // 16: go_cache.ExportEntry
+	(*ImportRequest)(nil),       // 17: go_cache.ImportRequest
+	(*ImportResponse)(nil),      // 18: go_cache.ImportResponse
+	(*ListOwnedRequest)(nil),    // 19: go_cache.ListOwnedRequest
+	(*OwnedKey)(nil),            // 20: go_cache.OwnedKey
+	(*ListOwnedResponse)(nil),   // 21: go_cache.ListOwnedResponse
+	(*InfoRequest)(nil),         // 22: go_cache.InfoRequest
+	(*InfoResponse)(nil),        // 23: go_cache.InfoResponse
+	nil,                         // 24: go_cache.InfoResponse.ConfigEntry
 }
 var file_cache_server_proto_depIdxs = []int32{
-	0, // 0: go_cache.GroupCache.Get:input_type -> go_cache.Request
-	2, // 1: go_cache.GroupCache.Delete:input_type -> go_cache.DeleteRequest
-	1, // 2: go_cache.GroupCache.Get:output_type -> go_cache.Response
-	3, // 3: go_cache.GroupCache.Delete:output_type -> go_cache.DeleteResponse
-	2, // [2:4] is the sub-list for method output_type
-	0, // [0:2] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	7,  // 0: go_cache.DeleteBatchResponse.results:type_name -> go_cache.DeleteBatchResult
+	10, // 1: go_cache.StatsResponse.groups:type_name -> go_cache.GroupStats
+	14, // 2: go_cache.StatsResponse.peers:type_name -> go_cache.PeerStats
+	13, // 3: go_cache.StatsResponse.warmup:type_name -> go_cache.WarmupStats
+	12, // 4: go_cache.StatsResponse.prime:type_name -> go_cache.PrimeStats
+	16, // 5: go_cache.ImportRequest.entry:type_name -> go_cache.ExportEntry
+	20, // 6: go_cache.ListOwnedResponse.keys:type_name -> go_cache.OwnedKey
+	24, // 7: go_cache.InfoResponse.config:type_name -> go_cache.InfoResponse.ConfigEntry
+	0,  // 8: go_cache.GroupCache.Get:input_type -> go_cache.Request
+	2,  // 9: go_cache.GroupCache.Delete:input_type -> go_cache.DeleteRequest
+	6,  // 10: go_cache.GroupCache.DeleteBatch:input_type -> go_cache.DeleteBatchRequest
+	9,  // 11: go_cache.GroupCache.Stats:input_type -> go_cache.StatsRequest
+	15, // 12: go_cache.GroupCache.Export:input_type -> go_cache.ExportRequest
+	17, // 13: go_cache.GroupCache.Import:input_type -> go_cache.ImportRequest
+	22, // 14: go_cache.GroupCache.Info:input_type -> go_cache.InfoRequest
+	19, // 15: go_cache.GroupCache.ListOwnedBy:input_type -> go_cache.ListOwnedRequest
+	1,  // 16: go_cache.GroupCache.Get:output_type -> go_cache.Response
+	3,  // 17: go_cache.GroupCache.Delete:output_type -> go_cache.DeleteResponse
+	8,  // 18: go_cache.GroupCache.DeleteBatch:output_type -> go_cache.DeleteBatchResponse
+	11, // 19: go_cache.GroupCache.Stats:output_type -> go_cache.StatsResponse
+	16, // 20: go_cache.GroupCache.Export:output_type -> go_cache.ExportEntry
+	18, // 21: go_cache.GroupCache.Import:output_type -> go_cache.ImportResponse
+	23, // 22: go_cache.GroupCache.Info:output_type -> go_cache.InfoResponse
+	21, // 23: go_cache.GroupCache.ListOwnedBy:output_type -> go_cache.ListOwnedResponse
+	16, // [16:24] is the sub-list for method output_type
+	8,  // [8:16] is the sub-list for method input_type
+	8,  // [8:8] is the sub-list for extension type_name
+	8,  // [8:8] is the sub-list for extension extendee
+	0,  // [0:8] is the sub-list for field type_name
 }
 
 func init() { file_cache_server_proto_init() }
@@ -269,13 +2238,29 @@ func file_cache_server_proto_init() {
 	if File_cache_server_proto != nil {
 		return
 	}
+	file_cache_server_proto_msgTypes[0].OneofWrappers = []any{}
+	file_cache_server_proto_msgTypes[1].OneofWrappers = []any{}
+	file_cache_server_proto_msgTypes[4].OneofWrappers = []any{}
+	file_cache_server_proto_msgTypes[7].OneofWrappers = []any{}
+	file_cache_server_proto_msgTypes[9].OneofWrappers = []any{}
+	file_cache_server_proto_msgTypes[10].OneofWrappers = []any{}
+	file_cache_server_proto_msgTypes[11].OneofWrappers = []any{}
+	file_cache_server_proto_msgTypes[12].OneofWrappers = []any{}
+	file_cache_server_proto_msgTypes[13].OneofWrappers = []any{}
+	file_cache_server_proto_msgTypes[14].OneofWrappers = []any{}
+	file_cache_server_proto_msgTypes[16].OneofWrappers = []any{}
+	file_cache_server_proto_msgTypes[18].OneofWrappers = []any{}
+	file_cache_server_proto_msgTypes[19].OneofWrappers = []any{}
+	file_cache_server_proto_msgTypes[20].OneofWrappers = []any{}
+	file_cache_server_proto_msgTypes[21].OneofWrappers = []any{}
+	file_cache_server_proto_msgTypes[23].OneofWrappers = []any{}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_cache_server_proto_rawDesc), len(file_cache_server_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   4,
+			NumMessages:   25,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -19,6 +19,12 @@ const _ = grpc.SupportPackageIsVersion7
 type GroupCacheClient interface {
 	Get(ctx context.Context, in *Request, opts ...grpc.CallOption) (*Response, error)
 	Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*DeleteResponse, error)
+	DeleteBatch(ctx context.Context, in *DeleteBatchRequest, opts ...grpc.CallOption) (*DeleteBatchResponse, error)
+	Stats(ctx context.Context, in *StatsRequest, opts ...grpc.CallOption) (*StatsResponse, error)
+	Export(ctx context.Context, in *ExportRequest, opts ...grpc.CallOption) (GroupCache_ExportClient, error)
+	Import(ctx context.Context, opts ...grpc.CallOption) (GroupCache_ImportClient, error)
+	Info(ctx context.Context, in *InfoRequest, opts ...grpc.CallOption) (*InfoResponse, error)
+	ListOwnedBy(ctx context.Context, in *ListOwnedRequest, opts ...grpc.CallOption) (*ListOwnedResponse, error)
 }
 
 type groupCacheClient struct {
@@ -47,12 +53,120 @@ func (c *groupCacheClient) Delete(ctx context.Context, in *DeleteRequest, opts .
 	return out, nil
 }
 
+func (c *groupCacheClient) DeleteBatch(ctx context.Context, in *DeleteBatchRequest, opts ...grpc.CallOption) (*DeleteBatchResponse, error) {
+	out := new(DeleteBatchResponse)
+	err := c.cc.Invoke(ctx, "/go_cache.GroupCache/DeleteBatch", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *groupCacheClient) Stats(ctx context.Context, in *StatsRequest, opts ...grpc.CallOption) (*StatsResponse, error) {
+	out := new(StatsResponse)
+	err := c.cc.Invoke(ctx, "/go_cache.GroupCache/Stats", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *groupCacheClient) Export(ctx context.Context, in *ExportRequest, opts ...grpc.CallOption) (GroupCache_ExportClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_GroupCache_serviceDesc.Streams[0], "/go_cache.GroupCache/Export", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &groupCacheExportClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type GroupCache_ExportClient interface {
+	Recv() (*ExportEntry, error)
+	grpc.ClientStream
+}
+
+type groupCacheExportClient struct {
+	grpc.ClientStream
+}
+
+func (x *groupCacheExportClient) Recv() (*ExportEntry, error) {
+	m := new(ExportEntry)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *groupCacheClient) Import(ctx context.Context, opts ...grpc.CallOption) (GroupCache_ImportClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_GroupCache_serviceDesc.Streams[1], "/go_cache.GroupCache/Import", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &groupCacheImportClient{stream}
+	return x, nil
+}
+
+type GroupCache_ImportClient interface {
+	Send(*ImportRequest) error
+	CloseAndRecv() (*ImportResponse, error)
+	grpc.ClientStream
+}
+
+type groupCacheImportClient struct {
+	grpc.ClientStream
+}
+
+func (x *groupCacheImportClient) Send(m *ImportRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *groupCacheImportClient) CloseAndRecv() (*ImportResponse, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(ImportResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *groupCacheClient) Info(ctx context.Context, in *InfoRequest, opts ...grpc.CallOption) (*InfoResponse, error) {
+	out := new(InfoResponse)
+	err := c.cc.Invoke(ctx, "/go_cache.GroupCache/Info", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *groupCacheClient) ListOwnedBy(ctx context.Context, in *ListOwnedRequest, opts ...grpc.CallOption) (*ListOwnedResponse, error) {
+	out := new(ListOwnedResponse)
+	err := c.cc.Invoke(ctx, "/go_cache.GroupCache/ListOwnedBy", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // GroupCacheServer is the server API for GroupCache service.
 // All implementations must embed UnimplementedGroupCacheServer
 // for forward compatibility
 type GroupCacheServer interface {
 	Get(context.Context, *Request) (*Response, error)
 	Delete(context.Context, *DeleteRequest) (*DeleteResponse, error)
+	DeleteBatch(context.Context, *DeleteBatchRequest) (*DeleteBatchResponse, error)
+	Stats(context.Context, *StatsRequest) (*StatsResponse, error)
+	Export(*ExportRequest, GroupCache_ExportServer) error
+	Import(GroupCache_ImportServer) error
+	Info(context.Context, *InfoRequest) (*InfoResponse, error)
+	ListOwnedBy(context.Context, *ListOwnedRequest) (*ListOwnedResponse, error)
 	mustEmbedUnimplementedGroupCacheServer()
 }
 
@@ -66,6 +180,24 @@ func (UnimplementedGroupCacheServer) Get(context.Context, *Request) (*Response,
 func (UnimplementedGroupCacheServer) Delete(context.Context, *DeleteRequest) (*DeleteResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Delete not implemented")
 }
+func (UnimplementedGroupCacheServer) DeleteBatch(context.Context, *DeleteBatchRequest) (*DeleteBatchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteBatch not implemented")
+}
+func (UnimplementedGroupCacheServer) Stats(context.Context, *StatsRequest) (*StatsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Stats not implemented")
+}
+func (UnimplementedGroupCacheServer) Export(*ExportRequest, GroupCache_ExportServer) error {
+	return status.Errorf(codes.Unimplemented, "method Export not implemented")
+}
+func (UnimplementedGroupCacheServer) Import(GroupCache_ImportServer) error {
+	return status.Errorf(codes.Unimplemented, "method Import not implemented")
+}
+func (UnimplementedGroupCacheServer) Info(context.Context, *InfoRequest) (*InfoResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Info not implemented")
+}
+func (UnimplementedGroupCacheServer) ListOwnedBy(context.Context, *ListOwnedRequest) (*ListOwnedResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListOwnedBy not implemented")
+}
 func (UnimplementedGroupCacheServer) mustEmbedUnimplementedGroupCacheServer() {}
 
 // UnsafeGroupCacheServer may be embedded to opt out of forward compatibility for this service.
@@ -115,6 +247,125 @@ func _GroupCache_Delete_Handler(srv interface{}, ctx context.Context, dec func(i
 	return interceptor(ctx, in, info, handler)
 }
 
+func _GroupCache_DeleteBatch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteBatchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GroupCacheServer).DeleteBatch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/go_cache.GroupCache/DeleteBatch",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GroupCacheServer).DeleteBatch(ctx, req.(*DeleteBatchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GroupCache_Stats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GroupCacheServer).Stats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/go_cache.GroupCache/Stats",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GroupCacheServer).Stats(ctx, req.(*StatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GroupCache_Export_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ExportRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(GroupCacheServer).Export(m, &groupCacheExportServer{stream})
+}
+
+type GroupCache_ExportServer interface {
+	Send(*ExportEntry) error
+	grpc.ServerStream
+}
+
+type groupCacheExportServer struct {
+	grpc.ServerStream
+}
+
+func (x *groupCacheExportServer) Send(m *ExportEntry) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _GroupCache_Import_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(GroupCacheServer).Import(&groupCacheImportServer{stream})
+}
+
+type GroupCache_ImportServer interface {
+	SendAndClose(*ImportResponse) error
+	Recv() (*ImportRequest, error)
+	grpc.ServerStream
+}
+
+type groupCacheImportServer struct {
+	grpc.ServerStream
+}
+
+func (x *groupCacheImportServer) SendAndClose(m *ImportResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *groupCacheImportServer) Recv() (*ImportRequest, error) {
+	m := new(ImportRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _GroupCache_Info_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(InfoRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GroupCacheServer).Info(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/go_cache.GroupCache/Info",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GroupCacheServer).Info(ctx, req.(*InfoRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GroupCache_ListOwnedBy_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListOwnedRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GroupCacheServer).ListOwnedBy(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/go_cache.GroupCache/ListOwnedBy",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GroupCacheServer).ListOwnedBy(ctx, req.(*ListOwnedRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _GroupCache_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "go_cache.GroupCache",
 	HandlerType: (*GroupCacheServer)(nil),
@@ -127,7 +378,34 @@ var _GroupCache_serviceDesc = grpc.ServiceDesc{
 			MethodName: "Delete",
 			Handler:    _GroupCache_Delete_Handler,
 		},
+		{
+			MethodName: "DeleteBatch",
+			Handler:    _GroupCache_DeleteBatch_Handler,
+		},
+		{
+			MethodName: "Stats",
+			Handler:    _GroupCache_Stats_Handler,
+		},
+		{
+			MethodName: "Info",
+			Handler:    _GroupCache_Info_Handler,
+		},
+		{
+			MethodName: "ListOwnedBy",
+			Handler:    _GroupCache_ListOwnedBy_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Export",
+			Handler:       _GroupCache_Export_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Import",
+			Handler:       _GroupCache_Import_Handler,
+			ClientStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "cache_server.proto",
 }